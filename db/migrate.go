@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations live in db/migrations as NNNN_name.up.sql / NNNN_name.down.sql pairs and are
+// applied in version order, each in its own transaction, tracked in schema_migrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationNameRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockKey serializes migrations across app instances (pg_advisory_lock key).
+const migrationLockKey = 727468
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState reports whether a migration has been applied.
+type MigrationState struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// LoadMigrations returns the embedded migrations sorted by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := migrationNameRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		b, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[v]
+		if !ok {
+			mig = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrate applies all pending migrations to the global connection.
+func Migrate() error {
+	_, err := MigrateUp(context.Background(), DB)
+	return err
+}
+
+// MigrateUp applies pending migrations in order and returns how many were applied.
+func MigrateUp(ctx context.Context, db *sqlx.DB) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = withMigrationLock(ctx, db, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.Up, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			slog.InfoContext(ctx, "migrate: applied", "version", m.Version, "name", m.Name)
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first.
+func MigrateDown(ctx context.Context, db *sqlx.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = withMigrationLock(ctx, db, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && n < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s is irreversible (no down file)", m.Version, m.Name)
+			}
+			if err := runMigration(ctx, conn, m.Down, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			slog.InfoContext(ctx, "migrate: reverted", "version", m.Version, "name", m.Name)
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// MigrationStatus lists every known migration with its applied state.
+func MigrationStatus(ctx context.Context, db *sqlx.DB) ([]MigrationState, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			at := at
+			st.Applied, st.AppliedAt = true, &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory lock,
+// so concurrently starting instances don't apply the same migration twice.
+func withMigrationLock(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Conn) error) error {
+	if db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sqlx.Conn) (map[int64]time.Time, error) {
+	out := map[int64]time.Time{}
+	rows, err := conn.QueryxContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		// Status may run before any migration created the table
+		var exists bool
+		if qerr := conn.GetContext(ctx, &exists, `SELECT to_regclass('public.schema_migrations') IS NOT NULL`); qerr == nil && !exists {
+			return out, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// runMigration executes a migration script and its bookkeeping in one transaction.
+func runMigration(ctx context.Context, conn *sqlx.Conn, script string, record func(*sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,21 @@
+package db
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	list, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 || list[0].Version != 1 || list[0].Name != "baseline" {
+		t.Fatalf("expected baseline migration first, got %+v", list)
+	}
+	for i, m := range list {
+		if m.Up == "" {
+			t.Errorf("migration %d has empty up script", m.Version)
+		}
+		if i > 0 && list[i-1].Version >= m.Version {
+			t.Errorf("migrations not strictly ordered at %d", m.Version)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+-- Drops every application table. Destructive: only for resetting development databases.
+DROP TABLE IF EXISTS pages CASCADE;
+DROP TABLE IF EXISTS cms_tombstones CASCADE;
+DROP TABLE IF EXISTS invites CASCADE;
+DROP TABLE IF EXISTS email_verifications CASCADE;
+DROP TABLE IF EXISTS password_resets CASCADE;
+DROP TABLE IF EXISTS site_settings CASCADE;
+DROP TABLE IF EXISTS collections CASCADE;
+DROP TABLE IF EXISTS likes CASCADE;
+DROP TABLE IF EXISTS images CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
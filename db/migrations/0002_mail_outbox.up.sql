@@ -0,0 +1,15 @@
+-- Persistent outbox for queued emails; rows are claimed with SKIP LOCKED so any instance can send.
+CREATE TABLE IF NOT EXISTS mail_outbox (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	to_email TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	sent_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_due ON mail_outbox(status, next_attempt_at);
@@ -0,0 +1 @@
+ALTER TABLE mail_outbox ADD COLUMN IF NOT EXISTS html_body TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_digest;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS notifications (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(32) NOT NULL,
+	actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	image_id UUID REFERENCES images(id) ON DELETE CASCADE,
+	message TEXT NOT NULL DEFAULT '',
+	read_at TIMESTAMP,
+	emailed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_digest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP;
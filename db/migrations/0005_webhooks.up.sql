@@ -0,0 +1,29 @@
+-- Outgoing webhooks and their delivery log; deliveries are claimed with SKIP LOCKED like mail_outbox.
+CREATE TABLE IF NOT EXISTS webhooks (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	url TEXT NOT NULL,
+	secret VARCHAR(128) NOT NULL,
+	events TEXT NOT NULL DEFAULT '*',
+	description VARCHAR(200) NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER,
+	last_error TEXT,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_hook ON webhook_deliveries(webhook_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_images_moderation_pending;
+ALTER TABLE images DROP COLUMN IF EXISTS moderation_status;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS moderation_hold_uploads;
@@ -0,0 +1,5 @@
+-- Hold a new user's first uploads for review; held images stay out of feeds until approved.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS moderation_hold_uploads INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(16) NOT NULL DEFAULT 'approved';
+
+CREATE INDEX IF NOT EXISTS idx_images_moderation_pending ON images(created_at) WHERE moderation_status = 'pending';
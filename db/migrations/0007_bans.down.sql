@@ -0,0 +1,3 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS block_disposable_emails;
+DROP TABLE IF EXISTS ban_audit;
+DROP TABLE IF EXISTS bans;
@@ -0,0 +1,28 @@
+-- Admin-managed banlists checked at registration and login, with an audit trail of changes and hits.
+CREATE TABLE IF NOT EXISTS bans (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	kind VARCHAR(16) NOT NULL,
+	value TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (kind, value)
+);
+
+-- Audit rows outlive the bans they describe, so ban_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS ban_audit (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	ban_id UUID,
+	action VARCHAR(16) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	value TEXT NOT NULL,
+	actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	ip TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ban_audit_created ON ban_audit(created_at DESC);
+
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS block_disposable_emails BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- Shadowbanned users' uploads stay visible to themselves but are left out of public feeds.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_shadowbanned BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_users_shadowbanned ON users(id) WHERE is_shadowbanned;
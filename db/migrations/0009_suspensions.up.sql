@@ -0,0 +1,5 @@
+-- Suspensions: a disabled account may carry a reason and an expiry after which it is re-enabled.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_suspended_until ON users(suspended_until) WHERE is_disabled AND suspended_until IS NOT NULL;
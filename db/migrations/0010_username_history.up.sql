@@ -0,0 +1,11 @@
+-- Username changes, so old profile links keep resolving to the renamed account for a while.
+CREATE TABLE IF NOT EXISTS username_history (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	old_username VARCHAR(30) NOT NULL,
+	new_username VARCHAR(30) NOT NULL,
+	changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_old ON username_history(old_username, changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, changed_at DESC);
@@ -0,0 +1,2 @@
+-- Per-profile presentation: accent color, gallery layout and an optional header image.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_theme JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_images_user_hidden;
+ALTER TABLE images DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,4 @@
+-- Per-image visibility: unlisted images open by direct link but stay out of feeds; private ones are owner-only.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS visibility VARCHAR(16) NOT NULL DEFAULT 'public';
+
+CREATE INDEX IF NOT EXISTS idx_images_user_hidden ON images(user_id, created_at) WHERE visibility <> 'public';
@@ -0,0 +1,2 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS download_watermark_text;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS download_watermark_enabled;
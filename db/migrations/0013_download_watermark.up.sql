@@ -0,0 +1,3 @@
+-- Optional watermark stamped on original downloads served to anyone but the owner.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS download_watermark_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS download_watermark_text TEXT NOT NULL DEFAULT '';
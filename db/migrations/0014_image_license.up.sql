@@ -0,0 +1,2 @@
+-- Per-image license chosen by the uploader; empty means no license was stated.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS license VARCHAR(32) NOT NULL DEFAULT '';
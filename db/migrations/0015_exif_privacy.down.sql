@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS strip_exif;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS exif_privacy_mode;
@@ -0,0 +1,3 @@
+-- Strip location and device-identifying EXIF from re-encoded uploads, site-wide or per user.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS exif_privacy_mode BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS strip_exif BOOLEAN NOT NULL DEFAULT FALSE;
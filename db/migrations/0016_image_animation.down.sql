@@ -0,0 +1,2 @@
+ALTER TABLE images DROP COLUMN IF EXISTS duration_ms;
+ALTER TABLE images DROP COLUMN IF EXISTS frame_count;
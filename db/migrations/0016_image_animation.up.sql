@@ -0,0 +1,3 @@
+-- Animated uploads (GIF, APNG, animated WebP) are stored as-is; 0 frames means a still image.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS frame_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS duration_ms INTEGER NOT NULL DEFAULT 0;
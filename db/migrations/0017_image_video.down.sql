@@ -0,0 +1,2 @@
+ALTER TABLE images DROP COLUMN IF EXISTS poster_filename;
+ALTER TABLE images DROP COLUMN IF EXISTS media_type;
@@ -0,0 +1,3 @@
+-- Video clips live in images alongside stills; poster_filename is the extracted still frame.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS media_type VARCHAR(16) NOT NULL DEFAULT 'image';
+ALTER TABLE images ADD COLUMN IF NOT EXISTS poster_filename TEXT;
@@ -0,0 +1,23 @@
+-- Background job queue. Rows are claimed with SKIP LOCKED so any instance's workers can run them.
+-- unique_key keeps at most one live (pending or running) row per key; periodic jobs are one
+-- such row per kind that is re-armed after each run instead of finishing.
+CREATE TABLE IF NOT EXISTS jobs (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	kind VARCHAR(64) NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	unique_key VARCHAR(64),
+	periodic BOOLEAN NOT NULL DEFAULT FALSE,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 5,
+	last_error TEXT,
+	result JSONB,
+	run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_created ON jobs(created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_live ON jobs(unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
@@ -0,0 +1,30 @@
+-- Admin "login as user" sessions. A session is live until it ends or expires; its tokens are
+-- refused once it is not.
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	admin_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	reason TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_user ON impersonation_sessions(user_id, started_at DESC);
+
+-- Admin audit log. Rows outlive the users and sessions they describe, so target_user_id and
+-- session_id are not foreign keys.
+CREATE TABLE IF NOT EXISTS admin_audit (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	action VARCHAR(32) NOT NULL,
+	target_user_id UUID,
+	session_id UUID,
+	ip TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created ON admin_audit(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_target ON admin_audit(target_user_id, created_at DESC);
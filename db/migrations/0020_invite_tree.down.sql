@@ -0,0 +1,7 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS user_invite_min_account_days;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS user_invite_quota;
+DROP INDEX IF EXISTS idx_users_invited_by;
+ALTER TABLE users DROP COLUMN IF EXISTS invited_by;
+ALTER TABLE users DROP COLUMN IF EXISTS invite_id;
+DROP INDEX IF EXISTS idx_invites_created_by;
+ALTER TABLE invites DROP COLUMN IF EXISTS note;
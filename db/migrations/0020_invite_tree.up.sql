@@ -0,0 +1,13 @@
+-- Invite notes, personal invite quotas, and which invite created each account.
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_invites_created_by ON invites(created_by, created_at DESC);
+
+-- invite_id and invited_by are kept when the invite or the inviter is deleted, so they are
+-- not foreign keys; the invite tree is traced through invited_by.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_id UUID;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invited_by UUID;
+CREATE INDEX IF NOT EXISTS idx_users_invited_by ON users(invited_by) WHERE invited_by IS NOT NULL;
+
+-- Monthly invites for users who are not staff (0 disables) and the account age they need first.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS user_invite_quota INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS user_invite_min_account_days INTEGER NOT NULL DEFAULT 30;
@@ -0,0 +1 @@
+ALTER TABLE invites DROP COLUMN IF EXISTS email;
@@ -0,0 +1,2 @@
+-- Invites sent by email are bound to the address they were sent to; '' means unbound.
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT '';
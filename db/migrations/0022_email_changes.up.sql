@@ -0,0 +1,12 @@
+-- Pending self-serve email changes. The new address confirms with token; the old address
+-- gets cancel_token to stop a change it did not ask for. Both are stored hashed.
+CREATE TABLE IF NOT EXISTS email_changes (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	new_email VARCHAR(255) NOT NULL,
+	token VARCHAR(255) UNIQUE NOT NULL,
+	cancel_token VARCHAR(255) UNIQUE NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_email_changes_user ON email_changes(user_id);
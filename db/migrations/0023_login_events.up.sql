@@ -0,0 +1,16 @@
+-- Successful sign-ins, for the user's login history and new-device alerts. IPs are only
+-- stored as keyed hashes: ip_hash of the address, network_hash of its /24 (IPv4) or /48
+-- (IPv6) network. device is the browser and OS family parsed from the user agent.
+CREATE TABLE IF NOT EXISTS login_events (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	ip_hash VARCHAR(64) NOT NULL,
+	network_hash VARCHAR(64) NOT NULL,
+	country VARCHAR(2) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	device VARCHAR(64) NOT NULL DEFAULT '',
+	new_device BOOLEAN NOT NULL DEFAULT FALSE,
+	new_location BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS challenge_secret_key;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS challenge_site_key;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS challenge_provider;
@@ -0,0 +1,5 @@
+-- Challenge shown on register and forgot-password to addresses the progressive rate limiter
+-- has flagged: '' (off), 'pow' (built-in proof of work), 'hcaptcha' or 'turnstile'.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS challenge_provider VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS challenge_site_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS challenge_secret_key TEXT NOT NULL DEFAULT '';
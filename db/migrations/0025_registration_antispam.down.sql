@@ -0,0 +1,3 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS registration_min_fill_seconds;
+DROP INDEX IF EXISTS idx_registration_blocks_created;
+DROP TABLE IF EXISTS registration_blocks;
@@ -0,0 +1,12 @@
+-- Registrations refused by the antispam checks (honeypot, timing, disposable), kept for
+-- the admin dashboard. The IP is only stored as a keyed hash.
+CREATE TABLE IF NOT EXISTS registration_blocks (
+	id BIGSERIAL PRIMARY KEY,
+	reason VARCHAR(32) NOT NULL,
+	ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_registration_blocks_created ON registration_blocks(created_at);
+
+-- Seconds a registration form must be open before it is submitted (0 disables the check).
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS registration_min_fill_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+-- Security events from the progressive rate limiter (lockouts, auth failures, backoff),
+-- persisted so they survive restarts. Rows are pruned by age; low-severity events are
+-- kept for a shorter time than the rest.
+CREATE TABLE IF NOT EXISTS security_events (
+	id BIGSERIAL PRIMARY KEY,
+	event_type VARCHAR(64) NOT NULL,
+	severity VARCHAR(16) NOT NULL,
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	path TEXT NOT NULL DEFAULT '',
+	method VARCHAR(16) NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_security_events_created ON security_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_severity ON security_events(severity, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_ip ON security_events(ip_address, created_at DESC);
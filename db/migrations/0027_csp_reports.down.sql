@@ -0,0 +1,3 @@
+ALTER TABLE site_settings DROP COLUMN IF EXISTS csp_script_sources;
+DROP INDEX IF EXISTS idx_csp_reports_last_seen;
+DROP TABLE IF EXISTS csp_reports;
@@ -0,0 +1,20 @@
+-- Content-Security-Policy violation reports sent by browsers. Identical reports are folded
+-- into one row with a count; rows not seen for 30 days are pruned.
+CREATE TABLE IF NOT EXISTS csp_reports (
+	id BIGSERIAL PRIMARY KEY,
+	fingerprint VARCHAR(64) NOT NULL UNIQUE,
+	document_uri TEXT NOT NULL DEFAULT '',
+	violated_directive VARCHAR(128) NOT NULL DEFAULT '',
+	blocked_uri TEXT NOT NULL DEFAULT '',
+	source_file TEXT NOT NULL DEFAULT '',
+	line_number INTEGER NOT NULL DEFAULT 0,
+	sample TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	count BIGINT NOT NULL DEFAULT 1,
+	first_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+	last_seen TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_csp_reports_last_seen ON csp_reports(last_seen DESC);
+
+-- Extra script origins for the generated Content-Security-Policy, space-separated.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS csp_script_sources TEXT NOT NULL DEFAULT '';
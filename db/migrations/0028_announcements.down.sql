@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_announcements_window;
+DROP TABLE IF EXISTS announcements;
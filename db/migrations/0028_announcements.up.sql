@@ -0,0 +1,13 @@
+-- Site-wide banners shown by the SPA between starts_at and ends_at (open-ended when NULL).
+CREATE TABLE IF NOT EXISTS announcements (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	message TEXT NOT NULL,
+	level VARCHAR(16) NOT NULL DEFAULT 'info',
+	starts_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	ends_at TIMESTAMP,
+	dismissible BOOLEAN NOT NULL DEFAULT TRUE,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_announcements_window ON announcements(starts_at, ends_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS page_revisions;
+ALTER TABLE pages DROP COLUMN IF EXISTS updated_by;
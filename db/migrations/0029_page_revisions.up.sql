@@ -0,0 +1,23 @@
+-- Every CMS page save is kept as a numbered revision so edits can be reviewed and rolled back.
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS page_revisions (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	page_id UUID NOT NULL REFERENCES pages(id) ON DELETE CASCADE,
+	rev INTEGER NOT NULL,
+	slug VARCHAR(60) NOT NULL,
+	title VARCHAR(200) NOT NULL,
+	markdown TEXT NOT NULL DEFAULT '',
+	is_published BOOLEAN NOT NULL DEFAULT FALSE,
+	redirect_url TEXT,
+	meta_title VARCHAR(200),
+	meta_description VARCHAR(300),
+	author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (page_id, rev)
+);
+
+-- Existing pages start their history at revision 1
+INSERT INTO page_revisions (page_id, rev, slug, title, markdown, is_published, redirect_url, meta_title, meta_description, created_at)
+SELECT id, 1, slug, title, markdown, is_published, redirect_url, meta_title, meta_description, updated_at FROM pages
+ON CONFLICT (page_id, rev) DO NOTHING;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_navigation_items_menu;
+DROP TABLE IF EXISTS navigation_items;
+ALTER TABLE page_revisions DROP COLUMN IF EXISTS position;
+ALTER TABLE pages DROP COLUMN IF EXISTS position;
+DELETE FROM pages WHERE slug LIKE '%/%';
+ALTER TABLE pages DROP CONSTRAINT IF EXISTS pages_slug_check;
+ALTER TABLE pages ALTER COLUMN slug TYPE VARCHAR(60);
+ALTER TABLE pages ADD CONSTRAINT pages_slug_check CHECK (slug ~ '^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$');
@@ -0,0 +1,22 @@
+-- CMS pages may live under other pages (help/faq). A page's parent is the page at its path
+-- minus the last segment; position orders siblings.
+ALTER TABLE pages DROP CONSTRAINT IF EXISTS pages_slug_check;
+ALTER TABLE pages ALTER COLUMN slug TYPE VARCHAR(200);
+ALTER TABLE pages ADD CONSTRAINT pages_slug_check
+	CHECK (slug ~ '^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?(?:/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?){0,3}$');
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE page_revisions ALTER COLUMN slug TYPE VARCHAR(200);
+ALTER TABLE page_revisions ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+-- Admin-managed site menus. An item links to a page (hidden while the page is unpublished)
+-- or to a URL; items may have one level of children.
+CREATE TABLE IF NOT EXISTS navigation_items (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	menu VARCHAR(32) NOT NULL,
+	parent_id UUID REFERENCES navigation_items(id) ON DELETE CASCADE,
+	label VARCHAR(100) NOT NULL,
+	page_id UUID REFERENCES pages(id) ON DELETE CASCADE,
+	url TEXT,
+	position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_navigation_items_menu ON navigation_items(menu, position);
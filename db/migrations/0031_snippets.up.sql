@@ -0,0 +1,10 @@
+-- Small pieces of admin-edited copy (footer text, upload guidelines...) the SPA drops into
+-- fixed places. html is the sanitized render of content, refreshed on every save.
+CREATE TABLE IF NOT EXISTS snippets (
+	key VARCHAR(64) PRIMARY KEY CHECK (key ~ '^[a-z0-9][a-z0-9_-]{0,63}$'),
+	content TEXT NOT NULL DEFAULT '',
+	html TEXT NOT NULL DEFAULT '',
+	updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
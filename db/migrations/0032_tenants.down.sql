@@ -0,0 +1,7 @@
+DELETE FROM pages WHERE tenant_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_pages_tenant_slug;
+ALTER TABLE pages ADD CONSTRAINT pages_slug_key UNIQUE (slug);
+ALTER TABLE pages DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS idx_images_tenant_created;
+ALTER TABLE images DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,23 @@
+-- Multi-site mode: each tenant is another domain served by this instance. Users and
+-- accounts are shared; the feed and CMS pages are kept per tenant (NULL is the primary
+-- site) and the branding columns override the matching site settings on that domain.
+CREATE TABLE IF NOT EXISTS tenants (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	host VARCHAR(253) NOT NULL UNIQUE,
+	name VARCHAR(100) NOT NULL,
+	site_name VARCHAR(100) NOT NULL DEFAULT '',
+	site_url TEXT NOT NULL DEFAULT '',
+	seo_title VARCHAR(200) NOT NULL DEFAULT '',
+	seo_description VARCHAR(300) NOT NULL DEFAULT '',
+	social_image_url TEXT NOT NULL DEFAULT '',
+	favicon_path TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE images ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS idx_images_tenant_created ON images(tenant_id, created_at DESC) WHERE tenant_id IS NOT NULL;
+
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE pages DROP CONSTRAINT IF EXISTS pages_slug_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pages_tenant_slug ON pages ((COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid)), slug);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS locale_strings;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS default_locale;
@@ -0,0 +1,13 @@
+-- Language for server-generated text (emails, API errors) when the browser does not ask
+-- for a supported one.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS default_locale VARCHAR(16) NOT NULL DEFAULT 'en';
+
+-- Admin overrides of translated strings. msgid is the English text as written in the code.
+CREATE TABLE IF NOT EXISTS locale_strings (
+	locale VARCHAR(16) NOT NULL CHECK (locale ~ '^[a-z]{2,3}(-[a-z0-9]{2,8})?$'),
+	msgid TEXT NOT NULL,
+	text TEXT NOT NULL,
+	updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (locale, msgid)
+);
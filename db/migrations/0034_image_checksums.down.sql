@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_images_checksum_mismatch;
+ALTER TABLE images DROP COLUMN IF EXISTS checksum_mismatch_at;
+ALTER TABLE images DROP COLUMN IF EXISTS sha256;
@@ -0,0 +1,5 @@
+-- SHA-256 of the stored file as written on upload, so mirrors can validate downloads and
+-- the verification job can spot objects that changed or rotted in storage.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS sha256 VARCHAR(64);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS checksum_mismatch_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_images_checksum_mismatch ON images(checksum_mismatch_at) WHERE checksum_mismatch_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_touch_updated_at ON users;
+DROP TRIGGER IF EXISTS images_touch_updated_at ON images;
+DROP FUNCTION IF EXISTS touch_updated_at();
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE images DROP COLUMN IF EXISTS updated_at;
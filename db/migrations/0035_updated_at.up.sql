@@ -0,0 +1,24 @@
+-- Last change time of images and users, so their API responses can carry ETags and
+-- Last-Modified without being rebuilt. Writes to both tables are spread across many
+-- statements, so a trigger keeps the column current instead of each query.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+UPDATE images SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE images ALTER COLUMN updated_at SET DEFAULT NOW(), ALTER COLUMN updated_at SET NOT NULL;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+UPDATE users SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE users ALTER COLUMN updated_at SET DEFAULT NOW(), ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+	NEW.updated_at := NOW();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS images_touch_updated_at ON images;
+CREATE TRIGGER images_touch_updated_at BEFORE UPDATE ON images
+	FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION touch_updated_at();
+DROP TRIGGER IF EXISTS users_touch_updated_at ON users;
+CREATE TRIGGER users_touch_updated_at BEFORE UPDATE ON users
+	FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION touch_updated_at();
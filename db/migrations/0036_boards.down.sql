@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS board_items;
+DROP TABLE IF EXISTS boards;
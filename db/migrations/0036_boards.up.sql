@@ -0,0 +1,36 @@
+-- Named collections (boards). The collections table stays the set of images a user has
+-- collected, for counts and notifications; an image is in it exactly while it is on at
+-- least one of the user's boards. Each user's first board is their default, where the
+-- collect button puts images.
+CREATE TABLE IF NOT EXISTS boards (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(80) NOT NULL,
+	description VARCHAR(500) NOT NULL DEFAULT '',
+	is_public BOOLEAN NOT NULL DEFAULT TRUE,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_boards_user ON boards(user_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_boards_user_default ON boards(user_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS board_items (
+	board_id UUID NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+	image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (board_id, image_id)
+);
+CREATE INDEX IF NOT EXISTS idx_board_items_image ON board_items(image_id);
+
+-- Existing collections become each user's default board, newest first
+INSERT INTO boards (user_id, name, is_default)
+SELECT DISTINCT user_id, 'Collected', TRUE FROM collections WHERE user_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+INSERT INTO board_items (board_id, image_id, position, created_at)
+SELECT b.id, c.image_id, ROW_NUMBER() OVER (PARTITION BY c.user_id ORDER BY c.created_at DESC, c.image_id) - 1, COALESCE(c.created_at, NOW())
+FROM collections c JOIN boards b ON b.user_id = c.user_id AND b.is_default
+WHERE c.image_id IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_collections_image_created;
+DROP TRIGGER IF EXISTS collections_count ON collections;
+DROP FUNCTION IF EXISTS count_collections();
+ALTER TABLE images DROP COLUMN IF EXISTS collected_count;
@@ -0,0 +1,24 @@
+-- How many users have collected each image, kept beside the row so listings can show it
+-- without counting. Collections change from several places (the collect button, boards,
+-- account deletion), so a trigger maintains it.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS collected_count INTEGER NOT NULL DEFAULT 0;
+UPDATE images i SET collected_count = c.n
+FROM (SELECT image_id, COUNT(*) AS n FROM collections GROUP BY image_id) c
+WHERE c.image_id = i.id;
+
+CREATE OR REPLACE FUNCTION count_collections() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE images SET collected_count = collected_count + 1 WHERE id = NEW.image_id;
+	ELSE
+		UPDATE images SET collected_count = GREATEST(collected_count - 1, 0) WHERE id = OLD.image_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS collections_count ON collections;
+CREATE TRIGGER collections_count AFTER INSERT OR DELETE ON collections
+	FOR EACH ROW EXECUTE FUNCTION count_collections();
+
+CREATE INDEX IF NOT EXISTS idx_collections_image_created ON collections(image_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- Users a user has blocked. Blocked users' images leave the blocker's feeds, and they can
+-- no longer collect the blocker's images.
+CREATE TABLE IF NOT EXISTS blocks (
+	blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked ON blocks(blocked_id);
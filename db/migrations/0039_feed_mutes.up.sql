@@ -0,0 +1,2 @@
+-- AI providers each user keeps out of their feeds, e.g. {"providers": ["midjourney"]}.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS feed_mutes JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,3 @@
+-- How each user displays content ratings below explicit, e.g. {"suggestive": "blur"}.
+-- Explicit images keep following nsfw_pref.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS content_prefs JSONB NOT NULL DEFAULT '{}'::jsonb;
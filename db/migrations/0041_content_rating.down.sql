@@ -0,0 +1,4 @@
+ALTER TABLE images DROP COLUMN IF EXISTS is_nsfw;
+ALTER TABLE images ADD COLUMN is_nsfw BOOLEAN DEFAULT FALSE;
+UPDATE images SET is_nsfw = rating IN ('mature', 'explicit');
+ALTER TABLE images DROP COLUMN IF EXISTS rating;
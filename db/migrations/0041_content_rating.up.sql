@@ -0,0 +1,9 @@
+-- Content ratings replace the binary NSFW flag. Flagged images become explicit, and
+-- is_nsfw is regenerated from the rating so readers of the flag keep working; writes go
+-- to rating.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS rating VARCHAR(12) NOT NULL DEFAULT 'safe'
+	CHECK (rating IN ('safe', 'suggestive', 'mature', 'explicit'));
+UPDATE images SET rating = 'explicit' WHERE is_nsfw;
+
+ALTER TABLE images DROP COLUMN is_nsfw;
+ALTER TABLE images ADD COLUMN is_nsfw BOOLEAN GENERATED ALWAYS AS (rating IN ('mature', 'explicit')) STORED;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_images_featured;
+ALTER TABLE images DROP COLUMN IF EXISTS featured_note;
+ALTER TABLE images DROP COLUMN IF EXISTS featured_at;
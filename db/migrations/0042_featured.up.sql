@@ -0,0 +1,5 @@
+-- Staff picks shown on the front page. featured_at orders the picks, newest first, and
+-- featured_note is the curator's note shown with the image.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS featured_at TIMESTAMP;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS featured_note TEXT;
+CREATE INDEX IF NOT EXISTS idx_images_featured ON images(featured_at DESC) WHERE featured_at IS NOT NULL;
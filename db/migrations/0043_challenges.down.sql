@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS challenge_entries;
+DROP TABLE IF EXISTS challenges;
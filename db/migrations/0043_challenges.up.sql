@@ -0,0 +1,24 @@
+-- Themed prompts users enter their images into while now is within [starts_at, ends_at).
+-- Entries are ranked by how many users collected the image.
+CREATE TABLE IF NOT EXISTS challenges (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	title VARCHAR(120) NOT NULL,
+	prompt TEXT NOT NULL,
+	starts_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	ends_at TIMESTAMP NOT NULL,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS idx_challenges_window ON challenges(starts_at, ends_at);
+
+CREATE TABLE IF NOT EXISTS challenge_entries (
+	challenge_id UUID NOT NULL REFERENCES challenges(id) ON DELETE CASCADE,
+	image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (challenge_id, image_id)
+);
+CREATE INDEX IF NOT EXISTS idx_challenge_entries_user ON challenge_entries(challenge_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_challenge_entries_image ON challenge_entries(image_id);
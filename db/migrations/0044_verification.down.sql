@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS verification_proofs;
+ALTER TABLE users DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS verified_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS is_verified;
@@ -0,0 +1,15 @@
+-- Verified creators. Staff grant the badge with a reason shown beside it; creators can
+-- also earn it by proving they control a domain or a page linked from their profile.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_reason TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+
+-- One pending self-serve proof per user: the token must appear at the target.
+CREATE TABLE IF NOT EXISTS verification_proofs (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('domain', 'link')),
+    target TEXT NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    checked_at TIMESTAMP
+);
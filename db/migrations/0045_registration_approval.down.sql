@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_pending_approval;
+ALTER TABLE users DROP COLUMN IF EXISTS pending_approval;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS registration_approval_required;
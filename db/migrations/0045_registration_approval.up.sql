@@ -0,0 +1,5 @@
+-- Approval queue: with registration_approval_required set, new accounts start pending and
+-- cannot sign in or upload until staff approve them. Rejected registrations are deleted.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS registration_approval_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_approval BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_users_pending_approval ON users(created_at) WHERE pending_approval;
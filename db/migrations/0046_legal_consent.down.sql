@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS legal_consents;
+ALTER TABLE users DROP COLUMN IF EXISTS accepted_privacy_revision;
+ALTER TABLE users DROP COLUMN IF EXISTS accepted_terms_revision;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS privacy_revision;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS terms_revision;
@@ -0,0 +1,18 @@
+-- Terms of service and privacy policy versions are revisions of the "terms" and "privacy"
+-- pages. The site requires the revisions staff last published (0 means not tracked);
+-- users record the ones they accepted, and must accept again once those fall behind.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS terms_revision INT NOT NULL DEFAULT 0;
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS privacy_revision INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS accepted_terms_revision INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS accepted_privacy_revision INT NOT NULL DEFAULT 0;
+
+-- Every acceptance, so the record shows what someone agreed to and when.
+CREATE TABLE IF NOT EXISTS legal_consents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    terms_revision INT NOT NULL,
+    privacy_revision INT NOT NULL,
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_legal_consents_user ON legal_consents(user_id, created_at DESC);
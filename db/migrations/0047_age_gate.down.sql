@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS age_confirmed_at;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS adult_site;
+ALTER TABLE site_settings DROP COLUMN IF EXISTS minimum_age;
@@ -0,0 +1,5 @@
+-- Age gate: minimum_age (0 disables) makes registration ask for an age confirmation;
+-- adult_site also makes signed-out visitors confirm it before the feed and images load.
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS minimum_age INT NOT NULL DEFAULT 0;
+ALTER TABLE site_settings ADD COLUMN IF NOT EXISTS adult_site BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS age_confirmed_at TIMESTAMP;
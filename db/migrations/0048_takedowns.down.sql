@@ -0,0 +1,3 @@
+UPDATE images SET moderation_status = 'approved' WHERE moderation_status = 'disabled';
+DROP TABLE IF EXISTS takedown_events;
+DROP TABLE IF EXISTS takedown_requests;
@@ -0,0 +1,32 @@
+-- Copyright takedown requests filed through the public form. Staff can disable the image
+-- while they review ('disabled' hides it from everyone but its owner and staff), then
+-- remove it or reject the request, which restores the image.
+CREATE TABLE IF NOT EXISTS takedown_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    image_id UUID REFERENCES images(id) ON DELETE SET NULL,
+    image_url TEXT NOT NULL DEFAULT '',
+    claimant_name VARCHAR(200) NOT NULL,
+    claimant_email VARCHAR(320) NOT NULL,
+    organization VARCHAR(200) NOT NULL DEFAULT '',
+    original_work TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    signature VARCHAR(200) NOT NULL,
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_takedown_requests_status ON takedown_requests(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_takedown_requests_image ON takedown_requests(image_id) WHERE image_id IS NOT NULL;
+
+-- The resolution log: every step taken on a request, who took it and why.
+CREATE TABLE IF NOT EXISTS takedown_events (
+    id BIGSERIAL PRIMARY KEY,
+    request_id UUID NOT NULL REFERENCES takedown_requests(id) ON DELETE CASCADE,
+    action VARCHAR(16) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_takedown_events_request ON takedown_events(request_id, created_at);
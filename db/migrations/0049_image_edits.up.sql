@@ -0,0 +1,13 @@
+-- Changes made to an image after upload: which field, the old and new values, and who
+-- made the change. A replaced file keeps its previous version in storage under
+-- versions/<image id>/, and old_value holds that version's key.
+CREATE TABLE IF NOT EXISTS image_edits (
+    id BIGSERIAL PRIMARY KEY,
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    editor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    field VARCHAR(32) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_image_edits_image ON image_edits(image_id, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE image_edits DROP COLUMN IF EXISTS by_moderator;
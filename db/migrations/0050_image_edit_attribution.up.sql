@@ -0,0 +1,3 @@
+-- Marks edits made by staff to someone else's image, so a moderator's change to a title,
+-- caption or rating can be told apart from the owner's own.
+ALTER TABLE image_edits ADD COLUMN IF NOT EXISTS by_moderator BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_images_remix_of;
+ALTER TABLE images DROP COLUMN IF EXISTS remix_of_url;
+ALTER TABLE images DROP COLUMN IF EXISTS remix_of;
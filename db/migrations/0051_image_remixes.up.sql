@@ -0,0 +1,5 @@
+-- An image can declare the work it was remixed from: another image on the site, or an
+-- external URL when the source lives elsewhere. Deleting the source keeps the remix.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS remix_of UUID REFERENCES images(id) ON DELETE SET NULL;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS remix_of_url TEXT;
+CREATE INDEX IF NOT EXISTS idx_images_remix_of ON images(remix_of, created_at DESC) WHERE remix_of IS NOT NULL;
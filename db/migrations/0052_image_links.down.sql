@@ -0,0 +1 @@
+ALTER TABLE images DROP COLUMN IF EXISTS links;
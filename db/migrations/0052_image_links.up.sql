@@ -0,0 +1,3 @@
+-- Up to three external links per image, such as a generator's share page or a workflow
+-- file, stored as a JSON array of {label, url}.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS links JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,3 @@
+-- Storage key of the ComfyUI workflow JSON pulled out of an upload's PNG text chunks,
+-- kept as a sidecar under workflows/ so it survives re-encoding.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS workflow_key TEXT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_images_ai_model;
+ALTER TABLE images DROP COLUMN IF EXISTS ai_model;
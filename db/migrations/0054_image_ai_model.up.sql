@@ -0,0 +1,4 @@
+-- The checkpoint or diffusion model named in an upload's generation parameters, such as
+-- "flux1-dev", so images can be browsed by model. Matching is case-insensitive.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS ai_model VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_images_ai_model ON images (lower(ai_model), created_at DESC) WHERE ai_model IS NOT NULL;
@@ -0,0 +1,8 @@
+DELETE FROM legal_consents WHERE tenant_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_legal_consents_tenant;
+ALTER TABLE legal_consents DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE tenants DROP COLUMN IF EXISTS privacy_revision;
+ALTER TABLE tenants DROP COLUMN IF EXISTS terms_revision;
+ALTER TABLE tenants DROP COLUMN IF EXISTS default_locale;
+ALTER TABLE tenants DROP COLUMN IF EXISTS minimum_age;
+ALTER TABLE tenants DROP COLUMN IF EXISTS adult_site;
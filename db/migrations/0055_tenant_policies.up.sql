@@ -0,0 +1,13 @@
+-- Per-site policy on tenant domains. NULL inherits the primary site's setting. The terms
+-- and privacy revisions are of the tenant's own pages and are published per tenant
+-- (0 does not track one).
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS adult_site BOOLEAN;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS minimum_age INT;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS default_locale VARCHAR(16);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS terms_revision INT NOT NULL DEFAULT 0;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS privacy_revision INT NOT NULL DEFAULT 0;
+
+-- Acceptances on a tenant domain are of that tenant's documents (NULL is the primary site,
+-- whose current acceptance is also kept on users).
+ALTER TABLE legal_consents ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_legal_consents_tenant ON legal_consents(user_id, tenant_id, created_at DESC) WHERE tenant_id IS NOT NULL;
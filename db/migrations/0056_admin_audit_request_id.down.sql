@@ -0,0 +1 @@
+ALTER TABLE admin_audit DROP COLUMN IF EXISTS request_id;
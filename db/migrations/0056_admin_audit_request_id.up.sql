@@ -0,0 +1,3 @@
+-- The request ID of the request that wrote each entry, so an audit row can be matched to
+-- the access and application logs for the same request.
+ALTER TABLE admin_audit ADD COLUMN IF NOT EXISTS request_id VARCHAR(64) NOT NULL DEFAULT '';
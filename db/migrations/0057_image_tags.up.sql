@@ -0,0 +1,4 @@
+-- Up to ten short lowercase tags per image, stored as a JSON array of strings. The GIN
+-- index serves containment lookups such as tags @> '["koi"]'.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'::jsonb;
+CREATE INDEX IF NOT EXISTS idx_images_tags ON images USING GIN (tags);
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// QueryTracer, when set before Connect, is called for every query the pool runs and
+// returns a function that receives the query's error. It lets the tracing layer record
+// database spans without this package depending on it.
+var QueryTracer func(ctx context.Context, query string) func(error)
+
+const tracedDriverName = "postgres+traced"
+
+var registerTracedDriver sync.Once
+
+// driverName returns the driver Connect should open: plain lib/pq, or lib/pq wrapped so
+// QueryTracer sees each statement.
+func driverName() string {
+	if QueryTracer == nil {
+		return "postgres"
+	}
+	registerTracedDriver.Do(func() {
+		sql.Register(tracedDriverName, tracedDriver{&pq.Driver{}})
+		// sqlx picks the placeholder style from the driver name
+		sqlx.BindDriver(tracedDriverName, sqlx.DOLLAR)
+	})
+	return tracedDriverName
+}
+
+func traceQuery(ctx context.Context, query string) func(error) {
+	if t := QueryTracer; t != nil {
+		return t(ctx, query)
+	}
+	return func(error) {}
+}
+
+type tracedDriver struct{ driver.Driver }
+
+func (d tracedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{c}, nil
+}
+
+// tracedConn forwards to lib/pq's connection, which implements every optional
+// context-aware interface, and wraps statement execution in QueryTracer.
+type tracedConn struct{ driver.Conn }
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := traceQuery(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args)
+	done(err)
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := traceQuery(ctx, query)
+	res, err := e.ExecContext(ctx, query, args)
+	done(err)
+	return res, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
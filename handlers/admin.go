@@ -1,12 +1,13 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -41,6 +42,24 @@ type AdminHandler struct {
 	pageRepo            models.PageRepositoryInterface
 	rateLimiter         *services.RateLimiter
 	progressiveRateLimiter *services.ProgressiveRateLimiter
+	mailOutbox          models.MailOutboxRepositoryInterface
+	webhooks            models.WebhookRepositoryInterface
+	stats               models.StatsRepositoryInterface
+	bans                models.BanRepositoryInterface
+	jobs                models.JobRepositoryInterface
+	audit               models.AuditRepositoryInterface
+	securityEvents      models.SecurityEventRepositoryInterface
+	loginEvents         models.LoginEventRepositoryInterface
+	loadPolicies        func() ([]services.RateLimitPolicy, error)
+	csrf                *middleware.CSRFProtection
+	cspReports          models.CSPReportRepositoryInterface
+	announcements       models.AnnouncementRepositoryInterface
+	navigation          models.NavigationRepositoryInterface
+	snippets            models.SnippetRepositoryInterface
+	tenants             models.TenantRepositoryInterface
+	localeStrings       models.LocaleStringRepositoryInterface
+	legal               models.LegalRepositoryInterface
+	openAPI             *openAPIDoc
 }
 
 func NewAdminHandler(settingsRepo models.SiteSettingsRepositoryInterface, userRepo models.UserRepositoryInterface, imageRepo models.ImageRepositoryInterface) *AdminHandler {
@@ -77,6 +96,24 @@ func (h *AdminHandler) WithRateLimiter(rl *services.RateLimiter) *AdminHandler {
 	return h
 }
 
+// WithMailOutbox injects the persistent mail outbox
+func (h *AdminHandler) WithMailOutbox(r models.MailOutboxRepositoryInterface) *AdminHandler {
+	h.mailOutbox = r
+	return h
+}
+
+// WithWebhooks injects the outgoing webhook repository
+func (h *AdminHandler) WithWebhooks(r models.WebhookRepositoryInterface) *AdminHandler {
+	h.webhooks = r
+	return h
+}
+
+// WithStats injects the dashboard statistics repository
+func (h *AdminHandler) WithStats(r models.StatsRepositoryInterface) *AdminHandler {
+	h.stats = r
+	return h
+}
+
 // WithProgressiveRateLimiter injects the progressive rate limiter
 func (h *AdminHandler) WithProgressiveRateLimiter(prl *services.ProgressiveRateLimiter) *AdminHandler {
 	h.progressiveRateLimiter = prl
@@ -86,17 +123,30 @@ func (h *AdminHandler) WithProgressiveRateLimiter(prl *services.ProgressiveRateL
 // Public site settings
 func (h *AdminHandler) GetPublicSite(c *fiber.Ctx) error {
 	set, _ := h.settingsRepo.Get()
+	modified, tenantID := set.UpdatedAt, ""
+	if t := middleware.GetTenant(c); t != nil {
+		modified, tenantID = latestTime(modified, t.UpdatedAt), t.ID.String()
+	}
+	if conditionalGet(c, modified, "site", tenantID, set.UpdatedAt.UnixNano(), modified.UnixNano()) {
+		return notModified(c)
+	}
+	set = tenantSettings(c, set)
 	emailEnabled := set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != ""
 	return c.JSON(fiber.Map{
-		"site_name":                   set.SiteName,
-		"site_url":                    set.SiteURL,
-		"seo_title":                   set.SEOTitle,
-		"seo_description":             set.SEODescription,
-		"social_image_url":            set.SocialImageURL,
-		"favicon_path":                set.FaviconPath,
-		"email_enabled":               emailEnabled,
-		"require_email_verification":  set.RequireEmailVerification,
-		"public_registration_enabled": set.PublicRegistrationEnabled,
+		"site_name":                      set.SiteName,
+		"site_url":                       set.SiteURL,
+		"seo_title":                      set.SEOTitle,
+		"seo_description":                set.SEODescription,
+		"social_image_url":               set.SocialImageURL,
+		"favicon_path":                   set.FaviconPath,
+		"email_enabled":                  emailEnabled,
+		"require_email_verification":     set.RequireEmailVerification,
+		"public_registration_enabled":    set.PublicRegistrationEnabled,
+		"registration_approval_required": set.RegistrationApprovalRequired,
+		"terms_revision":                 set.TermsRevision,
+		"privacy_revision":               set.PrivacyRevision,
+		"minimum_age":                    set.MinimumAge,
+		"adult_site":                     set.AdultSite,
 	})
 }
 
@@ -113,46 +163,19 @@ func (h *AdminHandler) CreateInvite(c *fiber.Ctx) error {
 		MaxUses   *int    `json:"max_uses"`
 		Duration  *string `json:"duration"`   // e.g., "24h", "7d", "3h"
 		ExpiresAt *string `json:"expires_at"` // ISO8601 optional alternative
+		Note      string  `json:"note"`
 	}
 	var b body
 	if err := c.BodyParser(&b); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
 	}
-	var expires *time.Time
-	// Prefer explicit expires_at if provided
-	if b.ExpiresAt != nil && strings.TrimSpace(*b.ExpiresAt) != "" {
-		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(*b.ExpiresAt)); err == nil {
-			expires = &t
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid expires_at (use RFC3339)"})
-		}
-	} else if b.Duration != nil && strings.TrimSpace(*b.Duration) != "" {
-		dstr := strings.ToLower(strings.TrimSpace(*b.Duration))
-		// support Nx where x in h,m,s and also days via 'd'
-		var d time.Duration
-		var err error
-		if strings.HasSuffix(dstr, "d") {
-			num := strings.TrimSuffix(dstr, "d")
-			// parse as integer days
-			if num == "" {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid duration"})
-			}
-			// simple parse
-			var days int
-			_, err = fmt.Sscanf(num, "%d", &days)
-			if err == nil && days > 0 {
-				d = time.Hour * 24 * time.Duration(days)
-			} else {
-				err = fmt.Errorf("invalid days")
-			}
-		} else {
-			d, err = time.ParseDuration(dstr)
-		}
-		if err != nil || d <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid duration"})
-		}
-		t := time.Now().Add(d)
-		expires = &t
+	note := strings.TrimSpace(b.Note)
+	if len([]rune(note)) > inviteNoteMaxLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note too long"})
+	}
+	expires, errMsg := parseInviteExpiry(b.ExpiresAt, b.Duration)
+	if errMsg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
 	}
 	// Sanitize max uses: nil => unlimited; 0 or negative => treat as 1
 	if b.MaxUses != nil && *b.MaxUses <= 0 {
@@ -165,20 +188,12 @@ func (h *AdminHandler) CreateInvite(c *fiber.Ctx) error {
 	if uid != uuid.Nil {
 		creator = &uid
 	}
-	inv, err := h.inviteRepo.Create(b.MaxUses, expires, creator)
-	if err != nil {
+	inv := &models.Invite{MaxUses: b.MaxUses, ExpiresAt: expires, CreatedBy: creator, Note: note}
+	if err := h.inviteRepo.Insert(inv); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invite"})
 	}
-	// Build link based on configured site URL if available
 	set, _ := h.settingsRepo.Get()
-	base := strings.TrimRight(strings.TrimSpace(set.SiteURL), "/")
-	var link string
-	if base != "" {
-		link = base + "/register?invite=" + inv.Code
-	} else {
-		link = "/register?invite=" + inv.Code
-	}
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"invite": inv, "link": link})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"invite": inv, "link": inviteLink(set, inv.Code)})
 }
 
 // ListInvites returns paginated invites for admins
@@ -189,6 +204,12 @@ func (h *AdminHandler) ListInvites(c *fiber.Ctx) error {
 	if h.inviteRepo == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invite repository not configured"})
 	}
+	if wantsCSV(c) {
+		return sendCSV(c, "invites", inviteCSVHeader, func(page, limit int) ([][]string, error) {
+			list, _, err := h.inviteRepo.List(page, limit)
+			return inviteCSVRows(list), err
+		})
+	}
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	if page < 1 {
 		page = 1
@@ -225,6 +246,348 @@ func (h *AdminHandler) DeleteInvite(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// PreviewEmail renders an email template with sample data so admins can check overrides
+// from the templates dir. ?format=text returns the plain-text part; ?format=json both.
+func (h *AdminHandler) PreviewEmail(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	base := strings.TrimRight(set.SiteURL, "/")
+	var msg services.EmailMessage
+	switch c.Params("name") {
+	case "verification":
+		msg = services.BuildVerificationMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/verify?token=preview-token")
+	case "password_reset":
+		msg = services.BuildPasswordResetMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/reset?token=preview-token")
+	case "email_change":
+		msg = services.BuildEmailChangeMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/confirm-email?token=preview-token", "new@example.com")
+	case "email_change_notice":
+		msg = services.BuildEmailChangeNoticeMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/cancel-email-change?token=preview-token", "new@example.com")
+	case "login_alert":
+		msg = services.BuildLoginAlertMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/settings", []string{"Time: " + time.Now().UTC().Format("2006-01-02 15:04 UTC"), "Device: Firefox on Linux"})
+	case "registration_approved":
+		msg = services.BuildRegistrationApprovedMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/")
+	case "registration_rejected":
+		msg = services.BuildRegistrationRejectedMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, "We could not confirm who you are.")
+	case "invite":
+		exp := time.Now().Add(sentInviteTTL)
+		msg = services.BuildInviteMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, base+"/register?invite=preview-code&email=preview%40example.com", &exp)
+	default:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown template", "templates": services.EmailTemplateNames})
+	}
+	switch c.Query("format", "html") {
+	case "text":
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(msg.Text)
+	case "json":
+		return c.JSON(fiber.Map{"subject": msg.Subject, "text": msg.Text, "html": msg.HTML})
+	}
+	if msg.HTML == "" {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Template failed to render"})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(msg.HTML)
+}
+
+// ListMailOutbox returns queued, failed and dead-lettered emails; ?status= filters.
+func (h *AdminHandler) ListMailOutbox(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.mailOutbox == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Mail outbox not configured"})
+	}
+	status := strings.ToLower(strings.TrimSpace(c.Query("status", "")))
+	switch status {
+	case "", models.MailStatusPending, models.MailStatusSending, models.MailStatusSent, models.MailStatusDead:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid status"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.mailOutbox.List(status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list mail", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"mail": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// RetryMail requeues a dead-lettered email for immediate delivery.
+func (h *AdminHandler) RetryMail(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.mailOutbox == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Mail outbox not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.mailOutbox.Retry(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteMail removes an email from the outbox.
+func (h *AdminHandler) DeleteMail(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.mailOutbox == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Mail outbox not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.mailOutbox.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type webhookRequest struct {
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	Description *string   `json:"description"`
+	Enabled     *bool     `json:"enabled"`
+	// RotateSecret issues a new signing secret on update
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+// apply validates req and copies it onto w.
+func (req webhookRequest) apply(w *models.Webhook) error {
+	if req.URL != nil {
+		u := strings.TrimSpace(*req.URL)
+		if err := services.ValidateWebhookURL(u); err != nil {
+			return err
+		}
+		w.URL = u
+	}
+	if req.Events != nil {
+		events := []string{}
+		for _, e := range *req.Events {
+			e = strings.TrimSpace(e)
+			if e == "" {
+				continue
+			}
+			valid := e == "*"
+			for _, known := range services.WebhookEvents {
+				valid = valid || e == known || (strings.HasSuffix(e, ".*") && strings.HasPrefix(known, strings.TrimSuffix(e, "*")))
+			}
+			if !valid {
+				return fmt.Errorf("unknown event %q", e)
+			}
+			events = append(events, e)
+		}
+		if len(events) == 0 {
+			return errors.New("at least one event is required")
+		}
+		w.Events = strings.Join(events, ",")
+	}
+	if req.Description != nil {
+		d := strings.TrimSpace(*req.Description)
+		if len(d) > 200 {
+			return errors.New("description too long (max 200 characters)")
+		}
+		w.Description = d
+	}
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// ListWebhooks returns configured webhooks and the events they can subscribe to.
+func (h *AdminHandler) ListWebhooks(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	list, err := h.webhooks.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list webhooks", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"webhooks": list, "events": services.WebhookEvents})
+}
+
+// CreateWebhook adds a webhook. The signing secret is returned only in this response.
+func (h *AdminHandler) CreateWebhook(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.URL == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "URL is required"})
+	}
+	w := &models.Webhook{Events: "*", Enabled: true}
+	if err := req.apply(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	secret, err := services.NewWebhookSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create secret"})
+	}
+	w.Secret = secret
+	if err := h.webhooks.Create(w); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create webhook", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"webhook": w, "secret": secret})
+}
+
+// UpdateWebhook edits a webhook; rotate_secret returns a fresh secret.
+func (h *AdminHandler) UpdateWebhook(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	w, err := h.webhooks.Get(id)
+	if err != nil || w == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Webhook not found"})
+	}
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.apply(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	resp := fiber.Map{"webhook": w}
+	if req.RotateSecret {
+		secret, err := services.NewWebhookSecret()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create secret"})
+		}
+		w.Secret = secret
+		resp["secret"] = secret
+	}
+	if err := h.webhooks.Update(w); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update webhook", "details": err.Error()})
+	}
+	return c.JSON(resp)
+}
+
+func (h *AdminHandler) DeleteWebhook(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.webhooks.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PingWebhook queues a "ping" delivery to check an endpoint and its signature handling.
+func (h *AdminHandler) PingWebhook(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	w, err := h.webhooks.Get(id)
+	if err != nil || w == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Webhook not found"})
+	}
+	if err := services.PingWebhook(h.webhooks, w); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to queue ping", "details": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// ListWebhookDeliveries returns the delivery log; ?webhook_id= and ?status= filter it.
+func (h *AdminHandler) ListWebhookDeliveries(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	var hookID *uuid.UUID
+	if raw := strings.TrimSpace(c.Query("webhook_id", "")); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook_id"})
+		}
+		hookID = &id
+	}
+	status := strings.ToLower(strings.TrimSpace(c.Query("status", "")))
+	switch status {
+	case "", models.WebhookStatusPending, models.WebhookStatusSending, models.WebhookStatusDelivered, models.WebhookStatusDead:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid status"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.webhooks.ListDeliveries(hookID, status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list deliveries", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"deliveries": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// RetryWebhookDelivery requeues a dead-lettered delivery.
+func (h *AdminHandler) RetryWebhookDelivery(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.webhooks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Webhooks not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.webhooks.RetryDelivery(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // PruneInvites deletes all fully-used or expired invite codes. Unlimited/time-unlimited active codes are kept.
 func (h *AdminHandler) PruneInvites(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
@@ -293,6 +656,9 @@ func (h *AdminHandler) GetSiteSettings(c *fiber.Ctx) error {
 	if redacted.S3SecretKey != "" {
 		redacted.S3SecretKey = "***"
 	}
+	if redacted.ChallengeSecretKey != "" {
+		redacted.ChallengeSecretKey = "***"
+	}
 	return c.JSON(redacted)
 }
 
@@ -312,6 +678,51 @@ func (h *AdminHandler) UpdateSiteSettings(c *fiber.Ctx) error {
 	body.SEOTitle = strings.TrimSpace(body.SEOTitle)
 	body.SEODescription = strings.TrimSpace(body.SEODescription)
 	body.SocialImageURL = strings.TrimSpace(body.SocialImageURL)
+	body.BackupRemoteBucket = strings.TrimSpace(body.BackupRemoteBucket)
+	if body.ModerationHoldUploads < 0 || body.ModerationHoldUploads > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "moderation_hold_uploads must be between 0 and 1000"})
+	}
+	if body.UserInviteQuota < 0 || body.UserInviteQuota > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_invite_quota must be between 0 and 100"})
+	}
+	if body.UserInviteMinAccountDays < 0 || body.UserInviteMinAccountDays > 3650 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_invite_min_account_days must be between 0 and 3650"})
+	}
+	if body.RegistrationMinFillSeconds < 0 || body.RegistrationMinFillSeconds > 60 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "registration_min_fill_seconds must be between 0 and 60"})
+	}
+	body.DownloadWatermarkText = strings.TrimSpace(body.DownloadWatermarkText)
+	if len([]rune(body.DownloadWatermarkText)) > 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "download_watermark_text must be at most 64 characters"})
+	}
+	body.ChallengeProvider = strings.ToLower(strings.TrimSpace(body.ChallengeProvider))
+	body.ChallengeSiteKey = strings.TrimSpace(body.ChallengeSiteKey)
+	body.ChallengeSecretKey = strings.TrimSpace(body.ChallengeSecretKey)
+	if !services.ValidChallengeProvider(body.ChallengeProvider) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "challenge_provider must be one of pow, hcaptcha, turnstile or empty"})
+	}
+
+	sources, err := services.NormalizeCSPSources(body.CSPScriptSources)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "csp_script_sources: " + err.Error()})
+	}
+	body.CSPScriptSources = sources
+
+	if strings.TrimSpace(body.DefaultLocale) == "" {
+		body.DefaultLocale = services.BaseLocale
+	}
+	locale, err := services.NormalizeLocale(body.DefaultLocale)
+	if err != nil || !services.LocaleSupported(locale) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "default_locale must be one of " + strings.Join(services.Locales(), ", ")})
+	}
+	body.DefaultLocale = locale
+
+	if body.MinimumAge < 0 || body.MinimumAge > 99 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "minimum_age must be between 0 and 99"})
+	}
+	if body.AdultSite && body.MinimumAge < services.AdultSiteMinimumAge {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "An adult site needs a minimum age of at least 18"})
+	}
 
 	// Validate analytics config conservatively
 	provider := strings.ToLower(strings.TrimSpace(body.AnalyticsProvider))
@@ -359,11 +770,23 @@ func (h *AdminHandler) UpdateSiteSettings(c *fiber.Ctx) error {
 		if body.SMTPPassword == "" || body.SMTPPassword == "***" {
 			body.SMTPPassword = existing.SMTPPassword
 		}
+		if body.ChallengeSecretKey == "" || body.ChallengeSecretKey == "***" {
+			body.ChallengeSecretKey = existing.ChallengeSecretKey
+		}
+		// Managed via AdminSetBackupPassphrase only
+		body.BackupPassphraseMarker = existing.BackupPassphraseMarker
+		// Managed via AdminPublishLegal only
+		body.TermsRevision, body.PrivacyRevision = existing.TermsRevision, existing.PrivacyRevision
+	}
+	if (body.ChallengeProvider == services.ChallengeHCaptcha || body.ChallengeProvider == services.ChallengeTurnstile) &&
+		(body.ChallengeSiteKey == "" || body.ChallengeSecretKey == "" || body.ChallengeSecretKey == "***") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A site key and secret key are required for " + body.ChallengeProvider})
 	}
 	body.UpdatedAt = time.Now()
-	log.Printf("Admin: updating site settings: provider=%s, s3_endpoint=%s, bucket=%s, public_base=%s, smtp_host=%s, smtp_port=%d, tls=%v, analytics=%v/%s",
-		strings.TrimSpace(body.StorageProvider), strings.TrimSpace(body.S3Endpoint), strings.TrimSpace(body.S3Bucket), strings.TrimSpace(body.PublicBaseURL), strings.TrimSpace(body.SMTPHost), body.SMTPPort, body.SMTPTLS,
-		body.AnalyticsEnabled, body.AnalyticsProvider)
+	services.Logger(c.Context()).Info("admin: updating site settings", "provider", strings.TrimSpace(body.StorageProvider),
+		"s3_endpoint", strings.TrimSpace(body.S3Endpoint), "bucket", strings.TrimSpace(body.S3Bucket), "public_base", strings.TrimSpace(body.PublicBaseURL),
+		"smtp_host", strings.TrimSpace(body.SMTPHost), "smtp_port", body.SMTPPort, "tls", body.SMTPTLS,
+		"analytics", body.AnalyticsEnabled, "analytics_provider", body.AnalyticsProvider)
 	if err := h.settingsRepo.Upsert(&body); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save settings"})
 	}
@@ -374,7 +797,7 @@ func (h *AdminHandler) UpdateSiteSettings(c *fiber.Ctx) error {
 		h.storage = st
 		services.SetCurrentStorage(st)
 	} else {
-		log.Printf("Admin: storage rebuild failed: %v", err)
+		services.Logger(c.Context()).Error("admin: storage rebuild failed", "error", err)
 	}
 	// Return redacted
 	saved := body
@@ -387,7 +810,10 @@ func (h *AdminHandler) UpdateSiteSettings(c *fiber.Ctx) error {
 	if saved.S3SecretKey != "" {
 		saved.S3SecretKey = "***"
 	}
-	log.Printf("Admin: settings updated successfully: provider=%s", strings.TrimSpace(saved.StorageProvider))
+	if saved.ChallengeSecretKey != "" {
+		saved.ChallengeSecretKey = "***"
+	}
+	services.Logger(c.Context()).Info("admin: settings updated", "provider", strings.TrimSpace(saved.StorageProvider))
 	return c.JSON(saved)
 }
 
@@ -440,14 +866,14 @@ func (h *AdminHandler) UploadFavicon(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset file pointer"})
 	}
 	
-	if err := os.MkdirAll(filepath.Join("uploads", "site"), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Join(services.UploadsDir(), "site"), 0755); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to prepare upload directory"})
 	}
 	ext := filepath.Ext(file.Filename)
 	if ext == "" {
 		ext = ".ico"
 	}
-	path := filepath.Join("uploads", "site", "favicon"+ext)
+	path := filepath.Join(services.UploadsDir(), "site", "favicon"+ext)
 	if err := c.SaveFile(file, path); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save favicon"})
 	}
@@ -512,14 +938,14 @@ func (h *AdminHandler) UploadSocialImage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset file pointer"})
 	}
 	
-	if err := os.MkdirAll(filepath.Join("uploads", "site"), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Join(services.UploadsDir(), "site"), 0755); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to prepare upload directory"})
 	}
 	ext := filepath.Ext(file.Filename)
 	if ext == "" {
 		ext = ".png"
 	}
-	path := filepath.Join("uploads", "site", "social-image"+ext)
+	path := filepath.Join(services.UploadsDir(), "site", "social-image"+ext)
 	if err := c.SaveFile(file, path); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save image"})
 	}
@@ -538,7 +964,9 @@ func (h *AdminHandler) UploadSocialImage(c *fiber.Ctx) error {
 }
 
 // ExportLocalUploadsToStorage migrates files from local storage to remote storage and updates database URLs.
-// This is a comprehensive migration that uploads files, updates database records, and optionally cleans up local files.
+// It is a storage migration from local to the current storage, run as a background job
+// (202 with the job to poll); only one migration runs at a time.
+// Body: {"cleanup_local": bool, "concurrency": n} removes local copies once uploaded.
 func (h *AdminHandler) ExportLocalUploadsToStorage(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
@@ -546,122 +974,63 @@ func (h *AdminHandler) ExportLocalUploadsToStorage(c *fiber.Ctx) error {
 	if h.storage == nil || h.storage.IsLocal() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Remote storage not configured"})
 	}
-
-	type MigrationRequest struct {
+	var req struct {
 		CleanupLocal bool `json:"cleanup_local"`
+		Concurrency  int  `json:"concurrency"`
 	}
-	var req MigrationRequest
 	c.BodyParser(&req) // Optional body
+	return h.queueStorageMigration(c, services.StorageMigration{
+		Source:       services.StorageBackend{Provider: services.StorageBackendLocal},
+		Target:       services.StorageBackend{Provider: services.StorageBackendCurrent},
+		DeleteSource: req.CleanupLocal,
+		Concurrency:  req.Concurrency,
+	})
+}
 
-	// The imageRepo is now available as h.imageRepo
-
-	// For now, let's migrate files and provide comprehensive feedback
-	type MigrationResult struct {
-		TotalFiles     int      `json:"total_files"`
-		UploadedFiles  int      `json:"uploaded_files"`
-		UpdatedRecords int      `json:"updated_records"`
-		CleanedFiles   int      `json:"cleaned_files,omitempty"`
-		Errors         []string `json:"errors,omitempty"`
-		Success        bool     `json:"success"`
+// MigrateStorage queues a copy of every stored file between two storage backends, with
+// database references rewritten to the target. Body: a services.StorageMigration.
+func (h *AdminHandler) MigrateStorage(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
-
-	result := MigrationResult{
-		Success: true,
-		Errors:  []string{},
+	var req services.StorageMigration
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
+	return h.queueStorageMigration(c, req)
+}
 
-	// Walk uploads dir and collect files
-	root := "uploads"
-	var filesToMigrate []string
-
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-		// Skip subdirectories we don't want to migrate (like avatars/site)
-		rel, _ := filepath.Rel(root, path)
-		if strings.Contains(rel, string(filepath.Separator)) {
-			return nil // Skip files in subdirectories
-		}
-		filesToMigrate = append(filesToMigrate, rel)
-		return nil
-	})
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to scan local files", "details": err.Error()})
+// queueStorageMigration validates and enqueues a migration. Migrations can run for a long
+// time, so they only run as jobs; progress is at the migration status endpoint.
+func (h *AdminHandler) queueStorageMigration(c *fiber.Ctx, m services.StorageMigration) error {
+	if err := m.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	result.TotalFiles = len(filesToMigrate)
-
-	// Upload each file to remote storage
-	var uploadedFiles []string
-	for _, filename := range filesToMigrate {
-		localPath := filepath.Join(root, filename)
-		b, err := os.ReadFile(localPath)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Failed to read %s: %v", filename, err))
-			continue
-		}
-
-		// Determine content type
-		ct := "application/octet-stream"
-		switch strings.ToLower(filepath.Ext(filename)) {
-		case ".jpg", ".jpeg":
-			ct = "image/jpeg"
-		case ".png":
-			ct = "image/png"
-		case ".webp":
-			ct = "image/webp"
-		}
-
-		// Upload to remote storage
-		publicURL, err := h.storage.Save(c.Context(), filename, bytes.NewReader(b), ct)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Failed to upload %s: %v", filename, err))
-			continue
-		}
-
-		uploadedFiles = append(uploadedFiles, filename)
-		result.UploadedFiles++
-
-		// Update database records for images with this filename
-		images, err := h.imageRepo.GetImagesByFilename(filename)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Failed to query database for %s: %v", filename, err))
-			continue
-		}
-
-		// Update each image record with the new public URL
-		for _, img := range images {
-			err := h.imageRepo.UpdateFilename(img.ID, publicURL)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Failed to update database for %s: %v", filename, err))
-			} else {
-				result.UpdatedRecords++
-			}
-		}
+	if handled, err := h.enqueueAdminJob(c, services.JobStorageMigrate, m, true); handled {
+		return err
 	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+}
 
-	// If cleanup is requested and uploads were successful, remove local files
-	if req.CleanupLocal && len(uploadedFiles) > 0 {
-		for _, filename := range uploadedFiles {
-			localPath := filepath.Join(root, filename)
-			if err := os.Remove(localPath); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Failed to cleanup %s: %v", filename, err))
-			} else {
-				result.CleanedFiles++
-			}
-		}
+// StorageMigrationStatus reports the latest storage migration job, uploads exports
+// included; while it runs its result holds the progress so far.
+func (h *AdminHandler) StorageMigrationStatus(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
-
-	if len(result.Errors) > 0 {
-		result.Success = false
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
 	}
-
-	return c.JSON(result)
+	list, _, err := h.jobs.List("", services.JobStorageMigrate, 1, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load migration status"})
+	}
+	if len(list) == 0 {
+		return c.JSON(fiber.Map{"job": nil, "running": false})
+	}
+	job := list[0]
+	running := job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning
+	return c.JSON(fiber.Map{"job": job, "running": running})
 }
 
 func (h *AdminHandler) TestSMTP(c *fiber.Ctx) error {
@@ -681,10 +1050,10 @@ func (h *AdminHandler) TestSMTP(c *fiber.Ctx) error {
 	}
 	sender := h.newMailSender(set)
 	if err := sender.Send(r.To, "SMTP test", "This is a test email from Trough."); err != nil {
-		log.Printf("Admin: SMTP test failed: %v", err)
+		services.Logger(c.Context()).Warn("admin: SMTP test failed", "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "SMTP send failed", "details": err.Error()})
 	}
-	log.Printf("Admin: SMTP test sent to %s", r.To)
+	services.Logger(c.Context()).Info("admin: SMTP test sent", "to", r.To)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -722,18 +1091,34 @@ func (h *AdminHandler) TestStorage(c *fiber.Ctx) error {
 
 // ---- Backups ----
 
-// AdminCreateBackup creates a new backup and returns it as a downloadable file (application/gzip).
+// AdminCreateBackup streams a new backup to the client as a downloadable file (application/gzip).
+// Pass ?uploads=1 to bundle local uploads into a tar.gz archive.
 func (h *AdminHandler) AdminCreateBackup(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
-	b, name, err := services.CreateBackup(c.Context(), models.DB())
+	pass, err := h.backupPassphraseFor(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create backup"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	opts := services.BackupOptions{IncludeUploads: c.QueryBool("uploads", false), Passphrase: pass}
+	now := time.Now().UTC()
+	name := services.BackupFileName(now, opts)
+	db := models.DB()
 	c.Set("Content-Type", "application/gzip")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
-	return c.Send(b)
+	// The request context is gone once the handler returns, so the stream gets its own deadline
+	logger := services.Logger(c.Context())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		if err := services.WriteBackup(ctx, db, w, now, opts); err != nil {
+			// Headers are already sent; the truncated archive fails gzip validation client-side
+			logger.Error("admin: backup stream failed", "error", err)
+		}
+		_ = w.Flush()
+	})
+	return nil
 }
 
 // AdminListBackups lists locally stored backup files (in backups/ directory).
@@ -741,25 +1126,52 @@ func (h *AdminHandler) AdminListBackups(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
-	list, err := services.ListBackups("backups")
+	list, err := services.ListBackups(services.BackupDir())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list backups"})
 	}
-	return c.JSON(fiber.Map{"backups": list})
+	encrypted := false
+	if set, err := h.settingsRepo.Get(); err == nil && set != nil {
+		encrypted = set.BackupPassphraseMarker != ""
+	}
+	return c.JSON(fiber.Map{"backups": list, "encrypted": encrypted})
 }
 
-// AdminSaveBackup writes a backup to server disk (backups/) and returns path metadata.
+// AdminSaveBackup writes a backup to server disk (backups/) and returns path metadata, or
+// 202 with the job when the backup is queued.
 func (h *AdminHandler) AdminSaveBackup(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
-	path, err := services.SaveBackupFile(c.Context(), models.DB(), "backups")
+	pass, err := h.backupPassphraseFor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if handled, err := h.queueBackup(c, services.BackupJob{IncludeUploads: c.QueryBool("uploads", false)}); handled {
+		return err
+	}
+	opts := services.BackupOptions{IncludeUploads: c.QueryBool("uploads", false), Passphrase: pass}
+	path, err := services.SaveBackupFile(c.Context(), models.DB(), services.BackupDir(), opts)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save backup"})
 	}
 	return c.JSON(fiber.Map{"path": path})
 }
 
+// queueBackup runs a backup as a background job when the worker can encrypt it without the
+// request's passphrase, which is never written to the queue: either encryption is off or
+// BACKUP_PASSPHRASE matches. Otherwise handled is false and the caller backs up inline.
+func (h *AdminHandler) queueBackup(c *fiber.Ctx, job services.BackupJob) (handled bool, err error) {
+	set, err := h.settingsRepo.Get()
+	if err != nil || set == nil {
+		return false, nil
+	}
+	if _, ok := services.EnvBackupPassphrase(*set); !ok {
+		return false, nil
+	}
+	return h.enqueueAdminJob(c, services.JobBackup, job, false)
+}
+
 // AdminDeleteBackup deletes a named backup from server disk.
 func (h *AdminHandler) AdminDeleteBackup(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
@@ -769,13 +1181,14 @@ func (h *AdminHandler) AdminDeleteBackup(c *fiber.Ctx) error {
 	if name == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name required"})
 	}
-	if err := services.DeleteBackup("backups", name); err != nil {
+	if err := services.DeleteBackup(services.BackupDir(), name); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Delete failed"})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// AdminRestoreBackup restores from an uploaded backup file.
+// AdminRestoreBackup restores from an uploaded backup file and returns a diff report.
+// Optional fields: tables, user_id (selective merge restore) and dry_run.
 func (h *AdminHandler) AdminRestoreBackup(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
@@ -790,13 +1203,20 @@ func (h *AdminHandler) AdminRestoreBackup(c *fiber.Ctx) error {
 	}
 	defer f.Close()
 	var r io.Reader = f
-	if err := services.RestoreBackup(c.Context(), models.DB(), r); err != nil {
-		log.Printf("Admin: restore failed: %v", err)
+	report, err := services.RestoreBackup(c.Context(), models.DB(), r, restoreOptions(c))
+	if err != nil {
+		if errors.Is(err, services.ErrBackupPassphraseRequired) || errors.Is(err, services.ErrBackupPassphraseInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		services.Logger(c.Context()).Error("admin: restore failed", "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Restore failed", "details": err.Error()})
 	}
 	// Invalidate caches that may depend on DB
-	services.InvalidateSettingsCache()
-	return c.SendStatus(fiber.StatusNoContent)
+	if !report.DryRun {
+		services.InvalidateSettingsCache()
+		services.InvalidateFeedCache(c.Context())
+	}
+	return c.JSON(report)
 }
 
 // AdminDownloadSavedBackup streams a previously-saved backup file by name.
@@ -808,14 +1228,308 @@ func (h *AdminHandler) AdminDownloadSavedBackup(c *fiber.Ctx) error {
 	if name == "" || strings.Contains(name, "/") || strings.Contains(name, "\\") || strings.Contains(name, "..") {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid name"})
 	}
-	path := filepath.Join("backups", name)
-	b, err := os.ReadFile(path)
-	if err != nil {
+	path := filepath.Join(services.BackupDir(), name)
+	if _, err := os.Stat(path); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not found"})
 	}
-	c.Set("Content-Type", "application/gzip")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
-	return c.Send(b)
+	if err := c.SendFile(path); err != nil {
+		return err
+	}
+	c.Set("Content-Type", "application/gzip")
+	return nil
+}
+
+// backupPassphrase reads a backup passphrase from the X-Backup-Passphrase header or a "passphrase" form field.
+func backupPassphrase(c *fiber.Ctx) string {
+	if p := c.Get("X-Backup-Passphrase"); p != "" {
+		return p
+	}
+	return c.FormValue("passphrase")
+}
+
+// restoreOptions reads restore options from form fields or query parameters:
+// tables (comma-separated), user_id, dry_run, and the backup passphrase.
+func restoreOptions(c *fiber.Ctx) services.RestoreOptions {
+	get := func(k string) string {
+		if v := strings.TrimSpace(c.FormValue(k)); v != "" {
+			return v
+		}
+		return strings.TrimSpace(c.Query(k))
+	}
+	opts := services.RestoreOptions{Passphrase: backupPassphrase(c), UserID: get("user_id")}
+	for _, t := range strings.Split(get("tables"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			opts.Tables = append(opts.Tables, t)
+		}
+	}
+	opts.DryRun, _ = strconv.ParseBool(get("dry_run"))
+	return opts
+}
+
+// backupPassphraseFor returns the passphrase to encrypt a new backup with. When encryption is
+// configured the request must supply the matching passphrase; otherwise backups stay plaintext.
+func (h *AdminHandler) backupPassphraseFor(c *fiber.Ctx) (string, error) {
+	set, err := h.settingsRepo.Get()
+	if err != nil || set == nil || set.BackupPassphraseMarker == "" {
+		return "", nil
+	}
+	pass := backupPassphrase(c)
+	if pass == "" {
+		return "", services.ErrBackupPassphraseRequired
+	}
+	if !services.CheckBackupPassphrase(set.BackupPassphraseMarker, pass) {
+		return "", services.ErrBackupPassphraseInvalid
+	}
+	return pass, nil
+}
+
+// AdminSetBackupPassphrase sets, rotates, or clears the backup encryption passphrase.
+// Body: {"passphrase": "...", "current_passphrase": "..."}; an empty passphrase disables encryption.
+// Only a bcrypt marker is stored; existing encrypted backups still need their original passphrase.
+func (h *AdminHandler) AdminSetBackupPassphrase(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var body struct {
+		Passphrase        string `json:"passphrase"`
+		CurrentPassphrase string `json:"current_passphrase"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	set, err := h.settingsRepo.Get()
+	if err != nil || set == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load settings"})
+	}
+	if set.BackupPassphraseMarker != "" && !services.CheckBackupPassphrase(set.BackupPassphraseMarker, body.CurrentPassphrase) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Current passphrase is incorrect"})
+	}
+	marker := ""
+	if body.Passphrase != "" {
+		if marker, err = services.HashBackupPassphrase(body.Passphrase); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	if err := models.SetBackupPassphraseMarker(marker); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save passphrase"})
+	}
+	services.InvalidateSettingsCache()
+	return c.JSON(fiber.Map{"encrypted": marker != ""})
+}
+
+// ---- Remote backups ----
+
+// remoteBackupStorage resolves the remote backup destination from current settings.
+func (h *AdminHandler) remoteBackupStorage() (services.Storage, error) {
+	set, err := h.settingsRepo.Get()
+	if err != nil || set == nil {
+		return nil, fmt.Errorf("settings unavailable")
+	}
+	return services.NewBackupStorage(*set)
+}
+
+// AdminListRemoteBackups lists backups stored in the remote backup bucket.
+func (h *AdminHandler) AdminListRemoteBackups(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	st, err := h.remoteBackupStorage()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Remote backups not configured", "details": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+	list, err := services.ListRemoteBackups(ctx, st)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to list remote backups"})
+	}
+	return c.JSON(fiber.Map{"backups": list})
+}
+
+// AdminPushRemoteBackup creates a backup on disk and uploads it to the remote backup bucket,
+// in the background when it can be queued (see queueBackup).
+func (h *AdminHandler) AdminPushRemoteBackup(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	st, err := h.remoteBackupStorage()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Remote backups not configured", "details": err.Error()})
+	}
+	pass, err := h.backupPassphraseFor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if handled, err := h.queueBackup(c, services.BackupJob{IncludeUploads: c.QueryBool("uploads", false), Remote: true}); handled {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	opts := services.BackupOptions{IncludeUploads: c.QueryBool("uploads", false), Passphrase: pass}
+	path, err := services.SaveBackupFile(ctx, models.DB(), services.BackupDir(), opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save backup"})
+	}
+	name, err := services.UploadBackup(ctx, st, path)
+	if err != nil {
+		services.Logger(c.Context()).Error("admin: remote backup upload failed", "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Upload failed", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"name": name, "path": path})
+}
+
+// AdminRestoreRemoteBackup restores the database from a named remote backup (same options as AdminRestoreBackup).
+func (h *AdminHandler) AdminRestoreRemoteBackup(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	name := strings.TrimSpace(c.Params("name"))
+	st, err := h.remoteBackupStorage()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Remote backups not configured", "details": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	rc, err := services.OpenRemoteBackup(ctx, st, name)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not found"})
+	}
+	defer rc.Close()
+	report, err := services.RestoreBackup(ctx, models.DB(), rc, restoreOptions(c))
+	if err != nil {
+		if errors.Is(err, services.ErrBackupPassphraseRequired) || errors.Is(err, services.ErrBackupPassphraseInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		services.Logger(c.Context()).Error("admin: remote restore failed", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Restore failed", "details": err.Error()})
+	}
+	if !report.DryRun {
+		services.InvalidateSettingsCache()
+		services.InvalidateFeedCache(c.Context())
+	}
+	return c.JSON(report)
+}
+
+// AdminDeleteRemoteBackup deletes a named remote backup.
+func (h *AdminHandler) AdminDeleteRemoteBackup(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	name := strings.TrimSpace(c.Params("name"))
+	st, err := h.remoteBackupStorage()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Remote backups not configured", "details": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+	if err := services.DeleteRemoteBackup(ctx, st, name); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Delete failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ---- Storage reconciliation ----
+
+// AdminReconcileStorage scans storage against image/avatar rows and optionally cleans up.
+// The scan runs as a background job (202 with the job to poll); its report is the job's result.
+// Body: {"delete_orphan_files": bool, "delete_dangling_records": bool}; empty body is a dry run.
+func (h *AdminHandler) AdminReconcileStorage(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var opts services.ReconcileOptions
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&opts); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	st := h.storage
+	if st == nil {
+		st = services.GetCurrentStorage()
+	}
+	if st == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage not configured"})
+	}
+	if _, ok := st.(services.ObjectLister); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Storage does not support listing"})
+	}
+	if handled, err := h.enqueueAdminJob(c, services.JobReconcile, opts, true); handled {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Minute)
+	defer cancel()
+	rep, err := services.ReconcileStorage(ctx, models.DB(), st, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrReconcileRunning) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Reconciliation already running"})
+		}
+		services.Logger(c.Context()).Error("admin: reconcile failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Reconciliation failed", "details": err.Error()})
+	}
+	return c.JSON(rep)
+}
+
+// AdminGetReconcileReport returns the most recent reconciliation report (manual or scheduled),
+// wherever it ran.
+func (h *AdminHandler) AdminGetReconcileReport(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	rep := services.LastReconcileReport()
+	if latest := h.latestReconcileReport(); latest != nil && (rep == nil || latest.FinishedAt.After(rep.FinishedAt)) {
+		rep = latest
+	}
+	if rep == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No reconciliation has run yet"})
+	}
+	return c.JSON(rep)
+}
+
+// AdminFeedCacheStats returns feed/image response cache hit and miss counters.
+func (h *AdminHandler) AdminFeedCacheStats(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	return c.JSON(services.GetFeedCacheStats())
+}
+
+// AdminAIRejectCacheStats reports how many re-uploads of rejected files were refused from
+// the AI detection rejection cache.
+func (h *AdminHandler) AdminAIRejectCacheStats(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	return c.JSON(services.GetAIRejectCacheStats())
+}
+
+// AdminFlushAIRejectCache forgets every remembered rejection, so files refused before a
+// detection change are scanned again.
+func (h *AdminHandler) AdminFlushAIRejectCache(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	services.FlushAIRejectCache(c.Context())
+	services.Logger(c.Context()).Info("admin: AI rejection cache flushed", "admin_id", middleware.GetUserID(c).String())
+	return c.JSON(services.GetAIRejectCacheStats())
+}
+
+// WithCSRF injects the CSRF middleware whose failure counters AdminCSRFStats reports
+func (h *AdminHandler) WithCSRF(cp *middleware.CSRFProtection) *AdminHandler {
+	h.csrf = cp
+	return h
+}
+
+// AdminCSRFStats returns rejected state-changing requests by reason and the number of
+// tokens rotated after sign-in or sign-out, since startup.
+func (h *AdminHandler) AdminCSRFStats(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.csrf == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "CSRF protection not configured"})
+	}
+	return c.JSON(h.csrf.Stats())
 }
 
 // AdminDiag returns quick sanity counts for core tables.
@@ -851,6 +1565,26 @@ func (h *AdminHandler) AdminDiag(c *fiber.Ctx) error {
 	return c.JSON(out)
 }
 
+// AdminStats returns dashboard time series (signups, uploads, collections, storage growth),
+// the AI provider mix and top uploaders over ?range= (24h, 7d, 30d, 90d, 365d; default 30d).
+func (h *AdminHandler) AdminStats(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.stats == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Stats not configured"})
+	}
+	rng, err := models.ParseStatsRange(c.Query("range"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid range", "details": err.Error()})
+	}
+	stats, err := h.stats.AdminStats(rng)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to compute stats", "details": err.Error()})
+	}
+	return c.JSON(stats)
+}
+
 // AdminRateLimiterStats returns rate limiter statistics and metrics
 func (h *AdminHandler) AdminRateLimiterStats(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
@@ -903,6 +1637,7 @@ func (h *AdminHandler) AdminListPages(c *fiber.Ctx) error {
 	if h.pageRepo == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
 	}
+	pages := tenantPages(c, h.pageRepo)
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	if page < 1 {
 		page = 1
@@ -913,7 +1648,7 @@ func (h *AdminHandler) AdminListPages(c *fiber.Ctx) error {
 	} else if limit > 200 {
 		limit = 200
 	}
-	list, total, err := h.pageRepo.ListAll(page, limit)
+	list, total, err := pages.ListAll(page, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
@@ -929,6 +1664,31 @@ type pageUpsertBody struct {
 	RedirectURL     *string `json:"redirect_url"`
 	MetaTitle       *string `json:"meta_title"`
 	MetaDescription *string `json:"meta_description"`
+	Position        int     `json:"position"`
+}
+
+// validatePagePath normalizes a page path and checks that its parent page exists.
+func validatePagePath(pages models.PageRepositoryInterface, raw string) (string, error) {
+	slug, err := services.NormalizePagePath(raw)
+	if err != nil {
+		return "", err
+	}
+	if parent := models.ParentPagePath(slug); parent != "" {
+		if p, err := pages.GetBySlug(parent); err != nil || p == nil {
+			return "", fmt.Errorf("parent page %q does not exist", parent)
+		}
+	}
+	return slug, nil
+}
+
+// isOwnDescendant reports whether moving page id to slug would put it below itself.
+func isOwnDescendant(pages models.PageRepositoryInterface, id uuid.UUID, slug string) bool {
+	for path := models.ParentPagePath(slug); path != ""; path = models.ParentPagePath(path) {
+		if p, err := pages.GetBySlug(path); err == nil && p != nil && p.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 // AdminCreatePage creates a page
@@ -939,18 +1699,14 @@ func (h *AdminHandler) AdminCreatePage(c *fiber.Ctx) error {
 	if h.pageRepo == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
 	}
+	pages := tenantPages(c, h.pageRepo)
 	var b pageUpsertBody
 	if err := c.BodyParser(&b); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
 	}
-	slug := strings.ToLower(strings.TrimSpace(b.Slug))
-	if !regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$`).MatchString(slug) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid slug"})
-	}
-	// Disallow conflicting reserved routes
-	reserved := map[string]bool{"api": true, "uploads": true, "assets": true, "@:username": true, "i": true, "register": true, "reset": true, "verify": true, "settings": true, "admin": true}
-	if reserved[slug] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Slug reserved"})
+	slug, err := validatePagePath(pages, b.Slug)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	// If redirect set, validate and store only redirect; else render markdown to HTML
 	if b.RedirectURL != nil && strings.TrimSpace(*b.RedirectURL) != "" {
@@ -960,9 +1716,15 @@ func (h *AdminHandler) AdminCreatePage(c *fiber.Ctx) error {
 		}
 		// force not published? allow published so it can be used
 	}
-	// Store as-is; HTML will be generated on the client from markdown
-	p := &models.Page{Slug: slug, Title: strings.TrimSpace(b.Title), Markdown: b.Markdown, HTML: "", IsPublished: b.IsPublished, RedirectURL: b.RedirectURL, MetaTitle: b.MetaTitle, MetaDescription: b.MetaDescription}
-	if err := h.pageRepo.Create(p); err != nil {
+	rendered, err := renderPageMarkdown(b.RedirectURL, b.Markdown)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Markdown could not be rendered"})
+	}
+	p := &models.Page{Slug: slug, Title: strings.TrimSpace(b.Title), Markdown: b.Markdown, HTML: rendered, IsPublished: b.IsPublished, RedirectURL: b.RedirectURL, MetaTitle: b.MetaTitle, MetaDescription: b.MetaDescription, Position: b.Position, UpdatedBy: actorID(c)}
+	if err := pages.Create(p); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A page with this slug already exists"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Create failed"})
 	}
 	return c.Status(fiber.StatusCreated).JSON(p)
@@ -976,6 +1738,7 @@ func (h *AdminHandler) AdminUpdatePage(c *fiber.Ctx) error {
 	if h.pageRepo == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
 	}
+	pages := tenantPages(c, h.pageRepo)
 	idStr := c.Params("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -985,13 +1748,12 @@ func (h *AdminHandler) AdminUpdatePage(c *fiber.Ctx) error {
 	if err := c.BodyParser(&b); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
 	}
-	slug := strings.ToLower(strings.TrimSpace(b.Slug))
-	if !regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$`).MatchString(slug) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid slug"})
+	slug, err := validatePagePath(pages, b.Slug)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	reserved := map[string]bool{"api": true, "uploads": true, "assets": true, "@:username": true, "i": true, "register": true, "reset": true, "verify": true, "settings": true, "admin": true}
-	if reserved[slug] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Slug reserved"})
+	if isOwnDescendant(pages, id, slug) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A page cannot be moved under itself"})
 	}
 	if b.RedirectURL != nil && strings.TrimSpace(*b.RedirectURL) != "" {
 		u := strings.TrimSpace(*b.RedirectURL)
@@ -999,13 +1761,33 @@ func (h *AdminHandler) AdminUpdatePage(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Redirect must be http(s) URL"})
 		}
 	}
-	p := &models.Page{ID: id, Slug: slug, Title: strings.TrimSpace(b.Title), Markdown: b.Markdown, HTML: "", IsPublished: b.IsPublished, RedirectURL: b.RedirectURL, MetaTitle: b.MetaTitle, MetaDescription: b.MetaDescription}
-	if err := h.pageRepo.Update(p); err != nil {
+	rendered, err := renderPageMarkdown(b.RedirectURL, b.Markdown)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Markdown could not be rendered"})
+	}
+	oldSlug := cdnPageSlug(pages, id)
+	p := &models.Page{ID: id, Slug: slug, Title: strings.TrimSpace(b.Title), Markdown: b.Markdown, HTML: rendered, IsPublished: b.IsPublished, RedirectURL: b.RedirectURL, MetaTitle: b.MetaTitle, MetaDescription: b.MetaDescription, Position: b.Position, UpdatedBy: actorID(c)}
+	if err := pages.Update(p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Page not found"})
+		}
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A page with this slug already exists"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Update failed"})
 	}
+	purgePagesFromCDN(c, h.settingsRepo, oldSlug, p.Slug)
 	return c.JSON(p)
 }
 
+// renderPageMarkdown returns the sanitized HTML stored with a page. Redirect pages have none.
+func renderPageMarkdown(redirectURL *string, markdown string) (string, error) {
+	if redirectURL != nil && strings.TrimSpace(*redirectURL) != "" {
+		return "", nil
+	}
+	return services.RenderMarkdown(markdown)
+}
+
 // AdminDeletePage deletes a page
 func (h *AdminHandler) AdminDeletePage(c *fiber.Ctx) error {
 	if !checkAdmin(c, h.userRepo) {
@@ -1014,12 +1796,18 @@ func (h *AdminHandler) AdminDeletePage(c *fiber.Ctx) error {
 	if h.pageRepo == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
 	}
+	pages := tenantPages(c, h.pageRepo)
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
 	}
-	if err := h.pageRepo.Delete(id); err != nil {
+	slug := cdnPageSlug(pages, id)
+	if err := pages.Delete(id); err != nil {
+		if errors.Is(err, models.ErrPageHasChildren) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Move or delete the pages under this page first"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Delete failed"})
 	}
+	purgePagesFromCDN(c, h.settingsRepo, slug)
 	return c.SendStatus(fiber.StatusNoContent)
 }
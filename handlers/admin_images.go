@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminListImages pages through every image on every site, hidden and pending ones
+// included, newest first. ?format=csv exports the whole list.
+func (h *AdminHandler) AdminListImages(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if wantsCSV(c) {
+		return sendCSV(c, "images", imageCSVHeader, func(page, limit int) ([][]string, error) {
+			list, _, err := h.imageRepo.AdminList(page, limit)
+			return imageCSVRows(list), err
+		})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.imageRepo.AdminList(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list images"})
+	}
+	return c.JSON(fiber.Map{"images": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
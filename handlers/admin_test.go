@@ -80,3 +80,32 @@ func TestAdminSMTP_Failure(t *testing.T) {
 		t.Fatalf("expected 400, got %d", resp.StatusCode)
 	}
 }
+
+type fakeStatsRepo struct {
+	models.StatsRepositoryInterface
+	got models.StatsRange
+}
+
+func (f *fakeStatsRepo) AdminStats(rng models.StatsRange) (*models.AdminStats, error) {
+	f.got = rng
+	return &models.AdminStats{Range: rng.Name, Bucket: rng.Bucket}, nil
+}
+
+func TestAdminStats_Range(t *testing.T) {
+	app := fiber.New()
+	stats := &fakeStatsRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithStats(stats)
+	app.Get("/stats", h.AdminStats)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if resp.StatusCode != http.StatusOK || stats.got.Name != "30d" || stats.got.Bucket != "day" {
+		t.Fatalf("expected default 30d/day, got %d %+v", resp.StatusCode, stats.got)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/stats?range=365d", nil))
+	if resp.StatusCode != http.StatusOK || stats.got.Bucket != "month" {
+		t.Fatalf("expected 365d/month, got %d %+v", resp.StatusCode, stats.got)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/stats?range=5y", nil))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown range, got %d", resp.StatusCode)
+	}
+}
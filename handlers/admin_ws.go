@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// Monitor connections close after adminMonitorLifetime so a revoked admin does not keep
+// watching; the dashboard reconnects and is checked again.
+const (
+	adminMonitorLifetime = 30 * time.Minute
+	adminMonitorPing     = 25 * time.Second
+	adminMonitorWrite    = 10 * time.Second
+)
+
+// adminMonitorMessage is one WebSocket message. "dropped" messages carry the number of
+// events a slow client missed in Count.
+type adminMonitorMessage struct {
+	ID    uint64 `json:"id,omitempty"`
+	Type  string `json:"type"`
+	Data  any    `json:"data,omitempty"`
+	Count uint64 `json:"count,omitempty"`
+}
+
+// AdminMonitor upgrades to a WebSocket that pushes security events, moderation queue
+// activity and uploads as they happen.
+func (h *AdminHandler) AdminMonitor(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	key := c.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") || !headerHasToken(c.Get(fiber.HeaderConnection), "upgrade") || !services.ValidWebSocketKey(key) {
+		c.Set(fiber.HeaderUpgrade, "websocket")
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{"error": "WebSocket upgrade required"})
+	}
+	if c.Get("Sec-WebSocket-Version") != "13" {
+		c.Set("Sec-WebSocket-Version", "13")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported WebSocket version"})
+	}
+	// Browsers send the auth cookie on cross-site WebSocket requests, and CORS does not
+	// apply to them, so only same-host pages may connect.
+	if !sameHostOrigin(c.Get(fiber.HeaderOrigin), c.Hostname()) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Origin not allowed"})
+	}
+	sub, err := services.SubscribeAdminEvents()
+	if err != nil {
+		c.Set(fiber.HeaderRetryAfter, "30")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Too many monitor connections"})
+	}
+	c.Status(fiber.StatusSwitchingProtocols)
+	c.Set(fiber.HeaderUpgrade, "websocket")
+	c.Set(fiber.HeaderConnection, "Upgrade")
+	c.Set("Sec-WebSocket-Accept", services.WebSocketAccept(key))
+	c.Context().Hijack(func(conn net.Conn) {
+		defer sub.Cancel()
+		ws := services.NewWSConn(conn, adminMonitorWrite)
+		defer ws.Close()
+		// The server's deadlines applied to the HTTP exchange; the monitor manages its own
+		_ = conn.SetDeadline(time.Time{})
+		serveAdminMonitor(ws, sub, adminMonitorLifetime, adminMonitorPing)
+	})
+	return nil
+}
+
+// serveAdminMonitor relays subscription events until the client leaves, a write fails or
+// stalls past the write timeout, the lifetime runs out or the server shuts down. Events
+// are never queued beyond the subscription buffer: a client that falls behind is told how
+// many it missed instead of slowing publishers down.
+func serveAdminMonitor(ws *services.WSConn, sub *services.Subscription, lifetime, ping time.Duration) {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			op, p, err := ws.ReadFrame()
+			if err != nil {
+				switch {
+				case errors.Is(err, services.ErrWSFrameTooBig):
+					_ = ws.WriteClose(services.WSCloseTooBig, "message too large")
+				case errors.Is(err, services.ErrWSProtocol):
+					_ = ws.WriteClose(services.WSCloseProtocol, "protocol error")
+				}
+				return
+			}
+			switch op {
+			case services.WSOpPing:
+				if ws.WritePong(p) != nil {
+					return
+				}
+			case services.WSOpClose:
+				_ = ws.WriteClose(services.WSCloseNormal, "")
+				return
+			}
+			// The monitor is one-way; anything else the client sends is ignored
+		}
+	}()
+	send := func(m adminMonitorMessage) bool {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return true
+		}
+		return ws.WriteText(b) == nil
+	}
+	ticker := time.NewTicker(ping)
+	defer ticker.Stop()
+	end := time.NewTimer(lifetime)
+	defer end.Stop()
+	for {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if n := sub.Dropped(); n > 0 && !send(adminMonitorMessage{Type: "dropped", Count: n}) {
+				return
+			}
+			if !send(adminMonitorMessage{ID: ev.ID, Type: ev.Type, Data: ev.Data}) {
+				return
+			}
+		case <-ticker.C:
+			if ws.WritePing() != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-end.C:
+			_ = ws.WriteClose(services.WSCloseGoingAway, "reconnect")
+			return
+		case <-services.ShuttingDown():
+			_ = ws.WriteClose(services.WSCloseGoingAway, "server shutting down")
+			return
+		}
+	}
+}
+
+// headerHasToken reports whether a comma-separated header such as Connection lists token.
+func headerHasToken(header, token string) bool {
+	for _, v := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHostOrigin reports whether origin names host. Requests without an Origin header do
+// not come from a browser page and are allowed.
+func sameHostOrigin(origin, host string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.EqualFold(u.Hostname(), host)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trough/services"
+)
+
+func TestServeAdminMonitor(t *testing.T) {
+	sub, err := services.SubscribeAdminEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Cancel()
+	server, client := net.Pipe()
+	ws := services.NewWSConn(server, time.Second)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer ws.Close()
+		serveAdminMonitor(ws, sub, time.Minute, time.Minute)
+	}()
+
+	services.PublishAdminEvent(services.AdminEventSecurity, map[string]string{"event_type": "ACCOUNT_LOCKOUT"})
+	r := bufio.NewReader(client)
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(hdr[1]&0x7F))
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatal(err)
+	}
+	var msg adminMonitorMessage
+	if err := json.Unmarshal(body, &msg); err != nil || msg.Type != services.AdminEventSecurity || msg.ID == 0 {
+		t.Fatalf("unexpected message %s (%v)", body, err)
+	}
+
+	// Closing the client ends the session
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("monitor did not stop after the client left")
+	}
+}
+
+func TestSameHostOrigin(t *testing.T) {
+	cases := []struct {
+		origin, host string
+		want         bool
+	}{
+		{"", "example.com", true},
+		{"https://example.com", "example.com", true},
+		{"http://EXAMPLE.com:8080", "example.com:8080", true},
+		{"https://evil.example", "example.com", false},
+		{"null", "example.com", false},
+	}
+	for _, tc := range cases {
+		if got := sameHostOrigin(tc.origin, tc.host); got != tc.want {
+			t.Errorf("sameHostOrigin(%q, %q) = %v, want %v", tc.origin, tc.host, got, tc.want)
+		}
+	}
+}
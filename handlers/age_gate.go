@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+// GetAgeGate tells the SPA whether the visitor still has to confirm their age before
+// the feed and images load.
+func (h *AuthHandler) GetAgeGate(c *fiber.Ctx) error {
+	set := middleware.SiteSettings(c, h.settingsRepo)
+	confirmed := !set.AdultSite || services.VerifyAgeGate(c.Cookies(services.AgeGateCookie), set.MinimumAge, time.Now())
+	return c.JSON(fiber.Map{"adult_site": set.AdultSite, "minimum_age": set.MinimumAge, "confirmed": confirmed || middleware.OptionalUserID(c) != uuid.Nil})
+}
+
+// ConfirmAge records a signed-out visitor's {"confirmed": true} in a signed cookie that
+// lasts services.AgeGateTTL.
+func (h *AuthHandler) ConfirmAge(c *fiber.Ctx) error {
+	var body struct {
+		Confirmed bool `json:"confirmed"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	if !body.Confirmed {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You must confirm you meet the minimum age"})
+	}
+	set := middleware.SiteSettings(c, h.settingsRepo)
+	if !set.AdultSite {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	expires := time.Now().Add(services.AgeGateTTL)
+	c.Cookie(&fiber.Cookie{
+		Name:     services.AgeGateCookie,
+		Value:    services.SignAgeGate(set.MinimumAge, expires),
+		Path:     "/",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   authCookieSecure(c),
+		SameSite: "Lax",
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// parseRedetectTime accepts a date (YYYY-MM-DD, UTC midnight) or an RFC 3339 timestamp.
+func parseRedetectTime(s string) (*time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, true
+		}
+	}
+	return nil, false
+}
+
+// AdminAIRedetect queues a job that re-runs AI detection over stored images, so detector
+// improvements reach the back catalog. Body (all optional): {"since": date, "until": date,
+// "missing_provider": bool}. Progress is at the status endpoint.
+func (h *AdminHandler) AdminAIRedetect(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var req struct {
+		Since           string `json:"since"`
+		Until           string `json:"until"`
+		MissingProvider bool   `json:"missing_provider"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	opts := services.AIRedetectOptions{MissingProvider: req.MissingProvider}
+	var ok bool
+	if opts.Since, ok = parseRedetectTime(req.Since); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "since must be a date (YYYY-MM-DD) or RFC 3339 time"})
+	}
+	if opts.Until, ok = parseRedetectTime(req.Until); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "until must be a date (YYYY-MM-DD) or RFC 3339 time"})
+	}
+	if opts.Since != nil && opts.Until != nil && !opts.Until.After(*opts.Since) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "until must be after since"})
+	}
+	st := h.storage
+	if st == nil {
+		st = services.GetCurrentStorage()
+	}
+	if st == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage not configured"})
+	}
+	if _, ok := st.(services.ObjectReader); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Storage does not support reading objects"})
+	}
+	if handled, err := h.enqueueAdminJob(c, services.JobAIRedetect, opts, true); handled {
+		return err
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+}
+
+// AdminAIRedetectStatus reports the latest re-detection job; while it runs its result
+// holds the progress so far.
+func (h *AdminHandler) AdminAIRedetectStatus(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	list, _, err := h.jobs.List("", services.JobAIRedetect, 1, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load re-detection status"})
+	}
+	if len(list) == 0 {
+		return c.JSON(fiber.Map{"job": nil, "running": false})
+	}
+	job := list[0]
+	running := job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning
+	return c.JSON(fiber.Map{"job": job, "running": running})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+func TestAdminAIRedetect_Validation(t *testing.T) {
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithStorage(&probeStorage{})
+	app.Post("/admin/ai-redetect", h.AdminAIRedetect)
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"since":"yesterday"}`, http.StatusBadRequest},
+		{`{"since":"2024-02-01","until":"2024-01-01"}`, http.StatusBadRequest},
+		// Valid filters get as far as the storage check; the fake cannot read objects
+		{`{"since":"2024-01-01","until":"2024-02-01T00:00:00Z","missing_provider":true}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/admin/ai-redetect", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: expected %d, got %d", c.body, c.code, resp.StatusCode)
+		}
+	}
+}
+
+func TestParseRedetectTime(t *testing.T) {
+	if tm, ok := parseRedetectTime(""); !ok || tm != nil {
+		t.Fatal("empty should mean no bound")
+	}
+	if tm, ok := parseRedetectTime("2024-03-05"); !ok || tm.Day() != 5 {
+		t.Fatalf("date not parsed: %v", tm)
+	}
+	if _, ok := parseRedetectTime("05/03/2024"); ok {
+		t.Fatal("expected other formats to be rejected")
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/services"
+)
+
+func TestStoreUpload_RejectCache(t *testing.T) {
+	services.ConfigureAIRejectCache(services.NewMemoryCacheStore(10), "memory", time.Minute)
+	defer services.ConfigureAIRejectCache(nil, "", 0)
+	var plain bytes.Buffer
+	if err := png.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	h := NewImageHandler(&createImageRepo{}, nil, nil, services.Config{}, services.NewLocalStorage(t.TempDir()))
+	upload := func() *uploadError {
+		req := uploadRequest{UserID: uuid.New(), Filename: "photo.png", Size: int64(plain.Len())}
+		_, _, uerr := h.storeUpload(context.Background(), req, bytes.NewReader(plain.Bytes()))
+		return uerr
+	}
+
+	if uerr := upload(); uerr == nil || uerr.msg != aiRejectedMsg {
+		t.Fatalf("expected a file without provenance rejected, got %v", uerr)
+	}
+	if uerr := upload(); uerr == nil || uerr.msg != aiRejectedMsg {
+		t.Fatalf("expected the re-upload rejected, got %v", uerr)
+	}
+	if st := services.GetAIRejectCacheStats(); st.Hits != 1 || st.Stored != 1 {
+		t.Fatalf("expected the re-upload refused from the cache, got %+v", st)
+	}
+}
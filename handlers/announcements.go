@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithAnnouncements injects the site announcement repository
+func (h *AdminHandler) WithAnnouncements(r models.AnnouncementRepositoryInterface) *AdminHandler {
+	h.announcements = r
+	return h
+}
+
+type announcementRequest struct {
+	Message  *string    `json:"message"`
+	Level    *string    `json:"level"`
+	StartsAt *time.Time `json:"starts_at"`
+	// EndsAt is RFC 3339; an empty string removes the end time
+	EndsAt      *string `json:"ends_at"`
+	Dismissible *bool   `json:"dismissible"`
+}
+
+// apply validates req and copies it onto a.
+func (req announcementRequest) apply(a *models.Announcement) error {
+	if req.Message != nil {
+		m := strings.TrimSpace(*req.Message)
+		if m == "" {
+			return errors.New("message is required")
+		}
+		if len([]rune(m)) > 1000 {
+			return errors.New("message too long (max 1000 characters)")
+		}
+		a.Message = m
+	}
+	if req.Level != nil {
+		switch l := strings.ToLower(strings.TrimSpace(*req.Level)); l {
+		case models.AnnouncementInfo, models.AnnouncementWarning, models.AnnouncementCritical:
+			a.Level = l
+		default:
+			return errors.New("level must be info, warning or critical")
+		}
+	}
+	if req.StartsAt != nil {
+		a.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		if s := strings.TrimSpace(*req.EndsAt); s == "" {
+			a.EndsAt = nil
+		} else {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return errors.New("ends_at must be an RFC 3339 time")
+			}
+			t = t.UTC()
+			a.EndsAt = &t
+		}
+	}
+	if req.Dismissible != nil {
+		a.Dismissible = *req.Dismissible
+	}
+	if a.Message == "" {
+		return errors.New("message is required")
+	}
+	if a.EndsAt != nil && !a.EndsAt.After(a.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// GetAnnouncements returns the announcements currently shown to visitors. It is public and
+// degrades to an empty list so a database hiccup never breaks the SPA shell.
+func (h *AdminHandler) GetAnnouncements(c *fiber.Ctx) error {
+	if h.announcements == nil {
+		return c.JSON(fiber.Map{"announcements": []models.Announcement{}})
+	}
+	list, err := h.announcements.ListActive()
+	if err != nil {
+		services.Logger(c.Context()).Error("announcements: list failed", "error", err)
+		list = []models.Announcement{}
+	}
+	return c.JSON(fiber.Map{"announcements": list})
+}
+
+// AdminListAnnouncements returns every announcement, including scheduled and expired ones.
+func (h *AdminHandler) AdminListAnnouncements(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.announcements == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Announcements not configured"})
+	}
+	list, err := h.announcements.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list announcements", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"announcements": list})
+}
+
+// AdminCreateAnnouncement schedules a banner. It starts now unless starts_at is given and
+// is dismissible unless dismissible is false.
+func (h *AdminHandler) AdminCreateAnnouncement(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.announcements == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Announcements not configured"})
+	}
+	var req announcementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	a := &models.Announcement{Level: models.AnnouncementInfo, StartsAt: time.Now().UTC(), Dismissible: true, CreatedBy: actorID(c)}
+	if err := req.apply(a); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.announcements.Create(a); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create announcement", "details": err.Error()})
+	}
+	services.Logger(c.Context()).Info("announcements: created", "id", a.ID.String(), "level", a.Level, "admin_id", middleware.GetUserID(c).String())
+	return c.Status(fiber.StatusCreated).JSON(a)
+}
+
+// AdminUpdateAnnouncement changes the fields present in the body.
+func (h *AdminHandler) AdminUpdateAnnouncement(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.announcements == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Announcements not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	a, err := h.announcements.Get(id)
+	if err != nil || a == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Announcement not found"})
+	}
+	var req announcementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.apply(a); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.announcements.Update(a); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update announcement", "details": err.Error()})
+	}
+	return c.JSON(a)
+}
+
+func (h *AdminHandler) AdminDeleteAnnouncement(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.announcements == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Announcements not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.announcements.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
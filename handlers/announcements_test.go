@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeAnnouncementRepo struct {
+	models.AnnouncementRepositoryInterface
+	items []models.Announcement
+}
+
+func (f *fakeAnnouncementRepo) Create(a *models.Announcement) error {
+	a.ID = uuid.New()
+	f.items = append(f.items, *a)
+	return nil
+}
+
+func (f *fakeAnnouncementRepo) Get(id uuid.UUID) (*models.Announcement, error) {
+	for i := range f.items {
+		if f.items[i].ID == id {
+			a := f.items[i]
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeAnnouncementRepo) Update(a *models.Announcement) error {
+	for i := range f.items {
+		if f.items[i].ID == a.ID {
+			f.items[i] = *a
+		}
+	}
+	return nil
+}
+
+func (f *fakeAnnouncementRepo) ListActive() ([]models.Announcement, error) {
+	out := []models.Announcement{}
+	for _, a := range f.items {
+		if a.Active(time.Now()) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func TestAnnouncementsCRUD(t *testing.T) {
+	app := fiber.New()
+	repo := &fakeAnnouncementRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithAnnouncements(repo)
+	app.Get("/announcements", h.GetAnnouncements)
+	app.Post("/admin/announcements", h.AdminCreateAnnouncement)
+	app.Patch("/admin/announcements/:id", h.AdminUpdateAnnouncement)
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := send(http.MethodPost, "/admin/announcements", `{"message":"  "}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty message, got %d", code)
+	}
+	if code := send(http.MethodPost, "/admin/announcements", `{"message":"x","level":"urgent"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown level, got %d", code)
+	}
+	if code := send(http.MethodPost, "/admin/announcements", `{"message":"x","ends_at":"2000-01-01T00:00:00Z"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for ends_at before starts_at, got %d", code)
+	}
+	if code := send(http.MethodPost, "/admin/announcements", `{"message":"Maintenance tonight","level":"Warning"}`); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if len(repo.items) != 1 || repo.items[0].Level != models.AnnouncementWarning || !repo.items[0].Dismissible {
+		t.Fatalf("unexpected announcement: %+v", repo.items)
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if code := send(http.MethodPost, "/admin/announcements", `{"message":"Later","starts_at":"`+future+`"}`); code != http.StatusCreated {
+		t.Fatalf("expected 201 for scheduled announcement, got %d", code)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/announcements", nil))
+	var body struct {
+		Announcements []models.Announcement `json:"announcements"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Announcements) != 1 || body.Announcements[0].Message != "Maintenance tonight" {
+		t.Fatalf("expected only the started announcement, got %+v", body.Announcements)
+	}
+
+	id := repo.items[0].ID.String()
+	if code := send(http.MethodPatch, "/admin/announcements/"+id, `{"dismissible":false,"ends_at":"`+future+`"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if repo.items[0].Dismissible || repo.items[0].EndsAt == nil || repo.items[0].Message != "Maintenance tonight" {
+		t.Fatalf("expected partial update, got %+v", repo.items[0])
+	}
+	if code := send(http.MethodPatch, "/admin/announcements/"+id, `{"ends_at":""}`); code != http.StatusOK || repo.items[0].EndsAt != nil {
+		t.Fatalf("expected ends_at cleared, got %d %+v", code, repo.items[0])
+	}
+	if code := send(http.MethodPatch, "/admin/announcements/"+uuid.NewString(), `{}`); code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", code)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithRegistrationBlocks records refused registrations for the admin dashboard.
+func (h *AuthHandler) WithRegistrationBlocks(r models.RegistrationBlockRepositoryInterface) *AuthHandler {
+	h.registrationBlocks = r
+	return h
+}
+
+// FormToken issues the signed timestamp the registration form sends back, so the timing
+// check can tell how long it was open.
+func (h *AuthHandler) FormToken(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{"token": services.NewFormToken()})
+}
+
+// registrationSpamBlock runs the antispam checks on a registration and returns the error
+// to show when it is refused, or "" to carry on. Refusals are counted, recorded and treated
+// as auth failures by the progressive rate limiter. Bots that filled the honeypot get a
+// deliberately vague message.
+func (h *AuthHandler) registrationSpamBlock(c *fiber.Ctx, sig services.RegistrationSignals) string {
+	set := services.GetCachedSettings(h.settingsRepo)
+	reason := services.CheckRegistrationSpam(sig, services.AntispamPolicy{
+		MinFillTime:     time.Duration(set.RegistrationMinFillSeconds) * time.Second,
+		BlockDisposable: set.BlockDisposableEmails,
+	})
+	if reason == "" {
+		return ""
+	}
+	services.RegistrationsBlocked.Inc(reason)
+	if h.registrationBlocks != nil {
+		if err := h.registrationBlocks.Record(reason, services.HashIP(services.ClientIP(c))); err != nil {
+			services.Logger(c.Context()).Error("register: recording antispam block failed", "error", err)
+		}
+	}
+	services.Logger(c.Context()).Warn("register: blocked by antispam", "reason", reason, "ip", services.ClientIP(c))
+	if h.progressiveRateLimiter != nil {
+		h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
+	}
+	switch reason {
+	case services.SpamTiming:
+		return "That was quick! Please wait a moment and try again"
+	case services.SpamDisposable:
+		return "Disposable email addresses are not allowed"
+	default:
+		return "Registration could not be completed"
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeRegistrationBlocks struct{ reasons []string }
+
+func (f *fakeRegistrationBlocks) Record(reason, ipHash string) error {
+	f.reasons = append(f.reasons, reason)
+	return nil
+}
+
+func TestRegistrationSpamBlock(t *testing.T) {
+	services.UpdateCachedSettings(models.SiteSettings{BlockDisposableEmails: true})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+	blocks := &fakeRegistrationBlocks{}
+	h := NewAuthHandler(&fakeUserRepo{}).WithRegistrationBlocks(blocks)
+	app := fiber.New()
+	app.Get("/check", func(c *fiber.Ctx) error {
+		return c.SendString(h.registrationSpamBlock(c, services.RegistrationSignals{Honeypot: c.Query("website"), Email: c.Query("email")}))
+	})
+	check := func(q url.Values) string {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/check?"+q.Encode(), nil))
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	if msg := check(url.Values{"email": {"a@example.com"}}); msg != "" {
+		t.Fatalf("a clean registration was refused: %q", msg)
+	}
+	if msg := check(url.Values{"email": {"a@example.com"}, "website": {"x"}}); msg != "Registration could not be completed" {
+		t.Fatalf("honeypot: got %q", msg)
+	}
+	if msg := check(url.Values{"email": {"a@yopmail.com"}}); msg == "" {
+		t.Fatal("disposable addresses should be refused")
+	}
+	if len(blocks.reasons) != 2 || blocks.reasons[0] != services.SpamHoneypot || blocks.reasons[1] != services.SpamDisposable {
+		t.Fatalf("unexpected recorded blocks: %v", blocks.reasons)
+	}
+}
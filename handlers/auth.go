@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"database/sql"
-	"log"
 	"net/mail"
 	"os"
 	"strings"
@@ -24,6 +23,11 @@ type AuthHandler struct {
 	newMailSender          func(*models.SiteSettings) services.MailSender
 	inviteRepo             models.InviteRepositoryInterface
 	progressiveRateLimiter *services.ProgressiveRateLimiter
+	banRepo                models.BanRepositoryInterface
+	history                models.UsernameHistoryRepositoryInterface
+	loginEvents            models.LoginEventRepositoryInterface
+	registrationBlocks     models.RegistrationBlockRepositoryInterface
+	legal                  models.LegalRepositoryInterface
 }
 
 // Backwards-compatible constructor used by existing tests
@@ -53,6 +57,45 @@ func (h *AuthHandler) WithProgressiveRateLimiter(prl *services.ProgressiveRateLi
 	return h
 }
 
+// WithBans enables IP and email-domain banlist enforcement.
+func (h *AuthHandler) WithBans(r models.BanRepositoryInterface) *AuthHandler {
+	h.banRepo = r
+	return h
+}
+
+// WithUsernameHistory keeps recently renamed-away handles from being registered.
+func (h *AuthHandler) WithUsernameHistory(r models.UsernameHistoryRepositoryInterface) *AuthHandler {
+	h.history = r
+	return h
+}
+
+// checkBans returns the ban refusing this client, recording the hit in the ban audit trail.
+// Disposable emails are refused by the registration antispam checks instead.
+func (h *AuthHandler) checkBans(c *fiber.Ctx, email string, registering bool) *services.BanHit {
+	if h.banRepo == nil {
+		return nil
+	}
+	hit := services.CheckBans(h.banRepo, services.ClientIP(c), email, false)
+	if hit == nil {
+		return nil
+	}
+	detail := "login"
+	if registering {
+		detail = "register"
+	}
+	if email != "" {
+		detail += " " + email
+	}
+	if err := h.banRepo.AddAudit(&models.BanAudit{BanID: hit.BanID, Action: models.BanActionBlocked, Kind: hit.Kind, Value: hit.Value, IP: services.ClientIP(c), Detail: detail}); err != nil {
+		services.Logger(c.Context()).Error("bans: audit write failed", "error", err)
+	}
+	services.Logger(c.Context()).Warn("bans: request blocked", "kind", hit.Kind, "value", hit.Value, "ip", services.ClientIP(c), "register", registering)
+	if h.progressiveRateLimiter != nil {
+		h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
+	}
+	return hit
+}
+
 // GetPasswordRequirements returns password requirements for UI display
 func (h *AuthHandler) GetPasswordRequirements(c *fiber.Ctx) error {
 	requirements := services.GetPasswordRequirements()
@@ -82,28 +125,38 @@ func (r *inMemorySettingsRepo) UpdateSocialImageURL(path string) error {
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	// Support invite codes which can bypass public registration toggle.
 	inviteCode := strings.TrimSpace(c.Query("invite", ""))
-	mustHaveInvite := false
+	mustHaveInvite, requireApproval := false, false
+	var legal models.LegalVersions
+	minimumAge := 0
 	if set, err := h.settingsRepo.Get(); err == nil {
+		set = tenantSettings(c, set)
 		mustHaveInvite = !set.PublicRegistrationEnabled
+		requireApproval = set.RegistrationApprovalRequired
+		legal = set.RequiredLegalVersions()
+		minimumAge = set.MinimumAge
 	}
 	var req models.CreateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if blocked := h.challengeBlock(c); blocked != nil {
+		return c.Status(fiber.StatusForbidden).JSON(blocked)
+	}
+	// Fields outside CreateUserRequest: the invite may also come in the body, website and
+	// form_token feed the antispam checks, and the rest confirm terms and age
+	type rawReq struct {
+		Invite       string `json:"invite"`
+		Website      string `json:"website"`
+		FormToken    string `json:"form_token"`
+		AcceptTerms  bool   `json:"accept_terms"`
+		AgeConfirmed bool   `json:"age_confirmed"`
+	}
+	var rr rawReq
+	_ = c.BodyParser(&rr)
+	if inviteCode == "" && strings.TrimSpace(rr.Invite) != "" {
+		inviteCode = strings.TrimSpace(rr.Invite)
+	}
 	if mustHaveInvite {
-		if inviteCode == "" {
-			// Also allow JSON body field to carry invite
-			if v := strings.TrimSpace(req.Password); v == "" { /* no-op to keep req used */
-			}
-			type rawReq struct {
-				Invite string `json:"invite"`
-			}
-			var rr rawReq
-			_ = c.BodyParser(&rr)
-			if strings.TrimSpace(rr.Invite) != "" {
-				inviteCode = strings.TrimSpace(rr.Invite)
-			}
-		}
 		if inviteCode == "" || h.inviteRepo == nil {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Registration is currently disabled"})
 		}
@@ -117,7 +170,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	if err := h.validator.Struct(req); err != nil {
 		// Record authentication failure for progressive rate limiting
 		if h.progressiveRateLimiter != nil {
-			h.progressiveRateLimiter.RecordFailure(c.IP(), c)
+			h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
 		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
 	}
@@ -125,17 +178,30 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	if err := services.ValidatePassword(req.Password); err != nil {
 		// Record authentication failure for progressive rate limiting
 		if h.progressiveRateLimiter != nil {
-			h.progressiveRateLimiter.RecordFailure(c.IP(), c)
+			h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
 		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	if msg := h.registrationSpamBlock(c, services.RegistrationSignals{Honeypot: rr.Website, FormToken: rr.FormToken, Email: req.Email}); msg != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": msg})
+	}
+	if hit := h.checkBans(c, req.Email, true); hit != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": hit.Message})
+	}
+	if minimumAge > 0 && !rr.AgeConfirmed {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You must confirm you meet the minimum age", "minimum_age": minimumAge})
+	}
+	trackTerms := h.legal != nil && legal.Tracked()
+	if trackTerms && !rr.AcceptTerms {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You must accept the terms of service and privacy policy"})
+	}
 	// Add timeout context for database operations
 	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
 	defer cancel()
 
 	// Check if email already exists
 	if existingUser, err := h.userRepo.GetByEmail(ctx, req.Email); err != nil && err != sql.ErrNoRows {
-		log.Printf("register error: GetByEmail failed: %v", err)
+		services.Logger(c.Context()).Error("register: GetByEmail failed", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Registration service unavailable"})
 	} else if existingUser != nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
@@ -143,11 +209,16 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 	// Check if username already exists
 	if existingUser, err := h.userRepo.GetByUsername(ctx, req.Username); err != nil && err != sql.ErrNoRows {
-		log.Printf("register error: GetByUsername failed: %v", err)
+		services.Logger(c.Context()).Error("register: GetByUsername failed", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Registration service unavailable"})
 	} else if existingUser != nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Username already taken"})
 	}
+	if h.history != nil {
+		if _, err := h.history.ResolveOld(req.Username, time.Now().Add(-models.UsernameRedirectGrace)); err == nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Username already taken"})
+		}
+	}
 
 	tx, err := h.userRepo.BeginTx()
 	if err != nil {
@@ -155,8 +226,10 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 	defer tx.Rollback()
 
-	// If an invite is required, validate and consume it within the transaction
-	var consumedInviteID *uuid.UUID
+	// If an invite is required, validate and consume it within the transaction. With open
+	// registration an invite is still consumed when given, so the invite tree records it,
+	// but an unusable one is ignored.
+	var consumedInvite *models.Invite
 	if mustHaveInvite {
 		// Attempt to consume the invite code atomically.
 		// This single operation should implicitly validate existence, expiry, and usage limits.
@@ -168,22 +241,51 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 			}
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid or expired invite code"})
 		}
-		consumedInviteID = &inv.ID
+		// Rolling back the transaction gives the use back
+		if !inviteAllowsEmail(inv, req.Email) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This invite was sent to a different email address"})
+		}
+		consumedInvite = inv
+	} else if inviteCode != "" && h.inviteRepo != nil {
+		if inv, err := h.inviteRepo.ConsumeWithTx(tx, inviteCode); err == nil {
+			if inviteAllowsEmail(inv, req.Email) {
+				consumedInvite = inv
+			} else {
+				_ = h.inviteRepo.RevertConsumeWithTx(tx, inv.ID)
+			}
+		}
+	}
+	// Someone who was invited has been vouched for, so only open registrations queue
+	user := &models.User{Username: req.Username, Email: req.Email, PendingApproval: requireApproval && consumedInvite == nil}
+	if minimumAge > 0 {
+		now := time.Now()
+		user.AgeConfirmedAt = &now
 	}
-	user := &models.User{Username: req.Username, Email: req.Email}
 	if err := user.HashPassword(req.Password); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process password"})
 	}
 	if err := h.userRepo.CreateWithTx(tx, user); err != nil {
-		if consumedInviteID != nil && h.inviteRepo != nil {
-			_ = h.inviteRepo.RevertConsumeWithTx(tx, *consumedInviteID)
+		if consumedInvite != nil && h.inviteRepo != nil {
+			_ = h.inviteRepo.RevertConsumeWithTx(tx, consumedInvite.ID)
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
 	}
+	if consumedInvite != nil && consumedInvite.CreatedBy != nil {
+		if err := h.inviteRepo.RecordInviteeWithTx(tx, user.ID, consumedInvite); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+		}
+	}
+	// The versions accepted on the form are the ones in force when it was submitted
+	if trackTerms {
+		if err := tenantLegal(c, h.legal).RecordWithTx(tx, user.ID, legal, services.HashIP(services.ClientIP(c))); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
 	}
+	services.EmitWebhook(services.WebhookUserRegistered, map[string]interface{}{"id": user.ID, "username": user.Username, "invited": consumedInvite != nil, "pending_approval": user.PendingApproval, "created_at": user.CreatedAt})
 
 	set, _ := h.settingsRepo.Get()
 	if set.RequireEmailVerification && set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "" {
@@ -196,19 +298,28 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 			exp := time.Now().Add(24 * time.Hour)
 			_ = models.CreateEmailVerification(u.ID, services.HashToken(token), exp)
 			link := strings.TrimRight(set.SiteURL, "/") + "/verify?token=" + token
-			subj, bodyTxt := services.BuildVerificationEmail(set.SiteName, set.SiteURL, link)
+			msg := services.BuildVerificationMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, link)
 			// Send asynchronously via queue only (avoid duplicate immediate send)
 			// Use goroutine to prevent any email sending delays from blocking response
+			logger := services.Logger(c.UserContext())
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Printf("Email verification send panic recovered: %v", r)
+						logger.Error("auth: email verification send panic recovered", "panic", r)
 					}
 				}()
-				services.EnqueueMail(u.Email, subj, bodyTxt)
+				services.EnqueueMessage(u.Email, msg)
 			}()
 		}
 	}
+	if user.PendingApproval {
+		if h.progressiveRateLimiter != nil {
+			h.progressiveRateLimiter.RecordSuccess(services.ClientIP(c), c)
+		}
+		services.Logger(c.Context()).Info("register: account waiting for approval", "user_id", user.ID.String())
+		// No session until staff approve the account
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"user": user.ToResponse(), "pending_approval": true})
+	}
 	token, err := middleware.GenerateToken(user.ID, user.Username)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
@@ -227,11 +338,11 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		HTTPOnly: true,
 		Secure:   secure,
 		SameSite: "Lax",
-		MaxAge:   24 * 60 * 60,
+		MaxAge:   middleware.TokenMaxAge(),
 	})
 	// Record registration success for progressive rate limiting
 	if h.progressiveRateLimiter != nil {
-		h.progressiveRateLimiter.RecordSuccess(c.IP(), c)
+		h.progressiveRateLimiter.RecordSuccess(services.ClientIP(c), c)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"user": user.ToResponse(), "token": token})
@@ -246,10 +357,13 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	if err := h.validator.Struct(req); err != nil {
 		// Record authentication failure for progressive rate limiting
 		if h.progressiveRateLimiter != nil {
-			h.progressiveRateLimiter.RecordFailure(c.IP(), c)
+			h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
 		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
 	}
+	if hit := h.checkBans(c, "", false); hit != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": hit.Message})
+	}
 
 	// Add timeout context for database operations
 	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
@@ -269,25 +383,34 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		if err == sql.ErrNoRows {
 			// Record authentication failure for progressive rate limiting
 			if h.progressiveRateLimiter != nil {
-				h.progressiveRateLimiter.RecordFailure(c.IP(), c)
+				h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
 			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid username or password"})
 		}
 		// Log server-side; avoid leaking DB state to clients
-		log.Printf("login error: GetByEmail/GetByUsername failed: %v", err)
+		services.Logger(c.Context()).Error("login: user lookup failed", "error", err)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication failed"})
 	}
 
-	if user.IsDisabled {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Account disabled"})
+	// A timed suspension that has run out no longer blocks sign-in, even before the expiry job
+	// clears it
+	if s := user.ActiveSuspension(); s != nil {
+		return c.Status(fiber.StatusForbidden).JSON(suspensionError(s))
 	}
 	if !user.CheckPassword(req.LoginPassword) {
 		// Record authentication failure for progressive rate limiting
 		if h.progressiveRateLimiter != nil {
-			h.progressiveRateLimiter.RecordFailure(c.IP(), c)
+			h.progressiveRateLimiter.RecordFailure(services.ClientIP(c), c)
 		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid username or password"})
 	}
+	// Domain bans are checked after the password so they don't reveal which accounts exist
+	if hit := h.checkBans(c, user.Email, false); hit != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": hit.Message})
+	}
+	if user.PendingApproval {
+		return c.Status(fiber.StatusForbidden).JSON(pendingApprovalError)
+	}
 	// Allow login even if email is not verified. We only gate privileged actions (uploads).
 	token, err := middleware.GenerateToken(user.ID, user.Username)
 	if err != nil {
@@ -308,12 +431,13 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		HTTPOnly: true,
 		Secure:   secure,
 		SameSite: "Lax",
-		MaxAge:   24 * 60 * 60,
+		MaxAge:   middleware.TokenMaxAge(),
 	})
 	// Record authentication success for progressive rate limiting
 	if h.progressiveRateLimiter != nil {
-		h.progressiveRateLimiter.RecordSuccess(c.IP(), c)
+		h.progressiveRateLimiter.RecordSuccess(services.ClientIP(c), c)
 	}
+	h.recordLogin(c, user)
 
 	// Return user as-is; frontend can detect email_verified flag and display banner/actions
 	return c.JSON(fiber.Map{"user": user.ToResponse(), "token": token})
@@ -333,7 +457,32 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	return c.JSON(fiber.Map{"user": user.ToResponse()})
+	resp := fiber.Map{"user": user.ToOwnResponse()}
+	// Sessions opened before a suspension stay valid; the client shows this as a banner
+	if s := user.ActiveSuspension(); s != nil {
+		resp["suspension"] = s
+	}
+	// An admin acting as this user sees a banner naming them and when the session ends
+	if imp := middleware.GetImpersonation(c); imp != nil {
+		info := fiber.Map{"session_id": imp.SessionID, "admin_id": imp.AdminID, "expires_at": imp.ExpiresAt}
+		if admin, err := h.userRepo.GetByID(ctx, imp.AdminID); err == nil {
+			info["admin_username"] = admin.Username
+		}
+		resp["impersonation"] = info
+	}
+	return c.JSON(resp)
+}
+
+// pendingApprovalError refuses a session to an account still in the approval queue.
+var pendingApprovalError = fiber.Map{"error": "Your account is waiting for approval", "pending_approval": true}
+
+// suspensionError is the error body for requests refused because the account is suspended.
+func suspensionError(s *models.Suspension) fiber.Map {
+	msg := "Account disabled"
+	if s.Until != nil {
+		msg = "Account suspended until " + s.Until.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	return fiber.Map{"error": msg, "suspension": s}
 }
 
 // Logout clears the auth cookie for the current session
@@ -348,6 +497,7 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	}
 	// Include an explicit past Expires to ensure deletion across browsers/proxies
 	c.Cookie(&fiber.Cookie{Name: "auth_token", Value: "", Path: "/", HTTPOnly: true, Secure: secure, SameSite: "Lax", MaxAge: -1, Expires: time.Unix(0, 0)})
+	c.Cookie(&fiber.Cookie{Name: impersonatorCookie, Value: "", Path: impersonatorCookiePath, HTTPOnly: true, Secure: secure, SameSite: "Strict", MaxAge: -1, Expires: time.Unix(0, 0)})
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -359,6 +509,9 @@ func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
 	if err := c.BodyParser(&r); err != nil || r.Email == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email required"})
 	}
+	if blocked := h.challengeBlock(c); blocked != nil {
+		return c.Status(fiber.StatusForbidden).JSON(blocked)
+	}
 
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
@@ -384,30 +537,9 @@ token := uuid.New().String()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
 	link := strings.TrimRight(set.SiteURL, "/") + "/reset?token=" + token
-	// Plain-text, ASCII-styled message with clear instructions and expiry notice
-	body := `============================
-  PASSWORD RESET REQUEST
-============================
-
-We received a request to reset your password.
-
-If you made this request, use the link below to set a new password.
-If you did NOT request this, you can safely ignore this email.
-
->>> RESET LINK (valid for 1 hour, single-use) <<<
-` + link + `
-
-Tips for a strong password:
-- 8+ characters
-- mix of UPPER/lower case, numbers, symbols
-
-This link expires in 1 hour or after it is used once.
-For security, never share this link.
-
-— TROUGH
-`
+	msg := services.BuildPasswordResetMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, link)
 	// Queue async send only to avoid duplicate emails
-	services.EnqueueMail(u.Email, "Reset your password", body)
+	services.EnqueueMessage(u.Email, msg)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -440,6 +572,9 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
 	_ = models.DeletePasswordReset(services.HashToken(r.Token))
+	if u.PendingApproval {
+		return c.Status(fiber.StatusForbidden).JSON(pendingApprovalError)
+	}
 	// Issue a fresh token so client can auto-login
 	tokenStr, err := middleware.GenerateToken(u.ID, u.Username)
 	if err != nil {
@@ -457,7 +592,7 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 		HTTPOnly: true,
 		Secure:   secure,
 		SameSite: "Lax",
-		MaxAge:   24 * 60 * 60,
+		MaxAge:   middleware.TokenMaxAge(),
 	})
 	return c.JSON(fiber.Map{"user": u.ToResponse(), "token": tokenStr})
 }
@@ -479,6 +614,51 @@ func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// ConfirmEmailChange applies a pending email change once the new address follows the link
+// it was sent. Confirming proves control of the address, so it also counts as verified.
+func (h *AuthHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	var r struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&r); err != nil || r.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token required"})
+	}
+	ch, err := models.GetEmailChange(services.HashToken(r.Token))
+	if err != nil || time.Now().After(ch.ExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	// The address may have been taken while the change waited
+	if existing, err := h.userRepo.GetByEmail(ctx, ch.NewEmail); err == nil && existing != nil && existing.ID != ch.UserID {
+		_ = models.DeleteEmailChanges(ch.UserID)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
+	}
+	if err := h.userRepo.UpdateEmail(ch.UserID, ch.NewEmail); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update email"})
+	}
+	_ = models.SetEmailVerified(ch.UserID, true)
+	_ = models.DeleteEmailChanges(ch.UserID)
+	services.Logger(c.Context()).Info("user: email change confirmed", "user_id", ch.UserID.String())
+	return c.JSON(fiber.Map{"email": ch.NewEmail})
+}
+
+// CancelEmailChange stops a pending email change from the link sent to the old address.
+func (h *AuthHandler) CancelEmailChange(c *fiber.Ctx) error {
+	var r struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&r); err != nil || r.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token required"})
+	}
+	uid, err := models.CancelEmailChange(services.HashToken(r.Token))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token or the change already happened"})
+	}
+	services.Logger(c.Context()).Warn("user: email change cancelled from the old address", "user_id", uid.String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
 	uid := middleware.GetUserID(c)
 	if uid == uuid.Nil {
@@ -509,8 +689,8 @@ func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
 	link := strings.TrimRight(set.SiteURL, "/") + "/verify?token=" + token
-	subj, bodyTxt := services.BuildVerificationEmail(set.SiteName, set.SiteURL, link)
+	msg := services.BuildVerificationMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, link)
 	// Queue async send only to avoid duplicate emails
-	services.EnqueueMail(u.Email, subj, bodyTxt)
+	services.EnqueueMessage(u.Email, msg)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+	"golang.org/x/image/webp"
+)
+
+// remoteStorage records objects in memory like a bucket behind a prefixed public URL.
+type remoteStorage struct {
+	services.Storage
+	objects map[string][]byte
+	deleted []string
+}
+
+func (s *remoteStorage) Save(_ context.Context, key string, r io.Reader, _ string) (string, error) {
+	b, _ := io.ReadAll(r)
+	s.objects[key] = b
+	return s.PublicURL(key), nil
+}
+
+func (s *remoteStorage) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *remoteStorage) PublicURL(key string) string {
+	return "https://s3.example.com/myavatars/" + key
+}
+
+func (s *remoteStorage) IsLocal() bool { return false }
+
+type avatarUserRepo struct {
+	models.UserRepositoryInterface
+	user *models.User
+}
+
+func (f *avatarUserRepo) GetByID(context.Context, uuid.UUID) (*models.User, error) {
+	u := *f.user
+	return &u, nil
+}
+
+func (f *avatarUserRepo) UpdateProfile(_ uuid.UUID, req models.UpdateUserRequest) (*models.User, error) {
+	if req.AvatarURL != nil {
+		f.user.AvatarURL = req.AvatarURL
+	}
+	if req.ProfileTheme != nil {
+		f.user.ProfileTheme = *req.ProfileTheme
+	}
+	return f.user, nil
+}
+
+func multipartImage(t *testing.T, field string, img image.Image) (*bytes.Buffer, string) {
+	t.Helper()
+	var file bytes.Buffer
+	if err := png.Encode(&file, img); err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile(field, "upload.png")
+	_, _ = part.Write(file.Bytes())
+	_ = mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func TestUploadAvatar(t *testing.T) {
+	userID := uuid.New()
+	old := "https://s3.example.com/myavatars/avatars/old.webp"
+	users := &avatarUserRepo{user: &models.User{ID: userID, Username: "alice", AvatarURL: &old}}
+	st := &remoteStorage{objects: map[string][]byte{"avatars/old.webp": {1}}}
+	h := NewUserHandler(users, &fakeImageRepo{}, st)
+	app := fiber.New()
+	app.Post("/me/avatar", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, h.UploadAvatar)
+
+	src := image.NewNRGBA(image.Rect(0, 0, 600, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 600; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 90, 255})
+		}
+	}
+	body, contentType := multipartImage(t, "avatar", src)
+	req := httptest.NewRequest(http.MethodPost, "/me/avatar", body)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, msg)
+	}
+	var out struct {
+		AvatarURL string `json:"avatar_url"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	key := strings.TrimPrefix(out.AvatarURL, "https://s3.example.com/myavatars/")
+	if !strings.HasPrefix(key, "avatars/") || !strings.HasSuffix(key, ".webp") || users.user.AvatarURL == nil || *users.user.AvatarURL != out.AvatarURL {
+		t.Fatalf("expected a stored webp avatar, got %q", out.AvatarURL)
+	}
+	img, err := webp.Decode(bytes.NewReader(st.objects[key]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 256 {
+		t.Fatalf("expected a 256px square, got %v", b)
+	}
+	if len(st.deleted) != 1 || st.deleted[0] != "avatars/old.webp" {
+		t.Fatalf("expected the old avatar deleted by key, got %v", st.deleted)
+	}
+}
+
+func TestUploadProfileBanner(t *testing.T) {
+	userID := uuid.New()
+	users := &avatarUserRepo{user: &models.User{ID: userID, Username: "alice", ProfileTheme: models.ProfileTheme{HeaderKey: "headers/old.jpg", HeaderURL: "https://s3.example.com/myavatars/headers/old.jpg"}}}
+	st := &remoteStorage{objects: map[string][]byte{"headers/old.jpg": {1}}}
+	h := NewUserHandler(users, &fakeImageRepo{}, st)
+	app := fiber.New()
+	app.Post("/me/banner", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, h.UploadProfileBanner)
+
+	// Older clients send the file as "header"
+	for _, field := range []string{"banner", "header"} {
+		body, contentType := multipartImage(t, field, image.NewNRGBA(image.Rect(0, 0, 120, 40)))
+		req := httptest.NewRequest(http.MethodPost, "/me/banner", body)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s: expected 200, got %d: %s", field, resp.StatusCode, msg)
+		}
+		key := users.user.ProfileTheme.HeaderKey
+		if !strings.HasPrefix(key, "banners/") || st.objects[key] == nil {
+			t.Fatalf("%s: expected the banner stored under banners/, got %q", field, key)
+		}
+		if r := users.user.ToResponse(); r.BannerURL == nil || *r.BannerURL != st.PublicURL(key) {
+			t.Fatalf("%s: expected banner_url in the user response, got %v", field, r.BannerURL)
+		}
+	}
+	if len(st.deleted) != 2 || st.deleted[0] != "headers/old.jpg" {
+		t.Fatalf("expected replaced banners deleted, got %v", st.deleted)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithBans injects the IP and email-domain banlist repository
+func (h *AdminHandler) WithBans(r models.BanRepositoryInterface) *AdminHandler {
+	h.bans = r
+	return h
+}
+
+// ListBans returns every ban, including expired ones, for the admin panel.
+func (h *AdminHandler) ListBans(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.bans == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Bans not configured"})
+	}
+	list, err := h.bans.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list bans", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"bans": list})
+}
+
+// CreateBan adds an IP/CIDR or email-domain ban. Values are normalized so the same range
+// can't be added twice in different spellings.
+func (h *AdminHandler) CreateBan(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.bans == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Bans not configured"})
+	}
+	var req struct {
+		Kind      string     `json:"kind"`
+		Value     string     `json:"value"`
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	value, err := services.NormalizeBanValue(kind, req.Value)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len([]rune(reason)) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason too long"})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at must be in the future"})
+	}
+	ban := &models.Ban{Kind: kind, Value: value, Reason: reason, ExpiresAt: req.ExpiresAt, CreatedBy: actorID(c)}
+	if err := h.bans.Create(ban); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Already banned"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create ban", "details": err.Error()})
+	}
+	h.auditBan(c, ban, models.BanActionCreated, reason)
+	services.InvalidateBanlist()
+	return c.Status(fiber.StatusCreated).JSON(ban)
+}
+
+// DeleteBan lifts a ban.
+func (h *AdminHandler) DeleteBan(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.bans == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Bans not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	ban, err := h.bans.Get(id)
+	if err != nil || ban == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Ban not found"})
+	}
+	if err := h.bans.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	h.auditBan(c, ban, models.BanActionDeleted, "")
+	services.InvalidateBanlist()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListBanAudit returns the ban audit trail, newest first: admin changes and refused
+// registrations and logins.
+func (h *AdminHandler) ListBanAudit(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.bans == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Bans not configured"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.bans.ListAudit(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list audit", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"audit": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+func (h *AdminHandler) auditBan(c *fiber.Ctx, ban *models.Ban, action, detail string) {
+	entry := &models.BanAudit{BanID: &ban.ID, Action: action, Kind: ban.Kind, Value: ban.Value, ActorID: actorID(c), IP: services.ClientIP(c), Detail: detail}
+	if err := h.bans.AddAudit(entry); err != nil {
+		services.Logger(c.Context()).Error("bans: audit write failed", "error", err)
+	}
+	services.Logger(c.Context()).Info("bans: "+action, "kind", ban.Kind, "value", ban.Value, "admin_id", middleware.GetUserID(c).String())
+}
+
+// actorID returns the signed-in user's id for nullable created_by/actor_id columns.
+func actorID(c *fiber.Ctx) *uuid.UUID {
+	if id := middleware.GetUserID(c); id != uuid.Nil {
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeBanRepo struct {
+	models.BanRepositoryInterface
+	created []models.Ban
+	audit   []models.BanAudit
+}
+
+func (f *fakeBanRepo) Create(b *models.Ban) error {
+	for _, x := range f.created {
+		if x.Kind == b.Kind && x.Value == b.Value {
+			return errors.New(`pq: duplicate key value violates unique constraint "bans_kind_value_key"`)
+		}
+	}
+	f.created = append(f.created, *b)
+	return nil
+}
+
+func (f *fakeBanRepo) AddAudit(a *models.BanAudit) error {
+	f.audit = append(f.audit, *a)
+	return nil
+}
+
+func TestCreateBan(t *testing.T) {
+	app := fiber.New()
+	bans := &fakeBanRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithBans(bans)
+	app.Post("/bans", h.CreateBan)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/bans", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := post(`{"kind":"ip","value":"203.0.113.7/24","reason":"spam"}`); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if len(bans.created) != 1 || bans.created[0].Value != "203.0.113.0/24" {
+		t.Fatalf("expected normalized range, got %+v", bans.created)
+	}
+	if len(bans.audit) != 1 || bans.audit[0].Action != models.BanActionCreated {
+		t.Fatalf("expected a created audit entry, got %+v", bans.audit)
+	}
+	if code := post(`{"kind":"ip","value":"203.0.113.0/24"}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate, got %d", code)
+	}
+	if code := post(`{"kind":"email_domain","value":"not a domain"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid domain, got %d", code)
+	}
+	if code := post(`{"kind":"user","value":"x"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown kind, got %d", code)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithBlocks enables blocking users from their profiles.
+func (h *UserHandler) WithBlocks(r models.BlockRepositoryInterface) *UserHandler {
+	h.blocks = r
+	return h
+}
+
+// WithBlocks stops blocked users from collecting their blocker's images.
+func (h *ImageHandler) WithBlocks(r models.BlockRepositoryInterface) *ImageHandler {
+	h.blocks = r
+	return h
+}
+
+// BlockUser blocks the user named by :username for the caller. Their images leave the
+// caller's feeds and they can no longer collect the caller's images.
+func (h *UserHandler) BlockUser(c *fiber.Ctx) error {
+	return h.setBlocked(c, true)
+}
+
+// UnblockUser lifts a block.
+func (h *UserHandler) UnblockUser(c *fiber.Ctx) error {
+	return h.setBlocked(c, false)
+}
+
+func (h *UserHandler) setBlocked(c *fiber.Ctx, block bool) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.blocks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Blocking not configured"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	target, err := h.findUser(ctx, normalizeUsername(c.Params("username")))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if target.ID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot block yourself"})
+	}
+	if !block {
+		if err := h.blocks.Unblock(userID, target.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unblock user"})
+		}
+		return c.JSON(fiber.Map{"blocked": false})
+	}
+	if already, err := h.blocks.IsBlocked(userID, target.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to block user"})
+	} else if !already {
+		if n, err := h.blocks.Count(userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to block user"})
+		} else if n >= models.MaxBlocks {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You have blocked too many users"})
+		}
+	}
+	if err := h.blocks.Block(userID, target.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to block user"})
+	}
+	return c.JSON(fiber.Map{"blocked": true})
+}
+
+// ListMyBlocks returns the users the caller blocked, most recent first.
+func (h *UserHandler) ListMyBlocks(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.blocks == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Blocking not configured"})
+	}
+	list, err := h.blocks.ListBlocked(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch blocked users"})
+	}
+	return c.JSON(fiber.Map{"blocks": list})
+}
+
+// blockedBy reports whether owner blocked userID. Lookup failures are logged and let the
+// action through.
+func (h *ImageHandler) blockedBy(c *fiber.Ctx, owner, userID uuid.UUID) bool {
+	if h.blocks == nil {
+		return false
+	}
+	blocked, err := h.blocks.IsBlocked(owner, userID)
+	if err != nil {
+		services.Logger(c.Context()).Warn("blocks: lookup failed", "owner", owner.String(), "user_id", userID.String(), "error", err)
+		return false
+	}
+	return blocked
+}
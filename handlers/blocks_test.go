@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeBlockRepo struct {
+	models.BlockRepositoryInterface
+	blocked map[[2]uuid.UUID]bool
+}
+
+func (f *fakeBlockRepo) Block(blocker, blocked uuid.UUID) error {
+	f.blocked[[2]uuid.UUID{blocker, blocked}] = true
+	return nil
+}
+
+func (f *fakeBlockRepo) IsBlocked(blocker, blocked uuid.UUID) (bool, error) {
+	return f.blocked[[2]uuid.UUID{blocker, blocked}], nil
+}
+
+func (f *fakeBlockRepo) Count(blocker uuid.UUID) (int, error) {
+	n := 0
+	for k := range f.blocked {
+		if k[0] == blocker {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type noCollectRepo struct {
+	models.CollectRepositoryInterface
+}
+
+func (noCollectRepo) GetByUser(_, _ uuid.UUID) (*models.Collect, error) { return nil, nil }
+
+func TestBlockUser(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	blocks := &fakeBlockRepo{blocked: map[[2]uuid.UUID]bool{}}
+	users := &profileUserRepo{user: &models.User{ID: bob, Username: "bob"}}
+	h := NewUserHandler(users, &fakeImageRepo{}, nil).WithBlocks(blocks)
+
+	caller := alice
+	app := fiber.New()
+	app.Post("/users/:username/block", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.BlockUser)
+	block := func(as uuid.UUID, username string) int {
+		caller = as
+		resp, _ := app.Test(httptest.NewRequest(http.MethodPost, "/users/"+username+"/block", nil))
+		return resp.StatusCode
+	}
+
+	if code := block(bob, "bob"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 blocking yourself, got %d", code)
+	}
+	if code := block(alice, "carol"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d", code)
+	}
+	if code := block(alice, "bob"); code != http.StatusOK || !blocks.blocked[[2]uuid.UUID{alice, bob}] {
+		t.Fatalf("expected bob blocked, got %d", code)
+	}
+}
+
+func TestCollectImage_Blocked(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	imageID := uuid.New()
+	images := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		imageID: {Image: models.Image{ID: imageID, UserID: alice, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+	}}
+	blocks := &fakeBlockRepo{blocked: map[[2]uuid.UUID]bool{{alice, bob}: true}}
+	h := NewImageHandler(images, nil, &fakeUserRepo{}, services.Config{}, nil).WithCollect(noCollectRepo{}).WithBlocks(blocks)
+
+	app := fiber.New()
+	app.Post("/images/:id/collect", func(c *fiber.Ctx) error {
+		c.Locals("user_id", bob)
+		return c.Next()
+	}, h.CollectImage)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodPost, "/images/"+imageID.String()+"/collect", nil))
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 collecting a blocker's image, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamFeedEvents_HidesBlocked(t *testing.T) {
+	blocked, other := uuid.New(), uuid.New()
+	events := make(chan services.LiveEvent, 2)
+	events <- services.LiveEvent{Type: "image_created", Data: fiber.Map{"id": "a", "user_id": blocked}}
+	events <- services.LiveEvent{Type: "image_created", Data: fiber.Map{"id": "b", "user_id": other}}
+	close(events)
+
+	var buf bytes.Buffer
+	filter := feedStreamFilter{FeedFilter: models.FeedFilter{DisplayPrefs: models.DisplayPrefs{Explicit: models.DisplayShow}}, blocked: map[uuid.UUID]bool{blocked: true}}
+	streamFeedEvents(bufio.NewWriter(&buf), events, filter, time.Minute, time.Minute, func() {})
+	out := buf.String()
+	if strings.Contains(out, `"id":"a"`) || !strings.Contains(out, `"id":"b"`) {
+		t.Fatalf("expected only the unblocked image, got %q", out)
+	}
+}
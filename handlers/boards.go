@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	maxBoardName        = 80
+	maxBoardDescription = 500
+	// maxBoardReorder bounds how many ids one reorder request may list.
+	maxBoardReorder = 1000
+)
+
+// WithBoards enables named collections. Collecting without a board uses the default one.
+func (h *ImageHandler) WithBoards(r models.BoardRepositoryInterface) *ImageHandler {
+	h.boards = r
+	return h
+}
+
+// WithBoards lists a user's boards on their profile.
+func (h *UserHandler) WithBoards(r models.BoardRepositoryInterface) *UserHandler {
+	h.boards = r
+	return h
+}
+
+type boardRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// apply validates the request and copies it onto b.
+func (req boardRequest) apply(b *models.Board) string {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxBoardName {
+			return "Board name must be 1-80 characters"
+		}
+		b.Name = name
+	}
+	if req.Description != nil {
+		desc := strings.TrimSpace(*req.Description)
+		if utf8.RuneCountInString(desc) > maxBoardDescription {
+			return "Board description must be at most 500 characters"
+		}
+		b.Description = desc
+	}
+	if req.IsPublic != nil {
+		b.IsPublic = *req.IsPublic
+	}
+	return ""
+}
+
+type boardOrderRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// GetUserBoards lists a user's boards in their order. The owner also sees private ones.
+func (h *UserHandler) GetUserBoards(c *fiber.Ctx) error {
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	username := normalizeUsername(c.Params("username"))
+	if username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username required"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	user, err := h.findUser(ctx, username)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	boards, err := h.boards.ListForUser(user.ID, middleware.OptionalUserID(c) == user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch boards"})
+	}
+	return c.JSON(fiber.Map{"boards": boards})
+}
+
+// GetBoard returns a board with a page of its images. Private boards are only found by
+// their owner.
+func (h *ImageHandler) GetBoard(c *fiber.Ctx) error {
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	viewer := middleware.OptionalUserID(c)
+	b, status, msg := h.findBoard(c, viewer, false)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	images, total, err := h.boards.Images(b.ID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch board"})
+	}
+	viewerDisplay(c, h.userRepo, viewer).Annotate(images)
+	return c.JSON(fiber.Map{"board": b, "images": images, "page": page, "total": total})
+}
+
+// ListMyBoards lists the caller's boards, creating the default one on first use. With
+// ?image_id= it also returns which of them hold that image, for an "add to board" picker.
+func (h *ImageHandler) ListMyBoards(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	if _, err := h.boards.EnsureDefault(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch boards"})
+	}
+	boards, err := h.boards.ListForUser(userID, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch boards"})
+	}
+	out := fiber.Map{"boards": boards}
+	if q := c.Query("image_id"); q != "" {
+		imageID, err := uuid.Parse(q)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image ID"})
+		}
+		ids, err := h.boards.BoardsWithImage(userID, imageID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch boards"})
+		}
+		out["board_ids"] = ids
+	}
+	return c.JSON(out)
+}
+
+// CreateBoard adds a board after the caller's others. Boards are public unless
+// is_public is false.
+func (h *ImageHandler) CreateBoard(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	var req boardRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.Name == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Board name must be 1-80 characters"})
+	}
+	b := &models.Board{UserID: userID, IsPublic: true}
+	if msg := req.apply(b); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.boards.Create(b); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create board"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(b)
+}
+
+// UpdateBoard renames a board, changes its description, or makes it public or private.
+func (h *ImageHandler) UpdateBoard(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	b, status, msg := h.findBoard(c, userID, true)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	var req boardRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if msg := req.apply(b); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.boards.Update(b); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update board"})
+	}
+	return c.JSON(b)
+}
+
+// DeleteBoard removes one of the caller's boards. Its images stay collected if another
+// of their boards holds them. The default board cannot be deleted.
+func (h *ImageHandler) DeleteBoard(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	b, status, msg := h.findBoard(c, userID, true)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.boards.Delete(b.ID); err != nil {
+		if errors.Is(err, models.ErrDefaultBoard) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The default board cannot be deleted"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete board"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ReorderBoards sets the order of the caller's boards; boards not listed follow.
+func (h *ImageHandler) ReorderBoards(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	var req boardOrderRequest
+	if err := c.BodyParser(&req); err != nil || len(req.IDs) == 0 || len(req.IDs) > maxBoardReorder {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.boards.Reorder(userID, req.IDs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reorder boards"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AddBoardImage puts an image at the top of one of the caller's boards, collecting it if
+// it was on none of them. The same rules as collecting apply.
+func (h *ImageHandler) AddBoardImage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	b, status, msg := h.findBoard(c, userID, true)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	var req struct {
+		ImageID uuid.UUID `json:"image_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.ImageID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image ID"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, req.ImageID)
+	if err != nil || img == nil || img.IsWithheld() || img.IsPrivate() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if img.UserID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot collect your own image"})
+	}
+	if h.blockedBy(c, img.UserID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot collect this user's images"})
+	}
+	added, err := h.boards.AddImage(b.ID, img.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add image to board"})
+	}
+	if added {
+		services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationCollected, ActorID: &userID, ImageID: &img.ID})
+		if h.collectRepo != nil {
+			h.publishCollectedCount(img)
+		}
+	}
+	return h.boardMembership(c, userID, img.ID)
+}
+
+// RemoveBoardImage takes an image off one of the caller's boards; it stays collected while
+// another of their boards holds it.
+func (h *ImageHandler) RemoveBoardImage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	b, status, msg := h.findBoard(c, userID, true)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	imageID, err := uuid.Parse(c.Params("imageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image ID"})
+	}
+	removed, err := h.boards.RemoveImage(b.ID, imageID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove image from board"})
+	}
+	if removed && h.collectRepo != nil {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if img, err := h.imageRepo.GetByID(ctx, imageID); err == nil && img != nil {
+			h.publishCollectedCount(img)
+		}
+	}
+	return h.boardMembership(c, userID, imageID)
+}
+
+// ReorderBoardImages sets the order of images on one of the caller's boards; images not
+// listed follow.
+func (h *ImageHandler) ReorderBoardImages(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.boards == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Boards not configured"})
+	}
+	b, status, msg := h.findBoard(c, userID, true)
+	if b == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	var req boardOrderRequest
+	if err := c.BodyParser(&req); err != nil || len(req.IDs) == 0 || len(req.IDs) > maxBoardReorder {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.boards.ReorderImages(b.ID, req.IDs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reorder board"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findBoard loads the board named by :id. Other users' private boards, and with own any
+// board not belonging to viewer, are reported as not found.
+func (h *ImageHandler) findBoard(c *fiber.Ctx, viewer uuid.UUID, own bool) (*models.Board, int, string) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid board ID"
+	}
+	b, err := h.boards.Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fiber.StatusNotFound, "Board not found"
+		}
+		return nil, fiber.StatusInternalServerError, "Failed to fetch board"
+	}
+	if b.UserID != viewer && (own || !b.IsPublic) {
+		return nil, fiber.StatusNotFound, "Board not found"
+	}
+	return b, 0, ""
+}
+
+// boardMembership answers a board change with the caller's boards that now hold the image.
+func (h *ImageHandler) boardMembership(c *fiber.Ctx, userID, imageID uuid.UUID) error {
+	ids, err := h.boards.BoardsWithImage(userID, imageID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch boards"})
+	}
+	return c.JSON(fiber.Map{"collected": len(ids) > 0, "board_ids": ids})
+}
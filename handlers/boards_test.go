@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeBoardRepo struct {
+	models.BoardRepositoryInterface
+	boards map[uuid.UUID]*models.Board
+	items  map[uuid.UUID][]uuid.UUID
+}
+
+func (f *fakeBoardRepo) Get(id uuid.UUID) (*models.Board, error) {
+	if b, ok := f.boards[id]; ok {
+		cp := *b
+		return &cp, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeBoardRepo) Create(b *models.Board) error {
+	b.ID = uuid.New()
+	cp := *b
+	f.boards[b.ID] = &cp
+	return nil
+}
+
+func (f *fakeBoardRepo) Delete(id uuid.UUID) error {
+	if f.boards[id].IsDefault {
+		return models.ErrDefaultBoard
+	}
+	delete(f.boards, id)
+	return nil
+}
+
+func (f *fakeBoardRepo) AddImage(boardID, imageID uuid.UUID) (bool, error) {
+	owner := f.boards[boardID].UserID
+	ids, _ := f.BoardsWithImage(owner, imageID)
+	f.items[boardID] = append(f.items[boardID], imageID)
+	return len(ids) == 0, nil
+}
+
+func (f *fakeBoardRepo) BoardsWithImage(userID, imageID uuid.UUID) ([]uuid.UUID, error) {
+	out := []uuid.UUID{}
+	for id, images := range f.items {
+		for _, img := range images {
+			if img == imageID && f.boards[id].UserID == userID {
+				out = append(out, id)
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestBoards(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	defaultID, privateID := uuid.New(), uuid.New()
+	boards := &fakeBoardRepo{
+		boards: map[uuid.UUID]*models.Board{
+			defaultID: {ID: defaultID, UserID: alice, Name: models.DefaultBoardName, IsDefault: true, IsPublic: true},
+			privateID: {ID: privateID, UserID: alice, Name: "Drafts"},
+		},
+		items: map[uuid.UUID][]uuid.UUID{},
+	}
+	bobsImage, ownImage := uuid.New(), uuid.New()
+	images := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		bobsImage: {Image: models.Image{ID: bobsImage, UserID: bob, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+		ownImage:  {Image: models.Image{ID: ownImage, UserID: alice, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+	}}
+	h := NewImageHandler(images, nil, &fakeUserRepo{}, services.Config{}, nil).WithBoards(boards)
+
+	caller := alice
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	})
+	app.Post("/me/boards", h.CreateBoard)
+	app.Delete("/me/boards/:id", h.DeleteBoard)
+	app.Post("/me/boards/:id/images", h.AddBoardImage)
+	do := func(as uuid.UUID, method, path, body string) (int, map[string]json.RawMessage) {
+		caller = as
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		var out map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if code, _ := do(alice, http.MethodPost, "/me/boards", `{"name":"   "}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a blank name, got %d", code)
+	}
+	code, out := do(alice, http.MethodPost, "/me/boards", `{"name":" Skies ","is_public":false}`)
+	if code != http.StatusCreated || string(out["name"]) != `"Skies"` || string(out["is_public"]) != "false" {
+		t.Fatalf("unexpected create: %d %v", code, out)
+	}
+
+	if code, _ := do(alice, http.MethodDelete, "/me/boards/"+defaultID.String(), ""); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 deleting the default board, got %d", code)
+	}
+	if code, _ := do(bob, http.MethodDelete, "/me/boards/"+privateID.String(), ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting someone else's board, got %d", code)
+	}
+
+	add := func(board, image uuid.UUID) (int, map[string]json.RawMessage) {
+		return do(alice, http.MethodPost, "/me/boards/"+board.String()+"/images", `{"image_id":"`+image.String()+`"}`)
+	}
+	if code, _ := add(defaultID, ownImage); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 collecting an own image, got %d", code)
+	}
+	if code, out := add(defaultID, bobsImage); code != http.StatusOK || string(out["collected"]) != "true" {
+		t.Fatalf("unexpected add: %d %v", code, out)
+	}
+	code, out = add(privateID, bobsImage)
+	var ids []uuid.UUID
+	if err := json.Unmarshal(out["board_ids"], &ids); code != http.StatusOK || err != nil || len(ids) != 2 {
+		t.Fatalf("expected the image on both boards: %d %s", code, out["board_ids"])
+	}
+}
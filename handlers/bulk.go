@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// bulkRequest is the body of the bulk admin endpoints. IsNSFW is the value set_nsfw sets.
+type bulkRequest struct {
+	IDs    []string `json:"ids"`
+	Action string   `json:"action"`
+	IsNSFW *bool    `json:"is_nsfw"`
+}
+
+// bulkBatch holds one result per distinct requested id, in request order, and the ids
+// still to be acted on.
+type bulkBatch struct {
+	results []models.BulkResult
+	index   map[uuid.UUID]int
+	pending []uuid.UUID
+}
+
+// checkBulkIDs returns why ids cannot be taken as a batch, or "".
+func checkBulkIDs(ids []string) string {
+	if len(ids) == 0 {
+		return "ids required"
+	}
+	if len(ids) > models.MaxBulkIDs {
+		return fmt.Sprintf("At most %d ids per request", models.MaxBulkIDs)
+	}
+	return ""
+}
+
+// newBulkBatch starts a batch over ids; unparsable ids fail at once.
+func newBulkBatch(ids []string) *bulkBatch {
+	b := &bulkBatch{results: []models.BulkResult{}, index: map[uuid.UUID]int{}}
+	seen := map[string]bool{}
+	for _, raw := range ids {
+		raw = strings.TrimSpace(raw)
+		if seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			b.results = append(b.results, models.BulkResult{ID: raw, Error: "Invalid id"})
+			continue
+		}
+		b.index[id] = len(b.results)
+		b.results = append(b.results, models.BulkResult{ID: id.String()})
+		b.pending = append(b.pending, id)
+	}
+	return b
+}
+
+// fail records why id was skipped and drops it from the pending ids.
+func (b *bulkBatch) fail(id uuid.UUID, msg string) {
+	b.results[b.index[id]].Error = msg
+	for i, p := range b.pending {
+		if p == id {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// settle marks the pending ids in changed as done and the rest with missing, which is
+// what a pending id that matched no row means.
+func (b *bulkBatch) settle(changed []uuid.UUID, missing string) {
+	done := make(map[uuid.UUID]bool, len(changed))
+	for _, id := range changed {
+		done[id] = true
+	}
+	for _, id := range b.pending {
+		if done[id] {
+			b.results[b.index[id]].OK = true
+		} else {
+			b.results[b.index[id]].Error = missing
+		}
+	}
+}
+
+// bulkResponse is the reply of the bulk admin endpoints.
+type bulkResponse struct {
+	Action    string              `json:"action"`
+	Results   []models.BulkResult `json:"results"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
+
+func (b *bulkBatch) respond(c *fiber.Ctx, action string) error {
+	out := bulkResponse{Action: action, Results: b.results}
+	for _, r := range b.results {
+		if r.OK {
+			out.Succeeded++
+		}
+	}
+	out.Failed = len(b.results) - out.Succeeded
+	return c.JSON(out)
+}
+
+// AdminBulkUsers disables, enables, deletes or verifies the email of many users at once.
+// The changes run in one transaction; ids that cannot be acted on, such as the default
+// admin or the caller for disable and delete, are reported per item and skipped.
+func (h *UserHandler) AdminBulkUsers(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var req bulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	if !models.IsBulkUserAction(req.Action) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown action"})
+	}
+	if msg := checkBulkIDs(req.IDs); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	batch := newBulkBatch(req.IDs)
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+	if len(batch.pending) > 0 {
+		targets, err := h.userRepo.GetByIDs(ctx, batch.pending)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load users"})
+		}
+		found := make(map[uuid.UUID]models.User, len(targets))
+		for _, u := range targets {
+			found[u.ID] = u
+		}
+		self := middleware.GetUserID(c)
+		defaultAdmin := os.Getenv("ADMIN_EMAIL")
+		removes := req.Action == models.BulkUserDisable || req.Action == models.BulkUserDelete
+		for _, id := range append([]uuid.UUID(nil), batch.pending...) {
+			u, ok := found[id]
+			switch {
+			case !ok:
+				batch.fail(id, "User not found")
+			case removes && u.Email != "" && strings.EqualFold(u.Email, defaultAdmin):
+				batch.fail(id, "Default admin cannot be "+bulkUserPastTense(req.Action))
+			case removes && id == self:
+				batch.fail(id, "You cannot "+req.Action+" yourself")
+			}
+		}
+	}
+	if len(batch.pending) > 0 {
+		changed, err := h.userRepo.BulkApply(req.Action, batch.pending)
+		if err != nil {
+			services.Logger(c.Context()).Error("admin: bulk user action failed", "action", req.Action, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply bulk action"})
+		}
+		batch.settle(changed, "User not found")
+		if req.Action != models.BulkUserVerifyEmail && len(changed) > 0 {
+			services.InvalidateFeedCache(c.Context())
+		}
+		services.Logger(c.Context()).Info("admin: bulk user action", "action", req.Action, "changed", len(changed), "by", middleware.GetUserID(c).String())
+	}
+	return batch.respond(c, req.Action)
+}
+
+func bulkUserPastTense(action string) string {
+	if action == models.BulkUserDelete {
+		return "deleted"
+	}
+	return "disabled"
+}
+
+// AdminBulkImages deletes or sets the NSFW flag of many images at once. The changes run
+// in one transaction; deleted images' files are removed after it commits.
+func (h *UserHandler) AdminBulkImages(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var req bulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	switch req.Action {
+	case models.BulkImageDelete:
+	case models.BulkImageSetNSFW:
+		if req.IsNSFW == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "is_nsfw required"})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown action"})
+	}
+	if msg := checkBulkIDs(req.IDs); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	batch := newBulkBatch(req.IDs)
+	if len(batch.pending) == 0 {
+		return batch.respond(c, req.Action)
+	}
+	if req.Action == models.BulkImageSetNSFW {
+		changed, err := h.imageRepo.BulkSetNSFW(batch.pending, *req.IsNSFW)
+		if err != nil {
+			services.Logger(c.Context()).Error("admin: bulk image action failed", "action", req.Action, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply bulk action"})
+		}
+		batch.settle(changed, "Image not found")
+		for _, id := range changed {
+			purgeImageFromCDN(c, h.settingsRepo, &models.Image{ID: id}, false)
+		}
+		if len(changed) > 0 {
+			services.InvalidateFeedCache(c.Context())
+		}
+		return batch.respond(c, req.Action)
+	}
+	deleted, err := h.imageRepo.BulkDelete(batch.pending)
+	if err != nil {
+		services.Logger(c.Context()).Error("admin: bulk image action failed", "action", req.Action, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to apply bulk action"})
+	}
+	changed := make([]uuid.UUID, len(deleted))
+	files := &ImageHandler{storage: h.storage}
+	by := middleware.GetUserID(c)
+	for i := range deleted {
+		img := &deleted[i]
+		changed[i] = img.ID
+		files.removeStoredFiles(c.Context(), img)
+		purgeImageFromCDN(c, h.settingsRepo, img, true)
+		services.EmitWebhook(services.WebhookImageDeleted, map[string]interface{}{"id": img.ID, "user_id": img.UserID, "deleted_by": by, "moderation": true})
+	}
+	batch.settle(changed, "Image not found")
+	if len(changed) > 0 {
+		services.InvalidateFeedCache(c.Context())
+	}
+	services.Logger(c.Context()).Info("admin: bulk image delete", "deleted", len(changed), "by", by.String())
+	return batch.respond(c, req.Action)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type bulkUserRepo struct {
+	impersonationUserRepo
+	applied []uuid.UUID
+}
+
+func (f *bulkUserRepo) GetByIDs(_ context.Context, ids []uuid.UUID) ([]models.User, error) {
+	out := []models.User{}
+	for _, id := range ids {
+		if u, ok := f.users[id]; ok {
+			out = append(out, *u)
+		}
+	}
+	return out, nil
+}
+
+func (f *bulkUserRepo) BulkApply(action string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	f.applied = append(f.applied, ids...)
+	return ids, nil
+}
+
+func TestAdminBulkUsers(t *testing.T) {
+	t.Setenv("ADMIN_EMAIL", "root@example.com")
+	adminID, aliceID, bobID, ownerID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := &bulkUserRepo{impersonationUserRepo: impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID: {ID: adminID, Username: "ops", IsAdmin: true},
+		aliceID: {ID: aliceID, Username: "alice"},
+		bobID:   {ID: bobID, Username: "bob"},
+		ownerID: {ID: ownerID, Username: "root", Email: "root@example.com", IsAdmin: true},
+	}}}
+	h := NewUserHandler(users, &fakeImageRepo{}, nil)
+	app := fiber.New()
+	app.Post("/admin/users/bulk", func(c *fiber.Ctx) error {
+		c.Locals("user_id", adminID)
+		return c.Next()
+	}, h.AdminBulkUsers)
+	post := func(body string) (*http.Response, bulkResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/bulk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		var out bulkResponse
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	if resp, _ := post(`{"action":"promote","ids":["` + aliceID.String() + `"]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown action, got %d", resp.StatusCode)
+	}
+	if resp, _ := post(`{"action":"disable","ids":[]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without ids, got %d", resp.StatusCode)
+	}
+
+	missing := uuid.New()
+	body := `{"action":"delete","ids":["` + aliceID.String() + `","` + bobID.String() + `","` + ownerID.String() + `","` + adminID.String() + `","` + missing.String() + `","nope","` + aliceID.String() + `"]}`
+	resp, out := post(body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if len(out.Results) != 6 || out.Succeeded != 2 || out.Failed != 4 {
+		t.Fatalf("unexpected summary: %+v", out)
+	}
+	want := []bool{true, true, false, false, false, false}
+	for i, r := range out.Results {
+		if r.OK != want[i] {
+			t.Fatalf("result %d: %+v", i, r)
+		}
+	}
+	if out.Results[2].Error != "Default admin cannot be deleted" || out.Results[3].Error != "You cannot delete yourself" {
+		t.Fatalf("unexpected errors: %+v", out.Results)
+	}
+	if len(users.applied) != 2 {
+		t.Fatalf("expected only alice and bob to reach the repository, got %v", users.applied)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// cdnBase is the origin the CDN caches the request's site under: the site URL when set,
+// else the origin the request arrived on.
+func cdnBase(c *fiber.Ctx, repo models.SiteSettingsRepositoryInterface) string {
+	if repo != nil {
+		set := services.GetCachedSettings(repo)
+		if base := strings.TrimSpace(tenantSettings(c, &set).SiteURL); base != "" {
+			return base
+		}
+	}
+	return c.BaseURL()
+}
+
+// purgeImageFromCDN drops img's page and API resource from the CDN, and its files when
+// they are gone.
+func purgeImageFromCDN(c *fiber.Ctx, repo models.SiteSettingsRepositoryInterface, img *models.Image, files bool) {
+	if img == nil || !services.CDNPurgeEnabled() {
+		return
+	}
+	services.PurgeCDN(services.ImagePurgeURLs(cdnBase(c, repo), img, files)...)
+}
+
+// purgePagesFromCDN drops the pages at slugs from the CDN.
+func purgePagesFromCDN(c *fiber.Ctx, repo models.SiteSettingsRepositoryInterface, slugs ...string) {
+	if !services.CDNPurgeEnabled() {
+		return
+	}
+	base := cdnBase(c, repo)
+	var urls []string
+	seen := map[string]bool{}
+	for _, slug := range slugs {
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		urls = append(urls, services.PagePurgeURLs(base, slug)...)
+	}
+	services.PurgeCDN(urls...)
+}
+
+// cdnPageSlug is a page's current path, read before a change so the old URLs can be
+// purged. Every save records a revision, so the latest one holds it. It is empty when
+// purging is off.
+func cdnPageSlug(pages models.PageRepositoryInterface, id uuid.UUID) string {
+	if !services.CDNPurgeEnabled() {
+		return ""
+	}
+	revs, _, err := pages.ListRevisions(id, 1, 1)
+	if err != nil || len(revs) == 0 {
+		return ""
+	}
+	return revs[0].Slug
+}
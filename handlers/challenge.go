@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// challengeFields carries a challenge response alongside register/forgot-password bodies.
+type challengeFields struct {
+	ChallengeToken    string `json:"challenge_token"`
+	ChallengeSolution string `json:"challenge_solution"`
+}
+
+// challengeRequired reports whether this request must solve the configured challenge:
+// only addresses the progressive rate limiter has flagged are asked.
+func (h *AuthHandler) challengeRequired(c *fiber.Ctx, set models.SiteSettings) bool {
+	if set.ChallengeProvider == "" || h.progressiveRateLimiter == nil {
+		return false
+	}
+	return h.progressiveRateLimiter.Suspicious(c.Context(), services.ClientIP(c))
+}
+
+// challengeDescriptor tells the client what to solve. Proof-of-work challenges are issued
+// fresh each time, so a failed attempt comes back with a new one.
+func challengeDescriptor(set models.SiteSettings, required bool) fiber.Map {
+	out := fiber.Map{"required": required, "provider": set.ChallengeProvider}
+	if !required {
+		return out
+	}
+	switch set.ChallengeProvider {
+	case services.ChallengePoW:
+		out["pow"] = fiber.Map{"challenge": services.NewPoWChallenge(), "difficulty": services.PoWDifficulty}
+	case services.ChallengeHCaptcha, services.ChallengeTurnstile:
+		out["site_key"] = set.ChallengeSiteKey
+	}
+	return out
+}
+
+// challengeBlock verifies the challenge response in the body when one is required and
+// returns the 403 body to send when it is missing or wrong, nil to let the request through.
+func (h *AuthHandler) challengeBlock(c *fiber.Ctx) fiber.Map {
+	set := services.GetCachedSettings(h.settingsRepo)
+	if !h.challengeRequired(c, set) {
+		return nil
+	}
+	var f challengeFields
+	_ = c.BodyParser(&f)
+	err := services.VerifyChallenge(c.Context(), set, f.ChallengeToken, f.ChallengeSolution, services.ClientIP(c))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, services.ErrChallengeFailed) {
+		services.Logger(c.Context()).Warn("auth: challenge verification unavailable", "provider", set.ChallengeProvider, "error", err)
+	}
+	msg := "Please complete the challenge to continue"
+	if f.ChallengeToken != "" {
+		msg = "Challenge failed, please try again"
+	}
+	return fiber.Map{"error": msg, "challenge_required": true, "challenge": challengeDescriptor(set, true)}
+}
+
+// AuthChallenge lets the register and forgot-password forms ask up front whether they need
+// to show a challenge.
+func (h *AuthHandler) AuthChallenge(c *fiber.Ctx) error {
+	set := services.GetCachedSettings(h.settingsRepo)
+	return c.JSON(challengeDescriptor(set, h.challengeRequired(c, set)))
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type noEmailUserRepo struct{ models.UserRepositoryInterface }
+
+func (noEmailUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, errors.New("not found")
+}
+
+func solvePoW(challenge string, difficulty int) string {
+	for n := 0; ; n++ {
+		sum := sha256.Sum256([]byte(challenge + ":" + strconv.Itoa(n)))
+		zeros := 0
+		for _, b := range sum {
+			zeros += bits.LeadingZeros8(b)
+			if b != 0 {
+				break
+			}
+		}
+		if zeros >= difficulty {
+			return strconv.Itoa(n)
+		}
+	}
+}
+
+func TestForgotPasswordChallenge(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	services.UpdateCachedSettings(models.SiteSettings{ChallengeProvider: services.ChallengePoW})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+	prl := services.NewProgressiveRateLimiter(services.ProgressiveRateLimitConfig{LockoutThreshold: 10, ChallengeThreshold: 1},
+		services.RateLimitConfig{MaxEntries: 10, CleanupInterval: time.Minute, EntryTTL: time.Minute})
+	defer prl.Stop()
+	h := NewAuthHandler(noEmailUserRepo{}).WithProgressiveRateLimiter(prl)
+	app := fiber.New()
+	app.Post("/forgot", h.ForgotPassword)
+	app.Get("/challenge", h.AuthChallenge)
+	app.Post("/fail", func(c *fiber.Ctx) error { prl.RecordFailure(c.IP(), c); return nil })
+	post := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/forgot", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if code, _ := post(`{"email":"a@example.com"}`); code != http.StatusNoContent {
+		t.Fatalf("unflagged addresses should not be challenged, got %d", code)
+	}
+	_, _ = app.Test(httptest.NewRequest(http.MethodPost, "/fail", nil))
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/challenge", nil))
+	var desc map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&desc)
+	if desc["required"] != true || desc["provider"] != "pow" {
+		t.Fatalf("expected a required pow challenge, got %v", desc)
+	}
+
+	code, body := post(`{"email":"a@example.com"}`)
+	if code != http.StatusForbidden || body["challenge_required"] != true {
+		t.Fatalf("expected the challenge to be required, got %d %v", code, body)
+	}
+	pow := body["challenge"].(map[string]any)["pow"].(map[string]any)
+	ch := pow["challenge"].(string)
+	sol := solvePoW(ch, int(pow["difficulty"].(float64)))
+	payload, _ := json.Marshal(map[string]string{"email": "a@example.com", "challenge_token": ch, "challenge_solution": sol})
+	if code, _ := post(string(payload)); code != http.StatusNoContent {
+		t.Fatalf("a solved challenge should pass, got %d", code)
+	}
+	if code, _ := post(string(payload)); code != http.StatusForbidden {
+		t.Fatalf("a replayed solution should be refused, got %d", code)
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	maxChallengeTitle  = 120
+	maxChallengePrompt = 2000
+)
+
+// ChallengeHandler serves themed prompts: listings and leaderboards for everyone, entries
+// for signed-in users and management for admins.
+type ChallengeHandler struct {
+	challenges models.ChallengeRepositoryInterface
+	imageRepo  models.ImageRepositoryInterface
+	userRepo   models.UserRepositoryInterface
+}
+
+func NewChallengeHandler(challenges models.ChallengeRepositoryInterface, imageRepo models.ImageRepositoryInterface, userRepo models.UserRepositoryInterface) *ChallengeHandler {
+	return &ChallengeHandler{challenges: challenges, imageRepo: imageRepo, userRepo: userRepo}
+}
+
+type challengeRequest struct {
+	Title    *string    `json:"title"`
+	Prompt   *string    `json:"prompt"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// apply validates req and copies it onto ch.
+func (req challengeRequest) apply(ch *models.Challenge) error {
+	if req.Title != nil {
+		t := strings.TrimSpace(*req.Title)
+		if t == "" || utf8.RuneCountInString(t) > maxChallengeTitle {
+			return errors.New("title must be 1-120 characters")
+		}
+		ch.Title = t
+	}
+	if req.Prompt != nil {
+		p := strings.TrimSpace(*req.Prompt)
+		if p == "" || utf8.RuneCountInString(p) > maxChallengePrompt {
+			return errors.New("prompt must be 1-2000 characters")
+		}
+		ch.Prompt = p
+	}
+	if req.StartsAt != nil {
+		ch.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		ch.EndsAt = req.EndsAt.UTC()
+	}
+	if ch.Title == "" || ch.Prompt == "" {
+		return errors.New("title and prompt are required")
+	}
+	if !ch.EndsAt.After(ch.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// pageParams reads ?page= and ?limit= (default 20, up to 100).
+func pageParams(c *fiber.Ctx) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// ListChallenges pages through the challenges in one phase: ?status=current (default),
+// upcoming or past.
+func (h *ChallengeHandler) ListChallenges(c *fiber.Ctx) error {
+	if h.challenges == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Challenges not configured"})
+	}
+	status := strings.ToLower(strings.TrimSpace(c.Query("status", models.ChallengeCurrent)))
+	switch status {
+	case models.ChallengeCurrent, models.ChallengeUpcoming, models.ChallengePast:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status must be current, upcoming or past"})
+	}
+	page, limit := pageParams(c)
+	list, total, err := h.challenges.List(status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list challenges"})
+	}
+	return c.JSON(fiber.Map{"challenges": list, "page": page, "limit": limit, "total": total})
+}
+
+// GetChallenge returns one challenge.
+func (h *ChallengeHandler) GetChallenge(c *fiber.Ctx) error {
+	ch, status, msg := h.challenge(c)
+	if ch == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.JSON(ch)
+}
+
+// ChallengeLeaderboard pages through a challenge's entries, most collected first, each
+// with its rank and how the viewer's preferences display it.
+func (h *ChallengeHandler) ChallengeLeaderboard(c *fiber.Ctx) error {
+	ch, status, msg := h.challenge(c)
+	if ch == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	page, limit := pageParams(c)
+	entries, total, err := h.challenges.Leaderboard(ch.ID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch leaderboard"})
+	}
+	prefs := viewerDisplay(c, h.userRepo, middleware.OptionalUserID(c))
+	for i := range entries {
+		entries[i].Display = prefs.For(entries[i].EffectiveRating())
+	}
+	return c.JSON(fiber.Map{"challenge": ch, "entries": entries, "page": page, "limit": limit, "total": total})
+}
+
+// EnterChallenge enters one of the caller's public images into a current challenge. Each
+// user may enter up to MaxChallengeEntries images; entering an image twice is a no-op.
+func (h *ChallengeHandler) EnterChallenge(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	ch, status, msg := h.challenge(c)
+	if ch == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if ch.Status != models.ChallengeCurrent {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "This challenge is not open for entries"})
+	}
+	var body struct {
+		ImageID string `json:"image_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	imageID, err := uuid.Parse(strings.TrimSpace(body.ImageID))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imageID)
+	if err != nil || img == nil || (img.UserID != userID && !img.IsListed()) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if img.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only enter your own images"})
+	}
+	if img.IsWithheld() || !img.IsListed() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only public, approved images can be entered"})
+	}
+	n, err := h.challenges.CountEntries(ch.ID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enter challenge"})
+	}
+	if n >= models.MaxChallengeEntries {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You have entered the most images allowed in this challenge"})
+	}
+	added, err := h.challenges.Enter(ch.ID, imageID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enter challenge"})
+	}
+	if added {
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"entered": true})
+	}
+	return c.JSON(fiber.Map{"entered": true})
+}
+
+// WithdrawChallengeEntry takes an image out of a challenge. Its owner may withdraw it
+// until the challenge ends; moderators at any time.
+func (h *ChallengeHandler) WithdrawChallengeEntry(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	ch, status, msg := h.challenge(c)
+	if ch == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	imageID, err := uuid.Parse(c.Params("imageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imageID)
+	if err != nil || img == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if !isModerator(c, h.userRepo) {
+		if img.UserID != userID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+		}
+		if ch.Status == models.ChallengePast {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "This challenge has ended"})
+		}
+	}
+	removed, err := h.challenges.Withdraw(ch.ID, imageID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to withdraw entry"})
+	}
+	if !removed {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image is not entered in this challenge"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// challenge looks up the challenge named by :id, or returns the status and message to
+// answer with.
+func (h *ChallengeHandler) challenge(c *fiber.Ctx) (*models.Challenge, int, string) {
+	if h.challenges == nil {
+		return nil, fiber.StatusServiceUnavailable, "Challenges not configured"
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid id"
+	}
+	ch, err := h.challenges.Get(id)
+	if err != nil || ch == nil {
+		return nil, fiber.StatusNotFound, "Challenge not found"
+	}
+	return ch, 0, ""
+}
+
+// AdminListChallenges pages through every challenge, latest start first.
+func (h *ChallengeHandler) AdminListChallenges(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.challenges == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Challenges not configured"})
+	}
+	page, limit := pageParams(c)
+	list, total, err := h.challenges.List("", page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list challenges"})
+	}
+	return c.JSON(fiber.Map{"challenges": list, "page": page, "limit": limit, "total": total})
+}
+
+// AdminCreateChallenge schedules a challenge. It starts now unless starts_at is given;
+// ends_at is required.
+func (h *ChallengeHandler) AdminCreateChallenge(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.challenges == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Challenges not configured"})
+	}
+	var req challengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	ch := &models.Challenge{StartsAt: time.Now().UTC(), CreatedBy: actorID(c)}
+	if err := req.apply(ch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.challenges.Create(ch); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create challenge"})
+	}
+	services.Logger(c.Context()).Info("challenges: created", "id", ch.ID.String(), "admin_id", middleware.GetUserID(c).String())
+	return c.Status(fiber.StatusCreated).JSON(ch)
+}
+
+// AdminUpdateChallenge changes the fields present in the body.
+func (h *ChallengeHandler) AdminUpdateChallenge(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	ch, status, msg := h.challenge(c)
+	if ch == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	var req challengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.apply(ch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.challenges.Update(ch); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update challenge"})
+	}
+	return c.JSON(ch)
+}
+
+// AdminDeleteChallenge removes a challenge and its entries; the images stay.
+func (h *ChallengeHandler) AdminDeleteChallenge(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.challenges == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Challenges not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.challenges.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete challenge"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeChallengeRepo struct {
+	models.ChallengeRepositoryInterface
+	challenges map[uuid.UUID]*models.Challenge
+	entries    map[uuid.UUID][]uuid.UUID
+}
+
+func (f *fakeChallengeRepo) Get(id uuid.UUID) (*models.Challenge, error) {
+	ch, ok := f.challenges[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	ch.Status = ch.StatusAt(time.Now())
+	return ch, nil
+}
+
+func (f *fakeChallengeRepo) CountEntries(challengeID, _ uuid.UUID) (int, error) {
+	return len(f.entries[challengeID]), nil
+}
+
+func (f *fakeChallengeRepo) Enter(challengeID, imageID, _ uuid.UUID) (bool, error) {
+	for _, id := range f.entries[challengeID] {
+		if id == imageID {
+			return false, nil
+		}
+	}
+	f.entries[challengeID] = append(f.entries[challengeID], imageID)
+	return true, nil
+}
+
+func TestEnterChallenge(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	now := time.Now()
+	current, upcoming := uuid.New(), uuid.New()
+	challenges := &fakeChallengeRepo{
+		challenges: map[uuid.UUID]*models.Challenge{
+			current:  {ID: current, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+			upcoming: {ID: upcoming, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+		},
+		entries: map[uuid.UUID][]uuid.UUID{},
+	}
+	mine, private, theirs := uuid.New(), uuid.New(), uuid.New()
+	images := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		mine:    {Image: models.Image{ID: mine, UserID: alice, Visibility: models.ImageVisibilityPublic}},
+		private: {Image: models.Image{ID: private, UserID: alice, Visibility: models.ImageVisibilityPrivate}},
+		theirs:  {Image: models.Image{ID: theirs, UserID: bob, Visibility: models.ImageVisibilityPublic}},
+	}}
+	h := NewChallengeHandler(challenges, images, &fakeUserRepo{})
+	app := fiber.New()
+	app.Post("/challenges/:id/entries", func(c *fiber.Ctx) error {
+		c.Locals("user_id", alice)
+		return c.Next()
+	}, h.EnterChallenge)
+	enter := func(challenge, image uuid.UUID) int {
+		req := httptest.NewRequest(http.MethodPost, "/challenges/"+challenge.String()+"/entries", strings.NewReader(`{"image_id":"`+image.String()+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	for _, tc := range []struct {
+		name             string
+		challenge, image uuid.UUID
+		want             int
+	}{
+		{"unknown challenge", uuid.New(), mine, http.StatusNotFound},
+		{"not started", upcoming, mine, http.StatusBadRequest},
+		{"someone else's image", current, theirs, http.StatusForbidden},
+		{"private image", current, private, http.StatusBadRequest},
+		{"entered", current, mine, http.StatusCreated},
+		{"entered again", current, mine, http.StatusOK},
+	} {
+		if got := enter(tc.challenge, tc.image); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	challenges.entries[current] = make([]uuid.UUID, models.MaxChallengeEntries)
+	if got := enter(current, mine); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 past the entry limit, got %d", got)
+	}
+}
+
+func TestChallengeRequestApply(t *testing.T) {
+	title, prompt := "Neon", "Cities at night"
+	start := time.Now()
+	end := start.Add(-time.Hour)
+	ch := &models.Challenge{StartsAt: start}
+	if err := (challengeRequest{Title: &title, Prompt: &prompt}).apply(ch); err == nil {
+		t.Fatal("expected an error without ends_at")
+	}
+	if err := (challengeRequest{Title: &title, Prompt: &prompt, EndsAt: &end}).apply(ch); err == nil {
+		t.Fatal("expected an error when ends_at is before starts_at")
+	}
+	end = start.Add(7 * 24 * time.Hour)
+	if err := (challengeRequest{Title: &title, Prompt: &prompt, EndsAt: &end}).apply(ch); err != nil || ch.StatusAt(start.Add(time.Hour)) != models.ChallengeCurrent {
+		t.Fatalf("expected a current challenge, got %v %+v", err, ch)
+	}
+}
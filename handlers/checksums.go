@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// AdminVerifyChecksums queues a job re-hashing every stored image against the checksum
+// recorded on upload. Body (optional): {"fill_missing": bool} also records checksums for
+// images uploaded before they existed. Mismatches are in the job result and flagged on
+// the images.
+func (h *AdminHandler) AdminVerifyChecksums(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var opts services.ChecksumVerifyOptions
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&opts); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	st := h.storage
+	if st == nil {
+		st = services.GetCurrentStorage()
+	}
+	if st == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage not configured"})
+	}
+	if _, ok := st.(services.ObjectReader); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Storage does not support reading objects"})
+	}
+	if handled, err := h.enqueueAdminJob(c, services.JobChecksumVerify, opts, true); handled {
+		return err
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+}
+
+// AdminChecksumVerifyStatus reports the latest verification job; while it runs its result
+// holds the progress so far.
+func (h *AdminHandler) AdminChecksumVerifyStatus(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	list, _, err := h.jobs.List("", services.JobChecksumVerify, 1, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load verification status"})
+	}
+	if len(list) == 0 {
+		return c.JSON(fiber.Map{"job": nil, "running": false})
+	}
+	job := list[0]
+	running := job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning
+	return c.JSON(fiber.Map{"job": job, "running": running})
+}
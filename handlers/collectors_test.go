@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type collectorsRepo struct {
+	models.CollectRepositoryInterface
+	collectors []models.Collector
+}
+
+func (f *collectorsRepo) Collectors(_ uuid.UUID, page, limit int) ([]models.Collector, int, error) {
+	return f.collectors, len(f.collectors), nil
+}
+
+func TestListCollectors(t *testing.T) {
+	ownerID, otherID, imageID := uuid.New(), uuid.New(), uuid.New()
+	images := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		imageID: {Image: models.Image{ID: imageID, UserID: ownerID, CollectedCount: 1}},
+	}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		ownerID: {ID: ownerID, Username: "owner"},
+		otherID: {ID: otherID, Username: "other"},
+	}}
+	collects := &collectorsRepo{collectors: []models.Collector{{UserID: otherID, Username: "other"}}}
+	h := NewImageHandler(images, nil, users, services.Config{}, nil).WithCollect(collects)
+
+	var caller uuid.UUID
+	app := fiber.New()
+	app.Get("/images/:id/collectors", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.ListCollectors)
+	get := func(as uuid.UUID) *http.Response {
+		caller = as
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+imageID.String()+"/collectors", nil))
+		return resp
+	}
+
+	if resp := get(otherID); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for someone else's image, got %d", resp.StatusCode)
+	}
+	resp := get(ownerID)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for the uploader, got %d", resp.StatusCode)
+	}
+	var out struct {
+		Collectors []models.Collector `json:"collectors"`
+		Total      int                `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Total != 1 || out.Collectors[0].Username != "other" {
+		t.Fatalf("unexpected collectors: %+v %v", out, err)
+	}
+}
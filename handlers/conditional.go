@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// conditionalGet gives a response a strong ETag derived from parts and a Last-Modified
+// of modified, and reports whether the client's copy is still current, in which case
+// the caller answers 304 without building the body. Signed media links change with
+// their TTL window, so the window counts as a change too. Responses are marked
+// no-cache so clients revalidate rather than guess a freshness from Last-Modified.
+func conditionalGet(c *fiber.Ctx, modified time.Time, parts ...interface{}) bool {
+	if w := services.SignedURLWindowStart(time.Now()); w.After(modified) {
+		modified = w
+	}
+	h := sha256.New()
+	fmt.Fprint(h, modified.UnixNano())
+	for _, p := range parts {
+		fmt.Fprintf(h, "|%v", p)
+	}
+	tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	c.Set(fiber.HeaderETag, tag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if !modified.IsZero() {
+		c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+	// If-None-Match wins when both are sent
+	if inm := c.Get(fiber.HeaderIfNoneMatch); inm != "" {
+		return etagMatches(inm, tag)
+	}
+	if ims := c.Get(fiber.HeaderIfModifiedSince); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match list names tag, comparing weakly as
+// RFC 9110 requires for GET.
+func etagMatches(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified ends a conditional GET whose client copy is current.
+func notModified(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusNotModified)
+}
+
+// latestTime returns the later of a and b.
+func latestTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+func TestGetFeed_DisplayPerImage(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+	blurID, legacyID, thresholdID := uuid.New(), uuid.New(), uuid.New()
+	nsfwID, matureID, safeID := uuid.New(), uuid.New(), uuid.New()
+	repo := &viewerFeedRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		// Rows read without a rating fall back on the NSFW flag
+		nsfwID:   {Image: models.Image{ID: nsfwID, IsNSFW: true}},
+		matureID: {Image: models.Image{ID: matureID, IsNSFW: true, Rating: models.RatingMature}},
+		safeID:   {Image: models.Image{ID: safeID, Rating: models.RatingSafe}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		blurID: {ID: blurID, Username: "blur", NsfwPref: "blur"},
+		// An explicit nsfw_pref wins over the legacy flag
+		legacyID: {ID: legacyID, Username: "legacy", NsfwPref: "hide", ShowNSFW: true},
+		// Blurring a milder rating blurs the harsher ones too
+		thresholdID: {ID: thresholdID, Username: "threshold", NsfwPref: "show", ContentPrefs: models.ContentPrefs{Mature: models.DisplayBlur}},
+	}}
+	app := fiber.New()
+	app.Get("/feed", NewImageHandler(repo, nil, users, services.Config{}, nil).GetFeed)
+	displays := func(as uuid.UUID) map[uuid.UUID]string {
+		req := httptest.NewRequest(http.MethodGet, "/feed?page=2", http.NoBody)
+		if as != uuid.Nil {
+			token, err := middleware.GenerateToken(as, "viewer")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out models.FeedResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		got := map[uuid.UUID]string{}
+		for _, img := range out.Images {
+			got[img.ID] = string(img.Display)
+		}
+		return got
+	}
+
+	for _, tc := range []struct {
+		name               string
+		viewer             uuid.UUID
+		nsfw, mature, safe string
+	}{
+		{"anonymous", uuid.Nil, "hide", "hide", "show"},
+		{"blur", blurID, "blur", "blur", "show"},
+		{"legacy", legacyID, "hide", "hide", "show"},
+		{"threshold", thresholdID, "blur", "blur", "show"},
+	} {
+		got := displays(tc.viewer)
+		if got[nsfwID] != tc.nsfw || got[matureID] != tc.mature || got[safeID] != tc.safe {
+			t.Errorf("%s: expected nsfw=%s mature=%s safe=%s, got %v", tc.name, tc.nsfw, tc.mature, tc.safe, got)
+		}
+	}
+}
+
+func TestUpdateMyProfile_ContentPrefs(t *testing.T) {
+	userID := uuid.New()
+	users := &mutesUserRepo{user: &models.User{ID: userID, Username: "viewer"}}
+	app := fiber.New()
+	app.Patch("/me/profile", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, NewUserHandler(users, &fakeImageRepo{}, nil).UpdateMyProfile)
+	patch := func(body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/me/profile", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := patch(`{"nsfw_pref":"sometimes"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown nsfw_pref, got %d", code)
+	}
+	if code := patch(`{"content_prefs":{"suggestive":"maybe"}}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown display, got %d", code)
+	}
+	if code := patch(`{"content_prefs":{"suggestive":" Blur "}}`); code != http.StatusOK || users.user.ContentPrefs.Suggestive != models.DisplayBlur {
+		t.Fatalf("expected suggestive images blurred, got %d %+v", code, users.user.ContentPrefs)
+	}
+}
+
+type ratingImageRepo struct {
+	visibilityImageRepo
+	rating *models.ContentRating
+}
+
+func (f *ratingImageRepo) UpdateMeta(_ uuid.UUID, _, _ *string, rating *models.ContentRating) error {
+	f.rating = rating
+	return nil
+}
+
+func TestUpdateImage_Rating(t *testing.T) {
+	ownerID, modID, imageID := uuid.New(), uuid.New(), uuid.New()
+	images := &ratingImageRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		imageID: {Image: models.Image{ID: imageID, UserID: ownerID, Rating: models.RatingSuggestive}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{modID: {ID: modID, IsModerator: true}}}
+	h := NewImageHandler(images, nil, users, services.Config{}, nil)
+	caller := ownerID
+	app := fiber.New()
+	app.Patch("/images/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.UpdateImage)
+	patch := func(as uuid.UUID, body string) int {
+		caller = as
+		req := httptest.NewRequest(http.MethodPatch, "/images/"+imageID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := patch(ownerID, `{"rating":"spicy"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown rating, got %d", code)
+	}
+	if code := patch(ownerID, `{"is_nsfw":true}`); code != http.StatusOK || images.rating == nil || *images.rating != models.RatingExplicit {
+		t.Fatalf("expected flagging a suggestive image to make it explicit, got %d %v", code, images.rating)
+	}
+	if code := patch(modID, `{"rating":" Mature "}`); code != http.StatusOK || *images.rating != models.RatingMature {
+		t.Fatalf("expected a moderator to set mature, got %d %v", code, *images.rating)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// maxCSPReportBody bounds a violation report; browsers send well under 4 KB.
+const maxCSPReportBody = 16 << 10
+
+// WithCSPReports injects the CSP violation report store
+func (h *AdminHandler) WithCSPReports(r models.CSPReportRepositoryInterface) *AdminHandler {
+	h.cspReports = r
+	return h
+}
+
+// cspViolation is the legacy report-uri body ({"csp-report": {...}}).
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	BlockedURI         string `json:"blocked-uri"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	ScriptSample       string `json:"script-sample"`
+}
+
+// reportingAPIEntry is one entry of a Reporting API batch (application/reports+json).
+type reportingAPIEntry struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Sample             string `json:"sample"`
+	} `json:"body"`
+}
+
+// ReceiveCSPReport stores violation reports sent by browsers. It accepts both the
+// report-uri format and Reporting API batches, and always answers 204 to well-formed input.
+func (h *AdminHandler) ReceiveCSPReport(c *fiber.Ctx) error {
+	if h.cspReports == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	body := c.Body()
+	if len(body) > maxCSPReportBody {
+		return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+	}
+	reports, err := parseCSPReports(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report"})
+	}
+	ua := clipBytes(c.Get(fiber.HeaderUserAgent), 512)
+	for _, r := range reports {
+		r.UserAgent = ua
+		if err := h.cspReports.Record(r); err != nil {
+			services.Logger(c.Context()).Warn("csp report not stored", "error", err)
+			break
+		}
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func parseCSPReports(body []byte) ([]models.CSPReport, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch []reportingAPIEntry
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		var out []models.CSPReport
+		for _, e := range batch {
+			if e.Type != "csp-violation" {
+				continue
+			}
+			out = append(out, cspReport(e.Body.DocumentURL, e.Body.EffectiveDirective, e.Body.BlockedURL, e.Body.SourceFile, e.Body.LineNumber, e.Body.Sample))
+			// A batch may carry many reports; a handful is enough to diagnose a page
+			if len(out) == 10 {
+				break
+			}
+		}
+		return out, nil
+	}
+	var legacy struct {
+		Report cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	v := legacy.Report
+	directive := v.EffectiveDirective
+	if directive == "" {
+		directive = v.ViolatedDirective
+	}
+	return []models.CSPReport{cspReport(v.DocumentURI, directive, v.BlockedURI, v.SourceFile, v.LineNumber, v.ScriptSample)}, nil
+}
+
+func cspReport(document, directive, blocked, source string, line int, sample string) models.CSPReport {
+	return models.CSPReport{
+		DocumentURI: clipBytes(stripQuery(document), 1024),
+		// "script-src-elem 'self' ..." from older browsers: keep the directive name only
+		ViolatedDirective: clipBytes(strings.Fields(directive + " ")[0], 128),
+		BlockedURI:        clipBytes(stripQuery(blocked), 1024),
+		SourceFile:        clipBytes(stripQuery(source), 1024),
+		LineNumber:        line,
+		Sample:            clipBytes(sample, 256),
+	}
+}
+
+// stripQuery drops query strings and fragments, which may carry tokens and would keep
+// identical violations from folding together.
+func stripQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
+
+// clipBytes cuts s to at most n bytes without splitting a UTF-8 sequence.
+func clipBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// ListCSPReports returns stored violation reports, most recently seen first, along with the
+// policy currently sent.
+func (h *AdminHandler) ListCSPReports(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.cspReports == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "CSP reports not configured"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.cspReports.List(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list CSP reports", "details": err.Error()})
+	}
+	policy := services.CachedCSP(services.GetCachedSettings(h.settingsRepo))
+	return c.JSON(fiber.Map{"reports": list, "policy": policy, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// ClearCSPReports deletes every stored violation report.
+func (h *AdminHandler) ClearCSPReports(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.cspReports == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "CSP reports not configured"})
+	}
+	if err := h.cspReports.Clear(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear CSP reports"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeCSPReportRepo struct {
+	models.CSPReportRepositoryInterface
+	got []models.CSPReport
+}
+
+func (f *fakeCSPReportRepo) Record(r models.CSPReport) error {
+	f.got = append(f.got, r)
+	return nil
+}
+
+func TestReceiveCSPReport(t *testing.T) {
+	app := fiber.New()
+	repo := &fakeCSPReportRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithCSPReports(repo)
+	app.Post("/api/csp-report", h.ReceiveCSPReport)
+	post := func(contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/csp-report", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	legacy := `{"csp-report":{"document-uri":"https://site.example/img/1?token=secret","violated-directive":"script-src-elem 'self'","blocked-uri":"https://evil.example/x.js","line-number":3}}`
+	if code := post("application/csp-report", legacy); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	batch := `[{"type":"csp-violation","body":{"documentURL":"https://site.example/","effectiveDirective":"img-src","blockedURL":"data"}},{"type":"deprecation","body":{}}]`
+	if code := post("application/reports+json", batch); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if len(repo.got) != 2 {
+		t.Fatalf("expected 2 stored reports, got %d", len(repo.got))
+	}
+	if r := repo.got[0]; r.DocumentURI != "https://site.example/img/1" || r.ViolatedDirective != "script-src-elem" || r.LineNumber != 3 {
+		t.Errorf("unexpected legacy report %+v", r)
+	}
+	if r := repo.got[1]; r.ViolatedDirective != "img-src" || r.BlockedURI != "data" {
+		t.Errorf("unexpected batch report %+v", r)
+	}
+
+	if code := post("application/csp-report", "not json"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for garbage, got %d", code)
+	}
+	if code := post("application/csp-report", strings.Repeat(" ", maxCSPReportBody+1)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized body, got %d", code)
+	}
+}
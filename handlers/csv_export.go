@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// csvExportPage is how many rows a CSV export reads per query.
+const csvExportPage = 500
+
+// wantsCSV reports whether a list endpoint was asked for ?format=csv.
+func wantsCSV(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Query("format"), "csv")
+}
+
+// csvPageFunc returns the rows of one page of a list; pages count from 1.
+type csvPageFunc func(page, limit int) ([][]string, error)
+
+// sendCSV streams a whole list as a CSV download named name-<date>.csv: the header, then
+// the rows of every page fetch returns until a short one. The first page is read before
+// responding so a failing query is a 500 rather than an empty file; a later failure can
+// only cut the file short, and is logged.
+func sendCSV(c *fiber.Ctx, name string, header []string, fetch csvPageFunc) error {
+	first, err := fetch(1, csvExportPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export " + name})
+	}
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s.csv\"", name, time.Now().UTC().Format("20060102")))
+	c.Set("Cache-Control", "no-store")
+	logger := services.Logger(c.Context())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(header)
+		rows := first
+		for page := 1; ; page++ {
+			for _, row := range rows {
+				for i := range row {
+					row[i] = csvCell(row[i])
+				}
+				if err := cw.Write(row); err != nil {
+					return
+				}
+			}
+			cw.Flush()
+			if cw.Error() != nil || len(rows) < csvExportPage {
+				break
+			}
+			next, err := fetch(page+1, csvExportPage)
+			if err != nil {
+				logger.Error("admin: csv export failed", "list", name, "page", page+1, "error", err)
+				break
+			}
+			rows = next
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+// csvCell keeps spreadsheets from running a cell as a formula by prefixing a quote to
+// values that start like one. encoding/csv takes care of quoting.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func csvUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func csvInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+var userCSVHeader = []string{"id", "username", "email", "email_verified", "is_admin", "is_moderator", "is_disabled", "is_shadowbanned", "suspended_until", "invited_by", "created_at"}
+
+func userCSVRows(users []models.User) [][]string {
+	rows := make([][]string, len(users))
+	for i, u := range users {
+		rows[i] = []string{u.ID.String(), u.Username, u.Email, strconv.FormatBool(u.EmailVerified), strconv.FormatBool(u.IsAdmin),
+			strconv.FormatBool(u.IsModerator), strconv.FormatBool(u.IsDisabled), strconv.FormatBool(u.IsShadowbanned),
+			csvTime(u.SuspendedUntil), csvUUID(u.InvitedBy), csvTime(&u.CreatedAt)}
+	}
+	return rows
+}
+
+var inviteCSVHeader = []string{"id", "code", "note", "email", "uses", "max_uses", "expires_at", "created_by", "created_at", "last_used_at"}
+
+func inviteCSVRows(invites []models.Invite) [][]string {
+	rows := make([][]string, len(invites))
+	for i, inv := range invites {
+		rows[i] = []string{inv.ID.String(), inv.Code, inv.Note, inv.Email, strconv.Itoa(inv.Uses), csvInt(inv.MaxUses),
+			csvTime(inv.ExpiresAt), csvUUID(inv.CreatedBy), csvTime(&inv.CreatedAt), csvTime(inv.LastUsedAt)}
+	}
+	return rows
+}
+
+var auditCSVHeader = []string{"id", "created_at", "actor_id", "actor_username", "action", "target_user_id", "session_id", "ip", "detail"}
+
+func auditCSVRows(list []models.AdminAudit) [][]string {
+	rows := make([][]string, len(list))
+	for i, a := range list {
+		rows[i] = []string{a.ID.String(), csvTime(&a.CreatedAt), csvUUID(a.ActorID), csvString(a.ActorUsername), a.Action,
+			csvUUID(a.TargetUserID), csvUUID(a.SessionID), a.IP, a.Detail}
+	}
+	return rows
+}
+
+var imageCSVHeader = []string{"id", "user_id", "username", "original_name", "media_type", "file_size", "width", "height", "is_nsfw", "rating", "moderation_status", "visibility", "license", "ai_provider", "likes_count", "collected_count", "sha256", "tenant_id", "created_at"}
+
+func imageCSVRows(images []models.ImageWithUser) [][]string {
+	rows := make([][]string, len(images))
+	for i, img := range images {
+		mediaType := img.MediaType
+		if mediaType == "" {
+			mediaType = models.MediaTypeImage
+		}
+		rows[i] = []string{img.ID.String(), img.UserID.String(), img.Username, csvString(img.OriginalName), mediaType,
+			csvInt(img.FileSize), csvInt(img.Width), csvInt(img.Height), strconv.FormatBool(img.IsNSFW), string(img.EffectiveRating()), img.ModerationStatus,
+			img.Visibility, img.License, csvString(img.AIProvider), strconv.Itoa(img.LikesCount), strconv.Itoa(img.CollectedCount), csvString(img.SHA256),
+			csvUUID(img.TenantID), csvTime(&img.CreatedAt)}
+	}
+	return rows
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSendCSVStreamsAllPagesAndEscapes(t *testing.T) {
+	total := csvExportPage + 3
+	var pages []int
+	app := fiber.New()
+	app.Get("/export", func(c *fiber.Ctx) error {
+		return sendCSV(c, "things", []string{"n", "note"}, func(page, limit int) ([][]string, error) {
+			pages = append(pages, page)
+			rows := [][]string{}
+			for i := (page - 1) * limit; i < total && i < page*limit; i++ {
+				rows = append(rows, []string{strconv.Itoa(i), "plain"})
+			}
+			if page == 1 {
+				rows[0][1] = `=HYPERLINK("x")`
+				rows[1][1] = "a, \"quoted\"\nvalue"
+			}
+			return rows, nil
+		})
+	})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/export", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, `filename="things-`) {
+		t.Fatalf("unexpected disposition %q", cd)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != total+1 || records[0][0] != "n" {
+		t.Fatalf("expected header and %d rows, got %d records", total, len(records))
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected two page reads, got %v", pages)
+	}
+	if records[1][1] != `'=HYPERLINK("x")` {
+		t.Fatalf("formula not neutralized: %q", records[1][1])
+	}
+	if records[2][1] != "a, \"quoted\"\nvalue" {
+		t.Fatalf("value not round-tripped: %q", records[2][1])
+	}
+}
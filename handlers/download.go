@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// maxWatermarkSource bounds how much of a master is buffered to stamp a watermark.
+const maxWatermarkSource = 64 << 20
+
+// DownloadImage streams the stored master as an attachment named after the image title.
+// When the site enables download watermarks, everyone but the owner gets a stamped copy.
+func (h *ImageHandler) DownloadImage(c *fiber.Ctx) error {
+	imageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image ID"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imageID)
+	if err != nil || img == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	viewer := middleware.OptionalUserID(c)
+	isOwner := viewer != uuid.Nil && viewer == img.UserID
+	if (img.IsWithheld() && !h.canSeePending(c, img.UserID)) || (img.IsPrivate() && !isOwner) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+
+	rc, err := h.openMaster(c.Context(), img.Filename)
+	if err != nil {
+		services.Logger(c.Context()).Warn("download: master unavailable", "image_id", imageID.String(), "error", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Original file not available"})
+	}
+	key := extractStorageKey(img.Filename)
+	ext := strings.ToLower(path.Ext(key))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	set := services.GetCachedSettings(h.settingsRepo)
+	// Clips already play from their stored file, so there is nothing to protect by stamping them
+	if set.DownloadWatermarkEnabled && !isOwner && !img.IsVideo() {
+		defer rc.Close()
+		text := set.DownloadWatermarkText
+		if text == "" {
+			text = set.SiteName
+		}
+		if text == "" {
+			text = "TROUGH"
+		}
+		if img.Username != "" {
+			text += " @" + img.Username
+		}
+		out, outExt, err := watermarkMaster(io.LimitReader(rc, maxWatermarkSource), ext, text)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to prepare download"})
+		}
+		setAttachment(c, downloadName(img, outExt), outExt)
+		return c.Send(out)
+	}
+	setAttachment(c, downloadName(img, ext), ext)
+	// fasthttp closes the stream once the body has been written
+	return c.SendStream(rc)
+}
+
+// openMaster opens the stored file behind an image row. With remote storage, bare filenames
+// are legacy files still kept on the local mount.
+func (h *ImageHandler) openMaster(ctx context.Context, filename string) (io.ReadCloser, error) {
+	st := services.GetCurrentStorage()
+	if st == nil {
+		st = h.storage
+	}
+	local := services.NewLocalStorage(services.UploadsDir())
+	if st == nil {
+		st = local
+	}
+	key := extractStorageKey(filename)
+	if reader, ok := st.(services.ObjectReader); ok {
+		rc, err := reader.Open(ctx, key)
+		if err == nil || st.IsLocal() || strings.Contains(filename, "://") {
+			return rc, err
+		}
+	}
+	return local.Open(ctx, key)
+}
+
+// watermarkMaster stamps text onto the image; PNGs stay PNG, everything else becomes JPEG.
+// Animations are stamped as a still of their first frame.
+func watermarkMaster(r io.Reader, ext, text string) ([]byte, string, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	var src image.Image
+	if _, ok := services.InspectAnimation(raw); ok {
+		src, err = services.DecodeFirstFrame(raw)
+	} else {
+		src, _, err = image.Decode(bytes.NewReader(raw))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	stamped := services.Watermark(src, text)
+	var buf bytes.Buffer
+	if ext == ".png" {
+		err = png.Encode(&buf, stamped)
+		return buf.Bytes(), ".png", err
+	}
+	err = jpeg.Encode(&buf, stamped, &jpeg.Options{Quality: 92})
+	return buf.Bytes(), ".jpg", err
+}
+
+// downloadName builds a filename from the image title, falling back to the image id.
+func downloadName(img *models.ImageWithUser, ext string) string {
+	base := ""
+	if img.OriginalName != nil {
+		base = strings.TrimSpace(*img.OriginalName)
+		base = strings.TrimSuffix(base, path.Ext(base))
+	}
+	var b strings.Builder
+	for _, r := range base {
+		// Drop control characters and anything a filesystem or the header would choke on
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`"\/:*?<>|`, r) {
+			continue
+		}
+		b.WriteRune(r)
+		if b.Len() >= 120 {
+			break
+		}
+	}
+	name := strings.Trim(b.String(), " .")
+	if name == "" {
+		name = "trough-" + img.ID.String()[:8]
+	}
+	return name + ext
+}
+
+func setAttachment(c *fiber.Ctx, filename, ext string) {
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		c.Set(fiber.HeaderContentType, ct)
+	} else {
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	}
+	// FormatMediaType switches to RFC 2231 encoding for non-ASCII names
+	disp := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	if disp == "" {
+		disp = `attachment; filename="download` + ext + `"`
+	}
+	c.Set(fiber.HeaderContentDisposition, disp)
+}
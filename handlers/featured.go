@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// maxFeaturedNote caps the length of a curator's note, in characters.
+const maxFeaturedNote = 500
+
+// GetFeatured lists the site's featured picks, most recently featured first. ?limit=
+// takes up to 50 (default 12). Signed-in viewers' preferences and mutes apply as in
+// the feed.
+func (h *ImageHandler) GetFeatured(c *fiber.Ctx) error {
+	limit := 12
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 && v <= 50 {
+		limit = v
+	}
+	var filter models.FeedFilter
+	uid := middleware.OptionalUserID(c)
+	if uid != uuid.Nil {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if user, err := h.userRepo.GetByID(ctx, uid); err == nil {
+			filter = models.FeedFilterFor(user)
+		}
+	}
+	images, err := forViewer(tenantImages(c, h.imageRepo), uid).GetFeatured(limit, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch featured images"})
+	}
+	filter.Annotate(images)
+	return c.JSON(fiber.Map{"images": images})
+}
+
+// AdminFeatureImage features a public image on the front page with an optional
+// curator's note. Featuring it again replaces the note and moves it to the front.
+func (h *UserHandler) AdminFeatureImage(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	imgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	var b struct {
+		Note *string `json:"note"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&b); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	if b.Note != nil {
+		note := strings.TrimSpace(*b.Note)
+		if utf8.RuneCountInString(note) > maxFeaturedNote {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note must be at most 500 characters"})
+		}
+		b.Note = &note
+		if note == "" {
+			b.Note = nil
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imgID)
+	if err != nil || img == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	// Picks are shown to everyone, so only images everyone may see can be featured
+	if img.IsWithheld() || !img.IsListed() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only public, approved images can be featured"})
+	}
+	if err := h.imageRepo.Feature(imgID, b.Note); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to feature image"})
+	}
+	services.Logger(c.Context()).Info("featured: image featured", "image_id", imgID.String(), "by", middleware.GetUserID(c).String())
+	return c.JSON(fiber.Map{"featured": true, "note": b.Note})
+}
+
+// AdminUnfeatureImage takes an image out of the featured picks.
+func (h *UserHandler) AdminUnfeatureImage(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	imgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	if err := h.imageRepo.Unfeature(imgID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unfeature image"})
+	}
+	return c.JSON(fiber.Map{"featured": false})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type featuredImageRepo struct {
+	visibilityImageRepo
+}
+
+func (f *featuredImageRepo) Feature(id uuid.UUID, note *string) error {
+	now := time.Now()
+	f.images[id].FeaturedAt, f.images[id].FeaturedNote = &now, note
+	return nil
+}
+
+func TestAdminFeatureImage(t *testing.T) {
+	adminID, userID := uuid.New(), uuid.New()
+	publicID, privateID := uuid.New(), uuid.New()
+	images := &featuredImageRepo{visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		publicID:  {Image: models.Image{ID: publicID, Visibility: models.ImageVisibilityPublic}},
+		privateID: {Image: models.Image{ID: privateID, Visibility: models.ImageVisibilityPrivate}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID: {ID: adminID, Username: "root", IsAdmin: true},
+		userID:  {ID: userID, Username: "alice"},
+	}}
+	h := NewUserHandler(users, images, nil)
+	caller := adminID
+	app := fiber.New()
+	app.Put("/admin/images/:id/featured", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.AdminFeatureImage)
+	feature := func(as, id uuid.UUID, body string) int {
+		caller = as
+		req := httptest.NewRequest(http.MethodPut, "/admin/images/"+id.String()+"/featured", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := feature(userID, publicID, `{}`); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin, got %d", code)
+	}
+	if code := feature(adminID, privateID, `{}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 featuring a private image, got %d", code)
+	}
+	if code := feature(adminID, publicID, `{"note":"`+strings.Repeat("x", maxFeaturedNote+1)+`"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an overlong note, got %d", code)
+	}
+	if code := feature(adminID, publicID, `{"note":" Lovely light "}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if img := images.images[publicID]; img.FeaturedAt == nil || img.FeaturedNote == nil || *img.FeaturedNote != "Lovely light" {
+		t.Fatalf("expected the image featured with a trimmed note, got %+v", img.Image)
+	}
+}
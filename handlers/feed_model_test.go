@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type modelFeedRepo struct {
+	models.ImageRepositoryInterface
+	filter models.FeedFilter
+}
+
+func (f *modelFeedRepo) GetFeed(_, _ int, filter models.FeedFilter) ([]models.ImageWithUser, int, error) {
+	f.filter = filter
+	return []models.ImageWithUser{}, 0, nil
+}
+
+func TestGetFeed_ModelFilter(t *testing.T) {
+	repo := &modelFeedRepo{}
+	app := fiber.New()
+	app.Get("/feed", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, nil).GetFeed)
+	get := func(query string) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/feed?page=2"+query, http.NoBody))
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected response: %v %v", resp, err)
+		}
+	}
+
+	get("&model=flux%2Fflux1-dev.safetensors")
+	if repo.filter.Model != "flux1-dev" {
+		t.Fatalf("expected the model normalized like stored names, got %q", repo.filter.Model)
+	}
+	get("")
+	if repo.filter.Model != "" {
+		t.Fatalf("expected no model filter by default, got %q", repo.filter.Model)
+	}
+}
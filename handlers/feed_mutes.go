@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/trough/models"
+)
+
+// normalizeFeedMutes trims, lowercases and de-duplicates muted providers so feed queries
+// can match them exactly against lowercased image providers.
+func normalizeFeedMutes(m models.FeedMutes) (models.FeedMutes, error) {
+	out := models.FeedMutes{}
+	seen := map[string]bool{}
+	for _, p := range m.Providers {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" || seen[p] {
+			continue
+		}
+		if len(p) > 100 {
+			return out, errors.New("provider names must be at most 100 characters")
+		}
+		seen[p] = true
+		out.Providers = append(out.Providers, p)
+	}
+	if len(out.Providers) > models.MaxMutedProviders {
+		return out, fmt.Errorf("at most %d providers can be muted", models.MaxMutedProviders)
+	}
+	return out, nil
+}
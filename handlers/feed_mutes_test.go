@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type mutesUserRepo struct {
+	models.UserRepositoryInterface
+	user *models.User
+}
+
+func (f *mutesUserRepo) UpdateProfile(_ uuid.UUID, req models.UpdateUserRequest) (*models.User, error) {
+	if req.FeedMutes != nil {
+		f.user.FeedMutes = *req.FeedMutes
+	}
+	if req.ContentPrefs != nil {
+		f.user.ContentPrefs = *req.ContentPrefs
+	}
+	return f.user, nil
+}
+
+func TestUpdateMyProfile_FeedMutes(t *testing.T) {
+	userID := uuid.New()
+	users := &mutesUserRepo{user: &models.User{ID: userID, Username: "viewer"}}
+	app := fiber.New()
+	app.Patch("/me/profile", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, NewUserHandler(users, &fakeImageRepo{}, nil).UpdateMyProfile)
+	patch := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPatch, "/me/profile", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	resp := patch(`{"feed_mutes":{"providers":[" Midjourney ","midjourney","","FLUX"]}}`)
+	var out models.UserResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response: %d %v", resp.StatusCode, err)
+	}
+	if out.FeedMutes == nil || strings.Join(out.FeedMutes.Providers, ",") != "midjourney,flux" {
+		t.Fatalf("expected normalized mutes, got %+v", out.FeedMutes)
+	}
+
+	many := make([]string, models.MaxMutedProviders+1)
+	for i := range many {
+		many[i] = uuid.NewString()
+	}
+	b, _ := json.Marshal(map[string]any{"feed_mutes": map[string]any{"providers": many}})
+	if resp := patch(string(b)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many mutes, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamFeedEvents_HidesMutedProviders(t *testing.T) {
+	mj, flux := "Midjourney", "FLUX"
+	events := make(chan services.LiveEvent, 2)
+	events <- services.LiveEvent{Type: services.FeedEventImage, Data: fiber.Map{"id": "a", "ai_provider": &mj}}
+	events <- services.LiveEvent{Type: services.FeedEventImage, Data: fiber.Map{"id": "b", "ai_provider": &flux}}
+	close(events)
+
+	var buf bytes.Buffer
+	filter := feedStreamFilter{FeedFilter: models.FeedFilter{FeedMutes: models.FeedMutes{Providers: []string{"midjourney"}}}}
+	streamFeedEvents(bufio.NewWriter(&buf), events, filter, time.Minute, time.Minute, func() {})
+	out := buf.String()
+	if strings.Contains(out, `"id":"a"`) || !strings.Contains(out, `"id":"b"`) {
+		t.Fatalf("expected only the unmuted provider, got %q", out)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// Streams end after feedStreamLifetime so NSFW preferences and load balancing are
+// re-evaluated; EventSource reconnects on its own after the advertised retry delay.
+const (
+	feedStreamLifetime  = 30 * time.Minute
+	feedStreamHeartbeat = 25 * time.Second
+	feedStreamRetryMS   = 5000
+)
+
+// FeedStream is a server-sent events stream of new public images and collection count
+// changes, so the SPA can offer "N new images" without polling the feed.
+func (h *ImageHandler) FeedStream(c *fiber.Ctx) error {
+	filter := feedStreamFilter{tenant: middleware.TenantID(c)}
+	if uid := middleware.OptionalUserID(c); uid != uuid.Nil {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		if user, err := h.userRepo.GetByID(ctx, uid); err == nil {
+			filter.FeedFilter = models.FeedFilterFor(user)
+		}
+		cancel()
+		if h.blocks != nil {
+			if list, err := h.blocks.ListBlocked(uid); err == nil && len(list) > 0 {
+				filter.blocked = make(map[uuid.UUID]bool, len(list))
+				for _, b := range list {
+					filter.blocked[b.UserID] = true
+				}
+			}
+		}
+	}
+	sub, err := services.SubscribeFeed()
+	if err != nil {
+		c.Set(fiber.HeaderRetryAfter, "30")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Live updates are busy, try again shortly"})
+	}
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	// Ask nginx and similar proxies not to buffer the stream
+	c.Set("X-Accel-Buffering", "no")
+	conn := c.Context().Conn()
+	writeTimeout := h.config.Server.WriteTimeout
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Cancel()
+		// The server's write deadline covers the whole response; push it forward before each
+		// write so only a stalled client times out.
+		extend := func() {
+			if conn != nil && writeTimeout > 0 {
+				_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			}
+		}
+		streamFeedEvents(w, sub.C, filter, feedStreamLifetime, feedStreamHeartbeat, extend)
+	})
+	return nil
+}
+
+// feedStreamFilter is what one subscriber's stream leaves out: what their feed filter
+// hides, new images by users they blocked, and new images on other sites.
+type feedStreamFilter struct {
+	models.FeedFilter
+	blocked map[uuid.UUID]bool
+	// tenant is the site the stream was opened on; nil is the primary site
+	tenant *uuid.UUID
+}
+
+func (f feedStreamFilter) hides(ev services.LiveEvent) bool {
+	m, _ := ev.Data.(fiber.Map)
+	if ev.Type == services.FeedEventImage {
+		tenant, _ := m["tenant_id"].(*uuid.UUID)
+		if (tenant == nil) != (f.tenant == nil) || (tenant != nil && *tenant != *f.tenant) {
+			return true
+		}
+	}
+	provider, _ := m["ai_provider"].(*string)
+	rating, ok := m["rating"].(models.ContentRating)
+	if !ok {
+		rating = models.ContentRating("").WithNSFW(ev.NSFW)
+	}
+	if f.Hides(rating, provider) {
+		return true
+	}
+	owner, ok := m["user_id"].(uuid.UUID)
+	return ok && f.blocked[owner]
+}
+
+// streamFeedEvents writes events to w until the subscription closes, a write fails, the
+// lifetime runs out or the server shuts down.
+func streamFeedEvents(w *bufio.Writer, events <-chan services.LiveEvent, filter feedStreamFilter, lifetime, heartbeat time.Duration, beforeWrite func()) {
+	flush := func(s string) bool {
+		beforeWrite()
+		if _, err := w.WriteString(s); err != nil {
+			return false
+		}
+		return w.Flush() == nil
+	}
+	if !flush("retry: " + strconv.Itoa(feedStreamRetryMS) + "\n\n") {
+		return
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	end := time.NewTimer(lifetime)
+	defer end.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if filter.hides(ev) {
+				continue
+			}
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			if !flush("id: " + strconv.FormatUint(ev.ID, 10) + "\nevent: " + ev.Type + "\ndata: " + string(data) + "\n\n") {
+				return
+			}
+		case <-ticker.C:
+			// Comment lines keep idle proxies from closing the connection
+			if !flush(": ping\n\n") {
+				return
+			}
+		case <-end.C:
+			return
+		case <-services.ShuttingDown():
+			return
+		}
+	}
+}
+
+// publishImageCreated tells live feed subscribers about a newly listed image.
+func publishImageCreated(img *models.Image) {
+	services.PublishFeedEvent(services.FeedEventImage, img.IsNSFW, fiber.Map{
+		"id": img.ID, "user_id": img.UserID, "is_nsfw": img.IsNSFW, "rating": img.EffectiveRating(), "ai_provider": img.AIProvider, "ai_model": img.AIModel,
+		"tenant_id": img.TenantID, "created_at": img.CreatedAt,
+	})
+}
+
+// publishCollectedCount sends an image's new collection count to live feed subscribers.
+func (h *ImageHandler) publishCollectedCount(img *models.ImageWithUser) {
+	// Counting costs a query; skip it when nobody is listening
+	if !img.IsListed() || services.FeedSubscribers() == 0 {
+		return
+	}
+	n, err := h.collectRepo.CountForImage(img.ID)
+	if err != nil {
+		return
+	}
+	services.PublishFeedEvent(services.FeedEventCollected, img.IsNSFW, fiber.Map{"image_id": img.ID, "collected_count": n})
+}
+
+// publishUploadActivity tells admin monitors about a recorded upload, and about a new
+// moderation queue entry when it is held for review.
+func publishUploadActivity(img *models.Image) {
+	if services.AdminSubscribers() == 0 {
+		return
+	}
+	services.PublishAdminEvent(services.AdminEventUpload, fiber.Map{
+		"id": img.ID, "user_id": img.UserID, "is_nsfw": img.IsNSFW, "rating": img.EffectiveRating(), "visibility": img.Visibility,
+		"pending": img.IsPending(), "ai_provider": img.AIProvider, "created_at": img.CreatedAt,
+	})
+	if img.IsPending() {
+		services.PublishAdminEvent(services.AdminEventModeration, fiber.Map{"action": "queued", "image_id": img.ID, "user_id": img.UserID})
+	}
+}
+
+// publishModerationDecision tells admin monitors a queued image was approved or rejected.
+func publishModerationDecision(action string, imageID, moderatorID uuid.UUID) {
+	services.PublishAdminEvent(services.AdminEventModeration, fiber.Map{"action": action, "image_id": imageID, "moderator_id": moderatorID})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+func TestStreamFeedEvents(t *testing.T) {
+	events := make(chan services.LiveEvent, 4)
+	events <- services.LiveEvent{ID: 1, Type: services.FeedEventImage, NSFW: true, Data: map[string]string{"id": "nsfw"}}
+	events <- services.LiveEvent{ID: 2, Type: services.FeedEventImage, Data: map[string]string{"id": "sfw"}}
+	close(events)
+
+	var buf bytes.Buffer
+	writes := 0
+	streamFeedEvents(bufio.NewWriter(&buf), events, feedStreamFilter{}, time.Minute, time.Minute, func() { writes++ })
+	out := buf.String()
+	if !strings.HasPrefix(out, "retry: 5000\n\n") {
+		t.Fatalf("stream should open with a retry hint, got %q", out)
+	}
+	if strings.Contains(out, "nsfw") {
+		t.Error("NSFW events should be withheld from viewers hiding NSFW")
+	}
+	if !strings.Contains(out, "id: 2\nevent: image\ndata: {\"id\":\"sfw\"}\n\n") {
+		t.Errorf("missing image event in %q", out)
+	}
+	if writes != 2 {
+		t.Errorf("the write deadline should be extended before each write, got %d", writes)
+	}
+
+	// An idle stream sends heartbeats and ends when its lifetime runs out
+	buf.Reset()
+	streamFeedEvents(bufio.NewWriter(&buf), make(chan services.LiveEvent), feedStreamFilter{FeedFilter: models.FeedFilter{DisplayPrefs: models.DisplayPrefs{Explicit: models.DisplayShow}}}, 35*time.Millisecond, 10*time.Millisecond, func() {})
+	if !strings.Contains(buf.String(), ": ping\n\n") {
+		t.Errorf("expected heartbeats, got %q", buf.String())
+	}
+}
+
+func TestFeedStreamFilterTenant(t *testing.T) {
+	site := uuid.New()
+	ev := func(tenant *uuid.UUID) services.LiveEvent {
+		return services.LiveEvent{Type: services.FeedEventImage, Data: fiber.Map{"id": uuid.New(), "tenant_id": tenant}}
+	}
+	primary := feedStreamFilter{}
+	tenant := feedStreamFilter{tenant: &site}
+	other := uuid.New()
+	if primary.hides(ev(nil)) || tenant.hides(ev(&site)) {
+		t.Error("images on the stream's own site should be shown")
+	}
+	if !primary.hides(ev(&site)) || !tenant.hides(ev(nil)) || !tenant.hides(ev(&other)) {
+		t.Error("images on other sites should be withheld")
+	}
+}
+
+type streamUserRepo struct {
+	models.UserRepositoryInterface
+	users map[uuid.UUID]*models.User
+}
+
+func (f *streamUserRepo) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestEmitImageCreatedSkipsShadowbanned(t *testing.T) {
+	banned, visible := uuid.New(), uuid.New()
+	repo := &streamUserRepo{users: map[uuid.UUID]*models.User{
+		banned:  {ID: banned, IsShadowbanned: true},
+		visible: {ID: visible},
+	}}
+	h := NewImageHandler(nil, nil, repo, services.Config{}, nil)
+	sub, err := services.SubscribeFeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Cancel()
+
+	h.emitImageCreated(context.Background(), &models.Image{ID: uuid.New(), UserID: banned})
+	shown := &models.Image{ID: uuid.New(), UserID: visible}
+	h.emitImageCreated(context.Background(), shown)
+	select {
+	case ev := <-sub.C:
+		if id := ev.Data.(fiber.Map)["id"]; id != shown.ID {
+			t.Fatalf("a shadowbanned uploader's image was announced: %v", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the visible uploader's image was not announced")
+	}
+}
@@ -0,0 +1,588 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// GraphQLHandler serves a read-only GraphQL view over the feed, images, profiles,
+// collections and CMS pages, so clients can fetch a profile with its images and
+// collections in one round trip. Visibility rules match the REST endpoints.
+type GraphQLHandler struct {
+	userRepo    models.UserRepositoryInterface
+	imageRepo   models.ImageRepositoryInterface
+	collectRepo models.CollectRepositoryInterface
+	pageRepo    models.PageRepositoryInterface
+	schema      *graphql.Schema
+}
+
+func NewGraphQLHandler(userRepo models.UserRepositoryInterface, imageRepo models.ImageRepositoryInterface) *GraphQLHandler {
+	h := &GraphQLHandler{userRepo: userRepo, imageRepo: imageRepo}
+	h.schema = graphql.MustParseSchema(graphQLSchema, &gqlQuery{h: h},
+		graphql.UseStringDescriptions(), graphql.MaxDepth(graphQLMaxDepth), graphql.DisableIntrospection())
+	return h
+}
+
+func (h *GraphQLHandler) WithCollect(r models.CollectRepositoryInterface) *GraphQLHandler {
+	h.collectRepo = r
+	return h
+}
+
+func (h *GraphQLHandler) WithPages(r models.PageRepositoryInterface) *GraphQLHandler {
+	h.pageRepo = r
+	return h
+}
+
+// Largest page a list field returns; nested lists multiply, so this stays below REST's 100.
+// graphQLMaxNodes bounds the list items one request resolves.
+const (
+	graphQLMaxLimit    = 50
+	graphQLMaxDepth    = 8
+	graphQLMaxNodes    = 2000
+	graphQLMaxQueryLen = 16 << 10
+)
+
+const graphQLSchema = `
+schema {
+  query: Query
+}
+
+type Query {
+  "The public feed, newest first, filtered by the viewer's NSFW preference, mutes and blocks"
+  feed(limit: Int = 20, cursor: String): ImageConnection!
+  image(id: ID!): Image
+  user(username: String!): User
+  pages: [Page!]!
+  page(slug: String!): Page
+}
+
+"A page of images; pass nextCursor back as cursor for the next one"
+type ImageConnection {
+  images: [Image!]!
+  nextCursor: String
+}
+
+"An uploaded image or video clip"
+type Image {
+  id: ID!
+  filename: String!
+  width: Int
+  height: Int
+  blurhash: String
+  dominantColor: String
+  caption: String
+  isNsfw: Boolean!
+  "safe, suggestive, mature or explicit"
+  rating: String!
+  aiProvider: String
+  license: String!
+  visibility: String!
+  mediaType: String!
+  frameCount: Int!
+  durationMs: Int!
+  posterFilename: String
+  collectedCount: Int!
+  "Hex SHA-256 of the stored file, for validating downloads"
+  sha256: String
+  createdAt: String!
+  "The uploader; all authors in a list are loaded together"
+  author: User
+  "Lowercase tags; all tags in a list are loaded together"
+  tags: [String!]!
+}
+
+"A public profile"
+type User {
+  id: ID!
+  username: String!
+  bio: String
+  avatarUrl: String
+  isAdmin: Boolean!
+  isModerator: Boolean!
+  createdAt: String!
+  "Uploads, newest first; owners also see their unlisted and private ones"
+  images(limit: Int = 20, cursor: String): ImageConnection!
+  "Images this user collected"
+  collections(limit: Int = 20, cursor: String): ImageConnection!
+}
+
+"A published CMS page"
+type Page {
+  slug: String!
+  title: String!
+  html: String!
+  markdown: String!
+  redirectUrl: String
+  metaTitle: String
+  metaDescription: String
+  updatedAt: String!
+}
+`
+
+// gqlRequestState is the per-request viewer, loaders and node budget, reached through the
+// context. Fields resolve concurrently, so the loaders and budget are guarded by mu.
+type gqlRequestState struct {
+	viewer uuid.UUID
+	// fiber is the HTTP request, for scoping feeds and pages to its tenant
+	fiber *fiber.Ctx
+	users map[uuid.UUID]*models.User
+	tags  map[uuid.UUID]models.ImageTags
+
+	mu    sync.Mutex
+	nodes int
+}
+
+type gqlStateKey struct{}
+
+func gqlState(ctx context.Context) *gqlRequestState {
+	if s, ok := ctx.Value(gqlStateKey{}).(*gqlRequestState); ok {
+		return s
+	}
+	return &gqlRequestState{users: map[uuid.UUID]*models.User{}}
+}
+
+// charge counts n more list items against the request's node budget.
+func (st *gqlRequestState) charge(n int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.nodes += n
+	if st.nodes > graphQLMaxNodes {
+		return errors.New("query resolves too many objects")
+	}
+	return nil
+}
+
+// loadUsers is the request's user loader: ids not seen yet are fetched in one query and
+// remembered, misses included, so every author on a page costs a single lookup.
+func (h *GraphQLHandler) loadUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.User, error) {
+	st := gqlState(ctx)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if _, ok := st.users[id]; !ok && id != uuid.Nil {
+			st.users[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		found, err := h.userRepo.GetByIDs(ctx, missing)
+		if err != nil {
+			for _, id := range missing {
+				delete(st.users, id)
+			}
+			return nil, err
+		}
+		for i := range found {
+			st.users[found[i].ID] = &found[i]
+		}
+	}
+	out := make(map[uuid.UUID]*models.User, len(ids))
+	for _, id := range ids {
+		out[id] = st.users[id]
+	}
+	return out, nil
+}
+
+// loadTags is the request's tag loader for images read from lists, whose rows come without
+// tags: ids not seen yet are fetched in one query and remembered, so every image on a page
+// costs a single lookup.
+func (h *GraphQLHandler) loadTags(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.ImageTags, error) {
+	st := gqlState(ctx)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tags == nil {
+		st.tags = map[uuid.UUID]models.ImageTags{}
+	}
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if _, ok := st.tags[id]; !ok {
+			st.tags[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		found, err := h.imageRepo.TagsByIDs(ctx, missing)
+		if err != nil {
+			for _, id := range missing {
+				delete(st.tags, id)
+			}
+			return nil, err
+		}
+		for id, tags := range found {
+			st.tags[id] = tags
+		}
+	}
+	out := make(map[uuid.UUID]models.ImageTags, len(ids))
+	for _, id := range ids {
+		out[id] = st.tags[id]
+	}
+	return out, nil
+}
+
+// viewer returns the signed-in user, or nil for anonymous requests.
+func (h *GraphQLHandler) viewer(ctx context.Context) *models.User {
+	id := gqlState(ctx).viewer
+	if id == uuid.Nil {
+		return nil
+	}
+	users, err := h.loadUsers(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil
+	}
+	return users[id]
+}
+
+func (h *GraphQLHandler) viewerIsStaff(ctx context.Context) bool {
+	u := h.viewer(ctx)
+	return u != nil && (u.IsAdmin || u.IsModerator) && !u.IsDisabled
+}
+
+// gqlPageArgs are the arguments of every paginated field.
+type gqlPageArgs struct {
+	Limit  int32
+	Cursor *string
+}
+
+func (a gqlPageArgs) limit() int {
+	limit := int(a.Limit)
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > graphQLMaxLimit {
+		limit = graphQLMaxLimit
+	}
+	return limit
+}
+
+func (a gqlPageArgs) cursor() string {
+	if a.Cursor == nil {
+		return ""
+	}
+	return *a.Cursor
+}
+
+func gqlInt(n *int) *int32 {
+	if n == nil {
+		return nil
+	}
+	v := int32(*n)
+	return &v
+}
+
+func gqlTime(t time.Time) string { return t.Format(time.RFC3339Nano) }
+
+// gqlQuery resolves the root fields.
+type gqlQuery struct{ h *GraphQLHandler }
+
+func (q *gqlQuery) Feed(ctx context.Context, args gqlPageArgs) (*gqlConnection, error) {
+	h := q.h
+	repo := h.imageRepo
+	if c := gqlState(ctx).fiber; c != nil {
+		repo = tenantImages(c, repo)
+	}
+	u := h.viewer(ctx)
+	if u != nil {
+		repo = repo.ForViewer(u.ID)
+	}
+	images, next, err := repo.GetFeedSeek(args.limit(), models.FeedFilterFor(u), args.cursor())
+	if err != nil {
+		return nil, errors.New("failed to fetch feed")
+	}
+	return &gqlConnection{h: h, images: images, next: next}, nil
+}
+
+func (q *gqlQuery) Image(ctx context.Context, args struct{ ID graphql.ID }) (*gqlImage, error) {
+	h := q.h
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, errors.New("invalid image ID")
+	}
+	img, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil
+	}
+	// Images of another site do not exist here, as in its feed
+	if c := gqlState(ctx).fiber; c != nil && !onRequestSite(c, img.TenantID) {
+		return nil, nil
+	}
+	viewer := gqlState(ctx).viewer
+	if img.IsWithheld() && viewer != img.UserID && !h.viewerIsStaff(ctx) {
+		return nil, nil
+	}
+	if img.IsPrivate() && viewer != img.UserID {
+		return nil, nil
+	}
+	return &gqlImage{h: h, img: img, batch: []uuid.UUID{img.UserID}}, nil
+}
+
+func (q *gqlQuery) User(ctx context.Context, args struct{ Username string }) (*gqlUser, error) {
+	username := normalizeUsername(args.Username)
+	if username == "" {
+		return nil, nil
+	}
+	u, err := q.h.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, nil
+	}
+	st := gqlState(ctx)
+	st.mu.Lock()
+	st.users[u.ID] = u
+	st.mu.Unlock()
+	return &gqlUser{h: q.h, u: u}, nil
+}
+
+// pages returns the page repository of the request's tenant.
+func (h *GraphQLHandler) pages(ctx context.Context) models.PageRepositoryInterface {
+	if c := gqlState(ctx).fiber; c != nil {
+		return tenantPages(c, h.pageRepo)
+	}
+	return h.pageRepo
+}
+
+func (q *gqlQuery) Pages(ctx context.Context) ([]*gqlPage, error) {
+	if q.h.pageRepo == nil {
+		return []*gqlPage{}, nil
+	}
+	list, err := q.h.pages(ctx).ListPublished()
+	if err != nil {
+		return nil, errors.New("failed to fetch pages")
+	}
+	if err := gqlState(ctx).charge(len(list)); err != nil {
+		return nil, err
+	}
+	out := make([]*gqlPage, len(list))
+	for i := range list {
+		out[i] = &gqlPage{p: &list[i]}
+	}
+	return out, nil
+}
+
+func (q *gqlQuery) Page(ctx context.Context, args struct{ Slug string }) (*gqlPage, error) {
+	if q.h.pageRepo == nil {
+		return nil, nil
+	}
+	p, err := q.h.pages(ctx).GetPublishedBySlug(args.Slug)
+	if err != nil || p == nil {
+		return nil, nil
+	}
+	return &gqlPage{p: p}, nil
+}
+
+// gqlConnection is a page of images with the cursor for the next one.
+type gqlConnection struct {
+	h      *GraphQLHandler
+	images []models.ImageWithUser
+	next   string
+}
+
+func (c *gqlConnection) Images(ctx context.Context) ([]*gqlImage, error) {
+	if err := gqlState(ctx).charge(len(c.images)); err != nil {
+		return nil, err
+	}
+	// Every image of the page shares the page's author and image ids, so the first author
+	// or tag list resolved loads them all
+	batch := make([]uuid.UUID, len(c.images))
+	ids := make([]uuid.UUID, len(c.images))
+	for i := range c.images {
+		batch[i] = c.images[i].UserID
+		ids[i] = c.images[i].ID
+	}
+	out := make([]*gqlImage, len(c.images))
+	for i := range c.images {
+		out[i] = &gqlImage{h: c.h, img: &c.images[i], batch: batch, tagBatch: ids}
+	}
+	return out, nil
+}
+
+func (c *gqlConnection) NextCursor() *string {
+	if c.next == "" {
+		return nil
+	}
+	return &c.next
+}
+
+// gqlImage is one image. batch holds the author ids of its page; tagBatch holds the image
+// ids of its page, and is nil for images read with GetByID, which carry their tags.
+type gqlImage struct {
+	h        *GraphQLHandler
+	img      *models.ImageWithUser
+	batch    []uuid.UUID
+	tagBatch []uuid.UUID
+}
+
+func (i *gqlImage) ID() graphql.ID         { return graphql.ID(i.img.ID.String()) }
+func (i *gqlImage) Filename() string       { return services.SignMediaRef(i.img.Filename) }
+func (i *gqlImage) Width() *int32          { return gqlInt(i.img.Width) }
+func (i *gqlImage) Height() *int32         { return gqlInt(i.img.Height) }
+func (i *gqlImage) Blurhash() *string      { return i.img.Blurhash }
+func (i *gqlImage) DominantColor() *string { return i.img.DominantColor }
+func (i *gqlImage) Caption() *string       { return i.img.Caption }
+func (i *gqlImage) IsNSFW() bool           { return i.img.IsNSFW }
+func (i *gqlImage) Rating() string         { return string(i.img.EffectiveRating()) }
+func (i *gqlImage) AIProvider() *string    { return i.img.AIProvider }
+func (i *gqlImage) License() string        { return i.img.License }
+func (i *gqlImage) FrameCount() int32      { return int32(i.img.FrameCount) }
+func (i *gqlImage) DurationMS() int32      { return int32(i.img.DurationMS) }
+func (i *gqlImage) CollectedCount() int32  { return int32(i.img.CollectedCount) }
+func (i *gqlImage) SHA256() *string        { return i.img.SHA256 }
+func (i *gqlImage) CreatedAt() string      { return gqlTime(i.img.CreatedAt) }
+
+func (i *gqlImage) Visibility() string {
+	if i.img.Visibility == "" {
+		return models.ImageVisibilityPublic
+	}
+	return i.img.Visibility
+}
+
+func (i *gqlImage) MediaType() string {
+	if i.img.MediaType == "" {
+		return models.MediaTypeImage
+	}
+	return i.img.MediaType
+}
+
+func (i *gqlImage) PosterFilename() *string {
+	if i.img.PosterFilename == nil {
+		return nil
+	}
+	s := services.SignMediaRef(*i.img.PosterFilename)
+	return &s
+}
+
+// Author loads the uploaders of the image's whole page with one query.
+func (i *gqlImage) Author(ctx context.Context) (*gqlUser, error) {
+	users, err := i.h.loadUsers(ctx, i.batch)
+	if err != nil {
+		return nil, errors.New("failed to load authors")
+	}
+	if u := users[i.img.UserID]; u != nil {
+		return &gqlUser{h: i.h, u: u}, nil
+	}
+	return nil, nil
+}
+
+// Tags come with the image for image(id), read by GetByID as on GET /api/images/:id;
+// images in a list load their whole page's tags with one query.
+func (i *gqlImage) Tags(ctx context.Context) ([]string, error) {
+	tags := i.img.Tags
+	if i.tagBatch != nil {
+		loaded, err := i.h.loadTags(ctx, i.tagBatch)
+		if err != nil {
+			return nil, errors.New("failed to load tags")
+		}
+		tags = loaded[i.img.ID]
+	}
+	if tags == nil {
+		return []string{}, nil
+	}
+	return tags, nil
+}
+
+type gqlUser struct {
+	h *GraphQLHandler
+	u *models.User
+}
+
+func (u *gqlUser) ID() graphql.ID     { return graphql.ID(u.u.ID.String()) }
+func (u *gqlUser) Username() string   { return u.u.Username }
+func (u *gqlUser) Bio() *string       { return u.u.Bio }
+func (u *gqlUser) AvatarURL() *string { return u.u.AvatarURL }
+func (u *gqlUser) IsAdmin() bool      { return u.u.IsAdmin }
+func (u *gqlUser) IsModerator() bool  { return u.u.IsModerator }
+func (u *gqlUser) CreatedAt() string  { return gqlTime(u.u.CreatedAt) }
+
+func (u *gqlUser) Images(ctx context.Context, args gqlPageArgs) (*gqlConnection, error) {
+	h := u.h
+	viewer := gqlState(ctx).viewer
+	// A shadowbanned user's gallery looks empty to everyone but them and staff
+	if u.u.IsShadowbanned && viewer != u.u.ID && !h.viewerIsStaff(ctx) {
+		return &gqlConnection{h: h}, nil
+	}
+	images, next, err := h.imageRepo.GetUserImagesSeek(u.u.ID, args.limit(), args.cursor(), viewer == u.u.ID)
+	if err != nil {
+		return nil, errors.New("failed to fetch user images")
+	}
+	return &gqlConnection{h: h, images: images, next: next}, nil
+}
+
+func (u *gqlUser) Collections(ctx context.Context, args gqlPageArgs) (*gqlConnection, error) {
+	h := u.h
+	if h.collectRepo == nil {
+		return nil, errors.New("collections are not available")
+	}
+	images, next, err := h.collectRepo.GetUserCollectionsSeek(u.u.ID, args.limit(), args.cursor(), false)
+	if err != nil {
+		return nil, errors.New("failed to fetch collections")
+	}
+	return &gqlConnection{h: h, images: images, next: next}, nil
+}
+
+type gqlPage struct{ p *models.Page }
+
+func (p *gqlPage) Slug() string             { return p.p.Slug }
+func (p *gqlPage) Title() string            { return p.p.Title }
+func (p *gqlPage) HTML() string             { return services.PageHTML(p.p) }
+func (p *gqlPage) Markdown() string         { return p.p.Markdown }
+func (p *gqlPage) RedirectURL() *string     { return p.p.RedirectURL }
+func (p *gqlPage) MetaTitle() *string       { return p.p.MetaTitle }
+func (p *gqlPage) MetaDescription() *string { return p.p.MetaDescription }
+func (p *gqlPage) UpdatedAt() string        { return gqlTime(p.p.UpdatedAt) }
+
+// graphQLRequest is the standard GraphQL-over-HTTP request body.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func graphQLError(c *fiber.Ctx, msg string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": []fiber.Map{{"message": msg}}})
+}
+
+// Query runs a GraphQL request from a POST body or GET query parameters. A GET without
+// a query returns the schema in SDL for client code generators.
+func (h *GraphQLHandler) Query(c *fiber.Ctx) error {
+	var req graphQLRequest
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		if req.Query == "" {
+			c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+			return c.SendString(strings.TrimSpace(graphQLSchema) + "\n")
+		}
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return graphQLError(c, "variables must be a JSON object")
+			}
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return graphQLError(c, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return graphQLError(c, "query is required")
+	}
+	if len(req.Query) > graphQLMaxQueryLen {
+		return graphQLError(c, "query is too large")
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	st := &gqlRequestState{viewer: middleware.OptionalUserID(c), fiber: c, users: map[uuid.UUID]*models.User{}}
+	resp := h.schema.Exec(context.WithValue(ctx, gqlStateKey{}, st), req.Query, req.OperationName, req.Variables)
+	// Without data the request itself was invalid and nothing ran
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type gqlUserRepo struct {
+	models.UserRepositoryInterface
+	users    map[uuid.UUID]*models.User
+	batches  int
+	requests []int
+}
+
+func (f *gqlUserRepo) GetByIDs(_ context.Context, ids []uuid.UUID) ([]models.User, error) {
+	f.batches++
+	f.requests = append(f.requests, len(ids))
+	var out []models.User
+	for _, id := range ids {
+		if u := f.users[id]; u != nil {
+			out = append(out, *u)
+		}
+	}
+	return out, nil
+}
+
+func (f *gqlUserRepo) GetByUsername(_ context.Context, username string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+type gqlImageRepo struct {
+	models.ImageRepositoryInterface
+	images     []models.ImageWithUser
+	tagBatches int
+}
+
+func (f *gqlImageRepo) TagsByIDs(_ context.Context, ids []uuid.UUID) (map[uuid.UUID]models.ImageTags, error) {
+	f.tagBatches++
+	out := map[uuid.UUID]models.ImageTags{}
+	for _, id := range ids {
+		for _, img := range f.images {
+			if img.ID == id {
+				out[id] = img.Tags
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *gqlImageRepo) GetFeedSeek(limit int, filter models.FeedFilter, cursor string) ([]models.ImageWithUser, string, error) {
+	return f.images, "next", nil
+}
+
+func (f *gqlImageRepo) GetByID(_ context.Context, id uuid.UUID) (*models.ImageWithUser, error) {
+	for i := range f.images {
+		if f.images[i].ID == id {
+			return &f.images[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *gqlImageRepo) GetUserImagesSeek(userID uuid.UUID, limit int, cursor string, includeHidden bool) ([]models.ImageWithUser, string, error) {
+	var out []models.ImageWithUser
+	for _, img := range f.images {
+		if img.UserID == userID && (includeHidden || img.IsListed()) {
+			out = append(out, img)
+		}
+	}
+	return out, "", nil
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	ann := &models.User{ID: uuid.New(), Username: "ann"}
+	bob := &models.User{ID: uuid.New(), Username: "bob"}
+	users := &gqlUserRepo{users: map[uuid.UUID]*models.User{ann.ID: ann, bob.ID: bob}}
+	img := func(owner *models.User, visibility string) models.ImageWithUser {
+		return models.ImageWithUser{Image: models.Image{ID: uuid.New(), UserID: owner.ID, Filename: visibility + ".png", Visibility: visibility}}
+	}
+	images := &gqlImageRepo{images: []models.ImageWithUser{img(ann, "public"), img(bob, "public"), img(ann, "private")}}
+	images.images[0].Tags = models.ImageTags{"koi", "ink"}
+	h := NewGraphQLHandler(users, images)
+	run := func(viewer uuid.UUID, query string) map[string]any {
+		t.Helper()
+		ctx := context.WithValue(context.Background(), gqlStateKey{}, &gqlRequestState{viewer: viewer, users: map[uuid.UUID]*models.User{}})
+		resp := h.schema.Exec(ctx, query, "", nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("%s: %v", query, resp.Errors)
+		}
+		var out map[string]any
+		_ = json.Unmarshal(resp.Data, &out)
+		return out
+	}
+
+	out := run(uuid.Nil, `{ feed { images { filename author { username } tags } nextCursor } }`)
+	feed := out["feed"].(map[string]any)
+	if feed["nextCursor"] != "next" || len(feed["images"].([]any)) != 3 {
+		t.Fatalf("unexpected feed: %v", out)
+	}
+	if users.batches != 1 || users.requests[0] != 2 {
+		t.Errorf("authors should load in one batch of distinct ids, got %v", users.requests)
+	}
+	first, second := feed["images"].([]any)[0].(map[string]any), feed["images"].([]any)[1].(map[string]any)
+	if tags := first["tags"].([]any); len(tags) != 2 || tags[0] != "koi" || len(second["tags"].([]any)) != 0 {
+		t.Errorf("unexpected feed tags: %v", feed["images"])
+	}
+	if images.tagBatches != 1 {
+		t.Errorf("a page's tags should load in one batch, got %d", images.tagBatches)
+	}
+
+	tagged := images.images[0].ID.String()
+	out = run(uuid.Nil, `{ image(id: "`+tagged+`") { tags } }`)
+	if tags := out["image"].(map[string]any)["tags"].([]any); len(tags) != 2 || images.tagBatches != 1 {
+		t.Errorf("image(id) should return the tags read with the image, got %v after %d batches", tags, images.tagBatches)
+	}
+
+	private := images.images[2].ID.String()
+	if out := run(uuid.Nil, `{ image(id: "`+private+`") { id } }`); out["image"] != nil {
+		t.Error("private images should be hidden from other viewers")
+	}
+	if out := run(ann.ID, `{ image(id: "`+private+`") { id } }`); out["image"] == nil {
+		t.Error("owners should see their private images")
+	}
+
+	q := `{ user(username: "ann") { username images { images { visibility } } } }`
+	count := func(out map[string]any) int {
+		return len(out["user"].(map[string]any)["images"].(map[string]any)["images"].([]any))
+	}
+	if n := count(run(uuid.Nil, q)); n != 1 {
+		t.Errorf("visitors should see only listed images, got %d", n)
+	}
+	if n := count(run(ann.ID, q)); n != 2 {
+		t.Errorf("owners should see hidden images in their gallery, got %d", n)
+	}
+
+	st := &gqlRequestState{}
+	if st.charge(graphQLMaxNodes) != nil || st.charge(1) == nil {
+		t.Error("the node budget should allow exactly graphQLMaxNodes list items")
+	}
+}
+
+func TestGraphQLHTTP(t *testing.T) {
+	h := NewGraphQLHandler(&gqlUserRepo{}, &gqlImageRepo{})
+	app := fiber.New()
+	app.Get("/api/graphql", h.Query)
+	app.Post("/api/graphql", h.Query)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/graphql", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), "feed(limit: Int = 20, cursor: String): ImageConnection!") {
+		t.Fatalf("GET without a query should return the SDL, got %d %s", resp.StatusCode, body)
+	}
+
+	req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(`{"query":"{ pages { slug } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != `{"data":{"pages":[]}}` {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, body)
+	}
+
+	for name, query := range map[string]string{
+		"unknown field": `{ nope }`,
+		"mutation":      `mutation { pages { slug } }`,
+		"too deep":      `{ feed { images { author { images { images { author { images { images { id } } } } } } } } }`,
+	} {
+		req = httptest.NewRequest("POST", "/api/graphql", strings.NewReader(`{"query":`+strconv.Quote(query)+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 400 {
+			t.Fatalf("%s: invalid queries should be a 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
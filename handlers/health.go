@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// Check statuses. A degraded check is reported but does not take the instance out of
+// rotation; a failed one does.
+const (
+	checkOK       = "ok"
+	checkDegraded = "degraded"
+	checkFail     = "fail"
+)
+
+// mailStallAfter is how long a due email may wait before the outbox counts as stalled.
+const mailStallAfter = 15 * time.Minute
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	ping    func(context.Context) error
+	storage func() services.Storage
+	outbox  models.MailOutboxRepositoryInterface
+	started time.Time
+}
+
+func NewHealthHandler(ping func(context.Context) error) *HealthHandler {
+	return &HealthHandler{ping: ping, started: time.Now()}
+}
+
+// WithStorage sets how the current storage is looked up (it changes with site settings)
+func (h *HealthHandler) WithStorage(f func() services.Storage) *HealthHandler {
+	h.storage = f
+	return h
+}
+
+// WithMailOutbox injects the outbox whose backlog readiness reports
+func (h *HealthHandler) WithMailOutbox(r models.MailOutboxRepositoryInterface) *HealthHandler {
+	h.outbox = r
+	return h
+}
+
+type healthCheck struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	Cached    bool   `json:"cached,omitempty"`
+	Detail    any    `json:"detail,omitempty"`
+}
+
+// Live reports that the process is up and serving. It checks nothing else, so a slow
+// database never gets the container restarted.
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": checkOK, "uptime_seconds": int64(time.Since(h.started).Seconds())})
+}
+
+// Ready reports whether the instance can take traffic: the database answers, storage
+// accepts writes and mail is flowing. It answers 503 while shutting down or when a
+// check fails.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	select {
+	case <-services.ShuttingDown():
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": checkFail, "error": "shutting down"})
+	default:
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 3*time.Second)
+	defer cancel()
+	checks := fiber.Map{"database": h.checkDatabase(ctx)}
+	if h.storage != nil {
+		checks["storage"] = h.checkStorage(ctx)
+	}
+	if h.outbox != nil {
+		checks["mail"] = h.checkMail()
+	}
+	status := checkOK
+	for _, v := range checks {
+		switch v.(healthCheck).Status {
+		case checkFail:
+			status = checkFail
+		case checkDegraded:
+			if status == checkOK {
+				status = checkDegraded
+			}
+		}
+	}
+	code := fiber.StatusOK
+	if status == checkFail {
+		code = fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(fiber.Map{"status": status, "checks": checks})
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) healthCheck {
+	start := time.Now()
+	if err := h.ping(ctx); err != nil {
+		return healthCheck{Status: checkFail, LatencyMS: time.Since(start).Milliseconds(), Error: err.Error()}
+	}
+	return healthCheck{Status: checkOK, LatencyMS: time.Since(start).Milliseconds()}
+}
+
+func (h *HealthHandler) checkStorage(ctx context.Context) healthCheck {
+	st := h.storage()
+	if st == nil {
+		return healthCheck{Status: checkFail, Error: "storage not configured"}
+	}
+	start := time.Now()
+	cached, err := services.ProbeStorage(ctx, st)
+	out := healthCheck{Status: checkOK, LatencyMS: time.Since(start).Milliseconds(), Cached: cached}
+	if err != nil {
+		out.Status, out.Error = checkFail, err.Error()
+	}
+	return out
+}
+
+// checkMail degrades rather than fails: queued mail survives until SMTP recovers, and
+// pulling every instance would not help it along.
+func (h *HealthHandler) checkMail() healthCheck {
+	start := time.Now()
+	b, err := h.outbox.Backlog()
+	out := healthCheck{Status: checkOK, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		out.Status, out.Error = checkDegraded, err.Error()
+		return out
+	}
+	out.Detail = b
+	if b.OldestDue != nil && time.Since(*b.OldestDue) > mailStallAfter {
+		out.Status, out.Error = checkDegraded, "mail has been waiting to send for over 15 minutes"
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type probeStorage struct {
+	services.Storage
+	saves int
+	err   error
+}
+
+func (s *probeStorage) Save(context.Context, string, io.Reader, string) (string, error) {
+	s.saves++
+	return "", s.err
+}
+
+func (s *probeStorage) Delete(context.Context, string) error { return nil }
+
+func (s *probeStorage) IsLocal() bool { return true }
+
+type backlogOutbox struct {
+	models.MailOutboxRepositoryInterface
+	b models.MailBacklog
+}
+
+func (o *backlogOutbox) Backlog() (*models.MailBacklog, error) { return &o.b, nil }
+
+func TestReadiness(t *testing.T) {
+	var pingErr error
+	st := &probeStorage{}
+	outbox := &backlogOutbox{}
+	h := NewHealthHandler(func(context.Context) error { return pingErr }).
+		WithStorage(func() services.Storage { return st }).WithMailOutbox(outbox)
+	app := fiber.New()
+	app.Get("/livez", h.Live)
+	app.Get("/readyz", h.Ready)
+	ready := func() (int, string, map[string]struct {
+		Status string `json:"status"`
+		Cached bool   `json:"cached"`
+	}) {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body struct {
+			Status string `json:"status"`
+			Checks map[string]struct {
+				Status string `json:"status"`
+				Cached bool   `json:"cached"`
+			} `json:"checks"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Status, body.Checks
+	}
+
+	code, status, checks := ready()
+	if code != http.StatusOK || status != "ok" || checks["database"].Status != "ok" || checks["storage"].Status != "ok" || checks["mail"].Status != "ok" {
+		t.Fatalf("expected all ok, got %d %s %+v", code, status, checks)
+	}
+	// The storage write test is cached between polls
+	if _, _, checks = ready(); st.saves != 1 || !checks["storage"].Cached {
+		t.Fatalf("expected a cached storage probe, got %d saves", st.saves)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	outbox.b = models.MailBacklog{Pending: 3, OldestDue: &old}
+	if code, status, checks = ready(); code != http.StatusOK || status != "degraded" || checks["mail"].Status != "degraded" {
+		t.Fatalf("expected stalled mail to degrade, got %d %s %+v", code, status, checks)
+	}
+
+	pingErr = errors.New("connection refused")
+	if code, status, checks = ready(); code != http.StatusServiceUnavailable || status != "fail" || checks["database"].Status != "fail" {
+		t.Fatalf("expected a failed database to fail readiness, got %d %s %+v", code, status, checks)
+	}
+	// Liveness ignores dependencies
+	if resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/livez", nil)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected live, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	maxLocaleStringsPerSave = 500
+	maxLocaleStringLen      = 5000
+)
+
+// WithLocaleStrings injects the repository of admin translation overrides
+func (h *AdminHandler) WithLocaleStrings(r models.LocaleStringRepositoryInterface) *AdminHandler {
+	h.localeStrings = r
+	return h
+}
+
+// ReloadLocaleStrings refreshes the in-memory translation overrides from the repository.
+func (h *AdminHandler) ReloadLocaleStrings() error {
+	if h.localeStrings == nil {
+		return nil
+	}
+	list, err := h.localeStrings.List()
+	if err != nil {
+		return err
+	}
+	services.SetLocaleOverrides(list)
+	return nil
+}
+
+// localeString is one message as shown in the admin editor: the shipped translation and
+// the admin's override, if any.
+type localeString struct {
+	Msgid    string  `json:"msgid"`
+	Default  string  `json:"default"`
+	Override *string `json:"override"`
+}
+
+// AdminListLocales returns the locales with translations and the site default.
+func (h *AdminHandler) AdminListLocales(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	return c.JSON(fiber.Map{"locales": services.Locales(), "default_locale": services.SiteLocale(set), "request_locale": middleware.GetLocale(c)})
+}
+
+// AdminGetLocaleStrings returns every translatable message for a locale with its shipped
+// translation and override.
+func (h *AdminHandler) AdminGetLocaleStrings(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.localeStrings == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Translations not configured"})
+	}
+	locale, err := services.NormalizeLocale(c.Params("locale"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid locale"})
+	}
+	overrides, err := h.localeStrings.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load translations", "details": err.Error()})
+	}
+	bundle := services.BundleStrings(locale)
+	byMsgid := map[string]*localeString{}
+	for _, msgid := range services.Catalog() {
+		byMsgid[msgid] = &localeString{Msgid: msgid, Default: bundle[msgid]}
+	}
+	for _, o := range overrides {
+		if o.Locale != locale {
+			continue
+		}
+		text := o.Text
+		if s, ok := byMsgid[o.Msgid]; ok {
+			s.Override = &text
+		} else {
+			byMsgid[o.Msgid] = &localeString{Msgid: o.Msgid, Override: &text}
+		}
+	}
+	out := make([]localeString, 0, len(byMsgid))
+	for _, s := range byMsgid {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Msgid < out[j].Msgid })
+	return c.JSON(fiber.Map{"locale": locale, "strings": out})
+}
+
+// AdminPutLocaleStrings sets overrides for a locale. The body maps msgids to their text;
+// an empty text removes the override so the shipped translation applies again.
+func (h *AdminHandler) AdminPutLocaleStrings(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.localeStrings == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Translations not configured"})
+	}
+	locale, err := services.NormalizeLocale(c.Params("locale"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid locale"})
+	}
+	var req struct {
+		Strings map[string]string `json:"strings"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if len(req.Strings) == 0 || len(req.Strings) > maxLocaleStringsPerSave {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "strings must have between 1 and 500 entries"})
+	}
+	set := map[string]string{}
+	var remove []string
+	for msgid, text := range req.Strings {
+		if strings.TrimSpace(msgid) == "" || len(msgid) > maxLocaleStringLen || len(text) > maxLocaleStringLen {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "msgids and texts must be 1 to 5000 characters"})
+		}
+		if strings.TrimSpace(text) == "" {
+			remove = append(remove, msgid)
+			continue
+		}
+		if !services.SameFormatVerbs(msgid, text) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The text for " + msgid + " must keep the placeholders (%s, %d...) of the original, in order"})
+		}
+		set[msgid] = text
+	}
+	if err := h.localeStrings.Replace(locale, set, remove, actorID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save translations", "details": err.Error()})
+	}
+	if err := h.ReloadLocaleStrings(); err != nil {
+		services.Logger(c.Context()).Error("i18n: reload overrides failed", "error", err)
+	}
+	services.Logger(c.Context()).Info("i18n: overrides saved", "locale", locale, "set", len(set), "removed", len(remove), "admin_id", middleware.GetUserID(c).String())
+	return h.AdminGetLocaleStrings(c)
+}
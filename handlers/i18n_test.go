@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeLocaleStringRepo struct {
+	models.LocaleStringRepositoryInterface
+	items map[[2]string]string
+}
+
+func (f *fakeLocaleStringRepo) List() ([]models.LocaleString, error) {
+	out := []models.LocaleString{}
+	for k, v := range f.items {
+		out = append(out, models.LocaleString{Locale: k[0], Msgid: k[1], Text: v})
+	}
+	return out, nil
+}
+
+func (f *fakeLocaleStringRepo) Replace(locale string, set map[string]string, remove []string, by *uuid.UUID) error {
+	for _, m := range remove {
+		delete(f.items, [2]string{locale, m})
+	}
+	for m, v := range set {
+		f.items[[2]string{locale, m}] = v
+	}
+	return nil
+}
+
+func TestLocaleStringOverrides(t *testing.T) {
+	defer services.SetLocaleOverrides(nil)
+	app := fiber.New()
+	repo := &fakeLocaleStringRepo{items: map[[2]string]string{}}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithLocaleStrings(repo)
+	app.Get("/admin/i18n/:locale", h.AdminGetLocaleStrings)
+	app.Put("/admin/i18n/:locale", h.AdminPutLocaleStrings)
+	put := func(locale, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/admin/i18n/"+locale, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := put("es", `{"strings":{"Forbidden":"Acceso denegado","Page":"Página web"}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if services.T("es", "Forbidden") != "Acceso denegado" {
+		t.Fatalf("expected the override to apply immediately")
+	}
+	if code := put("es", `{"strings":{"%s started following you":"te sigue"}}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for dropped placeholders, got %d", code)
+	}
+	if code := put("english%21", `{"strings":{"Forbidden":"x"}}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid locale, got %d", code)
+	}
+	if code := put("es", `{"strings":{"Page":""}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if services.T("es", "Page") != "Página" {
+		t.Fatalf("expected removing the override to restore the bundle text")
+	}
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/admin/i18n/ES", nil))
+	var body struct {
+		Locale  string `json:"locale"`
+		Strings []struct {
+			Msgid    string  `json:"msgid"`
+			Default  string  `json:"default"`
+			Override *string `json:"override"`
+		} `json:"strings"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.Locale != "es" {
+		t.Fatalf("expected a normalized locale, got %q", body.Locale)
+	}
+	found := false
+	for _, s := range body.Strings {
+		if s.Msgid == "Forbidden" {
+			found = s.Default == "Prohibido" && s.Override != nil && *s.Override == "Acceso denegado"
+		}
+	}
+	if !found {
+		t.Fatalf("expected Forbidden with its default and override, got %+v", body.Strings)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"image"
 	_ "image/png"
 	"io"
-	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -51,6 +51,13 @@ type ImageHandler struct {
 	storage      services.Storage
 	collectRepo  models.CollectRepositoryInterface
 	settingsRepo models.SiteSettingsRepositoryInterface
+	notifyRepo   models.NotificationRepositoryInterface
+	moderation   models.ModerationRepositoryInterface
+	jobs         models.JobRepositoryInterface
+	boards       models.BoardRepositoryInterface
+	blocks       models.BlockRepositoryInterface
+	takedowns    models.TakedownRepositoryInterface
+	edits        models.ImageEditRepositoryInterface
 }
 
 func NewImageHandler(imageRepo models.ImageRepositoryInterface, likeRepo models.LikeRepositoryInterface, userRepo models.UserRepositoryInterface, config services.Config, storage services.Storage) *ImageHandler {
@@ -73,16 +80,36 @@ func (h *ImageHandler) WithSettings(r models.SiteSettingsRepositoryInterface) *I
 	return h
 }
 
+// WithNotifications notifies image owners when their images are collected.
+func (h *ImageHandler) WithNotifications(r models.NotificationRepositoryInterface) *ImageHandler {
+	h.notifyRepo = r
+	return h
+}
+
+// WithModeration enables holding new users' uploads for review.
+func (h *ImageHandler) WithModeration(r models.ModerationRepositoryInterface) *ImageHandler {
+	h.moderation = r
+	return h
+}
+
 func (h *ImageHandler) Upload(c *fiber.Ctx) error {
+	defer func() { services.UploadsTotal.Inc(uploadResult(c.Response().StatusCode())) }()
 	userID := middleware.GetUserID(c)
 	if userID == uuid.Nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
 	}
+	req := uploadRequest{UserID: userID}
 	// Gate uploads for unverified users when email verification is enabled
 	if h.userRepo != nil {
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
 		if u, err := h.userRepo.GetByID(ctx, userID); err == nil && u != nil {
+			if s := u.ActiveSuspension(); s != nil {
+				return c.Status(fiber.StatusForbidden).JSON(suspensionError(s))
+			}
+			if u.PendingApproval {
+				return c.Status(fiber.StatusForbidden).JSON(pendingApprovalError)
+			}
 			// Read settings via cache for performance; treat missing repo as disabled
 			var requireVerify bool
 			if h.settingsRepo != nil {
@@ -92,18 +119,57 @@ func (h *ImageHandler) Upload(c *fiber.Ctx) error {
 			if requireVerify && !u.EmailVerified {
 				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Email not verified. Verify your email to upload images."})
 			}
+			req.Hold = h.shouldHoldUpload(u)
+			req.Uploader = u.Username
+			req.StripExif = u.StripExif
 		}
 	}
+	if services.GetCachedSettings(h.settingsRepo).ExifPrivacyMode {
+		req.StripExif = true
+	}
+	req.TenantID = middleware.TenantID(c)
 
 	file, err := c.FormFile("image")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No image file provided"})
 	}
+	req.Filename = file.Filename
+	req.Size = file.Size
+
+	req.Title = strings.TrimSpace(c.FormValue("title"))
+	req.IsNSFW = strings.ToLower(strings.TrimSpace(c.FormValue("is_nsfw"))) == "true"
+	// rating takes precedence over the older is_nsfw flag
+	if v := strings.TrimSpace(c.FormValue("rating")); v != "" {
+		rating, ok := models.ParseRating(v)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rating must be safe, suggestive, mature or explicit"})
+		}
+		req.Rating = rating
+	}
+	req.Caption = strings.TrimSpace(c.FormValue("caption"))
+	req.Visibility = strings.ToLower(strings.TrimSpace(c.FormValue("visibility", models.ImageVisibilityPublic)))
+	if !models.ValidImageVisibility(req.Visibility) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "visibility must be public, unlisted or private"})
+	}
+	req.License = strings.ToLower(strings.TrimSpace(c.FormValue("license")))
+	if _, ok := models.LicenseByID(req.License); req.License != "" && !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown license"})
+	}
+	remixOf, remixURL, remixErr := h.resolveRemixSource(c, uuid.Nil, c.FormValue("remix_of"))
+	if remixErr != nil {
+		return c.Status(remixErr.status).JSON(fiber.Map{"error": remixErr.msg})
+	}
+	req.RemixOf, req.RemixOfURL = remixOf, remixURL
 
+	if services.IsVideoUpload(file.Filename, file.Header.Get("Content-Type")) {
+		return h.uploadVideo(c, file, req.draft())
+	}
 
-	title := strings.TrimSpace(c.FormValue("title"))
-	isNSFW := strings.ToLower(strings.TrimSpace(c.FormValue("is_nsfw"))) == "true"
-	caption := strings.TrimSpace(c.FormValue("caption"))
+	// Phase spans show where upload latency goes; End is idempotent so each phase is both
+	// ended explicitly and deferred for early returns
+	_, validateSpan := services.StartSpan(c.Context(), "upload.validate")
+	defer validateSpan.End()
+	validateSpan.SetAttr("upload.size", file.Size)
 
 	src, err := file.Open()
 	if err != nil {
@@ -111,24 +177,18 @@ func (h *ImageHandler) Upload(c *fiber.Ctx) error {
 	}
 	defer src.Close()
 
-	// Use comprehensive file validation with streaming support
+	// The validator only reads the header, so bad files are still rejected in the request
 	fileValidator := services.NewFileValidator()
-	
-	// Validate file and get stream back for AI detection
-	result, remainingStream, err := fileValidator.ValidateImageStream(file.Filename, src)
+	result, _, err := fileValidator.ValidateImageStream(file.Filename, src)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate file"})
 	}
 	
 	if !result.IsValid {
+		validateSpan.SetAttr("upload.rejected", result.ErrorMessage)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": result.ErrorMessage})
 	}
-	
-	// Use the remaining stream for AI detection (avoids re-reading)
-	var streamReader io.Reader = remainingStream
-	
-	// Keep the original file reference for operations that need multipart.File interface
-	originalFile := src
+	validateSpan.End()
 	
 	// Add security information to response context
 	if result.SecurityLevel == "low" {
@@ -139,42 +199,164 @@ func (h *ImageHandler) Upload(c *fiber.Ctx) error {
 	// OPTIMIZED: Early format-based rejection for better performance
 	// Some formats are very unlikely to contain AI metadata
 	formatContentType := file.Header.Get("Content-Type")
-	if strings.Contains(formatContentType, "bmp") || strings.Contains(formatContentType, "gif") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "BMP and GIF formats rarely contain AI metadata. Please use JPEG, PNG, or WebP."})
+	if strings.Contains(formatContentType, "bmp") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "BMP files rarely contain AI metadata. Please use JPEG, PNG, WebP, or GIF."})
+	}
+
+	// Detection, re-encoding and storage run in a job when the queue is up, so large
+	// files do not hold the request open; the client polls the returned token
+	if queued, err := h.queueUpload(c, file, req); queued || err != nil {
+		return err
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+	}
+	imageModel, uerr := h.processUpload(c.Context(), req, src)
+	if uerr != nil {
+		return c.Status(uerr.status).JSON(fiber.Map{"error": uerr.msg})
 	}
+	return c.Status(fiber.StatusCreated).JSON(imageModel.ToUploadResponse())
+}
+
+// uploadRequest is what the upload pipeline needs from the form and the uploader. It is
+// the payload of upload.process jobs, so it is decided in the request and never re-read.
+type uploadRequest struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	Title      string    `json:"title,omitempty"`
+	Caption    string    `json:"caption,omitempty"`
+	IsNSFW     bool      `json:"is_nsfw"`
+	Visibility string    `json:"visibility"`
+	// Rating is the uploader's content rating; empty uses IsNSFW
+	Rating models.ContentRating `json:"rating,omitempty"`
+	License    string    `json:"license,omitempty"`
+	Hold       bool      `json:"hold"`
+	Uploader   string    `json:"uploader"`
+	StripExif  bool      `json:"strip_exif"`
+	// TenantID is the site the upload was made on; nil is the primary site
+	TenantID *uuid.UUID `json:"tenant_id,omitempty"`
+	// RemixOf and RemixOfURL are the declared source, on-site or external
+	RemixOf    *uuid.UUID `json:"remix_of,omitempty"`
+	RemixOfURL *string    `json:"remix_of_url,omitempty"`
+}
+
+// draft returns an image carrying the request's form fields.
+func (r uploadRequest) draft() *models.Image {
+	img := &models.Image{UserID: r.UserID, IsNSFW: r.IsNSFW, Rating: r.Rating, Visibility: r.Visibility, License: r.License, TenantID: r.TenantID, RemixOf: r.RemixOf, RemixOfURL: r.RemixOfURL}
+	if r.Title != "" {
+		img.OriginalName = &r.Title
+	}
+	if r.Caption != "" {
+		img.Caption = &r.Caption
+	}
+	if r.Hold {
+		img.ModerationStatus = models.ImageStatusPending
+	}
+	return img
+}
+
+// uploadError is a pipeline failure with the status the request would have answered.
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string { return e.msg }
+
+func uploadFailed(status int, msg string) *uploadError {
+	return &uploadError{status: status, msg: msg}
+}
+
+// aiRejectedMsg is the reason given for images without verifiable AI provenance.
+const aiRejectedMsg = "Upload rejected. Only AI-generated images with verifiable metadata (EXIF or XMP; C2PA optional) are accepted."
+
+// aiRejectedUpload refuses a file AI detection found no provenance in and remembers its
+// hash, so sending it again is refused without another scan.
+func aiRejectedUpload(ctx context.Context, sum string) *uploadError {
+	services.RememberAIReject(ctx, sum)
+	return uploadFailed(fiber.StatusBadRequest, aiRejectedMsg)
+}
+
+// processUpload runs AI detection, decoding, re-encoding and storage on an uploaded image
+// that has passed header validation, then records it. It runs in the request, or in an
+// upload.process job reading the staged file.
+func (h *ImageHandler) processUpload(ctx context.Context, req uploadRequest, src io.ReadSeeker) (*models.Image, *uploadError) {
+	imageModel, cleanup, uerr := h.storeUpload(ctx, req, src)
+	if uerr != nil {
+		return nil, uerr
+	}
+	if uerr := h.recordUpload(ctx, imageModel); uerr != nil {
+		cleanup()
+		return nil, uerr
+	}
+	return imageModel, nil
+}
+
+// storeUpload is processUpload up to the database: it returns the image with its file
+// fields filled in, and a cleanup that deletes the stored file.
+func (h *ImageHandler) storeUpload(ctx context.Context, req uploadRequest, src io.ReadSeeker) (*models.Image, func(), *uploadError) {
+	license, hasLicense := models.LicenseByID(req.License)
+	fileValidator := services.NewFileValidator()
 
 	var aiSignature string
 	var aiOK bool
 	var aiRes services.AIDetectionResult
 	var xmpOriginal []byte
+	// GIFs, APNGs and animated WebPs are stored as uploaded
+	var anim services.Animation
+	var isAnim bool
+	var err error
+
+	_, detectSpan := services.StartSpan(ctx, "upload.ai_detect")
+	defer detectSpan.End()
+
+	// A file detection rejected recently is refused before any scan runs
+	rejectSum := services.AIRejectSum(src)
+	if services.AIRejected(ctx, rejectSum) {
+		detectSpan.SetAttr("ai.accepted", false)
+		detectSpan.SetAttr("ai.cached", true)
+		return nil, nil, uploadFailed(fiber.StatusBadRequest, aiRejectedMsg)
+	}
 
 	// OPTIMIZED: Stream-based AI detection to avoid full file buffering
 	// For large files (>2MB), use streaming detection first
 	var originalBytes []byte
-	if file.Size > 2*1024*1024 { // 2MB threshold
+	if req.Size > 2*1024*1024 { // 2MB threshold
 		// For large files, use streaming AI detection first
-		if ok, res := detectAIStreaming(originalFile, file.Size); ok {
+		if ok, res := detectAIStreaming(src, req.Size); ok {
+			services.RecordAIDetection(true, res)
 			aiSignature = res.Details
 			goto ai_validated
 		}
-		// If streaming detection fails, buffer the file for full detection
-		originalFile.Seek(0, 0)
-		if buf, err := io.ReadAll(originalFile); err == nil {
-			originalBytes = buf
-		} else {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
-		}
+	}
+	// Buffer the file for full detection
+	src.Seek(0, io.SeekStart)
+	if buf, err := io.ReadAll(src); err == nil {
+		originalBytes = buf
 	} else {
-		// For small files, buffer immediately
-		if buf, err := io.ReadAll(streamReader); err == nil {
-			originalBytes = buf
-		} else {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+		return nil, nil, uploadFailed(fiber.StatusInternalServerError, "Failed to buffer upload")
+	}
+
+	anim, isAnim = services.InspectAnimation(originalBytes)
+	if isAnim {
+		// Judge animations on their metadata blocks alone; frame data is noise to the text scanners
+		meta := services.AnimationMetadata(originalBytes)
+		xmpOriginal = services.ExtractXMPXMLFromBytes(meta)
+		aiOK, aiRes = services.DetectAIProvenanceConcurrent(meta, xmpOriginal)
+		services.RecordAIDetection(aiOK, aiRes)
+		if !aiOK {
+			detectSpan.SetAttr("ai.accepted", false)
+			return nil, nil, aiRejectedUpload(ctx, rejectSum)
 		}
+		aiSignature = aiRes.Details
+		goto ai_validated
 	}
 
 	// FAST PATH: Quick AI detection first (rejects obvious non-AI immediately)
 	if aiOK, aiRes = services.DetectAIFast(originalBytes); aiOK {
+		services.RecordAIDetection(true, aiRes)
 		aiSignature = aiRes.Details
 		goto ai_validated
 	}
@@ -182,27 +364,72 @@ func (h *ImageHandler) Upload(c *fiber.Ctx) error {
 	// FALLBACK: Full concurrent AI detection for edge cases
 	xmpOriginal = services.ExtractXMPXMLFromBytes(originalBytes)
 	aiOK, aiRes = services.DetectAIProvenanceConcurrent(originalBytes, xmpOriginal)
+	services.RecordAIDetection(aiOK, aiRes)
 	if !aiOK {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload rejected. Only AI-generated images with verifiable metadata (EXIF or XMP; C2PA optional) are accepted."})
+		detectSpan.SetAttr("ai.accepted", false)
+		return nil, nil, aiRejectedUpload(ctx, rejectSum)
 	}
 	aiSignature = aiRes.Details
 
 ai_validated:
+	detectSpan.SetAttr("ai.accepted", true)
+	detectSpan.SetAttr("ai.method", aiRes.Method)
+	detectSpan.End()
+
+	_, encodeSpan := services.StartSpan(ctx, "upload.encode")
+	defer encodeSpan.End()
+
+	// Streaming detection accepts large files before they are buffered
+	if originalBytes == nil {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, uploadFailed(fiber.StatusInternalServerError, "Failed to buffer upload")
+		}
+		buf, err := io.ReadAll(src)
+		if err != nil {
+			return nil, nil, uploadFailed(fiber.StatusInternalServerError, "Failed to buffer upload")
+		}
+		originalBytes = buf
+		anim, isAnim = services.InspectAnimation(originalBytes)
+	}
 
 	// Now decode image for processing (only if AI validation passed)
-	img, format, err := image.Decode(bytes.NewReader(originalBytes))
+	var img image.Image
+	var format string
+	if isAnim {
+		if err := anim.CheckLimits(h.config.Animation); err != nil {
+			return nil, nil, uploadFailed(fiber.StatusBadRequest, "Upload rejected: "+err.Error())
+		}
+		// The validator skips GIF dimensions, so bound the canvas here
+		if anim.Width > fileValidator.MaxDimensions.Width || anim.Height > fileValidator.MaxDimensions.Height ||
+			int64(anim.Width)*int64(anim.Height) > fileValidator.MaxPixelCount {
+			return nil, nil, uploadFailed(fiber.StatusBadRequest, "Upload rejected: animation dimensions are too large")
+		}
+		img, err = services.DecodeFirstFrame(originalBytes)
+		format = anim.Format
+	} else {
+		img, format, err = image.Decode(bytes.NewReader(originalBytes))
+	}
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to decode image"})
+		return nil, nil, uploadFailed(fiber.StatusBadRequest, "Failed to decode image")
 	}
 	// Compute meta from decoded image to avoid double decode
 	imageMeta := services.ProcessDecodedImage(img, format)
+	if isAnim {
+		// Blurhash and color come from the first frame; the size is the full canvas
+		imageMeta.Width, imageMeta.Height = anim.Width, anim.Height
+	}
 
 	// Build final bytes. Preserve C2PA by keeping original bytes untouched when detected via C2PA.
 	var finalBytes []byte
 	var finalContentType string = "image/jpeg"
 	var filename string
-	originalExt := strings.ToLower(filepath.Ext(file.Filename))
-	if aiRes.Method == "c2pa" {
+	originalExt := strings.ToLower(filepath.Ext(req.Filename))
+	if isAnim {
+		// Re-encoding would flatten the animation, so store the upload untouched
+		finalBytes = originalBytes
+		finalContentType = anim.ContentType()
+		filename = uuid.New().String() + anim.Ext()
+	} else if aiRes.Method == "c2pa" {
 		finalBytes = originalBytes
 		// Preserve original extension and content type if supported
 		switch originalExt {
@@ -256,26 +483,43 @@ ai_validated:
 			}
 			// Extract raw EXIF to reattach if available
 			exifRaw := services.ExtractExifRawFromBytes(originalBytes)
-			out, err := services.EncodeJPEGWithMetadata(resized, quality, xmpOriginal, exifRaw)
+			xmpOut := xmpOriginal
+			if hasLicense {
+				// Keep the source packet when the fast path skipped extracting it
+				if xmpOut == nil {
+					xmpOut = services.ExtractXMPXMLFromBytes(originalBytes)
+				}
+				xmpOut = services.WithLicenseXMP(xmpOut, license, req.Uploader)
+			}
+			// Privacy mode drops location and device identifiers but keeps provenance tags
+			var exifFilter *services.ExifFilter
+			if req.StripExif {
+				exifFilter = &services.PrivacyExifFilter
+			}
+			out, err := services.EncodeJPEGWithFilteredMetadata(resized, quality, xmpOut, exifRaw, exifFilter)
 			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode image"})
+				return nil, nil, uploadFailed(fiber.StatusInternalServerError, "Failed to encode image")
 			}
 			finalBytes = out
 			filename = uuid.New().String() + ".jpg"
 			finalContentType = "image/jpeg"
 		}
 	}
+	encodeSpan.SetAttr("image.content_type", finalContentType)
+	encodeSpan.SetAttr("image.bytes", len(finalBytes))
+	encodeSpan.End()
+
 	// Save to storage (local or remote) under top-level key = filename
 	st := services.GetCurrentStorage()
 	if st == nil {
 		st = h.storage
 	}
 	if st == nil {
-		st = services.NewLocalStorage("uploads")
+		st = services.NewLocalStorage(services.UploadsDir())
 	}
-	publicURL, err := st.Save(c.Context(), filename, bytes.NewReader(finalBytes), finalContentType)
+	publicURL, err := st.Save(ctx, filename, bytes.NewReader(finalBytes), finalContentType)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store image"})
+		return nil, nil, uploadFailed(fiber.StatusInternalServerError, "Failed to store image")
 	}
 
 	// For local storage, ensure the public URL is just the filename for backward compatibility
@@ -310,40 +554,115 @@ ai_validated:
 		}
 	}
 
-	originalName := file.Filename
+	originalName := req.Filename
 	fileSize := len(finalBytes)
 
-	imageModel := &models.Image{
-		UserID:        userID,
-		Filename:      filenameOrURL, // Store either filename (local) or full URL (remote)
-		OriginalName:  &originalName,
-		FileSize:      &fileSize,
-		Width:         &imageMeta.Width,
-		Height:        &imageMeta.Height,
-		Blurhash:      &imageMeta.Blurhash,
-		DominantColor: &imageMeta.DominantColor,
-		IsNSFW:        isNSFW,
-		AISignature:   nil,
-		ExifData:      exifData,
+	imageModel := req.draft()
+	imageModel.Filename = filenameOrURL // Store either filename (local) or full URL (remote)
+	if imageModel.OriginalName == nil {
+		imageModel.OriginalName = &originalName
+	}
+	imageModel.FileSize = &fileSize
+	checksum := services.ChecksumSHA256(finalBytes)
+	imageModel.SHA256 = &checksum
+	imageModel.Width = &imageMeta.Width
+	imageModel.Height = &imageMeta.Height
+	imageModel.Blurhash = &imageMeta.Blurhash
+	imageModel.DominantColor = &imageMeta.DominantColor
+	imageModel.ExifData = exifData
+	if isAnim && anim.Frames > 1 {
+		imageModel.FrameCount = anim.Frames
+		imageModel.DurationMS = int(anim.Duration.Milliseconds())
 	}
 	// Mark AI provenance
 	imageModel.AISignature = &aiSignature
 	if aiRes.Provider != "" {
 		imageModel.AIProvider = &aiRes.Provider
 	}
-	if title != "" {
-		imageModel.OriginalName = &title
+	if name := services.ExtractModelName(originalBytes); name != "" {
+		imageModel.AIModel = &name
 	}
-	if caption != "" {
-		imageModel.Caption = &caption
+	// Re-encoding drops PNG text chunks, so a ComfyUI graph is kept beside the image
+	if workflow, ok := services.ExtractComfyWorkflow(originalBytes); ok {
+		key := models.ImageWorkflowPrefix + strings.TrimSuffix(filename, filepath.Ext(filename)) + ".json"
+		if _, err := st.Save(ctx, key, bytes.NewReader(workflow), "application/json"); err != nil {
+			services.Logger(ctx).Warn("upload: storing workflow failed", "key", key, "error", err.Error())
+		} else {
+			imageModel.WorkflowKey = &key
+		}
+	}
+
+	return imageModel, func() {
+		_ = st.Delete(ctx, filename)
+		if imageModel.WorkflowKey != nil {
+			_ = st.Delete(ctx, *imageModel.WorkflowKey)
+		}
+	}, nil
+}
+
+// finishUpload records a stored upload, announces it and answers 201. cleanup removes the
+// stored files when the row cannot be written.
+func (h *ImageHandler) finishUpload(c *fiber.Ctx, imageModel *models.Image, cleanup func()) error {
+	if uerr := h.recordUpload(c.Context(), imageModel); uerr != nil {
+		cleanup()
+		return c.Status(uerr.status).JSON(fiber.Map{"error": uerr.msg})
 	}
+	return c.Status(fiber.StatusCreated).JSON(imageModel.ToUploadResponse())
+}
 
+// recordUpload writes the image row and announces it unless it is held or hidden.
+func (h *ImageHandler) recordUpload(ctx context.Context, imageModel *models.Image) *uploadError {
 	if err := h.imageRepo.Create(imageModel); err != nil {
-		_ = st.Delete(c.Context(), filename) // Use original filename for cleanup
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save image metadata"})
+		return uploadFailed(fiber.StatusInternalServerError, "Failed to save image metadata")
+	}
+	publishUploadActivity(imageModel)
+	// Held images are announced when a moderator approves them; hidden ones never are
+	if !imageModel.IsPending() {
+		services.InvalidateFeedCache(ctx)
+		if imageModel.IsListed() {
+			h.emitImageCreated(ctx, imageModel)
+		}
 	}
+	return nil
+}
 
-	return c.Status(fiber.StatusCreated).JSON(imageModel.ToUploadResponse())
+// ListLicenses returns the licenses uploaders can choose from.
+func (h *ImageHandler) ListLicenses(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.JSON(fiber.Map{"licenses": models.Licenses})
+}
+
+// emitImageCreated announces a newly listed image on the live feed and to webhooks. A
+// shadowbanned uploader's images stay out of public feeds, so they are never announced.
+func (h *ImageHandler) emitImageCreated(ctx context.Context, img *models.Image) {
+	if h.userRepo == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	owner, err := h.userRepo.GetByID(ctx, img.UserID)
+	cancel()
+	if err != nil || owner.IsShadowbanned {
+		return
+	}
+	publishImageCreated(img)
+	services.EmitWebhook(services.WebhookImageCreated, map[string]interface{}{
+		"id": img.ID, "user_id": img.UserID, "filename": img.Filename, "title": img.OriginalName,
+		"caption": img.Caption, "is_nsfw": img.IsNSFW, "rating": img.Rating, "ai_provider": img.AIProvider, "license": img.License, "created_at": img.CreatedAt,
+	})
+}
+
+// uploadResult maps an upload response status to the uploads metric label.
+func uploadResult(status int) string {
+	switch {
+	case status == fiber.StatusCreated:
+		return "created"
+	case status == fiber.StatusAccepted:
+		return "queued"
+	case status >= 500:
+		return "error"
+	default:
+		return "rejected"
+	}
 }
 
 func (h *ImageHandler) GetFeed(c *fiber.Ctx) error {
@@ -358,35 +677,60 @@ func (h *ImageHandler) GetFeed(c *fiber.Ctx) error {
 		}
 	}
 
-	// Determine NSFW visibility based on user pref
-	showNSFW := false
+	// Signed-in viewers' content preferences and mutes decide what the feed leaves out
+	// and which images it marks for blurring
+	var filter models.FeedFilter
 	uid := middleware.OptionalUserID(c)
 	if uid != uuid.Nil {
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
 		if user, err := h.userRepo.GetByID(ctx, uid); err == nil {
-			showNSFW = user.ShowNSFW || strings.ToLower(strings.TrimSpace(user.NsfwPref)) != "hide"
+			filter = models.FeedFilterFor(user)
 		}
 	}
+	// Browsing everything made with one checkpoint, e.g. ?model=flux1-dev
+	filter.Model = services.NormalizeModelName(c.Query("model"))
+
+	// Polling for what is new since the last fetch
+	if since, sinceID := strings.TrimSpace(c.Query("since")), strings.TrimSpace(c.Query("since_id")); since != "" || sinceID != "" {
+		return h.feedSince(c, since, sinceID, limit, filter, uid)
+	}
 
 	// Prefer seek-based when cursor is provided; optional totals only when asked and on first page/no cursor
 	cursor := strings.TrimSpace(c.Query("cursor", ""))
+	includeTotal := strings.EqualFold(strings.TrimSpace(c.Query("include_total", "")), "true")
+	// Anonymous first pages are identical for every visitor; serve them from the feed cache
+	cacheKey := ""
+	if uid == uuid.Nil && cursor == "" && services.FeedCachePageCacheable(page) {
+		cacheKey = "feed:p" + strconv.Itoa(page) + ":l" + strconv.Itoa(limit) + ":t" + strconv.FormatBool(includeTotal && page == 1)
+		if filter.Model != "" {
+			cacheKey += ":m" + strings.ToLower(filter.Model)
+		}
+		if t := middleware.GetTenant(c); t != nil {
+			cacheKey = "tenant:" + t.ID.String() + ":" + cacheKey
+		}
+		if b, ok := services.FeedCacheGet(c.Context(), cacheKey); ok {
+			return sendCachedJSON(c, b)
+		}
+	}
+	// Each site of a multi-site install has its own feed; signed-in viewers see what they collected
+	feed := forViewer(tenantImages(c, h.imageRepo), uid)
 	if cursor != "" {
-		images, next, err := h.imageRepo.GetFeedSeek(limit, showNSFW, cursor)
+		images, next, err := feed.GetFeedSeek(limit, filter, cursor)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch images", "details": err.Error()})
 		}
+		filter.Annotate(images)
 		return c.JSON(models.FeedResponse{Images: images, NextCursor: next})
 	}
-	// Optional totals flag
-	includeTotal := strings.EqualFold(strings.TrimSpace(c.Query("include_total", "")), "true")
 	if includeTotal && page == 1 {
-		images, _, err := h.imageRepo.GetFeedSeek(limit, showNSFW, "")
+		images, _, err := feed.GetFeedSeek(limit, filter, "")
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch images"})
 		}
-		total, _ := h.imageRepo.CountFeed(showNSFW)
-		return c.JSON(models.FeedResponse{Images: images, Page: 1, Total: total, NextCursor: func() string {
+		filter.Annotate(images)
+		total, _ := feed.CountFeed(filter)
+		return respondCacheable(c, cacheKey, models.FeedResponse{Images: images, Page: 1, Total: total, SinceCursor: sinceCursor(images, ""), NextCursor: func() string {
 			if len(images) > 0 {
 				last := images[len(images)-1]
 				return models.EncodeCursor(last.CreatedAt, last.ID)
@@ -395,11 +739,90 @@ func (h *ImageHandler) GetFeed(c *fiber.Ctx) error {
 		}()})
 	}
 	// Backward-compatible page/offset fallback
-	images, total, err := h.imageRepo.GetFeed(page, limit, showNSFW)
+	images, total, err := feed.GetFeed(page, limit, filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch images", "details": err.Error()})
 	}
-	return c.JSON(models.FeedResponse{Images: images, Page: page, Total: total})
+	filter.Annotate(images)
+	resp := models.FeedResponse{Images: images, Page: page, Total: total}
+	if page == 1 {
+		resp.SinceCursor = sinceCursor(images, "")
+	}
+	return respondCacheable(c, cacheKey, resp)
+}
+
+// forViewer scopes r's listings to say whether viewer collected each image; anonymous
+// requests use r as-is.
+func forViewer(r models.ImageRepositoryInterface, viewer uuid.UUID) models.ImageRepositoryInterface {
+	if viewer == uuid.Nil {
+		return r
+	}
+	return r.ForViewer(viewer)
+}
+
+// viewerDisplay resolves how the signed-in viewer wants rated images displayed;
+// anonymous viewers and failed lookups get the defaults.
+func viewerDisplay(c *fiber.Ctx, users models.UserRepositoryInterface, viewer uuid.UUID) models.DisplayPrefs {
+	if viewer == uuid.Nil {
+		return models.DisplayPrefs{}
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := users.GetByID(ctx, viewer)
+	if err != nil {
+		return models.DisplayPrefs{}
+	}
+	return models.DisplayPrefsFor(u)
+}
+
+// feedSince answers a feed poll: the images newer than a since cursor, or than the image
+// since_id names. With If-Modified-Since and nothing new it answers 304.
+func (h *ImageHandler) feedSince(c *fiber.Ctx, since, sinceID string, limit int, filter models.FeedFilter, viewer uuid.UUID) error {
+	var cur *models.FeedSeekCursor
+	if since != "" {
+		var err error
+		if cur, err = models.DecodeCursor(since); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid since cursor"})
+		}
+	} else {
+		id, err := uuid.Parse(sinceID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid since_id"})
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		img, err := h.imageRepo.GetByID(ctx, id)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+		}
+		cur = &models.FeedSeekCursor{CreatedAt: img.CreatedAt, ID: img.ID}
+		since = models.EncodeCursor(img.CreatedAt, img.ID)
+	}
+	images, truncated, err := forViewer(tenantImages(c, h.imageRepo), viewer).GetFeedSince(limit, filter, *cur)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch images"})
+	}
+	filter.Annotate(images)
+	newest := cur.CreatedAt
+	if len(images) > 0 {
+		newest = images[0].CreatedAt
+	}
+	c.Set(fiber.HeaderLastModified, newest.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if len(images) == 0 {
+		if ims, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince)); err == nil && !newest.Truncate(time.Second).After(ims) {
+			return notModified(c)
+		}
+	}
+	return c.JSON(models.FeedResponse{Images: images, SinceCursor: sinceCursor(images, since), Truncated: truncated})
+}
+
+// sinceCursor is the cursor of the newest of images, or fallback when there are none.
+func sinceCursor(images []models.ImageWithUser, fallback string) string {
+	if len(images) == 0 {
+		return fallback
+	}
+	return models.EncodeCursor(images[0].CreatedAt, images[0].ID)
 }
 
 func (h *ImageHandler) GetImage(c *fiber.Ctx) error {
@@ -413,14 +836,71 @@ func (h *ImageHandler) GetImage(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
+	// Images anyone with the link may see are revalidated from their version alone,
+	// before the cache or the full row is read. Images of another site do not exist here.
+	if v, err := h.imageRepo.GetVersion(ctx, imageID); err == nil {
+		if !onRequestSite(c, v.TenantID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+		}
+		if v.ModerationStatus != models.ImageStatusPending && v.ModerationStatus != models.ImageStatusDisabled && v.Visibility != models.ImageVisibilityPrivate &&
+			conditionalGet(c, latestTime(v.UpdatedAt, v.UserUpdatedAt), "image", imageID, v.UpdatedAt.UnixNano(), v.UserUpdatedAt.UnixNano()) {
+			return notModified(c)
+		}
+	}
+
+	cacheKey := "image:" + imageID.String()
+	if t := middleware.GetTenant(c); t != nil {
+		cacheKey = "tenant:" + t.ID.String() + ":" + cacheKey
+	}
+	if b, ok := services.FeedCacheGet(c.Context(), cacheKey); ok {
+		return sendCachedJSON(c, b)
+	}
+
 	image, err := h.imageRepo.GetByID(ctx, imageID)
-	if err != nil {
+	if err != nil || !onRequestSite(c, image.TenantID) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Image not found",
 		})
 	}
+	image.Links = image.Links.WithRel(image.UserVerified)
+	image.HasWorkflow = image.WorkflowKey != nil
+	// Held and disabled images are visible to their owner and moderators only, and never cached
+	if image.IsWithheld() {
+		if !h.canSeePending(c, image.UserID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+		}
+		return c.JSON(image)
+	}
+	// Private images exist only for their owner; unlisted ones are served to anyone with the link
+	if image.IsPrivate() {
+		if middleware.OptionalUserID(c) != image.UserID {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+		}
+		return c.JSON(image)
+	}
+
+	return respondCacheable(c, cacheKey, image)
+}
+
+// respondCacheable writes v as JSON and stores it in the feed cache when key is set.
+func respondCacheable(c *fiber.Ctx, key string, v interface{}) error {
+	if key == "" {
+		return c.JSON(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return c.JSON(v)
+	}
+	services.FeedCacheSet(c.Context(), key, b)
+	c.Set("X-Cache", "MISS")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(b)
+}
 
-	return c.JSON(image)
+func sendCachedJSON(c *fiber.Ctx, b []byte) error {
+	c.Set("X-Cache", "HIT")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(b)
 }
 
 // LikeImage has been deprecated and is intentionally disabled
@@ -441,7 +921,7 @@ func (h *ImageHandler) CollectImage(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 	img, err := h.imageRepo.GetByID(ctx, imageID)
-	if err != nil || img == nil {
+	if err != nil || img == nil || img.IsWithheld() || (img.IsPrivate() && img.UserID != userID) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
 	}
 	// Disallow collecting own image
@@ -456,11 +936,20 @@ func (h *ImageHandler) CollectImage(c *fiber.Ctx) error {
 		if err := h.collectRepo.Delete(userID, imageID); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to uncollect image"})
 		}
+		services.InvalidateFeedCache(c.Context())
+		h.publishCollectedCount(img)
 		return c.JSON(fiber.Map{"collected": false})
 	}
+	if h.blockedBy(c, img.UserID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot collect this user's images"})
+	}
 	if err := h.collectRepo.Create(userID, imageID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to collect image"})
 	}
+	services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationCollected, ActorID: &userID, ImageID: &imageID})
+	// Cached feed pages carry collected counts
+	services.InvalidateFeedCache(c.Context())
+	h.publishCollectedCount(img)
 	return c.JSON(fiber.Map{"collected": true})
 }
 
@@ -494,9 +983,15 @@ func (h *ImageHandler) UpdateImage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
 	type body struct {
-		Title   *string `json:"title"`
-		Caption *string `json:"caption"`
-		IsNSFW  *bool   `json:"is_nsfw"`
+		Title      *string             `json:"title"`
+		Caption    *string             `json:"caption"`
+		IsNSFW     *bool               `json:"is_nsfw"`
+		Rating     *string             `json:"rating"`
+		Visibility *string             `json:"visibility"`
+		License    *string             `json:"license"`
+		RemixOf    *string             `json:"remix_of"`
+		Links      *[]models.ImageLink `json:"links"`
+		Tags       *[]string           `json:"tags"`
 	}
 	var b body
 	if err := c.BodyParser(&b); err != nil {
@@ -517,9 +1012,103 @@ func (h *ImageHandler) UpdateImage(c *fiber.Ctx) error {
 		}
 		b.Caption = &s
 	}
-	if err := h.imageRepo.UpdateMeta(imgID, b.Title, b.Caption, b.IsNSFW); err != nil {
+	if b.Visibility != nil {
+		v := strings.ToLower(strings.TrimSpace(*b.Visibility))
+		if !models.ValidImageVisibility(v) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "visibility must be public, unlisted or private"})
+		}
+		// Only the owner decides who may see their image
+		if !isOwner && v != img.Visibility {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can change visibility"})
+		}
+		b.Visibility = &v
+	}
+	if b.License != nil {
+		l := strings.ToLower(strings.TrimSpace(*b.License))
+		if _, ok := models.LicenseByID(l); l != "" && !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown license"})
+		}
+		// Licensing is the rights holder's call
+		if !isOwner && l != img.License {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can change the license"})
+		}
+		b.License = &l
+	}
+	var links models.ImageLinks
+	if b.Links != nil {
+		if links, err = models.NormalizeImageLinks(*b.Links); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	var tags models.ImageTags
+	if b.Tags != nil {
+		if tags, err = models.NormalizeImageTags(*b.Tags); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	var remixOf *uuid.UUID
+	var remixURL *string
+	if b.RemixOf != nil {
+		// Crediting a source is the uploader's statement
+		if !isOwner {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can change the remix source"})
+		}
+		var uerr *uploadError
+		if remixOf, remixURL, uerr = h.resolveRemixSource(c, imgID, *b.RemixOf); uerr != nil {
+			return c.Status(uerr.status).JSON(fiber.Map{"error": uerr.msg})
+		}
+	}
+	// rating takes precedence over the older is_nsfw flag, which moves the rating across
+	// the NSFW line only when it is on the other side
+	var rating *models.ContentRating
+	if b.Rating != nil {
+		r, ok := models.ParseRating(*b.Rating)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rating must be safe, suggestive, mature or explicit"})
+		}
+		rating = &r
+	} else if b.IsNSFW != nil {
+		r := img.EffectiveRating().WithNSFW(*b.IsNSFW)
+		rating = &r
+	}
+	if err := h.imageRepo.UpdateMeta(imgID, b.Title, b.Caption, rating); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
 	}
+	edits := models.MetaEdits(&img.Image, b.Title, b.Caption, rating)
+	if b.Links != nil {
+		if e, changed := models.LinksEdit(&img.Image, links); changed {
+			if err := h.imageRepo.SetLinks(imgID, links); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+			}
+			edits = append(edits, e)
+		}
+	}
+	if b.Tags != nil {
+		if e, changed := models.TagsEdit(&img.Image, tags); changed {
+			if err := h.imageRepo.SetTags(imgID, tags); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+			}
+			edits = append(edits, e)
+		}
+	}
+	recordImageEdits(c.Context(), h.edits, edits, userID, !isOwner)
+	if b.Visibility != nil && *b.Visibility != img.Visibility {
+		if err := h.imageRepo.SetVisibility(imgID, *b.Visibility); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+		}
+	}
+	if b.License != nil && *b.License != img.License {
+		if err := h.imageRepo.SetLicense(imgID, *b.License); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+		}
+	}
+	if b.RemixOf != nil {
+		if err := h.imageRepo.SetRemixOf(imgID, remixOf, remixURL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+		}
+	}
+	services.InvalidateFeedCache(c.Context())
+	purgeImageFromCDN(c, h.settingsRepo, &img.Image, false)
 	updated, _ := h.imageRepo.GetByID(ctx, imgID)
 	return c.JSON(updated)
 }
@@ -551,29 +1140,50 @@ func (h *ImageHandler) DeleteImage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
 	}
 	// Remove file from storage first; if it's already gone, continue
-	if img.Filename != "" {
-		st := services.GetCurrentStorage()
-		if st == nil {
-			st = h.storage
-		}
-		if st == nil {
-			st = services.NewLocalStorage("uploads")
-		}
-		// Extract the actual storage key from filename (which might be a full URL)
-		storageKey := extractStorageKey(img.Filename)
-		if remErr := st.Delete(c.Context(), storageKey); remErr != nil {
-			// best-effort; ignore not found
-		}
-	}
+	h.removeStoredFiles(c.Context(), &img.Image)
 	if err := h.imageRepo.Delete(imgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete image"})
 	}
+	services.InvalidateFeedCache(c.Context())
+	purgeImageFromCDN(c, h.settingsRepo, &img.Image, true)
+	services.EmitWebhook(services.WebhookImageDeleted, map[string]interface{}{"id": imgID, "user_id": img.UserID, "deleted_by": userID, "moderation": !isOwner})
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// removeStoredFiles deletes an image's file, its workflow sidecar, the previous versions
+// of a replaced file and, for videos, its poster.
+func (h *ImageHandler) removeStoredFiles(ctx context.Context, img *models.Image) {
+	h.removeImageFile(ctx, img.Filename)
+	if img.PosterFilename != nil {
+		h.removeImageFile(ctx, *img.PosterFilename)
+	}
+	if img.WorkflowKey != nil {
+		_ = h.currentStorage().Delete(ctx, *img.WorkflowKey)
+	}
+	h.removeImageVersions(ctx, img.ID)
+}
+
+// removeImageFile deletes an image's file from storage, best-effort.
+func (h *ImageHandler) removeImageFile(ctx context.Context, filename string) {
+	if filename == "" {
+		return
+	}
+	st := services.GetCurrentStorage()
+	if st == nil {
+		st = h.storage
+	}
+	if st == nil {
+		st = services.NewLocalStorage(services.UploadsDir())
+	}
+	// Extract the actual storage key from filename (which might be a full URL)
+	if remErr := st.Delete(ctx, extractStorageKey(filename)); remErr != nil {
+		// best-effort; ignore not found
+	}
+}
+
 // detectAIStreaming performs AI detection on large files without full buffering
 // It reads strategic sections of the file to find AI markers
-func detectAIStreaming(src multipart.File, fileSize int64) (bool, services.AIDetectionResult) {
+func detectAIStreaming(src io.ReadSeeker, fileSize int64) (bool, services.AIDetectionResult) {
 	// Create a buffer for reading sections
 	buf := make([]byte, 32*1024) // 32KB buffer
 
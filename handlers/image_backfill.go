@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// AdminBackfillImageMeta queues a job computing the blurhash and dominant color of images
+// that lack them. Progress is at the backfill status endpoint.
+func (h *AdminHandler) AdminBackfillImageMeta(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	st := h.storage
+	if st == nil {
+		st = services.GetCurrentStorage()
+	}
+	if st == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage not configured"})
+	}
+	if _, ok := st.(services.ObjectReader); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Storage does not support reading objects"})
+	}
+	if handled, err := h.enqueueAdminJob(c, services.JobImageMetaBackfill, struct{}{}, true); handled {
+		return err
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+}
+
+// AdminImageMetaBackfillStatus reports the latest backfill job, whose result holds the
+// progress so far while it runs, and how many images still lack metadata.
+func (h *AdminHandler) AdminImageMetaBackfillStatus(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	list, _, err := h.jobs.List("", services.JobImageMetaBackfill, 1, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load backfill status"})
+	}
+	out := fiber.Map{"job": nil, "running": false, "missing": nil}
+	if db := models.DB(); db != nil {
+		if n, err := services.CountImagesMissingMeta(db); err == nil {
+			out["missing"] = n
+		}
+	}
+	if len(list) > 0 {
+		job := list[0]
+		out["job"] = job
+		out["running"] = job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning
+	}
+	return c.JSON(out)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type backfillJobRepo struct {
+	models.JobRepositoryInterface
+	jobs []models.Job
+}
+
+func (f backfillJobRepo) List(status, kind string, page, limit int) ([]models.Job, int, error) {
+	var out []models.Job
+	for _, j := range f.jobs {
+		if kind == "" || j.Kind == kind {
+			out = append(out, j)
+		}
+	}
+	return out, len(out), nil
+}
+
+func TestAdminBackfillImageMeta_RequiresReadableStorage(t *testing.T) {
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithStorage(&probeStorage{})
+	app.Post("/admin/images/backfill-meta", h.AdminBackfillImageMeta)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodPost, "/admin/images/backfill-meta", nil))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for storage without reads, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminImageMetaBackfillStatus(t *testing.T) {
+	app := fiber.New()
+	repo := backfillJobRepo{jobs: []models.Job{
+		{ID: uuid.New(), Kind: services.JobImageMetaBackfill, Status: models.JobStatusRunning},
+		{ID: uuid.New(), Kind: services.JobBackup, Status: models.JobStatusDone},
+	}}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithJobs(repo)
+	app.Get("/admin/images/backfill-meta", h.AdminImageMetaBackfillStatus)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/admin/images/backfill-meta", nil))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Job     *models.Job `json:"job"`
+		Running bool        `json:"running"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Job == nil || body.Job.Kind != services.JobImageMetaBackfill || !body.Running {
+		t.Fatalf("expected the running backfill job, got %+v", body)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// recordImageEdits writes edits to an image's history, attributed to editorID. Failures
+// are logged and do not undo the edit.
+func recordImageEdits(ctx context.Context, repo models.ImageEditRepositoryInterface, edits []models.ImageEdit, editorID uuid.UUID, byModerator bool) {
+	if repo == nil {
+		return
+	}
+	for i := range edits {
+		e := &edits[i]
+		e.EditorID, e.ByModerator = &editorID, byModerator
+		if err := repo.Record(e); err != nil {
+			services.Logger(ctx).Warn("image: recording edit failed", "image_id", e.ImageID.String(), "field", e.Field, "error", err.Error())
+		}
+	}
+}
+
+// ListImageEdits returns an image's edit history, newest first, to moderators: who
+// changed the title, caption, rating or file, when, and what it was before.
+func (h *ImageHandler) ListImageEdits(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.edits == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Edit history not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := h.imageRepo.GetByID(ctx, id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	edits, err := h.edits.ListForImage(id, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch edit history"})
+	}
+	return c.JSON(fiber.Map{"edits": edits})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+func (f *fakeImageEdits) ListForImage(imageID uuid.UUID, _ int) ([]models.ImageEdit, error) {
+	out := []models.ImageEdit{}
+	for _, e := range f.recorded {
+		if e.ImageID == imageID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestUpdateImage_RecordsEdits(t *testing.T) {
+	ownerID, modID, imageID := uuid.New(), uuid.New(), uuid.New()
+	title := "Harbour"
+	images := &ratingImageRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		imageID: {Image: models.Image{ID: imageID, UserID: ownerID, OriginalName: &title, Rating: models.RatingSafe}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{ownerID: {ID: ownerID}, modID: {ID: modID, IsModerator: true}}}
+	edits := &fakeImageEdits{}
+	h := NewImageHandler(images, nil, users, services.Config{}, nil).WithImageEdits(edits)
+	caller := ownerID
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	})
+	app.Patch("/images/:id", h.UpdateImage)
+	app.Get("/images/:id/edits", h.ListImageEdits)
+	do := func(as uuid.UUID, method, path, body string) int {
+		caller = as
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := do(ownerID, http.MethodPatch, "/images/"+imageID.String(), `{"title":"Harbour","caption":"At dusk"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(edits.recorded) != 1 || edits.recorded[0].Field != models.ImageEditCaption || edits.recorded[0].OldValue != nil || edits.recorded[0].ByModerator {
+		t.Fatalf("expected only the owner's new caption recorded, got %+v", edits.recorded)
+	}
+	if code := do(modID, http.MethodPatch, "/images/"+imageID.String(), `{"title":"Harbor","is_nsfw":true}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(edits.recorded) != 3 {
+		t.Fatalf("expected the moderator's title and rating changes recorded, got %+v", edits.recorded)
+	}
+	for _, e := range edits.recorded[1:] {
+		if !e.ByModerator || *e.EditorID != modID {
+			t.Fatalf("expected the edit attributed to the moderator, got %+v", e)
+		}
+	}
+	if e := edits.recorded[1]; e.Field != models.ImageEditTitle || *e.OldValue != "Harbour" || *e.NewValue != "Harbor" {
+		t.Fatalf("expected the old title kept, got %+v", e)
+	}
+	if e := edits.recorded[2]; e.Field != models.ImageEditRating || *e.OldValue != "safe" || *e.NewValue != "explicit" {
+		t.Fatalf("expected the rating change recorded, got %+v", e)
+	}
+
+	if code := do(ownerID, http.MethodGet, "/images/"+imageID.String()+"/edits", ""); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for the owner, got %d", code)
+	}
+	if code := do(modID, http.MethodGet, "/images/"+imageID.String()+"/edits", ""); code != http.StatusOK {
+		t.Fatalf("expected 200 for a moderator, got %d", code)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type linksImageRepo struct {
+	remixImageRepo
+}
+
+func (f *linksImageRepo) SetLinks(id uuid.UUID, links models.ImageLinks) error {
+	f.images[id].Links = links
+	return nil
+}
+
+func TestUpdateImage_Links(t *testing.T) {
+	ownerID, id := uuid.New(), uuid.New()
+	repo := &linksImageRepo{remixImageRepo{visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		id: {Image: models.Image{ID: id, UserID: ownerID, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+	}}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{ownerID: {ID: ownerID}}}
+	edits := &fakeImageEdits{}
+	h := NewImageHandler(repo, nil, users, services.Config{}, nil).WithImageEdits(edits)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", ownerID)
+		return c.Next()
+	})
+	app.Patch("/images/:id", h.UpdateImage)
+	app.Get("/images/:id", h.GetImage)
+	patch := func(body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/images/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	four := `{"links":[{"url":"https://a.example"},{"url":"https://b.example"},{"url":"https://c.example"},{"url":"https://d.example"}]}`
+	if code := patch(four); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for more than 3 links, got %d", code)
+	}
+	if code := patch(`{"links":[{"url":"javascript:alert(1)"}]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-http link, got %d", code)
+	}
+	if code := patch(`{"links":[{"label":"  ComfyUI   workflow ","url":" https://gist.example/koi.json "},{"url":"https://gist.example/koi.json"}]}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	got := repo.images[id].Links
+	if len(got) != 1 || got[0].Label != "ComfyUI workflow" || got[0].URL != "https://gist.example/koi.json" || got[0].Rel != "" {
+		t.Fatalf("expected one trimmed link stored without rel, got %+v", got)
+	}
+	if len(edits.recorded) != 1 || edits.recorded[0].Field != models.ImageEditLinks || *edits.recorded[0].OldValue != "[]" {
+		t.Fatalf("expected the links change recorded, got %+v", edits.recorded)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+id.String(), nil))
+	var out models.ImageWithUser
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || len(out.Links) != 1 || out.Links[0].Rel != models.ImageLinkRelNoFollow {
+		t.Fatalf("expected the link served as nofollow for an unverified uploader, got %+v %v", out.Links, err)
+	}
+}
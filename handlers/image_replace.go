@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithImageEdits records changes made to images after upload.
+func (h *ImageHandler) WithImageEdits(r models.ImageEditRepositoryInterface) *ImageHandler {
+	h.edits = r
+	return h
+}
+
+// currentStorage returns the storage new files are written to.
+func (h *ImageHandler) currentStorage() services.Storage {
+	if st := services.GetCurrentStorage(); st != nil {
+		return st
+	}
+	if h.storage != nil {
+		return h.storage
+	}
+	return services.NewLocalStorage(services.UploadsDir())
+}
+
+// versionImageFile copies an image's current file under versions/<image id>/ and returns
+// the key it was kept at.
+func (h *ImageHandler) versionImageFile(ctx context.Context, img *models.Image) (string, error) {
+	rc, err := h.openMaster(ctx, img.Filename)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	key := fmt.Sprintf("%s%s/%d-%s", models.ImageVersionPrefix, img.ID, time.Now().Unix(), extractStorageKey(img.Filename))
+	if _, err := h.currentStorage().Save(ctx, key, rc, mime.TypeByExtension(path.Ext(key))); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// removeImageVersions deletes the previous files kept for a replaced image, where the
+// storage can list them.
+func (h *ImageHandler) removeImageVersions(ctx context.Context, id uuid.UUID) {
+	st := h.currentStorage()
+	lister, ok := st.(services.ObjectLister)
+	if !ok {
+		return
+	}
+	objects, err := lister.List(ctx, models.ImageVersionPrefix+id.String()+"/")
+	if err != nil {
+		return
+	}
+	for _, o := range objects {
+		_ = st.Delete(ctx, o.Key)
+	}
+}
+
+// ReplaceImageFile swaps the file of an image the caller owns for a new upload, such as
+// an upscaled version. The upload goes through the same validation and AI detection as a
+// new one; the id, URL, collections and counters stay, the previous file is kept under
+// versions/, and the change is recorded in the image's edit history.
+func (h *ImageHandler) ReplaceImageFile(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if img.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if img.IsVideo() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only images can be replaced"})
+	}
+	// A file under a copyright review stays as it is until staff decide
+	if img.ModerationStatus == models.ImageStatusDisabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Image is disabled pending review"})
+	}
+	u, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	if s := u.ActiveSuspension(); s != nil {
+		return c.Status(fiber.StatusForbidden).JSON(suspensionError(s))
+	}
+	if u.PendingApproval {
+		return c.Status(fiber.StatusForbidden).JSON(pendingApprovalError)
+	}
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No image file provided"})
+	}
+	if services.IsVideoUpload(file.Filename, file.Header.Get("Content-Type")) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only images can be replaced"})
+	}
+	if strings.Contains(file.Header.Get("Content-Type"), "bmp") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "BMP files rarely contain AI metadata. Please use JPEG, PNG, WebP, or GIF."})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open uploaded file"})
+	}
+	defer src.Close()
+	result, _, err := services.NewFileValidator().ValidateImageStream(file.Filename, src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate file"})
+	}
+	if !result.IsValid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": result.ErrorMessage})
+	}
+
+	req := uploadRequest{
+		UserID:    userID,
+		Filename:  file.Filename,
+		Size:      file.Size,
+		License:   img.License,
+		Uploader:  u.Username,
+		StripExif: u.StripExif || services.GetCachedSettings(h.settingsRepo).ExifPrivacyMode,
+		TenantID:  img.TenantID,
+	}
+	replacement, cleanup, uerr := h.storeUpload(c.Context(), req, src)
+	if uerr != nil {
+		return c.Status(uerr.status).JSON(fiber.Map{"error": uerr.msg})
+	}
+	versionKey, err := h.versionImageFile(c.Context(), &img.Image)
+	if err != nil {
+		cleanup()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to keep the previous version"})
+	}
+	replacement.ID = img.ID
+	if err := h.imageRepo.ReplaceFile(replacement); err != nil {
+		cleanup()
+		_ = h.currentStorage().Delete(c.Context(), versionKey)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save image metadata"})
+	}
+	h.removeImageFile(c.Context(), img.Filename)
+	if img.WorkflowKey != nil {
+		_ = h.currentStorage().Delete(c.Context(), *img.WorkflowKey)
+	}
+	if h.edits != nil {
+		if err := h.edits.Record(&models.ImageEdit{ImageID: id, EditorID: &userID, Field: models.ImageEditFile, OldValue: &versionKey, NewValue: &replacement.Filename}); err != nil {
+			services.Logger(c.Context()).Warn("image: recording file replacement failed", "image_id", id.String(), "error", err.Error())
+		}
+	}
+	services.InvalidateFeedCache(c.Context())
+	purgeImageFromCDN(c, h.settingsRepo, &img.Image, true)
+	services.Logger(c.Context()).Info("image: file replaced", "image_id", id.String(), "version_key", versionKey)
+
+	ctx, cancel = context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	updated, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load image"})
+	}
+	return c.JSON(updated)
+}
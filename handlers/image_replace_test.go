@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type replaceImageRepo struct {
+	visibilityImageRepo
+	replaced *models.Image
+}
+
+func (f *replaceImageRepo) ReplaceFile(img *models.Image) error {
+	f.replaced = img
+	cur := *f.images[img.ID]
+	cur.Filename, cur.Width, cur.FrameCount = img.Filename, img.Width, img.FrameCount
+	f.images[img.ID] = &cur
+	return nil
+}
+
+type fakeImageEdits struct {
+	models.ImageEditRepositoryInterface
+	recorded []models.ImageEdit
+}
+
+func (f *fakeImageEdits) Record(e *models.ImageEdit) error {
+	f.recorded = append(f.recorded, *e)
+	return nil
+}
+
+func TestReplaceImageFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "old.png"), []byte("old-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ownerID, otherID, id := uuid.New(), uuid.New(), uuid.New()
+	repo := &replaceImageRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		id: {Image: models.Image{ID: id, UserID: ownerID, Filename: "old.png", ModerationStatus: models.ImageStatusApproved, LikesCount: 7}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{ownerID: {ID: ownerID, Username: "ada"}, otherID: {ID: otherID}}}
+	edits := &fakeImageEdits{}
+	cfg := services.Config{Animation: services.AnimationConfig{MaxFrames: 10, MaxDuration: 10 * time.Second}}
+	h := NewImageHandler(repo, nil, users, cfg, services.NewLocalStorage(dir)).WithImageEdits(edits)
+	var caller uuid.UUID
+	app := fiber.New()
+	app.Post("/images/:id/file", func(c *fiber.Ctx) error { c.Locals("user_id", caller); return c.Next() }, h.ReplaceImageFile)
+	post := func() (int, string) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("image", "koi-upscaled.gif")
+		_, _ = part.Write(aiGIF(t))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/images/"+id.String()+"/file", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(msg)
+	}
+
+	caller = otherID
+	if code, _ := post(); code != http.StatusForbidden || repo.replaced != nil {
+		t.Fatalf("expected 403 for someone else's image, got %d", code)
+	}
+	caller = ownerID
+	if code, msg := post(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, msg)
+	}
+	if repo.replaced == nil || repo.replaced.ID != id || filepath.Ext(repo.replaced.Filename) != ".gif" || repo.replaced.FrameCount != 3 {
+		t.Fatalf("expected the row pointed at the new file, got %+v", repo.replaced)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.png")); !os.IsNotExist(err) {
+		t.Fatal("expected the old file moved out of the image's place")
+	}
+	if len(edits.recorded) != 1 || edits.recorded[0].Field != models.ImageEditFile || *edits.recorded[0].EditorID != ownerID {
+		t.Fatalf("expected the replacement recorded, got %+v", edits.recorded)
+	}
+	key := *edits.recorded[0].OldValue
+	if !strings.HasPrefix(key, models.ImageVersionPrefix+id.String()+"/") {
+		t.Fatalf("expected the old file versioned under the image, got %q", key)
+	}
+	if kept, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key))); err != nil || string(kept) != "old-bytes" {
+		t.Fatalf("expected the previous file kept at %q, got %q %v", key, kept, err)
+	}
+
+	repo.images[id].ModerationStatus = models.ImageStatusDisabled
+	if code, _ := post(); code != http.StatusConflict {
+		t.Fatalf("expected 409 while the image is disabled pending review, got %d", code)
+	}
+}
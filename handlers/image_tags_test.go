@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type tagsImageRepo struct {
+	remixImageRepo
+}
+
+func (f *tagsImageRepo) SetTags(id uuid.UUID, tags models.ImageTags) error {
+	f.images[id].Tags = tags
+	return nil
+}
+
+func TestUpdateImage_Tags(t *testing.T) {
+	ownerID, id := uuid.New(), uuid.New()
+	repo := &tagsImageRepo{remixImageRepo{visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		id: {Image: models.Image{ID: id, UserID: ownerID, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+	}}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{ownerID: {ID: ownerID}}}
+	edits := &fakeImageEdits{}
+	h := NewImageHandler(repo, nil, users, services.Config{}, nil).WithImageEdits(edits)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", ownerID)
+		return c.Next()
+	})
+	app.Patch("/images/:id", h.UpdateImage)
+	app.Get("/images/:id", h.GetImage)
+	patch := func(body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/images/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := patch(`{"tags":["a","b","c","d","e","f","g","h","i","j","k"]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for more than 10 tags, got %d", code)
+	}
+	if code := patch(`{"tags":["<script>"]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for punctuation in a tag, got %d", code)
+	}
+	if code := patch(`{"tags":["  Koi   Pond ","koi pond","ink-wash",""]}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	got := repo.images[id].Tags
+	if len(got) != 2 || got[0] != "koi pond" || got[1] != "ink-wash" {
+		t.Fatalf("expected two normalized tags, got %q", got)
+	}
+	if len(edits.recorded) != 1 || edits.recorded[0].Field != models.ImageEditTags || *edits.recorded[0].OldValue != "[]" {
+		t.Fatalf("expected the tags change recorded, got %+v", edits.recorded)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+id.String(), nil))
+	var out models.ImageWithUser
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || len(out.Tags) != 2 {
+		t.Fatalf("expected the tags served with the image, got %+v %v", out.Tags, err)
+	}
+}
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type visibilityImageRepo struct {
+	models.ImageRepositoryInterface
+	images map[uuid.UUID]*models.ImageWithUser
+}
+
+func (f *visibilityImageRepo) GetByID(_ context.Context, id uuid.UUID) (*models.ImageWithUser, error) {
+	if img, ok := f.images[id]; ok {
+		return img, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetVersion treats CreatedAt as the fake's change time
+func (f *visibilityImageRepo) GetVersion(_ context.Context, id uuid.UUID) (*models.ImageVersion, error) {
+	img, ok := f.images[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &models.ImageVersion{UserID: img.UserID, ModerationStatus: img.ModerationStatus, Visibility: img.Visibility, UpdatedAt: img.CreatedAt, TenantID: img.TenantID}, nil
+}
+
+func TestGetImage_Visibility(t *testing.T) {
+	owner := uuid.New()
+	repo := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{}}
+	add := func(visibility string) uuid.UUID {
+		id := uuid.New()
+		repo.images[id] = &models.ImageWithUser{Image: models.Image{ID: id, UserID: owner, Visibility: visibility, ModerationStatus: models.ImageStatusApproved}}
+		return id
+	}
+	app := fiber.New()
+	app.Get("/images/:id", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, nil).GetImage)
+
+	for _, tc := range []struct {
+		visibility string
+		want       int
+	}{
+		{models.ImageVisibilityPublic, http.StatusOK},
+		{models.ImageVisibilityUnlisted, http.StatusOK},
+		{models.ImageVisibilityPrivate, http.StatusNotFound},
+	} {
+		id := add(tc.visibility)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+id.String(), http.NoBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s image: expected %d for an anonymous viewer, got %d", tc.visibility, tc.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestGetImage_ConditionalGet(t *testing.T) {
+	id, owner := uuid.New(), uuid.New()
+	img := &models.ImageWithUser{Image: models.Image{ID: id, UserID: owner, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved, CreatedAt: time.Now().Add(-time.Hour)}}
+	private := uuid.New()
+	repo := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		id:      img,
+		private: {Image: models.Image{ID: private, UserID: owner, Visibility: models.ImageVisibilityPrivate, ModerationStatus: models.ImageStatusApproved}},
+	}}
+	app := fiber.New()
+	app.Get("/images/:id", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, nil).GetImage)
+	get := func(id uuid.UUID, header, value string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/images/"+id.String(), http.NoBody)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := get(id, "", "")
+	tag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || !strings.HasPrefix(tag, `"`) || first.Header.Get("Last-Modified") == "" {
+		t.Fatalf("first fetch: status %d, etag %q, last-modified %q", first.StatusCode, tag, first.Header.Get("Last-Modified"))
+	}
+	if resp := get(id, "If-None-Match", "W/"+tag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("matching If-None-Match: expected 304, got %d", resp.StatusCode)
+	}
+	if resp := get(id, "If-Modified-Since", time.Now().UTC().Format(http.TimeFormat)); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("later If-Modified-Since: expected 304, got %d", resp.StatusCode)
+	}
+	img.CreatedAt = time.Now()
+	if resp := get(id, "If-None-Match", tag); resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == tag {
+		t.Fatalf("after a change: expected 200 with a new tag, got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if resp := get(private, "", ""); resp.StatusCode != http.StatusNotFound || resp.Header.Get("ETag") != "" {
+		t.Fatalf("private image: expected an untagged 404, got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+// sinceFeedRepo serves feed polls from newer, newest first
+type sinceFeedRepo struct {
+	visibilityImageRepo
+	newer []models.ImageWithUser
+	since models.FeedSeekCursor
+}
+
+func (f *sinceFeedRepo) GetFeedSince(limit int, _ models.FeedFilter, since models.FeedSeekCursor) ([]models.ImageWithUser, bool, error) {
+	f.since = since
+	if len(f.newer) > limit {
+		return f.newer[:limit], true, nil
+	}
+	return f.newer, false, nil
+}
+
+func TestGetFeed_Since(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	seen := models.ImageWithUser{Image: models.Image{ID: uuid.New(), CreatedAt: base}}
+	repo := &sinceFeedRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{seen.ID: &seen}}}
+	app := fiber.New()
+	app.Get("/feed", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, nil).GetFeed)
+	get := func(query string, header ...string) (*http.Response, models.FeedResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/feed?"+query, http.NoBody)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out models.FeedResponse
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	cursor := models.EncodeCursor(seen.CreatedAt, seen.ID)
+
+	if resp, _ := get("since=not-a-cursor"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad cursor: expected 400, got %d", resp.StatusCode)
+	}
+	// Nothing new: the cursor comes back unchanged, or a 304 for a conditional request
+	resp, out := get("since_id=" + seen.ID.String())
+	if resp.StatusCode != http.StatusOK || len(out.Images) != 0 || out.SinceCursor != cursor || !repo.since.CreatedAt.Equal(base) || repo.since.ID != seen.ID {
+		t.Fatalf("since_id with nothing new: %d %+v (repo saw %+v)", resp.StatusCode, out, repo.since)
+	}
+	if resp, _ := get("since="+cursor, "If-Modified-Since", resp.Header.Get("Last-Modified")); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional poll with nothing new: expected 304, got %d", resp.StatusCode)
+	}
+
+	newest := models.ImageWithUser{Image: models.Image{ID: uuid.New(), CreatedAt: base.Add(2 * time.Minute)}}
+	older := models.ImageWithUser{Image: models.Image{ID: uuid.New(), CreatedAt: base.Add(time.Minute)}}
+	repo.newer = []models.ImageWithUser{newest, older}
+	resp, out = get("since="+cursor, "If-Modified-Since", resp.Header.Get("Last-Modified"))
+	if resp.StatusCode != http.StatusOK || len(out.Images) != 2 || out.Truncated || out.SinceCursor != models.EncodeCursor(newest.CreatedAt, newest.ID) {
+		t.Fatalf("poll with new images: %d %+v", resp.StatusCode, out)
+	}
+	if _, out := get("limit=1&since=" + cursor); len(out.Images) != 1 || !out.Truncated {
+		t.Fatalf("poll over the limit should be truncated: %+v", out)
+	}
+}
+
+func TestDownloadImage_ServesOriginalAsAttachment(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "abc.png"), []byte("png-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	title := "Night Garden.png"
+	id := uuid.New()
+	repo := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		id: {Image: models.Image{ID: id, UserID: uuid.New(), Filename: "abc.png", OriginalName: &title, ModerationStatus: models.ImageStatusApproved}},
+	}}
+	app := fiber.New()
+	app.Get("/images/:id/download", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, services.NewLocalStorage(dir)).DownloadImage)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+id.String()+"/download", http.NoBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="Night Garden.png"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "image/png" {
+		t.Fatalf("unexpected Content-Type %q", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "png-bytes" {
+		t.Fatalf("expected the stored master, got %q", body)
+	}
+}
+
+type createImageRepo struct {
+	models.ImageRepositoryInterface
+	created *models.Image
+}
+
+func (f *createImageRepo) Create(img *models.Image) error {
+	img.ID = uuid.New()
+	f.created = img
+	return nil
+}
+
+// aiGIF returns a 3-frame 20x10 GIF carrying generation parameters, which passes AI
+// detection and is stored as uploaded.
+func aiGIF(t *testing.T) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 20, 10), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	// The provenance lives in a comment block ahead of the frames
+	raw := buf.Bytes()
+	at := bytes.Index(raw, []byte{0x21, 0xf9})
+	comment := "prompt: neon koi, Steps: 30, Sampler: DPM++ 2M, CFG scale: 7, Seed: 42"
+	ext := append([]byte{0x21, 0xfe, byte(len(comment))}, comment...)
+	return append(append(append([]byte(nil), raw[:at]...), append(ext, 0)...), raw[at:]...)
+}
+
+func TestUpload_AnimatedGIFStoredAsIs(t *testing.T) {
+	upload := aiGIF(t)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("image", "koi.gif")
+	_, _ = part.Write(upload)
+	_ = mw.Close()
+
+	dir := t.TempDir()
+	repo := &createImageRepo{}
+	cfg := services.Config{Animation: services.AnimationConfig{MaxFrames: 10, MaxDuration: 10 * time.Second}}
+	h := NewImageHandler(repo, nil, nil, cfg, services.NewLocalStorage(dir))
+	app := fiber.New()
+	app.Post("/upload", func(c *fiber.Ctx) error { c.Locals("user_id", uuid.New()); return c.Next() }, h.Upload)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, msg)
+	}
+	img := repo.created
+	if img == nil || filepath.Ext(img.Filename) != ".gif" || img.FrameCount != 3 || img.DurationMS != 300 {
+		t.Fatalf("unexpected image row: %+v", img)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, img.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, upload) {
+		t.Fatal("animation was re-encoded instead of stored as uploaded")
+	}
+	if img.Width == nil || *img.Width != 20 || img.Blurhash == nil || *img.Blurhash == "" {
+		t.Fatalf("first-frame metadata missing: %+v", img)
+	}
+}
+
+func TestUpload_VideoDisabled(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("image", "clip.mp4")
+	_, _ = part.Write([]byte("\x00\x00\x00\x18ftypisom"))
+	_ = mw.Close()
+
+	repo := &createImageRepo{}
+	h := NewImageHandler(repo, nil, nil, services.Config{}, services.NewLocalStorage(t.TempDir()))
+	app := fiber.New()
+	app.Post("/upload", func(c *fiber.Ctx) error { c.Locals("user_id", uuid.New()); return c.Next() }, h.Upload)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || repo.created != nil || !strings.Contains(string(msg), "disabled") {
+		t.Fatalf("expected 400 with no row, got %d: %s", resp.StatusCode, msg)
+	}
+}
+
+type statusJobRepo struct {
+	models.JobRepositoryInterface
+	jobs map[uuid.UUID]*models.Job
+}
+
+func (f *statusJobRepo) Get(id uuid.UUID) (*models.Job, error) {
+	if j, ok := f.jobs[id]; ok {
+		return j, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestUploadStatus(t *testing.T) {
+	owner := uuid.New()
+	payload := []byte(`{"user_id":"` + owner.String() + `","staged":"x"}`)
+	image := json.RawMessage(`{"id":"abc"}`)
+	rejected := json.RawMessage(`{"code":400}`)
+	reason := "Upload rejected. Only AI-generated images"
+	done := &models.Job{ID: uuid.New(), Kind: services.JobUploadProcess, Status: models.JobStatusDone, Payload: payload, Result: &image}
+	failed := &models.Job{ID: uuid.New(), Kind: services.JobUploadProcess, Status: models.JobStatusDead, Payload: payload, Result: &rejected, LastError: &reason}
+	running := &models.Job{ID: uuid.New(), Kind: services.JobUploadProcess, Status: models.JobStatusRunning, Payload: payload}
+	other := &models.Job{ID: uuid.New(), Kind: services.JobBackup, Status: models.JobStatusDone, Payload: payload}
+	repo := &statusJobRepo{jobs: map[uuid.UUID]*models.Job{done.ID: done, failed.ID: failed, running.ID: running, other.ID: other}}
+
+	h := NewImageHandler(&createImageRepo{}, nil, nil, services.Config{}, nil).WithJobs(repo)
+	app := fiber.New()
+	viewer := owner
+	app.Get("/uploads/:token/status", func(c *fiber.Ctx) error { c.Locals("user_id", viewer); return c.Next() }, h.UploadStatus)
+
+	get := func(id uuid.UUID) (int, map[string]interface{}) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/uploads/"+id.String()+"/status", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if code, body := get(running.ID); code != http.StatusOK || body["status"] != UploadStatusProcessing {
+		t.Fatalf("running: %d %v", code, body)
+	}
+	if code, body := get(done.ID); code != http.StatusOK || body["status"] != UploadStatusDone || body["image"] == nil {
+		t.Fatalf("done: %d %v", code, body)
+	}
+	if code, body := get(failed.ID); code != http.StatusOK || body["status"] != UploadStatusFailed || body["error"] != reason || body["code"] != float64(400) {
+		t.Fatalf("failed: %d %v", code, body)
+	}
+	if code, _ := get(other.ID); code != http.StatusNotFound {
+		t.Fatalf("other job kinds must not be exposed, got %d", code)
+	}
+	viewer = uuid.New()
+	if code, _ := get(done.ID); code != http.StatusNotFound {
+		t.Fatalf("another user's upload must be hidden, got %d", code)
+	}
+}
+
+type viewerFeedRepo struct {
+	visibilityImageRepo
+	viewer uuid.UUID
+}
+
+func (f *viewerFeedRepo) ForViewer(viewer uuid.UUID) models.ImageRepositoryInterface {
+	return &viewerFeedRepo{visibilityImageRepo: f.visibilityImageRepo, viewer: viewer}
+}
+
+func (f *viewerFeedRepo) GetFeed(page, limit int, _ models.FeedFilter) ([]models.ImageWithUser, int, error) {
+	images := []models.ImageWithUser{}
+	for _, img := range f.images {
+		it := *img
+		if f.viewer != uuid.Nil {
+			collected := true
+			it.Collected = &collected
+		}
+		images = append(images, it)
+	}
+	return images, len(images), nil
+}
+
+func TestGetFeed_CollectedForViewer(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+	viewerID, imageID := uuid.New(), uuid.New()
+	repo := &viewerFeedRepo{visibilityImageRepo: visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		imageID: {Image: models.Image{ID: imageID}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{viewerID: {ID: viewerID, Username: "viewer"}}}
+	app := fiber.New()
+	app.Get("/feed", NewImageHandler(repo, nil, users, services.Config{}, nil).GetFeed)
+	get := func(token string) map[string]json.RawMessage {
+		req := httptest.NewRequest(http.MethodGet, "/feed?page=2", http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct {
+			Images []map[string]json.RawMessage `json:"images"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || len(out.Images) != 1 {
+			t.Fatalf("unexpected feed: %v %v", out, err)
+		}
+		return out.Images[0]
+	}
+
+	if v, ok := get("")["collected"]; ok {
+		t.Fatalf("anonymous feed should leave collected out, got %s", v)
+	}
+	token, err := middleware.GenerateToken(viewerID, "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := get(token)["collected"]; string(v) != "true" {
+		t.Fatalf("expected collected for the viewer, got %s", v)
+	}
+}
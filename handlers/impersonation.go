@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	impersonationDefaultTTL = 15 * time.Minute
+	impersonationMaxTTL     = time.Hour
+	// impersonatorCookie keeps the admin's own token while they impersonate, so ending the
+	// session restores it without minting a new admin token. It is only sent to the end route.
+	impersonatorCookie     = "impersonator_token"
+	impersonatorCookiePath = "/api/me/impersonation"
+)
+
+// WithAudit injects the admin audit log, which also holds impersonation sessions
+func (h *AdminHandler) WithAudit(r models.AuditRepositoryInterface) *AdminHandler {
+	h.audit = r
+	return h
+}
+
+// authCookieSecure mirrors the Secure flag used for the auth cookie at login.
+func authCookieSecure(c *fiber.Ctx) bool {
+	secure := strings.EqualFold(c.Protocol(), "https") || strings.EqualFold(strings.TrimSpace(c.Get("X-Forwarded-Proto")), "https")
+	if os.Getenv("FORCE_SECURE_COOKIES") == "1" || strings.EqualFold(os.Getenv("FORCE_SECURE_COOKIES"), "true") {
+		secure = true
+	}
+	if os.Getenv("ALLOW_INSECURE_COOKIES") == "1" || strings.EqualFold(os.Getenv("ALLOW_INSECURE_COOKIES"), "true") {
+		secure = false
+	}
+	return secure
+}
+
+// AdminImpersonate starts a time-boxed session in which the admin acts as another user, to
+// see what they see. Body: {"reason": "...", "minutes": 15}; a reason is required and at
+// most 60 minutes are granted. The response carries the session token and also sets it as
+// the auth cookie; the admin's own token is kept aside until the session ends. Every
+// request made in the session is written to the admin audit log. Admins cannot be impersonated.
+func (h *AdminHandler) AdminImpersonate(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) || middleware.GetImpersonation(c) != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.audit == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Audit log not configured"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	var body struct {
+		Reason  string `json:"reason"`
+		Minutes int    `json:"minutes"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+	if len([]rune(reason)) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason too long"})
+	}
+	ttl := impersonationDefaultTTL
+	if body.Minutes < 0 || time.Duration(body.Minutes)*time.Minute > impersonationMaxTTL {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "minutes must be between 1 and 60"})
+	} else if body.Minutes > 0 {
+		ttl = time.Duration(body.Minutes) * time.Minute
+	}
+	adminID := middleware.GetUserID(c)
+	if uid == adminID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot impersonate yourself"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	target, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if target.IsAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admins cannot be impersonated"})
+	}
+
+	session := &models.ImpersonationSession{AdminID: &adminID, UserID: uid, Reason: reason, IP: services.ClientIP(c), ExpiresAt: time.Now().Add(ttl)}
+	if err := h.audit.StartImpersonation(session); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start impersonation"})
+	}
+	token, err := middleware.GenerateImpersonationToken(target.ID, target.Username, adminID, session.ID, session.ExpiresAt)
+	if err != nil {
+		_ = h.audit.EndImpersonation(session.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	h.auditImpersonation(c, models.AuditImpersonationStarted, adminID, session, "reason: "+reason+"; until "+session.ExpiresAt.UTC().Format(time.RFC3339))
+	services.Logger(c.Context()).Warn("admin: impersonation started", "user_id", uid.String(), "by", adminID.String(), "session_id", session.ID.String(), "until", session.ExpiresAt)
+
+	secure := authCookieSecure(c)
+	if own := middleware.RequestToken(c); own != "" {
+		c.Cookie(&fiber.Cookie{Name: impersonatorCookie, Value: own, Path: impersonatorCookiePath, HTTPOnly: true, Secure: secure, SameSite: "Strict", MaxAge: middleware.TokenMaxAge()})
+	}
+	c.Cookie(&fiber.Cookie{Name: "auth_token", Value: token, Path: "/", HTTPOnly: true, Secure: secure, SameSite: "Lax", MaxAge: int(ttl.Seconds())})
+	return c.JSON(fiber.Map{
+		"token":         token,
+		"user":          target.ToResponse(),
+		"impersonation": middleware.Impersonation{SessionID: session.ID, AdminID: adminID, ExpiresAt: session.ExpiresAt},
+	})
+}
+
+// EndImpersonation closes the caller's impersonation session at once and restores the
+// admin's own session when its token was kept aside. The response token is then the
+// admin's, or empty when they need to sign in again.
+func (h *AdminHandler) EndImpersonation(c *fiber.Ctx) error {
+	imp := middleware.GetImpersonation(c)
+	if imp == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Not impersonating"})
+	}
+	if h.audit == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Audit log not configured"})
+	}
+	if err := h.audit.EndImpersonation(imp.SessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to end impersonation"})
+	}
+	userID := middleware.GetUserID(c)
+	h.auditImpersonation(c, models.AuditImpersonationEnded, imp.AdminID, &models.ImpersonationSession{ID: imp.SessionID, UserID: userID}, "")
+	services.Logger(c.Context()).Info("admin: impersonation ended", "user_id", userID.String(), "by", imp.AdminID.String(), "session_id", imp.SessionID.String())
+
+	secure := authCookieSecure(c)
+	c.Cookie(&fiber.Cookie{Name: impersonatorCookie, Value: "", Path: impersonatorCookiePath, HTTPOnly: true, Secure: secure, SameSite: "Strict", MaxAge: -1, Expires: time.Unix(0, 0)})
+	// Only a still-valid token of the impersonating admin is restored
+	own := c.Cookies(impersonatorCookie)
+	if claims, err := middleware.ParseToken(own); err == nil && claims.UserID == imp.AdminID && claims.Impersonator == nil {
+		c.Cookie(&fiber.Cookie{Name: "auth_token", Value: own, Path: "/", HTTPOnly: true, Secure: secure, SameSite: "Lax", MaxAge: middleware.TokenMaxAge()})
+		return c.JSON(fiber.Map{"token": own})
+	}
+	c.Cookie(&fiber.Cookie{Name: "auth_token", Value: "", Path: "/", HTTPOnly: true, Secure: secure, SameSite: "Lax", MaxAge: -1, Expires: time.Unix(0, 0)})
+	return c.JSON(fiber.Map{"token": ""})
+}
+
+// ListAdminAudit returns the admin audit log newest first; ?user_id= narrows it to one user.
+func (h *AdminHandler) ListAdminAudit(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.audit == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Audit log not configured"})
+	}
+	var target *uuid.UUID
+	if v := c.Query("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+		}
+		target = &id
+	}
+	if wantsCSV(c) {
+		return sendCSV(c, "audit", auditCSVHeader, func(page, limit int) ([][]string, error) {
+			list, _, err := h.audit.List(target, page, limit)
+			return auditCSVRows(list), err
+		})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.audit.List(target, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list audit", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"audit": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+func (h *AdminHandler) auditImpersonation(c *fiber.Ctx, action string, adminID uuid.UUID, s *models.ImpersonationSession, detail string) {
+	entry := &models.AdminAudit{
+		ActorID:      &adminID,
+		Action:       action,
+		TargetUserID: &s.UserID,
+		SessionID:    &s.ID,
+		IP:           services.ClientIP(c),
+		Detail:       detail,
+		RequestID:    services.RequestIDFromContext(c.UserContext()),
+	}
+	if err := h.audit.Add(entry); err != nil {
+		services.Logger(c.Context()).Error("admin: audit write failed", "action", action, "error", err)
+	}
+}
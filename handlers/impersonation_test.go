@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeAuditRepo struct {
+	models.AuditRepositoryInterface
+	sessions []models.ImpersonationSession
+	audit    []models.AdminAudit
+}
+
+func (f *fakeAuditRepo) StartImpersonation(s *models.ImpersonationSession) error {
+	s.ID = uuid.New()
+	f.sessions = append(f.sessions, *s)
+	return nil
+}
+
+func (f *fakeAuditRepo) Add(a *models.AdminAudit) error {
+	f.audit = append(f.audit, *a)
+	return nil
+}
+
+type impersonationUserRepo struct {
+	models.UserRepositoryInterface
+	users map[uuid.UUID]*models.User
+}
+
+func (f *impersonationUserRepo) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestAdminImpersonate(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+	adminID, userID, otherAdminID := uuid.New(), uuid.New(), uuid.New()
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID:      {ID: adminID, Username: "root", IsAdmin: true},
+		userID:       {ID: userID, Username: "alice"},
+		otherAdminID: {ID: otherAdminID, Username: "ops", IsAdmin: true},
+	}}
+	audit := &fakeAuditRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, users, &fakeImageRepo{}).WithAudit(audit)
+	app := fiber.New()
+	app.Post("/users/:id/impersonate", func(c *fiber.Ctx) error {
+		c.Locals("user_id", adminID)
+		c.SetUserContext(context.WithValue(c.UserContext(), services.RequestIDContextKey, "req-impersonate"))
+		return c.Next()
+	}, h.AdminImpersonate)
+	post := func(id uuid.UUID, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/users/"+id.String()+"/impersonate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-token")
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	if code := post(userID, `{}`).StatusCode; code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a reason, got %d", code)
+	}
+	if code := post(userID, `{"reason":"debug","minutes":120}`).StatusCode; code != http.StatusBadRequest {
+		t.Fatalf("expected 400 past the cap, got %d", code)
+	}
+	if code := post(otherAdminID, `{"reason":"debug"}`).StatusCode; code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an admin target, got %d", code)
+	}
+	if code := post(adminID, `{"reason":"debug"}`).StatusCode; code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for self, got %d", code)
+	}
+	if len(audit.sessions) != 0 {
+		t.Fatalf("refused requests must not open sessions: %+v", audit.sessions)
+	}
+
+	resp := post(userID, `{"reason":"reported broken feed","minutes":10}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	claims, err := middleware.ParseToken(out.Token)
+	if err != nil || claims.UserID != userID || claims.Impersonator == nil || *claims.Impersonator != adminID {
+		t.Fatalf("unexpected token claims %+v (%v)", claims, err)
+	}
+	if len(audit.sessions) != 1 || claims.ID != audit.sessions[0].ID.String() {
+		t.Fatalf("token must name the session, got %+v", audit.sessions)
+	}
+	if len(audit.audit) != 1 || audit.audit[0].Action != models.AuditImpersonationStarted || !strings.Contains(audit.audit[0].Detail, "reported broken feed") {
+		t.Fatalf("expected a started audit entry with the reason, got %+v", audit.audit)
+	}
+	if audit.audit[0].RequestID != "req-impersonate" {
+		t.Fatalf("expected the audit entry to carry the request ID, got %q", audit.audit[0].RequestID)
+	}
+	cookies := map[string]string{}
+	for _, ck := range resp.Cookies() {
+		cookies[ck.Name] = ck.Value
+	}
+	if cookies["auth_token"] != out.Token || cookies[impersonatorCookie] != "admin-token" {
+		t.Fatalf("expected the session cookie and the admin token kept aside, got %v", cookies)
+	}
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	inviteNoteMaxLen = 500
+	// personalInviteTTL is how long a user's personal invite stays usable.
+	personalInviteTTL       = 14 * 24 * time.Hour
+	personalInviteListLimit = 100
+	// sentInviteTTL is the default validity of an invite sent by email.
+	sentInviteTTL = 7 * 24 * time.Hour
+)
+
+// inviteLink builds the registration link for an invite, absolute when the site URL is set.
+func inviteLink(set *models.SiteSettings, code string) string {
+	base := ""
+	if set != nil {
+		base = strings.TrimRight(strings.TrimSpace(set.SiteURL), "/")
+	}
+	return base + "/register?invite=" + code
+}
+
+// parseInviteExpiry reads an invite's expiry from an RFC3339 expires_at, which wins, or a
+// duration such as "24h" or "7d". Both empty means no expiry; a bad value returns the
+// message for a 400.
+func parseInviteExpiry(expiresAt, duration *string) (*time.Time, string) {
+	if expiresAt != nil && strings.TrimSpace(*expiresAt) != "" {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(*expiresAt))
+		if err != nil {
+			return nil, "Invalid expires_at (use RFC3339)"
+		}
+		return &t, ""
+	}
+	if duration == nil || strings.TrimSpace(*duration) == "" {
+		return nil, ""
+	}
+	dstr := strings.ToLower(strings.TrimSpace(*duration))
+	var d time.Duration
+	var err error
+	if num, ok := strings.CutSuffix(dstr, "d"); ok {
+		var days int
+		if _, err = fmt.Sscanf(num, "%d", &days); err == nil {
+			d = 24 * time.Hour * time.Duration(days)
+		}
+	} else {
+		d, err = time.ParseDuration(dstr)
+	}
+	if err != nil || d <= 0 {
+		return nil, "Invalid duration"
+	}
+	t := time.Now().Add(d)
+	return &t, ""
+}
+
+// inviteAllowsEmail reports whether an account with email may register with inv. Invites
+// sent by email only work for that address.
+func inviteAllowsEmail(inv *models.Invite, email string) bool {
+	return inv.Email == "" || strings.EqualFold(inv.Email, strings.TrimSpace(email))
+}
+
+// personalInviteBlock explains why u may not issue personal invites, or returns "" when
+// they may. Staff always qualify while a quota is set; other users need an account old
+// enough, a verified email when verification is required, and good standing.
+func personalInviteBlock(u *models.User, set *models.SiteSettings) string {
+	switch {
+	case set.UserInviteQuota <= 0:
+		return "Personal invites are disabled"
+	case u.IsDisabled:
+		return "Account disabled"
+	case u.IsAdmin || u.IsModerator:
+		return ""
+	case u.IsShadowbanned:
+		return "Personal invites are not available for this account"
+	case set.RequireEmailVerification && !u.EmailVerified:
+		return "Verify your email to invite others"
+	case time.Since(u.CreatedAt) < time.Duration(set.UserInviteMinAccountDays)*24*time.Hour:
+		return "Your account is too new to invite others"
+	}
+	return ""
+}
+
+// MyInvites returns the caller's personal invites with this month's quota:
+// {invites, quota, used, remaining, eligible, reason}.
+func (h *AuthHandler) MyInvites(c *fiber.Ctx) error {
+	u, set, err := h.inviteCaller(c)
+	if err != nil {
+		return err
+	}
+	if h.inviteRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invite repository not configured"})
+	}
+	list, err := h.inviteRepo.ListByCreator(u.ID, personalInviteListLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list invites"})
+	}
+	used, err := h.inviteRepo.CountCreatedThisMonth(u.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list invites"})
+	}
+	remaining := set.UserInviteQuota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	block := personalInviteBlock(u, set)
+	links := make(map[string]string, len(list))
+	for _, inv := range list {
+		links[inv.ID.String()] = inviteLink(set, inv.Code)
+	}
+	return c.JSON(fiber.Map{"invites": list, "links": links, "quota": set.UserInviteQuota, "used": used, "remaining": remaining, "eligible": block == "", "reason": block})
+}
+
+// CreateMyInvite issues a single-use personal invite, valid for 14 days, from the caller's
+// monthly quota. Body: {"note": "..."}.
+func (h *AuthHandler) CreateMyInvite(c *fiber.Ctx) error {
+	u, set, err := h.inviteCaller(c)
+	if err != nil {
+		return err
+	}
+	if h.inviteRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invite repository not configured"})
+	}
+	if block := personalInviteBlock(u, set); block != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": block})
+	}
+	var body struct {
+		Note string `json:"note"`
+	}
+	_ = c.BodyParser(&body) // Optional body
+	note := strings.TrimSpace(body.Note)
+	if len([]rune(note)) > inviteNoteMaxLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note too long"})
+	}
+	one := 1
+	expires := time.Now().Add(personalInviteTTL)
+	inv := &models.Invite{MaxUses: &one, ExpiresAt: &expires, CreatedBy: &u.ID, Note: note}
+	if err := h.inviteRepo.InsertWithinQuota(inv, set.UserInviteQuota); err != nil {
+		if errors.Is(err, models.ErrInviteQuotaExceeded) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "You have used this month's invites"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invite"})
+	}
+	services.Logger(c.Context()).Info("invites: personal invite created", "user_id", u.ID.String(), "invite_id", inv.ID.String())
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"invite": inv, "link": inviteLink(set, inv.Code)})
+}
+
+// inviteCaller loads the signed-in user and current settings, or writes the error response.
+func (h *AuthHandler) inviteCaller(c *fiber.Ctx) (*models.User, *models.SiteSettings, error) {
+	uid := middleware.GetUserID(c)
+	if uid == uuid.Nil {
+		return nil, nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	return u, &set, nil
+}
+
+// inviteTreeNode is an account in the invite tree with everyone it brought in.
+type inviteTreeNode struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	Username       string     `json:"username"`
+	InviteID       *uuid.UUID `json:"invite_id,omitempty"`
+	InviteNote     string     `json:"invite_note,omitempty"`
+	IsDisabled     bool       `json:"is_disabled"`
+	IsShadowbanned bool       `json:"is_shadowbanned"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	// Descendants counts everyone below this account; Flagged those disabled or shadowbanned.
+	Descendants int               `json:"descendants"`
+	Flagged     int               `json:"flagged_descendants"`
+	Invitees    []*inviteTreeNode `json:"invitees,omitempty"`
+	parent      *inviteTreeNode
+}
+
+// buildInviteTree links invite edges into trees. Roots are inviters who did not register
+// with a tracked invite themselves; a deleted inviter shows as "(deleted)".
+func buildInviteTree(edges []models.InviteTreeEdge) ([]*inviteTreeNode, map[uuid.UUID]*inviteTreeNode) {
+	nodes := make(map[uuid.UUID]*inviteTreeNode, len(edges))
+	node := func(id uuid.UUID) *inviteTreeNode {
+		n, ok := nodes[id]
+		if !ok {
+			n = &inviteTreeNode{UserID: id, Username: "(deleted)"}
+			nodes[id] = n
+		}
+		return n
+	}
+	for _, e := range edges {
+		n := node(e.UserID)
+		n.Username, n.InviteID, n.IsDisabled, n.IsShadowbanned = e.Username, e.InviteID, e.IsDisabled, e.IsShadowbanned
+		created := e.CreatedAt
+		n.CreatedAt = &created
+		if e.InviteNote != nil {
+			n.InviteNote = *e.InviteNote
+		}
+		p := node(e.InvitedBy)
+		if e.InviterUsername != nil {
+			p.Username = *e.InviterUsername
+		}
+		n.parent = p
+		p.Invitees = append(p.Invitees, n)
+	}
+	var roots []*inviteTreeNode
+	isRoot := map[uuid.UUID]bool{}
+	for _, e := range edges {
+		if p := nodes[e.InvitedBy]; p.parent == nil && !isRoot[p.UserID] {
+			isRoot[p.UserID] = true
+			roots = append(roots, p)
+		}
+	}
+	for _, r := range roots {
+		countInviteTree(r, map[uuid.UUID]bool{})
+	}
+	return roots, nodes
+}
+
+// countInviteTree fills in descendant counts; seen guards against malformed cycles.
+func countInviteTree(n *inviteTreeNode, seen map[uuid.UUID]bool) {
+	seen[n.UserID] = true
+	n.Descendants, n.Flagged = 0, 0
+	for _, k := range n.Invitees {
+		if seen[k.UserID] {
+			continue
+		}
+		countInviteTree(k, seen)
+		n.Descendants += 1 + k.Descendants
+		n.Flagged += k.Flagged
+		if k.IsDisabled || k.IsShadowbanned {
+			n.Flagged++
+		}
+	}
+}
+
+// InviteTree returns the referral graph for abuse tracing: who invited whom, with counts of
+// descendants and of flagged (disabled or shadowbanned) descendants. ?user_id= narrows it to
+// that account's subtree plus the chain of inviters above it.
+func (h *AdminHandler) InviteTree(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.inviteRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invite repository not configured"})
+	}
+	edges, err := h.inviteRepo.TreeEdges()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load invite tree"})
+	}
+	roots, nodes := buildInviteTree(edges)
+	if v := c.Query("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+		}
+		n, ok := nodes[id]
+		if !ok {
+			return c.JSON(fiber.Map{"tree": nil, "chain": []fiber.Map{}})
+		}
+		chain := []fiber.Map{}
+		for p := n.parent; p != nil && len(chain) < len(nodes); p = p.parent {
+			chain = append(chain, fiber.Map{"user_id": p.UserID, "username": p.Username})
+		}
+		return c.JSON(fiber.Map{"tree": n, "chain": chain})
+	}
+	if roots == nil {
+		roots = []*inviteTreeNode{}
+	}
+	return c.JSON(fiber.Map{"roots": roots, "invited_users": len(edges)})
+}
+
+// SendInvite emails a single-use invite to an address through the mail queue. The invite
+// is bound to that address, so registering with another email is refused, and the link
+// pre-fills it on the registration form. Body: {"email", "note", "duration" | "expires_at"};
+// the invite is valid for 7 days unless told otherwise.
+func (h *AdminHandler) SendInvite(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.inviteRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Invite repository not configured"})
+	}
+	var body struct {
+		Email     string  `json:"email"`
+		Note      string  `json:"note"`
+		Duration  *string `json:"duration"`
+		ExpiresAt *string `json:"expires_at"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email || len(email) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+	note := strings.TrimSpace(body.Note)
+	if len([]rune(note)) > inviteNoteMaxLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note too long"})
+	}
+	expires, errMsg := parseInviteExpiry(body.ExpiresAt, body.Duration)
+	if errMsg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
+	}
+	if expires == nil {
+		t := time.Now().Add(sentInviteTTL)
+		expires = &t
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	if !(set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "SMTP not configured"})
+	}
+	if strings.TrimSpace(set.SiteURL) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Set the site URL before sending invites"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if u, err := h.userRepo.GetByEmail(ctx, email); err == nil && u != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+	}
+
+	one := 1
+	inv := &models.Invite{MaxUses: &one, ExpiresAt: expires, Note: note, Email: email}
+	if uid := middleware.GetUserID(c); uid != uuid.Nil {
+		inv.CreatedBy = &uid
+	}
+	if err := h.inviteRepo.Insert(inv); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invite"})
+	}
+	link := inviteLink(&set, inv.Code) + "&email=" + url.QueryEscape(email)
+	services.EnqueueMessage(email, services.BuildInviteMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, link, expires))
+	services.Logger(c.Context()).Info("admin: invite sent", "invite_id", inv.ID.String(), "by", middleware.GetUserID(c).String())
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"invite": inv, "link": link})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+func TestBuildInviteTree(t *testing.T) {
+	root, a, b, c := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	rootName := "root"
+	aName := "alice"
+	edges := []models.InviteTreeEdge{
+		{UserID: a, Username: "alice", InvitedBy: root, InviterUsername: &rootName, CreatedAt: time.Now()},
+		{UserID: b, Username: "bob", InvitedBy: a, InviterUsername: &aName, IsShadowbanned: true, CreatedAt: time.Now()},
+		{UserID: c, Username: "carol", InvitedBy: a, InviterUsername: &aName, IsDisabled: true, CreatedAt: time.Now()},
+	}
+	roots, nodes := buildInviteTree(edges)
+	if len(roots) != 1 || roots[0].UserID != root || roots[0].Username != "root" {
+		t.Fatalf("expected a single root, got %+v", roots)
+	}
+	if r := roots[0]; r.Descendants != 3 || r.Flagged != 2 {
+		t.Fatalf("root counts: descendants=%d flagged=%d", r.Descendants, r.Flagged)
+	}
+	if n := nodes[a]; n.Descendants != 2 || n.Flagged != 2 || n.parent != roots[0] {
+		t.Fatalf("alice counts: descendants=%d flagged=%d", n.Descendants, n.Flagged)
+	}
+	if n := nodes[b]; n.Descendants != 0 || len(n.Invitees) != 0 {
+		t.Fatalf("bob should be a leaf")
+	}
+
+	// An inviter that was deleted still anchors its subtree
+	gone := uuid.New()
+	roots, _ = buildInviteTree([]models.InviteTreeEdge{{UserID: a, Username: "alice", InvitedBy: gone}})
+	if len(roots) != 1 || roots[0].Username != "(deleted)" {
+		t.Fatalf("expected a deleted root, got %+v", roots)
+	}
+}
+
+func TestPersonalInviteBlock(t *testing.T) {
+	set := &models.SiteSettings{UserInviteQuota: 3, UserInviteMinAccountDays: 30, RequireEmailVerification: true}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	cases := []struct {
+		name    string
+		u       models.User
+		allowed bool
+	}{
+		{"eligible", models.User{CreatedAt: old, EmailVerified: true}, true},
+		{"too new", models.User{CreatedAt: time.Now(), EmailVerified: true}, false},
+		{"unverified", models.User{CreatedAt: old}, false},
+		{"shadowbanned", models.User{CreatedAt: old, EmailVerified: true, IsShadowbanned: true}, false},
+		{"disabled", models.User{CreatedAt: old, EmailVerified: true, IsDisabled: true}, false},
+		{"new moderator", models.User{CreatedAt: time.Now(), IsModerator: true}, true},
+	}
+	for _, tc := range cases {
+		if got := personalInviteBlock(&tc.u, set) == ""; got != tc.allowed {
+			t.Errorf("%s: allowed=%v, want %v", tc.name, got, tc.allowed)
+		}
+	}
+	if personalInviteBlock(&models.User{IsAdmin: true}, &models.SiteSettings{}) == "" {
+		t.Errorf("a zero quota should disable personal invites for everyone")
+	}
+}
+
+func TestInviteAllowsEmail(t *testing.T) {
+	open := &models.Invite{}
+	bound := &models.Invite{Email: "ann@example.com"}
+	if !inviteAllowsEmail(open, "anyone@example.com") {
+		t.Error("an unbound invite should work for any address")
+	}
+	if !inviteAllowsEmail(bound, " Ann@Example.com ") {
+		t.Error("a bound invite should match its address case-insensitively")
+	}
+	if inviteAllowsEmail(bound, "bob@example.com") {
+		t.Error("a bound invite should refuse other addresses")
+	}
+}
+
+func TestParseInviteExpiry(t *testing.T) {
+	str := func(s string) *string { return &s }
+	if exp, msg := parseInviteExpiry(nil, str("")); exp != nil || msg != "" {
+		t.Fatalf("blank should mean no expiry, got %v %q", exp, msg)
+	}
+	if exp, msg := parseInviteExpiry(nil, str("7d")); msg != "" || exp == nil || time.Until(*exp) < 6*24*time.Hour {
+		t.Fatalf("7d: got %v %q", exp, msg)
+	}
+	if exp, msg := parseInviteExpiry(str("2030-01-02T03:04:05Z"), str("1h")); msg != "" || exp.Year() != 2030 {
+		t.Fatalf("expires_at should win: got %v %q", exp, msg)
+	}
+	for _, bad := range []string{"0d", "d", "-1h", "soon"} {
+		if _, msg := parseInviteExpiry(nil, str(bad)); msg == "" {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+type sendInviteRepo struct {
+	models.InviteRepositoryInterface
+	inserted []models.Invite
+}
+
+func (f *sendInviteRepo) Insert(inv *models.Invite) error {
+	inv.Code = "code"
+	f.inserted = append(f.inserted, *inv)
+	return nil
+}
+
+func TestSendInviteValidation(t *testing.T) {
+	repo := &sendInviteRepo{}
+	set := &models.SiteSettings{SiteURL: "https://x.y"}
+	h := NewAdminHandler(&fakeSettingsRepo{s: set}, &fakeUserRepo{}, &fakeImageRepo{}).WithInvites(repo)
+	app := fiber.New()
+	app.Post("/send", h.SendInvite)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+	if code := post(`{"email":"not-an-email"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad address, got %d", code)
+	}
+	if code := post(`{"email":"Ann <ann@example.com>"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a display-name address, got %d", code)
+	}
+	if code := post(`{"email":"ann@example.com","duration":"soon"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad duration, got %d", code)
+	}
+	if len(repo.inserted) != 0 {
+		t.Fatal("nothing should be created for invalid requests")
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithJobs injects the background job repository
+func (h *AdminHandler) WithJobs(r models.JobRepositoryInterface) *AdminHandler {
+	h.jobs = r
+	return h
+}
+
+// ListJobs returns background jobs newest first; ?status= and ?kind= filter.
+func (h *AdminHandler) ListJobs(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	status := strings.ToLower(strings.TrimSpace(c.Query("status", "")))
+	switch status {
+	case "", models.JobStatusPending, models.JobStatusRunning, models.JobStatusDone, models.JobStatusDead:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid status"})
+	}
+	kind := strings.TrimSpace(c.Query("kind", ""))
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.jobs.List(status, kind, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list jobs", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"jobs": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// GetJob returns one job, for polling work started from the admin panel.
+func (h *AdminHandler) GetJob(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	job, err := h.jobs.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.JSON(job)
+}
+
+// RetryJob requeues a dead job with a fresh attempt budget, or runs a pending one now.
+func (h *AdminHandler) RetryJob(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Job queue not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if err := h.jobs.Retry(id); err != nil {
+		// A dead job whose unique key is held by a newer live job cannot be revived
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Job cannot be retried", "details": err.Error()})
+	}
+	services.Logger(c.Context()).Info("admin: job retried", "id", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// enqueueAdminJob queues kind and answers 202 with the job, or 409 with the live job when
+// unique is set and one is already queued or running. handled is false when the queue is
+// not running, in which case the caller does the work inline.
+func (h *AdminHandler) enqueueAdminJob(c *fiber.Ctx, kind string, payload interface{}, unique bool) (handled bool, err error) {
+	opts := services.JobOptions{}
+	if unique {
+		opts.UniqueKey = kind
+	}
+	job, created, err := services.EnqueueJob(kind, payload, opts)
+	if errors.Is(err, services.ErrJobsUnavailable) {
+		return false, nil
+	}
+	if err != nil {
+		services.Logger(c.Context()).Error("admin: enqueue job failed", "kind", kind, "error", err)
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to queue job"})
+	}
+	if !created {
+		return true, c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Already queued or running", "job": job})
+	}
+	services.Logger(c.Context()).Info("admin: job queued", "kind", kind, "id", job.ID)
+	return true, c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
+
+// latestReconcileReport returns the newest report stored by a finished reconciliation job,
+// which may have run on another instance.
+func (h *AdminHandler) latestReconcileReport() *services.ReconcileReport {
+	if h.jobs == nil {
+		return nil
+	}
+	var best *models.Job
+	for _, q := range []struct{ status, kind string }{
+		{models.JobStatusDone, services.JobReconcile},
+		{"", services.JobReconcileScheduled},
+	} {
+		list, _, err := h.jobs.List(q.status, q.kind, 1, 1)
+		if err != nil || len(list) == 0 || list[0].Result == nil || list[0].FinishedAt == nil {
+			continue
+		}
+		if best == nil || list[0].FinishedAt.After(*best.FinishedAt) {
+			best = &list[0]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	var rep services.ReconcileReport
+	if err := json.Unmarshal(*best.Result, &rep); err != nil {
+		return nil
+	}
+	return &rep
+}
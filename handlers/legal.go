@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// consentHistoryLimit caps the acceptances returned with the caller's consent.
+const consentHistoryLimit = 20
+
+// WithLegal records the terms and privacy versions new accounts accept.
+func (h *AuthHandler) WithLegal(r models.LegalRepositoryInterface) *AuthHandler {
+	h.legal = r
+	return h
+}
+
+// WithLegal enables terms acceptance for signed-in users.
+func (h *UserHandler) WithLegal(r models.LegalRepositoryInterface) *UserHandler {
+	h.legal = r
+	return h
+}
+
+// WithLegal enables publishing the terms and privacy versions users must accept.
+func (h *AdminHandler) WithLegal(r models.LegalRepositoryInterface) *AdminHandler {
+	h.legal = r
+	return h
+}
+
+// GetMyConsent returns the versions the site requires, the ones the caller accepted and
+// their recent acceptances.
+func (h *UserHandler) GetMyConsent(c *fiber.Ctx) error {
+	if h.legal == nil || h.settingsRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Terms tracking not configured"})
+	}
+	userID := middleware.GetUserID(c)
+	required := middleware.SiteSettings(c, h.settingsRepo).RequiredLegalVersions()
+	legal := tenantLegal(c, h.legal)
+	accepted, err := legal.Accepted(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load consent"})
+	}
+	history, err := legal.History(userID, consentHistoryLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load consent"})
+	}
+	if history == nil {
+		history = []models.LegalConsent{}
+	}
+	return c.JSON(fiber.Map{"required": required, "accepted": accepted, "consent_required": !accepted.Satisfies(required), "history": history})
+}
+
+// AcceptTerms records that the caller accepted the versions in the body, which must be
+// the ones the site requires now: a 409 with the current versions means they changed
+// since the client showed them.
+func (h *UserHandler) AcceptTerms(c *fiber.Ctx) error {
+	if h.legal == nil || h.settingsRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Terms tracking not configured"})
+	}
+	var body models.LegalVersions
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	required := middleware.SiteSettings(c, h.settingsRepo).RequiredLegalVersions()
+	if !required.Tracked() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "There are no terms to accept"})
+	}
+	if body != required {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The terms have changed, please review them again", "required": required})
+	}
+	userID := middleware.GetUserID(c)
+	if err := tenantLegal(c, h.legal).Record(userID, required, services.HashIP(services.ClientIP(c))); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record consent"})
+	}
+	services.Logger(c.Context()).Info("legal: terms accepted", "user_id", userID.String(), "terms_revision", required.Terms, "privacy_revision", required.Privacy)
+	return c.JSON(fiber.Map{"accepted": required})
+}
+
+// AdminGetLegal returns the versions users must accept and the latest revisions of the
+// terms and privacy pages, which publishing would require instead.
+func (h *AdminHandler) AdminGetLegal(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.legal == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Terms tracking not configured"})
+	}
+	latest, err := tenantLegal(c, h.legal).LatestRevisions()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load page revisions"})
+	}
+	return c.JSON(fiber.Map{"required": middleware.SiteSettings(c, h.settingsRepo).RequiredLegalVersions(), "latest": latest})
+}
+
+// AdminPublishLegal requires every user to accept the latest revision of the terms and
+// privacy pages, or of those listed in {"documents": ["terms", "privacy"]}; the other
+// keeps its version. {"disable": true} stops tracking both.
+func (h *AdminHandler) AdminPublishLegal(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.legal == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Terms tracking not configured"})
+	}
+	var body struct {
+		Documents []string `json:"documents"`
+		Disable   bool     `json:"disable"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	current, err := h.settingsRepo.Get()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load settings"})
+	}
+	current = tenantSettings(c, current)
+	legal := tenantLegal(c, h.legal)
+	next := models.LegalVersions{}
+	if !body.Disable {
+		latest, err := legal.LatestRevisions()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load page revisions"})
+		}
+		next = current.RequiredLegalVersions()
+		if len(body.Documents) == 0 {
+			body.Documents = []string{models.TermsPageSlug, models.PrivacyPageSlug}
+		}
+		for _, d := range body.Documents {
+			switch d {
+			case models.TermsPageSlug:
+				next.Terms = latest.Terms
+			case models.PrivacyPageSlug:
+				next.Privacy = latest.Privacy
+			default:
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "documents may only list terms and privacy"})
+			}
+		}
+		if !next.Tracked() {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Create the terms or privacy page first"})
+		}
+	}
+	if err := legal.Publish(next); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to publish terms"})
+	}
+	services.InvalidateSettingsCache()
+	if middleware.TenantID(c) != nil {
+		h.reloadTenantsLogged(c)
+	}
+	services.Logger(c.Context()).Info("admin: legal versions published", "terms_revision", next.Terms, "privacy_revision", next.Privacy, "by", middleware.GetUserID(c).String())
+	return c.JSON(fiber.Map{"required": next})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeLegalRepo struct {
+	models.LegalRepositoryInterface
+	latest    models.LegalVersions
+	published *models.LegalVersions
+	recorded  map[uuid.UUID]models.LegalVersions
+}
+
+func (f *fakeLegalRepo) Record(id uuid.UUID, v models.LegalVersions, _ string) error {
+	f.recorded[id] = v
+	return nil
+}
+
+func (f *fakeLegalRepo) LatestRevisions() (models.LegalVersions, error) { return f.latest, nil }
+
+func (f *fakeLegalRepo) Publish(v models.LegalVersions) error {
+	f.published = &v
+	return nil
+}
+
+func TestAcceptTerms(t *testing.T) {
+	services.UpdateCachedSettings(models.SiteSettings{TermsRevision: 4, PrivacyRevision: 2})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+	uid := uuid.New()
+	legal := &fakeLegalRepo{recorded: map[uuid.UUID]models.LegalVersions{}}
+	h := NewUserHandler(&fakeUserRepo{}, &fakeImageRepo{}, nil).WithSettings(&fakeSettingsRepo{s: &models.SiteSettings{}}).WithLegal(legal)
+	app := fiber.New()
+	app.Post("/me/consent", func(c *fiber.Ctx) error {
+		c.Locals("user_id", uid)
+		return c.Next()
+	}, h.AcceptTerms)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/me/consent", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if got := post(`{"terms_revision":3,"privacy_revision":2}`); got != http.StatusConflict || len(legal.recorded) != 0 {
+		t.Fatalf("expected 409 for versions the site no longer requires, got %d", got)
+	}
+	if got := post(`{"terms_revision":4,"privacy_revision":2}`); got != http.StatusOK || legal.recorded[uid] != (models.LegalVersions{Terms: 4, Privacy: 2}) {
+		t.Fatalf("expected the acceptance recorded, got %d %+v", got, legal.recorded)
+	}
+}
+
+func TestAdminPublishLegal(t *testing.T) {
+	defer services.InvalidateSettingsCache()
+	legal := &fakeLegalRepo{latest: models.LegalVersions{Terms: 5, Privacy: 3}}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{TermsRevision: 4, PrivacyRevision: 2}}, &fakeUserRepo{}, &fakeImageRepo{}).WithLegal(legal)
+	app := fiber.New()
+	app.Post("/admin/legal/publish", h.AdminPublishLegal)
+	post := func(body string) (int, models.LegalVersions) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/legal/publish", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		var out struct {
+			Required models.LegalVersions `json:"required"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out.Required
+	}
+
+	if code, got := post(`{"documents":["terms"]}`); code != http.StatusOK || got != (models.LegalVersions{Terms: 5, Privacy: 2}) {
+		t.Fatalf("expected only the terms bumped, got %d %+v", code, got)
+	}
+	if code, got := post(""); code != http.StatusOK || got != legal.latest {
+		t.Fatalf("expected both bumped by default, got %d %+v", code, got)
+	}
+	if code, _ := post(`{"documents":["cookies"]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown document, got %d", code)
+	}
+	if code, _ := post(`{"disable":true}`); code != http.StatusOK || legal.published.Tracked() {
+		t.Fatalf("expected tracking stopped, got %d %+v", code, legal.published)
+	}
+	legal.latest = models.LegalVersions{}
+	if code, _ := post(""); code != http.StatusConflict {
+		t.Fatalf("expected 409 without terms or privacy pages, got %d", code)
+	}
+}
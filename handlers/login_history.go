@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const loginHistoryLimit = 50
+
+// WithLoginEvents provides the login history used for new-device alerts.
+func (h *AuthHandler) WithLoginEvents(r models.LoginEventRepositoryInterface) *AuthHandler {
+	h.loginEvents = r
+	return h
+}
+
+// loginCountry reads the visitor's country from the header a trusted proxy or CDN sets,
+// named by LOGIN_COUNTRY_HEADER (e.g. CF-IPCountry). Without it locations are compared
+// by network instead.
+func loginCountry(c *fiber.Ctx) string {
+	name := strings.TrimSpace(os.Getenv("LOGIN_COUNTRY_HEADER"))
+	if name == "" {
+		return ""
+	}
+	v := strings.ToUpper(strings.TrimSpace(c.Get(name)))
+	// XX and T1 are what Cloudflare sends for unknown and Tor
+	if len(v) != 2 || v[0] < 'A' || v[0] > 'Z' || v[1] < 'A' || v[1] > 'Z' || v == "XX" || v == "T1" {
+		return ""
+	}
+	return v
+}
+
+func loginEventFor(c *fiber.Ctx, userID uuid.UUID) *models.LoginEvent {
+	ua := c.Get(fiber.HeaderUserAgent)
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	return &models.LoginEvent{
+		UserID:      userID,
+		IPHash:      services.HashIP(services.ClientIP(c)),
+		NetworkHash: services.HashIP(services.IPNetwork(services.ClientIP(c))),
+		Country:     loginCountry(c),
+		UserAgent:   ua,
+		Device:      services.DescribeDevice(ua),
+	}
+}
+
+// recordLogin adds a sign-in to the user's history and, when it comes from a new device
+// or location and mail is configured, emails them about it. Failures are only logged so
+// they never block signing in.
+func (h *AuthHandler) recordLogin(c *fiber.Ctx, user *models.User) {
+	if h.loginEvents == nil {
+		return
+	}
+	e := loginEventFor(c, user.ID)
+	if err := h.loginEvents.Record(e); err != nil {
+		services.Logger(c.Context()).Error("login: recording sign-in failed", "user_id", user.ID.String(), "error", err)
+		return
+	}
+	if !e.NewDevice && !e.NewLocation {
+		return
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	if !(set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "") || user.Email == "" {
+		return
+	}
+	details := []string{"Time: " + e.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), "Device: " + e.Device}
+	if e.Country != "" {
+		details = append(details, "Country: "+e.Country)
+	}
+	if e.NewDevice && e.NewLocation {
+		details = append(details, "Both the device and the location are new")
+	} else if e.NewLocation {
+		details = append(details, "The location is new")
+	}
+	link := strings.TrimRight(set.SiteURL, "/") + "/settings"
+	services.EnqueueMessage(user.Email, services.BuildLoginAlertMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, link, details))
+	services.Logger(c.Context()).Info("login: new device alert sent", "user_id", user.ID.String(), "new_device", e.NewDevice, "new_location", e.NewLocation)
+}
+
+// LoginHistory lists the caller's recent sign-ins, newest first; current marks ones made
+// from the same address and device as this request.
+func (h *AuthHandler) LoginHistory(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if h.loginEvents == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Login history not configured"})
+	}
+	list, err := h.loginEvents.ListForUser(userID, loginHistoryLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load login history"})
+	}
+	type entry struct {
+		models.LoginEvent
+		Current bool `json:"current"`
+	}
+	here := loginEventFor(c, userID)
+	out := make([]entry, 0, len(list))
+	for _, e := range list {
+		out = append(out, entry{LoginEvent: e, Current: e.IPHash == here.IPHash && e.Device == here.Device})
+	}
+	return c.JSON(fiber.Map{"logins": out, "retention_days": int(models.LoginEventRetention / (24 * time.Hour))})
+}
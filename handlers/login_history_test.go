@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeLoginEvents struct {
+	events []models.LoginEvent
+}
+
+func (f *fakeLoginEvents) Record(e *models.LoginEvent) error {
+	e.ID, e.CreatedAt = uuid.New(), time.Now()
+	f.events = append([]models.LoginEvent{*e}, f.events...)
+	return nil
+}
+
+func (f *fakeLoginEvents) ListForUser(uuid.UUID, int) ([]models.LoginEvent, error) {
+	return f.events, nil
+}
+
+func TestLoginHistoryMarksCurrentDevice(t *testing.T) {
+	userID := uuid.New()
+	events := &fakeLoginEvents{}
+	h := NewAuthHandler(&fakeUserRepo{}).WithLoginEvents(events)
+	app := fiber.New()
+	app.Post("/login", func(c *fiber.Ctx) error {
+		h.recordLogin(c, &models.User{ID: userID})
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/logins", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, h.LoginHistory)
+
+	const firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+	for _, ua := range []string{"curl/8.0", firefox} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.Header.Set("User-Agent", ua)
+		_, _ = app.Test(req)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/logins", nil)
+	req.Header.Set("User-Agent", firefox)
+	resp, _ := app.Test(req)
+	var body struct {
+		Logins []struct {
+			Device  string `json:"device"`
+			Current bool   `json:"current"`
+			IPHash  string `json:"ip_hash"`
+		} `json:"logins"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Logins) != 2 || body.Logins[0].Device != "Firefox on Linux" || !body.Logins[0].Current || body.Logins[1].Current {
+		t.Fatalf("unexpected history: %+v", body.Logins)
+	}
+	if body.Logins[0].IPHash != "" {
+		t.Fatal("IP hashes should not be exposed")
+	}
+}
+
+func TestLoginCountry(t *testing.T) {
+	app := fiber.New()
+	var got string
+	app.Get("/", func(c *fiber.Ctx) error { got = loginCountry(c); return nil })
+	try := func(v string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("CF-IPCountry", v)
+		_, _ = app.Test(req)
+		return got
+	}
+	if try("DE") != "" {
+		t.Fatal("the header should be ignored unless LOGIN_COUNTRY_HEADER names it")
+	}
+	t.Setenv("LOGIN_COUNTRY_HEADER", "CF-IPCountry")
+	if try("de") != "DE" || try("XX") != "" || try("T1") != "" || try("<b>") != "" {
+		t.Fatal("unexpected country parsing")
+	}
+}
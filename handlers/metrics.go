@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// Metrics serves Prometheus metrics. With METRICS_TOKEN set, scrapers must send it as a
+// bearer token; without it only loopback and private-network peers may scrape. The peer
+// address is the socket's, not a forwarded header, so it can't be spoofed.
+func Metrics(c *fiber.Ctx) error {
+	if token := strings.TrimSpace(os.Getenv("METRICS_TOKEN")); token != "" {
+		got := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+	} else if ip := c.Context().RemoteIP(); ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+		return fiber.ErrNotFound
+	}
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return services.WriteMetrics(c.Response().BodyWriter())
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// shouldHoldUpload reports whether u's next upload waits for review: the site setting holds
+// uploads until the user has that many approved images. Staff are never held; if the count
+// can't be read the upload is held rather than published unchecked.
+func (h *ImageHandler) shouldHoldUpload(u *models.User) bool {
+	if h.moderation == nil || h.settingsRepo == nil || u == nil || u.IsAdmin || u.IsModerator {
+		return false
+	}
+	limit := services.GetCachedSettings(h.settingsRepo).ModerationHoldUploads
+	if limit <= 0 {
+		return false
+	}
+	approved, err := h.moderation.CountApprovedByUser(u.ID)
+	return err != nil || approved < limit
+}
+
+// canSeePending reports whether the (optionally signed-in) viewer may see a held image.
+func (h *ImageHandler) canSeePending(c *fiber.Ctx, ownerID uuid.UUID) bool {
+	uid := middleware.OptionalUserID(c)
+	if uid == uuid.Nil {
+		return false
+	}
+	if uid == ownerID {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	return err == nil && (u.IsAdmin || u.IsModerator) && !u.IsDisabled
+}
+
+// ListModerationQueue returns held uploads, oldest first, for moderators.
+func (h *ImageHandler) ListModerationQueue(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.moderation == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Moderation not configured"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	images, total, err := h.moderation.ListPending(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list queue", "details": err.Error()})
+	}
+	totalPages := (total + limit - 1) / limit
+	return c.JSON(fiber.Map{"images": images, "page": page, "limit": limit, "total": total, "total_pages": totalPages})
+}
+
+// ApproveQueuedImage publishes a held upload and tells the uploader.
+func (h *ImageHandler) ApproveQueuedImage(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.moderation == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Moderation not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	ok, err := h.moderation.Approve(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to approve image"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Image is not pending review"})
+	}
+	services.InvalidateFeedCache(c.Context())
+	if img.IsListed() {
+		h.emitImageCreated(c.Context(), &img.Image)
+	}
+	services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationUploadApproved, ImageID: &id})
+	publishModerationDecision("approved", id, middleware.GetUserID(c))
+	services.Logger(c.Context()).Info("moderation: image approved", "image_id", id.String(), "moderator_id", middleware.GetUserID(c).String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RejectQueuedImage deletes a held upload and tells the uploader, with an optional reason.
+func (h *ImageHandler) RejectQueuedImage(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if len([]rune(reason)) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason too long"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if !img.IsPending() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Image is not pending review"})
+	}
+	h.removeStoredFiles(c.Context(), &img.Image)
+	if err := h.imageRepo.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete image"})
+	}
+	// The image row is gone, so the notification carries the reason but no image link
+	services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationUploadRejected, Message: reason})
+	publishModerationDecision("rejected", id, middleware.GetUserID(c))
+	services.Logger(c.Context()).Info("moderation: image rejected", "image_id", id.String(), "moderator_id", middleware.GetUserID(c).String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
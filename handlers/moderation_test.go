@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeModerationRepo struct {
+	models.ModerationRepositoryInterface
+	approved int
+	err      error
+}
+
+func (f *fakeModerationRepo) CountApprovedByUser(uuid.UUID) (int, error) { return f.approved, f.err }
+
+func TestShouldHoldUpload(t *testing.T) {
+	services.InvalidateSettingsCache()
+	t.Cleanup(services.InvalidateSettingsCache)
+	settings := &fakeSettingsRepo{s: &models.SiteSettings{ModerationHoldUploads: 3}}
+	mod := &fakeModerationRepo{approved: 2}
+	h := NewImageHandler(&fakeImageRepo{}, nil, &fakeUserRepo{}, services.Config{}, nil).WithSettings(settings).WithModeration(mod)
+	user := &models.User{ID: uuid.New()}
+
+	if !h.shouldHoldUpload(user) {
+		t.Fatal("expected upload held below the approved threshold")
+	}
+	mod.approved = 3
+	if h.shouldHoldUpload(user) {
+		t.Fatal("expected upload published once enough images are approved")
+	}
+	mod.err = errors.New("db down")
+	if !h.shouldHoldUpload(user) {
+		t.Fatal("expected upload held when the count is unavailable")
+	}
+	if h.shouldHoldUpload(&models.User{ID: uuid.New(), IsModerator: true}) {
+		t.Fatal("staff uploads are never held")
+	}
+	services.UpdateCachedSettings(models.SiteSettings{})
+	if h.shouldHoldUpload(user) {
+		t.Fatal("expected no hold when the setting is 0")
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// navMenus are the menus admins can edit, in display order.
+var navMenus = []string{models.NavMenuHeader, models.NavMenuFooter}
+
+const maxNavItems = 50
+
+// WithNavigation injects the site menu repository
+func (h *AdminHandler) WithNavigation(r models.NavigationRepositoryInterface) *AdminHandler {
+	h.navigation = r
+	return h
+}
+
+// navLink is a resolved menu entry as served to the SPA.
+type navLink struct {
+	Label    string    `json:"label"`
+	Href     string    `json:"href"`
+	Children []navLink `json:"children,omitempty"`
+}
+
+// navItemInput is one entry of a menu as edited by admins.
+type navItemInput struct {
+	Label    string         `json:"label"`
+	PageID   *uuid.UUID     `json:"page_id,omitempty"`
+	URL      *string        `json:"url,omitempty"`
+	PageSlug *string        `json:"page_slug,omitempty"`
+	Children []navItemInput `json:"children,omitempty"`
+}
+
+func navHref(it models.NavigationItem) (string, bool) {
+	if it.PageID != nil {
+		if it.PageSlug == nil || it.PagePublished == nil || !*it.PagePublished {
+			return "", false
+		}
+		return "/" + *it.PageSlug, true
+	}
+	if it.URL != nil {
+		return *it.URL, true
+	}
+	return "", false
+}
+
+// GetNavigation returns the site menus with links resolved. Items pointing at unpublished
+// pages are left out, along with their children.
+func (h *AdminHandler) GetNavigation(c *fiber.Ctx) error {
+	menus := fiber.Map{}
+	for _, m := range navMenus {
+		menus[m] = []navLink{}
+	}
+	if h.navigation == nil {
+		return c.JSON(fiber.Map{"menus": menus})
+	}
+	items, err := h.navigation.List()
+	if err != nil {
+		services.Logger(c.Context()).Error("navigation: list failed", "error", err)
+		return c.JSON(fiber.Map{"menus": menus})
+	}
+	// List returns parents first, so every child's parent has been placed already
+	placed := map[uuid.UUID]int{}
+	trees := map[string][]navLink{}
+	for _, it := range items {
+		href, ok := navHref(it)
+		if !ok {
+			continue
+		}
+		link := navLink{Label: it.Label, Href: href}
+		if it.ParentID == nil {
+			trees[it.Menu] = append(trees[it.Menu], link)
+			placed[it.ID] = len(trees[it.Menu]) - 1
+			continue
+		}
+		if i, ok := placed[*it.ParentID]; ok {
+			trees[it.Menu][i].Children = append(trees[it.Menu][i].Children, link)
+		}
+	}
+	for m, t := range trees {
+		menus[m] = t
+	}
+	return c.JSON(fiber.Map{"menus": menus})
+}
+
+// AdminGetNavigation returns every menu as edited, including links to unpublished pages.
+func (h *AdminHandler) AdminGetNavigation(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.navigation == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Navigation not configured"})
+	}
+	items, err := h.navigation.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load navigation", "details": err.Error()})
+	}
+	menus := map[string][]navItemInput{}
+	for _, m := range navMenus {
+		menus[m] = []navItemInput{}
+	}
+	index := map[uuid.UUID]int{}
+	for _, it := range items {
+		in := navItemInput{Label: it.Label, PageID: it.PageID, URL: it.URL, PageSlug: it.PageSlug}
+		if it.ParentID == nil {
+			menus[it.Menu] = append(menus[it.Menu], in)
+			index[it.ID] = len(menus[it.Menu]) - 1
+		} else if i, ok := index[*it.ParentID]; ok {
+			menus[it.Menu][i].Children = append(menus[it.Menu][i].Children, in)
+		}
+	}
+	return c.JSON(fiber.Map{"menus": menus})
+}
+
+// flattenNav validates a menu tree and returns its rows, parents before children.
+func flattenNav(items []navItemInput) ([]models.NavigationItem, error) {
+	var out []models.NavigationItem
+	var walk func(list []navItemInput, parent *uuid.UUID, depth int) error
+	walk = func(list []navItemInput, parent *uuid.UUID, depth int) error {
+		for i, in := range list {
+			label := strings.TrimSpace(in.Label)
+			if label == "" || len([]rune(label)) > 100 {
+				return errors.New("each item needs a label of at most 100 characters")
+			}
+			row := models.NavigationItem{ID: uuid.New(), ParentID: parent, Label: label, Position: i}
+			switch {
+			case in.PageID != nil && in.URL != nil:
+				return errors.New("an item links to a page or a URL, not both")
+			case in.PageID != nil:
+				row.PageID = in.PageID
+			case in.URL != nil:
+				u := strings.TrimSpace(*in.URL)
+				lower := strings.ToLower(u)
+				if !(strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//")) && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "http://") {
+					return errors.New("url must be a site path (/...) or an http(s) URL")
+				}
+				row.URL = &u
+			default:
+				return errors.New("each item needs a page_id or a url")
+			}
+			out = append(out, row)
+			if len(out) > maxNavItems {
+				return errors.New("a menu may have at most 50 items")
+			}
+			if len(in.Children) > 0 {
+				if depth > 0 {
+					return errors.New("menus may only nest one level deep")
+				}
+				id := row.ID
+				if err := walk(in.Children, &id, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(items, nil, 0); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminPutNavigation replaces one menu with the tree in the body.
+func (h *AdminHandler) AdminPutNavigation(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.navigation == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Navigation not configured"})
+	}
+	menu := strings.ToLower(c.Params("menu"))
+	known := false
+	for _, m := range navMenus {
+		known = known || m == menu
+	}
+	if !known {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown menu"})
+	}
+	var req struct {
+		Items []navItemInput `json:"items"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rows, err := flattenNav(req.Items)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.navigation.Replace(menu, rows); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "foreign key") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A linked page does not exist"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save navigation", "details": err.Error()})
+	}
+	return h.AdminGetNavigation(c)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeNavigationRepo struct {
+	models.NavigationRepositoryInterface
+	items []models.NavigationItem
+}
+
+func (f *fakeNavigationRepo) List() ([]models.NavigationItem, error) { return f.items, nil }
+
+func TestFlattenNavValidation(t *testing.T) {
+	page := uuid.New()
+	url := "/help"
+	bad := "javascript:alert(1)"
+	rows, err := flattenNav([]navItemInput{{Label: "Help", URL: &url, Children: []navItemInput{{Label: "FAQ", PageID: &page}}}})
+	if err != nil || len(rows) != 2 || rows[1].ParentID == nil || *rows[1].ParentID != rows[0].ID {
+		t.Fatalf("expected parent then child, got %+v %v", rows, err)
+	}
+	cases := map[string][]navItemInput{
+		"empty label": {{Label: " ", URL: &url}},
+		"no target":   {{Label: "x"}},
+		"both":        {{Label: "x", URL: &url, PageID: &page}},
+		"bad scheme":  {{Label: "x", URL: &bad}},
+		"too deep":    {{Label: "a", URL: &url, Children: []navItemInput{{Label: "b", URL: &url, Children: []navItemInput{{Label: "c", URL: &url}}}}}},
+	}
+	for name, in := range cases {
+		if _, err := flattenNav(in); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGetNavigationHidesUnpublishedPages(t *testing.T) {
+	yes, no := true, false
+	live, draft := "about", "draft"
+	parent := uuid.New()
+	pid := uuid.New()
+	repo := &fakeNavigationRepo{items: []models.NavigationItem{
+		{ID: parent, Menu: models.NavMenuHeader, Label: "About", PageID: &pid, PageSlug: &live, PagePublished: &yes},
+		{ID: uuid.New(), Menu: models.NavMenuHeader, Label: "Draft", PageID: &pid, PageSlug: &draft, PagePublished: &no},
+		{ID: uuid.New(), Menu: models.NavMenuHeader, ParentID: &parent, Label: "Team", URL: &[]string{"/about/team"}[0]},
+	}}
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithNavigation(repo)
+	app.Get("/navigation", h.GetNavigation)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/navigation", nil))
+	var body struct {
+		Menus map[string][]navLink `json:"menus"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	header := body.Menus[models.NavMenuHeader]
+	if len(header) != 1 || header[0].Href != "/about" || len(header[0].Children) != 1 || header[0].Children[0].Href != "/about/team" {
+		t.Fatalf("unexpected header menu %+v", header)
+	}
+	if footer, ok := body.Menus[models.NavMenuFooter]; !ok || len(footer) != 0 {
+		t.Fatalf("expected an empty footer menu, got %+v", body.Menus)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// NotificationHandler serves the signed-in user's notifications and lets admins message users.
+type NotificationHandler struct {
+	notifications models.NotificationRepositoryInterface
+	userRepo      models.UserRepositoryInterface
+}
+
+func NewNotificationHandler(repo models.NotificationRepositoryInterface, userRepo models.UserRepositoryInterface) *NotificationHandler {
+	return &NotificationHandler{notifications: repo, userRepo: userRepo}
+}
+
+// ListMyNotifications returns notifications newest first with the unread count; ?unread=1
+// limits the list to unread ones.
+func (h *NotificationHandler) ListMyNotifications(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if limit < 1 || limit > 200 {
+		limit = 20
+	}
+	unreadOnly := c.Query("unread") == "1" || c.Query("unread") == "true"
+	list, total, err := h.notifications.List(userID, unreadOnly, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications", "details": err.Error()})
+	}
+	unread, err := h.notifications.CountUnread(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications", "details": err.Error()})
+	}
+	digest := false
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if u, err := h.userRepo.GetByID(ctx, userID); err == nil && u != nil {
+		digest = u.NotifyDigest
+	}
+	for i := range list {
+		list[i].Text = services.DescribeNotification(middleware.GetLocale(c), list[i])
+	}
+	return c.JSON(fiber.Map{"notifications": list, "unread": unread, "digest": digest, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// UnreadCount is a cheap poll for the unread badge.
+func (h *NotificationHandler) UnreadCount(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	unread, err := h.notifications.CountUnread(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count notifications"})
+	}
+	return c.JSON(fiber.Map{"unread": unread})
+}
+
+// MarkRead marks the listed notification ids read, or all of them when ids is empty.
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if len(body.IDs) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many ids"})
+	}
+	ids := make([]uuid.UUID, 0, len(body.IDs))
+	for _, s := range body.IDs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification ID"})
+		}
+		ids = append(ids, id)
+	}
+	marked, err := h.notifications.MarkRead(userID, ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notifications"})
+	}
+	unread, _ := h.notifications.CountUnread(userID)
+	return c.JSON(fiber.Map{"marked": marked, "unread": unread})
+}
+
+func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification ID"})
+	}
+	if err := h.notifications.Delete(userID, id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete notification"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AdminMessageUser sends an admin message notification to a user.
+func (h *NotificationHandler) AdminMessageUser(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	msg := strings.TrimSpace(body.Message)
+	if msg == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Message is required"})
+	}
+	if utf8.RuneCountInString(msg) > 2000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Message too long (max 2000 characters)"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if u, err := h.userRepo.GetByID(ctx, id); err != nil || u == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	n := &models.Notification{UserID: id, Type: models.NotificationAdminMessage, Message: msg}
+	if err := h.notifications.Create(n); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send message", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(n)
+}
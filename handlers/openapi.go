@@ -0,0 +1,596 @@
+package handlers
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// The OpenAPI document is generated from the Fiber route table, so every API route is
+// listed even when nobody documented it. apiDocs adds summaries, parameters and the Go
+// types of request and response bodies, which are turned into JSON schemas by reflection.
+
+// apiDoc annotates one route, keyed by "METHOD /api/path" as registered.
+type apiDoc struct {
+	Summary  string
+	Query    []string // query parameters as "name" or "name:integer"
+	Body     any      // JSON request body; its Go type becomes the schema
+	Form     []string // multipart fields; "file:name" marks a file
+	Response any      // JSON response body
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+type authResponse struct {
+	User  models.UserResponse `json:"user"`
+	Token string              `json:"token"`
+}
+
+var apiDocs = map[string]apiDoc{
+	"POST /api/register": {Summary: "Create an account; answers 202 without a session when new accounts wait for approval", Query: []string{"invite"}, Body: struct {
+		models.CreateUserRequest
+		Invite            string `json:"invite"`
+		Website           string `json:"website"`
+		FormToken         string `json:"form_token"`
+		ChallengeToken    string `json:"challenge_token"`
+		ChallengeSolution string `json:"challenge_solution"`
+		AcceptTerms       bool   `json:"accept_terms"`
+		AgeConfirmed      bool   `json:"age_confirmed"`
+	}{}, Response: authResponse{}},
+	"POST /api/login":  {Summary: "Sign in with a username or email", Body: models.LoginRequest{}, Response: authResponse{}},
+	"POST /api/logout": {Summary: "Clear the auth cookie"},
+	"POST /api/forgot-password": {Summary: "Email a password reset link", Body: struct {
+		Email string `json:"email"`
+	}{}},
+	"GET /api/auth/challenge":  {Summary: "Whether register/forgot-password need a challenge, and what to solve"},
+	"GET /api/auth/form-token": {Summary: "Signed timestamp for the registration timing check"},
+	"POST /api/reset-password": {Summary: "Set a new password with a reset token", Body: struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}{}, Response: authResponse{}},
+	"POST /api/verify-email": {Summary: "Confirm an email address", Body: struct {
+		Token string `json:"token"`
+	}{}},
+	"POST /api/confirm-email-change": {Summary: "Apply a pending email change", Body: struct {
+		Token string `json:"token"`
+	}{}},
+	"POST /api/cancel-email-change": {Summary: "Cancel a pending email change from the old address", Body: struct {
+		Token string `json:"token"`
+	}{}},
+	"GET /api/password-requirements": {Summary: "Password policy for client-side hints", Response: services.PasswordRequirements{}},
+	"GET /api/age-gate":              {Summary: "Whether the visitor still has to confirm the minimum age of an adult site"},
+	"POST /api/age-gate": {Summary: "Confirm the minimum age; a signed cookie lets signed-out visitors see the feed and images for 30 days", Body: struct {
+		Confirmed bool `json:"confirmed"`
+	}{}},
+	"GET /api/invites/validate":        {Summary: "Check an invite code", Query: []string{"code"}},
+	"GET /api/csrf":                    {Summary: "Issue a CSRF token for state-changing requests (?form=METHOD /path adds a single-form token)"},
+	"POST /api/me/resend-verification": {Summary: "Send the verification email again"},
+	"GET /api/me":                      {Summary: "The signed-in user", Response: models.UserResponse{}},
+
+	"GET /api/feed": {Summary: "Public feed, newest first", Query: []string{"cursor", "page:integer", "limit:integer", "include_total", "since", "since_id", "model"}, Response: models.FeedResponse{}},
+	"GET /api/featured": {Summary: "Featured picks, most recently featured first", Query: []string{"limit:integer"}, Response: struct {
+		Images []models.ImageWithUser `json:"images"`
+	}{}},
+	"GET /api/challenges": {Summary: "Challenges in one phase: current (default), upcoming or past", Query: []string{"status", "page:integer", "limit:integer"}, Response: struct {
+		Challenges []models.Challenge `json:"challenges"`
+		Page       int                `json:"page"`
+		Total      int                `json:"total"`
+	}{}},
+	"GET /api/challenges/{id}": {Summary: "One challenge", Response: models.Challenge{}},
+	"GET /api/challenges/{id}/leaderboard": {Summary: "A challenge's entries, most collected first", Query: []string{"page:integer", "limit:integer"}, Response: struct {
+		Challenge models.Challenge        `json:"challenge"`
+		Entries   []models.ChallengeEntry `json:"entries"`
+		Page      int                     `json:"page"`
+		Total     int                     `json:"total"`
+	}{}},
+	"POST /api/challenges/{id}/entries": {Summary: "Enter one of your public images into a current challenge", Body: struct {
+		ImageID uuid.UUID `json:"image_id"`
+	}{}},
+	"DELETE /api/challenges/{id}/entries/{imageId}": {Summary: "Withdraw an entry (its owner until the challenge ends, moderators any time)"},
+	"GET /api/images/{id}":                          {Summary: "One image with its uploader", Response: models.ImageWithUser{}},
+	"GET /api/licenses":                             {Summary: "Licenses an image can carry"},
+	"GET /api/images/{id}/download":                 {Summary: "Download the original file"},
+	"GET /api/images/{id}/workflow":                 {Summary: "Download the ComfyUI workflow JSON extracted from an image's PNG metadata at upload; image responses carry has_workflow"},
+	"POST /api/upload":                              {Summary: "Upload an image", Form: []string{"file:image", "title", "caption", "rating", "is_nsfw", "license", "visibility", "remix_of"}},
+	"GET /api/uploads/{token}/status":               {Summary: "Progress of a queued upload"},
+	"POST /api/images/{id}/like":                    {Summary: "Toggle a like"},
+	"POST /api/images/{id}/collect":                 {Summary: "Toggle collecting an image"},
+	"GET /api/images/{id}/collectors": {Summary: "Who collected an image, most recent first (uploader and moderators)", Query: []string{"page:integer", "limit:integer"}, Response: struct {
+		Collectors []models.Collector `json:"collectors"`
+		Page       int                `json:"page"`
+		Total      int                `json:"total"`
+	}{}},
+	"PATCH /api/images/{id}": {Summary: "Edit an image's title, caption, license, remix source, links or flags"},
+	"GET /api/images/{id}/remixes": {Summary: "Public images declared as remixes of an image, newest first", Response: struct {
+		Remixes []models.ImageWithUser `json:"remixes"`
+	}{}},
+	"DELETE /api/images/{id}": {Summary: "Delete an image"},
+	"GET /api/images/{id}/edits": {Summary: "An image's edit history for moderators: field, old and new value, editor and whether staff made the change", Response: struct {
+		Edits []models.ImageEdit `json:"edits"`
+	}{}},
+	"POST /api/images/{id}/file": {Summary: "Replace an image's file (multipart field image), keeping its id, URL, collections and stats; the previous file is kept as a version", Response: models.ImageWithUser{}},
+
+	"GET /api/users/{username}":             {Summary: "Public profile", Response: models.UserResponse{}},
+	"GET /api/users/{username}/images":      {Summary: "A user's images", Query: []string{"cursor", "page:integer", "limit:integer"}, Response: models.FeedResponse{}},
+	"GET /api/users/{username}/stats":       {Summary: "A user's public stats", Response: models.UserStats{}},
+	"GET /api/users/{username}/collections": {Summary: "Images a user has collected", Query: []string{"cursor", "page:integer", "limit:integer"}},
+	"GET /api/users/{username}/boards": {Summary: "A user's boards in their order; the owner also sees private ones", Response: struct {
+		Boards []models.Board `json:"boards"`
+	}{}},
+	"POST /api/users/{username}/block":   {Summary: "Block a user: their images leave your feeds and they can't collect yours"},
+	"DELETE /api/users/{username}/block": {Summary: "Unblock a user"},
+	"GET /api/boards/{id}":               {Summary: "A board with a page of its images", Query: []string{"page:integer", "limit:integer"}},
+	"GET /api/pages":                     {Summary: "Published CMS pages"},
+	"GET /api/pages/{slug}":              {Summary: "One published CMS page"},
+	"GET /api/site":                      {Summary: "Public site settings"},
+	"GET /api/graphql":                   {Summary: "GraphQL schema (SDL), or run a query given as query/variables/operationName parameters", Query: []string{"query", "variables", "operationName"}},
+	"POST /api/graphql":                  {Summary: "Run a read-only GraphQL query over images, users, collections and pages", Body: graphQLRequest{}, Response: graphql.Response{}},
+
+	"GET /api/me/profile":              {Summary: "The signed-in user's profile", Response: models.UserResponse{}},
+	"PATCH /api/me/profile":            {Summary: "Update the signed-in user's profile", Body: models.UpdateUserRequest{}, Response: models.UserResponse{}},
+	"GET /api/me/account":              {Summary: "Account details including email"},
+	"GET /api/me/notifications":        {Summary: "Notifications, newest first", Query: []string{"unread", "page:integer", "limit:integer"}},
+	"GET /api/me/notifications/unread": {Summary: "Unread notification count"},
+	"POST /api/me/notifications/read":  {Summary: "Mark notifications read"},
+	"PATCH /api/me/email": {Summary: "Change email (confirmed from the new address when mail is set up)", Body: struct {
+		Email string `json:"email"`
+	}{}},
+	"PATCH /api/me/password": {Summary: "Change password", Body: struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}{}},
+	"DELETE /api/me":      {Summary: "Delete the signed-in account"},
+	"GET /api/me/invites": {Summary: "Personal invites and the remaining quota"},
+	"POST /api/me/invites": {Summary: "Create a personal invite", Body: struct {
+		Note string `json:"note"`
+	}{}},
+	"GET /api/me/security/logins": {Summary: "Recent sign-ins", Response: struct {
+		Logins []models.LoginEvent `json:"logins"`
+	}{}},
+	"GET /api/me/blocks": {Summary: "Users the signed-in user blocked, most recent first", Response: struct {
+		Blocks []models.BlockedUser `json:"blocks"`
+	}{}},
+	"GET /api/me/verification": {Summary: "The signed-in user's verified badge and pending self-serve proof"},
+	"POST /api/me/verification": {Summary: "Get a token to publish on a domain or linked page (self-serve verification)", Body: struct {
+		Kind   string `json:"kind"`
+		Target string `json:"target"`
+	}{}, Response: models.VerificationProof{}},
+	"POST /api/me/verification/check": {Summary: "Look for the published token and grant the verified badge when it is there"},
+	"GET /api/me/consent":             {Summary: "The terms and privacy revisions the site requires, the ones the signed-in user accepted and their recent acceptances"},
+	"POST /api/me/consent":            {Summary: "Accept the required terms and privacy revisions; state-changing requests answer 451 until then", Body: models.LegalVersions{}},
+	"GET /api/me/boards":              {Summary: "The signed-in user's boards; image_id also returns the ones holding that image", Query: []string{"image_id"}},
+	"POST /api/me/boards":             {Summary: "Create a board", Body: boardRequest{}, Response: models.Board{}},
+	"PUT /api/me/boards/order":        {Summary: "Order the signed-in user's boards", Body: boardOrderRequest{}},
+	"PATCH /api/me/boards/{id}":       {Summary: "Rename a board, change its description or make it public or private", Body: boardRequest{}, Response: models.Board{}},
+	"DELETE /api/me/boards/{id}":      {Summary: "Delete a board (not the default one)"},
+	"POST /api/me/boards/{id}/images": {Summary: "Add an image to a board, collecting it", Body: struct {
+		ImageID uuid.UUID `json:"image_id"`
+	}{}},
+	"DELETE /api/me/boards/{id}/images/{imageId}": {Summary: "Take an image off a board"},
+	"PUT /api/me/boards/{id}/images/order":        {Summary: "Order a board's images", Body: boardOrderRequest{}},
+	"POST /api/me/avatar":                         {Summary: "Upload an avatar; small animated GIF and WebP avatars are kept animated", Form: []string{"file:avatar"}},
+	"POST /api/me/banner":                         {Summary: "Upload a profile banner", Form: []string{"file:banner"}},
+	"DELETE /api/me/banner":                       {Summary: "Remove the profile banner"},
+	"GET /api/admin/stats":                        {Summary: "Dashboard stats", Query: []string{"range"}, Response: models.AdminStats{}},
+	"GET /api/admin/site":                         {Summary: "All site settings (secrets redacted)", Response: models.SiteSettings{}},
+	"PUT /api/admin/site":                         {Summary: "Save site settings", Body: models.SiteSettings{}, Response: models.SiteSettings{}},
+	"GET /api/admin/users":                        {Summary: "List users; format=csv exports them all", Query: []string{"q", "page:integer", "limit:integer", "format"}},
+	"GET /api/admin/images":                       {Summary: "List all images; format=csv exports them all", Query: []string{"page:integer", "limit:integer", "format"}},
+	"GET /api/admin/users/{id}":                   {Summary: "One account with its images, storage, invite origin, sign-ins, security events and audit trail"},
+	"POST /api/admin/users/bulk":                  {Summary: "Disable, enable, delete or verify the email of many users", Body: bulkRequest{}, Response: bulkResponse{}},
+	"POST /api/admin/images/bulk":                 {Summary: "Delete or set NSFW on many images", Body: bulkRequest{}, Response: bulkResponse{}},
+	"PUT /api/admin/users/{id}/verified": {Summary: "Grant or revoke a user's verified badge; granting needs a reason", Body: struct {
+		Verified bool   `json:"verified"`
+		Reason   string `json:"reason"`
+	}{}},
+	"GET /api/admin/registrations":               {Summary: "Registrations waiting for approval, oldest first, with their emails", Query: []string{"page:integer", "limit:integer"}},
+	"POST /api/admin/registrations/{id}/approve": {Summary: "Approve a queued registration and email its owner"},
+	"POST /api/admin/registrations/{id}/reject": {Summary: "Delete a queued registration and email its owner, with an optional reason", Body: struct {
+		Reason string `json:"reason"`
+	}{}},
+	"POST /api/takedown": {Summary: "File a copyright takedown request for an image, given as its id or page or file URL", Body: struct {
+		Image        string `json:"image"`
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		Organization string `json:"organization"`
+		OriginalWork string `json:"original_work"`
+		Description  string `json:"description"`
+		Signature    string `json:"signature"`
+		GoodFaith    bool   `json:"good_faith"`
+		Accurate     bool   `json:"accurate"`
+	}{}},
+	"GET /api/admin/takedowns": {Summary: "Copyright takedown requests, newest first", Query: []string{"status", "page:integer", "limit:integer"}},
+	"GET /api/admin/takedowns/{id}": {Summary: "A takedown request with its resolution log", Response: struct {
+		Takedown models.TakedownRequest `json:"takedown"`
+		Events   []models.TakedownEvent `json:"events"`
+	}{}},
+	"POST /api/admin/takedowns/{id}/actions": {Summary: "Disable the image pending review, remove it, reject the request (restoring the image) or add a note to the log", Body: struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}{}},
+	"GET /api/admin/legal": {Summary: "The terms and privacy revisions users must accept and the latest revisions of those pages"},
+	"POST /api/admin/legal/publish": {Summary: "Require every user to accept the latest terms and privacy pages, or only the listed ones; disable stops tracking", Body: struct {
+		Documents []string `json:"documents"`
+		Disable   bool     `json:"disable"`
+	}{}},
+	"PUT /api/admin/images/{id}/featured": {Summary: "Feature a public image on the front page, with an optional curator's note", Body: struct {
+		Note *string `json:"note"`
+	}{}},
+	"DELETE /api/admin/images/{id}/featured": {Summary: "Take an image out of the featured picks"},
+	"GET /api/admin/challenges":              {Summary: "All challenges, latest start first", Query: []string{"page:integer", "limit:integer"}},
+	"POST /api/admin/challenges":             {Summary: "Create a challenge; it starts now unless starts_at is given", Body: challengeRequest{}, Response: models.Challenge{}},
+	"PATCH /api/admin/challenges/{id}":       {Summary: "Change a challenge's title, prompt or dates", Body: challengeRequest{}, Response: models.Challenge{}},
+	"DELETE /api/admin/challenges/{id}":      {Summary: "Delete a challenge and its entries"},
+	"GET /api/moderation/queue":              {Summary: "Uploads held for review", Query: []string{"limit:integer"}},
+	"GET /api/openapi.json":                  {Summary: "This document"},
+	"GET /api/docs":                          {Summary: "Swagger UI (admins)"},
+}
+
+// staffOnlyPath reports routes left out of the document served to everyone else.
+func staffOnlyPath(path string) bool {
+	return strings.HasPrefix(path, "/api/admin/") || strings.HasPrefix(path, "/api/moderation/") || path == "/api/docs"
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaBuilder turns Go types into OpenAPI schemas, collecting named structs under
+// components so they are described once and may refer to themselves.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	s := b.nonNullSchema(t)
+	if nullable {
+		if _, isRef := s["$ref"]; isRef {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+	}
+	return s
+}
+
+func (b *schemaBuilder) nonNullSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			b.components[t.Name()] = map[string]any{} // placeholder for recursive types
+			b.components[t.Name()] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// object describes a struct the way encoding/json writes it: exported fields under their
+// json names, embedded structs flattened. validate:"required" marks required properties.
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = b.schema(f.Type)
+			if v := f.Tag.Get("validate"); v == "required" || strings.HasPrefix(v, "required,") {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+// openAPIPath converts a Fiber path to OpenAPI form and lists its path parameters.
+func openAPIPath(path string) (string, []string) {
+	segs := strings.Split(path, "/")
+	var params []string
+	for i, s := range segs {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			name := strings.TrimSuffix(strings.TrimPrefix(s, ":"), "?")
+			if j := strings.IndexByte(name, '<'); j >= 0 {
+				name = name[:j]
+			}
+			segs[i] = "{" + name + "}"
+			params = append(params, name)
+		case s == "*" || s == "+":
+			segs[i] = "{path}"
+			params = append(params, "path")
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// operationID derives a stable camelCase id, e.g. GET /api/images/{id} -> getImagesById.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, s := range strings.Split(strings.TrimPrefix(path, "/api"), "/") {
+		if s == "" {
+			continue
+		}
+		if strings.HasPrefix(s, "{") {
+			b.WriteString("By")
+			s = strings.Trim(s, "{}")
+		}
+		for _, w := range strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
+
+// buildOpenAPI assembles the document for routes. Operations whose handler chain includes
+// authMW require a session and those behind middleware.Deprecated are flagged deprecated;
+// staff-only routes are dropped unless full is set.
+func buildOpenAPI(routes []fiber.Route, authMW fiber.Handler, siteName string, full bool) map[string]any {
+	authPtr := uintptr(0)
+	if authMW != nil {
+		authPtr = reflect.ValueOf(authMW).Pointer()
+	}
+	deprecatedPtr := reflect.ValueOf(middleware.Deprecated(time.Time{}, time.Time{}, "")).Pointer()
+	b := &schemaBuilder{components: map[string]any{}}
+	b.components["Error"] = b.object(reflect.TypeOf(errorBody{}))
+	paths := map[string]map[string]any{}
+	seenIDs := map[string]int{}
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+	for _, r := range routes {
+		if r.Method == fiber.MethodHead || r.Method == fiber.MethodOptions || !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		path, params := openAPIPath(r.Path)
+		if !full && staffOnlyPath(path) {
+			continue
+		}
+		method := strings.ToLower(r.Method)
+		if paths[path] != nil && paths[path][method] != nil {
+			continue
+		}
+		doc := apiDocs[r.Method+" "+path]
+		tag := strings.SplitN(strings.TrimPrefix(path, "/api/"), "/", 2)[0]
+		if tag == "me" {
+			tag = "account"
+		}
+		op := map[string]any{"tags": []string{tag}, "summary": doc.Summary}
+		if doc.Summary == "" {
+			op["summary"] = r.Method + " " + path
+		}
+		id := operationID(r.Method, path)
+		if seenIDs[id]++; seenIDs[id] > 1 {
+			id += "_" + string(rune('0'+seenIDs[id]))
+		}
+		op["operationId"] = id
+
+		var parameters []any
+		for _, p := range params {
+			parameters = append(parameters, map[string]any{"name": p, "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		for _, q := range doc.Query {
+			name, typ, ok := strings.Cut(q, ":")
+			if !ok {
+				typ = "string"
+			}
+			parameters = append(parameters, map[string]any{"name": name, "in": "query", "schema": map[string]any{"type": typ}})
+		}
+		if len(parameters) > 0 {
+			op["parameters"] = parameters
+		}
+		if doc.Body != nil {
+			op["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(doc.Body))},
+			}}
+		} else if len(doc.Form) > 0 {
+			props := map[string]any{}
+			for _, f := range doc.Form {
+				if name, ok := strings.CutPrefix(f, "file:"); ok {
+					props[name] = map[string]any{"type": "string", "format": "binary"}
+				} else {
+					props[f] = map[string]any{"type": "string"}
+				}
+			}
+			op["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				"multipart/form-data": map[string]any{"schema": map[string]any{"type": "object", "properties": props}},
+			}}
+		}
+		ok := map[string]any{"description": "Success"}
+		if doc.Response != nil {
+			ok["content"] = map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(doc.Response))}}
+		}
+		op["responses"] = map[string]any{
+			"2XX": ok,
+			"default": map[string]any{"description": "Error", "content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			}},
+		}
+		for _, hnd := range r.Handlers {
+			switch ptr := reflect.ValueOf(hnd).Pointer(); {
+			case authPtr != 0 && ptr == authPtr:
+				op["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"cookieAuth": []string{}}}
+			case ptr == deprecatedPtr:
+				op["deprecated"] = true
+			}
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][method] = op
+	}
+	if siteName == "" {
+		siteName = "TROUGH"
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   siteName + " API",
+			"version": middleware.CurrentAPIVersion + ".0",
+			"description": "Sign in with POST /api/login and send the token as a Bearer header, or rely on the auth_token cookie. " +
+				"Cookie sessions must echo the token from GET /api/csrf in the X-CSRF-Token header on state-changing requests; it is bound to the session and rotates on sign-in and sign-out. " +
+				"Requests with a Bearer header need no CSRF token. " +
+				"Every path is also served under /api/v" + middleware.CurrentAPIVersion + "; pin a version with that prefix or the Accept-Version header. " +
+				"Deprecated operations answer with Deprecation and Sunset headers.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": "auth_token"},
+			},
+		},
+	}
+}
+
+// WithOpenAPI lets the admin handler describe app's routes; authMW is the session
+// middleware used to tell which routes need signing in.
+func (h *AdminHandler) WithOpenAPI(app *fiber.App, authMW fiber.Handler) *AdminHandler {
+	h.openAPI = &openAPIDoc{app: app, authMW: authMW}
+	return h
+}
+
+// openAPIDoc caches the rendered documents; routes don't change after startup.
+type openAPIDoc struct {
+	app    *fiber.App
+	authMW fiber.Handler
+	once   sync.Once
+	full   []byte
+	public []byte
+	err    error
+}
+
+func (d *openAPIDoc) render(siteName string) {
+	routes := d.app.GetRoutes(true)
+	if d.full, d.err = json.Marshal(buildOpenAPI(routes, d.authMW, siteName, true)); d.err != nil {
+		return
+	}
+	d.public, d.err = json.Marshal(buildOpenAPI(routes, d.authMW, siteName, false))
+}
+
+// OpenAPISpec serves the OpenAPI 3 document. Admins get every route; everyone else gets
+// the document without admin and moderation endpoints.
+func (h *AdminHandler) OpenAPISpec(c *fiber.Ctx) error {
+	if h.openAPI == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "API docs not configured"})
+	}
+	d := h.openAPI
+	d.once.Do(func() { d.render(services.GetCachedSettings(h.settingsRepo).SiteName) })
+	if d.err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build API docs"})
+	}
+	body := d.public
+	if h.optionalAdmin(c) {
+		body = d.full
+		c.Set(fiber.HeaderCacheControl, "private, no-store")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	}
+	c.Set(fiber.HeaderVary, "Cookie, Authorization")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(body)
+}
+
+// optionalAdmin reports whether the request carries an admin session, on routes that are
+// open to everyone.
+func (h *AdminHandler) optionalAdmin(c *fiber.Ctx) bool {
+	uid := middleware.OptionalUserID(c)
+	if uid == uuid.Nil {
+		return false
+	}
+	u, err := h.userRepo.GetByID(c.Context(), uid)
+	return err == nil && u.IsAdmin && !u.IsDisabled
+}
+
+const swaggerUIVersion = "5.17.14"
+
+// APIDocs serves Swagger UI for the OpenAPI document to admins.
+func (h *AdminHandler) APIDocs(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	cdn := "https://cdn.jsdelivr.net/npm/swagger-ui-dist@" + swaggerUIVersion
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendString(`<!doctype html>
+<html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1">
+<title>API docs</title><link rel="stylesheet" href="` + cdn + `/swagger-ui.css" crossorigin="anonymous"></head>
+<body><div id="swagger-ui"></div>
+<script src="` + cdn + `/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+<script>window.ui = SwaggerUIBundle({ url: '/api/openapi.json', dom_id: '#swagger-ui', withCredentials: true });</script>
+</body></html>`)
+}
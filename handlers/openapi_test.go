@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+)
+
+func TestBuildOpenAPI(t *testing.T) {
+	app := fiber.New()
+	auth := middleware.Protected()
+	noop := func(c *fiber.Ctx) error { return nil }
+	api := app.Group("/api")
+	api.Post("/register", noop)
+	api.Get("/images/:id", noop)
+	api.Patch("/me/profile", auth, noop)
+	api.Get("/admin/stats", auth, noop)
+	api.Post("/images/:id/like", middleware.Deprecated(time.Now(), time.Time{}, ""), auth, noop)
+	app.Get("/healthz", noop)
+
+	doc := buildOpenAPI(app.GetRoutes(true), middleware.Protected(), "Trough", false)
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Paths["/healthz"]; ok {
+		t.Error("non-API routes should be left out")
+	}
+	if _, ok := spec.Paths["/api/admin/stats"]; ok {
+		t.Error("admin routes should be left out of the public document")
+	}
+	img := spec.Paths["/api/images/{id}"]["get"]
+	if img == nil || img["operationId"] != "getImagesById" {
+		t.Fatalf("expected GET /api/images/{id}, got %v", spec.Paths)
+	}
+	if _, ok := img["security"]; ok {
+		t.Error("public routes should not require auth")
+	}
+	if _, ok := spec.Paths["/api/me/profile"]["patch"]["security"]; !ok {
+		t.Error("routes behind the auth middleware should require auth")
+	}
+	if spec.Paths["/api/images/{id}/like"]["post"]["deprecated"] != true {
+		t.Error("routes behind middleware.Deprecated should be marked deprecated")
+	}
+	if _, ok := img["deprecated"]; ok {
+		t.Error("only deprecated routes should be marked")
+	}
+	if _, ok := spec.Paths["/api/images/{id}"]["head"]; ok {
+		t.Error("implicit HEAD routes should be skipped")
+	}
+	user := spec.Components.Schemas["UserResponse"]
+	if user == nil || user["properties"].(map[string]any)["created_at"].(map[string]any)["format"] != "date-time" {
+		t.Fatalf("UserResponse schema missing or wrong: %v", user)
+	}
+	body := spec.Paths["/api/register"]["post"]["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	required, _ := body["required"].([]any)
+	if len(required) != 3 || body["properties"].(map[string]any)["invite"] == nil {
+		t.Fatalf("register body should flatten CreateUserRequest with its required fields: %v", body)
+	}
+
+	full := buildOpenAPI(app.GetRoutes(true), auth, "", true)
+	if _, ok := full["paths"].(map[string]map[string]any)["/api/admin/stats"]; !ok {
+		t.Error("the full document should include admin routes")
+	}
+}
+
+func TestAPIDocSchemas(t *testing.T) {
+	b := &schemaBuilder{components: map[string]any{}}
+	for key, doc := range apiDocs {
+		for _, v := range []any{doc.Body, doc.Response} {
+			if v == nil {
+				continue
+			}
+			if s := b.schema(reflect.TypeOf(v)); len(s) == 0 {
+				t.Errorf("%s: empty schema for %T", key, v)
+			}
+		}
+	}
+	if _, err := json.Marshal(b.components); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
 )
 
 // Public page view handler: returns JSON for SPA render or performs redirect if configured
@@ -17,17 +18,16 @@ func NewPageHandler(repo models.PageRepositoryInterface) *PageHandler {
 	return &PageHandler{pages: repo}
 }
 
-// GetPublicPage returns the public page content or redirect
+// GetPublicPage returns the public page content or redirect, with the page's published
+// children for section indexes
 func (h *PageHandler) GetPublicPage(c *fiber.Ctx) error {
-	slug := strings.ToLower(strings.TrimSpace(c.Params("slug")))
-	if slug == "" {
+	// The path may span several segments (help/faq)
+	slug, err := services.NormalizePagePath(c.Params("*"))
+	if err != nil {
 		return fiber.ErrNotFound
 	}
-	// Restrict to single-segment, alphanumeric/hyphen
-	if strings.Contains(slug, "/") {
-		return fiber.ErrNotFound
-	}
-	p, err := h.pages.GetPublishedBySlug(slug)
+	pages := tenantPages(c, h.pages)
+	p, err := pages.GetPublishedBySlug(slug)
 	if err != nil || p == nil {
 		return fiber.ErrNotFound
 	}
@@ -41,10 +41,20 @@ func (h *PageHandler) GetPublicPage(c *fiber.Ctx) error {
 	if p.MetaDescription != nil {
 		desc = strings.TrimSpace(*p.MetaDescription)
 	}
+	children := []fiber.Map{}
+	if all, err := pages.ListPublished(); err == nil {
+		for _, ch := range all {
+			if models.ParentPagePath(ch.Slug) == p.Slug {
+				children = append(children, fiber.Map{"slug": ch.Slug, "title": ch.Title})
+			}
+		}
+	}
 	return c.JSON(fiber.Map{
 		"slug":             p.Slug,
+		"parent":           models.ParentPagePath(p.Slug),
+		"children":         children,
 		"title":            title,
-		"html":             p.HTML,
+		"html":             services.PageHTML(p),
 		"markdown":         p.Markdown,
 		"redirect_url":     strings.TrimSpace(coalesce(p.RedirectURL)),
 		"meta_title":       html.EscapeString(metaTitle),
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// AdminListPageRevisions returns a page's save history, newest first.
+func (h *AdminHandler) AdminListPageRevisions(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.pageRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
+	}
+	pages := tenantPages(c, h.pageRepo)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := pages.ListRevisions(id, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list revisions", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"revisions": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// AdminRestorePageRevision makes an earlier revision the current page content. The
+// restore is itself saved as a new revision, so it can be undone the same way.
+func (h *AdminHandler) AdminRestorePageRevision(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.pageRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Page repository not configured"})
+	}
+	pages := tenantPages(c, h.pageRepo)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || rev < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid revision"})
+	}
+	r, err := pages.GetRevision(id, rev)
+	if err != nil || r == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+	}
+	// The tree may have changed since the revision was saved
+	if _, err := validatePagePath(pages, r.Slug); err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot restore: " + err.Error()})
+	}
+	if isOwnDescendant(pages, id, r.Slug) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot restore: the page would be moved under itself"})
+	}
+	rendered, err := renderPageMarkdown(r.RedirectURL, r.Markdown)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Markdown could not be rendered"})
+	}
+	oldSlug := cdnPageSlug(pages, id)
+	p := &models.Page{ID: id, Slug: r.Slug, Title: r.Title, Markdown: r.Markdown, HTML: rendered, IsPublished: r.IsPublished, RedirectURL: r.RedirectURL, MetaTitle: r.MetaTitle, MetaDescription: r.MetaDescription, Position: r.Position, UpdatedBy: actorID(c)}
+	if err := pages.Update(p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Page not found"})
+		}
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Another page now uses the slug " + r.Slug})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Restore failed"})
+	}
+	purgePagesFromCDN(c, h.settingsRepo, oldSlug, p.Slug)
+	services.Logger(c.Context()).Info("pages: revision restored", "page_id", id.String(), "rev", rev, "admin_id", middleware.GetUserID(c).String())
+	return c.JSON(fiber.Map{"page": p, "restored_from": rev})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakePageRepo struct {
+	models.PageRepositoryInterface
+	pages map[uuid.UUID]models.Page
+	revs  []models.PageRevision
+}
+
+func (f *fakePageRepo) Update(p *models.Page) error {
+	if _, ok := f.pages[p.ID]; !ok {
+		return sql.ErrNoRows
+	}
+	for id, other := range f.pages {
+		if id != p.ID && other.Slug == p.Slug {
+			return errors.New(`pq: duplicate key value violates unique constraint "pages_slug_key"`)
+		}
+	}
+	f.pages[p.ID] = *p
+	f.revs = append(f.revs, models.PageRevision{PageID: p.ID, Rev: len(f.revs) + 1, Slug: p.Slug, Title: p.Title, Markdown: p.Markdown, AuthorID: p.UpdatedBy})
+	return nil
+}
+
+func (f *fakePageRepo) GetRevision(pageID uuid.UUID, rev int) (*models.PageRevision, error) {
+	for _, r := range f.revs {
+		if r.PageID == pageID && r.Rev == rev {
+			return &r, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestRestorePageRevision(t *testing.T) {
+	id, other := uuid.New(), uuid.New()
+	repo := &fakePageRepo{pages: map[uuid.UUID]models.Page{
+		id:    {ID: id, Slug: "faq", Title: "FAQ", Markdown: "broken"},
+		other: {ID: other, Slug: "help"},
+	}, revs: []models.PageRevision{
+		{PageID: id, Rev: 1, Slug: "faq", Title: "FAQ", Markdown: "# Good"},
+		{PageID: id, Rev: 2, Slug: "help", Title: "Help", Markdown: "# Moved"},
+	}}
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithPages(repo)
+	app.Post("/pages/:id/revisions/:rev/restore", h.AdminRestorePageRevision)
+	restore := func(pageID uuid.UUID, rev string) *http.Response {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodPost, "/pages/"+pageID.String()+"/revisions/"+rev+"/restore", nil))
+		return resp
+	}
+
+	resp := restore(id, "1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Page         models.Page `json:"page"`
+		RestoredFrom int         `json:"restored_from"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.RestoredFrom != 1 || repo.pages[id].Markdown != "# Good" || repo.pages[id].HTML != "<h1 id=\"good\">Good</h1>\n" {
+		t.Fatalf("expected revision 1 content, got %+v", repo.pages[id])
+	}
+	if len(repo.revs) != 3 {
+		t.Fatalf("expected the restore to be saved as a new revision, got %d", len(repo.revs))
+	}
+	if code := restore(id, "2").StatusCode; code != http.StatusConflict {
+		t.Fatalf("expected 409 when the old slug is taken, got %d", code)
+	}
+	if code := restore(id, "9").StatusCode; code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing revision, got %d", code)
+	}
+	if code := restore(id, "x").StatusCode; code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad revision, got %d", code)
+	}
+}
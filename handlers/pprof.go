@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+// AdminPprof serves the Go profiler (index, profile, heap, goroutine, trace...) under
+// /api/admin/debug/pprof to admins. main.go only mounts it when server.pprof is on.
+func (h *AdminHandler) AdminPprof() fiber.Handler {
+	profiler := pprof.New(pprof.Config{Prefix: "/api/admin"})
+	return func(c *fiber.Ctx) error {
+		if !checkAdmin(c, h.userRepo) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+		}
+		services.Logger(c.Context()).Info("admin: pprof", "path", c.Path(), "admin_id", middleware.GetUserID(c).String())
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return profiler(c)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+func TestAdminPprof(t *testing.T) {
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{})
+	app.Use("/api/admin/debug/pprof", h.AdminPprof())
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/admin/debug/pprof/goroutine?debug=1", nil))
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine profile") {
+		t.Fatalf("expected a goroutine profile, got %d %.100s", resp.StatusCode, body)
+	}
+
+	prev := checkAdmin
+	checkAdmin = func(*fiber.Ctx, models.UserRepositoryInterface) bool { return false }
+	defer func() { checkAdmin = prev }()
+	if resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/admin/debug/pprof/heap", nil)); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admins, got %d", resp.StatusCode)
+	}
+}
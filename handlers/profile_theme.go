@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+var accentColorRe = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// maxProfileBannerWidth bounds stored banner images; they are shown full width at most.
+const maxProfileBannerWidth = 2400
+
+// normalizeProfileTheme validates a theme from PATCH /api/me/profile. Header fields are
+// owned by the banner upload, so the current ones are kept whatever the client sent.
+func normalizeProfileTheme(t models.ProfileTheme, current models.ProfileTheme) (models.ProfileTheme, error) {
+	t.Accent = strings.ToLower(strings.TrimSpace(t.Accent))
+	if t.Accent != "" && !accentColorRe.MatchString(t.Accent) {
+		return t, errors.New("accent must be a hex color like #7af0ff")
+	}
+	t.Layout = strings.ToLower(strings.TrimSpace(t.Layout))
+	switch t.Layout {
+	case "", models.ProfileLayoutMasonry, models.ProfileLayoutGrid, models.ProfileLayoutWide:
+	default:
+		return t, errors.New("layout must be masonry, grid or wide")
+	}
+	t.HeaderKey = current.HeaderKey
+	t.HeaderURL = current.HeaderURL
+	return t, nil
+}
+
+// UploadProfileBanner stores a banner image for the caller's profile under banners/,
+// replacing any previous one. The file comes in the "banner" field, or "header" as
+// sent by older clients.
+func (h *UserHandler) UploadProfileBanner(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	file, err := c.FormFile("banner")
+	if err != nil {
+		file, err = c.FormFile("header")
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No banner file provided"})
+	}
+	fileValidator := services.NewFileValidator()
+	fileValidator.MaxFileSize = 10 * 1024 * 1024
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open uploaded file"})
+	}
+	defer src.Close()
+	result, err := fileValidator.ValidateFile(file.Filename, src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate file"})
+	}
+	if !result.IsValid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": result.ErrorMessage})
+	}
+	if _, err := src.Seek(0, 0); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset file pointer"})
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to decode banner image"})
+	}
+	// Re-encoding drops metadata and bounds the stored size
+	img = services.FlattenIfAlpha(services.ResizeIfNeeded(img, maxProfileBannerWidth), color.White)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encode banner image"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	st := h.currentStorage()
+	key := services.NewProfileMediaKey(services.BannerPrefix, ".jpg")
+	publicURL, err := st.Save(c.Context(), key, bytes.NewReader(buf.Bytes()), "image/jpeg")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store banner image"})
+	}
+	theme := u.ProfileTheme
+	oldKey := theme.HeaderKey
+	theme.HeaderKey, theme.HeaderURL = key, publicURL
+	if _, err := h.userRepo.UpdateProfile(userID, models.UpdateUserRequest{ProfileTheme: &theme}); err != nil {
+		_ = st.Delete(c.Context(), key)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
+	}
+	if oldKey != "" {
+		_ = st.Delete(c.Context(), oldKey)
+	}
+	return c.JSON(fiber.Map{"profile_theme": theme})
+}
+
+// DeleteProfileBanner removes the caller's banner image.
+func (h *UserHandler) DeleteProfileBanner(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	theme := u.ProfileTheme
+	if theme.HeaderKey == "" {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	oldKey := theme.HeaderKey
+	theme.HeaderKey, theme.HeaderURL = "", ""
+	if _, err := h.userRepo.UpdateProfile(userID, models.UpdateUserRequest{ProfileTheme: &theme}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
+	}
+	_ = h.currentStorage().Delete(c.Context(), oldKey)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// currentStorage returns the active storage backend, falling back to local uploads.
+func (h *UserHandler) currentStorage() services.Storage {
+	if st := services.GetCurrentStorage(); st != nil {
+		return st
+	}
+	if h.storage != nil {
+		return h.storage
+	}
+	return services.NewLocalStorage(services.UploadsDir())
+}
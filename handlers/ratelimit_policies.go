@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+// WithPolicyReload sets how rate limit policies are re-read, normally from config.yaml
+func (h *AdminHandler) WithPolicyReload(load func() ([]services.RateLimitPolicy, error)) *AdminHandler {
+	h.loadPolicies = load
+	return h
+}
+
+// AdminRateLimitPolicies lists the per-route rate limit policies in effect.
+func (h *AdminHandler) AdminRateLimitPolicies(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	return c.JSON(rateLimitPoliciesResponse(services.CurrentRateLimitPolicies()))
+}
+
+// AdminReloadRateLimitPolicies re-reads the policies and applies them at once. An invalid
+// file leaves the current policies in place. With prefork only the process serving the
+// request reloads.
+func (h *AdminHandler) AdminReloadRateLimitPolicies(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.loadPolicies == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Policy reload not configured"})
+	}
+	list, err := h.loadPolicies()
+	if err == nil {
+		err = services.SetRateLimitPolicies(list)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to reload policies", "details": err.Error()})
+	}
+	services.Logger(c.Context()).Info("rate limit policies reloaded", "count", len(list), "admin_id", middleware.GetUserID(c).String())
+	return c.JSON(rateLimitPoliciesResponse(services.CurrentRateLimitPolicies()))
+}
+
+func rateLimitPoliciesResponse(set *services.RateLimitPolicySet) fiber.Map {
+	out := []fiber.Map{}
+	for _, p := range set.Policies() {
+		out = append(out, fiber.Map{"name": p.Name, "route": p.Route, "capacity": p.Capacity, "window": p.Window.String(), "key": p.Key})
+	}
+	return fiber.Map{"policies": out, "loaded_at": set.LoadedAt()}
+}
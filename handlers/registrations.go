@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// maxRejectReason caps the note sent to someone whose registration is rejected.
+const maxRejectReason = 500
+
+// pendingRegistration is a queued account as staff review it; unlike the user list it
+// carries the email, so the queue is for admins only.
+type pendingRegistration struct {
+	models.AdminUserResponse
+	Email string `json:"email"`
+}
+
+// AdminListPendingRegistrations lists accounts waiting for approval, oldest first.
+func (h *UserHandler) AdminListPendingRegistrations(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	users, total, err := h.userRepo.ListPendingApproval(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list registrations"})
+	}
+	resp := make([]pendingRegistration, len(users))
+	for i := range users {
+		resp[i] = pendingRegistration{AdminUserResponse: users[i].ToAdminResponse(), Email: users[i].Email}
+	}
+	return c.JSON(fiber.Map{"users": resp, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+// AdminApproveRegistration lets a queued account sign in and emails its owner.
+func (h *UserHandler) AdminApproveRegistration(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	ok, err := h.userRepo.Approve(uid)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to approve registration"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No pending registration"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	if set, ok := h.mailSettings(); ok {
+		link := strings.TrimRight(set.SiteURL, "/") + "/"
+		services.EnqueueMessage(u.Email, services.BuildRegistrationApprovedMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, link))
+	}
+	services.Logger(c.Context()).Info("admin: registration approved", "user_id", uid.String(), "by", middleware.GetUserID(c).String())
+	return c.JSON(fiber.Map{"user": u.ToAdminResponse()})
+}
+
+// AdminRejectRegistration deletes a queued account and emails its owner, with the
+// optional reason from the body.
+func (h *UserHandler) AdminRejectRegistration(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+		}
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if utf8.RuneCountInString(reason) > maxRejectReason {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason must be at most 500 characters"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil || !u.PendingApproval {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No pending registration"})
+	}
+	if err := h.userRepo.DeleteUser(uid); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reject registration"})
+	}
+	if set, ok := h.mailSettings(); ok {
+		services.EnqueueMessage(u.Email, services.BuildRegistrationRejectedMessage(services.SiteLocale(set), set.SiteName, set.SiteURL, reason))
+	}
+	services.Logger(c.Context()).Info("admin: registration rejected", "user_id", uid.String(), "username", u.Username, "by", middleware.GetUserID(c).String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// mailSettings returns the site settings when SMTP is set up to send from.
+func (h *UserHandler) mailSettings() (models.SiteSettings, bool) {
+	if h.settingsRepo == nil {
+		return models.SiteSettings{}, false
+	}
+	set := services.GetCachedSettings(h.settingsRepo)
+	return set, set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != ""
+}
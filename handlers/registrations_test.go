@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type approvalUserRepo struct {
+	impersonationUserRepo
+}
+
+func (f *approvalUserRepo) GetByUsername(_ context.Context, username string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *approvalUserRepo) Approve(id uuid.UUID) (bool, error) {
+	u, ok := f.users[id]
+	if !ok || !u.PendingApproval {
+		return false, nil
+	}
+	u.PendingApproval = false
+	return true, nil
+}
+
+func (f *approvalUserRepo) DeleteUser(id uuid.UUID) error {
+	delete(f.users, id)
+	return nil
+}
+
+func TestRegistrationQueue(t *testing.T) {
+	adminID, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	users := &approvalUserRepo{impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID: {ID: adminID, Username: "root", IsAdmin: true},
+		alice:   {ID: alice, Username: "alice", PendingApproval: true},
+		bob:     {ID: bob, Username: "bob", PendingApproval: true},
+	}}}
+	h := NewUserHandler(users, &fakeImageRepo{}, nil)
+	caller := adminID
+	app := fiber.New()
+	asCaller := func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}
+	app.Post("/admin/registrations/:id/approve", asCaller, h.AdminApproveRegistration)
+	app.Post("/admin/registrations/:id/reject", asCaller, h.AdminRejectRegistration)
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	caller = alice
+	if got := post("/admin/registrations/"+bob.String()+"/approve", ""); got != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admins, got %d", got)
+	}
+	caller = adminID
+	if got := post("/admin/registrations/"+alice.String()+"/approve", ""); got != http.StatusOK || users.users[alice].PendingApproval {
+		t.Fatalf("expected alice approved, got %d", got)
+	}
+	if got := post("/admin/registrations/"+alice.String()+"/approve", ""); got != http.StatusNotFound {
+		t.Fatalf("expected 404 approving an account that is not pending, got %d", got)
+	}
+	if got := post("/admin/registrations/"+alice.String()+"/reject", ""); got != http.StatusNotFound || users.users[alice] == nil {
+		t.Fatalf("expected approved accounts to be safe from rejection, got %d", got)
+	}
+	if got := post("/admin/registrations/"+bob.String()+"/reject", `{"reason":"`+strings.Repeat("x", maxRejectReason+1)+`"}`); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an overlong reason, got %d", got)
+	}
+	if got := post("/admin/registrations/"+bob.String()+"/reject", `{"reason":"Spam"}`); got != http.StatusNoContent || users.users[bob] != nil {
+		t.Fatalf("expected bob's registration deleted, got %d", got)
+	}
+}
+
+func TestLoginRefusesPendingAccount(t *testing.T) {
+	id := uuid.New()
+	u := &models.User{ID: id, Username: "alice", PendingApproval: true}
+	_ = u.HashPassword("correct horse battery")
+	users := &approvalUserRepo{impersonationUserRepo{users: map[uuid.UUID]*models.User{id: u}}}
+	app := fiber.New()
+	app.Post("/login", NewAuthHandler(users).Login)
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"login_identifier":"alice","login_password":"correct horse battery"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusForbidden || len(resp.Cookies()) != 0 {
+		t.Fatalf("expected 403 without a session, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// remixSourceMaxLen bounds an external remix source URL.
+const remixSourceMaxLen = 500
+
+// remixChainLimit is how far up a chain of remixes the cycle check looks.
+const remixChainLimit = 32
+
+var remixPageRe = regexp.MustCompile(`^/i/([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})/?$`)
+
+// resolveRemixSource reads what an uploader says an image was remixed from: an image id
+// or a page URL on this site names an on-site image, and any other http(s) URL is kept as
+// an external source. An empty value clears the source. self is the image being edited,
+// or uuid.Nil for a new upload.
+func (h *ImageHandler) resolveRemixSource(c *fiber.Ctx, self uuid.UUID, raw string) (*uuid.UUID, *string, *uploadError) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil, nil
+	}
+	parentID, err := uuid.Parse(raw)
+	if err != nil {
+		u, perr := url.Parse(raw)
+		if perr != nil {
+			return nil, nil, uploadFailed(fiber.StatusBadRequest, "remix_of must be an image id, an image page URL or an http(s) URL")
+		}
+		m := remixPageRe.FindStringSubmatch(u.Path)
+		if m == nil || (u.Host != "" && !strings.EqualFold(u.Hostname(), c.Hostname())) {
+			if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || len(raw) > remixSourceMaxLen {
+				return nil, nil, uploadFailed(fiber.StatusBadRequest, "remix_of must be an image id, an image page URL or an http(s) URL")
+			}
+			s := u.String()
+			return nil, &s, nil
+		}
+		parentID = uuid.MustParse(m[1])
+	}
+	if parentID == self {
+		return nil, nil, uploadFailed(fiber.StatusBadRequest, "An image cannot be a remix of itself")
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	parent, err := h.imageRepo.GetByID(ctx, parentID)
+	if err != nil || parent.IsWithheld() || parent.IsPrivate() {
+		return nil, nil, uploadFailed(fiber.StatusNotFound, "Remixed image not found")
+	}
+	// Editing an existing image must not close a loop of remixes
+	for next, i := parent.RemixOf, 0; self != uuid.Nil && next != nil && i < remixChainLimit; i++ {
+		if *next == self {
+			return nil, nil, uploadFailed(fiber.StatusBadRequest, "An image cannot be a remix of its own remix")
+		}
+		up, err := h.imageRepo.GetByID(ctx, *next)
+		if err != nil {
+			break
+		}
+		next = up.RemixOf
+	}
+	return &parent.ID, nil, nil
+}
+
+// ListRemixes returns the public images declared as remixes of an image, newest first.
+// The image itself follows the same visibility rules as GET /api/images/:id.
+func (h *ImageHandler) ListRemixes(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if (img.IsWithheld() || img.IsPrivate()) && !h.canSeePending(c, img.UserID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "24"))
+	if limit < 1 || limit > 100 {
+		limit = 24
+	}
+	remixes, err := h.imageRepo.Remixes(id, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch remixes"})
+	}
+	return c.JSON(fiber.Map{"remixes": remixes})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type remixImageRepo struct {
+	visibilityImageRepo
+}
+
+func (f *remixImageRepo) UpdateMeta(uuid.UUID, *string, *string, *models.ContentRating) error {
+	return nil
+}
+
+func (f *remixImageRepo) SetRemixOf(id uuid.UUID, parent *uuid.UUID, sourceURL *string) error {
+	f.images[id].RemixOf, f.images[id].RemixOfURL = parent, sourceURL
+	return nil
+}
+
+func (f *remixImageRepo) Remixes(parentID uuid.UUID, _ int) ([]models.ImageWithUser, error) {
+	out := []models.ImageWithUser{}
+	for _, img := range f.images {
+		if img.RemixOf != nil && *img.RemixOf == parentID && !img.IsPrivate() {
+			out = append(out, *img)
+		}
+	}
+	return out, nil
+}
+
+func TestUpdateImage_RemixOf(t *testing.T) {
+	ownerID, otherID := uuid.New(), uuid.New()
+	source, remix, private := uuid.New(), uuid.New(), uuid.New()
+	repo := &remixImageRepo{visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		source:  {Image: models.Image{ID: source, UserID: otherID, Visibility: models.ImageVisibilityPublic}},
+		remix:   {Image: models.Image{ID: remix, UserID: ownerID, Visibility: models.ImageVisibilityPublic}},
+		private: {Image: models.Image{ID: private, UserID: otherID, Visibility: models.ImageVisibilityPrivate}},
+	}}}
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{ownerID: {ID: ownerID}, otherID: {ID: otherID}}}
+	h := NewImageHandler(repo, nil, users, services.Config{}, nil)
+	caller := ownerID
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	})
+	app.Patch("/images/:id", h.UpdateImage)
+	app.Get("/images/:id/remixes", h.ListRemixes)
+	patch := func(as, id uuid.UUID, remixOf string) int {
+		caller = as
+		req := httptest.NewRequest(http.MethodPatch, "/images/"+id.String(), strings.NewReader(`{"remix_of":"`+remixOf+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := patch(ownerID, remix, "ftp://example.org/x"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-http source, got %d", code)
+	}
+	if code := patch(ownerID, remix, "https://civitai.example/images/42"); code != http.StatusOK || repo.images[remix].RemixOfURL == nil || repo.images[remix].RemixOf != nil {
+		t.Fatalf("expected an external source kept as a URL, got %d %+v", code, repo.images[remix].Image)
+	}
+	if code := patch(ownerID, remix, "http://example.com/i/"+source.String()); code != http.StatusOK || repo.images[remix].RemixOf == nil || *repo.images[remix].RemixOf != source || repo.images[remix].RemixOfURL != nil {
+		t.Fatalf("expected an image page on this site linked by id, got %d %+v", code, repo.images[remix].Image)
+	}
+	if code := patch(ownerID, remix, remix.String()); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an image remixing itself, got %d", code)
+	}
+	if code := patch(otherID, source, remix.String()); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a loop of remixes, got %d", code)
+	}
+	if code := patch(ownerID, remix, private.String()); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a private source, got %d", code)
+	}
+	if code := patch(otherID, remix, ""); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for someone else's image, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/images/"+source.String()+"/remixes", nil)
+	resp, _ := app.Test(req)
+	var out struct {
+		Remixes []models.ImageWithUser `json:"remixes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode != http.StatusOK || len(out.Remixes) != 1 || out.Remixes[0].ID != remix {
+		t.Fatalf("expected the remix listed on its source, got %d %+v", resp.StatusCode, out)
+	}
+}
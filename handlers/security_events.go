@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+var securityEventSeverities = map[string]bool{"low": true, "medium": true, "high": true}
+
+// WithSecurityEvents injects the persisted security event log
+func (h *AdminHandler) WithSecurityEvents(r models.SecurityEventRepositoryInterface) *AdminHandler {
+	h.securityEvents = r
+	return h
+}
+
+// ListSecurityEvents returns persisted rate limiter security events, newest first.
+// Filters: severity (comma-separated), type, ip, and since/until as RFC 3339 times.
+func (h *AdminHandler) ListSecurityEvents(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.securityEvents == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Security event log not configured"})
+	}
+	f, err := parseSecurityEventFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 {
+		limit = 1
+	} else if limit > 200 {
+		limit = 200
+	}
+	list, total, err := h.securityEvents.List(f, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list security events", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"events": list, "page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit})
+}
+
+func parseSecurityEventFilter(c *fiber.Ctx) (models.SecurityEventFilter, error) {
+	var f models.SecurityEventFilter
+	for _, s := range strings.Split(c.Query("severity"), ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if !securityEventSeverities[s] {
+			return f, errors.New("severity must be low, medium or high")
+		}
+		f.Severities = append(f.Severities, s)
+	}
+	f.EventType = strings.ToUpper(strings.TrimSpace(c.Query("type")))
+	if ip := strings.TrimSpace(c.Query("ip")); ip != "" {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return f, errors.New("ip must be an IP address")
+		}
+		f.IP = parsed.String()
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := strings.TrimSpace(c.Query(p.name)); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, errors.New(p.name + " must be an RFC 3339 time")
+			}
+			*p.dst = t.Local()
+		}
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Until.After(f.Since) {
+		return f, errors.New("until must be after since")
+	}
+	return f, nil
+}
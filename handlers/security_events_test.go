@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeSecurityEventRepo struct {
+	models.SecurityEventRepositoryInterface
+	filter models.SecurityEventFilter
+}
+
+func (f *fakeSecurityEventRepo) List(filter models.SecurityEventFilter, page, limit int) ([]models.SecurityEvent, int, error) {
+	f.filter = filter
+	return []models.SecurityEvent{}, 0, nil
+}
+
+func TestListSecurityEvents(t *testing.T) {
+	app := fiber.New()
+	repo := &fakeSecurityEventRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithSecurityEvents(repo)
+	app.Get("/events", h.ListSecurityEvents)
+	get := func(query string) int {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/events"+query, nil))
+		return resp.StatusCode
+	}
+
+	if code := get("?severity=high,Medium&ip=203.0.113.9&type=account_lockout&since=2026-01-01T00:00:00Z"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	f := repo.filter
+	if len(f.Severities) != 2 || f.Severities[1] != "medium" || f.IP != "203.0.113.9" || f.EventType != "ACCOUNT_LOCKOUT" || f.Since.IsZero() || !f.Until.IsZero() {
+		t.Fatalf("unexpected filter %+v", f)
+	}
+	for _, q := range []string{"?severity=critical", "?ip=nope", "?since=yesterday", "?since=2026-02-01T00:00:00Z&until=2026-01-01T00:00:00Z"} {
+		if code := get(q); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, code)
+		}
+	}
+}
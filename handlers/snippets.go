@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+var snippetKeyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+const maxSnippetContent = 20000
+
+// WithSnippets injects the content snippet repository
+func (h *AdminHandler) WithSnippets(r models.SnippetRepositoryInterface) *AdminHandler {
+	h.snippets = r
+	return h
+}
+
+// GetSnippets returns the rendered snippets as a key -> html map, optionally limited to
+// ?keys=a,b. Like announcements it degrades to an empty map rather than failing the SPA.
+func (h *AdminHandler) GetSnippets(c *fiber.Ctx) error {
+	out := map[string]string{}
+	if h.snippets == nil {
+		return c.JSON(fiber.Map{"snippets": out})
+	}
+	list, err := h.snippets.List()
+	if err != nil {
+		services.Logger(c.Context()).Error("snippets: list failed", "error", err)
+		return c.JSON(fiber.Map{"snippets": out})
+	}
+	var want map[string]bool
+	if q := strings.TrimSpace(c.Query("keys")); q != "" {
+		want = map[string]bool{}
+		for _, k := range strings.Split(q, ",") {
+			want[strings.ToLower(strings.TrimSpace(k))] = true
+		}
+	}
+	for _, s := range list {
+		if want == nil || want[s.Key] {
+			out[s.Key] = s.HTML
+		}
+	}
+	return c.JSON(fiber.Map{"snippets": out})
+}
+
+// AdminListSnippets returns every snippet with its source content.
+func (h *AdminHandler) AdminListSnippets(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.snippets == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Snippets not configured"})
+	}
+	list, err := h.snippets.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list snippets", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"snippets": list})
+}
+
+// AdminPutSnippet creates or replaces the snippet at :key. The content is rendered and
+// sanitized here so the public endpoint serves stored HTML.
+func (h *AdminHandler) AdminPutSnippet(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.snippets == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Snippets not configured"})
+	}
+	key := strings.ToLower(c.Params("key"))
+	if !snippetKeyRe.MatchString(key) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid key (lowercase letters, digits, - and _)"})
+	}
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	content := strings.ReplaceAll(req.Content, "\r\n", "\n")
+	if len([]rune(content)) > maxSnippetContent {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content too long (max 20000 characters)"})
+	}
+	rendered := ""
+	if strings.TrimSpace(content) != "" {
+		var err error
+		if rendered, err = services.RenderMarkdown(content); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Content could not be rendered"})
+		}
+	}
+	s := &models.Snippet{Key: key, Content: content, HTML: rendered, UpdatedBy: actorID(c)}
+	if err := h.snippets.Upsert(s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save snippet", "details": err.Error()})
+	}
+	services.Logger(c.Context()).Info("snippets: saved", "key", key, "admin_id", middleware.GetUserID(c).String())
+	return c.JSON(s)
+}
+
+func (h *AdminHandler) AdminDeleteSnippet(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.snippets == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Snippets not configured"})
+	}
+	key := strings.ToLower(c.Params("key"))
+	if _, err := h.snippets.Get(key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Snippet not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	if err := h.snippets.Delete(key); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
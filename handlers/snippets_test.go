@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeSnippetRepo struct {
+	models.SnippetRepositoryInterface
+	items map[string]models.Snippet
+}
+
+func (f *fakeSnippetRepo) List() ([]models.Snippet, error) {
+	out := []models.Snippet{}
+	for _, s := range f.items {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (f *fakeSnippetRepo) Get(key string) (*models.Snippet, error) {
+	s, ok := f.items[key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &s, nil
+}
+
+func (f *fakeSnippetRepo) Upsert(s *models.Snippet) error {
+	f.items[s.Key] = *s
+	return nil
+}
+
+func (f *fakeSnippetRepo) Delete(key string) error {
+	delete(f.items, key)
+	return nil
+}
+
+func TestSnippetsCRUD(t *testing.T) {
+	app := fiber.New()
+	repo := &fakeSnippetRepo{items: map[string]models.Snippet{}}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, &fakeUserRepo{}, &fakeImageRepo{}).WithSnippets(repo)
+	app.Get("/snippets", h.GetSnippets)
+	app.Put("/admin/snippets/:key", h.AdminPutSnippet)
+	app.Delete("/admin/snippets/:key", h.AdminDeleteSnippet)
+	put := func(key, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/admin/snippets/"+key, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := put("footer", `{"content":"**Hi** <script>alert(1)</script><a href=\"javascript:x()\">x</a>"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := put("upload_guidelines", `{"content":"Be nice"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := put("bad.key%21", `{"content":"x"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid key, got %d", code)
+	}
+	html := repo.items["footer"].HTML
+	if !strings.Contains(html, "<strong>Hi</strong>") || strings.Contains(html, "script") || strings.Contains(html, "javascript:") {
+		t.Fatalf("expected sanitized html, got %q", html)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/snippets?keys=footer", nil))
+	var body struct {
+		Snippets map[string]string `json:"snippets"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Snippets) != 1 || body.Snippets["footer"] != html {
+		t.Fatalf("expected only the footer snippet, got %+v", body.Snippets)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodDelete, "/admin/snippets/footer", nil))
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodDelete, "/admin/snippets/footer", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing snippet, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// AdminSystem returns runtime diagnostics for this instance: Go runtime and memory
+// stats, the build, database pool, storage health, settings cache age and background
+// jobs. Figures are per instance; the job counts come from the shared queue.
+func (h *AdminHandler) AdminSystem(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	out := fiber.Map{
+		"build": services.BuildInfo(),
+		"runtime": fiber.Map{
+			"uptime_seconds":    int64(time.Since(services.BuildInfo().StartedAt).Seconds()),
+			"goroutines":        runtime.NumGoroutine(),
+			"num_cpu":           runtime.NumCPU(),
+			"gomaxprocs":        runtime.GOMAXPROCS(0),
+			"heap_alloc_bytes":  mem.HeapAlloc,
+			"heap_sys_bytes":    mem.HeapSys,
+			"heap_objects":      mem.HeapObjects,
+			"sys_bytes":         mem.Sys,
+			"gc_cycles":         mem.NumGC,
+			"gc_pause_total_ms": float64(mem.PauseTotalNs) / 1e6,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 3*time.Second)
+	defer cancel()
+	if db := models.DB(); db != nil {
+		st := db.Stats()
+		pool := fiber.Map{
+			"max_open": st.MaxOpenConnections, "open": st.OpenConnections, "in_use": st.InUse, "idle": st.Idle,
+			"wait_count": st.WaitCount, "wait_ms": st.WaitDuration.Milliseconds(),
+			"max_idle_closed": st.MaxIdleClosed, "max_lifetime_closed": st.MaxLifetimeClosed,
+		}
+		start := time.Now()
+		if err := db.PingContext(ctx); err != nil {
+			pool["ping_error"] = err.Error()
+		}
+		pool["ping_ms"] = time.Since(start).Milliseconds()
+		out["database"] = pool
+	}
+
+	set := services.GetCachedSettings(h.settingsRepo)
+	storage := fiber.Map{"provider": set.StorageProvider}
+	st := services.GetCurrentStorage()
+	if st == nil {
+		st = h.storage
+	}
+	if st == nil {
+		storage["status"] = checkFail
+		storage["error"] = "storage not configured"
+	} else {
+		storage["local"] = st.IsLocal()
+		cached, err := services.ProbeStorage(ctx, st)
+		storage["status"], storage["cached"] = checkOK, cached
+		if err != nil {
+			storage["status"], storage["error"] = checkFail, err.Error()
+		}
+	}
+	out["storage"] = storage
+
+	cache := fiber.Map{"loaded": false}
+	if age, ok := services.SettingsCacheAge(); ok {
+		cache = fiber.Map{"loaded": true, "age_seconds": int64(age.Seconds())}
+	}
+	out["settings_cache"] = cache
+
+	workers, kinds := services.JobWorkerStatus()
+	jobs := fiber.Map{"workers": workers, "kinds": kinds}
+	if h.jobs != nil {
+		if counts, err := h.jobs.Summary(); err != nil {
+			jobs["error"] = err.Error()
+		} else {
+			jobs["counts"] = counts
+		}
+	}
+	out["jobs"] = jobs
+	return c.JSON(out)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+)
+
+type summaryJobRepo struct {
+	models.JobRepositoryInterface
+}
+
+func (summaryJobRepo) Summary() ([]models.JobCount, error) {
+	return []models.JobCount{{Kind: "mail.outbox", Status: models.JobStatusPending, Count: 1}}, nil
+}
+
+func TestAdminSystem(t *testing.T) {
+	app := fiber.New()
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{StorageProvider: "local"}}, &fakeUserRepo{}, &fakeImageRepo{}).
+		WithStorage(&probeStorage{}).WithJobs(summaryJobRepo{})
+	app.Get("/admin/system", h.AdminSystem)
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/admin/system", nil))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Build struct {
+			Version   string `json:"version"`
+			GoVersion string `json:"go_version"`
+		} `json:"build"`
+		Runtime struct {
+			Goroutines int    `json:"goroutines"`
+			HeapAlloc  uint64 `json:"heap_alloc_bytes"`
+		} `json:"runtime"`
+		Storage struct {
+			Provider string `json:"provider"`
+			Status   string `json:"status"`
+		} `json:"storage"`
+		Jobs struct {
+			Counts []models.JobCount `json:"counts"`
+		} `json:"jobs"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.Build.Version == "" || body.Build.GoVersion == "" || body.Runtime.Goroutines == 0 || body.Runtime.HeapAlloc == 0 {
+		t.Fatalf("expected build and runtime stats, got %+v", body)
+	}
+	if body.Storage.Provider != "local" || body.Storage.Status != "ok" {
+		t.Fatalf("expected healthy local storage, got %+v", body.Storage)
+	}
+	if len(body.Jobs.Counts) != 1 || body.Jobs.Counts[0].Kind != "mail.outbox" {
+		t.Fatalf("expected job counts, got %+v", body.Jobs)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// Field limits for takedown requests, in characters.
+const (
+	maxTakedownName      = 200
+	maxTakedownURL       = 2000
+	maxTakedownWork      = 2000
+	maxTakedownDesc      = 5000
+	maxTakedownSignature = 200
+	maxTakedownNote      = 2000
+)
+
+var takedownUUIDRe = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// WithTakedowns enables the public takedown form and the admin review of its requests.
+func (h *ImageHandler) WithTakedowns(r models.TakedownRepositoryInterface) *ImageHandler {
+	h.takedowns = r
+	return h
+}
+
+// resolveTakedownImage finds the image a claimant pointed at: an image id, a page URL
+// containing one, or the URL of the file itself (whose name is not the image id).
+func (h *ImageHandler) resolveTakedownImage(ctx context.Context, ref string) (*models.ImageWithUser, error) {
+	if id := takedownUUIDRe.FindString(ref); id != "" {
+		if uid, err := uuid.Parse(id); err == nil {
+			if img, err := h.imageRepo.GetByID(ctx, uid); err == nil {
+				return img, nil
+			}
+		}
+	}
+	name := ref
+	if u, err := url.Parse(ref); err == nil && u.Path != "" {
+		name = u.Path
+	}
+	if name = path.Base(name); name == "." || name == "/" || strings.ContainsAny(name, `%_\`) {
+		return nil, sql.ErrNoRows
+	}
+	id, err := h.takedowns.ImageByFilename(name)
+	if err != nil {
+		return nil, err
+	}
+	return h.imageRepo.GetByID(ctx, id)
+}
+
+// SubmitTakedown files a copyright takedown request from the public form and tells the
+// admins. The image stays up until staff act on the request.
+func (h *ImageHandler) SubmitTakedown(c *fiber.Ctx) error {
+	if h.takedowns == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Takedowns not configured"})
+	}
+	var body struct {
+		Image        string `json:"image"`
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		Organization string `json:"organization"`
+		OriginalWork string `json:"original_work"`
+		Description  string `json:"description"`
+		Signature    string `json:"signature"`
+		GoodFaith    bool   `json:"good_faith"`
+		Accurate     bool   `json:"accurate"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	t := &models.TakedownRequest{
+		ImageURL:      strings.TrimSpace(body.Image),
+		ClaimantName:  strings.TrimSpace(body.Name),
+		ClaimantEmail: strings.ToLower(strings.TrimSpace(body.Email)),
+		Organization:  strings.TrimSpace(body.Organization),
+		OriginalWork:  strings.TrimSpace(body.OriginalWork),
+		Description:   strings.TrimSpace(body.Description),
+		Signature:     strings.TrimSpace(body.Signature),
+	}
+	switch {
+	case t.ImageURL == "" || len([]rune(t.ImageURL)) > maxTakedownURL:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Link the image you are reporting"})
+	case t.ClaimantName == "" || len([]rune(t.ClaimantName)) > maxTakedownName || len([]rune(t.Organization)) > maxTakedownName:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Enter your name"})
+	case t.OriginalWork == "" || len([]rune(t.OriginalWork)) > maxTakedownWork || len([]rune(t.Description)) > maxTakedownDesc:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Describe the original work"})
+	case t.Signature == "" || len([]rune(t.Signature)) > maxTakedownSignature:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sign the request with your full name"})
+	case !body.GoodFaith || !body.Accurate:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You must confirm both statements"})
+	}
+	if addr, err := mail.ParseAddress(t.ClaimantEmail); err != nil || addr.Address != t.ClaimantEmail || len(t.ClaimantEmail) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.resolveTakedownImage(ctx, t.ImageURL)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	t.ImageID = &img.ID
+	t.IPHash = services.HashIP(services.ClientIP(c))
+	if err := h.takedowns.Create(t); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to file request"})
+	}
+	services.NotifyAdmins(h.notifyRepo, &models.Notification{Type: models.NotificationTakedownReceived, ImageID: &img.ID, Message: t.ClaimantName})
+	services.PublishAdminEvent(services.AdminEventTakedown, fiber.Map{"action": models.TakedownActionReceived, "takedown_id": t.ID, "image_id": img.ID})
+	services.Logger(c.Context()).Info("takedown: request received", "takedown_id", t.ID.String(), "image_id", img.ID.String())
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": t.ID, "status": t.Status})
+}
+
+// AdminListTakedowns lists takedown requests newest first, optionally by status.
+func (h *ImageHandler) AdminListTakedowns(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.takedowns == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Takedowns not configured"})
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.TakedownOpen, models.TakedownDisabled, models.TakedownRemoved, models.TakedownRejected:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid status"})
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	list, total, err := h.takedowns.List(status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list takedowns"})
+	}
+	totalPages := (total + limit - 1) / limit
+	return c.JSON(fiber.Map{"takedowns": list, "page": page, "limit": limit, "total": total, "total_pages": totalPages})
+}
+
+// AdminGetTakedown returns one request with its resolution log.
+func (h *ImageHandler) AdminGetTakedown(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.takedowns == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Takedowns not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid takedown id"})
+	}
+	t, err := h.takedowns.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Takedown request not found"})
+	}
+	events, err := h.takedowns.Events(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load takedown log"})
+	}
+	return c.JSON(fiber.Map{"takedown": t, "events": events})
+}
+
+// AdminTakedownAction takes {"action", "note"} on a request: disable hides the image
+// pending review, remove deletes it, reject restores it, and note only adds to the log.
+// The image owner is told of each outcome.
+func (h *ImageHandler) AdminTakedownAction(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.takedowns == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Takedowns not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid takedown id"})
+	}
+	var body struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	action, note := strings.TrimSpace(body.Action), strings.TrimSpace(body.Note)
+	if !models.IsTakedownAction(action) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown action"})
+	}
+	if len([]rune(note)) > maxTakedownNote {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note too long"})
+	}
+	t, err := h.takedowns.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Takedown request not found"})
+	}
+	actor := middleware.GetUserID(c)
+	if action == models.TakedownActionNote {
+		if note == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note required"})
+		}
+		if err := h.takedowns.AddEvent(id, action, note, actor); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add note"})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	var img *models.ImageWithUser
+	if t.ImageID != nil {
+		if img, err = h.imageRepo.GetByID(ctx, *t.ImageID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load image"})
+		}
+	}
+	ok, err := h.takedowns.Apply(id, action, note, actor)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update takedown"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The request's status does not allow that action"})
+	}
+	if img != nil {
+		switch action {
+		case models.TakedownActionDisable:
+			services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationImageDisabled, ImageID: &img.ID})
+		case models.TakedownActionReject:
+			// Another request may still hold the image; only tell the owner once it is back
+			if after, err := h.imageRepo.GetByID(ctx, img.ID); err == nil && img.ModerationStatus == models.ImageStatusDisabled && after.ModerationStatus == models.ImageStatusApproved {
+				services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationImageRestored, ImageID: &img.ID})
+			}
+		case models.TakedownActionRemove:
+			h.removeStoredFiles(c.Context(), &img.Image)
+			if err := h.imageRepo.Delete(img.ID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete image"})
+			}
+			// The image row is gone, so the notification carries the note but no image link
+			services.Notify(h.notifyRepo, &models.Notification{UserID: img.UserID, Type: models.NotificationImageRemoved, Message: note})
+		}
+	}
+	services.InvalidateFeedCache(c.Context())
+	services.PublishAdminEvent(services.AdminEventTakedown, fiber.Map{"action": action, "takedown_id": id, "image_id": t.ImageID, "admin_id": actor})
+	services.Logger(c.Context()).Info("takedown: "+action, "takedown_id", id.String(), "admin_id", actor.String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeTakedownRepo struct {
+	models.TakedownRepositoryInterface
+	filenames map[string]uuid.UUID
+	created   []models.TakedownRequest
+	request   *models.TakedownRequest
+	applyOK   bool
+	applied   []string
+}
+
+func (f *fakeTakedownRepo) Create(t *models.TakedownRequest) error {
+	t.ID, t.Status = uuid.New(), models.TakedownOpen
+	f.created = append(f.created, *t)
+	return nil
+}
+
+func (f *fakeTakedownRepo) ImageByFilename(name string) (uuid.UUID, error) {
+	if id, ok := f.filenames[name]; ok {
+		return id, nil
+	}
+	return uuid.Nil, sql.ErrNoRows
+}
+
+func (f *fakeTakedownRepo) Get(id uuid.UUID) (*models.TakedownRequest, error) {
+	if f.request == nil || f.request.ID != id {
+		return nil, sql.ErrNoRows
+	}
+	return f.request, nil
+}
+
+func (f *fakeTakedownRepo) Apply(_ uuid.UUID, action, _ string, _ uuid.UUID) (bool, error) {
+	if f.applyOK {
+		f.applied = append(f.applied, action)
+	}
+	return f.applyOK, nil
+}
+
+type takedownImageRepo struct {
+	models.ImageRepositoryInterface
+	images map[uuid.UUID]*models.ImageWithUser
+}
+
+func (f *takedownImageRepo) GetByID(_ context.Context, id uuid.UUID) (*models.ImageWithUser, error) {
+	if img, ok := f.images[id]; ok {
+		return img, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+type takedownNotifications struct {
+	models.NotificationRepositoryInterface
+	admin []models.Notification
+	users []models.Notification
+}
+
+func (f *takedownNotifications) NotifyAdmins(n *models.Notification) error {
+	f.admin = append(f.admin, *n)
+	return nil
+}
+
+func (f *takedownNotifications) Create(n *models.Notification) error {
+	f.users = append(f.users, *n)
+	return nil
+}
+
+func TestSubmitTakedown(t *testing.T) {
+	img := &models.ImageWithUser{Image: models.Image{ID: uuid.New(), UserID: uuid.New(), Filename: "3f1c.webp"}}
+	images := &takedownImageRepo{images: map[uuid.UUID]*models.ImageWithUser{img.ID: img}}
+	takedowns := &fakeTakedownRepo{filenames: map[string]uuid.UUID{"3f1c.webp": img.ID}}
+	notify := &takedownNotifications{}
+	h := NewImageHandler(images, nil, &fakeUserRepo{}, services.Config{}, nil).WithNotifications(notify).WithTakedowns(takedowns)
+	app := fiber.New()
+	app.Post("/takedown", h.SubmitTakedown)
+	post := func(image string, confirmed bool) int {
+		body := `{"image":"` + image + `","name":"Ada Artist","email":"ada@example.com","original_work":"Harbour at dusk, 2021","signature":"Ada Artist","good_faith":true,"accurate":` + map[bool]string{true: "true", false: "false"}[confirmed] + `}`
+		req := httptest.NewRequest(http.MethodPost, "/takedown", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if got := post("https://trough.example/i/"+img.ID.String(), false); got != http.StatusBadRequest || len(takedowns.created) != 0 {
+		t.Fatalf("expected 400 without both statements confirmed, got %d", got)
+	}
+	if got := post("https://trough.example/i/"+img.ID.String(), true); got != http.StatusCreated {
+		t.Fatalf("expected 201 for an image page URL, got %d", got)
+	}
+	if got := post("https://cdn.example/uploads/3f1c.webp?sig=x", true); got != http.StatusCreated {
+		t.Fatalf("expected 201 for the image's file URL, got %d", got)
+	}
+	if len(takedowns.created) != 2 || *takedowns.created[1].ImageID != img.ID || len(notify.admin) != 2 {
+		t.Fatalf("expected both requests filed against the image and admins told, got %+v %+v", takedowns.created, notify.admin)
+	}
+	if got := post("https://trough.example/i/"+uuid.NewString(), true); got != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown image, got %d", got)
+	}
+}
+
+func TestAdminTakedownAction(t *testing.T) {
+	adminID := uuid.New()
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{adminID: {ID: adminID, IsAdmin: true}}}
+	img := &models.ImageWithUser{Image: models.Image{ID: uuid.New(), UserID: uuid.New(), ModerationStatus: models.ImageStatusApproved}}
+	images := &takedownImageRepo{images: map[uuid.UUID]*models.ImageWithUser{img.ID: img}}
+	takedowns := &fakeTakedownRepo{request: &models.TakedownRequest{ID: uuid.New(), ImageID: &img.ID, Status: models.TakedownOpen}, applyOK: true}
+	notify := &takedownNotifications{}
+	h := NewImageHandler(images, nil, users, services.Config{}, nil).WithNotifications(notify).WithTakedowns(takedowns)
+	app := fiber.New()
+	app.Post("/admin/takedowns/:id/actions", func(c *fiber.Ctx) error {
+		c.Locals("user_id", adminID)
+		return c.Next()
+	}, h.AdminTakedownAction)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/takedowns/"+takedowns.request.ID.String()+"/actions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if got := post(`{"action":"hide"}`); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown action, got %d", got)
+	}
+	if got := post(`{"action":"disable"}`); got != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", got)
+	}
+	if len(notify.users) != 1 || notify.users[0].Type != models.NotificationImageDisabled || notify.users[0].UserID != img.UserID {
+		t.Fatalf("expected the owner told their image is disabled, got %+v", notify.users)
+	}
+	takedowns.applyOK = false
+	if got := post(`{"action":"disable"}`); got != http.StatusConflict || len(takedowns.applied) != 1 {
+		t.Fatalf("expected 409 when the status no longer allows the action, got %d", got)
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// WithTenants injects the multi-site tenant repository
+func (h *AdminHandler) WithTenants(r models.TenantRepositoryInterface) *AdminHandler {
+	h.tenants = r
+	return h
+}
+
+// tenantPages scopes r to the request's tenant. The primary site uses r as-is.
+func tenantPages(c *fiber.Ctx, r models.PageRepositoryInterface) models.PageRepositoryInterface {
+	if id := middleware.TenantID(c); id != nil {
+		return r.ForTenant(id)
+	}
+	return r
+}
+
+// tenantImages scopes r's feed to the request's tenant. The primary site uses r as-is.
+func tenantImages(c *fiber.Ctx, r models.ImageRepositoryInterface) models.ImageRepositoryInterface {
+	if id := middleware.TenantID(c); id != nil {
+		return r.ForTenant(id)
+	}
+	return r
+}
+
+// onRequestSite reports whether something belonging to tenant (nil for the primary site)
+// is served on the request's site, matching how tenantImages scopes feeds.
+func onRequestSite(c *fiber.Ctx, tenant *uuid.UUID) bool {
+	id := middleware.TenantID(c)
+	if id == nil || tenant == nil {
+		return id == nil && tenant == nil
+	}
+	return *id == *tenant
+}
+
+// tenantLegal scopes r to the terms of the request's tenant. The primary site uses r as-is.
+func tenantLegal(c *fiber.Ctx, r models.LegalRepositoryInterface) models.LegalRepositoryInterface {
+	if id := middleware.TenantID(c); id != nil {
+		return r.ForTenant(id)
+	}
+	return r
+}
+
+// tenantSettings overlays the request tenant's branding and policy on s.
+func tenantSettings(c *fiber.Ctx, s *models.SiteSettings) *models.SiteSettings {
+	return middleware.GetTenant(c).Apply(s)
+}
+
+// ReloadTenants refreshes the in-memory host table from the repository.
+func (h *AdminHandler) ReloadTenants() error {
+	if h.tenants == nil {
+		return nil
+	}
+	list, err := h.tenants.List()
+	if err != nil {
+		return err
+	}
+	services.SetTenants(list)
+	return nil
+}
+
+type tenantRequest struct {
+	Host           *string `json:"host"`
+	Name           *string `json:"name"`
+	SiteName       *string `json:"site_name"`
+	SiteURL        *string `json:"site_url"`
+	SEOTitle       *string `json:"seo_title"`
+	SEODescription *string `json:"seo_description"`
+	SocialImageURL *string `json:"social_image_url"`
+	FaviconPath    *string `json:"favicon_path"`
+	// Policy overrides: a value replaces the primary site's setting, null inherits it again
+	AdultSite     json.RawMessage `json:"adult_site"`
+	MinimumAge    json.RawMessage `json:"minimum_age"`
+	DefaultLocale json.RawMessage `json:"default_locale"`
+}
+
+// decodeOverride sets *dst from a policy override in a request body: null clears it,
+// an absent field leaves it alone.
+func decodeOverride[T any](raw json.RawMessage, dst **T) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if string(raw) == "null" {
+		*dst = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	*dst = &v
+	return nil
+}
+
+// apply validates req and copies it onto t.
+func (req tenantRequest) apply(t *models.Tenant) error {
+	if req.Host != nil {
+		host, err := services.NormalizeTenantHost(*req.Host)
+		if err != nil {
+			return err
+		}
+		t.Host = host
+	}
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if t.Host == "" {
+		return errors.New("host is required")
+	}
+	if t.Name == "" {
+		t.Name = t.Host
+	}
+	if len([]rune(t.Name)) > 100 {
+		return errors.New("name too long (max 100 characters)")
+	}
+	text := []struct {
+		dst *string
+		src *string
+		max int
+	}{
+		{&t.SiteName, req.SiteName, 100},
+		{&t.SEOTitle, req.SEOTitle, 200},
+		{&t.SEODescription, req.SEODescription, 300},
+		{&t.FaviconPath, req.FaviconPath, 500},
+	}
+	for _, f := range text {
+		if f.src == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.src)
+		if len([]rune(v)) > f.max {
+			return errors.New("a branding field is too long")
+		}
+		*f.dst = v
+	}
+	for _, f := range []struct {
+		dst *string
+		src *string
+	}{{&t.SiteURL, req.SiteURL}, {&t.SocialImageURL, req.SocialImageURL}} {
+		if f.src == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.src)
+		lower := strings.ToLower(v)
+		if v != "" && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "http://") {
+			return errors.New("site_url and social_image_url must be http(s) URLs")
+		}
+		*f.dst = v
+	}
+	if err := decodeOverride(req.AdultSite, &t.AdultSite); err != nil {
+		return errors.New("adult_site must be true, false or null")
+	}
+	if err := decodeOverride(req.MinimumAge, &t.MinimumAge); err != nil || (t.MinimumAge != nil && (*t.MinimumAge < 0 || *t.MinimumAge > 99)) {
+		return errors.New("minimum_age must be between 0 and 99, or null")
+	}
+	if err := decodeOverride(req.DefaultLocale, &t.DefaultLocale); err != nil {
+		return errors.New("default_locale must be a string or null")
+	}
+	if t.DefaultLocale != nil {
+		locale, err := services.NormalizeLocale(*t.DefaultLocale)
+		if err != nil || !services.LocaleSupported(locale) {
+			return errors.New("default_locale must be one of " + strings.Join(services.Locales(), ", "))
+		}
+		t.DefaultLocale = &locale
+	}
+	return nil
+}
+
+// checkTenantPolicy refuses a tenant that would be an adult site without the least minimum
+// age, once its overrides are applied to the primary site's settings.
+func (h *AdminHandler) checkTenantPolicy(t *models.Tenant) error {
+	set := services.GetCachedSettings(h.settingsRepo)
+	if eff := t.Apply(&set); eff.AdultSite && eff.MinimumAge < services.AdultSiteMinimumAge {
+		return errors.New("An adult site needs a minimum age of at least 18")
+	}
+	return nil
+}
+
+func (h *AdminHandler) AdminListTenants(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.tenants == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Tenants not configured"})
+	}
+	list, err := h.tenants.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list tenants", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"tenants": list})
+}
+
+// AdminCreateTenant starts serving another domain. DNS and TLS for the host are up to
+// the operator; requests for it are matched as soon as this returns.
+func (h *AdminHandler) AdminCreateTenant(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.tenants == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Tenants not configured"})
+	}
+	var req tenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	t := &models.Tenant{}
+	if err := req.apply(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.checkTenantPolicy(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.tenants.Create(t); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A tenant already uses this host"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create tenant", "details": err.Error()})
+	}
+	h.reloadTenantsLogged(c)
+	services.Logger(c.Context()).Info("tenants: created", "id", t.ID.String(), "host", t.Host, "admin_id", middleware.GetUserID(c).String())
+	return c.Status(fiber.StatusCreated).JSON(t)
+}
+
+// AdminUpdateTenant changes the fields present in the body.
+func (h *AdminHandler) AdminUpdateTenant(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.tenants == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Tenants not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	t, err := h.tenants.Get(id)
+	if err != nil || t == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tenant not found"})
+	}
+	var req tenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.apply(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.checkTenantPolicy(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.tenants.Update(t); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A tenant already uses this host"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update tenant", "details": err.Error()})
+	}
+	h.reloadTenantsLogged(c)
+	return c.JSON(t)
+}
+
+// AdminDeleteTenant removes a tenant and its pages. Images uploaded to it must be deleted
+// first, so nothing silently moves into the primary feed.
+func (h *AdminHandler) AdminDeleteTenant(c *fiber.Ctx) error {
+	if !checkAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.tenants == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Tenants not configured"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid id"})
+	}
+	if _, err := h.tenants.Get(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tenant not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	if err := h.tenants.Delete(id); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "foreign key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The tenant still has images; delete them first"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
+	}
+	h.reloadTenantsLogged(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AdminHandler) reloadTenantsLogged(c *fiber.Ctx) {
+	if err := h.ReloadTenants(); err != nil {
+		services.Logger(c.Context()).Error("tenants: reload failed", "error", err)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeTenantRepo struct {
+	models.TenantRepositoryInterface
+	items []models.Tenant
+}
+
+func (f *fakeTenantRepo) List() ([]models.Tenant, error) { return f.items, nil }
+
+func (f *fakeTenantRepo) Create(t *models.Tenant) error {
+	for _, o := range f.items {
+		if o.Host == t.Host {
+			return errors.New(`pq: duplicate key value violates unique constraint "tenants_host_key"`)
+		}
+	}
+	t.ID = uuid.New()
+	f.items = append(f.items, *t)
+	return nil
+}
+
+func TestTenantsCreateAndBranding(t *testing.T) {
+	defer services.SetTenants(nil)
+	app := fiber.New()
+	app.Use(middleware.Tenant())
+	repo := &fakeTenantRepo{}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{SiteName: "TROUGH", SEOTitle: "Main"}}, &fakeUserRepo{}, &fakeImageRepo{}).WithTenants(repo)
+	app.Post("/admin/tenants", h.AdminCreateTenant)
+	app.Get("/site", h.GetPublicSite)
+	create := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/tenants", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := create(`{"host":"https://bad.example.com/x"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a URL as host, got %d", code)
+	}
+	if code := create(`{"host":"Art.Example.com","site_name":"Art Trough"}`); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if code := create(`{"host":"art.example.com"}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate host, got %d", code)
+	}
+
+	site := func(host string) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/site", nil)
+		req.Host = host
+		resp, _ := app.Test(req)
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return out
+	}
+	if s := site("art.example.com:8080"); s["site_name"] != "Art Trough" || s["seo_title"] != "Main" {
+		t.Fatalf("expected tenant branding over the shared settings, got %+v", s)
+	}
+	if s := site("trough.example.com"); s["site_name"] != "TROUGH" {
+		t.Fatalf("expected the primary site on other hosts, got %+v", s)
+	}
+}
+
+func TestGetImageStaysOnItsSite(t *testing.T) {
+	tenant := models.Tenant{ID: uuid.New(), Host: "art.example.com"}
+	services.SetTenants([]models.Tenant{tenant})
+	defer services.SetTenants(nil)
+	owner := uuid.New()
+	primary, onTenant := uuid.New(), uuid.New()
+	repo := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{
+		primary:  {Image: models.Image{ID: primary, UserID: owner, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved}},
+		onTenant: {Image: models.Image{ID: onTenant, UserID: owner, Visibility: models.ImageVisibilityPublic, ModerationStatus: models.ImageStatusApproved, TenantID: &tenant.ID}},
+	}}
+	app := fiber.New()
+	app.Use(middleware.Tenant())
+	app.Get("/images/:id", NewImageHandler(repo, nil, &fakeUserRepo{}, services.Config{}, nil).GetImage)
+	get := func(host string, id uuid.UUID) int {
+		req := httptest.NewRequest(http.MethodGet, "/images/"+id.String(), http.NoBody)
+		req.Host = host
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	for _, tc := range []struct {
+		host string
+		id   uuid.UUID
+		want int
+	}{
+		{"trough.example.com", primary, http.StatusOK},
+		{"trough.example.com", onTenant, http.StatusNotFound},
+		{"art.example.com", onTenant, http.StatusOK},
+		{"art.example.com", primary, http.StatusNotFound},
+	} {
+		if got := get(tc.host, tc.id); got != tc.want {
+			t.Errorf("%s image %s: expected %d, got %d", tc.host, tc.id, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// uploadJob is the payload of an upload.process job: the request plus the staged file.
+type uploadJob struct {
+	uploadRequest
+	Staged string `json:"staged"`
+}
+
+// Statuses reported by GET /api/uploads/:token/status.
+const (
+	UploadStatusProcessing = "processing"
+	UploadStatusDone       = "done"
+	UploadStatusFailed     = "failed"
+)
+
+// WithJobs processes uploads in background jobs; without it uploads finish in the request.
+func (h *ImageHandler) WithJobs(r models.JobRepositoryInterface) *ImageHandler {
+	h.jobs = r
+	return h
+}
+
+// queueUpload stages a validated upload and queues its processing, answering 202 with the
+// token to poll. queued is false when the queue is not running; the caller then processes
+// the upload in the request.
+func (h *ImageHandler) queueUpload(c *fiber.Ctx, file *multipart.FileHeader, req uploadRequest) (queued bool, err error) {
+	if h.jobs == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(services.StagingDir(), 0o700); err != nil {
+		services.Logger(c.Context()).Error("upload: staging dir unavailable", "error", err)
+		return false, nil
+	}
+	staged := uuid.New().String()
+	path := filepath.Join(services.StagingDir(), staged)
+	if err := c.SaveFile(file, path); err != nil {
+		services.Logger(c.Context()).Error("upload: staging failed", "error", err)
+		return false, nil
+	}
+	job, _, err := services.EnqueueJob(services.JobUploadProcess, uploadJob{uploadRequest: req, Staged: staged}, services.JobOptions{})
+	if err != nil {
+		_ = os.Remove(path)
+		if errors.Is(err, services.ErrJobsUnavailable) {
+			return false, nil
+		}
+		services.Logger(c.Context()).Error("upload: enqueue failed", "error", err)
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to queue upload"})
+	}
+	return true, c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"token":      job.ID,
+		"status":     UploadStatusProcessing,
+		"status_url": "/api/uploads/" + job.ID.String() + "/status",
+	})
+}
+
+// RegisterUploadJob registers the upload.process job kind, which runs processUpload on a
+// staged file. Rejections fail at once; storage and database errors are retried.
+func (h *ImageHandler) RegisterUploadJob() {
+	services.RegisterJob(services.JobSpec{
+		Kind:        services.JobUploadProcess,
+		Timeout:     10 * time.Minute,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var p uploadJob
+			if err := json.Unmarshal(job.Payload, &p); err != nil || p.Staged == "" {
+				return nil, services.PermanentJobError(errors.New("invalid upload payload"))
+			}
+			path := filepath.Join(services.StagingDir(), filepath.Base(p.Staged))
+			f, err := os.Open(path)
+			if err != nil {
+				services.UploadsTotal.Inc("error")
+				return fiber.Map{"code": fiber.StatusGone}, services.PermanentJobError(uploadFailed(fiber.StatusGone, "Upload expired before it was processed"))
+			}
+			defer f.Close()
+			img, uerr := h.processUpload(ctx, p.uploadRequest, f)
+			// The staged file is kept only while a retry may still need it
+			if uerr == nil || uerr.status < 500 || job.Attempts >= job.MaxAttempts {
+				_ = os.Remove(path)
+				status := fiber.StatusCreated
+				if uerr != nil {
+					status = uerr.status
+				}
+				services.UploadsTotal.Inc(uploadResult(status))
+			}
+			if uerr != nil {
+				res := fiber.Map{"code": uerr.status}
+				if uerr.status < 500 {
+					return res, services.PermanentJobError(uerr)
+				}
+				return res, uerr
+			}
+			return img.ToUploadResponse(), nil
+		},
+	})
+}
+
+// UploadStatus reports a queued upload to its uploader: processing, done with the image,
+// or failed with the error the upload request would have returned.
+func (h *ImageHandler) UploadStatus(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	token, err := uuid.Parse(c.Params("token"))
+	if err != nil || h.jobs == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+	job, err := h.jobs.Get(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load upload status"})
+	}
+	var p uploadJob
+	// Other users' uploads and other job kinds are indistinguishable from unknown tokens
+	if job.Kind != services.JobUploadProcess || json.Unmarshal(job.Payload, &p) != nil || p.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	resp := fiber.Map{"token": job.ID, "status": UploadStatusProcessing}
+	switch job.Status {
+	case models.JobStatusDone:
+		resp["status"] = UploadStatusDone
+		if job.Result != nil {
+			resp["image"] = json.RawMessage(*job.Result)
+		}
+	case models.JobStatusDead:
+		resp["status"] = UploadStatusFailed
+		resp["error"] = "Upload failed"
+		if job.LastError != nil && *job.LastError != "" {
+			resp["error"] = *job.LastError
+		}
+		var res struct {
+			Code int `json:"code"`
+		}
+		if job.Result != nil && json.Unmarshal(*job.Result, &res) == nil && res.Code > 0 {
+			resp["code"] = res.Code
+		}
+	}
+	return c.JSON(resp)
+}
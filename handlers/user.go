@@ -4,14 +4,10 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
-	"fmt"
-	"image"
-	"image/draw"
-	"image/jpeg"
-	"io"
+	"errors"
 	_ "image/png"
+	"io"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -33,6 +29,13 @@ type UserHandler struct {
 	settingsRepo  models.SiteSettingsRepositoryInterface
 	newMailSender func(*models.SiteSettings) services.MailSender
 	pageRepo      models.PageRepositoryInterface
+	statsRepo     models.StatsRepositoryInterface
+	history       models.UsernameHistoryRepositoryInterface
+	boards        models.BoardRepositoryInterface
+	blocks        models.BlockRepositoryInterface
+	verification  models.VerificationRepositoryInterface
+	legal         models.LegalRepositoryInterface
+	edits         models.ImageEditRepositoryInterface
 }
 
 func NewUserHandler(userRepo models.UserRepositoryInterface, imageRepo models.ImageRepositoryInterface, storage services.Storage) *UserHandler {
@@ -51,17 +54,49 @@ func (h *UserHandler) WithSettings(r models.SiteSettingsRepositoryInterface) *Us
 	return h
 }
 
+// WithImageEdits records rating changes made from the admin panel in the image's history.
+func (h *UserHandler) WithImageEdits(r models.ImageEditRepositoryInterface) *UserHandler {
+	h.edits = r
+	return h
+}
+
 func (h *UserHandler) WithPages(r models.PageRepositoryInterface) *UserHandler {
 	h.pageRepo = r
 	return h
 }
 
+// WithStats injects the stats repository used for profile counters.
+func (h *UserHandler) WithStats(r models.StatsRepositoryInterface) *UserHandler {
+	h.statsRepo = r
+	return h
+}
+
+// WithUsernameHistory records renames and lets old handles resolve to the renamed account.
+func (h *UserHandler) WithUsernameHistory(r models.UsernameHistoryRepositoryInterface) *UserHandler {
+	h.history = r
+	return h
+}
+
+// findUser looks up a profile by handle. A handle given up within UsernameRedirectGrace
+// resolves to the account that renamed away from it, so shared links keep working.
+func (h *UserHandler) findUser(ctx context.Context, username string) (*models.User, error) {
+	user, err := h.userRepo.GetByUsername(ctx, username)
+	if err == nil || h.history == nil {
+		return user, err
+	}
+	id, herr := h.history.ResolveOld(username, time.Now().Add(-models.UsernameRedirectGrace))
+	if herr != nil {
+		return nil, err
+	}
+	return h.userRepo.GetByID(ctx, id)
+}
+
 // Public: list published pages for footer or navigation
 func (h *UserHandler) ListPublicPages(c *fiber.Ctx) error {
 	if h.pageRepo == nil {
 		return c.JSON(fiber.Map{"pages": []any{}})
 	}
-	list, err := h.pageRepo.ListPublished()
+	list, err := tenantPages(c, h.pageRepo).ListPublished()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
@@ -83,14 +118,51 @@ func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
-	user, err := h.userRepo.GetByUsername(ctx, username)
+	user, err := h.findUser(ctx, username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
 	}
 
-	return c.JSON(user.ToResponse())
+	resp := user.ToResponse()
+	if h.statsRepo != nil {
+		// Counters are decoration; a failure here should not hide the profile
+		if s, err := h.statsRepo.UserStatsSummary(user.ID); err == nil {
+			resp.Stats = s
+		}
+	}
+	stats := models.UserStatsSummary{}
+	if resp.Stats != nil {
+		stats = *resp.Stats
+	}
+	if conditionalGet(c, user.UpdatedAt, "user", user.ID, user.UpdatedAt.UnixNano(), resp.Stats != nil, stats.Images, stats.Collected) {
+		return notModified(c)
+	}
+	return c.JSON(resp)
+}
+
+// GetUserStats returns public upload statistics for a user: image and collected counts,
+// first/last upload times and the AI provider breakdown.
+func (h *UserHandler) GetUserStats(c *fiber.Ctx) error {
+	username := normalizeUsername(c.Params("username"))
+	if username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username required"})
+	}
+	if h.statsRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Stats not configured"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	user, err := h.findUser(ctx, username)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	stats, err := h.statsRepo.UserStats(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch stats"})
+	}
+	return c.JSON(stats)
 }
 
 func (h *UserHandler) GetUserImages(c *fiber.Ctx) error {
@@ -104,7 +176,7 @@ func (h *UserHandler) GetUserImages(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
-	user, err := h.userRepo.GetByUsername(ctx, username)
+	user, err := h.findUser(ctx, username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
@@ -118,25 +190,51 @@ func (h *UserHandler) GetUserImages(c *fiber.Ctx) error {
 			limit = v
 		}
 	}
+	// A shadowbanned user's gallery looks empty to everyone but them and staff
+	if user.IsShadowbanned && !h.canViewShadowbanned(c, user.ID) {
+		return c.JSON(models.FeedResponse{Images: []models.ImageWithUser{}, Page: 1})
+	}
+	// Owners also see their unlisted and private images in their own gallery
+	viewer := middleware.OptionalUserID(c)
+	includeHidden := viewer == user.ID
+	repo := forViewer(h.imageRepo, viewer)
+	display := viewerDisplay(c, h.userRepo, viewer)
 	cursor := strings.TrimSpace(c.Query("cursor", ""))
 	if cursor != "" {
-		images, next, err := h.imageRepo.GetUserImagesSeek(user.ID, limit, cursor)
+		images, next, err := repo.GetUserImagesSeek(user.ID, limit, cursor, includeHidden)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch user images"})
 		}
+		display.Annotate(images)
 		return c.JSON(models.FeedResponse{Images: images, NextCursor: next})
 	}
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	if page < 1 {
 		page = 1
 	}
-	images, total, err := h.imageRepo.GetUserImages(user.ID, page, limit)
+	images, total, err := repo.GetUserImages(user.ID, page, limit, includeHidden)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch user images"})
 	}
+	display.Annotate(images)
 	return c.JSON(models.FeedResponse{Images: images, Page: page, Total: total})
 }
 
+// canViewShadowbanned reports whether the viewer is the shadowbanned user or active staff.
+func (h *UserHandler) canViewShadowbanned(c *fiber.Ctx, ownerID uuid.UUID) bool {
+	uid := middleware.OptionalUserID(c)
+	if uid == uuid.Nil {
+		return false
+	}
+	if uid == ownerID {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	return err == nil && (u.IsAdmin || u.IsModerator) && !u.IsDisabled
+}
+
 // GetUserCollections returns images that the user has collected (not their own uploads).
 func (h *UserHandler) GetUserCollections(c *fiber.Ctx) error {
 	username := normalizeUsername(c.Params("username"))
@@ -145,13 +243,21 @@ func (h *UserHandler) GetUserCollections(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
-	user, err := h.userRepo.GetByUsername(ctx, username)
+	user, err := h.findUser(ctx, username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 	}
 	if h.collectRepo == nil {
 		h.collectRepo = models.NewCollectRepository(models.DB())
 	}
+	// Owners also see what they keep on private boards
+	viewer := middleware.OptionalUserID(c)
+	own := viewer == user.ID
+	collections := h.collectRepo
+	if viewer != uuid.Nil {
+		collections = collections.ForViewer(viewer)
+	}
+	display := viewerDisplay(c, h.userRepo, viewer)
 	// Support cursor and page
 	limit := 20
 	if lq := strings.TrimSpace(c.Query("limit", "")); lq != "" {
@@ -161,20 +267,22 @@ func (h *UserHandler) GetUserCollections(c *fiber.Ctx) error {
 	}
 	cursor := strings.TrimSpace(c.Query("cursor", ""))
 	if cursor != "" {
-		images, next, err := h.collectRepo.GetUserCollectionsSeek(user.ID, limit, cursor)
+		images, next, err := collections.GetUserCollectionsSeek(user.ID, limit, cursor, own)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch collections"})
 		}
+		display.Annotate(images)
 		return c.JSON(models.FeedResponse{Images: images, NextCursor: next})
 	}
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	if page < 1 {
 		page = 1
 	}
-	images, total, err := h.collectRepo.GetUserCollections(user.ID, page, limit)
+	images, total, err := collections.GetUserCollections(user.ID, page, limit, own)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch collections", "details": err.Error()})
 	}
+	display.Annotate(images)
 	return c.JSON(models.FeedResponse{Images: images, Page: page, Total: total})
 }
 
@@ -189,7 +297,7 @@ func (h *UserHandler) GetMyProfile(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 	}
-	return c.JSON(user.ToResponse())
+	return c.JSON(user.ToOwnResponse())
 }
 
 func (h *UserHandler) UpdateMyProfile(c *fiber.Ctx) error {
@@ -223,8 +331,57 @@ func (h *UserHandler) UpdateMyProfile(c *fiber.Ctx) error {
 				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Username already taken"})
 			}
 		}
+		// Handles still redirecting to another account can't be claimed until the grace period ends
+		if h.history != nil {
+			if owner, err := h.history.ResolveOld(uname, time.Now().Add(-models.UsernameRedirectGrace)); err == nil && owner != userID {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Username already taken"})
+			}
+		}
 		req.Username = &uname
 	}
+	var before *models.User
+	if (req.Username != nil && h.history != nil) || req.ProfileTheme != nil {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		before, _ = h.userRepo.GetByID(ctx, userID)
+	}
+	if req.ProfileTheme != nil {
+		if before == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		theme, err := normalizeProfileTheme(*req.ProfileTheme, before.ProfileTheme)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		req.ProfileTheme = &theme
+	}
+	if req.NsfwPref != nil {
+		d, ok := models.ParseDisplay(*req.NsfwPref)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nsfw_pref must be show, blur or hide"})
+		}
+		pref := string(d)
+		req.NsfwPref = &pref
+	}
+	if req.ContentPrefs != nil {
+		for name, pref := range map[string]*models.Display{"suggestive": &req.ContentPrefs.Suggestive, "mature": &req.ContentPrefs.Mature} {
+			if *pref == "" {
+				continue
+			}
+			d, ok := models.ParseDisplay(string(*pref))
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content_prefs." + name + " must be show, blur or hide"})
+			}
+			*pref = d
+		}
+	}
+	if req.FeedMutes != nil {
+		mutes, err := normalizeFeedMutes(*req.FeedMutes)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		req.FeedMutes = &mutes
+	}
 	// Enforce sensible bio length
 	if req.Bio != nil {
 		trimmed := strings.TrimSpace(*req.Bio)
@@ -238,7 +395,16 @@ func (h *UserHandler) UpdateMyProfile(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
 	}
-	return c.JSON(updated.ToResponse())
+	if before != nil && h.history != nil && before.Username != updated.Username {
+		if err := h.history.Record(userID, before.Username, updated.Username); err != nil {
+			services.Logger(c.Context()).Error("profile: recording username change failed", "error", err, "user_id", userID.String())
+		}
+	}
+	// Cached feed pages embed the uploader's username and avatar
+	if req.Username != nil || req.AvatarURL != nil {
+		services.InvalidateFeedCache(c.Context())
+	}
+	return c.JSON(updated.ToOwnResponse())
 }
 
 // Change email
@@ -268,27 +434,54 @@ func (h *UserHandler) UpdateEmail(c *fiber.Ctx) error {
 	// Conflict check
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if strings.EqualFold(user.Email, body.Email) {
+		return c.JSON(fiber.Map{"email": user.Email})
+	}
 	if existing, err := h.userRepo.GetByEmail(ctx, body.Email); err == nil && existing != nil {
 		if existing.ID != userID {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
 		}
 	}
-	if err := h.userRepo.UpdateEmail(userID, body.Email); err != nil {
+	set, _ := h.settingsRepo.Get()
+	if !(set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "") {
+		// Without mail the new address cannot be confirmed, so the change applies at once
+		if err := h.userRepo.UpdateEmail(userID, body.Email); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update email"})
+		}
+		return c.JSON(fiber.Map{"email": body.Email})
+	}
+	// The change waits for the new address to confirm, and the old address can cancel it, so a
+	// stolen session alone cannot take the account over.
+	last, _ := models.LastEmailChangeSentAt(userID)
+	if time.Since(last) < 5*time.Minute {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Please wait before requesting again"})
+	}
+	token, cancelToken := uuid.New().String(), uuid.New().String()
+	exp := time.Now().Add(24 * time.Hour)
+	if err := models.CreateEmailChange(userID, body.Email, services.HashToken(token), services.HashToken(cancelToken), exp); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update email"})
 	}
-	// If email verification is required, mark unverified and send verification email
-	set, _ := h.settingsRepo.Get()
-	if set.RequireEmailVerification && (set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "") {
-		_ = models.SetEmailVerified(userID, false)
-		token := uuid.New().String()
-		exp := time.Now().Add(24 * time.Hour)
-		_ = models.CreateEmailVerification(userID, services.HashToken(token), exp)
-		link := strings.TrimRight(set.SiteURL, "/") + "/verify?token=" + token
-		subj, bodyTxt := services.BuildVerificationEmail(set.SiteName, set.SiteURL, link)
-		// Send asynchronously via queue to avoid duplicate sends
-		services.EnqueueMail(body.Email, subj, bodyTxt)
+	base := strings.TrimRight(set.SiteURL, "/")
+	services.EnqueueMessage(body.Email, services.BuildEmailChangeMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, base+"/confirm-email?token="+token, body.Email))
+	services.EnqueueMessage(user.Email, services.BuildEmailChangeNoticeMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, base+"/cancel-email-change?token="+cancelToken, body.Email))
+	services.Logger(c.Context()).Info("user: email change requested", "user_id", userID.String())
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"email": user.Email, "pending_email": body.Email, "expires_at": exp})
+}
+
+// CancelPendingEmail drops the caller's pending email change.
+func (h *UserHandler) CancelPendingEmail(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if err := models.DeleteEmailChanges(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
-	return c.JSON(fiber.Map{"email": body.Email})
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // Change password (requires current password)
@@ -348,6 +541,7 @@ func (h *UserHandler) DeleteMyAccount(c *fiber.Ctx) error {
 	if err := h.userRepo.DeleteUser(userID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete account"})
 	}
+	services.InvalidateFeedCache(c.Context())
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -362,10 +556,18 @@ func (h *UserHandler) GetMyAccount(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 	}
-	return c.JSON(fiber.Map{"email": user.Email})
+	resp := fiber.Map{"email": user.Email}
+	if models.DB() != nil {
+		if ch, err := models.PendingEmailChange(userID); err == nil {
+			resp["pending_email"] = ch.NewEmail
+			resp["pending_email_expires_at"] = ch.ExpiresAt
+		}
+	}
+	return c.JSON(resp)
 }
 
-// UploadAvatar allows the authenticated user to upload a profile avatar image
+// UploadAvatar stores a new avatar for the caller, processed per the avatars config,
+// and removes the one it replaces. Small animated GIF and WebP avatars are kept as is.
 func (h *UserHandler) UploadAvatar(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == uuid.Nil {
@@ -375,167 +577,74 @@ func (h *UserHandler) UploadAvatar(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No avatar file provided"})
 	}
-	// Use comprehensive file validation for avatars
 	fileValidator := services.NewFileValidator()
-	
-	// Set smaller size limit for avatars (5MB)
 	fileValidator.MaxFileSize = 5 * 1024 * 1024
-	
 	src, err := file.Open()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open uploaded file"})
 	}
 	defer src.Close()
-	
-	// Read a small sample for validation
-	sample := make([]byte, 512)
-	n, err := src.Read(sample)
-	if err != nil && err != io.EOF {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file for validation"})
-	}
-	
-	// Validate file sample
-	result, err := fileValidator.ValidateFile(file.Filename, bytes.NewReader(sample[:n]))
+	result, err := fileValidator.ValidateFile(file.Filename, src)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate file"})
 	}
-	
 	if !result.IsValid {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": result.ErrorMessage})
 	}
-	
-	// Seek back to beginning for further processing
-	src.Seek(0, 0)
-	
-	// Ensure directory
-	avatarDir := filepath.Join("uploads", "avatars")
-	if err := os.MkdirAll(avatarDir, 0755); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create avatar directory"})
-	}
-	
-	// Fetch current user to know old avatar URL
-	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
-	defer cancel()
-	u, _ := h.userRepo.GetByID(ctx, userID)
-	oldAvatar := ""
-	if u != nil && u.AvatarURL != nil {
-		oldAvatar = *u.AvatarURL
-	}
-	
-	// Ensure file pointer is at beginning for image.Decode
 	if _, err := src.Seek(0, 0); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset file pointer"})
 	}
-	
-	// Debug: Check file size before decode
-	size, _ := src.Seek(0, 2) // End position
-	src.Seek(0, 0) // Reset to beginning
-	
-	// Attempt to decode and center-crop 95%
-	var fname, path string
-	var shouldProcess bool
-	
-	img, _, decErr := image.Decode(src)
-	if decErr != nil {
-		// For JPEG files that fail the final decode but passed validation,
-		// try to save them anyway without processing
-		if result.MIMEType == "image/jpeg" {
-			// Skip processing and save the original file
-			fname = uuid.New().String() + filepath.Ext(file.Filename)
-			path = filepath.Join(avatarDir, fname)
-			shouldProcess = false
-		} else {
-			// Provide more detailed error information for other formats
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Failed to decode avatar image: %v (file size: %d bytes)", decErr, size)})
-		}
-	} else {
-		// Normal processing path
-		shouldProcess = true
-		fname = uuid.New().String() + ".jpg"
-		path = filepath.Join(avatarDir, fname)
-	}
-	
-	if shouldProcess {
-		b := img.Bounds()
-		w := b.Dx()
-		h := b.Dy()
-		cropFactor := 0.95
-		cw := int(float64(w) * cropFactor)
-		ch := int(float64(h) * cropFactor)
-		// Center crop rectangle
-		x0 := b.Min.X + (w-cw)/2
-		y0 := b.Min.Y + (h-ch)/2
-		rect := image.Rect(x0, y0, x0+cw, y0+ch)
-		var cropped image.Image
-		if s, ok := img.(interface {
-			SubImage(r image.Rectangle) image.Image
-		}); ok {
-			cropped = s.SubImage(rect)
-		} else {
-			dst := image.NewRGBA(image.Rect(0, 0, cw, ch))
-			draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
-			cropped = dst
-		}
-		out, err := os.Create(path)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save avatar"})
-		}
-		defer out.Close()
-		if err := jpeg.Encode(out, cropped, &jpeg.Options{Quality: 90}); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write avatar"})
-		}
-	} else {
-		// For JPEG files that failed decoding but passed validation, save original
-		if err := c.SaveFile(file, path); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save avatar file"})
-		}
-	}
-	// Upload to storage (use current storage)
-	data, _ := os.ReadFile(path)
-	key := filepath.Join("avatars", filepath.Base(path))
-	st := services.GetCurrentStorage()
-	if st == nil {
-		st = h.storage
-	}
-	if st == nil {
-		st = services.NewLocalStorage("uploads")
-	}
-	publicURL := st.PublicURL(key)
-	// Detect content type by extension
-	var ct string = "image/jpeg"
-	switch strings.ToLower(filepath.Ext(path)) {
-	case ".png":
-		ct = "image/png"
-	case ".webp":
-		ct = "image/webp"
-	case ".ico":
-		ct = "image/x-icon"
-	}
-	if _, err := st.Save(c.Context(), key, bytes.NewReader(data), ct); err != nil {
-		// fallback to local path
-		publicURL = "/uploads/avatars/" + filepath.Base(path)
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read uploaded file"})
+	}
+	avatar, err := services.ProcessAvatar(data)
+	if errors.Is(err, services.ErrAvatarLimits) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to decode avatar image"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	// Written straight to the active storage; nothing is kept locally when it is remote
+	st := h.currentStorage()
+	key := services.NewProfileMediaKey(services.AvatarPrefix, avatar.Ext)
+	publicURL, err := st.Save(c.Context(), key, bytes.NewReader(avatar.Data), avatar.ContentType)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store avatar"})
 	}
 	if _, err := h.userRepo.UpdateProfile(userID, models.UpdateUserRequest{AvatarURL: &publicURL}); err != nil {
+		_ = st.Delete(c.Context(), key)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
 	}
-	// Best-effort delete of previous avatar file if it was under /uploads/avatars/
-	// Attempt to cleanup old avatar both locally and remote
-	if oldAvatar != "" {
-		// local cleanup
-		if strings.HasPrefix(oldAvatar, "/uploads/avatars/") {
-			oldPath := strings.TrimPrefix(oldAvatar, "/")
-			_ = os.Remove(oldPath)
-		} else {
-			// assume last segment is file name
-			parts := strings.Split(oldAvatar, "/")
-			if len(parts) > 0 {
-				_ = st.Delete(c.Context(), filepath.Join("avatars", parts[len(parts)-1]))
-			}
-		}
+	services.InvalidateFeedCache(c.Context())
+	if u.AvatarURL != nil {
+		h.deleteStoredAvatar(c.Context(), st, *u.AvatarURL)
 	}
 	return c.JSON(fiber.Map{"avatar_url": publicURL})
 }
 
+// deleteStoredAvatar removes a replaced avatar, best effort. Avatars stored before a
+// move to remote storage are still on local disk, so /uploads/ URLs are removed there.
+func (h *UserHandler) deleteStoredAvatar(ctx context.Context, st services.Storage, avatarURL string) {
+	key := services.AvatarKeyFromURL(avatarURL)
+	if key == "" {
+		return
+	}
+	if strings.HasPrefix(avatarURL, "/uploads/") && !st.IsLocal() {
+		st = services.NewLocalStorage(services.UploadsDir())
+	}
+	if err := st.Delete(ctx, key); err != nil {
+		services.Logger(ctx).Warn("avatar: failed to delete replaced avatar", "key", key, "error", err)
+	}
+}
+
 func isValidAvatarType(contentType string) bool {
 	valid := []string{"image/jpeg", "image/jpg", "image/png", "image/webp"}
 	for _, v := range valid {
@@ -564,6 +673,22 @@ func (h *UserHandler) AdminListUsers(c *fiber.Ctx) error {
 		limit = 200
 	}
 	q := strings.TrimSpace(c.Query("q", ""))
+	if wantsCSV(c) {
+		// Exports carry emails, so they are for admins only
+		if !isAdmin(c, h.userRepo) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+		}
+		return sendCSV(c, "users", userCSVHeader, func(page, limit int) ([][]string, error) {
+			var list []models.User
+			var err error
+			if q != "" {
+				list, _, err = h.userRepo.SearchUsers(q, page, limit)
+			} else {
+				list, _, err = h.userRepo.ListUsers(page, limit)
+			}
+			return userCSVRows(list), err
+		})
+	}
 	var (
 		users []models.User
 		total int
@@ -577,9 +702,9 @@ func (h *UserHandler) AdminListUsers(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list users"})
 	}
-	resp := make([]models.UserResponse, len(users))
+	resp := make([]models.AdminUserResponse, len(users))
 	for i := range users {
-		resp[i] = users[i].ToResponse()
+		resp[i] = users[i].ToAdminResponse()
 	}
 	totalPages := (total + limit - 1) / limit
 	return c.JSON(fiber.Map{"users": resp, "page": page, "limit": limit, "total": total, "total_pages": totalPages})
@@ -606,6 +731,8 @@ func (h *UserHandler) AdminSetUserFlags(c *fiber.Ctx) error {
 		IsAdmin     *bool `json:"is_admin"`
 		IsDisabled  *bool `json:"is_disabled"`
 		IsModerator *bool `json:"is_moderator"`
+		// IsShadowbanned quarantines the user's uploads out of public feeds
+		IsShadowbanned *bool `json:"is_shadowbanned"`
 	}
 	var b body
 	if err := c.BodyParser(&b); err != nil {
@@ -617,10 +744,13 @@ func (h *UserHandler) AdminSetUserFlags(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Default admin cannot be demoted"})
 		}
 	}
-	// Mods may only toggle moderator status
+	// Mods may only toggle moderator status and shadowban regular users
 	if isModUser && !isAdminUser {
-		if b.IsModerator == nil || b.IsAdmin != nil || b.IsDisabled != nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Moderators can only toggle moderator status"})
+		if (b.IsModerator == nil && b.IsShadowbanned == nil) || b.IsAdmin != nil || b.IsDisabled != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Moderators can only toggle moderator and shadowban status"})
+		}
+		if b.IsShadowbanned != nil && (target.IsAdmin || target.IsModerator) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Staff cannot be shadowbanned"})
 		}
 	}
 	if b.IsAdmin != nil {
@@ -644,8 +774,15 @@ func (h *UserHandler) AdminSetUserFlags(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to set moderator"})
 		}
 	}
+	if b.IsShadowbanned != nil {
+		if err := h.userRepo.SetShadowbanned(uid, *b.IsShadowbanned); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to set shadowban"})
+		}
+		services.InvalidateFeedCache(c.Context())
+		services.Logger(c.Context()).Info("admin: shadowban changed", "user_id", uid.String(), "shadowbanned", *b.IsShadowbanned, "by", middleware.GetUserID(c).String())
+	}
 	u, _ := h.userRepo.GetByID(ctx, uid)
-	return c.JSON(fiber.Map{"user": u.ToResponse()})
+	return c.JSON(fiber.Map{"user": u.ToAdminResponse()})
 }
 
 // AdminCreateUser: admin only
@@ -703,6 +840,104 @@ func (h *UserHandler) AdminCreateUser(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"user": u2.ToResponse()})
 }
 
+// AdminUsernameHistory lists a user's past handles for moderators.
+func (h *UserHandler) AdminUsernameHistory(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	if h.history == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Username history not configured"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	list, err := h.history.ListForUser(uid)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list username history"})
+	}
+	return c.JSON(fiber.Map{"history": list})
+}
+
+// AdminSuspendUser disables an account with a reason and an optional expiry, after which it
+// is re-enabled automatically. Moderators may suspend regular users for a fixed time only.
+func (h *UserHandler) AdminSuspendUser(c *fiber.Ctx) error {
+	isAdminUser := isAdmin(c, h.userRepo)
+	if !isAdminUser && !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	var body struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if len([]rune(reason)) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason too long"})
+	}
+	if body.Until != nil && !body.Until.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "until must be in the future"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	target, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if uid == middleware.GetUserID(c) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot suspend yourself"})
+	}
+	if !isAdminUser {
+		if target.IsAdmin || target.IsModerator {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can suspend staff"})
+		}
+		if body.Until == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Moderators must set an expiry"})
+		}
+	}
+	if err := h.userRepo.Suspend(uid, reason, body.Until); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to suspend user"})
+	}
+	services.Logger(c.Context()).Info("admin: user suspended", "user_id", uid.String(), "until", body.Until, "by", middleware.GetUserID(c).String())
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.JSON(fiber.Map{"user": u.ToAdminResponse()})
+}
+
+// AdminUnsuspendUser lifts a suspension early. Moderators may lift only timed suspensions.
+func (h *UserHandler) AdminUnsuspendUser(c *fiber.Ctx) error {
+	isAdminUser := isAdmin(c, h.userRepo)
+	if !isAdminUser && !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	target, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if !isAdminUser && target.IsDisabled && target.SuspendedUntil == nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can re-enable disabled accounts"})
+	}
+	if err := h.userRepo.SetDisabled(uid, false); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to lift suspension"})
+	}
+	services.Logger(c.Context()).Info("admin: suspension lifted", "user_id", uid.String(), "by", middleware.GetUserID(c).String())
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // AdminDeleteUser: admin only
 func (h *UserHandler) AdminDeleteUser(c *fiber.Ctx) error {
 	if !isAdmin(c, h.userRepo) {
@@ -724,6 +959,7 @@ func (h *UserHandler) AdminDeleteUser(c *fiber.Ctx) error {
 	if err := h.userRepo.DeleteUser(uid); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete user"})
 	}
+	services.InvalidateFeedCache(c.Context())
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -768,9 +1004,19 @@ func (h *UserHandler) AdminDeleteImage(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
 	}
+	// Loaded first so the CDN can be told which files went away
+	var img *models.Image
+	if services.CDNPurgeEnabled() {
+		if found, err := h.imageRepo.GetByID(c.Context(), imgID); err == nil {
+			img = &found.Image
+		}
+	}
 	if err := h.imageRepo.Delete(imgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete image"})
 	}
+	services.InvalidateFeedCache(c.Context())
+	purgeImageFromCDN(c, h.settingsRepo, img, true)
+	services.EmitWebhook(services.WebhookImageDeleted, map[string]interface{}{"id": imgID, "deleted_by": middleware.GetUserID(c), "moderation": true})
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -783,15 +1029,39 @@ func (h *UserHandler) AdminSetImageNSFW(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image id"})
 	}
 	type body struct {
-		IsNSFW bool `json:"is_nsfw"`
+		IsNSFW bool    `json:"is_nsfw"`
+		Rating *string `json:"rating"`
 	}
 	var b body
 	if err := c.BodyParser(&b); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
 	}
-	if err := h.imageRepo.SetNSFW(imgID, b.IsNSFW); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	var rating models.ContentRating
+	if b.Rating != nil {
+		r, ok := models.ParseRating(*b.Rating)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rating must be safe, suggestive, mature or explicit"})
+		}
+		rating = r
+		if err := h.imageRepo.SetRating(imgID, rating); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+		}
+	} else {
+		rating = img.EffectiveRating().WithNSFW(b.IsNSFW)
+		if err := h.imageRepo.SetNSFW(imgID, b.IsNSFW); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+		}
 	}
+	adminID := middleware.GetUserID(c)
+	recordImageEdits(c.Context(), h.edits, models.MetaEdits(&img.Image, nil, nil, &rating), adminID, img.UserID != adminID)
+	services.InvalidateFeedCache(c.Context())
+	purgeImageFromCDN(c, h.settingsRepo, &models.Image{ID: imgID}, false)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -824,9 +1094,9 @@ func (h *UserHandler) AdminSendVerification(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed"})
 	}
 	link := strings.TrimRight(set.SiteURL, "/") + "/verify?token=" + token
-	subj, bodyTxt := services.BuildVerificationEmail(set.SiteName, set.SiteURL, link)
+	msg := services.BuildVerificationMessage(services.SiteLocale(*set), set.SiteName, set.SiteURL, link)
 	// Use async queue only to avoid duplicates
-	services.EnqueueMail(u.Email, subj, bodyTxt)
+	services.EnqueueMessage(u.Email, msg)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
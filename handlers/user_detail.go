@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+const (
+	// userDetailListLimit bounds each list in the admin user detail.
+	userDetailListLimit = 20
+	// userDetailEventScan is how many recent security events are matched against the
+	// user's sign-in addresses, and userDetailEventWindow how far back they go.
+	userDetailEventScan   = 1000
+	userDetailEventWindow = 30 * 24 * time.Hour
+)
+
+// WithLoginEvents provides the sign-in history shown in the admin user detail.
+func (h *AdminHandler) WithLoginEvents(r models.LoginEventRepositoryInterface) *AdminHandler {
+	h.loginEvents = r
+	return h
+}
+
+// userInviteOrigin is the invite an account registered with and who created it.
+type userInviteOrigin struct {
+	Invite    *models.Invite       `json:"invite"`
+	InvitedBy *models.UserResponse `json:"invited_by"`
+}
+
+// AdminGetUser returns everything staff need about one account on one screen: the
+// profile, image count and storage used, the invite it came from, recent sign-ins,
+// security events from the addresses it signed in from, and admin actions taken on it.
+// The email and security events are for admins only. Sections whose repository is not
+// configured or fails to load, or that the caller may not see, are null.
+func (h *AdminHandler) AdminGetUser(c *fiber.Ctx) error {
+	if !isModerator(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	log := services.Logger(c.Context())
+	warn := func(section string, err error) {
+		log.Warn("admin: user detail section failed", "section", section, "user_id", uid.String(), "error", err)
+	}
+	out := fiber.Map{
+		"user":            u.ToAdminResponse(),
+		"email":           nil,
+		"images":          nil,
+		"storage_bytes":   nil,
+		"invite_origin":   nil,
+		"logins":          nil,
+		"security_events": nil,
+		"audit":           nil,
+	}
+	admin := isAdmin(c, h.userRepo)
+	if admin {
+		out["email"] = u.Email
+	}
+	if h.imageRepo != nil {
+		if n, err := h.imageRepo.CountByUser(uid); err == nil {
+			out["images"] = n
+		} else {
+			warn("images", err)
+		}
+		if n, err := h.imageRepo.StorageByUser(uid); err == nil {
+			out["storage_bytes"] = n
+		} else {
+			warn("storage", err)
+		}
+	}
+	if u.InviteID != nil || u.InvitedBy != nil {
+		origin := userInviteOrigin{}
+		if u.InviteID != nil && h.inviteRepo != nil {
+			if inv, err := h.inviteRepo.GetByID(*u.InviteID); err == nil {
+				origin.Invite = inv
+			}
+		}
+		if u.InvitedBy != nil {
+			if inviter, err := h.userRepo.GetByID(ctx, *u.InvitedBy); err == nil {
+				r := inviter.ToResponse()
+				origin.InvitedBy = &r
+			}
+		}
+		out["invite_origin"] = origin
+	}
+	var logins []models.LoginEvent
+	if h.loginEvents != nil {
+		if logins, err = h.loginEvents.ListForUser(uid, loginHistoryLimit); err == nil {
+			out["logins"] = logins[:min(len(logins), userDetailListLimit)]
+		} else {
+			warn("logins", err)
+		}
+	}
+	if admin && h.securityEvents != nil && logins != nil {
+		if events, err := h.userSecurityEvents(logins); err == nil {
+			out["security_events"] = events
+		} else {
+			warn("security_events", err)
+		}
+	}
+	if h.audit != nil {
+		if list, _, err := h.audit.List(&uid, 1, userDetailListLimit); err == nil {
+			out["audit"] = list
+		} else {
+			warn("audit", err)
+		}
+	}
+	return c.JSON(out)
+}
+
+// userSecurityEvents picks recent security events raised from addresses in logins.
+// Sign-ins only keep a hash of the address, so events are hashed the same way to match.
+func (h *AdminHandler) userSecurityEvents(logins []models.LoginEvent) ([]models.SecurityEvent, error) {
+	out := []models.SecurityEvent{}
+	hashes := map[string]bool{}
+	for _, l := range logins {
+		if l.IPHash != "" {
+			hashes[l.IPHash] = true
+		}
+	}
+	if len(hashes) == 0 {
+		return out, nil
+	}
+	events, _, err := h.securityEvents.List(models.SecurityEventFilter{Since: time.Now().Add(-userDetailEventWindow)}, 1, userDetailEventScan)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		if ev.IPAddress != "" && hashes[services.HashIP(ev.IPAddress)] {
+			out = append(out, ev)
+			if len(out) == userDetailListLimit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type detailImageRepo struct {
+	models.ImageRepositoryInterface
+}
+
+func (detailImageRepo) CountByUser(uuid.UUID) (int, error)     { return 3, nil }
+func (detailImageRepo) StorageByUser(uuid.UUID) (int64, error) { return 4096, nil }
+
+type detailSecurityEvents struct {
+	models.SecurityEventRepositoryInterface
+	events []models.SecurityEvent
+}
+
+func (f *detailSecurityEvents) List(models.SecurityEventFilter, int, int) ([]models.SecurityEvent, int, error) {
+	return f.events, len(f.events), nil
+}
+
+type detailAuditRepo struct {
+	fakeAuditRepo
+}
+
+func (f *detailAuditRepo) List(target *uuid.UUID, page, limit int) ([]models.AdminAudit, int, error) {
+	return f.audit, len(f.audit), nil
+}
+
+func TestAdminGetUser(t *testing.T) {
+	t.Setenv("JWT_SECRET", "detail-secret")
+	adminID, modID, userID, inviterID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := &impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID:   {ID: adminID, Username: "root", IsAdmin: true},
+		modID:     {ID: modID, Username: "mod", IsModerator: true},
+		userID:    {ID: userID, Username: "alice", Email: "alice@example.com", InvitedBy: &inviterID},
+		inviterID: {ID: inviterID, Username: "bob"},
+	}}
+	logins := &fakeLoginEvents{events: []models.LoginEvent{{UserID: userID, IPHash: services.HashIP("203.0.113.7")}}}
+	events := &detailSecurityEvents{events: []models.SecurityEvent{
+		{ID: 1, EventType: "LOCKOUT", IPAddress: "203.0.113.7"},
+		{ID: 2, EventType: "LOCKOUT", IPAddress: "198.51.100.1"},
+	}}
+	audit := &detailAuditRepo{}
+	audit.audit = []models.AdminAudit{{Action: models.AuditImpersonationStarted, TargetUserID: &userID}}
+	h := NewAdminHandler(&fakeSettingsRepo{s: &models.SiteSettings{}}, users, detailImageRepo{}).
+		WithLoginEvents(logins).WithSecurityEvents(events).WithAudit(audit)
+
+	var caller uuid.UUID
+	app := fiber.New()
+	app.Get("/admin/users/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.AdminGetUser)
+	get := func(as uuid.UUID, id string) (int, map[string]json.RawMessage) {
+		caller = as
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/admin/users/"+id, nil))
+		var out map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if code, _ := get(userID, userID.String()); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a regular user, got %d", code)
+	}
+	if code, _ := get(adminID, uuid.New().String()); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d", code)
+	}
+
+	code, out := get(adminID, userID.String())
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if string(out["images"]) != "3" || string(out["storage_bytes"]) != "4096" || string(out["email"]) != `"alice@example.com"` {
+		t.Fatalf("unexpected counts or email: %s %s %s", out["images"], out["storage_bytes"], out["email"])
+	}
+	var origin userInviteOrigin
+	if err := json.Unmarshal(out["invite_origin"], &origin); err != nil || origin.InvitedBy == nil || origin.InvitedBy.Username != "bob" {
+		t.Fatalf("unexpected invite origin: %s", out["invite_origin"])
+	}
+	var matched []models.SecurityEvent
+	if err := json.Unmarshal(out["security_events"], &matched); err != nil || len(matched) != 1 || matched[0].ID != 1 {
+		t.Fatalf("expected only the event from the user's address, got %s", out["security_events"])
+	}
+	var entries []models.AdminAudit
+	if err := json.Unmarshal(out["audit"], &entries); err != nil || len(entries) != 1 {
+		t.Fatalf("unexpected audit: %s", out["audit"])
+	}
+
+	code, out = get(modID, userID.String())
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for a moderator, got %d", code)
+	}
+	if string(out["email"]) != "null" || string(out["security_events"]) != "null" {
+		t.Fatalf("moderators should not see email or security events: %s %s", out["email"], out["security_events"])
+	}
+}
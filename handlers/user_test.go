@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type profileUserRepo struct {
+	models.UserRepositoryInterface
+	user *models.User
+}
+
+func (f *profileUserRepo) GetByUsername(_ context.Context, username string) (*models.User, error) {
+	if username != f.user.Username {
+		return nil, sql.ErrNoRows
+	}
+	return f.user, nil
+}
+
+func (f *profileUserRepo) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	if id != f.user.ID {
+		return nil, sql.ErrNoRows
+	}
+	return f.user, nil
+}
+
+type fakeHistoryRepo struct {
+	models.UsernameHistoryRepositoryInterface
+	old   string
+	owner uuid.UUID
+	since time.Time
+}
+
+func (f *fakeHistoryRepo) ResolveOld(username string, since time.Time) (uuid.UUID, error) {
+	f.since = since
+	if username != f.old {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return f.owner, nil
+}
+
+type countingImageRepo struct {
+	models.ImageRepositoryInterface
+	calls int
+}
+
+func (f *countingImageRepo) GetUserImages(uuid.UUID, int, int, bool) ([]models.ImageWithUser, int, error) {
+	f.calls++
+	return []models.ImageWithUser{{}}, 1, nil
+}
+
+func TestGetUserImages_ShadowbannedHiddenFromPublic(t *testing.T) {
+	users := &profileUserRepo{user: &models.User{ID: uuid.New(), Username: "spammer", IsShadowbanned: true}}
+	images := &countingImageRepo{}
+	app := fiber.New()
+	app.Get("/users/:username/images", NewUserHandler(users, images, nil).GetUserImages)
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/users/spammer/images", nil))
+	var body models.FeedResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK || len(body.Images) != 0 || images.calls != 0 {
+		t.Fatalf("expected an empty gallery for anonymous viewers, got %d %d images (%d queries)", resp.StatusCode, len(body.Images), images.calls)
+	}
+
+	users.user.IsShadowbanned = false
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/users/spammer/images", nil))
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Images) != 1 {
+		t.Fatalf("expected images once the shadowban is lifted, got %d", len(body.Images))
+	}
+}
+
+func TestGetProfile_OldHandleResolvesToRenamedUser(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "newname"}
+	history := &fakeHistoryRepo{old: "oldname", owner: user.ID}
+	app := fiber.New()
+	app.Get("/users/:username", NewUserHandler(&profileUserRepo{user: user}, &fakeImageRepo{}, nil).WithUsernameHistory(history).GetProfile)
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/users/oldname", nil))
+	var body models.UserResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK || body.Username != "newname" {
+		t.Fatalf("expected old handle to alias newname, got %d %q", resp.StatusCode, body.Username)
+	}
+	if time.Since(history.since) < models.UsernameRedirectGrace-time.Minute {
+		t.Fatalf("expected lookup bounded by the grace period, got since=%v", history.since)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/users/someoneelse", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown handle, got %d", resp.StatusCode)
+	}
+}
+
+func TestNormalizeProfileTheme(t *testing.T) {
+	current := models.ProfileTheme{Accent: "#000000", HeaderKey: "headers/a.jpg", HeaderURL: "/uploads/headers/a.jpg"}
+	got, err := normalizeProfileTheme(models.ProfileTheme{Accent: " #7AF0FF ", Layout: "Grid", HeaderKey: "other", HeaderURL: "https://evil.example/x.jpg"}, current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Accent != "#7af0ff" || got.Layout != models.ProfileLayoutGrid {
+		t.Fatalf("unexpected theme: %+v", got)
+	}
+	if got.HeaderKey != current.HeaderKey || got.HeaderURL != current.HeaderURL {
+		t.Fatalf("header fields must come from the stored theme, got %+v", got)
+	}
+	for _, bad := range []models.ProfileTheme{{Accent: "red"}, {Accent: "#fff"}, {Accent: "url(x)"}, {Layout: "carousel"}} {
+		if _, err := normalizeProfileTheme(bad, current); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
+
+type emailUserRepo struct {
+	profileUserRepo
+	updated string
+}
+
+func (f *emailUserRepo) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	if email == "taken@example.com" {
+		return &models.User{ID: uuid.New(), Email: email}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *emailUserRepo) UpdateEmail(_ uuid.UUID, email string) error {
+	f.updated = email
+	return nil
+}
+
+func TestUpdateEmail_WithoutMailAppliesAtOnce(t *testing.T) {
+	u := &models.User{ID: uuid.New(), Username: "ann", Email: "ann@example.com"}
+	repo := &emailUserRepo{profileUserRepo: profileUserRepo{user: u}}
+	h := NewUserHandler(repo, &fakeImageRepo{}, nil).WithSettings(&fakeSettingsRepo{s: &models.SiteSettings{}})
+	app := fiber.New()
+	app.Patch("/me/email", func(c *fiber.Ctx) error {
+		c.Locals("user_id", u.ID)
+		return c.Next()
+	}, h.UpdateEmail)
+	patch := func(body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/me/email", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+	if code := patch(`{"email":"taken@example.com"}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken address, got %d", code)
+	}
+	if code := patch(`{"email":"New@Example.com"}`); code != http.StatusOK || repo.updated != "new@example.com" {
+		t.Fatalf("expected the change to apply without SMTP, got %d %q", code, repo.updated)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// maxVerifiedReason caps the length of the reason shown with the verified badge.
+const maxVerifiedReason = 200
+
+// proofCheckInterval spaces out checks of one user's proof, since each one fetches a
+// page the user chose.
+const proofCheckInterval = 30 * time.Second
+
+// checkProof is services.CheckProof, swapped out in tests.
+var checkProof = services.CheckProof
+
+// WithVerification enables the self-serve verification flow.
+func (h *UserHandler) WithVerification(r models.VerificationRepositoryInterface) *UserHandler {
+	h.verification = r
+	return h
+}
+
+// AdminSetUserVerified grants or revokes a user's verified badge. Granting takes the
+// reason shown beside the badge.
+func (h *UserHandler) AdminSetUserVerified(c *fiber.Ctx) error {
+	if !isAdmin(c, h.userRepo) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	}
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	var body struct {
+		Verified bool   `json:"verified"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	var reason *string
+	if body.Verified {
+		r := strings.TrimSpace(body.Reason)
+		if r == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+		}
+		if utf8.RuneCountInString(r) > maxVerifiedReason {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason must be at most 200 characters"})
+		}
+		reason = &r
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := h.userRepo.GetByID(ctx, uid); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := h.userRepo.SetVerified(uid, body.Verified, reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update verification"})
+	}
+	// Cached feed pages embed the uploader's badge
+	services.InvalidateFeedCache(c.Context())
+	services.Logger(c.Context()).Info("admin: user verification set", "user_id", uid.String(), "verified", body.Verified, "by", middleware.GetUserID(c).String())
+	u, err := h.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.JSON(fiber.Map{"user": u.ToAdminResponse()})
+}
+
+// GetMyVerification returns the caller's badge and any pending self-serve proof.
+func (h *UserHandler) GetMyVerification(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	u, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	resp := fiber.Map{"verified": u.IsVerified, "reason": u.ToResponse().VerifiedReason, "self_serve": h.verification != nil && services.SelfVerificationEnabled(), "proof": nil}
+	if h.verification != nil && !u.IsVerified {
+		if p, err := h.verification.GetProof(userID); err == nil {
+			resp["proof"] = p
+		}
+	}
+	return c.JSON(resp)
+}
+
+// StartMyVerification issues a token for the caller to publish at a domain or link
+// they control, replacing any pending proof.
+func (h *UserHandler) StartMyVerification(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if h.verification == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Verification not configured"})
+	}
+	if !services.SelfVerificationEnabled() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Self-serve verification is disabled"})
+	}
+	var body struct {
+		Kind   string `json:"kind"`
+		Target string `json:"target"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid body"})
+	}
+	target, err := services.NormalizeProofTarget(body.Kind, body.Target)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	if u, err := h.userRepo.GetByID(ctx, userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	} else if u.IsVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Already verified"})
+	}
+	token, err := services.NewVerificationToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start verification"})
+	}
+	p := &models.VerificationProof{UserID: userID, Kind: body.Kind, Target: target, Token: token}
+	if err := h.verification.SaveProof(p); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start verification"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"proof": p, "well_known_path": services.VerificationWellKnownPath})
+}
+
+// CheckMyVerification looks for the caller's token at their proof target and grants the
+// badge when it is there.
+func (h *UserHandler) CheckMyVerification(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if h.verification == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Verification not configured"})
+	}
+	if !services.SelfVerificationEnabled() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Self-serve verification is disabled"})
+	}
+	p, err := h.verification.GetProof(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Start verification first"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load verification"})
+	}
+	if p.CheckedAt != nil && time.Since(*p.CheckedAt) < proofCheckInterval {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Please wait before checking again"})
+	}
+	_ = h.verification.MarkChecked(userID)
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+	if err := checkProof(ctx, p.Kind, p.Target, p.Token); err != nil {
+		if errors.Is(err, services.ErrProofNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The token was not found at " + p.Target})
+		}
+		services.Logger(c.Context()).Info("verification: proof check failed", "user_id", userID.String(), "target", p.Target, "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Could not reach " + p.Target})
+	}
+	reason := services.VerificationReason(p.Kind, p.Target)
+	if err := h.userRepo.SetVerified(userID, true, &reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update verification"})
+	}
+	_ = h.verification.DeleteProof(userID)
+	services.InvalidateFeedCache(c.Context())
+	services.Logger(c.Context()).Info("verification: user verified", "user_id", userID.String(), "kind", p.Kind, "target", p.Target)
+	return c.JSON(fiber.Map{"verified": true, "reason": reason})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type verifiedUserRepo struct {
+	impersonationUserRepo
+}
+
+func (f *verifiedUserRepo) SetVerified(id uuid.UUID, verified bool, reason *string) error {
+	f.users[id].IsVerified, f.users[id].VerifiedReason = verified, reason
+	return nil
+}
+
+type fakeVerificationRepo struct {
+	models.VerificationRepositoryInterface
+	proofs map[uuid.UUID]*models.VerificationProof
+}
+
+func (f *fakeVerificationRepo) GetProof(userID uuid.UUID) (*models.VerificationProof, error) {
+	if p, ok := f.proofs[userID]; ok {
+		return p, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeVerificationRepo) SaveProof(p *models.VerificationProof) error {
+	f.proofs[p.UserID] = p
+	return nil
+}
+
+func (f *fakeVerificationRepo) MarkChecked(uuid.UUID) error { return nil }
+
+func (f *fakeVerificationRepo) DeleteProof(userID uuid.UUID) error {
+	delete(f.proofs, userID)
+	return nil
+}
+
+func TestAdminSetUserVerified(t *testing.T) {
+	adminID, userID := uuid.New(), uuid.New()
+	users := &verifiedUserRepo{impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		adminID: {ID: adminID, Username: "root", IsAdmin: true},
+		userID:  {ID: userID, Username: "alice"},
+	}}}
+	services.ConfigureFeedCache(services.NewMemoryCacheStore(10), "memory", 1, time.Minute)
+	defer services.ConfigureFeedCache(nil, "", 0, 0)
+	h := NewUserHandler(users, &fakeImageRepo{}, nil)
+	caller := adminID
+	app := fiber.New()
+	app.Put("/admin/users/:id/verified", func(c *fiber.Ctx) error {
+		c.Locals("user_id", caller)
+		return c.Next()
+	}, h.AdminSetUserVerified)
+	set := func(as uuid.UUID, body string) int {
+		caller = as
+		req := httptest.NewRequest(http.MethodPut, "/admin/users/"+userID.String()+"/verified", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := set(userID, `{"verified":true,"reason":"Me"}`); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin, got %d", code)
+	}
+	if code := set(adminID, `{"verified":true,"reason":"  "}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a reason, got %d", code)
+	}
+	before := services.GetFeedCacheStats().Invalidations
+	if code := set(adminID, `{"verified":true,"reason":" Official studio account "}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if services.GetFeedCacheStats().Invalidations == before {
+		t.Fatal("expected cached feed pages invalidated so they show the badge")
+	}
+	if r := users.users[userID].ToResponse(); !r.IsVerified || r.VerifiedReason == nil || *r.VerifiedReason != "Official studio account" {
+		t.Fatalf("expected a verified user with a trimmed reason, got %+v", r)
+	}
+	if code := set(adminID, `{"verified":false}`); code != http.StatusOK || users.users[userID].IsVerified {
+		t.Fatalf("expected the badge revoked, got %d", code)
+	}
+}
+
+func TestCheckMyVerification(t *testing.T) {
+	services.SetVerification(services.VerificationConfig{SelfServe: true})
+	defer services.SetVerification(services.VerificationConfig{})
+	published := false
+	orig := checkProof
+	defer func() { checkProof = orig }()
+	checkProof = func(_ context.Context, _, _, token string) error {
+		if !published || !strings.HasPrefix(token, "trough-verification=") {
+			return services.ErrProofNotFound
+		}
+		return nil
+	}
+	userID := uuid.New()
+	users := &verifiedUserRepo{impersonationUserRepo{users: map[uuid.UUID]*models.User{
+		userID: {ID: userID, Username: "alice"},
+	}}}
+	proofs := &fakeVerificationRepo{proofs: map[uuid.UUID]*models.VerificationProof{}}
+	h := NewUserHandler(users, &fakeImageRepo{}, nil).WithVerification(proofs)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	app.Post("/me/verification", h.StartMyVerification)
+	app.Post("/me/verification/check", h.CheckMyVerification)
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if code := post("/me/verification/check", ``); code != http.StatusNotFound {
+		t.Fatalf("expected 404 before starting, got %d", code)
+	}
+	if code := post("/me/verification", `{"kind":"domain","target":"localhost"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bare host, got %d", code)
+	}
+	if code := post("/me/verification", `{"kind":"domain","target":"Example.com"}`); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if p := proofs.proofs[userID]; p == nil || p.Target != "example.com" {
+		t.Fatalf("expected a proof for example.com, got %+v", p)
+	}
+	if code := post("/me/verification/check", ``); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 while the token is missing, got %d", code)
+	}
+	published = true
+	if code := post("/me/verification/check", ``); code != http.StatusOK {
+		t.Fatalf("expected 200 once published, got %d", code)
+	}
+	if u := users.users[userID]; !u.IsVerified || u.VerifiedReason == nil || *u.VerifiedReason != "Controls example.com" {
+		t.Fatalf("expected the user verified for example.com, got %+v", u)
+	}
+	if _, ok := proofs.proofs[userID]; ok {
+		t.Fatal("expected the proof cleared after verification")
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// uploadVideo stores an MP4/WebM clip untouched next to a JPEG poster frame. draft carries
+// the form fields Upload has already validated.
+func (h *ImageHandler) uploadVideo(c *fiber.Ctx, file *multipart.FileHeader, draft *models.Image) error {
+	cfg := h.config.Video
+	if !cfg.Enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Video uploads are disabled"})
+	}
+	if cfg.MaxSizeMB > 0 && file.Size > int64(cfg.MaxSizeMB)<<20 {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": fmt.Sprintf("Videos may be at most %d MB", cfg.MaxSizeMB)})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to open uploaded file"})
+	}
+	defer src.Close()
+	// ffprobe and ffmpeg need a path, and multipart parts may only live in memory
+	tmp, err := services.SaveVideoTemp(src, strings.ToLower(filepath.Ext(file.Filename)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+	}
+	defer os.Remove(tmp)
+
+	ctx := c.Context()
+	probe, err := services.ProbeVideo(ctx, cfg, tmp)
+	if errors.Is(err, services.ErrVideoUnavailable) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Video uploads are not available on this server"})
+	}
+	if err != nil {
+		services.Logger(ctx).Info("upload: video probe failed", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Not a supported MP4 or WebM video"})
+	}
+	if err := probe.CheckLimits(cfg); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload rejected: " + err.Error()})
+	}
+
+	f, err := os.Open(tmp)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+	}
+
+	// Provenance comes from container tags and BMFF uuid boxes (C2PA, XMP), never frame data
+	meta := services.VideoMetadata(f, info.Size(), probe)
+	aiOK, aiRes := services.DetectAIProvenanceConcurrent(meta, services.ExtractXMPXMLFromBytes(meta))
+	services.RecordAIDetection(aiOK, aiRes)
+	if !aiOK {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload rejected. Only AI-generated videos with verifiable metadata (container tags, XMP or C2PA) are accepted."})
+	}
+
+	poster, err := services.VideoPoster(ctx, cfg, tmp, probe.Duration)
+	if err != nil {
+		services.Logger(ctx).Warn("upload: poster frame failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to extract a poster frame"})
+	}
+	still, err := jpeg.Decode(bytes.NewReader(poster))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to extract a poster frame"})
+	}
+	stillMeta := services.ProcessDecodedImage(still, "jpeg")
+
+	st := services.GetCurrentStorage()
+	if st == nil {
+		st = h.storage
+	}
+	if st == nil {
+		st = services.NewLocalStorage(services.UploadsDir())
+	}
+	base := uuid.New().String()
+	videoKey, posterKey := base+probe.Ext(), base+".poster.jpg"
+	// Hashed before saving so remote storage still sees an *os.File of known size
+	checksum, err := services.ChecksumSHA256Reader(f)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to buffer upload"})
+	}
+	videoURL, err := st.Save(ctx, videoKey, f, probe.ContentType())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store video"})
+	}
+	posterURL, err := st.Save(ctx, posterKey, bytes.NewReader(poster), "image/jpeg")
+	if err != nil {
+		_ = st.Delete(ctx, videoKey)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store video"})
+	}
+	// Local rows keep bare keys like images do; remote rows keep the public URL
+	if st.IsLocal() {
+		videoURL, posterURL = videoKey, posterKey
+	}
+
+	size := int(info.Size())
+	draft.Filename = videoURL
+	draft.PosterFilename = &posterURL
+	draft.MediaType = models.MediaTypeVideo
+	draft.FileSize = &size
+	draft.SHA256 = &checksum
+	draft.Width, draft.Height = &probe.Width, &probe.Height
+	draft.Blurhash, draft.DominantColor = &stillMeta.Blurhash, &stillMeta.DominantColor
+	draft.DurationMS = int(probe.Duration.Milliseconds())
+	draft.AISignature = &aiRes.Details
+	if aiRes.Provider != "" {
+		draft.AIProvider = &aiRes.Provider
+	}
+	if draft.OriginalName == nil {
+		name := file.Filename
+		draft.OriginalName = &name
+	}
+	draft.ExifData, _ = json.Marshal(map[string]interface{}{"ai_detected": true, "signature": aiRes.Details, "exif": probe.Tags})
+
+	return h.finishUpload(c, draft, func() {
+		_ = st.Delete(ctx, videoKey)
+		_ = st.Delete(ctx, posterKey)
+	})
+}
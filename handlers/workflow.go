@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+// GetImageWorkflow serves the ComfyUI workflow JSON extracted from an image at upload, as
+// a download that loads back into ComfyUI. It follows the image's own visibility rules.
+func (h *ImageHandler) GetImageWorkflow(c *fiber.Ctx) error {
+	imageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image ID"})
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+	img, err := h.imageRepo.GetByID(ctx, imageID)
+	if err != nil || img == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	viewer := middleware.OptionalUserID(c)
+	if (img.IsWithheld() && !h.canSeePending(c, img.UserID)) || (img.IsPrivate() && (viewer == uuid.Nil || viewer != img.UserID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+	if img.WorkflowKey == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No workflow for this image"})
+	}
+	// Sidecars are written to the active storage under their full key
+	reader, ok := h.currentStorage().(services.ObjectReader)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No workflow for this image"})
+	}
+	rc, err := reader.Open(c.Context(), *img.WorkflowKey)
+	if err != nil {
+		services.Logger(c.Context()).Warn("workflow: sidecar unavailable", "image_id", imageID.String(), "error", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No workflow for this image"})
+	}
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	name := downloadName(img, "")
+	setAttachment(c, name+"-workflow.json", ".json")
+	// fasthttp closes the stream once the body has been written
+	return c.SendStream(rc)
+}
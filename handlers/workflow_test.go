@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// comfyPNG returns an opaque PNG with ComfyUI's prompt and workflow text chunks after IHDR.
+func comfyPNG(t *testing.T, graph string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(3, 3, color.RGBA{200, 40, 40, 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	chunk := func(typ, data string) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(out, typ+data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE([]byte(typ+data)))
+	}
+	ihdrEnd := 8 + 12 + 13
+	out := append([]byte(nil), raw[:ihdrEnd]...)
+	out = append(out, chunk("tEXt", `prompt`+"\x00"+`{"3":{"class_type":"KSampler","inputs":{"seed":42}}}`)...)
+	out = append(out, chunk("tEXt", "workflow\x00"+graph)...)
+	return append(out, raw[ihdrEnd:]...)
+}
+
+func TestUpload_ComfyWorkflowSidecar(t *testing.T) {
+	graph := `{"last_node_id":3,"nodes":[{"id":3,"type":"KSampler"}],"links":[]}`
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("image", "koi.png")
+	_, _ = part.Write(comfyPNG(t, graph))
+	_ = mw.Close()
+
+	dir := t.TempDir()
+	repo := &createImageRepo{}
+	h := NewImageHandler(repo, nil, nil, services.Config{}, services.NewLocalStorage(dir))
+	app := fiber.New()
+	app.Post("/upload", func(c *fiber.Ctx) error { c.Locals("user_id", uuid.New()); return c.Next() }, h.Upload)
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, msg)
+	}
+	img := repo.created
+	if img == nil || img.WorkflowKey == nil || !strings.HasPrefix(*img.WorkflowKey, models.ImageWorkflowPrefix) {
+		t.Fatalf("expected a workflow sidecar recorded, got %+v", img)
+	}
+	if stored, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(*img.WorkflowKey))); err != nil || string(stored) != graph {
+		t.Fatalf("expected the editor graph stored, got %q %v", stored, err)
+	}
+
+	// The sidecar is served with the image's visibility rules
+	img.Visibility = models.ImageVisibilityPublic
+	images := &visibilityImageRepo{images: map[uuid.UUID]*models.ImageWithUser{img.ID: {Image: *img}}}
+	h = NewImageHandler(images, nil, nil, services.Config{}, services.NewLocalStorage(dir))
+	app = fiber.New()
+	app.Get("/images/:id/workflow", h.GetImageWorkflow)
+	get := func(id uuid.UUID) (int, string, string) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/images/"+id.String()+"/workflow", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderContentDisposition), string(b)
+	}
+	if code, disp, got := get(img.ID); code != http.StatusOK || got != graph || !strings.Contains(disp, "koi-workflow.json") {
+		t.Fatalf("expected the workflow as an attachment, got %d %q %q", code, disp, got)
+	}
+	images.images[img.ID].Visibility = models.ImageVisibilityPrivate
+	if code, _, _ := get(img.ID); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a private image's workflow, got %d", code)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serve runs app on the configured address and blocks until the listener stops. With TLS
+// configured the server terminates HTTPS itself, from certificate files or certificates
+// obtained from Let's Encrypt, and can answer plain HTTP with redirects.
+func serve(app *fiber.App, cfg services.ServerConfig) error {
+	addr := cfg.ListenAddress()
+	if !cfg.TLS.Enabled() {
+		services.Logger(context.Background()).Info("server: starting", "addr", addr)
+		return app.Listen(addr)
+	}
+	if cfg.TLS.CertFile != "" {
+		// Prefork children re-run main; only the parent may bind the redirect port
+		if cfg.TLS.RedirectAddress != "" && !fiber.IsChild() {
+			go serveRedirects(cfg.TLS.RedirectAddress, nil, cfg.Port)
+		}
+		// Certificates are loaded once; restart (or SIGTERM under a supervisor) after renewal
+		services.Logger(context.Background()).Info("server: starting", "addr", addr, "tls_cert", cfg.TLS.CertFile)
+		return app.ListenTLS(addr, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}
+
+	if err := os.MkdirAll(cfg.TLS.ACMECacheDir, 0o700); err != nil {
+		return err
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.TLS.ACMEDomains...),
+		Cache:      autocert.DirCache(cfg.TLS.ACMECacheDir),
+		Email:      cfg.TLS.ACMEEmail,
+	}
+	if cfg.TLS.RedirectAddress != "" {
+		// Also answers HTTP-01 challenges; TLS-ALPN-01 works on the main listener regardless
+		go serveRedirects(cfg.TLS.RedirectAddress, m, cfg.Port)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+		// fasthttp speaks HTTP/1.1 only, so h2 must not be negotiated
+		NextProtos: []string{"http/1.1", acme.ALPNProto},
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	services.Logger(context.Background()).Info("server: starting", "addr", addr, "acme_domains", cfg.TLS.ACMEDomains)
+	return app.Listener(tls.NewListener(ln, tlsCfg))
+}
+
+// serveRedirects answers plain HTTP on addr with permanent redirects to the HTTPS port,
+// routing ACME HTTP-01 challenges to m when set.
+func serveRedirects(addr string, m *autocert.Manager, httpsPort int) {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if m != nil {
+		h = m.HTTPHandler(h)
+	}
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-services.ShuttingDown()
+		_ = srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		services.Logger(context.Background()).Error("server: HTTP redirect listener failed", "addr", addr, "error", err)
+	}
+}
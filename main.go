@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"html"
-	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	gjson "github.com/goccy/go-json"
@@ -15,10 +19,10 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/etag"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 
 	// limiter intentionally omitted to avoid adding new dependencies in this change
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/yourusername/trough/db"
 	"github.com/yourusername/trough/handlers"
 	"github.com/yourusername/trough/middleware"
@@ -31,10 +35,12 @@ func customErrorHandler(c *fiber.Ctx, err error) error {
 	if e, ok := err.(*fiber.Error); ok {
 		code = e.Code
 	}
-	// Avoid leaking internal errors to clients. Log the detailed error server-side.
-	log.Printf("error: %v", err)
+	// Avoid leaking internal errors to clients. Log the detailed error server-side with
+	// the request ID the client sees, so reports can be matched to the log record.
+	services.Logger(c.Context()).Error("unhandled error", "error", err, "status", code, "method", c.Method(), "path", c.Path())
 	return c.Status(code).JSON(fiber.Map{
-		"error": "internal server error",
+		"error":      "internal server error",
+		"request_id": middleware.GetRequestID(c),
 	})
 }
 
@@ -50,27 +56,31 @@ func maybeSeedAdmin(userRepo models.UserRepositoryInterface) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if _, err := userRepo.GetByEmail(ctx, adminEmail); err == nil {
-		log.Printf("Admin seed: user %s already exists", adminEmail)
+		services.Logger(ctx).Info("admin seed: user already exists", "email", adminEmail)
 		return
 	}
 	u := &models.User{Username: adminUser, Email: adminEmail}
 	if err := u.HashPassword(adminPass); err != nil {
-		log.Printf("Admin seed: failed to hash password: %v", err)
+		services.Logger(ctx).Error("admin seed: failed to hash password", "error", err)
 		return
 	}
 	if err := userRepo.Create(u); err != nil {
-		log.Printf("Admin seed: create failed: %v", err)
+		services.Logger(ctx).Error("admin seed: create failed", "error", err)
 		return
 	}
 	if err := userRepo.SetAdmin(u.ID, true); err != nil {
-		log.Printf("Admin seed: set admin failed: %v", err)
+		services.Logger(ctx).Error("admin seed: set admin failed", "error", err)
 		return
 	}
-	log.Printf("Admin seed: created admin %s (@%s)", adminEmail, adminUser)
+	services.Logger(ctx).Info("admin seed: created admin", "email", adminEmail, "username", adminUser)
 }
 
+// ssrFeaturedLimit caps how many featured picks the home page meta lists.
+const ssrFeaturedLimit = 12
+
 // indexWithMetaHandler serves index.html with server-side SEO/OG meta tags injected from site settings
 // and, for /i/:id routes, from the specific image. For /@:username, it uses the user's bio and latest image.
+// The home page lists the featured picks as structured data.
 // For single-segment CMS pages, it keeps index SEO but adjusts the <title> to the page title (or meta title).
 func indexWithMetaHandler(
 	siteRepo models.SiteSettingsRepositoryInterface,
@@ -104,14 +114,18 @@ func indexWithMetaHandler(
 		}
 
 		set, _ := siteRepo.Get()
+		// Tenant domains carry their own branding
+		set = middleware.GetTenant(c).Apply(set)
+		// Fallback copy follows the negotiated language
+		locale := middleware.GetLocale(c)
 
 		// Defaults from site settings
 		title := strings.TrimSpace(set.SEOTitle)
 		if title == "" {
 			if strings.TrimSpace(set.SiteName) != "" {
-				title = set.SiteName + " · AI IMAGERY"
+				title = set.SiteName + " · " + services.T(locale, "AI IMAGERY")
 			} else {
-				title = "TROUGH · AI IMAGERY"
+				title = "TROUGH · " + services.T(locale, "AI IMAGERY")
 			}
 		}
 		description := strings.TrimSpace(set.SEODescription)
@@ -130,8 +144,25 @@ func indexWithMetaHandler(
 			origin = proto + "://" + c.Hostname()
 		}
 		fullURL := origin + path
+		// Remote storage stores absolute URLs; local rows hold bare filenames
+		absURL := func(fn string) string {
+			if services.SignedURLsEnabled() {
+				return origin + services.SignMediaRef(fn)
+			}
+			lowerFn := strings.ToLower(fn)
+			if strings.HasPrefix(lowerFn, "http://") || strings.HasPrefix(lowerFn, "https://") {
+				return fn
+			}
+			return origin + "/uploads/" + fn
+		}
 		imageURL := strings.TrimSpace(set.SocialImageURL)
 		ogType := "website"
+		noIndex := false
+		// Structured data for image pages (schema.org ImageObject)
+		var jsonLD map[string]interface{}
+		licenseURL := ""
+		// Video pages point og:video at the clip and use the poster as the image
+		videoURL, videoType := "", ""
 
 		// If this is an image page, override meta using the image
 		if strings.HasPrefix(c.Path(), "/i/") {
@@ -139,8 +170,11 @@ func indexWithMetaHandler(
 				if imgID, err := uuid.Parse(idStr); err == nil {
 					ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 					defer cancel()
-					if img, err := imageRepo.GetByID(ctx, imgID); err == nil && img != nil {
+					// Private images render the generic site meta so nothing about them leaks
+					if img, err := imageRepo.GetByID(ctx, imgID); err == nil && img != nil && !img.IsWithheld() && !img.IsPrivate() {
 						ogType = "article"
+						// Unlisted images share fine by link but should stay out of search results
+						noIndex = !img.IsListed()
 						// Compute site title for format "IMAGE TITLE - SITE TITLE"
 						siteTitle := strings.TrimSpace(set.SiteName)
 						if siteTitle == "" {
@@ -174,11 +208,13 @@ func indexWithMetaHandler(
 									}
 									// Latest user image for social card
 									if imageRepo != nil {
-										if imgs, _, err := imageRepo.GetUserImages(u.ID, 1, 1); err == nil && len(imgs) > 0 {
+										if imgs, _, err := imageRepo.GetUserImages(u.ID, 1, 1, false); err == nil && len(imgs) > 0 {
 											fn := strings.TrimSpace(imgs[0].Filename)
 											if fn != "" {
 												lowerFn := strings.ToLower(fn)
-												if strings.HasPrefix(lowerFn, "http://") || strings.HasPrefix(lowerFn, "https://") {
+												if services.SignedURLsEnabled() {
+													imageURL = origin + services.SignMediaRef(fn)
+												} else if strings.HasPrefix(lowerFn, "http://") || strings.HasPrefix(lowerFn, "https://") {
 													imageURL = fn
 												} else {
 													imageURL = origin + "/uploads/" + fn
@@ -189,35 +225,9 @@ func indexWithMetaHandler(
 									ogType = "profile"
 								}
 							}
-						} else {
-							// Single-segment CMS page: inherit index SEO but change only <title>
-							slug := strings.Trim(strings.TrimSpace(c.Path()), "/")
-							if slug != "" && !strings.Contains(slug, "/") {
-								// Reserved prefixes that are not CMS slugs
-								reserved := map[string]bool{"api": true, "uploads": true, "assets": true, "@": true, "i": true, "register": true, "reset": true, "verify": true, "settings": true, "admin": true}
-								if !reserved[slug] && pageRepo != nil {
-									if p, err := pageRepo.GetPublishedBySlug(strings.ToLower(slug)); err == nil && p != nil {
-										siteTitle := strings.TrimSpace(set.SiteName)
-										if siteTitle == "" {
-											siteTitle = "TROUGH"
-										}
-										// Prefer page meta title when provided; otherwise use "Page - SiteTitle"
-										if p.MetaTitle != nil && strings.TrimSpace(*p.MetaTitle) != "" {
-											title = strings.TrimSpace(*p.MetaTitle)
-										} else {
-											pt := strings.TrimSpace(p.Title)
-											if pt == "" {
-												pt = "Page"
-											}
-											title = pt + " - " + siteTitle
-										}
-										// Keep description/image/ogType from site defaults to inherit index SEO
-									}
-								}
-							}
 						}
 						// Title from image (original_name acts as title)
-						imgTitle := "Untitled"
+						imgTitle := services.T(locale, "Untitled")
 						if img.OriginalName != nil && strings.TrimSpace(*img.OriginalName) != "" {
 							imgTitle = strings.TrimSpace(*img.OriginalName)
 						}
@@ -229,11 +239,11 @@ func indexWithMetaHandler(
 							cap = strings.TrimSpace(*img.Caption)
 						}
 						// Provide a subtle ASCII fallback when caption is missing
-						asciiFallback := "~ artificial reverie ~"
+						asciiFallback := services.T(locale, "~ artificial reverie ~")
 						if author != "" && cap != "" {
-							description = "by @" + author + " — " + cap
+							description = services.T(locale, "by @%s", author) + " — " + cap
 						} else if author != "" && cap == "" {
-							description = "by @" + author + " — " + asciiFallback
+							description = services.T(locale, "by @%s", author) + " — " + asciiFallback
 						} else if author == "" && cap != "" {
 							description = cap
 						} else { // neither author nor caption
@@ -243,20 +253,111 @@ func indexWithMetaHandler(
 							description = description[:280]
 						}
 						if img.Filename != "" {
-							// If Filename is already an absolute URL (remote storage), use as-is
-							lowerFn := strings.ToLower(img.Filename)
-							if strings.HasPrefix(lowerFn, "http://") || strings.HasPrefix(lowerFn, "https://") {
-								imageURL = img.Filename
-							} else {
-								// Local filename
-								imageURL = origin + "/uploads/" + img.Filename
+							imageURL = absURL(img.Filename)
+						}
+						jsonLD = map[string]interface{}{
+							"@context":    "https://schema.org",
+							"@type":       "ImageObject",
+							"name":        imgTitle,
+							"description": description,
+							"contentUrl":  imageURL,
+							"url":         fullURL,
+							"uploadDate":  img.CreatedAt.UTC().Format(time.RFC3339),
+						}
+						if img.IsVideo() {
+							videoURL, imageURL = imageURL, ""
+							videoType = "video/mp4"
+							if strings.EqualFold(filepath.Ext(img.Filename), ".webm") {
+								videoType = "video/webm"
+							}
+							if img.PosterFilename != nil {
+								imageURL = absURL(*img.PosterFilename)
 							}
+							ogType = "video.other"
+							jsonLD["@type"] = "VideoObject"
+							jsonLD["thumbnailUrl"] = imageURL
+							jsonLD["duration"] = fmt.Sprintf("PT%.1fS", float64(img.DurationMS)/1000)
+						}
+						if author != "" {
+							jsonLD["creator"] = map[string]interface{}{"@type": "Person", "name": "@" + author, "url": origin + "/@" + author}
+							jsonLD["creditText"] = "@" + author
+						}
+						if lic, ok := models.LicenseByID(img.License); ok {
+							if lic.URL != "" {
+								licenseURL = lic.URL
+								jsonLD["license"] = lic.URL
+							}
+							jsonLD["copyrightNotice"] = lic.Name
 						}
 					}
 				}
 			}
 		}
 
+		// Home page: list the featured picks for crawlers, and use the latest as the social
+		// card when the site has none. Only picks anonymous visitors may see are listed.
+		if c.Path() == "/" && imageRepo != nil {
+			if picks, err := imageRepo.ForTenant(middleware.TenantID(c)).GetFeatured(ssrFeaturedLimit, models.FeedFilter{}); err == nil && len(picks) > 0 {
+				items := make([]map[string]interface{}, 0, len(picks))
+				for n, p := range picks {
+					still := p.Filename
+					if p.IsVideo() {
+						still = ""
+						if p.PosterFilename != nil {
+							still = *p.PosterFilename
+						}
+					}
+					item := map[string]interface{}{"@type": "ListItem", "position": n + 1, "url": origin + "/i/" + p.ID.String()}
+					if p.OriginalName != nil && strings.TrimSpace(*p.OriginalName) != "" {
+						item["name"] = strings.TrimSpace(*p.OriginalName)
+					}
+					if p.FeaturedNote != nil {
+						item["description"] = *p.FeaturedNote
+					}
+					if still != "" {
+						item["image"] = absURL(still)
+						if imageURL == "" {
+							imageURL = absURL(still)
+						}
+					}
+					items = append(items, item)
+				}
+				jsonLD = map[string]interface{}{
+					"@context":        "https://schema.org",
+					"@type":           "ItemList",
+					"name":            services.T(locale, "Featured"),
+					"itemListElement": items,
+				}
+			}
+		}
+
+		// CMS page: inherit index SEO, take the page title and render the body into the
+		// gallery so crawlers see the content without running the SPA
+		pageBody := ""
+		if slug, err := services.NormalizePagePath(c.Path()); err == nil && pageRepo != nil {
+			if p, err := pageRepo.ForTenant(middleware.TenantID(c)).GetPublishedBySlug(slug); err == nil && p != nil {
+				siteTitle := strings.TrimSpace(set.SiteName)
+				if siteTitle == "" {
+					siteTitle = "TROUGH"
+				}
+				// Prefer page meta title when provided; otherwise use "Page - SiteTitle"
+				if p.MetaTitle != nil && strings.TrimSpace(*p.MetaTitle) != "" {
+					title = strings.TrimSpace(*p.MetaTitle)
+				} else {
+					pt := strings.TrimSpace(p.Title)
+					if pt == "" {
+						pt = services.T(locale, "Page")
+					}
+					title = pt + " - " + siteTitle
+				}
+				pageBody = services.PageHTML(p)
+			}
+		}
+		if pageBody != "" {
+			htmlStr = strings.Replace(htmlStr, `<main class="gallery" id="gallery"></main>`,
+				`<main class="gallery settings-mode" id="gallery"><section class="mono-col" style="margin:0 auto 16px;max-width:980px;padding:16px"><article class="page-content">`+pageBody+`</article></section></main>`, 1)
+		}
+
 		// Replace title/meta description
 		htmlStr = titleRe.ReplaceAllString(htmlStr, "<title>"+html.EscapeString(title)+"</title>")
 		if description != "" {
@@ -266,6 +367,18 @@ func indexWithMetaHandler(
 		// Inject OG/Twitter tags just before </head>
 		var ogTags strings.Builder
 		ogTags.WriteString("\n    <!-- Server-side social/OG tags -->\n")
+		if noIndex {
+			ogTags.WriteString(`    <meta name="robots" content="noindex">\n`)
+		}
+		if licenseURL != "" {
+			ogTags.WriteString(`    <link rel="license" href="` + html.EscapeString(licenseURL) + `">\n`)
+		}
+		if jsonLD != nil {
+			// go-json escapes <, > and & so the payload cannot close the script element
+			if b, err := gjson.Marshal(jsonLD); err == nil {
+				ogTags.WriteString(`    <script type="application/ld+json">` + string(b) + `</script>\n`)
+			}
+		}
 		ogTags.WriteString(`    <meta property="og:site_name" content="` + html.EscapeString(set.SiteName) + `">\n`)
 		ogTags.WriteString(`    <meta property="og:title" content="` + html.EscapeString(title) + `">\n`)
 		if description != "" {
@@ -277,6 +390,12 @@ func indexWithMetaHandler(
 			ogTags.WriteString(`    <meta property="og:image" content="` + html.EscapeString(imageURL) + `">\n`)
 			ogTags.WriteString(`    <meta property="og:image:alt" content="` + html.EscapeString(title) + `">\n`)
 		}
+		if videoURL != "" {
+			ogTags.WriteString(`    <meta property="og:video" content="` + html.EscapeString(videoURL) + `">\n`)
+			if videoType != "" {
+				ogTags.WriteString(`    <meta property="og:video:type" content="` + html.EscapeString(videoType) + `">\n`)
+			}
+		}
 		// Twitter
 		card := "summary"
 		if imageURL != "" {
@@ -329,35 +448,58 @@ func indexWithMetaHandler(
 			htmlStr += insertion
 		}
 
+		htmlStr = strings.Replace(htmlStr, `<html lang="en">`, `<html lang="`+html.EscapeString(locale)+`">`, 1)
+		c.Vary(fiber.HeaderAcceptLanguage)
 		c.Set("Content-Type", "text/html; charset=utf-8")
 		return c.SendString(htmlStr)
 	}
 }
 
 func main() {
+	// Subcommands run without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+	services.InitLogging()
 	// Enforce strong JWT secret at startup
 	if len(os.Getenv("JWT_SECRET")) < 32 {
-		log.Fatalf("JWT_SECRET must be set and at least 32 characters")
+		fatal("startup: JWT_SECRET must be set and at least 32 characters")
 	}
 	config, err := services.LoadConfig("config.yaml")
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("startup: failed to load config", "error", err)
+	}
+	services.ApplyConfig(config)
+	middleware.TokenLifetime = config.Auth.JWTLifetime
+
+	// Tracing must start before the pool opens so queries are wrapped
+	flushTraces := services.InitTracing()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		flushTraces(ctx)
+	}()
+	if services.TracingEnabled() {
+		db.QueryTracer = services.TraceQuery
 	}
 
 	if err := db.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("startup: failed to connect to database", "error", err)
 	}
 	defer db.Close()
 
 	if err := db.Migrate(); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		fatal("startup: failed to migrate database", "error", err)
 	}
 
 	userRepo := models.NewUserRepository(db.DB)
 	imageRepo := models.NewImageRepository(db.DB)
 	likeRepo := models.NewLikeRepository(db.DB)
 	collectRepo := models.NewCollectRepository(db.DB)
+	boardRepo := models.NewBoardRepository(db.DB)
+	blockRepo := models.NewBlockRepository(db.DB)
 	siteRepo := models.NewSiteSettingsRepository(db.DB)
+	notificationRepo := models.NewNotificationRepository(db.DB)
 
 	maybeSeedAdmin(userRepo)
 
@@ -367,10 +509,11 @@ func main() {
 	stSettings := services.GetCachedSettings(siteRepo)
 	storage, err := services.NewStorageFromSettings(stSettings)
 	if err != nil {
-		storage = services.NewLocalStorage("uploads")
+		storage = services.NewLocalStorage(services.UploadsDir())
 	}
 	services.SetCurrentStorage(storage)
-	imageHandler := handlers.NewImageHandler(imageRepo, likeRepo, userRepo, *config, storage).WithCollect(collectRepo).WithSettings(siteRepo)
+	imageEdits := models.NewImageEditRepository(db.DB)
+	imageHandler := handlers.NewImageHandler(imageRepo, likeRepo, userRepo, *config, storage).WithCollect(collectRepo).WithSettings(siteRepo).WithNotifications(notificationRepo).WithModeration(models.NewModerationRepository(db.DB)).WithBoards(boardRepo).WithBlocks(blockRepo).WithTakedowns(models.NewTakedownRepository(db.DB)).WithImageEdits(imageEdits)
 	pageRepo := models.NewPageRepository(db.DB)
 	// Seed default CMS pages once per boot if missing (respect tombstones)
 	seedDefaultPages(pageRepo, siteRepo)
@@ -378,78 +521,144 @@ func main() {
 	// Create rate limiters for enhanced security
 	rateLimiter := services.NewRateLimiter(config.RateLimiting)
 	progressiveRateLimiter := services.NewProgressiveRateLimiter(config.ProgressiveRateLimiting, config.RateLimiting)
+	progressiveRateLimiter.SetEventCallback(func(ev services.SecurityEvent) {
+		services.RecordSecurityEvent(ev)
+		services.PublishAdminEvent(services.AdminEventSecurity, ev)
+	})
+	redisClient := redisFromEnv()
+	if redisClient != nil {
+		// Share limits and lockouts across replicas instead of multiplying them per instance
+		rlStore := services.NewRedisRateLimiterStore(redisClient, "trough:")
+		rateLimiter.WithStore(rlStore)
+		progressiveRateLimiter.WithStore(rlStore)
+	}
 
-	userHandler := handlers.NewUserHandler(userRepo, imageRepo, storage).WithSettings(siteRepo).WithCollect(collectRepo).WithPages(pageRepo)
+	statsRepo := models.NewStatsRepository(db.DB)
+	banRepo := models.NewBanRepository(db.DB)
+	auditRepo := models.NewAuditRepository(db.DB)
+	usernameHistory := models.NewUsernameHistoryRepository(db.DB)
+	legalRepo := models.NewLegalRepository(db.DB)
+	userHandler := handlers.NewUserHandler(userRepo, imageRepo, storage).WithSettings(siteRepo).WithCollect(collectRepo).WithPages(pageRepo).WithStats(statsRepo).WithUsernameHistory(usernameHistory).WithBoards(boardRepo).WithBlocks(blockRepo).WithVerification(models.NewVerificationRepository(db.DB)).WithLegal(legalRepo).WithImageEdits(imageEdits)
 	inviteRepo := models.NewInviteRepository(db.DB)
-	adminHandler := handlers.NewAdminHandler(siteRepo, userRepo, imageRepo).WithStorage(storage).WithInvites(inviteRepo).WithPages(pageRepo).WithRateLimiter(rateLimiter).WithProgressiveRateLimiter(progressiveRateLimiter)
+	mailOutbox := models.NewMailOutboxRepository(db.DB)
+	webhookRepo := models.NewWebhookRepository(db.DB)
+	jobRepo := models.NewJobRepository(db.DB)
+	securityEventRepo := models.NewSecurityEventRepository(db.DB)
+	cspReportRepo := models.NewCSPReportRepository(db.DB)
+	navigationRepo := models.NewNavigationRepository(db.DB)
+	snippetRepo := models.NewSnippetRepository(db.DB)
+	tenantRepo := models.NewTenantRepository(db.DB)
+	announcementRepo := models.NewAnnouncementRepository(db.DB)
+	csrfProtection := middleware.NewCSRFProtection(os.Getenv("CSRF_SECRET"))
+	loginEvents := models.NewLoginEventRepository(db.DB)
+	adminHandler := handlers.NewAdminHandler(siteRepo, userRepo, imageRepo).WithStorage(storage).WithLoginEvents(loginEvents).WithInvites(inviteRepo).WithPages(pageRepo).WithRateLimiter(rateLimiter).WithProgressiveRateLimiter(progressiveRateLimiter).WithMailOutbox(mailOutbox).WithWebhooks(webhookRepo).WithStats(statsRepo).WithBans(banRepo).WithJobs(jobRepo).WithAudit(auditRepo).WithSecurityEvents(securityEventRepo).WithCSRF(csrfProtection).WithCSPReports(cspReportRepo).WithAnnouncements(announcementRepo).WithNavigation(navigationRepo).WithSnippets(snippetRepo).WithTenants(tenantRepo).WithLocaleStrings(models.NewLocaleStringRepository(db.DB)).WithLegal(legalRepo).WithPolicyReload(func() ([]services.RateLimitPolicy, error) {
+		cfg, err := services.LoadConfig("config.yaml")
+		if err != nil {
+			return nil, err
+		}
+		return cfg.RateLimitPolicies, nil
+	})
 	pageHandler := handlers.NewPageHandler(pageRepo)
-	authHandler := handlers.NewAuthHandlerWithRepos(userRepo, siteRepo).WithInvites(inviteRepo).WithProgressiveRateLimiter(progressiveRateLimiter)
+	graphQLHandler := handlers.NewGraphQLHandler(userRepo, imageRepo).WithCollect(collectRepo).WithPages(pageRepo)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo, userRepo)
+	challengeHandler := handlers.NewChallengeHandler(models.NewChallengeRepository(db.DB), imageRepo, userRepo)
+	authHandler := handlers.NewAuthHandlerWithRepos(userRepo, siteRepo).WithInvites(inviteRepo).WithProgressiveRateLimiter(progressiveRateLimiter).WithBans(banRepo).WithUsernameHistory(usernameHistory).WithLoginEvents(loginEvents).WithRegistrationBlocks(models.NewRegistrationBlockRepository(db.DB)).WithLegal(legalRepo)
+	// Background jobs: upload processing, mail delivery, backups, storage migration and
+	// reconciliation run on the shared queue. With prefork only the parent process runs workers.
+	services.InitJobs(jobRepo)
+	services.RegisterBackupJobs(db.DB, siteRepo)
+	services.RegisterReconcileJobs(db.DB, siteRepo)
+	services.RegisterStorageMigrationJob(db.DB, siteRepo)
+	services.RegisterCDNPurgeJob()
+	services.RegisterImageMetaBackfillJob(db.DB)
+	services.RegisterAIRedetectJob(db.DB)
+	services.RegisterChecksumVerifyJob(db.DB)
+	imageHandler.WithJobs(jobRepo).RegisterUploadJob()
 	// Initialize async mail queue if SMTP is configured
 	if set, err := siteRepo.Get(); err == nil && set != nil {
 		if set.SMTPHost != "" && set.SMTPPort > 0 && set.SMTPUsername != "" && set.SMTPPassword != "" {
 			services.InitMailQueue(services.NewMailSender, siteRepo)
+			services.InitMailOutbox(services.NewMailSender, siteRepo, mailOutbox)
 		}
 	}
+	// Digests go through the mail queue and are skipped while SMTP is unconfigured
+	services.InitNotificationDigest(notificationRepo, siteRepo)
+	services.InitWebhooks(webhookRepo)
+	services.InitSuspensionExpiry(userRepo)
+	// Each process persists the security events its own limiter raises
+	services.InitSecurityEventLog(securityEventRepo, config.SecurityEvents)
+	services.InitCSPReports(cspReportRepo)
+	if !fiber.IsChild() {
+		services.StartJobWorkers(config.Jobs.Workers, config.Jobs.PollInterval)
+	}
+	services.RegisterGaugeFunc("trough_jobs_pending", "Background jobs waiting to run, including periodic jobs.", func() float64 {
+		_, total, err := jobRepo.List(models.JobStatusPending, "", 1, 1)
+		if err != nil {
+			return 0
+		}
+		return float64(total)
+	})
+	services.RegisterGaugeFunc("trough_jobs_dead", "Background jobs that exhausted their attempts.", func() float64 {
+		_, total, err := jobRepo.List(models.JobStatusDead, "", 1, 1)
+		if err != nil {
+			return 0
+		}
+		return float64(total)
+	})
+	services.RegisterGaugeFunc("trough_mail_outbox_pending", "Emails pending delivery in the persistent outbox.", func() float64 {
+		_, total, err := mailOutbox.List(models.MailStatusPending, 1, 1)
+		if err != nil {
+			return 0
+		}
+		return float64(total)
+	})
+
+	configureFeedCache(redisClient)
+	configureAIRejectCache(redisClient)
 
 	app := fiber.New(fiber.Config{
-		BodyLimit:    10 * 1024 * 1024,
+		BodyLimit:    config.Server.BodyLimitMB * 1024 * 1024,
 		ErrorHandler: customErrorHandler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
-		Prefork:      false, // enable in prod Linux if desired
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
+		IdleTimeout:  config.Server.IdleTimeout,
+		Prefork:      config.Server.Prefork,
 		JSONEncoder:  gjson.Marshal,
 		JSONDecoder:  gjson.Unmarshal,
+		// X-Forwarded-Host/-Proto (c.Hostname, c.Protocol) count only from trusted proxies.
+		// ProxyHeader stays unset: client IPs come from services.ClientIP, which walks
+		// X-Forwarded-For past trusted hops instead of taking its spoofable first entry.
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          config.Server.TrustedProxies,
 	})
 
 	// Initialize security components
-	csrfProtection := middleware.NewCSRFProtection(os.Getenv("CSRF_SECRET"))
-	securityHeaders := services.NewSecurityHeaders(nil)
+	// The CSP follows site settings (analytics, challenge widget, storage host, extra scripts)
+	securityHeaders := services.NewSecurityHeaders(nil).WithDynamicCSP(func() string {
+		return services.CachedCSP(services.GetCachedSettings(siteRepo))
+	})
 
 	// Apply security headers globally
 	app.Use(securityHeaders.Middleware())
 
-	// Start backup scheduler goroutine (best-effort, non-blocking)
-	go func() {
-		// Simple ticker-based scheduler using settings cache
-		for {
-			set := services.GetCachedSettings(siteRepo)
-			if set.BackupEnabled {
-				// Parse interval; fallback to 24h
-				d, err := time.ParseDuration(strings.TrimSpace(set.BackupInterval))
-				if err != nil || d <= 0 {
-					d = 24 * time.Hour
-				}
-				// Perform backup and cleanup
-				if _, err := services.SaveBackupFile(context.Background(), db.DB, "backups"); err == nil {
-					_ = services.CleanupBackups("backups", set.BackupKeepDays)
-				}
-				time.Sleep(d)
-				continue
-			}
-			time.Sleep(30 * time.Minute)
-		}
-	}()
-
 	// Cleanup rate limiters on shutdown
 	defer rateLimiter.Stop()
 	defer progressiveRateLimiter.Stop()
 
-	// Application logger; skip noise for static and health endpoints
-	app.Use(logger.New(logger.Config{
-		Next: func(c *fiber.Ctx) bool {
-			p := c.Path()
-			if strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") || p == "/healthz" || p == "/" {
-				return true
-			}
-			return false
-		},
-	}))
-	app.Use(etag.New(etag.Config{Weak: true}))
+	app.Use(middleware.Metrics())
+	app.Use(middleware.Tracing())
+	// Event streams must reach the client unbuffered: ETags and compression both need the whole body
+	eventStream := func(c *fiber.Ctx) bool {
+		return strings.HasSuffix(c.Path(), "/feed/stream") || strings.HasSuffix(c.Path(), "/admin/ws")
+	}
+	app.Use(etag.New(etag.Config{Weak: true, Next: eventStream}))
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestSpeed,
 		Next: func(c *fiber.Ctx) bool {
 			p := c.Path()
+			if eventStream(c) {
+				return true
+			}
 			// Skip already-compressed/static heavy assets
 			if strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") {
 				return true
@@ -461,6 +670,12 @@ func main() {
 			return false
 		},
 	}))
+	// Request IDs and structured access logs; skip noise for static and health endpoints.
+	// Registered after compression so JSON error bodies can carry the request ID.
+	app.Use(middleware.RequestLogger(func(c *fiber.Ctx) bool {
+		p := c.Path()
+		return strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") || p == "/healthz" || p == "/livez" || p == "/readyz" || p == "/"
+	}))
 	// Configure CORS for API. Do not affect images/scripts loading.
 	app.Use(cors.New(cors.Config{
 		AllowOriginsFunc: func(origin string) bool {
@@ -488,6 +703,23 @@ func main() {
 		AllowCredentials: true,
 	}))
 
+	// /api/v1 aliases and version negotiation; runs before routing so the rest of the
+	// stack only ever sees unversioned /api paths.
+	app.Use(middleware.APIVersion())
+
+	// Multi-site mode: match the Host to a tenant before any handler reads settings or feeds
+	if err := adminHandler.ReloadTenants(); err != nil {
+		services.Logger(context.Background()).Error("tenants: load failed", "error", err)
+	}
+	app.Use(middleware.Tenant())
+	// Audits requests answered for an impersonated user outside Protected routes
+	app.Use(middleware.ImpersonationAudit())
+	// Language for error messages and server-rendered copy
+	if err := adminHandler.ReloadLocaleStrings(); err != nil {
+		services.Logger(context.Background()).Error("i18n: load overrides failed", "error", err)
+	}
+	app.Use(middleware.Locale(siteRepo))
+
 	// Security headers - using the security headers service for consistency
 	app.Use(func(c *fiber.Ctx) error {
 		// Let the security headers service handle most CSP/security headers
@@ -495,43 +727,44 @@ func main() {
 		return c.Next()
 	})
 
+	// Prometheus scrape endpoint; token- or private-network-only (see handlers.Metrics)
+	app.Get("/metrics", handlers.Metrics)
+
 	// Serve SPA entry with server-side meta tags for key routes
 	index := indexWithMetaHandler(siteRepo, imageRepo, userRepo, pageRepo)
 	app.Get("/", index)
-	app.Get("/@:username", index)
+	app.Get("/@:username", func(c *fiber.Ctx) error {
+		// A recently renamed handle permanently redirects to the account's current profile
+		username := strings.ToLower(strings.TrimSpace(c.Params("username")))
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if _, err := userRepo.GetByUsername(ctx, username); err != nil {
+			if id, err := usernameHistory.ResolveOld(username, time.Now().Add(-models.UsernameRedirectGrace)); err == nil {
+				if u, err := userRepo.GetByID(ctx, id); err == nil {
+					return c.Redirect("/@"+u.Username, fiber.StatusMovedPermanently)
+				}
+			}
+		}
+		return index(c)
+	})
 	app.Get("/settings", index)
 	app.Get("/admin", index)
+	app.Get("/challenges", index)
+	app.Get("/takedown", index)
 	app.Get("/register", index)
 	app.Get("/reset", index)
 	app.Get("/verify", index)
+	app.Get("/confirm-email", index)
+	app.Get("/cancel-email-change", index)
 	app.Get("/i/:id", index)
-	// Single-segment CMS pages SSR entry
-	app.Get("/:slug", func(c *fiber.Ctx) error {
-		slug := strings.ToLower(strings.Trim(c.Params("slug"), "/"))
-		if slug == "" {
-			return index(c)
-		}
-		// Skip reserved prefixes and known routes
-		reserved := map[string]bool{"api": true, "uploads": true, "assets": true, "@": true, "i": true, "register": true, "reset": true, "verify": true, "settings": true, "admin": true}
-		if reserved[slug] {
-			return index(c)
-		}
-		// If slug corresponds to a published redirect page, perform server-side redirect immediately
-		if p, err := pageRepo.GetPublishedBySlug(slug); err == nil && p != nil {
-			if p.RedirectURL != nil && strings.TrimSpace(*p.RedirectURL) != "" {
-				return c.Redirect(strings.TrimSpace(*p.RedirectURL), fiber.StatusFound)
-			}
-		}
-		// Otherwise serve SPA; client will fetch and render page content
-		return index(c)
-	})
-
 	// Static assets
-	app.Static("/", "./static", fiber.Static{Compress: true, CacheDuration: 3600, MaxAge: 31536000})
+	app.Static("/", "./static", fiber.Static{Compress: true, CacheDuration: 3600, ModifyResponse: cacheControl(services.CacheAssets)})
 	// Local uploads are served statically when storage is local. For remote storage (S3/R2),
 	// we keep this mount (for legacy/local files), and add a redirector for /uploads/* to the
 	// configured public base if set.
-	app.Static("/uploads", "./uploads", fiber.Static{Compress: true, CacheDuration: 86400, MaxAge: 31536000})
+	// With signed URLs on, image files need a valid token before either handler serves them
+	app.Use("/uploads", middleware.SignedUploads())
+	app.Static("/uploads", services.UploadsDir(), fiber.Static{Compress: true, CacheDuration: 86400, ModifyResponse: cacheControl(services.CacheUploads)})
 	// Dynamic redirector for remote storage; uses current storage and latest settings cache
 	app.Get("/uploads/*", func(c *fiber.Ctx) error {
 		st := services.GetCurrentStorage()
@@ -543,14 +776,36 @@ func main() {
 			return c.Next()
 		}
 		key := c.Params("*")
-		return c.Redirect(st.PublicURL(key), fiber.StatusFound)
+		target := st.PublicURL(key)
+		// Pass the token on so a CDN sharing the secret can check it too
+		if services.SignedURLsEnabled() && services.UploadKeyNeedsToken(key) {
+			target += "?" + services.UploadTokenQuery(key, time.Now())
+		}
+		return c.Redirect(target, fiber.StatusFound)
 	})
 	// Simple health endpoint for uptime checks (not logged)
 	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	// Orchestrator probes: /livez only says the process serves, /readyz checks dependencies
+	healthHandler := handlers.NewHealthHandler(db.Ping).WithStorage(services.GetCurrentStorage).WithMailOutbox(mailOutbox)
+	app.Get("/livez", healthHandler.Live)
+	app.Get("/readyz", healthHandler.Ready)
 
 	api := app.Group("/api")
 	// Build auth middleware once to reuse its small cache
 	authMW := middleware.Protected()
+	adminHandler.WithOpenAPI(app, authMW)
+	// Account changes an impersonating admin must not make on the user's behalf
+	noImpersonation := middleware.NoImpersonation()
+
+	// Per-route limits from rate_limit_policies, checked before anything touches the database
+	api.Use(middleware.RateLimitPolicies(rateLimiter))
+	// Signed-out feed and image reads, so the API cannot be scraped at full speed
+	api.Use(middleware.AnonymousReadLimit(rateLimiter))
+	// On adult sites, signed-out visitors confirm their age before images load
+	api.Use(middleware.AgeGate(siteRepo))
+
+	// Image references leave the API as signed /uploads links when signed URLs are on
+	api.Use(middleware.SignMediaURLs())
 
 	// Add database health check middleware to all API routes
 	api.Use(middleware.DBPing())
@@ -558,100 +813,312 @@ func main() {
 	// Apply CSRF protection to API routes that change state
 	api.Use(csrfProtection.Middleware())
 
+	// Signed-in users must accept the current terms before changing anything (451)
+	api.Use(middleware.RequireConsent(siteRepo, legalRepo))
+
 	api.Post("/register", progressiveRateLimiter.Middleware(), authHandler.Register)
 	// NOTE: Consider adding rate limiting middleware in deployment env; omitted here to avoid new deps.
 	api.Post("/login", progressiveRateLimiter.Middleware(), authHandler.Login)
 	// Allow logout without auth guard so clients can always clear cookies
 	api.Post("/logout", authHandler.Logout)
 	api.Post("/forgot-password", progressiveRateLimiter.Middleware(), authHandler.ForgotPassword)
+	api.Get("/auth/challenge", authHandler.AuthChallenge)
+	api.Get("/auth/form-token", authHandler.FormToken)
 	api.Post("/reset-password", progressiveRateLimiter.Middleware(), authHandler.ResetPassword)
 	api.Post("/verify-email", progressiveRateLimiter.Middleware(), authHandler.VerifyEmail)
+	api.Post("/confirm-email-change", progressiveRateLimiter.Middleware(), authHandler.ConfirmEmailChange)
+	api.Post("/cancel-email-change", progressiveRateLimiter.Middleware(), authHandler.CancelEmailChange)
 
 	api.Get("/password-requirements", authHandler.GetPasswordRequirements)
+	api.Get("/age-gate", authHandler.GetAgeGate)
+	api.Post("/age-gate", authHandler.ConfirmAge)
+	api.Get("/openapi.json", adminHandler.OpenAPISpec)
+	api.Get("/docs", authMW, adminHandler.APIDocs)
 	api.Get("/invites/validate", adminHandler.ValidateInviteCode)
 
-	// Public CSRF token endpoint for initial page load
+	// Browsers post Content-Security-Policy violations here (report-uri)
+	api.Post("/csp-report", adminHandler.ReceiveCSPReport)
+
+	// Public CSRF token endpoint for initial page load. The existing token is kept while it
+	// still matches the session. ?form=METHOD%20/path also returns a token for that one form.
 	api.Get("/csrf", func(c *fiber.Ctx) error {
-		// Only set a new CSRF token if one doesn't already exist
-		existingToken := csrfProtection.GetCSRFToken(c)
-		if existingToken == "" {
-			if err := csrfProtection.SetCSRFToken(c); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to generate CSRF token",
-				})
+		token, err := csrfProtection.EnsureToken(c)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate CSRF token",
+			})
+		}
+		out := fiber.Map{"csrf_token": token}
+		if form := strings.TrimSpace(c.Query("form")); form != "" {
+			method, path, ok := strings.Cut(form, " ")
+			if !ok || !strings.HasPrefix(path, "/api/") {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "form must be METHOD /api/path"})
 			}
-			existingToken = csrfProtection.GetCSRFToken(c)
+			formToken, err := csrfProtection.FormToken(c, method, path)
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sign in to request a form token"})
+			}
+			out["form_token"] = formToken
 		}
-		return c.JSON(fiber.Map{
-			"csrf_token": existingToken,
-		})
+		return c.JSON(out)
 	})
 	api.Post("/me/resend-verification", authMW, authHandler.ResendVerification)
 	api.Get("/me", authMW, authHandler.Me)
 
 	api.Get("/feed", imageHandler.GetFeed)
+	api.Get("/featured", imageHandler.GetFeatured)
+	// Themed prompts: current/upcoming/past listings, leaderboards by collections and entries
+	api.Get("/challenges", challengeHandler.ListChallenges)
+	api.Get("/challenges/:id", challengeHandler.GetChallenge)
+	api.Get("/challenges/:id/leaderboard", challengeHandler.ChallengeLeaderboard)
+	api.Post("/challenges/:id/entries", authMW, challengeHandler.EnterChallenge)
+	api.Delete("/challenges/:id/entries/:imageId", authMW, challengeHandler.WithdrawChallengeEntry)
+	// Live new-image and collection-count events (SSE)
+	api.Get("/feed/stream", imageHandler.FeedStream)
 	api.Get("/images/:id", imageHandler.GetImage)
+	api.Get("/images/:id/remixes", imageHandler.ListRemixes)
+	api.Get("/licenses", imageHandler.ListLicenses)
+	// Originals are rate limited by the "download" policy
+	api.Get("/images/:id/download", imageHandler.DownloadImage)
+	api.Get("/images/:id/workflow", imageHandler.GetImageWorkflow)
+	api.Post("/takedown", progressiveRateLimiter.Middleware(), imageHandler.SubmitTakedown)
 	api.Post("/upload", authMW, imageHandler.Upload)
+	api.Get("/uploads/:token/status", authMW, imageHandler.UploadStatus)
 	// Likes are deprecated; route retained for compatibility but returns 410
-	api.Post("/images/:id/like", authMW, imageHandler.LikeImage)
+	// Likes were replaced by collections; the endpoint only answers 410 until its sunset.
+	api.Post("/images/:id/like", middleware.Deprecated(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC), "/api/openapi.json"), authMW, imageHandler.LikeImage)
 	api.Post("/images/:id/collect", authMW, imageHandler.CollectImage)
+	api.Get("/images/:id/collectors", authMW, imageHandler.ListCollectors)
+	api.Get("/images/:id/edits", authMW, imageHandler.ListImageEdits)
 	api.Patch("/images/:id", authMW, imageHandler.UpdateImage)
+	api.Post("/images/:id/file", authMW, imageHandler.ReplaceImageFile)
 	api.Delete("/images/:id", authMW, imageHandler.DeleteImage)
 
 	api.Get("/users/:username", userHandler.GetProfile)
 	api.Get("/users/:username/images", userHandler.GetUserImages)
+	api.Get("/users/:username/stats", userHandler.GetUserStats)
 	api.Get("/users/:username/collections", userHandler.GetUserCollections)
+	// Boards: named, ordered collections; private ones are visible only to their owner
+	api.Get("/users/:username/boards", userHandler.GetUserBoards)
+	api.Post("/users/:username/block", authMW, userHandler.BlockUser)
+	api.Delete("/users/:username/block", authMW, userHandler.UnblockUser)
+	api.Get("/boards/:id", imageHandler.GetBoard)
 	// Public pages list for footer
 	api.Get("/pages", userHandler.ListPublicPages)
 	// Public page data for SPA render (and server redirect)
-	api.Get("/pages/:slug", pageHandler.GetPublicPage)
+	api.Get("/pages/*", pageHandler.GetPublicPage)
+	// Header and footer menus managed under /admin
+	api.Get("/navigation", adminHandler.GetNavigation)
+	api.Get("/snippets", adminHandler.GetSnippets)
+	// Read-only GraphQL over the feed, profiles, collections and pages; GET without a query returns the SDL
+	api.Get("/graphql", graphQLHandler.Query)
+	api.Post("/graphql", graphQLHandler.Query)
 	api.Get("/me/profile", authMW, userHandler.GetMyProfile)
+	api.Get("/me/blocks", authMW, userHandler.ListMyBlocks)
+	api.Get("/me/verification", authMW, userHandler.GetMyVerification)
+	api.Post("/me/verification", authMW, noImpersonation, userHandler.StartMyVerification)
+	api.Post("/me/verification/check", authMW, noImpersonation, userHandler.CheckMyVerification)
+	api.Get("/me/consent", authMW, userHandler.GetMyConsent)
+	api.Post("/me/consent", authMW, noImpersonation, userHandler.AcceptTerms)
+	api.Get("/me/boards", authMW, imageHandler.ListMyBoards)
+	api.Post("/me/boards", authMW, imageHandler.CreateBoard)
+	api.Put("/me/boards/order", authMW, imageHandler.ReorderBoards)
+	api.Patch("/me/boards/:id", authMW, imageHandler.UpdateBoard)
+	api.Delete("/me/boards/:id", authMW, imageHandler.DeleteBoard)
+	api.Post("/me/boards/:id/images", authMW, imageHandler.AddBoardImage)
+	api.Delete("/me/boards/:id/images/:imageId", authMW, imageHandler.RemoveBoardImage)
+	api.Put("/me/boards/:id/images/order", authMW, imageHandler.ReorderBoardImages)
 	api.Patch("/me/profile", authMW, userHandler.UpdateMyProfile)
+	api.Post("/me/banner", authMW, userHandler.UploadProfileBanner)
+	api.Delete("/me/banner", authMW, userHandler.DeleteProfileBanner)
+	// Earlier names of the banner endpoints
+	bannerAlias := middleware.Deprecated(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), time.Date(2027, 10, 1, 0, 0, 0, 0, time.UTC), "/api/openapi.json")
+	api.Post("/me/profile/header", bannerAlias, authMW, userHandler.UploadProfileBanner)
+	api.Delete("/me/profile/header", bannerAlias, authMW, userHandler.DeleteProfileBanner)
 	api.Get("/me/account", authMW, userHandler.GetMyAccount)
-	api.Patch("/me/email", authMW, userHandler.UpdateEmail)
-	api.Patch("/me/password", authMW, userHandler.UpdatePassword)
-	api.Delete("/me", authMW, userHandler.DeleteMyAccount)
+	api.Get("/me/notifications", authMW, notificationHandler.ListMyNotifications)
+	api.Get("/me/notifications/unread", authMW, notificationHandler.UnreadCount)
+	api.Post("/me/notifications/read", authMW, notificationHandler.MarkRead)
+	api.Delete("/me/notifications/:id", authMW, notificationHandler.DeleteNotification)
+	api.Patch("/me/email", authMW, noImpersonation, userHandler.UpdateEmail)
+	api.Delete("/me/email/pending", authMW, noImpersonation, userHandler.CancelPendingEmail)
+	api.Patch("/me/password", authMW, noImpersonation, userHandler.UpdatePassword)
+	api.Delete("/me", authMW, noImpersonation, userHandler.DeleteMyAccount)
+	api.Post("/me/impersonation/end", authMW, adminHandler.EndImpersonation)
+	api.Get("/me/invites", authMW, authHandler.MyInvites)
+	api.Get("/me/security/logins", authMW, authHandler.LoginHistory)
+	api.Post("/me/invites", authMW, noImpersonation, authHandler.CreateMyInvite)
 	api.Post("/me/avatar", authMW, userHandler.UploadAvatar)
 
 	api.Get("/site", adminHandler.GetPublicSite)
+	api.Get("/announcements", adminHandler.GetAnnouncements)
 
 	api.Get("/admin/users", authMW, userHandler.AdminListUsers)
 	api.Post("/admin/users", authMW, userHandler.AdminCreateUser)
+	api.Post("/admin/users/bulk", authMW, userHandler.AdminBulkUsers)
+	api.Get("/admin/users/:id", authMW, adminHandler.AdminGetUser)
 	api.Patch("/admin/users/:id", authMW, userHandler.AdminSetUserFlags)
 	api.Patch("/admin/users/:id/password", authMW, userHandler.AdminSetUserPassword)
 	api.Post("/admin/users/:id/send-verification", authMW, userHandler.AdminSendVerification)
+	api.Post("/admin/users/:id/message", authMW, notificationHandler.AdminMessageUser)
 	api.Delete("/admin/users/:id", authMW, userHandler.AdminDeleteUser)
+	api.Post("/admin/users/:id/suspend", authMW, userHandler.AdminSuspendUser)
+	api.Put("/admin/users/:id/verified", authMW, userHandler.AdminSetUserVerified)
+	api.Get("/admin/registrations", authMW, userHandler.AdminListPendingRegistrations)
+	api.Post("/admin/registrations/:id/approve", authMW, userHandler.AdminApproveRegistration)
+	api.Post("/admin/registrations/:id/reject", authMW, userHandler.AdminRejectRegistration)
+	api.Get("/admin/legal", authMW, adminHandler.AdminGetLegal)
+	api.Post("/admin/legal/publish", authMW, adminHandler.AdminPublishLegal)
+	api.Get("/admin/users/:id/username-history", authMW, userHandler.AdminUsernameHistory)
+	api.Post("/admin/users/:id/impersonate", authMW, adminHandler.AdminImpersonate)
+	api.Get("/admin/audit", authMW, adminHandler.ListAdminAudit)
+	api.Delete("/admin/users/:id/suspend", authMW, userHandler.AdminUnsuspendUser)
+	api.Get("/admin/images", authMW, adminHandler.AdminListImages)
 	api.Delete("/admin/images/:id", authMW, userHandler.AdminDeleteImage)
 	api.Patch("/admin/images/:id/nsfw", authMW, userHandler.AdminSetImageNSFW)
+	api.Put("/admin/images/:id/featured", authMW, userHandler.AdminFeatureImage)
+	api.Delete("/admin/images/:id/featured", authMW, userHandler.AdminUnfeatureImage)
+	api.Post("/admin/images/bulk", authMW, userHandler.AdminBulkImages)
+	// Moderation queue (moderators and admins)
+	api.Get("/moderation/queue", authMW, imageHandler.ListModerationQueue)
+	api.Post("/moderation/queue/:id/approve", authMW, imageHandler.ApproveQueuedImage)
+	api.Post("/moderation/queue/:id/reject", authMW, imageHandler.RejectQueuedImage)
+	// Copyright takedown requests (admins)
+	api.Get("/admin/takedowns", authMW, imageHandler.AdminListTakedowns)
+	api.Get("/admin/takedowns/:id", authMW, imageHandler.AdminGetTakedown)
+	api.Post("/admin/takedowns/:id/actions", authMW, imageHandler.AdminTakedownAction)
 
 	// Admin invite management
 	api.Post("/admin/invites", authMW, adminHandler.CreateInvite)
+	api.Post("/admin/invites/send", authMW, adminHandler.SendInvite)
 	api.Get("/admin/invites", authMW, adminHandler.ListInvites)
+	api.Get("/admin/invites/tree", authMW, adminHandler.InviteTree)
 	api.Delete("/admin/invites/:id", authMW, adminHandler.DeleteInvite)
 	api.Post("/admin/invites/prune", authMW, adminHandler.PruneInvites)
+	api.Get("/admin/email/preview/:name", authMW, adminHandler.PreviewEmail)
+	// Admin mail outbox (failed and dead-lettered sends)
+	api.Get("/admin/mail/outbox", authMW, adminHandler.ListMailOutbox)
+	api.Post("/admin/mail/outbox/:id/retry", authMW, adminHandler.RetryMail)
+	api.Delete("/admin/mail/outbox/:id", authMW, adminHandler.DeleteMail)
+	// Background jobs
+	api.Get("/admin/jobs", authMW, adminHandler.ListJobs)
+	api.Get("/admin/jobs/:id", authMW, adminHandler.GetJob)
+	api.Post("/admin/jobs/:id/retry", authMW, adminHandler.RetryJob)
+	api.Get("/admin/webhooks", authMW, adminHandler.ListWebhooks)
+	api.Post("/admin/webhooks", authMW, adminHandler.CreateWebhook)
+	api.Get("/admin/webhooks/deliveries", authMW, adminHandler.ListWebhookDeliveries)
+	api.Post("/admin/webhooks/deliveries/:id/retry", authMW, adminHandler.RetryWebhookDelivery)
+	api.Patch("/admin/webhooks/:id", authMW, adminHandler.UpdateWebhook)
+	api.Delete("/admin/webhooks/:id", authMW, adminHandler.DeleteWebhook)
+	api.Post("/admin/webhooks/:id/ping", authMW, adminHandler.PingWebhook)
 
 	api.Get("/admin/site", authMW, adminHandler.GetSiteSettings)
 	api.Put("/admin/site", authMW, adminHandler.UpdateSiteSettings)
 	api.Post("/admin/site/favicon", authMW, adminHandler.UploadFavicon)
 	api.Post("/admin/site/social-image", authMW, adminHandler.UploadSocialImage)
 	api.Post("/admin/site/test-smtp", authMW, adminHandler.TestSMTP)
+	api.Get("/admin/site/export-status", authMW, adminHandler.StorageMigrationStatus)
+	api.Post("/admin/storage/migrate", authMW, adminHandler.MigrateStorage)
+	api.Get("/admin/storage/migrate/status", authMW, adminHandler.StorageMigrationStatus)
+	api.Post("/admin/images/backfill-meta", authMW, adminHandler.AdminBackfillImageMeta)
+	api.Get("/admin/images/backfill-meta", authMW, adminHandler.AdminImageMetaBackfillStatus)
+	api.Post("/admin/ai-redetect", authMW, adminHandler.AdminAIRedetect)
+	api.Get("/admin/ai-redetect", authMW, adminHandler.AdminAIRedetectStatus)
+	api.Post("/admin/images/verify-checksums", authMW, adminHandler.AdminVerifyChecksums)
+	api.Get("/admin/images/verify-checksums", authMW, adminHandler.AdminChecksumVerifyStatus)
 	api.Post("/admin/site/export-uploads", authMW, adminHandler.ExportLocalUploadsToStorage)
 	api.Post("/admin/site/test-storage", authMW, adminHandler.TestStorage)
 	// Admin CMS pages
 	// Admin backups
 	api.Post("/admin/backups/download", authMW, adminHandler.AdminCreateBackup)
 	api.Get("/admin/backups", authMW, adminHandler.AdminListBackups)
+	// Remote backups (registered before /admin/backups/:name)
+	api.Get("/admin/backups/remote", authMW, adminHandler.AdminListRemoteBackups)
+	api.Post("/admin/backups/remote", authMW, adminHandler.AdminPushRemoteBackup)
+	api.Post("/admin/backups/remote/:name/restore", authMW, adminHandler.AdminRestoreRemoteBackup)
+	api.Delete("/admin/backups/remote/:name", authMW, adminHandler.AdminDeleteRemoteBackup)
 	api.Post("/admin/backups/save", authMW, adminHandler.AdminSaveBackup)
 	api.Delete("/admin/backups/:name", authMW, adminHandler.AdminDeleteBackup)
 	api.Post("/admin/backups/restore", authMW, adminHandler.AdminRestoreBackup)
+	api.Put("/admin/backups/passphrase", authMW, adminHandler.AdminSetBackupPassphrase)
 	api.Get("/admin/backups/:name", authMW, adminHandler.AdminDownloadSavedBackup)
+	// Admin storage reconciliation
+	api.Get("/admin/cache/stats", authMW, adminHandler.AdminFeedCacheStats)
+	api.Get("/admin/cache/ai-rejects", authMW, adminHandler.AdminAIRejectCacheStats)
+	api.Delete("/admin/cache/ai-rejects", authMW, adminHandler.AdminFlushAIRejectCache)
+	api.Get("/admin/storage/reconcile", authMW, adminHandler.AdminGetReconcileReport)
+	api.Post("/admin/storage/reconcile", authMW, adminHandler.AdminReconcileStorage)
 	api.Get("/admin/diag", authMW, adminHandler.AdminDiag)
+	api.Get("/admin/system", authMW, adminHandler.AdminSystem)
+	if config.Server.Pprof {
+		api.Use("/admin/debug/pprof", authMW, adminHandler.AdminPprof())
+		services.Logger(context.Background()).Info("pprof: profiler enabled for admins", "path", "/api/admin/debug/pprof/")
+	}
+	api.Get("/admin/stats", authMW, adminHandler.AdminStats)
+	api.Get("/admin/bans", authMW, adminHandler.ListBans)
+	api.Post("/admin/bans", authMW, adminHandler.CreateBan)
+	api.Get("/admin/bans/audit", authMW, adminHandler.ListBanAudit)
+	api.Delete("/admin/bans/:id", authMW, adminHandler.DeleteBan)
 	api.Get("/admin/rate-limiter-stats", authMW, adminHandler.AdminRateLimiterStats)
 	api.Get("/admin/progressive-rate-limiter-stats", authMW, adminHandler.AdminProgressiveRateLimiterStats)
+	api.Get("/admin/security-events", authMW, adminHandler.ListSecurityEvents)
+	api.Get("/admin/csrf-stats", authMW, adminHandler.AdminCSRFStats)
+	api.Get("/admin/csp-reports", authMW, adminHandler.ListCSPReports)
+	api.Delete("/admin/csp-reports", authMW, adminHandler.ClearCSPReports)
+	api.Get("/admin/announcements", authMW, adminHandler.AdminListAnnouncements)
+	api.Post("/admin/announcements", authMW, adminHandler.AdminCreateAnnouncement)
+	api.Patch("/admin/announcements/:id", authMW, adminHandler.AdminUpdateAnnouncement)
+	api.Delete("/admin/announcements/:id", authMW, adminHandler.AdminDeleteAnnouncement)
+	api.Get("/admin/challenges", authMW, challengeHandler.AdminListChallenges)
+	api.Post("/admin/challenges", authMW, challengeHandler.AdminCreateChallenge)
+	api.Patch("/admin/challenges/:id", authMW, challengeHandler.AdminUpdateChallenge)
+	api.Delete("/admin/challenges/:id", authMW, challengeHandler.AdminDeleteChallenge)
+	api.Get("/admin/rate-limit-policies", authMW, adminHandler.AdminRateLimitPolicies)
+	api.Post("/admin/rate-limit-policies/reload", authMW, adminHandler.AdminReloadRateLimitPolicies)
+	// Live security, moderation and upload events for the dashboard (WebSocket)
+	api.Get("/admin/ws", authMW, adminHandler.AdminMonitor)
 	api.Get("/admin/pages", authMW, adminHandler.AdminListPages)
 	api.Post("/admin/pages", authMW, adminHandler.AdminCreatePage)
 	api.Put("/admin/pages/:id", authMW, adminHandler.AdminUpdatePage)
+	api.Get("/admin/pages/:id/revisions", authMW, adminHandler.AdminListPageRevisions)
+	api.Post("/admin/pages/:id/revisions/:rev/restore", authMW, adminHandler.AdminRestorePageRevision)
 	api.Delete("/admin/pages/:id", authMW, adminHandler.AdminDeletePage)
+	api.Get("/admin/navigation", authMW, adminHandler.AdminGetNavigation)
+	api.Put("/admin/navigation/:menu", authMW, adminHandler.AdminPutNavigation)
+	api.Get("/admin/snippets", authMW, adminHandler.AdminListSnippets)
+	api.Put("/admin/snippets/:key", authMW, adminHandler.AdminPutSnippet)
+	api.Delete("/admin/snippets/:key", authMW, adminHandler.AdminDeleteSnippet)
+	api.Get("/admin/tenants", authMW, adminHandler.AdminListTenants)
+	api.Post("/admin/tenants", authMW, adminHandler.AdminCreateTenant)
+	api.Patch("/admin/tenants/:id", authMW, adminHandler.AdminUpdateTenant)
+	api.Delete("/admin/tenants/:id", authMW, adminHandler.AdminDeleteTenant)
+	api.Get("/admin/i18n", authMW, adminHandler.AdminListLocales)
+	api.Get("/admin/i18n/:locale", authMW, adminHandler.AdminGetLocaleStrings)
+	api.Put("/admin/i18n/:locale", authMW, adminHandler.AdminPutLocaleStrings)
+
+	// CMS pages (help, help/faq) are served from the site root, so this catch-all comes
+	// after every other route and the static files. Page paths may not start with a segment
+	// any of those use.
+	services.SetReservedPageRoots(routeRoots(app))
+	app.Get("/*", func(c *fiber.Ctx) error {
+		slug, err := services.NormalizePagePath(c.Path())
+		if err != nil {
+			return c.Next()
+		}
+		p, err := pageRepo.ForTenant(middleware.TenantID(c)).GetPublishedBySlug(slug)
+		if err != nil || p == nil {
+			// Unknown single-segment paths have always fallen back to the SPA
+			if !strings.Contains(slug, "/") {
+				return index(c)
+			}
+			return c.Next()
+		}
+		// Redirect pages are answered here instead of by the SPA
+		if p.RedirectURL != nil && strings.TrimSpace(*p.RedirectURL) != "" {
+			return c.Redirect(strings.TrimSpace(*p.RedirectURL), fiber.StatusFound)
+		}
+		return index(c)
+	})
 
 	app.Use(func(c *fiber.Ctx) error {
 		if strings.HasPrefix(c.Path(), "/api") {
@@ -663,8 +1130,58 @@ func main() {
 		return c.SendStatus(fiber.StatusNotFound)
 	})
 
-	log.Printf("Server starting on port 8080")
-	log.Fatal(app.Listen(":8080"))
+	listenErr := make(chan error, 1)
+	go func() { listenErr <- serve(app, config.Server) }()
+
+	// SIGTERM (deploys, docker stop) and SIGINT trigger a graceful shutdown: stop accepting
+	// connections and finish in-flight requests, then let background work drain before the
+	// deferred cleanups stop the rate limiters, flush traces and close the database.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-listenErr:
+		fatal("server: failed", "error", err)
+	case s := <-sig:
+		services.Logger(context.Background()).Info("shutdown: signal received", "signal", s.String())
+	}
+	timeout := config.Server.ShutdownTimeout
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		services.Logger(context.Background()).Error("shutdown: HTTP server", "error", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := services.Shutdown(ctx); err != nil {
+		services.Logger(ctx).Warn("shutdown: background jobs still running; leased work will be retried by the next instance", "jobs", services.InFlightWork(), "timeout", timeout)
+	}
+	services.Logger(ctx).Info("shutdown: complete")
+}
+
+
+// routeRoots returns the first path segments the app serves: registered routes and the
+// entries of the static directory.
+func routeRoots(app *fiber.App) []string {
+	var roots []string
+	for _, r := range app.GetRoutes(true) {
+		seg, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+		if seg == "" || strings.ContainsAny(seg, ":*@") {
+			continue
+		}
+		roots = append(roots, seg)
+	}
+	if entries, err := os.ReadDir("./static"); err == nil {
+		for _, e := range entries {
+			roots = append(roots, e.Name())
+		}
+	}
+	return roots
+}
+
+// cacheControl sets the configured Cache-Control of class on files the static handler serves.
+func cacheControl(class string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, services.CacheControl(class))
+		return nil
+	}
 }
 
 // Create a few default pages if they do not yet exist. If deleted by admin, they will not be recreated
@@ -848,6 +1365,75 @@ If problems persist, visit the Contact page and email us. Include your username
 			continue
 		}
 		// Create as published default
-		_ = pageRepo.Create(&models.Page{Slug: d.slug, Title: d.title, Markdown: d.md, HTML: "", IsPublished: true})
+		rendered, _ := services.RenderMarkdown(d.md)
+		_ = pageRepo.Create(&models.Page{Slug: d.slug, Title: d.title, Markdown: d.md, HTML: rendered, IsPublished: true})
+	}
+}
+
+// fatal logs msg at error level and exits, like log.Fatalf, skipping deferred cleanups.
+func fatal(msg string, args ...any) {
+	services.Logger(context.Background()).Error(msg, args...)
+	os.Exit(1)
+}
+
+// redisFromEnv connects to REDIS_URL when set; nil means in-process stores are used.
+func redisFromEnv() *redis.Client {
+	raw := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if raw == "" {
+		return nil
+	}
+	rc, err := services.NewRedisClientFromURL(raw)
+	if err != nil {
+		services.Logger(context.Background()).Warn("redis: falling back to in-process stores", "error", err)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := rc.Ping(ctx).Err(); err != nil {
+		services.Logger(ctx).Warn("redis: ping failed, falling back to in-process stores", "error", err)
+		return nil
+	}
+	return rc
+}
+
+// configureFeedCache enables the anonymous feed/image response cache.
+// FEED_CACHE_PAGES (default 3, 0 disables) and FEED_CACHE_TTL (default 30s) tune it.
+func configureFeedCache(rc *redis.Client) {
+	pages := 3
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("FEED_CACHE_PAGES"))); err == nil && v >= 0 {
+		pages = v
+	}
+	if pages == 0 {
+		return
+	}
+	ttl := 30 * time.Second
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("FEED_CACHE_TTL"))); err == nil && d > 0 {
+		ttl = d
+	}
+	if rc != nil {
+		services.ConfigureFeedCache(services.NewRedisCacheStore(rc, "trough:feedcache:"), "redis", pages, ttl)
+		return
+	}
+	services.ConfigureFeedCache(services.NewMemoryCacheStore(500), "memory", pages, ttl)
+}
+
+// configureAIRejectCache remembers the hashes of uploads AI detection rejected, so the same
+// file sent again is refused without another scan. AI_REJECT_CACHE_TTL (default 1h, 0
+// disables) sets how long a rejection is remembered.
+func configureAIRejectCache(rc *redis.Client) {
+	ttl := time.Hour
+	if v := strings.TrimSpace(os.Getenv("AI_REJECT_CACHE_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if v == "0" || (err == nil && d <= 0) {
+			return
+		}
+		if err == nil {
+			ttl = d
+		}
+	}
+	if rc != nil {
+		services.ConfigureAIRejectCache(services.NewRedisCacheStore(rc, "trough:aireject:"), "redis", ttl)
+		return
 	}
+	services.ConfigureAIRejectCache(services.NewMemoryCacheStore(5000), "memory", ttl)
 }
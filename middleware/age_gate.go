@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// AgeGate keeps the feed and image endpoints of an adult site from signed-out visitors
+// until they confirm the minimum age, answering 403 with age_gate set. Signed-in users
+// confirmed it when they registered.
+func AgeGate(settings models.SiteSettingsRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		set := SiteSettings(c, settings)
+		if !set.AdultSite || !isAgeGatedPath(c.Path()) {
+			return c.Next()
+		}
+		if services.VerifyAgeGate(c.Cookies(services.AgeGateCookie), set.MinimumAge, time.Now()) {
+			return c.Next()
+		}
+		if OptionalUserID(c) != uuid.Nil {
+			return c.Next()
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Please confirm your age to continue", "age_gate": true, "minimum_age": set.MinimumAge})
+	}
+}
+
+// isAgeGatedPath matches the endpoints that return images: the feeds, images, boards,
+// GraphQL and a user's images and collections.
+func isAgeGatedPath(p string) bool {
+	p = strings.ToLower(strings.TrimRight(p, "/"))
+	for _, prefix := range []string{"/api/feed", "/api/featured", "/api/images", "/api/boards", "/api/graphql"} {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	if rest, ok := strings.CutPrefix(p, "/api/users/"); ok {
+		return strings.HasSuffix(rest, "/images") || strings.HasSuffix(rest, "/collections")
+	}
+	if rest, ok := strings.CutPrefix(p, "/api/challenges/"); ok {
+		return strings.HasSuffix(rest, "/leaderboard")
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+func TestAgeGate(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 40))
+	services.UpdateCachedSettings(models.SiteSettings{AdultSite: true, MinimumAge: 18})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+
+	app := fiber.New()
+	app.Use(middleware.AgeGate(nil))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/feed", ok)
+	app.Get("/api/users/:name/images", ok)
+	app.Get("/api/users/:name", ok)
+
+	do := func(path string, prep func(*http.Request)) int {
+		req := httptest.NewRequest("GET", path, nil)
+		if prep != nil {
+			prep(req)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	withCookie := func(minAge int) func(*http.Request) {
+		return func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: services.AgeGateCookie, Value: services.SignAgeGate(minAge, time.Now().Add(time.Hour))})
+		}
+	}
+
+	assert.Equal(t, 403, do("/api/feed", nil))
+	assert.Equal(t, 403, do("/api/users/alice/images", nil))
+	assert.Equal(t, 200, do("/api/users/alice", nil), "profiles carry no images")
+	assert.Equal(t, 200, do("/api/feed", withCookie(18)))
+	assert.Equal(t, 403, do("/api/feed", withCookie(16)), "a lower confirmed age does not count")
+	assert.Equal(t, 200, do("/api/feed", func(r *http.Request) {
+		token, err := middleware.GenerateToken(uuid.New(), "alice")
+		assert.NoError(t, err)
+		r.Header.Set("Authorization", "Bearer "+token)
+	}), "signed-in users confirmed their age at registration")
+
+	services.UpdateCachedSettings(models.SiteSettings{MinimumAge: 18})
+	assert.Equal(t, 200, do("/api/feed", nil), "only adult sites gate signed-out visitors")
+}
+
+func TestAgeGatePerTenant(t *testing.T) {
+	services.UpdateCachedSettings(models.SiteSettings{MinimumAge: 16})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+	adult, age := true, 21
+	services.SetTenants([]models.Tenant{{ID: uuid.New(), Host: "after-dark.example.com", AdultSite: &adult, MinimumAge: &age}})
+	defer services.SetTenants(nil)
+
+	app := fiber.New()
+	app.Use(middleware.Tenant())
+	app.Use(middleware.AgeGate(nil))
+	app.Get("/api/feed", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	do := func(host string, confirmedAge int) int {
+		req := httptest.NewRequest("GET", "/api/feed", nil)
+		req.Host = host
+		if confirmedAge > 0 {
+			req.AddCookie(&http.Cookie{Name: services.AgeGateCookie, Value: services.SignAgeGate(confirmedAge, time.Now().Add(time.Hour))})
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, do("trough.example.com", 0), "the primary site is not an adult site")
+	assert.Equal(t, 403, do("after-dark.example.com", 0), "the tenant is")
+	assert.Equal(t, 403, do("after-dark.example.com", 18), "the tenant's own minimum age applies")
+	assert.Equal(t, 200, do("after-dark.example.com", 21))
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/services"
+)
+
+// AnonymousReadLimit applies anonymous_reads to signed-out GET requests for the feed and
+// image endpoints, using rl's counters. Signed-in users and verified crawlers are not
+// limited here.
+func AnonymousReadLimit(rl *services.RateLimiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := services.AnonymousReads()
+		if !cfg.Enabled || (c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead) || !isAnonymousReadPath(c.Path()) {
+			return c.Next()
+		}
+		if OptionalUserID(c) != uuid.Nil {
+			return c.Next()
+		}
+		ip := services.ClientIP(c)
+		if ip == "" || services.VerifiedCrawler(ip, c.Get(fiber.HeaderUserAgent)) != "" {
+			return c.Next()
+		}
+		if !rl.Allow("anon-burst:"+ip, cfg.Burst, cfg.BurstWindow) {
+			return anonymousReadDenied(c, cfg.BurstWindow.Seconds())
+		}
+		if !rl.Allow("anon:"+ip, cfg.Capacity, cfg.Window) {
+			return anonymousReadDenied(c, cfg.Window.Seconds())
+		}
+		return c.Next()
+	}
+}
+
+func anonymousReadDenied(c *fiber.Ctx, retryAfter float64) error {
+	services.RateLimitDenials.Inc("anonymous_read")
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many requests, sign in or slow down"})
+}
+
+// isAnonymousReadPath matches /api/feed and /api/images and everything below them.
+func isAnonymousReadPath(p string) bool {
+	p = strings.ToLower(p)
+	for _, prefix := range []string{"/api/feed", "/api/images"} {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
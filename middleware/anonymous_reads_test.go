@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+func TestAnonymousReadLimit(t *testing.T) {
+	services.SetAnonymousReads(services.AnonymousReadsConfig{Enabled: true, Capacity: 3, Window: time.Minute, Burst: 2, BurstWindow: 10 * time.Second})
+	defer services.SetAnonymousReads(services.AnonymousReadsConfig{})
+
+	rl := services.NewRateLimiter(services.RateLimitConfig{MaxEntries: 100, CleanupInterval: time.Minute, EntryTTL: time.Minute})
+	defer rl.Stop()
+
+	app := fiber.New()
+	app.Use(middleware.AnonymousReadLimit(rl))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/feed", ok)
+	app.Get("/api/images/:id", ok)
+	app.Get("/api/users/:name", ok)
+	app.Post("/api/images/:id", ok)
+
+	do := func(method, path string) (int, string) {
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		assert.NoError(t, err)
+		return resp.StatusCode, resp.Header.Get("Retry-After")
+	}
+
+	// Burst of two, shared across the feed and image endpoints
+	code, _ := do("GET", "/api/feed")
+	assert.Equal(t, 200, code)
+	code, _ = do("GET", "/api/images/abc")
+	assert.Equal(t, 200, code)
+	code, retry := do("GET", "/api/feed")
+	assert.Equal(t, 429, code)
+	assert.Equal(t, "10", retry)
+
+	// Other routes and methods are not limited here
+	code, _ = do("GET", "/api/users/alice")
+	assert.Equal(t, 200, code)
+	code, _ = do("POST", "/api/images/abc")
+	assert.Equal(t, 200, code)
+
+	services.SetAnonymousReads(services.AnonymousReadsConfig{})
+	code, _ = do("GET", "/api/feed")
+	assert.Equal(t, 200, code)
+}
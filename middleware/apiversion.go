@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CurrentAPIVersion is the only API version served. Unversioned /api paths are a
+// permanent alias for it, so existing SPA and bot clients keep working.
+const CurrentAPIVersion = "1"
+
+var supportedAPIVersions = []string{CurrentAPIVersion}
+
+// APIVersion maps /api/v1/... onto the unversioned routes and negotiates the version a
+// client asks for via the Accept-Version header. It must run before routing, so the
+// rewritten path is what CSRF skip lists, rate limiters and route matching see.
+func APIVersion() fiber.Handler {
+	prefix := "/api/v" + CurrentAPIVersion
+	return func(c *fiber.Ctx) error {
+		p := c.Path()
+		if p != "/api" && !strings.HasPrefix(p, "/api/") {
+			return c.Next()
+		}
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			c.Path("/api" + strings.TrimPrefix(p, prefix))
+		} else if v, ok := versionSegment(p); ok && v != CurrentAPIVersion {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unsupported API version", "supported": supportedAPIVersions})
+		}
+		if want := strings.TrimPrefix(strings.TrimSpace(c.Get("Accept-Version")), "v"); want != "" && want != CurrentAPIVersion {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported API version", "supported": supportedAPIVersions})
+		}
+		c.Set("API-Version", CurrentAPIVersion)
+		return c.Next()
+	}
+}
+
+// versionSegment reports the version named by a /api/vN path.
+func versionSegment(p string) (string, bool) {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(p, "/api/"), "/")
+	if len(seg) < 2 || seg[0] != 'v' {
+		return "", false
+	}
+	if _, err := strconv.Atoi(seg[1:]); err != nil {
+		return "", false
+	}
+	return seg[1:], true
+}
+
+// Deprecated marks a route as deprecated with RFC 9745 Deprecation and RFC 8594 Sunset
+// headers; link, when set, points clients at migration notes.
+func Deprecated(since, sunset time.Time, link string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+		if !sunset.IsZero() {
+			c.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if link != "" {
+			c.Set(fiber.HeaderLink, "<"+link+`>; rel="deprecation"`)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+)
+
+func TestAPIVersion(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.APIVersion())
+	app.Get("/api/images/:id", func(c *fiber.Ctx) error { return c.SendString(c.Path() + " " + c.Params("id")) })
+	app.Get("/other", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	get := func(path, accept string) (int, string, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Version", accept)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), resp.Header.Get("API-Version")
+	}
+
+	code, body, ver := get("/api/v1/images/abc", "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "/api/images/abc abc", body)
+	assert.Equal(t, "1", ver)
+
+	code, body, ver = get("/api/images/abc", "v1")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "/api/images/abc abc", body)
+	assert.Equal(t, "1", ver)
+
+	code, _, _ = get("/api/images/abc", "2")
+	assert.Equal(t, 400, code)
+
+	code, body, _ = get("/api/v2/images/abc", "")
+	assert.Equal(t, 404, code)
+	assert.Contains(t, body, "Unsupported API version")
+
+	code, _, ver = get("/other", "2")
+	assert.Equal(t, 200, code)
+	assert.Empty(t, ver)
+}
+
+func TestDeprecated(t *testing.T) {
+	app := fiber.New()
+	since := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+	app.Post("/api/old", middleware.Deprecated(since, sunset, "/api/openapi.json"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusGone)
+	})
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/old", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusGone, resp.StatusCode)
+	assert.Equal(t, "@1756684800", resp.Header.Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", resp.Header.Get("Sunset"))
+	assert.Equal(t, `</api/openapi.json>; rel="deprecation"`, resp.Header.Get("Link"))
+}
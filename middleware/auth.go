@@ -2,7 +2,8 @@ package middleware
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
@@ -12,19 +13,36 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
 )
 
 type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
+	// Impersonator is set on tokens an admin minted to act as UserID; the
+	// registered ID (jti) is then the impersonation session.
+	Impersonator *uuid.UUID `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Impersonation describes a request made by an admin acting as the token's user.
+type Impersonation struct {
+	SessionID uuid.UUID `json:"session_id"`
+	AdminID   uuid.UUID `json:"admin_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 func getJWTSecret() string {
 	// Do not provide a default. Startup must ensure JWT_SECRET is set.
 	return os.Getenv("JWT_SECRET")
 }
 
+// TokenLifetime is how long issued JWTs and the auth cookie last (auth.jwt_lifetime).
+var TokenLifetime = 24 * time.Hour
+
+// TokenMaxAge is TokenLifetime in seconds, for the auth cookie's MaxAge.
+func TokenMaxAge() int { return int(TokenLifetime.Seconds()) }
+
 func GenerateToken(userID uuid.UUID, username string) (string, error) {
 	secret := getJWTSecret()
 	if len(secret) < 32 {
@@ -34,7 +52,7 @@ func GenerateToken(userID uuid.UUID, username string) (string, error) {
 		UserID:   userID,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenLifetime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -44,6 +62,104 @@ func GenerateToken(userID uuid.UUID, username string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+// GenerateImpersonationToken mints a token for userID on behalf of adminID, valid until
+// expires and only while the impersonation session stays open.
+func GenerateImpersonationToken(userID uuid.UUID, username string, adminID, sessionID uuid.UUID, expires time.Time) (string, error) {
+	secret := getJWTSecret()
+	if len(secret) < 32 {
+		return "", errors.New("JWT secret not configured or too weak")
+	}
+	claims := Claims{
+		UserID:       userID,
+		Username:     username,
+		Impersonator: &adminID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID.String(),
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// RequestToken returns the request's bearer token, falling back to the auth cookie.
+func RequestToken(c *fiber.Ctx) string {
+	tokenString := c.Get("Authorization")
+	if tokenString != "" {
+		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+			tokenString = tokenString[7:]
+		}
+	}
+	// Treat placeholder tokens from localStorage (e.g., "null", "undefined") as missing
+	switch strings.ToLower(strings.TrimSpace(tokenString)) {
+	case "", "null", "undefined", "\"null\"", "\"undefined\"":
+		tokenString = ""
+	}
+	if tokenString == "" {
+		// Fallback to auth cookie if Authorization header is absent or placeholder
+		if v := c.Cookies("auth_token"); strings.TrimSpace(v) != "" {
+			tokenString = v
+		}
+	}
+	return tokenString
+}
+
+// ParseToken verifies a token's signature and expiry and returns its claims.
+func ParseToken(tokenString string) (*Claims, error) {
+	secret := getJWTSecret()
+	if len(secret) < 32 {
+		return nil, errors.New("JWT secret not configured or too weak")
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		// Enforce expected signing method
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// impersonationFor checks that an impersonation token's session is still open. Sessions
+// are looked up on every request so ending one takes effect at once; without a database
+// impersonation tokens are refused.
+func impersonationFor(claims *Claims) (*Impersonation, bool) {
+	sid, err := uuid.Parse(claims.ID)
+	if err != nil || claims.ExpiresAt == nil || models.DB() == nil {
+		return nil, false
+	}
+	s, err := models.NewAuditRepository(models.DB()).GetImpersonation(sid)
+	if err != nil || !s.Active() || s.UserID != claims.UserID || s.AdminID == nil || *s.AdminID != *claims.Impersonator {
+		return nil, false
+	}
+	return &Impersonation{SessionID: sid, AdminID: *claims.Impersonator, ExpiresAt: claims.ExpiresAt.Time}, true
+}
+
+// auditImpersonatedRequest records a request made with an impersonation token in the admin audit log.
+func auditImpersonatedRequest(c *fiber.Ctx, imp *Impersonation, userID uuid.UUID) {
+	entry := &models.AdminAudit{
+		ActorID:      &imp.AdminID,
+		Action:       models.AuditImpersonationRequest,
+		TargetUserID: &userID,
+		SessionID:    &imp.SessionID,
+		IP:           services.ClientIP(c),
+		Detail:       fmt.Sprintf("%s %s -> %d", c.Method(), c.Path(), c.Response().StatusCode()),
+		RequestID:    services.RequestIDFromContext(c.UserContext()),
+	}
+	if err := models.NewAuditRepository(models.DB()).Add(entry); err != nil {
+		slog.Error("auth: impersonation audit failed", "session_id", imp.SessionID.String(), "error", err)
+	}
+}
+
 func Protected() fiber.Handler {
 	// Small cache for password_changed_at to reduce DB lookups on hot path
 	// Short TTL preserves security while improving performance.
@@ -84,7 +200,7 @@ func Protected() fiber.Handler {
 			changedAt = dbChangedAt
 		case <-time.After(5 * time.Second):
 			// If DB query times out, use default value to prevent hanging
-			log.Printf("Auth middleware: password_changed_at query timeout for user: %s", userID)
+			slog.Warn("auth: password_changed_at query timed out", "user_id", userID.String())
 			changedAt = time.Time{}
 		}
 		
@@ -98,52 +214,18 @@ func Protected() fiber.Handler {
 		return changedAt
 	}
 	return func(c *fiber.Ctx) error {
-		tokenString := c.Get("Authorization")
-		if tokenString != "" {
-			if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-				tokenString = tokenString[7:]
-			}
-		}
-		// Treat placeholder tokens from localStorage (e.g., "null", "undefined") as missing
-		switch strings.ToLower(strings.TrimSpace(tokenString)) {
-		case "", "null", "undefined", "\"null\"", "\"undefined\"":
-			tokenString = ""
-		}
-		if tokenString == "" {
-			// Fallback to auth cookie if Authorization header is absent or placeholder
-			if v := c.Cookies("auth_token"); strings.TrimSpace(v) != "" {
-				tokenString = v
-			}
-		}
+		tokenString := RequestToken(c)
 		if tokenString == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization token"})
 		}
 
-		secret := getJWTSecret()
-		if len(secret) < 32 {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Enforce expected signing method
-			if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-				return nil, errors.New("invalid signing method")
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseToken(tokenString)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid token",
 			})
 		}
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token claims",
-			})
-		}
-
 		// Optional token invalidation on password change: reject if token iat < password_changed_at
 		if claims.IssuedAt != nil {
 			changedAt := getChangedAt(claims.UserID)
@@ -154,11 +236,56 @@ func Protected() fiber.Handler {
 		c.Locals("user_id", claims.UserID)
 		c.Locals("username", claims.Username)
 
+		if claims.Impersonator != nil {
+			imp, ok := impersonationFor(claims)
+			if !ok {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Impersonation session ended"})
+			}
+			c.Locals("impersonation", imp)
+			// Audited here, so not again by ImpersonationAudit
+			c.Locals("impersonation_audit", nil)
+			err := c.Next()
+			auditImpersonatedRequest(c, imp, claims.UserID)
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// GetImpersonation returns the admin impersonation behind the request, or nil.
+func GetImpersonation(c *fiber.Ctx) *Impersonation {
+	imp, _ := c.Locals("impersonation").(*Impersonation)
+	return imp
+}
+
+// NoImpersonation refuses requests made with an impersonation token, for account
+// changes an admin must not make on a user's behalf.
+func NoImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if GetImpersonation(c) != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not allowed while impersonating"})
+		}
 		return c.Next()
 	}
 }
 
+// OptionalUserID returns the user behind the request's token, or uuid.Nil when there is
+// none. An impersonation token counts only while its session is open, like in Protected,
+// and the request is then audited by ImpersonationAudit. The result is kept for the rest
+// of the request so the session is looked up once.
 func OptionalUserID(c *fiber.Ctx) uuid.UUID {
+	if uid := GetUserID(c); uid != uuid.Nil {
+		return uid
+	}
+	if uid, ok := c.Locals("optional_user_id").(uuid.UUID); ok {
+		return uid
+	}
+	uid := optionalUserID(c)
+	c.Locals("optional_user_id", uid)
+	return uid
+}
+
+func optionalUserID(c *fiber.Ctx) uuid.UUID {
 	tokenString := c.Get("Authorization")
 	if tokenString != "" && len(tokenString) > 7 && tokenString[:7] == "Bearer " {
 		tokenString = tokenString[7:]
@@ -185,10 +312,33 @@ func OptionalUserID(c *fiber.Ctx) uuid.UUID {
 	if err != nil || !token.Valid {
 		return uuid.Nil
 	}
-	if claims, ok := token.Claims.(*Claims); ok {
-		return claims.UserID
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return uuid.Nil
+	}
+	if claims.Impersonator != nil {
+		imp, ok := impersonationFor(claims)
+		if !ok {
+			return uuid.Nil
+		}
+		c.Locals("impersonation", imp)
+		c.Locals("impersonation_audit", claims.UserID)
+	}
+	return claims.UserID
+}
+
+// ImpersonationAudit records requests outside Protected that were answered for an
+// impersonated user through OptionalUserID, once the response status is known.
+func ImpersonationAudit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if uid, ok := c.Locals("impersonation_audit").(uuid.UUID); ok {
+			if imp := GetImpersonation(c); imp != nil {
+				auditImpersonatedRequest(c, imp, uid)
+			}
+		}
+		return err
 	}
-	return uuid.Nil
 }
 
 func GetUserID(c *fiber.Ctx) uuid.UUID {
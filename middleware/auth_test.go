@@ -0,0 +1,41 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+)
+
+func TestOptionalUserIDRefusesUnverifiedImpersonation(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 40))
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(middleware.OptionalUserID(c).String())
+	})
+	viewer := func(token string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	uid := uuid.New()
+	token, err := middleware.GenerateToken(uid, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, uid.String(), viewer(token))
+
+	// Without a database the session can't be found open, so the token is anonymous
+	token, err = middleware.GenerateImpersonationToken(uid, "alice", uuid.New(), uuid.New(), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.Nil.String(), viewer(token))
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+// RequireConsent refuses state-changing requests from signed-in users who have not
+// accepted the terms and privacy revisions the request's site requires, with 451 and the
+// versions to accept. Reading, signing out, accepting and deleting the account stay open.
+func RequireConsent(settings models.SiteSettingsRepositoryInterface, legal models.LegalRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if consentExempt(c.Method(), c.Path()) {
+			return c.Next()
+		}
+		required := SiteSettings(c, settings).RequiredLegalVersions()
+		if !required.Tracked() {
+			return c.Next()
+		}
+		uid := OptionalUserID(c)
+		if uid == uuid.Nil {
+			return c.Next()
+		}
+		site := legal
+		if id := TenantID(c); id != nil {
+			site = legal.ForTenant(id)
+		}
+		accepted, err := site.Accepted(uid)
+		if err != nil || accepted.Satisfies(required) {
+			return c.Next()
+		}
+		return c.Status(fiber.StatusUnavailableForLegalReasons).JSON(fiber.Map{
+			"error":            "Please accept the updated terms to continue",
+			"consent_required": true,
+			"terms_revision":   required.Terms,
+			"privacy_revision": required.Privacy,
+		})
+	}
+}
+
+func consentExempt(method, path string) bool {
+	p := strings.TrimRight(strings.ToLower(path), "/")
+	switch p {
+	case "/api/me/consent", "/api/login", "/api/logout", "/api/register", "/api/csp-report", "/api/me/impersonation/end":
+		return true
+	case "/api/me":
+		return method == fiber.MethodDelete
+	}
+	return false
+}
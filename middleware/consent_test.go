@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+type fakeLegalRepo struct {
+	models.LegalRepositoryInterface
+	accepted map[uuid.UUID]models.LegalVersions
+	tenants  map[uuid.UUID]*fakeLegalRepo
+}
+
+func (f *fakeLegalRepo) Accepted(id uuid.UUID) (models.LegalVersions, error) {
+	return f.accepted[id], nil
+}
+
+func (f *fakeLegalRepo) ForTenant(tenant *uuid.UUID) models.LegalRepositoryInterface {
+	return f.tenants[*tenant]
+}
+
+func TestRequireConsent(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 40))
+	services.UpdateCachedSettings(models.SiteSettings{TermsRevision: 3, PrivacyRevision: 2})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+
+	current, behind := uuid.New(), uuid.New()
+	legal := &fakeLegalRepo{accepted: map[uuid.UUID]models.LegalVersions{
+		current: {Terms: 3, Privacy: 2},
+		behind:  {Terms: 2, Privacy: 2},
+	}}
+	app := fiber.New()
+	app.Use(middleware.RequireConsent(nil, legal))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/feed", ok)
+	app.Post("/api/images", ok)
+	app.Post("/api/me/consent", ok)
+	app.Delete("/api/me", ok)
+
+	do := func(method, path string, user uuid.UUID) int {
+		req := httptest.NewRequest(method, path, nil)
+		if user != uuid.Nil {
+			token, err := middleware.GenerateToken(user, "u")
+			assert.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, do("POST", "/api/images", current))
+	assert.Equal(t, 200, do("POST", "/api/images", uuid.Nil), "signed-out requests are left to the route")
+	assert.Equal(t, 451, do("POST", "/api/images", behind))
+	assert.Equal(t, 200, do("GET", "/api/feed", behind), "reading needs no consent")
+	assert.Equal(t, 200, do("POST", "/api/me/consent", behind))
+	assert.Equal(t, 200, do("DELETE", "/api/me", behind), "leaving needs no consent")
+
+	// Untracking privacy leaves only the terms to match
+	services.UpdateCachedSettings(models.SiteSettings{TermsRevision: 3})
+	legal.accepted[behind] = models.LegalVersions{Terms: 3}
+	assert.Equal(t, 200, do("POST", "/api/images", behind))
+	services.UpdateCachedSettings(models.SiteSettings{})
+	legal.accepted[behind] = models.LegalVersions{}
+	assert.Equal(t, 200, do("POST", "/api/images", behind))
+}
+
+func TestRequireConsentPerTenant(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 40))
+	services.UpdateCachedSettings(models.SiteSettings{TermsRevision: 3})
+	defer services.UpdateCachedSettings(models.SiteSettings{})
+	tenant := models.Tenant{ID: uuid.New(), Host: "art.example.com", TermsRevision: 1}
+	services.SetTenants([]models.Tenant{tenant})
+	defer services.SetTenants(nil)
+
+	user := uuid.New()
+	legal := &fakeLegalRepo{
+		accepted: map[uuid.UUID]models.LegalVersions{user: {Terms: 3}},
+		tenants:  map[uuid.UUID]*fakeLegalRepo{tenant.ID: {accepted: map[uuid.UUID]models.LegalVersions{}}},
+	}
+	app := fiber.New()
+	app.Use(middleware.Tenant())
+	app.Use(middleware.RequireConsent(nil, legal))
+	app.Post("/api/images", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	do := func(host string) int {
+		req := httptest.NewRequest("POST", "/api/images", nil)
+		req.Host = host
+		token, err := middleware.GenerateToken(user, "u")
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, do("trough.example.com"))
+	assert.Equal(t, 451, do("art.example.com"), "the tenant's own terms are not accepted yet")
+	legal.tenants[tenant.ID].accepted[user] = models.LegalVersions{Terms: 1}
+	assert.Equal(t, 200, do("art.example.com"))
+}
@@ -1,152 +1,299 @@
 package middleware
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// CSRF tokens are double-submit: the csrf_token cookie and the X-CSRF-Token header (or
+// csrf_token form field) must carry the same value. Tokens are also signed together with
+// the auth_token cookie they were issued for, so a token planted before sign-in or taken
+// from another session does not validate. Any response that changes the auth cookie
+// (sign-in, sign-out, impersonation) rotates the token. Requests authenticated with an
+// Authorization: Bearer header carry no ambient credentials and are exempt.
+
+// CSRF failure reasons, as counted in CSRFStats and trough_csrf_failures_total.
+const (
+	CSRFMissingCookie = "missing_cookie"
+	CSRFMissingToken  = "missing_token"
+	CSRFMismatch      = "mismatch"
+	CSRFWrongSession  = "wrong_session"
+	CSRFInvalidForm   = "invalid_form_token"
+)
+
+const (
+	csrfFormPrefix    = "f."
+	csrfSessionCookie = "auth_token"
+	csrfFormFieldName = "csrf_token"
+	csrfNonceBytes    = 16
 )
 
 // CSRFProtection provides CSRF protection middleware
 type CSRFProtection struct {
-	secretKey   []byte
-	cookieName  string
-	headerName  string
-	expiry      time.Duration
+	secretKey    []byte
+	cookieName   string
+	headerName   string
+	expiry       time.Duration
 	isProduction bool
+
+	mu        sync.Mutex
+	failures  map[string]int64
+	rotations int64
+}
+
+// CSRFStats counts rejected requests by reason and tokens rotated after a session change.
+type CSRFStats struct {
+	Failures  map[string]int64 `json:"failures"`
+	Rotations int64            `json:"rotations"`
 }
 
-// NewCSRFProtection creates a new CSRF protection middleware
+// NewCSRFProtection creates a new CSRF protection middleware. Without a secret, one is
+// derived from JWT_SECRET so every instance signs tokens alike.
 func NewCSRFProtection(secretKey string) *CSRFProtection {
+	if secretKey == "" {
+		if jwt := getJWTSecret(); len(jwt) >= 32 {
+			sum := sha256.Sum256([]byte("csrf:" + jwt))
+			secretKey = string(sum[:])
+		}
+	}
 	if secretKey == "" {
 		// Generate a random secret if none provided
 		secret := make([]byte, 32)
 		rand.Read(secret)
 		secretKey = string(secret)
 	}
-	
+
 	return &CSRFProtection{
-		secretKey:   []byte(secretKey),
-		cookieName:  "csrf_token",
-		headerName:  "X-CSRF-Token",
-		expiry:      24 * time.Hour,
+		secretKey:    []byte(secretKey),
+		cookieName:   "csrf_token",
+		headerName:   "X-CSRF-Token",
+		expiry:       24 * time.Hour,
 		isProduction: os.Getenv("GO_ENV") == "production" || os.Getenv("ENVIRONMENT") == "production",
+		failures:     map[string]int64{},
 	}
 }
 
-// GenerateToken generates a new simple CSRF token
+func (cp *CSRFProtection) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, cp.secretKey)
+	mac.Write([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateToken generates a new CSRF token for a request without a session.
 func (cp *CSRFProtection) GenerateToken() (string, error) {
-	token := make([]byte, 32)
-	if _, err := rand.Read(token); err != nil {
+	return cp.generateToken("")
+}
+
+// generateToken issues "<nonce>.<mac>", with the MAC over the nonce and session, the
+// auth cookie value ("" when signed out).
+func (cp *CSRFProtection) generateToken(session string) (string, error) {
+	nonce := make([]byte, csrfNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
-	return hex.EncodeToString(token), nil
+	n := hex.EncodeToString(nonce)
+	return n + "." + cp.sign("session", n, session), nil
 }
 
-// ValidateToken validates a CSRF token (simple approach - just check basic structure)
+// ValidateToken reports whether token is well formed. Use validFor to check its binding.
 func (cp *CSRFProtection) ValidateToken(token string) bool {
-	if token == "" {
+	nonce, mac, ok := strings.Cut(token, ".")
+	return ok && len(nonce) == 2*csrfNonceBytes && len(mac) == 2*sha256.Size && isHex(nonce) && isHex(mac)
+}
+
+// validFor reports whether token was issued for session.
+func (cp *CSRFProtection) validFor(token, session string) bool {
+	if !cp.ValidateToken(token) {
+		return false
+	}
+	nonce, mac, _ := strings.Cut(token, ".")
+	return hmac.Equal([]byte(mac), []byte(cp.sign("session", nonce, session)))
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// FormToken issues a token that is only good for one signed-in session and one form, the
+// method and path it submits to, until the CSRF expiry. It is sent in place of the
+// double-submit token and does not need the csrf_token cookie.
+func (cp *CSRFProtection) FormToken(c *fiber.Ctx, method, path string) (string, error) {
+	session := c.Cookies(csrfSessionCookie)
+	if session == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Form tokens need a session")
+	}
+	nonce := make([]byte, csrfNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	n := hex.EncodeToString(nonce)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	return csrfFormPrefix + ts + "." + n + "." + cp.sign("form", ts, n, session, strings.ToUpper(method)+" "+path), nil
+}
+
+func (cp *CSRFProtection) validForm(c *fiber.Ctx, token string) bool {
+	// "f.<issued unix>.<nonce>.<mac>"
+	parts := strings.Split(strings.TrimPrefix(token, csrfFormPrefix), ".")
+	session := c.Cookies(csrfSessionCookie)
+	if len(parts) != 3 || session == "" {
 		return false
 	}
-	
-	// Simple validation: check if it's reasonable length for either hex (64) or base64 (88)
-	length := len(token)
-	if length != 64 && length != 88 {
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Since(time.Unix(issued, 0)) > cp.expiry {
 		return false
 	}
-	
-	// Basic character validation - should be alphanumeric with common safe chars
-	for _, char := range token {
-		if !(('a' <= char && char <= 'z') ||
-			('A' <= char && char <= 'Z') ||
-			('0' <= char && char <= '9') ||
-			char == '_' || char == '-' || char == '=' || char == '+' || char == '/') {
-			return false
+	want := cp.sign("form", parts[0], parts[1], session, c.Method()+" "+c.Path())
+	return hmac.Equal([]byte(parts[2]), []byte(want))
+}
+
+// hasBearer reports whether the request authenticates with a valid Authorization header.
+// Browsers never attach one on their own, so such requests cannot be forged cross-site.
+func hasBearer(c *fiber.Ctx) bool {
+	h := c.Get(fiber.HeaderAuthorization)
+	if len(h) <= 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return false
+	}
+	_, err := ParseToken(strings.TrimSpace(h[7:]))
+	return err == nil
+}
+
+// csrfExempt lists requests that need no token: safe methods, sign-in and recovery flows
+// (which have no session to ride on yet) and public endpoints.
+func csrfExempt(c *fiber.Ctx) bool {
+	method := c.Method()
+	if method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions {
+		return true
+	}
+	path := c.Path()
+	for _, prefix := range []string{
+		"/api/register", "/api/login", "/api/logout", "/api/forgot-password", "/api/reset-password",
+		"/api/verify-email", "/api/confirm-email-change", "/api/cancel-email-change",
+		"/api/validate-invite", "/api/me/resend-verification",
+	} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	// GraphQL is query-only; browsers post CSP reports without credentials
+	return strings.Contains(path, "/send-verification") || strings.HasPrefix(path, "/api/feed") || path == "/api/graphql" || path == services.CSPReportPath
+}
+
+// check returns the failure reason for a request, or "" if it may proceed.
+func (cp *CSRFProtection) check(c *fiber.Ctx) string {
+	if csrfExempt(c) || hasBearer(c) {
+		return ""
+	}
+
+	// Get token from request (header or form)
+	requestToken := c.Get(cp.headerName)
+	if requestToken == "" && c.Method() == fiber.MethodPost {
+		// Try to get from form data for multipart forms
+		requestToken = c.FormValue(csrfFormFieldName)
+	}
+	if strings.HasPrefix(requestToken, csrfFormPrefix) {
+		if cp.validForm(c, requestToken) {
+			return ""
 		}
+		return CSRFInvalidForm
 	}
-	
-	return true
+
+	cookieToken := c.Cookies(cp.cookieName)
+	switch {
+	case cookieToken == "":
+		return CSRFMissingCookie
+	case requestToken == "":
+		return CSRFMissingToken
+	case !hmac.Equal([]byte(cookieToken), []byte(requestToken)) || !cp.ValidateToken(cookieToken):
+		return CSRFMismatch
+	case !cp.validFor(cookieToken, c.Cookies(csrfSessionCookie)):
+		return CSRFWrongSession
+	}
+	return ""
+}
+
+func (cp *CSRFProtection) recordFailure(reason string) {
+	cp.mu.Lock()
+	cp.failures[reason]++
+	cp.mu.Unlock()
+	services.CSRFFailures.Inc(reason)
+}
+
+// Stats returns failure and rotation counts since startup.
+func (cp *CSRFProtection) Stats() CSRFStats {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	out := CSRFStats{Failures: make(map[string]int64, len(cp.failures)), Rotations: cp.rotations}
+	for k, v := range cp.failures {
+		out.Failures[k] = v
+	}
+	return out
 }
 
 // Middleware returns the CSRF protection middleware
 func (cp *CSRFProtection) Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Skip CSRF for GET, HEAD, OPTIONS
-		if c.Method() == "GET" || c.Method() == "HEAD" || c.Method() == "OPTIONS" {
-			return c.Next()
-		}
-		
-		// Skip CSRF for authentication endpoints
-		path := c.Path()
-		if strings.HasPrefix(path, "/api/register") || 
-		   strings.HasPrefix(path, "/api/login") ||
-		   strings.HasPrefix(path, "/api/logout") ||
-		   strings.HasPrefix(path, "/api/forgot-password") ||
-		   strings.HasPrefix(path, "/api/reset-password") ||
-		   strings.HasPrefix(path, "/api/verify-email") ||
-		   strings.HasPrefix(path, "/api/validate-invite") ||
-		   strings.HasPrefix(path, "/api/me/resend-verification") ||
-		   strings.Contains(path, "/send-verification") {
-			return c.Next()
-		}
-		
-		// Skip CSRF for public endpoints
-		if strings.HasPrefix(path, "/api/feed") || 
-		   strings.HasPrefix(path, "/api/images/") && c.Method() == "GET" ||
-		   strings.HasPrefix(path, "/api/users/") && c.Method() == "GET" ||
-		   strings.HasPrefix(path, "/api/site") && c.Method() == "GET" {
-			return c.Next()
-		}
-		
-		// Get token from cookie
-		cookieToken := c.Cookies(cp.cookieName)
-		if cookieToken == "" {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "CSRF token missing",
-			})
-		}
-		
-		// Get token from request (header or form)
-		requestToken := c.Get(cp.headerName)
-		if requestToken == "" && c.Method() == "POST" {
-			// Try to get from form data for multipart forms
-			requestToken = c.FormValue("csrf_token")
-		}
-		
-		if requestToken == "" {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "CSRF token required",
-			})
-		}
-		
-		// Simple validation: check if tokens match and are valid format
-		if cookieToken == requestToken && cp.ValidateToken(cookieToken) {
-			return c.Next()
+		if reason := cp.check(c); reason != "" {
+			cp.recordFailure(reason)
+			msg := "Invalid CSRF token"
+			switch reason {
+			case CSRFMissingCookie:
+				msg = "CSRF token missing"
+			case CSRFMissingToken:
+				msg = "CSRF token required"
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": msg})
 		}
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Invalid CSRF token",
-		})
+		err := c.Next()
+		cp.rotateOnSessionChange(c)
+		return err
 	}
 }
 
-// SetCSRFToken sets the CSRF token in the response
-func (cp *CSRFProtection) SetCSRFToken(c *fiber.Ctx) error {
-	token, err := cp.GenerateToken()
+// rotateOnSessionChange issues a token for the new session when the response sets or
+// clears the auth cookie.
+func (cp *CSRFProtection) rotateOnSessionChange(c *fiber.Ctx) {
+	raw := c.Response().Header.PeekCookie(csrfSessionCookie)
+	if raw == nil {
+		return
+	}
+	// "auth_token=<value>; path=/; ..."; clearing the cookie sends an empty value
+	first, _, _ := strings.Cut(string(raw), ";")
+	_, session, _ := strings.Cut(first, "=")
+	if _, err := cp.issue(c, strings.TrimSpace(session)); err != nil {
+		services.Logger(c.Context()).Warn("csrf token rotation failed", "error", err)
+		return
+	}
+	cp.mu.Lock()
+	cp.rotations++
+	cp.mu.Unlock()
+}
+
+// issue sets a new token bound to session in the cookie and X-CSRF-Token header.
+func (cp *CSRFProtection) issue(c *fiber.Ctx, session string) (string, error) {
+	token, err := cp.generateToken(session)
 	if err != nil {
-		return err
+		return "", err
 	}
-	
+
 	// Set token in cookie with security flags
 	secure := cp.isProduction
 	sameSite := "Lax"
 	if cp.isProduction {
 		sameSite = "Strict"
 	}
-	
-	cookie := &fiber.Cookie{
+
+	c.Cookie(&fiber.Cookie{
 		Name:     cp.cookieName,
 		Value:    token,
 		Expires:  time.Now().Add(cp.expiry),
@@ -154,14 +301,17 @@ func (cp *CSRFProtection) SetCSRFToken(c *fiber.Ctx) error {
 		Secure:   secure,
 		SameSite: sameSite,
 		Path:     "/",
-	}
-	
-	c.Cookie(cookie)
-	
+	})
+
 	// Also set token in header for easy access by frontend
-	c.Set("X-CSRF-Token", token)
-	
-	return nil
+	c.Set(cp.headerName, token)
+	return token, nil
+}
+
+// SetCSRFToken sets a new CSRF token for the request's session in the response
+func (cp *CSRFProtection) SetCSRFToken(c *fiber.Ctx) error {
+	_, err := cp.issue(c, c.Cookies(csrfSessionCookie))
+	return err
 }
 
 // GetCSRFToken returns the current CSRF token
@@ -169,12 +319,21 @@ func (cp *CSRFProtection) GetCSRFToken(c *fiber.Ctx) string {
 	return c.Cookies(cp.cookieName)
 }
 
+// EnsureToken returns the request's CSRF token if it is valid for the current session, and
+// otherwise issues a new one.
+func (cp *CSRFProtection) EnsureToken(c *fiber.Ctx) (string, error) {
+	if token := cp.GetCSRFToken(c); cp.validFor(token, c.Cookies(csrfSessionCookie)) {
+		return token, nil
+	}
+	return cp.issue(c, c.Cookies(csrfSessionCookie))
+}
+
 // RequireCSRF is a convenience middleware that ensures CSRF token is set
 func (cp *CSRFProtection) RequireCSRF() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Set CSRF token for GET requests
-		if c.Method() == "GET" {
-			if err := cp.SetCSRFToken(c); err != nil {
+		if c.Method() == fiber.MethodGet {
+			if _, err := cp.EnsureToken(c); err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 					"error": "Failed to generate CSRF token",
 				})
@@ -182,4 +341,4 @@ func (cp *CSRFProtection) RequireCSRF() fiber.Handler {
 		}
 		return c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,140 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+)
+
+func csrfApp(cp *middleware.CSRFProtection) *fiber.App {
+	app := fiber.New()
+	app.Use(cp.Middleware())
+	app.Get("/api/csrf", func(c *fiber.Ctx) error {
+		token, err := cp.EnsureToken(c)
+		if err != nil {
+			return err
+		}
+		out := fiber.Map{"csrf_token": token}
+		if form := c.Query("form"); form != "" {
+			method, path, _ := strings.Cut(form, " ")
+			ft, err := cp.FormToken(c, method, path)
+			if err != nil {
+				return err
+			}
+			out["form_token"] = ft
+		}
+		return c.JSON(out)
+	})
+	app.Post("/api/login", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{Name: "auth_token", Value: "session-2", Path: "/"})
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Post("/api/me/profile", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	app.Post("/api/upload", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	return app
+}
+
+func cookieFrom(resp *http.Response, name string) string {
+	for _, ck := range resp.Cookies() {
+		if ck.Name == name {
+			return ck.Value
+		}
+	}
+	return ""
+}
+
+func TestCSRFSessionBindingAndRotation(t *testing.T) {
+	cp := middleware.NewCSRFProtection("test-secret")
+	app := csrfApp(cp)
+
+	post := func(path, session, cookieToken, headerToken string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: session})
+		}
+		if cookieToken != "" {
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: cookieToken})
+		}
+		if headerToken != "" {
+			req.Header.Set("X-CSRF-Token", headerToken)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// Token issued for session-1
+	req := httptest.NewRequest("GET", "/api/csrf", nil)
+	req.AddCookie(&http.Cookie{Name: "auth_token", Value: "session-1"})
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	token := cookieFrom(resp, "csrf_token")
+	assert.NotEmpty(t, token)
+
+	assert.Equal(t, 204, post("/api/me/profile", "session-1", token, token))
+	assert.Equal(t, 403, post("/api/me/profile", "session-1", token, ""), "missing header")
+	assert.Equal(t, 403, post("/api/me/profile", "session-1", token, strings.Repeat("a", 64)), "mismatch")
+	assert.Equal(t, 403, post("/api/me/profile", "session-other", token, token), "token from another session")
+	assert.Equal(t, 403, post("/api/me/profile", "", token, token), "token planted before sign-in")
+
+	// Signing in rotates the token to the new session
+	resp, err = app.Test(httptest.NewRequest("POST", "/api/login", nil))
+	assert.NoError(t, err)
+	rotated := cookieFrom(resp, "csrf_token")
+	assert.NotEmpty(t, rotated)
+	assert.Equal(t, rotated, resp.Header.Get("X-CSRF-Token"))
+	assert.Equal(t, 204, post("/api/me/profile", "session-2", rotated, rotated))
+
+	stats := cp.Stats()
+	assert.Equal(t, int64(1), stats.Rotations)
+	assert.Equal(t, int64(1), stats.Failures[middleware.CSRFMissingToken])
+	assert.Equal(t, int64(1), stats.Failures[middleware.CSRFMismatch])
+	assert.Equal(t, int64(2), stats.Failures[middleware.CSRFWrongSession])
+}
+
+func TestCSRFBearerExemptAndFormTokens(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 40))
+	cp := middleware.NewCSRFProtection("")
+	app := csrfApp(cp)
+
+	jwt, err := middleware.GenerateToken(uuid.New(), "bot")
+	assert.NoError(t, err)
+	req := httptest.NewRequest("POST", "/api/me/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode, "bearer clients need no CSRF token")
+
+	req = httptest.NewRequest("POST", "/api/me/profile", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	// A form token is good for its own form only
+	req = httptest.NewRequest("GET", "/api/csrf?form=POST%20/api/upload", nil)
+	req.AddCookie(&http.Cookie{Name: "auth_token", Value: "session-1"})
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	var body struct {
+		FormToken string `json:"form_token"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.True(t, strings.HasPrefix(body.FormToken, "f."))
+
+	for path, want := range map[string]int{"/api/upload": 204, "/api/me/profile": 403} {
+		req = httptest.NewRequest("POST", path, nil)
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: "session-1"})
+		req.Header.Set("X-CSRF-Token", body.FormToken)
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, want, resp.StatusCode, path)
+	}
+}
@@ -2,12 +2,12 @@ package middleware
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yourusername/trough/db"
+	"github.com/yourusername/trough/services"
 )
 
 // DBPing middleware checks the database connection before proceeding.
@@ -19,14 +19,15 @@ func DBPing() fiber.Handler {
 		defer cancel()
 
 		if err := db.Ping(ctx); err != nil {
-			log.Printf("Database ping failed or timed out: %v. Attempting to reconnect...", err)
+			logger := services.Logger(c.Context())
+			logger.Warn("database ping failed, reconnecting", "error", err)
 			if reconErr := db.Reconnect(); reconErr != nil {
-				log.Printf("Failed to reconnect to database: %v", reconErr)
+				logger.Error("database reconnect failed", "error", reconErr)
 				return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
 					"error": "Database connection is down",
 				})
 			}
-			log.Println("Successfully reconnected to the database.")
+			logger.Info("reconnected to the database")
 		}
 		return c.Next()
 	}
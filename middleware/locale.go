@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// Locale negotiates the request's language from Accept-Language, falling back to the
+// site default, and translates the "error" message of JSON error responses into it.
+// Handlers read the choice with GetLocale for anything else they render.
+func Locale(settings models.SiteSettingsRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		fallback := services.SiteLocale(SiteSettings(c, settings))
+		locale := services.NegotiateLocale(c.Get(fiber.HeaderAcceptLanguage), fallback)
+		c.Locals("locale", locale)
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() >= 400 {
+			c.Vary(fiber.HeaderAcceptLanguage)
+			if locale != services.BaseLocale {
+				translateErrorBody(c, locale)
+			}
+		}
+		return nil
+	}
+}
+
+// GetLocale returns the request's negotiated locale.
+func GetLocale(c *fiber.Ctx) string {
+	if l, ok := c.Locals("locale").(string); ok && l != "" {
+		return l
+	}
+	return services.BaseLocale
+}
+
+func translateErrorBody(c *fiber.Ctx, locale string) {
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(c.Response().Body(), &body); err != nil {
+		return
+	}
+	var msg string
+	if err := json.Unmarshal(body["error"], &msg); err != nil || msg == "" {
+		return
+	}
+	translated := services.T(locale, msg)
+	if translated == msg {
+		return
+	}
+	body["error"], _ = json.Marshal(translated)
+	out, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	c.Response().SetBodyRaw(out)
+	c.Set(fiber.HeaderContentLanguage, locale)
+}
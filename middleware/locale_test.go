@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+)
+
+func TestLocaleTranslatesErrors(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.Locale(nil))
+	app.Get("/denied", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden", "code": 7})
+	})
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"error": "Forbidden", "locale": middleware.GetLocale(c)})
+	})
+
+	get := func(path, lang string) (string, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header.Get("Content-Language")
+	}
+
+	body, lang := get("/denied", "es-ES,es;q=0.9")
+	assert.JSONEq(t, `{"error":"Prohibido","code":7}`, body)
+	assert.Equal(t, "es", lang)
+
+	body, lang = get("/denied", "")
+	assert.JSONEq(t, `{"error":"Forbidden","code":7}`, body)
+	assert.Equal(t, "", lang)
+
+	// Successful responses are left alone
+	body, _ = get("/ok", "de")
+	assert.JSONEq(t, `{"error":"Forbidden","locale":"de"}`, body)
+}
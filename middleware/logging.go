@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/services"
+)
+
+// RequestLogger assigns every request an ID (reusing a valid incoming X-Request-ID),
+// echoes it in the X-Request-ID response header and in JSON error bodies, and writes one
+// structured log record per request. Requests for which skip returns true get an ID but
+// no log record. It must run after compression so error bodies are still plain JSON.
+func RequestLogger(skip func(*fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		id := c.Get(fiber.HeaderXRequestID)
+		if !services.ValidRequestID(id) {
+			id = services.NewRequestID()
+		}
+		c.Locals(services.RequestIDContextKey, id)
+		c.SetUserContext(context.WithValue(c.UserContext(), services.RequestIDContextKey, id))
+		c.Set(fiber.HeaderXRequestID, id)
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The app error handler writes the response (with the ID) after middleware unwinds
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		} else if status >= 400 {
+			injectRequestID(c, id)
+		}
+		if skip != nil && skip(c) {
+			return err
+		}
+
+		route := ""
+		if r := c.Route(); r != nil {
+			route = r.Path
+		}
+		ip := services.ClientIP(c)
+		attrs := []any{
+			"method", c.Method(),
+			"path", c.Path(),
+			"route", route,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", ip,
+			"bytes", responseSize(c),
+		}
+		// Behind a proxy, also record the hop that connected to us
+		if peer := c.Context().RemoteIP().String(); peer != ip {
+			attrs = append(attrs, "peer_ip", peer)
+		}
+		if uid := GetUserID(c); uid != uuid.Nil {
+			attrs = append(attrs, "user_id", uid.String())
+		}
+		if err != nil {
+			attrs = append(attrs, "error", err.Error())
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		} else if status >= 400 {
+			level = slog.LevelWarn
+		}
+		services.Logger(c.Context()).Log(c.Context(), level, "request", attrs...)
+		return err
+	}
+}
+
+// responseSize is the buffered body length, or -1 for streamed bodies, which reading
+// would drain.
+func responseSize(c *fiber.Ctx) int {
+	if c.Response().IsBodyStream() {
+		return -1
+	}
+	return len(c.Response().Body())
+}
+
+// GetRequestID returns the ID RequestLogger assigned to the request.
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(services.RequestIDContextKey).(string)
+	return id
+}
+
+// injectRequestID adds "request_id" to a JSON object error body so users can quote it
+// in reports. IDs are validated to need no escaping.
+func injectRequestID(c *fiber.Ctx, id string) {
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+	body := bytes.TrimSpace(c.Response().Body())
+	if len(body) < 2 || body[0] != '{' || bytes.Contains(body, []byte(`"request_id"`)) {
+		return
+	}
+	field := `"request_id":"` + id + `"`
+	rest := bytes.TrimSpace(body[1:])
+	if len(rest) > 0 && rest[0] != '}' {
+		field += ","
+	}
+	out := make([]byte, 0, len(body)+len(field))
+	out = append(out, '{')
+	out = append(out, field...)
+	out = append(out, rest...)
+	c.Response().SetBodyRaw(out)
+}
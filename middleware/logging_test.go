@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+)
+
+func TestRequestLoggerAssignsAndPropagatesRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.RequestLogger(nil))
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"id": middleware.GetRequestID(c)})
+	})
+	app.Get("/bad", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nope"})
+	})
+
+	// A valid incoming ID is reused and echoed
+	req := httptest.NewRequest("GET", "/ok", nil)
+	req.Header.Set("X-Request-ID", "lb-1234")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "lb-1234", resp.Header.Get("X-Request-ID"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"id":"lb-1234"}`, string(body))
+
+	// Unsafe IDs are replaced, and error bodies carry the ID
+	req = httptest.NewRequest("GET", "/bad", nil)
+	req.Header.Set("X-Request-ID", `bad"id`)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	id := resp.Header.Get("X-Request-ID")
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, `bad"id`, id)
+	body, _ = io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"error":"nope","request_id":"`+id+`"}`, string(body))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// Metrics records request counts and latency per route pattern. Patterns (e.g.
+// /api/images/:id) rather than raw paths keep label cardinality bounded.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The app error handler writes the response after middleware unwinds
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		route := "unmatched"
+		if r := c.Route(); r != nil && r.Path != "" {
+			route = r.Path
+		}
+		method := c.Method()
+		services.HTTPRequests.Inc(method, route, strconv.Itoa(status))
+		services.HTTPRequestDuration.ObserveSince(start, method, route)
+		return err
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/services"
+)
+
+// RateLimitPolicies enforces the active per-route policies (see
+// services.SetRateLimitPolicies) with rl's counters. Each policy counts separately, so a
+// request matching two policies spends from both budgets.
+func RateLimitPolicies(rl *services.RateLimiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, p := range services.CurrentRateLimitPolicies().Match(c.Method(), c.Path()) {
+			key := policyKey(c, p.Key)
+			if key == "" {
+				// Same as the other limiters: an unidentifiable client is let through
+				continue
+			}
+			if !rl.Allow("policy:"+p.Name+":"+key, p.Capacity, p.Window) {
+				services.RateLimitDenials.Inc("policy:" + p.Name)
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(p.Window.Seconds()))))
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many requests"})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// policyKey identifies the client for a policy keyed by kind. Only a valid session counts
+// as a user or token, so forged tokens cannot mint fresh budgets; anonymous requests are
+// keyed by client IP.
+func policyKey(c *fiber.Ctx, kind string) string {
+	switch kind {
+	case services.RateLimitKeyUser:
+		if uid := OptionalUserID(c); uid != uuid.Nil {
+			return "u:" + uid.String()
+		}
+	case services.RateLimitKeyToken:
+		if tok := RequestToken(c); tok != "" {
+			if _, err := ParseToken(tok); err == nil {
+				sum := sha256.Sum256([]byte(tok))
+				return "t:" + hex.EncodeToString(sum[:12])
+			}
+		}
+	}
+	if ip := services.ClientIP(c); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
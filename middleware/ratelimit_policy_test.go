@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+func TestRateLimitPolicies(t *testing.T) {
+	defer services.SetRateLimitPolicies(services.DefaultRateLimitPolicies())
+	assert.NoError(t, services.SetRateLimitPolicies([]services.RateLimitPolicy{
+		{Name: "search", Route: "GET /api/search", Capacity: 2, Window: time.Minute, Key: services.RateLimitKeyIP},
+	}))
+
+	rl := services.NewRateLimiter(services.RateLimitConfig{MaxEntries: 100, CleanupInterval: time.Minute, EntryTTL: time.Minute})
+	defer rl.Stop()
+
+	app := fiber.New()
+	app.Use(middleware.RateLimitPolicies(rl))
+	app.Get("/api/search", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/api/other", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	status := func(path string) (int, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		return resp.StatusCode, resp.Header.Get("Retry-After")
+	}
+	for i := 0; i < 2; i++ {
+		code, _ := status("/api/search")
+		assert.Equal(t, 200, code)
+	}
+	code, retry := status("/api/search")
+	assert.Equal(t, 429, code)
+	assert.Equal(t, "60", retry)
+
+	code, _ = status("/api/other")
+	assert.Equal(t, 200, code)
+
+	// A reload takes effect on the next request
+	assert.NoError(t, services.SetRateLimitPolicies(nil))
+	code, _ = status("/api/search")
+	assert.Equal(t, 200, code)
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/middleware"
+	"github.com/yourusername/trough/services"
+)
+
+func TestSignedUploads(t *testing.T) {
+	services.SetSignedURLs(services.SignedURLsConfig{Enabled: true, TTL: time.Hour, Secret: "k"})
+	defer services.SetSignedURLs(services.SignedURLsConfig{})
+
+	app := fiber.New()
+	app.Use("/uploads", middleware.SignedUploads())
+	app.Get("/uploads/*", func(c *fiber.Ctx) error { return c.SendString("file") })
+
+	status := func(path string) int {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	if got := status("/uploads/a.jpg"); got != fiber.StatusForbidden {
+		t.Fatalf("unsigned master: expected 403, got %d", got)
+	}
+	if got := status(services.SignMediaRef("a.jpg")); got != fiber.StatusOK {
+		t.Fatalf("signed master: expected 200, got %d", got)
+	}
+	if got := status("/uploads/a.jpg?exp=9999999999&sig=forged"); got != fiber.StatusForbidden {
+		t.Fatalf("forged token: expected 403, got %d", got)
+	}
+	for _, path := range []string{"/uploads/avatars/u.png", "/uploads/banners/u.webp", "/uploads/site/favicon.ico"} {
+		if got := status(path); got != fiber.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, got)
+		}
+	}
+	for _, path := range []string{"/uploads/versions/1/2-a.jpg", "/uploads/workflows/a.json"} {
+		if got := status(path); got != fiber.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", path, got)
+		}
+	}
+	if got := status(services.SignMediaRef("workflows/a.json")); got != fiber.StatusOK {
+		t.Fatalf("signed workflow: expected 200, got %d", got)
+	}
+}
+
+func TestSignMediaURLs(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.SignMediaURLs())
+	app.Get("/api/feed", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"images":  []fiber.Map{{"id": 1, "filename": "a.jpg", "blurhash": "x", "caption": "<b>"}},
+			"backups": []fiber.Map{{"filename": "backup.tar.gz"}},
+		})
+	})
+	get := func() map[string][]map[string]any {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/feed", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		var out map[string][]map[string]any
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("invalid JSON %s: %v", b, err)
+		}
+		return out
+	}
+	if got := get()["images"][0]["filename"]; got != "a.jpg" {
+		t.Fatalf("disabled signing rewrote filename to %v", got)
+	}
+
+	services.SetSignedURLs(services.SignedURLsConfig{Enabled: true, TTL: time.Hour})
+	defer services.SetSignedURLs(services.SignedURLsConfig{})
+	out := get()
+	u, err := url.Parse(out["images"][0]["filename"].(string))
+	if err != nil || u.Path != "/uploads/a.jpg" || u.Query().Get("sig") == "" {
+		t.Fatalf("expected a signed link, got %v", out["images"][0]["filename"])
+	}
+	if out["images"][0]["caption"] != "<b>" || out["images"][0]["id"] != float64(1) {
+		t.Fatalf("other fields changed: %v", out["images"][0])
+	}
+	if got := out["backups"][0]["filename"]; !strings.HasPrefix(got.(string), "backup") {
+		t.Fatalf("non-image filename rewritten to %v", got)
+	}
+}
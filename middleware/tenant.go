@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+	"github.com/yourusername/trough/services"
+)
+
+// Tenant matches the request's Host against the configured tenants and records the hit,
+// so handlers can scope feeds, pages and branding. Hosts without a tenant are the
+// primary site.
+func Tenant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if t := services.TenantForHost(c.Hostname()); t != nil {
+			c.Locals("tenant", t)
+		}
+		return c.Next()
+	}
+}
+
+// GetTenant returns the request's tenant, or nil on the primary site.
+func GetTenant(c *fiber.Ctx) *models.Tenant {
+	t, _ := c.Locals("tenant").(*models.Tenant)
+	return t
+}
+
+// SiteSettings returns the cached site settings as they apply to the request's site: a
+// tenant's branding and policy replace the primary site's on its domain.
+func SiteSettings(c *fiber.Ctx, repo models.SiteSettingsRepositoryInterface) models.SiteSettings {
+	set := services.GetCachedSettings(repo)
+	return *GetTenant(c).Apply(&set)
+}
+
+// TenantID returns the request's tenant id, or nil on the primary site.
+func TenantID(c *fiber.Ctx) *uuid.UUID {
+	if t := GetTenant(c); t != nil {
+		id := t.ID
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/trough/services"
+)
+
+// Tracing starts a server span for every request, continuing the caller's trace from a
+// W3C traceparent header. The span is stored in c.Locals and the user context, so
+// handlers can pass either c.Context() or c.UserContext() to services.StartSpan.
+func Tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !services.TracingEnabled() {
+			return c.Next()
+		}
+		ctx, span := services.StartServerSpan(c.UserContext(), c.Method()+" "+c.Path(), c.Get("traceparent"))
+		if span == nil {
+			return c.Next()
+		}
+		defer span.End()
+		c.Locals(services.SpanContextKey, span)
+		c.SetUserContext(ctx)
+		c.Set("X-Trace-Id", span.TraceID())
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		// Name by route pattern so spans group like the metrics do
+		if r := c.Route(); r != nil && r.Path != "" {
+			span.SetName(c.Method() + " " + r.Path)
+			span.SetAttr("http.route", r.Path)
+		}
+		span.SetAttr("http.request.method", c.Method())
+		span.SetAttr("url.path", c.Path())
+		span.SetAttr("http.response.status_code", status)
+		if status >= 500 {
+			if err == nil {
+				err = errors.New(fiber.ErrInternalServerError.Message)
+			}
+			span.RecordError(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/trough/db"
+)
+
+const migrateUsage = `usage: trough migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    revert the last N applied migrations (default 1)
+  status      list migrations and whether they are applied`
+
+// runMigrateCommand implements `trough migrate up|down|status` and returns the exit code.
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err := db.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "connect: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := db.MigrateUp(ctx, db.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				fmt.Fprintln(os.Stderr, "down: N must be a positive integer")
+				return 2
+			}
+			steps = v
+		}
+		n, err := db.MigrateDown(ctx, db.DB, steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down: %v\n", err)
+			return 1
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+	case "status":
+		list, err := db.MigrationStatus(ctx, db.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		for _, m := range list {
+			state := "pending"
+			if m.Applied && m.AppliedAt != nil {
+				state = "applied " + m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s %s\n", m.Version, m.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
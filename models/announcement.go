@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Announcement levels, in increasing severity. The SPA styles the banner by level.
+const (
+	AnnouncementInfo     = "info"
+	AnnouncementWarning  = "warning"
+	AnnouncementCritical = "critical"
+)
+
+// Announcement is a banner shown to every visitor while now is within [StartsAt, EndsAt).
+// A nil EndsAt keeps it up until it is edited or deleted.
+type Announcement struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	Message     string     `db:"message" json:"message"`
+	Level       string     `db:"level" json:"level"`
+	StartsAt    time.Time  `db:"starts_at" json:"starts_at"`
+	EndsAt      *time.Time `db:"ends_at" json:"ends_at"`
+	Dismissible bool       `db:"dismissible" json:"dismissible"`
+	CreatedBy   *uuid.UUID `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Active reports whether the announcement is shown at t.
+func (a *Announcement) Active(t time.Time) bool {
+	return !a.StartsAt.After(t) && (a.EndsAt == nil || a.EndsAt.After(t))
+}
+
+type AnnouncementRepository struct {
+	db *sqlx.DB
+}
+
+func NewAnnouncementRepository(db *sqlx.DB) *AnnouncementRepository {
+	return &AnnouncementRepository{db: db}
+}
+
+func (r *AnnouncementRepository) Create(a *Announcement) error {
+	return r.db.QueryRow(`INSERT INTO announcements (message, level, starts_at, ends_at, dismissible, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`,
+		a.Message, a.Level, a.StartsAt, a.EndsAt, a.Dismissible, a.CreatedBy).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+}
+
+func (r *AnnouncementRepository) Get(id uuid.UUID) (*Announcement, error) {
+	var a Announcement
+	if err := r.db.Get(&a, `SELECT * FROM announcements WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// List returns every announcement, including scheduled and expired ones, newest first.
+func (r *AnnouncementRepository) List() ([]Announcement, error) {
+	out := []Announcement{}
+	err := r.db.Select(&out, `SELECT * FROM announcements ORDER BY starts_at DESC, created_at DESC`)
+	return out, err
+}
+
+// ListActive returns the announcements shown now, most severe first.
+func (r *AnnouncementRepository) ListActive() ([]Announcement, error) {
+	out := []Announcement{}
+	err := r.db.Select(&out, `SELECT * FROM announcements
+		WHERE starts_at <= NOW() AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY CASE level WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, starts_at DESC`)
+	return out, err
+}
+
+func (r *AnnouncementRepository) Update(a *Announcement) error {
+	return r.db.QueryRow(`UPDATE announcements SET message = $2, level = $3, starts_at = $4, ends_at = $5, dismissible = $6, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		a.ID, a.Message, a.Level, a.StartsAt, a.EndsAt, a.Dismissible).Scan(&a.UpdatedAt)
+}
+
+func (r *AnnouncementRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM announcements WHERE id = $1`, id)
+	return err
+}
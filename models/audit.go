@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Admin audit actions. ImpersonationRequest is one request made with an impersonation token.
+const (
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationRequest = "impersonation.request"
+	AuditImpersonationEnded   = "impersonation.ended"
+)
+
+// ImpersonationSession is an admin's time-boxed "login as user" session.
+type ImpersonationSession struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	AdminID   *uuid.UUID `db:"admin_id" json:"admin_id"`
+	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
+	Reason    string     `db:"reason" json:"reason"`
+	IP        string     `db:"ip" json:"ip"`
+	StartedAt time.Time  `db:"started_at" json:"started_at"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	EndedAt   *time.Time `db:"ended_at" json:"ended_at"`
+}
+
+// Active reports whether the session's tokens are still accepted.
+func (s *ImpersonationSession) Active() bool {
+	return s.EndedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// AdminAudit is one entry in the admin audit log. ActorUsername is joined in for display.
+type AdminAudit struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	ActorID       *uuid.UUID `db:"actor_id" json:"actor_id"`
+	ActorUsername *string    `db:"actor_username" json:"actor_username"`
+	Action        string     `db:"action" json:"action"`
+	TargetUserID  *uuid.UUID `db:"target_user_id" json:"target_user_id"`
+	SessionID     *uuid.UUID `db:"session_id" json:"session_id"`
+	IP            string     `db:"ip" json:"ip"`
+	Detail        string     `db:"detail" json:"detail"`
+	RequestID     string     `db:"request_id" json:"request_id"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Add(a *AdminAudit) error {
+	return r.db.QueryRow(`INSERT INTO admin_audit (actor_id, action, target_user_id, session_id, ip, detail, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		a.ActorID, a.Action, a.TargetUserID, a.SessionID, a.IP, a.Detail, a.RequestID).Scan(&a.ID, &a.CreatedAt)
+}
+
+// List returns the audit log newest first, optionally only entries about one user.
+func (r *AuditRepository) List(targetUserID *uuid.UUID, page, limit int) ([]AdminAudit, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM admin_audit WHERE ($1::uuid IS NULL OR target_user_id = $1)`, targetUserID); err != nil {
+		return nil, 0, err
+	}
+	out := []AdminAudit{}
+	err := r.db.Select(&out, `SELECT a.*, u.username AS actor_username
+		FROM admin_audit a LEFT JOIN users u ON u.id = a.actor_id
+		WHERE ($1::uuid IS NULL OR a.target_user_id = $1)
+		ORDER BY a.created_at DESC LIMIT $2 OFFSET $3`, targetUserID, limit, (page-1)*limit)
+	return out, total, err
+}
+
+func (r *AuditRepository) StartImpersonation(s *ImpersonationSession) error {
+	return r.db.QueryRow(`INSERT INTO impersonation_sessions (admin_id, user_id, reason, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, started_at`,
+		s.AdminID, s.UserID, s.Reason, s.IP, s.ExpiresAt).Scan(&s.ID, &s.StartedAt)
+}
+
+func (r *AuditRepository) GetImpersonation(id uuid.UUID) (*ImpersonationSession, error) {
+	var s ImpersonationSession
+	if err := r.db.Get(&s, `SELECT * FROM impersonation_sessions WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// EndImpersonation ends a session early; ending one that already ended is a no-op.
+func (r *AuditRepository) EndImpersonation(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE impersonation_sessions SET ended_at = NOW() WHERE id = $1 AND ended_at IS NULL`, id)
+	return err
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Ban kinds. IP bans hold a CIDR range (single addresses are stored as /32 or /128);
+// email-domain bans also match subdomains.
+const (
+	BanKindIP          = "ip"
+	BanKindEmailDomain = "email_domain"
+)
+
+// Ban audit actions. Blocked records a registration or login refused by a ban.
+const (
+	BanActionCreated = "created"
+	BanActionDeleted = "deleted"
+	BanActionBlocked = "blocked"
+)
+
+type Ban struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Kind      string     `db:"kind" json:"kind"`
+	Value     string     `db:"value" json:"value"`
+	Reason    string     `db:"reason" json:"reason"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at"`
+	CreatedBy *uuid.UUID `db:"created_by" json:"created_by"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// BanAudit is one entry in the ban audit trail. ActorUsername is joined in for display.
+type BanAudit struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	BanID         *uuid.UUID `db:"ban_id" json:"ban_id"`
+	Action        string     `db:"action" json:"action"`
+	Kind          string     `db:"kind" json:"kind"`
+	Value         string     `db:"value" json:"value"`
+	ActorID       *uuid.UUID `db:"actor_id" json:"actor_id"`
+	ActorUsername *string    `db:"actor_username" json:"actor_username"`
+	IP            string     `db:"ip" json:"ip"`
+	Detail        string     `db:"detail" json:"detail"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+type BanRepository struct {
+	db *sqlx.DB
+}
+
+func NewBanRepository(db *sqlx.DB) *BanRepository {
+	return &BanRepository{db: db}
+}
+
+func (r *BanRepository) Create(b *Ban) error {
+	return r.db.QueryRow(`INSERT INTO bans (kind, value, reason, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		b.Kind, b.Value, b.Reason, b.ExpiresAt, b.CreatedBy).Scan(&b.ID, &b.CreatedAt)
+}
+
+func (r *BanRepository) Get(id uuid.UUID) (*Ban, error) {
+	var b Ban
+	if err := r.db.Get(&b, `SELECT * FROM bans WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BanRepository) List() ([]Ban, error) {
+	out := []Ban{}
+	err := r.db.Select(&out, `SELECT * FROM bans ORDER BY kind, value`)
+	return out, err
+}
+
+// ListActive returns bans that have not expired, for the enforcement cache.
+func (r *BanRepository) ListActive() ([]Ban, error) {
+	out := []Ban{}
+	err := r.db.Select(&out, `SELECT * FROM bans WHERE expires_at IS NULL OR expires_at > NOW()`)
+	return out, err
+}
+
+func (r *BanRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM bans WHERE id = $1`, id)
+	return err
+}
+
+func (r *BanRepository) AddAudit(a *BanAudit) error {
+	return r.db.QueryRow(`INSERT INTO ban_audit (ban_id, action, kind, value, actor_id, ip, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		a.BanID, a.Action, a.Kind, a.Value, a.ActorID, a.IP, a.Detail).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *BanRepository) ListAudit(page, limit int) ([]BanAudit, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM ban_audit`); err != nil {
+		return nil, 0, err
+	}
+	out := []BanAudit{}
+	err := r.db.Select(&out, `SELECT a.*, u.username AS actor_username
+		FROM ban_audit a LEFT JOIN users u ON u.id = a.actor_id
+		ORDER BY a.created_at DESC LIMIT $1 OFFSET $2`, limit, (page-1)*limit)
+	return out, total, err
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxBlocks caps how many users one account can block.
+const MaxBlocks = 1000
+
+// BlockedUser is an account the user blocked, and when.
+type BlockedUser struct {
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	AvatarURL *string   `db:"avatar_url" json:"avatar_url"`
+	BlockedAt time.Time `db:"blocked_at" json:"blocked_at"`
+}
+
+type BlockRepository struct {
+	db *sqlx.DB
+}
+
+func NewBlockRepository(db *sqlx.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+// Block records that blocker blocked blocked; blocking twice is not an error.
+func (r *BlockRepository) Block(blocker, blocked uuid.UUID) error {
+	_, err := r.db.Exec(`INSERT INTO blocks (blocker_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, blocker, blocked)
+	return err
+}
+
+func (r *BlockRepository) Unblock(blocker, blocked uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2`, blocker, blocked)
+	return err
+}
+
+// IsBlocked reports whether blocker blocked blocked.
+func (r *BlockRepository) IsBlocked(blocker, blocked uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.db.Get(&ok, `SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2)`, blocker, blocked)
+	return ok, err
+}
+
+func (r *BlockRepository) Count(blocker uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM blocks WHERE blocker_id = $1`, blocker)
+	return n, err
+}
+
+// ListBlocked returns the users blocker blocked, most recent first.
+func (r *BlockRepository) ListBlocked(blocker uuid.UUID) ([]BlockedUser, error) {
+	out := []BlockedUser{}
+	err := r.db.Select(&out, `SELECT u.id AS user_id, u.username, u.avatar_url, b.created_at AS blocked_at
+		FROM blocks b JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1 ORDER BY b.created_at DESC`, blocker)
+	return out, err
+}
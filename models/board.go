@@ -0,0 +1,265 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultBoardName names the board the collect button fills, created on first collect.
+const DefaultBoardName = "Collected"
+
+// ErrDefaultBoard is returned when deleting a user's default board.
+var ErrDefaultBoard = errors.New("the default board cannot be deleted")
+
+// Board is a named, ordered list of collected images. Every image on a board is also in
+// the owner's collections, and leaves them when it is on none of their boards. Position
+// orders a user's boards; ItemCount and Cover (the still of the first visible image) are
+// filled on listings.
+type Board struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	UserID      uuid.UUID `db:"user_id" json:"user_id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	IsPublic    bool      `db:"is_public" json:"is_public"`
+	IsDefault   bool      `db:"is_default" json:"is_default"`
+	Position    int       `db:"position" json:"position"`
+	ItemCount   int       `db:"item_count" json:"item_count"`
+	Cover       *string   `db:"cover" json:"cover,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type BoardRepository struct {
+	db *sqlx.DB
+}
+
+func NewBoardRepository(db *sqlx.DB) *BoardRepository {
+	return &BoardRepository{db: db}
+}
+
+// boardVisibleItem limits board items to images their owner has not made private.
+const boardVisibleItem = `i.visibility <> 'private'`
+
+const boardColumns = `b.id, b.user_id, b.name, b.description, b.is_public, b.is_default, b.position, b.created_at, b.updated_at,
+	(SELECT COUNT(*) FROM board_items bi JOIN images i ON i.id = bi.image_id WHERE bi.board_id = b.id AND ` + boardVisibleItem + `) AS item_count,
+	(SELECT CASE WHEN i.media_type = 'video' THEN i.poster_filename ELSE i.filename END FROM board_items bi JOIN images i ON i.id = bi.image_id
+		WHERE bi.board_id = b.id AND ` + boardVisibleItem + ` ORDER BY bi.position, bi.created_at DESC LIMIT 1) AS cover`
+
+// ListForUser returns a user's boards in their order; private ones only with includePrivate.
+func (r *BoardRepository) ListForUser(userID uuid.UUID, includePrivate bool) ([]Board, error) {
+	out := []Board{}
+	err := r.db.Select(&out, `SELECT `+boardColumns+` FROM boards b WHERE b.user_id = $1 AND ($2 OR b.is_public)
+		ORDER BY b.position, b.created_at`, userID, includePrivate)
+	return out, err
+}
+
+func (r *BoardRepository) Get(id uuid.UUID) (*Board, error) {
+	var b Board
+	if err := r.db.Get(&b, `SELECT `+boardColumns+` FROM boards b WHERE b.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Create adds a board after the user's others.
+func (r *BoardRepository) Create(b *Board) error {
+	return r.db.Get(b, `INSERT INTO boards (user_id, name, description, is_public, position)
+		VALUES ($1, $2, $3, $4, (SELECT COALESCE(MAX(position), -1) + 1 FROM boards WHERE user_id = $1))
+		RETURNING id, user_id, name, description, is_public, is_default, position, created_at, updated_at`,
+		b.UserID, b.Name, b.Description, b.IsPublic)
+}
+
+// Update saves a board's name, description and visibility.
+func (r *BoardRepository) Update(b *Board) error {
+	_, err := r.db.Exec(`UPDATE boards SET name = $2, description = $3, is_public = $4, updated_at = NOW() WHERE id = $1`,
+		b.ID, b.Name, b.Description, b.IsPublic)
+	return err
+}
+
+// Delete removes a board. Its images leave the owner's collections unless another of
+// their boards holds them. Default boards are kept and return ErrDefaultBoard.
+func (r *BoardRepository) Delete(id uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var b Board
+	if err := tx.Get(&b, `SELECT id, user_id, is_default FROM boards WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return err
+	}
+	if b.IsDefault {
+		return ErrDefaultBoard
+	}
+	if _, err := tx.Exec(`DELETE FROM boards WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := pruneCollections(tx, b.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reorder sets the order of the user's boards to ids; boards left out follow them.
+func (r *BoardRepository) Reorder(userID uuid.UUID, ids []uuid.UUID) error {
+	return reorder(r.db, `UPDATE boards SET position = $2 + position - (SELECT MIN(position) FROM boards WHERE user_id = $1) WHERE user_id = $1`,
+		`UPDATE boards SET position = $3 WHERE user_id = $1 AND id = $2`, userID, ids)
+}
+
+// EnsureDefault returns the user's default board, creating it first if needed.
+func (r *BoardRepository) EnsureDefault(userID uuid.UUID) (*Board, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	id, err := ensureDefaultBoard(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Get(id)
+}
+
+// AddImage puts an image at the top of a board and in the board owner's collections. It
+// reports whether the image was newly collected, i.e. on none of their boards before.
+func (r *BoardRepository) AddImage(boardID, imageID uuid.UUID) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var userID uuid.UUID
+	if err := tx.Get(&userID, `SELECT user_id FROM boards WHERE id = $1`, boardID); err != nil {
+		return false, err
+	}
+	if err := addBoardItem(tx, boardID, imageID); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`INSERT INTO collections (user_id, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, imageID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, tx.Commit()
+}
+
+// RemoveImage takes an image off a board, and out of the owner's collections when no other
+// board of theirs holds it. It reports whether the image left their collections.
+func (r *BoardRepository) RemoveImage(boardID, imageID uuid.UUID) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var userID uuid.UUID
+	if err := tx.Get(&userID, `SELECT user_id FROM boards WHERE id = $1`, boardID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM board_items WHERE board_id = $1 AND image_id = $2`, boardID, imageID); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`DELETE FROM collections c WHERE c.user_id = $1 AND c.image_id = $2 AND NOT EXISTS (
+		SELECT 1 FROM board_items bi JOIN boards b ON b.id = bi.board_id WHERE b.user_id = $1 AND bi.image_id = $2)`, userID, imageID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, tx.Commit()
+}
+
+// ReorderImages sets the order of a board's images to ids; images left out follow them.
+func (r *BoardRepository) ReorderImages(boardID uuid.UUID, ids []uuid.UUID) error {
+	return reorder(r.db, `UPDATE board_items SET position = $2 + position - (SELECT MIN(position) FROM board_items WHERE board_id = $1) WHERE board_id = $1`,
+		`UPDATE board_items SET position = $3 WHERE board_id = $1 AND image_id = $2`, boardID, ids)
+}
+
+// Images pages through a board's visible images in board order.
+func (r *BoardRepository) Images(boardID uuid.UUID, page, limit int) ([]ImageWithUser, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM board_items bi JOIN images i ON i.id = bi.image_id WHERE bi.board_id = $1 AND `+boardVisibleItem, boardID); err != nil {
+		return nil, 0, err
+	}
+	images := []ImageWithUser{}
+	err := r.db.Select(&images, `
+        SELECT
+            i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified
+        FROM board_items bi
+        JOIN images i ON i.id = bi.image_id
+        LEFT JOIN users u ON i.user_id = u.id
+        WHERE bi.board_id = $1 AND `+boardVisibleItem+`
+        ORDER BY bi.position, bi.created_at DESC, i.id
+        LIMIT $2 OFFSET $3`, boardID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return images, total, nil
+}
+
+// BoardsWithImage lists the ids of the user's boards that hold an image.
+func (r *BoardRepository) BoardsWithImage(userID, imageID uuid.UUID) ([]uuid.UUID, error) {
+	out := []uuid.UUID{}
+	err := r.db.Select(&out, `SELECT b.id FROM boards b JOIN board_items bi ON bi.board_id = b.id
+		WHERE b.user_id = $1 AND bi.image_id = $2 ORDER BY b.position`, userID, imageID)
+	return out, err
+}
+
+// ensureDefaultBoard returns the id of the user's default board, creating it if needed.
+func ensureDefaultBoard(tx *sqlx.Tx, userID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.Get(&id, `SELECT id FROM boards WHERE user_id = $1 AND is_default`, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+	// The default board leads; concurrent creators meet on the partial unique index
+	err = tx.Get(&id, `INSERT INTO boards (user_id, name, is_default, position)
+		VALUES ($1, $2, TRUE, (SELECT COALESCE(MIN(position), 1) - 1 FROM boards WHERE user_id = $1))
+		ON CONFLICT (user_id) WHERE is_default DO UPDATE SET updated_at = boards.updated_at
+		RETURNING id`, userID, DefaultBoardName)
+	return id, err
+}
+
+// addBoardItem puts an image at the top of a board; one already on it stays where it is.
+func addBoardItem(tx *sqlx.Tx, boardID, imageID uuid.UUID) error {
+	_, err := tx.Exec(`INSERT INTO board_items (board_id, image_id, position)
+		VALUES ($1, $2, (SELECT COALESCE(MIN(position), 1) - 1 FROM board_items WHERE board_id = $1))
+		ON CONFLICT DO NOTHING`, boardID, imageID)
+	return err
+}
+
+// pruneCollections drops the user's collections that are on none of their boards.
+func pruneCollections(tx *sqlx.Tx, userID uuid.UUID) error {
+	_, err := tx.Exec(`DELETE FROM collections c WHERE c.user_id = $1 AND NOT EXISTS (
+		SELECT 1 FROM board_items bi JOIN boards b ON b.id = bi.board_id WHERE b.user_id = $1 AND bi.image_id = c.image_id)`, userID)
+	return err
+}
+
+// reorder moves the rows named by ids to positions 0..len(ids)-1 under owner. shift
+// ($1 owner, $2 first free position) first moves every row past them, so rows left out
+// keep their relative order after the listed ones; set ($1 owner, $2 id, $3 position)
+// then places one.
+func reorder(db *sqlx.DB, shift, set string, owner uuid.UUID, ids []uuid.UUID) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(shift, owner, len(ids)); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if _, err := tx.Exec(set, owner, id, i); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxBulkIDs bounds how many ids one bulk admin request may name.
+const MaxBulkIDs = 500
+
+// Bulk user actions.
+const (
+	BulkUserDisable     = "disable"
+	BulkUserEnable      = "enable"
+	BulkUserDelete      = "delete"
+	BulkUserVerifyEmail = "verify_email"
+)
+
+// Bulk image actions.
+const (
+	BulkImageDelete  = "delete"
+	BulkImageSetNSFW = "set_nsfw"
+)
+
+// BulkResult is the outcome of a bulk action for one id. Error is set when OK is false.
+type BulkResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+var bulkUserQueries = map[string]string{
+	BulkUserDisable:     `UPDATE users SET is_disabled = TRUE, suspension_reason = '', suspended_until = NULL WHERE id = $1`,
+	BulkUserEnable:      `UPDATE users SET is_disabled = FALSE, suspension_reason = '', suspended_until = NULL WHERE id = $1`,
+	BulkUserDelete:      `DELETE FROM users WHERE id = $1`,
+	BulkUserVerifyEmail: `UPDATE users SET email_verified = TRUE WHERE id = $1`,
+}
+
+// IsBulkUserAction reports whether action is one BulkApply accepts.
+func IsBulkUserAction(action string) bool {
+	_, ok := bulkUserQueries[action]
+	return ok
+}
+
+// BulkApply runs action on every user in ids in one transaction and returns the ids it
+// changed; ids with no user are left out. Any database error rolls the whole batch back.
+func (r *UserRepository) BulkApply(action string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	query, ok := bulkUserQueries[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown bulk action %q", action)
+	}
+	return bulkExec(r.db, query, ids)
+}
+
+// BulkDelete deletes the images in ids in one transaction and returns the deleted rows'
+// id, owner and stored files, so callers can remove the files once it commits.
+func (r *ImageRepository) BulkDelete(ids []uuid.UUID) ([]Image, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	deleted := []Image{}
+	for _, id := range ids {
+		var img Image
+		err := tx.Get(&img, `DELETE FROM images WHERE id = $1 RETURNING id, user_id, filename, poster_filename, media_type`, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, img)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// BulkSetNSFW sets the NSFW flag of the images in ids in one transaction, moving their
+// ratings as ContentRating.WithNSFW does, and returns the ids it changed.
+func (r *ImageRepository) BulkSetNSFW(ids []uuid.UUID, isNSFW bool) ([]uuid.UUID, error) {
+	return bulkExec(r.db, `UPDATE images SET rating = `+nsfwRating(2)+` WHERE id = $1`, ids, isNSFW)
+}
+
+// bulkExec runs query for each of ids in one transaction, with the id as $1 and args
+// after it, and returns the ids that matched a row.
+func bulkExec(db *sqlx.DB, query string, ids []uuid.UUID, args ...interface{}) ([]uuid.UUID, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	changed := []uuid.UUID{}
+	for _, id := range ids {
+		res, err := tx.Exec(query, append([]interface{}{id}, args...)...)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed = append(changed, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxChallengeEntries caps how many images one user can enter into a challenge.
+const MaxChallengeEntries = 3
+
+// Challenge phases, from the challenge's window and the current time.
+const (
+	ChallengeUpcoming = "upcoming"
+	ChallengeCurrent  = "current"
+	ChallengePast     = "past"
+)
+
+// Challenge is a themed prompt open for entries while now is within [StartsAt, EndsAt).
+// EntryCount and Status are filled on reads.
+type Challenge struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	Title      string     `db:"title" json:"title"`
+	Prompt     string     `db:"prompt" json:"prompt"`
+	StartsAt   time.Time  `db:"starts_at" json:"starts_at"`
+	EndsAt     time.Time  `db:"ends_at" json:"ends_at"`
+	CreatedBy  *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	EntryCount int        `db:"entry_count" json:"entry_count"`
+	Status     string     `db:"-" json:"status"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// StatusAt returns the challenge's phase at t.
+func (c *Challenge) StatusAt(t time.Time) string {
+	switch {
+	case t.Before(c.StartsAt):
+		return ChallengeUpcoming
+	case t.Before(c.EndsAt):
+		return ChallengeCurrent
+	}
+	return ChallengePast
+}
+
+// ChallengeEntry is an image entered into a challenge. Rank is its place on the
+// leaderboard, by collections.
+type ChallengeEntry struct {
+	ImageWithUser
+	Rank        int       `db:"-" json:"rank"`
+	SubmittedAt time.Time `db:"submitted_at" json:"submitted_at"`
+}
+
+type ChallengeRepository struct {
+	db *sqlx.DB
+}
+
+func NewChallengeRepository(db *sqlx.DB) *ChallengeRepository {
+	return &ChallengeRepository{db: db}
+}
+
+// challengeVisibleEntry limits entries to images everyone may still see.
+const challengeVisibleEntry = `i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT u.is_shadowbanned`
+
+const challengeColumns = `c.id, c.title, c.prompt, c.starts_at, c.ends_at, c.created_by, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM challenge_entries e JOIN images i ON i.id = e.image_id JOIN users u ON u.id = i.user_id
+		WHERE e.challenge_id = c.id AND ` + challengeVisibleEntry + `) AS entry_count`
+
+func stampChallenges(list []Challenge) {
+	now := time.Now()
+	for i := range list {
+		list[i].Status = list[i].StatusAt(now)
+	}
+}
+
+func (r *ChallengeRepository) Create(c *Challenge) error {
+	if err := r.db.QueryRow(`INSERT INTO challenges (title, prompt, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		c.Title, c.Prompt, c.StartsAt, c.EndsAt, c.CreatedBy).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return err
+	}
+	c.Status = c.StatusAt(time.Now())
+	return nil
+}
+
+func (r *ChallengeRepository) Get(id uuid.UUID) (*Challenge, error) {
+	var c Challenge
+	if err := r.db.Get(&c, `SELECT `+challengeColumns+` FROM challenges c WHERE c.id = $1`, id); err != nil {
+		return nil, err
+	}
+	c.Status = c.StatusAt(time.Now())
+	return &c, nil
+}
+
+// List pages through the challenges in a phase: current ones ending soonest first,
+// upcoming ones starting soonest first and past ones most recently ended first. An
+// empty status lists them all, latest start first.
+func (r *ChallengeRepository) List(status string, page, limit int) ([]Challenge, int, error) {
+	where, order := `TRUE`, `c.starts_at DESC`
+	switch status {
+	case ChallengeCurrent:
+		where, order = `c.starts_at <= NOW() AND c.ends_at > NOW()`, `c.ends_at ASC`
+	case ChallengeUpcoming:
+		where, order = `c.starts_at > NOW()`, `c.starts_at ASC`
+	case ChallengePast:
+		where, order = `c.ends_at <= NOW()`, `c.ends_at DESC`
+	}
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM challenges c WHERE `+where); err != nil {
+		return nil, 0, err
+	}
+	out := []Challenge{}
+	if err := r.db.Select(&out, `SELECT `+challengeColumns+` FROM challenges c WHERE `+where+`
+		ORDER BY `+order+`, c.id LIMIT $1 OFFSET $2`, limit, (page-1)*limit); err != nil {
+		return nil, 0, err
+	}
+	stampChallenges(out)
+	return out, total, nil
+}
+
+func (r *ChallengeRepository) Update(c *Challenge) error {
+	if err := r.db.QueryRow(`UPDATE challenges SET title = $2, prompt = $3, starts_at = $4, ends_at = $5, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		c.ID, c.Title, c.Prompt, c.StartsAt, c.EndsAt).Scan(&c.UpdatedAt); err != nil {
+		return err
+	}
+	c.Status = c.StatusAt(time.Now())
+	return nil
+}
+
+// Delete removes a challenge and its entries; the images stay.
+func (r *ChallengeRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM challenges WHERE id = $1`, id)
+	return err
+}
+
+// Enter adds userID's image to the challenge and reports whether it was not already in.
+func (r *ChallengeRepository) Enter(challengeID, imageID, userID uuid.UUID) (bool, error) {
+	res, err := r.db.Exec(`INSERT INTO challenge_entries (challenge_id, image_id, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		challengeID, imageID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Withdraw takes an image out of the challenge and reports whether it was in.
+func (r *ChallengeRepository) Withdraw(challengeID, imageID uuid.UUID) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM challenge_entries WHERE challenge_id = $1 AND image_id = $2`, challengeID, imageID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// CountEntries returns how many images userID entered into the challenge.
+func (r *ChallengeRepository) CountEntries(challengeID, userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM challenge_entries WHERE challenge_id = $1 AND user_id = $2`, challengeID, userID)
+	return n, err
+}
+
+// Leaderboard pages through the challenge's visible entries, most collected first; ties
+// go to the earlier entry.
+func (r *ChallengeRepository) Leaderboard(challengeID uuid.UUID, page, limit int) ([]ChallengeEntry, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM challenge_entries e JOIN images i ON i.id = e.image_id JOIN users u ON u.id = i.user_id
+		WHERE e.challenge_id = $1 AND `+challengeVisibleEntry, challengeID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	out := []ChallengeEntry{}
+	if err := r.db.Select(&out, `
+        SELECT
+            i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            'null'::jsonb AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified, e.created_at AS submitted_at
+        FROM challenge_entries e
+        JOIN images i ON i.id = e.image_id
+        JOIN users u ON u.id = i.user_id
+        WHERE e.challenge_id = $1 AND `+challengeVisibleEntry+`
+        ORDER BY i.collected_count DESC, e.created_at ASC, i.id
+        LIMIT $2 OFFSET $3`, challengeID, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	for i := range out {
+		out[i].Rank = offset + i + 1
+	}
+	return out, total, nil
+}
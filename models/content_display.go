@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ContentRating grades how explicit an image is. The uploader sets it and moderators can
+// change it; mature and explicit images are the ones flagged NSFW.
+type ContentRating string
+
+const (
+	RatingSafe       ContentRating = "safe"
+	RatingSuggestive ContentRating = "suggestive"
+	RatingMature     ContentRating = "mature"
+	RatingExplicit   ContentRating = "explicit"
+)
+
+// contentRatings lists the ratings from mildest to most explicit.
+var contentRatings = []ContentRating{RatingSafe, RatingSuggestive, RatingMature, RatingExplicit}
+
+// ParseRating returns the ContentRating named by s, ignoring case and surrounding space.
+func ParseRating(s string) (ContentRating, bool) {
+	r := ContentRating(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range contentRatings {
+		if r == known {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// NSFW reports whether images of the rating are flagged NSFW.
+func (r ContentRating) NSFW() bool { return r == RatingMature || r == RatingExplicit }
+
+// WithNSFW returns the rating after setting the NSFW flag: r itself when it is already on
+// that side, otherwise explicit or safe.
+func (r ContentRating) WithNSFW(nsfw bool) ContentRating {
+	switch {
+	case r.NSFW() == nsfw && r != "":
+		return r
+	case nsfw:
+		return RatingExplicit
+	}
+	return RatingSafe
+}
+
+// EffectiveRating returns the image's rating; rows read without it fall back on IsNSFW.
+func (i *Image) EffectiveRating() ContentRating {
+	if i.Rating != "" {
+		return i.Rating
+	}
+	return ContentRating("").WithNSFW(i.IsNSFW)
+}
+
+// nsfwRating is SQL for the rating an image gets when its NSFW flag is set to placeholder
+// $n, as WithNSFW computes it.
+func nsfwRating(n int) string {
+	return fmt.Sprintf(`CASE WHEN $%[1]d = (rating IN ('mature', 'explicit')) THEN rating WHEN $%[1]d THEN 'explicit' ELSE 'safe' END`, n)
+}
+
+// Display is how a viewer sees images of a rating: in full, blurred until clicked, or not
+// at all.
+type Display string
+
+const (
+	DisplayShow Display = "show"
+	DisplayBlur Display = "blur"
+	DisplayHide Display = "hide"
+)
+
+// ParseDisplay returns the Display named by s, ignoring case and surrounding space.
+func ParseDisplay(s string) (Display, bool) {
+	switch d := Display(strings.ToLower(strings.TrimSpace(s))); d {
+	case DisplayShow, DisplayBlur, DisplayHide:
+		return d, true
+	}
+	return "", false
+}
+
+// ContentPrefs holds a user's display choices for the ratings below explicit, which is
+// still set with nsfw_pref. Empty entries use the default.
+type ContentPrefs struct {
+	Suggestive Display `json:"suggestive,omitempty"`
+	Mature     Display `json:"mature,omitempty"`
+}
+
+// Value stores the preferences as JSONB.
+func (p ContentPrefs) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan reads the preferences from a JSONB column.
+func (p *ContentPrefs) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*p = ContentPrefs{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("content_prefs: unsupported type")
+	}
+	*p = ContentPrefs{}
+	return json.Unmarshal(b, p)
+}
+
+// DisplayPrefs resolves how one viewer sees each rating. Empty entries use the defaults:
+// suggestive images are shown, explicit ones hidden and mature ones displayed like
+// explicit ones, which is also what anonymous viewers get from the zero value. The
+// choices act as thresholds: no rating is displayed more openly than a milder one.
+type DisplayPrefs struct {
+	Suggestive Display
+	Mature     Display
+	Explicit   Display
+}
+
+// DisplayPrefsFor returns u's display preferences; nil means an anonymous viewer. The
+// nsfw_pref setting decides explicit images and falls back to the legacy show_nsfw flag
+// when it is unset.
+func DisplayPrefsFor(u *User) DisplayPrefs {
+	if u == nil {
+		return DisplayPrefs{}
+	}
+	p := DisplayPrefs{Suggestive: u.ContentPrefs.Suggestive, Mature: u.ContentPrefs.Mature}
+	if d, ok := ParseDisplay(u.NsfwPref); ok {
+		p.Explicit = d
+	} else if u.ShowNSFW {
+		p.Explicit = DisplayShow
+	}
+	return p
+}
+
+// own returns the display chosen for r alone, before thresholds apply.
+func (p DisplayPrefs) own(r ContentRating) Display {
+	switch r {
+	case RatingSuggestive:
+		if p.Suggestive != "" {
+			return p.Suggestive
+		}
+		return DisplayShow
+	case RatingMature:
+		if p.Mature != "" {
+			return p.Mature
+		}
+		return p.own(RatingExplicit)
+	case RatingExplicit:
+		if p.Explicit != "" {
+			return p.Explicit
+		}
+		return DisplayHide
+	}
+	return DisplayShow
+}
+
+// displayStrictness orders displays from most to least open.
+var displayStrictness = map[Display]int{DisplayShow: 0, DisplayBlur: 1, DisplayHide: 2}
+
+// For returns how images rated r are displayed: the strictest choice among r and the
+// ratings milder than it.
+func (p DisplayPrefs) For(r ContentRating) Display {
+	d := DisplayShow
+	for _, milder := range contentRatings {
+		if own := p.own(milder); displayStrictness[own] > displayStrictness[d] {
+			d = own
+		}
+		if milder == r {
+			break
+		}
+	}
+	return d
+}
+
+// Hidden returns the ratings For hides.
+func (p DisplayPrefs) Hidden() []ContentRating {
+	var out []ContentRating
+	for _, r := range contentRatings {
+		if p.For(r) == DisplayHide {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Annotate sets Display on each image so clients know which to blur or hide.
+func (p DisplayPrefs) Annotate(images []ImageWithUser) {
+	for i := range images {
+		images[i].Display = p.For(images[i].EffectiveRating())
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CSPReport is a Content-Security-Policy violation, folded with identical reports.
+type CSPReport struct {
+	ID                int64     `db:"id" json:"id"`
+	Fingerprint       string    `db:"fingerprint" json:"-"`
+	DocumentURI       string    `db:"document_uri" json:"document_uri"`
+	ViolatedDirective string    `db:"violated_directive" json:"violated_directive"`
+	BlockedURI        string    `db:"blocked_uri" json:"blocked_uri"`
+	SourceFile        string    `db:"source_file" json:"source_file"`
+	LineNumber        int       `db:"line_number" json:"line_number"`
+	Sample            string    `db:"sample" json:"sample"`
+	UserAgent         string    `db:"user_agent" json:"user_agent"`
+	Count             int64     `db:"count" json:"count"`
+	FirstSeen         time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen          time.Time `db:"last_seen" json:"last_seen"`
+}
+
+// CSPReportFingerprint identifies reports of the same violation.
+func CSPReportFingerprint(r CSPReport) string {
+	sum := sha256.Sum256([]byte(r.DocumentURI + "\x00" + r.ViolatedDirective + "\x00" + r.BlockedURI + "\x00" + r.SourceFile + "\x00" + strconv.Itoa(r.LineNumber)))
+	return hex.EncodeToString(sum[:])
+}
+
+type CSPReportRepository struct {
+	db *sqlx.DB
+}
+
+func NewCSPReportRepository(db *sqlx.DB) *CSPReportRepository {
+	return &CSPReportRepository{db: db}
+}
+
+// Record stores a report, or bumps the count and last_seen of an identical one.
+func (r *CSPReportRepository) Record(rep CSPReport) error {
+	rep.Fingerprint = CSPReportFingerprint(rep)
+	_, err := r.db.NamedExec(`INSERT INTO csp_reports (fingerprint, document_uri, violated_directive, blocked_uri, source_file, line_number, sample, user_agent)
+		VALUES (:fingerprint, :document_uri, :violated_directive, :blocked_uri, :source_file, :line_number, :sample, :user_agent)
+		ON CONFLICT (fingerprint) DO UPDATE SET count = csp_reports.count + 1, last_seen = NOW(), user_agent = EXCLUDED.user_agent`, rep)
+	return err
+}
+
+// List returns reports, most recently seen first, with the total count.
+func (r *CSPReportRepository) List(page, limit int) ([]CSPReport, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM csp_reports`); err != nil {
+		return nil, 0, err
+	}
+	out := []CSPReport{}
+	err := r.db.Select(&out, `SELECT * FROM csp_reports ORDER BY last_seen DESC, id DESC LIMIT $1 OFFSET $2`, limit, (page-1)*limit)
+	return out, total, err
+}
+
+// Purge drops reports last seen before the given time.
+func (r *CSPReportRepository) Purge(before time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM csp_reports WHERE last_seen < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Clear drops every report.
+func (r *CSPReportRepository) Clear() error {
+	_, err := r.db.Exec(`DELETE FROM csp_reports`)
+	return err
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxMutedProviders caps how many AI providers one user can mute.
+const MaxMutedProviders = 50
+
+// FeedMutes is what a user asked to keep out of their feeds besides NSFW images.
+// Providers are lowercased AI provider names, matched exactly against images'.
+type FeedMutes struct {
+	Providers []string `json:"providers,omitempty"`
+}
+
+// Value stores the mutes as JSONB.
+func (m FeedMutes) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan reads the mutes from a JSONB column.
+func (m *FeedMutes) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*m = FeedMutes{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("feed_mutes: unsupported type")
+	}
+	*m = FeedMutes{}
+	return json.Unmarshal(b, m)
+}
+
+// FeedFilter is what one viewer's feeds leave out: images rated for hiding, and images
+// from the providers they muted. Model, when set, narrows the feed to images made with
+// that checkpoint, matched case-insensitively. The zero value is the anonymous filter.
+type FeedFilter struct {
+	DisplayPrefs
+	FeedMutes
+	Model string
+}
+
+// FeedFilterFor returns the filter for u's preferences; nil means an anonymous viewer.
+func FeedFilterFor(u *User) FeedFilter {
+	if u == nil {
+		return FeedFilter{}
+	}
+	return FeedFilter{DisplayPrefs: DisplayPrefsFor(u), FeedMutes: u.FeedMutes}
+}
+
+// Hides reports whether the filter leaves out an image with these attributes.
+func (f FeedFilter) Hides(rating ContentRating, provider *string) bool {
+	if f.For(rating) == DisplayHide {
+		return true
+	}
+	if provider != nil && len(f.Providers) > 0 {
+		p := strings.ToLower(*provider)
+		for _, muted := range f.Providers {
+			if muted == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// where returns the filter as a condition on images i, starting with AND and bound from
+// placeholder $n, and the arguments to append.
+func (f FeedFilter) where(n int) (string, []any) {
+	var cond string
+	var args []any
+	if hidden := f.Hidden(); len(hidden) > 0 {
+		ratings, _ := json.Marshal(hidden)
+		cond += fmt.Sprintf(`AND i.rating NOT IN (SELECT jsonb_array_elements_text($%d::jsonb)) `, n)
+		args = append(args, string(ratings))
+	}
+	if len(f.Providers) > 0 {
+		providers, _ := json.Marshal(f.Providers)
+		cond += fmt.Sprintf(`AND NOT EXISTS (SELECT 1 FROM jsonb_array_elements_text($%d::jsonb) mp WHERE mp = lower(i.ai_provider)) `, n+len(args))
+		args = append(args, string(providers))
+	}
+	if f.Model != "" {
+		cond += fmt.Sprintf(`AND lower(i.ai_model) = lower($%d) `, n+len(args))
+		args = append(args, f.Model)
+	}
+	return cond, args
+}
@@ -17,29 +17,154 @@ func EncodeCursor(t time.Time, id uuid.UUID) string {
 	return base64.RawURLEncoding.EncodeToString([]byte(payload))
 }
 
+// DecodeCursor reads a cursor made by EncodeCursor.
+func DecodeCursor(s string) (*FeedSeekCursor, error) {
+	cur, err := decodeFeedCursor(s)
+	if err == nil && cur == nil {
+		err = fmt.Errorf("empty cursor")
+	}
+	return cur, err
+}
+
+// Moderation states. Pending images are only visible to their owner and moderators;
+// rejected uploads are deleted rather than kept. Disabled images are withheld the same
+// way while staff review a takedown request against them.
+const (
+	ImageStatusApproved = "approved"
+	ImageStatusPending  = "pending"
+	ImageStatusDisabled = "disabled"
+)
+
 type Image struct {
-	ID            uuid.UUID       `json:"id" db:"id"`
-	UserID        uuid.UUID       `json:"user_id" db:"user_id"`
-	Filename      string          `json:"filename" db:"filename"`
-	OriginalName  *string         `json:"original_name" db:"original_name"`
-	FileSize      *int            `json:"file_size" db:"file_size"`
-	Width         *int            `json:"width" db:"width"`
-	Height        *int            `json:"height" db:"height"`
-	Blurhash      *string         `json:"blurhash" db:"blurhash"`
-	DominantColor *string         `json:"dominant_color" db:"dominant_color"`
-	IsNSFW        bool            `json:"is_nsfw" db:"is_nsfw"`
-	AISignature   *string         `json:"ai_signature" db:"ai_signature"`
-	AIProvider    *string         `json:"ai_provider" db:"ai_provider"`
-	ExifData      json.RawMessage `json:"exif_data,omitempty" db:"exif_data"`
-	Caption       *string         `json:"caption" db:"caption"`
-	LikesCount    int             `json:"likes_count" db:"likes_count"`
-	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	ID            uuid.UUID     `json:"id" db:"id"`
+	UserID        uuid.UUID     `json:"user_id" db:"user_id"`
+	Filename      string        `json:"filename" db:"filename"`
+	OriginalName  *string       `json:"original_name" db:"original_name"`
+	FileSize      *int          `json:"file_size" db:"file_size"`
+	Width         *int          `json:"width" db:"width"`
+	Height        *int          `json:"height" db:"height"`
+	Blurhash      *string       `json:"blurhash" db:"blurhash"`
+	DominantColor *string       `json:"dominant_color" db:"dominant_color"`
+	IsNSFW        bool          `json:"is_nsfw" db:"is_nsfw"`
+	Rating        ContentRating `json:"rating,omitempty" db:"rating"`
+	AISignature   *string       `json:"ai_signature" db:"ai_signature"`
+	AIProvider    *string       `json:"ai_provider" db:"ai_provider"`
+	// AIModel is the checkpoint named in the generation parameters, e.g. "flux1-dev"
+	AIModel    *string         `json:"ai_model,omitempty" db:"ai_model"`
+	ExifData   json.RawMessage `json:"exif_data,omitempty" db:"exif_data"`
+	Caption    *string         `json:"caption" db:"caption"`
+	LikesCount int             `json:"likes_count" db:"likes_count"`
+	// CollectedCount is how many users have collected the image
+	CollectedCount int `json:"collected_count" db:"collected_count"`
+	// ModerationStatus is empty on rows read without it and treated as approved
+	ModerationStatus string `json:"moderation_status,omitempty" db:"moderation_status"`
+	// Visibility is one of the ImageVisibility levels; empty on rows read without it
+	Visibility string `json:"visibility,omitempty" db:"visibility"`
+	// License is a Licenses id, empty when the uploader stated none
+	License string `json:"license,omitempty" db:"license"`
+	// FrameCount is set for animations, DurationMS for animations and videos; both are zero for stills
+	FrameCount int `json:"frame_count,omitempty" db:"frame_count"`
+	DurationMS int `json:"duration_ms,omitempty" db:"duration_ms"`
+	// MediaType is MediaTypeImage or MediaTypeVideo; videos show PosterFilename as their still
+	MediaType      string  `json:"media_type,omitempty" db:"media_type"`
+	PosterFilename *string `json:"poster_filename,omitempty" db:"poster_filename"`
+	// SHA256 is the hex digest of the stored file as written; nil for images uploaded before checksums
+	SHA256 *string `json:"sha256,omitempty" db:"sha256"`
+	// ChecksumMismatchAt is set when verification found the stored file no longer matches SHA256
+	ChecksumMismatchAt *time.Time `json:"checksum_mismatch_at,omitempty" db:"checksum_mismatch_at"`
+	// TenantID is the site whose feed the image was uploaded to; nil is the primary site
+	TenantID *uuid.UUID `json:"tenant_id,omitempty" db:"tenant_id"`
+	// FeaturedAt is set while staff feature the image on the front page; FeaturedNote is
+	// the curator's note
+	FeaturedAt   *time.Time `json:"featured_at,omitempty" db:"featured_at"`
+	FeaturedNote *string    `json:"featured_note,omitempty" db:"featured_note"`
+	// RemixOf is the on-site image this one was remixed from; RemixOfURL is set instead
+	// when the source is elsewhere
+	RemixOf    *uuid.UUID `json:"remix_of,omitempty" db:"remix_of"`
+	RemixOfURL *string    `json:"remix_of_url,omitempty" db:"remix_of_url"`
+	// Links are the uploader's external links, at most MaxImageLinks
+	Links ImageLinks `json:"links,omitempty" db:"links"`
+	// Tags are short lowercase labels set by the uploader or staff, at most MaxImageTags
+	Tags ImageTags `json:"tags,omitempty" db:"tags"`
+	// WorkflowKey is the storage key of the ComfyUI workflow sidecar; HasWorkflow reports
+	// one to clients without exposing the key
+	WorkflowKey *string   `json:"-" db:"workflow_key"`
+	HasWorkflow bool      `json:"has_workflow,omitempty" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Media types. Rows read without the column have an empty type and are images.
+const (
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
+)
+
+// ImageWorkflowPrefix is the storage prefix ComfyUI workflow sidecars are written under.
+const ImageWorkflowPrefix = "workflows/"
+
+// IsVideo reports whether the stored file is a video clip.
+func (i *Image) IsVideo() bool { return i.MediaType == MediaTypeVideo }
+
+// IsAnimated reports whether the stored file is a multi-frame animation.
+func (i *Image) IsAnimated() bool { return i.FrameCount > 1 }
+
+// IsPending reports whether the image is held for moderation.
+func (i *Image) IsPending() bool { return i.ModerationStatus == ImageStatusPending }
+
+// IsWithheld reports whether only the owner and staff may see the image: it is held for
+// moderation or disabled pending a takedown review.
+func (i *Image) IsWithheld() bool {
+	return i.ModerationStatus == ImageStatusPending || i.ModerationStatus == ImageStatusDisabled
+}
+
+// Visibility levels. Unlisted images open by direct link but never appear in feeds or
+// galleries; private images are visible to their owner (and staff) only.
+const (
+	ImageVisibilityPublic   = "public"
+	ImageVisibilityUnlisted = "unlisted"
+	ImageVisibilityPrivate  = "private"
+)
+
+// ValidImageVisibility reports whether v is a known visibility level.
+func ValidImageVisibility(v string) bool {
+	switch v {
+	case ImageVisibilityPublic, ImageVisibilityUnlisted, ImageVisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// IsPrivate reports whether only the owner may see the image.
+func (i *Image) IsPrivate() bool { return i.Visibility == ImageVisibilityPrivate }
+
+// IsListed reports whether the image may appear in feeds and galleries. Rows read
+// without the column have an empty visibility and count as public.
+func (i *Image) IsListed() bool {
+	return i.Visibility == "" || i.Visibility == ImageVisibilityPublic
+}
+
+// ImageVersion is what decides whether a client's copy of an image's API resource is
+// current: who may see it, and when the image or its uploader last changed.
+type ImageVersion struct {
+	UserID           uuid.UUID  `db:"user_id"`
+	ModerationStatus string     `db:"moderation_status"`
+	Visibility       string     `db:"visibility"`
+	UpdatedAt        time.Time  `db:"updated_at"`
+	UserUpdatedAt    time.Time  `db:"user_updated_at"`
+	TenantID         *uuid.UUID `db:"tenant_id"`
 }
 
 type ImageWithUser struct {
 	Image
 	Username  string  `json:"username" db:"username"`
 	AvatarURL *string `json:"user_avatar_url" db:"avatar_url"`
+	// UserVerified says whether the uploader carries the verified badge
+	UserVerified bool `json:"user_verified" db:"user_verified"`
+	// Collected says whether the signed-in viewer collected the image; nil when listed
+	// for nobody in particular
+	Collected *bool `json:"collected,omitempty" db:"collected"`
+	// Display is how the viewer's preferences show the image, set on listings
+	Display Display `json:"display,omitempty" db:"-"`
 }
 
 type Like struct {
@@ -54,6 +179,14 @@ type Collect struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// Collector is a user who collected an image, and when.
+type Collector struct {
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Username    string    `json:"username" db:"username"`
+	AvatarURL   *string   `json:"avatar_url" db:"avatar_url"`
+	CollectedAt time.Time `json:"collected_at" db:"collected_at"`
+}
+
 type UploadResponse struct {
 	ID            uuid.UUID `json:"id"`
 	Filename      string    `json:"filename"`
@@ -65,20 +198,37 @@ type UploadResponse struct {
 	FileSize      *int      `json:"file_size"`
 	Caption       *string   `json:"caption"`
 	CreatedAt     time.Time `json:"created_at"`
+	// Pending tells the uploader the image is waiting for review
+	Pending        bool    `json:"pending,omitempty"`
+	Visibility     string  `json:"visibility,omitempty"`
+	License        string  `json:"license,omitempty"`
+	FrameCount     int     `json:"frame_count,omitempty"`
+	DurationMS     int     `json:"duration_ms,omitempty"`
+	MediaType      string  `json:"media_type,omitempty"`
+	PosterFilename *string `json:"poster_filename,omitempty"`
+	SHA256         *string `json:"sha256,omitempty"`
 }
 
 func (i *Image) ToUploadResponse() UploadResponse {
 	return UploadResponse{
-		ID:            i.ID,
-		Filename:      i.Filename,
-		OriginalName:  i.OriginalName,
-		Width:         i.Width,
-		Height:        i.Height,
-		Blurhash:      i.Blurhash,
-		DominantColor: i.DominantColor,
-		FileSize:      i.FileSize,
-		Caption:       i.Caption,
-		CreatedAt:     i.CreatedAt,
+		ID:             i.ID,
+		Filename:       i.Filename,
+		OriginalName:   i.OriginalName,
+		Width:          i.Width,
+		Height:         i.Height,
+		Blurhash:       i.Blurhash,
+		DominantColor:  i.DominantColor,
+		FileSize:       i.FileSize,
+		Caption:        i.Caption,
+		CreatedAt:      i.CreatedAt,
+		Pending:        i.IsPending(),
+		Visibility:     i.Visibility,
+		FrameCount:     i.FrameCount,
+		DurationMS:     i.DurationMS,
+		MediaType:      i.MediaType,
+		PosterFilename: i.PosterFilename,
+		License:        i.License,
+		SHA256:         i.SHA256,
 	}
 }
 
@@ -87,4 +237,9 @@ type FeedResponse struct {
 	Page       int             `json:"page"`
 	Total      int             `json:"total"`
 	NextCursor string          `json:"next_cursor,omitempty"`
+	// SinceCursor marks the newest image seen; pass it as since to fetch only newer ones
+	SinceCursor string `json:"since_cursor,omitempty"`
+	// Truncated is set on since requests with more new images than the limit; the
+	// client should reload the first page instead
+	Truncated bool `json:"truncated,omitempty"`
 }
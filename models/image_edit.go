@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Fields recorded in an image's edit history. For ImageEditFile the old value is the
+// storage key the previous file was versioned under.
+const (
+	ImageEditFile    = "file"
+	ImageEditTitle   = "title"
+	ImageEditCaption = "caption"
+	ImageEditRating  = "rating"
+	ImageEditLinks   = "links"
+	ImageEditTags    = "tags"
+)
+
+// ImageVersionPrefix is the storage prefix previous files of replaced images are kept
+// under, one directory per image.
+const ImageVersionPrefix = "versions/"
+
+// ImageEdit is one change to an image after upload. ByModerator is set when staff changed
+// someone else's image. EditorUsername is joined in for display and is nil once the
+// editor's account is gone.
+type ImageEdit struct {
+	ID             int64      `db:"id" json:"id"`
+	ImageID        uuid.UUID  `db:"image_id" json:"image_id"`
+	EditorID       *uuid.UUID `db:"editor_id" json:"editor_id"`
+	EditorUsername *string    `db:"editor_username" json:"editor_username"`
+	ByModerator    bool       `db:"by_moderator" json:"by_moderator"`
+	Field          string     `db:"field" json:"field"`
+	OldValue       *string    `db:"old_value" json:"old_value"`
+	NewValue       *string    `db:"new_value" json:"new_value"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// MetaEdits returns the edits that setting title, caption and rating on img would make,
+// leaving out fields that are nil or unchanged. Editor and attribution are left to the
+// caller.
+func MetaEdits(img *Image, title, caption *string, rating *ContentRating) []ImageEdit {
+	var out []ImageEdit
+	add := func(field string, old *string, next string) {
+		if (old == nil && next == "") || (old != nil && *old == next) {
+			return
+		}
+		v := next
+		out = append(out, ImageEdit{ImageID: img.ID, Field: field, OldValue: old, NewValue: &v})
+	}
+	if title != nil {
+		add(ImageEditTitle, img.OriginalName, *title)
+	}
+	if caption != nil {
+		add(ImageEditCaption, img.Caption, *caption)
+	}
+	if rating != nil {
+		old := string(img.EffectiveRating())
+		add(ImageEditRating, &old, string(*rating))
+	}
+	return out
+}
+
+// LinksEdit returns the edit replacing img's links with links, with both lists as JSON,
+// and whether they differ.
+func LinksEdit(img *Image, links ImageLinks) (ImageEdit, bool) {
+	encode := func(l ImageLinks) string {
+		out := ImageLinks{}
+		for _, link := range l {
+			out = append(out, ImageLink{Label: link.Label, URL: link.URL})
+		}
+		b, _ := json.Marshal(out)
+		return string(b)
+	}
+	o, n := encode(img.Links), encode(links)
+	if o == n {
+		return ImageEdit{}, false
+	}
+	return ImageEdit{ImageID: img.ID, Field: ImageEditLinks, OldValue: &o, NewValue: &n}, true
+}
+
+// TagsEdit returns the edit replacing img's tags with tags, with both lists as JSON, and
+// whether they differ.
+func TagsEdit(img *Image, tags ImageTags) (ImageEdit, bool) {
+	encode := func(t ImageTags) string {
+		if t == nil {
+			t = ImageTags{}
+		}
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+	o, n := encode(img.Tags), encode(tags)
+	if o == n {
+		return ImageEdit{}, false
+	}
+	return ImageEdit{ImageID: img.ID, Field: ImageEditTags, OldValue: &o, NewValue: &n}, true
+}
+
+type ImageEditRepository struct {
+	db *sqlx.DB
+}
+
+func NewImageEditRepository(db *sqlx.DB) *ImageEditRepository {
+	return &ImageEditRepository{db: db}
+}
+
+func (r *ImageEditRepository) Record(e *ImageEdit) error {
+	return r.db.QueryRow(`INSERT INTO image_edits (image_id, editor_id, by_moderator, field, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		e.ImageID, e.EditorID, e.ByModerator, e.Field, e.OldValue, e.NewValue).Scan(&e.ID, &e.CreatedAt)
+}
+
+// ListForImage returns an image's edits newest first.
+func (r *ImageEditRepository) ListForImage(imageID uuid.UUID, limit int) ([]ImageEdit, error) {
+	out := []ImageEdit{}
+	err := r.db.Select(&out, `SELECT e.*, u.username AS editor_username
+		FROM image_edits e
+		LEFT JOIN users u ON u.id = e.editor_id
+		WHERE e.image_id = $1
+		ORDER BY e.created_at DESC, e.id DESC LIMIT $2`, imageID, limit)
+	return out, err
+}
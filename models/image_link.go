@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on an image's external links.
+const (
+	MaxImageLinks     = 3
+	imageLinkMaxLen   = 500
+	imageLinkLabelMax = 60
+)
+
+// Link rel values. Links from unverified uploaders are not endorsed.
+const (
+	ImageLinkRel         = "ugc noopener"
+	ImageLinkRelNoFollow = "ugc nofollow noopener"
+)
+
+// ImageLink is an external link shown with an image, such as a generator's share page or
+// a workflow file. Rel is filled in for responses and never stored.
+type ImageLink struct {
+	Label string `json:"label,omitempty"`
+	URL   string `json:"url"`
+	Rel   string `json:"rel,omitempty"`
+}
+
+// ImageLinks is an image's external links, stored as a JSONB array.
+type ImageLinks []ImageLink
+
+// Value stores the links as JSONB.
+func (l ImageLinks) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan reads the links from a JSONB column.
+func (l *ImageLinks) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("image_links: unsupported type")
+	}
+	*l = nil
+	return json.Unmarshal(b, l)
+}
+
+// WithRel returns the links with Rel set for display: nofollow unless the uploader is
+// verified.
+func (l ImageLinks) WithRel(verified bool) ImageLinks {
+	if len(l) == 0 {
+		return l
+	}
+	rel := ImageLinkRelNoFollow
+	if verified {
+		rel = ImageLinkRel
+	}
+	out := make(ImageLinks, len(l))
+	for i, link := range l {
+		link.Rel = rel
+		out[i] = link
+	}
+	return out
+}
+
+// NormalizeImageLinks trims and validates links submitted for an image: at most
+// MaxImageLinks absolute http(s) URLs without credentials, each listed once, with
+// optional short labels.
+func NormalizeImageLinks(in []ImageLink) (ImageLinks, error) {
+	if len(in) > MaxImageLinks {
+		return nil, errors.New("an image can have at most 3 links")
+	}
+	out := ImageLinks{}
+	seen := map[string]bool{}
+	for _, link := range in {
+		raw := strings.TrimSpace(link.URL)
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || len(raw) > imageLinkMaxLen {
+			return nil, errors.New("links must be absolute http(s) URLs of at most 500 characters")
+		}
+		label := strings.Join(strings.Fields(link.Label), " ")
+		if utf8.RuneCountInString(label) > imageLinkLabelMax {
+			return nil, errors.New("link labels can be at most 60 characters")
+		}
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			out = append(out, ImageLink{Label: label, URL: s})
+		}
+	}
+	return out, nil
+}
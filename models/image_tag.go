@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on an image's tags.
+const (
+	MaxImageTags   = 10
+	imageTagMaxLen = 32
+)
+
+// ImageTags is an image's tags, stored as a JSONB array of strings.
+type ImageTags []string
+
+// Value stores the tags as JSONB.
+func (t ImageTags) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
+
+// Scan reads the tags from a JSONB column.
+func (t *ImageTags) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("image_tags: unsupported type")
+	}
+	*t = nil
+	return json.Unmarshal(b, t)
+}
+
+// NormalizeImageTags lowercases and validates tags submitted for an image: at most
+// MaxImageTags, each of letters, digits, spaces, hyphens and underscores, with inner
+// whitespace collapsed. Empty tags and repeats are dropped.
+func NormalizeImageTags(in []string) (ImageTags, error) {
+	out := ImageTags{}
+	seen := map[string]bool{}
+	for _, raw := range in {
+		tag := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > imageTagMaxLen {
+			return nil, errors.New("tags can be at most 32 characters")
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' && r != '_' {
+				return nil, errors.New("tags can contain only letters, digits, spaces, hyphens and underscores")
+			}
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > MaxImageTags {
+		return nil, errors.New("an image can have at most 10 tags")
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,12 +15,23 @@ type UserRepositoryInterface interface {
 	    GetByEmail(ctx context.Context, email string) (*User, error)
     GetByUsername(ctx context.Context, username string) (*User, error)
 	    GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
 	UpdateProfile(id uuid.UUID, updates UpdateUserRequest) (*User, error)
 	UpdateEmail(id uuid.UUID, email string) error
 	UpdatePassword(id uuid.UUID, passwordHash string) error
 	DeleteUser(id uuid.UUID) error
+	// BulkApply runs a Bulk* user action on ids in one transaction and returns the ids it changed
+	BulkApply(action string, ids []uuid.UUID) ([]uuid.UUID, error)
 	SetAdmin(id uuid.UUID, isAdmin bool) error
+	// SetVerified grants or revokes the verified badge; reason is shown beside it
+	SetVerified(id uuid.UUID, verified bool, reason *string) error
+	// ListPendingApproval and Approve manage the registration approval queue
+	ListPendingApproval(page, limit int) ([]User, int, error)
+	Approve(id uuid.UUID) (bool, error)
 	SetDisabled(id uuid.UUID, disabled bool) error
+	Suspend(id uuid.UUID, reason string, until *time.Time) error
+	ReleaseExpiredSuspensions() ([]uuid.UUID, error)
+	SetShadowbanned(id uuid.UUID, shadowbanned bool) error
 	SetModerator(id uuid.UUID, isModerator bool) error
 	ListUsers(page, limit int) ([]User, int, error)
 	SearchUsers(q string, page, limit int) ([]User, int, error)
@@ -28,19 +40,44 @@ type UserRepositoryInterface interface {
 
 type ImageRepositoryInterface interface {
 	Create(image *Image) error
-	GetFeed(page, limit int, showNSFW bool) ([]ImageWithUser, int, error)
-	GetFeedSeek(limit int, showNSFW bool, cursorEncoded string) ([]ImageWithUser, string, error)
-	CountFeed(showNSFW bool) (int, error)
+	GetFeed(page, limit int, filter FeedFilter) ([]ImageWithUser, int, error)
+	GetFeedSeek(limit int, filter FeedFilter, cursorEncoded string) ([]ImageWithUser, string, error)
+	GetFeedSince(limit int, filter FeedFilter, since FeedSeekCursor) ([]ImageWithUser, bool, error)
+	CountFeed(filter FeedFilter) (int, error)
+	// AdminList pages through all images on all sites for staff
+	AdminList(page, limit int) ([]ImageWithUser, int, error)
 	    GetByID(ctx context.Context, id uuid.UUID) (*ImageWithUser, error)
-	GetUserImages(userID uuid.UUID, page, limit int) ([]ImageWithUser, int, error)
-	GetUserImagesSeek(userID uuid.UUID, limit int, cursorEncoded string) ([]ImageWithUser, string, error)
+	// GetVersion reads the fields ETags of the image's resource are derived from
+	GetVersion(ctx context.Context, id uuid.UUID) (*ImageVersion, error)
+	GetUserImages(userID uuid.UUID, page, limit int, includeHidden bool) ([]ImageWithUser, int, error)
+	GetUserImagesSeek(userID uuid.UUID, limit int, cursorEncoded string, includeHidden bool) ([]ImageWithUser, string, error)
 	CountUserImages(userID uuid.UUID) (int, error)
 	Delete(id uuid.UUID) error
 	SetNSFW(id uuid.UUID, isNSFW bool) error
+	SetRating(id uuid.UUID, rating ContentRating) error
+	// Feature and Unfeature curate the front page picks GetFeatured lists
+	Feature(id uuid.UUID, note *string) error
+	Unfeature(id uuid.UUID) error
+	GetFeatured(limit int, filter FeedFilter) ([]ImageWithUser, error)
+	// BulkDelete and BulkSetNSFW change many images in one transaction
+	BulkDelete(ids []uuid.UUID) ([]Image, error)
+	BulkSetNSFW(ids []uuid.UUID, isNSFW bool) ([]uuid.UUID, error)
+	SetVisibility(id uuid.UUID, visibility string) error
+	SetLicense(id uuid.UUID, license string) error
+	SetRemixOf(id uuid.UUID, parent *uuid.UUID, sourceURL *string) error
+	SetLinks(id uuid.UUID, links ImageLinks) error
+	SetTags(id uuid.UUID, tags ImageTags) error
+	TagsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]ImageTags, error)
+	Remixes(parentID uuid.UUID, limit int) ([]ImageWithUser, error)
 	CountByUser(userID uuid.UUID) (int, error)
-	UpdateMeta(id uuid.UUID, title *string, caption *string, isNSFW *bool) error
+	StorageByUser(userID uuid.UUID) (int64, error)
+	UpdateMeta(id uuid.UUID, title *string, caption *string, rating *ContentRating) error
 	UpdateFilename(id uuid.UUID, newFilename string) error
+	ReplaceFile(img *Image) error
 	GetImagesByFilename(filename string) ([]ImageWithUser, error)
+	ForTenant(tenant *uuid.UUID) ImageRepositoryInterface
+	// ForViewer tailors listings to viewer: Collected is set and feeds skip users they blocked
+	ForViewer(viewer uuid.UUID) ImageRepositoryInterface
 }
 
 type LikeRepositoryInterface interface {
@@ -53,13 +90,39 @@ type CollectRepositoryInterface interface {
 	Create(userID, imageID uuid.UUID) error
 	Delete(userID, imageID uuid.UUID) error
 	GetByUser(userID uuid.UUID, imageID uuid.UUID) (*Collect, error)
-	GetUserCollections(userID uuid.UUID, page, limit int) ([]ImageWithUser, int, error)
-	GetUserCollectionsSeek(userID uuid.UUID, limit int, cursorEncoded string) ([]ImageWithUser, string, error)
+	CountForImage(imageID uuid.UUID) (int, error)
+	Collectors(imageID uuid.UUID, page, limit int) ([]Collector, int, error)
+	GetUserCollections(userID uuid.UUID, page, limit int, includePrivate bool) ([]ImageWithUser, int, error)
+	GetUserCollectionsSeek(userID uuid.UUID, limit int, cursorEncoded string, includePrivate bool) ([]ImageWithUser, string, error)
+	ForViewer(viewer uuid.UUID) CollectRepositoryInterface
+}
+
+// BoardRepositoryInterface manages a user's named, ordered collections.
+type BoardRepositoryInterface interface {
+	ListForUser(userID uuid.UUID, includePrivate bool) ([]Board, error)
+	Get(id uuid.UUID) (*Board, error)
+	Create(b *Board) error
+	Update(b *Board) error
+	Delete(id uuid.UUID) error
+	Reorder(userID uuid.UUID, ids []uuid.UUID) error
+	EnsureDefault(userID uuid.UUID) (*Board, error)
+	AddImage(boardID, imageID uuid.UUID) (bool, error)
+	RemoveImage(boardID, imageID uuid.UUID) (bool, error)
+	ReorderImages(boardID uuid.UUID, ids []uuid.UUID) error
+	Images(boardID uuid.UUID, page, limit int) ([]ImageWithUser, int, error)
+	BoardsWithImage(userID, imageID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type InviteRepositoryInterface interface {
 	Create(maxUses *int, expiresAt *time.Time, createdBy *uuid.UUID) (*Invite, error)
+	Insert(inv *Invite) error
+	InsertWithinQuota(inv *Invite, quota int) error
+	CountCreatedThisMonth(userID uuid.UUID) (int, error)
+	ListByCreator(userID uuid.UUID, limit int) ([]Invite, error)
+	RecordInviteeWithTx(tx *sqlx.Tx, userID uuid.UUID, inv *Invite) error
+	TreeEdges() ([]InviteTreeEdge, error)
 	List(page, limit int) ([]Invite, int, error)
+	GetByID(id uuid.UUID) (*Invite, error)
 	GetByCode(code string) (*Invite, error)
 	GetByCodeWithTx(tx *sqlx.Tx, code string) (*Invite, error)
 	Consume(code string) (*Invite, error)
@@ -79,4 +142,207 @@ type PageRepositoryInterface interface {
 	GetPublishedBySlug(slug string) (*Page, error)
 	ListAll(page, limit int) ([]Page, int, error)
 	ListPublished() ([]Page, error)
+	ListRevisions(pageID uuid.UUID, page, limit int) ([]PageRevision, int, error)
+	GetRevision(pageID uuid.UUID, rev int) (*PageRevision, error)
+	ForTenant(tenant *uuid.UUID) PageRepositoryInterface
+}
+
+// Persistent email outbox
+type MailOutboxRepositoryInterface interface {
+	Enqueue(to, subject, body, html string) error
+	ClaimDue(limit int, lease time.Duration) ([]OutboxMail, error)
+	MarkSent(id uuid.UUID) error
+	MarkFailed(id uuid.UUID, errMsg string, next time.Time, dead bool) error
+	List(status string, page, limit int) ([]OutboxMail, int, error)
+	Retry(id uuid.UUID) error
+	Delete(id uuid.UUID) error
+	PurgeSent(before time.Time) (int, error)
+	Backlog() (*MailBacklog, error)
+}
+
+// Background job queue
+type JobRepositoryInterface interface {
+	Enqueue(j *Job) (bool, error)
+	ClaimDue(kinds []string, limit int, lease time.Duration) ([]Job, error)
+	Extend(id uuid.UUID, lease time.Duration) error
+	MarkDone(id uuid.UUID, result json.RawMessage) error
+	SetProgress(id uuid.UUID, result json.RawMessage) error
+	MarkFailed(id uuid.UUID, result json.RawMessage, errMsg string, next time.Time, dead bool) error
+	Rearm(id uuid.UUID, result json.RawMessage, errMsg string, next time.Time) error
+	RunNow(uniqueKey string) error
+	Get(id uuid.UUID) (*Job, error)
+	List(status, kind string, page, limit int) ([]Job, int, error)
+	Retry(id uuid.UUID) error
+	Purge(before time.Time) (int, error)
+	Summary() ([]JobCount, error)
+}
+
+type NotificationRepositoryInterface interface {
+	Create(n *Notification) error
+	List(userID uuid.UUID, unreadOnly bool, page, limit int) ([]Notification, int, error)
+	CountUnread(userID uuid.UUID) (int, error)
+	MarkRead(userID uuid.UUID, ids []uuid.UUID) (int, error)
+	Delete(userID, id uuid.UUID) error
+	ClaimDigestRecipients(interval time.Duration, limit int) ([]DigestRecipient, error)
+	PendingDigest(userID uuid.UUID, limit int) ([]Notification, error)
+	MarkEmailed(userID uuid.UUID, before time.Time) error
+	PurgeRead(before time.Time) (int, error)
+	NotifyAdmins(n *Notification) error
+}
+
+type WebhookRepositoryInterface interface {
+	Create(w *Webhook) error
+	Update(w *Webhook) error
+	Get(id uuid.UUID) (*Webhook, error)
+	List() ([]Webhook, error)
+	ListEnabled() ([]Webhook, error)
+	Delete(id uuid.UUID) error
+	EnqueueDelivery(webhookID uuid.UUID, event, payload string) error
+	ClaimDue(limit int, lease time.Duration) ([]WebhookDelivery, error)
+	MarkDelivered(id uuid.UUID, responseStatus int) error
+	MarkFailed(id uuid.UUID, responseStatus int, errMsg string, next time.Time, dead bool) error
+	ListDeliveries(webhookID *uuid.UUID, status string, page, limit int) ([]WebhookDelivery, int, error)
+	RetryDelivery(id uuid.UUID) error
+	PurgeDeliveries(before time.Time) (int, error)
+}
+
+type StatsRepositoryInterface interface {
+	AdminStats(rng StatsRange) (*AdminStats, error)
+	UserStatsSummary(userID uuid.UUID) (*UserStatsSummary, error)
+	UserStats(userID uuid.UUID) (*UserStats, error)
+}
+
+type ModerationRepositoryInterface interface {
+	CountApprovedByUser(userID uuid.UUID) (int, error)
+	ListPending(page, limit int) ([]ImageWithUser, int, error)
+	Approve(id uuid.UUID) (bool, error)
+}
+
+type BanRepositoryInterface interface {
+	Create(b *Ban) error
+	Get(id uuid.UUID) (*Ban, error)
+	List() ([]Ban, error)
+	ListActive() ([]Ban, error)
+	Delete(id uuid.UUID) error
+	AddAudit(a *BanAudit) error
+	ListAudit(page, limit int) ([]BanAudit, int, error)
+}
+
+type LoginEventRepositoryInterface interface {
+	Record(e *LoginEvent) error
+	ListForUser(userID uuid.UUID, limit int) ([]LoginEvent, error)
+}
+
+type AuditRepositoryInterface interface {
+	Add(a *AdminAudit) error
+	List(targetUserID *uuid.UUID, page, limit int) ([]AdminAudit, int, error)
+	StartImpersonation(s *ImpersonationSession) error
+	GetImpersonation(id uuid.UUID) (*ImpersonationSession, error)
+	EndImpersonation(id uuid.UUID) error
+}
+
+type BlockRepositoryInterface interface {
+	Block(blocker, blocked uuid.UUID) error
+	Unblock(blocker, blocked uuid.UUID) error
+	IsBlocked(blocker, blocked uuid.UUID) (bool, error)
+	Count(blocker uuid.UUID) (int, error)
+	ListBlocked(blocker uuid.UUID) ([]BlockedUser, error)
+}
+
+type VerificationRepositoryInterface interface {
+	GetProof(userID uuid.UUID) (*VerificationProof, error)
+	SaveProof(p *VerificationProof) error
+	MarkChecked(userID uuid.UUID) error
+	DeleteProof(userID uuid.UUID) error
+}
+
+type LegalRepositoryInterface interface {
+	ForTenant(tenant *uuid.UUID) LegalRepositoryInterface
+	Accepted(userID uuid.UUID) (LegalVersions, error)
+	Record(userID uuid.UUID, v LegalVersions, ipHash string) error
+	RecordWithTx(tx *sqlx.Tx, userID uuid.UUID, v LegalVersions, ipHash string) error
+	History(userID uuid.UUID, limit int) ([]LegalConsent, error)
+	LatestRevisions() (LegalVersions, error)
+	Publish(v LegalVersions) error
+}
+
+type ImageEditRepositoryInterface interface {
+	Record(e *ImageEdit) error
+	ListForImage(imageID uuid.UUID, limit int) ([]ImageEdit, error)
+}
+
+type TakedownRepositoryInterface interface {
+	Create(t *TakedownRequest) error
+	Get(id uuid.UUID) (*TakedownRequest, error)
+	ImageByFilename(name string) (uuid.UUID, error)
+	List(status string, page, limit int) ([]TakedownRequest, int, error)
+	Apply(id uuid.UUID, action, note string, actorID uuid.UUID) (bool, error)
+	AddEvent(id uuid.UUID, action, note string, actorID uuid.UUID) error
+	Events(id uuid.UUID) ([]TakedownEvent, error)
+}
+
+type UsernameHistoryRepositoryInterface interface {
+	Record(userID uuid.UUID, oldUsername, newUsername string) error
+	ResolveOld(username string, since time.Time) (uuid.UUID, error)
+	ListForUser(userID uuid.UUID) ([]UsernameChange, error)
+}
+
+type SecurityEventRepositoryInterface interface {
+	InsertBatch(events []SecurityEvent) error
+	List(f SecurityEventFilter, page, limit int) ([]SecurityEvent, int, error)
+	Purge(before, lowBefore time.Time) (int, error)
+}
+
+type CSPReportRepositoryInterface interface {
+	Record(r CSPReport) error
+	List(page, limit int) ([]CSPReport, int, error)
+	Purge(before time.Time) (int, error)
+	Clear() error
+}
+
+type AnnouncementRepositoryInterface interface {
+	Create(a *Announcement) error
+	Get(id uuid.UUID) (*Announcement, error)
+	List() ([]Announcement, error)
+	ListActive() ([]Announcement, error)
+	Update(a *Announcement) error
+	Delete(id uuid.UUID) error
+}
+
+// ChallengeRepositoryInterface manages themed prompts and the images entered into them.
+type ChallengeRepositoryInterface interface {
+	Create(c *Challenge) error
+	Get(id uuid.UUID) (*Challenge, error)
+	List(status string, page, limit int) ([]Challenge, int, error)
+	Update(c *Challenge) error
+	Delete(id uuid.UUID) error
+	Enter(challengeID, imageID, userID uuid.UUID) (bool, error)
+	Withdraw(challengeID, imageID uuid.UUID) (bool, error)
+	CountEntries(challengeID, userID uuid.UUID) (int, error)
+	Leaderboard(challengeID uuid.UUID, page, limit int) ([]ChallengeEntry, int, error)
+}
+
+type NavigationRepositoryInterface interface {
+	List() ([]NavigationItem, error)
+	Replace(menu string, items []NavigationItem) error
+}
+
+type SnippetRepositoryInterface interface {
+	List() ([]Snippet, error)
+	Get(key string) (*Snippet, error)
+	Upsert(s *Snippet) error
+	Delete(key string) error
+}
+
+type LocaleStringRepositoryInterface interface {
+	List() ([]LocaleString, error)
+	Replace(locale string, set map[string]string, remove []string, by *uuid.UUID) error
+}
+
+type TenantRepositoryInterface interface {
+	List() ([]Tenant, error)
+	Get(id uuid.UUID) (*Tenant, error)
+	Create(t *Tenant) error
+	Update(t *Tenant) error
+	Delete(id uuid.UUID) error
 }
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Job statuses. One-shot jobs move pending -> running -> done, or back to pending with a
+// later run_at after a failure, and to dead once attempts are exhausted. Periodic jobs go
+// back to pending after every run and are never done or dead.
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusDead    = "dead"
+)
+
+// Job is a unit of background work. Payload and Result are JSON; payloads never carry
+// secrets since they are stored and shown to admins as-is.
+type Job struct {
+	ID          uuid.UUID        `db:"id" json:"id"`
+	Kind        string           `db:"kind" json:"kind"`
+	Payload     json.RawMessage  `db:"payload" json:"payload"`
+	UniqueKey   *string          `db:"unique_key" json:"unique_key,omitempty"`
+	Periodic    bool             `db:"periodic" json:"periodic"`
+	Status      string           `db:"status" json:"status"`
+	Attempts    int              `db:"attempts" json:"attempts"`
+	MaxAttempts int              `db:"max_attempts" json:"max_attempts"`
+	LastError   *string          `db:"last_error" json:"last_error"`
+	Result      *json.RawMessage `db:"result" json:"result"`
+	RunAt       time.Time        `db:"run_at" json:"run_at"`
+	LockedUntil *time.Time       `db:"locked_until" json:"-"`
+	CreatedAt   time.Time        `db:"created_at" json:"created_at"`
+	StartedAt   *time.Time       `db:"started_at" json:"started_at"`
+	FinishedAt  *time.Time       `db:"finished_at" json:"finished_at"`
+}
+
+type JobRepository struct {
+	db *sqlx.DB
+}
+
+func NewJobRepository(db *sqlx.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue inserts j and fills in the stored row. When j has a UniqueKey that a pending or
+// running job already holds, nothing is inserted, j is filled from that job and created is
+// false.
+func (r *JobRepository) Enqueue(j *Job) (created bool, err error) {
+	if len(j.Payload) == 0 {
+		j.Payload = json.RawMessage(`{}`)
+	}
+	if j.MaxAttempts <= 0 {
+		j.MaxAttempts = 5
+	}
+	if j.RunAt.IsZero() {
+		j.RunAt = time.Now()
+	}
+	err = r.db.Get(j, `INSERT INTO jobs (kind, payload, unique_key, periodic, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+		RETURNING *`, j.Kind, j.Payload, j.UniqueKey, j.Periodic, j.MaxAttempts, j.RunAt)
+	if errors.Is(err, sql.ErrNoRows) && j.UniqueKey != nil {
+		err = r.db.Get(j, `SELECT * FROM jobs WHERE unique_key = $1 AND status IN ('pending', 'running')`, *j.UniqueKey)
+		return false, err
+	}
+	return err == nil, err
+}
+
+// ClaimDue leases up to limit due jobs of the given kinds. Jobs whose lease expired (the
+// instance running them crashed) are reclaimed.
+func (r *JobRepository) ClaimDue(kinds []string, limit int, lease time.Duration) ([]Job, error) {
+	out := []Job{}
+	if len(kinds) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`UPDATE jobs SET status = 'running', attempts = attempts + 1, started_at = NOW(),
+			locked_until = NOW() + (? * INTERVAL '1 second')
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE kind IN (?)
+			  AND ((status = 'pending' AND run_at <= NOW())
+			   OR (status = 'running' AND locked_until < NOW()))
+			ORDER BY run_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, int(lease.Seconds()), kinds, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Select(&out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// Extend pushes out the lease of a running job so long work is not reclaimed mid-run.
+func (r *JobRepository) Extend(id uuid.UUID, lease time.Duration) error {
+	_, err := r.db.Exec(`UPDATE jobs SET locked_until = NOW() + ($2 * INTERVAL '1 second') WHERE id = $1 AND status = 'running'`, id, int(lease.Seconds()))
+	return err
+}
+
+// MarkDone records a successful one-shot run.
+func (r *JobRepository) MarkDone(id uuid.UUID, result json.RawMessage) error {
+	_, err := r.db.Exec(`UPDATE jobs SET status = 'done', result = $2, last_error = NULL, finished_at = NOW(), locked_until = NULL WHERE id = $1`, id, nullJSON(result))
+	return err
+}
+
+// SetProgress stores a running job's interim result so pollers can follow it.
+func (r *JobRepository) SetProgress(id uuid.UUID, result json.RawMessage) error {
+	_, err := r.db.Exec(`UPDATE jobs SET result = $2 WHERE id = $1 AND status = 'running'`, id, nullJSON(result))
+	return err
+}
+
+// MarkFailed reschedules a one-shot job at next, or dead-letters it when dead is set. A
+// non-nil result (e.g. a partial export report) is kept alongside the error.
+func (r *JobRepository) MarkFailed(id uuid.UUID, result json.RawMessage, errMsg string, next time.Time, dead bool) error {
+	status := JobStatusPending
+	var finished *time.Time
+	if dead {
+		status = JobStatusDead
+		now := time.Now()
+		finished = &now
+	}
+	_, err := r.db.Exec(`UPDATE jobs SET status = $2, last_error = $3, run_at = $4, finished_at = $5, result = COALESCE($6, result), locked_until = NULL WHERE id = $1`,
+		id, status, errMsg, next, finished, nullJSON(result))
+	return err
+}
+
+// Rearm returns a periodic job to pending for its next run, keeping the outcome of this one.
+// errMsg is empty after a successful run.
+func (r *JobRepository) Rearm(id uuid.UUID, result json.RawMessage, errMsg string, next time.Time) error {
+	var lastErr *string
+	if errMsg != "" {
+		lastErr = &errMsg
+	}
+	_, err := r.db.Exec(`UPDATE jobs SET status = 'pending', attempts = 0, result = COALESCE($2, result), last_error = $3,
+		run_at = $4, finished_at = NOW(), locked_until = NULL WHERE id = $1`, id, nullJSON(result), lastErr, next)
+	return err
+}
+
+// RunNow makes a pending job with the given unique key due immediately.
+func (r *JobRepository) RunNow(uniqueKey string) error {
+	_, err := r.db.Exec(`UPDATE jobs SET run_at = NOW() WHERE unique_key = $1 AND status = 'pending' AND run_at > NOW()`, uniqueKey)
+	return err
+}
+
+func (r *JobRepository) Get(id uuid.UUID) (*Job, error) {
+	var j Job
+	if err := r.db.Get(&j, `SELECT * FROM jobs WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// List returns jobs newest first, optionally filtered by status and kind.
+func (r *JobRepository) List(status, kind string, page, limit int) ([]Job, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM jobs WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)`, status, kind); err != nil {
+		return nil, 0, err
+	}
+	out := []Job{}
+	err := r.db.Select(&out, `SELECT * FROM jobs WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC LIMIT $3 OFFSET $4`, status, kind, limit, offset)
+	return out, total, err
+}
+
+// Retry requeues a dead or pending job for an immediate run with a fresh attempt budget.
+func (r *JobRepository) Retry(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE jobs SET status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL, locked_until = NULL WHERE id = $1 AND status IN ('pending', 'dead')`, id)
+	return err
+}
+
+// Purge deletes finished one-shot jobs older than the cutoff.
+func (r *JobRepository) Purge(before time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM jobs WHERE NOT periodic AND status IN ('done', 'dead') AND finished_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return []byte(b)
+}
+
+// JobCount is the number of jobs of one kind in one status. NextRunAt is the earliest
+// run_at among them, for pending jobs.
+type JobCount struct {
+	Kind      string     `db:"kind" json:"kind"`
+	Status    string     `db:"status" json:"status"`
+	Count     int        `db:"count" json:"count"`
+	NextRunAt *time.Time `db:"next_run_at" json:"next_run_at,omitempty"`
+}
+
+// Summary counts jobs by kind and status.
+func (r *JobRepository) Summary() ([]JobCount, error) {
+	out := []JobCount{}
+	err := r.db.Select(&out, `SELECT kind, status, COUNT(*) AS count,
+			MIN(run_at) FILTER (WHERE status = 'pending') AS next_run_at
+		FROM jobs GROUP BY kind, status ORDER BY kind, status`)
+	return out, err
+}
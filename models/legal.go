@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Slugs of the pages whose revisions version each site's terms of service and privacy
+// policy.
+const (
+	TermsPageSlug   = "terms"
+	PrivacyPageSlug = "privacy"
+)
+
+// LegalVersions pairs a terms revision with a privacy revision. In the site's required
+// versions 0 means that document is not tracked.
+type LegalVersions struct {
+	Terms   int `db:"terms_revision" json:"terms_revision"`
+	Privacy int `db:"privacy_revision" json:"privacy_revision"`
+}
+
+// Tracked reports whether either document requires consent.
+func (v LegalVersions) Tracked() bool {
+	return v.Terms > 0 || v.Privacy > 0
+}
+
+// Satisfies reports whether these accepted versions are the required ones. Any other
+// revision needs accepting again, including an older one after a page was recreated.
+func (v LegalVersions) Satisfies(required LegalVersions) bool {
+	return (required.Terms == 0 || v.Terms == required.Terms) && (required.Privacy == 0 || v.Privacy == required.Privacy)
+}
+
+// RequiredLegalVersions returns the versions s requires users to have accepted.
+func (s SiteSettings) RequiredLegalVersions() LegalVersions {
+	return LegalVersions{Terms: s.TermsRevision, Privacy: s.PrivacyRevision}
+}
+
+// LegalConsent is one recorded acceptance.
+type LegalConsent struct {
+	ID     int64     `db:"id" json:"id"`
+	UserID uuid.UUID `db:"user_id" json:"-"`
+	LegalVersions
+	IPHash    string    `db:"ip_hash" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// LegalRepository tracks the terms of one site: the primary site, or the tenant it was
+// scoped to with ForTenant. Each site versions its own terms and privacy pages, and users
+// accept each site's separately.
+type LegalRepository struct {
+	db     *sqlx.DB
+	tenant *uuid.UUID
+}
+
+func NewLegalRepository(db *sqlx.DB) *LegalRepository {
+	return &LegalRepository{db: db}
+}
+
+// ForTenant returns a repository for the terms of tenant (nil for the primary site).
+func (r *LegalRepository) ForTenant(tenant *uuid.UUID) LegalRepositoryInterface {
+	return &LegalRepository{db: r.db, tenant: tenant}
+}
+
+// Accepted returns the versions the user last accepted.
+func (r *LegalRepository) Accepted(userID uuid.UUID) (LegalVersions, error) {
+	var v LegalVersions
+	if r.tenant != nil {
+		err := r.db.Get(&v, `SELECT terms_revision, privacy_revision FROM legal_consents
+			WHERE user_id = $1 AND tenant_id = $2 ORDER BY created_at DESC, id DESC LIMIT 1`, userID, *r.tenant)
+		if errors.Is(err, sql.ErrNoRows) {
+			return LegalVersions{}, nil
+		}
+		return v, err
+	}
+	err := r.db.Get(&v, `SELECT accepted_terms_revision AS terms_revision, accepted_privacy_revision AS privacy_revision FROM users WHERE id = $1`, userID)
+	return v, err
+}
+
+// Record stores the user's acceptance of v.
+func (r *LegalRepository) Record(userID uuid.UUID, v LegalVersions, ipHash string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := r.RecordWithTx(tx, userID, v, ipHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordWithTx is Record within tx, for accepting the terms as the account is created.
+func (r *LegalRepository) RecordWithTx(tx *sqlx.Tx, userID uuid.UUID, v LegalVersions, ipHash string) error {
+	if _, err := tx.Exec(`INSERT INTO legal_consents (user_id, terms_revision, privacy_revision, ip_hash, tenant_id) VALUES ($1, $2, $3, $4, $5)`,
+		userID, v.Terms, v.Privacy, ipHash, r.tenant); err != nil {
+		return err
+	}
+	if r.tenant != nil {
+		return nil
+	}
+	_, err := tx.Exec(`UPDATE users SET accepted_terms_revision = $2, accepted_privacy_revision = $3 WHERE id = $1`, userID, v.Terms, v.Privacy)
+	return err
+}
+
+// History returns the user's acceptances, newest first.
+func (r *LegalRepository) History(userID uuid.UUID, limit int) ([]LegalConsent, error) {
+	var out []LegalConsent
+	err := r.db.Select(&out, `SELECT id, user_id, terms_revision, privacy_revision, ip_hash, created_at
+		FROM legal_consents WHERE user_id = $1 AND tenant_id IS NOT DISTINCT FROM $3 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit, r.tenant)
+	return out, err
+}
+
+// LatestRevisions returns the newest revision of the site's terms and privacy pages, 0 for
+// a page that does not exist.
+func (r *LegalRepository) LatestRevisions() (LegalVersions, error) {
+	var v LegalVersions
+	err := r.db.Get(&v, `SELECT
+		COALESCE((SELECT MAX(pr.rev) FROM page_revisions pr JOIN pages p ON p.id = pr.page_id WHERE p.slug = $1 AND p.tenant_id IS NOT DISTINCT FROM $3), 0) AS terms_revision,
+		COALESCE((SELECT MAX(pr.rev) FROM page_revisions pr JOIN pages p ON p.id = pr.page_id WHERE p.slug = $2 AND p.tenant_id IS NOT DISTINCT FROM $3), 0) AS privacy_revision`,
+		TermsPageSlug, PrivacyPageSlug, r.tenant)
+	return v, err
+}
+
+// Publish makes v the versions users must have accepted.
+func (r *LegalRepository) Publish(v LegalVersions) error {
+	if r.tenant != nil {
+		_, err := r.db.Exec(`UPDATE tenants SET terms_revision = $1, privacy_revision = $2, updated_at = NOW() WHERE id = $3`, v.Terms, v.Privacy, *r.tenant)
+		return err
+	}
+	_, err := r.db.Exec(`UPDATE site_settings SET terms_revision = $1, privacy_revision = $2, updated_at = NOW() WHERE id = 1`, v.Terms, v.Privacy)
+	return err
+}
@@ -0,0 +1,34 @@
+package models
+
+// License is a content license an uploader can attach to an image. URL is the canonical
+// deed used in machine-readable metadata; it is empty for all rights reserved.
+type License struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// LicenseAllRightsReserved is the explicit "no reuse" choice, distinct from not stating a license.
+const LicenseAllRightsReserved = "all-rights-reserved"
+
+// Licenses lists the selectable licenses in display order.
+var Licenses = []License{
+	{ID: LicenseAllRightsReserved, Name: "All rights reserved"},
+	{ID: "cc0-1.0", Name: "CC0 1.0 (public domain)", URL: "https://creativecommons.org/publicdomain/zero/1.0/"},
+	{ID: "cc-by-4.0", Name: "CC BY 4.0", URL: "https://creativecommons.org/licenses/by/4.0/"},
+	{ID: "cc-by-sa-4.0", Name: "CC BY-SA 4.0", URL: "https://creativecommons.org/licenses/by-sa/4.0/"},
+	{ID: "cc-by-nc-4.0", Name: "CC BY-NC 4.0", URL: "https://creativecommons.org/licenses/by-nc/4.0/"},
+	{ID: "cc-by-nc-sa-4.0", Name: "CC BY-NC-SA 4.0", URL: "https://creativecommons.org/licenses/by-nc-sa/4.0/"},
+	{ID: "cc-by-nd-4.0", Name: "CC BY-ND 4.0", URL: "https://creativecommons.org/licenses/by-nd/4.0/"},
+	{ID: "cc-by-nc-nd-4.0", Name: "CC BY-NC-ND 4.0", URL: "https://creativecommons.org/licenses/by-nc-nd/4.0/"},
+}
+
+// LicenseByID looks up a license; the empty id (no license stated) is not found.
+func LicenseByID(id string) (License, bool) {
+	for _, l := range Licenses {
+		if l.ID == id {
+			return l, true
+		}
+	}
+	return License{}, false
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// LocaleString overrides the translation of one server message (Msgid, the English text)
+// in one locale.
+type LocaleString struct {
+	Locale    string     `db:"locale" json:"locale"`
+	Msgid     string     `db:"msgid" json:"msgid"`
+	Text      string     `db:"text" json:"text"`
+	UpdatedBy *uuid.UUID `db:"updated_by" json:"updated_by"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type LocaleStringRepository struct {
+	db *sqlx.DB
+}
+
+func NewLocaleStringRepository(db *sqlx.DB) *LocaleStringRepository {
+	return &LocaleStringRepository{db: db}
+}
+
+// List returns every override ordered by locale and msgid.
+func (r *LocaleStringRepository) List() ([]LocaleString, error) {
+	out := []LocaleString{}
+	err := r.db.Select(&out, `SELECT * FROM locale_strings ORDER BY locale, msgid`)
+	return out, err
+}
+
+// Replace sets the given overrides of a locale and removes those in remove, in one
+// transaction.
+func (r *LocaleStringRepository) Replace(locale string, set map[string]string, remove []string, by *uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, msgid := range remove {
+		if _, err := tx.Exec(`DELETE FROM locale_strings WHERE locale = $1 AND msgid = $2`, locale, msgid); err != nil {
+			return err
+		}
+	}
+	for msgid, text := range set {
+		if _, err := tx.Exec(`INSERT INTO locale_strings (locale, msgid, text, updated_by) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (locale, msgid) DO UPDATE SET text = EXCLUDED.text, updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+			locale, msgid, text, by); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
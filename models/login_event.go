@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginEventRetention is how long sign-ins are kept in a user's login history.
+const LoginEventRetention = 180 * 24 * time.Hour
+
+// LoginEvent is one successful sign-in. The IP is only kept as keyed hashes.
+type LoginEvent struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	UserID      uuid.UUID `db:"user_id" json:"-"`
+	IPHash      string    `db:"ip_hash" json:"-"`
+	NetworkHash string    `db:"network_hash" json:"-"`
+	Country     string    `db:"country" json:"country,omitempty"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	Device      string    `db:"device" json:"device"`
+	NewDevice   bool      `db:"new_device" json:"new_device"`
+	NewLocation bool      `db:"new_location" json:"new_location"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+type LoginEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginEventRepository(db *sqlx.DB) *LoginEventRepository {
+	return &LoginEventRepository{db: db}
+}
+
+// Record stores e, first working out whether its device and location are new for the
+// user. The first sign-in on record is never new. Entries past the retention are dropped.
+func (r *LoginEventRepository) Record(e *LoginEvent) error {
+	var seen struct {
+		Any      bool `db:"any"`
+		Device   bool `db:"device"`
+		Location bool `db:"location"`
+	}
+	err := r.db.Get(&seen, `SELECT COUNT(*) > 0 AS any,
+			COALESCE(BOOL_OR(device = $2), FALSE) AS device,
+			COALESCE(BOOL_OR(CASE WHEN $3 <> '' THEN country = $3 ELSE network_hash = $4 END), FALSE) AS location
+		FROM login_events WHERE user_id = $1`, e.UserID, e.Device, e.Country, e.NetworkHash)
+	if err != nil {
+		return err
+	}
+	e.NewDevice = seen.Any && !seen.Device
+	e.NewLocation = seen.Any && !seen.Location
+	if err := r.db.QueryRow(`INSERT INTO login_events (user_id, ip_hash, network_hash, country, user_agent, device, new_device, new_location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		e.UserID, e.IPHash, e.NetworkHash, e.Country, e.UserAgent, e.Device, e.NewDevice, e.NewLocation).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM login_events WHERE user_id = $1 AND created_at < $2`, e.UserID, time.Now().Add(-LoginEventRetention))
+	return err
+}
+
+// ListForUser returns the user's most recent sign-ins, newest first.
+func (r *LoginEventRepository) ListForUser(userID uuid.UUID, limit int) ([]LoginEvent, error) {
+	out := []LoginEvent{}
+	err := r.db.Select(&out, `SELECT * FROM login_events WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`, userID, limit)
+	return out, err
+}
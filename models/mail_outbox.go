@@ -0,0 +1,140 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Outbox statuses. Rows move pending -> sending -> sent, or back to pending with a later
+// next_attempt_at after a failure, and finally to dead once retries are exhausted.
+const (
+	MailStatusPending = "pending"
+	MailStatusSending = "sending"
+	MailStatusSent    = "sent"
+	MailStatusDead    = "dead"
+)
+
+// OutboxMail is a persisted email awaiting (or done with) delivery.
+// The body is never serialized: it may carry single-use reset or verification links.
+type OutboxMail struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	ToEmail       string     `db:"to_email" json:"to_email"`
+	Subject       string     `db:"subject" json:"subject"`
+	Body          string     `db:"body" json:"-"`
+	HTMLBody      string     `db:"html_body" json:"-"`
+	Status        string     `db:"status" json:"status"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	LastError     *string    `db:"last_error" json:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   *time.Time `db:"locked_until" json:"-"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	SentAt        *time.Time `db:"sent_at" json:"sent_at"`
+}
+
+type MailOutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewMailOutboxRepository(db *sqlx.DB) *MailOutboxRepository {
+	return &MailOutboxRepository{db: db}
+}
+
+// Enqueue stores a message; html may be empty for plain-text mail.
+func (r *MailOutboxRepository) Enqueue(to, subject, body, html string) error {
+	_, err := r.db.Exec(`INSERT INTO mail_outbox (to_email, subject, body, html_body) VALUES ($1, $2, $3, $4)`, to, subject, body, html)
+	return err
+}
+
+// ClaimDue leases up to limit due messages for sending. Messages whose lease expired
+// (e.g. the sending instance crashed) are reclaimed.
+func (r *MailOutboxRepository) ClaimDue(limit int, lease time.Duration) ([]OutboxMail, error) {
+	var out []OutboxMail
+	err := r.db.Select(&out, `UPDATE mail_outbox SET status = 'sending', attempts = attempts + 1,
+			locked_until = NOW() + ($2 * INTERVAL '1 second')
+		WHERE id IN (
+			SELECT id FROM mail_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, limit, int(lease.Seconds()))
+	return out, err
+}
+
+// MarkSent records delivery and drops the body so delivered links don't linger at rest.
+func (r *MailOutboxRepository) MarkSent(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE mail_outbox SET status = 'sent', body = '', html_body = '', sent_at = NOW(), locked_until = NULL, last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkFailed reschedules a message at next, or dead-letters it when dead is set.
+func (r *MailOutboxRepository) MarkFailed(id uuid.UUID, errMsg string, next time.Time, dead bool) error {
+	status := MailStatusPending
+	if dead {
+		status = MailStatusDead
+	}
+	_, err := r.db.Exec(`UPDATE mail_outbox SET status = $2, last_error = $3, next_attempt_at = $4, locked_until = NULL WHERE id = $1`, id, status, errMsg, next)
+	return err
+}
+
+// List returns messages newest first, optionally filtered by status.
+func (r *MailOutboxRepository) List(status string, page, limit int) ([]OutboxMail, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM mail_outbox WHERE ($1 = '' OR status = $1)`, status); err != nil {
+		return nil, 0, err
+	}
+	out := []OutboxMail{}
+	err := r.db.Select(&out, `SELECT * FROM mail_outbox WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC LIMIT $2 OFFSET $3`, status, limit, offset)
+	return out, total, err
+}
+
+// Retry requeues a dead or pending message for immediate delivery with a fresh attempt budget.
+func (r *MailOutboxRepository) Retry(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE mail_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL WHERE id = $1 AND status IN ('pending', 'dead')`, id)
+	return err
+}
+
+func (r *MailOutboxRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM mail_outbox WHERE id = $1`, id)
+	return err
+}
+
+// PurgeSent deletes delivered messages older than the cutoff.
+func (r *MailOutboxRepository) PurgeSent(before time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM mail_outbox WHERE status = 'sent' AND sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// MailBacklog summarizes the undelivered part of the outbox for health checks.
+type MailBacklog struct {
+	Pending int `db:"pending" json:"pending"`
+	Dead    int `db:"dead" json:"dead"`
+	// OldestDue is when the longest-waiting due message became due, if any is waiting
+	OldestDue *time.Time `db:"oldest_due" json:"oldest_due"`
+}
+
+// Backlog counts pending and dead messages and finds the oldest one that is due.
+func (r *MailOutboxRepository) Backlog() (*MailBacklog, error) {
+	var b MailBacklog
+	err := r.db.Get(&b, `SELECT
+			COUNT(*) FILTER (WHERE status IN ('pending', 'sending')) AS pending,
+			COUNT(*) FILTER (WHERE status = 'dead') AS dead,
+			MIN(next_attempt_at) FILTER (WHERE status = 'pending' AND next_attempt_at <= NOW()) AS oldest_due
+		FROM mail_outbox`)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
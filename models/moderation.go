@@ -0,0 +1,55 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ModerationRepository backs the queue of uploads held for review.
+type ModerationRepository struct {
+	db *sqlx.DB
+}
+
+func NewModerationRepository(db *sqlx.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// CountApprovedByUser counts the user's approved images, which decides whether new
+// uploads are still held.
+func (r *ModerationRepository) CountApprovedByUser(userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM images WHERE user_id = $1 AND moderation_status = 'approved'`, userID)
+	return n, err
+}
+
+// ListPending returns held images oldest first so the queue is worked in arrival order.
+func (r *ModerationRepository) ListPending(page, limit int) ([]ImageWithUser, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM images WHERE moderation_status = 'pending'`); err != nil {
+		return nil, 0, err
+	}
+	images := []ImageWithUser{}
+	err := r.db.Select(&images, `
+		SELECT
+			i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+			i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+			COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+			u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified
+		FROM images i
+		LEFT JOIN users u ON i.user_id = u.id
+		WHERE i.moderation_status = 'pending'
+		ORDER BY i.created_at ASC, i.id ASC
+		LIMIT $1 OFFSET $2`, limit, (page-1)*limit)
+	return images, total, err
+}
+
+// Approve publishes a held image. It returns false when the image was not pending, so two
+// moderators working the queue don't both notify the uploader.
+func (r *ModerationRepository) Approve(id uuid.UUID) (bool, error) {
+	res, err := r.db.Exec(`UPDATE images SET moderation_status = 'approved' WHERE id = $1 AND moderation_status = 'pending'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Navigation menus the SPA renders.
+const (
+	NavMenuHeader = "header"
+	NavMenuFooter = "footer"
+)
+
+// NavigationItem is one link in a menu. It points at a page (PageID) or a URL; ParentID
+// nests it one level under another item of the same menu. PageSlug and PagePublished are
+// joined in from the linked page.
+type NavigationItem struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	Menu          string     `db:"menu" json:"menu"`
+	ParentID      *uuid.UUID `db:"parent_id" json:"parent_id"`
+	Label         string     `db:"label" json:"label"`
+	PageID        *uuid.UUID `db:"page_id" json:"page_id"`
+	URL           *string    `db:"url" json:"url"`
+	Position      int        `db:"position" json:"position"`
+	PageSlug      *string    `db:"page_slug" json:"page_slug,omitempty"`
+	PagePublished *bool      `db:"page_published" json:"page_published,omitempty"`
+}
+
+type NavigationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNavigationRepository(db *sqlx.DB) *NavigationRepository {
+	return &NavigationRepository{db: db}
+}
+
+// List returns every menu item, parents before children, each level in position order.
+func (r *NavigationRepository) List() ([]NavigationItem, error) {
+	out := []NavigationItem{}
+	err := r.db.Select(&out, `SELECT n.*, p.slug AS page_slug, p.is_published AS page_published
+		FROM navigation_items n LEFT JOIN pages p ON p.id = n.page_id
+		ORDER BY n.menu, n.parent_id IS NOT NULL, n.position`)
+	return out, err
+}
+
+// Replace swaps a menu's items for items in one transaction. Items carry their own ids so
+// children can reference parents inserted in the same call; parents must come first.
+func (r *NavigationRepository) Replace(menu string, items []NavigationItem) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM navigation_items WHERE menu = $1`, menu); err != nil {
+		return err
+	}
+	for _, it := range items {
+		if _, err := tx.Exec(`INSERT INTO navigation_items (id, menu, parent_id, label, page_id, url, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			it.ID, menu, it.ParentID, it.Label, it.PageID, it.URL, it.Position); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
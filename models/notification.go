@@ -0,0 +1,163 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Notification types. Comment and follow notifications are emitted by those features;
+// the types are fixed here so clients can render every kind from day one.
+const (
+	NotificationCollected    = "collected"
+	NotificationComment      = "comment"
+	NotificationFollow       = "follow"
+	NotificationAdminMessage = "admin_message"
+	// Moderation outcomes for uploads held for review
+	NotificationUploadApproved = "upload_approved"
+	NotificationUploadRejected = "upload_rejected"
+	// Copyright takedowns: a new request for admins, and its outcome for the image owner
+	NotificationTakedownReceived = "takedown_received"
+	NotificationImageDisabled    = "image_disabled"
+	NotificationImageRestored    = "image_restored"
+	NotificationImageRemoved     = "image_removed"
+)
+
+// Notification is an in-app event for a user. ActorUsername and ImageFilename are joined
+// in for display and are empty when the actor or image no longer exists.
+type Notification struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	UserID        uuid.UUID  `db:"user_id" json:"-"`
+	Type          string     `db:"type" json:"type"`
+	ActorID       *uuid.UUID `db:"actor_id" json:"actor_id"`
+	ActorUsername *string    `db:"actor_username" json:"actor_username"`
+	ImageID       *uuid.UUID `db:"image_id" json:"image_id"`
+	ImageFilename *string    `db:"image_filename" json:"image_filename"`
+	Message       string     `db:"message" json:"message"`
+	ReadAt        *time.Time `db:"read_at" json:"read_at"`
+	EmailedAt     *time.Time `db:"emailed_at" json:"-"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	// Text is a rendered one-line summary filled in by handlers
+	Text string `db:"-" json:"text"`
+}
+
+// DigestRecipient is a user claimed for a digest email.
+type DigestRecipient struct {
+	ID       uuid.UUID `db:"id"`
+	Username string    `db:"username"`
+	Email    string    `db:"email"`
+}
+
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+const notificationSelect = `SELECT n.*, u.username AS actor_username, i.filename AS image_filename
+	FROM notifications n
+	LEFT JOIN users u ON u.id = n.actor_id
+	LEFT JOIN images i ON i.id = n.image_id`
+
+func (r *NotificationRepository) Create(n *Notification) error {
+	return r.db.QueryRow(`INSERT INTO notifications (user_id, type, actor_id, image_id, message)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		n.UserID, n.Type, n.ActorID, n.ImageID, n.Message).Scan(&n.ID, &n.CreatedAt)
+}
+
+// NotifyAdmins records n once for every active admin; n.UserID is ignored.
+func (r *NotificationRepository) NotifyAdmins(n *Notification) error {
+	_, err := r.db.Exec(`INSERT INTO notifications (user_id, type, actor_id, image_id, message)
+		SELECT id, $1, $2, $3, $4 FROM users WHERE is_admin AND NOT is_disabled`,
+		n.Type, n.ActorID, n.ImageID, n.Message)
+	return err
+}
+
+// List returns a user's notifications newest first, optionally only unread ones.
+func (r *NotificationRepository) List(userID uuid.UUID, unreadOnly bool, page, limit int) ([]Notification, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)`, userID, unreadOnly); err != nil {
+		return nil, 0, err
+	}
+	out := []Notification{}
+	err := r.db.Select(&out, notificationSelect+` WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC LIMIT $3 OFFSET $4`, userID, unreadOnly, limit, offset)
+	return out, total, err
+}
+
+func (r *NotificationRepository) CountUnread(userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID)
+	return n, err
+}
+
+// MarkRead marks the given notifications read; an empty ids marks all of the user's.
+func (r *NotificationRepository) MarkRead(userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	q, args := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, []interface{}{userID}
+	if len(ids) > 0 {
+		in, inArgs, err := sqlx.In(`UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL AND id IN (?)`, userID, ids)
+		if err != nil {
+			return 0, err
+		}
+		q, args = r.db.Rebind(in), inArgs
+	}
+	res, err := r.db.Exec(q, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (r *NotificationRepository) Delete(userID, id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM notifications WHERE id = $1 AND user_id = $2`, id, userID)
+	return err
+}
+
+// ClaimDigestRecipients picks opted-in users with unread, not yet emailed notifications
+// whose last digest is at least interval old, and stamps digest_sent_at so concurrent
+// instances don't pick the same users.
+func (r *NotificationRepository) ClaimDigestRecipients(interval time.Duration, limit int) ([]DigestRecipient, error) {
+	out := []DigestRecipient{}
+	err := r.db.Select(&out, `UPDATE users SET digest_sent_at = NOW()
+		WHERE id IN (
+			SELECT u.id FROM users u
+			WHERE u.notify_digest AND u.email_verified AND NOT u.is_disabled
+			  AND (u.digest_sent_at IS NULL OR u.digest_sent_at < NOW() - ($1 * INTERVAL '1 second'))
+			  AND EXISTS (SELECT 1 FROM notifications n WHERE n.user_id = u.id AND n.read_at IS NULL AND n.emailed_at IS NULL)
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, username, email`, int(interval.Seconds()), limit)
+	return out, err
+}
+
+// PendingDigest returns unread notifications not yet included in a digest, oldest first.
+func (r *NotificationRepository) PendingDigest(userID uuid.UUID, limit int) ([]Notification, error) {
+	out := []Notification{}
+	err := r.db.Select(&out, notificationSelect+` WHERE n.user_id = $1 AND n.read_at IS NULL AND n.emailed_at IS NULL
+		ORDER BY n.created_at LIMIT $2`, userID, limit)
+	return out, err
+}
+
+func (r *NotificationRepository) MarkEmailed(userID uuid.UUID, before time.Time) error {
+	_, err := r.db.Exec(`UPDATE notifications SET emailed_at = NOW() WHERE user_id = $1 AND emailed_at IS NULL AND created_at <= $2`, userID, before)
+	return err
+}
+
+// PurgeRead deletes read notifications older than the cutoff.
+func (r *NotificationRepository) PurgeRead(before time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM notifications WHERE read_at IS NOT NULL AND created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
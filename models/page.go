@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -10,64 +11,188 @@ import (
 
 // Page represents a simple CMS page or redirect.
 // If RedirectURL is non-empty, the page acts as a redirect and HTML/Markdown are ignored in serving.
+// Slug is the page's path and may have up to four segments (help/faq); the page at the path
+// minus its last segment is the parent. Position orders siblings.
 type Page struct {
-	ID              uuid.UUID `db:"id" json:"id"`
-	Slug            string    `db:"slug" json:"slug"`
-	Title           string    `db:"title" json:"title"`
-	Markdown        string    `db:"markdown" json:"markdown"`
-	HTML            string    `db:"html" json:"html"`
-	IsPublished     bool      `db:"is_published" json:"is_published"`
-	RedirectURL     *string   `db:"redirect_url" json:"redirect_url,omitempty"`
-	MetaTitle       *string   `db:"meta_title" json:"meta_title,omitempty"`
-	MetaDescription *string   `db:"meta_description" json:"meta_description,omitempty"`
-	CreatedAt       time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+	ID              uuid.UUID  `db:"id" json:"id"`
+	Slug            string     `db:"slug" json:"slug"`
+	Title           string     `db:"title" json:"title"`
+	Markdown        string     `db:"markdown" json:"markdown"`
+	HTML            string     `db:"html" json:"html"`
+	IsPublished     bool       `db:"is_published" json:"is_published"`
+	RedirectURL     *string    `db:"redirect_url" json:"redirect_url,omitempty"`
+	MetaTitle       *string    `db:"meta_title" json:"meta_title,omitempty"`
+	MetaDescription *string    `db:"meta_description" json:"meta_description,omitempty"`
+	Position        int        `db:"position" json:"position"`
+	TenantID        *uuid.UUID `db:"tenant_id" json:"tenant_id,omitempty"`
+	UpdatedBy       *uuid.UUID `db:"updated_by" json:"updated_by,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
+// PageRevision is a snapshot of a page taken on every save. Rev counts up from 1 per page.
+// AuthorUsername is joined in for display.
+type PageRevision struct {
+	ID              uuid.UUID  `db:"id" json:"id"`
+	PageID          uuid.UUID  `db:"page_id" json:"page_id"`
+	Rev             int        `db:"rev" json:"rev"`
+	Slug            string     `db:"slug" json:"slug"`
+	Title           string     `db:"title" json:"title"`
+	Markdown        string     `db:"markdown" json:"markdown"`
+	IsPublished     bool       `db:"is_published" json:"is_published"`
+	RedirectURL     *string    `db:"redirect_url" json:"redirect_url,omitempty"`
+	MetaTitle       *string    `db:"meta_title" json:"meta_title,omitempty"`
+	MetaDescription *string    `db:"meta_description" json:"meta_description,omitempty"`
+	Position        int        `db:"position" json:"position"`
+	AuthorID        *uuid.UUID `db:"author_id" json:"author_id"`
+	AuthorUsername  *string    `db:"author_username" json:"author_username"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
+
+// ErrPageHasChildren is returned when deleting a page that other pages live under.
+var ErrPageHasChildren = errors.New("page has child pages")
+
+// ParentPagePath returns the path of a page's parent, or "" for a top-level page.
+func ParentPagePath(slug string) string {
+	if i := strings.LastIndex(slug, "/"); i > 0 {
+		return slug[:i]
+	}
+	return ""
+}
+
+// PageRepository reads and writes the pages of one site: the primary site, or the tenant
+// it was scoped to with ForTenant.
 type PageRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	tenant *uuid.UUID
 }
 
 func NewPageRepository(db *sqlx.DB) *PageRepository { return &PageRepository{db: db} }
 
+// ForTenant returns a repository for the pages of tenant (nil for the primary site).
+func (r *PageRepository) ForTenant(tenant *uuid.UUID) PageRepositoryInterface {
+	return &PageRepository{db: r.db, tenant: tenant}
+}
+
 func (r *PageRepository) Create(p *Page) error {
 	p.Slug = strings.ToLower(strings.TrimSpace(p.Slug))
 	now := time.Now()
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 	q := `
-        INSERT INTO pages (slug, title, markdown, html, is_published, redirect_url, meta_title, meta_description, created_at, updated_at)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9)
+        INSERT INTO pages (slug, title, markdown, html, is_published, redirect_url, meta_title, meta_description, position, updated_by, created_at, updated_at, tenant_id)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$11,$12)
         RETURNING id, created_at, updated_at`
-	return r.db.QueryRow(q, p.Slug, p.Title, p.Markdown, p.HTML, p.IsPublished, p.RedirectURL, p.MetaTitle, p.MetaDescription, now).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	p.TenantID = r.tenant
+	if err := tx.QueryRow(q, p.Slug, p.Title, p.Markdown, p.HTML, p.IsPublished, p.RedirectURL, p.MetaTitle, p.MetaDescription, p.Position, p.UpdatedBy, now, r.tenant).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	if err := snapshotPage(tx, p.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// Update saves p and records the result as the page's next revision. When the path
+// changes, pages below it move with it.
 func (r *PageRepository) Update(p *Page) error {
 	p.Slug = strings.ToLower(strings.TrimSpace(p.Slug))
 	now := time.Now()
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var oldSlug string
+	if err := tx.Get(&oldSlug, `SELECT slug FROM pages WHERE id=$1 AND tenant_id IS NOT DISTINCT FROM $2 FOR UPDATE`, p.ID, r.tenant); err != nil {
+		return err
+	}
+	p.TenantID = r.tenant
 	q := `
         UPDATE pages
-        SET slug=$1, title=$2, markdown=$3, html=$4, is_published=$5, redirect_url=$6, meta_title=$7, meta_description=$8, updated_at=$9
-        WHERE id=$10`
-	_, err := r.db.Exec(q, p.Slug, p.Title, p.Markdown, p.HTML, p.IsPublished, p.RedirectURL, p.MetaTitle, p.MetaDescription, now, p.ID)
-	if err == nil {
-		p.UpdatedAt = now
+        SET slug=$1, title=$2, markdown=$3, html=$4, is_published=$5, redirect_url=$6, meta_title=$7, meta_description=$8, position=$9, updated_by=$10, updated_at=$11
+        WHERE id=$12`
+	if _, err := tx.Exec(q, p.Slug, p.Title, p.Markdown, p.HTML, p.IsPublished, p.RedirectURL, p.MetaTitle, p.MetaDescription, p.Position, p.UpdatedBy, now, p.ID); err != nil {
+		return err
+	}
+	if oldSlug != p.Slug {
+		// Slugs are limited to [a-z0-9-/], so the prefix needs no LIKE escaping
+		if _, err := tx.Exec(`UPDATE pages SET slug = $1 || substr(slug, $2) WHERE slug LIKE $3 AND tenant_id IS NOT DISTINCT FROM $4`,
+			p.Slug, len(oldSlug)+1, oldSlug+"/%", r.tenant); err != nil {
+			return err
+		}
 	}
+	if err := snapshotPage(tx, p.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// snapshotPage copies the page row into page_revisions as its next revision. The page row
+// is locked by the preceding write, so concurrent saves cannot take the same number.
+func snapshotPage(tx *sqlx.Tx, id uuid.UUID) error {
+	_, err := tx.Exec(`
+        INSERT INTO page_revisions (page_id, rev, slug, title, markdown, is_published, redirect_url, meta_title, meta_description, position, author_id, created_at)
+        SELECT id, COALESCE((SELECT MAX(rev) FROM page_revisions WHERE page_id = pages.id), 0) + 1,
+            slug, title, markdown, is_published, redirect_url, meta_title, meta_description, position, updated_by, updated_at
+        FROM pages WHERE id = $1`, id)
 	return err
 }
 
+// ListRevisions returns a page's revisions, newest first.
+func (r *PageRepository) ListRevisions(pageID uuid.UUID, page, limit int) ([]PageRevision, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM page_revisions WHERE page_id = $1`, pageID); err != nil {
+		return nil, 0, err
+	}
+	out := []PageRevision{}
+	err := r.db.Select(&out, `SELECT pr.*, u.username AS author_username
+		FROM page_revisions pr LEFT JOIN users u ON u.id = pr.author_id
+		WHERE pr.page_id = $1 ORDER BY pr.rev DESC LIMIT $2 OFFSET $3`, pageID, limit, (page-1)*limit)
+	return out, total, err
+}
+
+func (r *PageRepository) GetRevision(pageID uuid.UUID, rev int) (*PageRevision, error) {
+	var pr PageRevision
+	err := r.db.Get(&pr, `SELECT pr.*, u.username AS author_username
+		FROM page_revisions pr LEFT JOIN users u ON u.id = pr.author_id
+		WHERE pr.page_id = $1 AND pr.rev = $2`, pageID, rev)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
 func (r *PageRepository) Delete(id uuid.UUID) error {
 	// Before delete, capture slug for tombstone if this is a seeded default
 	var slug string
-	_ = r.db.Get(&slug, `SELECT slug FROM pages WHERE id=$1`, id)
-	if slug != "" && (slug == "about" || slug == "contact" || slug == "terms" || slug == "privacy" || slug == "faq") {
+	_ = r.db.Get(&slug, `SELECT slug FROM pages WHERE id=$1 AND tenant_id IS NOT DISTINCT FROM $2`, id, r.tenant)
+	if slug != "" {
+		var children int
+		if err := r.db.Get(&children, `SELECT COUNT(*) FROM pages WHERE slug LIKE $1 AND tenant_id IS NOT DISTINCT FROM $2`, slug+"/%", r.tenant); err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrPageHasChildren
+		}
+	}
+	if slug != "" && r.tenant == nil && (slug == "about" || slug == "contact" || slug == "terms" || slug == "privacy" || slug == "faq") {
 		_, _ = r.db.Exec(`INSERT INTO cms_tombstones(slug, deleted_at) VALUES($1, NOW()) ON CONFLICT (slug) DO NOTHING`, slug)
 	}
-	_, err := r.db.Exec(`DELETE FROM pages WHERE id=$1`, id)
+	_, err := r.db.Exec(`DELETE FROM pages WHERE id=$1 AND tenant_id IS NOT DISTINCT FROM $2`, id, r.tenant)
 	return err
 }
 
 func (r *PageRepository) GetBySlug(slug string) (*Page, error) {
 	var p Page
-	err := r.db.Get(&p, `SELECT * FROM pages WHERE slug=$1`, strings.ToLower(strings.TrimSpace(slug)))
+	err := r.db.Get(&p, `SELECT * FROM pages WHERE slug=$1 AND tenant_id IS NOT DISTINCT FROM $2`, strings.ToLower(strings.TrimSpace(slug)), r.tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +201,7 @@ func (r *PageRepository) GetBySlug(slug string) (*Page, error) {
 
 func (r *PageRepository) GetPublishedBySlug(slug string) (*Page, error) {
 	var p Page
-	err := r.db.Get(&p, `SELECT * FROM pages WHERE slug=$1 AND is_published=true`, strings.ToLower(strings.TrimSpace(slug)))
+	err := r.db.Get(&p, `SELECT * FROM pages WHERE slug=$1 AND is_published=true AND tenant_id IS NOT DISTINCT FROM $2`, strings.ToLower(strings.TrimSpace(slug)), r.tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -95,11 +220,11 @@ func (r *PageRepository) ListAll(page, limit int) ([]Page, int, error) {
 	}
 	offset := (page - 1) * limit
 	var total int
-	if err := r.db.Get(&total, `SELECT COUNT(*) FROM pages`); err != nil {
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM pages WHERE tenant_id IS NOT DISTINCT FROM $1`, r.tenant); err != nil {
 		return nil, 0, err
 	}
 	var list []Page
-	if err := r.db.Select(&list, `SELECT * FROM pages ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset); err != nil {
+	if err := r.db.Select(&list, `SELECT * FROM pages WHERE tenant_id IS NOT DISTINCT FROM $3 ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset, r.tenant); err != nil {
 		return nil, 0, err
 	}
 	return list, total, nil
@@ -107,7 +232,7 @@ func (r *PageRepository) ListAll(page, limit int) ([]Page, int, error) {
 
 func (r *PageRepository) ListPublished() ([]Page, error) {
 	var list []Page
-	if err := r.db.Select(&list, `SELECT * FROM pages WHERE is_published=true ORDER BY title ASC`); err != nil {
+	if err := r.db.Select(&list, `SELECT * FROM pages WHERE is_published=true AND tenant_id IS NOT DISTINCT FROM $1 ORDER BY position ASC, title ASC`, r.tenant); err != nil {
 		return nil, err
 	}
 	return list, nil
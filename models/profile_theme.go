@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Profile gallery layouts. An empty layout renders the default masonry.
+const (
+	ProfileLayoutMasonry = "masonry"
+	ProfileLayoutGrid    = "grid"
+	ProfileLayoutWide    = "wide"
+)
+
+// ProfileTheme customizes how a user's profile renders. HeaderKey is the storage key of the
+// uploaded header image and HeaderURL its public URL, both set by the header upload.
+type ProfileTheme struct {
+	Accent    string `json:"accent,omitempty"`
+	Layout    string `json:"layout,omitempty"`
+	HeaderKey string `json:"header_key,omitempty"`
+	HeaderURL string `json:"header_url,omitempty"`
+}
+
+// IsZero reports whether the theme has no customizations.
+func (t ProfileTheme) IsZero() bool { return t == ProfileTheme{} }
+
+// Value stores the theme as JSONB.
+func (t ProfileTheme) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan reads the theme from a JSONB column.
+func (t *ProfileTheme) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*t = ProfileTheme{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("profile_theme: unsupported type")
+	}
+	*t = ProfileTheme{}
+	return json.Unmarshal(b, t)
+}
@@ -0,0 +1,25 @@
+package models
+
+import "github.com/jmoiron/sqlx"
+
+type RegistrationBlockRepository struct {
+	db *sqlx.DB
+}
+
+func NewRegistrationBlockRepository(db *sqlx.DB) *RegistrationBlockRepository {
+	return &RegistrationBlockRepository{db: db}
+}
+
+// Record notes a registration refused for reason. Entries older than the longest stats
+// range are dropped as new ones arrive.
+func (r *RegistrationBlockRepository) Record(reason, ipHash string) error {
+	if _, err := r.db.Exec(`INSERT INTO registration_blocks (reason, ip_hash) VALUES ($1, $2)`, reason, ipHash); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`DELETE FROM registration_blocks WHERE created_at < NOW()::timestamp - INTERVAL '400 days'`)
+	return err
+}
+
+type RegistrationBlockRepositoryInterface interface {
+	Record(reason, ipHash string) error
+}
@@ -37,11 +37,11 @@ func (r *UserRepository) Create(user *User) error {
 
 func (r *UserRepository) CreateWithTx(tx *sqlx.Tx, user *User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, bio, avatar_url)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (username, email, password_hash, bio, avatar_url, pending_approval, age_confirmed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at`
 
-	return tx.QueryRow(query, user.Username, user.Email, user.PasswordHash, user.Bio, user.AvatarURL).
+	return tx.QueryRow(query, user.Username, user.Email, user.PasswordHash, user.Bio, user.AvatarURL, user.PendingApproval, user.AgeConfirmedAt).
 		Scan(&user.ID, &user.CreatedAt)
 }
 
@@ -75,6 +75,22 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*User, erro
     return &user, nil
 }
 
+// GetByIDs loads several users in one query; ids that do not exist are left out.
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	users := []User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	q, args, err := sqlx.In(`SELECT * FROM users WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &users, r.db.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) UpdateProfile(id uuid.UUID, updates UpdateUserRequest) (*User, error) {
 	// Build dynamic update
 	setClauses := []string{}
@@ -105,6 +121,31 @@ func (r *UserRepository) UpdateProfile(id uuid.UUID, updates UpdateUserRequest)
 		args = append(args, *updates.NsfwPref)
 		argPos++
 	}
+	if updates.NotifyDigest != nil {
+		setClauses = append(setClauses, fmt.Sprintf("notify_digest = $%d", argPos))
+		args = append(args, *updates.NotifyDigest)
+		argPos++
+	}
+	if updates.StripExif != nil {
+		setClauses = append(setClauses, fmt.Sprintf("strip_exif = $%d", argPos))
+		args = append(args, *updates.StripExif)
+		argPos++
+	}
+	if updates.ProfileTheme != nil {
+		setClauses = append(setClauses, fmt.Sprintf("profile_theme = $%d", argPos))
+		args = append(args, *updates.ProfileTheme)
+		argPos++
+	}
+	if updates.FeedMutes != nil {
+		setClauses = append(setClauses, fmt.Sprintf("feed_mutes = $%d", argPos))
+		args = append(args, *updates.FeedMutes)
+		argPos++
+	}
+	if updates.ContentPrefs != nil {
+		setClauses = append(setClauses, fmt.Sprintf("content_prefs = $%d", argPos))
+		args = append(args, *updates.ContentPrefs)
+		argPos++
+	}
 	if len(setClauses) == 0 {
 		return r.GetByID(context.Background(), id)
 	}
@@ -128,8 +169,43 @@ func (r *UserRepository) SetAdmin(id uuid.UUID, isAdmin bool) error {
 	return err
 }
 
+// SetVerified grants the verified badge with the reason shown beside it, or revokes it.
+func (r *UserRepository) SetVerified(id uuid.UUID, verified bool, reason *string) error {
+	if !verified {
+		reason = nil
+	}
+	_, err := r.db.Exec(`UPDATE users SET is_verified = $1, verified_reason = $2,
+		verified_at = CASE WHEN $1 THEN NOW() ELSE NULL END, updated_at = NOW() WHERE id = $3`, verified, reason, id)
+	return err
+}
+
+// SetDisabled disables an account indefinitely, or re-enables it and clears any suspension.
 func (r *UserRepository) SetDisabled(id uuid.UUID, disabled bool) error {
-	_, err := r.db.Exec(`UPDATE users SET is_disabled = $1 WHERE id = $2`, disabled, id)
+	_, err := r.db.Exec(`UPDATE users SET is_disabled = $1, suspension_reason = '', suspended_until = NULL WHERE id = $2`, disabled, id)
+	return err
+}
+
+// Suspend disables an account with a reason shown to the user. A nil until suspends it
+// until an admin lifts it; otherwise ReleaseExpiredSuspensions re-enables it after until.
+func (r *UserRepository) Suspend(id uuid.UUID, reason string, until *time.Time) error {
+	_, err := r.db.Exec(`UPDATE users SET is_disabled = TRUE, suspension_reason = $1, suspended_until = $2 WHERE id = $3`, reason, until, id)
+	return err
+}
+
+// ReleaseExpiredSuspensions re-enables accounts whose timed suspension has ended and
+// returns their ids.
+func (r *UserRepository) ReleaseExpiredSuspensions() ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	err := r.db.Select(&ids, `UPDATE users SET is_disabled = FALSE, suspension_reason = '', suspended_until = NULL
+		WHERE is_disabled AND suspended_until IS NOT NULL AND suspended_until <= NOW()
+		RETURNING id`)
+	return ids, err
+}
+
+// SetShadowbanned toggles quarantine: the user's uploads drop out of public feeds but
+// stay visible to the user.
+func (r *UserRepository) SetShadowbanned(id uuid.UUID, shadowbanned bool) error {
+	_, err := r.db.Exec(`UPDATE users SET is_shadowbanned = $1 WHERE id = $2`, shadowbanned, id)
 	return err
 }
 
@@ -146,6 +222,30 @@ func (r *UserRepository) ListUsers(page, limit int) ([]User, int, error) {
 	return users, total, nil
 }
 
+// ListPendingApproval returns registrations waiting for approval, oldest first.
+func (r *UserRepository) ListPendingApproval(page, limit int) ([]User, int, error) {
+	offset := (page - 1) * limit
+	users := []User{}
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM users WHERE pending_approval`); err != nil {
+		return nil, 0, err
+	}
+	if err := r.db.Select(&users, `SELECT * FROM users WHERE pending_approval ORDER BY created_at ASC LIMIT $1 OFFSET $2`, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Approve lets a pending account sign in. It reports false when the account was not pending.
+func (r *UserRepository) Approve(id uuid.UUID) (bool, error) {
+	res, err := r.db.Exec(`UPDATE users SET pending_approval = FALSE, updated_at = NOW() WHERE id = $1 AND pending_approval`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
 func (r *UserRepository) SetModerator(id uuid.UUID, isModerator bool) error {
 	_, err := r.db.Exec(`UPDATE users SET is_moderator = $1 WHERE id = $2`, isModerator, id)
 	return err
@@ -165,25 +265,57 @@ func (r *UserRepository) SearchUsers(q string, page, limit int) ([]User, int, er
 	return users, total, nil
 }
 
+// ImageRepository's feed queries cover one site: the primary site, or the tenant it was
+// scoped to with ForTenant. Lookups by id, user or filename are not scoped. Feed and
+// gallery listings of a repository from ForViewer also say whether the viewer collected
+// each image, and its feeds leave out users the viewer blocked.
 type ImageRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	tenant *uuid.UUID
+	viewer *uuid.UUID
 }
 
 func NewImageRepository(db *sqlx.DB) *ImageRepository {
 	return &ImageRepository{db: db}
 }
 
+// ForTenant returns a repository whose feed is tenant's (nil for the primary site).
+func (r *ImageRepository) ForTenant(tenant *uuid.UUID) ImageRepositoryInterface {
+	return &ImageRepository{db: r.db, tenant: tenant, viewer: r.viewer}
+}
+
+// ForViewer returns a repository whose listings are tailored to viewer.
+func (r *ImageRepository) ForViewer(viewer uuid.UUID) ImageRepositoryInterface {
+	return &ImageRepository{db: r.db, tenant: r.tenant, viewer: &viewer}
+}
+
+// viewerScope returns the parts of a listing over images i that depend on the viewer,
+// bound to placeholder $n: a select column and join saying whether they collected each
+// image, a condition leaving out users they blocked, and the argument to append. Without
+// a viewer all are empty and Collected stays nil.
+func viewerScope(viewer *uuid.UUID, n int) (column, join, filter string, args []any) {
+	if viewer == nil {
+		return "", "", "", nil
+	}
+	return `, (vc.user_id IS NOT NULL) AS collected`,
+		fmt.Sprintf(`LEFT JOIN collections vc ON vc.image_id = i.id AND vc.user_id = $%d`, n),
+		fmt.Sprintf(`AND NOT EXISTS (SELECT 1 FROM blocks bl WHERE bl.blocker_id = $%d AND bl.blocked_id = i.user_id)`, n),
+		[]any{*viewer}
+}
+
 func (r *ImageRepository) Create(image *Image) error {
+	image.Rating = image.EffectiveRating()
+	image.IsNSFW = image.Rating.NSFW()
 	// Preferred insert including ai_provider (new installs / migrated DBs)
 	queryNew := `
-        INSERT INTO images (user_id, filename, original_name, file_size, width, height, blurhash, dominant_color, is_nsfw, ai_signature, ai_provider, exif_data, caption)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        INSERT INTO images (user_id, filename, original_name, file_size, width, height, blurhash, dominant_color, rating, ai_signature, ai_provider, exif_data, caption, moderation_status, visibility, license, frame_count, duration_ms, media_type, poster_filename, tenant_id, sha256, remix_of, remix_of_url, workflow_key, ai_model)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE(NULLIF($14, ''), 'approved'), COALESCE(NULLIF($15, ''), 'public'), $16, $17, $18, COALESCE(NULLIF($19, ''), 'image'), $20, $21, $22, $23, $24, $25, $26)
         RETURNING id, created_at`
 
 	if err := r.db.QueryRow(queryNew,
 		image.UserID, image.Filename, image.OriginalName, image.FileSize,
 		image.Width, image.Height, image.Blurhash, image.DominantColor,
-		image.IsNSFW, image.AISignature, image.AIProvider, image.ExifData, image.Caption).
+		image.Rating, image.AISignature, image.AIProvider, image.ExifData, image.Caption, image.ModerationStatus, image.Visibility, image.License, image.FrameCount, image.DurationMS, image.MediaType, image.PosterFilename, image.TenantID, image.SHA256, image.RemixOf, image.RemixOfURL, image.WorkflowKey, image.AIModel).
 		Scan(&image.ID, &image.CreatedAt); err != nil {
 		// Fallback for older schema without ai_provider column
 		if !containsIgnoreCase(err.Error(), "ai_provider") {
@@ -202,31 +334,33 @@ func (r *ImageRepository) Create(image *Image) error {
 	return nil
 }
 
-func (r *ImageRepository) GetFeed(page, limit int, showNSFW bool) ([]ImageWithUser, int, error) {
+func (r *ImageRepository) GetFeed(page, limit int, feedFilter FeedFilter) ([]ImageWithUser, int, error) {
 	offset := (page - 1) * limit
 
 	var images []ImageWithUser
-	var total int
 
-	countQuery := `SELECT COUNT(*) FROM images WHERE ($1 OR is_nsfw = false)`
-	err := r.db.Get(&total, countQuery, showNSFW)
+	total, err := r.CountFeed(feedFilter)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	col, join, filter, args := viewerScope(r.viewer, 4)
+	prefs, prefArgs := feedFilter.where(4 + len(args))
 	query := `
         SELECT 
             i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-            i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-            u.username, u.avatar_url
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
         FROM images i
         LEFT JOIN users u ON i.user_id = u.id
-        WHERE ($1 OR i.is_nsfw = false)
+        ` + join + `
+        WHERE i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT COALESCE(u.is_shadowbanned, FALSE)
+          AND i.tenant_id IS NOT DISTINCT FROM $3 ` + filter + prefs + `
         ORDER BY i.created_at DESC, i.id DESC
-        LIMIT $2 OFFSET $3`
+        LIMIT $1 OFFSET $2`
 
-	err = r.db.Select(&images, query, showNSFW, limit, offset)
+	err = r.db.Select(&images, query, append(append([]any{limit, offset, r.tenant}, args...), prefArgs...)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -273,7 +407,7 @@ func decodeFeedCursor(s string) (*FeedSeekCursor, error) {
 
 // GetFeedSeek returns images before the cursor (exclusive), ordered desc.
 // If cursor is nil, returns the first page.
-func (r *ImageRepository) GetFeedSeek(limit int, showNSFW bool, cursorEncoded string) ([]ImageWithUser, string, error) {
+func (r *ImageRepository) GetFeedSeek(limit int, feedFilter FeedFilter, cursorEncoded string) ([]ImageWithUser, string, error) {
 	cur, err := decodeFeedCursor(cursorEncoded)
 	if err != nil {
 		return nil, "", err
@@ -281,34 +415,42 @@ func (r *ImageRepository) GetFeedSeek(limit int, showNSFW bool, cursorEncoded st
 	var images []ImageWithUser
 	if cur == nil {
 		// First page
+		col, join, filter, args := viewerScope(r.viewer, 3)
+		prefs, prefArgs := feedFilter.where(3 + len(args))
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM images i
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE ($1 OR i.is_nsfw = false)
+            ` + join + `
+            WHERE i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT COALESCE(u.is_shadowbanned, FALSE)
+              AND i.tenant_id IS NOT DISTINCT FROM $2 ` + filter + prefs + `
             ORDER BY i.created_at DESC, i.id DESC
-            LIMIT $2`
-		if err := r.db.Select(&images, q, showNSFW, limit); err != nil {
+            LIMIT $1`
+		if err := r.db.Select(&images, q, append(append([]any{limit, r.tenant}, args...), prefArgs...)...); err != nil {
 			return nil, "", err
 		}
 	} else {
+		col, join, filter, args := viewerScope(r.viewer, 5)
+		prefs, prefArgs := feedFilter.where(5 + len(args))
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM images i
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE ($1 OR i.is_nsfw = false)
-              AND (i.created_at < $2 OR (i.created_at = $2 AND i.id < $3))
+            ` + join + `
+            WHERE i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT COALESCE(u.is_shadowbanned, FALSE)
+              AND (i.created_at < $1 OR (i.created_at = $1 AND i.id < $2))
+              AND i.tenant_id IS NOT DISTINCT FROM $4 ` + filter + prefs + `
             ORDER BY i.created_at DESC, i.id DESC
-            LIMIT $4`
-		if err := r.db.Select(&images, q, showNSFW, cur.CreatedAt, cur.ID, limit); err != nil {
+            LIMIT $3`
+		if err := r.db.Select(&images, q, append(append([]any{cur.CreatedAt, cur.ID, limit, r.tenant}, args...), prefArgs...)...); err != nil {
 			return nil, "", err
 		}
 	}
@@ -320,10 +462,67 @@ func (r *ImageRepository) GetFeedSeek(limit int, showNSFW bool, cursorEncoded st
 	return images, next, nil
 }
 
-// CountFeed returns the total number of feed images under the current NSFW filter.
-func (r *ImageRepository) CountFeed(showNSFW bool) (int, error) {
+// GetFeedSince returns up to limit of the newest feed images after since (exclusive),
+// ordered desc, and whether more than limit are newer. A backward scan of the
+// (created_at, id) index stops at since, so polling with a recent cursor is cheap.
+func (r *ImageRepository) GetFeedSince(limit int, feedFilter FeedFilter, since FeedSeekCursor) ([]ImageWithUser, bool, error) {
+	images := []ImageWithUser{}
+	col, join, filter, args := viewerScope(r.viewer, 5)
+	prefs, prefArgs := feedFilter.where(5 + len(args))
+	q := `
+        SELECT 
+            i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
+        FROM images i
+        LEFT JOIN users u ON i.user_id = u.id
+        ` + join + `
+        WHERE i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT COALESCE(u.is_shadowbanned, FALSE)
+          AND (i.created_at > $1 OR (i.created_at = $1 AND i.id > $2))
+          AND i.tenant_id IS NOT DISTINCT FROM $4 ` + filter + prefs + `
+        ORDER BY i.created_at DESC, i.id DESC
+        LIMIT $3`
+	if err := r.db.Select(&images, q, append(append([]any{since.CreatedAt, since.ID, limit + 1, r.tenant}, args...), prefArgs...)...); err != nil {
+		return nil, false, err
+	}
+	if len(images) > limit {
+		return images[:limit], true, nil
+	}
+	return images, false, nil
+}
+
+// AdminList pages through every image on every site, hidden and pending ones included,
+// newest first. EXIF data is left out to keep pages small.
+func (r *ImageRepository) AdminList(page, limit int) ([]ImageWithUser, int, error) {
 	var total int
-	err := r.db.Get(&total, `SELECT COUNT(*) FROM images WHERE ($1 OR is_nsfw = false)`, showNSFW)
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM images`); err != nil {
+		return nil, 0, err
+	}
+	images := []ImageWithUser{}
+	err := r.db.Select(&images, `
+        SELECT
+            i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            'null'::jsonb AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.tenant_id, i.created_at,
+            COALESCE(u.username, '') AS username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified
+        FROM images i
+        LEFT JOIN users u ON i.user_id = u.id
+        ORDER BY i.created_at DESC, i.id DESC
+        LIMIT $1 OFFSET $2`, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return images, total, nil
+}
+
+// CountFeed returns the total number of feed images under filter.
+func (r *ImageRepository) CountFeed(feedFilter FeedFilter) (int, error) {
+	var total int
+	_, _, filter, args := viewerScope(r.viewer, 2)
+	prefs, prefArgs := feedFilter.where(2 + len(args))
+	err := r.db.Get(&total, `SELECT COUNT(*) FROM images i WHERE moderation_status = 'approved' AND visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE is_shadowbanned) AND tenant_id IS NOT DISTINCT FROM $1 `+filter+prefs,
+		append(append([]any{r.tenant}, args...), prefArgs...)...)
 	return total, err
 }
 
@@ -332,9 +531,9 @@ func (r *ImageRepository) GetByID(ctx context.Context, id uuid.UUID) (*ImageWith
 	query := `
         SELECT 
             i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-            i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-            u.username, u.avatar_url
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.featured_at, i.featured_note, i.remix_of, i.remix_of_url, i.links, i.tags, i.workflow_key, i.tenant_id, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified
         FROM images i
         LEFT JOIN users u ON i.user_id = u.id
         WHERE i.id = $1`
@@ -346,31 +545,47 @@ func (r *ImageRepository) GetByID(ctx context.Context, id uuid.UUID) (*ImageWith
 	return &image, nil
 }
 
-func (r *ImageRepository) GetUserImages(userID uuid.UUID, page, limit int) ([]ImageWithUser, int, error) {
+// GetVersion is a primary key lookup of what GetByID's response depends on changing.
+func (r *ImageRepository) GetVersion(ctx context.Context, id uuid.UUID) (*ImageVersion, error) {
+	var v ImageVersion
+	err := r.db.GetContext(ctx, &v, `SELECT i.user_id, i.moderation_status, i.visibility, i.updated_at,
+		COALESCE(u.updated_at, i.updated_at) AS user_updated_at, i.tenant_id
+		FROM images i LEFT JOIN users u ON u.id = i.user_id WHERE i.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetUserImages lists a user's approved images. Unlisted and private ones are only
+// included when includeHidden is set, i.e. for the owner's own gallery.
+func (r *ImageRepository) GetUserImages(userID uuid.UUID, page, limit int, includeHidden bool) ([]ImageWithUser, int, error) {
 	offset := (page - 1) * limit
 
 	var images []ImageWithUser
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM images WHERE user_id = $1`
-	err := r.db.Get(&total, countQuery, userID)
+	countQuery := `SELECT COUNT(*) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND ($2 OR visibility = 'public')`
+	err := r.db.Get(&total, countQuery, userID, includeHidden)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	col, join, _, args := viewerScope(r.viewer, 5)
 	query := `
         SELECT 
             i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-            i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-            u.username, u.avatar_url
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
         FROM images i
         LEFT JOIN users u ON i.user_id = u.id
-        WHERE i.user_id = $1
+        ` + join + `
+        WHERE i.user_id = $1 AND i.moderation_status = 'approved' AND ($2 OR i.visibility = 'public')
         ORDER BY i.created_at DESC
-        LIMIT $2 OFFSET $3`
+        LIMIT $3 OFFSET $4`
 
-	err = r.db.Select(&images, query, userID, limit, offset)
+	err = r.db.Select(&images, query, append([]any{userID, includeHidden, limit, offset}, args...)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -378,41 +593,46 @@ func (r *ImageRepository) GetUserImages(userID uuid.UUID, page, limit int) ([]Im
 	return images, total, nil
 }
 
-// GetUserImagesSeek returns images for a user before the cursor.
-func (r *ImageRepository) GetUserImagesSeek(userID uuid.UUID, limit int, cursorEncoded string) ([]ImageWithUser, string, error) {
+// GetUserImagesSeek returns images for a user before the cursor, with the same
+// includeHidden rule as GetUserImages.
+func (r *ImageRepository) GetUserImagesSeek(userID uuid.UUID, limit int, cursorEncoded string, includeHidden bool) ([]ImageWithUser, string, error) {
 	cur, err := decodeFeedCursor(cursorEncoded)
 	if err != nil {
 		return nil, "", err
 	}
 	var images []ImageWithUser
 	if cur == nil {
+		col, join, _, args := viewerScope(r.viewer, 4)
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM images i
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE i.user_id = $1
+            ` + join + `
+            WHERE i.user_id = $1 AND i.moderation_status = 'approved' AND ($2 OR i.visibility = 'public')
             ORDER BY i.created_at DESC, i.id DESC
-            LIMIT $2`
-		if err := r.db.Select(&images, q, userID, limit); err != nil {
+            LIMIT $3`
+		if err := r.db.Select(&images, q, append([]any{userID, includeHidden, limit}, args...)...); err != nil {
 			return nil, "", err
 		}
 	} else {
+		col, join, _, args := viewerScope(r.viewer, 6)
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM images i
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE i.user_id = $1 AND (i.created_at < $2 OR (i.created_at = $2 AND i.id < $3))
+            ` + join + `
+            WHERE i.user_id = $1 AND i.moderation_status = 'approved' AND ($2 OR i.visibility = 'public') AND (i.created_at < $3 OR (i.created_at = $3 AND i.id < $4))
             ORDER BY i.created_at DESC, i.id DESC
-            LIMIT $4`
-		if err := r.db.Select(&images, q, userID, cur.CreatedAt, cur.ID, limit); err != nil {
+            LIMIT $5`
+		if err := r.db.Select(&images, q, append([]any{userID, includeHidden, cur.CreatedAt, cur.ID, limit}, args...)...); err != nil {
 			return nil, "", err
 		}
 	}
@@ -426,7 +646,7 @@ func (r *ImageRepository) GetUserImagesSeek(userID uuid.UUID, limit int, cursorE
 
 func (r *ImageRepository) CountUserImages(userID uuid.UUID) (int, error) {
 	var total int
-	err := r.db.Get(&total, `SELECT COUNT(*) FROM images WHERE user_id = $1`, userID)
+	err := r.db.Get(&total, `SELECT COUNT(*) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public'`, userID)
 	return total, err
 }
 
@@ -435,11 +655,122 @@ func (r *ImageRepository) Delete(id uuid.UUID) error {
 	return err
 }
 
+// SetNSFW sets an image's NSFW flag, moving its rating as ContentRating.WithNSFW does.
 func (r *ImageRepository) SetNSFW(id uuid.UUID, isNSFW bool) error {
-	_, err := r.db.Exec(`UPDATE images SET is_nsfw = $1 WHERE id = $2`, isNSFW, id)
+	_, err := r.db.Exec(`UPDATE images SET rating = `+nsfwRating(1)+` WHERE id = $2`, isNSFW, id)
+	return err
+}
+
+// Feature puts an image at the front of the featured picks with the curator's note;
+// featuring it again replaces the note and moves it back to the front.
+func (r *ImageRepository) Feature(id uuid.UUID, note *string) error {
+	_, err := r.db.Exec(`UPDATE images SET featured_at = NOW(), featured_note = $1 WHERE id = $2`, note, id)
+	return err
+}
+
+func (r *ImageRepository) Unfeature(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE images SET featured_at = NULL, featured_note = NULL WHERE id = $1`, id)
+	return err
+}
+
+// GetFeatured returns up to limit featured images of the site that are still public,
+// most recently featured first, leaving out what filter hides.
+func (r *ImageRepository) GetFeatured(limit int, feedFilter FeedFilter) ([]ImageWithUser, error) {
+	col, join, filter, args := viewerScope(r.viewer, 3)
+	prefs, prefArgs := feedFilter.where(3 + len(args))
+	images := []ImageWithUser{}
+	err := r.db.Select(&images, `
+        SELECT
+            i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            'null'::jsonb AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.featured_at, i.featured_note, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified`+col+`
+        FROM images i
+        JOIN users u ON i.user_id = u.id
+        `+join+`
+        WHERE i.featured_at IS NOT NULL AND i.moderation_status = 'approved' AND i.visibility = 'public' AND NOT u.is_shadowbanned
+          AND i.tenant_id IS NOT DISTINCT FROM $2 `+filter+prefs+`
+        ORDER BY i.featured_at DESC, i.id DESC
+        LIMIT $1`, append(append([]any{limit, r.tenant}, args...), prefArgs...)...)
+	return images, err
+}
+
+// SetRating sets an image's content rating.
+func (r *ImageRepository) SetRating(id uuid.UUID, rating ContentRating) error {
+	_, err := r.db.Exec(`UPDATE images SET rating = $1 WHERE id = $2`, rating, id)
+	return err
+}
+
+func (r *ImageRepository) SetVisibility(id uuid.UUID, visibility string) error {
+	_, err := r.db.Exec(`UPDATE images SET visibility = $1 WHERE id = $2`, visibility, id)
 	return err
 }
 
+func (r *ImageRepository) SetLicense(id uuid.UUID, license string) error {
+	_, err := r.db.Exec(`UPDATE images SET license = $1 WHERE id = $2`, license, id)
+	return err
+}
+
+// SetRemixOf records what an image was remixed from: an on-site image, an external URL,
+// or neither when both are nil.
+func (r *ImageRepository) SetRemixOf(id uuid.UUID, parent *uuid.UUID, sourceURL *string) error {
+	_, err := r.db.Exec(`UPDATE images SET remix_of = $1, remix_of_url = $2 WHERE id = $3`, parent, sourceURL, id)
+	return err
+}
+
+// SetLinks replaces an image's external links.
+func (r *ImageRepository) SetLinks(id uuid.UUID, links ImageLinks) error {
+	_, err := r.db.Exec(`UPDATE images SET links = $1 WHERE id = $2`, links, id)
+	return err
+}
+
+// SetTags replaces an image's tags.
+func (r *ImageRepository) SetTags(id uuid.UUID, tags ImageTags) error {
+	_, err := r.db.Exec(`UPDATE images SET tags = $1 WHERE id = $2`, tags, id)
+	return err
+}
+
+// TagsByIDs loads the tags of several images in one query, for lists whose rows are
+// read without them; ids that do not exist are left out.
+func (r *ImageRepository) TagsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]ImageTags, error) {
+	out := map[uuid.UUID]ImageTags{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	q, args, err := sqlx.In(`SELECT id, tags FROM images WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID   uuid.UUID `db:"id"`
+		Tags ImageTags `db:"tags"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.ID] = row.Tags
+	}
+	return out, nil
+}
+
+// Remixes lists the public, approved images declared as remixes of parentID, newest first.
+func (r *ImageRepository) Remixes(parentID uuid.UUID, limit int) ([]ImageWithUser, error) {
+	images := []ImageWithUser{}
+	err := r.db.Select(&images, `
+        SELECT
+            i.id, i.user_id, i.filename, i.original_name, i.width, i.height,
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.media_type, i.poster_filename, i.remix_of, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified
+        FROM images i
+        LEFT JOIN users u ON i.user_id = u.id
+        WHERE i.remix_of = $1 AND i.moderation_status = 'approved' AND i.visibility = 'public'
+          AND NOT COALESCE(u.is_shadowbanned, FALSE)
+        ORDER BY i.created_at DESC, i.id DESC
+        LIMIT $2`, parentID, limit)
+	return images, err
+}
+
 func (r *ImageRepository) CountByUser(userID uuid.UUID) (int, error) {
 	var cnt int
 	if err := r.db.Get(&cnt, `SELECT COUNT(*) FROM images WHERE user_id = $1`, userID); err != nil {
@@ -448,8 +779,16 @@ func (r *ImageRepository) CountByUser(userID uuid.UUID) (int, error) {
 	return cnt, nil
 }
 
+// StorageByUser sums the stored size of all of a user's images, hidden ones included.
+// Video posters are not counted.
+func (r *ImageRepository) StorageByUser(userID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Get(&n, `SELECT COALESCE(SUM(file_size), 0) FROM images WHERE user_id = $1`, userID)
+	return n, err
+}
+
 // UpdateMeta updates optional fields on an image
-func (r *ImageRepository) UpdateMeta(id uuid.UUID, title *string, caption *string, isNSFW *bool) error {
+func (r *ImageRepository) UpdateMeta(id uuid.UUID, title *string, caption *string, rating *ContentRating) error {
 	set := []string{}
 	args := []interface{}{}
 	i := 1
@@ -463,9 +802,9 @@ func (r *ImageRepository) UpdateMeta(id uuid.UUID, title *string, caption *strin
 		args = append(args, *caption)
 		i++
 	}
-	if isNSFW != nil {
-		set = append(set, fmt.Sprintf("is_nsfw = $%d", i))
-		args = append(args, *isNSFW)
+	if rating != nil {
+		set = append(set, fmt.Sprintf("rating = $%d", i))
+		args = append(args, *rating)
 		i++
 	}
 	if len(set) == 0 {
@@ -477,6 +816,17 @@ func (r *ImageRepository) UpdateMeta(id uuid.UUID, title *string, caption *strin
 	return err
 }
 
+// ReplaceFile points an image at a new file, taking the file's size, dimensions, preview
+// colors, metadata, workflow sidecar, model and AI provenance from img. The id, owner, text and counters stay.
+func (r *ImageRepository) ReplaceFile(img *Image) error {
+	_, err := r.db.Exec(`UPDATE images SET filename = $1, file_size = $2, width = $3, height = $4, blurhash = $5, dominant_color = $6,
+		ai_signature = $7, ai_provider = $8, exif_data = $9, frame_count = $10, duration_ms = $11, sha256 = $12, workflow_key = $13, ai_model = $14, checksum_mismatch_at = NULL
+		WHERE id = $15`,
+		img.Filename, img.FileSize, img.Width, img.Height, img.Blurhash, img.DominantColor,
+		img.AISignature, img.AIProvider, img.ExifData, img.FrameCount, img.DurationMS, img.SHA256, img.WorkflowKey, img.AIModel, img.ID)
+	return err
+}
+
 func (r *ImageRepository) UpdateFilename(id uuid.UUID, newFilename string) error {
 	_, err := r.db.Exec(`UPDATE images SET filename = $1 WHERE id = $2`, newFilename, id)
 	return err
@@ -589,24 +939,77 @@ func (r *LikeRepository) GetByUser(userID uuid.UUID, imageID uuid.UUID) (*Like,
 	return &like, nil
 }
 
+// CollectRepository's collection listings from ForViewer also say whether the viewer
+// collected each image, and leave out images by users the viewer blocked.
 type CollectRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	viewer *uuid.UUID
 }
 
 func NewCollectRepository(db *sqlx.DB) *CollectRepository {
 	return &CollectRepository{db: db}
 }
 
+// ForViewer returns a repository whose listings are tailored to viewer.
+func (r *CollectRepository) ForViewer(viewer uuid.UUID) CollectRepositoryInterface {
+	return &CollectRepository{db: r.db, viewer: &viewer}
+}
+
+// Create collects an image onto the user's default board, creating the board if needed.
 func (r *CollectRepository) Create(userID, imageID uuid.UUID) error {
-	_, err := r.db.Exec(`INSERT INTO collections (user_id, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, imageID)
-	return err
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	boardID, err := ensureDefaultBoard(tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := addBoardItem(tx, boardID, imageID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO collections (user_id, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, imageID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// Delete uncollects an image, taking it off all of the user's boards.
 func (r *CollectRepository) Delete(userID, imageID uuid.UUID) error {
-	_, err := r.db.Exec(`DELETE FROM collections WHERE user_id = $1 AND image_id = $2`, userID, imageID)
+	_, err := r.db.Exec(`WITH items AS (
+		DELETE FROM board_items WHERE image_id = $2 AND board_id IN (SELECT id FROM boards WHERE user_id = $1)
+	)
+	DELETE FROM collections WHERE user_id = $1 AND image_id = $2`, userID, imageID)
 	return err
 }
 
+// CountForImage returns how many users have collected the image.
+func (r *CollectRepository) CountForImage(imageID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT collected_count FROM images WHERE id = $1`, imageID)
+	return n, err
+}
+
+// Collectors pages through the users who collected an image, most recent first. Disabled
+// accounts are left out.
+func (r *CollectRepository) Collectors(imageID uuid.UUID, page, limit int) ([]Collector, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM collections c JOIN users u ON u.id = c.user_id WHERE c.image_id = $1 AND NOT COALESCE(u.is_disabled, FALSE)`, imageID); err != nil {
+		return nil, 0, err
+	}
+	out := []Collector{}
+	err := r.db.Select(&out, `SELECT u.id AS user_id, u.username, u.avatar_url, c.created_at AS collected_at
+		FROM collections c JOIN users u ON u.id = c.user_id
+		WHERE c.image_id = $1 AND NOT COALESCE(u.is_disabled, FALSE)
+		ORDER BY c.created_at DESC, u.id
+		LIMIT $2 OFFSET $3`, imageID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
 func (r *CollectRepository) GetByUser(userID uuid.UUID, imageID uuid.UUID) (*Collect, error) {
 	var col Collect
 	err := r.db.Get(&col, `SELECT * FROM collections WHERE user_id = $1 AND image_id = $2`, userID, imageID)
@@ -616,68 +1019,85 @@ func (r *CollectRepository) GetByUser(userID uuid.UUID, imageID uuid.UUID) (*Col
 	return &col, nil
 }
 
-func (r *CollectRepository) GetUserCollections(userID uuid.UUID, page, limit int) ([]ImageWithUser, int, error) {
+// collectionVisible is a condition on collections c that drops images kept only on the
+// collector's private boards unless the placeholder param is true.
+func collectionVisible(param string) string {
+	return `(` + param + ` OR NOT EXISTS (SELECT 1 FROM board_items bi JOIN boards b ON b.id = bi.board_id WHERE b.user_id = c.user_id AND bi.image_id = c.image_id)
+		OR EXISTS (SELECT 1 FROM board_items bi JOIN boards b ON b.id = bi.board_id WHERE b.user_id = c.user_id AND bi.image_id = c.image_id AND b.is_public))`
+}
+
+// GetUserCollections pages through the images a user has collected; includePrivate also
+// lists those that are only on their private boards.
+func (r *CollectRepository) GetUserCollections(userID uuid.UUID, page, limit int, includePrivate bool) ([]ImageWithUser, int, error) {
 	offset := (page - 1) * limit
 	var images []ImageWithUser
 	var total int
-	if err := r.db.Get(&total, `SELECT COUNT(*) FROM collections WHERE user_id = $1`, userID); err != nil {
+	_, _, countFilter, countArgs := viewerScope(r.viewer, 3)
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM collections c JOIN images i ON c.image_id = i.id WHERE c.user_id = $1 AND i.visibility <> 'private' AND `+collectionVisible("$2")+` `+countFilter,
+		append([]any{userID, includePrivate}, countArgs...)...); err != nil {
 		return nil, 0, err
 	}
+	col, join, filter, args := viewerScope(r.viewer, 5)
 	q := `
         SELECT 
             i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-            i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-            u.username, u.avatar_url
+            i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+            COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+            u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
         FROM collections c
         JOIN images i ON c.image_id = i.id
         LEFT JOIN users u ON i.user_id = u.id
-        WHERE c.user_id = $1
+        ` + join + `
+        WHERE c.user_id = $1 AND i.visibility <> 'private' AND ` + collectionVisible("$4") + ` ` + filter + `
         ORDER BY i.created_at DESC, i.id DESC
         LIMIT $2 OFFSET $3`
-	if err := r.db.Select(&images, q, userID, limit, offset); err != nil {
+	if err := r.db.Select(&images, q, append([]any{userID, limit, offset, includePrivate}, args...)...); err != nil {
 		return nil, 0, err
 	}
 	return images, total, nil
 }
 
-func (r *CollectRepository) GetUserCollectionsSeek(userID uuid.UUID, limit int, cursorEncoded string) ([]ImageWithUser, string, error) {
+func (r *CollectRepository) GetUserCollectionsSeek(userID uuid.UUID, limit int, cursorEncoded string, includePrivate bool) ([]ImageWithUser, string, error) {
 	cur, err := decodeFeedCursor(cursorEncoded)
 	if err != nil {
 		return nil, "", err
 	}
 	var images []ImageWithUser
 	if cur == nil {
+		col, join, filter, args := viewerScope(r.viewer, 4)
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM collections c
             JOIN images i ON c.image_id = i.id
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE c.user_id = $1
+            ` + join + `
+            WHERE c.user_id = $1 AND i.visibility <> 'private' AND ` + collectionVisible("$3") + ` ` + filter + `
             ORDER BY i.created_at DESC, i.id DESC
             LIMIT $2`
-		if err := r.db.Select(&images, q, userID, limit); err != nil {
+		if err := r.db.Select(&images, q, append([]any{userID, limit, includePrivate}, args...)...); err != nil {
 			return nil, "", err
 		}
 	} else {
 		// For seek pagination: use images.created_at as primary order when available; fallback to collections.created_at
+		col, join, filter, args := viewerScope(r.viewer, 6)
 		q := `
             SELECT 
                 i.id, i.user_id, i.filename, i.original_name, i.file_size, i.width, i.height,
-                i.blurhash, i.dominant_color, i.is_nsfw, i.ai_signature, i.ai_provider,
-                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.created_at,
-                u.username, u.avatar_url
+                i.blurhash, i.dominant_color, i.is_nsfw, i.rating, i.ai_signature, i.ai_provider, i.ai_model,
+                COALESCE(i.exif_data, 'null'::jsonb) AS exif_data, i.caption, i.likes_count, i.collected_count, i.moderation_status, i.visibility, i.license, i.frame_count, i.duration_ms, i.media_type, i.poster_filename, i.sha256, i.checksum_mismatch_at, i.created_at,
+                u.username, u.avatar_url, COALESCE(u.is_verified, FALSE) AS user_verified` + col + `
             FROM collections c
             JOIN images i ON c.image_id = i.id
             LEFT JOIN users u ON i.user_id = u.id
-            WHERE c.user_id = $1 AND (i.created_at < $2 OR (i.created_at = $2 AND i.id < $3))
+            ` + join + `
+            WHERE c.user_id = $1 AND i.visibility <> 'private' AND (i.created_at < $2 OR (i.created_at = $2 AND i.id < $3)) AND ` + collectionVisible("$5") + ` ` + filter + `
             ORDER BY i.created_at DESC, i.id DESC
             LIMIT $4`
-		if err := r.db.Select(&images, q, userID, cur.CreatedAt, cur.ID, limit); err != nil {
+		if err := r.db.Select(&images, q, append([]any{userID, cur.CreatedAt, cur.ID, limit, includePrivate}, args...)...); err != nil {
 			return nil, "", err
 		}
 	}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SecurityEvent is a persisted rate limiter security event.
+type SecurityEvent struct {
+	ID          int64     `db:"id" json:"id"`
+	EventType   string    `db:"event_type" json:"event_type"`
+	Severity    string    `db:"severity" json:"severity"`
+	IPAddress   string    `db:"ip_address" json:"ip_address"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	Path        string    `db:"path" json:"path"`
+	Method      string    `db:"method" json:"method"`
+	Description string    `db:"description" json:"description"`
+	RequestID   string    `db:"request_id" json:"request_id,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// SecurityEventFilter narrows a listing. Zero values match everything.
+type SecurityEventFilter struct {
+	Severities []string
+	EventType  string
+	IP         string
+	Since      time.Time
+	Until      time.Time
+}
+
+type SecurityEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewSecurityEventRepository(db *sqlx.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+// InsertBatch stores events in one statement.
+func (r *SecurityEventRepository) InsertBatch(events []SecurityEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := r.db.NamedExec(`INSERT INTO security_events (event_type, severity, ip_address, user_agent, path, method, description, request_id, created_at)
+		VALUES (:event_type, :severity, :ip_address, :user_agent, :path, :method, :description, :request_id, :created_at)`, events)
+	return err
+}
+
+// List returns matching events, newest first, with the total match count.
+func (r *SecurityEventRepository) List(f SecurityEventFilter, page, limit int) ([]SecurityEvent, int, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if len(f.Severities) > 0 {
+		ph := make([]string, len(f.Severities))
+		for i, s := range f.Severities {
+			ph[i] = arg(s)
+		}
+		where = append(where, "severity IN ("+strings.Join(ph, ", ")+")")
+	}
+	if f.EventType != "" {
+		where = append(where, "event_type = "+arg(f.EventType))
+	}
+	if f.IP != "" {
+		where = append(where, "ip_address = "+arg(f.IP))
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= "+arg(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < "+arg(f.Until))
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM security_events`+cond, args...); err != nil {
+		return nil, 0, err
+	}
+	out := []SecurityEvent{}
+	q := `SELECT * FROM security_events` + cond + ` ORDER BY created_at DESC, id DESC LIMIT ` + arg(limit) + ` OFFSET ` + arg((page-1)*limit)
+	err := r.db.Select(&out, q, args...)
+	return out, total, err
+}
+
+// Purge drops low-severity events older than lowBefore and all others older than before.
+func (r *SecurityEventRepository) Purge(before, lowBefore time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM security_events WHERE created_at < $1 OR (severity = 'low' AND created_at < $2)`, before, lowBefore)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
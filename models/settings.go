@@ -43,6 +43,46 @@ type SiteSettings struct {
 	BackupEnabled  bool   `db:"backup_enabled" json:"backup_enabled"`
 	BackupInterval string `db:"backup_interval" json:"backup_interval"`
 	BackupKeepDays int    `db:"backup_keep_days" json:"backup_keep_days"`
+	// Remote backups are pushed to S3/R2 under backups/; the bucket must be a private one other than the uploads bucket
+	BackupRemoteEnabled bool   `db:"backup_remote_enabled" json:"backup_remote_enabled"`
+	BackupRemoteBucket  string `db:"backup_remote_bucket" json:"backup_remote_bucket"`
+	// bcrypt marker of the backup encryption passphrase; managed via SetBackupPassphraseMarker, never serialized
+	BackupPassphraseMarker string `db:"backup_passphrase_marker" json:"-"`
+	// Storage reconciliation (scheduled runs are report-only)
+	StorageReconcileEnabled  bool   `db:"storage_reconcile_enabled" json:"storage_reconcile_enabled"`
+	StorageReconcileInterval string `db:"storage_reconcile_interval" json:"storage_reconcile_interval"`
+	// Uploads are held for review until the uploader has this many approved images (0 disables)
+	ModerationHoldUploads int `db:"moderation_hold_uploads" json:"moderation_hold_uploads"`
+	// Refuse registrations from known disposable email providers
+	BlockDisposableEmails bool `db:"block_disposable_emails" json:"block_disposable_emails"`
+	// Stamp original downloads for non-owners; empty text falls back to the site name
+	DownloadWatermarkEnabled bool   `db:"download_watermark_enabled" json:"download_watermark_enabled"`
+	DownloadWatermarkText    string `db:"download_watermark_text" json:"download_watermark_text"`
+	// Strip GPS and device identifiers from every re-encoded upload (users can opt in individually)
+	ExifPrivacyMode bool `db:"exif_privacy_mode" json:"exif_privacy_mode"`
+	// Invites a non-staff user may issue per calendar month (0 disables) once their account is old enough
+	UserInviteQuota          int `db:"user_invite_quota" json:"user_invite_quota"`
+	UserInviteMinAccountDays int `db:"user_invite_min_account_days" json:"user_invite_min_account_days"`
+	// Challenge for flagged addresses on register/forgot-password: "", "pow", "hcaptcha" or "turnstile"
+	ChallengeProvider  string `db:"challenge_provider" json:"challenge_provider"`
+	ChallengeSiteKey   string `db:"challenge_site_key" json:"challenge_site_key"`
+	ChallengeSecretKey string `db:"challenge_secret_key" json:"challenge_secret_key"`
+	// Seconds the registration form must be open before submitting (0 disables the timing check)
+	RegistrationMinFillSeconds int `db:"registration_min_fill_seconds" json:"registration_min_fill_seconds"`
+	// New accounts wait for staff approval before they can sign in or upload
+	RegistrationApprovalRequired bool `db:"registration_approval_required" json:"registration_approval_required"`
+	// Terms and privacy page revisions users must have accepted (0 does not track one);
+	// managed via LegalRepository.Publish
+	TermsRevision   int `db:"terms_revision" json:"terms_revision"`
+	PrivacyRevision int `db:"privacy_revision" json:"privacy_revision"`
+	// Age registration asks users to confirm (0 asks nothing); an adult site also has
+	// signed-out visitors confirm it before the feed and images load
+	MinimumAge int  `db:"minimum_age" json:"minimum_age"`
+	AdultSite  bool `db:"adult_site" json:"adult_site"`
+	// Extra script origins allowed by the Content-Security-Policy, space-separated
+	CSPScriptSources string `db:"csp_script_sources" json:"csp_script_sources"`
+	// Locale for emails and API messages when the browser asks for none we support
+	DefaultLocale string `db:"default_locale" json:"default_locale"`
 }
 
 type SiteSettingsRepository struct{ db *sqlx.DB }
@@ -63,7 +103,7 @@ func (r *SiteSettingsRepository) Get() (*SiteSettings, error) {
 	err := r.db.Get(&s, `SELECT * FROM site_settings WHERE id = 1`)
 	if err != nil {
 		// Safe defaults when no settings row exists yet
-		return &SiteSettings{ID: 1, SiteName: "TROUGH", PublicRegistrationEnabled: true, BackupInterval: "24h", BackupKeepDays: 7}, nil
+		return &SiteSettings{ID: 1, SiteName: "TROUGH", PublicRegistrationEnabled: true, BackupInterval: "24h", BackupKeepDays: 7, StorageReconcileInterval: "24h", UserInviteMinAccountDays: 30, DefaultLocale: "en"}, nil
 	}
 	return &s, nil
 }
@@ -78,6 +118,18 @@ func (r *SiteSettingsRepository) Upsert(s *SiteSettings) error {
             analytics_enabled, analytics_provider, ga4_measurement_id, umami_src, umami_website_id,
             plausible_src, plausible_domain,
             backup_enabled, backup_interval, backup_keep_days,
+            storage_reconcile_enabled, storage_reconcile_interval,
+            backup_remote_enabled, backup_remote_bucket,
+            moderation_hold_uploads, block_disposable_emails,
+            download_watermark_enabled, download_watermark_text,
+            exif_privacy_mode,
+            user_invite_quota, user_invite_min_account_days,
+            challenge_provider, challenge_site_key, challenge_secret_key,
+            registration_min_fill_seconds,
+            csp_script_sources,
+            default_locale,
+            registration_approval_required,
+            minimum_age, adult_site,
             updated_at
         ) VALUES (
             1, $1, $2, $3, $4, $5,
@@ -87,6 +139,18 @@ func (r *SiteSettingsRepository) Upsert(s *SiteSettings) error {
             $21, $22, $23, $24, $25,
             $26, $27,
             $28, $29, $30,
+            $31, $32,
+            $33, $34,
+            $35, $36,
+            $37, $38,
+            $39,
+            $40, $41,
+            $42, $43, $44,
+            $45,
+            $46,
+            $47,
+            $48,
+            $49, $50,
             NOW()
         )
         ON CONFLICT (id) DO UPDATE SET
@@ -120,6 +184,26 @@ func (r *SiteSettingsRepository) Upsert(s *SiteSettings) error {
             backup_enabled = EXCLUDED.backup_enabled,
             backup_interval = EXCLUDED.backup_interval,
             backup_keep_days = EXCLUDED.backup_keep_days,
+            storage_reconcile_enabled = EXCLUDED.storage_reconcile_enabled,
+            storage_reconcile_interval = EXCLUDED.storage_reconcile_interval,
+            backup_remote_enabled = EXCLUDED.backup_remote_enabled,
+            backup_remote_bucket = EXCLUDED.backup_remote_bucket,
+            moderation_hold_uploads = EXCLUDED.moderation_hold_uploads,
+            block_disposable_emails = EXCLUDED.block_disposable_emails,
+            download_watermark_enabled = EXCLUDED.download_watermark_enabled,
+            download_watermark_text = EXCLUDED.download_watermark_text,
+            exif_privacy_mode = EXCLUDED.exif_privacy_mode,
+            user_invite_quota = EXCLUDED.user_invite_quota,
+            user_invite_min_account_days = EXCLUDED.user_invite_min_account_days,
+            challenge_provider = EXCLUDED.challenge_provider,
+            challenge_site_key = EXCLUDED.challenge_site_key,
+            challenge_secret_key = EXCLUDED.challenge_secret_key,
+            registration_min_fill_seconds = EXCLUDED.registration_min_fill_seconds,
+            csp_script_sources = EXCLUDED.csp_script_sources,
+            default_locale = EXCLUDED.default_locale,
+            registration_approval_required = EXCLUDED.registration_approval_required,
+            minimum_age = EXCLUDED.minimum_age,
+            adult_site = EXCLUDED.adult_site,
             updated_at = NOW()
     `,
 		s.SiteName, s.SiteURL, s.SEOTitle, s.SEODescription, s.SocialImageURL,
@@ -129,10 +213,29 @@ func (r *SiteSettingsRepository) Upsert(s *SiteSettings) error {
 		s.AnalyticsEnabled, s.AnalyticsProvider, s.GA4MeasurementID, s.UmamiSrc, s.UmamiWebsiteID,
 		s.PlausibleSrc, s.PlausibleDomain,
 		s.BackupEnabled, s.BackupInterval, s.BackupKeepDays,
+		s.StorageReconcileEnabled, s.StorageReconcileInterval,
+		s.BackupRemoteEnabled, s.BackupRemoteBucket,
+		s.ModerationHoldUploads, s.BlockDisposableEmails,
+		s.DownloadWatermarkEnabled, s.DownloadWatermarkText,
+		s.ExifPrivacyMode,
+		s.UserInviteQuota, s.UserInviteMinAccountDays,
+		s.ChallengeProvider, s.ChallengeSiteKey, s.ChallengeSecretKey,
+		s.RegistrationMinFillSeconds,
+		s.CSPScriptSources,
+		s.DefaultLocale,
+		s.RegistrationApprovalRequired,
+		s.MinimumAge, s.AdultSite,
 	)
 	return err
 }
 
+// SetBackupPassphraseMarker stores the backup passphrase marker (empty disables encryption).
+// Not part of the interface to keep external mocks stable; Upsert never touches this column.
+func SetBackupPassphraseMarker(marker string) error {
+	_, err := DB().Exec(`UPDATE site_settings SET backup_passphrase_marker=$1, updated_at=NOW() WHERE id=1`, marker)
+	return err
+}
+
 func (r *SiteSettingsRepository) UpdateFavicon(path string) error {
 	_, err := r.db.Exec(`UPDATE site_settings SET favicon_path=$1, updated_at=NOW() WHERE id=1`, path)
 	return err
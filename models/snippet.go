@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Snippet is a keyed block of copy shown by the SPA wherever it has a slot for Key.
+// Content is markdown (raw HTML allowed); HTML is its sanitized render.
+type Snippet struct {
+	Key       string     `db:"key" json:"key"`
+	Content   string     `db:"content" json:"content"`
+	HTML      string     `db:"html" json:"html"`
+	UpdatedBy *uuid.UUID `db:"updated_by" json:"updated_by"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type SnippetRepository struct {
+	db *sqlx.DB
+}
+
+func NewSnippetRepository(db *sqlx.DB) *SnippetRepository {
+	return &SnippetRepository{db: db}
+}
+
+// List returns every snippet ordered by key.
+func (r *SnippetRepository) List() ([]Snippet, error) {
+	out := []Snippet{}
+	err := r.db.Select(&out, `SELECT * FROM snippets ORDER BY key`)
+	return out, err
+}
+
+func (r *SnippetRepository) Get(key string) (*Snippet, error) {
+	var s Snippet
+	if err := r.db.Get(&s, `SELECT * FROM snippets WHERE key = $1`, key); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Upsert creates the snippet or replaces its content.
+func (r *SnippetRepository) Upsert(s *Snippet) error {
+	return r.db.QueryRow(`INSERT INTO snippets (key, content, html, updated_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET content = EXCLUDED.content, html = EXCLUDED.html, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		s.Key, s.Content, s.HTML, s.UpdatedBy).Scan(&s.CreatedAt, &s.UpdatedAt)
+}
+
+func (r *SnippetRepository) Delete(key string) error {
+	_, err := r.db.Exec(`DELETE FROM snippets WHERE key = $1`, key)
+	return err
+}
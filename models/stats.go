@@ -0,0 +1,202 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// StatsRange is a dashboard window: how far back to look and how wide each bucket is.
+type StatsRange struct {
+	Name     string
+	Interval string // Postgres interval literal, e.g. "30 days"
+	Bucket   string // date_trunc unit: hour, day, week or month
+}
+
+// StatsRanges are the windows the admin dashboard can select.
+var StatsRanges = []StatsRange{
+	{Name: "24h", Interval: "24 hours", Bucket: "hour"},
+	{Name: "7d", Interval: "7 days", Bucket: "day"},
+	{Name: "30d", Interval: "30 days", Bucket: "day"},
+	{Name: "90d", Interval: "90 days", Bucket: "week"},
+	{Name: "365d", Interval: "365 days", Bucket: "month"},
+}
+
+// ParseStatsRange looks up a range by name; "" selects 30d.
+func ParseStatsRange(name string) (StatsRange, error) {
+	if name == "" {
+		name = "30d"
+	}
+	for _, r := range StatsRanges {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return StatsRange{}, fmt.Errorf("unknown range %q", name)
+}
+
+// StatsPoint is one bucket of the dashboard time series. StorageBytes is the running
+// total of image bytes at the end of the bucket; UploadBytes is what the bucket added.
+type StatsPoint struct {
+	Bucket       time.Time `db:"bucket" json:"bucket"`
+	Signups      int       `db:"signups" json:"signups"`
+	Uploads      int       `db:"uploads" json:"uploads"`
+	UploadBytes  int64     `db:"upload_bytes" json:"upload_bytes"`
+	Collections  int       `db:"collections" json:"collections"`
+	Blocked      int       `db:"blocked" json:"blocked_signups"`
+	StorageBytes int64     `db:"-" json:"storage_bytes"`
+}
+
+type ProviderCount struct {
+	Provider string `db:"provider" json:"provider"`
+	Count    int    `db:"count" json:"count"`
+}
+
+type ReasonCount struct {
+	Reason string `db:"reason" json:"reason"`
+	Count  int    `db:"count" json:"count"`
+}
+
+type TopUploader struct {
+	UserID   uuid.UUID `db:"user_id" json:"user_id"`
+	Username string    `db:"username" json:"username"`
+	Uploads  int       `db:"uploads" json:"uploads"`
+	Bytes    int64     `db:"bytes" json:"bytes"`
+}
+
+// SiteTotals are all-time counts shown alongside the series.
+type SiteTotals struct {
+	Users        int   `db:"users" json:"users"`
+	Images       int   `db:"images" json:"images"`
+	Collections  int   `db:"collections" json:"collections"`
+	StorageBytes int64 `db:"storage_bytes" json:"storage_bytes"`
+}
+
+type AdminStats struct {
+	Range        string          `json:"range"`
+	Bucket       string          `json:"bucket"`
+	Totals       SiteTotals      `json:"totals"`
+	Series       []StatsPoint    `json:"series"`
+	AIProviders  []ProviderCount `json:"ai_providers"`
+	TopUploaders []TopUploader   `json:"top_uploaders"`
+	// Registrations refused by the antispam checks in the window, by reason
+	BlockedSignups []ReasonCount `json:"blocked_signups"`
+}
+
+type StatsRepository struct {
+	db *sqlx.DB
+}
+
+func NewStatsRepository(db *sqlx.DB) *StatsRepository { return &StatsRepository{db: db} }
+
+// The series query aggregates each table once per window (using the created_at indexes)
+// and left-joins the results onto a generated bucket series so empty buckets read as zero.
+// Timestamps are stored without a zone, so bounds are computed against NOW()::timestamp.
+const statsSeriesQuery = `
+WITH bounds AS (SELECT NOW()::timestamp - $2::interval AS since),
+buckets AS (
+	SELECT generate_series(date_trunc($1, (SELECT since FROM bounds)), date_trunc($1, NOW()::timestamp), ('1 ' || $1)::interval) AS bucket
+),
+u AS (SELECT date_trunc($1, created_at) AS bucket, COUNT(*) AS n FROM users WHERE created_at >= (SELECT since FROM bounds) GROUP BY 1),
+i AS (SELECT date_trunc($1, created_at) AS bucket, COUNT(*) AS n, COALESCE(SUM(file_size), 0) AS bytes FROM images WHERE created_at >= (SELECT since FROM bounds) GROUP BY 1),
+c AS (SELECT date_trunc($1, created_at) AS bucket, COUNT(*) AS n FROM collections WHERE created_at >= (SELECT since FROM bounds) GROUP BY 1),
+x AS (SELECT date_trunc($1, created_at) AS bucket, COUNT(*) AS n FROM registration_blocks WHERE created_at >= (SELECT since FROM bounds) GROUP BY 1)
+SELECT b.bucket, COALESCE(u.n, 0) AS signups, COALESCE(i.n, 0) AS uploads, COALESCE(i.bytes, 0) AS upload_bytes, COALESCE(c.n, 0) AS collections, COALESCE(x.n, 0) AS blocked
+FROM buckets b
+LEFT JOIN u ON u.bucket = b.bucket
+LEFT JOIN i ON i.bucket = b.bucket
+LEFT JOIN c ON c.bucket = b.bucket
+LEFT JOIN x ON x.bucket = b.bucket
+ORDER BY b.bucket`
+
+// AdminStats computes the dashboard for rng: per-bucket signups, uploads, collections and
+// storage growth, the AI provider mix and the top uploaders within the window.
+func (r *StatsRepository) AdminStats(rng StatsRange) (*AdminStats, error) {
+	out := &AdminStats{Range: rng.Name, Bucket: rng.Bucket}
+	if err := r.db.Get(&out.Totals, `SELECT
+		(SELECT COUNT(*) FROM users) AS users,
+		(SELECT COUNT(*) FROM images) AS images,
+		(SELECT COUNT(*) FROM collections) AS collections,
+		(SELECT COALESCE(SUM(file_size), 0) FROM images) AS storage_bytes`); err != nil {
+		return nil, err
+	}
+	if err := r.db.Select(&out.Series, statsSeriesQuery, rng.Bucket, rng.Interval); err != nil {
+		return nil, err
+	}
+	// Walk the running total back from today's figure so the series ends at Totals
+	var windowBytes int64
+	for _, p := range out.Series {
+		windowBytes += p.UploadBytes
+	}
+	running := out.Totals.StorageBytes - windowBytes
+	for i := range out.Series {
+		running += out.Series[i].UploadBytes
+		out.Series[i].StorageBytes = running
+	}
+	out.AIProviders = []ProviderCount{}
+	if err := r.db.Select(&out.AIProviders, `SELECT COALESCE(NULLIF(ai_provider, ''), 'unknown') AS provider, COUNT(*) AS count
+		FROM images WHERE created_at >= NOW()::timestamp - $1::interval
+		GROUP BY 1 ORDER BY count DESC, provider LIMIT 20`, rng.Interval); err != nil {
+		return nil, err
+	}
+	out.TopUploaders = []TopUploader{}
+	if err := r.db.Select(&out.TopUploaders, `SELECT u.id AS user_id, u.username, COUNT(*) AS uploads, COALESCE(SUM(i.file_size), 0) AS bytes
+		FROM images i JOIN users u ON u.id = i.user_id
+		WHERE i.created_at >= NOW()::timestamp - $1::interval
+		GROUP BY u.id, u.username ORDER BY uploads DESC, bytes DESC LIMIT 10`, rng.Interval); err != nil {
+		return nil, err
+	}
+	out.BlockedSignups = []ReasonCount{}
+	if err := r.db.Select(&out.BlockedSignups, `SELECT reason, COUNT(*) AS count FROM registration_blocks
+		WHERE created_at >= NOW()::timestamp - $1::interval
+		GROUP BY reason ORDER BY count DESC, reason`, rng.Interval); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserStatsSummary is the compact form embedded in profile responses.
+type UserStatsSummary struct {
+	Images    int `db:"images" json:"images"`
+	Collected int `db:"collected" json:"collected"`
+}
+
+// UserStats is the public per-user breakdown: Collected counts how many times the user's
+// images have been collected by anyone.
+type UserStats struct {
+	UserStatsSummary
+	FirstUploadAt *time.Time      `db:"first_upload_at" json:"first_upload_at"`
+	LastUploadAt  *time.Time      `db:"last_upload_at" json:"last_upload_at"`
+	AIProviders   []ProviderCount `db:"-" json:"ai_providers"`
+}
+
+// UserStatsSummary counts the user's images and the collections of them in one round trip.
+func (r *StatsRepository) UserStatsSummary(userID uuid.UUID) (*UserStatsSummary, error) {
+	var s UserStatsSummary
+	if err := r.db.Get(&s, `SELECT
+		(SELECT COUNT(*) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public') AS images,
+		(SELECT COUNT(*) FROM collections c JOIN images i ON i.id = c.image_id WHERE i.user_id = $1 AND i.visibility = 'public') AS collected`, userID); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UserStats adds the upload time span and provider breakdown to the summary counters.
+func (r *StatsRepository) UserStats(userID uuid.UUID) (*UserStats, error) {
+	var s UserStats
+	if err := r.db.Get(&s, `SELECT
+		(SELECT COUNT(*) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public') AS images,
+		(SELECT COUNT(*) FROM collections c JOIN images i ON i.id = c.image_id WHERE i.user_id = $1 AND i.visibility = 'public') AS collected,
+		(SELECT MIN(created_at) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public') AS first_upload_at,
+		(SELECT MAX(created_at) FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public') AS last_upload_at`, userID); err != nil {
+		return nil, err
+	}
+	s.AIProviders = []ProviderCount{}
+	if err := r.db.Select(&s.AIProviders, `SELECT COALESCE(NULLIF(ai_provider, ''), 'unknown') AS provider, COUNT(*) AS count
+		FROM images WHERE user_id = $1 AND moderation_status = 'approved' AND visibility = 'public' GROUP BY 1 ORDER BY count DESC, provider`, userID); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Takedown request statuses. An open request awaits review; a disabled one has its image
+// hidden while staff decide; removed and rejected are final.
+const (
+	TakedownOpen     = "open"
+	TakedownDisabled = "disabled"
+	TakedownRemoved  = "removed"
+	TakedownRejected = "rejected"
+)
+
+// Takedown log actions. Received is written when the request is filed; note records a
+// comment without changing the status.
+const (
+	TakedownActionReceived = "received"
+	TakedownActionDisable  = "disable"
+	TakedownActionRemove   = "remove"
+	TakedownActionReject   = "reject"
+	TakedownActionNote     = "note"
+)
+
+// takedownTransitions maps each status-changing action to the statuses it applies to and
+// the status it leaves the request in.
+var takedownTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	TakedownActionDisable: {[]string{TakedownOpen}, TakedownDisabled},
+	TakedownActionRemove:  {[]string{TakedownOpen, TakedownDisabled}, TakedownRemoved},
+	TakedownActionReject:  {[]string{TakedownOpen, TakedownDisabled}, TakedownRejected},
+}
+
+// IsTakedownAction reports whether action is one staff can take on a request.
+func IsTakedownAction(action string) bool {
+	_, ok := takedownTransitions[action]
+	return ok || action == TakedownActionNote
+}
+
+// TakedownRequest is a copyright complaint about an image. ImageID is nil once the image
+// is deleted; the image and owner fields are joined in for staff.
+type TakedownRequest struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	ImageID       *uuid.UUID `db:"image_id" json:"image_id"`
+	ImageURL      string     `db:"image_url" json:"image_url"`
+	ClaimantName  string     `db:"claimant_name" json:"claimant_name"`
+	ClaimantEmail string     `db:"claimant_email" json:"claimant_email"`
+	Organization  string     `db:"organization" json:"organization"`
+	OriginalWork  string     `db:"original_work" json:"original_work"`
+	Description   string     `db:"description" json:"description"`
+	Signature     string     `db:"signature" json:"signature"`
+	IPHash        string     `db:"ip_hash" json:"-"`
+	Status        string     `db:"status" json:"status"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+	ResolvedAt    *time.Time `db:"resolved_at" json:"resolved_at"`
+	ImageFilename *string    `db:"image_filename" json:"image_filename"`
+	OwnerID       *uuid.UUID `db:"owner_id" json:"owner_id"`
+	OwnerUsername *string    `db:"owner_username" json:"owner_username"`
+}
+
+// TakedownEvent is one entry in a request's resolution log.
+type TakedownEvent struct {
+	ID            int64      `db:"id" json:"id"`
+	RequestID     uuid.UUID  `db:"request_id" json:"-"`
+	Action        string     `db:"action" json:"action"`
+	Note          string     `db:"note" json:"note"`
+	ActorID       *uuid.UUID `db:"actor_id" json:"actor_id"`
+	ActorUsername *string    `db:"actor_username" json:"actor_username"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+type TakedownRepository struct {
+	db *sqlx.DB
+}
+
+func NewTakedownRepository(db *sqlx.DB) *TakedownRepository {
+	return &TakedownRepository{db: db}
+}
+
+const takedownSelect = `SELECT t.*, i.filename AS image_filename, i.user_id AS owner_id, u.username AS owner_username
+	FROM takedown_requests t
+	LEFT JOIN images i ON i.id = t.image_id
+	LEFT JOIN users u ON u.id = i.user_id`
+
+// Create files a request and opens its log with a received entry.
+func (r *TakedownRepository) Create(t *TakedownRequest) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	t.Status = TakedownOpen
+	if err := tx.QueryRow(`INSERT INTO takedown_requests (image_id, image_url, claimant_name, claimant_email, organization, original_work, description, signature, ip_hash, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at`,
+		t.ImageID, t.ImageURL, t.ClaimantName, t.ClaimantEmail, t.Organization, t.OriginalWork, t.Description, t.Signature, t.IPHash, t.Status,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO takedown_events (request_id, action) VALUES ($1, $2)`, t.ID, TakedownActionReceived); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TakedownRepository) Get(id uuid.UUID) (*TakedownRequest, error) {
+	var t TakedownRequest
+	if err := r.db.Get(&t, takedownSelect+` WHERE t.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ImageByFilename finds the image stored as name, which remote storage keeps as a full URL.
+func (r *TakedownRepository) ImageByFilename(name string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.Get(&id, `SELECT id FROM images WHERE filename = $1 OR filename LIKE '%/' || $1 ORDER BY created_at LIMIT 1`, name)
+	return id, err
+}
+
+// List returns requests newest first, optionally only those in status.
+func (r *TakedownRepository) List(status string, page, limit int) ([]TakedownRequest, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM takedown_requests WHERE ($1 = '' OR status = $1)`, status); err != nil {
+		return nil, 0, err
+	}
+	out := []TakedownRequest{}
+	err := r.db.Select(&out, takedownSelect+` WHERE ($1 = '' OR t.status = $1)
+		ORDER BY t.created_at DESC, t.id LIMIT $2 OFFSET $3`, status, limit, (page-1)*limit)
+	return out, total, err
+}
+
+// Apply moves a request through action and logs it, in one transaction. Disabling hides
+// the image; rejecting restores it unless another disabled request still covers it.
+// Removing only closes the request: the caller deletes the image and its files. It
+// returns false when the request's status doesn't allow the action, so two admins can't
+// both resolve it.
+func (r *TakedownRepository) Apply(id uuid.UUID, action, note string, actorID uuid.UUID) (bool, error) {
+	tr, ok := takedownTransitions[action]
+	if !ok {
+		return false, errors.New("unknown takedown action")
+	}
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q, args, err := sqlx.In(`UPDATE takedown_requests
+		SET status = ?, updated_at = NOW(), resolved_at = CASE WHEN ?::text IN ('removed', 'rejected') THEN NOW() ELSE resolved_at END
+		WHERE id = ? AND status IN (?) RETURNING image_id`, tr.to, tr.to, id, tr.from)
+	if err != nil {
+		return false, err
+	}
+	var imageID *uuid.UUID
+	if err := tx.QueryRow(tx.Rebind(q), args...).Scan(&imageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if imageID != nil {
+		switch action {
+		case TakedownActionDisable:
+			_, err = tx.Exec(`UPDATE images SET moderation_status = 'disabled' WHERE id = $1 AND moderation_status = 'approved'`, *imageID)
+		case TakedownActionReject:
+			_, err = tx.Exec(`UPDATE images SET moderation_status = 'approved'
+				WHERE id = $1 AND moderation_status = 'disabled'
+				  AND NOT EXISTS (SELECT 1 FROM takedown_requests WHERE image_id = $1 AND status = 'disabled' AND id <> $2)`, *imageID, id)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO takedown_events (request_id, action, note, actor_id) VALUES ($1, $2, $3, $4)`, id, action, note, actorID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// AddEvent logs an entry without changing the request's status.
+func (r *TakedownRepository) AddEvent(id uuid.UUID, action, note string, actorID uuid.UUID) error {
+	_, err := r.db.Exec(`INSERT INTO takedown_events (request_id, action, note, actor_id) VALUES ($1, $2, $3, $4)`, id, action, note, actorID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE takedown_requests SET updated_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// Events returns a request's log oldest first.
+func (r *TakedownRepository) Events(id uuid.UUID) ([]TakedownEvent, error) {
+	out := []TakedownEvent{}
+	err := r.db.Select(&out, `SELECT e.*, u.username AS actor_username
+		FROM takedown_events e
+		LEFT JOIN users u ON u.id = e.actor_id
+		WHERE e.request_id = $1
+		ORDER BY e.created_at, e.id`, id)
+	return out, err
+}
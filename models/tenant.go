@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tenant is another domain served by this instance. Requests are matched to a tenant by
+// their Host; the branding and policy fields replace the site settings of the same name
+// when set.
+type Tenant struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	Host           string    `db:"host" json:"host"`
+	Name           string    `db:"name" json:"name"`
+	SiteName       string    `db:"site_name" json:"site_name"`
+	SiteURL        string    `db:"site_url" json:"site_url"`
+	SEOTitle       string    `db:"seo_title" json:"seo_title"`
+	SEODescription string    `db:"seo_description" json:"seo_description"`
+	SocialImageURL string    `db:"social_image_url" json:"social_image_url"`
+	FaviconPath    string    `db:"favicon_path" json:"favicon_path"`
+	// Policy overrides; nil inherits the primary site's setting
+	AdultSite     *bool   `db:"adult_site" json:"adult_site"`
+	MinimumAge    *int    `db:"minimum_age" json:"minimum_age"`
+	DefaultLocale *string `db:"default_locale" json:"default_locale"`
+	// Revisions of the tenant's own terms and privacy pages its users must have accepted
+	// (0 does not track one); managed via LegalRepository.Publish
+	TermsRevision   int       `db:"terms_revision" json:"terms_revision"`
+	PrivacyRevision int       `db:"privacy_revision" json:"privacy_revision"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Apply returns a copy of s with the tenant's branding and policy in place of the primary
+// site's. The tenant always has its own terms and privacy versions, since its pages are its
+// own. Mail, storage and the other operational settings stay shared.
+func (t *Tenant) Apply(s *SiteSettings) *SiteSettings {
+	out := *s
+	if t == nil {
+		return &out
+	}
+	override := func(dst *string, v string) {
+		if strings.TrimSpace(v) != "" {
+			*dst = v
+		}
+	}
+	override(&out.SiteName, t.SiteName)
+	override(&out.SiteURL, t.SiteURL)
+	override(&out.SEOTitle, t.SEOTitle)
+	override(&out.SEODescription, t.SEODescription)
+	override(&out.SocialImageURL, t.SocialImageURL)
+	override(&out.FaviconPath, t.FaviconPath)
+	if t.AdultSite != nil {
+		out.AdultSite = *t.AdultSite
+	}
+	if t.MinimumAge != nil {
+		out.MinimumAge = *t.MinimumAge
+	}
+	if t.DefaultLocale != nil {
+		override(&out.DefaultLocale, *t.DefaultLocale)
+	}
+	out.TermsRevision, out.PrivacyRevision = t.TermsRevision, t.PrivacyRevision
+	return &out
+}
+
+type TenantRepository struct {
+	db *sqlx.DB
+}
+
+func NewTenantRepository(db *sqlx.DB) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+func (r *TenantRepository) List() ([]Tenant, error) {
+	out := []Tenant{}
+	err := r.db.Select(&out, `SELECT * FROM tenants ORDER BY host`)
+	return out, err
+}
+
+func (r *TenantRepository) Get(id uuid.UUID) (*Tenant, error) {
+	var t Tenant
+	if err := r.db.Get(&t, `SELECT * FROM tenants WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TenantRepository) Create(t *Tenant) error {
+	return r.db.QueryRow(`INSERT INTO tenants (host, name, site_name, site_url, seo_title, seo_description, social_image_url, favicon_path,
+		adult_site, minimum_age, default_locale)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created_at, updated_at`,
+		t.Host, t.Name, t.SiteName, t.SiteURL, t.SEOTitle, t.SEODescription, t.SocialImageURL, t.FaviconPath,
+		t.AdultSite, t.MinimumAge, t.DefaultLocale).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *TenantRepository) Update(t *Tenant) error {
+	return r.db.QueryRow(`UPDATE tenants SET host = $2, name = $3, site_name = $4, site_url = $5, seo_title = $6, seo_description = $7,
+		social_image_url = $8, favicon_path = $9, adult_site = $10, minimum_age = $11, default_locale = $12, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`,
+		t.ID, t.Host, t.Name, t.SiteName, t.SiteURL, t.SEOTitle, t.SEODescription, t.SocialImageURL, t.FaviconPath,
+		t.AdultSite, t.MinimumAge, t.DefaultLocale).Scan(&t.UpdatedAt)
+}
+
+// Delete removes the tenant and its pages. It fails while images still belong to it.
+func (r *TenantRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM tenants WHERE id = $1`, id)
+	return err
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 	"time"
@@ -19,7 +21,8 @@ import (
 // - We track uses and last_used_at atomically via a single UPDATE with constraints
 // - We avoid exposing internal IDs; only the code is shared externally
 //
-// Admins manage invites via admin endpoints only.
+// Admins manage invites via admin endpoints; users who qualify can also issue a monthly
+// quota of personal invites.
 type Invite struct {
 	ID         uuid.UUID  `db:"id" json:"id"`
 	Code       string     `db:"code" json:"code"`
@@ -29,6 +32,26 @@ type Invite struct {
 	CreatedBy  *uuid.UUID `db:"created_by" json:"created_by"`
 	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
 	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	// Note is free text for the creator, e.g. who the invite was meant for
+	Note string `db:"note" json:"note"`
+	// Email binds the invite to one address when it was sent by email; "" means anyone
+	Email string `db:"email" json:"email,omitempty"`
+}
+
+// ErrInviteQuotaExceeded is returned when a user has used up this month's invites.
+var ErrInviteQuotaExceeded = errors.New("invite quota exceeded")
+
+// InviteTreeEdge is one account created from an invite, with who issued that invite.
+type InviteTreeEdge struct {
+	UserID          uuid.UUID  `db:"user_id" json:"user_id"`
+	Username        string     `db:"username" json:"username"`
+	InvitedBy       uuid.UUID  `db:"invited_by" json:"invited_by"`
+	InviterUsername *string    `db:"inviter_username" json:"inviter_username"`
+	InviteID        *uuid.UUID `db:"invite_id" json:"invite_id"`
+	InviteNote      *string    `db:"invite_note" json:"invite_note"`
+	IsDisabled      bool       `db:"is_disabled" json:"is_disabled"`
+	IsShadowbanned  bool       `db:"is_shadowbanned" json:"is_shadowbanned"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
 }
 
 // Invite repository interface is declared in interfaces.go to avoid circular deps
@@ -73,24 +96,88 @@ func generateInviteCode() (string, error) {
 }
 
 func (r *InviteRepository) Create(maxUses *int, expiresAt *time.Time, createdBy *uuid.UUID) (*Invite, error) {
-	q := `INSERT INTO invites (code, max_uses, expires_at, created_by) VALUES ($1,$2,$3,$4) RETURNING id, uses, created_at`
+	inv := &Invite{MaxUses: maxUses, ExpiresAt: expiresAt, CreatedBy: createdBy}
+	if err := r.Insert(inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Insert stores inv under a fresh code, filling in its code, id and timestamps.
+func (r *InviteRepository) Insert(inv *Invite) error {
+	q := `INSERT INTO invites (code, max_uses, expires_at, created_by, note, email) VALUES ($1,$2,$3,$4,$5,$6) RETURNING id, uses, created_at`
+	return r.insertWithCode(inv, q)
+}
+
+// InsertWithinQuota stores a personal invite unless its creator already issued quota
+// invites this calendar month, in which case it returns ErrInviteQuotaExceeded. The check
+// and the insert are one statement.
+func (r *InviteRepository) InsertWithinQuota(inv *Invite, quota int) error {
+	if inv.CreatedBy == nil {
+		return errors.New("personal invites need a creator")
+	}
+	q := fmt.Sprintf(`INSERT INTO invites (code, max_uses, expires_at, created_by, note, email)
+		SELECT $1,$2,$3,$4,$5,$6
+		WHERE (SELECT COUNT(*) FROM invites WHERE created_by = $4 AND created_at >= date_trunc('month', NOW())) < %d
+		RETURNING id, uses, created_at`, quota)
+	err := r.insertWithCode(inv, q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInviteQuotaExceeded
+	}
+	return err
+}
+
+func (r *InviteRepository) insertWithCode(inv *Invite, q string) error {
 	for attempts := 0; attempts < 5; attempts++ {
 		code, err := generateInviteCode()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		inv := &Invite{Code: code, MaxUses: maxUses, ExpiresAt: expiresAt, CreatedBy: createdBy}
-		if err := r.db.QueryRowx(q, inv.Code, inv.MaxUses, inv.ExpiresAt, inv.CreatedBy).Scan(&inv.ID, &inv.Uses, &inv.CreatedAt); err != nil {
+		inv.Code = code
+		if err := r.db.QueryRowx(q, inv.Code, inv.MaxUses, inv.ExpiresAt, inv.CreatedBy, inv.Note, inv.Email).Scan(&inv.ID, &inv.Uses, &inv.CreatedAt); err != nil {
 			// Retry on duplicate
 			if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
 				continue
 			}
-			return nil, err
-		} else {
-			return inv, nil
+			return err
 		}
+		return nil
 	}
-	return nil, errors.New("failed to generate unique invite code")
+	return errors.New("failed to generate unique invite code")
+}
+
+// CountCreatedThisMonth counts the invites a user issued this calendar month.
+func (r *InviteRepository) CountCreatedThisMonth(userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM invites WHERE created_by = $1 AND created_at >= date_trunc('month', NOW())`, userID)
+	return n, err
+}
+
+// ListByCreator returns the invites a user issued, newest first.
+func (r *InviteRepository) ListByCreator(userID uuid.UUID, limit int) ([]Invite, error) {
+	out := []Invite{}
+	err := r.db.Select(&out, `SELECT * FROM invites WHERE created_by = $1 ORDER BY created_at DESC LIMIT $2`, userID, limit)
+	return out, err
+}
+
+// RecordInviteeWithTx records on a new account which invite it registered with and who issued it.
+func (r *InviteRepository) RecordInviteeWithTx(tx *sqlx.Tx, userID uuid.UUID, inv *Invite) error {
+	_, err := tx.Exec(`UPDATE users SET invite_id = $1, invited_by = $2 WHERE id = $3`, inv.ID, inv.CreatedBy, userID)
+	return err
+}
+
+// TreeEdges returns every account that registered with an invite whose creator is known,
+// oldest first. Together the edges form the invite tree.
+func (r *InviteRepository) TreeEdges() ([]InviteTreeEdge, error) {
+	out := []InviteTreeEdge{}
+	err := r.db.Select(&out, `SELECT u.id AS user_id, u.username, u.invited_by, p.username AS inviter_username,
+			u.invite_id, i.note AS invite_note, u.is_disabled, u.is_shadowbanned, u.created_at
+		FROM users u
+		LEFT JOIN users p ON p.id = u.invited_by
+		LEFT JOIN invites i ON i.id = u.invite_id
+		WHERE u.invited_by IS NOT NULL
+		ORDER BY u.created_at`)
+	return out, err
 }
 
 func (r *InviteRepository) List(page, limit int) ([]Invite, int, error) {
@@ -110,6 +197,15 @@ func (r *InviteRepository) List(page, limit int) ([]Invite, int, error) {
 	return out, total, err
 }
 
+func (r *InviteRepository) GetByID(id uuid.UUID) (*Invite, error) {
+	var inv Invite
+	err := r.db.Get(&inv, `SELECT * FROM invites WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
 func (r *InviteRepository) GetByCode(code string) (*Invite, error) {
 	var inv Invite
 	err := r.db.Get(&inv, `SELECT * FROM invites WHERE code=$1`, code)
@@ -139,9 +235,9 @@ func (r *InviteRepository) Consume(code string) (*Invite, error) {
         WHERE code = $1
           AND (expires_at IS NULL OR NOW() < expires_at)
           AND (max_uses IS NULL OR uses < max_uses)
-        RETURNING id, code, max_uses, uses, expires_at, created_by, created_at, last_used_at`
+        RETURNING id, code, max_uses, uses, expires_at, created_by, created_at, last_used_at, email`
 	var inv Invite
-	err := r.db.QueryRowx(q, code).Scan(&inv.ID, &inv.Code, &inv.MaxUses, &inv.Uses, &inv.ExpiresAt, &inv.CreatedBy, &inv.CreatedAt, &inv.LastUsedAt)
+	err := r.db.QueryRowx(q, code).Scan(&inv.ID, &inv.Code, &inv.MaxUses, &inv.Uses, &inv.ExpiresAt, &inv.CreatedBy, &inv.CreatedAt, &inv.LastUsedAt, &inv.Email)
 	if err != nil {
 		return nil, errors.New("invalid or expired invite")
 	}
@@ -157,9 +253,9 @@ func (r *InviteRepository) ConsumeWithTx(tx *sqlx.Tx, code string) (*Invite, err
         WHERE code = $1
           AND (expires_at IS NULL OR NOW() < expires_at)
           AND (max_uses IS NULL OR uses < max_uses)
-        RETURNING id, code, max_uses, uses, expires_at, created_by, created_at, last_used_at`
+        RETURNING id, code, max_uses, uses, expires_at, created_by, created_at, last_used_at, email`
 	var inv Invite
-	err := tx.QueryRowx(q, code).Scan(&inv.ID, &inv.Code, &inv.MaxUses, &inv.Uses, &inv.ExpiresAt, &inv.CreatedBy, &inv.CreatedAt, &inv.LastUsedAt)
+	err := tx.QueryRowx(q, code).Scan(&inv.ID, &inv.Code, &inv.MaxUses, &inv.Uses, &inv.ExpiresAt, &inv.CreatedBy, &inv.CreatedAt, &inv.LastUsedAt, &inv.Email)
 	if err != nil {
 		return nil, errors.New("invalid or expired invite")
 	}
@@ -256,3 +352,67 @@ func LastVerificationSentAt(userID uuid.UUID) (time.Time, error) {
 	err := DB().Get(&t, `SELECT COALESCE(MAX(created_at), to_timestamp(0)) FROM email_verifications WHERE user_id=$1`, userID)
 	return t, err
 }
+
+// EmailChange is a pending change of a user's email, applied once the new address confirms.
+type EmailChange struct {
+	UserID    uuid.UUID `db:"user_id" json:"-"`
+	NewEmail  string    `db:"new_email" json:"new_email"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// CreateEmailChange replaces any pending email change of the user with a new one.
+func CreateEmailChange(userID uuid.UUID, newEmail, tokenHash, cancelHash string, expires time.Time) error {
+	tx, err := DB().Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM email_changes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO email_changes (user_id, new_email, token, cancel_token, expires_at) VALUES ($1,$2,$3,$4,$5)`, userID, newEmail, tokenHash, cancelHash, expires); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetEmailChange finds a pending change by its confirmation token hash.
+func GetEmailChange(tokenHash string) (*EmailChange, error) {
+	var ch EmailChange
+	err := DB().Get(&ch, `SELECT user_id, new_email, expires_at, created_at FROM email_changes WHERE token=$1`, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// PendingEmailChange returns the user's unexpired pending change, or sql.ErrNoRows.
+func PendingEmailChange(userID uuid.UUID) (*EmailChange, error) {
+	var ch EmailChange
+	err := DB().Get(&ch, `SELECT user_id, new_email, expires_at, created_at FROM email_changes WHERE user_id=$1 AND expires_at > NOW()`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// CancelEmailChange drops the pending change matching a cancel token hash and reports
+// whose it was.
+func CancelEmailChange(cancelHash string) (uuid.UUID, error) {
+	var uid uuid.UUID
+	err := DB().Get(&uid, `DELETE FROM email_changes WHERE cancel_token=$1 RETURNING user_id`, cancelHash)
+	return uid, err
+}
+
+// DeleteEmailChanges drops every pending email change of the user.
+func DeleteEmailChanges(userID uuid.UUID) error {
+	_, err := DB().Exec(`DELETE FROM email_changes WHERE user_id=$1`, userID)
+	return err
+}
+
+func LastEmailChangeSentAt(userID uuid.UUID) (time.Time, error) {
+	var t time.Time
+	err := DB().Get(&t, `SELECT COALESCE(MAX(created_at), to_timestamp(0)) FROM email_changes WHERE user_id=$1`, userID)
+	return t, err
+}
@@ -8,20 +8,59 @@ import (
 )
 
 type User struct {
-	ID                uuid.UUID  `json:"id" db:"id"`
-	Username          string     `json:"username" db:"username"`
-	Email             string     `json:"email" db:"email"`
-	PasswordHash      string     `json:"-" db:"password_hash"`
-	Bio               *string    `json:"bio" db:"bio"`
-	AvatarURL         *string    `json:"avatar_url" db:"avatar_url"`
-	IsAdmin           bool       `json:"is_admin" db:"is_admin"`
-	IsModerator       bool       `json:"is_moderator" db:"is_moderator"`
-	ShowNSFW          bool       `json:"show_nsfw" db:"show_nsfw"`
-	IsDisabled        bool       `json:"is_disabled" db:"is_disabled"`
-	NsfwPref          string     `json:"nsfw_pref" db:"nsfw_pref"`
-	EmailVerified     bool       `json:"email_verified" db:"email_verified"`
-	PasswordChangedAt *time.Time `json:"-" db:"password_changed_at"`
-	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	ID                uuid.UUID    `json:"id" db:"id"`
+	Username          string       `json:"username" db:"username"`
+	Email             string       `json:"email" db:"email"`
+	PasswordHash      string       `json:"-" db:"password_hash"`
+	Bio               *string      `json:"bio" db:"bio"`
+	AvatarURL         *string      `json:"avatar_url" db:"avatar_url"`
+	IsAdmin           bool         `json:"is_admin" db:"is_admin"`
+	IsModerator       bool         `json:"is_moderator" db:"is_moderator"`
+	ShowNSFW          bool         `json:"show_nsfw" db:"show_nsfw"`
+	IsDisabled        bool         `json:"is_disabled" db:"is_disabled"`
+	NsfwPref          string       `json:"nsfw_pref" db:"nsfw_pref"`
+	EmailVerified     bool         `json:"email_verified" db:"email_verified"`
+	PasswordChangedAt *time.Time   `json:"-" db:"password_changed_at"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time    `json:"-" db:"updated_at"`
+	NotifyDigest      bool         `json:"notify_digest" db:"notify_digest"`
+	DigestSentAt      *time.Time   `json:"-" db:"digest_sent_at"`
+	IsShadowbanned    bool         `json:"-" db:"is_shadowbanned"`
+	SuspensionReason  string       `json:"-" db:"suspension_reason"`
+	SuspendedUntil    *time.Time   `json:"-" db:"suspended_until"`
+	ProfileTheme      ProfileTheme `json:"-" db:"profile_theme"`
+	StripExif         bool         `json:"strip_exif" db:"strip_exif"`
+	FeedMutes         FeedMutes    `json:"-" db:"feed_mutes"`
+	ContentPrefs      ContentPrefs `json:"-" db:"content_prefs"`
+	// InviteID and InvitedBy record the invite the account registered with and its creator
+	InviteID  *uuid.UUID `json:"-" db:"invite_id"`
+	InvitedBy *uuid.UUID `json:"-" db:"invited_by"`
+	// IsVerified marks a verified creator; VerifiedReason says what was verified
+	IsVerified     bool       `json:"is_verified" db:"is_verified"`
+	VerifiedReason *string    `json:"-" db:"verified_reason"`
+	VerifiedAt     *time.Time `json:"-" db:"verified_at"`
+	// PendingApproval holds a new account until staff approve it, when the site requires that
+	PendingApproval bool `json:"-" db:"pending_approval"`
+	// Revisions of the terms and privacy pages the user last accepted
+	AcceptedTermsRevision   int `json:"-" db:"accepted_terms_revision"`
+	AcceptedPrivacyRevision int `json:"-" db:"accepted_privacy_revision"`
+	// AgeConfirmedAt is when the user confirmed the site's minimum age at registration
+	AgeConfirmedAt *time.Time `json:"-" db:"age_confirmed_at"`
+}
+
+// Suspension explains why an account is disabled. Until is nil for an indefinite suspension.
+type Suspension struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+// ActiveSuspension returns the user's suspension, or nil when the account is enabled or a
+// timed suspension has run out (the expiry job re-enables it shortly after).
+func (u *User) ActiveSuspension() *Suspension {
+	if !u.IsDisabled || (u.SuspendedUntil != nil && !u.SuspendedUntil.After(time.Now())) {
+		return nil
+	}
+	return &Suspension{Reason: u.SuspensionReason, Until: u.SuspendedUntil}
 }
 
 type CreateUserRequest struct {
@@ -42,6 +81,16 @@ type UpdateUserRequest struct {
 	ShowNSFW  *bool   `json:"show_nsfw"`
 	Password  *string `json:"password" validate:"omitempty,min=8"`
 	NsfwPref  *string `json:"nsfw_pref" validate:"omitempty,oneof=hide show blur"`
+	// NotifyDigest opts in to a daily email of unread notifications
+	NotifyDigest *bool `json:"notify_digest"`
+	// ProfileTheme replaces the whole theme; the header image is set by its own upload
+	ProfileTheme *ProfileTheme `json:"profile_theme"`
+	// StripExif removes GPS and device identifiers from this user's re-encoded uploads
+	StripExif *bool `json:"strip_exif"`
+	// FeedMutes replaces the providers kept out of this user's feeds
+	FeedMutes *FeedMutes `json:"feed_mutes"`
+	// ContentPrefs replaces how ratings below explicit are displayed
+	ContentPrefs *ContentPrefs `json:"content_prefs"`
 }
 
 type UserResponse struct {
@@ -54,7 +103,30 @@ type UserResponse struct {
 	ShowNSFW      bool      `json:"show_nsfw"`
 	NsfwPref      string    `json:"nsfw_pref"`
 	EmailVerified bool      `json:"email_verified"`
+	StripExif     bool      `json:"strip_exif"`
 	CreatedAt     time.Time `json:"created_at"`
+	// IsVerified shows the verified badge, with VerifiedReason as its explanation
+	IsVerified     bool    `json:"is_verified"`
+	VerifiedReason *string `json:"verified_reason,omitempty"`
+	// Stats is filled on public profile lookups for the profile header
+	Stats        *UserStatsSummary `json:"stats,omitempty"`
+	ProfileTheme *ProfileTheme     `json:"profile_theme,omitempty"`
+	// BannerURL is the profile banner, also found in profile_theme as header_url
+	BannerURL *string `json:"banner_url,omitempty"`
+	// FeedMutes is only included in the account holder's own responses
+	FeedMutes    *FeedMutes    `json:"feed_mutes,omitempty"`
+	ContentPrefs *ContentPrefs `json:"content_prefs,omitempty"`
+}
+
+// AdminUserResponse adds the account-state flags that only staff may see.
+type AdminUserResponse struct {
+	UserResponse
+	IsDisabled     bool        `json:"is_disabled"`
+	IsShadowbanned bool        `json:"is_shadowbanned"`
+	Suspension     *Suspension `json:"suspension,omitempty"`
+	InvitedBy      *uuid.UUID  `json:"invited_by,omitempty"`
+	// PendingApproval marks a registration waiting in the approval queue
+	PendingApproval bool `json:"pending_approval"`
 }
 
 func (u *User) HashPassword(password string) error {
@@ -72,7 +144,7 @@ func (u *User) CheckPassword(password string) bool {
 }
 
 func (u *User) ToResponse() UserResponse {
-	return UserResponse{
+	r := UserResponse{
 		ID:            u.ID,
 		Username:      u.Username,
 		Bio:           u.Bio,
@@ -82,6 +154,37 @@ func (u *User) ToResponse() UserResponse {
 		ShowNSFW:      u.ShowNSFW,
 		NsfwPref:      u.NsfwPref,
 		EmailVerified: u.EmailVerified,
+		StripExif:     u.StripExif,
 		CreatedAt:     u.CreatedAt,
+		IsVerified:    u.IsVerified,
+		ProfileTheme:  u.profileTheme(),
+	}
+	if u.IsVerified {
+		r.VerifiedReason = u.VerifiedReason
 	}
+	if u.ProfileTheme.HeaderURL != "" {
+		banner := u.ProfileTheme.HeaderURL
+		r.BannerURL = &banner
+	}
+	return r
+}
+
+// ToOwnResponse adds the settings only the account holder sees to ToResponse.
+func (u *User) ToOwnResponse() UserResponse {
+	r := u.ToResponse()
+	mutes, prefs := u.FeedMutes, u.ContentPrefs
+	r.FeedMutes, r.ContentPrefs = &mutes, &prefs
+	return r
+}
+
+func (u *User) profileTheme() *ProfileTheme {
+	if u.ProfileTheme.IsZero() {
+		return nil
+	}
+	t := u.ProfileTheme
+	return &t
+}
+
+func (u *User) ToAdminResponse() AdminUserResponse {
+	return AdminUserResponse{UserResponse: u.ToResponse(), IsDisabled: u.IsDisabled, IsShadowbanned: u.IsShadowbanned, Suspension: u.ActiveSuspension(), InvitedBy: u.InvitedBy, PendingApproval: u.PendingApproval}
 }
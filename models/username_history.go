@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// UsernameRedirectGrace is how long an old handle keeps resolving to the account that
+// gave it up. During this window nobody else can claim it.
+const UsernameRedirectGrace = 90 * 24 * time.Hour
+
+type UsernameChange struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	UserID      uuid.UUID `db:"user_id" json:"user_id"`
+	OldUsername string    `db:"old_username" json:"old_username"`
+	NewUsername string    `db:"new_username" json:"new_username"`
+	ChangedAt   time.Time `db:"changed_at" json:"changed_at"`
+}
+
+type UsernameHistoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewUsernameHistoryRepository(db *sqlx.DB) *UsernameHistoryRepository {
+	return &UsernameHistoryRepository{db: db}
+}
+
+func (r *UsernameHistoryRepository) Record(userID uuid.UUID, oldUsername, newUsername string) error {
+	_, err := r.db.Exec(`INSERT INTO username_history (user_id, old_username, new_username) VALUES ($1, $2, $3)`, userID, oldUsername, newUsername)
+	return err
+}
+
+// ResolveOld returns the account that most recently gave up username after since.
+// It returns sql.ErrNoRows when the handle has no recent owner.
+func (r *UsernameHistoryRepository) ResolveOld(username string, since time.Time) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.Get(&id, `SELECT user_id FROM username_history
+		WHERE old_username = $1 AND changed_at > $2
+		ORDER BY changed_at DESC LIMIT 1`, username, since)
+	return id, err
+}
+
+// ListForUser returns the user's renames, newest first.
+func (r *UsernameHistoryRepository) ListForUser(userID uuid.UUID) ([]UsernameChange, error) {
+	out := []UsernameChange{}
+	err := r.db.Select(&out, `SELECT * FROM username_history WHERE user_id = $1 ORDER BY changed_at DESC`, userID)
+	return out, err
+}
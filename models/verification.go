@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// VerificationProof is a user's pending self-serve verification: Token has to be
+// published at Target, a domain or https link depending on Kind.
+type VerificationProof struct {
+	UserID    uuid.UUID  `db:"user_id" json:"-"`
+	Kind      string     `db:"kind" json:"kind"`
+	Target    string     `db:"target" json:"target"`
+	Token     string     `db:"token" json:"token"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	CheckedAt *time.Time `db:"checked_at" json:"checked_at,omitempty"`
+}
+
+type VerificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewVerificationRepository(db *sqlx.DB) *VerificationRepository {
+	return &VerificationRepository{db: db}
+}
+
+// GetProof returns the user's pending proof, or sql.ErrNoRows.
+func (r *VerificationRepository) GetProof(userID uuid.UUID) (*VerificationProof, error) {
+	var p VerificationProof
+	if err := r.db.Get(&p, `SELECT user_id, kind, target, token, created_at, checked_at FROM verification_proofs WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SaveProof replaces the user's pending proof.
+func (r *VerificationRepository) SaveProof(p *VerificationProof) error {
+	return r.db.QueryRow(`INSERT INTO verification_proofs (user_id, kind, target, token) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET kind = EXCLUDED.kind, target = EXCLUDED.target, token = EXCLUDED.token,
+			created_at = NOW(), checked_at = NULL
+		RETURNING created_at`, p.UserID, p.Kind, p.Target, p.Token).Scan(&p.CreatedAt)
+}
+
+// MarkChecked records a check of the user's proof.
+func (r *VerificationRepository) MarkChecked(userID uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE verification_proofs SET checked_at = NOW() WHERE user_id = $1`, userID)
+	return err
+}
+
+func (r *VerificationRepository) DeleteProof(userID uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM verification_proofs WHERE user_id = $1`, userID)
+	return err
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Delivery statuses mirror the mail outbox: pending -> sending -> delivered, or back to
+// pending after a failure, and dead once retries are exhausted.
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusSending   = "sending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusDead      = "dead"
+)
+
+// Webhook is an admin-configured endpoint. Events is a comma-separated filter; "*" matches
+// every event. The secret is only returned when the webhook is created.
+type Webhook struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	URL         string    `db:"url" json:"url"`
+	Secret      string    `db:"secret" json:"-"`
+	Events      string    `db:"events" json:"events"`
+	Description string    `db:"description" json:"description"`
+	Enabled     bool      `db:"enabled" json:"enabled"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Matches reports whether the webhook subscribes to event.
+func (w *Webhook) Matches(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == event {
+			return true
+		}
+		// "image.*" subscribes to every image event
+		if strings.HasSuffix(e, ".*") && strings.HasPrefix(event, strings.TrimSuffix(e, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one attempt-tracked POST of an event to a webhook.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	WebhookID      uuid.UUID  `db:"webhook_id" json:"webhook_id"`
+	Event          string     `db:"event" json:"event"`
+	Payload        string     `db:"payload" json:"payload"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	ResponseStatus *int       `db:"response_status" json:"response_status"`
+	LastError      *string    `db:"last_error" json:"last_error"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil    *time.Time `db:"locked_until" json:"-"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at"`
+}
+
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(w *Webhook) error {
+	return r.db.QueryRow(`INSERT INTO webhooks (url, secret, events, description, enabled)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		w.URL, w.Secret, w.Events, w.Description, w.Enabled).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *WebhookRepository) Update(w *Webhook) error {
+	return r.db.QueryRow(`UPDATE webhooks SET url = $2, secret = $3, events = $4, description = $5, enabled = $6, updated_at = NOW()
+		WHERE id = $1 RETURNING updated_at`, w.ID, w.URL, w.Secret, w.Events, w.Description, w.Enabled).Scan(&w.UpdatedAt)
+}
+
+func (r *WebhookRepository) Get(id uuid.UUID) (*Webhook, error) {
+	var w Webhook
+	if err := r.db.Get(&w, `SELECT * FROM webhooks WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *WebhookRepository) List() ([]Webhook, error) {
+	out := []Webhook{}
+	err := r.db.Select(&out, `SELECT * FROM webhooks ORDER BY created_at`)
+	return out, err
+}
+
+func (r *WebhookRepository) ListEnabled() ([]Webhook, error) {
+	out := []Webhook{}
+	err := r.db.Select(&out, `SELECT * FROM webhooks WHERE enabled ORDER BY created_at`)
+	return out, err
+}
+
+func (r *WebhookRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+func (r *WebhookRepository) EnqueueDelivery(webhookID uuid.UUID, event, payload string) error {
+	_, err := r.db.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES ($1, $2, $3)`, webhookID, event, payload)
+	return err
+}
+
+// ClaimDue leases up to limit due deliveries; expired leases are reclaimed.
+func (r *WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	var out []WebhookDelivery
+	err := r.db.Select(&out, `UPDATE webhook_deliveries SET status = 'sending', attempts = attempts + 1,
+			locked_until = NOW() + ($2 * INTERVAL '1 second')
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, limit, int(lease.Seconds()))
+	return out, err
+}
+
+func (r *WebhookRepository) MarkDelivered(id uuid.UUID, responseStatus int) error {
+	_, err := r.db.Exec(`UPDATE webhook_deliveries SET status = 'delivered', response_status = $2, delivered_at = NOW(), locked_until = NULL, last_error = NULL WHERE id = $1`, id, responseStatus)
+	return err
+}
+
+// MarkFailed reschedules a delivery at next, or dead-letters it when dead is set.
+// responseStatus is 0 when no response was received.
+func (r *WebhookRepository) MarkFailed(id uuid.UUID, responseStatus int, errMsg string, next time.Time, dead bool) error {
+	status := WebhookStatusPending
+	if dead {
+		status = WebhookStatusDead
+	}
+	var rs *int
+	if responseStatus > 0 {
+		rs = &responseStatus
+	}
+	_, err := r.db.Exec(`UPDATE webhook_deliveries SET status = $2, response_status = $3, last_error = $4, next_attempt_at = $5, locked_until = NULL WHERE id = $1`, id, status, rs, errMsg, next)
+	return err
+}
+
+// ListDeliveries returns deliveries newest first, optionally filtered by webhook and status.
+func (r *WebhookRepository) ListDeliveries(webhookID *uuid.UUID, status string, page, limit int) ([]WebhookDelivery, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM webhook_deliveries WHERE ($1::uuid IS NULL OR webhook_id = $1) AND ($2 = '' OR status = $2)`, webhookID, status); err != nil {
+		return nil, 0, err
+	}
+	out := []WebhookDelivery{}
+	err := r.db.Select(&out, `SELECT * FROM webhook_deliveries WHERE ($1::uuid IS NULL OR webhook_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC LIMIT $3 OFFSET $4`, webhookID, status, limit, offset)
+	return out, total, err
+}
+
+// RetryDelivery requeues a dead or pending delivery for immediate sending.
+func (r *WebhookRepository) RetryDelivery(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL WHERE id = $1 AND status IN ('pending', 'dead')`, id)
+	return err
+}
+
+// PurgeDeliveries deletes delivered and dead deliveries older than the cutoff.
+func (r *WebhookRepository) PurgeDeliveries(before time.Time) (int, error) {
+	res, err := r.db.Exec(`DELETE FROM webhook_deliveries WHERE status IN ('delivered', 'dead') AND created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgeGateCookie holds a signed-out visitor's age confirmation for adult sites.
+const AgeGateCookie = "age_confirmed"
+
+// AgeGateTTL is how long an age confirmation lasts before the visitor is asked again.
+const AgeGateTTL = 30 * 24 * time.Hour
+
+// AdultSiteMinimumAge is the least minimum age an adult site can be configured with.
+const AdultSiteMinimumAge = 18
+
+// SignAgeGate returns the cookie value confirming minAge until expires. The signature
+// key derives from JWT_SECRET, and the age is signed too, so raising the minimum age
+// asks everyone again.
+func SignAgeGate(minAge int, expires time.Time) string {
+	payload := strconv.Itoa(minAge) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + ageGateMAC(payload)
+}
+
+// VerifyAgeGate reports whether value is an unexpired confirmation of at least minAge.
+func VerifyAgeGate(value string, minAge int, now time.Time) bool {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return false
+	}
+	payload, mac := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(ageGateMAC(payload))) {
+		return false
+	}
+	age, exp, ok := strings.Cut(payload, ".")
+	if !ok {
+		return false
+	}
+	a, err1 := strconv.Atoi(age)
+	e, err2 := strconv.ParseInt(exp, 10, 64)
+	return err1 == nil && err2 == nil && a >= minAge && now.Unix() < e
+}
+
+func ageGateMAC(payload string) string {
+	mac := hmac.New(sha256.New, []byte("trough-age:"+os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgeGateCookie(t *testing.T) {
+	t.Setenv("JWT_SECRET", "age-gate-test-secret")
+	now := time.Now()
+	v := SignAgeGate(18, now.Add(time.Hour))
+	if !VerifyAgeGate(v, 18, now) {
+		t.Fatal("expected a fresh confirmation to verify")
+	}
+	if VerifyAgeGate(v, 21, now) {
+		t.Fatal("expected a higher minimum age to ask again")
+	}
+	if VerifyAgeGate(v, 18, now.Add(2*time.Hour)) {
+		t.Fatal("expected an expired confirmation to fail")
+	}
+	if VerifyAgeGate("21"+v[2:], 18, now) || VerifyAgeGate("", 18, now) {
+		t.Fatal("expected tampered values to fail")
+	}
+	t.Setenv("JWT_SECRET", "another-secret")
+	if VerifyAgeGate(v, 18, now) {
+		t.Fatal("expected a new secret to invalidate confirmations")
+	}
+}
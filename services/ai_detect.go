@@ -2,10 +2,11 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 	"sync"
@@ -15,24 +16,8 @@ import (
 	"github.com/dsoprea/go-exif/v3"
 )
 
-// Buffer pool for memory optimization
-var bufferPool = sync.Pool{
-	New: func() interface{} {
-		return make([]byte, 0, 1024*1024) // 1MB initial buffer
-	},
-}
-
-// getBuffer gets a buffer from the pool
-func getBuffer() []byte {
-	return bufferPool.Get().([]byte)
-}
-
-// putBuffer returns a buffer to the pool
-func putBuffer(buf []byte) {
-	if cap(buf) <= 2*1024*1024 { // Don't pool buffers larger than 2MB
-		bufferPool.Put(buf[:0])
-	}
-}
+// aiLog is the logger for detection, which runs outside any request context.
+func aiLog() *slog.Logger { return Logger(context.Background()) }
 
 // AIDetectionResult describes detected AI provenance for an image.
 type AIDetectionResult struct {
@@ -83,6 +68,30 @@ var (
 	// More prompt variations
 	promptVariations = []string{"prompt", "prompts", "positive_prompt", "negative_prompt", "text_prompt", "input_prompt", "ai_prompt", "generation_prompt"}
 
+	// Grok writes its prompt fields as plain text
+	grokMarkers = []string{"grok image prompt", "grok image upsampled prompt", "\x00grok\x00", " g r o k ", "grok:", "\"grok\""}
+
+	// Technical terms that make a prompt count as AI parameters
+	promptTechTerms = []string{"sampler", "steps", "cfg", "seed", "checkpoint", "lora", "vae", "embeddings"}
+
+	// binaryTextMarkers is everything detectFromBinaryTextBytes looks for, matched in one pass
+	binaryTextMarkers = newMarkerSet(grokMarkers, comfyuiPatterns, sdxlTerms, promptVariations, promptTechTerms, []string{"prompt", "workflow", "flux"})
+
+	// Markers specific enough to AI generation for DetectAIFast to accept on their own
+	fastAIMarkers = []string{
+		"sui_image_params",  // Very specific to AI generation
+		"textual_inversion", // AI-specific term
+		"stable diffusion",  // Full phrase less likely to appear accidentally
+		"midjourney",        // Full phrase
+		"dall-e",            // Full phrase
+		"negative_prompt",   // AI-specific term
+		"positive_prompt",   // AI-specific term
+		// Midjourney parameters (very specific to AI generation)
+		"--chaos", "--ar", "--profile", "--stylize", "--weird", "--v ", "--no ", "--seed",
+	}
+	midjourneyParams = []string{"--chaos", "--ar", "--profile", "--stylize", "--weird", "--v ", "--no ", "--seed", "Job ID:"}
+	fastMarkers      = newMarkerSet(fastAIMarkers, midjourneyParams)
+
 	// Generic AI terms - REMOVED to prevent false positives
 	// These terms were too generic and caused non-AI images to be accepted
 	// genericAITerms = []string{"ai_art", "ai_generated", "ai_artwork", "machine_learning", "neural_network", "gan", "generative", "synthetic", "computer_vision", "deep_learning", "text_to_image", "artificial", "generator", "synthetic"}
@@ -125,7 +134,7 @@ func DetectAIProvenanceFromBytes(imageBytes []byte, xmpXML []byte) (ok bool, res
 	// 1) Heuristic presence of C2PA JUMBF/labels in file body
 	c2paMatch := c2paSniffRegex.Find(imageBytes)
 	if c2paMatch != nil {
-		log.Printf("AI Detection Debug: C2PA pattern found: %s", string(c2paMatch))
+		aiLog().Debug("ai detection: C2PA pattern found", "match", string(c2paMatch))
 		provider := classifyC2PAProvider(xmpXML)
 		if provider == "" {
 			provider = "Unknown C2PA"
@@ -136,7 +145,7 @@ func DetectAIProvenanceFromBytes(imageBytes []byte, xmpXML []byte) (ok bool, res
 	// Enhanced C2PA detection for binary JUMBF chunks
 	// C2PA manifests are stored in PNG chunks as binary data
 	if bytes.Contains(imageBytes, []byte("jumb")) && bytes.Contains(imageBytes, []byte("c2pa")) {
-		log.Printf("AI Detection Debug: C2PA JUMBF binary chunks detected")
+		aiLog().Debug("ai detection: C2PA JUMBF binary chunks detected")
 		provider := classifyC2PAProvider(xmpXML)
 		if provider == "" {
 			provider = "Unknown C2PA"
@@ -146,7 +155,7 @@ func DetectAIProvenanceFromBytes(imageBytes []byte, xmpXML []byte) (ok bool, res
 
 	// Check for C2PA URN pattern (binary)
 	if bytes.Contains(imageBytes, []byte("urn:c2pa:")) {
-		log.Printf("AI Detection Debug: C2PA URN pattern detected")
+		aiLog().Debug("ai detection: C2PA URN pattern detected")
 		provider := classifyC2PAProvider(xmpXML)
 		if provider == "" {
 			provider = "Unknown C2PA"
@@ -159,7 +168,7 @@ func DetectAIProvenanceFromBytes(imageBytes []byte, xmpXML []byte) (ok bool, res
 		// Look for "c2pa" manually in the first few KB
 		preview := string(imageBytes[:min(4096, len(imageBytes))])
 		if strings.Contains(strings.ToLower(preview), "c2pa") {
-			log.Printf("AI Detection Debug: C2PA found manually in preview but not by regex")
+			aiLog().Debug("ai detection: C2PA found manually in preview but not by regex")
 		}
 	}
 	// 2) EXIF
@@ -240,17 +249,19 @@ func detectFromEXIFBytes(b []byte) (bool, AIDetectionResult) {
 }
 
 func detectFromBinaryTextBytes(b []byte) (bool, AIDetectionResult) {
-	s := strings.ToLower(string(b))
-	if strings.Contains(s, "grok image prompt") || strings.Contains(s, "grok image upsampled prompt") || strings.Contains(s, "\x00grok\x00") || strings.Contains(s, " g r o k ") || strings.Contains(s, "grok:") || strings.Contains(s, "\"grok\"") {
+	s := binaryTextMarkers.scan()
+	_, _ = s.Write(b)
+	if s.FoundAny(grokMarkers) {
 		return true, AIDetectionResult{Provider: "Grok", Method: "binary", Details: "Grok prompt fields in image"}
 	}
-	if containsAnyFold(s, comfyuiPatterns) || (strings.Contains(s, "prompt") && strings.Contains(s, "workflow")) {
+	if s.FoundAny(comfyuiPatterns) || (s.Found("prompt") && s.Found("workflow")) {
 		return true, AIDetectionResult{Provider: "ComfyUI", Method: "binary", Details: "ComfyUI markers present"}
 	}
-	if containsAnyFold(s, sdxlTerms) {
+	if s.FoundAny(sdxlTerms) {
 		return true, AIDetectionResult{Provider: "Stable Diffusion (SDXL)", Method: "binary", Details: "SDXL/SD params present"}
 	}
-	if aiSoftwareRegex.MatchString(s) && strings.Contains(strings.ToLower(s), "flux") {
+	// aiSoftwareRegex matches whenever "flux" is present, so the marker alone decides
+	if s.Found("flux") {
 		return true, AIDetectionResult{Provider: "FLUX", Method: "binary", Details: "Flux markers present"}
 	}
 	// Generic AI terms detection - DISABLED to prevent false positives
@@ -259,9 +270,9 @@ func detectFromBinaryTextBytes(b []byte) (bool, AIDetectionResult) {
 	// }
 
 	// Enhanced prompt detection - requires additional context to avoid false positives
-	if containsAnyFold(s, promptVariations) {
+	if s.FoundAny(promptVariations) {
 		// Only accept as AI if there are ALSO technical AI terms present
-		if containsAnyFold(s, sdxlTerms) || containsAnyFold(s, comfyuiPatterns) || containsAnyFold(s, []string{"sampler", "steps", "cfg", "seed", "checkpoint", "lora", "vae", "embeddings"}) {
+		if s.FoundAny(sdxlTerms) || s.FoundAny(comfyuiPatterns) || s.FoundAny(promptTechTerms) {
 			return true, AIDetectionResult{Provider: "AI (Prompt + Technical Terms)", Method: "binary", Details: "Prompt with technical AI parameters present"}
 		}
 	}
@@ -299,7 +310,7 @@ func classifyC2PAProvider(xmp []byte) string {
 func detectFromEXIF(imagePath string) (bool, AIDetectionResult) {
 	rawExif, err := exif.SearchFileAndExtractExif(imagePath)
 	if err != nil {
-		log.Printf("AI Detection: EXIF extraction failed for %s: %v", imagePath, err)
+		aiLog().Info("ai detection: EXIF extraction failed", "path", imagePath, "error", err)
 		return false, AIDetectionResult{}
 	}
 
@@ -310,18 +321,18 @@ func detectFromEXIF(imagePath string) (bool, AIDetectionResult) {
 		bytes.Contains(rawExif, buildUTF16BEPattern("sui_image_params")) ||
 		bytes.Contains(rawExif, buildUTF16LEPattern("prompt")) ||
 		bytes.Contains(rawExif, buildUTF16BEPattern("prompt")) {
-		log.Printf("AI Detection: Found SDXL markers in raw EXIF data")
+		aiLog().Debug("ai detection: found SDXL markers in raw EXIF data")
 		return true, AIDetectionResult{Provider: "Stable Diffusion (SDXL)", Method: "exif", Details: "sui_image_params/prompt in raw EXIF"}
 	}
 
 	entries, _, err := exif.GetFlatExifData(rawExif, nil)
 	if err != nil {
-		log.Printf("AI Detection: EXIF parsing failed for %s: %v", imagePath, err)
+		aiLog().Info("ai detection: EXIF parsing failed", "path", imagePath, "error", err)
 		return false, AIDetectionResult{}
 	}
 
 	// Avoid verbose logging of user-provided metadata to reduce leakage/noise
-	log.Printf("AI Detection: EXIF parsed for %s", imagePath)
+	aiLog().Debug("ai detection: EXIF parsed", "path", imagePath)
 	var softwareVal string
 	for _, e := range entries {
 		tn := strings.TrimSpace(e.TagName)
@@ -330,7 +341,7 @@ func detectFromEXIF(imagePath string) (bool, AIDetectionResult) {
 		// Log UserComment specifically since that's where SDXL params often are
 		if strings.EqualFold(tn, "UserComment") {
 			// Avoid logging raw user comment content
-			log.Printf("AI Detection: UserComment present (formatted)")
+			aiLog().Debug("ai detection: UserComment present (formatted)")
 
 			// Try to get raw value for UserComment since formatted might not work
 			if e.Value != nil {
@@ -347,7 +358,7 @@ func detectFromEXIF(imagePath string) (bool, AIDetectionResult) {
 				}
 				if len(rawStr) > 0 && rawStr != val {
 					// Avoid logging raw user comment content
-					log.Printf("AI Detection: UserComment raw present")
+					aiLog().Debug("ai detection: UserComment raw present")
 					val = rawStr // Use raw value instead of formatted
 				}
 			}
@@ -543,7 +554,7 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 	s := strings.ToLower(string(b))
 
 	// DEBUG: Log file type and size
-	log.Printf("AI Detection Debug: Binary text detection - file size: %d bytes", len(b))
+	aiLog().Debug("ai detection: binary text detection", "bytes", len(b))
 
 	// FIXED: Skip binary JPEG headers to avoid false positives (first ~1000 bytes)
 	scanStart := 1000
@@ -560,21 +571,18 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 			sig6 := b[6]
 			sig7 := b[7]
 
-			log.Printf("AI Detection Debug: Binary text PNG signature check - bytes: %x %x %x %x %x %x %x %x", sig0, sig1, sig2, sig3, sig4, sig5, sig6, sig7)
-			log.Printf("AI Detection Debug: Expected PNG signature: %x %x %x %x %x %x %x %x", 0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A)
-
 			isPNG = sig0 == 0x89 && sig1 == 0x50 && sig2 == 0x4E && sig3 == 0x47 &&
 				sig4 == 0x0D && sig5 == 0x0A && sig6 == 0x1A && sig7 == 0x0A
 
-			log.Printf("AI Detection Debug: Binary text PNG signature detected: %v", isPNG)
+			aiLog().Debug("ai detection: binary text PNG signature checked", "png", isPNG, "signature", fmt.Sprintf("%x", b[:8]))
 		}
 
 		if isPNG {
-			log.Printf("AI Detection Debug: Binary detection - Detected PNG file via binary signature, scanning for text chunks")
+			aiLog().Debug("ai detection: PNG signature found, scanning text chunks")
 			// For PNG files, scan the entire file but skip just the signature
 			scanStart = 8 // Skip PNG signature (8 bytes)
 		} else {
-			log.Printf("AI Detection Debug: Binary detection - Detected non-PNG file, skipping first %d bytes", scanStart)
+			aiLog().Debug("ai detection: non-PNG file, skipping binary headers", "skip", scanStart)
 		}
 		s = s[scanStart:]
 	}
@@ -583,7 +591,7 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 	midjourneyParams := []string{"--chaos", "--ar", "--profile", "--stylize", "--weird", "--v ", "--no ", "--seed", "Job ID:"}
 	for _, param := range midjourneyParams {
 		if strings.Contains(s, param) {
-			log.Printf("AI Detection Debug: Found Midjourney parameter in binary detection: %s", param)
+			aiLog().Debug("ai detection: found Midjourney parameter in binary", "param", param)
 		}
 	}
 
@@ -592,7 +600,7 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 
 	// 1. Look for specific AI generation parameters
 	if strings.Contains(s, "sui_image_params") {
-		log.Printf("AI Detection Debug: Found sui_image_params in binary")
+		aiLog().Debug("ai detection: found sui_image_params in binary")
 		return true, AIDetectionResult{Provider: "Stable Diffusion (SDXL)", Method: "binary", Details: "sui_image_params found"}
 	}
 
@@ -610,7 +618,7 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 
 	for _, phrase := range aiPhrases {
 		if strings.Contains(s, phrase) {
-			log.Printf("AI Detection Debug: Found AI phrase in binary: %s", phrase)
+			aiLog().Debug("ai detection: found AI phrase in binary", "phrase", phrase)
 			return true, AIDetectionResult{Provider: "AI (Binary Phrase)", Method: "binary", Details: "AI phrase: " + phrase}
 		}
 	}
@@ -619,7 +627,7 @@ func detectFromBinaryText(imagePath string) (bool, AIDetectionResult) {
 	utf16Needles := []string{"sui_image_params", "textual_inversion", "checkpoint", "lora", "vae", "embeddings"}
 	for _, n := range utf16Needles {
 		if bytes.Contains(b, buildUTF16LEPattern(n)) || bytes.Contains(b, buildUTF16BEPattern(n)) {
-			log.Printf("AI Detection Debug: Found UTF-16 AI parameter: %s", n)
+			aiLog().Debug("ai detection: found UTF-16 AI parameter", "param", n)
 			return true, AIDetectionResult{Provider: "Stable Diffusion (SDXL)", Method: "binary", Details: "UTF-16 AI param: " + n}
 		}
 	}
@@ -710,21 +718,15 @@ func decodeUTF16(data []byte) (string, error) {
 	return string(runes), nil
 }
 
-// DetectAIFast performs quick AI detection using pre-compiled regex patterns
+// DetectAIFast performs quick AI detection with one pass of a precompiled marker automaton
 // FIXED: Now only scans text-based metadata, not entire binary file
 func DetectAIFast(imageBytes []byte) (bool, AIDetectionResult) {
-	// Use buffer pool for string conversion to avoid allocations
-	buf := getBuffer()
-	defer putBuffer(buf)
-
-	buf = append(buf, imageBytes...)
-
 	// Early exit for very small files (unlikely to be AI)
 	if len(imageBytes) < 1024 {
 		return false, AIDetectionResult{}
 	}
 
-	// Check PNG signature BEFORE string conversion (string conversion corrupts binary signature)
+	// Check PNG signature
 	isPNG := false
 	if len(imageBytes) >= 8 {
 		sig0 := imageBytes[0]
@@ -736,64 +738,41 @@ func DetectAIFast(imageBytes []byte) (bool, AIDetectionResult) {
 		sig6 := imageBytes[6]
 		sig7 := imageBytes[7]
 
-		log.Printf("AI Detection Debug: PNG signature check - bytes: %x %x %x %x %x %x %x %x", sig0, sig1, sig2, sig3, sig4, sig5, sig6, sig7)
-		log.Printf("AI Detection Debug: Expected PNG signature: %x %x %x %x %x %x %x %x", 0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A)
-
 		isPNG = sig0 == 0x89 && sig1 == 0x50 && sig2 == 0x4E && sig3 == 0x47 &&
 			sig4 == 0x0D && sig5 == 0x0A && sig6 == 0x1A && sig7 == 0x0A
 
-		log.Printf("AI Detection Debug: PNG signature detected: %v", isPNG)
+		aiLog().Debug("ai detection: PNG signature checked", "png", isPNG, "signature", fmt.Sprintf("%x", imageBytes[:8]))
 	}
 
-	content := strings.ToLower(string(buf))
-
 	// DEBUG: Log the first 200 chars to see what we're matching
-	if len(content) > 0 {
-		log.Printf("AI Detection Debug: Fast detection scanning content (first 200 chars): %s", content[:min(200, len(content))])
-	}
-
-	// DEBUG: Check for Midjourney parameters specifically
-	midjourneyParams := []string{"--chaos", "--ar", "--profile", "--stylize", "--weird", "--v ", "--no ", "--seed", "Job ID:"}
-	for _, param := range midjourneyParams {
-		if strings.Contains(content, param) {
-			log.Printf("AI Detection Debug: Found Midjourney parameter in fast detection: %s", param)
-		}
-	}
+	aiLog().Debug("ai detection: fast detection scanning content", "prefix", strings.ToLower(string(imageBytes[:200])))
 
 	// FIXED: Skip binary JPEG headers and ICC profiles to avoid false positives
 	// But for PNG files, we need to check text chunks which might be in the first part
 	scanStart := 1000
-	if len(imageBytes) > scanStart {
-		// Use the pre-computed isPNG variable from above
-		if isPNG {
-			log.Printf("AI Detection Debug: Detected PNG file via binary signature, checking text chunks in full content")
-			// For PNG files, scan the entire file but skip just the signature
-			scanStart = 8 // Skip PNG signature (8 bytes)
-		} else {
-			log.Printf("AI Detection Debug: Detected non-PNG file, skipping first %d bytes (binary headers)", scanStart)
+	if isPNG {
+		aiLog().Debug("ai detection: PNG signature found, checking text chunks in full content")
+		// For PNG files, scan the entire file but skip just the signature
+		scanStart = 8 // Skip PNG signature (8 bytes)
+	} else {
+		aiLog().Debug("ai detection: non-PNG file, skipping binary headers", "skip", scanStart)
+	}
+	// One case-insensitive pass finds every marker, without a lowercased copy of the file
+	found := fastMarkers.scan()
+	_, _ = found.Write(imageBytes[scanStart:])
+
+	// DEBUG: Check for Midjourney parameters specifically
+	for _, param := range midjourneyParams {
+		if found.Found(param) {
+			aiLog().Debug("ai detection: found Midjourney parameter in fast detection", "param", param)
 		}
-		content = content[scanStart:]
 	}
 
 	// FIXED: Be much more restrictive - only scan for very specific AI markers
 	// Don't scan for generic software patterns that can appear in binary data
-
-	// Check for very specific AI markers that wouldn't appear in regular JPEGs
-	specificMarkers := []string{
-		"sui_image_params",  // Very specific to AI generation
-		"textual_inversion", // AI-specific term
-		"stable diffusion",  // Full phrase less likely to appear accidentally
-		"midjourney",        // Full phrase
-		"dall-e",            // Full phrase
-		"negative_prompt",   // AI-specific term
-		"positive_prompt",   // AI-specific term
-		// Midjourney parameters (very specific to AI generation)
-		"--chaos", "--ar", "--profile", "--stylize", "--weird", "--v ", "--no ", "--seed",
-	}
-
-	for _, marker := range specificMarkers {
-		if strings.Contains(content, marker) {
-			log.Printf("AI Detection Debug: Specific marker matched! Marker: %s", marker)
+	for _, marker := range fastAIMarkers {
+		if found.Found(marker) {
+			aiLog().Debug("ai detection: specific marker matched", "marker", marker)
 			return true, AIDetectionResult{
 				Provider: "AI (Specific Marker)",
 				Method:   "binary",
@@ -925,7 +904,7 @@ func DetectAIProvenanceConcurrent(imageBytes []byte, xmpXML []byte) (bool, AIDet
 		return false, AIDetectionResult{}
 	case <-timeout:
 		// Timeout reached, assume no AI to prevent hanging
-		log.Printf("AI Detection: Concurrent detection timed out after 5 seconds")
+		aiLog().Warn("ai detection: concurrent detection timed out", "after", "5s")
 		return false, AIDetectionResult{}
 	}
 
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+// aiRedetectMaxBytes bounds how much of a stored file is read for re-detection; uploads
+// are far smaller, so anything larger is not an image this instance stored.
+const aiRedetectMaxBytes = 64 << 20
+
+// AIRedetectOptions is the payload of an ai.redetect job and selects the images to scan.
+type AIRedetectOptions struct {
+	// Since and Until bound the images' upload time; either may be nil.
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	// MissingProvider only scans images whose provider was never identified.
+	MissingProvider bool `json:"missing_provider,omitempty"`
+}
+
+// AIRedetectResult is the progress and final result of an ai.redetect job. Undetected
+// counts images on which the current pipeline finds nothing; they keep their values.
+type AIRedetectResult struct {
+	Options    AIRedetectOptions `json:"options"`
+	Total      int               `json:"total"`
+	Processed  int               `json:"processed"`
+	Changed    int               `json:"changed"`
+	Unchanged  int               `json:"unchanged"`
+	Undetected int               `json:"undetected"`
+	Skipped    int               `json:"skipped"`
+	Failed     int               `json:"failed"`
+	Providers  map[string]int    `json:"providers"`
+	Errors     []string          `json:"errors"`
+}
+
+func (r *AIRedetectResult) addError(msg string) {
+	r.Failed++
+	if len(r.Errors) < backfillErrorCap {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+type redetectRow struct {
+	ID          uuid.UUID `db:"id"`
+	Filename    string    `db:"filename"`
+	MediaType   string    `db:"media_type"`
+	AIProvider  *string   `db:"ai_provider"`
+	AISignature *string   `db:"ai_signature"`
+}
+
+// where builds the filter for o, numbering placeholders from $1.
+func (o AIRedetectOptions) where() (string, []interface{}) {
+	conds := []string{"media_type <> '" + models.MediaTypeVideo + "'"}
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if o.Since != nil {
+		add("created_at >= ?", *o.Since)
+	}
+	if o.Until != nil {
+		add("created_at < ?", *o.Until)
+	}
+	if o.MissingProvider {
+		conds = append(conds, "(ai_provider IS NULL OR ai_provider = '')")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// DetectStoredAIProvenance runs the upload detection pipeline over a stored file:
+// animations are judged on their metadata blocks, everything else on the fast scanners
+// and then the full concurrent pass.
+func DetectStoredAIProvenance(b []byte) (bool, AIDetectionResult) {
+	if _, ok := InspectAnimation(b); ok {
+		meta := AnimationMetadata(b)
+		return DetectAIProvenanceConcurrent(meta, ExtractXMPXMLFromBytes(meta))
+	}
+	if ok, res := DetectAIFast(b); ok {
+		return true, res
+	}
+	return DetectAIProvenanceConcurrent(b, ExtractXMPXMLFromBytes(b))
+}
+
+// RedetectAIProvenance re-runs AI detection over the stored files of the images o selects,
+// so detector improvements reach images uploaded before them. A detection replaces the
+// image's signature, and its provider when one is named; images where nothing is found
+// keep what they have, since stored copies can carry less metadata than the upload did.
+func RedetectAIProvenance(ctx context.Context, db *sqlx.DB, st Storage, o AIRedetectOptions) (*AIRedetectResult, error) {
+	if db == nil || st == nil {
+		return nil, fmt.Errorf("storage or database not configured")
+	}
+	reader, ok := st.(ObjectReader)
+	if !ok {
+		return nil, errors.New("storage does not support reading objects")
+	}
+	where, args := o.where()
+	result := &AIRedetectResult{Options: o, Providers: map[string]int{}, Errors: []string{}}
+	if err := db.GetContext(ctx, &result.Total, `SELECT COUNT(*) FROM images WHERE `+where, args...); err != nil {
+		return nil, err
+	}
+	ReportJobProgress(ctx, result)
+
+	var (
+		after      uuid.UUID
+		lastReport time.Time
+	)
+	n := len(args)
+	query := fmt.Sprintf(`SELECT id, filename, media_type, ai_provider, ai_signature FROM images
+		WHERE %s AND id > $%d ORDER BY id LIMIT $%d`, where, n+1, n+2)
+	for {
+		var rows []redetectRow
+		if err := db.SelectContext(ctx, &rows, query, append(args, after, backfillBatch)...); err != nil {
+			return result, err
+		}
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			after = row.ID
+			result.Processed++
+			if err := redetectOne(ctx, db, reader, row, result); err != nil {
+				result.addError(fmt.Sprintf("%s: %v", row.ID, err))
+			}
+			if time.Since(lastReport) >= time.Second {
+				lastReport = time.Now()
+				ReportJobProgress(ctx, result)
+			}
+		}
+		if len(rows) < backfillBatch {
+			break
+		}
+	}
+	if result.Changed > 0 {
+		InvalidateFeedCache(ctx)
+	}
+	return result, nil
+}
+
+func redetectOne(ctx context.Context, db *sqlx.DB, reader ObjectReader, row redetectRow, result *AIRedetectResult) error {
+	key := StorageKeyFromRef(row.Filename)
+	if key == "" {
+		result.Skipped++
+		return nil
+	}
+	rc, err := reader.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(io.LimitReader(rc, aiRedetectMaxBytes+1))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if len(b) > aiRedetectMaxBytes {
+		result.Skipped++
+		return nil
+	}
+	ok, res := DetectStoredAIProvenance(b)
+	if !ok {
+		result.Undetected++
+		return nil
+	}
+	provider := res.Provider
+	if provider == "" && row.AIProvider != nil {
+		provider = *row.AIProvider
+	}
+	if provider != "" {
+		result.Providers[provider]++
+	}
+	if provider == derefString(row.AIProvider) && res.Details == derefString(row.AISignature) {
+		result.Unchanged++
+		return nil
+	}
+	signature, _ := json.Marshal(res.Details)
+	if _, err := db.ExecContext(ctx, `UPDATE images SET ai_provider = NULLIF($2, ''), ai_signature = $3,
+		exif_data = CASE WHEN jsonb_typeof(exif_data) = 'object' THEN exif_data || jsonb_build_object('ai_detected', true, 'signature', $4::jsonb) ELSE exif_data END
+		WHERE id = $1`, row.ID, provider, res.Details, string(signature)); err != nil {
+		return err
+	}
+	result.Changed++
+	return nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// RegisterAIRedetectJob registers admin-triggered AI re-detection. It reads from the
+// storage in use when the job runs.
+func RegisterAIRedetectJob(db *sqlx.DB) {
+	RegisterJob(JobSpec{
+		Kind:        JobAIRedetect,
+		Timeout:     6 * time.Hour,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var o AIRedetectOptions
+			if err := json.Unmarshal(job.Payload, &o); err != nil {
+				return nil, PermanentJobError(err)
+			}
+			st := GetCurrentStorage()
+			if st == nil {
+				return nil, PermanentJobError(errors.New("storage not configured"))
+			}
+			if _, ok := st.(ObjectReader); !ok {
+				return nil, PermanentJobError(errors.New("storage does not support reading objects"))
+			}
+			return RedetectAIProvenance(ctx, db, st, o)
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAIRedetectOptionsWhere(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 1, 0)
+	where, args := AIRedetectOptions{Since: &since, Until: &until, MissingProvider: true}.where()
+	want := "media_type <> 'video' AND created_at >= $1 AND created_at < $2 AND (ai_provider IS NULL OR ai_provider = '')"
+	if where != want {
+		t.Fatalf("where = %q, want %q", where, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{since, until}) {
+		t.Fatalf("unexpected args %v", args)
+	}
+	where, args = AIRedetectOptions{}.where()
+	if where != "media_type <> 'video'" || len(args) != 0 {
+		t.Fatalf("unexpected unfiltered clause %q %v", where, args)
+	}
+}
+
+func TestDetectStoredAIProvenance(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if ok, res := DetectStoredAIProvenance(buf.Bytes()); ok {
+		t.Fatalf("plain PNG detected as %+v", res)
+	}
+	tagged := append(buf.Bytes(), []byte(`tEXtparameters{"prompt": "a cat", "sampler": "euler", "steps": 20, "cfg": 7}`)...)
+	if ok, res := DetectStoredAIProvenance(tagged); !ok {
+		t.Fatalf("expected generation parameters to be detected, got %+v", res)
+	}
+}
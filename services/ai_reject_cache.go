@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AIRejectCacheStats reports how often re-uploads of rejected files were refused from the
+// cache since startup.
+type AIRejectCacheStats struct {
+	Enabled    bool   `json:"enabled"`
+	Backend    string `json:"backend"`
+	TTLSeconds int    `json:"ttl_seconds"`
+	Hits       int64  `json:"hits"`
+	Misses     int64  `json:"misses"`
+	Stored     int64  `json:"stored"`
+	Flushes    int64  `json:"flushes"`
+}
+
+// aiRejectCache remembers the SHA-256 of uploads AI detection rejected, so sending the same
+// file again is refused without running the EXIF, XMP and binary scans. Only rejections
+// are kept: an accepted file still goes through detection for its signature and provider.
+// Entries are keyed by generation like the feed cache, so a flush is a counter bump.
+var aiRejectCache struct {
+	mu      sync.RWMutex
+	store   ResponseCacheStore
+	backend string
+	ttl     time.Duration
+	hits    atomic.Int64
+	misses  atomic.Int64
+	stored  atomic.Int64
+	flushes atomic.Int64
+}
+
+// ConfigureAIRejectCache enables the rejection cache with entries kept for ttl. A nil
+// store disables it.
+func ConfigureAIRejectCache(store ResponseCacheStore, backend string, ttl time.Duration) {
+	aiRejectCache.mu.Lock()
+	defer aiRejectCache.mu.Unlock()
+	aiRejectCache.store = store
+	aiRejectCache.backend = backend
+	aiRejectCache.ttl = ttl
+}
+
+func aiRejectStore() (ResponseCacheStore, time.Duration) {
+	aiRejectCache.mu.RLock()
+	defer aiRejectCache.mu.RUnlock()
+	return aiRejectCache.store, aiRejectCache.ttl
+}
+
+// AIRejectSum returns the hex SHA-256 the rejection cache keys r's content by, and rewinds
+// r. It returns "" without reading when the cache is disabled or r can't be read.
+func AIRejectSum(r io.ReadSeeker) string {
+	if store, _ := aiRejectStore(); store == nil {
+		return ""
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	sum, err := ChecksumSHA256Reader(r)
+	if _, serr := r.Seek(0, io.SeekStart); err != nil || serr != nil {
+		return ""
+	}
+	return sum
+}
+
+// AIRejected reports whether a file with this SHA-256 was rejected recently.
+func AIRejected(ctx context.Context, sum string) bool {
+	store, _ := aiRejectStore()
+	if store == nil || sum == "" {
+		return false
+	}
+	_, ok := store.Get(ctx, feedCacheKey(ctx, store, sum))
+	if ok {
+		aiRejectCache.hits.Add(1)
+	} else {
+		aiRejectCache.misses.Add(1)
+	}
+	return ok
+}
+
+// RememberAIReject records that the file with this SHA-256 was rejected.
+func RememberAIReject(ctx context.Context, sum string) {
+	store, ttl := aiRejectStore()
+	if store == nil || sum == "" {
+		return
+	}
+	store.Set(ctx, feedCacheKey(ctx, store, sum), []byte{1}, ttl)
+	aiRejectCache.stored.Add(1)
+}
+
+// FlushAIRejectCache forgets every remembered rejection, e.g. after detection rules
+// change and files it refused might now pass.
+func FlushAIRejectCache(ctx context.Context) {
+	store, _ := aiRejectStore()
+	if store == nil {
+		return
+	}
+	store.Bump(ctx)
+	aiRejectCache.flushes.Add(1)
+}
+
+// GetAIRejectCacheStats returns the rejection cache's configuration and counters.
+func GetAIRejectCacheStats() AIRejectCacheStats {
+	aiRejectCache.mu.RLock()
+	defer aiRejectCache.mu.RUnlock()
+	return AIRejectCacheStats{
+		Enabled:    aiRejectCache.store != nil,
+		Backend:    aiRejectCache.backend,
+		TTLSeconds: int(aiRejectCache.ttl / time.Second),
+		Hits:       aiRejectCache.hits.Load(),
+		Misses:     aiRejectCache.misses.Load(),
+		Stored:     aiRejectCache.stored.Load(),
+		Flushes:    aiRejectCache.flushes.Load(),
+	}
+}
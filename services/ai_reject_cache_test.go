@@ -0,0 +1,43 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAIRejectCache(t *testing.T) {
+	ctx := context.Background()
+	file := bytes.NewReader([]byte("a photo with no provenance"))
+	if sum := AIRejectSum(file); sum != "" {
+		t.Fatalf("expected no hashing while the cache is disabled, got %q", sum)
+	}
+
+	ConfigureAIRejectCache(NewMemoryCacheStore(10), "memory", time.Minute)
+	defer ConfigureAIRejectCache(nil, "", 0)
+	_, _ = file.Seek(5, io.SeekStart)
+	sum := AIRejectSum(file)
+	if sum != ChecksumSHA256([]byte("a photo with no provenance")) {
+		t.Fatalf("expected the whole file hashed, got %q", sum)
+	}
+	if pos, _ := file.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Fatalf("expected the reader rewound, at %d", pos)
+	}
+	if AIRejected(ctx, sum) {
+		t.Fatal("unexpected hit on an empty cache")
+	}
+	RememberAIReject(ctx, sum)
+	if !AIRejected(ctx, sum) || AIRejected(ctx, ChecksumSHA256([]byte("another file"))) {
+		t.Fatal("expected only the rejected file remembered")
+	}
+	FlushAIRejectCache(ctx)
+	if AIRejected(ctx, sum) {
+		t.Fatal("rejection survived a flush")
+	}
+	st := GetAIRejectCacheStats()
+	if !st.Enabled || st.Hits != 1 || st.Misses != 3 || st.Stored != 1 || st.Flushes != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// Animation describes an animated container found by InspectAnimation. Width and Height
+// are the canvas size, which individual frames may not fill.
+type Animation struct {
+	Format   string // "gif", "apng" or "webp"
+	Frames   int
+	Duration time.Duration
+	Width    int
+	Height   int
+}
+
+// ContentType is the MIME type the file is served with.
+func (a Animation) ContentType() string {
+	switch a.Format {
+	case "gif":
+		return "image/gif"
+	case "webp":
+		return "image/webp"
+	}
+	return "image/png"
+}
+
+// Ext is the file extension the stored file gets.
+func (a Animation) Ext() string {
+	switch a.Format {
+	case "gif":
+		return ".gif"
+	case "webp":
+		return ".webp"
+	}
+	return ".png"
+}
+
+// CheckLimits rejects animations over the configured frame count or play time. Zero
+// limits are not enforced.
+func (a Animation) CheckLimits(cfg AnimationConfig) error {
+	if cfg.MaxFrames > 0 && a.Frames > cfg.MaxFrames {
+		return fmt.Errorf("animation has %d frames; the limit is %d", a.Frames, cfg.MaxFrames)
+	}
+	if cfg.MaxDuration > 0 && a.Duration > cfg.MaxDuration {
+		return fmt.Errorf("animation runs %s; the limit is %s", a.Duration.Round(100*time.Millisecond), cfg.MaxDuration)
+	}
+	return nil
+}
+
+// maxAnimationText bounds how much decompressed PNG text is collected for detection.
+const maxAnimationText = 1 << 20
+
+// InspectAnimation reports whether b is a GIF (animated or not), an APNG or an animated
+// WebP. Plain PNG and WebP stills return false and keep using the regular pipeline.
+func InspectAnimation(b []byte) (Animation, bool) {
+	switch {
+	case bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a")):
+		a, _, err := scanGIF(b)
+		return a, err == nil
+	case bytes.HasPrefix(b, pngSignature):
+		a, _, err := scanAPNG(b)
+		return a, err == nil && a.Frames > 0
+	case len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WEBP":
+		a, _, _, err := scanWebP(b)
+		return a, err == nil && a.Frames > 0
+	}
+	return Animation{}, false
+}
+
+// AnimationMetadata returns the container's metadata blocks (GIF comments and application
+// extensions, PNG text and eXIf chunks, WebP EXIF and XMP chunks) joined by newlines.
+// AI detection runs on this rather than the whole file so frame data cannot produce
+// false positives.
+func AnimationMetadata(b []byte) []byte {
+	var meta []byte
+	switch {
+	case bytes.HasPrefix(b, []byte("GIF8")):
+		_, meta, _ = scanGIF(b)
+	case bytes.HasPrefix(b, pngSignature):
+		_, meta, _ = scanAPNG(b)
+	case bytes.HasPrefix(b, []byte("RIFF")):
+		_, meta, _, _ = scanWebP(b)
+	}
+	return meta
+}
+
+// DecodeFirstFrame decodes the first frame of an animation, for the blurhash, dominant
+// color and anything else that needs a still.
+func DecodeFirstFrame(b []byte) (image.Image, error) {
+	switch {
+	case bytes.HasPrefix(b, []byte("GIF8")):
+		return gif.Decode(bytes.NewReader(b))
+	case bytes.HasPrefix(b, pngSignature):
+		// The default image of an APNG is what non-animating decoders show
+		return png.Decode(bytes.NewReader(b))
+	case bytes.HasPrefix(b, []byte("RIFF")):
+		_, _, first, err := scanWebP(b)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			return webp.Decode(bytes.NewReader(b))
+		}
+		return webp.Decode(bytes.NewReader(first))
+	}
+	return nil, errors.New("not an animated image")
+}
+
+var (
+	pngSignature          = []byte("\x89PNG\r\n\x1a\n")
+	errTruncatedAnimation = errors.New("truncated animation")
+)
+
+// scanGIF walks the block structure without decoding frames.
+func scanGIF(b []byte) (Animation, []byte, error) {
+	a := Animation{Format: "gif"}
+	if len(b) < 13 {
+		return a, nil, errTruncatedAnimation
+	}
+	a.Width = int(binary.LittleEndian.Uint16(b[6:8]))
+	a.Height = int(binary.LittleEndian.Uint16(b[8:10]))
+	p := 13
+	if b[10]&0x80 != 0 {
+		p += 3 << ((b[10] & 7) + 1)
+	}
+	var meta []byte
+	var delay time.Duration
+	for p < len(b) {
+		switch b[p] {
+		case 0x3b: // trailer
+			return a, meta, nil
+		case 0x21: // extension
+			if p+2 > len(b) {
+				return a, meta, errTruncatedAnimation
+			}
+			label := b[p+1]
+			start := p + 2
+			end, ok := skipGIFSubBlocks(b, start)
+			if !ok {
+				return a, meta, errTruncatedAnimation
+			}
+			switch label {
+			case 0xf9: // graphic control: delay in hundredths of a second
+				if end-start >= 6 {
+					cs := int(binary.LittleEndian.Uint16(b[start+2 : start+4]))
+					// Browsers play 0 and 1 as 10, so count them the same way
+					if cs <= 1 {
+						cs = 10
+					}
+					delay = time.Duration(cs) * 10 * time.Millisecond
+				}
+			case 0xfe, 0xff: // comment, application (XMP is stored raw across sub-blocks)
+				meta = append(append(meta, b[start:end]...), '\n')
+			}
+			p = end
+		case 0x2c: // image descriptor
+			if p+10 > len(b) {
+				return a, meta, errTruncatedAnimation
+			}
+			packed := b[p+9]
+			p += 10
+			if packed&0x80 != 0 {
+				p += 3 << ((packed & 7) + 1)
+			}
+			p++ // LZW minimum code size
+			end, ok := skipGIFSubBlocks(b, p)
+			if !ok {
+				return a, meta, errTruncatedAnimation
+			}
+			p = end
+			a.Frames++
+			if delay == 0 {
+				// No graphic control block plays like a zero delay
+				delay = 100 * time.Millisecond
+			}
+			a.Duration += delay
+			delay = 0
+		default:
+			return a, meta, errors.New("invalid GIF block")
+		}
+	}
+	// Many encoders omit the trailer; a file with frames is still playable
+	if a.Frames == 0 {
+		return a, meta, errTruncatedAnimation
+	}
+	return a, meta, nil
+}
+
+// skipGIFSubBlocks returns the offset just past the block terminator.
+func skipGIFSubBlocks(b []byte, p int) (int, bool) {
+	for p < len(b) {
+		n := int(b[p])
+		p++
+		if n == 0 {
+			return p, true
+		}
+		p += n
+	}
+	return p, false
+}
+
+// scanAPNG reads the chunk list. Frames stays zero for a PNG without an acTL chunk.
+func scanAPNG(b []byte) (Animation, []byte, error) {
+	a := Animation{Format: "apng"}
+	var meta []byte
+	textBudget := maxAnimationText
+	p := len(pngSignature)
+	for p+12 <= len(b) {
+		n := int(binary.BigEndian.Uint32(b[p : p+4]))
+		typ := string(b[p+4 : p+8])
+		if n < 0 || p+12+n > len(b) {
+			return a, meta, errTruncatedAnimation
+		}
+		data := b[p+8 : p+8+n]
+		switch typ {
+		case "IHDR":
+			if n >= 8 {
+				a.Width = int(binary.BigEndian.Uint32(data[0:4]))
+				a.Height = int(binary.BigEndian.Uint32(data[4:8]))
+			}
+		case "acTL":
+			if n >= 4 {
+				a.Frames = int(binary.BigEndian.Uint32(data[0:4]))
+			}
+		case "fcTL":
+			if n >= 26 {
+				num := time.Duration(binary.BigEndian.Uint16(data[20:22]))
+				den := time.Duration(binary.BigEndian.Uint16(data[22:24]))
+				if den == 0 {
+					den = 100
+				}
+				a.Duration += num * time.Second / den
+			}
+		case "tEXt", "eXIf":
+			meta = append(append(meta, data...), '\n')
+		case "zTXt", "iTXt":
+			if text := pngText(typ, data, &textBudget); text != nil {
+				meta = append(append(meta, text...), '\n')
+			}
+		case "IEND":
+			return a, meta, nil
+		}
+		p += 12 + n
+	}
+	return a, meta, nil
+}
+
+// pngText returns the keyword and text of a zTXt or iTXt chunk, inflating compressed text
+// within the remaining budget.
+func pngText(typ string, data []byte, budget *int) []byte {
+	kw := bytes.IndexByte(data, 0)
+	if kw < 0 {
+		return nil
+	}
+	rest := data[kw+1:]
+	compressed := typ == "zTXt"
+	if typ == "iTXt" {
+		// compression flag, method, language tag\0, translated keyword\0, text
+		if len(rest) < 2 {
+			return nil
+		}
+		compressed = rest[0] == 1
+		rest = rest[2:]
+		for i := 0; i < 2; i++ {
+			z := bytes.IndexByte(rest, 0)
+			if z < 0 {
+				return nil
+			}
+			rest = rest[z+1:]
+		}
+	} else if len(rest) > 0 {
+		rest = rest[1:] // compression method
+	}
+	out := append([]byte(nil), data[:kw]...)
+	out = append(out, 0)
+	if !compressed {
+		return append(out, rest...)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(rest))
+	if err != nil || *budget <= 0 {
+		return out
+	}
+	defer zr.Close()
+	text, _ := io.ReadAll(io.LimitReader(zr, int64(*budget)))
+	*budget -= len(text)
+	return append(out, text...)
+}
+
+// scanWebP reads the RIFF chunk list. For animations it also returns the first frame
+// wrapped as a standalone still WebP that x/image/webp can decode.
+func scanWebP(b []byte) (Animation, []byte, []byte, error) {
+	a := Animation{Format: "webp"}
+	var meta, first []byte
+	animated := false
+	p := 12
+	for p+8 <= len(b) {
+		id := string(b[p : p+4])
+		n := int(binary.LittleEndian.Uint32(b[p+4 : p+8]))
+		if n < 0 || p+8+n > len(b) {
+			return a, meta, first, errTruncatedAnimation
+		}
+		data := b[p+8 : p+8+n]
+		switch id {
+		case "VP8X":
+			if n >= 10 {
+				animated = data[0]&0x02 != 0
+				a.Width = int(uint24(data[4:7])) + 1
+				a.Height = int(uint24(data[7:10])) + 1
+			}
+		case "ANMF":
+			if n < 16 {
+				return a, meta, first, errTruncatedAnimation
+			}
+			a.Frames++
+			a.Duration += time.Duration(uint24(data[12:15])) * time.Millisecond
+			if first == nil {
+				first = stillWebP(int(uint24(data[6:9]))+1, int(uint24(data[9:12]))+1, data[16:])
+			}
+		case "EXIF", "XMP ":
+			meta = append(append(meta, data...), '\n')
+		}
+		p += 8 + n + n&1
+	}
+	if !animated {
+		a.Frames = 0
+	}
+	return a, meta, first, nil
+}
+
+// stillWebP builds an extended-format WebP holding one frame's ALPH/VP8/VP8L chunks.
+func stillWebP(w, h int, frame []byte) []byte {
+	var flags byte
+	if bytes.Contains(frame[:min(len(frame), 4)], []byte("ALPH")) {
+		flags |= 0x10
+	}
+	vp8x := []byte{'V', 'P', '8', 'X', 10, 0, 0, 0, flags, 0, 0, 0,
+		byte(w - 1), byte((w - 1) >> 8), byte((w - 1) >> 16),
+		byte(h - 1), byte((h - 1) >> 8), byte((h - 1) >> 16)}
+	out := make([]byte, 0, 12+len(vp8x)+len(frame))
+	out = append(out, 'R', 'I', 'F', 'F', 0, 0, 0, 0, 'W', 'E', 'B', 'P')
+	out = append(out, vp8x...)
+	out = append(out, frame...)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
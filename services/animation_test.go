@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testGIF(t *testing.T, delays []int, comment string) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for i, d := range delays {
+		fr := image.NewPaletted(image.Rect(0, 0, 16, 8), palette.Plan9)
+		fr.SetColorIndex(i, 0, uint8(i+1))
+		g.Image = append(g.Image, fr)
+		g.Delay = append(g.Delay, d)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if comment == "" {
+		return b
+	}
+	// Insert a comment extension ahead of the first frame's graphic control block
+	at := bytes.Index(b, []byte{0x21, 0xf9})
+	ext := append([]byte{0x21, 0xfe, byte(len(comment))}, comment...)
+	ext = append(ext, 0)
+	return append(append(append([]byte(nil), b[:at]...), ext...), b[at:]...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	out = append(out, typ...)
+	out = append(out, data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+}
+
+func testAPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 6))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	iend := bytes.LastIndex(b, []byte("IEND")) - 4
+	actl := binary.BigEndian.AppendUint32(nil, 2)
+	actl = binary.BigEndian.AppendUint32(actl, 0)
+	fctl := func(seq uint32, num, den uint16) []byte {
+		d := binary.BigEndian.AppendUint32(nil, seq)
+		d = binary.BigEndian.AppendUint32(d, 10)
+		d = binary.BigEndian.AppendUint32(d, 6)
+		d = append(d, make([]byte, 8)...)
+		d = binary.BigEndian.AppendUint16(d, num)
+		d = binary.BigEndian.AppendUint16(d, den)
+		return append(d, 0, 0)
+	}
+	var extra []byte
+	extra = append(extra, pngChunk("acTL", actl)...)
+	extra = append(extra, pngChunk("fcTL", fctl(0, 1, 2))...)
+	extra = append(extra, pngChunk("fcTL", fctl(1, 250, 0))...)
+	extra = append(extra, pngChunk("tEXt", []byte("parameters\x00a cat, Steps: 20, Sampler: Euler"))...)
+	return append(append(append([]byte(nil), b[:iend]...), extra...), b[iend:]...)
+}
+
+// testAnimatedWebP wraps a 1x1 lossless frame twice into an animated container.
+func testAnimatedWebP(t *testing.T) []byte {
+	t.Helper()
+	still, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	vp8l := still[12:] // the VP8L chunk with its header
+	chunk := func(id string, data []byte) []byte {
+		out := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		out = append(out, data...)
+		if len(data)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	anmf := func(ms int) []byte {
+		h := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(ms), byte(ms >> 8), 0, 0}
+		return chunk("ANMF", append(h, vp8l...))
+	}
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", []byte{0x02 | 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	body = append(body, chunk("ANIM", []byte{0, 0, 0, 0, 0, 0})...)
+	body = append(body, anmf(100)...)
+	body = append(body, anmf(300)...)
+	body = append(body, chunk("XMP ", []byte(`<x:xmpmeta><rdf:RDF><rdf:Description xmp:CreatorTool="Midjourney"/></rdf:RDF></x:xmpmeta>`))...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func TestInspectAnimation_GIF(t *testing.T) {
+	b := testGIF(t, []int{0, 5, 20}, "prompt: a cat, steps 20")
+	a, ok := InspectAnimation(b)
+	if !ok || a.Format != "gif" || a.Frames != 3 || a.Width != 16 || a.Height != 8 {
+		t.Fatalf("unexpected inspection: %+v ok=%v", a, ok)
+	}
+	// A zero delay plays as 100ms
+	if a.Duration != 350*time.Millisecond {
+		t.Fatalf("duration = %s", a.Duration)
+	}
+	if !strings.Contains(string(AnimationMetadata(b)), "prompt: a cat") {
+		t.Fatalf("comment not in metadata: %q", AnimationMetadata(b))
+	}
+	if img, err := DecodeFirstFrame(b); err != nil || img.Bounds().Dx() != 16 {
+		t.Fatalf("first frame: %v", err)
+	}
+	if _, ok := InspectAnimation(testGIF(t, []int{0}, "")); !ok {
+		t.Fatal("single-frame GIF should use the animation pipeline")
+	}
+}
+
+func TestInspectAnimation_APNG(t *testing.T) {
+	b := testAPNG(t)
+	a, ok := InspectAnimation(b)
+	if !ok || a.Format != "apng" || a.Frames != 2 || a.Width != 10 {
+		t.Fatalf("unexpected inspection: %+v ok=%v", a, ok)
+	}
+	// 1/2s plus 250/100s (a zero denominator means hundredths)
+	if a.Duration != 3*time.Second {
+		t.Fatalf("duration = %s", a.Duration)
+	}
+	if !strings.Contains(string(AnimationMetadata(b)), "Sampler: Euler") {
+		t.Fatalf("tEXt not in metadata: %q", AnimationMetadata(b))
+	}
+	if _, err := DecodeFirstFrame(b); err != nil {
+		t.Fatalf("first frame: %v", err)
+	}
+
+	var still bytes.Buffer
+	_ = png.Encode(&still, image.NewGray(image.Rect(0, 0, 4, 4)))
+	if _, ok := InspectAnimation(still.Bytes()); ok {
+		t.Fatal("plain PNG reported as animated")
+	}
+}
+
+func TestInspectAnimation_WebP(t *testing.T) {
+	b := testAnimatedWebP(t)
+	a, ok := InspectAnimation(b)
+	if !ok || a.Format != "webp" || a.Frames != 2 || a.Width != 1 {
+		t.Fatalf("unexpected inspection: %+v ok=%v", a, ok)
+	}
+	if a.Duration != 400*time.Millisecond {
+		t.Fatalf("duration = %s", a.Duration)
+	}
+	if !bytes.Contains(AnimationMetadata(b), []byte("Midjourney")) {
+		t.Fatal("XMP chunk not in metadata")
+	}
+	img, err := DecodeFirstFrame(b)
+	if err != nil || img.Bounds().Dx() != 1 {
+		t.Fatalf("first frame: %v", err)
+	}
+}
+
+func TestAnimationCheckLimits(t *testing.T) {
+	a := Animation{Frames: 10, Duration: 5 * time.Second}
+	if err := a.CheckLimits(AnimationConfig{MaxFrames: 10, MaxDuration: 5 * time.Second}); err != nil {
+		t.Fatalf("at the limit should pass: %v", err)
+	}
+	if err := a.CheckLimits(AnimationConfig{MaxFrames: 9}); err == nil {
+		t.Fatal("too many frames accepted")
+	}
+	if err := a.CheckLimits(AnimationConfig{MaxDuration: time.Second}); err == nil {
+		t.Fatal("too long accepted")
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signed-out readers of the feed and image endpoints are limited per client IP so the
+// API cannot be scraped at an unlimited rate. Search engine crawlers are exempt once
+// their address is confirmed: the IP must reverse-resolve into one of the crawler's
+// domains and that name must resolve back to the IP. A User-Agent alone proves nothing.
+
+var (
+	anonReadsMu  sync.RWMutex
+	anonReadsCfg AnonymousReadsConfig
+)
+
+// DefaultCrawlers are the crawlers exempt from anonymous read limits unless
+// anonymous_reads.crawlers is set.
+func DefaultCrawlers() []CrawlerConfig {
+	return []CrawlerConfig{
+		{Name: "googlebot", UserAgent: "Googlebot", Domains: []string{"googlebot.com", "google.com"}},
+		{Name: "bingbot", UserAgent: "bingbot", Domains: []string{"search.msn.com"}},
+		{Name: "applebot", UserAgent: "Applebot", Domains: []string{"applebot.apple.com"}},
+		{Name: "yandexbot", UserAgent: "YandexBot", Domains: []string{"yandex.ru", "yandex.net", "yandex.com"}},
+	}
+}
+
+// SetAnonymousReads configures anonymous read limits; ApplyConfig calls it at startup.
+func SetAnonymousReads(cfg AnonymousReadsConfig) {
+	anonReadsMu.Lock()
+	anonReadsCfg = cfg
+	anonReadsMu.Unlock()
+	crawlerCache.Lock()
+	crawlerCache.m = map[string]crawlerVerdict{}
+	crawlerCache.Unlock()
+}
+
+// AnonymousReads returns the active anonymous read limits.
+func AnonymousReads() AnonymousReadsConfig {
+	anonReadsMu.RLock()
+	defer anonReadsMu.RUnlock()
+	return anonReadsCfg
+}
+
+const (
+	crawlerCacheMax    = 10000
+	crawlerVerifiedTTL = 24 * time.Hour
+	crawlerRejectedTTL = time.Hour
+	crawlerPendingTTL  = 30 * time.Second
+	crawlerLookupLimit = 8
+)
+
+type crawlerVerdict struct {
+	ok      bool
+	expires time.Time
+}
+
+var (
+	crawlerCache = struct {
+		sync.Mutex
+		m map[string]crawlerVerdict
+	}{m: map[string]crawlerVerdict{}}
+	crawlerLookups = make(chan struct{}, crawlerLookupLimit)
+
+	// DNS lookups, replaced in tests
+	lookupAddr   = net.DefaultResolver.LookupAddr
+	lookupIPAddr = net.DefaultResolver.LookupIPAddr
+)
+
+// VerifiedCrawler returns the name of the configured crawler that userAgent claims to be,
+// if ip has been confirmed to belong to it. Verification runs in the background on first
+// sight, so the first requests from a new crawler address count against the limits like
+// anyone else's; it returns "" until the DNS checks have passed.
+func VerifiedCrawler(ip, userAgent string) string {
+	cr := matchCrawler(AnonymousReads().Crawlers, userAgent)
+	if cr == nil || ip == "" {
+		return ""
+	}
+	key := cr.Name + "|" + ip
+	now := time.Now()
+	crawlerCache.Lock()
+	if v, ok := crawlerCache.m[key]; ok && now.Before(v.expires) {
+		crawlerCache.Unlock()
+		if v.ok {
+			return cr.Name
+		}
+		return ""
+	}
+	if len(crawlerCache.m) >= crawlerCacheMax {
+		crawlerCache.m = map[string]crawlerVerdict{}
+	}
+	// Mark the lookup as in flight so concurrent requests do not start another
+	crawlerCache.m[key] = crawlerVerdict{expires: now.Add(crawlerPendingTTL)}
+	crawlerCache.Unlock()
+
+	select {
+	case crawlerLookups <- struct{}{}:
+	default:
+		// Too many lookups already running; try again after the pending mark expires
+		return ""
+	}
+	domains := cr.Domains
+	go func() {
+		defer func() { <-crawlerLookups }()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ok := verifyCrawlerIP(ctx, ip, domains)
+		ttl := crawlerRejectedTTL
+		if ok {
+			ttl = crawlerVerifiedTTL
+		}
+		crawlerCache.Lock()
+		crawlerCache.m[key] = crawlerVerdict{ok: ok, expires: time.Now().Add(ttl)}
+		crawlerCache.Unlock()
+	}()
+	return ""
+}
+
+func matchCrawler(crawlers []CrawlerConfig, userAgent string) *CrawlerConfig {
+	if userAgent == "" {
+		return nil
+	}
+	ua := strings.ToLower(userAgent)
+	for i := range crawlers {
+		if needle := strings.ToLower(strings.TrimSpace(crawlers[i].UserAgent)); needle != "" && strings.Contains(ua, needle) {
+			cr := crawlers[i]
+			if cr.Name == "" {
+				cr.Name = needle
+			}
+			return &cr
+		}
+	}
+	return nil
+}
+
+// verifyCrawlerIP does forward-confirmed reverse DNS: some PTR name of ip must be in one of
+// domains and must resolve back to ip.
+func verifyCrawlerIP(ctx context.Context, ip string, domains []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	names, err := lookupAddr(ctx, ip)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		host := strings.ToLower(strings.TrimSuffix(name, "."))
+		if !inDomains(host, domains) {
+			continue
+		}
+		addrs, err := lookupIPAddr(ctx, host)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if a.IP.Equal(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inDomains(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
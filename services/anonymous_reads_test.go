@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubCrawlerDNS(t *testing.T, ptr map[string][]string, fwd map[string][]string) {
+	origAddr, origIP := lookupAddr, lookupIPAddr
+	t.Cleanup(func() { lookupAddr, lookupIPAddr = origAddr, origIP })
+	lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		if names, ok := ptr[addr]; ok {
+			return names, nil
+		}
+		return nil, errors.New("no PTR")
+	}
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		var out []net.IPAddr
+		for _, s := range fwd[host] {
+			out = append(out, net.IPAddr{IP: net.ParseIP(s)})
+		}
+		if out == nil {
+			return nil, errors.New("no such host")
+		}
+		return out, nil
+	}
+}
+
+func TestVerifyCrawlerIP(t *testing.T) {
+	stubCrawlerDNS(t,
+		map[string][]string{
+			"66.249.66.1": {"crawl-66-249-66-1.googlebot.com."},
+			"203.0.113.5": {"googlebot.com.evil.example."},
+			"203.0.113.6": {"fake.googlebot.com."},
+		},
+		map[string][]string{
+			"crawl-66-249-66-1.googlebot.com": {"66.249.66.1"},
+			"googlebot.com.evil.example":      {"203.0.113.5"},
+			"fake.googlebot.com":              {"66.249.66.9"},
+		})
+	domains := []string{"googlebot.com", "google.com"}
+	ctx := context.Background()
+	assert.True(t, verifyCrawlerIP(ctx, "66.249.66.1", domains))
+	assert.False(t, verifyCrawlerIP(ctx, "203.0.113.5", domains), "domain suffix must be a whole label")
+	assert.False(t, verifyCrawlerIP(ctx, "203.0.113.6", domains), "forward lookup must return the IP")
+	assert.False(t, verifyCrawlerIP(ctx, "198.51.100.1", domains), "no PTR record")
+}
+
+func TestDefaultGooglebotRejectsGoogleusercontent(t *testing.T) {
+	// Anyone can rent a Google Cloud VM whose PTR record is under googleusercontent.com
+	stubCrawlerDNS(t,
+		map[string][]string{"34.1.2.3": {"3.2.1.34.bc.googleusercontent.com."}},
+		map[string][]string{"3.2.1.34.bc.googleusercontent.com": {"34.1.2.3"}})
+	var domains []string
+	for _, c := range DefaultCrawlers() {
+		if c.Name == "googlebot" {
+			domains = c.Domains
+		}
+	}
+	assert.ElementsMatch(t, []string{"googlebot.com", "google.com"}, domains)
+	assert.False(t, verifyCrawlerIP(context.Background(), "34.1.2.3", domains))
+}
+
+func TestVerifiedCrawler(t *testing.T) {
+	stubCrawlerDNS(t,
+		map[string][]string{"157.55.39.1": {"msnbot-157-55-39-1.search.msn.com."}},
+		map[string][]string{"msnbot-157-55-39-1.search.msn.com": {"157.55.39.1"}})
+	SetAnonymousReads(AnonymousReadsConfig{Enabled: true, Crawlers: DefaultCrawlers()})
+	t.Cleanup(func() { SetAnonymousReads(AnonymousReadsConfig{}) })
+
+	ua := "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)"
+	assert.Equal(t, "", VerifiedCrawler("157.55.39.1", "curl/8.0"))
+	// The first request starts verification in the background
+	assert.Equal(t, "", VerifiedCrawler("157.55.39.1", ua))
+	assert.Eventually(t, func() bool { return VerifiedCrawler("157.55.39.1", ua) == "bingbot" }, time.Second, 10*time.Millisecond)
+
+	// Same User-Agent from an address that is not Bing's
+	VerifiedCrawler("203.0.113.7", ua)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "", VerifiedCrawler("203.0.113.7", ua))
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reasons a registration is refused by the antispam checks, as recorded in the admin
+// stats and the trough_registrations_blocked_total metric.
+const (
+	SpamHoneypot   = "honeypot"
+	SpamTiming     = "timing"
+	SpamDisposable = "disposable"
+)
+
+// formTokenMaxAge bounds how long a registration form may stay open; older tokens fail
+// the timing check and the form fetches a new one.
+const formTokenMaxAge = 2 * time.Hour
+
+// RegistrationSignals are the antispam fields submitted with a registration.
+type RegistrationSignals struct {
+	Honeypot  string // hidden field people never see, so never fill in
+	FormToken string // from NewFormToken, issued when the form was opened
+	Email     string
+}
+
+// AntispamPolicy holds the site settings the checks depend on.
+type AntispamPolicy struct {
+	MinFillTime     time.Duration // 0 disables the timing check
+	BlockDisposable bool
+}
+
+// NewFormToken returns a signed timestamp for the registration form, so the server can
+// later tell how long the form was open without remembering anything.
+func NewFormToken() string {
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return ts + "." + formTokenSign(ts)
+}
+
+func formTokenSign(ts string) string {
+	mac := hmac.New(sha256.New, []byte("trough-form:"+os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(ts))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// formTokenAge returns how long ago token was issued; ok is false for forged, malformed
+// or future tokens.
+func formTokenAge(token string, now time.Time) (time.Duration, bool) {
+	ts, sig, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(sig), []byte(formTokenSign(ts))) {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	age := now.Sub(time.UnixMilli(ms))
+	if age < 0 {
+		return 0, false
+	}
+	return age, true
+}
+
+// CheckRegistrationSpam returns why a registration looks automated, or "" to let it
+// through: a filled honeypot, a form submitted faster than a person could (or without a
+// valid form token), or a disposable email address.
+func CheckRegistrationSpam(sig RegistrationSignals, p AntispamPolicy) string {
+	if strings.TrimSpace(sig.Honeypot) != "" {
+		return SpamHoneypot
+	}
+	if p.MinFillTime > 0 {
+		age, ok := formTokenAge(sig.FormToken, time.Now())
+		if !ok || age < p.MinFillTime || age > formTokenMaxAge {
+			return SpamTiming
+		}
+	}
+	if p.BlockDisposable && IsDisposableEmail(sig.Email) {
+		return SpamDisposable
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckRegistrationSpam(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	policy := AntispamPolicy{MinFillTime: 3 * time.Second, BlockDisposable: true}
+	aged := func(d time.Duration) string {
+		ts := strconv.FormatInt(time.Now().Add(-d).UnixMilli(), 10)
+		return ts + "." + formTokenSign(ts)
+	}
+	cases := []struct {
+		name string
+		sig  RegistrationSignals
+		want string
+	}{
+		{"clean", RegistrationSignals{FormToken: aged(10 * time.Second), Email: "a@example.com"}, ""},
+		{"honeypot", RegistrationSignals{Honeypot: "http://spam", FormToken: aged(10 * time.Second)}, SpamHoneypot},
+		{"too fast", RegistrationSignals{FormToken: NewFormToken(), Email: "a@example.com"}, SpamTiming},
+		{"no token", RegistrationSignals{Email: "a@example.com"}, SpamTiming},
+		{"stale token", RegistrationSignals{FormToken: aged(3 * time.Hour), Email: "a@example.com"}, SpamTiming},
+		{"forged token", RegistrationSignals{FormToken: strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10) + ".abc"}, SpamTiming},
+		{"disposable", RegistrationSignals{FormToken: aged(10 * time.Second), Email: "a@mailinator.com"}, SpamDisposable},
+	}
+	for _, tc := range cases {
+		if got := CheckRegistrationSpam(tc.sig, policy); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	// With the timing check off a missing token is fine
+	if got := CheckRegistrationSpam(RegistrationSignals{Email: "a@example.com"}, AntispamPolicy{}); got != "" {
+		t.Errorf("timing check should be off, got %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Avatar output formats.
+const (
+	AvatarFormatWebP = "webp"
+	AvatarFormatJPEG = "jpeg"
+)
+
+// Storage prefixes of profile media. Each upload gets a new key under its prefix, so
+// caches never serve a replaced file.
+const (
+	AvatarPrefix = "avatars/"
+	BannerPrefix = "banners/"
+)
+
+// maxAnimatedAvatarSide bounds the canvas of animated avatars, which are stored as
+// uploaded rather than scaled.
+const maxAnimatedAvatarSide = 1024
+
+// ErrAvatarLimits is wrapped by ProcessAvatar's errors for animations over the caps; the
+// message says which.
+var ErrAvatarLimits = errors.New("animated avatar over the limits")
+
+// AvatarsConfig sets how uploaded avatars are stored: cropped to a centred square, scaled
+// down to Size pixels and re-encoded as Format, "webp" (lossless, keeps transparency) or
+// "jpeg" (at Quality, flattened on white). Animated GIF and WebP avatars of at most
+// AnimatedMaxFrames frames and AnimatedMaxKB are kept as uploaded; with AnimatedMaxFrames
+// at 0 only their first frame is used. Env overrides: AVATAR_SIZE, AVATAR_FORMAT,
+// AVATAR_ANIMATED_MAX_FRAMES.
+type AvatarsConfig struct {
+	Size              int    `yaml:"size"`
+	Format            string `yaml:"format"`
+	Quality           int    `yaml:"quality"`
+	AnimatedMaxFrames int    `yaml:"animated_max_frames"`
+	AnimatedMaxKB     int    `yaml:"animated_max_kb"`
+}
+
+var (
+	avatarsMu  sync.RWMutex
+	avatarsCfg = AvatarsConfig{Size: 256, Format: AvatarFormatWebP, Quality: 90, AnimatedMaxFrames: 60, AnimatedMaxKB: 1024}
+)
+
+// SetAvatars configures avatar processing; ApplyConfig calls it at startup.
+func SetAvatars(cfg AvatarsConfig) {
+	avatarsMu.Lock()
+	avatarsCfg = cfg
+	avatarsMu.Unlock()
+}
+
+func avatarsConfig() AvatarsConfig {
+	avatarsMu.RLock()
+	defer avatarsMu.RUnlock()
+	return avatarsCfg
+}
+
+// ProcessedAvatar is an encoded avatar ready to store.
+type ProcessedAvatar struct {
+	Data        []byte
+	ContentType string
+	// Ext is the file extension for its storage key, with the dot
+	Ext           string
+	Width, Height int
+	Animated      bool
+}
+
+// ProcessAvatar re-encodes an uploaded avatar per the avatars config, which also drops
+// any metadata it carried. Animations within the caps are returned as uploaded.
+func ProcessAvatar(data []byte) (*ProcessedAvatar, error) {
+	cfg := avatarsConfig()
+	var img image.Image
+	var err error
+	if a, ok := InspectAnimation(data); ok && a.Frames > 1 && a.Format != "apng" {
+		if cfg.AnimatedMaxFrames > 0 {
+			if err := checkAnimatedAvatar(a, len(data), cfg); err != nil {
+				return nil, err
+			}
+			return &ProcessedAvatar{Data: data, ContentType: a.ContentType(), Ext: a.Ext(), Width: a.Width, Height: a.Height, Animated: true}, nil
+		}
+		img, err = DecodeFirstFrame(data)
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode avatar: %w", err)
+	}
+	img = ResizeIfNeeded(cropSquare(img), cfg.Size)
+	out := &ProcessedAvatar{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	var buf bytes.Buffer
+	if strings.EqualFold(cfg.Format, AvatarFormatJPEG) {
+		if err := jpeg.Encode(&buf, FlattenIfAlpha(img, color.White), &jpeg.Options{Quality: cfg.Quality}); err != nil {
+			return nil, fmt.Errorf("encode avatar: %w", err)
+		}
+		out.ContentType, out.Ext = "image/jpeg", ".jpg"
+	} else {
+		if err := EncodeWebP(&buf, img); err != nil {
+			return nil, fmt.Errorf("encode avatar: %w", err)
+		}
+		out.ContentType, out.Ext = "image/webp", ".webp"
+	}
+	out.Data = buf.Bytes()
+	return out, nil
+}
+
+func checkAnimatedAvatar(a Animation, size int, cfg AvatarsConfig) error {
+	switch {
+	case a.Frames > cfg.AnimatedMaxFrames:
+		return fmt.Errorf("%w: it has %d frames; the limit is %d", ErrAvatarLimits, a.Frames, cfg.AnimatedMaxFrames)
+	case cfg.AnimatedMaxKB > 0 && size > cfg.AnimatedMaxKB*1024:
+		return fmt.Errorf("%w: it is %d KB; the limit is %d KB", ErrAvatarLimits, (size+1023)/1024, cfg.AnimatedMaxKB)
+	case a.Width > maxAnimatedAvatarSide || a.Height > maxAnimatedAvatarSide:
+		return fmt.Errorf("%w: it is %dx%d; the limit is %dx%d", ErrAvatarLimits, a.Width, a.Height, maxAnimatedAvatarSide, maxAnimatedAvatarSide)
+	}
+	return nil
+}
+
+// NewProfileMediaKey returns a fresh storage key under prefix, AvatarPrefix or BannerPrefix.
+func NewProfileMediaKey(prefix, ext string) string {
+	return prefix + uuid.New().String() + ext
+}
+
+// cropSquare returns the largest centred square of img.
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0, y0 := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+	rect := image.Rect(x0, y0, x0+side, y0+side)
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(rect)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Rect, img, rect.Min, draw.Src)
+	return dst
+}
+
+// AvatarKeyFromURL returns the storage key of an avatar URL this site stored, or ""
+// for anything else, such as an external avatar URL.
+func AvatarKeyFromURL(ref string) string {
+	key := StorageKeyFromRef(ref)
+	if !strings.HasPrefix(key, AvatarPrefix) || strings.Contains(key, "..") {
+		return ""
+	}
+	return key
+}
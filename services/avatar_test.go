@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestProcessAvatar_Animated(t *testing.T) {
+	defer SetAvatars(avatarsConfig())
+	SetAvatars(AvatarsConfig{Size: 256, Format: AvatarFormatWebP, AnimatedMaxFrames: 3, AnimatedMaxKB: 64})
+
+	gif := testGIF(t, []int{10, 10, 10}, "")
+	out, err := ProcessAvatar(gif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Animated || out.ContentType != "image/gif" || out.Ext != ".gif" || !bytes.Equal(out.Data, gif) {
+		t.Fatalf("expected the GIF kept as uploaded, got %+v", out)
+	}
+	if _, err := ProcessAvatar(testGIF(t, []int{10, 10, 10, 10}, "")); !errors.Is(err, ErrAvatarLimits) {
+		t.Fatalf("expected ErrAvatarLimits over the frame cap, got %v", err)
+	}
+	if out, err := ProcessAvatar(testAnimatedWebP(t)); err != nil || !out.Animated || out.Ext != ".webp" {
+		t.Fatalf("expected the animated WebP kept, got %+v %v", out, err)
+	}
+
+	// With animation off the first frame becomes a still avatar
+	SetAvatars(AvatarsConfig{Size: 256, Format: AvatarFormatWebP})
+	for name, data := range map[string][]byte{"gif": gif, "webp": testAnimatedWebP(t)} {
+		out, err := ProcessAvatar(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		img, err := webp.Decode(bytes.NewReader(out.Data))
+		if err != nil || out.Animated {
+			t.Fatalf("%s: expected a still webp, got %+v %v", name, out, err)
+		}
+		if b := img.Bounds(); b.Dx() != b.Dy() {
+			t.Fatalf("%s: expected a square, got %v", name, b)
+		}
+	}
+}
+
+func TestCheckAnimatedAvatar(t *testing.T) {
+	cfg := AvatarsConfig{AnimatedMaxFrames: 10, AnimatedMaxKB: 1}
+	for _, tc := range []struct {
+		a    Animation
+		size int
+		ok   bool
+	}{
+		{Animation{Frames: 10, Width: 64, Height: 64}, 1024, true},
+		{Animation{Frames: 11, Width: 64, Height: 64}, 1024, false},
+		{Animation{Frames: 2, Width: 64, Height: 64}, 1025, false},
+		{Animation{Frames: 2, Width: maxAnimatedAvatarSide + 1, Height: 64}, 100, false},
+	} {
+		if err := checkAnimatedAvatar(tc.a, tc.size, cfg); (err == nil) != tc.ok {
+			t.Errorf("%+v at %d bytes: got %v", tc.a, tc.size, err)
+		}
+	}
+}
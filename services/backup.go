@@ -1,13 +1,17 @@
 package services
 
 import (
-	"bytes"
+	"archive/tar"
+	"bufio"
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -30,16 +34,45 @@ func includedTables() []string {
 		"site_settings",
 		"users",
 		"pages",
+		"page_revisions",
+		"navigation_items",
 		"images",
+		"image_edits",
 		"likes",
 		"collections",
+		"boards",
+		"board_items",
 		"invites",
 		"cms_tombstones",
 		"password_resets",
 		"email_verifications",
+		"notifications",
+		"webhooks",
+		"bans",
+		"ban_audit",
+		"username_history",
+		"impersonation_sessions",
+		"admin_audit",
+		"announcements",
+		"snippets",
+		"locale_strings",
+		"login_events",
+		"blocks",
+		"legal_consents",
+		"takedown_requests",
+		"takedown_events",
+		"challenges",
+		"challenge_entries",
 	}
 }
 
+// backupExcludedTables lists tables referencing users, images or pages that are deliberately
+// left out of backups, with the reason. A full restore empties them through TRUNCATE ... CASCADE.
+var backupExcludedTables = map[string]string{
+	"email_changes":       "pending email changes; restoring one could revive a change its owner cancelled",
+	"verification_proofs": "pending verification proofs; the token is re-issued by starting the check again",
+}
+
 // DumpTableJSON returns the JSON array of rows for a given table using Postgres row_to_json.
 func DumpTableJSON(ctx context.Context, db *sqlx.DB, table string) (json.RawMessage, error) {
 	// We wrap with COALESCE to ensure we always get [] when empty
@@ -51,82 +84,322 @@ func DumpTableJSON(ctx context.Context, db *sqlx.DB, table string) (json.RawMess
 	return data, nil
 }
 
-// CreateBackup builds a gzipped JSON backup of the database contents for selected tables.
-func CreateBackup(ctx context.Context, db *sqlx.DB) ([]byte, string, error) {
-	payload := backupPayload{
-		FormatVersion: 1,
-		GeneratedAt:   time.Now().UTC(),
-		Tables:        make(map[string]json.RawMessage, 8),
-		Notes:         "Application data only; no binary uploads included.",
+// BackupOptions controls what a backup archive contains.
+type BackupOptions struct {
+	// IncludeUploads bundles files under UploadsDir into a tar.gz archive alongside the JSON dump.
+	IncludeUploads bool
+	// UploadsDir is the local uploads directory; defaults to "uploads".
+	UploadsDir string
+	// Passphrase, when set, encrypts the archive with AES-256-GCM (see backup_crypto.go).
+	Passphrase string
+}
+
+// RestoreOptions controls how a backup is restored.
+type RestoreOptions struct {
+	// Passphrase decrypts encrypted backups; ignored for plaintext archives.
+	Passphrase string
+	// Tables restores only the named tables, merged by primary key (see backup_restore.go).
+	Tables []string
+	// UserID restores only one user's account and content, merged by primary key.
+	UserID string
+	// DryRun computes the diff report without changing anything.
+	DryRun bool
+}
+
+const (
+	backupJSONEntry    = "backup.json"
+	backupUploadsEntry = "uploads/"
+)
+
+// BackupFileName returns the file name used for a backup generated at t.
+// Data-only backups are gzipped JSON; backups with uploads are tar.gz archives.
+func BackupFileName(t time.Time, opts BackupOptions) string {
+	ext := ".json.gz"
+	if opts.IncludeUploads {
+		ext = ".tar.gz"
 	}
-	tables := includedTables()
-	for _, t := range tables {
-		data, err := DumpTableJSON(ctx, db, t)
+	if opts.Passphrase != "" {
+		ext += backupEncSuffix
+	}
+	return "trough-backup-" + t.UTC().Format("20060102T150405Z") + ext
+}
+
+// WriteBackup streams a gzipped backup to w without buffering the archive in memory.
+// Rows are read with a cursor and encoded one at a time, so memory use stays flat
+// regardless of table size.
+func WriteBackup(ctx context.Context, db *sqlx.DB, w io.Writer, generatedAt time.Time, opts BackupOptions) error {
+	if opts.Passphrase != "" {
+		enc, err := newBackupEncryptWriter(w, opts.Passphrase)
 		if err != nil {
-			return nil, "", err
+			return err
+		}
+		plain := opts
+		plain.Passphrase = ""
+		if err := WriteBackup(ctx, db, enc, generatedAt, plain); err != nil {
+			return err
 		}
-		payload.Tables[t] = data
+		return enc.Close()
 	}
-	js, err := json.MarshalIndent(payload, "", "  ")
+	gz := gzip.NewWriter(w)
+	if !opts.IncludeUploads {
+		if err := writeBackupJSON(ctx, db, gz, generatedAt, "Application data only; no binary uploads included."); err != nil {
+			_ = gz.Close()
+			return err
+		}
+		return gz.Close()
+	}
+	tw := tar.NewWriter(gz)
+	// The JSON dump size is unknown up front and tar needs it in the header, so spool it to a temp file.
+	tmp, err := os.CreateTemp("", "trough-backup-*.json")
 	if err != nil {
-		return nil, "", err
+		_ = gz.Close()
+		return err
 	}
-	// gzip without extra string copy
-	var b bytes.Buffer
-	gz := gzip.NewWriter(&b)
-	if _, err := gz.Write(js); err != nil {
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := writeBackupJSON(ctx, db, tmp, generatedAt, "Application data with local uploads."); err != nil {
 		_ = gz.Close()
-		return nil, "", err
+		return err
+	}
+	if err := addTarFile(tw, tmp, backupJSONEntry, generatedAt); err != nil {
+		_ = gz.Close()
+		return err
 	}
-	if err := gz.Close(); err != nil {
-		return nil, "", err
+	dir := opts.UploadsDir
+	if strings.TrimSpace(dir) == "" {
+		dir = UploadsDir()
 	}
-	name := "trough-backup-" + payload.GeneratedAt.Format("20060102T150405Z") + ".json.gz"
-	return b.Bytes(), name, nil
+	walkErr := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return addTarFile(tw, f, backupUploadsEntry+filepath.ToSlash(rel), info.ModTime())
+	})
+	if walkErr != nil {
+		_ = gz.Close()
+		return walkErr
+	}
+	if err := tw.Close(); err != nil {
+		_ = gz.Close()
+		return err
+	}
+	return gz.Close()
 }
 
-// RestoreBackup consumes a backup stream (gzipped JSON or raw JSON) and restores tables in a transaction.
-// This replaces existing data in the included tables. It does not touch binary uploads.
-func RestoreBackup(ctx context.Context, db *sqlx.DB, r io.Reader) error {
-	// Attempt to read gzip; if it fails, fall back to plain JSON
-	var dec io.Reader
-	if zr, err := gzip.NewReader(r); err == nil {
-		defer zr.Close()
-		dec = zr
-	} else {
-		dec = r
+// writeBackupJSON writes the backup payload as JSON, streaming each table row by row.
+// The output matches backupPayload so RestoreBackup can decode it.
+func writeBackupJSON(ctx context.Context, db *sqlx.DB, w io.Writer, generatedAt time.Time, notes string) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	head, err := json.Marshal(struct {
+		FormatVersion int       `json:"format_version"`
+		GeneratedAt   time.Time `json:"generated_at"`
+		Notes         string    `json:"notes,omitempty"`
+	}{1, generatedAt.UTC(), notes})
+	if err != nil {
+		return err
 	}
-	var payload backupPayload
-	if err := json.NewDecoder(dec).Decode(&payload); err != nil {
+	// Reopen the header object so tables can be appended as they stream
+	bw.Write(head[:len(head)-1])
+	bw.WriteString(`,"tables":{`)
+	for i, t := range includedTables() {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		name, _ := json.Marshal(t)
+		bw.Write(name)
+		bw.WriteByte(':')
+		if err := streamTableJSON(ctx, db, bw, t); err != nil {
+			return fmt.Errorf("dump %s: %w", t, err)
+		}
+	}
+	bw.WriteString("}}\n")
+	return bw.Flush()
+}
+
+// streamTableJSON writes a table as a JSON array, one row at a time.
+func streamTableJSON(ctx context.Context, db *sqlx.DB, w *bufio.Writer, table string) error {
+	rows, err := db.QueryxContext(ctx, fmt.Sprintf("SELECT row_to_json(t) FROM (SELECT * FROM %s) t", pqQuoteIdent(table)))
+	if err != nil {
 		return err
 	}
-	// Basic format check
-	if payload.FormatVersion <= 0 {
-		return fmt.Errorf("invalid backup format")
+	defer rows.Close()
+	w.WriteByte('[')
+	first := true
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
-	// Start transaction
-	tx, err := db.BeginTxx(ctx, nil)
+	return w.WriteByte(']')
+}
+
+// addTarFile copies an open file into the archive under name.
+func addTarFile(tw *tar.Writer, f *os.File, name string, modTime time.Time) error {
+	info, err := f.Stat()
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
 
+// RestoreBackup consumes a backup stream (gzipped JSON, raw JSON, or a tar.gz archive with
+// uploads, optionally encrypted) and restores tables in a transaction. This replaces existing
+// data in the included tables. Upload files from archives are written to the local uploads directory.
+func RestoreBackup(ctx context.Context, db *sqlx.DB, r io.Reader, opts RestoreOptions) (*RestoreReport, error) {
+	br := bufio.NewReader(r)
+	if isEncryptedBackup(br) {
+		if opts.Passphrase == "" {
+			return nil, ErrBackupPassphraseRequired
+		}
+		dr, err := newBackupDecryptReader(br, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(dr)
+	}
+	// Gzip streams start with 0x1f 0x8b; anything else is treated as plain JSON
+	var dec io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		dec = zr
+	}
+	inner := bufio.NewReader(dec)
+	if isTarStream(inner) {
+		return restoreArchive(ctx, db, tar.NewReader(inner), UploadsDir(), opts)
+	}
+	var payload backupPayload
+	if err := json.NewDecoder(inner).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return restorePayload(ctx, db, &payload, opts)
+}
+
+// isTarStream reports whether r starts with a ustar header.
+func isTarStream(r *bufio.Reader) bool {
+	hdr, err := r.Peek(512)
+	if err != nil {
+		return false
+	}
+	return string(hdr[257:262]) == "ustar"
+}
+
+// restoreArchive restores the JSON dump from a backup archive, then extracts bundled uploads.
+// The dump is always the first entry, so files are only written once the database restore succeeded.
+// Uploads are only extracted by full restores.
+func restoreArchive(ctx context.Context, db *sqlx.DB, tr *tar.Reader, uploadsDir string, opts RestoreOptions) (*RestoreReport, error) {
+	var report *RestoreReport
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case hdr.Name == backupJSONEntry:
+			var payload backupPayload
+			if err := json.NewDecoder(tr).Decode(&payload); err != nil {
+				return nil, err
+			}
+			if report, err = restorePayload(ctx, db, &payload, opts); err != nil {
+				return nil, err
+			}
+			if report.Mode != restoreModeFull || report.DryRun {
+				return report, nil
+			}
+		case strings.HasPrefix(hdr.Name, backupUploadsEntry) && hdr.Typeflag == tar.TypeReg:
+			if report == nil {
+				return nil, fmt.Errorf("invalid backup archive: uploads before data")
+			}
+			rel := path.Clean(strings.TrimPrefix(hdr.Name, backupUploadsEntry))
+			if rel == "." || strings.HasPrefix(rel, "../") || rel == ".." || path.IsAbs(rel) {
+				continue
+			}
+			dst := filepath.Join(uploadsDir, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return nil, err
+			}
+			f, err := os.Create(dst)
+			if err != nil {
+				return nil, err
+			}
+			_, cerr := io.Copy(f, tr)
+			if err := f.Close(); cerr == nil {
+				cerr = err
+			}
+			if cerr != nil {
+				return nil, cerr
+			}
+		}
+	}
+	if report == nil {
+		return nil, fmt.Errorf("invalid backup archive: missing %s", backupJSONEntry)
+	}
+	return report, nil
+}
+
+// restoreAllTables replaces the included tables with the payload contents inside tx.
+func restoreAllTables(ctx context.Context, tx *sqlx.Tx, payload *backupPayload) error {
 	// Disable triggers and defer constraints within transaction (best-effort)
 	if _, err := tx.ExecContext(ctx, "SET CONSTRAINTS ALL DEFERRED"); err != nil {
 		// continue anyway
 		_ = err
 	}
 
-	// Truncate in reverse dependency order: children first
-	truncateOrder := []string{"likes", "collections", "images", "invites", "pages", "cms_tombstones", "users", "site_settings"}
-	for _, t := range truncateOrder {
-		if _, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", t)); err != nil {
-			return err
-		}
+	// Truncate every restored table in one statement. CASCADE also empties tables outside the
+	// backup that reference them, so anything worth keeping must be in includedTables.
+	tables := includedTables()
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", pqQuoteIdents(tables, ", "))); err != nil {
+		return err
 	}
 	// Insert in dependency order
-	insertOrder := includedTables()
-	for _, t := range insertOrder {
+	for _, t := range tables {
 		data, ok := payload.Tables[t]
 		if !ok || len(data) == 0 {
 			continue
@@ -136,27 +409,9 @@ func RestoreBackup(ctx context.Context, db *sqlx.DB, r io.Reader) error {
 		if trimmed == "[]" || trimmed == "null" || trimmed == "" {
 			continue
 		}
-		// Determine which columns are present in backup to let DB defaults apply for new columns
-		cols, err := unionJSONKeys(data)
-		if err != nil {
-			return fmt.Errorf("restore %s: %w", t, err)
-		}
-		if len(cols) == 0 {
-			continue
-		}
-		validCols, err := getTableColumns(ctx, tx, t)
+		finalCols, err := restoreColumns(ctx, tx, t, data)
 		if err != nil {
-			return fmt.Errorf("describe %s: %w", t, err)
-		}
-		colSet := make(map[string]bool, len(cols))
-		for _, c := range cols {
-			colSet[strings.ToLower(c)] = true
-		}
-		var finalCols []string
-		for _, vc := range validCols {
-			if colSet[strings.ToLower(vc)] {
-				finalCols = append(finalCols, vc)
-			}
+			return err
 		}
 		if len(finalCols) == 0 {
 			continue
@@ -172,9 +427,57 @@ func RestoreBackup(ctx context.Context, db *sqlx.DB, r io.Reader) error {
 		if _, err := tx.ExecContext(ctx, q, data); err != nil {
 			return fmt.Errorf("restore %s: %w", t, err)
 		}
+		if err := resetSequences(ctx, tx, t); err != nil {
+			return fmt.Errorf("restore %s: %w", t, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreColumns returns the live table columns present in the backup rows, so DB defaults
+// apply for columns added after the backup was taken.
+func restoreColumns(ctx context.Context, tx *sqlx.Tx, table string, data json.RawMessage) ([]string, error) {
+	cols, err := unionJSONKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("restore %s: %w", table, err)
+	}
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	validCols, err := getTableColumns(ctx, tx, table)
+	if err != nil {
+		return nil, fmt.Errorf("describe %s: %w", table, err)
+	}
+	colSet := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		colSet[strings.ToLower(c)] = true
+	}
+	var finalCols []string
+	for _, vc := range validCols {
+		if colSet[strings.ToLower(vc)] {
+			finalCols = append(finalCols, vc)
+		}
 	}
+	return finalCols, nil
+}
 
-	return tx.Commit()
+// resetSequences moves the sequences behind a table's serial columns past the restored ids,
+// so rows inserted after a restore don't collide with them.
+func resetSequences(ctx context.Context, tx *sqlx.Tx, table string) error {
+	var cols []string
+	if err := tx.SelectContext(ctx, &cols, `SELECT column_name FROM information_schema.columns
+		WHERE table_schema='public' AND table_name=$1 AND column_default LIKE 'nextval(%'`, table); err != nil {
+		return err
+	}
+	for _, c := range cols {
+		q := fmt.Sprintf("SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%[1]s), 1), MAX(%[1]s) IS NOT NULL) FROM %[2]s",
+			pqQuoteIdent(c), pqQuoteIdent(table))
+		if _, err := tx.ExecContext(ctx, q, pqQuoteIdent(table), c); err != nil {
+			return fmt.Errorf("reset sequence %s.%s: %w", table, c, err)
+		}
+	}
+	return nil
 }
 
 // getTableColumns returns column names for a table in ordinal order.
@@ -228,23 +531,34 @@ func pqQuoteIdents(ids []string, sep string) string {
 	return strings.Join(out, sep)
 }
 
-// SaveBackupFile writes a backup to the given directory and returns the absolute file path.
-func SaveBackupFile(ctx context.Context, db *sqlx.DB, dir string) (string, error) {
+// SaveBackupFile streams a backup into the given directory and returns the file path.
+// The archive is written to a temp file first so partial backups never show up in listings.
+func SaveBackupFile(ctx context.Context, db *sqlx.DB, dir string, opts BackupOptions) (string, error) {
 	if strings.TrimSpace(dir) == "" {
-		dir = "backups"
+		dir = BackupDir()
 	}
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", err
 	}
-	b, name, err := CreateBackup(ctx, db)
+	now := time.Now().UTC()
+	name := BackupFileName(now, opts)
+	tmp, err := os.CreateTemp(dir, ".tmp-"+name+"-*")
 	if err != nil {
 		return "", err
 	}
-	path := filepath.Join(dir, name)
-	if err := os.WriteFile(path, b, 0o644); err != nil {
+	defer os.Remove(tmp.Name())
+	if err := WriteBackup(ctx, db, tmp, now, opts); err != nil {
+		_ = tmp.Close()
 		return "", err
 	}
-	return path, nil
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
 }
 
 type BackupFile struct {
@@ -256,7 +570,7 @@ type BackupFile struct {
 // ListBackups returns metadata for backup files in dir.
 func ListBackups(dir string) ([]BackupFile, error) {
 	if strings.TrimSpace(dir) == "" {
-		dir = "backups"
+		dir = BackupDir()
 	}
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -271,7 +585,7 @@ func ListBackups(dir string) ([]BackupFile, error) {
 			continue
 		}
 		name := e.Name()
-		if !strings.HasSuffix(strings.ToLower(name), ".json.gz") {
+		if !isBackupName(name) {
 			continue
 		}
 		info, err := e.Info()
@@ -284,10 +598,19 @@ func ListBackups(dir string) ([]BackupFile, error) {
 	return out, nil
 }
 
+// isBackupName reports whether name is a plain backup file name (no path components).
+func isBackupName(name string) bool {
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return false
+	}
+	lower := strings.TrimSuffix(strings.ToLower(name), backupEncSuffix)
+	return strings.HasSuffix(lower, ".json.gz") || strings.HasSuffix(lower, ".tar.gz")
+}
+
 // DeleteBackup removes a named backup file from dir.
 func DeleteBackup(dir, name string) error {
 	if strings.TrimSpace(dir) == "" {
-		dir = "backups"
+		dir = BackupDir()
 	}
 	// sanitize name: must not contain separators
 	if strings.Contains(name, "/") || strings.Contains(name, "\\") || strings.TrimSpace(name) == "" {
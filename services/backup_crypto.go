@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Encrypted backups are a header followed by AES-256-GCM sealed chunks:
+//
+//	magic | salt (16) | nonce prefix (4) | chunk... | final chunk
+//
+// Each chunk uses nonce = prefix || big-endian counter, and the final chunk is
+// sealed with different additional data so truncation at a chunk boundary is detected.
+const (
+	backupEncMagic     = "TROUGHENC1"
+	backupEncSaltLen   = 16
+	backupEncPrefixLen = 4
+	backupEncChunk     = 64 * 1024
+	backupEncSuffix    = ".enc"
+	minBackupPassLen   = 12
+)
+
+var (
+	// ErrBackupPassphraseRequired is returned when an encrypted backup is restored without a passphrase.
+	ErrBackupPassphraseRequired = errors.New("backup is encrypted; passphrase required")
+	// ErrBackupPassphraseInvalid is returned when decryption fails, typically a wrong passphrase.
+	ErrBackupPassphraseInvalid = errors.New("invalid backup passphrase or corrupted backup")
+)
+
+// HashBackupPassphrase returns the marker stored in settings for a backup passphrase.
+// Only the bcrypt marker is persisted; the passphrase itself is never stored.
+func HashBackupPassphrase(pass string) (string, error) {
+	if len(pass) < minBackupPassLen {
+		return "", fmt.Errorf("passphrase must be at least %d characters", minBackupPassLen)
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+// CheckBackupPassphrase reports whether pass matches the stored marker.
+func CheckBackupPassphrase(marker, pass string) bool {
+	if strings.TrimSpace(marker) == "" || pass == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(marker), []byte(pass)) == nil
+}
+
+func backupAEAD(pass string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(pass), salt, 1, 64*1024, 4, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type backupEncryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     []byte
+}
+
+// newBackupEncryptWriter writes the encryption header to w and returns a writer that
+// seals everything written to it. Close must be called to emit the final chunk.
+func newBackupEncryptWriter(w io.Writer, pass string) (io.WriteCloser, error) {
+	hdr := make([]byte, backupEncSaltLen+backupEncPrefixLen)
+	if _, err := rand.Read(hdr); err != nil {
+		return nil, err
+	}
+	aead, err := backupAEAD(pass, hdr[:backupEncSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, backupEncMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &backupEncryptWriter{w: w, aead: aead, prefix: hdr[backupEncSaltLen:], buf: make([]byte, 0, backupEncChunk)}, nil
+}
+
+func (e *backupEncryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// Keep at least one byte buffered so Close always has data for the final chunk
+		if len(e.buf) == backupEncChunk {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		take := backupEncChunk - len(e.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+		e.buf = append(e.buf, p[:take]...)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (e *backupEncryptWriter) Close() error { return e.seal(true) }
+
+func (e *backupEncryptWriter) seal(final bool) error {
+	out := e.aead.Seal(nil, backupNonce(e.prefix, e.counter), e.buf, backupChunkAD(final))
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(out)
+	return err
+}
+
+type backupDecryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	plain   []byte
+	done    bool
+}
+
+// newBackupDecryptReader consumes the header from r (which must start with backupEncMagic).
+func newBackupDecryptReader(r *bufio.Reader, pass string) (io.Reader, error) {
+	hdr := make([]byte, len(backupEncMagic)+backupEncSaltLen+backupEncPrefixLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, ErrBackupPassphraseInvalid
+	}
+	hdr = hdr[len(backupEncMagic):]
+	aead, err := backupAEAD(pass, hdr[:backupEncSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	return &backupDecryptReader{r: r, aead: aead, prefix: hdr[backupEncSaltLen:]}, nil
+}
+
+func (d *backupDecryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		sealed := make([]byte, backupEncChunk+d.aead.Overhead())
+		n, err := io.ReadFull(d.r, sealed)
+		final := false
+		switch {
+		case err == io.ErrUnexpectedEOF || err == io.EOF:
+			final = true
+		case err != nil:
+			return 0, err
+		default:
+			if _, perr := d.r.Peek(1); perr == io.EOF {
+				final = true
+			}
+		}
+		plain, oerr := d.aead.Open(nil, backupNonce(d.prefix, d.counter), sealed[:n], backupChunkAD(final))
+		if oerr != nil {
+			return 0, ErrBackupPassphraseInvalid
+		}
+		d.counter++
+		d.plain = plain
+		d.done = final
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// isEncryptedBackup reports whether r starts with the encrypted backup header.
+func isEncryptedBackup(r *bufio.Reader) bool {
+	b, err := r.Peek(len(backupEncMagic))
+	return err == nil && string(b) == backupEncMagic
+}
+
+func backupNonce(prefix []byte, counter uint64) []byte {
+	nonce := make([]byte, backupEncPrefixLen+8)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[backupEncPrefixLen:], counter)
+	return nonce
+}
+
+func backupChunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestBackupEncryptionRoundTrip(t *testing.T) {
+	// Sizes around the chunk boundary exercise the final-chunk handling
+	for _, size := range []int{0, 1, backupEncChunk - 1, backupEncChunk, backupEncChunk + 1, 3*backupEncChunk + 17} {
+		plain := bytes.Repeat([]byte("trough"), size/6+1)[:size]
+		var enc bytes.Buffer
+		w, err := newBackupEncryptWriter(&enc, "correct horse battery")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		br := bufio.NewReader(bytes.NewReader(enc.Bytes()))
+		if !isEncryptedBackup(br) {
+			t.Fatalf("size %d: missing header", size)
+		}
+		r, err := newBackupDecryptReader(br, "correct horse battery")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestBackupDecryptionRejectsWrongPassAndTruncation(t *testing.T) {
+	var enc bytes.Buffer
+	w, _ := newBackupEncryptWriter(&enc, "correct horse battery")
+	_, _ = w.Write(bytes.Repeat([]byte{'x'}, 2*backupEncChunk+5))
+	_ = w.Close()
+
+	r, _ := newBackupDecryptReader(bufio.NewReader(bytes.NewReader(enc.Bytes())), "wrong passphrase!!")
+	if _, err := io.ReadAll(r); err != ErrBackupPassphraseInvalid {
+		t.Fatalf("wrong passphrase: got %v", err)
+	}
+
+	// Drop the final chunk; the previous chunk must not be accepted as final
+	sealed := backupEncChunk + 16
+	hdr := len(backupEncMagic) + backupEncSaltLen + backupEncPrefixLen
+	truncated := enc.Bytes()[:hdr+2*sealed]
+	r, _ = newBackupDecryptReader(bufio.NewReader(bytes.NewReader(truncated)), "correct horse battery")
+	if _, err := io.ReadAll(r); err != ErrBackupPassphraseInvalid {
+		t.Fatalf("truncated: got %v", err)
+	}
+}
+
+func TestBackupPassphraseMarker(t *testing.T) {
+	if _, err := HashBackupPassphrase("short"); err == nil {
+		t.Fatal("expected short passphrase to be rejected")
+	}
+	m, err := HashBackupPassphrase("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckBackupPassphrase(m, "correct horse battery") || CheckBackupPassphrase(m, "nope") {
+		t.Fatal("marker check mismatch")
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+// ErrBackupEnvPassphrase is returned when encryption is configured but the BACKUP_PASSPHRASE
+// environment variable is missing or does not match.
+var ErrBackupEnvPassphrase = errors.New("backup encryption is enabled but BACKUP_PASSPHRASE is missing or does not match")
+
+// BackupJob is the payload of a backup.create job. It never carries the passphrase, since
+// payloads are stored in the jobs table; encrypted backups use BACKUP_PASSPHRASE from the
+// environment of the instance that runs them.
+type BackupJob struct {
+	IncludeUploads bool `json:"include_uploads"`
+	// Remote also uploads the file to the remote backup bucket.
+	Remote bool `json:"remote"`
+}
+
+// BackupJobResult is the stored result of a backup job.
+type BackupJobResult struct {
+	Path   string `json:"path"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// EnvBackupPassphrase returns the passphrase background backups encrypt with. ok is false
+// when encryption is configured and BACKUP_PASSPHRASE does not match it.
+func EnvBackupPassphrase(set models.SiteSettings) (pass string, ok bool) {
+	if set.BackupPassphraseMarker == "" {
+		return "", true
+	}
+	pass = os.Getenv("BACKUP_PASSPHRASE")
+	return pass, CheckBackupPassphrase(set.BackupPassphraseMarker, pass)
+}
+
+// RunBackup saves a backup to BackupDir and, when asked, pushes it to the remote bucket.
+func RunBackup(ctx context.Context, db *sqlx.DB, set models.SiteSettings, job BackupJob) (*BackupJobResult, error) {
+	pass, ok := EnvBackupPassphrase(set)
+	if !ok {
+		return nil, ErrBackupEnvPassphrase
+	}
+	path, err := SaveBackupFile(ctx, db, BackupDir(), BackupOptions{IncludeUploads: job.IncludeUploads, Passphrase: pass})
+	if err != nil {
+		return nil, err
+	}
+	res := &BackupJobResult{Path: path}
+	if !job.Remote {
+		return res, nil
+	}
+	st, err := NewBackupStorage(set)
+	if err != nil {
+		return res, fmt.Errorf("remote destination unavailable: %w", err)
+	}
+	if res.Remote, err = UploadBackup(ctx, st, path); err != nil {
+		return res, fmt.Errorf("remote upload failed: %w", err)
+	}
+	return res, nil
+}
+
+// RegisterBackupJobs registers on-demand backups and the scheduled backup, which replaces
+// the old per-process ticker: as a periodic job it runs once per interval across all
+// instances.
+func RegisterBackupJobs(db *sqlx.DB, settings models.SiteSettingsRepositoryInterface) {
+	RegisterJob(JobSpec{
+		Kind: JobBackup,
+		// A failed backup is reported, not silently retried; admins can retry it
+		MaxAttempts: 1,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var p BackupJob
+			if err := json.Unmarshal(job.Payload, &p); err != nil {
+				return nil, err
+			}
+			return RunBackup(ctx, db, GetCachedSettings(settings), p)
+		},
+	})
+	RegisterJob(JobSpec{
+		Kind: JobBackupScheduled,
+		Every: func() time.Duration {
+			set := GetCachedSettings(settings)
+			if !set.BackupEnabled {
+				return 30 * time.Minute
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(set.BackupInterval))
+			if err != nil || d <= 0 {
+				d = 24 * time.Hour
+			}
+			return d
+		},
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			set := GetCachedSettings(settings)
+			if !set.BackupEnabled {
+				return nil, nil
+			}
+			res, err := RunBackup(ctx, db, set, BackupJob{Remote: set.BackupRemoteEnabled})
+			if res == nil {
+				return nil, err
+			}
+			_ = CleanupBackups(BackupDir(), set.BackupKeepDays)
+			if res.Remote != "" {
+				if st, sErr := NewBackupStorage(set); sErr == nil {
+					_ = CleanupRemoteBackups(ctx, st, set.BackupKeepDays)
+				}
+			}
+			return res, err
+		},
+	})
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// remoteBackupPrefix is the key prefix for backups pushed to object storage.
+const remoteBackupPrefix = "backups/"
+
+// NewBackupStorage builds the remote destination for backups from site settings. It
+// reuses the S3/R2 credentials against BackupRemoteBucket, which must be set and differ
+// from the uploads bucket: dumps hold password hashes, emails and credentials, and
+// uploads buckets are usually publicly readable.
+func NewBackupStorage(set models.SiteSettings) (Storage, error) {
+	b := strings.TrimSpace(set.BackupRemoteBucket)
+	if b == "" {
+		return nil, fmt.Errorf("remote backups require a dedicated backup bucket")
+	}
+	if b == strings.TrimSpace(set.S3Bucket) {
+		return nil, fmt.Errorf("the backup bucket must not be the uploads bucket")
+	}
+	set.S3Bucket = b
+	st, err := NewStorageFromSettings(set)
+	if err != nil {
+		return nil, err
+	}
+	if st.IsLocal() {
+		return nil, fmt.Errorf("remote backups require S3/R2 storage settings")
+	}
+	if _, ok := st.(ObjectLister); !ok {
+		return nil, fmt.Errorf("storage does not support listing")
+	}
+	if _, ok := st.(ObjectReader); !ok {
+		return nil, fmt.Errorf("storage does not support reading")
+	}
+	if _, ok := st.(PrivateSaver); !ok {
+		return nil, fmt.Errorf("storage does not support private objects")
+	}
+	return st, nil
+}
+
+// UploadBackup pushes a local backup file to remote storage and returns its name. It is
+// written private and uncacheable, never with the public uploads cache policy.
+func UploadBackup(ctx context.Context, st Storage, localPath string) (string, error) {
+	saver, ok := st.(PrivateSaver)
+	if !ok {
+		return "", fmt.Errorf("storage does not support private objects")
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	name := filepath.Base(localPath)
+	if err := saver.SavePrivate(ctx, remoteBackupPrefix+name, f, "application/gzip"); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// ListRemoteBackups returns backups stored remotely, newest first.
+func ListRemoteBackups(ctx context.Context, st Storage) ([]BackupFile, error) {
+	lister, ok := st.(ObjectLister)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support listing")
+	}
+	objs, err := lister.List(ctx, remoteBackupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	out := []BackupFile{}
+	for _, o := range objs {
+		name := strings.TrimPrefix(o.Key, remoteBackupPrefix)
+		if !isBackupName(name) {
+			continue
+		}
+		out = append(out, BackupFile{Name: name, Size: o.Size, ModTime: o.ModTime})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ModTime.After(out[j].ModTime) })
+	return out, nil
+}
+
+// OpenRemoteBackup opens a named remote backup for reading.
+func OpenRemoteBackup(ctx context.Context, st Storage, name string) (io.ReadCloser, error) {
+	if !isBackupName(name) {
+		return nil, fmt.Errorf("invalid name")
+	}
+	reader, ok := st.(ObjectReader)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support reading")
+	}
+	return reader.Open(ctx, remoteBackupPrefix+name)
+}
+
+// DeleteRemoteBackup removes a named remote backup.
+func DeleteRemoteBackup(ctx context.Context, st Storage, name string) error {
+	if !isBackupName(name) {
+		return fmt.Errorf("invalid name")
+	}
+	return st.Delete(ctx, remoteBackupPrefix+name)
+}
+
+// CleanupRemoteBackups deletes remote backups older than keepDays.
+func CleanupRemoteBackups(ctx context.Context, st Storage, keepDays int) error {
+	if keepDays <= 0 {
+		return nil
+	}
+	list, err := ListRemoteBackups(ctx, st)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-time.Duration(keepDays) * 24 * time.Hour)
+	for _, f := range list {
+		if f.ModTime.Before(cutoff) {
+			_ = st.Delete(ctx, remoteBackupPrefix+f.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestNewBackupStorageRequiresDedicatedBucket(t *testing.T) {
+	set := models.SiteSettings{StorageProvider: "s3", S3Bucket: "uploads", S3Endpoint: "s3.example.com", S3AccessKey: "k", S3SecretKey: "s"}
+	if _, err := NewBackupStorage(set); err == nil {
+		t.Fatal("expected an error without a backup bucket")
+	}
+	set.BackupRemoteBucket = " uploads "
+	if _, err := NewBackupStorage(set); err == nil {
+		t.Fatal("expected an error when the backup bucket is the uploads bucket")
+	}
+}
+
+func TestUploadBackupRequiresPrivateSaver(t *testing.T) {
+	if _, err := UploadBackup(context.Background(), &LocalStorage{}, "missing.sql.gz"); err == nil {
+		t.Fatal("expected an error for storage without private objects")
+	}
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	restoreModeFull   = "full"
+	restoreModeTables = "tables"
+	restoreModeUser   = "user"
+)
+
+// userScopedTables maps tables restored for a single user to the column holding the user id.
+// Settings kept as users columns, such as feed mutes and content preferences, come back with
+// the account row.
+var userScopedTables = map[string]string{
+	"users":          "id",
+	"images":         "user_id",
+	"likes":          "user_id",
+	"collections":    "user_id",
+	"boards":         "user_id",
+	"blocks":         "blocker_id",
+	"notifications":  "user_id",
+	"login_events":   "user_id",
+	"legal_consents": "user_id",
+}
+
+// userScopedChildren maps tables restored for a single user that have no user column to the
+// column referencing a user-scoped parent table, so their rows follow the parent's.
+var userScopedChildren = map[string][2]string{
+	"board_items": {"board_id", "boards"},
+}
+
+// restoreGuards skip backup rows whose references no longer exist in the live database
+// during merge restores, instead of failing the whole transaction on a foreign key.
+var restoreGuards = map[string]string{
+	"page_revisions":         "b.page_id IN (SELECT id FROM pages)",
+	"images":                 "b.user_id IN (SELECT id FROM users)",
+	"likes":                  "b.user_id IN (SELECT id FROM users) AND b.image_id IN (SELECT id FROM images)",
+	"collections":            "b.user_id IN (SELECT id FROM users) AND b.image_id IN (SELECT id FROM images)",
+	"boards":                 "b.user_id IN (SELECT id FROM users)",
+	"board_items":            "b.board_id IN (SELECT id FROM boards) AND b.image_id IN (SELECT id FROM images)",
+	"invites":                "(b.created_by IS NULL OR b.created_by IN (SELECT id FROM users))",
+	"password_resets":        "b.user_id IN (SELECT id FROM users)",
+	"email_verifications":    "b.user_id IN (SELECT id FROM users)",
+	"bans":                   "(b.created_by IS NULL OR b.created_by IN (SELECT id FROM users))",
+	"username_history":       "b.user_id IN (SELECT id FROM users)",
+	"impersonation_sessions": "b.user_id IN (SELECT id FROM users)",
+	"login_events":           "b.user_id IN (SELECT id FROM users)",
+	"blocks":                 "b.blocker_id IN (SELECT id FROM users) AND b.blocked_id IN (SELECT id FROM users)",
+	"challenge_entries":      "b.challenge_id IN (SELECT id FROM challenges) AND b.image_id IN (SELECT id FROM images) AND b.user_id IN (SELECT id FROM users)",
+	"legal_consents":         "b.user_id IN (SELECT id FROM users)",
+	"takedown_events":        "b.request_id IN (SELECT id FROM takedown_requests)",
+	"image_edits":            "b.image_id IN (SELECT id FROM images)",
+	"notifications":          "b.user_id IN (SELECT id FROM users) AND (b.image_id IS NULL OR b.image_id IN (SELECT id FROM images))",
+	"navigation_items":       "(b.page_id IS NULL OR b.page_id IN (SELECT id FROM pages))",
+}
+
+// restoreNullableRefs lists ON DELETE SET NULL references (table -> column -> referenced
+// table). Merge restores clear them when the referenced row no longer exists, as deleting it
+// would have, rather than skipping the whole row.
+var restoreNullableRefs = map[string]map[string]string{
+	"pages":                  {"updated_by": "users"},
+	"page_revisions":         {"author_id": "users"},
+	"impersonation_sessions": {"admin_id": "users"},
+	"admin_audit":            {"actor_id": "users"},
+	"challenges":             {"created_by": "users"},
+	"takedown_requests":      {"image_id": "images"},
+	"takedown_events":        {"actor_id": "users"},
+	"image_edits":            {"editor_id": "users"},
+	"images":                 {"remix_of": "images"},
+	"notifications":          {"actor_id": "users"},
+	"ban_audit":              {"actor_id": "users"},
+	"announcements":          {"created_by": "users"},
+	"snippets":               {"updated_by": "users"},
+	"locale_strings":         {"updated_by": "users"},
+}
+
+// RestoreTableDiff describes what a restore does (or would do) to one table.
+type RestoreTableDiff struct {
+	Table      string `json:"table"`
+	BackupRows int    `json:"backup_rows"`
+	Insert     int    `json:"insert"`
+	Update     int    `json:"update"`
+	Unchanged  int    `json:"unchanged"`
+	// Delete counts live rows missing from the backup; only full restores remove them.
+	Delete int `json:"delete"`
+	// Skipped counts backup rows ignored because what they reference no longer exists.
+	Skipped int `json:"skipped"`
+}
+
+// RestoreReport summarizes a restore or dry run.
+type RestoreReport struct {
+	Mode        string             `json:"mode"`
+	DryRun      bool               `json:"dry_run"`
+	UserID      string             `json:"user_id,omitempty"`
+	GeneratedAt time.Time          `json:"backup_generated_at"`
+	Tables      []RestoreTableDiff `json:"tables"`
+}
+
+// restorePayload restores the payload according to opts in one transaction. Full restores
+// replace every included table; table and user restores merge rows by primary key and never
+// delete live rows.
+func restorePayload(ctx context.Context, db *sqlx.DB, payload *backupPayload, opts RestoreOptions) (*RestoreReport, error) {
+	// Basic format check
+	if payload.FormatVersion <= 0 {
+		return nil, fmt.Errorf("invalid backup format")
+	}
+	report := &RestoreReport{Mode: restoreModeFull, DryRun: opts.DryRun, GeneratedAt: payload.GeneratedAt}
+	selected := map[string]bool{}
+	switch {
+	case strings.TrimSpace(opts.UserID) != "":
+		uid, err := uuid.Parse(strings.TrimSpace(opts.UserID))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id")
+		}
+		report.Mode, report.UserID = restoreModeUser, uid.String()
+		for t, col := range userScopedTables {
+			filtered, err := filterRowsByColumn(payload.Tables[t], col, uid.String())
+			if err != nil {
+				return nil, fmt.Errorf("filter %s: %w", t, err)
+			}
+			payload.Tables[t] = filtered
+			selected[t] = true
+		}
+		for t, ref := range userScopedChildren {
+			parents, err := columnValues(payload.Tables[ref[1]], "id")
+			if err != nil {
+				return nil, fmt.Errorf("filter %s: %w", t, err)
+			}
+			filtered, err := filterRowsByValues(payload.Tables[t], ref[0], parents)
+			if err != nil {
+				return nil, fmt.Errorf("filter %s: %w", t, err)
+			}
+			payload.Tables[t] = filtered
+			selected[t] = true
+		}
+	case len(opts.Tables) > 0:
+		report.Mode = restoreModeTables
+		known := map[string]bool{}
+		for _, t := range includedTables() {
+			known[t] = true
+		}
+		for _, t := range opts.Tables {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !known[t] {
+				return nil, fmt.Errorf("unknown table %q", t)
+			}
+			selected[t] = true
+		}
+	default:
+		for _, t := range includedTables() {
+			selected[t] = true
+		}
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	merge := report.Mode != restoreModeFull
+	// Merges run table by table (parents first) so guards and diffs see rows merged earlier
+	// in the same transaction; dry runs simply roll back at the end.
+	for _, t := range includedTables() {
+		if !selected[t] {
+			continue
+		}
+		diff, err := diffRestoreTable(ctx, tx, t, payload.Tables[t], merge)
+		if err != nil {
+			return nil, fmt.Errorf("diff %s: %w", t, err)
+		}
+		report.Tables = append(report.Tables, diff)
+		if merge {
+			if err := mergeRestoreTable(ctx, tx, t, payload.Tables[t]); err != nil {
+				return nil, fmt.Errorf("restore %s: %w", t, err)
+			}
+		}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+	if !merge {
+		if err := restoreAllTables(ctx, tx, payload); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// diffRestoreTable compares backup rows against the live table by primary key.
+func diffRestoreTable(ctx context.Context, tx *sqlx.Tx, table string, data json.RawMessage, merge bool) (RestoreTableDiff, error) {
+	diff := RestoreTableDiff{Table: table}
+	if isEmptyJSONArray(data) {
+		data = json.RawMessage("[]")
+	}
+	var total int
+	if err := tx.GetContext(ctx, &total, `SELECT json_array_length($1::json)`, data); err != nil {
+		return diff, err
+	}
+	diff.BackupRows = total
+	cols, err := restoreColumns(ctx, tx, table, data)
+	if err != nil {
+		return diff, err
+	}
+	pk, err := getPrimaryKey(ctx, tx, table)
+	if err != nil {
+		return diff, err
+	}
+	if len(pk) == 0 {
+		return diff, fmt.Errorf("table has no primary key")
+	}
+	guard := "TRUE"
+	if g, ok := restoreGuards[table]; ok && merge {
+		guard = g
+	}
+	var joins []string
+	for _, c := range pk {
+		joins = append(joins, "x."+pqQuoteIdent(c)+" = b."+pqQuoteIdent(c))
+	}
+	// Compare as text so json columns without equality operators still work
+	changed := "FALSE"
+	if len(cols) > 0 {
+		var parts []string
+		for _, c := range cols {
+			parts = append(parts, fmt.Sprintf("x.%[1]s::text IS DISTINCT FROM b.%[1]s::text", pqQuoteIdent(c)))
+		}
+		changed = strings.Join(parts, " OR ")
+	}
+	src := fmt.Sprintf("json_populate_recordset(NULL::%s.%s, $1::json)", pqQuoteIdent("public"), pqQuoteIdent(table))
+	tbl := pqQuoteIdent(table)
+	match := strings.Join(joins, " AND ")
+	q := fmt.Sprintf(`WITH b AS (SELECT * FROM %[1]s AS b WHERE %[2]s)
+		SELECT
+			(SELECT COUNT(*) FROM b),
+			(SELECT COUNT(*) FROM b WHERE NOT EXISTS (SELECT 1 FROM %[3]s x WHERE %[4]s)),
+			(SELECT COUNT(*) FROM b JOIN %[3]s x ON %[4]s WHERE %[5]s),
+			(SELECT COUNT(*) FROM %[3]s x WHERE NOT EXISTS (SELECT 1 FROM b WHERE %[4]s))`,
+		src, guard, tbl, match, changed)
+	var kept, insert, update, missing int
+	if err := tx.QueryRowxContext(ctx, q, data).Scan(&kept, &insert, &update, &missing); err != nil {
+		return diff, err
+	}
+	diff.Insert = insert
+	diff.Update = update
+	diff.Unchanged = kept - insert - update
+	diff.Skipped = total - kept
+	if !merge {
+		diff.Delete = missing
+	}
+	return diff, nil
+}
+
+// mergeRestoreTable upserts backup rows by primary key, leaving rows absent from the backup alone.
+func mergeRestoreTable(ctx context.Context, tx *sqlx.Tx, table string, data json.RawMessage) error {
+	if isEmptyJSONArray(data) {
+		return nil
+	}
+	cols, err := restoreColumns(ctx, tx, table, data)
+	if err != nil || len(cols) == 0 {
+		return err
+	}
+	pk, err := getPrimaryKey(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	if len(pk) == 0 {
+		return fmt.Errorf("table has no primary key")
+	}
+	isPK := map[string]bool{}
+	for _, c := range pk {
+		isPK[c] = true
+	}
+	sel := make([]string, 0, len(cols))
+	var sets []string
+	for _, c := range cols {
+		sel = append(sel, restoreColumnExpr(table, c))
+		if !isPK[c] {
+			sets = append(sets, pqQuoteIdent(c)+" = EXCLUDED."+pqQuoteIdent(c))
+		}
+	}
+	conflict := "DO NOTHING"
+	if len(sets) > 0 {
+		conflict = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+	guard := "TRUE"
+	if g, ok := restoreGuards[table]; ok {
+		guard = g
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM json_populate_recordset(NULL::%s.%s, $1::json) AS b WHERE %s ON CONFLICT (%s) %s",
+		pqQuoteIdent(table), pqQuoteIdents(cols, ","), strings.Join(sel, ","), pqQuoteIdent("public"), pqQuoteIdent(table), guard, pqQuoteIdents(pk, ","), conflict)
+	if _, err := tx.ExecContext(ctx, q, data); err != nil {
+		return err
+	}
+	return resetSequences(ctx, tx, table)
+}
+
+// restoreColumnExpr selects column c of backup row b, nulling dangling nullable references.
+// Rows merged by the same statement are invisible to the subquery, so self references are
+// also checked against the backup rows in $1.
+func restoreColumnExpr(table, c string) string {
+	col := "b." + pqQuoteIdent(c)
+	ref, ok := restoreNullableRefs[table][c]
+	if !ok {
+		return col
+	}
+	exists := fmt.Sprintf("%s IN (SELECT id FROM %s)", col, pqQuoteIdent(ref))
+	if ref == table {
+		exists += fmt.Sprintf(" OR %s IN (SELECT (r->>'id')::uuid FROM json_array_elements($1::json) r)", col)
+	}
+	return fmt.Sprintf("CASE WHEN %s THEN %s END", exists, col)
+}
+
+// getPrimaryKey returns the primary key columns of a table in key order.
+func getPrimaryKey(ctx context.Context, tx *sqlx.Tx, table string) ([]string, error) {
+	var cols []string
+	err := tx.SelectContext(ctx, &cols, `SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+		WHERE tc.table_schema = 'public' AND tc.table_name = $1 AND tc.constraint_type = 'PRIMARY KEY'
+		ORDER BY kcu.ordinal_position`, table)
+	return cols, err
+}
+
+// filterRowsByColumn keeps only rows whose column equals value.
+func filterRowsByColumn(data json.RawMessage, column, value string) (json.RawMessage, error) {
+	return filterRowsByValues(data, column, map[string]bool{strings.ToLower(value): true})
+}
+
+// filterRowsByValues keeps only rows whose column is one of values (lowercase keys).
+func filterRowsByValues(data json.RawMessage, column string, values map[string]bool) (json.RawMessage, error) {
+	if isEmptyJSONArray(data) {
+		return json.RawMessage("[]"), nil
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	out := make([]map[string]json.RawMessage, 0)
+	for _, r := range rows {
+		var v string
+		if raw, ok := r[column]; ok && json.Unmarshal(raw, &v) == nil && values[strings.ToLower(v)] {
+			out = append(out, r)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// columnValues returns the lowercased string values of column across the rows.
+func columnValues(data json.RawMessage, column string) (map[string]bool, error) {
+	values := map[string]bool{}
+	if isEmptyJSONArray(data) {
+		return values, nil
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		var v string
+		if raw, ok := r[column]; ok && json.Unmarshal(raw, &v) == nil {
+			values[strings.ToLower(v)] = true
+		}
+	}
+	return values, nil
+}
+
+func isEmptyJSONArray(data json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(data))
+	return trimmed == "" || trimmed == "[]" || trimmed == "null"
+}
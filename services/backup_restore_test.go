@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterRowsByColumn(t *testing.T) {
+	data := json.RawMessage(`[{"id":"1","user_id":"AAA"},{"id":"2","user_id":"bbb"},{"id":"3"}]`)
+	out, err := filterRowsByColumn(data, "user_id", "aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(out, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != "1" {
+		t.Fatalf("unexpected rows: %s", out)
+	}
+	if out, _ := filterRowsByColumn(nil, "user_id", "aaa"); string(out) != "[]" {
+		t.Fatalf("empty input: got %s", out)
+	}
+}
+
+func TestFilterRowsByValuesFollowsParents(t *testing.T) {
+	boards := json.RawMessage(`[{"id":"B1","user_id":"u"},{"id":"b2","user_id":"u"}]`)
+	parents, err := columnValues(boards, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := json.RawMessage(`[{"board_id":"b1","image_id":"i1"},{"board_id":"b3","image_id":"i2"},{"board_id":"B2","image_id":"i3"}]`)
+	out, err := filterRowsByValues(items, "board_id", parents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(out, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0]["image_id"] != "i1" || rows[1]["image_id"] != "i3" {
+		t.Fatalf("unexpected rows: %s", out)
+	}
+}
+
+func TestRestoreColumnExprNullsDanglingRefs(t *testing.T) {
+	if got := restoreColumnExpr("pages", "title"); got != `b."title"` {
+		t.Fatalf("plain column: got %s", got)
+	}
+	got := restoreColumnExpr("pages", "updated_by")
+	want := `CASE WHEN b."updated_by" IN (SELECT id FROM "users") THEN b."updated_by" END`
+	if got != want {
+		t.Fatalf("nullable ref:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRestoreColumnExprKeepsSelfRefsInBackup(t *testing.T) {
+	got := restoreColumnExpr("images", "remix_of")
+	want := `CASE WHEN b."remix_of" IN (SELECT id FROM "images") OR b."remix_of" IN (SELECT (r->>'id')::uuid FROM json_array_elements($1::json) r) THEN b."remix_of" END`
+	if got != want {
+		t.Fatalf("self ref:\n got %s\nwant %s", got, want)
+	}
+}
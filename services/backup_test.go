@@ -0,0 +1,60 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/yourusername/trough/db"
+)
+
+var (
+	createTableRe = regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\s*\);`)
+	addColumnRe   = regexp.MustCompile(`ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS [^;]*`)
+	contentRefRe  = regexp.MustCompile(`REFERENCES (users|images|pages)\(`)
+)
+
+// A full restore truncates users, images and pages with CASCADE, which empties every table
+// referencing them. Each such table must be backed up or excluded on purpose.
+func TestBackupCoversTablesReferencingContent(t *testing.T) {
+	migrations, err := db.LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	included := map[string]bool{}
+	for _, table := range includedTables() {
+		included[table] = true
+	}
+	created := map[string]bool{}
+	referencing := map[string]bool{}
+	for _, m := range migrations {
+		for _, c := range createTableRe.FindAllStringSubmatch(m.Up, -1) {
+			created[c[1]] = true
+			if contentRefRe.MatchString(c[2]) {
+				referencing[c[1]] = true
+			}
+		}
+		for _, c := range addColumnRe.FindAllStringSubmatch(m.Up, -1) {
+			if contentRefRe.MatchString(c[0]) {
+				referencing[c[1]] = true
+			}
+		}
+	}
+	if len(referencing) < 10 {
+		t.Fatalf("found only %d referencing tables; did the migration format change?", len(referencing))
+	}
+	for table := range referencing {
+		if !included[table] && backupExcludedTables[table] == "" {
+			t.Errorf("%s references users, images or pages but is neither in includedTables nor backupExcludedTables", table)
+		}
+	}
+	for table := range included {
+		if !created[table] {
+			t.Errorf("includedTables lists %s, which no migration creates", table)
+		}
+	}
+	for table := range backupExcludedTables {
+		if included[table] {
+			t.Errorf("%s is both included and excluded", table)
+		}
+	}
+}
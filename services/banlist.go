@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+// Banlist matches client IPs against banned CIDR ranges and email addresses against banned
+// domains. It is rebuilt from the bans table and cached; admin changes invalidate the cache,
+// other replicas pick them up within banlistTTL.
+type Banlist struct {
+	nets    []bannedNet
+	domains map[string]models.Ban
+}
+
+type bannedNet struct {
+	net *net.IPNet
+	ban models.Ban
+}
+
+// NewBanlist indexes bans for matching. Entries with unparsable values are skipped.
+func NewBanlist(bans []models.Ban) *Banlist {
+	b := &Banlist{domains: map[string]models.Ban{}}
+	for _, ban := range bans {
+		switch ban.Kind {
+		case models.BanKindIP:
+			if _, n, err := net.ParseCIDR(ban.Value); err == nil {
+				b.nets = append(b.nets, bannedNet{net: n, ban: ban})
+			}
+		case models.BanKindEmailDomain:
+			b.domains[ban.Value] = ban
+		}
+	}
+	return b
+}
+
+// MatchIP returns the ban covering ip, or nil.
+func (b *Banlist) MatchIP(ip string) *models.Ban {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if b == nil || parsed == nil {
+		return nil
+	}
+	for i := range b.nets {
+		if b.nets[i].net.Contains(parsed) {
+			return &b.nets[i].ban
+		}
+	}
+	return nil
+}
+
+// MatchEmail returns the ban covering the email's domain or any parent domain, or nil.
+func (b *Banlist) MatchEmail(email string) *models.Ban {
+	if b == nil {
+		return nil
+	}
+	for _, d := range domainAndParents(emailDomain(email)) {
+		if ban, ok := b.domains[d]; ok {
+			return &ban
+		}
+	}
+	return nil
+}
+
+// NormalizeBanValue validates a ban value and returns its canonical form: IPs become
+// single-address CIDRs, ranges are masked to their network, domains are lowercased.
+func NormalizeBanValue(kind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case models.BanKindIP:
+		if ip := net.ParseIP(value); ip != nil {
+			if v4 := ip.To4(); v4 != nil {
+				return v4.String() + "/32", nil
+			}
+			return ip.String() + "/128", nil
+		}
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", errors.New("value must be an IP address or CIDR range")
+		}
+		if ones, _ := n.Mask.Size(); ones == 0 {
+			return "", errors.New("refusing to ban every address")
+		}
+		return n.String(), nil
+	case models.BanKindEmailDomain:
+		value = strings.Trim(strings.ToLower(value), "@.")
+		if i := strings.LastIndex(value, "@"); i >= 0 {
+			value = value[i+1:]
+		}
+		if len(value) > 253 || !strings.Contains(value, ".") {
+			return "", errors.New("value must be a domain such as example.com")
+		}
+		for _, r := range value {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+				return "", errors.New("value must be a domain such as example.com")
+			}
+		}
+		return value, nil
+	}
+	return "", errors.New("kind must be ip or email_domain")
+}
+
+// disposableDomains lists widely used throwaway-mailbox providers. It is deliberately
+// short; admins add the rest as email_domain bans.
+var disposableDomains = map[string]bool{
+	"10minutemail.com": true, "20minutemail.com": true, "33mail.com": true, "burnermail.io": true,
+	"discard.email": true, "dispostable.com": true, "dropmail.me": true, "emailondeck.com": true,
+	"fakeinbox.com": true, "getairmail.com": true, "getnada.com": true, "guerrillamail.biz": true,
+	"guerrillamail.com": true, "guerrillamail.de": true, "guerrillamail.info": true, "guerrillamail.net": true,
+	"guerrillamail.org": true, "guerrillamailblock.com": true, "harakirimail.com": true, "inboxbear.com": true,
+	"mailcatch.com": true, "maildrop.cc": true, "mailinator.com": true, "mailinator.net": true,
+	"mailnesia.com": true, "mailpoof.com": true, "mintemail.com": true, "moakt.com": true,
+	"mohmal.com": true, "mytemp.email": true, "sharklasers.com": true, "spam4.me": true,
+	"spamgourmet.com": true, "temp-mail.io": true, "temp-mail.org": true, "tempail.com": true,
+	"tempmail.dev": true, "tempmail.net": true, "tempmailo.com": true, "tempr.email": true,
+	"throwawaymail.com": true, "trashmail.com": true, "trashmail.de": true, "trashmail.net": true,
+	"yopmail.com": true, "yopmail.fr": true, "yopmail.net": true,
+}
+
+// IsDisposableEmail reports whether the email uses a known disposable-mailbox domain.
+func IsDisposableEmail(email string) bool {
+	for _, d := range domainAndParents(emailDomain(email)) {
+		if disposableDomains[d] {
+			return true
+		}
+	}
+	return false
+}
+
+func emailDomain(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(email[i+1:], ".")
+}
+
+// domainAndParents returns d and each parent domain with at least two labels.
+func domainAndParents(d string) []string {
+	var out []string
+	for strings.Contains(d, ".") {
+		out = append(out, d)
+		d = d[strings.Index(d, ".")+1:]
+	}
+	return out
+}
+
+// BanHit describes a request refused by a ban or the disposable-email check.
+type BanHit struct {
+	Kind    string
+	Value   string
+	BanID   *uuid.UUID
+	Message string
+}
+
+// CheckBans returns why a client at ip using email may not register or sign in, or nil.
+// email may be empty to check the IP alone.
+func CheckBans(repo models.BanRepositoryInterface, ip, email string, blockDisposable bool) *BanHit {
+	list := CurrentBanlist(repo)
+	if ban := list.MatchIP(ip); ban != nil {
+		return &BanHit{Kind: ban.Kind, Value: ban.Value, BanID: &ban.ID, Message: "Access from your network is not allowed"}
+	}
+	if email == "" {
+		return nil
+	}
+	if ban := list.MatchEmail(email); ban != nil {
+		return &BanHit{Kind: ban.Kind, Value: ban.Value, BanID: &ban.ID, Message: "This email domain is not allowed"}
+	}
+	if blockDisposable && IsDisposableEmail(email) {
+		return &BanHit{Kind: models.BanKindEmailDomain, Value: emailDomain(email), Message: "Disposable email addresses are not allowed"}
+	}
+	return nil
+}
+
+const banlistTTL = 30 * time.Second
+
+var banlistCache struct {
+	mu      sync.Mutex
+	list    *Banlist
+	expires time.Time
+}
+
+// CurrentBanlist returns the cached banlist, reloading it from repo when stale. A failed
+// reload keeps serving the previous list (nil, which matches nothing, before the first load).
+func CurrentBanlist(repo models.BanRepositoryInterface) *Banlist {
+	banlistCache.mu.Lock()
+	defer banlistCache.mu.Unlock()
+	if time.Now().Before(banlistCache.expires) {
+		return banlistCache.list
+	}
+	if repo == nil {
+		return banlistCache.list
+	}
+	bans, err := repo.ListActive()
+	if err != nil {
+		// Retry soon without querying on every request while the database is struggling
+		banlistCache.expires = time.Now().Add(5 * time.Second)
+		return banlistCache.list
+	}
+	banlistCache.list = NewBanlist(bans)
+	banlistCache.expires = time.Now().Add(banlistTTL)
+	return banlistCache.list
+}
+
+// InvalidateBanlist forces the next CurrentBanlist call to reload.
+func InvalidateBanlist() {
+	banlistCache.mu.Lock()
+	banlistCache.expires = time.Time{}
+	banlistCache.mu.Unlock()
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestNormalizeBanValue(t *testing.T) {
+	cases := []struct{ kind, in, want string }{
+		{models.BanKindIP, " 203.0.113.7 ", "203.0.113.7/32"},
+		{models.BanKindIP, "203.0.113.77/24", "203.0.113.0/24"},
+		{models.BanKindIP, "2001:db8::1", "2001:db8::1/128"},
+		{models.BanKindEmailDomain, "@Spam.Example.", "spam.example"},
+		{models.BanKindEmailDomain, "bob@mail.example.org", "mail.example.org"},
+	}
+	for _, c := range cases {
+		got, err := NormalizeBanValue(c.kind, c.in)
+		if err != nil || got != c.want {
+			t.Errorf("NormalizeBanValue(%q, %q) = %q, %v; want %q", c.kind, c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []struct{ kind, in string }{
+		{models.BanKindIP, "not-an-ip"},
+		{models.BanKindIP, "0.0.0.0/0"},
+		{models.BanKindEmailDomain, "localhost"},
+		{models.BanKindEmailDomain, "exa mple.com"},
+		{"user", "x"},
+	} {
+		if _, err := NormalizeBanValue(bad.kind, bad.in); err == nil {
+			t.Errorf("NormalizeBanValue(%q, %q) should fail", bad.kind, bad.in)
+		}
+	}
+}
+
+func TestBanlistMatching(t *testing.T) {
+	list := NewBanlist([]models.Ban{
+		{Kind: models.BanKindIP, Value: "198.51.100.0/24"},
+		{Kind: models.BanKindIP, Value: "2001:db8::/32"},
+		{Kind: models.BanKindEmailDomain, Value: "spam.example"},
+	})
+	if list.MatchIP("198.51.100.200") == nil || list.MatchIP("2001:db8:1::5") == nil {
+		t.Fatal("expected addresses inside banned ranges to match")
+	}
+	if list.MatchIP("198.51.101.1") != nil || list.MatchIP("garbage") != nil {
+		t.Fatal("unexpected IP match")
+	}
+	if list.MatchEmail("a@spam.example") == nil || list.MatchEmail("a@eu.spam.example") == nil {
+		t.Fatal("expected banned domain and its subdomains to match")
+	}
+	if list.MatchEmail("a@notspam.example") != nil || list.MatchEmail("a@example") != nil {
+		t.Fatal("unexpected domain match")
+	}
+	var none *Banlist
+	if none.MatchIP("198.51.100.1") != nil || none.MatchEmail("a@spam.example") != nil {
+		t.Fatal("nil banlist must match nothing")
+	}
+}
+
+func TestIsDisposableEmail(t *testing.T) {
+	if !IsDisposableEmail("Someone@Mailinator.com") || !IsDisposableEmail("x@eu.yopmail.com") {
+		t.Fatal("expected disposable domains to be detected")
+	}
+	if IsDisposableEmail("someone@example.com") || IsDisposableEmail("no-at-sign") {
+		t.Fatal("unexpected disposable match")
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Version is the release this binary was built from. Release builds set it with
+// -ldflags "-X github.com/yourusername/trough/services.Version=v1.2.3".
+var Version = "dev"
+
+var processStarted = time.Now()
+
+// BuildDetails identifies the running binary.
+type BuildDetails struct {
+	Version    string    `json:"version"`
+	Commit     string    `json:"commit,omitempty"`
+	CommitTime string    `json:"commit_time,omitempty"`
+	Modified   bool      `json:"modified,omitempty"`
+	GoVersion  string    `json:"go_version"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// BuildInfo returns the version set at link time plus the VCS stamp the Go toolchain
+// embeds when building from a git checkout.
+func BuildInfo() BuildDetails {
+	out := BuildDetails{Version: Version, GoVersion: runtime.Version(), StartedAt: processStarted}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				out.Commit = s.Value
+			case "vcs.time":
+				out.CommitTime = s.Value
+			case "vcs.modified":
+				out.Modified = s.Value == "true"
+			}
+		}
+	}
+	return out
+}
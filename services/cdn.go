@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// CDN providers with a purge API.
+const (
+	CDNCloudflare = "cloudflare"
+	CDNBunny      = "bunny"
+)
+
+// Cache classes for CacheControl.
+const (
+	// CacheUploads is stored media: keys are never reused, so it is immutable.
+	CacheUploads = "uploads"
+	// CacheAssets is the site's JS, CSS and images under ./static.
+	CacheAssets = "assets"
+)
+
+const (
+	// cloudflarePurgeBatch is the most URLs Cloudflare accepts in one purge request.
+	cloudflarePurgeBatch = 30
+	cdnPurgeTimeout      = 30 * time.Second
+	defaultCacheMaxAge   = 365 * 24 * time.Hour
+)
+
+var (
+	cdnMu  sync.RWMutex
+	cdnCfg = CDNConfig{AssetsMaxAge: defaultCacheMaxAge, UploadsMaxAge: defaultCacheMaxAge}
+	// cdnEndpoints points purgers at test servers
+	cdnEndpoints = map[string]string{
+		CDNCloudflare: "https://api.cloudflare.com/client/v4",
+		CDNBunny:      "https://api.bunny.net",
+	}
+)
+
+// SetCDN configures cache headers and purging; ApplyConfig calls it at startup.
+func SetCDN(cfg CDNConfig) {
+	cdnMu.Lock()
+	cdnCfg = cfg
+	cdnMu.Unlock()
+}
+
+func cdnConfig() CDNConfig {
+	cdnMu.RLock()
+	defer cdnMu.RUnlock()
+	return cdnCfg
+}
+
+// CDNPurgeEnabled reports whether changed URLs are purged from a CDN.
+func CDNPurgeEnabled() bool {
+	return cdnConfig().Provider != ""
+}
+
+// CacheMaxAge is how long browsers may keep responses of class, in seconds.
+func CacheMaxAge(class string) int {
+	cfg := cdnConfig()
+	if class == CacheUploads {
+		return int(cfg.UploadsMaxAge / time.Second)
+	}
+	return int(cfg.AssetsMaxAge / time.Second)
+}
+
+// CacheControl is the Cache-Control value for responses of class. With edge_max_age set,
+// shared caches get their own s-maxage, so a CDN that is purged on change can hold
+// assets longer than browsers, which cannot be purged.
+func CacheControl(class string) string {
+	cfg := cdnConfig()
+	v := "public, max-age=" + strconv.Itoa(CacheMaxAge(class))
+	if cfg.EdgeMaxAge > 0 {
+		v += ", s-maxage=" + strconv.Itoa(int(cfg.EdgeMaxAge/time.Second))
+	}
+	if class == CacheUploads {
+		v += ", immutable"
+	}
+	return v
+}
+
+// ImagePurgeURLs lists the URLs under base that show img: its page and API resource, and
+// with files its stored files too. Files on remote storage are listed at their stored URL.
+func ImagePurgeURLs(base string, img *models.Image, files bool) []string {
+	base = strings.TrimRight(base, "/")
+	id := img.ID.String()
+	urls := []string{base + "/i/" + id, base + "/api/images/" + id}
+	if !files {
+		return urls
+	}
+	for _, ref := range []string{img.Filename, derefString(img.PosterFilename)} {
+		if ref == "" {
+			continue
+		}
+		if strings.Contains(ref, "://") {
+			urls = append(urls, ref)
+		} else if key := StorageKeyFromRef(ref); key != "" {
+			urls = append(urls, base+"/uploads/"+key)
+		}
+	}
+	return urls
+}
+
+// PagePurgeURLs lists the URLs under base that show the page at slug. Slugs are limited
+// to [a-z0-9-/], so they need no escaping.
+func PagePurgeURLs(base, slug string) []string {
+	base = strings.TrimRight(base, "/")
+	slug = strings.Trim(slug, "/")
+	return []string{base + "/" + slug, base + "/api/pages/" + slug}
+}
+
+type cdnPurgePayload struct {
+	URLs []string `json:"urls"`
+}
+
+// PurgeCDN asks the configured CDN to drop urls. It returns at once: the purge runs as a
+// job, so failures are retried, or in the background when the queue is not running.
+func PurgeCDN(urls ...string) {
+	if !CDNPurgeEnabled() || len(urls) == 0 {
+		return
+	}
+	_, _, err := EnqueueJob(JobCDNPurge, cdnPurgePayload{URLs: urls}, JobOptions{})
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, ErrJobsUnavailable) {
+		Logger(context.Background()).Warn("cdn: queue purge failed", "error", err)
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cdnPurgeTimeout)
+		defer cancel()
+		if err := purgeCDNURLs(ctx, cdnConfig(), urls); err != nil {
+			Logger(ctx).Warn("cdn: purge failed", "error", err, "urls", len(urls))
+		}
+	}()
+}
+
+// purgeCDNURLs purges urls through cfg's provider.
+func purgeCDNURLs(ctx context.Context, cfg CDNConfig, urls []string) error {
+	cdnMu.RLock()
+	endpoint := cdnEndpoints[cfg.Provider]
+	cdnMu.RUnlock()
+	client := &http.Client{Timeout: cdnPurgeTimeout}
+	switch cfg.Provider {
+	case CDNCloudflare:
+		for i := 0; i < len(urls); i += cloudflarePurgeBatch {
+			end := i + cloudflarePurgeBatch
+			if end > len(urls) {
+				end = len(urls)
+			}
+			if err := purgeCloudflare(ctx, client, endpoint, cfg, urls[i:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CDNBunny:
+		for _, u := range urls {
+			if err := purgeBunny(ctx, client, endpoint, cfg, u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown CDN provider %q", cfg.Provider)
+}
+
+func purgeCloudflare(ctx context.Context, client *http.Client, endpoint string, cfg CDNConfig, urls []string) error {
+	body, _ := json.Marshal(map[string][]string{"files": urls})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/zones/"+url.PathEscape(cfg.ZoneID)+"/purge_cache", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out)
+	if resp.StatusCode/100 != 2 || !out.Success {
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("cloudflare purge: %s (HTTP %d)", out.Errors[0].Message, resp.StatusCode)
+		}
+		return fmt.Errorf("cloudflare purge: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func purgeBunny(ctx context.Context, client *http.Client, endpoint string, cfg CDNConfig, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/purge?url="+url.QueryEscape(u), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("AccessKey", cfg.APIToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("bunny purge %s: HTTP %d", u, resp.StatusCode)
+	}
+	return nil
+}
+
+// RegisterCDNPurgeJob registers the purge jobs PurgeCDN queues; failed purges are retried
+// with the queue's backoff.
+func RegisterCDNPurgeJob() {
+	RegisterJob(JobSpec{
+		Kind:        JobCDNPurge,
+		Timeout:     2 * time.Minute,
+		MaxAttempts: 5,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var p cdnPurgePayload
+			if err := json.Unmarshal(job.Payload, &p); err != nil {
+				return nil, PermanentJobError(err)
+			}
+			cfg := cdnConfig()
+			if cfg.Provider == "" {
+				return nil, nil
+			}
+			return nil, purgeCDNURLs(ctx, cfg, p.URLs)
+		},
+	})
+}
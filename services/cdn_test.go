@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+func withCDNEndpoint(t *testing.T, provider, url string) {
+	t.Helper()
+	cdnMu.Lock()
+	prev := cdnEndpoints[provider]
+	cdnEndpoints[provider] = url
+	cdnMu.Unlock()
+	t.Cleanup(func() {
+		cdnMu.Lock()
+		cdnEndpoints[provider] = prev
+		cdnMu.Unlock()
+	})
+}
+
+func TestCacheControl(t *testing.T) {
+	prev := cdnConfig()
+	t.Cleanup(func() { SetCDN(prev) })
+
+	SetCDN(DefaultConfig().CDN)
+	if got := CacheControl(CacheUploads); got != "public, max-age=31536000, immutable" {
+		t.Fatalf("uploads = %q", got)
+	}
+	if got := CacheControl(CacheAssets); got != "public, max-age=31536000" {
+		t.Fatalf("assets = %q", got)
+	}
+	SetCDN(CDNConfig{AssetsMaxAge: time.Hour, UploadsMaxAge: 24 * time.Hour, EdgeMaxAge: 7 * 24 * time.Hour})
+	if got := CacheControl(CacheAssets); got != "public, max-age=3600, s-maxage=604800" {
+		t.Fatalf("assets with edge = %q", got)
+	}
+	if got := CacheMaxAge(CacheUploads); got != 86400 {
+		t.Fatalf("uploads max age = %d", got)
+	}
+}
+
+func TestImagePurgeURLs(t *testing.T) {
+	id := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	poster := "https://media.example.com/p.jpg"
+	img := &models.Image{ID: id, Filename: "/uploads/a.mp4", PosterFilename: &poster}
+	got := ImagePurgeURLs("https://example.com/", img, true)
+	want := []string{
+		"https://example.com/i/" + id.String(),
+		"https://example.com/api/images/" + id.String(),
+		"https://example.com/uploads/a.mp4",
+		poster,
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := ImagePurgeURLs("https://example.com", img, false); len(got) != 2 {
+		t.Fatalf("without files got %v", got)
+	}
+	if got := PagePurgeURLs("https://example.com", "/about/team"); strings.Join(got, " ") != "https://example.com/about/team https://example.com/api/pages/about/team" {
+		t.Fatalf("page urls %v", got)
+	}
+}
+
+func TestPurgeCloudflareBatches(t *testing.T) {
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/zones/zone1/purge_cache" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Files []string `json:"files"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, body.Files)
+		w.Write([]byte(`{"success":true,"errors":[]}`))
+	}))
+	defer srv.Close()
+	withCDNEndpoint(t, CDNCloudflare, srv.URL)
+
+	urls := make([]string, 65)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://example.com/i/%d", i)
+	}
+	cfg := CDNConfig{Provider: CDNCloudflare, APIToken: "tok", ZoneID: "zone1"}
+	if err := purgeCDNURLs(context.Background(), cfg, urls); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 3 || len(batches[0]) != 30 || len(batches[2]) != 5 {
+		t.Fatalf("batches = %d (%v)", len(batches), batches)
+	}
+}
+
+func TestPurgeCloudflareError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`))
+	}))
+	defer srv.Close()
+	withCDNEndpoint(t, CDNCloudflare, srv.URL)
+
+	err := purgeCDNURLs(context.Background(), CDNConfig{Provider: CDNCloudflare, APIToken: "bad", ZoneID: "z"}, []string{"https://example.com/x"})
+	if err == nil || !strings.Contains(err.Error(), "Authentication error") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestPurgeBunny(t *testing.T) {
+	var purged []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/purge" || r.Header.Get("AccessKey") != "key" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		purged = append(purged, r.URL.Query().Get("url"))
+	}))
+	defer srv.Close()
+	withCDNEndpoint(t, CDNBunny, srv.URL)
+
+	urls := []string{"https://example.com/i/1?x=1", "https://example.com/uploads/a.jpg"}
+	if err := purgeCDNURLs(context.Background(), CDNConfig{Provider: CDNBunny, APIToken: "key"}, urls); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(purged, " ") != strings.Join(urls, " ") {
+		t.Fatalf("purged %v", purged)
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// Challenge providers selectable in site settings; an empty provider disables challenges.
+const (
+	ChallengePoW       = "pow"
+	ChallengeHCaptcha  = "hcaptcha"
+	ChallengeTurnstile = "turnstile"
+)
+
+// PoWDifficulty is the number of leading zero bits a proof-of-work solution must produce,
+// about a second of hashing in a browser.
+const PoWDifficulty = 16
+
+const powChallengeTTL = 5 * time.Minute
+
+// ErrChallengeFailed is returned when a challenge response is missing, wrong or reused.
+var ErrChallengeFailed = errors.New("challenge verification failed")
+
+var (
+	hcaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	challengeClient    = &http.Client{Timeout: 10 * time.Second}
+
+	// Solved proof-of-work challenges until they expire, so each can be used once
+	powUsedMu sync.Mutex
+	powUsed   = map[string]time.Time{}
+)
+
+// ValidChallengeProvider reports whether p names a supported provider ("" included).
+func ValidChallengeProvider(p string) bool {
+	switch p {
+	case "", ChallengePoW, ChallengeHCaptcha, ChallengeTurnstile:
+		return true
+	}
+	return false
+}
+
+// NewPoWChallenge issues a stateless proof-of-work challenge: random bytes and an expiry,
+// signed with a key derived from JWT_SECRET so the server need not remember it.
+func NewPoWChallenge() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	payload := base64.RawURLEncoding.EncodeToString(b) + "." + strconv.FormatInt(time.Now().Add(powChallengeTTL).Unix(), 10)
+	return payload + "." + powSign(payload)
+}
+
+func powSign(payload string) string {
+	mac := hmac.New(sha256.New, []byte("trough-pow:"+os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// powLeadingZeros counts the leading zero bits of SHA-256(challenge + ":" + solution).
+func powLeadingZeros(challenge, solution string) int {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// VerifyPoW checks a solution to a challenge from NewPoWChallenge. A challenge is accepted
+// once; replays and expired or tampered challenges fail.
+func VerifyPoW(challenge, solution string) error {
+	if len(solution) == 0 || len(solution) > 32 {
+		return ErrChallengeFailed
+	}
+	i := strings.LastIndexByte(challenge, '.')
+	if i < 0 || !hmac.Equal([]byte(challenge[i+1:]), []byte(powSign(challenge[:i]))) {
+		return ErrChallengeFailed
+	}
+	_, expStr, _ := strings.Cut(challenge[:i], ".")
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	now := time.Now()
+	if err != nil || now.Unix() > exp {
+		return ErrChallengeFailed
+	}
+	if powLeadingZeros(challenge, solution) < PoWDifficulty {
+		return ErrChallengeFailed
+	}
+	powUsedMu.Lock()
+	defer powUsedMu.Unlock()
+	for k, until := range powUsed {
+		if now.After(until) {
+			delete(powUsed, k)
+		}
+	}
+	if _, seen := powUsed[challenge]; seen {
+		return ErrChallengeFailed
+	}
+	powUsed[challenge] = time.Unix(exp, 0)
+	return nil
+}
+
+// VerifyCaptcha asks hCaptcha or Turnstile whether token is a valid widget response. Both
+// share the siteverify form API; an unreachable service counts as a failure.
+func VerifyCaptcha(ctx context.Context, provider, secret, token, remoteIP string) error {
+	endpoint := hcaptchaVerifyURL
+	if provider == ChallengeTurnstile {
+		endpoint = turnstileVerifyURL
+	}
+	if secret == "" || token == "" || len(token) > 4096 {
+		return ErrChallengeFailed
+	}
+	form := url.Values{"secret": {secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := challengeClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s siteverify: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("%s siteverify: %w", provider, err)
+	}
+	if !out.Success {
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+// VerifyChallenge checks a challenge response against the provider configured in set.
+// For proof of work token is the challenge and solution the nonce; for captchas token is
+// the widget response.
+func VerifyChallenge(ctx context.Context, set models.SiteSettings, token, solution, remoteIP string) error {
+	switch provider := set.ChallengeProvider; provider {
+	case "":
+		return nil
+	case ChallengePoW:
+		return VerifyPoW(token, solution)
+	case ChallengeHCaptcha, ChallengeTurnstile:
+		return VerifyCaptcha(ctx, provider, set.ChallengeSecretKey, token, remoteIP)
+	default:
+		return ErrChallengeFailed
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func solvePoWForTest(challenge string) string {
+	for n := 0; ; n++ {
+		if s := strconv.Itoa(n); powLeadingZeros(challenge, s) >= PoWDifficulty {
+			return s
+		}
+	}
+}
+
+func TestVerifyPoW(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	ch := NewPoWChallenge()
+	sol := solvePoWForTest(ch)
+	if err := VerifyPoW(ch, sol); err != nil {
+		t.Fatalf("valid solution rejected: %v", err)
+	}
+	if err := VerifyPoW(ch, sol); err == nil {
+		t.Fatal("a challenge must only be accepted once")
+	}
+
+	ch = NewPoWChallenge()
+	if err := VerifyPoW(ch, ""); err == nil {
+		t.Fatal("an empty solution must fail")
+	}
+	// Tampering with the expiry breaks the signature
+	parts := strings.Split(ch, ".")
+	forged := parts[0] + "." + strconv.FormatInt(9999999999, 10) + "." + parts[2]
+	if err := VerifyPoW(forged, solvePoWForTest(forged)); err == nil {
+		t.Fatal("a forged challenge must fail")
+	}
+	// A signed but expired challenge fails too
+	payload := parts[0] + ".1"
+	expired := payload + "." + powSign(payload)
+	if err := VerifyPoW(expired, solvePoWForTest(expired)); err == nil {
+		t.Fatal("an expired challenge must fail")
+	}
+}
+
+func TestVerifyCaptcha(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("secret") != "sekrit" || r.PostForm.Get("remoteip") != "10.0.0.1" {
+			t.Errorf("unexpected form: %v", r.PostForm)
+		}
+		_, _ = w.Write([]byte(`{"success":` + strconv.FormatBool(r.PostForm.Get("response") == "good") + `}`))
+	}))
+	defer srv.Close()
+	prev := turnstileVerifyURL
+	turnstileVerifyURL = srv.URL
+	defer func() { turnstileVerifyURL = prev }()
+
+	ctx := context.Background()
+	if err := VerifyCaptcha(ctx, ChallengeTurnstile, "sekrit", "good", "10.0.0.1"); err != nil {
+		t.Fatalf("good token rejected: %v", err)
+	}
+	if err := VerifyCaptcha(ctx, ChallengeTurnstile, "sekrit", "bad", "10.0.0.1"); err != ErrChallengeFailed {
+		t.Fatalf("bad token: got %v", err)
+	}
+	if err := VerifyCaptcha(ctx, ChallengeTurnstile, "sekrit", "", "10.0.0.1"); err != ErrChallengeFailed {
+		t.Fatalf("missing token: got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+// ChecksumSHA256 returns the hex SHA-256 of b.
+func ChecksumSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChecksumSHA256Reader returns the hex SHA-256 of everything read from r.
+func ChecksumSHA256Reader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChecksumVerifyOptions is the payload of an images.verify_checksums job.
+type ChecksumVerifyOptions struct {
+	// FillMissing records the current hash of images stored before checksums existed.
+	// Their files are trusted as they are, so only enable it on storage believed intact.
+	FillMissing bool `json:"fill_missing,omitempty"`
+}
+
+// ChecksumMismatch is an image whose stored file no longer hashes to its checksum.
+type ChecksumMismatch struct {
+	ID       string `json:"id"`
+	Key      string `json:"key"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// ChecksumVerifyResult is the progress and final result of an images.verify_checksums job.
+// Mismatched counts every mismatch; Mismatches lists the first ones.
+type ChecksumVerifyResult struct {
+	Options    ChecksumVerifyOptions `json:"options"`
+	Total      int                   `json:"total"`
+	Processed  int                   `json:"processed"`
+	Verified   int                   `json:"verified"`
+	Mismatched int                   `json:"mismatched"`
+	Filled     int                   `json:"filled"`
+	Unhashed   int                   `json:"unhashed"`
+	Failed     int                   `json:"failed"`
+	Mismatches []ChecksumMismatch    `json:"mismatches"`
+	Errors     []string              `json:"errors"`
+}
+
+func (r *ChecksumVerifyResult) addError(msg string) {
+	r.Failed++
+	if len(r.Errors) < backfillErrorCap {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+type checksumRow struct {
+	ID       uuid.UUID `db:"id"`
+	Filename string    `db:"filename"`
+	SHA256   *string   `db:"sha256"`
+}
+
+// VerifyImageChecksums re-hashes the stored file of every image and compares it with the
+// checksum recorded on upload. Mismatches are flagged on the row (checksum_mismatch_at)
+// and listed in the result; a later matching pass clears the flag. Files that cannot be
+// read count as failures, not mismatches, so a storage outage flags nothing.
+func VerifyImageChecksums(ctx context.Context, db *sqlx.DB, st Storage, o ChecksumVerifyOptions) (*ChecksumVerifyResult, error) {
+	if db == nil || st == nil {
+		return nil, fmt.Errorf("storage or database not configured")
+	}
+	reader, ok := st.(ObjectReader)
+	if !ok {
+		return nil, errors.New("storage does not support reading objects")
+	}
+	result := &ChecksumVerifyResult{Options: o, Mismatches: []ChecksumMismatch{}, Errors: []string{}}
+	if err := db.GetContext(ctx, &result.Total, `SELECT COUNT(*) FROM images`); err != nil {
+		return nil, err
+	}
+	ReportJobProgress(ctx, result)
+
+	var (
+		after      uuid.UUID
+		lastReport time.Time
+	)
+	for {
+		var rows []checksumRow
+		if err := db.SelectContext(ctx, &rows, `SELECT id, filename, sha256 FROM images WHERE id > $1 ORDER BY id LIMIT $2`, after, backfillBatch); err != nil {
+			return result, err
+		}
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			after = row.ID
+			result.Processed++
+			if err := verifyOne(ctx, db, reader, row, o, result); err != nil {
+				result.addError(fmt.Sprintf("%s: %v", row.ID, err))
+			}
+			if time.Since(lastReport) >= time.Second {
+				lastReport = time.Now()
+				ReportJobProgress(ctx, result)
+			}
+		}
+		if len(rows) < backfillBatch {
+			break
+		}
+	}
+	return result, nil
+}
+
+func verifyOne(ctx context.Context, db *sqlx.DB, reader ObjectReader, row checksumRow, o ChecksumVerifyOptions, result *ChecksumVerifyResult) error {
+	expected := derefString(row.SHA256)
+	if expected == "" && !o.FillMissing {
+		result.Unhashed++
+		return nil
+	}
+	key := StorageKeyFromRef(row.Filename)
+	rc, err := reader.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	actual, err := ChecksumSHA256Reader(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	switch {
+	case expected == "":
+		_, err = db.ExecContext(ctx, `UPDATE images SET sha256 = $2 WHERE id = $1 AND sha256 IS NULL`, row.ID, actual)
+		if err == nil {
+			result.Filled++
+		}
+	case expected == actual:
+		_, err = db.ExecContext(ctx, `UPDATE images SET checksum_mismatch_at = NULL WHERE id = $1 AND checksum_mismatch_at IS NOT NULL`, row.ID)
+		if err == nil {
+			result.Verified++
+		}
+	default:
+		_, err = db.ExecContext(ctx, `UPDATE images SET checksum_mismatch_at = COALESCE(checksum_mismatch_at, NOW()) WHERE id = $1`, row.ID)
+		result.Mismatched++
+		if len(result.Mismatches) < reconcileListCap {
+			result.Mismatches = append(result.Mismatches, ChecksumMismatch{ID: row.ID.String(), Key: key, Expected: expected, Actual: actual})
+		}
+		Logger(ctx).Warn("checksums: stored file does not match", "image_id", row.ID, "key", key)
+	}
+	return err
+}
+
+// RegisterChecksumVerifyJob registers admin-triggered checksum verification against the
+// storage in use when the job runs.
+func RegisterChecksumVerifyJob(db *sqlx.DB) {
+	RegisterJob(JobSpec{
+		Kind:        JobChecksumVerify,
+		Timeout:     6 * time.Hour,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var o ChecksumVerifyOptions
+			if err := json.Unmarshal(job.Payload, &o); err != nil {
+				return nil, PermanentJobError(err)
+			}
+			st := GetCurrentStorage()
+			if st == nil {
+				return nil, PermanentJobError(errors.New("storage not configured"))
+			}
+			if _, ok := st.(ObjectReader); !ok {
+				return nil, PermanentJobError(errors.New("storage does not support reading objects"))
+			}
+			return VerifyImageChecksums(ctx, db, st, o)
+		},
+	})
+}
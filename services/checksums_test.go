@@ -0,0 +1,17 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChecksumSHA256(t *testing.T) {
+	const abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := ChecksumSHA256([]byte("abc")); got != abc {
+		t.Fatalf("ChecksumSHA256 = %s", got)
+	}
+	got, err := ChecksumSHA256Reader(strings.NewReader("abc"))
+	if err != nil || got != abc {
+		t.Fatalf("ChecksumSHA256Reader = %s, %v", got, err)
+	}
+}
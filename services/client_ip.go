@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Client IPs are resolved in one place for rate limiting, bans, audit entries and logs.
+// Forwarding headers are only believed when the connection comes from a trusted proxy
+// (server.trusted_proxies). X-Forwarded-For is read right to left, skipping trusted hops,
+// so a client cannot pick its own address by sending the header itself, and chains of
+// proxies (CDN → load balancer → app) resolve to the first untrusted hop.
+
+// ClientIPResolver picks the client address for a request.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver trusts the given proxy addresses and CIDR ranges.
+func NewClientIPResolver(proxies []string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{}
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", p)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q", p)
+		}
+		r.trusted = append(r.trusted, n)
+	}
+	return r, nil
+}
+
+// Trusted reports whether ip belongs to a trusted proxy.
+func (r *ClientIPResolver) Trusted(ip net.IP) bool {
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client address for a connection from peer carrying the given
+// X-Forwarded-For and X-Real-IP values. X-Real-IP is only used when a trusted peer sent
+// no X-Forwarded-For. An unparseable hop ends the walk at the trusted hop after it.
+func (r *ClientIPResolver) Resolve(peer net.IP, forwardedFor, realIP string) string {
+	if peer == nil {
+		return ""
+	}
+	if !r.Trusted(peer) {
+		return peer.String()
+	}
+	if strings.TrimSpace(forwardedFor) == "" {
+		if ip := parseHop(realIP); ip != nil {
+			return ip.String()
+		}
+		return peer.String()
+	}
+	cur := peer
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHop(hops[i])
+		if ip == nil {
+			break
+		}
+		cur = ip
+		if !r.Trusted(ip) {
+			break
+		}
+	}
+	return cur.String()
+}
+
+// parseHop parses one forwarding header entry, which some proxies send with a port.
+func parseHop(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
+
+var clientIPResolver atomic.Pointer[ClientIPResolver]
+
+func init() {
+	r, _ := NewClientIPResolver([]string{"127.0.0.1", "::1"})
+	clientIPResolver.Store(r)
+}
+
+// SetTrustedProxies replaces the process-wide trusted proxy list.
+func SetTrustedProxies(proxies []string) error {
+	r, err := NewClientIPResolver(proxies)
+	if err != nil {
+		return err
+	}
+	clientIPResolver.Store(r)
+	return nil
+}
+
+const clientIPLocalsKey = "client_ip"
+
+// ClientIP returns the resolved client address of the request, or "" if the peer address
+// is unknown. The result is cached on the request.
+func ClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPLocalsKey).(string); ok {
+		return ip
+	}
+	ip := clientIPResolver.Load().Resolve(c.Context().RemoteIP(), c.Get(fiber.HeaderXForwardedFor), c.Get("X-Real-IP"))
+	c.Locals(clientIPLocalsKey, ip)
+	return ip
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"net"
+	"testing"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	r, err := NewClientIPResolver([]string{"127.0.0.1", "10.0.0.0/8", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name, peer, xff, realIP, want string
+	}{
+		{"untrusted peer ignores headers", "198.51.100.7", "203.0.113.1", "203.0.113.2", "198.51.100.7"},
+		{"trusted peer, single hop", "127.0.0.1", "203.0.113.1", "", "203.0.113.1"},
+		{"client-supplied entry is skipped", "127.0.0.1", "1.2.3.4, 203.0.113.1", "", "203.0.113.1"},
+		{"multi-hop chain of trusted proxies", "127.0.0.1", "1.2.3.4, 203.0.113.1, 10.1.2.3, 10.0.0.9", "", "203.0.113.1"},
+		{"all hops trusted", "127.0.0.1", "10.0.0.5, 10.0.0.6", "", "10.0.0.5"},
+		{"garbage stops at the last trusted hop", "127.0.0.1", "203.0.113.1, junk, 10.0.0.6", "", "10.0.0.6"},
+		{"ports and IPv6", "2001:db8::1", "[2001:db8::2]:443, 203.0.113.1:5555", "", "203.0.113.1"},
+		{"X-Real-IP from trusted peer", "127.0.0.1", "", "203.0.113.9", "203.0.113.9"},
+		{"no headers", "127.0.0.1", "", "", "127.0.0.1"},
+	}
+	for _, tc := range cases {
+		if got := r.Resolve(net.ParseIP(tc.peer), tc.xff, tc.realIP); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := NewClientIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("expected an invalid proxy to be rejected")
+	}
+	if _, err := NewClientIPResolver([]string{"10.0.0.0/99"}); err == nil {
+		t.Error("expected an invalid range to be rejected")
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// maxComfyWorkflow bounds the workflow JSON kept from one upload.
+const maxComfyWorkflow = 4 << 20
+
+// pngTextChunks returns the text of a PNG's tEXt, zTXt and iTXt chunks by keyword, keeping
+// the first of each and at most maxComfyWorkflow bytes of text in all. It is nil for
+// anything but a PNG.
+func pngTextChunks(b []byte) map[string][]byte {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil
+	}
+	out := map[string][]byte{}
+	budget := maxComfyWorkflow
+	for p := len(pngSignature); p+12 <= len(b); {
+		n := int(binary.BigEndian.Uint32(b[p : p+4]))
+		typ := string(b[p+4 : p+8])
+		if n < 0 || p+12+n > len(b) || typ == "IEND" {
+			break
+		}
+		data := b[p+8 : p+8+n]
+		p += 12 + n
+		var text []byte
+		switch typ {
+		case "tEXt":
+			text = data
+		case "zTXt", "iTXt":
+			text = pngText(typ, data, &budget)
+		default:
+			continue
+		}
+		kw := bytes.IndexByte(text, 0)
+		if kw < 0 || len(text)-kw-1 > maxComfyWorkflow {
+			continue
+		}
+		if _, seen := out[string(text[:kw])]; !seen {
+			out[string(text[:kw])] = bytes.TrimSpace(text[kw+1:])
+		}
+	}
+	return out
+}
+
+// ExtractComfyWorkflow returns the ComfyUI graph embedded in a PNG's text chunks. ComfyUI
+// writes the editor graph under the "workflow" keyword and the API-format prompt under
+// "prompt"; the graph is preferred since it loads back into the editor as-is. Text that is
+// not JSON shaped like either is ignored, so other tools' "prompt" chunks don't count.
+func ExtractComfyWorkflow(b []byte) ([]byte, bool) {
+	chunks := pngTextChunks(b)
+	if v := chunks["workflow"]; isComfyGraph(v) {
+		return v, true
+	}
+	if v := chunks["prompt"]; isComfyPrompt(v) {
+		return v, true
+	}
+	return nil, false
+}
+
+// comfyGraph is the part of an editor workflow that names its nodes and their widget values.
+type comfyGraph struct {
+	Nodes []struct {
+		Type          string            `json:"type"`
+		WidgetsValues []json.RawMessage `json:"widgets_values"`
+	} `json:"nodes"`
+}
+
+// comfyPrompt is an API-format prompt: node ids mapped to their class and inputs.
+type comfyPrompt map[string]struct {
+	ClassType string                     `json:"class_type"`
+	Inputs    map[string]json.RawMessage `json:"inputs"`
+}
+
+// isComfyGraph reports whether v is an editor workflow: an object with a nodes array.
+func isComfyGraph(v []byte) bool {
+	var g comfyGraph
+	return len(v) > 0 && json.Unmarshal(v, &g) == nil && len(g.Nodes) > 0
+}
+
+// isComfyPrompt reports whether v is an API-format prompt: node ids mapped to objects
+// naming their class_type.
+func isComfyPrompt(v []byte) bool {
+	var nodes comfyPrompt
+	if len(v) == 0 || json.Unmarshal(v, &nodes) != nil || len(nodes) == 0 {
+		return false
+	}
+	for _, n := range nodes {
+		if n.ClassType == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func pngWithChunks(chunks ...[2]string) []byte {
+	b := append([]byte(nil), pngSignature...)
+	add := func(typ string, data []byte) {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+		b = append(b, n[:]...)
+		b = append(b, typ...)
+		b = append(b, data...)
+		binary.BigEndian.PutUint32(n[:], crc32.ChecksumIEEE(append([]byte(typ), data...)))
+		b = append(b, n[:]...)
+	}
+	add("IHDR", make([]byte, 13))
+	for _, c := range chunks {
+		add(c[0], []byte(c[1]))
+	}
+	add("IEND", nil)
+	return b
+}
+
+func zTXt(keyword, text string) string {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, _ = zw.Write([]byte(text))
+	_ = zw.Close()
+	return keyword + "\x00\x00" + z.String()
+}
+
+func TestExtractComfyWorkflow(t *testing.T) {
+	graph := `{"last_node_id":3,"nodes":[{"id":1,"type":"KSampler"}],"links":[]}`
+	prompt := `{"1":{"class_type":"KSampler","inputs":{"seed":7}}}`
+
+	got, ok := ExtractComfyWorkflow(pngWithChunks([2]string{"tEXt", "prompt\x00" + prompt}, [2]string{"zTXt", zTXt("workflow", graph)}))
+	if !ok || string(got) != graph {
+		t.Fatalf("expected the editor graph preferred over the prompt, got %q", got)
+	}
+	if got, ok := ExtractComfyWorkflow(pngWithChunks([2]string{"tEXt", "prompt\x00" + prompt})); !ok || string(got) != prompt {
+		t.Fatalf("expected the API prompt without a graph, got %q", got)
+	}
+	if _, ok := ExtractComfyWorkflow(pngWithChunks([2]string{"tEXt", "prompt\x00a koi pond at dusk"}, [2]string{"tEXt", "workflow\x00{}"})); ok {
+		t.Fatal("expected plain-text prompts and empty graphs ignored")
+	}
+	if _, ok := ExtractComfyWorkflow([]byte("\xff\xd8\xff" + graph)); ok {
+		t.Fatal("expected only PNGs read")
+	}
+}
@@ -2,7 +2,10 @@ package services
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -11,8 +14,150 @@ import (
 type Config struct {
 	AISignatures        []AISignature          `yaml:"ai_signatures"`
 	Aesthetic           Aesthetic              `yaml:"aesthetic"`
+	Animation           AnimationConfig        `yaml:"animation"`
+	Video               VideoConfig            `yaml:"video"`
 	RateLimiting        RateLimitConfig        `yaml:"rate_limiting"`
 	ProgressiveRateLimiting ProgressiveRateLimitConfig `yaml:"progressive_rate_limiting"`
+	Server              ServerConfig           `yaml:"server"`
+	Paths               PathsConfig            `yaml:"paths"`
+	Auth                AuthConfig             `yaml:"auth"`
+	Jobs                JobsConfig             `yaml:"jobs"`
+	SecurityEvents      SecurityEventsConfig   `yaml:"security_events"`
+	// RateLimitPolicies replace the built-in per-route limits when set
+	RateLimitPolicies   []RateLimitPolicy      `yaml:"rate_limit_policies"`
+	AnonymousReads      AnonymousReadsConfig   `yaml:"anonymous_reads"`
+	SignedURLs          SignedURLsConfig       `yaml:"signed_urls"`
+	CDN                 CDNConfig              `yaml:"cdn"`
+	Verification        VerificationConfig     `yaml:"verification"`
+	Avatars             AvatarsConfig          `yaml:"avatars"`
+}
+
+// ServerConfig holds the listener and HTTP server limits. Env overrides: BIND_ADDRESS,
+// PORT, BODY_LIMIT_MB, READ_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT, SHUTDOWN_TIMEOUT, PREFORK,
+// TRUSTED_PROXIES, PPROF_ENABLED, and the TLS_* / ACME_* variables for TLS.
+type ServerConfig struct {
+	BindAddress     string        `yaml:"bind_address"`
+	Port            int           `yaml:"port"`
+	Prefork         bool          `yaml:"prefork"`
+	TLS             TLSConfig     `yaml:"tls"`
+	BodyLimitMB     int           `yaml:"body_limit_mb"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TrustedProxies lists proxy addresses or CIDR ranges whose X-Forwarded-For,
+	// X-Forwarded-Proto and X-Forwarded-Host headers are believed
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// Pprof mounts the Go profiler under /api/admin/debug/pprof for admins (PPROF_ENABLED)
+	Pprof bool `yaml:"pprof"`
+}
+
+// TLSConfig enables built-in HTTPS, either from certificate files or from Let's Encrypt
+// via ACME. RedirectAddress (e.g. ":80") serves plain-HTTP redirects to HTTPS and, with
+// ACME, the HTTP-01 challenge.
+type TLSConfig struct {
+	CertFile        string   `yaml:"cert_file"`
+	KeyFile         string   `yaml:"key_file"`
+	ACMEDomains     []string `yaml:"acme_domains"`
+	ACMEEmail       string   `yaml:"acme_email"`
+	ACMECacheDir    string   `yaml:"acme_cache_dir"`
+	RedirectAddress string   `yaml:"redirect_address"`
+}
+
+// Enabled reports whether the server should terminate TLS itself.
+func (t TLSConfig) Enabled() bool { return t.CertFile != "" || len(t.ACMEDomains) > 0 }
+
+// ListenAddress is the host:port the server binds.
+func (s ServerConfig) ListenAddress() string {
+	return net.JoinHostPort(s.BindAddress, strconv.Itoa(s.Port))
+}
+
+// PathsConfig holds local directories. Env overrides: UPLOADS_DIR, BACKUP_DIR, STAGING_DIR.
+type PathsConfig struct {
+	UploadsDir string `yaml:"uploads_dir"`
+	BackupDir  string `yaml:"backup_dir"`
+	// StagingDir holds uploads waiting for background processing. It is never served;
+	// instances that share a job queue must share it, as they share uploads_dir.
+	StagingDir string `yaml:"staging_dir"`
+}
+
+// JobsConfig sizes the background job workers. Workers 0 runs none on this instance (it
+// still enqueues), for web-only replicas. Env override: JOB_WORKERS.
+type JobsConfig struct {
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// AuthConfig holds session and password settings. Env overrides: JWT_LIFETIME,
+// PASSWORD_BREACH_CHECK, PASSWORD_BREACH_URL.
+type AuthConfig struct {
+	JWTLifetime time.Duration     `yaml:"jwt_lifetime"`
+	BreachCheck BreachCheckConfig `yaml:"breach_check"`
+}
+
+// BreachCheckConfig refuses new passwords found in breach data, using a Pwned Passwords
+// range API. Off by default; the API only ever sees the first 5 characters of a hash.
+type BreachCheckConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+	// FailClosed refuses passwords while the API is unreachable instead of allowing them
+	FailClosed bool `yaml:"fail_closed"`
+}
+
+// SecurityEventsConfig sets how long persisted rate limiter security events are kept.
+// Low-severity events (successful sign-ins, lockout resets) are the bulk and expire
+// sooner. Env override: SECURITY_EVENT_RETENTION.
+type SecurityEventsConfig struct {
+	Retention    time.Duration `yaml:"retention"`
+	LowRetention time.Duration `yaml:"low_retention"`
+}
+
+// AnonymousReadsConfig limits signed-out GET requests to the feed and image endpoints per
+// client IP: at most Capacity per Window, and at most Burst per BurstWindow within that.
+// Crawlers matching an entry in Crawlers, confirmed by reverse and forward DNS, are
+// exempt. Env override: ANONYMOUS_READ_LIMITS (true/false).
+type AnonymousReadsConfig struct {
+	Enabled     bool            `yaml:"enabled"`
+	Capacity    int             `yaml:"capacity"`
+	Window      time.Duration   `yaml:"window"`
+	Burst       int             `yaml:"burst"`
+	BurstWindow time.Duration   `yaml:"burst_window"`
+	Crawlers    []CrawlerConfig `yaml:"crawlers"`
+}
+
+// SignedURLsConfig makes image files under /uploads require an expiring HMAC token in
+// the query, so other sites cannot hotlink the masters. API responses carry signed
+// links; tokens stay the same for a TTL window so browsers and CDNs can cache them.
+// Secret defaults to one derived from JWT_SECRET; set it to share it with a CDN that
+// checks tokens itself. Env overrides: SIGNED_URLS (true/false), SIGNED_URLS_TTL,
+// SIGNED_URLS_SECRET.
+type SignedURLsConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"`
+	Secret  string        `yaml:"secret"`
+}
+
+// CDNConfig sets the Cache-Control headers of static assets and stored media, and the CDN
+// whose cache is purged when an image or page changes. Provider is "", "cloudflare" or
+// "bunny"; Cloudflare needs ZoneID and an API token with cache purge permission, Bunny
+// an account API key. EdgeMaxAge adds an s-maxage for shared caches. Env overrides:
+// CDN_PROVIDER, CDN_API_TOKEN, CDN_ZONE_ID.
+type CDNConfig struct {
+	Provider      string        `yaml:"provider"`
+	APIToken      string        `yaml:"api_token"`
+	ZoneID        string        `yaml:"zone_id"`
+	AssetsMaxAge  time.Duration `yaml:"assets_max_age"`
+	UploadsMaxAge time.Duration `yaml:"uploads_max_age"`
+	EdgeMaxAge    time.Duration `yaml:"edge_max_age"`
+}
+
+// CrawlerConfig identifies a search engine crawler: a User-Agent substring, and the
+// domains its addresses reverse-resolve into.
+type CrawlerConfig struct {
+	Name      string   `yaml:"name"`
+	UserAgent string   `yaml:"user_agent"`
+	Domains   []string `yaml:"domains"`
 }
 
 type AISignature struct {
@@ -28,54 +173,395 @@ type Aesthetic struct {
 	Formats          []string `yaml:"formats"`
 }
 
-func LoadConfig(path string) (*Config, error) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &Config{
-			AISignatures: []AISignature{
-				{
-					Key:   "DigitalSourceType",
-					Value: "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia",
-				},
-				{
-					Key:      "Software",
-					Contains: []string{"Midjourney", "DALL-E", "Stable Diffusion", "Flux"},
-				},
-			},
-			Aesthetic: Aesthetic{
-				BlurRadius:       20,
-				ThumbnailQuality: 85,
-				MaxWidth:         2048,
-				Formats:          []string{".jpg", ".jpeg", ".png", ".webp"},
-			},
-			RateLimiting: RateLimitConfig{
-				MaxEntries:      1000,
-				CleanupInterval: 1 * time.Minute,
-				EntryTTL:        30 * time.Minute,
-				TrustedProxies:  []string{"127.0.0.1", "::1"},
-				EnableDebug:     false,
+// AnimationConfig bounds animated uploads (GIF, APNG, animated WebP), which are stored
+// without re-encoding.
+type AnimationConfig struct {
+	MaxFrames   int           `yaml:"max_frames"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// VideoConfig enables MP4/WebM uploads. ffprobe and ffmpeg must be on PATH (or given
+// here); without them video uploads are refused. server.body_limit_mb must be at least
+// max_size_mb for large clips to reach the handler.
+type VideoConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	FFprobePath string        `yaml:"ffprobe_path"`
+	FFmpegPath  string        `yaml:"ffmpeg_path"`
+	MaxSizeMB   int           `yaml:"max_size_mb"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// DefaultConfig returns the built-in configuration used when config.yaml is missing and
+// as the base that config.yaml and the environment override.
+func DefaultConfig() *Config {
+	return &Config{
+		AISignatures: []AISignature{
+			{
+				Key:   "DigitalSourceType",
+				Value: "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia",
 			},
-			ProgressiveRateLimiting: ProgressiveRateLimitConfig{
-				BaseWindow:      1 * time.Minute,
-				MaxWindow:       1 * time.Hour,
-				BaseCapacity:    60,
-				MinCapacity:     5,
-				BackoffFactor:   2.0,
-				LockoutThreshold: 10,
-				LockoutDuration: 15 * time.Minute,
-				EnableLogging:   true,
+			{
+				Key:      "Software",
+				Contains: []string{"Midjourney", "DALL-E", "Stable Diffusion", "Flux"},
 			},
-		}, nil
+		},
+		Aesthetic: Aesthetic{
+			BlurRadius:       20,
+			ThumbnailQuality: 85,
+			MaxWidth:         2048,
+			Formats:          []string{".jpg", ".jpeg", ".png", ".webp"},
+		},
+		Animation: AnimationConfig{MaxFrames: 600, MaxDuration: 60 * time.Second},
+		Video:     VideoConfig{Enabled: true, MaxSizeMB: 50, MaxDuration: 60 * time.Second},
+		Jobs:      JobsConfig{Workers: 2, PollInterval: 5 * time.Second},
+		SecurityEvents: SecurityEventsConfig{Retention: 90 * 24 * time.Hour, LowRetention: 14 * 24 * time.Hour},
+		RateLimitPolicies: DefaultRateLimitPolicies(),
+		AnonymousReads: AnonymousReadsConfig{
+			Enabled:     true,
+			Capacity:    300,
+			Window:      5 * time.Minute,
+			Burst:       30,
+			BurstWindow: 10 * time.Second,
+			Crawlers:    DefaultCrawlers(),
+		},
+		SignedURLs: SignedURLsConfig{TTL: 6 * time.Hour},
+		Avatars:    AvatarsConfig{Size: 256, Format: AvatarFormatWebP, Quality: 90, AnimatedMaxFrames: 60, AnimatedMaxKB: 1024},
+		CDN:        CDNConfig{AssetsMaxAge: defaultCacheMaxAge, UploadsMaxAge: defaultCacheMaxAge},
+		RateLimiting: RateLimitConfig{
+			MaxEntries:      1000,
+			CleanupInterval: 1 * time.Minute,
+			EntryTTL:        30 * time.Minute,
+			EnableDebug:     false,
+		},
+		ProgressiveRateLimiting: ProgressiveRateLimitConfig{
+			BaseWindow:      1 * time.Minute,
+			MaxWindow:       1 * time.Hour,
+			BaseCapacity:    60,
+			MinCapacity:     5,
+			BackoffFactor:   2.0,
+			LockoutThreshold: 10,
+			LockoutDuration: 15 * time.Minute,
+			ChallengeThreshold: 3,
+			EnableLogging:   true,
+		},
+		Server: ServerConfig{
+			Port:            8080,
+			TLS:             TLSConfig{ACMECacheDir: "certs"},
+			BodyLimitMB:     10,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			TrustedProxies:  []string{"127.0.0.1", "::1"},
+		},
+		Paths: PathsConfig{UploadsDir: "uploads", BackupDir: "backups", StagingDir: "staging"},
+		Auth: AuthConfig{
+			JWTLifetime: 24 * time.Hour,
+			BreachCheck: BreachCheckConfig{URL: "https://api.pwnedpasswords.com/range/", Timeout: 3 * time.Second},
+		},
 	}
+}
 
+// LoadConfig layers config.yaml (if present) over the defaults, then environment
+// overrides, and validates the result so bad values fail at startup rather than at use.
+func LoadConfig(path string) (*Config, error) {
+	config := DefaultConfig()
 	data, err := os.ReadFile(path)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	config.mergeTrustedProxies()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) applyEnv() error {
+	ints := []struct {
+		env string
+		dst *int
+	}{{"PORT", &c.Server.Port}, {"BODY_LIMIT_MB", &c.Server.BodyLimitMB}, {"JOB_WORKERS", &c.Jobs.Workers}}
+	for _, o := range ints {
+		if v := strings.TrimSpace(os.Getenv(o.env)); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", o.env, v, err)
+			}
+			*o.dst = n
+		}
+	}
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout},
+		{"JWT_LIFETIME", &c.Auth.JWTLifetime},
+		{"SECURITY_EVENT_RETENTION", &c.SecurityEvents.Retention},
+	}
+	for _, o := range durations {
+		if v := strings.TrimSpace(os.Getenv(o.env)); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", o.env, v, err)
+			}
+			*o.dst = d
+		}
+	}
+	strs := []struct {
+		env string
+		dst *string
+	}{
+		{"BIND_ADDRESS", &c.Server.BindAddress},
+		{"TLS_CERT_FILE", &c.Server.TLS.CertFile},
+		{"TLS_KEY_FILE", &c.Server.TLS.KeyFile},
+		{"ACME_EMAIL", &c.Server.TLS.ACMEEmail},
+		{"ACME_CACHE_DIR", &c.Server.TLS.ACMECacheDir},
+		{"TLS_REDIRECT_ADDRESS", &c.Server.TLS.RedirectAddress},
+		{"PASSWORD_BREACH_URL", &c.Auth.BreachCheck.URL},
+	}
+	for _, o := range strs {
+		if v := strings.TrimSpace(os.Getenv(o.env)); v != "" {
+			*o.dst = v
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES")); v != "" {
+		c.Server.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.Server.TrustedProxies = append(c.Server.TrustedProxies, p)
+			}
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ACME_DOMAINS")); v != "" {
+		c.Server.TLS.ACMEDomains = nil
+		for _, d := range strings.Split(v, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				c.Server.TLS.ACMEDomains = append(c.Server.TLS.ACMEDomains, d)
+			}
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("PREFORK")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid PREFORK %q: %w", v, err)
+		}
+		c.Server.Prefork = b
+	}
+	if v := strings.TrimSpace(os.Getenv("PPROF_ENABLED")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid PPROF_ENABLED %q: %w", v, err)
+		}
+		c.Server.Pprof = b
+	}
+	if v := strings.TrimSpace(os.Getenv("PASSWORD_BREACH_CHECK")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid PASSWORD_BREACH_CHECK %q: %w", v, err)
+		}
+		c.Auth.BreachCheck.Enabled = b
+	}
+	if v := strings.TrimSpace(os.Getenv("ANONYMOUS_READ_LIMITS")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ANONYMOUS_READ_LIMITS %q: %w", v, err)
+		}
+		c.AnonymousReads.Enabled = b
+	}
+	if v := strings.TrimSpace(os.Getenv("SIGNED_URLS")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid SIGNED_URLS %q: %w", v, err)
+		}
+		c.SignedURLs.Enabled = b
+	}
+	if v := strings.TrimSpace(os.Getenv("SIGNED_URLS_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid SIGNED_URLS_TTL %q: %w", v, err)
+		}
+		c.SignedURLs.TTL = d
+	}
+	if v := strings.TrimSpace(os.Getenv("SIGNED_URLS_SECRET")); v != "" {
+		c.SignedURLs.Secret = v
+	}
+	if v := strings.TrimSpace(os.Getenv("AVATAR_SIZE")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid AVATAR_SIZE %q: %w", v, err)
+		}
+		c.Avatars.Size = n
+	}
+	if v := strings.TrimSpace(os.Getenv("AVATAR_FORMAT")); v != "" {
+		c.Avatars.Format = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("AVATAR_ANIMATED_MAX_FRAMES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid AVATAR_ANIMATED_MAX_FRAMES %q: %w", v, err)
+		}
+		c.Avatars.AnimatedMaxFrames = n
+	}
+	if v := strings.TrimSpace(os.Getenv("VERIFICATION_SELF_SERVE")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid VERIFICATION_SELF_SERVE %q: %w", v, err)
+		}
+		c.Verification.SelfServe = b
+	}
+	if v := strings.TrimSpace(os.Getenv("CDN_PROVIDER")); v != "" {
+		c.CDN.Provider = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("CDN_API_TOKEN")); v != "" {
+		c.CDN.APIToken = v
+	}
+	if v := strings.TrimSpace(os.Getenv("CDN_ZONE_ID")); v != "" {
+		c.CDN.ZoneID = v
+	}
+	if v := strings.TrimSpace(os.Getenv("UPLOADS_DIR")); v != "" {
+		c.Paths.UploadsDir = v
+	}
+	if v := strings.TrimSpace(os.Getenv("BACKUP_DIR")); v != "" {
+		c.Paths.BackupDir = v
+	}
+	if v := strings.TrimSpace(os.Getenv("STAGING_DIR")); v != "" {
+		c.Paths.StagingDir = v
+	}
+	return nil
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+// mergeTrustedProxies folds the deprecated rate_limiting.trusted_proxies list into
+// server.trusted_proxies so both limiters and Fiber share one list.
+func (c *Config) mergeTrustedProxies() {
+	seen := map[string]bool{}
+	merged := make([]string, 0, len(c.Server.TrustedProxies)+len(c.RateLimiting.TrustedProxies))
+	for _, p := range append(append([]string{}, c.Server.TrustedProxies...), c.RateLimiting.TrustedProxies...) {
+		if p = strings.TrimSpace(p); p != "" && !seen[p] {
+			seen[p] = true
+			merged = append(merged, p)
+		}
 	}
+	c.Server.TrustedProxies = merged
+	c.RateLimiting.TrustedProxies = nil
+}
 
-	return &config, nil
+// Validate reports the first setting that is out of range.
+func (c *Config) Validate() error {
+	switch {
+	case c.Server.Port < 1 || c.Server.Port > 65535:
+		return fmt.Errorf("config: server.port %d out of range", c.Server.Port)
+	case c.Server.BodyLimitMB < 1 || c.Server.BodyLimitMB > 1024:
+		return fmt.Errorf("config: server.body_limit_mb must be between 1 and 1024")
+	case c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0:
+		return fmt.Errorf("config: server timeouts must be positive")
+	case (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == ""):
+		return fmt.Errorf("config: server.tls.cert_file and server.tls.key_file must be set together")
+	case c.Server.TLS.CertFile != "" && len(c.Server.TLS.ACMEDomains) > 0:
+		return fmt.Errorf("config: use either server.tls certificate files or acme_domains, not both")
+	case len(c.Server.TLS.ACMEDomains) > 0 && c.Server.Prefork:
+		return fmt.Errorf("config: server.prefork is not supported with ACME certificates")
+	case len(c.Server.TLS.ACMEDomains) > 0 && strings.TrimSpace(c.Server.TLS.ACMECacheDir) == "":
+		return fmt.Errorf("config: server.tls.acme_cache_dir is required with acme_domains")
+	case strings.TrimSpace(c.Paths.UploadsDir) == "" || strings.TrimSpace(c.Paths.BackupDir) == "" || strings.TrimSpace(c.Paths.StagingDir) == "":
+		return fmt.Errorf("config: paths.uploads_dir, paths.backup_dir and paths.staging_dir are required")
+	case c.Auth.JWTLifetime < 5*time.Minute || c.Auth.JWTLifetime > 90*24*time.Hour:
+		return fmt.Errorf("config: auth.jwt_lifetime must be between 5m and 2160h")
+	case c.Auth.BreachCheck.Enabled && !strings.HasPrefix(c.Auth.BreachCheck.URL, "https://") && !strings.HasPrefix(c.Auth.BreachCheck.URL, "http://"):
+		return fmt.Errorf("config: auth.breach_check.url must be an http(s) URL")
+	case c.Auth.BreachCheck.Enabled && (c.Auth.BreachCheck.Timeout <= 0 || c.Auth.BreachCheck.Timeout > 30*time.Second):
+		return fmt.Errorf("config: auth.breach_check.timeout must be positive and at most 30s")
+	case c.Aesthetic.MaxWidth < 0:
+		return fmt.Errorf("config: aesthetic.max_width must not be negative")
+	case c.Aesthetic.ThumbnailQuality < 0 || c.Aesthetic.ThumbnailQuality > 100:
+		return fmt.Errorf("config: aesthetic.thumbnail_quality must be between 0 and 100")
+	case c.Animation.MaxFrames < 1 || c.Animation.MaxDuration <= 0:
+		return fmt.Errorf("config: animation.max_frames and animation.max_duration must be positive")
+	case c.Video.Enabled && (c.Video.MaxSizeMB < 1 || c.Video.MaxDuration <= 0):
+		return fmt.Errorf("config: video.max_size_mb and video.max_duration must be positive")
+	case c.Jobs.Workers < 0 || c.Jobs.Workers > 64:
+		return fmt.Errorf("config: jobs.workers must be between 0 and 64")
+	case c.Jobs.PollInterval < 100*time.Millisecond:
+		return fmt.Errorf("config: jobs.poll_interval must be at least 100ms")
+	case c.SecurityEvents.Retention < 24*time.Hour:
+		return fmt.Errorf("config: security_events.retention must be at least 24h")
+	case c.SecurityEvents.LowRetention <= 0 || c.SecurityEvents.LowRetention > c.SecurityEvents.Retention:
+		return fmt.Errorf("config: security_events.low_retention must be positive and at most security_events.retention")
+	case c.AnonymousReads.Enabled && (c.AnonymousReads.Capacity < 1 || c.AnonymousReads.Window < time.Second):
+		return fmt.Errorf("config: anonymous_reads.capacity must be positive and anonymous_reads.window at least 1s")
+	case c.AnonymousReads.Enabled && (c.AnonymousReads.Burst < 1 || c.AnonymousReads.BurstWindow < time.Second || c.AnonymousReads.BurstWindow > c.AnonymousReads.Window):
+		return fmt.Errorf("config: anonymous_reads.burst must be positive and anonymous_reads.burst_window between 1s and anonymous_reads.window")
+	case c.SignedURLs.Enabled && c.SignedURLs.TTL < time.Minute:
+		return fmt.Errorf("config: signed_urls.ttl must be at least 1m")
+	case c.CDN.Provider != "" && c.CDN.Provider != CDNCloudflare && c.CDN.Provider != CDNBunny:
+		return fmt.Errorf("config: cdn.provider must be cloudflare or bunny")
+	case c.CDN.Provider != "" && strings.TrimSpace(c.CDN.APIToken) == "":
+		return fmt.Errorf("config: cdn.api_token is required with cdn.provider")
+	case c.CDN.Provider == CDNCloudflare && strings.TrimSpace(c.CDN.ZoneID) == "":
+		return fmt.Errorf("config: cdn.zone_id is required for cloudflare")
+	case c.CDN.AssetsMaxAge < 0 || c.CDN.UploadsMaxAge < 0 || c.CDN.EdgeMaxAge < 0:
+		return fmt.Errorf("config: cdn max ages must not be negative")
+	case c.Avatars.Size < 32 || c.Avatars.Size > 2048:
+		return fmt.Errorf("config: avatars.size must be between 32 and 2048")
+	case c.Avatars.Format != AvatarFormatWebP && c.Avatars.Format != AvatarFormatJPEG:
+		return fmt.Errorf("config: avatars.format must be webp or jpeg")
+	case c.Avatars.Format == AvatarFormatJPEG && (c.Avatars.Quality < 1 || c.Avatars.Quality > 100):
+		return fmt.Errorf("config: avatars.quality must be between 1 and 100")
+	case c.Avatars.AnimatedMaxFrames < 0 || c.Avatars.AnimatedMaxKB < 0:
+		return fmt.Errorf("config: avatars.animated_max_frames and avatars.animated_max_kb must not be negative")
+	}
+	for i, cr := range c.AnonymousReads.Crawlers {
+		if strings.TrimSpace(cr.UserAgent) == "" || len(cr.Domains) == 0 {
+			return fmt.Errorf("config: anonymous_reads.crawlers[%d] needs user_agent and domains", i)
+		}
+	}
+	if _, err := NewClientIPResolver(c.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("config: server.trusted_proxies: %w", err)
+	}
+	if _, err := CompileRateLimitPolicies(c.RateLimitPolicies); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
 }
+
+var (
+	uploadsDir = "uploads"
+	backupDir  = "backups"
+	stagingDir = "staging"
+)
+
+// ApplyConfig publishes process-wide settings from cfg; call once at startup.
+func ApplyConfig(cfg *Config) {
+	uploadsDir = cfg.Paths.UploadsDir
+	backupDir = cfg.Paths.BackupDir
+	stagingDir = cfg.Paths.StagingDir
+	SetBreachCheck(cfg.Auth.BreachCheck)
+	// Validate has already rejected malformed entries
+	_ = SetTrustedProxies(cfg.Server.TrustedProxies)
+	_ = SetRateLimitPolicies(cfg.RateLimitPolicies)
+	SetAnonymousReads(cfg.AnonymousReads)
+	SetSignedURLs(cfg.SignedURLs)
+	SetCDN(cfg.CDN)
+	SetVerification(cfg.Verification)
+	SetAvatars(cfg.Avatars)
+}
+
+// UploadsDir is the local uploads directory (paths.uploads_dir / UPLOADS_DIR).
+func UploadsDir() string { return uploadsDir }
+
+// BackupDir is the local backup directory (paths.backup_dir / BACKUP_DIR).
+func BackupDir() string { return backupDir }
+
+// StagingDir holds uploads queued for processing (paths.staging_dir / STAGING_DIR).
+func StagingDir() string { return stagingDir }
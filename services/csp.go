@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// The Content-Security-Policy is built from site settings rather than fixed, so only the
+// hosts the site actually uses are allowed: the configured analytics provider, the
+// challenge widget, the storage public base URL for media, and any script origins the
+// admin adds. Browsers send violations to CSPReportPath.
+
+// CSPReportPath receives violation reports (report-uri).
+const CSPReportPath = "/api/csp-report"
+
+// JobCSPReportsPurge prunes stale violation reports.
+const JobCSPReportsPurge = "csp_reports.purge"
+
+// cspReportRetention is how long a report is kept after it was last seen.
+const cspReportRetention = 30 * 24 * time.Hour
+
+// CSPBuilder assembles a policy, keeping directives in the order they were first added
+// and dropping duplicate sources.
+type CSPBuilder struct {
+	order   []string
+	sources map[string][]string
+}
+
+// NewCSPBuilder returns an empty policy.
+func NewCSPBuilder() *CSPBuilder {
+	return &CSPBuilder{sources: map[string][]string{}}
+}
+
+// Add appends sources to directive. A directive added without sources (e.g.
+// upgrade-insecure-requests) is emitted bare.
+func (b *CSPBuilder) Add(directive string, sources ...string) *CSPBuilder {
+	cur, ok := b.sources[directive]
+	if !ok {
+		b.order = append(b.order, directive)
+	}
+	for _, s := range sources {
+		if s == "" {
+			continue
+		}
+		dup := false
+		for _, have := range cur {
+			if have == s {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			cur = append(cur, s)
+		}
+	}
+	b.sources[directive] = cur
+	return b
+}
+
+// String renders the policy header value.
+func (b *CSPBuilder) String() string {
+	parts := make([]string, 0, len(b.order))
+	for _, d := range b.order {
+		if src := b.sources[d]; len(src) > 0 {
+			parts = append(parts, d+" "+strings.Join(src, " "))
+		} else {
+			parts = append(parts, d)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// cspSourceRe accepts a host source: optional https scheme, optional leading wildcard
+// label, optional port and path. Keywords, nonces and schemes other than https are refused.
+var cspSourceRe = regexp.MustCompile(`^(https://)?(\*\.)?[a-z0-9-]+(\.[a-z0-9-]+)+(:[0-9]{1,5})?(/[A-Za-z0-9._~/%-]*)?$`)
+
+// NormalizeCSPSources splits a space- or comma-separated list of script origins,
+// validates each and returns them joined by single spaces.
+func NormalizeCSPSources(list string) (string, error) {
+	var out []string
+	for _, s := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\t' || r == '\r' }) {
+		s = strings.ToLower(s)
+		if !cspSourceRe.MatchString(s) {
+			return "", fmt.Errorf("invalid script source %q: use a host such as https://cdn.example.com or *.example.com", s)
+		}
+		out = append(out, s)
+	}
+	if len(out) > 20 {
+		return "", errors.New("at most 20 script sources are allowed")
+	}
+	return strings.Join(out, " "), nil
+}
+
+// originOf returns scheme://host[:port] of an absolute http(s) URL, or "".
+func originOf(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// storageOrigin is where public media URLs point when uploads are not served locally.
+func storageOrigin(set models.SiteSettings) string {
+	if p := strings.ToLower(strings.TrimSpace(set.StorageProvider)); p == "" || p == "local" {
+		return ""
+	}
+	if base := firstNonEmpty(set.PublicBaseURL, os.Getenv("STORAGE_PUBLIC_BASE_URL")); base != "" {
+		return originOf(base)
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(set.S3Endpoint), "https://"), "http://")
+	host = strings.TrimRight(host, "/")
+	if host == "" {
+		return ""
+	}
+	if set.S3ForcePathStyle || set.S3Bucket == "" {
+		return "https://" + host
+	}
+	return "https://" + set.S3Bucket + "." + host
+}
+
+// BuildCSP returns the policy for the given site settings.
+func BuildCSP(set models.SiteSettings) string {
+	b := NewCSPBuilder().
+		Add("default-src", "'self'").
+		// Inline scripts stay allowed for the API docs page; external hosts are listed below
+		Add("script-src", "'self'", "'unsafe-inline'", "https://cdn.jsdelivr.net").
+		Add("style-src", "'self'", "'unsafe-inline'", "https://cdn.jsdelivr.net", "https://fonts.googleapis.com").
+		Add("font-src", "'self'", "data:", "https://cdn.jsdelivr.net", "https://fonts.gstatic.com").
+		Add("img-src", "'self'", "data:", "blob:", "https:").
+		Add("media-src", "'self'", "blob:").
+		Add("connect-src", "'self'").
+		Add("frame-src")
+
+	if origin := storageOrigin(set); origin != "" {
+		b.Add("img-src", origin).Add("media-src", origin).Add("connect-src", origin)
+	}
+
+	if set.AnalyticsEnabled {
+		switch strings.ToLower(strings.TrimSpace(set.AnalyticsProvider)) {
+		case "ga4":
+			b.Add("script-src", "https://www.googletagmanager.com").
+				Add("connect-src", "https://www.googletagmanager.com", "https://*.google-analytics.com", "https://*.analytics.google.com")
+		case "umami":
+			if o := originOf(set.UmamiSrc); o != "" {
+				b.Add("script-src", o).Add("connect-src", o)
+			}
+		case "plausible":
+			if o := originOf(set.PlausibleSrc); o != "" {
+				b.Add("script-src", o).Add("connect-src", o)
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(set.ChallengeProvider)) {
+	case "hcaptcha":
+		b.Add("script-src", "https://hcaptcha.com", "https://*.hcaptcha.com").
+			Add("style-src", "https://hcaptcha.com", "https://*.hcaptcha.com").
+			Add("frame-src", "https://hcaptcha.com", "https://*.hcaptcha.com").
+			Add("connect-src", "https://hcaptcha.com", "https://*.hcaptcha.com")
+	case "turnstile":
+		b.Add("script-src", "https://challenges.cloudflare.com").
+			Add("frame-src", "https://challenges.cloudflare.com")
+	}
+
+	for _, s := range strings.Fields(set.CSPScriptSources) {
+		// Sources were validated on save; re-check in case the column was edited by hand
+		if cspSourceRe.MatchString(s) {
+			b.Add("script-src", s)
+		}
+	}
+
+	if len(b.sources["frame-src"]) == 0 {
+		b.Add("frame-src", "'none'")
+	}
+	return b.Add("object-src", "'none'").
+		Add("base-uri", "'self'").
+		Add("form-action", "'self'").
+		Add("frame-ancestors", "'none'").
+		Add("block-all-mixed-content").
+		Add("report-uri", CSPReportPath).
+		String()
+}
+
+var cspCache struct {
+	mu     sync.Mutex
+	key    string
+	policy string
+}
+
+// CachedCSP is BuildCSP memoized on the settings it reads, for use on every response.
+func CachedCSP(set models.SiteSettings) string {
+	key := strings.Join([]string{
+		set.StorageProvider, set.PublicBaseURL, set.S3Endpoint, set.S3Bucket, boolKey(set.S3ForcePathStyle),
+		boolKey(set.AnalyticsEnabled), set.AnalyticsProvider, set.UmamiSrc, set.PlausibleSrc,
+		set.ChallengeProvider, set.CSPScriptSources,
+	}, "\x00")
+	cspCache.mu.Lock()
+	defer cspCache.mu.Unlock()
+	if cspCache.policy == "" || cspCache.key != key {
+		cspCache.key, cspCache.policy = key, BuildCSP(set)
+	}
+	return cspCache.policy
+}
+
+func boolKey(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// InitCSPReports schedules pruning of stale violation reports.
+func InitCSPReports(repo models.CSPReportRepositoryInterface) {
+	if repo == nil {
+		return
+	}
+	RegisterJob(JobSpec{
+		Kind:  JobCSPReportsPurge,
+		Every: func() time.Duration { return 24 * time.Hour },
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			n, err := repo.Purge(time.Now().Add(-cspReportRetention))
+			return map[string]int{"purged": n}, err
+		},
+	})
+}
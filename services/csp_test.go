@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/trough/models"
+)
+
+func directive(policy, name string) string {
+	for _, d := range strings.Split(policy, "; ") {
+		if d == name || strings.HasPrefix(d, name+" ") {
+			return d
+		}
+	}
+	return ""
+}
+
+func TestBuildCSP(t *testing.T) {
+	base := BuildCSP(models.SiteSettings{})
+	assert.Equal(t, "script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net", directive(base, "script-src"))
+	assert.Equal(t, "frame-src 'none'", directive(base, "frame-src"))
+	assert.Equal(t, "report-uri "+CSPReportPath, directive(base, "report-uri"))
+
+	p := BuildCSP(models.SiteSettings{
+		StorageProvider:   "s3",
+		PublicBaseURL:     "media.example.com/uploads",
+		AnalyticsEnabled:  true,
+		AnalyticsProvider: "plausible",
+		PlausibleSrc:      "https://stats.example.com/js/script.js",
+		ChallengeProvider: "turnstile",
+		CSPScriptSources:  "https://cdn.example.org 'unsafe-eval'",
+	})
+	assert.Equal(t, "script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://stats.example.com https://challenges.cloudflare.com https://cdn.example.org", directive(p, "script-src"))
+	assert.Equal(t, "media-src 'self' blob: https://media.example.com", directive(p, "media-src"))
+	assert.Equal(t, "connect-src 'self' https://media.example.com https://stats.example.com", directive(p, "connect-src"))
+	assert.Equal(t, "frame-src https://challenges.cloudflare.com", directive(p, "frame-src"))
+
+	// Analytics hosts are only allowed while analytics is on
+	off := BuildCSP(models.SiteSettings{AnalyticsProvider: "ga4"})
+	assert.NotContains(t, off, "googletagmanager")
+
+	// Virtual-hosted S3 without a public base URL
+	s3 := BuildCSP(models.SiteSettings{StorageProvider: "s3", S3Endpoint: "https://s3.example.net/", S3Bucket: "trough"})
+	assert.Contains(t, directive(s3, "img-src"), "https://trough.s3.example.net")
+}
+
+func TestNormalizeCSPSources(t *testing.T) {
+	got, err := NormalizeCSPSources(" https://CDN.example.com,*.example.org\nscripts.example.net:8443/js/ ")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com *.example.org scripts.example.net:8443/js/", got)
+
+	for _, bad := range []string{"'unsafe-eval'", "http://cdn.example.com", "data:", "*", "https://x.example.com;script-src", "localhost"} {
+		_, err := NormalizeCSPSources(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"mime"
@@ -19,6 +21,19 @@ type MailSender interface {
 	Send(to, subject, body string) error
 }
 
+// HTMLMailSender is implemented by senders that can deliver text+HTML alternatives.
+// Senders without it receive the plain-text part only.
+type HTMLMailSender interface {
+	SendHTML(to, subject, text, html string) error
+}
+
+func sendMail(sender MailSender, to, subject, text, html string) error {
+	if hs, ok := sender.(HTMLMailSender); ok && html != "" {
+		return hs.SendHTML(to, subject, text, html)
+	}
+	return sender.Send(to, subject, text)
+}
+
 type Mailer struct {
 	host string
 	port int
@@ -60,31 +75,31 @@ var NewMailSender = func(cfg *models.SiteSettings) MailSender { return NewMailer
 
 // BuildVerificationEmail returns a subject and plain-text body for email verification.
 // It is intentionally whimsical and text-only (UTF-8) to keep compatibility while feeling distinct.
-func BuildVerificationEmail(siteName, siteURL, link string) (string, string) {
+func BuildVerificationEmail(locale, siteName, siteURL, link string) (string, string) {
 	if strings.TrimSpace(siteName) == "" {
 		siteName = "TROUGH"
 	}
 	// Normalize siteURL for display
 	siteURL = strings.TrimSpace(siteURL)
 	// Subject keeps it short and eye-catching with unicode arrows and blocks.
-	subject := "▣ Verify your email · " + siteName
+	subject := "▣ " + T(locale, "Verify your email") + " · " + siteName
 
 	// Body: retro-cyber ASCII/Unicode style, no HTML.
 	// Keep lines relatively short to render nicely in plain-text clients.
 	body := "" +
 		"┌──────────────────────────────────────────────┐\n" +
-		"│   " + siteName + " — SIGNAL CONFIRMATION RITUAL   │\n" +
+		"│   " + siteName + " — " + T(locale, "SIGNAL CONFIRMATION RITUAL") + "   │\n" +
 		"└──────────────────────────────────────────────┘\n\n" +
-		"greetings operator,\n\n" +
-		"to complete your account setup you must verify your email.\n" +
-		"this proves you control this address and unlocks uploads.\n\n" +
-		"→ verification link (valid ~24 hours)\n" +
+		T(locale, "greetings operator,") + "\n\n" +
+		T(locale, "to complete your account setup you must verify your email.") + "\n" +
+		T(locale, "this proves you control this address and unlocks uploads.") + "\n\n" +
+		"→ " + T(locale, "verification link (valid ~24 hours)") + "\n" +
 		link + "\n\n" +
-		"if the link is not clickable, copy + paste it into your browser.\n" +
-		"keep this link secret; it works once.\n\n" +
-		"site: " + siteURL + "\n" +
-		"time: " + time.Now().Format(time.RFC1123) + "\n\n" +
-		"— " + siteName + " // see you on the other side ✷\n"
+		T(locale, "if the link is not clickable, copy + paste it into your browser.") + "\n" +
+		T(locale, "keep this link secret; it works once.") + "\n\n" +
+		T(locale, "site:") + " " + siteURL + "\n" +
+		T(locale, "time:") + " " + time.Now().Format(time.RFC1123) + "\n\n" +
+		"— " + siteName + " // " + T(locale, "see you on the other side") + " ✷\n"
 
 	return subject, body
 }
@@ -96,24 +111,53 @@ func HashToken(token string) string {
 }
 
 func (s *Mailer) Send(to, subject, body string) error {
-	headerSafe := func(v string) string {
-		// Strip CR/LF to prevent header injection; headers must be single-line
-		v = strings.ReplaceAll(v, "\r", "")
-		v = strings.ReplaceAll(v, "\n", "")
-		return v
+	return s.SendHTML(to, subject, body, "")
+}
+
+// SendHTML sends a multipart/alternative message when html is set, plain text otherwise.
+func (s *Mailer) SendHTML(to, subject, text, html string) error {
+	return s.deliver(to, buildMailMessage(s.from, to, subject, text, html))
+}
+
+func mailHeaderSafe(v string) string {
+	// Strip CR/LF to prevent header injection; headers must be single-line
+	v = strings.ReplaceAll(v, "\r", "")
+	v = strings.ReplaceAll(v, "\n", "")
+	return v
+}
+
+// buildMailMessage assembles the RFC 5322 message. Parts are base64 encoded so long HTML
+// lines and non-ASCII text survive strict relays.
+func buildMailMessage(from, to, subject, text, html string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + mailHeaderSafe(from) + "\r\n")
+	b.WriteString("To: " + mailHeaderSafe(to) + "\r\n")
+	// RFC 2047 encoded-word for non-ASCII
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", mailHeaderSafe(subject)) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if html == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n" + text + "\r\n")
+		return []byte(b.String())
 	}
-	encodeHeader := func(v string) string {
-		// RFC 2047 encoded-word for non-ASCII
-		return mime.QEncoding.Encode("utf-8", headerSafe(v))
+	boundary := "trough-" + HashToken(subject + to + time.Now().String())[:24]
+	b.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n\r\n")
+	for _, part := range []struct{ ctype, body string }{{"text/plain", text}, {"text/html", html}} {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + part.ctype + "; charset=UTF-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+		enc := base64.StdEncoding.EncodeToString([]byte(part.body))
+		for len(enc) > 76 {
+			b.WriteString(enc[:76] + "\r\n")
+			enc = enc[76:]
+		}
+		b.WriteString(enc + "\r\n")
 	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return []byte(b.String())
+}
+
+func (s *Mailer) deliver(to string, msg []byte) error {
 	// Build dial address; net.Dial supports bracketed IPv6
 	hostPort := net.JoinHostPort(s.host, fmt.Sprintf("%d", s.port))
-	safeFrom := headerSafe(s.from)
-	safeTo := headerSafe(to)
-	msg := []byte("From: " + safeFrom + "\r\n" +
-		"To: " + safeTo + "\r\n" +
-		"Subject: " + encodeHeader(subject) + "\r\n" +
-		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n" + body + "\r\n")
 	auth := smtp.PlainAuth("", s.user, s.pass, s.host)
 	// Common dialer with timeouts for non-implicit TLS path
 	dialer := &net.Dialer{Timeout: 10 * time.Second}
@@ -206,6 +250,7 @@ type queuedMail struct {
 	to      string
 	subject string
 	body    string
+	html    string
 }
 
 var (
@@ -236,27 +281,141 @@ func InitMailQueue(senderFactory func(*models.SiteSettings) MailSender, repo mod
 					}
 				}
 			}
-			if sender == nil {
-				// drop silently when not configured
+			// drop silently when not configured
+			if sender != nil {
+				// Try with one retry on transient error
+				if err := sendMail(sender, msg.to, msg.subject, msg.body, msg.html); err != nil {
+					time.Sleep(2 * time.Second)
+					_ = sendMail(sender, msg.to, msg.subject, msg.body, msg.html)
+				}
+			}
+			// Each queued message was registered with BeginWork so shutdown drains the queue
+			EndWork()
+		}
+	}()
+}
+
+// ---- Persistent outbox ----
+
+const (
+	mailMaxAttempts   = 8
+	mailLease         = 2 * time.Minute
+	mailSentRetention = 7 * 24 * time.Hour
+	// Queued mail older than this is dead-lettered rather than sent; links inside
+	// (verification, reset) would have expired anyway.
+	mailMaxAge = 24 * time.Hour
+)
+
+var mailOutbox models.MailOutboxRepositoryInterface
+
+// mailOutboxEvery is how often the outbox job looks for due mail when nothing wakes it.
+const mailOutboxEvery = 15 * time.Second
+
+// InitMailOutbox registers the outbox job. Once initialized, EnqueueMail persists mail in
+// the outbox so it survives restarts and is retried with backoff; any instance running job
+// workers may send it.
+func InitMailOutbox(senderFactory func(*models.SiteSettings) MailSender, repo models.SiteSettingsRepositoryInterface, outbox models.MailOutboxRepositoryInterface) {
+	if mailOutbox != nil || outbox == nil {
+		return
+	}
+	mailOutbox = outbox
+	var lastPurge time.Time
+	RegisterJob(JobSpec{
+		Kind:    JobMailOutbox,
+		Timeout: 10 * time.Minute,
+		Every:   func() time.Duration { return mailOutboxEvery },
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			set := GetCachedSettings(repo)
+			if set.SMTPHost == "" || set.SMTPPort <= 0 {
+				// Leave mail pending until SMTP is configured again
+				return nil, nil
+			}
+			sent := ProcessMailOutbox(senderFactory(&set), outbox)
+			if time.Since(lastPurge) > time.Hour {
+				if _, err := outbox.PurgeSent(time.Now().Add(-mailSentRetention)); err != nil {
+					Logger(ctx).Error("mail outbox: purge failed", "error", err)
+				}
+				lastPurge = time.Now()
+			}
+			return map[string]int{"sent": sent}, nil
+		},
+	})
+}
+
+// ProcessMailOutbox sends every due message once and returns how many were delivered.
+func ProcessMailOutbox(sender MailSender, outbox models.MailOutboxRepositoryInterface) int {
+	sent := 0
+	for {
+		batch, err := outbox.ClaimDue(20, mailLease)
+		if err != nil {
+			Logger(context.Background()).Error("mail outbox: claim failed", "error", err)
+			return sent
+		}
+		if len(batch) == 0 {
+			return sent
+		}
+		for _, m := range batch {
+			if time.Since(m.CreatedAt) > mailMaxAge {
+				_ = outbox.MarkFailed(m.ID, "expired before delivery", time.Now(), true)
+				continue
+			}
+			if err := sendMail(sender, m.ToEmail, m.Subject, m.Body, m.HTMLBody); err != nil {
+				msg := err.Error()
+				if len(msg) > 500 {
+					msg = msg[:500]
+				}
+				dead := m.Attempts >= mailMaxAttempts
+				if err := outbox.MarkFailed(m.ID, msg, time.Now().Add(mailRetryDelay(m.Attempts)), dead); err != nil {
+					Logger(context.Background()).Error("mail outbox: mark failed", "error", err)
+				}
 				continue
 			}
-			// Try with one retry on transient error
-			if err := sender.Send(msg.to, msg.subject, msg.body); err != nil {
-				time.Sleep(2 * time.Second)
-				_ = sender.Send(msg.to, msg.subject, msg.body)
+			if err := outbox.MarkSent(m.ID); err != nil {
+				Logger(context.Background()).Error("mail outbox: mark sent failed", "error", err)
 			}
+			sent++
 		}
-	}()
+	}
+}
+
+// mailRetryDelay backs off exponentially from 30s, capped at one hour.
+func mailRetryDelay(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
 }
 
 // EnqueueMail enqueues a message to be sent asynchronously; no-op if queue not initialized.
 func EnqueueMail(to, subject, body string) {
+	EnqueueMessage(to, EmailMessage{Subject: subject, Text: body})
+}
+
+// EnqueueMessage enqueues a text+HTML message; see EnqueueMail.
+func EnqueueMessage(to string, msg EmailMessage) {
+	if mailOutbox != nil {
+		err := mailOutbox.Enqueue(to, msg.Subject, msg.Text, msg.HTML)
+		if err == nil {
+			WakePeriodicJob(JobMailOutbox)
+			return
+		}
+		Logger(context.Background()).Warn("mail outbox: enqueue failed, using in-memory queue", "error", err)
+	}
 	if mailQueueCh == nil {
 		return
 	}
+	if !BeginWork() {
+		Logger(context.Background()).Warn("mail queue: shutting down, dropping message", "to", to)
+		return
+	}
 	select {
-	case mailQueueCh <- queuedMail{to: to, subject: subject, body: body}:
+	case mailQueueCh <- queuedMail{to: to, subject: msg.Subject, body: msg.Text, html: msg.HTML}:
 	default:
 		// queue full: drop to avoid blocking request path
+		EndWork()
 	}
 }
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default HTML email templates. Each email template defines "subject", "preheader" and
+// "content"; layout.html wraps content. Operators can override any file by placing one with
+// the same name in EMAIL_TEMPLATES_DIR (default templates/email).
+//
+//go:embed email_templates/*.html
+var emailTemplateFiles embed.FS
+
+// EmailTemplateNames lists the emails that can be rendered and previewed.
+var EmailTemplateNames = []string{"verification", "password_reset", "digest", "invite", "email_change", "email_change_notice", "login_alert", "registration_approved", "registration_rejected"}
+
+const defaultEmailAccent = "#7af0ff"
+
+var hexColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{3}([0-9a-fA-F]{3})?$`)
+
+// EmailMessage is a rendered email with plain-text and HTML alternatives.
+type EmailMessage struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailData is what templates see. Templates translate their copy with the T function,
+// e.g. {{T "Verify email"}} or {{T "You're invited to %s" .SiteName}}.
+type EmailData struct {
+	// Locale the email is written in; empty means the base locale
+	Locale    string
+	SiteName  string
+	SiteURL   string
+	Link      string
+	Accent    string
+	Subject   string
+	Preheader string
+	Year      int
+	// Digest emails list Items; Count is the total and More those not listed
+	Items []string
+	Count int
+	More  int
+	// Expires is when an invite stops working, already formatted; "" for never
+	Expires string
+	// Email is the new address in email change messages
+	Email string
+	// Reason is the staff note on a rejected registration
+	Reason string
+}
+
+func emailTemplatesDir() string {
+	if d := strings.TrimSpace(os.Getenv("EMAIL_TEMPLATES_DIR")); d != "" {
+		return d
+	}
+	return filepath.Join("templates", "email")
+}
+
+// readEmailTemplate prefers an override from the templates dir over the embedded default.
+func readEmailTemplate(file string) (string, error) {
+	if b, err := os.ReadFile(filepath.Join(emailTemplatesDir(), file)); err == nil {
+		return string(b), nil
+	}
+	b, err := emailTemplateFiles.ReadFile("email_templates/" + file)
+	return string(b), err
+}
+
+// RenderEmailHTML renders the named template into a subject and HTML body.
+func RenderEmailHTML(name string, data EmailData) (string, string, error) {
+	known := false
+	for _, n := range EmailTemplateNames {
+		known = known || n == name
+	}
+	if !known {
+		return "", "", fmt.Errorf("unknown email template %q", name)
+	}
+	if strings.TrimSpace(data.SiteName) == "" {
+		data.SiteName = "TROUGH"
+	}
+	if data.Accent == "" {
+		data.Accent = defaultEmailAccent
+		if a := strings.TrimSpace(os.Getenv("EMAIL_ACCENT_COLOR")); hexColorRe.MatchString(a) {
+			data.Accent = a
+		}
+	}
+	if data.Year == 0 {
+		data.Year = time.Now().Year()
+	}
+	if data.Locale == "" {
+		data.Locale = BaseLocale
+	}
+	layout, err := readEmailTemplate("layout.html")
+	if err != nil {
+		return "", "", err
+	}
+	body, err := readEmailTemplate(name + ".html")
+	if err != nil {
+		return "", "", err
+	}
+	funcs := template.FuncMap{"T": func(msgid string, args ...any) string { return T(data.Locale, msgid, args...) }}
+	t, err := template.New("layout").Funcs(funcs).Parse(layout)
+	if err == nil {
+		_, err = t.Parse(body)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("parse email template %s: %w", name, err)
+	}
+	var subj, pre, out bytes.Buffer
+	if err := t.ExecuteTemplate(&subj, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := t.ExecuteTemplate(&pre, "preheader", data); err != nil {
+		return "", "", err
+	}
+	// Template output is HTML-escaped; subjects go into headers as plain text
+	data.Subject = html.UnescapeString(strings.TrimSpace(subj.String()))
+	data.Preheader = html.UnescapeString(strings.TrimSpace(pre.String()))
+	if err := t.ExecuteTemplate(&out, "layout", data); err != nil {
+		return "", "", err
+	}
+	return data.Subject, out.String(), nil
+}
+
+// BuildVerificationMessage returns the verification email with text and HTML parts.
+// If the HTML template fails to render, the plain-text version is sent alone. Every
+// Build*Message function writes in locale, normally the site default.
+func BuildVerificationMessage(locale, siteName, siteURL, link string) EmailMessage {
+	subject, text := BuildVerificationEmail(locale, siteName, siteURL, link)
+	msg := EmailMessage{Subject: subject, Text: text}
+	if subj, body, err := RenderEmailHTML("verification", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildPasswordResetMessage returns the password reset email with text and HTML parts.
+func BuildPasswordResetMessage(locale, siteName, siteURL, link string) EmailMessage {
+	text := `============================
+  ` + T(locale, "PASSWORD RESET REQUEST") + `
+============================
+
+` + T(locale, "We received a request to reset your password.") + `
+
+` + T(locale, "If you made this request, use the link below to set a new password.") + `
+` + T(locale, "If you did NOT request this, you can safely ignore this email.") + `
+
+>>> ` + T(locale, "RESET LINK (valid for 1 hour, single-use)") + ` <<<
+` + link + `
+
+` + T(locale, "Tips for a strong password:") + `
+- ` + T(locale, "8+ characters") + `
+- ` + T(locale, "mix of UPPER/lower case, numbers, symbols") + `
+
+` + T(locale, "This link expires in 1 hour or after it is used once.") + `
+` + T(locale, "For security, never share this link.") + `
+
+— TROUGH
+`
+	msg := EmailMessage{Subject: T(locale, "Reset your password"), Text: text}
+	if subj, body, err := RenderEmailHTML("password_reset", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildDigestMessage returns the notification digest email for the given item lines.
+// total counts every pending notification, including ones not listed.
+func BuildDigestMessage(locale, siteName, siteURL, link string, items []string, total int) EmailMessage {
+	if strings.TrimSpace(siteName) == "" {
+		siteName = "TROUGH"
+	}
+	if total < len(items) {
+		total = len(items)
+	}
+	more := total - len(items)
+	var b strings.Builder
+	b.WriteString(T(locale, "Here's what happened on %s since your last digest:", siteName) + "\n\n")
+	for _, it := range items {
+		b.WriteString("- " + it + "\n")
+	}
+	if more > 0 {
+		b.WriteString(T(locale, "...and %d more.", more) + "\n")
+	}
+	b.WriteString("\n" + T(locale, "View notifications:") + " " + link + "\n\n" + T(locale, "You get this email because daily digests are on in your settings.") + "\n")
+	subject := T(locale, "%d new notifications", total)
+	if total == 1 {
+		subject = T(locale, "1 new notification")
+	}
+	msg := EmailMessage{Subject: subject, Text: b.String()}
+	if subj, body, err := RenderEmailHTML("digest", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link, Items: items, Count: total, More: more}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildInviteMessage returns the email inviting someone to register via link. expires may
+// be nil for an invite that does not expire.
+func BuildInviteMessage(locale, siteName, siteURL, link string, expires *time.Time) EmailMessage {
+	if strings.TrimSpace(siteName) == "" {
+		siteName = "TROUGH"
+	}
+	exp := ""
+	if expires != nil {
+		exp = expires.UTC().Format("2006-01-02")
+		if locale == BaseLocale {
+			exp = expires.UTC().Format("January 2, 2006")
+		}
+	}
+	var b strings.Builder
+	b.WriteString(T(locale, "You've been invited to join %s.", siteName) + "\n\n" + T(locale, "Create your account with this link:") + "\n" + link + "\n\n")
+	b.WriteString(T(locale, "The invitation is for this email address, so sign up with it.") + "\n")
+	if exp != "" {
+		b.WriteString(T(locale, "The invitation expires on %s.", exp) + "\n")
+	}
+	b.WriteString("\n" + T(locale, "Not expecting this? You can ignore this email.") + "\n")
+	msg := EmailMessage{Subject: T(locale, "You're invited to %s", siteName), Text: b.String()}
+	if subj, body, err := RenderEmailHTML("invite", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link, Expires: exp}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildEmailChangeMessage returns the email asking the new address to confirm a change.
+func BuildEmailChangeMessage(locale, siteName, siteURL, link, newEmail string) EmailMessage {
+	text := T(locale, "Your account asked to use %s from now on.", newEmail) + "\n\n" +
+		T(locale, "Confirm the change with this link (valid for 24 hours, single-use):") + "\n" + link + "\n\n" +
+		T(locale, "Didn't ask for this? Ignore this email and nothing changes.") + "\n"
+	msg := EmailMessage{Subject: T(locale, "Confirm your new email"), Text: text}
+	if subj, body, err := RenderEmailHTML("email_change", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link, Email: newEmail}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildEmailChangeNoticeMessage returns the warning sent to the old address when a change
+// is requested, with a link that cancels it.
+func BuildEmailChangeNoticeMessage(locale, siteName, siteURL, cancelLink, newEmail string) EmailMessage {
+	text := T(locale, "Your account asked to change its email to %s.", newEmail) + "\n" +
+		T(locale, "It will switch once that address is confirmed.") + "\n\n" +
+		T(locale, "If this wasn't you, cancel the change and change your password:") + "\n" + cancelLink + "\n"
+	msg := EmailMessage{Subject: T(locale, "Your email is being changed"), Text: text}
+	if subj, body, err := RenderEmailHTML("email_change_notice", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: cancelLink, Email: newEmail}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildLoginAlertMessage returns the warning about a sign-in from a new device or
+// location; details are lines such as the time and device.
+func BuildLoginAlertMessage(locale, siteName, siteURL, link string, details []string) EmailMessage {
+	var b strings.Builder
+	b.WriteString(T(locale, "Your account was just signed in to from a device or location we haven't seen before.") + "\n\n")
+	for _, d := range details {
+		b.WriteString("- " + d + "\n")
+	}
+	b.WriteString("\n" + T(locale, "If this was you, there's nothing to do. If not, change your password now and review your recent sign-ins:") + "\n" + link + "\n")
+	msg := EmailMessage{Subject: T(locale, "New sign-in to your account"), Text: b.String()}
+	if subj, body, err := RenderEmailHTML("login_alert", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link, Items: details}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildRegistrationApprovedMessage tells someone their queued registration was approved;
+// link is the sign-in page.
+func BuildRegistrationApprovedMessage(locale, siteName, siteURL, link string) EmailMessage {
+	if strings.TrimSpace(siteName) == "" {
+		siteName = "TROUGH"
+	}
+	text := T(locale, "Your registration on %s was approved. You can sign in and start uploading.", siteName) + "\n\n" + link + "\n"
+	msg := EmailMessage{Subject: T(locale, "Your account on %s is approved", siteName), Text: text}
+	if subj, body, err := RenderEmailHTML("registration_approved", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Link: link}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
+
+// BuildRegistrationRejectedMessage tells someone their queued registration was turned down,
+// with staff's reason when one was given.
+func BuildRegistrationRejectedMessage(locale, siteName, siteURL, reason string) EmailMessage {
+	if strings.TrimSpace(siteName) == "" {
+		siteName = "TROUGH"
+	}
+	text := T(locale, "Your registration on %s was not approved, and the account has been removed.", siteName) + "\n"
+	if reason != "" {
+		text += "\n" + T(locale, "Note from the team:") + " " + reason + "\n"
+	}
+	msg := EmailMessage{Subject: T(locale, "Your registration on %s", siteName), Text: text}
+	if subj, body, err := RenderEmailHTML("registration_rejected", EmailData{Locale: locale, SiteName: siteName, SiteURL: siteURL, Reason: reason}); err == nil {
+		msg.Subject, msg.HTML = subj, body
+	}
+	return msg
+}
@@ -2,8 +2,13 @@ package services
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/yourusername/trough/models"
 )
 
@@ -52,3 +57,151 @@ func TestMailSenderMock(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+// fakeOutbox is an in-memory MailOutboxRepositoryInterface.
+type fakeOutbox struct {
+	mail map[uuid.UUID]*models.OutboxMail
+}
+
+func (f *fakeOutbox) Enqueue(to, subject, body, html string) error {
+	id := uuid.New()
+	f.mail[id] = &models.OutboxMail{ID: id, ToEmail: to, Subject: subject, Body: body, HTMLBody: html, Status: models.MailStatusPending, CreatedAt: time.Now(), NextAttemptAt: time.Now()}
+	return nil
+}
+
+func (f *fakeOutbox) ClaimDue(limit int, _ time.Duration) ([]models.OutboxMail, error) {
+	var out []models.OutboxMail
+	for _, m := range f.mail {
+		if len(out) < limit && m.Status == models.MailStatusPending && !m.NextAttemptAt.After(time.Now()) {
+			m.Status = models.MailStatusSending
+			m.Attempts++
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeOutbox) MarkSent(id uuid.UUID) error {
+	f.mail[id].Status = models.MailStatusSent
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(id uuid.UUID, errMsg string, next time.Time, dead bool) error {
+	m := f.mail[id]
+	m.Status, m.LastError, m.NextAttemptAt = models.MailStatusPending, &errMsg, next
+	if dead {
+		m.Status = models.MailStatusDead
+	}
+	return nil
+}
+
+func (f *fakeOutbox) List(string, int, int) ([]models.OutboxMail, int, error) { return nil, 0, nil }
+func (f *fakeOutbox) Retry(uuid.UUID) error                                   { return nil }
+func (f *fakeOutbox) Delete(uuid.UUID) error                                  { return nil }
+func (f *fakeOutbox) PurgeSent(time.Time) (int, error)                        { return 0, nil }
+func (f *fakeOutbox) Backlog() (*models.MailBacklog, error)                   { return &models.MailBacklog{}, nil }
+
+func TestProcessMailOutboxRetriesAndDeadLetters(t *testing.T) {
+	ob := &fakeOutbox{mail: map[uuid.UUID]*models.OutboxMail{}}
+	_ = ob.Enqueue("a@b.c", "hi", "body", "")
+	f := &fakeSender{fail: errors.New("smtp down")}
+	if n := ProcessMailOutbox(f, ob); n != 0 {
+		t.Fatalf("expected no deliveries, got %d", n)
+	}
+	var m *models.OutboxMail
+	for _, v := range ob.mail {
+		m = v
+	}
+	if m.Status != models.MailStatusPending || !m.NextAttemptAt.After(time.Now()) || m.LastError == nil {
+		t.Fatalf("expected rescheduled pending mail, got %+v", m)
+	}
+	// Exhaust the attempt budget
+	m.Attempts = mailMaxAttempts - 1
+	m.NextAttemptAt = time.Now()
+	ProcessMailOutbox(f, ob)
+	if m.Status != models.MailStatusDead {
+		t.Fatalf("expected dead-lettered mail, got %s", m.Status)
+	}
+	// A healthy sender delivers requeued mail
+	m.Status, m.NextAttemptAt = models.MailStatusPending, time.Now()
+	f.fail = nil
+	if n := ProcessMailOutbox(f, ob); n != 1 || m.Status != models.MailStatusSent {
+		t.Fatalf("expected delivery, got %d %s", n, m.Status)
+	}
+}
+
+func TestMailRetryDelay(t *testing.T) {
+	if mailRetryDelay(1) != 30*time.Second || mailRetryDelay(3) != 2*time.Minute || mailRetryDelay(20) != time.Hour {
+		t.Fatal("unexpected backoff schedule")
+	}
+}
+
+func TestBuildMailMessageMultipart(t *testing.T) {
+	msg := string(buildMailMessage("from@x.y", "to@x.y", "Hi\r\nBcc: evil@x.y", "plain", "<p>html</p>"))
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Fatal("header injection not stripped")
+	}
+	if !strings.Contains(msg, "multipart/alternative") || !strings.Contains(msg, "text/plain") || !strings.Contains(msg, "text/html") {
+		t.Fatalf("expected both alternatives, got %q", msg)
+	}
+	if plain := string(buildMailMessage("f", "t", "s", "plain", "")); strings.Contains(plain, "multipart") {
+		t.Fatal("plain message should not be multipart")
+	}
+}
+
+func TestRenderEmailHTMLEscapesAndOverrides(t *testing.T) {
+	subj, html, err := RenderEmailHTML("verification", EmailData{SiteName: "A & <B>", Link: "https://x.y/verify?token=t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subj != "Verify your email · A & <B>" {
+		t.Fatalf("subject should be plain text, got %q", subj)
+	}
+	if strings.Contains(html, "<B>") || !strings.Contains(html, "https://x.y/verify?token=t") {
+		t.Fatal("expected escaped site name and link in html")
+	}
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "verification.html"), []byte(`{{define "subject"}}Custom{{end}}{{define "preheader"}}{{end}}{{define "content"}}custom body {{.Link}}{{end}}`), 0o644)
+	t.Setenv("EMAIL_TEMPLATES_DIR", dir)
+	subj, html, err = RenderEmailHTML("verification", EmailData{Link: "L"})
+	if err != nil || subj != "Custom" || !strings.Contains(html, "custom body L") {
+		t.Fatalf("override not applied: %q %v", subj, err)
+	}
+}
+
+func TestBuildInviteMessage(t *testing.T) {
+	exp := time.Date(2030, 3, 4, 12, 0, 0, 0, time.UTC)
+	msg := BuildInviteMessage("en", "Site", "https://x.y", "https://x.y/register?invite=abc", &exp)
+	if msg.Subject != "You're invited to Site" || !strings.Contains(msg.Text, "March 4, 2030") || !strings.Contains(msg.HTML, "register?invite=abc") {
+		t.Fatalf("unexpected invite message: %+v", msg)
+	}
+	if msg := BuildInviteMessage("en", "", "", "L", nil); strings.Contains(msg.Text, "expires") {
+		t.Fatalf("an invite without expiry should not mention one: %q", msg.Text)
+	}
+}
+
+func TestBuildEmailChangeMessages(t *testing.T) {
+	confirm := BuildEmailChangeMessage("en", "Site", "https://x.y", "https://x.y/confirm-email?token=t", "new@x.y")
+	if !strings.Contains(confirm.Text, "new@x.y") || !strings.Contains(confirm.HTML, "confirm-email?token=t") {
+		t.Fatalf("unexpected confirmation message: %+v", confirm)
+	}
+	notice := BuildEmailChangeNoticeMessage("en", "Site", "https://x.y", "https://x.y/cancel-email-change?token=c", "new@x.y")
+	if !strings.Contains(notice.HTML, "new@x.y") || !strings.Contains(notice.Text, "cancel-email-change?token=c") {
+		t.Fatalf("unexpected notice: %+v", notice)
+	}
+}
+
+func TestBuildRegistrationMessages(t *testing.T) {
+	approved := BuildRegistrationApprovedMessage("en", "Site", "https://x.y", "https://x.y/")
+	if approved.Subject != "Your account on Site is approved" || !strings.Contains(approved.HTML, "https://x.y/") {
+		t.Fatalf("unexpected approval message: %+v", approved)
+	}
+	rejected := BuildRegistrationRejectedMessage("de", "Site", "https://x.y", "<b>spam</b>")
+	if !strings.Contains(rejected.Text, "Hinweis vom Team: <b>spam</b>") || strings.Contains(rejected.HTML, "<b>spam") {
+		t.Fatalf("expected the reason in text and escaped in HTML: %+v", rejected)
+	}
+	if msg := BuildRegistrationRejectedMessage("en", "Site", "", ""); strings.Contains(msg.Text, "Note from the team") {
+		t.Fatalf("a rejection without a reason should not mention one: %q", msg.Text)
+	}
+}
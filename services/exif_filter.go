@@ -0,0 +1,104 @@
+package services
+
+import (
+	"regexp"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+// ExifFilter describes which EXIF tags to drop when re-encoding. Tags are keyed by the
+// name of the IFD that holds them ("IFD" for IFD0, "Exif", "GPSInfo", "Iop"); dropping a
+// child-IFD pointer such as GPSInfo removes the whole sub-IFD.
+type ExifFilter struct {
+	Drop          map[string][]uint16
+	DropThumbnail bool
+}
+
+// PrivacyExifFilter removes location and device-identifying tags. Everything else, in
+// particular Software, ImageDescription, UserComment and Artist where generators record
+// provenance, is kept as-is.
+var PrivacyExifFilter = ExifFilter{
+	Drop: map[string][]uint16{
+		"IFD": {
+			0x8825, // GPSInfo
+			0x013c, // HostComputer
+			0xc62f, // CameraSerialNumber
+		},
+		"Exif": {
+			0x927c, // MakerNote (vendor blob, usually carries serials)
+			0xa420, // ImageUniqueID
+			0xa430, // CameraOwnerName
+			0xa431, // BodySerialNumber
+			0xa435, // LensSerialNumber
+		},
+	},
+	// The embedded preview can show the uncropped original
+	DropThumbnail: true,
+}
+
+// FilterExif rewrites a raw TIFF EXIF payload without the tags named by f. It fails
+// closed: if the payload cannot be parsed or re-encoded, nil is returned so the caller
+// stores no EXIF rather than the unfiltered original.
+func FilterExif(raw []byte, f ExifFilter) (out []byte) {
+	if len(raw) == 0 {
+		return nil
+	}
+	// go-exif reports some malformed input by panicking
+	defer func() {
+		if recover() != nil {
+			out = nil
+		}
+	}()
+	im, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
+		return nil
+	}
+	_, index, err := exif.Collect(im, exif.NewTagIndex(), raw)
+	if err != nil || index.RootIfd == nil {
+		return nil
+	}
+	root := exif.NewIfdBuilderFromExistingChain(index.RootIfd)
+	if f.DropThumbnail {
+		if err := root.SetNextIb(nil); err != nil {
+			return nil
+		}
+	}
+	dropTags(root, f.Drop)
+	out, err = exif.NewIfdByteEncoder().EncodeToExif(root)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// dropTags removes the listed tags from ib and, recursively, from the child IFDs it keeps.
+func dropTags(ib *exif.IfdBuilder, drop map[string][]uint16) {
+	for _, id := range drop[ib.IfdIdentity().Name()] {
+		// Not-found is the common case and not an error here
+		_, _ = ib.DeleteAll(id)
+	}
+	for _, bt := range ib.Tags() {
+		if v := bt.Value(); v != nil && v.IsIb() {
+			dropTags(v.Ib(), drop)
+		}
+	}
+	if next, err := ib.NextIb(); err == nil && next != nil {
+		dropTags(next, drop)
+	}
+}
+
+var (
+	xmpGPSAttr    = regexp.MustCompile(`\s+exif:GPS\w+="[^"]*"`)
+	xmpGPSElement = regexp.MustCompile(`(?s)<exif:GPS\w+\b[^>]*?(?:/>|>.*?</exif:GPS\w+>)`)
+)
+
+// StripXMPLocation removes exif:GPS* properties, which photo tools copy into XMP, in both
+// attribute and element form. The rest of the packet is left untouched.
+func StripXMPLocation(xmp []byte) []byte {
+	if len(xmp) == 0 {
+		return xmp
+	}
+	xmp = xmpGPSAttr.ReplaceAll(xmp, nil)
+	return xmpGPSElement.ReplaceAll(xmp, nil)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+func buildTestExif(t *testing.T) []byte {
+	t.Helper()
+	im, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := exif.NewTagIndex()
+	root := exif.NewIfdBuilder(im, ti, exifcommon.IfdStandardIfdIdentity, binary.BigEndian)
+	if err := root.AddStandardWithName("Software", "Midjourney v6"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddStandardWithName("ImageDescription", "prompt: a cat in a hat"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddStandardWithName("HostComputer", "alice-laptop"); err != nil {
+		t.Fatal(err)
+	}
+	exifIb, err := exif.GetOrCreateIbFromRootIb(root, "IFD/Exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exifIb.AddStandardWithName("BodySerialNumber", "SN123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err := exifIb.AddStandardWithName("ExposureTime", []exifcommon.Rational{{Numerator: 1, Denominator: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	gpsIb, err := exif.GetOrCreateIbFromRootIb(root, "IFD/GPSInfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gpsIb.AddStandardWithName("GPSLatitudeRef", "N"); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := exif.NewIfdByteEncoder().EncodeToExif(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func exifTagNames(t *testing.T, raw []byte) map[string]string {
+	t.Helper()
+	entries, _, err := exif.GetFlatExifData(raw, nil)
+	if err != nil {
+		t.Fatalf("parse filtered exif: %v", err)
+	}
+	m := map[string]string{}
+	for _, e := range entries {
+		m[e.TagName] = e.Formatted
+	}
+	return m
+}
+
+func TestFilterExif_PrivacyKeepsProvenance(t *testing.T) {
+	raw := buildTestExif(t)
+	if tags := exifTagNames(t, raw); tags["GPSLatitudeRef"] == "" || tags["BodySerialNumber"] == "" {
+		t.Fatalf("fixture missing sensitive tags: %v", tags)
+	}
+	tags := exifTagNames(t, FilterExif(raw, PrivacyExifFilter))
+	for _, gone := range []string{"GPSLatitudeRef", "BodySerialNumber", "HostComputer"} {
+		if _, ok := tags[gone]; ok {
+			t.Errorf("%s should be dropped", gone)
+		}
+	}
+	if tags["Software"] != "Midjourney v6" || !strings.Contains(tags["ImageDescription"], "prompt") {
+		t.Errorf("provenance tags lost: %v", tags)
+	}
+	if _, ok := tags["ExposureTime"]; !ok {
+		t.Errorf("unrelated Exif tag dropped: %v", tags)
+	}
+}
+
+func TestFilterExif_FailsClosed(t *testing.T) {
+	if out := FilterExif([]byte("not exif at all"), PrivacyExifFilter); out != nil {
+		t.Fatalf("expected nil for unparseable input, got %d bytes", len(out))
+	}
+}
+
+func TestStripXMPLocation(t *testing.T) {
+	in := []byte(`<rdf:Description exif:GPSLatitude="52,31.2N" xmp:CreatorTool="ComfyUI"><exif:GPSLongitude>13,24.1E</exif:GPSLongitude><exif:GPSVersionID/><dc:title>x</dc:title></rdf:Description>`)
+	out := string(StripXMPLocation(in))
+	if strings.Contains(out, "GPS") {
+		t.Fatalf("GPS left in XMP: %s", out)
+	}
+	if !strings.Contains(out, `xmp:CreatorTool="ComfyUI"`) || !strings.Contains(out, "<dc:title>x</dc:title>") {
+		t.Fatalf("unrelated XMP altered: %s", out)
+	}
+}
+
+func TestEncodeJPEGWithFilteredMetadata(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	out, err := EncodeJPEGWithFilteredMetadata(img, 80, nil, buildTestExif(t), &PrivacyExifFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := ExtractExifRawFromBytes(out)
+	if raw == nil {
+		t.Fatal("filtered EXIF not embedded")
+	}
+	if bytes.Contains(raw, []byte("SN123456")) || bytes.Contains(raw, []byte("alice-laptop")) {
+		t.Fatal("sensitive values still present in embedded EXIF")
+	}
+	if !bytes.Contains(raw, []byte("Midjourney")) {
+		t.Fatal("Software tag missing from embedded EXIF")
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ResponseCacheStore holds serialized JSON responses. Implementations must be safe for
+// concurrent use; errors are treated as misses so a flaky backend never fails a request.
+type ResponseCacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	// Generation returns the current invalidation generation; Bump advances it so every
+	// previously cached key becomes unreachable.
+	Generation(ctx context.Context) int64
+	Bump(ctx context.Context)
+}
+
+// memoryCacheStore is the default in-process store with a bounded entry count.
+type memoryCacheStore struct {
+	mu         sync.Mutex
+	entries    map[string]memoryCacheEntry
+	maxEntries int
+	gen        int64
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns an in-process store holding at most maxEntries responses.
+func NewMemoryCacheStore(maxEntries int) ResponseCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 500
+	}
+	return &memoryCacheStore{entries: map[string]memoryCacheEntry{}, maxEntries: maxEntries}
+}
+
+func (m *memoryCacheStore) Get(_ context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (m *memoryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= m.maxEntries {
+		// Drop expired entries first, then arbitrary ones; the cache is small and short-lived
+		now := time.Now()
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		for k := range m.entries {
+			if len(m.entries) < m.maxEntries {
+				break
+			}
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+func (m *memoryCacheStore) Generation(_ context.Context) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gen
+}
+
+func (m *memoryCacheStore) Bump(_ context.Context) {
+	m.mu.Lock()
+	m.gen++
+	m.entries = map[string]memoryCacheEntry{}
+	m.mu.Unlock()
+}
+
+// redisCacheStore shares cached responses and the invalidation generation across instances.
+type redisCacheStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCacheStore returns a store backed by Redis under the given key prefix.
+func NewRedisCacheStore(client *redis.Client, prefix string) ResponseCacheStore {
+	return &redisCacheStore{client: client, prefix: prefix}
+}
+
+func (r *redisCacheStore) Get(ctx context.Context, key string) ([]byte, bool) {
+	b, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func (r *redisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := r.client.Set(ctx, r.prefix+key, value, ttl).Err(); err != nil {
+		Logger(ctx).Warn("feed cache: redis set failed", "error", err)
+	}
+}
+
+func (r *redisCacheStore) Generation(ctx context.Context) int64 {
+	n, err := r.client.Get(ctx, r.prefix+"gen").Int64()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func (r *redisCacheStore) Bump(ctx context.Context) {
+	if err := r.client.Incr(ctx, r.prefix+"gen").Err(); err != nil {
+		Logger(ctx).Warn("feed cache: redis invalidate failed", "error", err)
+	}
+}
+
+// FeedCacheStats reports cache effectiveness since startup.
+type FeedCacheStats struct {
+	Enabled       bool   `json:"enabled"`
+	Backend       string `json:"backend"`
+	Pages         int    `json:"pages"`
+	TTLSeconds    int    `json:"ttl_seconds"`
+	Hits          int64  `json:"hits"`
+	Misses        int64  `json:"misses"`
+	Invalidations int64  `json:"invalidations"`
+}
+
+// feedCache caches anonymous feed pages and image detail JSON. Entries are keyed by the
+// current generation, so invalidation is a single counter bump rather than a key scan.
+// The TTL bounds staleness for changes that don't invalidate (e.g. a username edit).
+var feedCache struct {
+	mu            sync.RWMutex
+	store         ResponseCacheStore
+	backend       string
+	pages         int
+	ttl           time.Duration
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// ConfigureFeedCache enables the cache for the first pages feed pages. A nil store disables it.
+func ConfigureFeedCache(store ResponseCacheStore, backend string, pages int, ttl time.Duration) {
+	feedCache.mu.Lock()
+	defer feedCache.mu.Unlock()
+	feedCache.store = store
+	feedCache.backend = backend
+	feedCache.pages = pages
+	feedCache.ttl = ttl
+}
+
+// FeedCachePageCacheable reports whether the given feed page falls within the cached range.
+func FeedCachePageCacheable(page int) bool {
+	feedCache.mu.RLock()
+	defer feedCache.mu.RUnlock()
+	return feedCache.store != nil && page >= 1 && page <= feedCache.pages
+}
+
+// FeedCacheGet returns a cached response for key, counting the hit or miss.
+func FeedCacheGet(ctx context.Context, key string) ([]byte, bool) {
+	feedCache.mu.RLock()
+	store := feedCache.store
+	feedCache.mu.RUnlock()
+	if store == nil {
+		return nil, false
+	}
+	b, ok := store.Get(ctx, feedCacheKey(ctx, store, key))
+	if ok {
+		feedCache.hits.Add(1)
+	} else {
+		feedCache.misses.Add(1)
+	}
+	return b, ok
+}
+
+// FeedCacheSet stores a response under key for the configured TTL.
+func FeedCacheSet(ctx context.Context, key string, value []byte) {
+	feedCache.mu.RLock()
+	store, ttl := feedCache.store, feedCache.ttl
+	feedCache.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	store.Set(ctx, feedCacheKey(ctx, store, key), value, ttl)
+}
+
+// InvalidateFeedCache drops every cached feed page and image, e.g. after an upload,
+// delete or NSFW change, or when an uploader's name, avatar or badge changes.
+func InvalidateFeedCache(ctx context.Context) {
+	feedCache.mu.RLock()
+	store := feedCache.store
+	feedCache.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	store.Bump(ctx)
+	feedCache.invalidations.Add(1)
+}
+
+// GetFeedCacheStats returns the current hit/miss counters.
+func GetFeedCacheStats() FeedCacheStats {
+	feedCache.mu.RLock()
+	defer feedCache.mu.RUnlock()
+	return FeedCacheStats{
+		Enabled:       feedCache.store != nil,
+		Backend:       feedCache.backend,
+		Pages:         feedCache.pages,
+		TTLSeconds:    int(feedCache.ttl / time.Second),
+		Hits:          feedCache.hits.Load(),
+		Misses:        feedCache.misses.Load(),
+		Invalidations: feedCache.invalidations.Load(),
+	}
+}
+
+func feedCacheKey(ctx context.Context, store ResponseCacheStore, key string) string {
+	return "g" + strconv.FormatInt(store.Generation(ctx), 10) + ":" + key
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFeedCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	ConfigureFeedCache(NewMemoryCacheStore(10), "memory", 2, time.Minute)
+	defer ConfigureFeedCache(nil, "", 0, 0)
+
+	if !FeedCachePageCacheable(2) || FeedCachePageCacheable(3) {
+		t.Fatal("page range not respected")
+	}
+	if _, ok := FeedCacheGet(ctx, "feed:p1"); ok {
+		t.Fatal("unexpected hit on empty cache")
+	}
+	FeedCacheSet(ctx, "feed:p1", []byte(`{"images":[]}`))
+	if b, ok := FeedCacheGet(ctx, "feed:p1"); !ok || string(b) != `{"images":[]}` {
+		t.Fatalf("expected hit, got %q %v", b, ok)
+	}
+	InvalidateFeedCache(ctx)
+	if _, ok := FeedCacheGet(ctx, "feed:p1"); ok {
+		t.Fatal("entry survived invalidation")
+	}
+	st := GetFeedCacheStats()
+	if st.Hits != 1 || st.Misses != 2 || st.Invalidations != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
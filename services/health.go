@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// storageProbeTTL is how long a storage write test result is reused. Orchestrators poll
+// readiness every few seconds; writing an object each time would be wasteful on S3.
+const storageProbeTTL = 60 * time.Second
+
+var storageProbe struct {
+	mu      sync.Mutex
+	st      Storage
+	checked time.Time
+	err     error
+}
+
+// ProbeStorage writes and deletes a small object to prove st accepts uploads. Results
+// are cached per storage for a minute; cached reports whether this one was.
+func ProbeStorage(ctx context.Context, st Storage) (cached bool, err error) {
+	storageProbe.mu.Lock()
+	defer storageProbe.mu.Unlock()
+	if storageProbe.st == st && time.Since(storageProbe.checked) < storageProbeTTL {
+		return true, storageProbe.err
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	key := "healthcheck/" + hex.EncodeToString(b) + ".txt"
+	if _, err = st.Save(ctx, key, strings.NewReader("ok"), "text/plain"); err == nil {
+		err = st.Delete(ctx, key)
+	}
+	storageProbe.st, storageProbe.checked, storageProbe.err = st, time.Now(), err
+	return false, err
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yourusername/trough/models"
+)
+
+// Server-generated text (API errors, emails, server-rendered fallbacks) is written in
+// English in the code and translated at the edge. Bundles map the English message to
+// its translation, gettext style, so a missing entry simply falls back to English.
+// Admins can override any string per locale; overrides win over the bundles and are held
+// in memory like the tenant table.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// BaseLocale is the language the messages are written in.
+const BaseLocale = "en"
+
+var localeTagRe = regexp.MustCompile(`^[a-z]{2,3}(?:-[a-z0-9]{2,8})?$`)
+
+var (
+	localeBundles   map[string]map[string]string
+	localeOverrides atomic.Pointer[map[string]map[string]string]
+)
+
+func init() {
+	localeBundles = map[string]map[string]string{}
+	entries, _ := localeFiles.ReadDir("locales")
+	for _, e := range entries {
+		b, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			continue
+		}
+		m := map[string]string{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			panic(fmt.Sprintf("locale bundle %s: %v", e.Name(), err))
+		}
+		localeBundles[strings.TrimSuffix(e.Name(), path.Ext(e.Name()))] = m
+	}
+	SetLocaleOverrides(nil)
+}
+
+// NormalizeLocale lowercases a language tag such as "pt-BR" and checks its shape.
+func NormalizeLocale(raw string) (string, error) {
+	l := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "_", "-"))
+	if !localeTagRe.MatchString(l) {
+		return "", fmt.Errorf("invalid locale %q", raw)
+	}
+	return l, nil
+}
+
+// SetLocaleOverrides replaces the admin-edited strings.
+func SetLocaleOverrides(list []models.LocaleString) {
+	m := map[string]map[string]string{}
+	for _, s := range list {
+		if m[s.Locale] == nil {
+			m[s.Locale] = map[string]string{}
+		}
+		m[s.Locale][s.Msgid] = s.Text
+	}
+	localeOverrides.Store(&m)
+}
+
+// Locales lists the locales with any translations, the base locale first.
+func Locales() []string {
+	seen := map[string]bool{BaseLocale: true}
+	out := []string{}
+	for l := range localeBundles {
+		seen[l] = true
+	}
+	for l := range *localeOverrides.Load() {
+		seen[l] = true
+	}
+	for l := range seen {
+		if l != BaseLocale {
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+	return append([]string{BaseLocale}, out...)
+}
+
+// LocaleSupported reports whether l has a bundle or overrides (the base locale always does).
+func LocaleSupported(l string) bool {
+	if l == BaseLocale {
+		return true
+	}
+	if _, ok := localeBundles[l]; ok {
+		return true
+	}
+	_, ok := (*localeOverrides.Load())[l]
+	return ok
+}
+
+// BundleStrings returns the shipped translations for a locale.
+func BundleStrings(locale string) map[string]string {
+	out := make(map[string]string, len(localeBundles[locale]))
+	for k, v := range localeBundles[locale] {
+		out[k] = v
+	}
+	return out
+}
+
+// Catalog returns every message that has a shipped translation, sorted.
+func Catalog() []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, m := range localeBundles {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+var formatVerbRe = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// SameFormatVerbs reports whether a translation uses the same fmt verbs, in the same
+// order, as its msgid, so T cannot produce %!s(MISSING) garbage.
+func SameFormatVerbs(msgid, text string) bool {
+	verbs := func(s string) []string {
+		out := []string{}
+		for _, v := range formatVerbRe.FindAllString(s, -1) {
+			if v != "%%" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	a, b := verbs(msgid), verbs(text)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup resolves msgid in one locale: override, then bundle.
+func lookup(locale, msgid string) (string, bool) {
+	if s, ok := (*localeOverrides.Load())[locale][msgid]; ok {
+		return s, true
+	}
+	s, ok := localeBundles[locale][msgid]
+	return s, ok
+}
+
+// T translates msgid into locale, falling back from a region ("pt-br") to its language
+// ("pt") and then to the English msgid. With args the result is a fmt format.
+func T(locale, msgid string, args ...any) string {
+	out := msgid
+	if s, ok := lookup(locale, msgid); ok {
+		out = s
+	} else if i := strings.IndexByte(locale, '-'); i > 0 {
+		if s, ok := lookup(locale[:i], msgid); ok {
+			out = s
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(out, args...)
+	}
+	return out
+}
+
+// NegotiateLocale picks the best supported locale from an Accept-Language header, or
+// fallback when nothing matches. A region tag matches its bare language.
+func NegotiateLocale(acceptLanguage, fallback string) string {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if p, err := strconv.ParseFloat(v, 64); err == nil {
+					q = p
+				}
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{tag, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	for _, c := range choices {
+		if c.tag == "*" {
+			return fallback
+		}
+		if LocaleSupported(c.tag) {
+			return c.tag
+		}
+		if i := strings.IndexByte(c.tag, '-'); i > 0 && LocaleSupported(c.tag[:i]) {
+			return c.tag[:i]
+		}
+	}
+	return fallback
+}
+
+// SiteLocale returns a settings' default locale, or the base locale when unset.
+func SiteLocale(s models.SiteSettings) string {
+	if l := strings.TrimSpace(s.DefaultLocale); l != "" {
+		return l
+	}
+	return BaseLocale
+}
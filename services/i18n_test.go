@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestTranslate(t *testing.T) {
+	defer SetLocaleOverrides(nil)
+	if got := T("es", "Forbidden"); got != "Prohibido" {
+		t.Fatalf("expected bundle translation, got %q", got)
+	}
+	if got := T("es-mx", "%d new notifications", 3); got != "3 notificaciones nuevas" {
+		t.Fatalf("expected region fallback with args, got %q", got)
+	}
+	if got := T("fr", "Forbidden"); got != "Forbidden" {
+		t.Fatalf("expected English fallback, got %q", got)
+	}
+	SetLocaleOverrides([]models.LocaleString{{Locale: "es", Msgid: "Forbidden", Text: "No puedes pasar"}, {Locale: "en", Msgid: "Untitled", Text: "Nameless"}})
+	if T("es", "Forbidden") != "No puedes pasar" || T("en", "Untitled") != "Nameless" {
+		t.Fatalf("expected overrides to win")
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	cases := map[string]string{
+		"":                        "en",
+		"fr-FR,fr;q=0.9":          "en",
+		"de-AT,de;q=0.9,en;q=0.8": "de",
+		"en;q=0.5, es;q=0.9":      "es",
+		"es;q=0":                  "en",
+		"*":                       "en",
+		"pt-BR, ES-419;q=0.7, xx": "es",
+	}
+	for header, want := range cases {
+		if got := NegotiateLocale(header, "en"); got != want {
+			t.Errorf("NegotiateLocale(%q) = %q, want %q", header, got, want)
+		}
+	}
+	if got := NegotiateLocale("fr", "de"); got != "de" {
+		t.Fatalf("expected the site default, got %q", got)
+	}
+}
+
+func TestBundlesKeepFormatVerbs(t *testing.T) {
+	for locale := range localeBundles {
+		for msgid, text := range localeBundles[locale] {
+			if !SameFormatVerbs(msgid, text) {
+				t.Errorf("%s: %q changes the placeholders of %q", locale, text, msgid)
+			}
+		}
+	}
+	if SameFormatVerbs("%s commented: %s", "%s comentó") || !SameFormatVerbs("100%% of %d", "%d al 100%%") {
+		t.Fatalf("unexpected verb comparison")
+	}
+}
+
+func TestLocalizedEmails(t *testing.T) {
+	msg := BuildPasswordResetMessage("de", "Site", "https://x.y", "https://x.y/reset?token=t")
+	if !strings.HasPrefix(msg.Subject, "Setze dein Passwort zurück") || !strings.Contains(msg.Text, "Tipps für ein starkes Passwort") {
+		t.Fatalf("expected a German email, got %q / %q", msg.Subject, msg.Text)
+	}
+	if !strings.Contains(msg.HTML, `lang="de"`) || !strings.Contains(msg.HTML, "Neues Passwort wählen") {
+		t.Fatalf("expected German HTML, got %s", msg.HTML)
+	}
+	digest := BuildDigestMessage("es", "Site", "", "L", []string{DescribeNotification("es", models.Notification{Type: models.NotificationFollow})}, 1)
+	if !strings.HasPrefix(digest.Subject, "1 notificación nueva") || !strings.Contains(digest.Text, "Alguien empezó a seguirte") {
+		t.Fatalf("expected a Spanish digest, got %q / %q", digest.Subject, digest.Text)
+	}
+}
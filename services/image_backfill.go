@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+const (
+	// backfillBatch is how many image rows are read per query.
+	backfillBatch = 100
+	// backfillMaxAnimationBytes bounds how much of an animation is buffered to find its
+	// first frame; stills are decoded straight from the stream.
+	backfillMaxAnimationBytes = 64 << 20
+	// backfillErrorCap bounds how many error messages a result carries; Failed counts them all.
+	backfillErrorCap = 200
+)
+
+// ImageMetaBackfillResult is the progress and final result of an images.backfill_meta job.
+type ImageMetaBackfillResult struct {
+	Total     int      `json:"total"`
+	Processed int      `json:"processed"`
+	Updated   int      `json:"updated"`
+	Skipped   int      `json:"skipped"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors"`
+}
+
+func (r *ImageMetaBackfillResult) addError(msg string) {
+	r.Failed++
+	if len(r.Errors) < backfillErrorCap {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// backfillRow is an image missing its blurhash or dominant color.
+type backfillRow struct {
+	ID             uuid.UUID `db:"id"`
+	Filename       string    `db:"filename"`
+	MediaType      string    `db:"media_type"`
+	PosterFilename *string   `db:"poster_filename"`
+	FrameCount     int       `db:"frame_count"`
+}
+
+// stillRef is the stored file the row's blurhash and color come from: the poster for
+// videos, the file itself otherwise.
+func (r backfillRow) stillRef() string {
+	if r.MediaType == models.MediaTypeVideo {
+		if r.PosterFilename == nil {
+			return ""
+		}
+		return *r.PosterFilename
+	}
+	return r.Filename
+}
+
+const backfillMissing = `(blurhash IS NULL OR blurhash = '' OR dominant_color IS NULL OR dominant_color = '')`
+
+// CountImagesMissingMeta counts images without a blurhash or dominant color.
+func CountImagesMissingMeta(db *sqlx.DB) (int, error) {
+	var n int
+	err := db.Get(&n, `SELECT COUNT(*) FROM images WHERE `+backfillMissing)
+	return n, err
+}
+
+// BackfillImageMeta computes the blurhash and dominant color of every image that lacks
+// them, e.g. rows imported or created before either existed, reading each file from st.
+// Rows are walked by id, so a file that cannot be read is reported once and skipped.
+// Values already present are kept.
+func BackfillImageMeta(ctx context.Context, db *sqlx.DB, st Storage) (*ImageMetaBackfillResult, error) {
+	if db == nil || st == nil {
+		return nil, fmt.Errorf("storage or database not configured")
+	}
+	reader, ok := st.(ObjectReader)
+	if !ok {
+		return nil, errors.New("storage does not support reading objects")
+	}
+	result := &ImageMetaBackfillResult{Errors: []string{}}
+	total, err := CountImagesMissingMeta(db)
+	if err != nil {
+		return nil, err
+	}
+	result.Total = total
+	ReportJobProgress(ctx, result)
+
+	var (
+		after      uuid.UUID
+		lastReport time.Time
+	)
+	for {
+		var rows []backfillRow
+		err := db.SelectContext(ctx, &rows, `SELECT id, filename, media_type, poster_filename, frame_count
+			FROM images WHERE `+backfillMissing+` AND id > $1 ORDER BY id LIMIT $2`, after, backfillBatch)
+		if err != nil {
+			return result, err
+		}
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			after = row.ID
+			result.Processed++
+			ref := row.stillRef()
+			if ref == "" {
+				result.Skipped++
+				continue
+			}
+			meta, err := imageMetaFromStorage(ctx, reader, StorageKeyFromRef(ref), row.FrameCount > 1)
+			if err != nil {
+				result.addError(fmt.Sprintf("%s: %v", row.ID, err))
+				continue
+			}
+			if _, err := db.ExecContext(ctx, `UPDATE images SET
+				blurhash = COALESCE(NULLIF(blurhash, ''), NULLIF($2, '')),
+				dominant_color = COALESCE(NULLIF(dominant_color, ''), NULLIF($3, ''))
+				WHERE id = $1`, row.ID, meta.Blurhash, meta.DominantColor); err != nil {
+				result.addError(fmt.Sprintf("%s: %v", row.ID, err))
+				continue
+			}
+			result.Updated++
+			if time.Since(lastReport) >= time.Second {
+				lastReport = time.Now()
+				ReportJobProgress(ctx, result)
+			}
+		}
+		if len(rows) < backfillBatch {
+			break
+		}
+	}
+	if result.Updated > 0 {
+		InvalidateFeedCache(ctx)
+	}
+	return result, nil
+}
+
+// imageMetaFromStorage decodes the object at key and computes its metadata. Animations
+// are buffered to decode their first frame; stills are decoded as they stream in.
+func imageMetaFromStorage(ctx context.Context, reader ObjectReader, key string, animated bool) (ImageMeta, error) {
+	rc, err := reader.Open(ctx, key)
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	defer rc.Close()
+	if !animated {
+		img, format, err := image.Decode(rc)
+		if err != nil {
+			return ImageMeta{}, err
+		}
+		return ProcessDecodedImage(img, format), nil
+	}
+	raw, err := io.ReadAll(io.LimitReader(rc, backfillMaxAnimationBytes+1))
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	if len(raw) > backfillMaxAnimationBytes {
+		return ImageMeta{}, errors.New("animation too large")
+	}
+	var img image.Image
+	format := ""
+	if _, ok := InspectAnimation(raw); ok {
+		img, err = DecodeFirstFrame(raw)
+	} else {
+		img, format, err = image.Decode(bytes.NewReader(raw))
+	}
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	return ProcessDecodedImage(img, format), nil
+}
+
+// RegisterImageMetaBackfillJob registers the admin-triggered blurhash and dominant color
+// backfill. It reads from the storage in use when the job runs.
+func RegisterImageMetaBackfillJob(db *sqlx.DB) {
+	RegisterJob(JobSpec{
+		Kind:        JobImageMetaBackfill,
+		Timeout:     6 * time.Hour,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			st := GetCurrentStorage()
+			if st == nil {
+				return nil, PermanentJobError(errors.New("storage not configured"))
+			}
+			if _, ok := st.(ObjectReader); !ok {
+				return nil, PermanentJobError(errors.New("storage does not support reading objects"))
+			}
+			return BackfillImageMeta(ctx, db, st)
+		},
+	})
+}
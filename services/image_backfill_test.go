@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestImageMetaFromStorage(t *testing.T) {
+	dir := t.TempDir()
+	red := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			red.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	f, err := os.Create(filepath.Join(dir, "still.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, red); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	pal := color.Palette{color.RGBA{B: 255, A: 255}, color.RGBA{G: 255, A: 255}}
+	anim := &gif.GIF{}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), pal)
+		for p := range frame.Pix {
+			frame.Pix[p] = uint8(i)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	f, err = os.Create(filepath.Join(dir, "anim.gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	st := NewLocalStorage(dir)
+	meta, err := imageMetaFromStorage(context.Background(), st, "still.png", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Blurhash == "" || !strings.EqualFold(meta.DominantColor, "#b20000") {
+		t.Fatalf("unexpected still meta %+v", meta)
+	}
+	meta, err = imageMetaFromStorage(context.Background(), st, "anim.gif", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Blurhash == "" || !strings.EqualFold(meta.DominantColor, "#0000b2") {
+		t.Fatalf("expected the first frame's color, got %+v", meta)
+	}
+	if _, err := imageMetaFromStorage(context.Background(), st, "missing.png", false); err == nil {
+		t.Fatal("expected an error for a missing object")
+	}
+}
+
+func TestBackfillRowStillRef(t *testing.T) {
+	poster := "poster.jpg"
+	cases := []struct {
+		row  backfillRow
+		want string
+	}{
+		{backfillRow{Filename: "a.png", MediaType: models.MediaTypeImage}, "a.png"},
+		{backfillRow{Filename: "v.mp4", MediaType: models.MediaTypeVideo, PosterFilename: &poster}, "poster.jpg"},
+		{backfillRow{Filename: "v.mp4", MediaType: models.MediaTypeVideo}, ""},
+	}
+	for _, c := range cases {
+		if got := c.row.stillRef(); got != c.want {
+			t.Errorf("stillRef(%+v) = %q, want %q", c.row, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// Job kinds. Periodic kinds are a single row re-armed after each run; the rest are
+// enqueued on demand.
+const (
+	JobMailOutbox         = "mail.outbox"
+	JobBackup             = "backup.create"
+	JobBackupScheduled    = "backup.scheduled"
+	JobReconcile          = "storage.reconcile"
+	JobReconcileScheduled = "storage.reconcile.scheduled"
+	JobStorageMigrate     = "storage.migrate"
+	JobUploadProcess      = "upload.process"
+	JobImageMetaBackfill  = "images.backfill_meta"
+	JobAIRedetect         = "ai.redetect"
+	JobChecksumVerify     = "images.verify_checksums"
+	JobCDNPurge           = "cdn.purge"
+	jobPurge              = "jobs.purge"
+)
+
+const (
+	jobLease          = 2 * time.Minute
+	jobDefaultTimeout = time.Hour
+	jobMaxAttempts    = 5
+	jobRetention      = 14 * 24 * time.Hour
+)
+
+// ErrJobsUnavailable is returned by EnqueueJob before InitJobs; callers fall back to
+// running the work inline.
+var ErrJobsUnavailable = errors.New("job queue not initialized")
+
+// JobFunc runs one job. The returned value is stored as the job's JSON result, for
+// failures too when it is non-nil.
+type JobFunc func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// JobSpec describes a job kind.
+type JobSpec struct {
+	Kind string
+	Run  JobFunc
+	// Timeout bounds one run; zero means one hour.
+	Timeout time.Duration
+	// MaxAttempts bounds retries of one-shot jobs; zero means 5.
+	MaxAttempts int
+	// Every makes the kind periodic and returns the delay until its next run. It is read
+	// after each run, so settings-driven intervals take effect without a restart.
+	Every func() time.Duration
+}
+
+var (
+	jobsMu   sync.RWMutex
+	jobSpecs = map[string]JobSpec{}
+	jobRepo  models.JobRepositoryInterface
+	jobWake  chan struct{}
+	// jobWorkers counts the workers StartJobWorkers started on this instance
+	jobWorkers int
+)
+
+// RegisterJob makes a kind runnable by this instance's workers. Workers only claim kinds
+// they know, so instances running different versions can share one queue.
+func RegisterJob(spec JobSpec) {
+	jobsMu.Lock()
+	jobSpecs[spec.Kind] = spec
+	repo := jobRepo
+	jobsMu.Unlock()
+	if repo != nil && spec.Every != nil {
+		ensurePeriodicJob(repo, spec)
+	}
+}
+
+// InitJobs connects the queue to repo so EnqueueJob persists work, and registers the
+// periodic rows of kinds registered so far. Workers are started separately with
+// StartJobWorkers.
+func InitJobs(repo models.JobRepositoryInterface) {
+	if repo == nil {
+		return
+	}
+	jobsMu.Lock()
+	if jobRepo != nil {
+		jobsMu.Unlock()
+		return
+	}
+	jobRepo = repo
+	jobWake = make(chan struct{}, 1)
+	jobSpecs[jobPurge] = JobSpec{Kind: jobPurge, Every: func() time.Duration { return time.Hour }, Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+		n, err := repo.Purge(time.Now().Add(-jobRetention))
+		return map[string]int{"purged": n}, err
+	}}
+	specs := make([]JobSpec, 0, len(jobSpecs))
+	for _, s := range jobSpecs {
+		specs = append(specs, s)
+	}
+	jobsMu.Unlock()
+	for _, s := range specs {
+		if s.Every != nil {
+			ensurePeriodicJob(repo, s)
+		}
+	}
+}
+
+// ensurePeriodicJob creates the row for a periodic kind unless one exists already.
+func ensurePeriodicJob(repo models.JobRepositoryInterface, spec JobSpec) {
+	key := spec.Kind
+	if _, err := repo.Enqueue(&models.Job{Kind: spec.Kind, UniqueKey: &key, Periodic: true, MaxAttempts: 1}); err != nil {
+		Logger(context.Background()).Error("jobs: register periodic failed", "kind", spec.Kind, "error", err)
+	}
+}
+
+// StartJobWorkers runs n workers that poll for due jobs every poll interval, and
+// immediately when work is enqueued on this instance.
+func StartJobWorkers(n int, poll time.Duration) {
+	jobsMu.RLock()
+	repo, wake := jobRepo, jobWake
+	jobsMu.RUnlock()
+	if repo == nil || n <= 0 {
+		return
+	}
+	jobsMu.Lock()
+	jobWorkers += n
+	jobsMu.Unlock()
+	for i := 0; i < n; i++ {
+		go func() {
+			ticker := time.NewTicker(poll)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-wake:
+				case <-ShuttingDown():
+					return
+				}
+				for {
+					if !BeginWork() {
+						return
+					}
+					ran := runNextJob(repo)
+					EndWork()
+					if !ran {
+						break
+					}
+				}
+			}
+		}()
+	}
+}
+
+// JobWorkerStatus reports how many workers run on this instance and the job kinds they
+// can claim.
+func JobWorkerStatus() (workers int, kinds []string) {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+	for k := range jobSpecs {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return jobWorkers, kinds
+}
+
+// runNextJob claims and runs one due job, reporting whether there was one.
+func runNextJob(repo models.JobRepositoryInterface) bool {
+	jobsMu.RLock()
+	kinds := make([]string, 0, len(jobSpecs))
+	for k := range jobSpecs {
+		kinds = append(kinds, k)
+	}
+	jobsMu.RUnlock()
+	sort.Strings(kinds)
+	batch, err := repo.ClaimDue(kinds, 1, jobLease)
+	if err != nil {
+		Logger(context.Background()).Error("jobs: claim failed", "error", err)
+		return false
+	}
+	if len(batch) == 0 {
+		return false
+	}
+	jobsMu.RLock()
+	spec := jobSpecs[batch[0].Kind]
+	jobsMu.RUnlock()
+	RunJob(repo, spec, &batch[0])
+	return true
+}
+
+// RunJob runs a claimed job and records the outcome: periodic jobs are re-armed, failed
+// one-shot jobs are retried with backoff and dead-lettered after MaxAttempts, or at once
+// for a PermanentJobError.
+func RunJob(repo models.JobRepositoryInterface, spec JobSpec, job *models.Job) {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = jobDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, jobProgressKey{}, func(v interface{}) {
+		if b, err := json.Marshal(v); err == nil {
+			_ = repo.SetProgress(job.ID, b)
+		}
+	})
+
+	// Keep the lease alive while the job runs so another worker does not reclaim it
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		t := time.NewTicker(jobLease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				_ = repo.Extend(job.ID, jobLease)
+			}
+		}
+	}()
+
+	start := time.Now()
+	res, err := runJobFunc(ctx, spec, job)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	JobRuns.Inc(job.Kind, result)
+	JobDuration.ObserveSince(start, job.Kind)
+
+	var raw json.RawMessage
+	if res != nil {
+		// A typed nil pointer marshals to null; store no result instead
+		if b, mErr := json.Marshal(res); mErr == nil && string(b) != "null" {
+			raw = b
+		}
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+		if len(errMsg) > 500 {
+			errMsg = errMsg[:500]
+		}
+		Logger(ctx).Warn("jobs: run failed", "kind", job.Kind, "job_id", job.ID, "attempt", job.Attempts, "max_attempts", job.MaxAttempts, "error", errMsg)
+	}
+	if job.Periodic {
+		next := time.Hour
+		if spec.Every != nil {
+			next = spec.Every()
+		}
+		if err := repo.Rearm(job.ID, raw, errMsg, time.Now().Add(next)); err != nil {
+			Logger(ctx).Error("jobs: re-arm failed", "kind", job.Kind, "error", err)
+		}
+		return
+	}
+	if err == nil {
+		if err := repo.MarkDone(job.ID, raw); err != nil {
+			Logger(ctx).Error("jobs: mark done failed", "kind", job.Kind, "error", err)
+		}
+		return
+	}
+	dead := job.Attempts >= job.MaxAttempts || IsPermanentJobError(err)
+	if err := repo.MarkFailed(job.ID, raw, errMsg, time.Now().Add(jobRetryDelay(job.Attempts)), dead); err != nil {
+		Logger(ctx).Error("jobs: recording failure failed", "kind", job.Kind, "error", err)
+	}
+}
+
+type jobProgressKey struct{}
+
+// ReportJobProgress stores v as the running job's result so pollers can follow long work;
+// the final result replaces it. Outside a job it does nothing. Callers should throttle it,
+// as every call is a write.
+func ReportJobProgress(ctx context.Context, v interface{}) {
+	if report, ok := ctx.Value(jobProgressKey{}).(func(interface{})); ok {
+		report(v)
+	}
+}
+
+// runJobFunc turns a panic in a job into an error so one bad job cannot kill a worker.
+func runJobFunc(ctx context.Context, spec JobSpec, job *models.Job) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if spec.Run == nil {
+		return nil, fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+	return spec.Run(ctx, job)
+}
+
+// permanentJobError marks a failure that retrying cannot fix.
+type permanentJobError struct{ err error }
+
+func (e permanentJobError) Error() string { return e.err.Error() }
+func (e permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError wraps err so RunJob dead-letters the job instead of retrying it, e.g.
+// when the input itself is rejected.
+func PermanentJobError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentJobError{err}
+}
+
+// IsPermanentJobError reports whether err was wrapped with PermanentJobError.
+func IsPermanentJobError(err error) bool {
+	var p permanentJobError
+	return errors.As(err, &p)
+}
+
+// jobRetryDelay backs off exponentially from 30s, capped at one hour.
+func jobRetryDelay(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// JobOptions tunes EnqueueJob.
+type JobOptions struct {
+	// UniqueKey keeps at most one pending or running job per key; enqueueing again returns
+	// the existing job with created false.
+	UniqueKey string
+	// RunAt delays the first run; zero means now.
+	RunAt time.Time
+}
+
+// EnqueueJob persists a one-shot job of a registered kind and wakes a local worker.
+func EnqueueJob(kind string, payload interface{}, opts JobOptions) (job *models.Job, created bool, err error) {
+	jobsMu.RLock()
+	repo := jobRepo
+	spec, ok := jobSpecs[kind]
+	jobsMu.RUnlock()
+	if repo == nil {
+		return nil, false, ErrJobsUnavailable
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("unknown job kind %q", kind)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false, err
+	}
+	job = &models.Job{Kind: kind, Payload: b, MaxAttempts: spec.MaxAttempts, RunAt: opts.RunAt}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = jobMaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+	created, err = repo.Enqueue(job)
+	if err != nil {
+		return nil, false, err
+	}
+	wakeJobs()
+	return job, created, nil
+}
+
+// WakePeriodicJob makes a periodic kind due now, e.g. after mail is queued.
+func WakePeriodicJob(kind string) {
+	jobsMu.RLock()
+	repo := jobRepo
+	jobsMu.RUnlock()
+	if repo == nil {
+		return
+	}
+	if err := repo.RunNow(kind); err != nil {
+		Logger(context.Background()).Error("jobs: wake failed", "kind", kind, "error", err)
+		return
+	}
+	wakeJobs()
+}
+
+func wakeJobs() {
+	if jobWake == nil {
+		return
+	}
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+// recordingJobRepo captures how RunJob settles a job.
+type recordingJobRepo struct {
+	models.JobRepositoryInterface
+	done     json.RawMessage
+	doneSet  bool
+	failed   string
+	dead     bool
+	next     time.Time
+	rearmErr string
+	rearmed  bool
+}
+
+func (r *recordingJobRepo) Extend(uuid.UUID, time.Duration) error { return nil }
+
+func (r *recordingJobRepo) MarkDone(_ uuid.UUID, result json.RawMessage) error {
+	r.done, r.doneSet = result, true
+	return nil
+}
+
+func (r *recordingJobRepo) MarkFailed(_ uuid.UUID, result json.RawMessage, errMsg string, next time.Time, dead bool) error {
+	r.done, r.failed, r.next, r.dead = result, errMsg, next, dead
+	return nil
+}
+
+func (r *recordingJobRepo) Rearm(_ uuid.UUID, _ json.RawMessage, errMsg string, next time.Time) error {
+	r.rearmed, r.rearmErr, r.next = true, errMsg, next
+	return nil
+}
+
+func TestRunJob_Success(t *testing.T) {
+	repo := &recordingJobRepo{}
+	spec := JobSpec{Kind: "test.ok", Run: func(ctx context.Context, j *models.Job) (interface{}, error) {
+		return map[string]int{"n": 3}, nil
+	}}
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.ok", Attempts: 1, MaxAttempts: 5})
+	if !repo.doneSet || string(repo.done) != `{"n":3}` {
+		t.Fatalf("expected done with result, got %+v", repo)
+	}
+}
+
+func TestRunJob_FailureRetriesThenDies(t *testing.T) {
+	spec := JobSpec{Kind: "test.fail", Run: func(ctx context.Context, j *models.Job) (interface{}, error) {
+		return nil, errors.New("boom")
+	}}
+	repo := &recordingJobRepo{}
+	before := time.Now()
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.fail", Attempts: 1, MaxAttempts: 3})
+	if repo.failed != "boom" || repo.dead || repo.next.Sub(before) < 30*time.Second {
+		t.Fatalf("expected a retry in ~30s, got %+v", repo)
+	}
+
+	repo = &recordingJobRepo{}
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.fail", Attempts: 3, MaxAttempts: 3})
+	if !repo.dead {
+		t.Fatal("expected the job to be dead-lettered after its last attempt")
+	}
+}
+
+func TestRunJob_PanicIsAFailure(t *testing.T) {
+	repo := &recordingJobRepo{}
+	spec := JobSpec{Kind: "test.panic", Run: func(ctx context.Context, j *models.Job) (interface{}, error) {
+		panic("nil map")
+	}}
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.panic", Attempts: 1, MaxAttempts: 1})
+	if !repo.dead || !strings.Contains(repo.failed, "nil map") {
+		t.Fatalf("expected panic to dead-letter the job, got %+v", repo)
+	}
+}
+
+func TestRunJob_PeriodicRearms(t *testing.T) {
+	repo := &recordingJobRepo{}
+	spec := JobSpec{
+		Kind:  "test.periodic",
+		Every: func() time.Duration { return 10 * time.Minute },
+		Run: func(ctx context.Context, j *models.Job) (interface{}, error) {
+			return nil, errors.New("smtp down")
+		},
+	}
+	before := time.Now()
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.periodic", Periodic: true, Attempts: 1, MaxAttempts: 1})
+	if !repo.rearmed || repo.dead || repo.failed != "" {
+		t.Fatalf("periodic job must be re-armed, never failed: %+v", repo)
+	}
+	if repo.rearmErr != "smtp down" || repo.next.Sub(before) < 10*time.Minute {
+		t.Fatalf("unexpected re-arm: %+v", repo)
+	}
+}
+
+func TestJobRetryDelay(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, w := range want {
+		if got := jobRetryDelay(i + 1); got != w {
+			t.Errorf("attempt %d: got %s, want %s", i+1, got, w)
+		}
+	}
+	if got := jobRetryDelay(20); got != time.Hour {
+		t.Errorf("delay should cap at an hour, got %s", got)
+	}
+}
+
+func TestEnqueueJob_Uninitialized(t *testing.T) {
+	if _, _, err := EnqueueJob(JobBackup, BackupJob{}, JobOptions{}); !errors.Is(err, ErrJobsUnavailable) {
+		t.Fatalf("expected ErrJobsUnavailable, got %v", err)
+	}
+}
+
+func TestRunJob_PermanentErrorSkipsRetries(t *testing.T) {
+	repo := &recordingJobRepo{}
+	spec := JobSpec{Kind: "test.reject", Run: func(ctx context.Context, j *models.Job) (interface{}, error) {
+		return map[string]int{"status": 400}, PermanentJobError(errors.New("not an AI image"))
+	}}
+	RunJob(repo, spec, &models.Job{ID: uuid.New(), Kind: "test.reject", Attempts: 1, MaxAttempts: 5})
+	if !repo.dead || repo.failed != "not an AI image" {
+		t.Fatalf("expected a permanent failure to dead-letter at once, got %+v", repo)
+	}
+	if string(repo.done) != `{"status":400}` {
+		t.Fatalf("expected the failure result to be kept, got %s", repo.done)
+	}
+}
@@ -11,6 +11,17 @@ import (
 // Order of APP1 segments: EXIF first, then XMP. If a segment would exceed
 // the JPEG APP1 maximum size, it is skipped to preserve a valid file.
 func EncodeJPEGWithMetadata(img image.Image, quality int, xmpXML []byte, exifRaw []byte) ([]byte, error) {
+	return EncodeJPEGWithFilteredMetadata(img, quality, xmpXML, exifRaw, nil)
+}
+
+// EncodeJPEGWithFilteredMetadata is EncodeJPEGWithMetadata with selective tag filtering.
+// When filter is non-nil the EXIF payload is rewritten without the filtered tags and
+// GPS properties are stripped from the XMP packet before either is embedded.
+func EncodeJPEGWithFilteredMetadata(img image.Image, quality int, xmpXML []byte, exifRaw []byte, filter *ExifFilter) ([]byte, error) {
+	if filter != nil {
+		exifRaw = FilterExif(exifRaw, *filter)
+		xmpXML = StripXMPLocation(xmpXML)
+	}
 	var buf bytes.Buffer
 	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
 		return nil, err
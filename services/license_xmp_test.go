@@ -0,0 +1,39 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestWithLicenseXMP_KeepsExistingPacket(t *testing.T) {
+	lic, _ := models.LicenseByID("cc-by-4.0")
+	src := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="" xmlns:tool="x"><tool:prompt>a cat</tool:prompt></rdf:Description></rdf:RDF></x:xmpmeta>`)
+	out := WithLicenseXMP(src, lic, "alice<&>")
+	s := string(out)
+	if !strings.Contains(s, `<tool:prompt>a cat</tool:prompt></rdf:Description>`) {
+		t.Fatalf("original description altered: %s", s)
+	}
+	if !strings.Contains(s, `<cc:license rdf:resource="`+lic.URL+`"/>`) {
+		t.Fatalf("license missing: %s", s)
+	}
+	if !strings.Contains(s, "alice&lt;&amp;&gt;") || strings.Contains(s, "alice<&>") {
+		t.Fatalf("creator not escaped: %s", s)
+	}
+	if !bytes.HasSuffix(out, []byte(`</rdf:Description></rdf:RDF></x:xmpmeta>`)) {
+		t.Fatalf("license description not placed before </rdf:RDF>: %s", s)
+	}
+}
+
+func TestWithLicenseXMP_BuildsPacket(t *testing.T) {
+	lic, _ := models.LicenseByID("cc0-1.0")
+	s := string(WithLicenseXMP(nil, lic, ""))
+	if !strings.HasPrefix(s, "<x:xmpmeta") || !strings.Contains(s, "</rdf:RDF></x:xmpmeta>") {
+		t.Fatalf("expected minimal packet, got %s", s)
+	}
+	if !strings.Contains(s, "<xmpRights:Marked>False</xmpRights:Marked>") {
+		t.Fatalf("cc0 should be unmarked: %s", s)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Live events fan out to streaming clients through in-process hubs: the public feed
+// stream (/api/feed/stream) and the admin monitor (/api/admin/ws). Publishing never
+// blocks: a subscriber that falls behind loses events rather than stalling uploads or the
+// rate limiter, and learns how many it missed from Dropped. With prefork or several
+// instances each process only sees its own publishes.
+
+// Feed event types, used as the SSE event name.
+const (
+	FeedEventImage     = "image"
+	FeedEventCollected = "collected"
+)
+
+// Admin monitor event types.
+const (
+	AdminEventSecurity   = "security"
+	AdminEventUpload     = "upload"
+	AdminEventModeration = "moderation"
+	AdminEventTakedown   = "takedown"
+)
+
+// LiveEvent is one message for stream subscribers. NSFW feed events are withheld from
+// viewers whose feed hides NSFW images, so their "new images" count matches a refresh.
+type LiveEvent struct {
+	ID   uint64
+	Type string
+	NSFW bool
+	Data any
+}
+
+// ErrStreamFull is returned when a hub's subscriber limit is reached.
+var ErrStreamFull = errors.New("too many stream subscribers")
+
+// Subscriber limits per process; each open stream holds a connection and a buffer.
+const (
+	MaxFeedSubscribers  = 2000
+	MaxAdminSubscribers = 50
+)
+
+const liveSubscriberBuffer = 32
+
+// Subscription is an open stream. Cancel must be called when the stream ends; it closes C.
+type Subscription struct {
+	C       <-chan LiveEvent
+	ch      chan LiveEvent
+	dropped atomic.Uint64
+	cancel  func()
+}
+
+// Dropped returns and resets the number of events lost since the last call.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Swap(0) }
+
+func (s *Subscription) Cancel() { s.cancel() }
+
+type eventHub struct {
+	name string
+	max  int
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	seq  atomic.Uint64
+}
+
+func newEventHub(name string, max int) *eventHub {
+	h := &eventHub{name: name, max: max, subs: map[*Subscription]struct{}{}}
+	RegisterGaugeFunc("trough_"+name+"_stream_subscribers", "Open "+name+" event streams.", func() float64 {
+		return float64(h.count())
+	})
+	return h
+}
+
+var (
+	liveFeed    = newEventHub("feed", MaxFeedSubscribers)
+	adminEvents = newEventHub("admin", MaxAdminSubscribers)
+)
+
+// SubscribeFeed opens a live feed subscription.
+func SubscribeFeed() (*Subscription, error) { return liveFeed.subscribe() }
+
+// PublishFeedEvent sends a feed event to every subscriber without waiting on any of them.
+func PublishFeedEvent(typ string, nsfw bool, data any) { liveFeed.publish(typ, nsfw, data) }
+
+// FeedSubscribers reports the number of open feed streams.
+func FeedSubscribers() int { return liveFeed.count() }
+
+// SubscribeAdminEvents opens an admin monitor subscription.
+func SubscribeAdminEvents() (*Subscription, error) { return adminEvents.subscribe() }
+
+// PublishAdminEvent sends an event to connected admin monitors.
+func PublishAdminEvent(typ string, data any) { adminEvents.publish(typ, false, data) }
+
+// AdminSubscribers reports the number of open admin monitors.
+func AdminSubscribers() int { return adminEvents.count() }
+
+func (h *eventHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *eventHub) subscribe() (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) >= h.max {
+		return nil, ErrStreamFull
+	}
+	ch := make(chan LiveEvent, liveSubscriberBuffer)
+	s := &Subscription{C: ch, ch: ch}
+	var once sync.Once
+	s.cancel = func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, s)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+func (h *eventHub) publish(typ string, nsfw bool, data any) {
+	ev := LiveEvent{ID: h.seq.Add(1), Type: typ, NSFW: nsfw, Data: data}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+			LiveEventsDropped.Inc(h.name)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import "testing"
+
+func TestEventHub(t *testing.T) {
+	hub := &eventHub{name: "test", max: 1, subs: map[*Subscription]struct{}{}}
+	sub, err := hub.subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.subscribe(); err != ErrStreamFull {
+		t.Fatalf("expected the subscriber limit to apply, got %v", err)
+	}
+	hub.publish(FeedEventImage, false, "a")
+	hub.publish(FeedEventCollected, true, "b")
+	first, second := <-sub.C, <-sub.C
+	if first.Type != FeedEventImage || second.Type != FeedEventCollected || !second.NSFW || second.ID <= first.ID {
+		t.Fatalf("unexpected events %+v %+v", first, second)
+	}
+
+	// A subscriber that stops reading loses events instead of blocking publishers
+	for i := 0; i < liveSubscriberBuffer+5; i++ {
+		hub.publish(FeedEventImage, false, i)
+	}
+	if len(sub.C) != liveSubscriberBuffer {
+		t.Fatalf("expected a full buffer, got %d", len(sub.C))
+	}
+	if n := sub.Dropped(); n != 5 {
+		t.Fatalf("expected 5 dropped events, got %d", n)
+	}
+	if n := sub.Dropped(); n != 0 {
+		t.Fatalf("Dropped should reset, got %d", n)
+	}
+
+	sub.Cancel()
+	sub.Cancel()
+	if hub.count() != 0 {
+		t.Fatalf("Cancel should unsubscribe, got %d", hub.count())
+	}
+	for range sub.C {
+	}
+}
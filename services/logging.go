@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type requestIDKey struct{}
+
+// RequestIDContextKey is the key the request ID is stored under, both in c.Locals and in
+// the request's user context, so anything holding c.Context() can log with it.
+var RequestIDContextKey = requestIDKey{}
+
+// InitLogging installs the default structured logger: JSON on stdout, or logfmt-style
+// text with LOG_FORMAT=text, at LOG_LEVEL (debug, info, warn, error; default info).
+// The standard log package writes through the same handler, so output from
+// dependencies that use it becomes structured records too.
+func InitLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(os.Getenv("LOG_LEVEL")))); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("LOG_FORMAT")), "text") {
+		h = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		h = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(h))
+}
+
+// NewRequestID returns a fresh request ID.
+func NewRequestID() string { return uuid.NewString() }
+
+// ValidRequestID accepts caller-supplied IDs (e.g. from a load balancer) that are short
+// and limited to characters safe to echo into headers, JSON and logs unescaped.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(RequestIDContextKey).(string)
+	return id
+}
+
+// Logger returns the default logger annotated with the request and trace IDs in ctx.
+func Logger(ctx context.Context) *slog.Logger {
+	l := slog.Default()
+	if id := RequestIDFromContext(ctx); id != "" {
+		l = l.With("request_id", id)
+	}
+	if s := SpanFromContext(ctx); s != nil {
+		l = l.With("trace_id", s.TraceID())
+	}
+	return l
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+)
+
+// HashIP returns a keyed hash of an IP address, so sign-ins can be compared without
+// storing the address. The key derives from JWT_SECRET; changing it makes every known
+// device and location look new once.
+func HashIP(ip string) string {
+	mac := hmac.New(sha256.New, []byte("trough-ip:"+os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IPNetwork returns the /24 (IPv4) or /48 (IPv6) network an address belongs to, a rough
+// stand-in for location when no country is known. Unparseable input is returned as is.
+func IPNetwork(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// DescribeDevice names the browser and OS family in a user agent, e.g. "Firefox on Linux".
+// Versions are left out so browser updates don't count as a new device.
+func DescribeDevice(ua string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also claim Chrome, Chrome also claims Safari
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	platform := "unknown OS"
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"Macintosh", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+	return browser + " on " + platform
+}
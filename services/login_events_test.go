@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestDescribeDevice(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0":                                                                  "Firefox on Linux",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36 Edg/126.0":                   "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Mobile Safari/537.36":                                "Chrome on Android",
+		"": "Unknown browser on unknown OS",
+	}
+	for ua, want := range cases {
+		if got := DescribeDevice(ua); got != want {
+			t.Errorf("DescribeDevice(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestIPNetworkAndHash(t *testing.T) {
+	if got := IPNetwork("203.0.113.77"); got != "203.0.113.0/24" {
+		t.Fatalf("IPv4 network: %q", got)
+	}
+	if got := IPNetwork("2001:db8:1234:5678::1"); got != "2001:db8:1234::/48" {
+		t.Fatalf("IPv6 network: %q", got)
+	}
+	t.Setenv("JWT_SECRET", "one")
+	a := HashIP("203.0.113.77")
+	if a == HashIP("203.0.113.78") || len(a) != 64 {
+		t.Fatal("different addresses should hash differently")
+	}
+	t.Setenv("JWT_SECRET", "two")
+	if a == HashIP("203.0.113.77") {
+		t.Fatal("the hash should be keyed by the secret")
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yourusername/trough/models"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// CMS pages are rendered from markdown on save and the sanitized HTML is stored with the
+// page, so the API and server-rendered pages serve the same markup. The dialect follows
+// the SPA editor: GFM with hard line breaks, footnotes, ::: containers (admonitions and
+// collapsible details) and an optional [[TOC]] placeholder. Raw HTML is allowed in the
+// source and everything is passed through a strict allowlist afterwards.
+
+// admonitionKinds are the ::: container names styled as callouts.
+var admonitionKinds = map[string]bool{"note": true, "info": true, "tip": true, "warning": true, "danger": true, "success": true, "quote": true}
+
+var (
+	markdownOnce   sync.Once
+	markdownEngine goldmark.Markdown
+	markdownPolicy *bluemonday.Policy
+)
+
+func initMarkdown() {
+	markdownEngine = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(gmhtml.WithHardWraps(), gmhtml.WithUnsafe()),
+	)
+
+	p := bluemonday.UGCPolicy()
+	classRe := regexp.MustCompile(`^[a-z0-9 _-]{1,100}$`)
+	p.AllowAttrs("class").Matching(classRe).OnElements("div", "details", "nav", "ul", "ol", "li", "a", "code", "pre", "span", "sup", "section", "hr")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).OnElements("a", "div", "section")
+	p.AllowElements("nav")
+	// GFM task lists
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	// Only off-site links get nofollow and open in a new tab; anchors and site paths stay as-is
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	markdownPolicy = p
+}
+
+// RenderMarkdown converts CMS markdown to sanitized HTML.
+func RenderMarkdown(src string) (string, error) {
+	markdownOnce.Do(initMarkdown)
+	source := []byte(expandContainers(strings.ReplaceAll(src, "\r\n", "\n")))
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := markdownEngine.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := markdownEngine.Renderer().Render(&buf, source, doc); err != nil {
+		return "", err
+	}
+	out := buf.String()
+	if strings.Contains(out, "<p>[[TOC]]</p>") {
+		out = strings.Replace(out, "<p>[[TOC]]</p>", tableOfContents(doc, source), 1)
+	}
+	return markdownPolicy.Sanitize(out), nil
+}
+
+// PageHTML returns a page's stored HTML, rendering the markdown for pages saved before
+// server-side rendering existed.
+func PageHTML(p *models.Page) string {
+	if p.HTML != "" || strings.TrimSpace(p.Markdown) == "" {
+		return p.HTML
+	}
+	out, err := RenderMarkdown(p.Markdown)
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+var containerOpenRe = regexp.MustCompile(`^:::\s*([a-z]+)\s*(.*)$`)
+
+// expandContainers rewrites ::: blocks into HTML wrappers with blank lines around the
+// content, so the content is still parsed as markdown. Fenced code is left untouched.
+func expandContainers(src string) string {
+	lines := strings.Split(src, "\n")
+	out := make([]string, 0, len(lines))
+	var stack []string
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			out = append(out, line)
+			continue
+		}
+		if trimmed == ":::" && len(stack) > 0 {
+			out = append(out, "", stack[len(stack)-1], "")
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if m := containerOpenRe.FindStringSubmatch(trimmed); m != nil {
+			switch {
+			case admonitionKinds[m[1]]:
+				out = append(out, "", `<div class="admon admon-`+m[1]+`">`, "")
+				stack = append(stack, "</div>")
+				continue
+			case m[1] == "details":
+				title := strings.TrimSpace(m[2])
+				if title == "" {
+					title = "Details"
+				}
+				out = append(out, "", `<details class="md-details"><summary>`+html.EscapeString(title)+`</summary>`, "")
+				stack = append(stack, "</details>")
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		out = append(out, "", stack[i])
+	}
+	return strings.Join(out, "\n")
+}
+
+// tableOfContents lists the document's headings, linking to their generated ids.
+func tableOfContents(doc ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, _ := h.AttributeString("id")
+		idb, _ := id.([]byte)
+		b.WriteString(`<li class="lv` + strconv.Itoa(h.Level) + `"><a href="#` + html.EscapeString(string(idb)) + `">` + html.EscapeString(nodeText(h, source)) + `</a></li>`)
+		return ast.WalkSkipChildren, nil
+	})
+	if b.Len() == 0 {
+		return ""
+	}
+	return `<nav class="page-toc"><ul>` + b.String() + `</ul></nav>`
+}
+
+// nodeText concatenates the plain text below n.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// headingIDs generates anchors the way the SPA editor preview does (lowercase, punctuation
+// dropped, spaces to hyphens), numbering repeats.
+type headingIDs struct {
+	seen map[string]int
+}
+
+func newHeadingIDs() *headingIDs { return &headingIDs{seen: map[string]int{}} }
+
+var headingIDDropRe = regexp.MustCompile(`[^a-z0-9\s-]`)
+
+func (h *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	id := strings.Join(strings.Fields(headingIDDropRe.ReplaceAllString(strings.ToLower(string(value)), "")), "-")
+	if id == "" {
+		id = "section"
+	}
+	if n := h.seen[id]; n > 0 {
+		h.seen[id] = n + 1
+		id = id + "-" + strconv.Itoa(n)
+	} else {
+		h.seen[id] = 1
+	}
+	return []byte(id)
+}
+
+func (h *headingIDs) Put(value []byte) {
+	h.seen[string(value)]++
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trough/models"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	src := "[[TOC]]\n\n# Getting Started!\n\nLine one\nline two[^1]\n\n::: tip\nUse **bold**\n:::\n\n::: details How <do> I reset?\nClick reset.\n:::\n\n```\n::: tip\n```\n\n## Getting Started\n\n<script>alert(1)</script><a href=\"javascript:alert(1)\" onclick=\"x()\">bad</a>\n\n[ext](https://example.com) [home](/about)\n\n[^1]: A note.\n"
+	out, err := RenderMarkdown(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<nav class="page-toc"><ul><li class="lv1"><a href="#getting-started">Getting Started!</a></li><li class="lv2"><a href="#getting-started-1">Getting Started</a></li></ul></nav>`,
+		`<h1 id="getting-started">`,
+		"Line one<br>",
+		`<div class="admon admon-tip">`,
+		"<strong>bold</strong>",
+		`<details class="md-details"><summary>How &lt;do&gt; I reset?</summary>`,
+		"<code>::: tip\n</code>",
+		`class="footnote-ref"`,
+		`href="https://example.com" rel="nofollow noopener" target="_blank"`,
+		`<a href="/about">home</a>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	for _, bad := range []string{"<script", "javascript:", "onclick"} {
+		if strings.Contains(out, bad) {
+			t.Errorf("unsanitized %q in:\n%s", bad, out)
+		}
+	}
+}
+
+func TestPageHTMLFallsBackToMarkdown(t *testing.T) {
+	if got := PageHTML(&models.Page{HTML: "<p>stored</p>", Markdown: "# other"}); got != "<p>stored</p>" {
+		t.Fatalf("expected stored HTML, got %q", got)
+	}
+	if got := PageHTML(&models.Page{Markdown: "# Legacy"}); !strings.Contains(got, "<h1") {
+		t.Fatalf("expected rendered markdown, got %q", got)
+	}
+}
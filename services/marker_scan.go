@@ -0,0 +1,155 @@
+package services
+
+// markerSet finds which of a fixed set of ASCII markers occur in a byte stream, ignoring
+// ASCII case, in a single pass of an Aho-Corasick automaton. Unlike lowercasing a copy of
+// the input and searching it once per marker, a scan allocates nothing per byte and can be
+// fed a file in chunks: matches that straddle two chunks are still found.
+type markerSet struct {
+	markers []string
+	index   map[string]int
+	// class maps an input byte to a column of next; 0 is every byte no marker contains
+	class [256]uint8
+	width int
+	// next[state*width+class] is the state after reading a byte of that class
+	next []int32
+	// out lists the markers ending at each state, those ending in a suffix included
+	out [][]int
+}
+
+func lowerASCII(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// newMarkerSet compiles the markers of every group, lowercased and without duplicates.
+// Markers are package literals, so a set using more distinct bytes than fit a column
+// index panics at startup.
+func newMarkerSet(groups ...[]string) *markerSet {
+	m := &markerSet{index: map[string]int{}, width: 1}
+	for _, group := range groups {
+		for _, mk := range group {
+			low := bytesLowerASCII(mk)
+			if _, dup := m.index[string(low)]; dup || len(low) == 0 {
+				continue
+			}
+			m.index[string(low)] = len(m.markers)
+			m.markers = append(m.markers, string(low))
+			for _, b := range low {
+				if m.class[b] != 0 {
+					continue
+				}
+				if m.width > 255 {
+					panic("markerSet: too many distinct bytes")
+				}
+				m.class[b] = uint8(m.width)
+				m.width++
+			}
+		}
+	}
+	for b := 'A'; b <= 'Z'; b++ {
+		m.class[b] = m.class[b+'a'-'A']
+	}
+
+	// The trie of markers, with -1 for transitions still missing
+	addState := func() int32 {
+		for i := 0; i < m.width; i++ {
+			m.next = append(m.next, -1)
+		}
+		m.out = append(m.out, nil)
+		return int32(len(m.out) - 1)
+	}
+	addState()
+	for i, mk := range m.markers {
+		var s int32
+		for j := 0; j < len(mk); j++ {
+			at := int(s)*m.width + int(m.class[mk[j]])
+			if m.next[at] < 0 {
+				t := addState()
+				m.next[at] = t
+			}
+			s = m.next[at]
+		}
+		m.out[s] = append(m.out[s], i)
+	}
+
+	// Breadth first, so each state's fallback is complete before the state itself: missing
+	// transitions follow the fallback's, and markers ending at the fallback end here too
+	fail := make([]int32, len(m.out))
+	var queue []int32
+	for c := 0; c < m.width; c++ {
+		if t := m.next[c]; t < 0 {
+			m.next[c] = 0
+		} else {
+			queue = append(queue, t)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		m.out[s] = append(m.out[s], m.out[fail[s]]...)
+		for c := 0; c < m.width; c++ {
+			at := int(s)*m.width + c
+			via := m.next[int(fail[s])*m.width+c]
+			if t := m.next[at]; t < 0 {
+				m.next[at] = via
+			} else {
+				fail[t] = via
+				queue = append(queue, t)
+			}
+		}
+	}
+	return m
+}
+
+// scan starts a pass over a new stream.
+func (m *markerSet) scan() *markerScan {
+	return &markerScan{set: m, found: make([]bool, len(m.markers))}
+}
+
+// markerScan is one pass of a markerSet over a stream. It is an io.Writer, so a reader can
+// be fed to it with io.Copy.
+type markerScan struct {
+	set   *markerSet
+	state int32
+	found []bool
+}
+
+// Write reads the next chunk of the stream.
+func (s *markerScan) Write(p []byte) (int, error) {
+	m, state := s.set, s.state
+	for _, b := range p {
+		state = m.next[int(state)*m.width+int(m.class[b])]
+		for _, i := range m.out[state] {
+			s.found[i] = true
+		}
+	}
+	s.state = state
+	return len(p), nil
+}
+
+// Found reports whether marker occurred in what was read so far. Markers the set wasn't
+// compiled with are never found.
+func (s *markerScan) Found(marker string) bool {
+	i, ok := s.set.index[string(bytesLowerASCII(marker))]
+	return ok && s.found[i]
+}
+
+// FoundAny reports whether any of markers occurred in what was read so far.
+func (s *markerScan) FoundAny(markers []string) bool {
+	for _, mk := range markers {
+		if s.Found(mk) {
+			return true
+		}
+	}
+	return false
+}
+
+func bytesLowerASCII(s string) []byte {
+	b := []byte(s)
+	for i := range b {
+		b[i] = lowerASCII(b[i])
+	}
+	return b
+}
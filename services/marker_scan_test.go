@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMarkerScan(t *testing.T) {
+	set := newMarkerSet([]string{"he", "she", "hers"}, []string{"Negative_Prompt", "\x00grok\x00", "she"})
+	s := set.scan()
+	// Fed in small chunks so markers straddle them
+	if _, err := io.CopyBuffer(s, strings.NewReader("USHERS wrote a NEGATIVE_prompt"), make([]byte, 3)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"he", "she", "hers", "negative_prompt"} {
+		if !s.Found(want) {
+			t.Errorf("expected %q found", want)
+		}
+	}
+	if s.Found("\x00grok\x00") || s.Found("never compiled") {
+		t.Fatal("unexpected match")
+	}
+	_, _ = s.Write([]byte("\x00GROK\x00"))
+	if !s.FoundAny([]string{"absent", "\x00grok\x00"}) {
+		t.Fatal("expected a match in a later chunk")
+	}
+}
+
+func TestDetectAIFast_Markers(t *testing.T) {
+	pad := bytes.Repeat([]byte{0xff}, 1200)
+	jpeg := func(text string) []byte {
+		return append(append(append([]byte{}, pad...), text...), pad...)
+	}
+	if ok, res := DetectAIFast(jpeg("Prompt: koi --AR 16:9 --v 6")); !ok || res.Details != "Specific AI marker: --ar" {
+		t.Fatalf("expected the Midjourney parameter found, got %v %+v", ok, res)
+	}
+	// Non-PNG headers are skipped, so a marker there is ignored
+	header := append([]byte("midjourney"), pad...)
+	if ok, _ := DetectAIFast(header); ok {
+		t.Fatal("expected a marker in the first 1000 bytes of a non-PNG ignored")
+	}
+	if ok, _ := DetectAIFast(jpeg("a plain photograph")); ok {
+		t.Fatal("expected no markers in a plain file")
+	}
+}
+
+func TestDetectFromBinaryTextBytes(t *testing.T) {
+	cases := []struct {
+		text, provider string
+	}{
+		{`{"GROK": "x", "Grok Image Prompt": "a koi"}`, "Grok"},
+		{`{"prompt": {}, "extra": {"WORKFLOW": {}}}`, "ComfyUI"},
+		{"Steps: 30, Sampler: DPM++", "Stable Diffusion (SDXL)"},
+		{"made with FLUX", "FLUX"},
+		{"prompts with a seed", "AI (Prompt + Technical Terms)"},
+		{"a prompt and nothing else", ""},
+	}
+	for _, c := range cases {
+		ok, res := detectFromBinaryTextBytes([]byte(c.text))
+		if ok != (c.provider != "") || res.Provider != c.provider {
+			t.Errorf("%q: expected %q, got %v %+v", c.text, c.provider, ok, res)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A small Prometheus text-format registry. The app only needs counters, histograms and
+// scrape-time gauges, which keeps the client library out of the dependency tree.
+
+// CounterVec is a monotonically increasing counter partitioned by label values.
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// HistogramVec tracks observations in cumulative buckets partitioned by label values.
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+type gaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+type metricsRegistry struct {
+	mu         sync.RWMutex
+	counters   []*CounterVec
+	histograms []*HistogramVec
+	gauges     []gaugeFunc
+}
+
+var registry = &metricsRegistry{}
+
+// DefaultLatencyBuckets suit HTTP handlers, from 5ms to 10s.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewCounterVec registers a counter.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]*counterSeries{}}
+	registry.mu.Lock()
+	registry.counters = append(registry.counters, c)
+	registry.mu.Unlock()
+	return c
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	registry.mu.Lock()
+	registry.histograms = append(registry.histograms, h)
+	registry.mu.Unlock()
+	return h
+}
+
+// RegisterGaugeFunc exposes a gauge whose value is computed at scrape time. Registering
+// the same name again replaces the previous function.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for i, g := range registry.gauges {
+		if g.name == name {
+			registry.gauges[i].fn = fn
+			return
+		}
+	}
+	registry.gauges = append(registry.gauges, gaugeFunc{name: name, help: help, fn: fn})
+}
+
+// Inc adds one to the series for labelValues.
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v to the series for labelValues.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = s
+	}
+	s.value += v
+	c.mu.Unlock()
+}
+
+// Observe records v in the series for labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+	h.mu.Unlock()
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// WriteMetrics writes every registered metric in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer) error {
+	registry.mu.RLock()
+	counters := append([]*CounterVec(nil), registry.counters...)
+	histograms := append([]*HistogramVec(nil), registry.histograms...)
+	gauges := append([]gaugeFunc(nil), registry.gauges...)
+	registry.mu.RUnlock()
+
+	var b strings.Builder
+	for _, c := range counters {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		c.mu.Lock()
+		for _, key := range sortedKeys(c.values) {
+			s := c.values[key]
+			fmt.Fprintf(&b, "%s%s %s\n", c.name, formatLabels(c.labels, s.labelValues, "", ""), formatFloat(s.value))
+		}
+		c.mu.Unlock()
+	}
+	for _, h := range histograms {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+		h.mu.Lock()
+		for _, key := range sortedKeys(h.series) {
+			s := h.series[key]
+			for i, le := range h.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", formatFloat(le)), s.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), s.count)
+		}
+		h.mu.Unlock()
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.fn()))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		parts = append(parts, n+`="`+labelEscaper.Replace(v)+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Application metrics.
+var (
+	HTTPRequests          = NewCounterVec("trough_http_requests_total", "HTTP requests by method, route pattern and status code.", "method", "route", "status")
+	HTTPRequestDuration   = NewHistogramVec("trough_http_request_duration_seconds", "HTTP request latency by method and route pattern.", DefaultLatencyBuckets, "method", "route")
+	UploadsTotal          = NewCounterVec("trough_uploads_total", "Image uploads by result (created, queued, rejected, error); queued uploads are counted again when their job settles.", "result")
+	AIDetections          = NewCounterVec("trough_ai_detections_total", "AI provenance detection outcomes by provider and method; provider \"none\" means rejected.", "provider", "method")
+	RateLimitDenials      = NewCounterVec("trough_rate_limit_denials_total", "Requests denied by a rate limiter.", "limiter")
+	CSRFFailures          = NewCounterVec("trough_csrf_failures_total", "State-changing requests rejected by CSRF protection.", "reason")
+	StorageOpDuration     = NewHistogramVec("trough_storage_operation_duration_seconds", "Storage operation latency by backend, operation and result.", DefaultLatencyBuckets, "backend", "op", "result")
+	JobRuns               = NewCounterVec("trough_jobs_total", "Background job runs by kind and result (ok, error).", "kind", "result")
+	RegistrationsBlocked  = NewCounterVec("trough_registrations_blocked_total", "Registrations refused by the antispam checks by reason (honeypot, timing, disposable).", "reason")
+	SecurityEventsDropped = NewCounterVec("trough_security_events_dropped_total", "Security events not persisted because the writer queue was full or a write failed.")
+	LiveEventsDropped     = NewCounterVec("trough_live_events_dropped_total", "Live stream events not delivered because a subscriber fell behind, by stream (feed, admin).", "stream")
+	JobDuration           = NewHistogramVec("trough_job_duration_seconds", "Background job run time by kind.", []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600}, "kind")
+)
+
+// RecordAIDetection counts a detection outcome; ok=false records a rejection.
+func RecordAIDetection(ok bool, res AIDetectionResult) {
+	if !ok {
+		AIDetections.Inc("none", "none")
+		return
+	}
+	provider, method := res.Provider, res.Method
+	if provider == "" {
+		provider = "unknown"
+	}
+	if method == "" {
+		method = "unknown"
+	}
+	AIDetections.Inc(provider, method)
+}
+
+// startStorageSpan traces a storage call made on behalf of a traced request.
+func startStorageSpan(ctx context.Context, backend, op, key string) (context.Context, *Span) {
+	ctx, span := StartClientSpan(ctx, "storage."+op)
+	span.SetAttr("storage.backend", backend)
+	span.SetAttr("storage.key", key)
+	return ctx, span
+}
+
+// observeStorage records a storage call started at start.
+func observeStorage(backend, op string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	StorageOpDuration.ObserveSince(start, backend, op, result)
+}
+
+func init() {
+	RegisterGaugeFunc("trough_mail_queue_depth", "Emails waiting in the in-memory mail queue.", func() float64 {
+		return float64(len(mailQueueCh))
+	})
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetricsExposition(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Test counter.", "route")
+	c.Inc(`/a"b`)
+	c.Add(2, "/x")
+	h := NewHistogramVec("test_latency_seconds", "Test histogram.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "save")
+	h.Observe(0.5, "save")
+	h.Observe(5, "save")
+	RegisterGaugeFunc("test_depth", "Test gauge.", func() float64 { return 3 })
+
+	var b strings.Builder
+	if err := WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/a\"b"} 1`,
+		`test_requests_total{route="/x"} 2`,
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{op="save",le="0.1"} 1`,
+		`test_latency_seconds_bucket{op="save",le="1"} 2`,
+		`test_latency_seconds_bucket{op="save",le="+Inf"} 3`,
+		`test_latency_seconds_sum{op="save"} 5.55`,
+		`test_latency_seconds_count{op="save"} 3`,
+		"test_depth 3",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
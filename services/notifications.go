@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+const (
+	// digestInterval is the minimum time between two digests for one user
+	digestInterval = 24 * time.Hour
+	// digestMaxItems caps how many notifications are listed in one email
+	digestMaxItems = 20
+	// notificationRetention is how long read notifications are kept
+	notificationRetention = 90 * 24 * time.Hour
+)
+
+// Notify records a notification without failing the caller: notifications are a side
+// effect of the action that produced them and must never block it.
+func Notify(repo models.NotificationRepositoryInterface, n *models.Notification) {
+	if repo == nil || n == nil {
+		return
+	}
+	// Don't notify users about their own actions
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return
+	}
+	if err := repo.Create(n); err != nil {
+		Logger(context.Background()).Error("notifications: create failed", "type", n.Type, "error", err)
+	}
+}
+
+// NotifyAdmins records a notification for every admin, logging rather than returning
+// failures like Notify.
+func NotifyAdmins(repo models.NotificationRepositoryInterface, n *models.Notification) {
+	if repo == nil || n == nil {
+		return
+	}
+	if err := repo.NotifyAdmins(n); err != nil {
+		Logger(context.Background()).Error("notifications: admin notify failed", "type", n.Type, "error", err)
+	}
+}
+
+// DescribeNotification renders a one-line, plain-text summary of a notification in locale.
+func DescribeNotification(locale string, n models.Notification) string {
+	actor := T(locale, "Someone")
+	if n.ActorUsername != nil && *n.ActorUsername != "" {
+		actor = "@" + *n.ActorUsername
+	}
+	switch n.Type {
+	case models.NotificationCollected:
+		return T(locale, "%s collected your image", actor)
+	case models.NotificationComment:
+		if n.Message != "" {
+			return T(locale, "%s commented on your image: %s", actor, truncateRunes(n.Message, 140))
+		}
+		return T(locale, "%s commented on your image", actor)
+	case models.NotificationFollow:
+		return T(locale, "%s started following you", actor)
+	case models.NotificationAdminMessage:
+		return T(locale, "Message from the admins: %s", truncateRunes(n.Message, 280))
+	case models.NotificationUploadApproved:
+		return T(locale, "Your upload was approved and is now public")
+	case models.NotificationUploadRejected:
+		if n.Message != "" {
+			return T(locale, "Your upload was not approved: %s", truncateRunes(n.Message, 280))
+		}
+		return T(locale, "Your upload was not approved")
+	case models.NotificationTakedownReceived:
+		return T(locale, "New copyright takedown request from %s", truncateRunes(n.Message, 140))
+	case models.NotificationImageDisabled:
+		return T(locale, "Your image was disabled while a copyright complaint is reviewed")
+	case models.NotificationImageRestored:
+		return T(locale, "Your image was restored after a copyright complaint was reviewed")
+	case models.NotificationImageRemoved:
+		if n.Message != "" {
+			return T(locale, "Your image was removed following a copyright complaint: %s", truncateRunes(n.Message, 280))
+		}
+		return T(locale, "Your image was removed following a copyright complaint")
+	}
+	if n.Message != "" {
+		return truncateRunes(n.Message, 280)
+	}
+	return T(locale, "New notification")
+}
+
+func truncateRunes(s string, max int) string {
+	s = strings.TrimSpace(s)
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
+// InitNotificationDigest starts the hourly worker that emails opted-in users a digest of
+// unread notifications, at most once per day each. Mail goes through the regular queue.
+func InitNotificationDigest(repo models.NotificationRepositoryInterface, settingsRepo models.SiteSettingsRepositoryInterface) {
+	if repo == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if !BeginWork() {
+				return
+			}
+			set := GetCachedSettings(settingsRepo)
+			if set.SMTPHost != "" && set.SMTPPort > 0 {
+				ProcessNotificationDigests(repo, SiteLocale(set), set.SiteName, set.SiteURL)
+			}
+			if _, err := repo.PurgeRead(time.Now().Add(-notificationRetention)); err != nil {
+				Logger(context.Background()).Error("notifications: purge failed", "error", err)
+			}
+			EndWork()
+			select {
+			case <-ticker.C:
+			case <-ShuttingDown():
+				return
+			}
+		}
+	}()
+}
+
+// ProcessNotificationDigests enqueues one digest per due recipient, written in locale,
+// and returns how many were queued.
+func ProcessNotificationDigests(repo models.NotificationRepositoryInterface, locale, siteName, siteURL string) int {
+	link := strings.TrimRight(siteURL, "/") + "/settings"
+	queued := 0
+	for {
+		batch, err := repo.ClaimDigestRecipients(digestInterval, 50)
+		if err != nil {
+			Logger(context.Background()).Error("notifications: claim digest recipients failed", "error", err)
+			return queued
+		}
+		if len(batch) == 0 {
+			return queued
+		}
+		for _, u := range batch {
+			cutoff := time.Now()
+			pending, err := repo.PendingDigest(u.ID, 200)
+			if err != nil {
+				Logger(context.Background()).Error("notifications: load digest failed", "user_id", u.ID, "error", err)
+				continue
+			}
+			if len(pending) == 0 {
+				continue
+			}
+			total := len(pending)
+			if len(pending) > digestMaxItems {
+				pending = pending[:digestMaxItems]
+			}
+			items := make([]string, 0, len(pending))
+			for _, n := range pending {
+				items = append(items, DescribeNotification(locale, n))
+			}
+			EnqueueMessage(u.Email, BuildDigestMessage(locale, siteName, siteURL, link, items, total))
+			if err := repo.MarkEmailed(u.ID, cutoff); err != nil {
+				Logger(context.Background()).Error("notifications: mark emailed failed", "user_id", u.ID, "error", err)
+			}
+			queued++
+		}
+	}
+}
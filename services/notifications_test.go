@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeNotifications struct {
+	models.NotificationRepositoryInterface
+	created []models.Notification
+}
+
+func (f *fakeNotifications) Create(n *models.Notification) error {
+	f.created = append(f.created, *n)
+	return nil
+}
+
+func TestNotifySkipsSelfActions(t *testing.T) {
+	repo := &fakeNotifications{}
+	owner, other := uuid.New(), uuid.New()
+	Notify(repo, &models.Notification{UserID: owner, Type: models.NotificationCollected, ActorID: &owner})
+	Notify(repo, &models.Notification{UserID: owner, Type: models.NotificationCollected, ActorID: &other})
+	Notify(nil, &models.Notification{UserID: owner, Type: models.NotificationCollected, ActorID: &other})
+	if len(repo.created) != 1 || *repo.created[0].ActorID != other {
+		t.Fatalf("expected only the other user's collect to notify, got %+v", repo.created)
+	}
+}
+
+func TestDescribeNotification(t *testing.T) {
+	name := "alice"
+	cases := map[string]models.Notification{
+		"@alice collected your image":             {Type: models.NotificationCollected, ActorUsername: &name},
+		"@alice started following you":            {Type: models.NotificationFollow, ActorUsername: &name},
+		"Someone commented on your image: nice":   {Type: models.NotificationComment, Message: " nice "},
+		"Message from the admins: welcome aboard": {Type: models.NotificationAdminMessage, Message: "welcome aboard"},
+		"Your upload was not approved: spam":      {Type: models.NotificationUploadRejected, Message: "spam"},
+	}
+	for want, n := range cases {
+		if got := DescribeNotification("en", n); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestBuildDigestMessage(t *testing.T) {
+	msg := BuildDigestMessage("en", "Trough", "https://example.com", "https://example.com/settings", []string{"<b>@bob</b> collected your image"}, 3)
+	if msg.Subject != "3 new notifications · Trough" {
+		t.Fatalf("subject: %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "...and 2 more.") || !strings.Contains(msg.Text, "https://example.com/settings") {
+		t.Fatalf("text missing items or link: %q", msg.Text)
+	}
+	if strings.Contains(msg.HTML, "<b>@bob</b>") || !strings.Contains(msg.HTML, "&lt;b&gt;@bob&lt;/b&gt;") {
+		t.Fatal("digest items must be escaped in HTML")
+	}
+	if !strings.Contains(msg.HTML, "and 2 more") {
+		t.Fatal("expected remaining count in HTML")
+	}
+}
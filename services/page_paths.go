@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// CMS pages are served from the site root, so a page path must not start with a segment
+// the app already routes (api, admin, uploads, a static directory...). main.go reports
+// the first segments of its routes once they are registered.
+
+const maxPagePathSegments = 4
+
+var pageSegmentRe = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$`)
+
+// defaultReservedPageRoots cover the SPA's own routes until the router reports the rest.
+var defaultReservedPageRoots = []string{"api", "uploads", "assets", "i", "register", "reset", "verify", "settings", "admin"}
+
+var reservedPageRoots atomic.Pointer[map[string]bool]
+
+func init() {
+	SetReservedPageRoots(nil)
+}
+
+// SetReservedPageRoots replaces the first path segments that pages may not use, in
+// addition to the built-in ones.
+func SetReservedPageRoots(roots []string) {
+	m := map[string]bool{}
+	for _, r := range append(append([]string{}, defaultReservedPageRoots...), roots...) {
+		if r = strings.ToLower(strings.Trim(r, "/")); r != "" {
+			m[r] = true
+		}
+	}
+	reservedPageRoots.Store(&m)
+}
+
+// PageRootReserved reports whether root, the first segment of a path, belongs to the app.
+func PageRootReserved(root string) bool {
+	return (*reservedPageRoots.Load())[strings.ToLower(root)]
+}
+
+// NormalizePagePath lowercases and validates a page path such as "help/faq": one to four
+// segments of letters, digits and inner hyphens, not under a reserved root.
+func NormalizePagePath(raw string) (string, error) {
+	p := strings.ToLower(strings.Trim(strings.TrimSpace(raw), "/"))
+	if p == "" {
+		return "", errors.New("invalid slug")
+	}
+	segs := strings.Split(p, "/")
+	if len(segs) > maxPagePathSegments {
+		return "", errors.New("slug may have at most 4 segments")
+	}
+	for _, s := range segs {
+		if !pageSegmentRe.MatchString(s) {
+			return "", errors.New("invalid slug")
+		}
+	}
+	if PageRootReserved(segs[0]) {
+		return "", errors.New("slug is reserved")
+	}
+	return p, nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import "testing"
+
+func TestNormalizePagePath(t *testing.T) {
+	defer SetReservedPageRoots(nil)
+	SetReservedPageRoots([]string{"explore"})
+	ok := map[string]string{"About": "about", "/help/faq/": "help/faq", "a/b/c/d": "a/b/c/d"}
+	for in, want := range ok {
+		if got, err := NormalizePagePath(in); err != nil || got != want {
+			t.Errorf("%q: got %q, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "a/b/c/d/e", "help//faq", "-x", "api/docs", "explore", "i/abc", "a_b"} {
+		if _, err := NormalizePagePath(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
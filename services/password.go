@@ -25,6 +25,8 @@ type PasswordRequirements struct {
 	RequireSpecial bool    `json:"require_special"`
 	AllowedSpecial string `json:"allowed_special"`
 	Examples       []string `json:"examples"`
+	// NotBreached is set when passwords found in known breaches are refused
+	NotBreached bool `json:"not_breached"`
 }
 
 // DefaultPasswordPolicy returns the default password policy
@@ -55,13 +57,18 @@ func GetPasswordRequirements() *PasswordRequirements {
 			"StrongPass123!",
 			"GoLangRocks24",
 		},
+		NotBreached: BreachCheckEnabled(),
 	}
 }
 
-// ValidatePassword enforces strong password rules
+// ValidatePassword enforces strong password rules and, when configured, refuses
+// passwords known from data breaches
 func ValidatePassword(password string) error {
 	policy := DefaultPasswordPolicy()
-	return policy.ValidatePassword(password)
+	if err := policy.ValidatePassword(password); err != nil {
+		return err
+	}
+	return checkPasswordBreach(password)
 }
 
 // ValidatePassword validates a password against the policy
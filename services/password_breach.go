@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" // The range API is keyed by SHA-1; nothing here relies on it for security
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrPasswordBreached is returned for passwords found in known data breaches.
+var ErrPasswordBreached = errors.New("this password has appeared in a data breach; choose a different one")
+
+// errBreachCheckUnavailable is returned instead when the API is unreachable and
+// auth.breach_check.fail_closed is set.
+var errBreachCheckUnavailable = errors.New("password check is unavailable right now, please try again later")
+
+var (
+	breachMu     sync.RWMutex
+	breachConfig BreachCheckConfig
+	breachClient = &http.Client{}
+)
+
+// SetBreachCheck configures the breach check; ApplyConfig calls it at startup.
+func SetBreachCheck(cfg BreachCheckConfig) {
+	breachMu.Lock()
+	breachConfig = cfg
+	breachMu.Unlock()
+}
+
+// BreachCheckEnabled reports whether new passwords are checked against breach data.
+func BreachCheckEnabled() bool {
+	breachMu.RLock()
+	defer breachMu.RUnlock()
+	return breachConfig.Enabled
+}
+
+// checkPasswordBreach applies the configured breach check to a password. It is a no-op when
+// disabled. An unreachable API lets the password through unless fail_closed is set, so an
+// offline instance keeps working.
+func checkPasswordBreach(password string) error {
+	breachMu.RLock()
+	cfg := breachConfig
+	breachMu.RUnlock()
+	if !cfg.Enabled {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	breached, err := passwordBreached(ctx, cfg.URL, password)
+	if err != nil {
+		Logger(ctx).Warn("password breach check failed", "error", err, "fail_closed", cfg.FailClosed)
+		if cfg.FailClosed {
+			return errBreachCheckUnavailable
+		}
+		return nil
+	}
+	if breached {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+// passwordBreached asks a Pwned Passwords style range API about password using
+// k-anonymity: only the first 5 hex characters of its SHA-1 leave the server, and the
+// suffix is matched locally against the returned list.
+func passwordBreached(ctx context.Context, baseURL, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the real response size from anyone watching the traffic
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "TROUGH-PasswordCheck/1.0")
+	resp, err := breachClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("range API answered %d", resp.StatusCode)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		hash, count, ok := strings.Cut(strings.TrimSpace(sc.Text()), ":")
+		// Padding entries carry a count of 0
+		if ok && strings.EqualFold(hash, suffix) && strings.TrimLeft(count, "0") != "" {
+			return true, nil
+		}
+	}
+	return false, sc.Err()
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordBreachCheck(t *testing.T) {
+	const breached, padded = "Breached#Pass1", "Padded#Pass1"
+	hashOf := func(p string) string {
+		sum := sha1.Sum([]byte(p))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+	var prefixes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		prefixes = append(prefixes, prefix)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("expected a padded request")
+		}
+		for _, p := range []struct {
+			pw    string
+			count int
+		}{{breached, 42}, {padded, 0}} {
+			if h := hashOf(p.pw); h[:5] == prefix {
+				fmt.Fprintf(w, "%s:%d\r\n", h[5:], p.count)
+			}
+		}
+		fmt.Fprint(w, "0000000000000000000000000000000000A:3\r\n")
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { SetBreachCheck(BreachCheckConfig{}) })
+
+	SetBreachCheck(BreachCheckConfig{Enabled: true, URL: srv.URL + "/range/", Timeout: time.Second})
+	if err := ValidatePassword(breached); !errors.Is(err, ErrPasswordBreached) {
+		t.Fatalf("expected a breached password to be refused, got %v", err)
+	}
+	if err := ValidatePassword(padded); err != nil {
+		t.Fatalf("a padding entry should not count as a breach, got %v", err)
+	}
+	if err := ValidatePassword("Unique#Pass-7781"); err != nil {
+		t.Fatalf("expected a clean password to pass, got %v", err)
+	}
+	for _, p := range prefixes {
+		if len(p) != 5 {
+			t.Fatalf("only a 5 character prefix should be sent, got %q", p)
+		}
+	}
+	if !GetPasswordRequirements().NotBreached {
+		t.Fatal("requirements should report the breach check")
+	}
+
+	// Offline: allowed unless fail_closed
+	SetBreachCheck(BreachCheckConfig{Enabled: true, URL: "http://127.0.0.1:1/range/", Timeout: time.Second})
+	if err := ValidatePassword(breached); err != nil {
+		t.Fatalf("an unreachable API should fail open, got %v", err)
+	}
+	SetBreachCheck(BreachCheckConfig{Enabled: true, URL: "http://127.0.0.1:1/range/", Timeout: time.Second, FailClosed: true})
+	if err := ValidatePassword(breached); err == nil {
+		t.Fatal("fail_closed should refuse while the API is unreachable")
+	}
+
+	SetBreachCheck(BreachCheckConfig{})
+	if err := ValidatePassword(breached); err != nil || GetPasswordRequirements().NotBreached {
+		t.Fatalf("a disabled check should not run, got %v", err)
+	}
+}
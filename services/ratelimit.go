@@ -2,11 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -18,7 +18,8 @@ type RateLimitConfig struct {
 	MaxEntries      int           `yaml:"max_entries" default:"10000"`
 	CleanupInterval time.Duration `yaml:"cleanup_interval" default:"5m"`
 	EntryTTL        time.Duration `yaml:"entry_ttl" default:"1h"`
-	TrustedProxies  []string      `yaml:"trusted_proxies" default:"[\"127.0.0.1\", \"::1\"]"`
+	// Deprecated: use server.trusted_proxies; entries here are added to that list.
+	TrustedProxies  []string      `yaml:"trusted_proxies"`
 	EnableDebug     bool          `yaml:"enable_debug" default:"false"`
 }
 
@@ -43,6 +44,7 @@ type SecurityEvent struct {
 	Method      string    `json:"method"`
 	Severity    string    `json:"severity"`
 	Description string    `json:"description"`
+	RequestID   string    `json:"request_id,omitempty"`
 }
 
 // ProgressiveRateLimitConfig defines configuration for progressive rate limiting
@@ -54,26 +56,30 @@ type ProgressiveRateLimitConfig struct {
 	BackoffFactor  float64       `yaml:"backoff_factor" default:"2.0"`
 	LockoutThreshold int          `yaml:"lockout_threshold" default:"10"`
 	LockoutDuration time.Duration `yaml:"lockout_duration" default:"15m"`
+	// Consecutive failures after which register/forgot-password ask for a challenge
+	ChallengeThreshold int       `yaml:"challenge_threshold" default:"3"`
 	EnableLogging  bool          `yaml:"enable_logging" default:"true"`
 }
 
-// progressiveEntry represents a progressive rate limiting entry
-type progressiveEntry struct {
-	currentWindow  time.Time
-	currentCapacity int
-	consecutiveFailures int
-	totalAttempts      int
-	firstFailure       time.Time
-	isLockedOut        bool
-	lockoutUntil       time.Time
-	lastUpdated       time.Time
-	ipAddress         string
+// ProgressiveState is the per-IP state of the progressive limiter. It is serialized as JSON
+// when kept in a shared RateLimiterStore.
+type ProgressiveState struct {
+	WindowStart         time.Time `json:"window_start"`
+	Capacity            int       `json:"capacity"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalAttempts       int       `json:"total_attempts"`
+	FirstFailure        time.Time `json:"first_failure"`
+	LockedOut           bool      `json:"locked_out"`
+	LockoutUntil        time.Time `json:"lockout_until"`
+	LastUpdated         time.Time `json:"last_updated"`
+	IP                  string    `json:"ip"`
 }
 
 // ProgressiveRateLimiter provides progressive rate limiting with backoff
 type ProgressiveRateLimiter struct {
 	mu              sync.RWMutex
-	entries         map[string]*progressiveEntry
+	local           *memoryRateLimiterStore
+	store           RateLimiterStore
 	config          ProgressiveRateLimitConfig
 	baseConfig      RateLimitConfig
 	stats           RateLimitStats
@@ -95,13 +101,13 @@ type rlEntry struct {
 // RateLimiter provides enhanced rate limiting with LRU eviction and cleanup
 type RateLimiter struct {
 	mu           sync.RWMutex
-	entries      map[string]*rlEntry
+	local        *memoryRateLimiterStore
+	store        RateLimiterStore
 	config       RateLimitConfig
 	stats        RateLimitStats
 	startTime    time.Time
 	cleanupTimer  *time.Timer
 	stopCleanup  chan struct{}
-	trustedProxyMap map[string]bool
 }
 
 // NewRateLimiter creates a new enhanced rate limiter
@@ -116,18 +122,13 @@ func NewRateLimiter(config RateLimitConfig) *RateLimiter {
 		config.EntryTTL = 30 * time.Minute
 	}
 
-	// Build trusted proxy map for O(1) lookups
-	trustedProxyMap := make(map[string]bool)
-	for _, proxy := range config.TrustedProxies {
-		trustedProxyMap[proxy] = true
-	}
-
+	local := newMemoryRateLimiterStore(config.MaxEntries)
 	rl := &RateLimiter{
-		entries:        make(map[string]*rlEntry),
+		local:          local,
+		store:          local,
 		config:         config,
 		startTime:      time.Now(),
 		stopCleanup:    make(chan struct{}),
-		trustedProxyMap: trustedProxyMap,
 	}
 
 	// Start background cleanup
@@ -153,15 +154,13 @@ func (rl *RateLimiter) Middleware(capacity int, refill time.Duration) fiber.Hand
 		case ip = <-ipChan:
 		case <-ctx.Done():
 			// If IP extraction times out, allow request but log it
-			log.Printf("Rate limiter IP extraction timeout, allowing request")
+			Logger(ctx).Warn("rate limiter: IP extraction timed out, allowing request")
 			return c.Next()
 		}
 		
 		if ip == "" {
 			// If we can't get a valid IP, allow the request but log it
-			if rl.config.EnableDebug {
-				rl.logDebug("Unable to determine client IP, allowing request")
-			}
+			rl.logDebug(ctx, "unable to determine client IP, allowing request")
 			return c.Next()
 		}
 
@@ -174,15 +173,14 @@ func (rl *RateLimiter) Middleware(capacity int, refill time.Duration) fiber.Hand
 		select {
 		case allowed = <-allowedChan:
 		case <-ctx.Done():
-			log.Printf("Rate limiter decision timeout for IP: %s, allowing request", ip)
+			Logger(ctx).Warn("rate limiter: decision timed out, allowing request", "ip", ip)
 			return c.Next()
 		}
 		
 		if !allowed {
 			rl.stats.DeniedCount++
-			if rl.config.EnableDebug {
-				rl.logDebug("Rate limit exceeded for IP: %s", ip)
-			}
+			RateLimitDenials.Inc("basic")
+			rl.logDebug(ctx, "rate limit exceeded", "ip", ip)
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"error": "Too many requests",
 			})
@@ -192,71 +190,38 @@ func (rl *RateLimiter) Middleware(capacity int, refill time.Duration) fiber.Hand
 	}
 }
 
-// allowRequest checks if a request from the given IP should be allowed
-func (rl *RateLimiter) allowRequest(ip string, capacity int, refill time.Duration) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	entry, exists := rl.entries[ip]
-
-	// Clean up expired entries or create new one
-	if !exists || now.After(entry.refillAt) {
-		entry = &rlEntry{
-			tokens:    capacity,
-			refillAt:  now.Add(refill),
-			lastUsed:  now,
-			ipAddress: ip,
-		}
-		rl.entries[ip] = entry
-		rl.stats.TotalEntries++
+// WithStore shares request counts through store so limits hold across replicas.
+// Nil keeps counts in the limiter's in-memory store.
+func (rl *RateLimiter) WithStore(store RateLimiterStore) *RateLimiter {
+	if store == nil {
+		store = rl.local
 	}
+	rl.store = store
+	return rl
+}
 
-	// Update last used time
-	entry.lastUsed = now
-
-	// Check if we need to evict entries
-	if len(rl.entries) > rl.config.MaxEntries {
-		rl.evictLRU()
-	}
+// Allow counts one request for key against capacity per window and reports whether it
+// fits. Keys are free-form, so callers can keep separate budgets per policy.
+func (rl *RateLimiter) Allow(key string, capacity int, window time.Duration) bool {
+	return rl.allowRequest(key, capacity, window)
+}
 
-	// Check if request is allowed
-	if entry.tokens <= 0 {
-		return false
+// allowRequest checks if a request from the given IP should be allowed
+func (rl *RateLimiter) allowRequest(ip string, capacity int, refill time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	allowed, err := rl.store.Take(ctx, ip, capacity, refill)
+	if err != nil && rl.store != rl.local {
+		// Fall back to local counting rather than failing open entirely
+		Logger(ctx).Warn("rate limiter: shared store failed, using local state", "error", err)
+		allowed, err = rl.local.Take(ctx, ip, capacity, refill)
 	}
-
-	entry.tokens--
-	return true
+	return err == nil && allowed
 }
 
-// getClientIP extracts the real client IP address, handling proxies
+// getClientIP returns the client address resolved through the trusted proxy list
 func (rl *RateLimiter) getClientIP(c *fiber.Ctx) string {
-	// Try to get real IP from X-Forwarded-For header
-	if forwarded := c.Get("X-Forwarded-For"); forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		if len(ips) > 0 {
-			// Get the leftmost IP (original client)
-			clientIP := strings.TrimSpace(ips[0])
-			if rl.isValidIP(clientIP) {
-				return rl.normalizeIP(clientIP)
-			}
-		}
-	}
-
-	// Try X-Real-IP header
-	if realIP := c.Get("X-Real-IP"); realIP != "" {
-		if rl.isValidIP(realIP) {
-			return rl.normalizeIP(realIP)
-		}
-	}
-
-	// Fall back to remote address
-	remoteAddr := c.IP()
-	if remoteAddr != "" && rl.isValidIP(remoteAddr) {
-		return rl.normalizeIP(remoteAddr)
-	}
-
-	return ""
+	return ClientIP(c)
 }
 
 // isValidIP checks if an IP address is valid
@@ -288,37 +253,6 @@ func (rl *RateLimiter) normalizeIP(ip string) string {
 	return parsedIP.String()
 }
 
-// evictLRU removes the least recently used entries
-func (rl *RateLimiter) evictLRU() {
-	if len(rl.entries) <= rl.config.MaxEntries {
-		return
-	}
-
-	// Find the oldest entry
-	var oldestKey string
-	var oldestTime time.Time
-	first := true
-
-	for key, entry := range rl.entries {
-		if first || entry.lastUsed.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.lastUsed
-			first = false
-		}
-	}
-
-	// Remove the oldest entry
-	if oldestKey != "" {
-		delete(rl.entries, oldestKey)
-		rl.stats.EvictedCount++
-		rl.stats.TotalEntries--
-
-		if rl.config.EnableDebug {
-			rl.logDebug("Evicted LRU entry for IP: %s", oldestKey)
-		}
-	}
-}
-
 // startCleanup starts the background cleanup goroutine
 func (rl *RateLimiter) startCleanup() {
 	rl.cleanupTimer = time.NewTimer(rl.config.CleanupInterval)
@@ -338,25 +272,16 @@ func (rl *RateLimiter) startCleanup() {
 
 // cleanup removes expired entries
 func (rl *RateLimiter) cleanup() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
+	expiredCount := rl.local.expire(rl.config.EntryTTL)
 	now := time.Now()
-	expiredCount := 0
-
-	for key, entry := range rl.entries {
-		if now.After(entry.lastUsed.Add(rl.config.EntryTTL)) {
-			delete(rl.entries, key)
-			expiredCount++
-		}
-	}
 
-	rl.stats.TotalEntries -= int64(expiredCount)
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 	rl.stats.CleanupCount++
 	rl.stats.LastCleanupTime = now
 
-	if rl.config.EnableDebug && expiredCount > 0 {
-		rl.logDebug("Cleaned up %d expired entries", expiredCount)
+	if expiredCount > 0 {
+		rl.logDebug(context.Background(), "cleaned up expired entries", "count", expiredCount)
 	}
 }
 
@@ -366,7 +291,9 @@ func (rl *RateLimiter) GetStats() RateLimitStats {
 	defer rl.mu.RUnlock()
 
 	stats := rl.stats
-	stats.TotalEntries = int64(len(rl.entries))
+	entries, evicted := rl.local.sizes()
+	stats.TotalEntries = int64(entries)
+	stats.EvictedCount = evicted
 	stats.Uptime = time.Since(rl.startTime)
 	
 	// Estimate memory usage (rough calculation)
@@ -385,12 +312,10 @@ func (rl *RateLimiter) Stop() {
 	}
 }
 
-// logDebug logs debug messages if enabled
-func (rl *RateLimiter) logDebug(format string, args ...interface{}) {
+// logDebug logs at debug level when EnableDebug is set
+func (rl *RateLimiter) logDebug(ctx context.Context, msg string, args ...any) {
 	if rl.config.EnableDebug {
-		// In a real implementation, you might use a proper logger
-		// For now, we'll just format the message
-		// log.Printf("[RateLimiter DEBUG] "+format, args...)
+		Logger(ctx).Debug("rate limiter: "+msg, args...)
 	}
 }
 
@@ -423,9 +348,17 @@ func NewProgressiveRateLimiter(config ProgressiveRateLimitConfig, baseConfig Rat
 	if config.LockoutDuration <= 0 {
 		config.LockoutDuration = 15 * time.Minute
 	}
+	if config.ChallengeThreshold <= 0 || config.ChallengeThreshold > config.LockoutThreshold {
+		config.ChallengeThreshold = config.LockoutThreshold / 3
+		if config.ChallengeThreshold < 1 {
+			config.ChallengeThreshold = 1
+		}
+	}
 
+	local := newMemoryRateLimiterStore(0)
 	prl := &ProgressiveRateLimiter{
-		entries:        make(map[string]*progressiveEntry),
+		local:          local,
+		store:          local,
 		config:         config,
 		baseConfig:     baseConfig,
 		startTime:      time.Now(),
@@ -445,116 +378,145 @@ func (prl *ProgressiveRateLimiter) Middleware() fiber.Handler {
 		// Add timeout to prevent rate limiter from hanging
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
-		
+
 		ipChan := make(chan string, 1)
 		go func() {
 			ipChan <- prl.getClientIP(c)
 		}()
-		
+
 		var ip string
 		select {
 		case ip = <-ipChan:
 		case <-ctx.Done():
 			// If IP extraction times out, allow request but log it
-			log.Printf("Rate limiter IP extraction timeout, allowing request")
+			Logger(ctx).Warn("rate limiter: IP extraction timed out, allowing request")
 			return c.Next()
 		}
-		
+
+		path, method := c.Path(), c.Method()
 		if ip == "" {
 			// If we can't get a valid IP, allow the request but log it
 			prl.mu.Lock()
-			prl.logSecurityEvent("UNKNOWN_IP", ip, c.Path(), c.Method(), "low", "Unable to determine client IP")
+			prl.logSecurityEvent(ctx, "UNKNOWN_IP", ip, path, method, "low", "Unable to determine client IP")
 			prl.mu.Unlock()
 			return c.Next()
 		}
 
-		allowedChan := make(chan bool, 1)
-		retryAfterChan := make(chan time.Duration, 1)
-		
+		type decision struct {
+			allowed    bool
+			retryAfter time.Duration
+			state      ProgressiveState
+		}
+		decisionChan := make(chan decision, 1)
 		go func() {
-			allowed, retryAfter := prl.allowRequest(ip, c)
-			allowedChan <- allowed
-			retryAfterChan <- retryAfter
+			allowed, retryAfter, st := prl.allowRequest(ctx, ip, path, method)
+			decisionChan <- decision{allowed, retryAfter, st}
 		}()
-		
-		var allowed bool
-		var retryAfter time.Duration
-		
+
+		var d decision
 		select {
-		case allowed = <-allowedChan:
-			retryAfter = <-retryAfterChan
+		case d = <-decisionChan:
 		case <-ctx.Done():
-			log.Printf("Rate limiter decision timeout for IP: %s, allowing request", ip)
+			Logger(ctx).Warn("rate limiter: decision timed out, allowing request", "ip", ip)
 			return c.Next()
 		}
-		
-		if !allowed {
-			prl.stats.DeniedCount++
-			
+
+		lockedOut := d.state.LockedOut && time.Now().Before(d.state.LockoutUntil)
+		reset := strconv.Itoa(int(time.Until(d.state.WindowStart.Add(prl.config.BaseWindow)).Seconds()))
+		if !d.allowed {
 			// Log security event
 			eventType := "RATE_LIMIT_EXCEEDED"
 			severity := "medium"
-			if prl.isLockedOut(ip) {
+			if lockedOut {
 				eventType = "ACCOUNT_LOCKOUT"
 				severity = "high"
 			}
-			
-			prl.logSecurityEvent(eventType, ip, c.Path(), c.Method(), severity, 
-				fmt.Sprintf("Rate limit exceeded. Retry after: %s", retryAfter))
+			if lockedOut {
+				RateLimitDenials.Inc("progressive_lockout")
+			} else {
+				RateLimitDenials.Inc("progressive")
+			}
+			prl.mu.Lock()
+			prl.stats.DeniedCount++
+			prl.logSecurityEvent(ctx, eventType, ip, path, method, severity,
+				fmt.Sprintf("Rate limit exceeded. Retry after: %s", d.retryAfter))
+			prl.mu.Unlock()
 
 			// Set retry-after header
-			if retryAfter > 0 {
-				c.Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
-				c.Set("X-RateLimit-Limit", strconv.Itoa(prl.getCurrentCapacity(ip)))
+			if d.retryAfter > 0 {
+				c.Set("Retry-After", strconv.Itoa(int(d.retryAfter.Seconds())))
+				c.Set("X-RateLimit-Limit", strconv.Itoa(d.state.Capacity))
 				c.Set("X-RateLimit-Remaining", "0")
-				c.Set("X-RateLimit-Reset", strconv.Itoa(int(time.Until(prl.getResetTime(ip)).Seconds())))
+				c.Set("X-RateLimit-Reset", reset)
 			}
 
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"error":      "Too many requests",
-				"retry_after": retryAfter,
-				"locked_out": prl.isLockedOut(ip),
+				"retry_after": d.retryAfter,
+				"locked_out": lockedOut,
 			})
 		}
 
 		// Add rate limit headers for successful requests
-		c.Set("X-RateLimit-Limit", strconv.Itoa(prl.getCurrentCapacity(ip)))
-		c.Set("X-RateLimit-Remaining", strconv.Itoa(prl.getRemainingTokens(ip)))
-		c.Set("X-RateLimit-Reset", strconv.Itoa(int(time.Until(prl.getResetTime(ip)).Seconds())))
+		c.Set("X-RateLimit-Limit", strconv.Itoa(d.state.Capacity))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(d.state.Capacity))
+		c.Set("X-RateLimit-Reset", reset)
 
 		return c.Next()
 	}
 }
 
-// allowRequest checks if a request from the given IP should be allowed with progressive backoff
-func (prl *ProgressiveRateLimiter) allowRequest(ip string, c *fiber.Ctx) (bool, time.Duration) {
-	prl.mu.Lock()
-	defer prl.mu.Unlock()
+// progressiveEvent is a security event produced while state is being updated; it is
+// logged afterwards so store callbacks never run under the limiter lock.
+type progressiveEvent struct {
+	eventType   string
+	severity    string
+	description string
+}
 
-	now := time.Now()
-	entry, exists := prl.entries[ip]
+// allowRequest checks if a request from the given IP should be allowed with progressive backoff
+func (prl *ProgressiveRateLimiter) allowRequest(ctx context.Context, ip, path, method string) (bool, time.Duration, ProgressiveState) {
+	var allowed bool
+	var retryAfter time.Duration
+	var events []progressiveEvent
+	st, err := prl.update(ctx, ip, func(entry *ProgressiveState) *ProgressiveState {
+		entry, allowed, retryAfter, events = prl.applyRequest(entry, ip, time.Now())
+		return entry
+	})
+	if err != nil {
+		Logger(ctx).Error("progressive rate limiter: update failed, denying request", "error", err)
+		return false, time.Second, st
+	}
+	prl.flushEvents(ctx, events, ip, path, method)
+	return allowed, retryAfter, st
+}
+
+// applyRequest advances entry (nil when the IP has no state yet) for one request.
+func (prl *ProgressiveRateLimiter) applyRequest(entry *ProgressiveState, ip string, now time.Time) (*ProgressiveState, bool, time.Duration, []progressiveEvent) {
+	var events []progressiveEvent
+	exists := entry != nil
 
 	// Check if IP is locked out
-	if exists && entry.isLockedOut && now.Before(entry.lockoutUntil) {
-		return false, time.Until(entry.lockoutUntil)
+	if exists && entry.LockedOut && now.Before(entry.LockoutUntil) {
+		return entry, false, entry.LockoutUntil.Sub(now), nil
 	}
 
 	// Reset lockout if period has passed
-	if exists && entry.isLockedOut && now.After(entry.lockoutUntil) {
-		entry.isLockedOut = false
-		entry.consecutiveFailures = 0
-		entry.firstFailure = time.Time{}
-		prl.logSecurityEvent("LOCKOUT_RESET", ip, c.Path(), c.Method(), "low", "Lockout period reset for IP")
+	if exists && entry.LockedOut && now.After(entry.LockoutUntil) {
+		entry.LockedOut = false
+		entry.ConsecutiveFailures = 0
+		entry.FirstFailure = time.Time{}
+		events = append(events, progressiveEvent{"LOCKOUT_RESET", "low", "Lockout period reset for IP"})
 	}
 
 	// Create new entry if it doesn't exist or window has expired
-	if !exists || now.After(entry.currentWindow.Add(prl.config.BaseWindow)) {
+	if !exists || now.After(entry.WindowStart.Add(prl.config.BaseWindow)) {
 		capacity := prl.config.BaseCapacity
-		
+
 		// Reduce capacity based on previous failures
-		if exists && entry.consecutiveFailures > 0 {
+		if exists && entry.ConsecutiveFailures > 0 {
 			reductionFactor := prl.config.BackoffFactor
-			for i := 0; i < entry.consecutiveFailures && i < 10; i++ {
+			for i := 0; i < entry.ConsecutiveFailures && i < 10; i++ {
 				capacity = int(float64(capacity) / reductionFactor)
 				if capacity < prl.config.MinCapacity {
 					capacity = prl.config.MinCapacity
@@ -563,43 +525,36 @@ func (prl *ProgressiveRateLimiter) allowRequest(ip string, c *fiber.Ctx) (bool,
 			}
 		}
 
-		entry = &progressiveEntry{
-			currentWindow:  now,
-			currentCapacity: capacity,
-			consecutiveFailures: 0,
-			totalAttempts:      0,
-			firstFailure:       time.Time{},
-			isLockedOut:        false,
-			lockoutUntil:       time.Time{},
-			lastUpdated:       now,
-			ipAddress:         ip,
+		entry = &ProgressiveState{
+			WindowStart: now,
+			Capacity:    capacity,
+			LastUpdated: now,
+			IP:          ip,
 		}
-		prl.entries[ip] = entry
-		prl.stats.TotalEntries++
 	}
 
 	// Update last used time
-	entry.lastUpdated = now
-	entry.totalAttempts++
+	entry.LastUpdated = now
+	entry.TotalAttempts++
 
 	// Check if request is allowed
-	if entry.currentCapacity <= 0 {
-		entry.consecutiveFailures++
-		
+	if entry.Capacity <= 0 {
+		entry.ConsecutiveFailures++
+
 		// Check if we should lock out this IP
-		if entry.consecutiveFailures >= prl.config.LockoutThreshold {
-			entry.isLockedOut = true
-			entry.lockoutUntil = now.Add(prl.config.LockoutDuration)
-			
-			prl.logSecurityEvent("ACCOUNT_LOCKOUT", ip, c.Path(), c.Method(), "high", 
-				fmt.Sprintf("IP locked out after %d consecutive failures", entry.consecutiveFailures))
-			
-			return false, prl.config.LockoutDuration
+		if entry.ConsecutiveFailures >= prl.config.LockoutThreshold {
+			entry.LockedOut = true
+			entry.LockoutUntil = now.Add(prl.config.LockoutDuration)
+
+			events = append(events, progressiveEvent{"ACCOUNT_LOCKOUT", "high",
+				fmt.Sprintf("IP locked out after %d consecutive failures", entry.ConsecutiveFailures)})
+
+			return entry, false, prl.config.LockoutDuration, events
 		}
 
 		// Calculate progressive backoff
 		backoffWindow := prl.config.BaseWindow
-		for i := 1; i < entry.consecutiveFailures && i < 10; i++ {
+		for i := 1; i < entry.ConsecutiveFailures && i < 10; i++ {
 			backoffWindow = time.Duration(float64(backoffWindow) * prl.config.BackoffFactor)
 			if backoffWindow > prl.config.MaxWindow {
 				backoffWindow = prl.config.MaxWindow
@@ -608,116 +563,161 @@ func (prl *ProgressiveRateLimiter) allowRequest(ip string, c *fiber.Ctx) (bool,
 		}
 
 		// Extend the current window
-		entry.currentWindow = now.Add(backoffWindow)
-		entry.currentCapacity = prl.config.BaseCapacity / (entry.consecutiveFailures + 1)
-		if entry.currentCapacity < prl.config.MinCapacity {
-			entry.currentCapacity = prl.config.MinCapacity
+		entry.WindowStart = now.Add(backoffWindow)
+		entry.Capacity = prl.config.BaseCapacity / (entry.ConsecutiveFailures + 1)
+		if entry.Capacity < prl.config.MinCapacity {
+			entry.Capacity = prl.config.MinCapacity
 		}
 
-		prl.logSecurityEvent("PROGRESSIVE_BACKOFF", ip, c.Path(), c.Method(), "medium",
-			fmt.Sprintf("Progressive backoff applied: %d consecutive failures, window: %s", 
-				entry.consecutiveFailures, backoffWindow))
+		events = append(events, progressiveEvent{"PROGRESSIVE_BACKOFF", "medium",
+			fmt.Sprintf("Progressive backoff applied: %d consecutive failures, window: %s",
+				entry.ConsecutiveFailures, backoffWindow)})
 
-		return false, backoffWindow
+		return entry, false, backoffWindow, events
 	}
 
-	entry.currentCapacity--
-	return true, 0
+	entry.Capacity--
+	return entry, true, 0, events
 }
 
 // RecordFailure records a failed authentication attempt for progressive backoff
 func (prl *ProgressiveRateLimiter) RecordFailure(ip string, c *fiber.Ctx) {
-	prl.mu.Lock()
-	defer prl.mu.Unlock()
+	var events []progressiveEvent
+	_, err := prl.update(c.Context(), ip, func(entry *ProgressiveState) *ProgressiveState {
+		events = nil
+		now := time.Now()
+		if entry == nil {
+			entry = &ProgressiveState{
+				WindowStart: now,
+				Capacity:    prl.config.BaseCapacity,
+				LastUpdated: now,
+				IP:          ip,
+			}
+		}
 
-	now := time.Now()
-	entry, exists := prl.entries[ip]
-
-	if !exists {
-		entry = &progressiveEntry{
-			currentWindow:  now,
-			currentCapacity: prl.config.BaseCapacity,
-			consecutiveFailures: 0,
-			totalAttempts:      0,
-			firstFailure:       time.Time{},
-			isLockedOut:        false,
-			lockoutUntil:       time.Time{},
-			lastUpdated:       now,
-			ipAddress:         ip,
+		entry.ConsecutiveFailures++
+		entry.TotalAttempts++
+
+		if entry.FirstFailure.IsZero() {
+			entry.FirstFailure = now
 		}
-		prl.entries[ip] = entry
-		prl.stats.TotalEntries++
-	}
 
-	entry.consecutiveFailures++
-	entry.totalAttempts++
-	
-	if entry.firstFailure.IsZero() {
-		entry.firstFailure = now
-	}
+		// Check for immediate lockout (for repeated auth failures)
+		if entry.ConsecutiveFailures >= prl.config.LockoutThreshold {
+			entry.LockedOut = true
+			entry.LockoutUntil = now.Add(prl.config.LockoutDuration)
 
-	// Check for immediate lockout (for repeated auth failures)
-	if entry.consecutiveFailures >= prl.config.LockoutThreshold {
-		entry.isLockedOut = true
-		entry.lockoutUntil = now.Add(prl.config.LockoutDuration)
-		
-		prl.logSecurityEvent("AUTH_FAILURE_LOCKOUT", ip, c.Path(), c.Method(), "high",
-			fmt.Sprintf("Authentication failure lockout: %d consecutive failures", entry.consecutiveFailures))
-	} else {
-		prl.logSecurityEvent("AUTH_FAILURE", ip, c.Path(), c.Method(), "medium",
-			fmt.Sprintf("Authentication failure recorded: %d consecutive failures", entry.consecutiveFailures))
+			events = append(events, progressiveEvent{"AUTH_FAILURE_LOCKOUT", "high",
+				fmt.Sprintf("Authentication failure lockout: %d consecutive failures", entry.ConsecutiveFailures)})
+		} else {
+			events = append(events, progressiveEvent{"AUTH_FAILURE", "medium",
+				fmt.Sprintf("Authentication failure recorded: %d consecutive failures", entry.ConsecutiveFailures)})
+		}
+		return entry
+	})
+	if err != nil {
+		Logger(c.Context()).Error("progressive rate limiter: recording failure failed", "error", err)
+		return
 	}
+	prl.flushEvents(c.Context(), events, ip, c.Path(), c.Method())
 }
 
 // RecordSuccess resets the failure counter for successful authentication
 func (prl *ProgressiveRateLimiter) RecordSuccess(ip string, c *fiber.Ctx) {
-	prl.mu.Lock()
-	defer prl.mu.Unlock()
-
-	entry, exists := prl.entries[ip]
-	if exists {
+	var events []progressiveEvent
+	_, err := prl.update(c.Context(), ip, func(entry *ProgressiveState) *ProgressiveState {
+		events = nil
+		if entry == nil {
+			return nil
+		}
 		// Reset failure counter on successful authentication
-		if entry.consecutiveFailures > 0 {
-			prl.logSecurityEvent("AUTH_SUCCESS", ip, c.Path(), c.Method(), "low",
-				fmt.Sprintf("Authentication success after %d failures", entry.consecutiveFailures))
+		if entry.ConsecutiveFailures > 0 {
+			events = append(events, progressiveEvent{"AUTH_SUCCESS", "low",
+				fmt.Sprintf("Authentication success after %d failures", entry.ConsecutiveFailures)})
 		}
-		
-		entry.consecutiveFailures = 0
-		entry.firstFailure = time.Time{}
-		entry.currentCapacity = prl.config.BaseCapacity
-		entry.isLockedOut = false
-		entry.lockoutUntil = time.Time{}
+
+		entry.ConsecutiveFailures = 0
+		entry.FirstFailure = time.Time{}
+		entry.Capacity = prl.config.BaseCapacity
+		entry.LockedOut = false
+		entry.LockoutUntil = time.Time{}
+		return entry
+	})
+	if err != nil {
+		Logger(c.Context()).Error("progressive rate limiter: recording success failed", "error", err)
+		return
 	}
+	prl.flushEvents(c.Context(), events, ip, c.Path(), c.Method())
 }
 
-// Helper methods
-func (prl *ProgressiveRateLimiter) getClientIP(c *fiber.Ctx) string {
-	// Try to get real IP from X-Forwarded-For header
-	if forwarded := c.Get("X-Forwarded-For"); forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		if len(ips) > 0 {
-			// Get the leftmost IP (original client)
-			clientIP := strings.TrimSpace(ips[0])
-			if prl.isValidIP(clientIP) {
-				return prl.normalizeIP(clientIP)
-			}
-		}
+// Suspicious reports whether ip has failed authentication often enough (or is locked out)
+// that abuse-prone endpoints should ask it to solve a challenge first. When its state
+// can't be read it is treated as suspicious.
+func (prl *ProgressiveRateLimiter) Suspicious(ctx context.Context, ip string) bool {
+	st, err := prl.update(ctx, ip, func(entry *ProgressiveState) *ProgressiveState {
+		return entry
+	})
+	if err != nil {
+		return true
 	}
+	if st.LockedOut && time.Now().Before(st.LockoutUntil) {
+		return true
+	}
+	return st.ConsecutiveFailures >= prl.config.ChallengeThreshold
+}
 
-	// Try X-Real-IP header
-	if realIP := c.Get("X-Real-IP"); realIP != "" {
-		if prl.isValidIP(realIP) {
-			return prl.normalizeIP(realIP)
-		}
+// WithStore shares progressive state (failure counters, lockouts) through store, so every
+// replica behind a load balancer sees the same lockouts. Nil keeps state in memory.
+func (prl *ProgressiveRateLimiter) WithStore(store RateLimiterStore) *ProgressiveRateLimiter {
+	if store == nil {
+		store = prl.local
 	}
+	prl.store = store
+	return prl
+}
 
-	// Fall back to remote address
-	remoteAddr := c.IP()
-	if remoteAddr != "" && prl.isValidIP(remoteAddr) {
-		return prl.normalizeIP(remoteAddr)
+// update applies fn to the state for ip through the store, falling back to the local
+// store if a shared one is unavailable. fn receives nil when no state exists and may
+// return nil to leave the state untouched. A contended shared state is not retried
+// locally: the error is returned so callers fail closed.
+func (prl *ProgressiveRateLimiter) update(ctx context.Context, ip string, fn func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error) {
+	st, err := prl.store.UpdateProgressive(ctx, ip, prl.stateTTL(), fn)
+	if err != nil && prl.store != prl.local && !errors.Is(err, ErrRateLimitContended) {
+		Logger(ctx).Warn("progressive rate limiter: shared store failed, using local state", "error", err)
+		st, err = prl.local.UpdateProgressive(ctx, ip, prl.stateTTL(), fn)
 	}
+	return st, err
+}
 
-	return ""
+// stateTTL bounds how long idle state survives in a shared store.
+func (prl *ProgressiveRateLimiter) stateTTL() time.Duration {
+	ttl := prl.baseConfig.EntryTTL
+	if prl.config.LockoutDuration > ttl {
+		ttl = prl.config.LockoutDuration
+	}
+	if prl.config.MaxWindow > ttl {
+		ttl = prl.config.MaxWindow
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return ttl
+}
+
+func (prl *ProgressiveRateLimiter) flushEvents(ctx context.Context, events []progressiveEvent, ip, path, method string) {
+	if len(events) == 0 {
+		return
+	}
+	prl.mu.Lock()
+	defer prl.mu.Unlock()
+	for _, ev := range events {
+		prl.logSecurityEvent(ctx, ev.eventType, ip, path, method, ev.severity, ev.description)
+	}
+}
+
+// Helper methods
+func (prl *ProgressiveRateLimiter) getClientIP(c *fiber.Ctx) string {
+	return ClientIP(c)
 }
 
 func (prl *ProgressiveRateLimiter) isValidIP(ip string) bool {
@@ -738,40 +738,6 @@ func (prl *ProgressiveRateLimiter) normalizeIP(ip string) string {
 	return parsedIP.String()
 }
 
-func (prl *ProgressiveRateLimiter) isLockedOut(ip string) bool {
-	entry, exists := prl.entries[ip]
-	if !exists {
-		return false
-	}
-	
-	now := time.Now()
-	return entry.isLockedOut && now.Before(entry.lockoutUntil)
-}
-
-func (prl *ProgressiveRateLimiter) getCurrentCapacity(ip string) int {
-	entry, exists := prl.entries[ip]
-	if !exists {
-		return prl.config.BaseCapacity
-	}
-	return entry.currentCapacity
-}
-
-func (prl *ProgressiveRateLimiter) getRemainingTokens(ip string) int {
-	entry, exists := prl.entries[ip]
-	if !exists {
-		return prl.config.BaseCapacity
-	}
-	return entry.currentCapacity
-}
-
-func (prl *ProgressiveRateLimiter) getResetTime(ip string) time.Time {
-	entry, exists := prl.entries[ip]
-	if !exists {
-		return time.Now().Add(prl.config.BaseWindow)
-	}
-	return entry.currentWindow.Add(prl.config.BaseWindow)
-}
-
 // startCleanup starts the background cleanup goroutine
 func (prl *ProgressiveRateLimiter) startCleanup() {
 	prl.cleanupTimer = time.NewTimer(prl.baseConfig.CleanupInterval)
@@ -791,21 +757,12 @@ func (prl *ProgressiveRateLimiter) startCleanup() {
 
 // cleanup removes expired entries
 func (prl *ProgressiveRateLimiter) cleanup() {
-	prl.mu.Lock()
-	defer prl.mu.Unlock()
-
+	// Remove entries that haven't been used for the TTL period
+	prl.local.expire(prl.baseConfig.EntryTTL)
 	now := time.Now()
-	expiredCount := 0
 
-	for key, entry := range prl.entries {
-		// Remove entries that haven't been used for the TTL period
-		if now.After(entry.lastUpdated.Add(prl.baseConfig.EntryTTL)) {
-			delete(prl.entries, key)
-			expiredCount++
-		}
-	}
-
-	prl.stats.TotalEntries -= int64(expiredCount)
+	prl.mu.Lock()
+	defer prl.mu.Unlock()
 	prl.stats.CleanupCount++
 	prl.stats.LastCleanupTime = now
 }
@@ -816,9 +773,10 @@ func (prl *ProgressiveRateLimiter) GetProgressiveStats() map[string]interface{}
 	defer prl.mu.RUnlock()
 
 	stats := make(map[string]interface{})
+	states := prl.local.progressiveStates()
 	
 	// Basic stats
-	stats["total_entries"] = len(prl.entries)
+	stats["total_entries"] = len(states)
 	stats["denied_count"] = prl.stats.DeniedCount
 	stats["uptime"] = time.Since(prl.startTime).String()
 	
@@ -827,21 +785,23 @@ func (prl *ProgressiveRateLimiter) GetProgressiveStats() map[string]interface{}
 	totalFailures := 0
 	totalAttempts := 0
 	
-	for _, entry := range prl.entries {
-		if entry.isLockedOut {
+	for _, entry := range states {
+		if entry.LockedOut {
 			lockedOutCount++
 		}
-		totalFailures += entry.consecutiveFailures
-		totalAttempts += entry.totalAttempts
+		totalFailures += entry.ConsecutiveFailures
+		totalAttempts += entry.TotalAttempts
 	}
 	
 	stats["locked_out_ips"] = lockedOutCount
 	stats["total_failures"] = totalFailures
 	stats["total_attempts"] = totalAttempts
 	stats["security_events"] = len(prl.securityEvents)
+	// With a shared store, entry counts cover only IPs this instance has seen locally
+	stats["shared_store"] = prl.store != prl.local
 	
 	// Estimate memory usage
-	estimatedMemory := int64(len(prl.entries)) * 120 // Rough estimate
+	estimatedMemory := int64(len(states)) * 120 // Rough estimate
 	stats["memory_usage_bytes"] = estimatedMemory
 	
 	return stats
@@ -869,7 +829,7 @@ func (prl *ProgressiveRateLimiter) SetEventCallback(callback func(SecurityEvent)
 
 // logSecurityEvent logs a security event.
 // IMPORTANT: This method does NOT acquire a lock, and should only be called by methods that have already acquired the lock.
-func (prl *ProgressiveRateLimiter) logSecurityEvent(eventType, ip, path, method, severity, description string) {
+func (prl *ProgressiveRateLimiter) logSecurityEvent(ctx context.Context, eventType, ip, path, method, severity, description string) {
 	if !prl.config.EnableLogging {
 		return
 	}
@@ -882,7 +842,14 @@ func (prl *ProgressiveRateLimiter) logSecurityEvent(eventType, ip, path, method,
 		Method:      method,
 		Severity:    severity,
 		Description: description,
+		RequestID:   RequestIDFromContext(ctx),
+	}
+	level := slog.LevelWarn
+	if severity == "low" {
+		level = slog.LevelInfo
 	}
+	slog.Log(context.Background(), level, "security event", "event_type", eventType, "severity", severity, "ip", ip,
+		"path", path, "method", method, "description", description, "request_id", event.RequestID)
 
 	prl.securityEvents = append(prl.securityEvents, event)
 	
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Rate limit policies are declared in config.yaml (rate_limit_policies) and applied to
+// every API request by one middleware, instead of being wired at route registration.
+// Every matching policy applies, each with its own counter. Admins can reload the list
+// from config.yaml without a restart.
+
+// Policy keys: what a policy counts requests by. user and token fall back to the client
+// IP for anonymous requests.
+const (
+	RateLimitKeyIP    = "ip"
+	RateLimitKeyUser  = "user"
+	RateLimitKeyToken = "token"
+)
+
+// RateLimitPolicy limits requests to routes matching Route to Capacity per Window.
+// Route is "METHOD /path" or "/path" for any method; ":param" matches one path segment
+// and a trailing "*" matches the rest of the path.
+type RateLimitPolicy struct {
+	Name     string        `yaml:"name" json:"name"`
+	Route    string        `yaml:"route" json:"route"`
+	Capacity int           `yaml:"capacity" json:"capacity"`
+	Window   time.Duration `yaml:"window" json:"-"`
+	Key      string        `yaml:"key" json:"key"`
+}
+
+// DefaultRateLimitPolicies are used when config.yaml declares none.
+func DefaultRateLimitPolicies() []RateLimitPolicy {
+	return []RateLimitPolicy{
+		// Originals are heavier than the public renditions and may be re-encoded with a watermark
+		{Name: "download", Route: "GET /api/images/:id/download", Capacity: 20, Window: 3 * time.Second, Key: RateLimitKeyIP},
+		{Name: "upload", Route: "POST /api/upload", Capacity: 30, Window: 10 * time.Minute, Key: RateLimitKeyUser},
+		{Name: "graphql", Route: "/api/graphql", Capacity: 60, Window: time.Minute, Key: RateLimitKeyIP},
+		{Name: "csp-report", Route: "POST " + CSPReportPath, Capacity: 20, Window: time.Minute, Key: RateLimitKeyIP},
+	}
+}
+
+type compiledPolicy struct {
+	RateLimitPolicy
+	method string
+	segs   []string
+}
+
+// RateLimitPolicySet is a validated, ready to match list of policies.
+type RateLimitPolicySet struct {
+	policies []compiledPolicy
+	loadedAt time.Time
+}
+
+// CompileRateLimitPolicies validates list and prepares it for matching.
+func CompileRateLimitPolicies(list []RateLimitPolicy) (*RateLimitPolicySet, error) {
+	set := &RateLimitPolicySet{loadedAt: time.Now()}
+	seen := map[string]bool{}
+	for i, p := range list {
+		p.Name = strings.TrimSpace(p.Name)
+		p.Key = strings.ToLower(strings.TrimSpace(p.Key))
+		if p.Key == "" {
+			p.Key = RateLimitKeyIP
+		}
+		switch {
+		case p.Name == "":
+			return nil, fmt.Errorf("rate_limit_policies[%d]: name is required", i)
+		case seen[p.Name]:
+			return nil, fmt.Errorf("rate_limit_policies[%d]: duplicate name %q", i, p.Name)
+		case p.Capacity < 1:
+			return nil, fmt.Errorf("rate limit policy %q: capacity must be at least 1", p.Name)
+		case p.Window < 100*time.Millisecond || p.Window > 24*time.Hour:
+			return nil, fmt.Errorf("rate limit policy %q: window must be between 100ms and 24h", p.Name)
+		case p.Key != RateLimitKeyIP && p.Key != RateLimitKeyUser && p.Key != RateLimitKeyToken:
+			return nil, fmt.Errorf("rate limit policy %q: key must be ip, user or token", p.Name)
+		}
+		seen[p.Name] = true
+		cp := compiledPolicy{RateLimitPolicy: p}
+		route := strings.TrimSpace(p.Route)
+		if method, path, ok := strings.Cut(route, " "); ok {
+			cp.method = strings.ToUpper(strings.TrimSpace(method))
+			route = strings.TrimSpace(path)
+		}
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("rate limit policy %q: route must be a path, optionally after a method", p.Name)
+		}
+		cp.segs = splitPath(route)
+		for j, s := range cp.segs {
+			if s == "*" && j != len(cp.segs)-1 {
+				return nil, fmt.Errorf("rate limit policy %q: * is only allowed at the end of a route", p.Name)
+			}
+		}
+		set.policies = append(set.policies, cp)
+	}
+	return set, nil
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func (p *compiledPolicy) matches(method string, path []string) bool {
+	if p.method != "" && p.method != method && !(p.method == "GET" && method == "HEAD") {
+		return false
+	}
+	for i, s := range p.segs {
+		if s == "*" {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		if strings.HasPrefix(s, ":") {
+			if path[i] == "" {
+				return false
+			}
+			continue
+		}
+		if !strings.EqualFold(s, path[i]) {
+			return false
+		}
+	}
+	return len(path) == len(p.segs)
+}
+
+// Match returns the policies that apply to a request.
+func (s *RateLimitPolicySet) Match(method, path string) []RateLimitPolicy {
+	var out []RateLimitPolicy
+	segs := splitPath(path)
+	for i := range s.policies {
+		if s.policies[i].matches(method, segs) {
+			out = append(out, s.policies[i].RateLimitPolicy)
+		}
+	}
+	return out
+}
+
+// Policies returns the policies in declaration order.
+func (s *RateLimitPolicySet) Policies() []RateLimitPolicy {
+	out := make([]RateLimitPolicy, len(s.policies))
+	for i := range s.policies {
+		out[i] = s.policies[i].RateLimitPolicy
+	}
+	return out
+}
+
+// LoadedAt is when the set was compiled.
+func (s *RateLimitPolicySet) LoadedAt() time.Time { return s.loadedAt }
+
+var rateLimitPolicies atomic.Pointer[RateLimitPolicySet]
+
+func init() {
+	set, _ := CompileRateLimitPolicies(DefaultRateLimitPolicies())
+	rateLimitPolicies.Store(set)
+}
+
+// SetRateLimitPolicies validates list and makes it the process-wide policy set. The
+// current set is kept when list is invalid.
+func SetRateLimitPolicies(list []RateLimitPolicy) error {
+	set, err := CompileRateLimitPolicies(list)
+	if err != nil {
+		return err
+	}
+	rateLimitPolicies.Store(set)
+	return nil
+}
+
+// CurrentRateLimitPolicies returns the active policy set.
+func CurrentRateLimitPolicies() *RateLimitPolicySet { return rateLimitPolicies.Load() }
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileRateLimitPolicies(t *testing.T) {
+	_, err := CompileRateLimitPolicies(DefaultRateLimitPolicies())
+	assert.NoError(t, err)
+
+	bad := []struct {
+		name string
+		p    RateLimitPolicy
+	}{
+		{"no name", RateLimitPolicy{Route: "/api/x", Capacity: 1, Window: time.Second}},
+		{"zero capacity", RateLimitPolicy{Name: "a", Route: "/api/x", Window: time.Second}},
+		{"tiny window", RateLimitPolicy{Name: "a", Route: "/api/x", Capacity: 1, Window: time.Millisecond}},
+		{"unknown key", RateLimitPolicy{Name: "a", Route: "/api/x", Capacity: 1, Window: time.Second, Key: "cookie"}},
+		{"relative route", RateLimitPolicy{Name: "a", Route: "GET api/x", Capacity: 1, Window: time.Second}},
+		{"inner wildcard", RateLimitPolicy{Name: "a", Route: "/api/*/x", Capacity: 1, Window: time.Second}},
+	}
+	for _, tc := range bad {
+		_, err := CompileRateLimitPolicies([]RateLimitPolicy{tc.p})
+		assert.Error(t, err, tc.name)
+	}
+
+	dup := RateLimitPolicy{Name: "a", Route: "/api/x", Capacity: 1, Window: time.Second}
+	_, err = CompileRateLimitPolicies([]RateLimitPolicy{dup, dup})
+	assert.Error(t, err)
+}
+
+func TestRateLimitPolicyMatch(t *testing.T) {
+	set, err := CompileRateLimitPolicies([]RateLimitPolicy{
+		{Name: "download", Route: "GET /api/images/:id/download", Capacity: 1, Window: time.Second},
+		{Name: "admin", Route: "/api/admin/*", Capacity: 1, Window: time.Second, Key: "USER"},
+		{Name: "any", Route: "/api/graphql", Capacity: 1, Window: time.Second},
+	})
+	assert.NoError(t, err)
+
+	names := func(method, path string) []string {
+		var out []string
+		for _, p := range set.Match(method, path) {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"download"}, names("GET", "/api/images/abc/download"))
+	assert.Equal(t, []string{"download"}, names("HEAD", "/api/images/abc/download/"))
+	assert.Nil(t, names("POST", "/api/images/abc/download"))
+	assert.Nil(t, names("GET", "/api/images/abc"))
+	assert.Equal(t, []string{"admin"}, names("DELETE", "/api/admin/users/1"))
+	assert.Equal(t, []string{"admin"}, names("GET", "/api/admin"))
+	assert.Equal(t, []string{"any"}, names("POST", "/api/GraphQL"))
+	assert.Equal(t, RateLimitKeyUser, set.Policies()[1].Key)
+}
+
+func TestSetRateLimitPoliciesKeepsCurrentOnError(t *testing.T) {
+	defer SetRateLimitPolicies(DefaultRateLimitPolicies())
+	before := CurrentRateLimitPolicies()
+	assert.Error(t, SetRateLimitPolicies([]RateLimitPolicy{{Name: "a"}}))
+	assert.Same(t, before, CurrentRateLimitPolicies())
+
+	assert.NoError(t, SetRateLimitPolicies(nil))
+	assert.Empty(t, CurrentRateLimitPolicies().Policies())
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimiterStore holds limiter state. Limiters without a shared store use an in-memory
+// one of their own; a shared store (Redis) makes limits and progressive
+// lockouts apply across every replica behind a load balancer instead of per instance.
+type RateLimiterStore interface {
+	// Take counts one request for key in a fixed window and reports whether it fits capacity.
+	Take(ctx context.Context, key string, capacity int, window time.Duration) (bool, error)
+	// UpdateProgressive applies fn to the progressive state of key (nil when absent) and
+	// persists the result for ttl. fn may return nil to leave the state untouched.
+	UpdateProgressive(ctx context.Context, key string, ttl time.Duration, fn func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error)
+}
+
+type redisRateLimiterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimiterStore returns a RateLimiterStore backed by Redis under prefix.
+func NewRedisRateLimiterStore(client *redis.Client, prefix string) RateLimiterStore {
+	return &redisRateLimiterStore{client: client, prefix: prefix}
+}
+
+// The first hit in a window sets its expiry, so the window is fixed from the first request.
+var redisTakeScript = redis.NewScript(`local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return n`)
+
+// Writes ARGV[2] only if the key still holds ARGV[1], the empty string standing for absent.
+var redisCompareAndSetScript = redis.NewScript(`local cur = redis.call('GET', KEYS[1]) or ''
+if cur ~= ARGV[1] then return 0 end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1`)
+
+// redisUpdateAttempts bounds how often UpdateProgressive rereads a key other replicas keep
+// changing before giving up with ErrRateLimitContended.
+const redisUpdateAttempts = 10
+
+// ErrRateLimitContended is returned when a shared progressive state could not be updated
+// atomically. Limiters treat it as a denial rather than risk losing a failure or lockout.
+var ErrRateLimitContended = errors.New("rate limit state contended")
+
+func (s *redisRateLimiterStore) Take(ctx context.Context, key string, capacity int, window time.Duration) (bool, error) {
+	ms := window.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	n, err := redisTakeScript.Run(ctx, s.client, []string{s.prefix + "rl:" + key}, ms).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n <= int64(capacity), nil
+}
+
+// UpdateProgressive reads the state, applies fn and writes the result back only if the
+// stored value is still the one read, retrying when another replica got there first. When
+// the key stays contended it returns ErrRateLimitContended rather than write blindly.
+func (s *redisRateLimiterStore) UpdateProgressive(ctx context.Context, key string, ttl time.Duration, fn func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error) {
+	stateKey := s.prefix + "pl:" + key
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	for i := 0; i < redisUpdateAttempts; i++ {
+		var cur *ProgressiveState
+		old, err := s.client.Get(ctx, stateKey).Bytes()
+		switch {
+		case err == nil:
+			var st ProgressiveState
+			if json.Unmarshal(old, &st) == nil {
+				cur = &st
+			}
+		case errors.Is(err, redis.Nil):
+			old = nil
+		default:
+			return ProgressiveState{}, err
+		}
+		next := fn(cur)
+		if next == nil {
+			if cur != nil {
+				return *cur, nil
+			}
+			return ProgressiveState{IP: key, WindowStart: time.Now()}, nil
+		}
+		b, err := json.Marshal(next)
+		if err != nil {
+			return ProgressiveState{}, err
+		}
+		n, err := redisCompareAndSetScript.Run(ctx, s.client, []string{stateKey}, string(old), string(b), ms).Int64()
+		if err != nil {
+			return ProgressiveState{}, err
+		}
+		if n == 1 {
+			return *next, nil
+		}
+		select {
+		case <-ctx.Done():
+			return ProgressiveState{}, ctx.Err()
+		case <-time.After(time.Duration(i+1) * 5 * time.Millisecond):
+		}
+	}
+	return ProgressiveState{}, ErrRateLimitContended
+}
+
+// memoryRateLimiterStore keeps limiter state in process. Every limiter has one: it is the
+// store when no shared one is configured, and the fallback when the shared one is down.
+type memoryRateLimiterStore struct {
+	mu         sync.Mutex
+	counts     map[string]*rlEntry
+	states     map[string]*ProgressiveState
+	maxEntries int
+	evicted    int64
+}
+
+func newMemoryRateLimiterStore(maxEntries int) *memoryRateLimiterStore {
+	return &memoryRateLimiterStore{
+		counts:     make(map[string]*rlEntry),
+		states:     make(map[string]*ProgressiveState),
+		maxEntries: maxEntries,
+	}
+}
+
+func (s *memoryRateLimiterStore) Take(_ context.Context, key string, capacity int, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, exists := s.counts[key]
+	if !exists || now.After(entry.refillAt) {
+		entry = &rlEntry{tokens: capacity, refillAt: now.Add(window), ipAddress: key}
+		s.counts[key] = entry
+	}
+	entry.lastUsed = now
+	if s.maxEntries > 0 && len(s.counts) > s.maxEntries {
+		s.evictLRU()
+	}
+	if entry.tokens <= 0 {
+		return false, nil
+	}
+	entry.tokens--
+	return true, nil
+}
+
+// evictLRU removes the least recently used count. Callers hold s.mu.
+func (s *memoryRateLimiterStore) evictLRU() {
+	var oldestKey string
+	var oldestTime time.Time
+	for key, entry := range s.counts {
+		if oldestKey == "" || entry.lastUsed.Before(oldestTime) {
+			oldestKey, oldestTime = key, entry.lastUsed
+		}
+	}
+	if oldestKey != "" {
+		delete(s.counts, oldestKey)
+		s.evicted++
+	}
+}
+
+func (s *memoryRateLimiterStore) UpdateProgressive(_ context.Context, key string, _ time.Duration, fn func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cur *ProgressiveState
+	if e, ok := s.states[key]; ok {
+		cp := *e
+		cur = &cp
+	}
+	next := fn(cur)
+	if next == nil {
+		if cur != nil {
+			return *cur, nil
+		}
+		return ProgressiveState{IP: key, WindowStart: time.Now()}, nil
+	}
+	s.states[key] = next
+	return *next, nil
+}
+
+// expire drops counts and states idle for longer than ttl and reports how many went.
+func (s *memoryRateLimiterStore) expire(ttl time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for key, entry := range s.counts {
+		if now.After(entry.lastUsed.Add(ttl)) {
+			delete(s.counts, key)
+			n++
+		}
+	}
+	for key, st := range s.states {
+		if now.After(st.LastUpdated.Add(ttl)) {
+			delete(s.states, key)
+			n++
+		}
+	}
+	return n
+}
+
+// sizes reports the number of counts held and how many were evicted so far.
+func (s *memoryRateLimiterStore) sizes() (counts int, evicted int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counts), s.evicted
+}
+
+// progressiveStates returns a copy of every progressive state held.
+func (s *memoryRateLimiterStore) progressiveStates() []ProgressiveState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ProgressiveState, 0, len(s.states))
+	for _, st := range s.states {
+		out = append(out, *st)
+	}
+	return out
+}
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,7 +25,7 @@ func TestRateLimiterBasics(t *testing.T) {
 
 	// Test that rate limiter is created
 	assert.NotNil(t, limiter)
-	assert.NotNil(t, limiter.entries)
+	assert.NotNil(t, limiter.store)
 
 	// Test basic rate limiting functionality
 	allowed := limiter.allowRequest("192.168.1.1", 2, time.Minute)
@@ -177,4 +180,105 @@ func TestRateLimiterCleanup(t *testing.T) {
 	stats := limiter.GetStats()
 	assert.Equal(t, int64(0), stats.TotalEntries)
 	assert.Greater(t, stats.CleanupCount, int64(0))
-}
\ No newline at end of file
+}
+// sharedTestStore is an in-memory RateLimiterStore standing in for Redis.
+type sharedTestStore struct {
+	mu     sync.Mutex
+	counts map[string]int
+	states map[string]ProgressiveState
+}
+
+func (s *sharedTestStore) Take(_ context.Context, key string, capacity int, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key]++
+	return s.counts[key] <= capacity, nil
+}
+
+func (s *sharedTestStore) UpdateProgressive(_ context.Context, key string, _ time.Duration, fn func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cur *ProgressiveState
+	if st, ok := s.states[key]; ok {
+		cur = &st
+	}
+	next := fn(cur)
+	if next == nil {
+		return ProgressiveState{}, nil
+	}
+	s.states[key] = *next
+	return *next, nil
+}
+
+func TestRateLimitersShareStore(t *testing.T) {
+	store := &sharedTestStore{counts: map[string]int{}, states: map[string]ProgressiveState{}}
+	cfg := RateLimitConfig{MaxEntries: 10, CleanupInterval: time.Minute, EntryTTL: time.Minute}
+	a := NewRateLimiter(cfg).WithStore(store)
+	b := NewRateLimiter(cfg).WithStore(store)
+	defer a.Stop()
+	defer b.Stop()
+	assert.True(t, a.allowRequest("10.0.0.1", 2, time.Minute))
+	assert.True(t, b.allowRequest("10.0.0.1", 2, time.Minute))
+	assert.False(t, a.allowRequest("10.0.0.1", 2, time.Minute), "limit must be shared across instances")
+
+	pcfg := ProgressiveRateLimitConfig{LockoutThreshold: 2, LockoutDuration: time.Minute}
+	p1 := NewProgressiveRateLimiter(pcfg, cfg).WithStore(store)
+	p2 := NewProgressiveRateLimiter(pcfg, cfg).WithStore(store)
+	defer p1.Stop()
+	defer p2.Stop()
+	app := fiber.New()
+	app.Post("/login/:n", func(c *fiber.Ctx) error {
+		if c.Params("n") == "1" {
+			p1.RecordFailure("10.0.0.2", c)
+		} else {
+			p2.RecordFailure("10.0.0.2", c)
+		}
+		return nil
+	})
+	for _, n := range []string{"1", "2"} {
+		_, _ = app.Test(httptest.NewRequest(http.MethodPost, "/login/"+n, nil))
+	}
+	allowed, _, st := p1.allowRequest(context.Background(), "10.0.0.2", "/login", "POST")
+	assert.False(t, allowed, "lockout recorded on one instance must apply to the other")
+	assert.True(t, st.LockedOut)
+}
+
+func TestProgressiveSuspicious(t *testing.T) {
+	cfg := RateLimitConfig{MaxEntries: 10, CleanupInterval: time.Minute, EntryTTL: time.Minute}
+	p := NewProgressiveRateLimiter(ProgressiveRateLimitConfig{LockoutThreshold: 10, ChallengeThreshold: 2}, cfg)
+	defer p.Stop()
+	app := fiber.New()
+	app.Post("/fail", func(c *fiber.Ctx) error { p.RecordFailure("10.0.0.3", c); return nil })
+	app.Post("/ok", func(c *fiber.Ctx) error { p.RecordSuccess("10.0.0.3", c); return nil })
+	ctx := context.Background()
+	assert.False(t, p.Suspicious(ctx, "10.0.0.3"), "unknown addresses are not flagged")
+	_, _ = app.Test(httptest.NewRequest(http.MethodPost, "/fail", nil))
+	assert.False(t, p.Suspicious(ctx, "10.0.0.3"))
+	_, _ = app.Test(httptest.NewRequest(http.MethodPost, "/fail", nil))
+	assert.True(t, p.Suspicious(ctx, "10.0.0.3"))
+	_, _ = app.Test(httptest.NewRequest(http.MethodPost, "/ok", nil))
+	assert.False(t, p.Suspicious(ctx, "10.0.0.3"), "a success clears the flag")
+
+	// Without an explicit threshold a third of the lockout threshold is used
+	q := NewProgressiveRateLimiter(ProgressiveRateLimitConfig{LockoutThreshold: 9}, cfg)
+	defer q.Stop()
+	assert.Equal(t, 3, q.config.ChallengeThreshold)
+}
+
+// contendedStore always reports its progressive state as contended.
+type contendedStore struct{ sharedTestStore }
+
+func (s *contendedStore) UpdateProgressive(context.Context, string, time.Duration, func(*ProgressiveState) *ProgressiveState) (ProgressiveState, error) {
+	return ProgressiveState{}, ErrRateLimitContended
+}
+
+func TestProgressiveFailsClosedWhenContended(t *testing.T) {
+	cfg := RateLimitConfig{MaxEntries: 10, CleanupInterval: time.Minute, EntryTTL: time.Minute}
+	p := NewProgressiveRateLimiter(ProgressiveRateLimitConfig{}, cfg).WithStore(&contendedStore{})
+	defer p.Stop()
+	allowed, retry, _ := p.allowRequest(context.Background(), "10.0.0.4", "/login", "POST")
+	assert.False(t, allowed, "a contended update must deny rather than proceed")
+	assert.Greater(t, retry, time.Duration(0))
+	assert.True(t, p.Suspicious(context.Background(), "10.0.0.4"))
+	assert.Empty(t, p.local.progressiveStates(), "contention must not fall back to local state")
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+// reconcileSkipPrefixes are storage prefixes that are not owned by image or avatar rows.
+// versions/ holds the previous files of replaced images and workflows/ the ComfyUI
+// workflow sidecars, both removed with their image.
+var reconcileSkipPrefixes = []string{"site/", "health/", "backups/", models.ImageVersionPrefix, models.ImageWorkflowPrefix}
+
+// reconcileGrace protects files written by in-flight uploads (stored before the DB row exists).
+const reconcileGrace = time.Hour
+
+// reconcileListCap bounds how many entries are echoed back per list in a report.
+const reconcileListCap = 500
+
+// ErrReconcileRunning is returned when a reconciliation is already in progress.
+var ErrReconcileRunning = errors.New("reconciliation already running")
+
+// ReconcileOptions controls what a reconciliation run is allowed to change.
+type ReconcileOptions struct {
+	// DeleteOrphanFiles removes stored objects that no image or avatar references.
+	DeleteOrphanFiles bool `json:"delete_orphan_files"`
+	// DeleteDanglingRecords removes image rows (and clears avatar URLs) whose object is missing.
+	DeleteDanglingRecords bool `json:"delete_dangling_records"`
+}
+
+// DanglingRecord is a DB row that points to an object missing from storage.
+type DanglingRecord struct {
+	Kind string `json:"kind"` // "image" or "avatar"
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+}
+
+// ReconcileReport summarizes a reconciliation run.
+type ReconcileReport struct {
+	StartedAt        time.Time        `json:"started_at"`
+	FinishedAt       time.Time        `json:"finished_at"`
+	Options          ReconcileOptions `json:"options"`
+	ScannedObjects   int              `json:"scanned_objects"`
+	ScannedImages    int              `json:"scanned_images"`
+	ScannedAvatars   int              `json:"scanned_avatars"`
+	OrphanCount      int              `json:"orphan_count"`
+	OrphanBytes      int64            `json:"orphan_bytes"`
+	DanglingCount    int              `json:"dangling_count"`
+	DeletedFiles     int              `json:"deleted_files"`
+	DeletedRecords   int              `json:"deleted_records"`
+	Orphans          []StorageObject  `json:"orphans"`
+	Dangling         []DanglingRecord `json:"dangling"`
+	Errors           []string         `json:"errors,omitempty"`
+	ListingSupported bool             `json:"listing_supported"`
+}
+
+var (
+	reconcileRunMu sync.Mutex
+	reconcileMu    sync.Mutex
+	lastReconcile  *ReconcileReport
+)
+
+// LastReconcileReport returns the most recent reconciliation report, if any.
+func LastReconcileReport() *ReconcileReport {
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+	return lastReconcile
+}
+
+// StorageKeyFromRef converts a stored filename or public URL into a storage key.
+// Image rows keep either a bare filename (local) or an absolute URL (remote);
+// avatars are stored as "/uploads/avatars/<file>" or an absolute URL ending in "avatars/<file>".
+func StorageKeyFromRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	if i := strings.Index(ref, "://"); i >= 0 {
+		ref = ref[i+3:]
+		if j := strings.Index(ref, "/"); j >= 0 {
+			ref = ref[j+1:]
+		} else {
+			return ""
+		}
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	ref = strings.TrimPrefix(ref, "/")
+	ref = strings.TrimPrefix(ref, "uploads/")
+	// Keep the avatars/ namespace; everything else lives at top-level under its file name.
+	// Match it as a whole path segment so a bucket or prefix such as "myavatars/" in a
+	// path-style URL is not mistaken for it.
+	if strings.HasPrefix(ref, "avatars/") {
+		return ref
+	}
+	if i := strings.Index(ref, "/avatars/"); i >= 0 {
+		return ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ref
+}
+
+// ReconcileStorage walks storage keys and DB references in both directions, reporting
+// orphan objects and dangling rows, and optionally cleaning them up.
+// Only one run executes at a time; concurrent callers receive an error.
+func ReconcileStorage(ctx context.Context, db *sqlx.DB, st Storage, opts ReconcileOptions) (*ReconcileReport, error) {
+	if db == nil || st == nil {
+		return nil, fmt.Errorf("storage or database not configured")
+	}
+	lister, ok := st.(ObjectLister)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support listing")
+	}
+	if !reconcileRunMu.TryLock() {
+		return nil, ErrReconcileRunning
+	}
+	defer reconcileRunMu.Unlock()
+
+	rep := &ReconcileReport{StartedAt: time.Now().UTC(), Options: opts, ListingSupported: true, Orphans: []StorageObject{}, Dangling: []DanglingRecord{}}
+
+	objects, err := lister.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list storage: %w", err)
+	}
+	stored := make(map[string]StorageObject, len(objects))
+	for _, o := range objects {
+		if skipReconcileKey(o.Key) {
+			continue
+		}
+		stored[o.Key] = o
+	}
+	rep.ScannedObjects = len(stored)
+
+	type ref struct {
+		ID  string `db:"id"`
+		Ref string `db:"ref"`
+	}
+	var images []ref
+	if err := db.SelectContext(ctx, &images, `SELECT id::text AS id, filename AS ref FROM images`); err != nil {
+		return nil, fmt.Errorf("scan images: %w", err)
+	}
+	var avatars []ref
+	if err := db.SelectContext(ctx, &avatars, `SELECT id::text AS id, avatar_url AS ref FROM users WHERE avatar_url IS NOT NULL AND avatar_url <> ''`); err != nil {
+		return nil, fmt.Errorf("scan avatars: %w", err)
+	}
+	rep.ScannedImages = len(images)
+	rep.ScannedAvatars = len(avatars)
+
+	referenced := make(map[string]bool, len(images)+len(avatars))
+	check := func(kind string, rows []ref) {
+		for _, r := range rows {
+			key := StorageKeyFromRef(r.Ref)
+			if key == "" {
+				continue
+			}
+			referenced[key] = true
+			if _, ok := stored[key]; ok {
+				continue
+			}
+			// With remote storage, relative refs are legacy files still served from the local mount
+			if !st.IsLocal() && !strings.Contains(r.Ref, "://") {
+				continue
+			}
+			rep.DanglingCount++
+			if len(rep.Dangling) < reconcileListCap {
+				rep.Dangling = append(rep.Dangling, DanglingRecord{Kind: kind, ID: r.ID, Key: key})
+			}
+			if !opts.DeleteDanglingRecords {
+				continue
+			}
+			var q string
+			if kind == "image" {
+				q = `DELETE FROM images WHERE id = $1::uuid`
+			} else {
+				q = `UPDATE users SET avatar_url = NULL WHERE id = $1::uuid`
+			}
+			if _, err := db.ExecContext(ctx, q, r.ID); err != nil {
+				rep.Errors = append(rep.Errors, fmt.Sprintf("%s %s: %v", kind, r.ID, err))
+				continue
+			}
+			rep.DeletedRecords++
+		}
+	}
+	check("image", images)
+	check("avatar", avatars)
+	// Profile headers are stored by key; missing ones are simply not shown, so they are never dangling
+	var headers []string
+	if err := db.SelectContext(ctx, &headers, `SELECT profile_theme->>'header_key' FROM users WHERE COALESCE(profile_theme->>'header_key', '') <> ''`); err != nil {
+		return nil, fmt.Errorf("scan headers: %w", err)
+	}
+	for _, k := range headers {
+		referenced[k] = true
+	}
+	// Video posters hang off image rows; like headers, a missing one is never dangling
+	var posters []string
+	if err := db.SelectContext(ctx, &posters, `SELECT poster_filename FROM images WHERE COALESCE(poster_filename, '') <> ''`); err != nil {
+		return nil, fmt.Errorf("scan posters: %w", err)
+	}
+	for _, ref := range posters {
+		if key := StorageKeyFromRef(ref); key != "" {
+			referenced[key] = true
+		}
+	}
+
+	cutoff := time.Now().Add(-reconcileGrace)
+	for key, o := range stored {
+		if referenced[key] {
+			continue
+		}
+		// Recent objects may belong to an upload whose row is not committed yet
+		if !o.ModTime.IsZero() && o.ModTime.After(cutoff) {
+			continue
+		}
+		rep.OrphanCount++
+		rep.OrphanBytes += o.Size
+		if len(rep.Orphans) < reconcileListCap {
+			rep.Orphans = append(rep.Orphans, o)
+		}
+		if !opts.DeleteOrphanFiles {
+			continue
+		}
+		if err := st.Delete(ctx, key); err != nil {
+			rep.Errors = append(rep.Errors, fmt.Sprintf("delete %s: %v", key, err))
+			continue
+		}
+		rep.DeletedFiles++
+	}
+
+	rep.FinishedAt = time.Now().UTC()
+	reconcileMu.Lock()
+	lastReconcile = rep
+	reconcileMu.Unlock()
+	Logger(ctx).Info("reconcile: finished",
+		"objects", rep.ScannedObjects, "images", rep.ScannedImages, "avatars", rep.ScannedAvatars,
+		"orphans", rep.OrphanCount, "deleted_files", rep.DeletedFiles, "dangling", rep.DanglingCount, "deleted_records", rep.DeletedRecords)
+	return rep, nil
+}
+
+func skipReconcileKey(key string) bool {
+	for _, p := range reconcileSkipPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterReconcileJobs registers admin-triggered reconciliation and the scheduled
+// report-only scan, which runs once per interval across all instances.
+func RegisterReconcileJobs(db *sqlx.DB, settings models.SiteSettingsRepositoryInterface) {
+	RegisterJob(JobSpec{
+		Kind:        JobReconcile,
+		Timeout:     30 * time.Minute,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var opts ReconcileOptions
+			if err := json.Unmarshal(job.Payload, &opts); err != nil {
+				return nil, err
+			}
+			return ReconcileStorage(ctx, db, GetCurrentStorage(), opts)
+		},
+	})
+	RegisterJob(JobSpec{
+		Kind:    JobReconcileScheduled,
+		Timeout: 30 * time.Minute,
+		Every: func() time.Duration {
+			set := GetCachedSettings(settings)
+			if !set.StorageReconcileEnabled {
+				return 30 * time.Minute
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(set.StorageReconcileInterval))
+			if err != nil || d <= 0 {
+				d = 24 * time.Hour
+			}
+			return d
+		},
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			st := GetCurrentStorage()
+			if !GetCachedSettings(settings).StorageReconcileEnabled || st == nil {
+				return nil, nil
+			}
+			return ReconcileStorage(ctx, db, st, ReconcileOptions{})
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorageKeyFromRef(t *testing.T) {
+	cases := map[string]string{
+		"":                                 "",
+		"abc.webp":                         "abc.webp",
+		"/uploads/abc.webp":                "abc.webp",
+		"/uploads/avatars/u1.jpg":          "avatars/u1.jpg",
+		"https://cdn.example.com/abc.webp": "abc.webp",
+		"https://cdn.example.com/avatars/u1.jpg?v=2":       "avatars/u1.jpg",
+		"https://cdn.example.com":                          "",
+		"https://s3.example.com/myavatars/avatars/u1.webp": "avatars/u1.webp",
+		"https://cdn.example.com/media/avatars/u1.webp":    "avatars/u1.webp",
+	}
+	for in, want := range cases {
+		if got := StorageKeyFromRef(in); got != want {
+			t.Errorf("StorageKeyFromRef(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLocalStorageList(t *testing.T) {
+	dir := t.TempDir()
+	ls := NewLocalStorage(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "avatars"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a.webp", filepath.Join("avatars", "u.jpg")} {
+		if err := os.WriteFile(filepath.Join(dir, p), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := ls.List(context.Background(), "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("List all = %v, %v", all, err)
+	}
+	av, err := ls.List(context.Background(), "avatars/")
+	if err != nil || len(av) != 1 || av[0].Key != "avatars/u.jpg" {
+		t.Fatalf("List avatars = %v, %v", av, err)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisTimeout = 2 * time.Second
+	redisMaxIdle = 8
+)
+
+// NewRedisClientFromURL parses redis://[:password@]host[:port][/db]. The rediss:// scheme
+// connects over TLS, so AUTH and all traffic are encrypted. A user part without a
+// password, as in redis://secret@host, is taken as the password.
+func NewRedisClientFromURL(raw string) (*redis.Client, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("invalid redis url")
+	}
+	opts, err := redis.ParseURL(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); !ok {
+			opts.Username, opts.Password = "", u.User.Username()
+		}
+	}
+	opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout = redisTimeout, redisTimeout, redisTimeout
+	opts.MaxIdleConns = redisMaxIdle
+	return redis.NewClient(opts), nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewRedisClientFromURL(t *testing.T) {
+	cl, err := NewRedisClientFromURL("redis://:secret@cache:6380/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := cl.Options(); o.Addr != "cache:6380" || o.Password != "secret" || o.DB != 2 || o.TLSConfig != nil {
+		t.Fatalf("unexpected options %+v", o)
+	}
+	cl, err = NewRedisClientFromURL("redis://secret@cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := cl.Options(); o.Username != "" || o.Password != "secret" {
+		t.Fatalf("a bare user part should be the password, got %q/%q", o.Username, o.Password)
+	}
+	cl, err = NewRedisClientFromURL("rediss://cache.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := cl.Options(); o.Addr != "cache.example.com:6379" || o.TLSConfig == nil || o.TLSConfig.ServerName != "cache.example.com" {
+		t.Fatalf("rediss should dial TLS to the host, got %+v", o)
+	}
+	if _, err := NewRedisClientFromURL("http://cache"); err == nil {
+		t.Fatal("expected an error for a non-redis scheme")
+	}
+}
+
+// readRESPCommand reads one command sent as a RESP array of bulk strings.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisClientTLS(t *testing.T) {
+	// Borrow httptest's certificate and the pool that trusts it
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	roots := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	auth := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := readRESPCommand(r)
+			if err != nil || len(cmd) == 0 {
+				return
+			}
+			switch strings.ToUpper(cmd[0]) {
+			case "HELLO":
+				// Answer like a server without RESP3 so the client falls back to AUTH
+				_, _ = conn.Write([]byte("-ERR unknown command 'HELLO'\r\n"))
+			case "AUTH":
+				auth <- strings.Join(cmd[1:], " ")
+				_, _ = conn.Write([]byte("+OK\r\n"))
+			case "PING":
+				_, _ = conn.Write([]byte("+PONG\r\n"))
+			default:
+				_, _ = conn.Write([]byte("+OK\r\n"))
+			}
+		}
+	}()
+
+	cl, err := NewRedisClientFromURL("rediss://:secret@" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	cl.Options().TLSConfig.RootCAs = roots
+	cl.Options().TLSConfig.ServerName = "example.com"
+	if err := cl.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("ping over TLS: %v", err)
+	}
+	if got := <-auth; got != "secret" {
+		t.Fatalf("expected AUTH with the URL password, got %q", got)
+	}
+}
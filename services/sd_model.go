@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxModelName bounds a stored checkpoint name.
+const MaxModelName = 100
+
+// a1111ModelRe finds the checkpoint in A1111/Forge generation parameters, such as
+// "Steps: 20, Sampler: Euler, Model hash: 4e0b, Model: flux1-dev, VAE: ae".
+var a1111ModelRe = regexp.MustCompile(`(?:^|[,\n]\s*)Model:\s*([^,\n]+)`)
+
+// comfyLoaders are the ComfyUI node classes that load a model, with the input naming it.
+var comfyLoaders = map[string]string{
+	"CheckpointLoaderSimple":    "ckpt_name",
+	"CheckpointLoader":          "ckpt_name",
+	"ImageOnlyCheckpointLoader": "ckpt_name",
+	"UNETLoader":                "unet_name",
+	"UnetLoaderGGUF":            "unet_name",
+}
+
+// unicodeCommentPrefix starts an EXIF UserComment written as UTF-16, as A1111 does for JPEG
+// and WebP.
+var unicodeCommentPrefix = []byte("UNICODE\x00")
+
+// ExtractModelName returns the checkpoint or diffusion model an image was generated with,
+// read from A1111/Forge parameters, SwarmUI's sui_image_params or a ComfyUI graph, or ""
+// when the metadata doesn't name one. Directories and file extensions are dropped, so
+// "flux/flux1-dev.safetensors" becomes "flux1-dev".
+func ExtractModelName(b []byte) string {
+	if chunks := pngTextChunks(b); chunks != nil {
+		if v, ok := chunks["parameters"]; ok {
+			if m := modelFromParameters(v); m != "" {
+				return m
+			}
+		}
+		if m := modelFromComfyPrompt(chunks["prompt"]); m != "" {
+			return m
+		}
+		return modelFromComfyGraph(chunks["workflow"])
+	}
+	raw := ExtractExifRawFromBytes(b)
+	if raw == nil {
+		return ""
+	}
+	if i := bytes.Index(raw, unicodeCommentPrefix); i >= 0 {
+		// The parameters are ASCII in practice, so dropping the zero bytes of either
+		// UTF-16 byte order leaves them readable
+		if m := modelFromParameters(bytes.ReplaceAll(raw[i+len(unicodeCommentPrefix):], []byte{0}, nil)); m != "" {
+			return m
+		}
+	}
+	return modelFromParameters(raw)
+}
+
+// modelFromParameters reads the model from A1111-style parameter text or a SwarmUI
+// sui_image_params object.
+func modelFromParameters(v []byte) string {
+	if t := bytes.TrimSpace(v); len(t) > 0 && t[0] == '{' {
+		var swarm struct {
+			Params struct {
+				Model string `json:"model"`
+			} `json:"sui_image_params"`
+		}
+		if json.Unmarshal(t, &swarm) == nil && swarm.Params.Model != "" {
+			return NormalizeModelName(swarm.Params.Model)
+		}
+	}
+	if m := a1111ModelRe.FindSubmatch(v); m != nil {
+		return NormalizeModelName(string(m[1]))
+	}
+	return ""
+}
+
+// modelFromComfyPrompt reads the model from the first loader node of an API-format prompt,
+// by node id so the choice doesn't depend on map order.
+func modelFromComfyPrompt(v []byte) string {
+	var nodes comfyPrompt
+	if len(v) == 0 || json.Unmarshal(v, &nodes) != nil {
+		return ""
+	}
+	best, bestID := "", ""
+	for id, n := range nodes {
+		input, ok := comfyLoaders[n.ClassType]
+		if !ok {
+			continue
+		}
+		var name string
+		if json.Unmarshal(n.Inputs[input], &name) != nil || name == "" {
+			continue
+		}
+		if bestID == "" || len(id) < len(bestID) || (len(id) == len(bestID) && id < bestID) {
+			best, bestID = name, id
+		}
+	}
+	return NormalizeModelName(best)
+}
+
+// modelFromComfyGraph reads the model from the first loader node of an editor workflow,
+// whose first widget value is the file name.
+func modelFromComfyGraph(v []byte) string {
+	var g comfyGraph
+	if len(v) == 0 || json.Unmarshal(v, &g) != nil {
+		return ""
+	}
+	for _, n := range g.Nodes {
+		if _, ok := comfyLoaders[n.Type]; !ok || len(n.WidgetsValues) == 0 {
+			continue
+		}
+		var name string
+		if json.Unmarshal(n.WidgetsValues[0], &name) == nil && name != "" {
+			return NormalizeModelName(name)
+		}
+	}
+	return ""
+}
+
+// NormalizeModelName strips the directory and model file extension from a checkpoint name
+// and bounds its length.
+func NormalizeModelName(name string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, `\`, "/"))
+	name = path.Base(name)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".safetensors", ".ckpt", ".pt", ".pth", ".bin", ".gguf", ".sft":
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" || !utf8.ValidString(name) {
+		return ""
+	}
+	for utf8.RuneCountInString(name) > MaxModelName {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
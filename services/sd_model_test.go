@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestExtractModelName(t *testing.T) {
+	params := "neon koi\nNegative prompt: blurry\nSteps: 20, Sampler: Euler, CFG scale: 1, Seed: 42, Size: 1024x1024, Model hash: 4e0b, Model: flux1-dev, Version: f2.0"
+	cases := []struct {
+		name string
+		png  []byte
+		want string
+	}{
+		{"a1111", pngWithChunks([2]string{"tEXt", "parameters\x00" + params}), "flux1-dev"},
+		{"swarm", pngWithChunks([2]string{"tEXt", `parameters` + "\x00" + `{"sui_image_params":{"prompt":"koi","model":"Flux/FLUX.1-dev.safetensors"}}`}), "FLUX.1-dev"},
+		{"comfy prompt", pngWithChunks([2]string{"tEXt", `prompt` + "\x00" + `{"12":{"class_type":"UNETLoader","inputs":{"unet_name":"flux1-schnell.sft"}},"4":{"class_type":"CheckpointLoaderSimple","inputs":{"ckpt_name":"sdxl\\juggernautXL_v9.safetensors"}}}`}), "juggernautXL_v9"},
+		{"comfy graph", pngWithChunks([2]string{"zTXt", zTXt("workflow", `{"nodes":[{"type":"KSampler","widgets_values":[42]},{"type":"CheckpointLoaderSimple","widgets_values":["dreamshaper_8.ckpt"]}]}`)}), "dreamshaper_8"},
+		{"none", pngWithChunks([2]string{"tEXt", "parameters\x00koi, Steps: 20, Model hash: 4e0b"}), ""},
+	}
+	for _, c := range cases {
+		if got := ExtractModelName(c.png); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// Rate limiter security events are written to the security_events table by a background
+// writer so the limiter never waits on the database. Events are batched; when the queue is
+// full (the database is down or the site is under a flood) new events are dropped and
+// counted rather than buffered without bound.
+
+const (
+	JobSecurityEventsPurge = "security_events.purge"
+
+	securityEventQueue = 2048
+	securityEventBatch = 200
+	securityEventFlush = 2 * time.Second
+)
+
+var securityEventCh chan models.SecurityEvent
+
+// InitSecurityEventLog starts the writer and registers the hourly retention purge. Until
+// it is called RecordSecurityEvent is a no-op.
+func InitSecurityEventLog(repo models.SecurityEventRepositoryInterface, cfg SecurityEventsConfig) {
+	if securityEventCh != nil || repo == nil {
+		return
+	}
+	// Held for the writer's lifetime so a shutdown waits for the final flush
+	if !BeginWork() {
+		return
+	}
+	ch := make(chan models.SecurityEvent, securityEventQueue)
+	securityEventCh = ch
+	go func() {
+		defer EndWork()
+		runSecurityEventWriter(repo, ch, securityEventFlush, ShuttingDown())
+	}()
+	RegisterJob(JobSpec{
+		Kind:  JobSecurityEventsPurge,
+		Every: func() time.Duration { return time.Hour },
+		Run: func(ctx context.Context, _ *models.Job) (interface{}, error) {
+			now := time.Now()
+			n, err := repo.Purge(now.Add(-cfg.Retention), now.Add(-cfg.LowRetention))
+			return map[string]int{"purged": n}, err
+		},
+	})
+}
+
+// RecordSecurityEvent queues ev for persistence without blocking.
+func RecordSecurityEvent(ev SecurityEvent) {
+	ch := securityEventCh
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- securityEventRow(ev):
+	default:
+		SecurityEventsDropped.Inc()
+	}
+}
+
+// securityEventRow caps fields one rune under their column widths, leaving room for the
+// ellipsis truncateRunes adds.
+func securityEventRow(ev SecurityEvent) models.SecurityEvent {
+	return models.SecurityEvent{
+		EventType:   truncateRunes(ev.EventType, 63),
+		Severity:    truncateRunes(ev.Severity, 15),
+		IPAddress:   truncateRunes(ev.IPAddress, 63),
+		UserAgent:   truncateRunes(ev.UserAgent, 512),
+		Path:        truncateRunes(ev.Path, 1024),
+		Method:      truncateRunes(ev.Method, 15),
+		Description: truncateRunes(ev.Description, 1024),
+		RequestID:   truncateRunes(ev.RequestID, 63),
+		CreatedAt:   ev.Timestamp,
+	}
+}
+
+// runSecurityEventWriter inserts queued events in batches every flush interval, or sooner
+// when a batch fills, until stop is closed, when it writes what is left.
+func runSecurityEventWriter(repo models.SecurityEventRepositoryInterface, ch <-chan models.SecurityEvent, flush time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(flush)
+	defer ticker.Stop()
+	batch := make([]models.SecurityEvent, 0, securityEventBatch)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := repo.InsertBatch(batch); err != nil {
+			Logger(context.Background()).Error("security events: write failed", "count", len(batch), "error", err)
+			SecurityEventsDropped.Add(float64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case ev := <-ch:
+			batch = append(batch, ev)
+			if len(batch) >= securityEventBatch {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case <-stop:
+			for {
+				select {
+				case ev := <-ch:
+					batch = append(batch, ev)
+					if len(batch) >= securityEventBatch {
+						write()
+					}
+				default:
+					write()
+					return
+				}
+			}
+		}
+	}
+}
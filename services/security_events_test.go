@@ -0,0 +1,82 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+type fakeSecurityEventRepo struct {
+	models.SecurityEventRepositoryInterface
+	mu      sync.Mutex
+	batches [][]models.SecurityEvent
+}
+
+func (f *fakeSecurityEventRepo) InsertBatch(events []models.SecurityEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]models.SecurityEvent(nil), events...))
+	return nil
+}
+
+func (f *fakeSecurityEventRepo) written() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, b := range f.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestSecurityEventWriter(t *testing.T) {
+	repo := &fakeSecurityEventRepo{}
+	ch := make(chan models.SecurityEvent, securityEventQueue)
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		runSecurityEventWriter(repo, ch, 20*time.Millisecond, stop)
+	}()
+
+	ch <- securityEventRow(SecurityEvent{EventType: "ACCOUNT_LOCKOUT", Severity: "high", IPAddress: "203.0.113.9", Timestamp: time.Now()})
+	for i := 0; i < securityEventBatch; i++ {
+		ch <- securityEventRow(SecurityEvent{EventType: "AUTH_FAILURE", Severity: "medium"})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for repo.written() < securityEventBatch+1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := repo.written(); n != securityEventBatch+1 {
+		t.Fatalf("expected %d events written, got %d", securityEventBatch+1, n)
+	}
+	repo.mu.Lock()
+	first := repo.batches[0]
+	repo.mu.Unlock()
+	if len(first) > securityEventBatch || first[0].EventType != "ACCOUNT_LOCKOUT" || first[0].IPAddress != "203.0.113.9" {
+		t.Fatalf("unexpected first batch %+v", first[0])
+	}
+
+	// Events still queued at shutdown are written before the writer exits
+	ch <- securityEventRow(SecurityEvent{EventType: "LOCKOUT_RESET", Severity: "low"})
+	close(stop)
+	<-done
+	if n := repo.written(); n != securityEventBatch+2 {
+		t.Fatalf("expected the queued event to be flushed on stop, got %d written", n)
+	}
+}
+
+func TestSecurityEventRowTruncates(t *testing.T) {
+	long := make([]rune, 200)
+	for i := range long {
+		long[i] = 'x'
+	}
+	row := securityEventRow(SecurityEvent{EventType: string(long), RequestID: string(long)})
+	if n := len([]rune(row.EventType)); n > 64 {
+		t.Fatalf("event type not capped to the column width: %d runes", n)
+	}
+	if n := len([]rune(row.RequestID)); n > 64 {
+		t.Fatalf("request id not capped to the column width: %d runes", n)
+	}
+}
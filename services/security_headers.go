@@ -11,7 +11,8 @@ import (
 
 // SecurityHeaders provides security headers middleware
 type SecurityHeaders struct {
-	config *SecurityConfig
+	config  *SecurityConfig
+	cspFunc func() string
 }
 
 // SecurityConfig contains security header configuration
@@ -55,12 +56,25 @@ func NewSecurityHeaders(config *SecurityConfig) *SecurityHeaders {
 	}
 }
 
+// WithDynamicCSP computes the Content-Security-Policy per response (see BuildCSP) in place
+// of the static CSPPolicy.
+func (sh *SecurityHeaders) WithDynamicCSP(fn func() string) *SecurityHeaders {
+	sh.cspFunc = fn
+	return sh
+}
+
 // Middleware returns the security headers middleware
 func (sh *SecurityHeaders) Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Set Content Security Policy
-		if sh.config.CSPEnabled && sh.config.CSPPolicy != "" {
-			c.Set("Content-Security-Policy", sh.config.CSPPolicy)
+		if sh.config.CSPEnabled {
+			policy := sh.config.CSPPolicy
+			if sh.cspFunc != nil {
+				policy = sh.cspFunc()
+			}
+			if policy != "" {
+				c.Set("Content-Security-Policy", policy)
+			}
 		}
 		
 		// Set HTTP Strict Transport Security
@@ -67,3 +67,14 @@ func InvalidateSettingsCache() {
 	settingsCache.expires = time.Time{}
 	settingsCache.mu.Unlock()
 }
+
+// SettingsCacheAge reports how long ago the cached settings were loaded, and false when
+// nothing has been cached yet or the cache was invalidated.
+func SettingsCacheAge() (time.Duration, bool) {
+	settingsCache.mu.RLock()
+	defer settingsCache.mu.RUnlock()
+	if settingsCache.expires.IsZero() {
+		return 0, false
+	}
+	return time.Since(settingsCache.expires.Add(-settingsCache.ttl)), true
+}
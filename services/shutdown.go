@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Background work (mail sends, webhook batches, digests, scheduled backups) registers
+// with BeginWork/EndWork so a shutdown can wait for it instead of killing it mid-write.
+// Once Shutdown starts, BeginWork refuses new work; leased outbox rows left unclaimed are
+// picked up by the next instance.
+
+var (
+	workMu       sync.Mutex
+	workInFlight int
+	workStopping bool
+	workStopCh   = make(chan struct{})
+)
+
+// BeginWork registers one unit of background work. It returns false once shutdown has
+// begun, in which case the caller must not start the work (and must not call EndWork).
+func BeginWork() bool {
+	workMu.Lock()
+	defer workMu.Unlock()
+	if workStopping {
+		return false
+	}
+	workInFlight++
+	return true
+}
+
+// EndWork marks a unit of work registered by BeginWork as finished.
+func EndWork() {
+	workMu.Lock()
+	if workInFlight > 0 {
+		workInFlight--
+	}
+	workMu.Unlock()
+}
+
+// ShuttingDown is closed when Shutdown begins, for loops that sleep between runs.
+func ShuttingDown() <-chan struct{} { return workStopCh }
+
+// Shutdown stops new background work from starting and waits for in-flight work, returning
+// ctx.Err() if the deadline passes first.
+func Shutdown(ctx context.Context) error {
+	workMu.Lock()
+	if !workStopping {
+		workStopping = true
+		close(workStopCh)
+	}
+	workMu.Unlock()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		workMu.Lock()
+		n := workInFlight
+		workMu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// InFlightWork reports how many units of background work are running or queued.
+func InFlightWork() int {
+	workMu.Lock()
+	defer workMu.Unlock()
+	return workInFlight
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForWorkAndRefusesNew(t *testing.T) {
+	// Shutdown state is global; reopen it so later tests can start work
+	t.Cleanup(func() {
+		workMu.Lock()
+		workStopping, workStopCh = false, make(chan struct{})
+		workMu.Unlock()
+	})
+	if !BeginWork() {
+		t.Fatal("work refused before shutdown")
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(released)
+		EndWork()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Fatal("shutdown returned before in-flight work finished")
+	}
+	if BeginWork() {
+		t.Fatal("work accepted after shutdown")
+	}
+	select {
+	case <-ShuttingDown():
+	default:
+		t.Fatal("ShuttingDown not closed")
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadTokens(t *testing.T) {
+	SetSignedURLs(SignedURLsConfig{Enabled: true, TTL: time.Hour, Secret: "s3cret"})
+	defer SetSignedURLs(SignedURLsConfig{})
+
+	now := time.Unix(1_700_000_000, 0)
+	exp, sig := SignUploadKey("a.jpg", now)
+	if d := time.Unix(exp, 0).Sub(now); d < time.Hour || d > 2*time.Hour {
+		t.Fatalf("expiry %v out of the one to two TTL range", d)
+	}
+	// Links are stable within a TTL window so caches keep hitting
+	if e2, s2 := SignUploadKey("a.jpg", now.Add(time.Minute)); e2 != exp || s2 != sig {
+		t.Fatal("expected the same token within a window")
+	}
+	e := strconv.FormatInt(exp, 10)
+	if !VerifyUploadToken("a.jpg", e, sig, now) {
+		t.Fatal("valid token rejected")
+	}
+	if VerifyUploadToken("b.jpg", e, sig, now) {
+		t.Fatal("token accepted for another key")
+	}
+	if VerifyUploadToken("a.jpg", strconv.FormatInt(exp+3600, 10), sig, now) {
+		t.Fatal("token accepted with a changed expiry")
+	}
+	if VerifyUploadToken("a.jpg", e, sig, time.Unix(exp+1, 0)) {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestSignMediaRef(t *testing.T) {
+	if got := SignMediaRef("a.jpg"); got != "a.jpg" {
+		t.Fatalf("disabled signing changed the ref: %s", got)
+	}
+	SetSignedURLs(SignedURLsConfig{Enabled: true, TTL: time.Hour})
+	defer SetSignedURLs(SignedURLsConfig{})
+
+	for _, ref := range []string{"a.jpg", "https://cdn.example.com/a.jpg"} {
+		got := SignMediaRef(ref)
+		u, err := url.Parse(got)
+		if err != nil || u.Path != "/uploads/a.jpg" {
+			t.Fatalf("SignMediaRef(%q) = %q", ref, got)
+		}
+		if !VerifyUploadToken("a.jpg", u.Query().Get("exp"), u.Query().Get("sig"), time.Now()) {
+			t.Fatalf("SignMediaRef(%q) produced an invalid token", ref)
+		}
+		// Signing an already signed link yields the same link
+		if again := SignMediaRef(got); again != got {
+			t.Fatalf("re-signing changed %q to %q", got, again)
+		}
+	}
+	if got := SignMediaRef("/uploads/avatars/u.png"); strings.Contains(got, "sig=") {
+		t.Fatalf("avatars should stay public, got %s", got)
+	}
+}
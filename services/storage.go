@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Storage defines a minimal interface for saving and deleting public assets
@@ -24,6 +25,32 @@ type Storage interface {
 	IsLocal() bool
 }
 
+// StorageObject describes a stored object as reported by a listing.
+type StorageObject struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ObjectLister is implemented by storages that can enumerate their keys.
+// It is kept separate from Storage so existing implementations and fakes stay valid.
+type ObjectLister interface {
+	// List returns all objects whose key starts with prefix ("" lists everything).
+	List(ctx context.Context, prefix string) ([]StorageObject, error)
+}
+
+// ObjectReader is implemented by storages that can read objects back.
+type ObjectReader interface {
+	// Open returns a reader for the object at key. Callers must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// PrivateSaver is implemented by storages that can write an object served as private
+// and never cached, unlike Save, which applies the public uploads cache policy.
+type PrivateSaver interface {
+	SavePrivate(ctx context.Context, key string, r io.Reader, contentType string) error
+}
+
 // ----- Local storage implementation -----
 
 type LocalStorage struct {
@@ -33,12 +60,14 @@ type LocalStorage struct {
 
 func NewLocalStorage(baseDir string) *LocalStorage {
 	if baseDir == "" {
-		baseDir = "uploads"
+		baseDir = UploadsDir()
 	}
 	return &LocalStorage{baseDir: baseDir, publicBase: "/uploads"}
 }
 
-func (s *LocalStorage) Save(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+func (s *LocalStorage) Save(ctx context.Context, key string, r io.Reader, contentType string) (_ string, err error) {
+	_, span := startStorageSpan(ctx, "local", "save", key)
+	defer func(start time.Time) { observeStorage("local", "save", start, err); span.Finish(err) }(time.Now())
 	// Normalize separators
 	key = filepath.ToSlash(key)
 	dstPath := filepath.Join(s.baseDir, filepath.FromSlash(key))
@@ -57,7 +86,9 @@ func (s *LocalStorage) Save(ctx context.Context, key string, r io.Reader, conten
 	return s.PublicURL(key), nil
 }
 
-func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+func (s *LocalStorage) Delete(ctx context.Context, key string) (err error) {
+	_, span := startStorageSpan(ctx, "local", "delete", key)
+	defer func(start time.Time) { observeStorage("local", "delete", start, err); span.Finish(err) }(time.Now())
 	key = filepath.ToSlash(key)
 	path := filepath.Join(s.baseDir, filepath.FromSlash(key))
 	if err := os.Remove(path); err != nil {
@@ -76,6 +107,47 @@ func (s *LocalStorage) PublicURL(key string) string {
 
 func (s *LocalStorage) IsLocal() bool { return true }
 
+// List walks the base directory and returns slash-separated keys relative to it.
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]StorageObject, error) {
+	prefix = strings.TrimPrefix(filepath.ToSlash(prefix), "/")
+	var out []StorageObject
+	err := filepath.WalkDir(s.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.baseDir, path)
+		if err != nil {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		if prefix != "" && !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		out = append(out, StorageObject{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return out, err
+}
+
+// Open opens the file stored under key.
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	key = strings.TrimPrefix(filepath.ToSlash(key), "/")
+	return os.Open(filepath.Join(s.baseDir, filepath.FromSlash(key)))
+}
+
 // ----- S3 (R2-compatible) configuration placeholders -----
 
 type S3Config struct {
@@ -128,11 +200,7 @@ func NewStorageFromSettings(s StorageSettings) (Storage, error) {
 		}
 	}
 	// default local
-	baseDir := os.Getenv("UPLOADS_DIR")
-	if baseDir == "" {
-		baseDir = "uploads"
-	}
-	return NewLocalStorage(baseDir), nil
+	return NewLocalStorage(UploadsDir()), nil
 }
 
 func firstNonEmpty(vals ...string) string {
@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yourusername/trough/models"
+)
+
+const (
+	migrateDefaultConcurrency = 4
+	migrateMaxConcurrency     = 16
+	// migrateErrorCap bounds how many error messages a result carries; ErrorCount has them all.
+	migrateErrorCap = 200
+)
+
+// Storage backend providers a migration can name.
+const (
+	StorageBackendLocal   = "local"
+	StorageBackendCurrent = "current"
+	StorageBackendS3      = "s3"
+	StorageBackendR2      = "r2"
+)
+
+// migrateSkipPrefixes are bucket prefixes that hold no uploads: remote backups and the
+// storage health probe.
+var migrateSkipPrefixes = []string{"backups/", "health/"}
+
+// migrateRefColumns are the columns that can point at a stored file, either by bare file
+// name (local top-level images and posters), by /uploads/ path (local avatars and site
+// assets) or by public URL (remote storage). Profile headers are stored as storage keys
+// and need no rewrite.
+var migrateRefColumns = []struct{ table, column string }{
+	{"images", "filename"},
+	{"images", "poster_filename"},
+	{"users", "avatar_url"},
+	{"site_settings", "favicon_path"},
+	{"site_settings", "social_image_url"},
+}
+
+// StorageBackend names one side of a migration. It never carries credentials, since it is
+// stored in the job payload: s3 and r2 backends use MIGRATE_S3_ACCESS_KEY_ID and
+// MIGRATE_S3_SECRET_ACCESS_KEY from the environment, or else the configured storage keys.
+type StorageBackend struct {
+	// Provider is local, current (the configured storage), s3 or r2.
+	Provider       string `json:"provider"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	Bucket         string `json:"bucket,omitempty"`
+	ForcePathStyle bool   `json:"force_path_style,omitempty"`
+	PublicBaseURL  string `json:"public_base_url,omitempty"`
+}
+
+// UnmarshalJSON also accepts a bare provider name, e.g. "local".
+func (b *StorageBackend) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*b = StorageBackend{Provider: name}
+		return nil
+	}
+	type plain StorageBackend
+	return json.Unmarshal(data, (*plain)(b))
+}
+
+func (b StorageBackend) validate() error {
+	switch strings.ToLower(b.Provider) {
+	case StorageBackendLocal, StorageBackendCurrent:
+		return nil
+	case StorageBackendS3, StorageBackendR2:
+		if strings.TrimSpace(b.Endpoint) == "" || strings.TrimSpace(b.Bucket) == "" {
+			return errors.New("endpoint and bucket are required for s3 and r2 backends")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown storage provider %q", b.Provider)
+}
+
+// open builds the backend's Storage. current resolves to the storage in use when the
+// migration runs.
+func (b StorageBackend) open(set models.SiteSettings) (Storage, error) {
+	switch strings.ToLower(b.Provider) {
+	case StorageBackendLocal:
+		return NewLocalStorage(UploadsDir()), nil
+	case StorageBackendCurrent:
+		if st := GetCurrentStorage(); st != nil {
+			return st, nil
+		}
+		return NewStorageFromSettings(set)
+	}
+	if buildS3Storage == nil {
+		return nil, errors.New("s3 storage is not available in this build")
+	}
+	return buildS3Storage(S3Config{
+		Endpoint:       b.Endpoint,
+		AccessKey:      firstNonEmpty(os.Getenv("MIGRATE_S3_ACCESS_KEY_ID"), set.S3AccessKey, os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("R2_ACCESS_KEY_ID")),
+		SecretKey:      firstNonEmpty(os.Getenv("MIGRATE_S3_SECRET_ACCESS_KEY"), set.S3SecretKey, os.Getenv("S3_SECRET_ACCESS_KEY"), os.Getenv("R2_SECRET_ACCESS_KEY")),
+		UseSSL:         true,
+		Bucket:         b.Bucket,
+		ForcePathStyle: b.ForcePathStyle,
+		PublicBaseURL:  b.PublicBaseURL,
+	})
+}
+
+// StorageMigration is the payload of a storage.migrate job: copy every stored file from
+// Source to Target and point database references at the copies.
+type StorageMigration struct {
+	Source StorageBackend `json:"source"`
+	Target StorageBackend `json:"target"`
+	// DryRun reports what would be copied and rewritten without changing anything.
+	DryRun bool `json:"dry_run"`
+	// RewriteURLs updates database references to the target; nil means true.
+	RewriteURLs *bool `json:"rewrite_urls,omitempty"`
+	// DeleteSource removes each source file once it is copied and no longer referenced.
+	// It requires RewriteURLs.
+	DeleteSource bool `json:"delete_source"`
+	// Concurrency is the number of parallel copies; zero means 4, at most 16.
+	Concurrency int `json:"concurrency,omitempty"`
+	// BandwidthKBps caps the combined read rate of all copies; zero is unlimited.
+	BandwidthKBps int `json:"bandwidth_kbps,omitempty"`
+}
+
+// Validate checks the migration before it is queued.
+func (m StorageMigration) Validate() error {
+	if err := m.Source.validate(); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	if err := m.Target.validate(); err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	if m.DeleteSource && !m.rewrite() {
+		return errors.New("delete_source requires rewrite_urls")
+	}
+	if m.Concurrency < 0 || m.Concurrency > migrateMaxConcurrency {
+		return fmt.Errorf("concurrency must be between 0 and %d", migrateMaxConcurrency)
+	}
+	if m.BandwidthKBps < 0 {
+		return errors.New("bandwidth_kbps must not be negative")
+	}
+	return nil
+}
+
+func (m StorageMigration) rewrite() bool { return m.RewriteURLs == nil || *m.RewriteURLs }
+
+// MigrationResult summarizes a storage migration. While the job runs it is also its
+// progress. In a dry run the counts are what a real run would do.
+type MigrationResult struct {
+	DryRun         bool  `json:"dry_run"`
+	TotalFiles     int   `json:"total_files"`
+	TotalBytes     int64 `json:"total_bytes"`
+	ProcessedFiles int   `json:"processed_files"`
+	UploadedFiles  int   `json:"uploaded_files"`
+	CopiedBytes    int64 `json:"copied_bytes"`
+	// SkippedFiles were already in the target at the same size, e.g. from an interrupted run.
+	SkippedFiles   int      `json:"skipped_files"`
+	UpdatedRecords int      `json:"updated_records"`
+	CleanedFiles   int      `json:"cleaned_files,omitempty"`
+	ErrorCount     int      `json:"error_count"`
+	Errors         []string `json:"errors,omitempty"`
+	Success        bool     `json:"success"`
+}
+
+func (r *MigrationResult) addError(msg string) {
+	r.ErrorCount++
+	if len(r.Errors) < migrateErrorCap {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// MigrateStorage copies every file in src to dst with a bounded pool of workers, then
+// points database references at the copies and, with DeleteSource, removes each source
+// file once nothing refers to it.
+//
+// Runs are resumable: when dst can list objects, files already stored at the same size are
+// not copied again, but their references are still rewritten. Per-file failures are
+// collected in the result rather than aborting the run.
+func MigrateStorage(ctx context.Context, db *sqlx.DB, src, dst Storage, m StorageMigration) (*MigrationResult, error) {
+	if db == nil || src == nil || dst == nil {
+		return nil, errors.New("storage or database not configured")
+	}
+	if src.PublicURL("k") == dst.PublicURL("k") {
+		return nil, errors.New("source and target are the same storage")
+	}
+	reader, ok := src.(ObjectReader)
+	if !ok {
+		return nil, errors.New("source storage cannot be read")
+	}
+	files, err := listMigrationFiles(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("list source: %w", err)
+	}
+	result := &MigrationResult{DryRun: m.DryRun, TotalFiles: len(files), Errors: []string{}}
+	for _, f := range files {
+		result.TotalBytes += f.Size
+	}
+
+	stored := map[string]int64{}
+	if lister, ok := dst.(ObjectLister); ok {
+		objects, err := lister.List(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("list target: %w", err)
+		}
+		for _, o := range objects {
+			stored[o.Key] = o.Size
+		}
+	}
+
+	workers := m.Concurrency
+	if workers <= 0 {
+		workers = migrateDefaultConcurrency
+	} else if workers > migrateMaxConcurrency {
+		workers = migrateMaxConcurrency
+	}
+	mig := &migrator{db: db, src: src, dst: dst, reader: reader, stored: stored, m: m, limit: newByteRateLimiter(int64(m.BandwidthKBps) * 1024)}
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		lastReport time.Time
+		touched    migrationTouched
+	)
+	queue := make(chan StorageObject)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				out := mig.one(ctx, f)
+				mu.Lock()
+				result.ProcessedFiles++
+				if out.copied {
+					result.UploadedFiles++
+					result.CopiedBytes += f.Size
+				}
+				if out.skipped {
+					result.SkippedFiles++
+				}
+				if out.cleaned {
+					result.CleanedFiles++
+				}
+				result.UpdatedRecords += out.updated
+				touched.merge(out.touched)
+				for _, e := range out.errors {
+					result.addError(e)
+				}
+				if time.Since(lastReport) >= time.Second {
+					lastReport = time.Now()
+					ReportJobProgress(ctx, result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	ReportJobProgress(ctx, result)
+feed:
+	for _, f := range files {
+		select {
+		case queue <- f:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if touched.images {
+		InvalidateFeedCache(ctx)
+	}
+	if touched.settings {
+		InvalidateSettingsCache()
+	}
+	if err := ctx.Err(); err != nil {
+		result.addError("migration interrupted: " + err.Error())
+		return result, err
+	}
+	result.Success = result.ErrorCount == 0
+	return result, nil
+}
+
+// listMigrationFiles lists the source's files in key order. Local sources skip dot-files
+// and the staging and backup directories; remote ones skip backups/ and health/.
+func listMigrationFiles(ctx context.Context, src Storage) ([]StorageObject, error) {
+	if ls, ok := src.(*LocalStorage); ok {
+		return scanLocalUploads(ls.baseDir)
+	}
+	lister, ok := src.(ObjectLister)
+	if !ok {
+		return nil, errors.New("source storage cannot be listed")
+	}
+	objects, err := lister.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	files := make([]StorageObject, 0, len(objects))
+	for _, o := range objects {
+		skip := strings.HasSuffix(o.Key, "/")
+		for _, p := range migrateSkipPrefixes {
+			skip = skip || strings.HasPrefix(o.Key, p)
+		}
+		if !skip {
+			files = append(files, o)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, nil
+}
+
+// scanLocalUploads lists the files under root in key order. Dot-files and the staging and
+// backup directories, when they live inside root, are not uploads and are skipped.
+func scanLocalUploads(root string) ([]StorageObject, error) {
+	skipDirs := map[string]bool{}
+	for _, d := range []string{StagingDir(), BackupDir()} {
+		if abs, err := filepath.Abs(d); err == nil {
+			skipDirs[abs] = true
+		}
+	}
+	var files []StorageObject
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && skipDirs[abs] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, StorageObject{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, err
+}
+
+// migrator holds what every file copy of one migration shares.
+type migrator struct {
+	db       *sqlx.DB
+	src, dst Storage
+	reader   ObjectReader
+	stored   map[string]int64
+	m        StorageMigration
+	limit    *byteRateLimiter
+}
+
+// migrationTouched records which cached tables a migration rewrote.
+type migrationTouched struct{ images, settings bool }
+
+func (t *migrationTouched) merge(o migrationTouched) {
+	t.images = t.images || o.images
+	t.settings = t.settings || o.settings
+}
+
+type migrationOutcome struct {
+	copied, skipped, cleaned bool
+	updated                  int
+	touched                  migrationTouched
+	errors                   []string
+}
+
+// one copies a file unless the target already has it, rewrites its references and
+// optionally removes the source copy.
+func (g *migrator) one(ctx context.Context, f StorageObject) (out migrationOutcome) {
+	newRef := storageRef(g.dst, f.Key)
+	if size, ok := g.stored[f.Key]; ok && size == f.Size {
+		out.skipped = true
+	} else if !g.m.DryRun {
+		if err := g.copy(ctx, f); err != nil {
+			out.errors = append(out.errors, err.Error())
+			return out
+		}
+		out.copied = true
+	} else {
+		out.copied = true
+	}
+	if !g.m.rewrite() {
+		return out
+	}
+
+	oldRefs := storageRefs(g.src, f.Key)
+	refFailed := false
+	for _, col := range migrateRefColumns {
+		var q string
+		var args []interface{}
+		var err error
+		if g.m.DryRun {
+			q, args, err = sqlx.In(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IN (?)`, col.table, col.column), oldRefs)
+		} else {
+			q, args, err = sqlx.In(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s IN (?)`, col.table, col.column, col.column), newRef, oldRefs)
+		}
+		if err != nil {
+			out.errors = append(out.errors, err.Error())
+			refFailed = true
+			continue
+		}
+		q = g.db.Rebind(q)
+		var n int64
+		if g.m.DryRun {
+			err = g.db.GetContext(ctx, &n, q, args...)
+		} else {
+			var res sql.Result
+			if res, err = g.db.ExecContext(ctx, q, args...); err == nil {
+				n, _ = res.RowsAffected()
+			}
+		}
+		if err != nil {
+			out.errors = append(out.errors, fmt.Sprintf("Failed to update %s.%s for %s: %v", col.table, col.column, f.Key, err))
+			refFailed = true
+			continue
+		}
+		if n > 0 {
+			out.updated += int(n)
+			switch col.table {
+			case "images":
+				out.touched.images = !g.m.DryRun
+			case "site_settings":
+				out.touched.settings = !g.m.DryRun
+			}
+		}
+	}
+
+	// A source copy is only removed once nothing points at it any more
+	if g.m.DeleteSource && !refFailed {
+		if g.m.DryRun {
+			out.cleaned = true
+		} else if err := g.src.Delete(ctx, f.Key); err != nil {
+			out.errors = append(out.errors, fmt.Sprintf("Failed to cleanup %s: %v", f.Key, err))
+		} else {
+			out.cleaned = true
+		}
+	}
+	return out
+}
+
+func (g *migrator) copy(ctx context.Context, f StorageObject) error {
+	rc, err := g.reader.Open(ctx, f.Key)
+	if err != nil {
+		return fmt.Errorf("Failed to read %s: %v", f.Key, err)
+	}
+	defer rc.Close()
+	r := &migrationReader{ctx: ctx, r: rc, size: f.Size, limit: g.limit}
+	if _, err := g.dst.Save(ctx, f.Key, r, storageContentType(f.Key)); err != nil {
+		return fmt.Errorf("Failed to upload %s: %v", f.Key, err)
+	}
+	return nil
+}
+
+// storageRefs returns the forms a database reference to key in st takes. Local files are
+// referenced by bare file name (top-level only), by /uploads/ path, or by "/" + their path
+// under uploads_dir, which is how site assets are recorded; remote ones by public URL.
+func storageRefs(st Storage, key string) []string {
+	if !st.IsLocal() {
+		return []string{st.PublicURL(key)}
+	}
+	refs := []string{"/uploads/" + key}
+	if !strings.Contains(key, "/") {
+		refs = append(refs, key)
+	}
+	if p := "/" + filepath.ToSlash(filepath.Join(UploadsDir(), key)); p != refs[0] {
+		refs = append(refs, p)
+	}
+	return refs
+}
+
+// storageRef is how a reference to key in st is written: local top-level files by bare
+// file name as uploads record them, other local files by /uploads/ path, remote ones by
+// public URL.
+func storageRef(st Storage, key string) string {
+	if !st.IsLocal() {
+		return st.PublicURL(key)
+	}
+	if !strings.Contains(key, "/") {
+		return key
+	}
+	return "/uploads/" + key
+}
+
+func storageContentType(key string) string {
+	switch strings.ToLower(filepath.Ext(key)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".webp":
+		return "image/webp"
+	case ".gif":
+		return "image/gif"
+	case ".ico":
+		return "image/x-icon"
+	case ".svg":
+		return "image/svg+xml"
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	}
+	return "application/octet-stream"
+}
+
+// migrationReader reads a source file under the migration's bandwidth cap and reports its
+// size, so remote targets can upload it in one request.
+type migrationReader struct {
+	ctx   context.Context
+	r     io.Reader
+	size  int64
+	limit *byteRateLimiter
+}
+
+func (r *migrationReader) Size() int64 { return r.size }
+
+func (r *migrationReader) Read(p []byte) (int, error) {
+	if r.limit != nil && len(p) > 32*1024 {
+		p = p[:32*1024]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 && r.limit != nil {
+		if werr := r.limit.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// byteRateLimiter spreads reads shared by all workers over time so together they stay
+// under a byte rate.
+type byteRateLimiter struct {
+	mu   sync.Mutex
+	rate float64 // bytes per second
+	next time.Time
+}
+
+// newByteRateLimiter returns nil, meaning unlimited, for a non-positive rate.
+func newByteRateLimiter(bytesPerSec int64) *byteRateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &byteRateLimiter{rate: float64(bytesPerSec)}
+}
+
+// wait books n bytes and sleeps until the rate allows them.
+func (l *byteRateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterStorageMigrationJob registers storage migrations, including the local uploads
+// export. Backends resolve when the job runs. Failed runs are retried and resume where the
+// last one stopped.
+func RegisterStorageMigrationJob(db *sqlx.DB, settings models.SiteSettingsRepositoryInterface) {
+	RegisterJob(JobSpec{
+		Kind:        JobStorageMigrate,
+		Timeout:     6 * time.Hour,
+		MaxAttempts: 3,
+		Run: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			var m StorageMigration
+			if err := json.Unmarshal(job.Payload, &m); err != nil {
+				return nil, PermanentJobError(err)
+			}
+			if err := m.Validate(); err != nil {
+				return nil, PermanentJobError(err)
+			}
+			set := GetCachedSettings(settings)
+			src, err := m.Source.open(set)
+			if err != nil {
+				return nil, PermanentJobError(fmt.Errorf("source: %w", err))
+			}
+			dst, err := m.Target.open(set)
+			if err != nil {
+				return nil, PermanentJobError(fmt.Errorf("target: %w", err))
+			}
+			res, err := MigrateStorage(ctx, db, src, dst, m)
+			if err == nil && !res.Success {
+				err = fmt.Errorf("migration finished with %d errors", res.ErrorCount)
+			}
+			return res, err
+		},
+	})
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeRemoteStorage is a remote storage that only builds URLs.
+type fakeRemoteStorage struct{ Storage }
+
+func (fakeRemoteStorage) IsLocal() bool               { return false }
+func (fakeRemoteStorage) PublicURL(key string) string { return "https://cdn.example.com/" + key }
+
+func TestScanLocalUploads_IncludesSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	prevStaging, prevBackup := stagingDir, backupDir
+	stagingDir, backupDir = filepath.Join(root, "staging"), filepath.Join(root, "backups")
+	defer func() { stagingDir, backupDir = prevStaging, prevBackup }()
+
+	for _, p := range []string{"a.jpg", "avatars/u.png", "site/favicon.ico", ".hidden", ".cache/x.jpg", "staging/pending", "backups/b.tar.gz"} {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := scanLocalUploads(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Key)
+		if f.Size != 4 {
+			t.Errorf("%s: size %d", f.Key, f.Size)
+		}
+	}
+	want := []string{"a.jpg", "avatars/u.png", "site/favicon.ico"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+}
+
+func TestStorageRefs_Local(t *testing.T) {
+	prev := uploadsDir
+	defer func() { uploadsDir = prev }()
+
+	uploadsDir = "uploads"
+	local := NewLocalStorage("uploads")
+	if got := storageRefs(local, "a.jpg"); !reflect.DeepEqual(got, []string{"/uploads/a.jpg", "a.jpg"}) {
+		t.Errorf("top-level: %v", got)
+	}
+	if got := storageRefs(local, "avatars/u.png"); !reflect.DeepEqual(got, []string{"/uploads/avatars/u.png"}) {
+		t.Errorf("avatar: %v", got)
+	}
+	// Site assets record "/" + the path under uploads_dir, doubling the slash of absolute dirs
+	uploadsDir = "/srv/trough/uploads"
+	if got := storageRefs(local, "site/favicon.ico"); !reflect.DeepEqual(got, []string{"/uploads/site/favicon.ico", "//srv/trough/uploads/site/favicon.ico"}) {
+		t.Errorf("custom uploads dir: %v", got)
+	}
+}
+
+func TestStorageRef(t *testing.T) {
+	local, remote := NewLocalStorage("uploads"), fakeRemoteStorage{}
+	cases := []struct {
+		st        Storage
+		key, want string
+	}{
+		{local, "a.jpg", "a.jpg"},
+		{local, "avatars/u.png", "/uploads/avatars/u.png"},
+		{remote, "a.jpg", "https://cdn.example.com/a.jpg"},
+		{remote, "site/favicon.ico", "https://cdn.example.com/site/favicon.ico"},
+	}
+	for _, tc := range cases {
+		if got := storageRef(tc.st, tc.key); got != tc.want {
+			t.Errorf("storageRef(%s): got %q, want %q", tc.key, got, tc.want)
+		}
+	}
+	if got := storageRefs(remote, "a.jpg"); !reflect.DeepEqual(got, []string{"https://cdn.example.com/a.jpg"}) {
+		t.Errorf("remote refs: %v", got)
+	}
+}
+
+func TestMigrationResult_CapsErrors(t *testing.T) {
+	var r MigrationResult
+	for i := 0; i < migrateErrorCap+5; i++ {
+		r.addError("boom")
+	}
+	if r.ErrorCount != migrateErrorCap+5 || len(r.Errors) != migrateErrorCap {
+		t.Fatalf("count %d, kept %d", r.ErrorCount, len(r.Errors))
+	}
+}
+
+func TestStorageMigration_Decode(t *testing.T) {
+	var m StorageMigration
+	body := `{"source":"local","target":{"provider":"r2","endpoint":"acct.r2.cloudflarestorage.com","bucket":"media"},"dry_run":true}`
+	if err := json.Unmarshal([]byte(body), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Source.Provider != StorageBackendLocal || m.Target.Bucket != "media" || !m.DryRun || !m.rewrite() {
+		t.Fatalf("unexpected decode: %+v", m)
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected a valid migration, got %v", err)
+	}
+}
+
+func TestStorageMigration_Validate(t *testing.T) {
+	no := false
+	cases := map[string]StorageMigration{
+		"unknown provider":   {Source: StorageBackend{Provider: "ftp"}, Target: StorageBackend{Provider: "current"}},
+		"s3 without bucket":  {Source: StorageBackend{Provider: "local"}, Target: StorageBackend{Provider: "s3", Endpoint: "s3.example.com"}},
+		"delete w/o rewrite": {Source: StorageBackend{Provider: "local"}, Target: StorageBackend{Provider: "current"}, DeleteSource: true, RewriteURLs: &no},
+		"concurrency":        {Source: StorageBackend{Provider: "local"}, Target: StorageBackend{Provider: "current"}, Concurrency: migrateMaxConcurrency + 1},
+		"negative bandwidth": {Source: StorageBackend{Provider: "local"}, Target: StorageBackend{Provider: "current"}, BandwidthKBps: -1},
+	}
+	for name, m := range cases {
+		if err := m.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestByteRateLimiter(t *testing.T) {
+	if newByteRateLimiter(0) != nil {
+		t.Fatal("a zero rate must mean unlimited")
+	}
+	l := newByteRateLimiter(100 * 1024)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.wait(context.Background(), 5*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 20KiB at 100KiB/s takes ~200ms
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("limiter let 20KiB through in %s", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, 1024*1024); err == nil {
+		t.Fatal("expected a cancelled wait to fail")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -53,6 +54,20 @@ func buildS3StorageImpl(cfg S3Config) (Storage, error) {
 }
 
 func (s *s3Storage) Save(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	if err := s.put(ctx, key, r, contentType, CacheControl(CacheUploads)); err != nil {
+		return "", err
+	}
+	return s.PublicURL(key), nil
+}
+
+// SavePrivate writes an object that caches and CDNs must not keep, such as a backup.
+func (s *s3Storage) SavePrivate(ctx context.Context, key string, r io.Reader, contentType string) error {
+	return s.put(ctx, key, r, contentType, "private, no-store")
+}
+
+func (s *s3Storage) put(ctx context.Context, key string, r io.Reader, contentType, cacheControl string) (err error) {
+	ctx, span := startStorageSpan(ctx, "s3", "save", key)
+	defer func(start time.Time) { observeStorage("s3", "save", start, err); span.Finish(err) }(time.Now())
 	key = strings.TrimPrefix(key, "/")
 	// Bound network time for save operations
 	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
@@ -63,18 +78,24 @@ func (s *s3Storage) Save(ctx context.Context, key string, r io.Reader, contentTy
 	var size int64 = -1
 	if br, ok := r.(*bytes.Reader); ok {
 		size = int64(br.Len())
+	} else if f, ok := r.(*os.File); ok {
+		// Known sizes avoid minio's large multipart buffers for unknown-length streams
+		if info, err := f.Stat(); err == nil {
+			size = info.Size()
+		}
+	} else if sr, ok := r.(interface{ Size() int64 }); ok {
+		size = sr.Size()
 	}
-	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+	_, err = s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
 		ContentType:  contentType,
-		CacheControl: "public, max-age=31536000, immutable",
+		CacheControl: cacheControl,
 	})
-	if err != nil {
-		return "", err
-	}
-	return s.PublicURL(key), nil
+	return err
 }
 
-func (s *s3Storage) Delete(ctx context.Context, key string) error {
+func (s *s3Storage) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := startStorageSpan(ctx, "s3", "delete", key)
+	defer func(start time.Time) { observeStorage("s3", "delete", start, err); span.Finish(err) }(time.Now())
 	key = strings.TrimPrefix(key, "/")
 	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
 		c, cancel := context.WithTimeout(ctx, 15*time.Second)
@@ -108,6 +129,36 @@ func (s *s3Storage) PublicURL(key string) string {
 
 func (s *s3Storage) IsLocal() bool { return false }
 
+// List enumerates objects in the bucket under prefix.
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]StorageObject, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+	var out []StorageObject
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		out = append(out, StorageObject{Key: obj.Key, Size: obj.Size, ModTime: obj.LastModified})
+	}
+	return out, nil
+}
+
+// Open streams an object from the bucket.
+func (s *s3Storage) Open(ctx context.Context, key string) (_ io.ReadCloser, err error) {
+	ctx, span := startStorageSpan(ctx, "s3", "open", key)
+	defer func(start time.Time) { observeStorage("s3", "open", start, err); span.Finish(err) }(time.Now())
+	key = strings.TrimPrefix(key, "/")
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat surfaces missing keys before the caller starts reading
+	if _, err := obj.Stat(); err != nil {
+		_ = obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
 // Wire function pointer used by storage.go
 func init() {
 	buildS3Storage = func(cfg S3Config) (Storage, error) { return buildS3StorageImpl(cfg) }
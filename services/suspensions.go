@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/trough/models"
+)
+
+// suspensionCheckInterval is how often timed suspensions are checked for expiry. Login
+// already ignores an expired suspension, so this only bounds how long the flag lingers.
+const suspensionCheckInterval = time.Minute
+
+// InitSuspensionExpiry starts the worker that re-enables accounts whose timed suspension
+// has ended.
+func InitSuspensionExpiry(repo models.UserRepositoryInterface) {
+	if repo == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(suspensionCheckInterval)
+		defer ticker.Stop()
+		for {
+			if !BeginWork() {
+				return
+			}
+			ReleaseExpiredSuspensions(repo)
+			EndWork()
+			select {
+			case <-ticker.C:
+			case <-ShuttingDown():
+				return
+			}
+		}
+	}()
+}
+
+// ReleaseExpiredSuspensions re-enables expired suspensions and returns how many were lifted.
+func ReleaseExpiredSuspensions(repo models.UserRepositoryInterface) int {
+	ids, err := repo.ReleaseExpiredSuspensions()
+	if err != nil {
+		Logger(context.Background()).Error("suspensions: release failed", "error", err)
+		return 0
+	}
+	for _, id := range ids {
+		Logger(context.Background()).Info("suspensions: suspension expired, account re-enabled", "user_id", id)
+	}
+	return len(ids)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yourusername/trough/models"
+)
+
+// Multi-site mode maps request hosts to tenants. The table is small and read on every
+// request, so it is held in memory and replaced whenever an admin changes a tenant.
+
+var tenantsByHost atomic.Pointer[map[string]*models.Tenant]
+
+var tenantHostRe = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$|^localhost$`)
+
+func init() {
+	SetTenants(nil)
+}
+
+// SetTenants replaces the host table.
+func SetTenants(list []models.Tenant) {
+	m := make(map[string]*models.Tenant, len(list))
+	for i := range list {
+		t := list[i]
+		m[strings.ToLower(t.Host)] = &t
+	}
+	tenantsByHost.Store(&m)
+}
+
+// TenantForHost returns the tenant serving host, or nil for the primary site. A port in
+// host is ignored.
+func TenantForHost(host string) *models.Tenant {
+	m := *tenantsByHost.Load()
+	if len(m) == 0 {
+		return nil
+	}
+	return m[stripHostPort(strings.ToLower(strings.TrimSpace(host)))]
+}
+
+// NormalizeTenantHost lowercases a tenant hostname and checks it is a plain DNS name
+// (no scheme, path or port).
+func NormalizeTenantHost(raw string) (string, error) {
+	h := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), ".")
+	if len(h) > 253 || !tenantHostRe.MatchString(h) {
+		return "", errors.New("host must be a domain name such as gallery.example.com")
+	}
+	return h, nil
+}
+
+func stripHostPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
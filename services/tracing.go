@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing is the OpenTelemetry SDK exporting over OTLP/HTTP, configured by the standard
+// OTEL_* environment variables; with no endpoint set tracing is a no-op. Span wraps the
+// SDK's span so call sites stay nil-safe and never import OpenTelemetry themselves.
+
+const tracerName = "github.com/yourusername/trough"
+
+type spanContextKey struct{}
+
+// SpanContextKey is the context key spans are stored under. Fiber handlers can pass
+// c.Context() to StartSpan because the middleware also stores the span in c.Locals.
+var SpanContextKey = spanContextKey{}
+
+// Span is one timed operation in a trace. A nil *Span is valid and ignores every call,
+// which is what StartSpan returns when tracing is off.
+type Span struct {
+	span trace.Span
+}
+
+var (
+	tracerProvider *sdktrace.TracerProvider
+	tracer         trace.Tracer
+	propagator     = propagation.TraceContext{}
+)
+
+// TracingEnabled reports whether InitTracing configured an exporter.
+func TracingEnabled() bool { return tracerProvider != nil }
+
+// InitTracing starts the OTLP exporter when OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or
+// OTEL_EXPORTER_OTLP_ENDPOINT is set. The returned function flushes buffered spans and
+// should be called on shutdown; it is safe to call when tracing is disabled.
+func InitTracing() func(context.Context) {
+	endpoint := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if endpoint == "" {
+		endpoint = strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	}
+	if endpoint == "" || tracerProvider != nil {
+		return func(context.Context) {}
+	}
+	// Endpoint, headers, TLS and timeouts are read from the OTEL_EXPORTER_OTLP_* variables
+	exp, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		Logger(context.Background()).Error("tracing: exporter setup failed", "error", err)
+		return func(context.Context) {}
+	}
+	service := strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME"))
+	if service == "" {
+		service = "trough"
+	}
+	ratio := 1.0
+	if v := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			ratio = f
+		}
+	}
+	tp := startTracing(sdktrace.WithBatcher(exp), service, ratio)
+	Logger(context.Background()).Info("tracing: exporting spans", "endpoint", endpoint, "service", service, "sample_ratio", ratio)
+	return func(ctx context.Context) {
+		if err := tp.Shutdown(ctx); err != nil {
+			Logger(ctx).Warn("tracing: flush failed", "error", err)
+		}
+	}
+}
+
+// startTracing installs a provider sending spans through processor. Sampling follows the
+// caller's decision for continued traces and ratio for new ones, so every service
+// sampling at the same ratio keeps the same traces.
+func startTracing(processor sdktrace.TracerProviderOption, service string, ratio float64) *sdktrace.TracerProvider {
+	tp := sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	tracerProvider, tracer = tp, tp.Tracer(tracerName)
+	return tp
+}
+
+// SpanFromContext returns the active span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if s, ok := ctx.Value(SpanContextKey).(*Span); ok {
+		return s
+	}
+	if s := trace.SpanFromContext(ctx); s.SpanContext().IsValid() {
+		return &Span{span: s}
+	}
+	return nil
+}
+
+// ContextWithSpan returns a copy of ctx carrying s, for both StartSpan and code using
+// the OpenTelemetry API directly.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(trace.ContextWithSpan(ctx, s.span), SpanContextKey, s)
+}
+
+// StartSpan starts an internal span as a child of the span in ctx, or a new root span.
+// End must be called on the returned span.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, trace.SpanKindInternal, SpanFromContext(ctx))
+}
+
+// StartClientSpan starts a client span only when ctx already carries a span, so
+// background queries and storage calls don't each produce a one-span trace.
+func StartClientSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return startSpan(ctx, name, trace.SpanKindClient, parent)
+}
+
+// StartServerSpan starts the span for an incoming request, continuing the caller's trace
+// when traceparent is a valid W3C trace context header.
+func StartServerSpan(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	ctx = propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+	return startSpan(ctx, name, trace.SpanKindServer, nil)
+}
+
+func startSpan(ctx context.Context, name string, kind trace.SpanKind, parent *Span) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	parentCtx := ctx
+	if parent != nil {
+		// ctx may be a fasthttp context that only carries the span under SpanContextKey
+		parentCtx = trace.ContextWithSpan(ctx, parent.span)
+	}
+	_, span := tracer.Start(parentCtx, name, trace.WithSpanKind(kind))
+	s := &Span{span: span}
+	return ContextWithSpan(ctx, s), s
+}
+
+// TraceID returns the hex trace id, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
+
+// Traceparent formats the span as a W3C traceparent header for outgoing requests.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(trace.ContextWithSpan(context.Background(), s.span), carrier)
+	return carrier.Get("traceparent")
+}
+
+// SetName renames the span, e.g. once the matched route is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.span.SetName(name)
+}
+
+// SetAttr sets a string, bool, integer or float attribute; other values are formatted.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil || !s.span.IsRecording() {
+		return
+	}
+	var kv attribute.KeyValue
+	switch x := value.(type) {
+	case string:
+		kv = attribute.String(key, x)
+	case bool:
+		kv = attribute.Bool(key, x)
+	case int:
+		kv = attribute.Int(key, x)
+	case int64:
+		kv = attribute.Int64(key, x)
+	case float64:
+		kv = attribute.Float64(key, x)
+	case error:
+		kv = attribute.String(key, x.Error())
+	default:
+		kv = attribute.String(key, fmt.Sprint(x))
+	}
+	s.span.SetAttributes(kv)
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span and hands it to the exporter. Later calls are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// Finish records err, if any, and ends the span; convenient in a defer with a named error.
+func (s *Span) Finish(err error) {
+	s.RecordError(err)
+	s.End()
+}
+
+// TraceQuery is the db.QueryTracer hook: it records a client span for a statement run
+// on behalf of a traced request.
+func TraceQuery(ctx context.Context, query string) func(error) {
+	_, span := StartClientSpan(ctx, "db.query")
+	if span == nil || !span.span.IsRecording() {
+		span.End()
+		return func(error) {}
+	}
+	q := strings.Join(strings.Fields(query), " ")
+	if op, _, _ := strings.Cut(q, " "); op != "" {
+		span.SetName("db." + strings.ToLower(op))
+	}
+	span.SetAttr("db.system", "postgresql")
+	span.SetAttr("db.statement", truncateRunes(q, 1000))
+	return span.Finish
+}
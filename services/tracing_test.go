@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordTracing routes spans to an in-memory exporter for the rest of the test.
+func recordTracing(t *testing.T, ratio float64) *tracetest.InMemoryExporter {
+	t.Helper()
+	prevProvider, prevTracer := tracerProvider, tracer
+	t.Cleanup(func() { tracerProvider, tracer = prevProvider, prevTracer })
+	exp := tracetest.NewInMemoryExporter()
+	startTracing(sdktrace.WithSyncer(exp), "trough-test", ratio)
+	return exp
+}
+
+func spanAttr(s tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingSpans(t *testing.T) {
+	exp := recordTracing(t, 1)
+
+	// Client spans need a parent so untraced background work stays silent
+	if _, s := StartClientSpan(context.Background(), "orphan"); s != nil {
+		t.Fatal("client span started without a parent")
+	}
+	ctx, root := StartServerSpan(context.Background(), "POST /api/upload", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if root.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("incoming trace not continued, got %s", root.TraceID())
+	}
+	if SpanFromContext(ctx) != root {
+		t.Fatal("server span not stored in the context")
+	}
+	_, child := StartSpan(ctx, "upload.encode")
+	child.SetAttr("image.bytes", 42)
+	child.Finish(errors.New("boom"))
+	done := TraceQuery(ctx, "\n\t\tSELECT * FROM images WHERE id = $1")
+	done(nil)
+	root.End()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exp.GetSpans() {
+		spans[s.Name] = s
+	}
+	server, ok := spans["POST /api/upload"]
+	if !ok || server.SpanKind != trace.SpanKindServer || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected server span %+v", server)
+	}
+	if server.Resource.String() != "service.name=trough-test" {
+		t.Fatalf("unexpected resource %s", server.Resource)
+	}
+	encode := spans["upload.encode"]
+	if encode.Parent.SpanID() != server.SpanContext.SpanID() || spanAttr(encode, "image.bytes").AsInt64() != 42 {
+		t.Fatalf("unexpected child span %+v", encode)
+	}
+	if encode.Status.Code != codes.Error || encode.Status.Description != "boom" {
+		t.Fatalf("error not recorded, got %+v", encode.Status)
+	}
+	query, ok := spans["db.select"]
+	if !ok || query.SpanKind != trace.SpanKindClient || spanAttr(query, "db.statement").AsString() != "SELECT * FROM images WHERE id = $1" {
+		t.Fatalf("unexpected query span %+v", spans)
+	}
+}
+
+func TestTracingHonoursUnsampledParent(t *testing.T) {
+	exp := recordTracing(t, 1)
+	ctx, root := StartServerSpan(context.Background(), "GET /", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, child := StartSpan(ctx, "child")
+	child.End()
+	root.End()
+	if n := len(exp.GetSpans()); n != 0 {
+		t.Fatalf("expected no spans for an unsampled trace, got %d", n)
+	}
+	if root.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal("unsampled requests should still log the caller's trace id")
+	}
+}
+
+func TestTracingExportsOTLP(t *testing.T) {
+	got := make(chan *http.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case got <- r:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	prevProvider, prevTracer := tracerProvider, tracer
+	defer func() { tracerProvider, tracer = prevProvider, prevTracer }()
+	tracerProvider = nil
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer k")
+	flush := InitTracing()
+	_, s := StartSpan(context.Background(), "job")
+	s.End()
+	fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	flush(fctx)
+
+	select {
+	case r := <-got:
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Fatalf("unexpected export request %s %v", r.URL.Path, r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no export received")
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Self-serve verification lets creators earn the verified badge by showing they control
+// a domain or a page they link from their profile. The creator is given a token and
+// publishes it: for a domain, as a DNS TXT record or in /.well-known/trough-verify.txt
+// over https; for a link, anywhere in the page. CheckProof looks for it. Fetches go
+// through the webhook client, so private addresses and redirects are refused.
+
+// Proof kinds.
+const (
+	ProofDomain = "domain"
+	ProofLink   = "link"
+)
+
+// VerificationWellKnownPath is where a domain proof may be published instead of DNS.
+const VerificationWellKnownPath = "/.well-known/trough-verify.txt"
+
+// verificationTokenPrefix names the token so it reads sensibly in a TXT record.
+const verificationTokenPrefix = "trough-verification="
+
+// maxProofBody caps how much of a proof page is read.
+const maxProofBody = 1 << 20
+
+// ErrProofNotFound means the target was reachable but did not carry the token.
+var ErrProofNotFound = errors.New("verification token not found")
+
+// VerificationConfig enables the self-serve proof flow; staff can grant the badge either
+// way. Env override: VERIFICATION_SELF_SERVE (true/false).
+type VerificationConfig struct {
+	SelfServe bool `yaml:"self_serve"`
+}
+
+var (
+	verificationMu  sync.RWMutex
+	verificationCfg VerificationConfig
+
+	// lookupTXT and proofHTTPClient are swapped out in tests
+	lookupTXT       = net.DefaultResolver.LookupTXT
+	proofHTTPClient = func() *http.Client { return NewWebhookHTTPClient(false) }
+)
+
+// SetVerification configures self-serve verification; ApplyConfig calls it at startup.
+func SetVerification(cfg VerificationConfig) {
+	verificationMu.Lock()
+	verificationCfg = cfg
+	verificationMu.Unlock()
+}
+
+// SelfVerificationEnabled reports whether users may verify themselves with a proof.
+func SelfVerificationEnabled() bool {
+	verificationMu.RLock()
+	defer verificationMu.RUnlock()
+	return verificationCfg.SelfServe
+}
+
+// NewVerificationToken returns a random token to publish at a proof target.
+func NewVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return verificationTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// NormalizeProofTarget checks a proof target and returns it in canonical form: a
+// lowercase host name for a domain, an https URL without a fragment for a link.
+func NormalizeProofTarget(kind, target string) (string, error) {
+	target = strings.TrimSpace(target)
+	switch kind {
+	case ProofDomain:
+		host := strings.ToLower(target)
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+		host = strings.TrimSuffix(host, ".")
+		if net.ParseIP(host) != nil || !strings.Contains(host, ".") || len(host) > 253 {
+			return "", errors.New("enter a domain name such as example.com")
+		}
+		for _, label := range strings.Split(host, ".") {
+			if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+				return "", errors.New("enter a domain name such as example.com")
+			}
+			for _, r := range label {
+				if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+					return "", errors.New("enter a domain name such as example.com")
+				}
+			}
+		}
+		return host, nil
+	case ProofLink:
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil || len(target) > 2048 {
+			return "", errors.New("enter an https:// link")
+		}
+		u.Host = strings.ToLower(u.Host)
+		u.Fragment = ""
+		return u.String(), nil
+	}
+	return "", errors.New("kind must be domain or link")
+}
+
+// CheckProof reports whether token is published at the normalized target. It returns
+// ErrProofNotFound when the target was checked but lacks the token, and other errors
+// when it could not be checked.
+func CheckProof(ctx context.Context, kind, target, token string) error {
+	switch kind {
+	case ProofDomain:
+		if records, err := lookupTXT(ctx, target); err == nil {
+			for _, rec := range records {
+				if strings.TrimSpace(rec) == token {
+					return nil
+				}
+			}
+		}
+		found, err := fetchContainsToken(ctx, "https://"+target+VerificationWellKnownPath, token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrProofNotFound
+		}
+		return nil
+	case ProofLink:
+		found, err := fetchContainsToken(ctx, target, token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrProofNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown proof kind %q", kind)
+}
+
+// VerificationReason describes a passed proof for the badge.
+func VerificationReason(kind, target string) string {
+	if kind == ProofDomain {
+		return "Controls " + target
+	}
+	return "Links from " + target
+}
+
+func fetchContainsToken(ctx context.Context, rawURL, token string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", "Trough-Verification/1.0")
+	resp, err := proofHTTPClient().Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, maxProofBody))
+	sc.Buffer(make([]byte, 64*1024), maxProofBody)
+	for sc.Scan() {
+		if strings.Contains(sc.Text(), token) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
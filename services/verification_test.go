@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeProofTarget(t *testing.T) {
+	for _, tc := range []struct {
+		kind, in, want string
+	}{
+		{ProofDomain, " Example.COM ", "example.com"},
+		{ProofDomain, "https://art.example.com/about", "art.example.com"},
+		{ProofDomain, "localhost", ""},
+		{ProofDomain, "10.0.0.1", ""},
+		{ProofDomain, "bad_host.example.com", ""},
+		{ProofLink, "https://Social.example/@me#top", "https://social.example/@me"},
+		{ProofLink, "http://social.example/@me", ""},
+		{ProofLink, "https://user:pw@social.example/", ""},
+		{"email", "me@example.com", ""},
+	} {
+		got, err := NormalizeProofTarget(tc.kind, tc.in)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s %q: expected an error, got %q", tc.kind, tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s %q: expected %q, got %q %v", tc.kind, tc.in, tc.want, got, err)
+		}
+	}
+}
+
+func TestCheckProof(t *testing.T) {
+	const token = verificationTokenPrefix + "abc"
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case VerificationWellKnownPath, "/profile":
+			_, _ = w.Write([]byte("<p>" + token + "</p>"))
+		default:
+			_, _ = w.Write([]byte("nothing here"))
+		}
+	}))
+	defer srv.Close()
+	origTXT, origClient := lookupTXT, proofHTTPClient
+	defer func() { lookupTXT, proofHTTPClient = origTXT, origClient }()
+	proofHTTPClient = srv.Client
+	lookupTXT = func(_ context.Context, host string) ([]string, error) {
+		if host == "dns.example" {
+			return []string{"v=spf1 -all", token}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	ctx := context.Background()
+
+	if err := CheckProof(ctx, ProofDomain, "dns.example", token); err != nil {
+		t.Fatalf("expected the TXT record to pass, got %v", err)
+	}
+	host := srv.Listener.Addr().String()
+	if err := CheckProof(ctx, ProofDomain, host, token); err != nil {
+		t.Fatalf("expected the well-known file to pass, got %v", err)
+	}
+	if err := CheckProof(ctx, ProofLink, "https://"+host+"/profile", token); err != nil {
+		t.Fatalf("expected the linked page to pass, got %v", err)
+	}
+	if err := CheckProof(ctx, ProofLink, "https://"+host+"/elsewhere", token); !errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("expected ErrProofNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrVideoUnavailable is returned when video uploads are disabled or ffprobe/ffmpeg
+// cannot be found.
+var ErrVideoUnavailable = errors.New("video uploads are not available on this server")
+
+// VideoProbe is the subset of ffprobe output the upload path needs.
+type VideoProbe struct {
+	Container  string // "mp4" or "webm"
+	VideoCodec string
+	AudioCodec string
+	Width      int
+	Height     int
+	Duration   time.Duration
+	// Tags holds container and stream tags, stream tags prefixed with "stream:"
+	Tags map[string]string
+}
+
+// Accepted codecs per container; anything else will not play in common browsers.
+var videoCodecs = map[string]map[string]bool{
+	"mp4":  {"h264": true, "hevc": true, "av1": true},
+	"webm": {"vp8": true, "vp9": true, "av1": true},
+}
+
+var audioCodecs = map[string]map[string]bool{
+	"mp4":  {"aac": true, "mp3": true, "opus": true},
+	"webm": {"opus": true, "vorbis": true},
+}
+
+// IsVideoUpload reports whether an upload should take the video path.
+func IsVideoUpload(filename, contentType string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp4", ".m4v", ".webm":
+		return true
+	}
+	ct := strings.ToLower(contentType)
+	return strings.HasPrefix(ct, "video/mp4") || strings.HasPrefix(ct, "video/webm")
+}
+
+// ContentType is the MIME type the stored file is served with.
+func (p *VideoProbe) ContentType() string {
+	if p.Container == "webm" {
+		return "video/webm"
+	}
+	return "video/mp4"
+}
+
+// Ext is the file extension the stored file gets.
+func (p *VideoProbe) Ext() string {
+	if p.Container == "webm" {
+		return ".webm"
+	}
+	return ".mp4"
+}
+
+// CheckLimits rejects clips with unsupported codecs or over the configured duration.
+func (p *VideoProbe) CheckLimits(cfg VideoConfig) error {
+	if p.VideoCodec == "" {
+		return errors.New("no video stream found")
+	}
+	if !videoCodecs[p.Container][p.VideoCodec] {
+		return fmt.Errorf("video codec %s is not supported in %s", p.VideoCodec, p.Container)
+	}
+	if p.AudioCodec != "" && !audioCodecs[p.Container][p.AudioCodec] {
+		return fmt.Errorf("audio codec %s is not supported in %s", p.AudioCodec, p.Container)
+	}
+	if p.Width <= 0 || p.Height <= 0 {
+		return errors.New("video has no frame size")
+	}
+	if p.Duration <= 0 {
+		return errors.New("video has no duration")
+	}
+	if cfg.MaxDuration > 0 && p.Duration > cfg.MaxDuration {
+		return fmt.Errorf("video runs %s; the limit is %s", p.Duration.Round(100*time.Millisecond), cfg.MaxDuration)
+	}
+	return nil
+}
+
+// videoTool resolves a configured binary, failing with ErrVideoUnavailable.
+func videoTool(cfg VideoConfig, path, fallback string) (string, error) {
+	if !cfg.Enabled {
+		return "", ErrVideoUnavailable
+	}
+	if path == "" {
+		path = fallback
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", ErrVideoUnavailable
+	}
+	return resolved, nil
+}
+
+// ProbeVideo runs ffprobe on a local file.
+func ProbeVideo(ctx context.Context, cfg VideoConfig, path string) (*VideoProbe, error) {
+	bin, err := videoTool(cfg, cfg.FFprobePath, "ffprobe")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	return parseFFprobe(out)
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string            `json:"codec_type"`
+		CodecName string            `json:"codec_name"`
+		Width     int               `json:"width"`
+		Height    int               `json:"height"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+func parseFFprobe(out []byte) (*VideoProbe, error) {
+	var raw ffprobeOutput
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("ffprobe output: %w", err)
+	}
+	p := &VideoProbe{Tags: map[string]string{}}
+	// format_name lists every demuxer alias, e.g. "mov,mp4,m4a,3gp,3g2,mj2" or "matroska,webm"
+	names := "," + raw.Format.FormatName + ","
+	switch {
+	case strings.Contains(names, ",mp4,"):
+		p.Container = "mp4"
+	case strings.Contains(names, ",webm,"):
+		p.Container = "webm"
+	default:
+		return nil, fmt.Errorf("unsupported container %q", raw.Format.FormatName)
+	}
+	if secs, err := strconv.ParseFloat(raw.Format.Duration, 64); err == nil && secs > 0 {
+		p.Duration = time.Duration(secs * float64(time.Second))
+	}
+	for k, v := range raw.Format.Tags {
+		p.Tags[k] = v
+	}
+	for _, s := range raw.Streams {
+		switch s.CodecType {
+		case "video":
+			// Cover art is reported as a video stream too; the first real one wins
+			if p.VideoCodec == "" && s.CodecName != "mjpeg" && s.CodecName != "png" {
+				p.VideoCodec, p.Width, p.Height = s.CodecName, s.Width, s.Height
+			}
+		case "audio":
+			if p.AudioCodec == "" {
+				p.AudioCodec = s.CodecName
+			}
+		}
+		for k, v := range s.Tags {
+			p.Tags["stream:"+k] = v
+		}
+	}
+	return p, nil
+}
+
+// VideoPoster extracts one frame as JPEG, a little way in so fade-ins do not give a
+// black poster.
+func VideoPoster(ctx context.Context, cfg VideoConfig, path string, duration time.Duration) ([]byte, error) {
+	bin, err := videoTool(cfg, cfg.FFmpegPath, "ffmpeg")
+	if err != nil {
+		return nil, err
+	}
+	at := duration / 3
+	if at > time.Second {
+		at = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-v", "error", "-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", path, "-frames:v", "1", "-f", "image2", "-c:v", "mjpeg", "-q:v", "3", "pipe:1")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg poster: %w", err)
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("ffmpeg produced no poster frame")
+	}
+	return stdout.Bytes(), nil
+}
+
+// maxVideoMetadataBox bounds how much of one uuid box is read for detection.
+const maxVideoMetadataBox = 16 << 20
+
+// VideoMetadata collects what provenance detection looks at for a clip: container and
+// stream tags as "key=value" lines, followed by the top-level BMFF uuid boxes of MP4s,
+// which is where C2PA manifests and XMP packets live.
+func VideoMetadata(f io.ReaderAt, size int64, p *VideoProbe) []byte {
+	var b bytes.Buffer
+	keys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, p.Tags[k])
+	}
+	if p.Container == "mp4" {
+		for _, box := range bmffUUIDBoxes(f, size) {
+			b.Write(box)
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// bmffUUIDBoxes walks the top-level ISO BMFF boxes and returns the payloads of uuid
+// boxes, skipping media data without reading it.
+func bmffUUIDBoxes(f io.ReaderAt, size int64) [][]byte {
+	var boxes [][]byte
+	var hdr [16]byte
+	for off := int64(0); off+8 <= size; {
+		if _, err := f.ReadAt(hdr[:8], off); err != nil {
+			break
+		}
+		n := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		head := int64(8)
+		switch n {
+		case 0: // box runs to the end of the file
+			n = size - off
+		case 1: // 64-bit size follows the type
+			if _, err := f.ReadAt(hdr[8:16], off+8); err != nil {
+				return boxes
+			}
+			n = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			head = 16
+		}
+		if n < head || off+n > size {
+			break
+		}
+		if typ == "uuid" && n-head <= maxVideoMetadataBox {
+			payload := make([]byte, n-head)
+			if _, err := f.ReadAt(payload, off+head); err == nil {
+				boxes = append(boxes, payload)
+			}
+		}
+		off += n
+	}
+	return boxes
+}
+
+// SaveVideoTemp writes an upload to a temporary file for ffprobe and ffmpeg. The caller
+// removes it.
+func SaveVideoTemp(r io.Reader, ext string) (string, error) {
+	f, err := os.CreateTemp("", "trough-video-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
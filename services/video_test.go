@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ffprobeMP4 = `{
+  "streams": [
+    {"codec_type": "video", "codec_name": "h264", "width": 1280, "height": 720, "tags": {"handler_name": "VideoHandler"}},
+    {"codec_type": "audio", "codec_name": "aac"},
+    {"codec_type": "video", "codec_name": "mjpeg", "width": 320, "height": 320}
+  ],
+  "format": {
+    "format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+    "duration": "4.250000",
+    "tags": {"comment": "Generated with Sora", "encoder": "Lavf60.3.100"}
+  }
+}`
+
+func TestParseFFprobe(t *testing.T) {
+	p, err := parseFFprobe([]byte(ffprobeMP4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Container != "mp4" || p.VideoCodec != "h264" || p.AudioCodec != "aac" {
+		t.Fatalf("unexpected probe %+v", p)
+	}
+	// The mjpeg cover art stream must not override the real one
+	if p.Width != 1280 || p.Height != 720 {
+		t.Fatalf("size = %dx%d", p.Width, p.Height)
+	}
+	if p.Duration != 4250*time.Millisecond {
+		t.Fatalf("duration = %s", p.Duration)
+	}
+	if p.Tags["comment"] != "Generated with Sora" || p.Tags["stream:handler_name"] != "VideoHandler" {
+		t.Fatalf("tags = %v", p.Tags)
+	}
+	if p.ContentType() != "video/mp4" || p.Ext() != ".mp4" {
+		t.Fatalf("content type %s ext %s", p.ContentType(), p.Ext())
+	}
+
+	webm, err := parseFFprobe([]byte(`{"streams":[{"codec_type":"video","codec_name":"vp9","width":64,"height":64}],"format":{"format_name":"matroska,webm","duration":"1.0"}}`))
+	if err != nil || webm.Container != "webm" || webm.Ext() != ".webm" {
+		t.Fatalf("webm probe %+v, %v", webm, err)
+	}
+
+	if _, err := parseFFprobe([]byte(`{"format":{"format_name":"avi"}}`)); err == nil {
+		t.Fatal("expected avi to be rejected")
+	}
+}
+
+func TestVideoProbe_CheckLimits(t *testing.T) {
+	cfg := VideoConfig{MaxDuration: 10 * time.Second}
+	ok := VideoProbe{Container: "webm", VideoCodec: "vp9", AudioCodec: "opus", Width: 64, Height: 64, Duration: 5 * time.Second}
+	if err := ok.CheckLimits(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]VideoProbe{
+		"no video":    {Container: "mp4", Width: 64, Height: 64, Duration: time.Second},
+		"video codec": {Container: "webm", VideoCodec: "h264", Width: 64, Height: 64, Duration: time.Second},
+		"audio codec": {Container: "webm", VideoCodec: "vp9", AudioCodec: "aac", Width: 64, Height: 64, Duration: time.Second},
+		"too long":    {Container: "mp4", VideoCodec: "h264", Width: 64, Height: 64, Duration: 11 * time.Second},
+		"no duration": {Container: "mp4", VideoCodec: "h264", Width: 64, Height: 64},
+	}
+	for name, p := range cases {
+		if err := p.CheckLimits(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func bmffBox(typ string, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b[:4], uint32(8+len(payload)))
+	copy(b[4:8], typ)
+	return append(b, payload...)
+}
+
+func TestVideoMetadata_UUIDBoxes(t *testing.T) {
+	var file []byte
+	file = append(file, bmffBox("ftyp", []byte("isom\x00\x00\x02\x00isomiso2"))...)
+	manifest := append(bytes.Repeat([]byte{0xd8}, 16), []byte("c2pa.claim_generator=Adobe Firefly")...)
+	file = append(file, bmffBox("uuid", manifest)...)
+	// Frame data must not be scanned
+	file = append(file, bmffBox("mdat", []byte("Midjourney"))...)
+
+	p := &VideoProbe{Container: "mp4", Tags: map[string]string{"title": "koi", "comment": "x"}}
+	meta := string(VideoMetadata(bytes.NewReader(file), int64(len(file)), p))
+	if !strings.HasPrefix(meta, "comment=x\ntitle=koi\n") {
+		t.Fatalf("tags not sorted first: %q", meta)
+	}
+	if !strings.Contains(meta, "c2pa.claim_generator=Adobe Firefly") {
+		t.Fatalf("uuid payload missing: %q", meta)
+	}
+	if strings.Contains(meta, "Midjourney") {
+		t.Fatal("mdat contents leaked into metadata")
+	}
+
+	// A truncated box ends the walk without reading past the file
+	if boxes := bmffUUIDBoxes(bytes.NewReader(file[:len(file)-3]), int64(len(file)-3)); len(boxes) != 1 {
+		t.Fatalf("got %d boxes from truncated file", len(boxes))
+	}
+}
+
+func TestIsVideoUpload(t *testing.T) {
+	for _, tc := range []struct {
+		name, ct string
+		want     bool
+	}{
+		{"clip.MP4", "", true},
+		{"clip.webm", "", true},
+		{"clip.bin", "video/webm", true},
+		{"still.png", "image/png", false},
+		{"clip.mov", "video/quicktime", false},
+	} {
+		if got := IsVideoUpload(tc.name, tc.ct); got != tc.want {
+			t.Errorf("IsVideoUpload(%q, %q) = %v", tc.name, tc.ct, got)
+		}
+	}
+}
+
+func TestProbeVideo_Disabled(t *testing.T) {
+	if _, err := ProbeVideo(context.Background(), VideoConfig{}, "missing.mp4"); !errors.Is(err, ErrVideoUnavailable) {
+		t.Fatalf("expected ErrVideoUnavailable, got %v", err)
+	}
+	if _, err := VideoPoster(context.Background(), VideoConfig{Enabled: true, FFmpegPath: "/nonexistent/ffmpeg"}, "x.mp4", time.Second); !errors.Is(err, ErrVideoUnavailable) {
+		t.Fatalf("expected ErrVideoUnavailable for a missing binary, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Watermark stamps text into the bottom-right corner of src, sized relative to the
+// shorter side so it stays legible on large masters. The built-in bitmap face covers
+// Latin-1; other characters render as a placeholder glyph.
+func Watermark(src image.Image, text string) image.Image {
+	b := src.Bounds()
+	if text == "" || b.Dx() < 32 || b.Dy() < 32 {
+		return src
+	}
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+	tw := d.MeasureString(text).Ceil()
+	th := face.Height
+	mask := image.NewAlpha(image.Rect(0, 0, tw, th))
+	d.Dst = mask
+	d.Src = image.Opaque
+	d.Dot = fixed.P(0, face.Ascent)
+	d.DrawString(text)
+
+	// Aim for text about 3% of the shorter side tall, but never wider than half the image
+	short := b.Dx()
+	if b.Dy() < short {
+		short = b.Dy()
+	}
+	scale := float64(short) * 0.03 / float64(th)
+	if limit := float64(b.Dx()) / 2 / float64(tw); scale > limit {
+		scale = limit
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	sw, sh := int(float64(tw)*scale), int(float64(th)*scale)
+	scaled := image.NewAlpha(image.Rect(0, 0, sw, sh))
+	xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), mask, mask.Bounds(), xdraw.Src, nil)
+
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, src, b.Min, draw.Src)
+	margin := int(float64(sh) * 0.6)
+	at := image.Rect(b.Max.X-margin-sw, b.Max.Y-margin-sh, b.Max.X-margin, b.Max.Y-margin)
+	shadow := int(scale)
+	if shadow < 1 {
+		shadow = 1
+	}
+	draw.DrawMask(out, at.Add(image.Pt(shadow, shadow)), image.NewUniform(color.NRGBA{0, 0, 0, 110}), image.Point{}, scaled, image.Point{}, draw.Over)
+	draw.DrawMask(out, at, image.NewUniform(color.NRGBA{255, 255, 255, 170}), image.Point{}, scaled, image.Point{}, draw.Over)
+	return out
+}
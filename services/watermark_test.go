@@ -0,0 +1,40 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestWatermark(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	out := Watermark(src, "TROUGH @artist")
+	if out.Bounds() != src.Bounds() {
+		t.Fatalf("bounds changed: %v", out.Bounds())
+	}
+	stamped := func(r image.Rectangle) bool {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if cr, _, _, _ := out.At(x, y).RGBA(); cr > 0x8000 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !stamped(image.Rect(400, 500, 800, 600)) {
+		t.Fatal("expected light text in the bottom-right corner")
+	}
+	if stamped(image.Rect(0, 0, 400, 300)) {
+		t.Fatal("expected the rest of the image untouched")
+	}
+	if src.RGBAAt(799, 599) != (color.RGBA{0, 0, 0, 255}) {
+		t.Fatal("source image must not be modified")
+	}
+	if Watermark(src, "") != image.Image(src) {
+		t.Fatal("empty text should return the source")
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+// Webhook events. report.created is reserved for the reporting feature.
+const (
+	WebhookImageCreated   = "image.created"
+	WebhookImageDeleted   = "image.deleted"
+	WebhookUserRegistered = "user.registered"
+	WebhookReportCreated  = "report.created"
+	webhookPing           = "ping"
+)
+
+// WebhookEvents lists the events admins can subscribe to.
+var WebhookEvents = []string{WebhookImageCreated, WebhookImageDeleted, WebhookUserRegistered, WebhookReportCreated}
+
+const (
+	webhookMaxAttempts = 8
+	webhookLease       = time.Minute
+	webhookTimeout     = 10 * time.Second
+	webhookRetention   = 14 * 24 * time.Hour
+)
+
+var (
+	webhookRepo   models.WebhookRepositoryInterface
+	webhookWake   chan struct{}
+	webhookClient *http.Client
+)
+
+// WebhookEnvelope is the JSON body POSTed to webhooks.
+type WebhookEnvelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// InitWebhooks starts the delivery worker. Until it is called EmitWebhook is a no-op.
+func InitWebhooks(repo models.WebhookRepositoryInterface) {
+	if webhookRepo != nil || repo == nil {
+		return
+	}
+	webhookRepo = repo
+	webhookWake = make(chan struct{}, 1)
+	webhookClient = NewWebhookHTTPClient(strings.EqualFold(os.Getenv("WEBHOOK_ALLOW_PRIVATE"), "true"))
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		var lastPurge time.Time
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			case <-ShuttingDown():
+				return
+			}
+			if !BeginWork() {
+				return
+			}
+			ProcessWebhookDeliveries(webhookClient, repo)
+			if time.Since(lastPurge) > time.Hour {
+				if _, err := repo.PurgeDeliveries(time.Now().Add(-webhookRetention)); err != nil {
+					Logger(context.Background()).Error("webhooks: purge failed", "error", err)
+				}
+				lastPurge = time.Now()
+			}
+			EndWork()
+		}
+	}()
+}
+
+// EmitWebhook queues event for every enabled webhook subscribed to it. It runs in the
+// background so request handlers never wait on the database for a side effect.
+func EmitWebhook(event string, data interface{}) {
+	repo := webhookRepo
+	if repo == nil {
+		return
+	}
+	go func() {
+		if err := enqueueWebhookEvent(repo, event, data, nil); err != nil {
+			Logger(context.Background()).Error("webhooks: enqueue failed", "event", event, "error", err)
+			return
+		}
+		wakeWebhooks()
+	}()
+}
+
+// PingWebhook queues a ping delivery for a single webhook regardless of its filter.
+func PingWebhook(repo models.WebhookRepositoryInterface, w *models.Webhook) error {
+	if err := enqueueWebhookEvent(repo, webhookPing, map[string]string{"webhook_id": w.ID.String()}, w); err != nil {
+		return err
+	}
+	wakeWebhooks()
+	return nil
+}
+
+func wakeWebhooks() {
+	if webhookWake == nil {
+		return
+	}
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+func enqueueWebhookEvent(repo models.WebhookRepositoryInterface, event string, data interface{}, only *models.Webhook) error {
+	hooks := []models.Webhook{}
+	if only != nil {
+		hooks = append(hooks, *only)
+	} else {
+		all, err := repo.ListEnabled()
+		if err != nil {
+			return err
+		}
+		for _, w := range all {
+			if w.Matches(event) {
+				hooks = append(hooks, w)
+			}
+		}
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	// Every subscriber gets the same envelope so receivers can dedupe on its id
+	body, err := json.Marshal(WebhookEnvelope{ID: uuid.NewString(), Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	for _, w := range hooks {
+		if err := repo.EnqueueDelivery(w.ID, event, string(body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessWebhookDeliveries sends every due delivery once and returns how many succeeded.
+func ProcessWebhookDeliveries(client *http.Client, repo models.WebhookRepositoryInterface) int {
+	delivered := 0
+	hooks := map[uuid.UUID]*models.Webhook{}
+	for {
+		batch, err := repo.ClaimDue(20, webhookLease)
+		if err != nil {
+			Logger(context.Background()).Error("webhooks: claim failed", "error", err)
+			return delivered
+		}
+		if len(batch) == 0 {
+			return delivered
+		}
+		for _, d := range batch {
+			w, ok := hooks[d.WebhookID]
+			if !ok {
+				w, _ = repo.Get(d.WebhookID)
+				hooks[d.WebhookID] = w
+			}
+			if w == nil || (!w.Enabled && d.Event != webhookPing) {
+				_ = repo.MarkFailed(d.ID, 0, "webhook disabled or deleted", time.Now(), true)
+				continue
+			}
+			status, err := SendWebhook(client, w, d.ID.String(), d.Event, []byte(d.Payload))
+			if err != nil {
+				msg := err.Error()
+				if len(msg) > 500 {
+					msg = msg[:500]
+				}
+				dead := d.Attempts >= webhookMaxAttempts
+				if err := repo.MarkFailed(d.ID, status, msg, time.Now().Add(mailRetryDelay(d.Attempts)), dead); err != nil {
+					Logger(context.Background()).Error("webhooks: mark failed", "error", err)
+				}
+				continue
+			}
+			if err := repo.MarkDelivered(d.ID, status); err != nil {
+				Logger(context.Background()).Error("webhooks: mark delivered failed", "error", err)
+			}
+			delivered++
+		}
+	}
+}
+
+// SendWebhook POSTs a signed payload and returns the response status. Any non-2xx
+// response is an error so the delivery is retried.
+func SendWebhook(client *http.Client, w *models.Webhook, deliveryID, event string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TROUGH-Webhooks/1.0")
+	req.Header.Set("X-Trough-Event", event)
+	req.Header.Set("X-Trough-Delivery", deliveryID)
+	req.Header.Set("X-Trough-Timestamp", ts)
+	req.Header.Set("X-Trough-Signature", "sha256="+SignWebhookPayload(w.Secret, ts, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.body". Receivers recompute
+// it with the shared secret and should reject stale timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookSecret returns a random signing secret.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ValidateWebhookURL accepts absolute http(s) URLs without credentials.
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("webhook URL must be an absolute http(s) URL")
+	}
+	if u.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient returns a client that doesn't follow redirects and, unless
+// allowPrivate is set, refuses to connect to loopback, private or link-local addresses so
+// a webhook can't be pointed at internal services. The check runs at dial time, after DNS
+// resolution, so rebinding a hostname doesn't bypass it.
+func NewWebhookHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+				return fmt.Errorf("webhook target %s is not a public address", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: webhookTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
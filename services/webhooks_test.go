@@ -0,0 +1,101 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/trough/models"
+)
+
+type fakeWebhooks struct {
+	models.WebhookRepositoryInterface
+	hook      models.Webhook
+	due       []models.WebhookDelivery
+	delivered []uuid.UUID
+	failed    []bool
+}
+
+func (f *fakeWebhooks) Get(uuid.UUID) (*models.Webhook, error) { h := f.hook; return &h, nil }
+
+func (f *fakeWebhooks) ClaimDue(int, time.Duration) ([]models.WebhookDelivery, error) {
+	out := f.due
+	f.due = nil
+	return out, nil
+}
+
+func (f *fakeWebhooks) MarkDelivered(id uuid.UUID, _ int) error {
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeWebhooks) MarkFailed(_ uuid.UUID, _ int, _ string, _ time.Time, dead bool) error {
+	f.failed = append(f.failed, dead)
+	return nil
+}
+
+func TestWebhookMatches(t *testing.T) {
+	w := models.Webhook{Events: "image.*, user.registered"}
+	for ev, want := range map[string]bool{"image.created": true, "image.deleted": true, "user.registered": true, "report.created": false} {
+		if got := w.Matches(ev); got != want {
+			t.Errorf("%s: got %v, want %v", ev, got, want)
+		}
+	}
+	if !(&models.Webhook{Events: "*"}).Matches("report.created") {
+		t.Error("* should match every event")
+	}
+}
+
+func TestProcessWebhookDeliveriesSignsAndRetries(t *testing.T) {
+	var gotSig, gotTS string
+	var gotBody []byte
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig, gotTS = r.Header.Get("X-Trough-Signature"), r.Header.Get("X-Trough-Timestamp")
+		gotBody, _ = io.ReadAll(r.Body)
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := &fakeWebhooks{hook: models.Webhook{ID: uuid.New(), URL: srv.URL, Secret: "s3cret", Enabled: true}}
+	payload := `{"event":"image.created"}`
+	repo.due = []models.WebhookDelivery{{ID: uuid.New(), WebhookID: repo.hook.ID, Event: "image.created", Payload: payload, Attempts: 1}}
+	if n := ProcessWebhookDeliveries(NewWebhookHTTPClient(true), repo); n != 1 || len(repo.delivered) != 1 {
+		t.Fatalf("expected one delivery, got %d", n)
+	}
+	if string(gotBody) != payload || gotSig != "sha256="+SignWebhookPayload("s3cret", gotTS, []byte(payload)) {
+		t.Fatalf("bad signature or body: %q %q", gotSig, gotBody)
+	}
+
+	fail = true
+	repo.due = []models.WebhookDelivery{
+		{ID: uuid.New(), WebhookID: repo.hook.ID, Event: "image.created", Payload: payload, Attempts: 1},
+		{ID: uuid.New(), WebhookID: repo.hook.ID, Event: "image.created", Payload: payload, Attempts: webhookMaxAttempts},
+	}
+	ProcessWebhookDeliveries(NewWebhookHTTPClient(true), repo)
+	if len(repo.failed) != 2 || repo.failed[0] || !repo.failed[1] {
+		t.Fatalf("expected a retry then a dead letter, got %v", repo.failed)
+	}
+}
+
+func TestWebhookClientRefusesPrivateTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	w := &models.Webhook{URL: srv.URL, Secret: "x"}
+	if _, err := SendWebhook(NewWebhookHTTPClient(false), w, "d", "ping", []byte("{}")); err == nil {
+		t.Fatal("expected loopback target to be refused")
+	}
+	if err := ValidateWebhookURL("ftp://example.com"); err == nil {
+		t.Fatal("expected non-http scheme to be rejected")
+	}
+	if err := ValidateWebhookURL("https://user:pw@example.com/hook"); err == nil {
+		t.Fatal("expected credentials to be rejected")
+	}
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// EncodeWebP writes img as a lossless WebP (VP8L). x/image only decodes WebP, so this
+// is a small encoder of our own: it applies the subtract-green and average predictor
+// transforms and Huffman codes the residuals, without backward references or a color
+// cache. That keeps it simple at the cost of larger files than libwebp, which is fine
+// for small images such as avatars.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return errors.New("webp: image must be between 1x1 and 16384x16384")
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) || nrgba.Stride != 4*width {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+	}
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+	for i := range argb {
+		p := nrgba.Pix[4*i : 4*i+4]
+		r, g, bl, a := uint32(p[0]), uint32(p[1]), uint32(p[2]), uint32(p[3])
+		if a != 0xff {
+			hasAlpha = true
+		}
+		// Subtract green: red and blue are stored relative to green
+		argb[i] = a<<24 | ((r-g)&0xff)<<16 | g<<8 | (bl-g)&0xff
+	}
+	residuals := make([]uint32, len(argb))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var pred uint32
+			switch {
+			case i == 0:
+				pred = 0xff000000
+			case y == 0:
+				pred = argb[i-1]
+			case x == 0:
+				pred = argb[i-width]
+			default:
+				pred = average2(argb[i-1], argb[i-width])
+			}
+			residuals[i] = subPixels(argb[i], pred)
+		}
+	}
+
+	bw := &webpBitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+	// Transforms are listed in the order they were applied and undone in reverse
+	bw.write(1, 1)
+	bw.write(webpSubtractGreen, 2)
+	bw.write(1, 1)
+	bw.write(webpPredictor, 2)
+	bw.write(webpPredictorBits-2, 3)
+	tiles := make([]uint32, webpTiles(width)*webpTiles(height))
+	for i := range tiles {
+		tiles[i] = webpPredictorAverageLT << 8
+	}
+	writeWebPEntropyImage(bw, tiles, false)
+	bw.write(0, 1)
+	writeWebPEntropyImage(bw, residuals, true)
+	data := bw.bytes()
+
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+const (
+	webpPredictor     = 0
+	webpSubtractGreen = 2
+	// webpPredictorBits sizes predictor tiles at 512 pixels, the largest allowed; every
+	// tile uses the same mode
+	webpPredictorBits = 9
+	// webpPredictorAverageLT predicts a pixel as the average of its left and top neighbours
+	webpPredictorAverageLT = 7
+	webpMaxCodeLength      = 15
+)
+
+// webpCodeLengthOrder is the order code length code lengths are written in.
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func webpTiles(n int) int {
+	return (n + 1<<webpPredictorBits - 1) >> webpPredictorBits
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// subPixels subtracts b from a per 8-bit channel.
+func subPixels(a, b uint32) uint32 {
+	ag := (a | 0x00ff00ff) - (b & 0xff00ff00)
+	rb := (a | 0xff00ff00) - (b & 0x00ff00ff)
+	return ag&0xff00ff00 | rb&0x00ff00ff
+}
+
+// writeWebPEntropyImage writes pixels with one prefix code group: the main image also
+// says it uses no meta prefix codes, transform sub-images do not.
+func writeWebPEntropyImage(bw *webpBitWriter, pix []uint32, main bool) {
+	bw.write(0, 1) // no color cache
+	if main {
+		bw.write(0, 1)
+	}
+	green, red, blue, alpha := make([]int, 256+24), make([]int, 256), make([]int, 256), make([]int, 256)
+	for _, p := range pix {
+		green[p>>8&0xff]++
+		red[p>>16&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+	}
+	gc, rc, bc, ac := writeWebPPrefixCode(bw, green), writeWebPPrefixCode(bw, red), writeWebPPrefixCode(bw, blue), writeWebPPrefixCode(bw, alpha)
+	writeWebPPrefixCode(bw, make([]int, 40)) // distances, unused
+	for _, p := range pix {
+		gc.put(bw, int(p>>8&0xff))
+		rc.put(bw, int(p>>16&0xff))
+		bc.put(bw, int(p&0xff))
+		ac.put(bw, int(p>>24))
+	}
+}
+
+type webpPrefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func (c *webpPrefixCode) put(bw *webpBitWriter, symbol int) {
+	bw.write(uint32(c.codes[symbol]), uint(c.lengths[symbol]))
+}
+
+// writeWebPPrefixCode writes a prefix code for the symbol counts and returns it. Codes
+// of one or two literals use the simple form; a single symbol then takes no bits.
+func writeWebPPrefixCode(bw *webpBitWriter, counts []int) *webpPrefixCode {
+	code := &webpPrefixCode{lengths: make([]uint8, len(counts)), codes: make([]uint16, len(counts))}
+	var used []int
+	for s, n := range counts {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		bw.write(1, 1) // the first symbol takes 8 bits
+		bw.write(uint32(used[0]), 8)
+		if len(used) == 2 {
+			// Decoders give the first listed symbol code 0; listing them in order also
+			// matches the canonical code
+			bw.write(uint32(used[1]), 8)
+			code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+			code.codes[used[1]] = 1
+		}
+		return code
+	}
+
+	bw.write(0, 1)
+	lengths := huffmanLengths(counts, webpMaxCodeLength)
+	clCounts := make([]int, 19)
+	for _, l := range lengths {
+		clCounts[l]++
+	}
+	// A code length code needs two symbols to be a proper tree
+	distinct := 0
+	for _, n := range clCounts {
+		if n > 0 {
+			distinct++
+		}
+	}
+	if distinct == 1 {
+		for s := range clCounts[:16] {
+			if clCounts[s] == 0 {
+				clCounts[s] = 1
+				break
+			}
+		}
+	}
+	clLengths := huffmanLengths(clCounts, 7)
+	clCodes := canonicalCodes(clLengths)
+	bw.write(uint32(len(webpCodeLengthOrder)-4), 4)
+	for _, s := range webpCodeLengthOrder {
+		bw.write(uint32(clLengths[s]), 3)
+	}
+	bw.write(0, 1) // code lengths for the whole alphabet follow
+	for _, l := range lengths {
+		bw.write(uint32(clCodes[l]), uint(clLengths[l]))
+	}
+	code.lengths, code.codes = lengths, canonicalCodes(lengths)
+	return code
+}
+
+// huffmanLengths returns Huffman code lengths of at most limit bits for the counts,
+// which must have at least two non-zero entries. When the optimal code is too deep,
+// rare symbols are counted as more frequent until it fits.
+func huffmanLengths(counts []int, limit int) []uint8 {
+	var leaves []int
+	for s, n := range counts {
+		if n > 0 {
+			leaves = append(leaves, s)
+		}
+	}
+	lengths := make([]uint8, len(counts))
+	weights := make([]int, len(counts))
+	copy(weights, counts)
+	for floor := 1; ; floor *= 2 {
+		for _, s := range leaves {
+			if weights[s] < floor {
+				weights[s] = floor
+			}
+		}
+		sort.SliceStable(leaves, func(i, j int) bool { return weights[leaves[i]] < weights[leaves[j]] })
+		n := len(leaves)
+		w := make([]int, n, 2*n-1)
+		for i, s := range leaves {
+			w[i] = weights[s]
+		}
+		parent := make([]int, 2*n-1)
+		// Two queues: sorted leaves, and merged nodes, which come out sorted too
+		li, ni := 0, n
+		next := func() int {
+			if li < n && (ni >= len(w) || w[li] <= w[ni]) {
+				li++
+				return li - 1
+			}
+			ni++
+			return ni - 1
+		}
+		for len(w) < 2*n-1 {
+			a, b := next(), next()
+			parent[a], parent[b] = len(w), len(w)
+			w = append(w, w[a]+w[b])
+		}
+		depth := make([]int, 2*n-1)
+		deepest := 0
+		for k := 2*n - 3; k >= 0; k-- {
+			depth[k] = depth[parent[k]] + 1
+		}
+		for k, s := range leaves {
+			lengths[s] = uint8(depth[k])
+			if depth[k] > deepest {
+				deepest = depth[k]
+			}
+		}
+		if deepest <= limit {
+			return lengths
+		}
+	}
+}
+
+// canonicalCodes assigns canonical Huffman codes to the lengths, bit-reversed since the
+// stream is written least significant bit first but codes are read from their top bit.
+func canonicalCodes(lengths []uint8) []uint16 {
+	var count, next [webpMaxCodeLength + 1]int
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+	code := 0
+	for l := 1; l <= webpMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint16, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c, rev := next[l], 0
+		next[l]++
+		for i := 0; i < int(l); i++ {
+			rev = rev<<1 | c>>i&1
+		}
+		codes[s] = uint16(rev)
+	}
+	return codes
+}
+
+// webpBitWriter packs bits least significant first, as VP8L reads them.
+type webpBitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (w *webpBitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+func (w *webpBitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range []struct {
+		name string
+		w, h int
+		px   func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(int, int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"flat", 16, 9, func(int, int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} }},
+		{"gradient", 64, 48, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 4), uint8(y * 5), uint8(x + y), 255} }},
+		{"translucent noise", 33, 17, func(int, int) color.NRGBA {
+			return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+		}},
+		{"wider than a predictor tile", 600, 3, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x), uint8(x >> 2), uint8(y * 80), 255} }},
+	} {
+		src := image.NewNRGBA(image.Rect(0, 0, tc.w, tc.h))
+		for y := 0; y < tc.h; y++ {
+			for x := 0; x < tc.w; x++ {
+				src.SetNRGBA(x, y, tc.px(x, y))
+			}
+		}
+		var buf bytes.Buffer
+		if err := EncodeWebP(&buf, src); err != nil {
+			t.Fatalf("%s: encode: %v", tc.name, err)
+		}
+		got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: decode: %v", tc.name, err)
+		}
+		if got.Bounds() != src.Bounds() {
+			t.Fatalf("%s: expected bounds %v, got %v", tc.name, src.Bounds(), got.Bounds())
+		}
+		for y := 0; y < tc.h; y++ {
+			for x := 0; x < tc.w; x++ {
+				if c := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA); c != src.NRGBAAt(x, y) {
+					t.Fatalf("%s: pixel (%d,%d): expected %v, got %v", tc.name, x, y, src.NRGBAAt(x, y), c)
+				}
+			}
+		}
+	}
+}
+
+func TestHuffmanLengthsLimit(t *testing.T) {
+	// Fibonacci counts give the deepest possible tree
+	counts := make([]int, 30)
+	counts[0], counts[1] = 1, 1
+	for i := 2; i < len(counts); i++ {
+		counts[i] = counts[i-1] + counts[i-2]
+	}
+	lengths := huffmanLengths(counts, webpMaxCodeLength)
+	kraft := 0.0
+	for _, l := range lengths {
+		if l == 0 || l > webpMaxCodeLength {
+			t.Fatalf("expected lengths between 1 and %d, got %v", webpMaxCodeLength, lengths)
+		}
+		kraft += 1 / float64(int(1)<<l)
+	}
+	if kraft != 1 {
+		t.Fatalf("expected a complete code, Kraft sum is %v", kraft)
+	}
+}
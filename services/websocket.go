@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal server side of RFC 6455, enough for the admin monitor: the server sends
+// unfragmented text frames and pings, and reads client frames only to answer pings and
+// notice a close. Extensions and subprotocols are not negotiated.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	WSOpText  byte = 0x1
+	WSOpClose byte = 0x8
+	WSOpPing  byte = 0x9
+	WSOpPong  byte = 0xA
+)
+
+// Close codes sent by the server.
+const (
+	WSCloseNormal    uint16 = 1000
+	WSCloseGoingAway uint16 = 1001
+	WSCloseProtocol  uint16 = 1002
+	WSCloseTooBig    uint16 = 1009
+)
+
+// Clients only send control frames and the odd keepalive, so reads are capped small.
+const wsMaxClientFrame = 4096
+
+// ErrWSProtocol is returned by ReadFrame for frames a server must reject.
+var ErrWSProtocol = errors.New("websocket protocol error")
+
+// ErrWSFrameTooBig is returned by ReadFrame when a client frame exceeds the read limit.
+var ErrWSFrameTooBig = errors.New("websocket frame too large")
+
+// WebSocketAccept computes the Sec-WebSocket-Accept value for a client key.
+func WebSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(key) + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ValidWebSocketKey reports whether key is a base64 encoded 16-byte nonce.
+func ValidWebSocketKey(key string) bool {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	return err == nil && len(b) == 16
+}
+
+// WSConn is an upgraded connection. Writes are serialized so a reader goroutine can
+// answer pings while another goroutine sends messages.
+type WSConn struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	mu           sync.Mutex
+	writeTimeout time.Duration
+}
+
+// NewWSConn wraps a hijacked connection. Each write must finish within writeTimeout, so a
+// client that stops reading is disconnected instead of holding the writer.
+func NewWSConn(conn net.Conn, writeTimeout time.Duration) *WSConn {
+	return &WSConn{conn: conn, br: bufio.NewReaderSize(conn, 1024), writeTimeout: writeTimeout}
+}
+
+// WriteText sends p as one text message.
+func (w *WSConn) WriteText(p []byte) error { return w.writeFrame(WSOpText, p) }
+
+// WritePing sends an empty ping.
+func (w *WSConn) WritePing() error { return w.writeFrame(WSOpPing, nil) }
+
+// WritePong answers a ping with its payload.
+func (w *WSConn) WritePong(p []byte) error { return w.writeFrame(WSOpPong, p) }
+
+// WriteClose sends a close frame with code and a short reason.
+func (w *WSConn) WriteClose(code uint16, reason string) error {
+	if len(reason) > 120 {
+		reason = reason[:120]
+	}
+	p := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(p, code)
+	copy(p[2:], reason)
+	return w.writeFrame(WSOpClose, p)
+}
+
+// Close closes the underlying connection.
+func (w *WSConn) Close() error { return w.conn.Close() }
+
+func (w *WSConn) writeFrame(op byte, p []byte) error {
+	hdr := make([]byte, 2, 10)
+	hdr[0] = 0x80 | op
+	switch n := len(p); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xFFFF:
+		hdr[1] = 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.writeTimeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	}
+	if _, err := w.conn.Write(hdr); err != nil {
+		return err
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := w.conn.Write(p)
+	return err
+}
+
+// ReadFrame reads one client frame and returns its opcode and unmasked payload. Client
+// frames must be masked, and control frames must be final and short.
+func (w *WSConn) ReadFrame() (byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(w.br, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	op := hdr[0] & 0x0F
+	final := hdr[0]&0x80 != 0
+	if hdr[0]&0x70 != 0 || hdr[1]&0x80 == 0 {
+		return 0, nil, ErrWSProtocol
+	}
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(w.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(w.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= WSOpClose && (!final || n > 125) {
+		return 0, nil, ErrWSProtocol
+	}
+	if n > wsMaxClientFrame {
+		return 0, nil, ErrWSFrameTooBig
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(w.br, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(w.br, p); err != nil {
+		return 0, nil, err
+	}
+	for i := range p {
+		p[i] ^= mask[i%4]
+	}
+	return op, p, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWebSocketAccept(t *testing.T) {
+	// Example handshake from RFC 6455 section 1.3
+	if got := WebSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept value %q", got)
+	}
+	if !ValidWebSocketKey("dGhlIHNhbXBsZSBub25jZQ==") || ValidWebSocketKey("short") {
+		t.Fatal("key validation is wrong")
+	}
+}
+
+func maskedFrame(op byte, payload []byte) []byte {
+	mask := [4]byte{1, 2, 3, 4}
+	f := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	f = append(f, mask[:]...)
+	for i, b := range payload {
+		f = append(f, b^mask[i%4])
+	}
+	return f
+}
+
+func TestWSConnFrames(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := NewWSConn(server, time.Second)
+	defer ws.Close()
+
+	go func() { _ = ws.WriteText([]byte(`{"type":"security"}`)) }()
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(client, hdr); err != nil {
+		t.Fatal(err)
+	}
+	if hdr[0] != 0x81 || hdr[1] != 19 {
+		t.Fatalf("unexpected text frame header %x", hdr)
+	}
+	body := make([]byte, 19)
+	if _, err := io.ReadFull(client, body); err != nil || string(body) != `{"type":"security"}` {
+		t.Fatalf("unexpected payload %q (%v)", body, err)
+	}
+
+	go func() { _, _ = client.Write(maskedFrame(WSOpPing, []byte("hi"))) }()
+	op, p, err := ws.ReadFrame()
+	if err != nil || op != WSOpPing || string(p) != "hi" {
+		t.Fatalf("unexpected frame %x %q %v", op, p, err)
+	}
+
+	// Servers must reject unmasked client frames
+	go func() { _, _ = client.Write([]byte{0x81, 0x01, 'x'}) }()
+	if _, _, err := ws.ReadFrame(); err != ErrWSProtocol {
+		t.Fatalf("expected a protocol error, got %v", err)
+	}
+}
@@ -147,22 +147,106 @@ class TroughApp {
             await this.fetchCSRFToken();
         }
         
-        const headers = {
-            ...options.headers,
-            'X-CSRF-Token': this.csrfToken
+        const send = () => {
+            // For multipart forms, add CSRF token as form field
+            if (options.body && options.body instanceof FormData) {
+                options.body.set('csrf_token', this.csrfToken);
+            }
+            return fetch(url, { ...options, headers: { ...options.headers, 'X-CSRF-Token': this.csrfToken } });
         };
-        
-        // For multipart forms, add CSRF token as form field
-        if (options.body && options.body instanceof FormData) {
-            options.body.append('csrf_token', this.csrfToken);
+        let response = await send();
+        // Tokens are bound to the session and rotate on sign-in and sign-out; refetch once
+        if (response.status === 403 && !options._csrfRetried) {
+            const err = await response.clone().json().catch(() => ({}));
+            if (String(err.error || '').includes('CSRF')) {
+                options._csrfRetried = true;
+                this.resetCSRFToken();
+                await this.fetchCSRFToken();
+                response = await send();
+            }
         }
-        
-        return fetch(url, {
-            ...options,
-            headers
+        // 451: the terms changed since the user accepted them; ask once, then retry
+        if (response.status === 451 && !options._consentRetried) {
+            const err = await response.clone().json().catch(() => ({}));
+            if (err.consent_required && await this.promptConsent(err)) {
+                options._consentRetried = true;
+                response = await send();
+            }
+        }
+        return response;
+    }
+
+    // Adult sites ask signed-out visitors to confirm their age before anything loads; the
+    // server keeps the answer in a signed cookie
+    async ensureAgeGate() {
+        if (!window.__ADULT_SITE__ || this.currentUser) return;
+        const r = await fetch('/api/age-gate', { credentials: 'include' }).catch(() => null);
+        const d = r && r.ok ? await r.json().catch(() => ({})) : {};
+        if (!d.adult_site || d.confirmed) return;
+        await new Promise((resolve) => {
+            const overlay = document.createElement('div');
+            overlay.style.cssText = 'position:fixed;inset:0;z-index:3000;background:rgba(0,0,0,0.85);backdrop-filter:blur(16px);display:flex;align-items:center;justify-content:center;padding:24px;';
+            overlay.innerHTML = `
+                <div style="max-width:440px;width:100%;background:var(--surface-elevated);border:1px solid var(--border-strong);border-radius:12px;padding:20px;color:var(--text-primary);text-align:center">
+                  <div style="font-weight:700;font-size:1.1rem;margin-bottom:8px">Adult content</div>
+                  <div style="color:var(--text-secondary);line-height:1.5;margin-bottom:16px">This site contains material for adults. You must be at least ${Number(d.minimum_age) || 18} years old to enter.</div>
+                  <div style="display:flex;gap:8px;justify-content:center">
+                    <button id="age-leave" class="link-btn">Leave</button>
+                    <button id="age-enter" class="nav-btn">I am ${Number(d.minimum_age) || 18} or older</button>
+                  </div>
+                </div>`;
+            overlay.querySelector('#age-leave').onclick = () => { location.href = 'about:blank'; };
+            overlay.querySelector('#age-enter').onclick = async () => {
+                const resp = await this.fetchWithCSRF('/api/age-gate', { method: 'POST', credentials: 'include', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ confirmed: true }) }).catch(() => null);
+                if (resp && resp.ok) { overlay.remove(); resolve(); }
+                else this.showNotification('Could not save your answer, please try again', 'error');
+            };
+            document.body.appendChild(overlay);
         });
     }
 
+    // Shows the updated terms and privacy policy and records acceptance; resolves true
+    // once accepted. Concurrent 451s share one prompt.
+    promptConsent(versions) {
+        if (this._consentPrompt) return this._consentPrompt;
+        this._consentPrompt = new Promise((resolve) => {
+            const overlay = document.createElement('div');
+            overlay.style.cssText = 'position:fixed;inset:0;z-index:3000;background:rgba(0,0,0,0.6);backdrop-filter:blur(8px);display:flex;align-items:center;justify-content:center;padding:24px;';
+            const panel = document.createElement('div');
+            panel.style.cssText = 'max-width:520px;width:100%;background:var(--surface-elevated);border:1px solid var(--border-strong);border-radius:12px;padding:16px;color:var(--text-primary);box-shadow:0 20px 60px rgba(0,0,0,0.45)';
+            panel.innerHTML = `
+                <div style="font-weight:700;margin-bottom:8px">Our terms have changed</div>
+                <div style="color:var(--text-secondary);line-height:1.5;margin-bottom:12px">Please review the updated <a href="/terms" target="_blank" rel="noopener">Terms of Service</a> and <a href="/privacy" target="_blank" rel="noopener">Privacy Policy</a>. You can keep browsing, but need to accept them to post, comment or change anything.</div>
+                <div id="consent-error" style="color:#ef4444;font-size:12px;margin-bottom:8px;display:none"></div>
+                <div style="display:flex;gap:8px;justify-content:flex-end">
+                  <button id="consent-later" class="link-btn">Not now</button>
+                  <button id="consent-accept" class="nav-btn">I agree</button>
+                </div>`;
+            overlay.appendChild(panel);
+            const close = (ok) => { overlay.remove(); this._consentPrompt = null; resolve(ok); };
+            panel.querySelector('#consent-later').onclick = () => close(false);
+            panel.querySelector('#consent-accept').onclick = async () => {
+                const resp = await this.fetchWithCSRF('/api/me/consent', {
+                    method: 'POST', credentials: 'include', headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ terms_revision: versions.terms_revision || 0, privacy_revision: versions.privacy_revision || 0 })
+                }).catch(() => null);
+                if (resp && resp.ok) { close(true); return; }
+                const data = resp ? await resp.json().catch(() => ({})) : {};
+                if (resp && resp.status === 409 && data.required) versions = data.required;
+                const msg = panel.querySelector('#consent-error');
+                msg.textContent = data.error || 'Could not record your consent, please try again';
+                msg.style.display = 'block';
+            };
+            document.body.appendChild(overlay);
+        });
+        return this._consentPrompt;
+    }
+
+    resetCSRFToken() {
+        this.csrfToken = null;
+        this.csrfTokenPromise = null;
+    }
+
     async seedMyCollectedSet() {
         try {
             // Only for logged-in users
@@ -177,6 +261,12 @@ class TroughApp {
         }
     }
 
+    // Still to show for an image row: the poster frame for videos, the file itself otherwise
+    stillURL(image) {
+        if (image && image.media_type === 'video') return this.getImageURL(image.poster_filename || '');
+        return this.getImageURL(image && image.filename);
+    }
+
     // Helper function to get the correct image URL (handles both local filenames and remote URLs)
     getImageURL(filename) {
         if (!filename) return '';
@@ -185,6 +275,11 @@ class TroughApp {
         if (filename.startsWith('http://') || filename.startsWith('https://')) {
             return filename;
         }
+
+        // Signed links arrive as /uploads/<key>?exp=...&sig=...
+        if (filename.startsWith('/uploads/')) {
+            return filename;
+        }
         
         // Check for domain-based URLs without protocol (like z.disinfo.zone/file.jpg)
         if (filename.includes('.') && filename.includes('/') && !filename.startsWith('/')) {
@@ -220,20 +315,35 @@ class TroughApp {
         this.setupHistoryHandler();
         this.setupEventListeners();
         this.setupImageLazyLoader();
+        this.setupLiveFeed();
 
         await this.applyPublicSiteSettings(); // Moved this line up
+        await this.ensureAgeGate();
+        this.loadAnnouncements();
+        this.loadSnippets();
 
         if (location.pathname === '/reset') { await this.renderResetPage(); return; }
         if (location.pathname === '/verify') { await this.renderVerifyPage(); return; }
+        if (location.pathname === '/confirm-email' || location.pathname === '/cancel-email-change') { await this.renderEmailChangePage(); return; }
         if (location.pathname.startsWith('/@')) {
             const username = decodeURIComponent(location.pathname.slice(2));
             this.beginRender('profile');
             await this.renderProfilePage(username);
             return;
         }
-		// CMS pages (single-segment slugs)
-		if (/^\/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$/.test(location.pathname)) {
-			const slug = location.pathname.slice(1);
+        if (location.pathname === '/challenges') {
+            this.beginRender('challenges');
+            await this.renderChallengesPage();
+            return;
+        }
+        if (location.pathname === '/takedown') {
+            this.beginRender('takedown');
+            await this.renderTakedownPage();
+            return;
+        }
+		// CMS pages (help, help/faq)
+		if (this.cmsSlugOf(location.pathname)) {
+			const slug = this.cmsSlugOf(location.pathname);
 			this.beginRender('cms');
 			const ok = await this.renderCMSPage(slug);
 			if (ok) return;
@@ -263,6 +373,7 @@ class TroughApp {
                     tabs.forEach(t => t.classList.remove('active')); registerTab.classList.add('active');
                     const loginForm = document.getElementById('login-form'); const registerForm = document.getElementById('register-form'); const submitBtn = document.getElementById('auth-submit');
                     if (loginForm && registerForm && submitBtn) { loginForm.style.display='none'; registerForm.style.display='block'; submitBtn.textContent='Create Account'; }
+                    this.loadRegisterFormToken();
                 }
             };
 
@@ -281,6 +392,10 @@ class TroughApp {
                     if (r.status === 204) {
                         this._pendingInvite = invite;
                         await proceedToRegister();
+                        // Emailed invites carry the address they are bound to
+                        const invitedEmail = url.searchParams.get('email');
+                        const emailInput = document.getElementById('register-email');
+                        if (invitedEmail && emailInput && !emailInput.value) emailInput.value = invitedEmail;
                     } else {
                         const data = await r.json().catch(() => ({}));
                         await proceedToLogin(data.error || 'Invalid invitation link');
@@ -521,8 +636,9 @@ class TroughApp {
     setupHistoryHandler() {
         window.onpopstate = async () => {
             // Bump epoch at the start of any history-driven navigation
-            const isCMSPage = /^\/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$/.test(location.pathname);
+            const isCMSPage = !!this.cmsSlugOf(location.pathname) && location.pathname !== '/challenges';
             this.beginRender(
+                location.pathname === '/challenges' ? 'challenges' :
                 location.pathname === '/' ? 'home' :
                 location.pathname.startsWith('/@') ? 'profile' :
                 (location.pathname === '/settings' ? 'settings' : (location.pathname === '/admin' ? 'admin' : (isCMSPage ? 'cms' : 'image')))
@@ -540,9 +656,11 @@ class TroughApp {
                 await this.renderSettingsPage();
             } else if (location.pathname === '/admin') {
                 await this.renderAdminPage();
+            } else if (location.pathname === '/challenges') {
+                await this.renderChallengesPage();
             } else if (isCMSPage) {
                 // Handle CMS pages
-                const slug = location.pathname.slice(1);
+                const slug = this.cmsSlugOf(location.pathname);
                 const ok = await this.renderCMSPage(slug);
                 if (!ok) {
                     // Fallback to home if CMS page fails
@@ -566,7 +684,7 @@ class TroughApp {
             }
 
             // After any route change, normalize gallery mode classes:
-            if (location.pathname.startsWith('/i/') || location.pathname === '/settings' || location.pathname === '/admin') {
+            if (location.pathname.startsWith('/i/') || location.pathname === '/settings' || location.pathname === '/admin' || location.pathname === '/challenges') {
                 this.gallery.classList.add('settings-mode');
             } else {
                 this.gallery.classList.remove('settings-mode');
@@ -601,9 +719,133 @@ class TroughApp {
             this.disableManagedMasonry();
             // Stop any in-flight scroll animations
             if (this._activeScrollAnim) { this._activeScrollAnim.cancelled = true; this._activeScrollAnim = null; }
+            // Drop any profile theme from the previous view
+            this.applyProfileTheme(null);
+            this.resetNewImages();
         } catch {}
     }
 
+    // Live feed: count new images announced over SSE while the home feed is open and offer
+    // to show them, instead of polling /api/feed
+    setupLiveFeed() {
+        if (!window.EventSource) return;
+        this._newImageIds = new Set();
+        try {
+            const es = new EventSource('/api/feed/stream', { withCredentials: true });
+            es.addEventListener('image', (e) => {
+                if (this.routeMode !== 'home') return;
+                let ev = null;
+                try { ev = JSON.parse(e.data); } catch { return; }
+                const id = String(ev && ev.id || '');
+                if (!id || document.querySelector(`.image-card[data-image-id="${CSS.escape(id)}"]`)) return;
+                const model = new URLSearchParams(location.search).get('model');
+                if (model && String(ev.ai_model || '').toLowerCase() !== model.toLowerCase()) return;
+                this._newImageIds.add(id);
+                this.renderNewImagesPill();
+            });
+            this._liveFeed = es;
+        } catch {}
+    }
+
+    renderNewImagesPill() {
+        let pill = document.getElementById('new-images-pill');
+        const n = this._newImageIds ? this._newImageIds.size : 0;
+        if (!pill) {
+            if (!n) return;
+            pill = document.createElement('button');
+            pill.id = 'new-images-pill';
+            pill.type = 'button';
+            pill.className = 'new-images-pill';
+            pill.addEventListener('click', () => this.showNewImages());
+            document.body.appendChild(pill);
+        }
+        pill.textContent = n === 1 ? '1 new image' : `${n} new images`;
+        pill.hidden = !n;
+    }
+
+    resetNewImages() {
+        if (this._newImageIds) this._newImageIds.clear();
+        this.renderNewImagesPill();
+    }
+
+    async showNewImages() {
+        if (this.routeMode !== 'home') return;
+        if (await this.prependNewImages()) return;
+        this.gallery.classList.remove('settings-mode');
+        this.gallery.innerHTML = '';
+        this.page = 1; this.hasMore = true;
+        window.scrollTo(0, 0);
+        this.beginRender('home');
+        this.enableManagedMasonry();
+        await this.loadImages();
+        this.setupInfiniteScroll();
+    }
+
+    // Fetch only the images newer than the first page and put them on top of the gallery.
+    // Returns false when the caller should reload the feed instead.
+    async prependNewImages() {
+        if (!this._feedSince) return false;
+        try {
+            const resp = await fetch(`/api/feed?since=${encodeURIComponent(this._feedSince)}&limit=50${this.feedModelQuery()}`, { credentials: 'include' });
+            if (!resp.ok) return false;
+            const data = await resp.json();
+            if (data.truncated || this.routeMode !== 'home') return false;
+            const images = data.images || [];
+            // Oldest first, so the newest ends up on top
+            for (let i = images.length - 1; i >= 0; i--) {
+                const id = String(images[i].id || '');
+                if (!id) continue;
+                this.createImageCard(images[i]);
+                const card = this.gallery.querySelector(`.image-card[data-image-id="${CSS.escape(id)}"]`);
+                const parent = card && card.parentNode;
+                if (parent && parent.firstChild !== card) parent.insertBefore(card, parent.firstChild);
+            }
+            if (data.since_cursor) this._feedSince = data.since_cursor;
+            this.resetNewImages();
+            window.scrollTo({ top: 0, behavior: 'smooth' });
+            return true;
+        } catch {
+            return false;
+        }
+    }
+
+    // Block/unblock toggle on someone else's profile; the state comes from /api/me/blocks
+    async wireBlockButton(btn, user) {
+        let blocked = false;
+        try {
+            const r = await fetch('/api/me/blocks', { credentials: 'include' });
+            if (!r.ok) return;
+            const data = await r.json();
+            blocked = (data.blocks || []).some(b => b.user_id === user.id);
+        } catch { return; }
+        const paint = () => { btn.textContent = blocked ? 'Unblock' : 'Block'; btn.style.display = ''; };
+        paint();
+        btn.onclick = async () => {
+            try {
+                const r = await fetch(`/api/users/${encodeURIComponent(user.username)}/block`, { method: blocked ? 'DELETE' : 'POST', credentials: 'include' });
+                const data = await r.json().catch(() => ({}));
+                if (!r.ok) { this.showNotification(data.error || 'Unable to update block', 'error'); return; }
+                blocked = !!data.blocked;
+                paint();
+                this.showNotification(blocked ? `Blocked @${user.username}` : `Unblocked @${user.username}`);
+            } catch {}
+        };
+    }
+
+    // Scope a profile's accent color and gallery layout to the profile view
+    applyProfileTheme(theme) {
+        const targets = [this.profileTop, this.gallery].filter(Boolean);
+        for (const el of targets) el.style.removeProperty('--color-accent');
+        if (this.gallery) this.gallery.classList.remove('profile-layout-grid', 'profile-layout-wide');
+        if (!theme) return;
+        if (/^#[0-9a-f]{6}$/i.test(String(theme.accent || ''))) {
+            for (const el of targets) el.style.setProperty('--color-accent', theme.accent);
+        }
+        if (this.gallery && (theme.layout === 'grid' || theme.layout === 'wide')) {
+            this.gallery.classList.add(`profile-layout-${theme.layout}`);
+        }
+    }
+
     trackTimeout(id) { try { if (id) this.pendingTimers.add(id); } catch {} return id; }
     untrackTimeout(id) { try { if (id) this.pendingTimers.delete(id); } catch {} }
 
@@ -791,6 +1033,13 @@ class TroughApp {
             window.__SITE_EMAIL_ENABLED__ = !!s.email_enabled;
             window.__REQUIRE_VERIFY__ = !!s.require_email_verification;
             window.__PUBLIC_REG_ENABLED__ = s.public_registration_enabled !== false; // default true
+            window.__MIN_AGE__ = Number(s.minimum_age) || 0;
+            window.__ADULT_SITE__ = !!s.adult_site;
+            const ageRow = document.getElementById('register-age-row');
+            if (ageRow) {
+                ageRow.style.display = window.__MIN_AGE__ ? '' : 'none';
+                const minAge = document.getElementById('register-min-age'); if (minAge) minAge.textContent = String(window.__MIN_AGE__);
+            }
             if (s.from_email) window.__SITE_FROM_EMAIL__ = s.from_email;
             if (s.site_name) {
                 const logo = document.querySelector('.logo');
@@ -878,6 +1127,8 @@ class TroughApp {
                 this.currentUser = data.user;
                 localStorage.setItem('user', JSON.stringify(data.user));
                 this.updateAuthButton();
+                this.renderSuspensionBanner(data.suspension);
+                this.renderImpersonationBanner(data.impersonation);
                 return;
             }
         } catch {}
@@ -890,6 +1141,8 @@ class TroughApp {
                     this.currentUser = data2.user;
                     localStorage.setItem('user', JSON.stringify(data2.user));
                     this.updateAuthButton();
+                    this.renderSuspensionBanner(data2.suspension);
+                    this.renderImpersonationBanner(data2.impersonation);
                     return;
                 }
             } catch {}
@@ -898,6 +1151,97 @@ class TroughApp {
         try { localStorage.removeItem('user'); } catch {}
         this.currentUser = null;
         this.updateAuthButton();
+        this.renderSuspensionBanner(null);
+        this.renderImpersonationBanner(null);
+    }
+
+    // Shows (or clears) the strip under the nav telling a signed-in user their account is suspended
+    renderSuspensionBanner(suspension) {
+        const nav = document.getElementById('nav');
+        let el = document.getElementById('suspension-banner');
+        if (!suspension) { if (el) el.remove(); return; }
+        if (!el && nav) {
+            el = document.createElement('div');
+            el.id = 'suspension-banner';
+            el.className = 'suspension-banner';
+            el.setAttribute('role', 'alert');
+            nav.appendChild(el);
+        }
+        if (!el) return;
+        const until = suspension.until ? `until ${new Date(suspension.until).toLocaleString()}` : 'until further notice';
+        el.innerHTML = `<strong>Your account is suspended ${this.escapeHTML(until)}.</strong>${suspension.reason ? ' Reason: ' + this.escapeHTML(String(suspension.reason)) : ''} Uploads are disabled.`;
+    }
+
+    // Shows (or clears) the strip telling an admin they are acting as another user, with a way out
+    renderImpersonationBanner(imp) {
+        const nav = document.getElementById('nav');
+        let el = document.getElementById('impersonation-banner');
+        if (!imp) { if (el) el.remove(); return; }
+        if (!el && nav) {
+            el = document.createElement('div');
+            el.id = 'impersonation-banner';
+            el.className = 'suspension-banner';
+            el.setAttribute('role', 'alert');
+            nav.appendChild(el);
+        }
+        if (!el) return;
+        const who = this.currentUser ? '@' + this.currentUser.username : 'this user';
+        const by = imp.admin_username ? ` on behalf of @${this.escapeHTML(String(imp.admin_username))}` : '';
+        el.innerHTML = `<strong>You are viewing the site as ${this.escapeHTML(who)}${by}.</strong> Everything you do is audited. Session ends ${this.escapeHTML(new Date(imp.expires_at).toLocaleTimeString())}. <button id="end-impersonation" class="link-btn">End session</button>`;
+        document.getElementById('end-impersonation').onclick = async () => {
+            const r = await this.fetchWithCSRF('/api/me/impersonation/end', { method: 'POST', credentials: 'include' });
+            const d = await r.json().catch(() => ({}));
+            try { if (d.token) localStorage.setItem('token', d.token); else localStorage.removeItem('token'); localStorage.removeItem('user'); } catch {}
+            location.href = '/';
+        };
+    }
+
+    // Fills every [data-snippet] slot with the admin-edited snippet of that key. The HTML is
+    // sanitized by the server; it is run through DOMPurify too when available.
+    async loadSnippets() {
+        let snippets = {};
+        try {
+            const r = await fetch('/api/snippets');
+            if (r.ok) snippets = (await r.json()).snippets || {};
+        } catch {}
+        this.snippets = snippets;
+        document.querySelectorAll('[data-snippet]').forEach(el => {
+            const html = String(snippets[el.getAttribute('data-snippet')] || '');
+            el.innerHTML = (window.DOMPurify && html) ? window.DOMPurify.sanitize(html) : html;
+        });
+    }
+
+    // Shows the operator announcements that are live now, minus the ones this browser dismissed
+    async loadAnnouncements() {
+        let list = [];
+        try {
+            const r = await fetch('/api/announcements');
+            if (r.ok) list = (await r.json()).announcements || [];
+        } catch {}
+        let dismissed = [];
+        try { dismissed = JSON.parse(localStorage.getItem('dismissed_announcements') || '[]'); } catch {}
+        // Forget dismissals of announcements that are no longer live
+        const live = new Set(list.map(a => a.id));
+        dismissed = dismissed.filter(id => live.has(id));
+        try { localStorage.setItem('dismissed_announcements', JSON.stringify(dismissed)); } catch {}
+        const nav = document.getElementById('nav');
+        document.querySelectorAll('.announcement-banner').forEach(el => el.remove());
+        if (!nav) return;
+        for (const a of list) {
+            if (a.dismissible && dismissed.includes(a.id)) continue;
+            const level = ['info', 'warning', 'critical'].includes(a.level) ? a.level : 'info';
+            const el = document.createElement('div');
+            el.className = `announcement-banner level-${level}`;
+            el.setAttribute('role', level === 'info' ? 'status' : 'alert');
+            el.innerHTML = `<span>${this.escapeHTML(String(a.message || ''))}</span>${a.dismissible ? '<button class="ab-dismiss" aria-label="Dismiss">&times;</button>' : ''}`;
+            const btn = el.querySelector('.ab-dismiss');
+            if (btn) btn.onclick = () => {
+                dismissed.push(a.id);
+                try { localStorage.setItem('dismissed_announcements', JSON.stringify(dismissed)); } catch {}
+                el.remove();
+            };
+            nav.appendChild(el);
+        }
     }
 
     updateAuthButton() {
@@ -987,6 +1331,9 @@ class TroughApp {
                 await this.renderSettingsPage();
             } else if (href === '/admin') {
                 await this.renderAdminPage();
+            } else if (href === '/challenges') {
+                this.beginRender('challenges');
+                await this.renderChallengesPage();
             } else if (href === '/') {
                 // Explicit navigation to home should be fresh (no restore)
                 this.gallery.classList.remove('settings-mode');
@@ -1000,9 +1347,9 @@ class TroughApp {
                 await this.seedMyCollectedSet();
                 await this.loadImages();
                 this.setupInfiniteScroll();
-            } else if (/^\/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$/.test(href)) {
-                // Single-segment CMS page
-                const slug = href.slice(1);
+            } else if (this.cmsSlugOf(href)) {
+                // CMS page
+                const slug = this.cmsSlugOf(href);
                 this.beginRender('cms');
                 const ok = await this.renderCMSPage(slug);
                 if (!ok) {
@@ -1171,6 +1518,7 @@ class TroughApp {
                     setSubmit('Create Account', false);
                     // Fetch and display password requirements
                     this.fetchPasswordRequirements();
+                    this.loadRegisterFormToken();
                 }
                 this.hideAuthError();
                 // Re-ensure eye toggles remain in place after DOM flips
@@ -1262,6 +1610,11 @@ class TroughApp {
             this.showAuthError('Please agree to the ToS and Privacy');
             return;
         }
+        const age_confirmed = !!document.getElementById('register-age')?.checked;
+        if (window.__MIN_AGE__ && !age_confirmed) {
+            this.showAuthError(`Please confirm you are at least ${window.__MIN_AGE__}`);
+            return;
+        }
 
         if (password !== confirm) {
             this.showAuthError('Passwords do not match');
@@ -1299,21 +1652,36 @@ class TroughApp {
         } catch {}
 
         try {
-            const response = await fetch('/api/register' + (invite ? ('?invite=' + encodeURIComponent(invite)) : ''), {
+            if (!this._registerFormToken) await this.loadRegisterFormToken();
+            const website = document.getElementById('register-website')?.value || '';
+            const form_token = this._registerFormToken || '';
+            const accept_terms = !!document.getElementById('register-tos')?.checked;
+            const send = (extra) => fetch('/api/register' + (invite ? ('?invite=' + encodeURIComponent(invite)) : ''), {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 credentials: 'include',
-                body: JSON.stringify({ username, email, password, invite })
+                body: JSON.stringify({ username, email, password, invite, website, form_token, accept_terms, age_confirmed, ...extra })
             });
+            let response = await send({});
+            let data = await response.json().catch(() => ({}));
+            if (response.status === 403 && data && data.challenge_required) {
+                let mount = document.getElementById('auth-challenge');
+                if (!mount) { mount = document.createElement('div'); mount.id = 'auth-challenge'; mount.style.margin = '8px 0'; document.getElementById('register-form')?.appendChild(mount); }
+                response = await send(await this.solveAuthChallenge(data.challenge, mount));
+                data = await response.json().catch(() => ({}));
+                mount.remove();
+            }
 
             if (response.status === 401) {
                 localStorage.removeItem('token');
                 localStorage.removeItem('user');
             }
 
-            const data = await response.json().catch(() => ({}));
-
-            if (response.ok) {
+            if (response.status === 202 && data && data.pending_approval) {
+                this.closeAuthModal();
+                this._pendingInvite = '';
+                this.showNotification('Thanks for signing up! Your account is waiting for approval; we will email you once it is reviewed.', 'success');
+            } else if (response.ok) {
                 try { if (data && data.token) localStorage.setItem('token', data.token); } catch {}
                 localStorage.setItem('user', JSON.stringify(data.user));
                 this.currentUser = data.user;
@@ -1341,6 +1709,8 @@ class TroughApp {
                 } else {
                     this.showAuthError(err || 'Registration failed');
                 }
+                // A stale or missing form token fails the timing check; fetch a new one for the retry
+                if (response.status === 403 && /wait a moment/i.test(err)) this.loadRegisterFormToken();
             }
         } catch (error) {
             console.error('Registration error:', error);
@@ -1385,6 +1755,14 @@ class TroughApp {
         errorDiv.style.display = 'none';
     }
 
+    // The registration timing check needs a token issued when the form was opened
+    async loadRegisterFormToken() {
+        try {
+            const r = await fetch('/api/auth/form-token', { credentials: 'include' });
+            if (r.ok) this._registerFormToken = (await r.json()).token || '';
+        } catch {}
+    }
+
     async fetchPasswordRequirements() {
         try {
             const r = await fetch('/api/password-requirements');
@@ -1399,6 +1777,7 @@ class TroughApp {
             if (reqs.require_lower) html += '<li>At least one lowercase letter</li>';
             if (reqs.require_number) html += '<li>At least one number</li>';
             if (reqs.require_special) html += `<li>At least one special character (${reqs.allowed_special})</li>`;
+            if (reqs.not_breached) html += '<li>Not found in known data breaches</li>';
             html += '</ul>';
             pwReqsDiv.innerHTML = html;
         } catch (e) {
@@ -1457,6 +1836,10 @@ class TroughApp {
             if (!u.ok) throw new Error('User not found');
             user = await u.json();
             imgs = i.ok ? await i.json() : { images: [] };
+            // Old handles resolve to the renamed account; show its current address
+            if (user.username && user.username !== username && location.pathname.startsWith('/@')) {
+                history.replaceState(history.state, '', `/@${encodeURIComponent(user.username)}${location.search}`);
+            }
         } catch (e) {
             // Styled in-app error view
             this.gallery.innerHTML = '';
@@ -1485,6 +1868,15 @@ class TroughApp {
             caption: typeof img.caption === 'string' ? img.caption : (img.caption || '')
         }));
 
+        const theme = user.profile_theme || null;
+        this.applyProfileTheme(theme);
+        if (theme && theme.header_url) {
+            const cover = document.createElement('section');
+            cover.className = 'mono-col profile-cover';
+            try { cover.style.backgroundImage = `url('${encodeURI(String(theme.header_url))}')`; } catch {}
+            this.profileTop.appendChild(cover);
+        }
+
         // Header (no backdrop)
         const header = document.createElement('section');
         header.className = 'mono-col';
@@ -1495,7 +1887,10 @@ class TroughApp {
         header.innerHTML = `
           <div class="profile-left" style="display:flex;gap:12px;align-items:center;min-width:0;flex:1">
             ${avatar}
-            <div class="profile-username" style="font-weight:700;font-size:1.1rem;font-family:var(--font-mono);white-space:nowrap;overflow:hidden;text-overflow:ellipsis;max-width:100%">@${this.escapeHTML(String(user.username))}</div>
+            <div style="min-width:0">
+              <div class="profile-username" style="font-weight:700;font-size:1.1rem;font-family:var(--font-mono);white-space:nowrap;overflow:hidden;text-overflow:ellipsis;max-width:100%">@${this.escapeHTML(String(user.username))}${this.verifiedBadge(user.is_verified, user.verified_reason)}</div>
+              ${user.stats ? `<div class="profile-stats meta" style="opacity:.7;font-family:var(--font-mono);font-size:12px">${Number(user.stats.images) || 0} posts · collected ${Number(user.stats.collected) || 0}×</div>` : ''}
+            </div>
           </div>
           ${isOwner ? `
           <div class="profile-actions" style="display:flex;gap:8px;align-items:center;flex-shrink:0">
@@ -1512,7 +1907,10 @@ class TroughApp {
                 <button id="menu-signout" class="profile-item link-btn" style="display:block;width:100%;text-align:left;padding:8px 10px;color:#ff6666">Sign out</button>
               </div>
             </div>
-          </div>` : ''}
+          </div>` : (this.currentUser ? `
+          <div class="profile-actions" style="display:flex;gap:8px;align-items:center;flex-shrink:0">
+            <button id="profile-block" class="link-btn" style="display:none">Block</button>
+          </div>` : '')}
         `;
         // Set avatar background via style API to avoid inline URL injection
         const avatarEl = header.querySelector('.avatar-preview');
@@ -1520,6 +1918,8 @@ class TroughApp {
             try { avatarEl.style.backgroundImage = `url('${encodeURI(safeAvatar)}')`; } catch {}
         }
         this.profileTop.appendChild(header);
+        const blockBtn = header.querySelector('#profile-block');
+        if (blockBtn) this.wireBlockButton(blockBtn, user);
         // If owner and unverified, show banner with resend action
         if (isOwner && this.currentUser && this.currentUser.email_verified === false) {
             const banner = document.createElement('section');
@@ -1651,7 +2051,7 @@ class TroughApp {
                 for (const f of files) {
                     const uploaded = await app.uploadImage(f, {});
                     if (uploaded) {
-                        app.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', is_nsfw: false, filename: uploaded.filename }, null);
+                        app.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', rating: uploaded.rating || 'safe', filename: uploaded.filename, visibility: uploaded.visibility, license: uploaded.license }, null);
                     }
                 }
             };
@@ -1715,8 +2115,11 @@ class TroughApp {
           <div class="tab-group" style="margin-bottom:8px">
             <button id="tab-posts" class="tab-btn" aria-pressed="true">User Images</button>
             <button id="tab-collections" class="tab-btn" aria-pressed="false">Collected</button>
-          </div>`;
+          </div>
+          <div id="profile-boards" class="tab-group" style="margin-bottom:8px;flex-wrap:wrap;display:none"></div>`;
         this.profileTop.appendChild(tabs);
+        this.profileBoard = null;
+        const boardsRow = tabs.querySelector('#profile-boards');
 
         const loadPosts = async () => {
             this.profileTab = 'posts';
@@ -1739,6 +2142,25 @@ class TroughApp {
             if (!suppressInfinite) this.setupInfiniteScroll();
         };
 
+        // Board chips narrow the Collected tab to one board; "All" lists every collected image
+        const renderBoards = async () => {
+            if (!boardsRow) return;
+            try {
+                const resp = await fetch(`/api/users/${encodeURIComponent(username)}/boards`, { credentials: 'include' });
+                const boards = resp.ok ? ((await resp.json()).boards || []) : [];
+                if (boards.length < 2) { boardsRow.style.display = 'none'; return; }
+                const chip = (id, label) => `<button class="tab-btn" data-board="${this.escapeHTML(id)}" aria-pressed="${(this.profileBoard || '') === id}">${this.escapeHTML(label)}</button>`;
+                boardsRow.innerHTML = chip('', 'All') + boards.map(b => chip(b.id, `${b.name}${b.is_public ? '' : ' 🔒'} · ${b.item_count}`)).join('');
+                boardsRow.style.display = 'flex';
+                boardsRow.querySelectorAll('[data-board]').forEach(btn => {
+                    btn.onclick = async () => {
+                        this.profileBoard = btn.dataset.board || null;
+                        await loadCollections();
+                    };
+                });
+            } catch { boardsRow.style.display = 'none'; }
+        };
+
         const loadCollections = async () => {
             this.profileTab = 'collections';
             this.gallery.innerHTML = '';
@@ -1747,8 +2169,11 @@ class TroughApp {
             this.unrendered = [];
             this.page = 1;
             this.hasMore = true;
+            await renderBoards();
             try {
-                const resp = await fetch(`/api/users/${encodeURIComponent(username)}/collections?page=1`);
+                const resp = await fetch(this.profileBoard
+                    ? `/api/boards/${encodeURIComponent(this.profileBoard)}?page=1`
+                    : `/api/users/${encodeURIComponent(username)}/collections?page=1`, { credentials: 'include' });
                 if (!resp.ok) { this.showNotification('Failed to load collections','error'); return; }
                 const data = await resp.json();
                 const firstPage = data.images || [];
@@ -1770,6 +2195,7 @@ class TroughApp {
             postsBtn.onclick = async () => {
                 postsBtn.setAttribute('aria-pressed','true');
                 colBtn.setAttribute('aria-pressed','false');
+                if (boardsRow) boardsRow.style.display = 'none';
                 await loadPosts();
                 try { this.persistListState(); } catch {}
             };
@@ -1829,7 +2255,7 @@ class TroughApp {
             const first = (imgs.images||[])[0];
             if (first && first.filename) {
                 const fn = String(first.filename);
-                imgAbs = (/^https?:\/\//i.test(fn)) ? fn : (location.origin + '/uploads/' + fn);
+                imgAbs = (/^https?:\/\//i.test(fn)) ? fn : (location.origin + (fn.startsWith('/uploads/') ? fn : '/uploads/' + fn));
             } else {
                 try { const ss = JSON.parse(localStorage.getItem('site_settings')||'null'); const si = String(ss?.social_image_url||''); if (si) { imgAbs = si.startsWith('http') ? si : (si.startsWith('/') ? (location.origin + si) : si); } } catch {}
             }
@@ -1854,6 +2280,12 @@ class TroughApp {
         } catch {}
     }
 
+    // The home feed narrowed to one model, from /?model=
+    feedModelQuery() {
+        const model = new URLSearchParams(location.search).get('model');
+        return model ? `&model=${encodeURIComponent(model)}` : '';
+    }
+
     // Gallery/loading functions
     async loadImages() {
         // Load home feed or profile pages depending on route
@@ -1864,18 +2296,23 @@ class TroughApp {
         try {
             let resp = null;
             if (this.routeMode === 'home') {
-                resp = await fetch(`/api/feed?page=${this.page}`, { credentials: 'include' });
+                if (this.page === 1) this.renderFeaturedStrip();
+                resp = await fetch(`/api/feed?page=${this.page}${this.feedModelQuery()}`, { credentials: 'include' });
             } else {
                 // Profiles: choose endpoint based on active tab
                 const uname = this.profileUsername || decodeURIComponent(location.pathname.slice(2));
                 const tab = this.profileTab || 'posts';
                 const url = (tab === 'collections')
-                    ? `/api/users/${encodeURIComponent(uname)}/collections?page=${this.page}`
+                    ? (this.profileBoard
+                        ? `/api/boards/${encodeURIComponent(this.profileBoard)}?page=${this.page}`
+                        : `/api/users/${encodeURIComponent(uname)}/collections?page=${this.page}`)
                     : `/api/users/${encodeURIComponent(uname)}/images?page=${this.page}`;
                 resp = await fetch(url, { credentials: 'include' });
             }
             if (resp.ok) {
                 const data = await resp.json();
+                // Remember the newest image so "new images" can fetch only what came after it
+                if (this.routeMode === 'home' && this.page === 1) this._feedSince = data.since_cursor || null;
                 if (data.images && data.images.length > 0) {
 					// Append to unrendered queue; do not immediately create DOM nodes for all
 					this.enqueueUnrendered(data.images);
@@ -1898,6 +2335,24 @@ class TroughApp {
         }
     }
 
+    // Staff picks above the home feed; nothing is shown when there are none
+    async renderFeaturedStrip() {
+        if (!this.profileTop || document.getElementById('featured-strip')) return;
+        const epoch = this.renderEpoch;
+        let images = [];
+        try {
+            const r = await fetch('/api/featured?limit=12', { credentials: 'include' });
+            if (r.ok) images = ((await r.json()).images || []).filter(i => i.display !== 'hide');
+        } catch {}
+        if (!images.length || epoch !== this.renderEpoch || this.routeMode !== 'home' || document.getElementById('featured-strip')) return;
+        const strip = document.createElement('section');
+        strip.id = 'featured-strip';
+        strip.style.cssText = 'display:grid;gap:8px';
+        strip.innerHTML = `<div style="font-weight:800;letter-spacing:-0.02em">Featured</div>
+            <div style="display:flex;gap:10px;overflow-x:auto;padding-bottom:4px">${images.map(i => `<a href="/i/${encodeURIComponent(i.id)}" title="${this.escapeHTML(String(i.featured_note || i.original_name || ''))}" style="flex:0 0 auto"><img src="${this.stillURL(i)}" alt="${this.escapeHTML(String(i.original_name || ''))}" loading="lazy" style="height:120px;width:auto;border-radius:8px;border:1px solid var(--border);${i.display === 'blur' ? 'filter:blur(16px)' : ''}"/></a>`).join('')}</div>`;
+        this.profileTop.appendChild(strip);
+    }
+
     renderDemoImages() {
         const epoch = this.renderEpoch;
         const demoImages = [
@@ -1929,7 +2384,14 @@ class TroughApp {
 
     enqueueUnrendered(images) {
         if (!Array.isArray(images) || images.length === 0) return;
-        for (const it of images) this.unrendered.push(it);
+        for (const it of images) {
+            // Signed-in listings say whether we collected each image; trust that over the seeded set
+            if (typeof it.collected === 'boolean') {
+                if (!this._myCollectedSet) this._myCollectedSet = new Set();
+                if (it.collected) this._myCollectedSet.add(String(it.id)); else this._myCollectedSet.delete(String(it.id));
+            }
+            this.unrendered.push(it);
+        }
     }
 
     maybeRevealCards(maxToReveal) {
@@ -2019,7 +2481,7 @@ class TroughApp {
                 </div>`;
         } else {
             img = document.createElement('img');
-            const imgURL = this.getImageURL(image.filename);
+            const imgURL = this.stillURL(image);
             // Defer actual src assignment to our lazy loader
             img.dataset.src = imgURL;
             img.alt = image.original_name || image.title || '';
@@ -2031,10 +2493,12 @@ class TroughApp {
                 try { img.style.aspectRatio = `${image.width} / ${image.height}`; } catch {}
             }
             img.style.background = 'var(--surface)';
-            // NSFW blur logic based on current user preference
+            // Listings say how the viewer's content preferences display each image; fall back to
+            // the NSFW preference for images from elsewhere
             const nsfwPref = (this.currentUser?.nsfw_pref || (this.currentUser?.show_nsfw ? 'show' : 'hide'));
-            const shouldBlur = image.is_nsfw && nsfwPref === 'blur';
-            const shouldHide = image.is_nsfw && (!this.currentUser || nsfwPref === 'hide');
+            const display = image.display || (image.is_nsfw ? (this.currentUser ? nsfwPref : 'hide') : 'show');
+            const shouldBlur = display === 'blur';
+            const shouldHide = display === 'hide';
             if (shouldHide) { 
                 // Don't return early - we still need to append the card but without the image
                 card.innerHTML = `
@@ -2057,6 +2521,26 @@ class TroughApp {
             } else {
                 card.appendChild(img);
             }
+            // Owners see which of their images are hidden from others
+            if (image.visibility === 'unlisted' || image.visibility === 'private') {
+                const badge = document.createElement('span');
+                badge.className = 'visibility-badge';
+                badge.textContent = image.visibility;
+                card.appendChild(badge);
+            }
+            if (image.media_type === 'video') {
+                const vid = document.createElement('span');
+                vid.className = 'visibility-badge anim-badge';
+                vid.textContent = 'video';
+                if (image.duration_ms) vid.title = `${(image.duration_ms / 1000).toFixed(1)}s`;
+                card.appendChild(vid);
+            } else if (image.frame_count > 1) {
+                const anim = document.createElement('span');
+                anim.className = 'visibility-badge anim-badge';
+                anim.textContent = String(image.filename || '').split('?')[0].toLowerCase().endsWith('.gif') ? 'gif' : 'anim';
+                anim.title = `${image.frame_count} frames` + (image.duration_ms ? `, ${(image.duration_ms / 1000).toFixed(1)}s` : '');
+                card.appendChild(anim);
+            }
 
             const meta = document.createElement('div');
             meta.className = 'image-meta';
@@ -2077,7 +2561,7 @@ class TroughApp {
                 <div style="display:flex;align-items:center;justify-content:space-between;gap:12px">
                   <div style="min-width:0">
                     <div class="image-title" style="white-space:nowrap;overflow:hidden;text-overflow:ellipsis"><a href="/i/${encodeURIComponent(image.id)}" class="image-link" style="color:inherit;text-decoration:none">${this.escapeHTML(String((image.title || image.original_name || 'Untitled')).trim())}</a></div>
-                    <div class="image-author" style="font-family:var(--font-mono)"><a href="/@${encodeURIComponent(username)}" style="color:inherit;text-decoration:none">@${this.escapeHTML(String(username))}</a></div>
+                    <div class="image-author" style="font-family:var(--font-mono)"><a href="/@${encodeURIComponent(username)}" style="color:inherit;text-decoration:none">@${this.escapeHTML(String(username))}</a>${this.verifiedBadge(image.user_verified)}</div>
                   </div>
                   <div style="display:flex;gap:6px;align-items:center">${collectBtn}${actions}</div>
                 </div>
@@ -2218,21 +2702,60 @@ class TroughApp {
         }
     }
 
+    // Selectable licenses, fetched once per session
+    async getLicenses() {
+        if (!this._licenses) {
+            try { const r = await fetch('/api/licenses'); const d = r.ok ? await r.json() : {}; this._licenses = Array.isArray(d.licenses) ? d.licenses : []; } catch { return []; }
+        }
+        return this._licenses;
+    }
+
     async openEditModal(image, cardNode) {
-        let filename = image.filename;
+        let filename = image.filename ? this.stillURL(image) : '';
         if (!filename && image.id) {
-            try { const r = await fetch(`/api/images/${image.id}`); if (r.ok) { const d = await r.json(); filename = d.filename; } } catch {}
+            try { const r = await fetch(`/api/images/${image.id}`); if (r.ok) { const d = await r.json(); filename = this.stillURL(d); image = { ...image, remix_of: d.remix_of, remix_of_url: d.remix_of_url, links: d.links }; } } catch {}
+        } else if (image.id && image.remix_of == null && image.remix_of_url == null && image.links == null) {
+            // Feed cards don't carry the remix source or links
+            try { const r = await fetch(`/api/images/${image.id}`, { credentials: 'include' }); if (r.ok) { const d = await r.json(); image = { ...image, remix_of: d.remix_of, remix_of_url: d.remix_of_url, links: d.links || [] }; } } catch {}
         }
+        const links = Array.isArray(image.links) ? image.links : [];
+        const linksSource = JSON.stringify(links.map(l => ({ label: l.label || '', url: l.url })));
+        const remixSource = image.remix_of || image.remix_of_url || '';
         const overlay = document.createElement('div');
         overlay.style.cssText = 'position:fixed;inset:0;z-index:2700;background:rgba(0,0,0,0.6);backdrop-filter:blur(8px);display:flex;align-items:center;justify-content:center;padding:24px;';
         const panel = document.createElement('div');
         panel.style.cssText = 'max-width:980px;width:100%;max-height:90vh;overflow:auto;background:var(--surface-elevated);border:1px solid var(--border);border-radius:12px;padding:16px;color:var(--text-primary)';
         panel.innerHTML = `
-            ${filename ? `<div style="display:flex;justify-content:center;"><img src="${this.getImageURL(filename)}" alt="" style="max-height:60vh;width:auto;border-radius:10px;border:1px solid var(--border);margin-bottom:12px"/></div>` : ''}
+            ${filename ? `<div style="display:flex;justify-content:center;"><img src="${filename}" alt="" style="max-height:60vh;width:auto;border-radius:10px;border:1px solid var(--border);margin-bottom:12px"/></div>` : ''}
             <div style="position:sticky;bottom:0;background:var(--surface-elevated);border-top:1px solid var(--border);padding-top:12px;display:grid;gap:12px">
               <input id="e-title" placeholder="Title" value="${this.escapeHTML(String(image.title || image.original_name || ''))}" style="width:100%;padding:10px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)"/>
               <textarea id="e-caption" placeholder="Caption" rows="3" maxlength="2000" style="width:100%;padding:10px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)">${this.escapeHTML(String(image.caption||''))}</textarea>
-              <label style="display:flex;gap:8px;align-items:center;color:var(--text-secondary)"><input type="checkbox" id="e-nsfw" ${image.is_nsfw ? 'checked' : ''}/> NSFW</label>
+              <label style="display:flex;gap:8px;align-items:center;color:var(--text-secondary)">Rating
+                <select id="e-rating" style="padding:6px 8px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)">
+                  <option value="safe">Safe</option>
+                  <option value="suggestive">Suggestive</option>
+                  <option value="mature">Mature (NSFW)</option>
+                  <option value="explicit">Explicit (NSFW)</option>
+                </select>
+              </label>
+              <label style="display:flex;gap:8px;align-items:center;color:var(--text-secondary)">Visibility
+                <select id="e-visibility" style="padding:6px 8px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)">
+                  <option value="public">Public</option>
+                  <option value="unlisted">Unlisted (link only)</option>
+                  <option value="private">Private (only me)</option>
+                </select>
+              </label>
+              <label style="display:flex;gap:8px;align-items:center;color:var(--text-secondary)">License
+                <select id="e-license" style="padding:6px 8px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)">
+                  <option value="">Not specified</option>
+                  ${(await this.getLicenses()).map(l => `<option value="${this.escapeHTML(String(l.id))}">${this.escapeHTML(String(l.name))}</option>`).join('')}
+                </select>
+              </label>
+              <input id="e-remix" placeholder="Remixed from (image link or id, or an external URL)" value="${this.escapeHTML(String(remixSource))}" maxlength="500" style="width:100%;padding:10px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)"/>
+              <div style="display:grid;gap:6px">
+                <span style="color:var(--text-secondary)">Links (up to 3, e.g. a workflow file or generator share page)</span>
+                ${[0, 1, 2].map(i => `<div style="display:flex;gap:6px"><input data-link-label="${i}" placeholder="Label" maxlength="60" value="${this.escapeHTML(String(links[i]?.label || ''))}" style="flex:0 0 30%;padding:8px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)"/><input data-link-url="${i}" placeholder="https://" maxlength="500" value="${this.escapeHTML(String(links[i]?.url || ''))}" style="flex:1;padding:8px;border:1px solid var(--border);border-radius:8px;background:var(--surface);color:var(--text-primary)"/></div>`).join('')}
+              </div>
               <div style="display:flex;gap:8px;justify-content:flex-end">
                 <button id="e-cancel" class="nav-btn">Cancel</button>
                 <button id="e-save" class="nav-btn">Save</button>
@@ -2241,11 +2764,18 @@ class TroughApp {
         overlay.appendChild(panel);
         overlay.addEventListener('click', (e) => { if (e.target === overlay) overlay.remove(); });
         document.body.appendChild(overlay);
+        panel.querySelector('#e-rating').value = image.rating || (image.is_nsfw ? 'explicit' : 'safe');
+        panel.querySelector('#e-visibility').value = image.visibility || 'public';
+        panel.querySelector('#e-license').value = image.license || '';
         panel.querySelector('#e-cancel').onclick = () => overlay.remove();
         panel.querySelector('#e-save').onclick = async () => {
-            const body = { title: panel.querySelector('#e-title').value, caption: panel.querySelector('#e-caption').value, is_nsfw: panel.querySelector('#e-nsfw').checked };
+            const body = { title: panel.querySelector('#e-title').value, caption: panel.querySelector('#e-caption').value, rating: panel.querySelector('#e-rating').value, visibility: panel.querySelector('#e-visibility').value, license: panel.querySelector('#e-license').value };
+            const remix = panel.querySelector('#e-remix').value.trim();
+            if (remix !== remixSource) body.remix_of = remix;
+            const editedLinks = [0, 1, 2].map(i => ({ label: panel.querySelector(`[data-link-label="${i}"]`).value.trim(), url: panel.querySelector(`[data-link-url="${i}"]`).value.trim() })).filter(l => l.url);
+            if (JSON.stringify(editedLinks) !== linksSource) body.links = editedLinks;
             const resp = await this.fetchWithCSRF(`/api/images/${image.id}`, { method:'PATCH', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify(body) });
-            if (resp.ok) { overlay.remove(); this.showNotification('Saved'); location.reload(); } else { this.showNotification('Save failed', 'error'); }
+            if (resp.ok) { overlay.remove(); this.showNotification('Saved'); location.reload(); } else { const e = await resp.json().catch(() => ({})); this.showNotification(e.error || 'Save failed', 'error'); }
         };
     }
 
@@ -2258,7 +2788,7 @@ class TroughApp {
         if (!lightboxImg) return;
 
         if (image.filename) {
-            lightboxImg.src = this.getImageURL(image.filename);
+            lightboxImg.src = this.stillURL(image);
             lightboxImg.alt = image.original_name || image.title || '';
         }
         const username = image.username || image.author || 'Unknown';
@@ -2367,7 +2897,7 @@ class TroughApp {
             for (const file of files) {
                 const uploaded = await this.uploadImage(file, {});
                 if (uploaded) {
-                    this.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', is_nsfw: false, filename: uploaded.filename }, null);
+                    this.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', rating: uploaded.rating || 'safe', filename: uploaded.filename, visibility: uploaded.visibility, license: uploaded.license }, null);
                 }
             }
         };
@@ -2385,7 +2915,7 @@ class TroughApp {
             for (const file of files) {
                 const uploaded = await this.uploadImage(file, {});
                 if (uploaded) {
-                    this.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', is_nsfw: false, filename: uploaded.filename }, null);
+                    this.openEditModal({ id: uploaded.id, original_name: uploaded.original_name, caption: uploaded.caption || '', rating: uploaded.rating || 'safe', filename: uploaded.filename, visibility: uploaded.visibility, license: uploaded.license }, null);
                 }
             }
             // Clear the input to allow selecting the same file again
@@ -2439,11 +2969,304 @@ class TroughApp {
         });
     }
 
+    async loadModerationQueue() {
+        const listEl = document.getElementById('queue-list');
+        if (!listEl) return;
+        const r = await fetch('/api/moderation/queue?limit=50', { credentials: 'include' });
+        if (!r.ok) { listEl.innerHTML = '<small style="opacity:.7">Queue unavailable</small>'; return; }
+        const d = await r.json();
+        const images = Array.isArray(d.images) ? d.images : [];
+        listEl.innerHTML = images.length ? '' : '<small style="opacity:.7">Nothing waiting for review</small>';
+        images.forEach(img => {
+            const row = document.createElement('div');
+            row.className = 'user-row';
+            const title = img.original_name ? String(img.original_name) : 'Untitled';
+            row.innerHTML = `<div class="left" style="display:flex;gap:10px;align-items:center;min-width:0"><img alt="" loading="lazy" style="width:64px;height:64px;object-fit:cover;border-radius:8px;border:1px solid var(--border)"/><div style="min-width:0"><div class="handle" style="overflow-wrap:anywhere"><a href="/i/${encodeURIComponent(img.id)}" target="_blank" rel="noopener">${this.escapeHTML(title)}</a></div><div class="id">@${this.escapeHTML(String(img.username || ''))} · ${this.escapeHTML(new Date(img.created_at).toLocaleString())}${img.is_nsfw ? ' · NSFW' : ''}</div></div></div>`;
+            const thumb = row.querySelector('img');
+            thumb.src = this.stillURL(img);
+            const right = document.createElement('div'); right.className = 'actions';
+            const mk = (label, fn) => { const b = document.createElement('button'); b.className = 'nav-btn'; b.textContent = label; b.onclick = fn; right.appendChild(b); return b; };
+            mk('Approve', async () => { const rr = await this.fetchWithCSRF(`/api/moderation/queue/${img.id}/approve`, { method: 'POST', credentials: 'include' }); if (rr.status === 204) { this.showNotification('Approved'); row.remove(); } else this.showNotification('Failed', 'error'); });
+            const rej = mk('Reject', async () => {
+                const ok = await this.showConfirm('Reject and delete this upload?'); if (!ok) return;
+                const reason = (window.prompt('Reason shown to the uploader (optional)') || '').trim();
+                const rr = await this.fetchWithCSRF(`/api/moderation/queue/${img.id}/reject`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ reason }) });
+                if (rr.status === 204) { this.showNotification('Rejected'); row.remove(); } else this.showNotification('Failed', 'error');
+            });
+            rej.style.background = 'var(--color-danger)'; rej.style.color = '#fff';
+            row.appendChild(right); listEl.appendChild(row);
+        });
+    }
+
+    async loadAdminStats() {
+        const rangeEl = document.getElementById('stats-range');
+        const totalsEl = document.getElementById('stats-totals');
+        const seriesEl = document.getElementById('stats-series');
+        const provEl = document.getElementById('stats-providers');
+        const upEl = document.getElementById('stats-uploaders');
+        if (!rangeEl || !seriesEl) return;
+        if (!rangeEl.dataset.bound) { rangeEl.dataset.bound = '1'; rangeEl.onchange = () => this.loadAdminStats(); }
+        const r = await fetch(`/api/admin/stats?range=${encodeURIComponent(rangeEl.value)}`, { credentials: 'include' });
+        if (!r.ok) { seriesEl.innerHTML = '<small style="opacity:.7">Stats unavailable</small>'; return; }
+        const d = await r.json();
+        const mb = (b) => `${(Number(b || 0) / (1024 * 1024)).toFixed(1)} MB`;
+        const t = d.totals || {};
+        totalsEl.textContent = `${t.users || 0} users · ${t.images || 0} images · ${t.collections || 0} collections · ${mb(t.storage_bytes)} stored`;
+        const series = Array.isArray(d.series) ? d.series : [];
+        const max = Math.max(1, ...series.map(p => p.uploads));
+        const fmt = (iso) => { const dt = new Date(iso); return d.bucket === 'hour' ? dt.toLocaleString([], { month: 'short', day: 'numeric', hour: '2-digit' }) : dt.toLocaleDateString(); };
+        seriesEl.innerHTML = series.map(p => `<div style="display:flex;gap:8px;align-items:center"><small style="width:120px;opacity:.7">${this.escapeHTML(fmt(p.bucket))}</small><div style="flex:1;min-width:0"><div style="height:8px;border-radius:4px;background:var(--color-accent, #888);width:${Math.round(p.uploads / max * 100)}%"></div></div><small style="min-width:240px;opacity:.8">${p.uploads} uploads · ${p.signups} signups${p.blocked_signups ? ` (${p.blocked_signups} blocked)` : ''} · ${p.collections} collects · ${mb(p.storage_bytes)}</small></div>`).join('') || '<small style="opacity:.7">No data</small>';
+        const provs = Array.isArray(d.ai_providers) ? d.ai_providers : [];
+        provEl.innerHTML = provs.map(p => `<small>${this.escapeHTML(String(p.provider))} · ${p.count}</small>`).join('') || '<small style="opacity:.7">No uploads</small>';
+        const ups = Array.isArray(d.top_uploaders) ? d.top_uploaders : [];
+        upEl.innerHTML = ups.map(u => `<small>@${this.escapeHTML(String(u.username))} · ${u.uploads} uploads · ${mb(u.bytes)}</small>`).join('') || '<small style="opacity:.7">No uploads</small>';
+        const blockedEl = document.getElementById('stats-blocked');
+        const blocked = Array.isArray(d.blocked_signups) ? d.blocked_signups : [];
+        if (blockedEl) blockedEl.innerHTML = blocked.map(b => `<small>${this.escapeHTML(String(b.reason))} · ${b.count}</small>`).join('') || '<small style="opacity:.7">None</small>';
+    }
+
+    async loadAdminWebhooks() {
+        const listEl = document.getElementById('wh-list');
+        const evEl = document.getElementById('wh-events');
+        const delEl = document.getElementById('wh-deliveries');
+        if (!listEl || !evEl || !delEl) return;
+        const r = await fetch('/api/admin/webhooks', { credentials: 'include' });
+        if (!r.ok) { listEl.innerHTML = '<small style="opacity:.7">Webhooks unavailable</small>'; return; }
+        const d = await r.json();
+        const events = Array.isArray(d.events) ? d.events : [];
+        if (!evEl.childElementCount) {
+            evEl.innerHTML = ['*'].concat(events).map(e => `<label style="display:flex;gap:6px;align-items:center"><input type="checkbox" value="${this.escapeHTML(e)}" ${e === '*' ? 'checked' : ''}> ${e === '*' ? 'All events' : this.escapeHTML(e)}</label>`).join('');
+        }
+        const hooks = Array.isArray(d.webhooks) ? d.webhooks : [];
+        listEl.innerHTML = hooks.length ? '' : '<small style="opacity:.7">No webhooks yet</small>';
+        hooks.forEach(w => {
+            const row = document.createElement('div');
+            row.className = 'user-row';
+            row.innerHTML = `<div class="left"><div class="handle" style="word-break:break-all">${this.escapeHTML(String(w.url))}</div><div class="id">${this.escapeHTML(String(w.events))}${w.description ? ' · ' + this.escapeHTML(String(w.description)) : ''}</div></div>`;
+            const right = document.createElement('div'); right.className = 'actions';
+            const mk = (label, fn) => { const b = document.createElement('button'); b.className = 'nav-btn'; b.textContent = label; b.onclick = fn; right.appendChild(b); return b; };
+            mk(w.enabled ? 'Disable' : 'Enable', async () => { const rr = await this.fetchWithCSRF(`/api/admin/webhooks/${w.id}`, { method: 'PATCH', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ enabled: !w.enabled }) }); if (rr.ok) this.loadAdminWebhooks(); else this.showNotification('Failed', 'error'); });
+            mk('Ping', async () => { const rr = await this.fetchWithCSRF(`/api/admin/webhooks/${w.id}/ping`, { method: 'POST', credentials: 'include' }); if (rr.status === 202) { this.showNotification('Ping queued'); setTimeout(() => this.loadAdminWebhooks(), 2000); } else this.showNotification('Failed', 'error'); });
+            mk('Rotate secret', async () => { const ok = await this.showConfirm('Rotate the signing secret? The receiver must be updated.'); if (!ok) return; const rr = await this.fetchWithCSRF(`/api/admin/webhooks/${w.id}`, { method: 'PATCH', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ rotate_secret: true }) }); if (rr.ok) { const x = await rr.json(); const sEl = document.getElementById('wh-secret'); sEl.style.display = 'block'; sEl.textContent = `New secret (shown once): ${x.secret}`; } else this.showNotification('Failed', 'error'); });
+            const del = mk('Delete', async () => { const ok = await this.showConfirm('Delete webhook?'); if (!ok) return; const rr = await this.fetchWithCSRF(`/api/admin/webhooks/${w.id}`, { method: 'DELETE', credentials: 'include' }); if (rr.status === 204) this.loadAdminWebhooks(); else this.showNotification('Failed', 'error'); });
+            del.style.background = 'var(--color-danger)'; del.style.color = '#fff';
+            row.appendChild(right); listEl.appendChild(row);
+        });
+        const dr = await fetch('/api/admin/webhooks/deliveries?limit=25', { credentials: 'include' });
+        const dd = dr.ok ? await dr.json() : { deliveries: [] };
+        const deliveries = Array.isArray(dd.deliveries) ? dd.deliveries : [];
+        delEl.innerHTML = deliveries.length ? '' : '<small style="opacity:.7">No deliveries yet</small>';
+        deliveries.forEach(x => {
+            const row = document.createElement('div');
+            row.style.cssText = 'display:flex;gap:8px;align-items:center;flex-wrap:wrap';
+            const code = x.response_status ? ` · HTTP ${x.response_status}` : '';
+            row.innerHTML = `<span style="flex:1;min-width:0;overflow-wrap:anywhere">${this.escapeHTML(String(x.event))} · ${this.escapeHTML(String(x.status))}${code} · ${x.attempts} attempt(s)${x.last_error ? ' · ' + this.escapeHTML(String(x.last_error)) : ''}</span><small style="opacity:.7">${this.escapeHTML(new Date(x.created_at).toLocaleString())}</small>`;
+            if (x.status === 'dead') {
+                const b = document.createElement('button'); b.className = 'link-btn'; b.textContent = 'Retry';
+                b.onclick = async () => { const rr = await this.fetchWithCSRF(`/api/admin/webhooks/deliveries/${x.id}/retry`, { method: 'POST', credentials: 'include' }); if (rr.status === 204) this.loadAdminWebhooks(); else this.showNotification('Failed', 'error'); };
+                row.appendChild(b);
+            }
+            delEl.appendChild(row);
+        });
+        const createBtn = document.getElementById('btn-wh-create');
+        if (createBtn && !createBtn.dataset.bound) {
+            createBtn.dataset.bound = '1';
+            createBtn.onclick = async () => {
+                const url = (document.getElementById('wh-url').value || '').trim();
+                const description = (document.getElementById('wh-desc').value || '').trim();
+                const selected = Array.from(evEl.querySelectorAll('input:checked')).map(i => i.value);
+                const rr = await this.fetchWithCSRF('/api/admin/webhooks', { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ url, description, events: selected.includes('*') ? ['*'] : selected }) });
+                const x = await rr.json().catch(() => ({}));
+                if (!rr.ok) { this.showNotification(x.error || 'Failed', 'error'); return; }
+                document.getElementById('wh-url').value = ''; document.getElementById('wh-desc').value = '';
+                const sEl = document.getElementById('wh-secret'); sEl.style.display = 'block'; sEl.textContent = `Signing secret (shown once): ${x.secret}`;
+                this.loadAdminWebhooks();
+            };
+            document.getElementById('btn-wh-deliveries').onclick = () => this.loadAdminWebhooks();
+        }
+    }
+
+    async loadAdminJobs() {
+        const listEl = document.getElementById('jobs-list');
+        const statusSel = document.getElementById('jobs-status');
+        const refreshBtn = document.getElementById('btn-jobs-refresh');
+        if (!listEl) return;
+        if (refreshBtn && !refreshBtn.dataset.bound) {
+            refreshBtn.dataset.bound = '1';
+            refreshBtn.onclick = () => this.loadAdminJobs();
+            if (statusSel) statusSel.onchange = () => this.loadAdminJobs();
+        }
+        const status = statusSel ? statusSel.value : '';
+        const r = await fetch(`/api/admin/jobs?limit=100${status ? '&status=' + encodeURIComponent(status) : ''}`, { credentials: 'include' });
+        if (!r.ok) { listEl.innerHTML = '<small style="opacity:.7">Job queue unavailable</small>'; return; }
+        const d = await r.json();
+        const jobs = Array.isArray(d.jobs) ? d.jobs : [];
+        listEl.innerHTML = jobs.length ? '' : '<small style="opacity:.7">No jobs</small>';
+        jobs.forEach(j => {
+            const row = document.createElement('div');
+            row.className = 'user-row';
+            const when = j.periodic ? `next ${new Date(j.run_at).toLocaleString()}` : new Date(j.created_at).toLocaleString();
+            const last = j.finished_at ? ` · last run ${new Date(j.finished_at).toLocaleString()}` : '';
+            const attempts = j.periodic ? '' : ` · ${j.attempts}/${j.max_attempts} attempts`;
+            row.innerHTML = `<div class="left" style="min-width:0"><div class="handle">${this.escapeHTML(String(j.kind))} <small style="opacity:.7">${this.escapeHTML(String(j.status))}${j.periodic ? ' · periodic' : ''}</small></div><div class="id" style="overflow-wrap:anywhere">${this.escapeHTML(when)}${this.escapeHTML(last)}${attempts}${j.last_error ? ' · ' + this.escapeHTML(String(j.last_error)) : ''}</div></div>`;
+            const right = document.createElement('div'); right.className = 'actions';
+            if (j.result) {
+                const view = document.createElement('button'); view.className = 'nav-btn'; view.textContent = 'Result';
+                const pre = document.createElement('pre'); pre.className = 'meta'; pre.style.cssText = 'display:none;white-space:pre-wrap;margin:0;grid-column:1/-1;max-height:240px;overflow:auto';
+                pre.textContent = JSON.stringify(j.result, null, 2);
+                view.onclick = () => { pre.style.display = pre.style.display === 'none' ? 'block' : 'none'; };
+                right.appendChild(view); row.appendChild(pre);
+            }
+            if (j.status === 'dead' || j.status === 'pending') {
+                const retry = document.createElement('button'); retry.className = 'nav-btn'; retry.textContent = j.status === 'dead' ? 'Retry' : 'Run now';
+                retry.onclick = async () => { const rr = await this.fetchWithCSRF(`/api/admin/jobs/${j.id}/retry`, { method: 'POST', credentials: 'include' }); if (rr.status === 204) { this.showNotification('Requeued'); this.loadAdminJobs(); } else { const e = await rr.json().catch(()=>({})); this.showNotification(e.error || 'Retry failed', 'error'); } };
+                right.appendChild(retry);
+            }
+            row.insertBefore(right, row.children[1] || null);
+            listEl.appendChild(row);
+        });
+    }
+
+    async loadAdminTakedowns() {
+        const listEl = document.getElementById('takedowns-list');
+        const statusSel = document.getElementById('takedowns-status');
+        const refreshBtn = document.getElementById('btn-takedowns-refresh');
+        if (!listEl) return;
+        if (refreshBtn && !refreshBtn.dataset.bound) {
+            refreshBtn.dataset.bound = '1';
+            refreshBtn.onclick = () => this.loadAdminTakedowns();
+            if (statusSel) statusSel.onchange = () => this.loadAdminTakedowns();
+        }
+        const status = statusSel ? statusSel.value : '';
+        const r = await fetch(`/api/admin/takedowns?limit=100${status ? '&status=' + encodeURIComponent(status) : ''}`, { credentials: 'include' });
+        if (!r.ok) { listEl.innerHTML = '<small style="opacity:.7">Takedowns unavailable</small>'; return; }
+        const d = await r.json();
+        const list = Array.isArray(d.takedowns) ? d.takedowns : [];
+        listEl.innerHTML = list.length ? '' : '<small style="opacity:.7">No takedown requests</small>';
+        list.forEach(t => {
+            const row = document.createElement('div');
+            row.className = 'user-row';
+            const image = t.image_id ? `<a href="/i/${encodeURIComponent(t.image_id)}" target="_blank" rel="noopener">image</a>${t.owner_username ? ' by @' + this.escapeHTML(String(t.owner_username)) : ''}` : 'image deleted';
+            const claimant = `${this.escapeHTML(String(t.claimant_name))}${t.organization ? ' (' + this.escapeHTML(String(t.organization)) + ')' : ''} &lt;${this.escapeHTML(String(t.claimant_email))}&gt;`;
+            row.innerHTML = `<div class="left" style="min-width:0"><div class="handle">${claimant} <small style="opacity:.7">${this.escapeHTML(String(t.status))}</small></div><div class="id" style="overflow-wrap:anywhere">${image} · ${this.escapeHTML(new Date(t.created_at).toLocaleString())}</div></div>`;
+            const details = document.createElement('div'); details.className = 'meta'; details.style.cssText = 'display:none;white-space:pre-wrap;grid-column:1/-1;overflow-wrap:anywhere';
+            const right = document.createElement('div'); right.className = 'actions';
+            const mk = (label, fn) => { const b = document.createElement('button'); b.className = 'nav-btn'; b.textContent = label; b.onclick = fn; right.appendChild(b); return b; };
+            mk('Details', async () => {
+                if (details.style.display !== 'none') { details.style.display = 'none'; return; }
+                const rr = await fetch(`/api/admin/takedowns/${encodeURIComponent(t.id)}`, { credentials: 'include' });
+                if (!rr.ok) { this.showNotification('Failed', 'error'); return; }
+                const dd = await rr.json();
+                const log = (Array.isArray(dd.events) ? dd.events : []).map(e => `${new Date(e.created_at).toLocaleString()} · ${e.action}${e.actor_username ? ' by @' + e.actor_username : ''}${e.note ? ': ' + e.note : ''}`).join('\n');
+                details.textContent = `Reported: ${t.image_url}\nOriginal work: ${t.original_work}\n${t.description ? 'Details: ' + t.description + '\n' : ''}Signed: ${t.signature}\n\nLog:\n${log}`;
+                details.style.display = 'block';
+            });
+            const act = async (action, confirmText) => {
+                if (confirmText) { const ok = await this.showConfirm(confirmText); if (!ok) return; }
+                const note = action === 'note' ? (window.prompt('Note for the log') || '').trim() : (window.prompt('Note for the log (optional)') || '').trim();
+                if (action === 'note' && !note) return;
+                const rr = await this.fetchWithCSRF(`/api/admin/takedowns/${encodeURIComponent(t.id)}/actions`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ action, note }) });
+                if (rr.status === 204) { this.showNotification('Saved'); this.loadAdminTakedowns(); } else { const e = await rr.json().catch(()=>({})); this.showNotification(e.error || 'Failed', 'error'); }
+            };
+            if (t.status === 'open') mk('Disable', () => act('disable'));
+            if (t.status === 'open' || t.status === 'disabled') {
+                const rm = mk('Remove', () => act('remove', 'Delete this image permanently?'));
+                rm.style.background = 'var(--color-danger)'; rm.style.color = '#fff';
+                mk('Reject', () => act('reject'));
+            }
+            mk('Note', () => act('note'));
+            row.appendChild(right); row.appendChild(details);
+            listEl.appendChild(row);
+        });
+    }
+
+    // Polls a queued admin job until it finishes; resolves with the final job, or null on timeout.
+    async waitForJob(job, timeoutMs = 10 * 60 * 1000) {
+        const deadline = Date.now() + timeoutMs;
+        let cur = job;
+        while (cur && (cur.status === 'pending' || cur.status === 'running') && Date.now() < deadline) {
+            await new Promise(res => setTimeout(res, 1500));
+            const r = await fetch(`/api/admin/jobs/${encodeURIComponent(job.id)}`, { credentials: 'include' });
+            if (!r.ok) return null;
+            cur = await r.json();
+        }
+        return cur && (cur.status === 'done' || cur.status === 'dead' || (cur.status === 'pending' && cur.last_error)) ? cur : null;
+    }
+
+    // Follows the storage migration through its status endpoint, passing its progress to
+    // onProgress, until the job finishes or is dead-lettered. Failed runs retry and resume on their own.
+    async followMigration(onProgress, timeoutMs = 6 * 60 * 60 * 1000) {
+        const deadline = Date.now() + timeoutMs;
+        while (Date.now() < deadline) {
+            await new Promise(res => setTimeout(res, 1500));
+            const r = await fetch('/api/admin/storage/migrate/status', { credentials: 'include' });
+            if (!r.ok) return null;
+            const { job } = await r.json();
+            if (!job) return null;
+            try { onProgress(job.result); } catch {}
+            if (job.status === 'done' || job.status === 'dead') return job;
+        }
+        return null;
+    }
+
+    async loadAdminBans() {
+        const listEl = document.getElementById('ban-list');
+        const auditEl = document.getElementById('ban-audit');
+        if (!listEl || !auditEl) return;
+        const r = await fetch('/api/admin/bans', { credentials: 'include' });
+        if (!r.ok) { listEl.innerHTML = '<small style="opacity:.7">Bans unavailable</small>'; return; }
+        const d = await r.json();
+        const bans = Array.isArray(d.bans) ? d.bans : [];
+        listEl.innerHTML = bans.length ? '' : '<small style="opacity:.7">No bans</small>';
+        bans.forEach(b => {
+            const expired = b.expires_at && new Date(b.expires_at) <= new Date();
+            const until = b.expires_at ? (expired ? ' · expired ' : ' · until ') + new Date(b.expires_at).toLocaleString() : '';
+            const row = document.createElement('div');
+            row.className = 'user-row';
+            row.innerHTML = `<div class="left"><div class="handle" style="word-break:break-all">${this.escapeHTML(String(b.value))}</div><div class="id">${b.kind === 'ip' ? 'IP' : 'Email domain'}${this.escapeHTML(until)}${b.reason ? ' · ' + this.escapeHTML(String(b.reason)) : ''}</div></div>`;
+            const right = document.createElement('div'); right.className = 'actions';
+            const del = document.createElement('button'); del.className = 'nav-btn'; del.textContent = 'Lift';
+            del.onclick = async () => { const ok = await this.showConfirm(`Lift ban on ${b.value}?`); if (!ok) return; const rr = await this.fetchWithCSRF(`/api/admin/bans/${b.id}`, { method: 'DELETE', credentials: 'include' }); if (rr.status === 204) this.loadAdminBans(); else this.showNotification('Failed', 'error'); };
+            right.appendChild(del); row.appendChild(right); listEl.appendChild(row);
+        });
+        const ar = await fetch('/api/admin/bans/audit?limit=50', { credentials: 'include' });
+        const ad = ar.ok ? await ar.json() : { audit: [] };
+        const audit = Array.isArray(ad.audit) ? ad.audit : [];
+        auditEl.innerHTML = audit.length ? '' : '<small style="opacity:.7">No entries yet</small>';
+        audit.forEach(a => {
+            const row = document.createElement('div');
+            row.style.cssText = 'display:flex;gap:8px;align-items:center;flex-wrap:wrap';
+            const who = a.actor_username ? ` by @${this.escapeHTML(String(a.actor_username))}` : '';
+            row.innerHTML = `<span style="flex:1;min-width:0;overflow-wrap:anywhere">${this.escapeHTML(String(a.action))} · ${this.escapeHTML(String(a.value))}${who}${a.ip ? ' · ' + this.escapeHTML(String(a.ip)) : ''}${a.detail ? ' · ' + this.escapeHTML(String(a.detail)) : ''}</span><small style="opacity:.7">${this.escapeHTML(new Date(a.created_at).toLocaleString())}</small>`;
+            auditEl.appendChild(row);
+        });
+        const createBtn = document.getElementById('btn-ban-create');
+        if (createBtn && !createBtn.dataset.bound) {
+            createBtn.dataset.bound = '1';
+            createBtn.onclick = async () => {
+                const kind = document.getElementById('ban-kind').value;
+                const value = (document.getElementById('ban-value').value || '').trim();
+                const reason = (document.getElementById('ban-reason').value || '').trim();
+                const exp = document.getElementById('ban-expires').value;
+                const body = { kind, value, reason };
+                if (exp) body.expires_at = new Date(exp).toISOString();
+                const rr = await this.fetchWithCSRF('/api/admin/bans', { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify(body) });
+                const x = await rr.json().catch(() => ({}));
+                if (!rr.ok) { this.showNotification(x.error || 'Failed', 'error'); return; }
+                document.getElementById('ban-value').value = ''; document.getElementById('ban-reason').value = ''; document.getElementById('ban-expires').value = '';
+                this.showNotification('Ban added');
+                this.loadAdminBans();
+            };
+            document.getElementById('btn-ban-audit').onclick = () => this.loadAdminBans();
+        }
+    }
+
     async renderSettingsPage() {
         if (this.magneticScroll && this.magneticScroll.updateEnabledState) this.magneticScroll.updateEnabledState();
         if (!this.currentUser) { this.showAuthModal(); return; }
         let email = '';
-        try { const resp = await fetch('/api/me/account', { credentials: 'include' }); if (resp.ok) { const acc = await resp.json(); email = acc.email || ''; } } catch {}
+        let pendingEmail = '';
+        try { const resp = await fetch('/api/me/account', { credentials: 'include' }); if (resp.ok) { const acc = await resp.json(); email = acc.email || ''; pendingEmail = acc.pending_email || ''; } } catch {}
 
         this.gallery.innerHTML = '';
         if (this.profileTop) this.profileTop.innerHTML = '';
@@ -2486,6 +3309,11 @@ class TroughApp {
                   <button id="btn-email" class="nav-btn">Save Email</button>
                   ${needVerify ? '<button id="btn-resend-verify" class="nav-btn">Resend verification</button>' : ''}
                 </div>
+                <div id="pending-email" style="display:${pendingEmail ? 'flex' : 'none'};gap:8px;align-items:center;flex-wrap:wrap">
+                  <small style="opacity:.8">Waiting for <strong id="pending-email-addr">${this.escapeHTML(pendingEmail)}</strong> to be confirmed. Check that inbox for the link.</small>
+                  <button id="btn-cancel-email" class="link-btn">Cancel change</button>
+                </div>
+                <small id="err-email" style="color:#ff5c5c"></small>
                 <label class="settings-label">Password</label>
                 <input type="password" id="current-password" placeholder="Current password" class="settings-input"/>
                 <input type="password" id="new-password" placeholder="New password" minlength="8" class="settings-input"/>
@@ -2499,9 +3327,70 @@ class TroughApp {
                   <label style="display:flex;gap:6px;align-items:center"><input type="radio" name="nsfw-pref" value="blur"> Blur until clicked</label>
                 </div>
                 <div class="settings-actions"><button id="btn-nsfw" class="nav-btn">Save NSFW preference</button></div>
+                <label class="settings-label">Milder ratings</label>
+                <div style="display:flex;gap:8px;flex-wrap:wrap">
+                  ${['suggestive', 'mature'].map(r => `<label style="display:flex;gap:6px;align-items:center">${r === 'suggestive' ? 'Suggestive' : 'Mature'}
+                    <select id="pref-${r}" class="settings-input" style="width:auto">
+                      <option value="">Default</option>
+                      <option value="show">Show</option>
+                      <option value="blur">Blur until clicked</option>
+                      <option value="hide">Hide</option>
+                    </select></label>`).join('')}
+                </div>
+                <div class="settings-actions"><button id="btn-content-prefs" class="nav-btn">Save rating preferences</button></div>
+                <label class="settings-label" for="muted-providers">Muted AI providers</label>
+                <input type="text" id="muted-providers" placeholder="e.g. Midjourney, FLUX" class="settings-input" value="${this.escapeHTML(((this.currentUser?.feed_mutes?.providers) || []).join(', '))}"/>
+                <div class="settings-actions"><button id="btn-mutes" class="nav-btn">Save mutes</button></div>
+                <label class="settings-label">Upload privacy</label>
+                <label style="display:flex;gap:6px;align-items:center"><input type="checkbox" id="strip-exif" ${this.currentUser?.strip_exif ? 'checked' : ''}> Remove GPS location and camera serial numbers from my uploads</label>
+                <label class="settings-label">Verification</label>
+                <div id="verification-box" style="display:grid;gap:8px"></div>
+              </div>
+            </div>
+          </section>
+          <section class="settings-group">
+            <div class="settings-label">Profile theme</div>
+            <div style="display:grid;gap:10px;min-width:0">
+              <label class="settings-label">Accent color</label>
+              <div class="settings-actions" style="gap:8px;align-items:center">
+                <input type="color" id="theme-accent" value="#7af0ff"/>
+                <label style="display:flex;gap:6px;align-items:center"><input type="checkbox" id="theme-accent-default"> Use site default</label>
               </div>
+              <label class="settings-label">Gallery layout</label>
+              <select id="theme-layout" class="settings-input">
+                <option value="">Default</option>
+                <option value="masonry">Masonry</option>
+                <option value="grid">Square grid</option>
+                <option value="wide">Wide</option>
+              </select>
+              <div class="settings-actions" style="gap:8px;align-items:center"><button id="btn-theme" class="nav-btn">Save theme</button><small id="err-theme" style="color:#ff5c5c"></small></div>
+              <label class="settings-label">Banner image</label>
+              <div class="profile-cover" id="theme-header-preview" style="display:none"></div>
+              <div class="settings-actions" style="gap:8px;align-items:center;min-width:0"><input type="file" id="theme-header-file" accept="image/*" style="min-width:0"/><button id="theme-header-upload" class="nav-btn">Upload</button><button id="theme-header-remove" class="link-btn">Remove</button></div>
             </div>
           </section>
+          <section class="settings-group">
+            <div class="settings-label">Notifications <span id="notif-unread" style="opacity:.7"></span></div>
+            <div id="notif-list" style="display:grid;gap:6px"></div>
+            <div class="settings-actions" style="gap:8px;align-items:center;flex-wrap:wrap">
+              <button id="btn-notif-read" class="nav-btn">Mark all read</button>
+              <label style="display:flex;gap:6px;align-items:center"><input type="checkbox" id="notify-digest"> Email me a daily digest of unread notifications</label>
+            </div>
+          </section>
+          <section class="settings-group" id="login-history-group">
+            <div class="settings-label">Recent sign-ins</div>
+            <small style="opacity:.7">Didn't sign in from one of these? Change your password.</small>
+            <div id="login-history" style="display:grid;gap:6px"></div>
+          </section>
+          <section class="settings-group" id="my-invites-group" style="display:none">
+            <div class="settings-label">Invites <span id="my-invites-quota" style="opacity:.7"></span></div>
+            <div class="settings-actions" style="gap:8px;align-items:center">
+              <input type="text" id="my-invite-note" class="settings-input" maxlength="500" placeholder="Who is it for? (only you and admins see this)" style="flex:1"/>
+              <button id="btn-my-invite" class="nav-btn">Create invite</button>
+            </div>
+            <small id="my-invites-reason" style="opacity:.7"></small>
+            <div id="my-invites-list" style="display:grid;gap:6px"></div>
+          </section>
           <section class="settings-group">
             <div class="settings-label" style="color:#ff5c5c">Delete</div>
             <div class="settings-actions" style="gap:8px;align-items:center">
@@ -2512,20 +3401,27 @@ class TroughApp {
           </section>`;
         this.gallery.appendChild(wrap);
 
-        // Footer with public pages
+        // Footer with the footer menu, or the top-level public pages when it is empty
         try {
-            const r = await fetch('/api/pages');
-            const d = await r.json().catch(()=>({pages:[]}));
-            const pages = Array.isArray(d.pages) ? d.pages : [];
+            const nr = await fetch('/api/navigation');
+            const nd = await nr.json().catch(()=>({menus:{}}));
+            let pages = ((nd.menus||{}).footer||[]).map(l => ({ slug: l.href, title: l.label }));
+            if (!pages.length) {
+                const r = await fetch('/api/pages');
+                const d = await r.json().catch(()=>({pages:[]}));
+                pages = (Array.isArray(d.pages) ? d.pages : []).filter(p => !String(p.slug||'').includes('/'));
+            }
             if (pages.length) {
                 const footer = document.createElement('div');
                 footer.style.cssText = 'margin:12px auto 0;max-width:980px;display:flex;gap:12px;flex-wrap:wrap;justify-content:center;opacity:.8';
                 pages.forEach(p => {
                     const a = document.createElement('a');
-                    a.href = '/' + String(p.slug||'').replace(/^\/+/, '');
+                    const external = /^https?:\/\//i.test(String(p.slug||''));
+                    a.href = external ? String(p.slug) : '/' + String(p.slug||'').replace(/^\/+/, '');
                     a.className = 'link-btn';
                     a.textContent = String(p.title||'');
-                    a.onclick = (e) => { e.preventDefault(); history.pushState({}, '', a.href); this.init(); };
+                    if (external) { a.target = '_blank'; a.rel = 'noopener noreferrer'; }
+                    else a.onclick = (e) => { e.preventDefault(); history.pushState({}, '', a.href); this.init(); };
                     footer.appendChild(a);
                 });
                 this.gallery.appendChild(footer);
@@ -2573,6 +3469,135 @@ class TroughApp {
             const sel = document.querySelector("input[name='nsfw-pref']:checked")?.value || 'hide';
             try { const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ nsfw_pref: sel }) }); if (!resp.ok) throw await resp.json(); const u = await resp.json(); this.currentUser = u; localStorage.setItem('user', JSON.stringify(u)); this.showNotification('NSFW preference saved'); } catch (e) { document.getElementById('err-nsfw').textContent = e.error || 'Failed'; }
         };
+        ['suggestive', 'mature'].forEach(r => { document.getElementById(`pref-${r}`).value = (this.currentUser?.content_prefs?.[r]) || ''; });
+        document.getElementById('btn-content-prefs').onclick = async () => {
+            const prefs = {};
+            ['suggestive', 'mature'].forEach(r => { const v = document.getElementById(`pref-${r}`).value; if (v) prefs[r] = v; });
+            try { const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ content_prefs: prefs }) }); if (!resp.ok) throw await resp.json(); const u = await resp.json(); this.currentUser = u; localStorage.setItem('user', JSON.stringify(u)); this.showNotification('Rating preferences saved'); } catch (e) { this.showNotification(e.error || 'Failed', 'error'); }
+        };
+        const loadVerification = async () => {
+            const box = document.getElementById('verification-box');
+            if (!box) return;
+            try {
+                const r = await fetch('/api/me/verification', { credentials: 'include' });
+                if (!r.ok) return;
+                const d = await r.json();
+                if (d.verified) { box.innerHTML = `<small>Verified ✓${d.reason ? ' · ' + this.escapeHTML(String(d.reason)) : ''}</small>`; return; }
+                if (!d.self_serve) { box.innerHTML = '<small style="opacity:.7">Not verified. Staff grant the badge to known creators.</small>'; return; }
+                const p = d.proof;
+                const how = p ? (p.kind === 'domain'
+                    ? `Add a DNS TXT record to ${this.escapeHTML(p.target)}, or serve https://${this.escapeHTML(p.target)}/.well-known/trough-verify.txt, containing:`
+                    : `Put this text anywhere on ${this.escapeHTML(p.target)}:`) : '';
+                box.innerHTML = `
+                  <div style="display:flex;gap:8px;flex-wrap:wrap">
+                    <select id="verify-kind" class="settings-input" style="width:auto"><option value="domain">Domain</option><option value="link">Link</option></select>
+                    <input type="text" id="verify-target" class="settings-input" style="flex:1;min-width:200px" placeholder="example.com or https://…" value="${p ? this.escapeHTML(p.target) : ''}"/>
+                    <button id="btn-verify-start" class="nav-btn">Get token</button>
+                  </div>
+                  ${p ? `<small>${how}</small><code style="overflow-wrap:anywhere">${this.escapeHTML(p.token)}</code><div class="settings-actions"><button id="btn-verify-check" class="nav-btn">Check</button></div>` : ''}`;
+                if (p) document.getElementById('verify-kind').value = p.kind;
+                document.getElementById('btn-verify-start').onclick = async () => {
+                    const body = { kind: document.getElementById('verify-kind').value, target: document.getElementById('verify-target').value };
+                    const resp = await this.fetchWithCSRF('/api/me/verification', { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify(body) });
+                    if (!resp.ok) { const e = await resp.json().catch(() => ({})); this.showNotification(e.error || 'Failed', 'error'); return; }
+                    loadVerification();
+                };
+                const check = document.getElementById('btn-verify-check');
+                if (check) check.onclick = async () => {
+                    const resp = await this.fetchWithCSRF('/api/me/verification/check', { method: 'POST', credentials: 'include' });
+                    const e = await resp.json().catch(() => ({}));
+                    if (!resp.ok) { this.showNotification(e.error || 'Failed', 'error'); return; }
+                    this.showNotification('Verified');
+                    loadVerification();
+                };
+            } catch {}
+        };
+        loadVerification();
+        const loadNotifications = async () => {
+            const listEl = document.getElementById('notif-list');
+            if (!listEl) return;
+            try {
+                const r = await fetch('/api/me/notifications?limit=30', { credentials: 'include' });
+                if (!r.ok) return;
+                const d = await r.json();
+                const items = Array.isArray(d.notifications) ? d.notifications : [];
+                document.getElementById('notif-unread').textContent = d.unread ? `(${d.unread} unread)` : '';
+                document.getElementById('notify-digest').checked = !!d.digest;
+                listEl.innerHTML = items.length ? items.map(n => {
+                    const when = new Date(n.created_at).toLocaleString();
+                    const link = n.image_id ? `<a href="/i/${this.escapeHTML(String(n.image_id))}" class="link-btn">View</a>` : '';
+                    return `<div style="display:flex;gap:8px;align-items:center;${n.read_at ? 'opacity:.6' : ''}"><span style="flex:1;min-width:0;overflow-wrap:anywhere">${this.escapeHTML(String(n.text||''))}</span>${link}<small style="opacity:.7">${this.escapeHTML(when)}</small></div>`;
+                }).join('') : '<small style="opacity:.7">No notifications yet</small>';
+            } catch {}
+        };
+        loadNotifications();
+        const loadMyInvites = async () => {
+            const group = document.getElementById('my-invites-group');
+            if (!group) return;
+            try {
+                const r = await fetch('/api/me/invites', { credentials: 'include' });
+                if (!r.ok) return;
+                const d = await r.json();
+                const invites = Array.isArray(d.invites) ? d.invites : [];
+                // Hidden entirely while the site gives users no invites and they never had any
+                if (!d.quota && !invites.length) { group.style.display = 'none'; return; }
+                group.style.display = '';
+                document.getElementById('my-invites-quota').textContent = d.quota ? `(${d.remaining} of ${d.quota} left this month)` : '';
+                document.getElementById('my-invites-reason').textContent = d.eligible ? 'Each invite works once and expires after 14 days.' : (d.reason || '');
+                document.getElementById('btn-my-invite').disabled = !d.eligible || !d.remaining;
+                const listEl = document.getElementById('my-invites-list');
+                listEl.innerHTML = invites.map(inv => {
+                    const used = inv.max_uses != null && inv.uses >= inv.max_uses;
+                    const expired = inv.expires_at && new Date(inv.expires_at) <= new Date();
+                    const state = used ? 'used' : (expired ? 'expired' : 'expires ' + new Date(inv.expires_at).toLocaleDateString());
+                    const link = (d.links || {})[inv.id] || '';
+                    return `<div style="display:flex;gap:8px;align-items:center;${used || expired ? 'opacity:.6' : ''}"><code style="flex:0 0 auto">${this.escapeHTML(String(inv.code))}</code><span style="flex:1;min-width:0;overflow-wrap:anywhere">${this.escapeHTML(String(inv.note || ''))}</span><small style="opacity:.7">${this.escapeHTML(state)}</small>${used || expired ? '' : `<button class="link-btn" data-link="${this.escapeHTML(link)}">Copy link</button>`}</div>`;
+                }).join('');
+                listEl.querySelectorAll('[data-link]').forEach(btn => {
+                    btn.onclick = async () => { try { await navigator.clipboard.writeText(new URL(btn.dataset.link, location.origin).href); this.showNotification('Link copied'); } catch { this.showNotification('Copy failed', 'error'); } };
+                });
+            } catch {}
+        };
+        loadMyInvites();
+        (async () => {
+            const listEl = document.getElementById('login-history');
+            if (!listEl) return;
+            try {
+                const r = await fetch('/api/me/security/logins', { credentials: 'include' });
+                if (!r.ok) { document.getElementById('login-history-group').style.display = 'none'; return; }
+                const d = await r.json();
+                const logins = Array.isArray(d.logins) ? d.logins : [];
+                if (!logins.length) { listEl.innerHTML = '<small style="opacity:.7">No sign-ins recorded yet</small>'; return; }
+                listEl.innerHTML = logins.map(l => {
+                    const flags = [l.current ? 'this device' : '', l.new_device ? 'new device' : '', l.new_location ? 'new location' : ''].filter(Boolean).join(' · ');
+                    return `<div style="display:flex;gap:8px;align-items:baseline;flex-wrap:wrap"><span>${this.escapeHTML(String(l.device || 'Unknown device'))}</span>${l.country ? `<small style="opacity:.7">${this.escapeHTML(String(l.country))}</small>` : ''}<small style="opacity:.7">${this.escapeHTML(new Date(l.created_at).toLocaleString())}</small>${flags ? `<small style="color:var(--accent)">${this.escapeHTML(flags)}</small>` : ''}</div>`;
+                }).join('');
+            } catch {}
+        })();
+        document.getElementById('btn-my-invite').onclick = async () => {
+            const note = document.getElementById('my-invite-note').value.trim();
+            try {
+                const r = await this.fetchWithCSRF('/api/me/invites', { method: 'POST', headers: authHeader, body: JSON.stringify({ note }) });
+                const d = await r.json().catch(() => ({}));
+                if (!r.ok) throw d;
+                document.getElementById('my-invite-note').value = '';
+                try { await navigator.clipboard.writeText(new URL(d.link, location.origin).href); this.showNotification('Invite created and link copied'); } catch { this.showNotification('Invite created'); }
+                await loadMyInvites();
+            } catch (e) { this.showNotification(e.error || 'Failed', 'error'); }
+        };
+        document.getElementById('btn-notif-read').onclick = async () => {
+            try { const r = await this.fetchWithCSRF('/api/me/notifications/read', { method: 'POST', headers: authHeader, body: JSON.stringify({}) }); if (!r.ok) throw await r.json(); await loadNotifications(); } catch (e) { this.showNotification(e.error || 'Failed', 'error'); }
+        };
+        document.getElementById('btn-mutes').onclick = async () => {
+            const providers = document.getElementById('muted-providers').value.split(',').map(s => s.trim()).filter(Boolean);
+            try { const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ feed_mutes: { providers } }) }); if (!resp.ok) throw await resp.json(); const u = await resp.json(); this.currentUser = u; localStorage.setItem('user', JSON.stringify(u)); document.getElementById('muted-providers').value = ((u.feed_mutes && u.feed_mutes.providers) || []).join(', '); this.showNotification('Mutes saved'); } catch (e) { this.showNotification(e.error || 'Failed', 'error'); }
+        };
+        document.getElementById('strip-exif').onchange = async (ev) => {
+            try { const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ strip_exif: !!ev.target.checked }) }); if (!resp.ok) throw await resp.json(); const u = await resp.json(); this.currentUser = u; localStorage.setItem('user', JSON.stringify(u)); this.showNotification(ev.target.checked ? 'Location data will be removed from uploads' : 'Upload metadata kept as-is'); } catch (e) { ev.target.checked = !ev.target.checked; this.showNotification(e.error || 'Failed', 'error'); }
+        };
+        document.getElementById('notify-digest').onchange = async (ev) => {
+            try { const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ notify_digest: !!ev.target.checked }) }); if (!resp.ok) throw await resp.json(); this.showNotification(ev.target.checked ? 'Daily digest on' : 'Daily digest off'); } catch (e) { ev.target.checked = !ev.target.checked; this.showNotification(e.error || 'Failed', 'error'); }
+        };
         document.getElementById('btn-username').onclick = async () => {
             const inputEl = document.getElementById('settings-username');
             const errEl = document.getElementById('err-username');
@@ -2617,7 +3642,29 @@ class TroughApp {
         };
         document.getElementById('btn-email').onclick = async () => {
             const v = document.getElementById('settings-email').value.trim();
-            try { const resp = await this.fetchWithCSRF('/api/me/email', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ email: v }) }); if (!resp.ok) throw await resp.json(); this.showNotification('Email updated'); } catch (e) { document.getElementById('err-email').textContent = e.error || 'Failed'; }
+            document.getElementById('err-email').textContent = '';
+            try {
+                const resp = await this.fetchWithCSRF('/api/me/email', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ email: v }) });
+                const d = await resp.json().catch(() => ({}));
+                if (!resp.ok) throw d;
+                if (resp.status === 202 && d.pending_email) {
+                    // The change only applies once the new address confirms it
+                    document.getElementById('settings-email').value = d.email || '';
+                    document.getElementById('pending-email-addr').textContent = d.pending_email;
+                    document.getElementById('pending-email').style.display = 'flex';
+                    this.showNotification('Check ' + d.pending_email + ' to confirm the change');
+                } else {
+                    this.showNotification('Email updated');
+                }
+            } catch (e) { document.getElementById('err-email').textContent = e.error || 'Failed'; }
+        };
+        document.getElementById('btn-cancel-email').onclick = async () => {
+            try {
+                const resp = await this.fetchWithCSRF('/api/me/email/pending', { method: 'DELETE', headers: authHeader });
+                if (!resp.ok) throw await resp.json();
+                document.getElementById('pending-email').style.display = 'none';
+                this.showNotification('Email change cancelled');
+            } catch (e) { document.getElementById('err-email').textContent = e.error || 'Failed'; }
         };
         document.getElementById('btn-password').onclick = async () => {
             const current = document.getElementById('current-password').value; const next = pw.value; const confirm = pwc.value;
@@ -2629,6 +3676,57 @@ class TroughApp {
             try { const resp = await this.fetchWithCSRF('/api/me', { method:'DELETE', headers: authHeader, body: JSON.stringify({ confirm:'DELETE' }) }); if (resp.status !== 204) throw await resp.json(); await this.signOut(); window.location.href='/'; } catch (e) { document.getElementById('err-delete').textContent = e.error || 'Failed'; }
         };
 
+        // Profile theme
+        const themeAccent = document.getElementById('theme-accent');
+        const themeAccentDefault = document.getElementById('theme-accent-default');
+        const themeLayout = document.getElementById('theme-layout');
+        const themePreview = document.getElementById('theme-header-preview');
+        const renderThemeForm = (theme) => {
+            theme = theme || {};
+            themeAccentDefault.checked = !theme.accent;
+            if (theme.accent) themeAccent.value = theme.accent;
+            themeLayout.value = theme.layout || '';
+            if (theme.header_url) {
+                try { themePreview.style.backgroundImage = `url('${encodeURI(String(theme.header_url))}')`; } catch {}
+                themePreview.style.display = 'block';
+            } else {
+                themePreview.style.display = 'none';
+            }
+        };
+        try { const r = await fetch('/api/me/profile', { credentials: 'include' }); if (r.ok) { const me = await r.json(); renderThemeForm(me.profile_theme); } } catch {}
+        themeAccent.oninput = () => { themeAccentDefault.checked = false; };
+        document.getElementById('btn-theme').onclick = async () => {
+            const errEl = document.getElementById('err-theme'); errEl.textContent = '';
+            const profile_theme = { accent: themeAccentDefault.checked ? '' : themeAccent.value, layout: themeLayout.value };
+            try {
+                const resp = await this.fetchWithCSRF('/api/me/profile', { method: 'PATCH', headers: authHeader, body: JSON.stringify({ profile_theme }) });
+                if (!resp.ok) throw await resp.json();
+                const u = await resp.json();
+                this.currentUser = u; localStorage.setItem('user', JSON.stringify(u));
+                this.showNotification('Theme saved');
+            } catch (e) { errEl.textContent = e.error || 'Failed'; }
+        };
+        document.getElementById('theme-header-upload').onclick = async () => {
+            const fileInput = document.getElementById('theme-header-file'); const file = fileInput.files && fileInput.files[0]; if (!file) { this.showNotification('Choose a file first', 'error'); return; }
+            const fd = new FormData(); fd.append('banner', file);
+            try {
+                const resp = await this.fetchWithCSRF('/api/me/banner', { method: 'POST', credentials: 'include', body: fd });
+                if (!resp.ok) throw await resp.json();
+                const data = await resp.json();
+                renderThemeForm(data.profile_theme);
+                fileInput.value = '';
+                this.showNotification('Banner updated');
+            } catch (e) { this.showNotification(e.error || 'Upload failed', 'error'); }
+        };
+        document.getElementById('theme-header-remove').onclick = async () => {
+            try {
+                const resp = await this.fetchWithCSRF('/api/me/banner', { method: 'DELETE', credentials: 'include' });
+                if (resp.status !== 204) throw await resp.json();
+                themePreview.style.display = 'none';
+                this.showNotification('Banner removed');
+            } catch (e) { this.showNotification(e.error || 'Failed', 'error'); }
+        };
+
         // Avatar upload
         document.getElementById('avatar-upload').onclick = async () => {
             const fileInput = document.getElementById('avatar-file'); const file = fileInput.files && fileInput.files[0]; if (!file) { this.showNotification('Choose a file first', 'error'); return; }
@@ -2675,16 +3773,32 @@ class TroughApp {
         formData.append('image', file);
         if (options.title) formData.append('title', options.title);
         if (typeof options.nsfw === 'boolean') formData.append('is_nsfw', String(options.nsfw));
+        if (options.rating) formData.append('rating', options.rating);
         if (options.caption) formData.append('caption', options.caption);
+        if (options.visibility) formData.append('visibility', options.visibility);
+        if (options.license) formData.append('license', options.license);
         
         this.showLoader();
         
         try {
             const response = await this.fetchWithCSRF('/api/upload', { method: 'POST', credentials: 'include', body: formData });
             
-            if (response.ok) {
+            if (response.status === 202) {
+                // Processing continues in the background; follow the token until it settles
+                const queued = await response.json();
+                const st = await this.waitForUpload(queued.token);
+                if (st.status === 'done' && st.image) {
+                    this.showNotification(st.image.pending ? 'Image uploaded. It will appear publicly once a moderator approves it.' : 'Image uploaded');
+                    return st.image;
+                }
+                if (st.status === 'failed' && st.code >= 400 && st.code < 500) {
+                    await this.showErrorModal('Upload rejected', st.error || 'Only AI images with verifiable metadata (EXIF/XMP/C2PA) are accepted.');
+                } else {
+                    await this.showErrorModal('Upload failed', st.error || 'Unknown error');
+                }
+            } else if (response.ok) {
                 const image = await response.json();
-                this.showNotification('Image uploaded');
+                this.showNotification(image.pending ? 'Image uploaded. It will appear publicly once a moderator approves it.' : 'Image uploaded');
                 return image;
             } else if (response.status === 400) {
                 const error = await response.json().catch(() => ({}));
@@ -2711,6 +3825,24 @@ class TroughApp {
         return null;
     }
 
+    // Polls a queued upload until it is done or failed; gives up after timeoutMs.
+    async waitForUpload(token, timeoutMs = 5 * 60 * 1000) {
+        const deadline = Date.now() + timeoutMs;
+        let delay = 500;
+        while (Date.now() < deadline) {
+            await new Promise(r => setTimeout(r, delay));
+            delay = Math.min(delay * 2, 3000);
+            try {
+                const r = await fetch(`/api/uploads/${encodeURIComponent(token)}/status`, { credentials: 'include' });
+                if (r.status === 404) return { status: 'failed', error: 'Upload not found' };
+                if (!r.ok) continue;
+                const st = await r.json();
+                if (st.status === 'done' || st.status === 'failed') return st;
+            } catch {}
+        }
+        return { status: 'failed', error: 'Still processing. Check your profile in a few minutes.' };
+    }
+
     async showErrorModal(title, message) {
         return new Promise((resolve) => {
             const overlay = document.createElement('div');
@@ -2740,6 +3872,13 @@ class TroughApp {
 
     escapeHTML(s){ return (s||'').replace(/[&<>"]/g, c=>({ '&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;' }[c])); }
 
+    // Verified creators get a check mark after their handle; the reason is its tooltip
+    verifiedBadge(verified, reason) {
+        if (!verified) return '';
+        const title = reason ? `Verified · ${String(reason)}` : 'Verified';
+        return ` <span class="verified-badge" title="${this.escapeHTML(title)}" aria-label="${this.escapeHTML(title)}" style="color:var(--color-accent)">✓</span>`;
+    }
+
     setupInfiniteScroll() {
         // Ensure only one scroll listener is attached at a time
         if (this._infiniteScrollCleanup) { try { this._infiniteScrollCleanup(); } catch {} this._infiniteScrollCleanup = null; }
@@ -3137,6 +4276,27 @@ class TroughApp {
               </div>
               <div class="settings-label">Registration</div>
               <label style="display:flex;gap:8px;align-items:center"><input id="public-reg" type="checkbox" ${s.public_registration_enabled!==false?'checked':''}/> Allow public registration</label>
+              <label style="display:flex;gap:8px;align-items:center"><input id="reg-approval" type="checkbox" ${s.registration_approval_required?'checked':''}/> Hold new accounts for approval (invited users skip the queue)</label>
+              <label style="display:flex;gap:8px;align-items:center">Ask new users to confirm they are at least <input id="minimum-age" class="settings-input no-spinner" type="number" min="0" max="99" style="width:80px" value="${Number(s.minimum_age)||0}"/> years old (0 asks nothing)</label>
+              <label style="display:flex;gap:8px;align-items:center"><input id="adult-site" type="checkbox" ${s.adult_site?'checked':''}/> Adult site: signed-out visitors confirm the minimum age (18 or more) before images load</label>
+              <div style="display:flex;gap:8px;align-items:center;flex-wrap:wrap"><span id="legal-status" style="color:var(--text-secondary)">Terms acceptance: loading…</span><button id="legal-publish" class="nav-btn">Require the latest terms and privacy pages</button><button id="legal-disable" class="link-btn">Stop tracking</button></div>
+              <label style="display:flex;gap:8px;align-items:center">Hold each new user's first <input id="moderation-hold" class="settings-input no-spinner" type="number" min="0" max="1000" style="width:80px" value="${Number(s.moderation_hold_uploads)||0}"/> uploads for review (0 disables)</label>
+              <label style="display:flex;gap:8px;align-items:center;flex-wrap:wrap">Let users issue <input id="user-invite-quota" class="settings-input no-spinner" type="number" min="0" max="100" style="width:80px" value="${Number(s.user_invite_quota)||0}"/> invites a month once their account is <input id="user-invite-min-days" class="settings-input no-spinner" type="number" min="0" max="3650" style="width:80px" value="${s.user_invite_min_account_days ?? 30}"/> days old (0 disables)</label>
+              <label style="display:flex;gap:8px;align-items:center"><input id="block-disposable" type="checkbox" ${s.block_disposable_emails?'checked':''}/> Block disposable email addresses at registration</label>
+              <label style="display:flex;gap:8px;align-items:center">Refuse sign-up forms submitted within <input id="reg-min-fill" class="settings-input no-spinner" type="number" min="0" max="60" style="width:80px" value="${Number(s.registration_min_fill_seconds)||0}"/> seconds of opening (0 disables; 3 works well)</label>
+              <label style="display:flex;gap:8px;align-items:center;flex-wrap:wrap">Challenge for flagged addresses on sign-up and password reset
+                <select id="challenge-provider" class="settings-input" style="width:auto">
+                  <option value="" ${!s.challenge_provider?'selected':''}>Off</option>
+                  <option value="pow" ${s.challenge_provider==='pow'?'selected':''}>Built-in proof of work</option>
+                  <option value="hcaptcha" ${s.challenge_provider==='hcaptcha'?'selected':''}>hCaptcha</option>
+                  <option value="turnstile" ${s.challenge_provider==='turnstile'?'selected':''}>Cloudflare Turnstile</option>
+                </select></label>
+              <input id="challenge-site-key" class="settings-input" placeholder="Captcha site key" value="${this.escapeHTML(String(s.challenge_site_key||''))}"/>
+              <input id="challenge-secret-key" class="settings-input" type="password" placeholder="Captcha secret key" value="${this.escapeHTML(String(s.challenge_secret_key||''))}"/>
+              <div class="settings-label" style="margin-top:8px">Downloads</div>
+              <label style="display:flex;gap:8px;align-items:center"><input id="download-watermark" type="checkbox" ${s.download_watermark_enabled?'checked':''}/> Watermark original downloads for everyone but the owner</label>
+              <input id="download-watermark-text" class="settings-input" maxlength="64" placeholder="Watermark text (defaults to the site name)" value="${this.escapeHTML(String(s.download_watermark_text||''))}"/>
+              <label style="display:flex;gap:8px;align-items:center"><input id="exif-privacy" type="checkbox" ${s.exif_privacy_mode?'checked':''}/> Strip GPS and camera serials from all re-encoded uploads (AI provenance is kept)</label>
               <div class="settings-label" style="margin-top:8px">Analytics</div>
               <label style="display:flex;gap:8px;align-items:center;margin-bottom:4px"><input id="analytics-enabled" type="checkbox" ${s.analytics_enabled?'checked':''}/> Enable site analytics</label>
               <div id="analytics-config" style="display:${s.analytics_enabled?'grid':'none'};gap:8px">
@@ -3160,6 +4320,10 @@ class TroughApp {
                   <input id="plausible-domain" class="settings-input" placeholder="Your site domain (example.com)" value="${s.plausible_domain||''}"/>
                 </div>
               </div>
+              <div class="settings-label" style="margin-top:8px">Extra script sources (Content-Security-Policy)</div>
+              <input id="csp-script-sources" class="settings-input" placeholder="https://cdn.example.com *.example.org" value="${this.escapeHTML(String(s.csp_script_sources||''))}"/>
+              <div class="settings-label" style="margin-top:8px">Default language (emails and server messages)</div>
+              <select id="default-locale" class="settings-input"><option value="${this.escapeHTML(String(s.default_locale||'en'))}" selected>${this.escapeHTML(String(s.default_locale||'en'))}</option></select>
               <div class="settings-actions"><button id="btn-save-site-core" class="nav-btn">Save site settings</button></div>
               <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between">
                 <span>Storage settings (advanced)</span>
@@ -3204,6 +4368,7 @@ class TroughApp {
                 ${smtpConfigured ? `<label style=\"display:flex;gap:8px;align-items:center\"><input id=\"require-verify\" type=\"checkbox\" ${s.require_email_verification?'checked':''}/> Require email verification for new accounts</label>
                 <div class="settings-actions" style="gap:8px;align-items:center"><input id="smtp-test-to" class="settings-input" placeholder="Test email to"/><button id="btn-smtp-test" class="nav-btn">Send test</button></div>` : '<small style="color:var(--text-tertiary)">Enter SMTP settings to enable email features</small>'}
                 <div class="settings-actions" style="gap:8px;align-items:center;margin-top:8px"><button id="btn-save-site" class="nav-btn">Save SMTP settings</button></div>
+                ${smtpConfigured ? `<div class=\"meta\" style=\"margin-top:8px\">Preview emails: <a href=\"/api/admin/email/preview/verification\" target=\"_blank\" rel=\"noopener\">verification</a> · <a href=\"/api/admin/email/preview/password_reset\" target=\"_blank\" rel=\"noopener\">password reset</a></div><div style=\"display:flex;gap:8px;align-items:center;margin-top:8px\"><span>Failed sends</span><button id=\"btn-mail-outbox\" class=\"link-btn\">Show</button></div><div id=\"mail-outbox-list\" style=\"display:none;gap:6px\"></div>` : ''}
               </div>`;
             // Event handlers will be wired up in the isAdmin block below
 
@@ -3252,12 +4417,35 @@ class TroughApp {
             };
             if (analyticsProviderSel) analyticsProviderSel.onchange = showByProvider;
             showByProvider();
+
+            // Terms versions: users accept the published revisions of the terms and privacy pages
+            const legalStatus = siteSection.querySelector('#legal-status');
+            const loadLegal = async () => {
+                const r = await this.fetchWithCSRF('/api/admin/legal', { credentials: 'include' });
+                if (!r.ok) { if (legalStatus) legalStatus.textContent = 'Terms acceptance: unavailable'; return; }
+                const d = await r.json(); const req = d.required || {}, latest = d.latest || {};
+                const tracked = req.terms_revision || req.privacy_revision;
+                legalStatus.textContent = tracked
+                    ? `Users must accept terms r${req.terms_revision||'-'} and privacy r${req.privacy_revision||'-'} (latest: r${latest.terms_revision||'-'}, r${latest.privacy_revision||'-'})`
+                    : 'Terms acceptance is not tracked';
+            };
+            const publishLegal = async (body, confirmText) => {
+                if (!confirm(confirmText)) return;
+                const r = await this.fetchWithCSRF('/api/admin/legal/publish', { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify(body) });
+                if (r.ok) { this.showNotification('Terms versions updated'); loadLegal(); }
+                else { const e = await r.json().catch(() => ({})); this.showNotification(e.error || 'Failed', 'error'); }
+            };
+            const legalPublish = siteSection.querySelector('#legal-publish');
+            if (legalPublish) legalPublish.onclick = () => publishLegal({}, 'Every user will have to accept the latest terms and privacy pages before changing anything. Continue?');
+            const legalDisable = siteSection.querySelector('#legal-disable');
+            if (legalDisable) legalDisable.onclick = () => publishLegal({ disable: true }, 'Stop asking users to accept the terms?');
+            if (legalStatus) loadLegal();
         }
 
         const pagesSection = document.createElement('section');
         pagesSection.className = 'settings-group';
         pagesSection.innerHTML = `
-          <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between"><span>Add/Edit Pages</span> <small class="meta" style="opacity:.8">Single-segment slugs only (e.g., about, faq)</small></div>
+          <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between"><span>Add/Edit Pages</span> <small class="meta" style="opacity:.8">Up to 4 segments (e.g., about, help/faq); the parent page must exist</small></div>
           <div style="display:grid;gap:8px">
             <div style="display:grid;gap:6px;grid-template-columns:repeat(auto-fit,minmax(220px,1fr))">
               <div style="display:grid;gap:6px">
@@ -3281,15 +4469,34 @@ class TroughApp {
             <div style="display:grid;gap:6px;grid-template-columns:repeat(auto-fit,minmax(220px,1fr))">
               <div style="display:grid;gap:6px"><label class="settings-label">Meta title (optional)</label><input id="pg-meta-title" class="settings-input" placeholder="Overrides <title>"/></div>
               <div style="display:grid;gap:6px"><label class="settings-label">Meta description (optional)</label><input id="pg-meta-desc" class="settings-input" placeholder="Short description for SEO"/></div>
+              <div style="display:grid;gap:6px"><label class="settings-label">Position</label><input id="pg-position" type="number" class="settings-input" value="0" title="Orders pages that share a parent"/></div>
             </div>
             <label style="display:flex;gap:8px;align-items:center"><input id="pg-published" type="checkbox"/> Published</label>
             <div class="settings-actions" style="gap:8px;align-items:center">
               <button id="pg-save" class="nav-btn">Save</button>
               <button id="pg-new" class="link-btn">New</button>
               <button id="pg-delete" class="link-btn" style="color:#ff6666">Delete</button>
+              <button id="pg-history" class="link-btn">History</button>
             </div>
+            <div id="pg-revisions" style="display:grid;gap:6px"></div>
           </div>
           <div id="pg-list" style="display:grid;gap:6px;margin-top:8px"></div>
+          <div class="settings-label" style="margin-top:12px">Navigation menus</div>
+          <small class="meta" style="opacity:.8">JSON list of items: {"label", "page_id" or "url", "children"}. One level of nesting.</small>
+          <div style="display:grid;gap:6px;grid-template-columns:repeat(auto-fit,minmax(260px,1fr))">
+            <div style="display:grid;gap:6px"><label class="settings-label">Header</label><textarea id="nav-header" class="settings-input" style="min-height:140px;font-family:monospace"></textarea></div>
+            <div style="display:grid;gap:6px"><label class="settings-label">Footer</label><textarea id="nav-footer" class="settings-input" style="min-height:140px;font-family:monospace"></textarea></div>
+          </div>
+          <div class="settings-actions" style="gap:8px;align-items:center"><button id="nav-save" class="nav-btn">Save menus</button></div>
+          <div class="settings-label" style="margin-top:12px">Snippets</div>
+          <small class="meta" style="opacity:.8">Short markdown blocks shown in fixed places: banner, footer, upload_guidelines.</small>
+          <div style="display:grid;gap:6px;grid-template-columns:minmax(160px,240px) 1fr">
+            <input id="sn-key" class="settings-input" placeholder="key, e.g. footer" list="sn-keys"/>
+            <datalist id="sn-keys"><option value="banner"></option><option value="footer"></option><option value="upload_guidelines"></option></datalist>
+            <textarea id="sn-content" class="settings-input" style="min-height:100px" placeholder="Markdown (HTML allowed, sanitized)"></textarea>
+          </div>
+          <div class="settings-actions" style="gap:8px;align-items:center"><button id="sn-save" class="nav-btn">Save snippet</button><button id="sn-delete" class="link-btn" style="color:#ff6666">Delete</button></div>
+          <div id="sn-list" style="display:grid;gap:6px"></div>
         `;
 
         const invitesSection = document.createElement('section');
@@ -3301,10 +4508,14 @@ class TroughApp {
             <div style="display:grid;gap:8px;grid-template-columns:repeat(auto-fit,minmax(220px,1fr))">
               <div style="display:grid;gap:6px"><label class="settings-label" for="inv-max-uses">Max uses</label><input id="inv-max-uses" class="settings-input no-spinner" type="number" min="0" placeholder="0 = unlimited"/></div>
               <div style="display:grid;gap:6px"><label class="settings-label" for="inv-duration">Validity</label><input id="inv-duration" class="settings-input" placeholder="e.g., 24h or 7d (blank = no expiration)"/></div>
+              <div style="display:grid;gap:6px"><label class="settings-label" for="inv-note">Note</label><input id="inv-note" class="settings-input" maxlength="500" placeholder="e.g., onboarding drive, who it's for"/></div>
             </div>
             <div class="settings-actions" style="gap:8px;align-items:center">
               <button id="btn-create-invite" class="nav-btn">Create invite</button>
+              <input id="inv-email" class="settings-input" type="email" placeholder="name@example.com" style="flex:1;min-width:180px"/>
+              <button id="btn-send-invite" class="nav-btn">Send by email</button>
             </div>
+            <small style="opacity:.7">Emailed invites work once, only for that address, and last 7 days unless a validity is set.</small>
           </div>
           <div id="invite-list" style="display:grid;gap:8px"></div>
           <div id="invite-pagination" class="pager">
@@ -3314,11 +4525,22 @@ class TroughApp {
             </div>
             <div id="inv-page-info" class="meta" style="opacity:.8"></div>
           </div>
+          <div class="settings-label" style="margin-top:12px">Invite tree</div>
+          <div class="meta" style="opacity:.8">Who invited whom. Flagged counts disabled or shadowbanned accounts below each inviter.</div>
+          <div class="settings-actions" style="gap:8px;align-items:center">
+            <input id="inv-tree-user" class="settings-input" placeholder="User id (blank = whole tree)" style="flex:1"/>
+            <button id="btn-inv-tree" class="nav-btn">Show tree</button>
+          </div>
+          <div id="inv-tree" style="display:grid;gap:2px;font-family:var(--font-mono);font-size:.85rem"></div>
         `;
 
         const usersSection = document.createElement('section');
         usersSection.className = 'settings-group';
         usersSection.innerHTML = `
+          <div id="pending-regs-box" style="display:none">
+            <div class="settings-label">Waiting for approval</div>
+            <div id="pending-regs" class="user-results"></div>
+          </div>
           <div class="settings-label">User management</div>
           <input id="user-search" class="settings-input" placeholder="Search users by name or email"/>
           <div id="user-results" class="user-results"></div>
@@ -3342,12 +4564,24 @@ class TroughApp {
         const tabPages = isAdmin ? mkTab('pages', 'Add/Edit Pages') : null;
         const tabInv = mkTab('invites', 'Invitations');
         const tabUsers = mkTab('users', 'User management');
+        const tabQueue = mkTab('queue', 'Review queue');
         const tabBackups = isAdmin ? mkTab('backups', 'Backups') : null;
+        const tabWebhooks = isAdmin ? mkTab('webhooks', 'Webhooks') : null;
+        const tabStats = isAdmin ? mkTab('stats', 'Stats') : null;
+        const tabBans = isAdmin ? mkTab('bans', 'Bans') : null;
+        const tabJobs = isAdmin ? mkTab('jobs', 'Jobs') : null;
+        const tabTakedowns = isAdmin ? mkTab('takedowns', 'Takedowns') : null;
         tabsWrap.appendChild(tabSite);
         if (tabPages) tabsWrap.appendChild(tabPages);
         tabsWrap.appendChild(tabInv);
         tabsWrap.appendChild(tabUsers);
+        tabsWrap.appendChild(tabQueue);
         if (tabBackups) tabsWrap.appendChild(tabBackups);
+        if (tabWebhooks) tabsWrap.appendChild(tabWebhooks);
+        if (tabStats) tabsWrap.appendChild(tabStats);
+        if (tabBans) tabsWrap.appendChild(tabBans);
+        if (tabJobs) tabsWrap.appendChild(tabJobs);
+        if (tabTakedowns) tabsWrap.appendChild(tabTakedowns);
         wrap.appendChild(tabsWrap);
         // Sections container
         const sections = document.createElement('div');
@@ -3355,21 +4589,39 @@ class TroughApp {
         if (isAdmin) sections.appendChild(pagesSection);
         sections.appendChild(invitesSection);
         sections.appendChild(usersSection);
+        const queueSection = document.createElement('section');
+        queueSection.className = 'settings-group';
+        queueSection.innerHTML = `
+          <div class="settings-label">Review queue</div>
+          <div class="meta" style="opacity:.8">Uploads held for review stay out of feeds and profiles until approved. Rejecting deletes the image and notifies the uploader.</div>
+          <div id="queue-list" style="display:grid;gap:8px;margin-top:8px"></div>`;
+        sections.appendChild(queueSection);
         let backupsSection = null;
         if (isAdmin) {
             backupsSection = document.createElement('section');
             backupsSection.className = 'settings-group';
             backupsSection.innerHTML = `
-              <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between"><span>Backups</span><small class="meta" style="opacity:.8">Database only; images are not included</small></div>
+              <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between"><span>Backups</span><small class="meta" style="opacity:.8">Database only unless local uploads are included</small></div>
               <div style="display:grid;gap:8px">
                 <div class="settings-actions" style="gap:8px;align-items:center">
                   <button id="btn-backup-download" class="nav-btn">Create & download backup</button>
                   <button id="btn-backup-save" class="nav-btn">Create & save on server</button>
+                  <label style="display:flex;gap:8px;align-items:center"><input id="backup-uploads" type="checkbox"/> Include local uploads</label>
+                </div>
+                <div style="display:grid;gap:8px">
+                  <label class="settings-label">Encryption passphrase <small id="backup-enc-state" class="meta" style="opacity:.8"></small></label>
+                  <input id="backup-pass" type="password" class="settings-input" autocomplete="off" placeholder="Required for encrypted backups and restores"/>
+                  <div class="settings-actions" style="gap:8px;align-items:center"><input id="backup-new-pass" type="password" class="settings-input" autocomplete="new-password" placeholder="New passphrase (empty disables)"/><button id="btn-backup-set-pass" class="nav-btn">Set passphrase</button></div>
                 </div>
                 <div style="display:grid;gap:8px">
                   <label class="settings-label">Restore</label>
-                  <input id="backup-file" type="file" accept=".gz,.json"/>
-                  <button id="btn-backup-restore" class="nav-btn">Restore from file</button>
+                  <input id="backup-file" type="file" accept=".gz,.json,.enc"/>
+                  <div style="display:grid;gap:6px;grid-template-columns:repeat(auto-fit,minmax(220px,1fr))">
+                    <input id="backup-restore-tables" class="settings-input" placeholder="Only tables (comma-separated, optional)"/>
+                    <input id="backup-restore-user" class="settings-input" placeholder="Only user ID (optional)"/>
+                  </div>
+                  <div class="settings-actions" style="gap:8px;align-items:center"><button id="btn-backup-dryrun" class="nav-btn">Preview restore</button><button id="btn-backup-restore" class="nav-btn">Restore from file</button></div>
+                  <pre id="backup-restore-report" class="meta" style="display:none;white-space:pre-wrap;margin:0"></pre>
                 </div>
                 <div style="display:grid;gap:8px">
                   <label class="settings-label">Automatic backups</label>
@@ -3378,19 +4630,109 @@ class TroughApp {
                     <div style="display:grid;gap:6px"><label class="settings-label">Interval</label><input id="backup-interval" class="settings-input" placeholder="e.g., 24h, 7h"/></div>
                     <div style="display:grid;gap:6px"><label class="settings-label">Keep days</label><input id="backup-keep" class="settings-input no-spinner" type="number" min="1"/></div>
                   </div>
+                  <label style="display:flex;gap:8px;align-items:center"><input id="backup-remote" type="checkbox"/> Also upload to S3/R2</label>
+                  <div style="display:grid;gap:6px"><label class="settings-label">Backup bucket (required, private, not the uploads bucket)</label><input id="backup-remote-bucket" class="settings-input" placeholder="e.g., trough-backups"/></div>
                   <div class="settings-actions" style="gap:8px;align-items:center"><button id="btn-save-backup-settings" class="nav-btn">Save backup settings</button></div>
                 </div>
                 <div style="display:grid;gap:8px">
                   <label class="settings-label">Saved backups</label>
                   <div id="backup-list" style="display:grid;gap:6px"></div>
                 </div>
+                <div style="display:grid;gap:8px">
+                  <div class="settings-actions" style="gap:8px;align-items:center;justify-content:space-between"><label class="settings-label">Remote backups</label><button id="btn-backup-push" class="nav-btn">Create & upload now</button></div>
+                  <div id="backup-remote-list" style="display:grid;gap:6px"></div>
+                </div>
               </div>`;
             sections.appendChild(backupsSection);
         }
+        let webhooksSection = null;
+        if (isAdmin) {
+            webhooksSection = document.createElement('section');
+            webhooksSection.className = 'settings-group';
+            webhooksSection.innerHTML = `
+              <div class="settings-label">Webhooks</div>
+              <div class="meta" style="opacity:.8">POST signed JSON to external services when site events happen. Verify <code>X-Trough-Signature</code> (HMAC-SHA256 of <code>timestamp.body</code> using the secret).</div>
+              <div style="display:grid;gap:8px;margin:8px 0">
+                <input id="wh-url" class="settings-input" placeholder="https://example.com/hooks/trough"/>
+                <input id="wh-desc" class="settings-input" placeholder="Description (optional)" maxlength="200"/>
+                <div id="wh-events" style="display:flex;gap:10px;flex-wrap:wrap"></div>
+                <div class="settings-actions"><button id="btn-wh-create" class="nav-btn">Add webhook</button></div>
+                <div id="wh-secret" class="meta" style="display:none;word-break:break-all"></div>
+              </div>
+              <div id="wh-list" style="display:grid;gap:8px"></div>
+              <div class="settings-actions" style="gap:8px;align-items:center;justify-content:space-between;margin-top:8px"><label class="settings-label">Recent deliveries</label><button id="btn-wh-deliveries" class="link-btn">Refresh</button></div>
+              <div id="wh-deliveries" style="display:grid;gap:6px"></div>`;
+            sections.appendChild(webhooksSection);
+        }
+        let statsSection = null;
+        if (isAdmin) {
+            statsSection = document.createElement('section');
+            statsSection.className = 'settings-group';
+            statsSection.innerHTML = `
+              <div class="settings-label" style="display:flex;align-items:center;justify-content:space-between"><span>Stats</span>
+                <select id="stats-range" class="settings-input" style="width:auto"><option value="24h">24 hours</option><option value="7d">7 days</option><option value="30d" selected>30 days</option><option value="90d">90 days</option><option value="365d">1 year</option></select></div>
+              <div id="stats-totals" class="meta" style="opacity:.8;margin-bottom:8px"></div>
+              <div id="stats-series" style="display:grid;gap:4px"></div>
+              <div style="display:grid;gap:12px;grid-template-columns:repeat(auto-fit,minmax(220px,1fr));margin-top:12px">
+                <div><label class="settings-label">AI providers</label><div id="stats-providers" style="display:grid;gap:4px"></div></div>
+                <div><label class="settings-label">Top uploaders</label><div id="stats-uploaders" style="display:grid;gap:4px"></div></div>
+                <div><label class="settings-label">Blocked sign-ups</label><div id="stats-blocked" style="display:grid;gap:4px"></div></div>
+              </div>`;
+            sections.appendChild(statsSection);
+        }
+        let bansSection = null;
+        if (isAdmin) {
+            bansSection = document.createElement('section');
+            bansSection.className = 'settings-group';
+            bansSection.innerHTML = `
+              <div class="settings-label">Bans</div>
+              <div class="meta" style="opacity:.8">Refuse registration and login from IP addresses or CIDR ranges, and registration and login with email addresses at a domain (subdomains included).</div>
+              <div style="display:grid;gap:8px;margin:8px 0">
+                <div style="display:flex;gap:8px;flex-wrap:wrap">
+                  <select id="ban-kind" class="settings-input" style="width:auto"><option value="ip">IP / CIDR</option><option value="email_domain">Email domain</option></select>
+                  <input id="ban-value" class="settings-input" style="flex:1;min-width:180px" placeholder="203.0.113.0/24 or example.com"/>
+                </div>
+                <input id="ban-reason" class="settings-input" placeholder="Reason (optional)" maxlength="500"/>
+                <label style="display:flex;gap:8px;align-items:center">Expires <input id="ban-expires" class="settings-input" type="datetime-local" style="width:auto"/> (leave empty for permanent)</label>
+                <div class="settings-actions"><button id="btn-ban-create" class="nav-btn">Add ban</button></div>
+              </div>
+              <div id="ban-list" style="display:grid;gap:8px"></div>
+              <div class="settings-actions" style="gap:8px;align-items:center;justify-content:space-between;margin-top:8px"><label class="settings-label">Audit log</label><button id="btn-ban-audit" class="link-btn">Refresh</button></div>
+              <div id="ban-audit" style="display:grid;gap:6px"></div>`;
+            sections.appendChild(bansSection);
+        }
+        let jobsSection = null;
+        if (isAdmin) {
+            jobsSection = document.createElement('section');
+            jobsSection.className = 'settings-group';
+            jobsSection.innerHTML = `
+              <div class="settings-label">Background jobs</div>
+              <div class="meta" style="opacity:.8">Mail delivery, backups, storage migrations and reconciliation run here. Periodic jobs re-arm after every run; failed one-off jobs are retried with backoff and then dead-lettered.</div>
+              <div class="settings-actions" style="gap:8px;align-items:center;margin:8px 0">
+                <select id="jobs-status" class="settings-input" style="width:auto"><option value="">All</option><option value="pending">Pending</option><option value="running">Running</option><option value="done">Done</option><option value="dead">Dead</option></select>
+                <button id="btn-jobs-refresh" class="link-btn">Refresh</button>
+              </div>
+              <div id="jobs-list" style="display:grid;gap:8px"></div>`;
+            sections.appendChild(jobsSection);
+        }
+        let takedownsSection = null;
+        if (isAdmin) {
+            takedownsSection = document.createElement('section');
+            takedownsSection.className = 'settings-group';
+            takedownsSection.innerHTML = `
+              <div class="settings-label">Copyright takedowns</div>
+              <div class="meta" style="opacity:.8">Requests filed through the public form at /takedown. Disable hides the image from everyone but its owner while you review; remove deletes it; reject restores it. Every step is logged and the owner is notified.</div>
+              <div class="settings-actions" style="gap:8px;align-items:center;margin:8px 0">
+                <select id="takedowns-status" class="settings-input" style="width:auto"><option value="">All</option><option value="open" selected>Open</option><option value="disabled">Disabled</option><option value="removed">Removed</option><option value="rejected">Rejected</option></select>
+                <button id="btn-takedowns-refresh" class="link-btn">Refresh</button>
+              </div>
+              <div id="takedowns-list" style="display:grid;gap:8px"></div>`;
+            sections.appendChild(takedownsSection);
+        }
         wrap.appendChild(sections);
         const showSection = (name) => {
-            const map = { site: siteSection, pages: pagesSection, invites: invitesSection, users: usersSection, backups: backupsSection };
-            [siteSection, pagesSection, invitesSection, usersSection, backupsSection].forEach(sec => { if (sec) sec.style.display = 'none'; });
+            const map = { site: siteSection, pages: pagesSection, invites: invitesSection, users: usersSection, queue: queueSection, backups: backupsSection, webhooks: webhooksSection, stats: statsSection, bans: bansSection, jobs: jobsSection, takedowns: takedownsSection };
+            [siteSection, pagesSection, invitesSection, usersSection, queueSection, backupsSection, webhooksSection, statsSection, bansSection, jobsSection, takedownsSection].forEach(sec => { if (sec) sec.style.display = 'none'; });
             if (map[name]) map[name].style.display = 'block';
             const setActive = (btn, on) => {
                 if (!btn) return;
@@ -3403,7 +4745,7 @@ class TroughApp {
                     btn.classList.remove('active');
                 }
             };
-            setActive(tabSite, name==='site'); setActive(tabPages, name==='pages'); setActive(tabInv, name==='invites'); setActive(tabUsers, name==='users'); setActive(tabBackups, name==='backups');
+            setActive(tabSite, name==='site'); setActive(tabPages, name==='pages'); setActive(tabInv, name==='invites'); setActive(tabUsers, name==='users'); setActive(tabQueue, name==='queue'); setActive(tabBackups, name==='backups'); setActive(tabWebhooks, name==='webhooks'); setActive(tabStats, name==='stats'); setActive(tabBans, name==='bans'); setActive(tabJobs, name==='jobs'); setActive(tabTakedowns, name==='takedowns');
         };
         // Default tab
         showSection('site');
@@ -3412,7 +4754,13 @@ class TroughApp {
         if (tabPages) tabPages.onclick = () => showSection('pages');
         tabInv.onclick = () => showSection('invites');
         tabUsers.onclick = () => showSection('users');
+        tabQueue.onclick = () => { showSection('queue'); this.loadModerationQueue(); };
         if (tabBackups) tabBackups.onclick = () => showSection('backups');
+        if (tabWebhooks) tabWebhooks.onclick = () => { showSection('webhooks'); this.loadAdminWebhooks(); };
+        if (tabStats) tabStats.onclick = () => { showSection('stats'); this.loadAdminStats(); };
+        if (tabBans) tabBans.onclick = () => { showSection('bans'); this.loadAdminBans(); };
+        if (tabJobs) tabJobs.onclick = () => { showSection('jobs'); this.loadAdminJobs(); };
+        if (tabTakedowns) tabTakedowns.onclick = () => { showSection('takedowns'); this.loadAdminTakedowns(); };
         
         this.gallery.appendChild(wrap);
 
@@ -3429,6 +4777,10 @@ class TroughApp {
                     if (be) be.checked = !!s.backup_enabled;
                     if (bi) bi.value = s.backup_interval || '24h';
                     if (bk) bk.value = s.backup_keep_days || 7;
+                    const brm = backupsSection.querySelector('#backup-remote');
+                    const brb = backupsSection.querySelector('#backup-remote-bucket');
+                    if (brm) brm.checked = !!s.backup_remote_enabled;
+                    if (brb) brb.value = s.backup_remote_bucket || '';
                 } catch {}
                 // Load server backups list
                 const listEl = backupsSection.querySelector('#backup-list');
@@ -3437,6 +4789,8 @@ class TroughApp {
                     listEl.innerHTML = '';
                     if (!r.ok) return;
                     const d = await r.json().catch(()=>({backups:[]}));
+                    const encState = backupsSection.querySelector('#backup-enc-state');
+                    if (encState) encState.textContent = d.encrypted ? '(encryption enabled)' : '(backups are not encrypted)';
                     (d.backups||[]).forEach(f => {
                         const row = document.createElement('div');
                         row.style.cssText = 'display:grid;grid-template-columns:1fr auto auto;gap:8px;align-items:center;border:1px solid var(--border);border-radius:8px;padding:8px;';
@@ -3454,11 +4808,51 @@ class TroughApp {
                     });
                 };
                 await loadList();
+                const remoteListEl = backupsSection.querySelector('#backup-remote-list');
+                const loadRemote = async () => {
+                    const r = await fetch('/api/admin/backups/remote', { credentials:'include' });
+                    remoteListEl.innerHTML = '';
+                    if (!r.ok) { remoteListEl.innerHTML = '<div class="meta" style="opacity:.8">Remote backups not configured</div>'; return; }
+                    const d = await r.json().catch(()=>({backups:[]}));
+                    (d.backups||[]).forEach(f => {
+                        const row = document.createElement('div');
+                        row.style.cssText = 'display:grid;grid-template-columns:1fr auto auto;gap:8px;align-items:center;border:1px solid var(--border);border-radius:8px;padding:8px;';
+                        const sizeMB = (f.size/1024/1024).toFixed(2);
+                        row.innerHTML = `<div><div style="font-weight:600">${this.escapeHTML(String(f.name||''))}</div><div class="meta" style="opacity:.8">${sizeMB} MB • ${new Date(f.mod_time).toLocaleString()}</div></div><button class="nav-btn" data-act="restore">Restore</button><button class="nav-btn nav-btn-danger" data-act="remove">Delete</button>`;
+                        row.querySelector('[data-act="restore"]').onclick = async () => {
+                            const ok = await this.showConfirm('Restore will replace existing data. Continue?'); if (!ok) return;
+                            const rr = await this.fetchWithCSRF(`/api/admin/backups/remote/${encodeURIComponent(f.name)}/restore?${restoreScopeQS()}`, { method:'POST', headers: passHeaders(), credentials:'include' });
+                            if (rr.ok) { showRestoreReport(await rr.json().catch(()=>null)); this.showNotification('Restored'); } else { const e = await rr.json().catch(()=>({})); this.showNotification(e.error||'Restore failed','error'); }
+                        };
+                        row.querySelector('[data-act="remove"]').onclick = async () => {
+                            const ok = await this.showConfirm('Delete this remote backup?'); if (!ok) return;
+                            const rr = await this.fetchWithCSRF(`/api/admin/backups/remote/${encodeURIComponent(f.name)}`, { method:'DELETE', credentials:'include' });
+                            if (rr.status===204) { this.showNotification('Deleted'); loadRemote(); } else { this.showNotification('Delete failed','error'); }
+                        };
+                        remoteListEl.appendChild(row);
+                    });
+                };
+                loadRemote();
                 // Wire actions
                 const dlBtn = backupsSection.querySelector('#btn-backup-download');
+                const uploadsQS = () => backupsSection.querySelector('#backup-uploads')?.checked ? '?uploads=1' : '';
+                const restoreScopeQS = () => new URLSearchParams({ tables: backupsSection.querySelector('#backup-restore-tables')?.value || '', user_id: backupsSection.querySelector('#backup-restore-user')?.value || '' }).toString();
+                const showRestoreReport = (rep) => {
+                    const el = backupsSection.querySelector('#backup-restore-report'); if (!el || !rep) return;
+                    const lines = (rep.tables||[]).map(t => `${t.table}: +${t.insert} ~${t.update} =${t.unchanged} -${t.delete}${t.skipped ? ` (skipped ${t.skipped})` : ''}`);
+                    el.textContent = `${rep.dry_run ? 'Preview' : 'Restored'} (${rep.mode})\n` + lines.join('\n'); el.style.display = '';
+                };
+                const passHeaders = () => { const p = backupsSection.querySelector('#backup-pass')?.value || ''; return p ? { 'X-Backup-Passphrase': p } : {}; };
+                const setPassBtn = backupsSection.querySelector('#btn-backup-set-pass');
+                if (setPassBtn) setPassBtn.onclick = async () => {
+                    const body = { passphrase: backupsSection.querySelector('#backup-new-pass')?.value || '', current_passphrase: backupsSection.querySelector('#backup-pass')?.value || '' };
+                    const r = await this.fetchWithCSRF('/api/admin/backups/passphrase', { method:'PUT', headers:{'Content-Type':'application/json'}, credentials:'include', body: JSON.stringify(body) });
+                    if (r.ok) { const d = await r.json().catch(()=>({})); this.showNotification(d.encrypted ? 'Encryption enabled' : 'Encryption disabled'); await loadList(); }
+                    else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); }
+                };
                 if (dlBtn) dlBtn.onclick = async () => {
                     try {
-                        const r = await this.fetchWithCSRF('/api/admin/backups/download', { method:'POST', credentials:'include' });
+                        const r = await this.fetchWithCSRF('/api/admin/backups/download' + uploadsQS(), { method:'POST', headers: passHeaders(), credentials:'include' });
                         if (!r.ok) { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); return; }
                         const blob = await r.blob();
                         const cd = r.headers.get('Content-Disposition')||'';
@@ -3468,20 +4862,44 @@ class TroughApp {
                 };
                 const saveBtn = backupsSection.querySelector('#btn-backup-save');
                 if (saveBtn) saveBtn.onclick = async () => {
-                    const r = await this.fetchWithCSRF('/api/admin/backups/save', { method:'POST', credentials:'include' });
-                    if (r.ok) { this.showNotification('Saved'); await loadList(); }
+                    const r = await this.fetchWithCSRF('/api/admin/backups/save' + uploadsQS(), { method:'POST', headers: passHeaders(), credentials:'include' });
+                    if (r.status === 202) {
+                        const { job } = await r.json();
+                        this.showNotification('Backup queued');
+                        const done = await this.waitForJob(job);
+                        if (done && done.status === 'done') { this.showNotification('Saved'); await loadList(); }
+                        else this.showNotification((done && done.last_error) || 'Backup is still running; see the Jobs tab', done ? 'error' : 'info');
+                    }
+                    else if (r.ok) { this.showNotification('Saved'); await loadList(); }
                     else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); }
                 };
+                const pushBtn = backupsSection.querySelector('#btn-backup-push');
+                if (pushBtn) pushBtn.onclick = async () => {
+                    const r = await this.fetchWithCSRF('/api/admin/backups/remote' + uploadsQS(), { method:'POST', headers: passHeaders(), credentials:'include' });
+                    if (r.status === 202) {
+                        const { job } = await r.json();
+                        this.showNotification('Backup queued');
+                        const done = await this.waitForJob(job);
+                        if (done && done.status === 'done') { this.showNotification('Uploaded'); await loadList(); await loadRemote(); }
+                        else this.showNotification((done && done.last_error) || 'Backup is still running; see the Jobs tab', done ? 'error' : 'info');
+                    }
+                    else if (r.ok) { this.showNotification('Uploaded'); await loadList(); await loadRemote(); }
+                    else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Upload failed','error'); }
+                };
                 const restoreBtn = backupsSection.querySelector('#btn-backup-restore');
+                const dryRunBtn = backupsSection.querySelector('#btn-backup-dryrun');
                 const fileInp = backupsSection.querySelector('#backup-file');
-                if (restoreBtn) restoreBtn.onclick = async () => {
+                const runRestore = async (dryRun) => {
                     const f = fileInp && fileInp.files && fileInp.files[0]; if (!f) { this.showNotification('Choose a backup file','error'); return; }
-                    const ok = await this.showConfirm('Restore will replace existing data. Continue?'); if (!ok) return;
+                    if (!dryRun) { const ok = await this.showConfirm('Restore will replace existing data. Continue?'); if (!ok) return; }
                     const fd = new FormData(); fd.append('file', f);
-                    const r = await this.fetchWithCSRF('/api/admin/backups/restore', { method:'POST', credentials:'include', body: fd });
-                    if (r.status===204) { this.showNotification('Restored'); }
+                    const pass = backupsSection.querySelector('#backup-pass')?.value || ''; if (pass) fd.append('passphrase', pass);
+                    const r = await this.fetchWithCSRF(`/api/admin/backups/restore?${restoreScopeQS()}${dryRun ? '&dry_run=1' : ''}`, { method:'POST', credentials:'include', body: fd });
+                    if (r.ok) { showRestoreReport(await r.json().catch(()=>null)); this.showNotification(dryRun ? 'Preview ready' : 'Restored'); }
                     else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Restore failed','error'); }
                 };
+                if (restoreBtn) restoreBtn.onclick = () => runRestore(false);
+                if (dryRunBtn) dryRunBtn.onclick = () => runRestore(true);
                 const saveSettingsBtn = backupsSection.querySelector('#btn-save-backup-settings');
                 if (saveSettingsBtn) saveSettingsBtn.onclick = async () => {
                     const rs = await fetch('/api/admin/site', { credentials:'include' });
@@ -3492,9 +4910,24 @@ class TroughApp {
                         smtp_host: s.smtp_host||'', smtp_port: s.smtp_port||0, smtp_username: s.smtp_username||'', smtp_password: s.smtp_password||'', smtp_from_email: s.smtp_from_email||'', smtp_tls: !!s.smtp_tls,
                         require_email_verification: !!s.require_email_verification, public_registration_enabled: s.public_registration_enabled!==false,
                         analytics_enabled: !!s.analytics_enabled, analytics_provider: s.analytics_provider||'', ga4_measurement_id: s.ga4_measurement_id||'', umami_src: s.umami_src||'', umami_website_id: s.umami_website_id||'', plausible_src: s.plausible_src||'', plausible_domain: s.plausible_domain||'',
+                        csp_script_sources: s.csp_script_sources||'',
+                        default_locale: s.default_locale||'en',
                         backup_enabled: backupsSection.querySelector('#backup-enabled')?.checked || false,
                         backup_interval: backupsSection.querySelector('#backup-interval')?.value || '24h',
-                        backup_keep_days: parseInt(backupsSection.querySelector('#backup-keep')?.value||'7',10)
+                        backup_keep_days: parseInt(backupsSection.querySelector('#backup-keep')?.value||'7',10),
+                        backup_remote_enabled: backupsSection.querySelector('#backup-remote')?.checked || false,
+                        backup_remote_bucket: backupsSection.querySelector('#backup-remote-bucket')?.value || '',
+                        storage_reconcile_enabled: !!s.storage_reconcile_enabled, storage_reconcile_interval: s.storage_reconcile_interval||'24h',
+                        moderation_hold_uploads: Number(s.moderation_hold_uploads)||0,
+                        user_invite_quota: Number(s.user_invite_quota)||0,
+                        user_invite_min_account_days: Number(s.user_invite_min_account_days)||0,
+                        block_disposable_emails: !!s.block_disposable_emails,
+                        registration_min_fill_seconds: Number(s.registration_min_fill_seconds)||0,
+                        registration_approval_required: !!s.registration_approval_required,
+                        minimum_age: Number(s.minimum_age)||0, adult_site: !!s.adult_site,
+                        challenge_provider: s.challenge_provider||'', challenge_site_key: s.challenge_site_key||'', challenge_secret_key: s.challenge_secret_key||'',
+                        download_watermark_enabled: !!s.download_watermark_enabled, download_watermark_text: s.download_watermark_text||'',
+                        exif_privacy_mode: !!s.exif_privacy_mode
                     };
                     const r = await this.fetchWithCSRF('/api/admin/site', { method:'PUT', headers:{'Content-Type':'application/json'}, credentials:'include', body: JSON.stringify(body) });
                     if (r.ok) { this.showNotification('Saved'); }
@@ -3510,12 +4943,16 @@ class TroughApp {
             const pgMetaTitle = pagesSection.querySelector('#pg-meta-title');
             const pgMetaDesc = pagesSection.querySelector('#pg-meta-desc');
             const pgPub = pagesSection.querySelector('#pg-published');
+            const pgPosition = pagesSection.querySelector('#pg-position');
             const pgSave = pagesSection.querySelector('#pg-save');
             const pgNew = pagesSection.querySelector('#pg-new');
             const pgDel = pagesSection.querySelector('#pg-delete');
             const pgList = pagesSection.querySelector('#pg-list');
+            const pgHistory = pagesSection.querySelector('#pg-history');
+            const pgRevisions = pagesSection.querySelector('#pg-revisions');
             let selectedId = null;
-            const slugRe = /^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?$/;
+            const fillPageForm = (p) => { pgSlug.value = p.slug||''; pgTitle.value = p.title||''; pgMarkdown.value = p.markdown||''; pgRedirect.value = p.redirect_url||''; pgMetaTitle.value = p.meta_title||''; pgMetaDesc.value = p.meta_description||''; pgPosition.value = String(p.position||0); pgPub.checked = !!p.is_published; };
+            const slugRe = /^[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?(?:\/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?){0,3}$/;
             const loadPages = async (page=1) => {
                 const r = await fetch(`/api/admin/pages?page=${page}&limit=200`, { credentials:'include' });
                 if (!r.ok) { this.showNotification('Failed to load pages','error'); return; }
@@ -3526,7 +4963,7 @@ class TroughApp {
                     row.style.cssText = 'display:grid;grid-template-columns:1fr auto auto;gap:8px;align-items:center;border:1px solid var(--border);border-radius:8px;padding:8px;';
                     row.innerHTML = `<div><div style="font-weight:600">${this.escapeHTML(String(p.title||''))}</div><div class="meta" style="opacity:.8">/${this.escapeHTML(String(p.slug||''))} ${p.is_published?'• Published':''}</div></div><button class="nav-btn" data-act="edit">Edit</button><button class="nav-btn nav-btn-danger" data-act="remove">Delete</button>`;
                     row.querySelector('[data-act="edit"]').onclick = () => {
-                        selectedId = p.id; pgSlug.value = p.slug||''; pgTitle.value = p.title||''; pgMarkdown.value = p.markdown||''; pgRedirect.value = p.redirect_url||''; pgMetaTitle.value = p.meta_title||''; pgMetaDesc.value = p.meta_description||''; pgPub.checked = !!p.is_published;
+                        selectedId = p.id; fillPageForm(p); pgRevisions.innerHTML = '';
                     };
                     row.querySelector('[data-act="remove"]').onclick = async () => {
                         const ok = await this.showConfirm('Delete this page?'); if (!ok) return;
@@ -3537,7 +4974,29 @@ class TroughApp {
                     pgList.appendChild(row);
                 });
             };
-            pgNew.onclick = () => { selectedId = null; pgSlug.value=''; pgTitle.value=''; pgRedirect.value=''; pgMarkdown.value=''; pgMetaTitle.value=''; pgMetaDesc.value=''; pgPub.checked=false; };
+            pgNew.onclick = () => { selectedId = null; pgSlug.value=''; pgTitle.value=''; pgRedirect.value=''; pgMarkdown.value=''; pgMetaTitle.value=''; pgMetaDesc.value=''; pgPosition.value='0'; pgPub.checked=false; pgRevisions.innerHTML=''; };
+            pgHistory.onclick = async () => {
+                if (!selectedId) { this.showNotification('Select a page first','error'); return; }
+                const r = await fetch(`/api/admin/pages/${encodeURIComponent(selectedId)}/revisions?limit=50`, { credentials:'include' });
+                if (!r.ok) { this.showNotification('Failed to load history','error'); return; }
+                const d = await r.json().catch(()=>({revisions:[]}));
+                pgRevisions.innerHTML = '';
+                (d.revisions||[]).forEach((rev, i) => {
+                    const row = document.createElement('div');
+                    row.style.cssText = 'display:grid;grid-template-columns:1fr auto;gap:8px;align-items:center;border:1px dashed var(--border);border-radius:8px;padding:6px 8px;';
+                    const who = rev.author_username ? '@' + this.escapeHTML(String(rev.author_username)) : 'system';
+                    row.innerHTML = `<div class="meta">#${rev.rev} · ${this.escapeHTML(new Date(rev.created_at).toLocaleString())} · ${who}${i===0?' · current':''}</div>${i===0 && (d.page||1)===1 ? '' : '<button class="link-btn" data-act="restore">Restore</button>'}`;
+                    const btn = row.querySelector('[data-act="restore"]');
+                    if (btn) btn.onclick = async () => {
+                        const ok = await this.showConfirm(`Restore revision #${rev.rev}? The current content stays in history.`); if (!ok) return;
+                        const rr = await this.fetchWithCSRF(`/api/admin/pages/${encodeURIComponent(selectedId)}/revisions/${rev.rev}/restore`, { method:'POST', credentials:'include' });
+                        const e = await rr.json().catch(()=>({}));
+                        if (rr.ok) { this.showNotification(`Restored revision #${rev.rev}`); fillPageForm(e.page||rev); loadPages(1); pgHistory.click(); }
+                        else { this.showNotification(e.error||'Restore failed','error'); }
+                    };
+                    pgRevisions.appendChild(row);
+                });
+            };
             pgDel.onclick = async () => { if (!selectedId) return; const ok = await this.showConfirm('Delete this page?'); if (!ok) return; const r = await this.fetchWithCSRF(`/api/admin/pages/${encodeURIComponent(selectedId)}`, { method:'DELETE', credentials:'include' }); if (r.status===204) { this.showNotification('Deleted'); selectedId=null; pgNew.click(); loadPages(1); } else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Delete failed','error'); } };
             pgSave.onclick = async () => {
                 const slug = (pgSlug.value||'').trim().toLowerCase();
@@ -3550,14 +5009,70 @@ class TroughApp {
                     redirect_url: (pgRedirect.value||'').trim() || null,
                     meta_title: (pgMetaTitle.value||'').trim() || null,
                     meta_description: (pgMetaDesc.value||'').trim() || null,
+                    position: parseInt(pgPosition.value, 10) || 0,
                 };
                 const method = selectedId ? 'PUT' : 'POST';
                 const url = selectedId ? `/api/admin/pages/${encodeURIComponent(selectedId)}` : '/api/admin/pages';
                 const r = await this.fetchWithCSRF(url, { method, headers:{'Content-Type':'application/json'}, credentials:'include', body: JSON.stringify(body) });
-                if (r.ok || r.status===201) { this.showNotification('Saved'); loadPages(1); }
+                if (r.ok || r.status===201) { this.showNotification('Saved'); loadPages(1); pgRevisions.innerHTML = ''; }
                 else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Save failed','error'); }
             };
             await loadPages(1);
+            // Navigation menus
+            const navBoxes = { header: pagesSection.querySelector('#nav-header'), footer: pagesSection.querySelector('#nav-footer') };
+            const strip = (items) => (items||[]).map(it => { const o = { label: it.label }; if (it.page_id) o.page_id = it.page_id; else o.url = it.url; if (it.page_slug) o.page_slug = it.page_slug; if (it.children && it.children.length) o.children = strip(it.children); return o; });
+            const loadNav = async () => {
+                const r = await fetch('/api/admin/navigation', { credentials:'include' });
+                if (!r.ok) return;
+                const d = await r.json().catch(()=>({menus:{}}));
+                Object.keys(navBoxes).forEach(m => { navBoxes[m].value = JSON.stringify(strip((d.menus||{})[m]), null, 2); });
+            };
+            pagesSection.querySelector('#nav-save').onclick = async () => {
+                for (const m of Object.keys(navBoxes)) {
+                    let items;
+                    try { items = JSON.parse(navBoxes[m].value || '[]'); } catch { this.showNotification(`${m} menu is not valid JSON`,'error'); return; }
+                    const r = await this.fetchWithCSRF(`/api/admin/navigation/${m}`, { method:'PUT', headers:{'Content-Type':'application/json'}, credentials:'include', body: JSON.stringify({ items }) });
+                    if (!r.ok) { const e = await r.json().catch(()=>({})); this.showNotification(`${m}: ${e.error||'Save failed'}`,'error'); return; }
+                }
+                this.showNotification('Menus saved'); loadNav();
+            };
+            await loadNav();
+            // Snippets
+            const snKey = pagesSection.querySelector('#sn-key');
+            const snContent = pagesSection.querySelector('#sn-content');
+            const snList = pagesSection.querySelector('#sn-list');
+            let snippetRows = [];
+            const loadSnippetRows = async () => {
+                const r = await fetch('/api/admin/snippets', { credentials:'include' });
+                if (!r.ok) return;
+                snippetRows = (await r.json().catch(()=>({}))).snippets || [];
+                snList.innerHTML = '';
+                snippetRows.forEach(sn => {
+                    const row = document.createElement('button');
+                    row.className = 'link-btn';
+                    row.style.cssText = 'text-align:left';
+                    row.textContent = `${sn.key} — updated ${new Date(sn.updated_at).toLocaleString()}`;
+                    row.onclick = () => { snKey.value = sn.key; snContent.value = sn.content || ''; };
+                    snList.appendChild(row);
+                });
+            };
+            snKey.oninput = () => { const hit = snippetRows.find(sn => sn.key === snKey.value.trim().toLowerCase()); if (hit) snContent.value = hit.content || ''; };
+            pagesSection.querySelector('#sn-save').onclick = async () => {
+                const key = (snKey.value||'').trim().toLowerCase();
+                if (!/^[a-z0-9][a-z0-9_-]{0,63}$/.test(key)) { this.showNotification('Invalid key','error'); return; }
+                const r = await this.fetchWithCSRF(`/api/admin/snippets/${encodeURIComponent(key)}`, { method:'PUT', headers:{'Content-Type':'application/json'}, credentials:'include', body: JSON.stringify({ content: (snContent.value||'').replace(/\r\n/g,'\n') }) });
+                if (r.ok) { this.showNotification('Snippet saved'); loadSnippetRows(); this.loadSnippets(); }
+                else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Save failed','error'); }
+            };
+            pagesSection.querySelector('#sn-delete').onclick = async () => {
+                const key = (snKey.value||'').trim().toLowerCase();
+                if (!key) return;
+                const ok = await this.showConfirm(`Delete snippet "${key}"?`); if (!ok) return;
+                const r = await this.fetchWithCSRF(`/api/admin/snippets/${encodeURIComponent(key)}`, { method:'DELETE', credentials:'include' });
+                if (r.status===204) { this.showNotification('Deleted'); snKey.value=''; snContent.value=''; loadSnippetRows(); this.loadSnippets(); }
+                else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Delete failed','error'); }
+            };
+            await loadSnippetRows();
             // Define doSave function with all settings (including storage)
             const doSave = async () => {
                 const rawHost = document.getElementById('smtp-host').value.trim();
@@ -3583,6 +5098,20 @@ class TroughApp {
                     smtp_tls: document.getElementById('smtp-tls').checked,
                     require_email_verification: document.getElementById('require-verify')?.checked || false,
                     public_registration_enabled: document.getElementById('public-reg')?.checked !== false,
+                    registration_approval_required: document.getElementById('reg-approval')?.checked || false,
+                    minimum_age: parseInt(document.getElementById('minimum-age')?.value||'0',10) || 0,
+                    adult_site: document.getElementById('adult-site')?.checked || false,
+                    moderation_hold_uploads: parseInt(document.getElementById('moderation-hold')?.value||'0',10) || 0,
+                    user_invite_quota: parseInt(document.getElementById('user-invite-quota')?.value||'0',10) || 0,
+                    user_invite_min_account_days: parseInt(document.getElementById('user-invite-min-days')?.value||'0',10) || 0,
+                    block_disposable_emails: document.getElementById('block-disposable')?.checked || false,
+                    registration_min_fill_seconds: parseInt(document.getElementById('reg-min-fill')?.value||'0',10) || 0,
+                    challenge_provider: document.getElementById('challenge-provider')?.value || '',
+                    challenge_site_key: document.getElementById('challenge-site-key')?.value || '',
+                    challenge_secret_key: document.getElementById('challenge-secret-key')?.value || '',
+                    download_watermark_enabled: document.getElementById('download-watermark')?.checked || false,
+                    download_watermark_text: document.getElementById('download-watermark-text')?.value || '',
+                    exif_privacy_mode: document.getElementById('exif-privacy')?.checked || false,
                     analytics_enabled: document.getElementById('analytics-enabled')?.checked || false,
                     analytics_provider: document.getElementById('analytics-provider')?.value || '',
                     ga4_measurement_id: document.getElementById('ga4-id')?.value || '',
@@ -3590,6 +5119,8 @@ class TroughApp {
                     umami_website_id: document.getElementById('umami-website-id')?.value || '',
                     plausible_src: document.getElementById('plausible-src')?.value || '',
                     plausible_domain: document.getElementById('plausible-domain')?.value || '',
+                    csp_script_sources: document.getElementById('csp-script-sources')?.value || '',
+                    default_locale: document.getElementById('default-locale')?.value || 'en',
                 };
                 const r = await this.fetchWithCSRF('/api/admin/site', { method:'PUT', headers: { 'Content-Type':'application/json' }, credentials: 'include', body: JSON.stringify(body) });
                 if (r.ok) { this.showNotification('Saved'); await this.applyPublicSiteSettings(); }
@@ -3647,7 +5178,7 @@ class TroughApp {
                         row.innerHTML = `
                           <div class="left">
                             <div class="code">${this.escapeHTML(String(inv.code))}</div>
-                            <div class="invite-meta">Uses: ${this.escapeHTML(String(usesStr))} • Expires: ${this.escapeHTML(String(expStr))}</div>
+                            <div class="invite-meta">Uses: ${this.escapeHTML(String(usesStr))} • Expires: ${this.escapeHTML(String(expStr))}${inv.email ? ' • For ' + this.escapeHTML(String(inv.email)) : ''}${inv.note ? ' • ' + this.escapeHTML(String(inv.note)) : ''}</div>
                           </div>
                           <div class="invite-actions">
                             <button class="nav-btn" data-act="copy">Copy link</button>
@@ -3701,6 +5232,8 @@ class TroughApp {
                 const body = {};
                 if (maxUsesVal !== '') { body.max_uses = parseInt(maxUsesVal, 10); }
                 if (durationVal !== '') { body.duration = durationVal; }
+                const noteVal = document.getElementById('inv-note').value.trim();
+                if (noteVal !== '') { body.note = noteVal; }
                 const r = await this.fetchWithCSRF('/api/admin/invites', { method:'POST', headers:{ 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify(body) });
                 if (r.ok || r.status === 201) {
                     const d = await r.json().catch(()=>({}));
@@ -3712,7 +5245,52 @@ class TroughApp {
                     this.showNotification(e.error||'Create failed','error');
                 }
             };
+            const btnSendInvite = document.getElementById('btn-send-invite');
+            if (btnSendInvite) btnSendInvite.onclick = async (e) => {
+                e.preventDefault();
+                const email = document.getElementById('inv-email').value.trim();
+                if (!email) { this.showNotification('Enter an email address','error'); return; }
+                const body = { email };
+                const durationVal = document.getElementById('inv-duration').value.trim();
+                if (durationVal !== '') { body.duration = durationVal; }
+                const noteVal = document.getElementById('inv-note').value.trim();
+                if (noteVal !== '') { body.note = noteVal; }
+                btnSendInvite.disabled = true;
+                try {
+                    const r = await this.fetchWithCSRF('/api/admin/invites/send', { method:'POST', headers:{ 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify(body) });
+                    const d = await r.json().catch(()=>({}));
+                    if (!r.ok) { this.showNotification(d.error||'Send failed','error'); return; }
+                    document.getElementById('inv-email').value = '';
+                    this.showNotification('Invite sent to ' + email);
+                    await loadInvites(1);
+                } finally { btnSendInvite.disabled = false; }
+            };
             await loadInvites(1);
+            const treeBtn = document.getElementById('btn-inv-tree');
+            if (treeBtn) treeBtn.onclick = async () => {
+                const out = document.getElementById('inv-tree');
+                const uid = document.getElementById('inv-tree-user').value.trim();
+                const r = await fetch('/api/admin/invites/tree' + (uid ? '?user_id=' + encodeURIComponent(uid) : ''), { credentials:'include' });
+                const d = await r.json().catch(()=>({}));
+                if (!r.ok) { this.showNotification(d.error||'Failed to load tree','error'); return; }
+                const lines = [];
+                const walk = (n, depth) => {
+                    const flags = (n.is_disabled ? ' [disabled]' : '') + (n.is_shadowbanned ? ' [shadowbanned]' : '');
+                    const counts = n.descendants ? ` · ${n.descendants} below${n.flagged_descendants ? `, ${n.flagged_descendants} flagged` : ''}` : '';
+                    lines.push(`<div style="padding-left:${depth * 16}px${n.flagged_descendants || flags ? ';color:var(--color-danger, #ef4444)' : ''}">@${this.escapeHTML(String(n.username))}${this.escapeHTML(flags + counts)}${n.invite_note ? ' <span style="opacity:.7">— ' + this.escapeHTML(String(n.invite_note)) + '</span>' : ''}</div>`);
+                    (n.invitees || []).forEach(k => walk(k, depth + 1));
+                };
+                if (uid) {
+                    if (!d.tree) { out.innerHTML = '<small style="opacity:.7">This user is not in the invite tree</small>'; return; }
+                    const chain = (d.chain || []).map(p => '@' + this.escapeHTML(String(p.username))).reverse();
+                    if (chain.length) lines.push(`<div style="opacity:.7">Invited via ${chain.join(' → ')}</div>`);
+                    walk(d.tree, 0);
+                } else {
+                    (d.roots || []).forEach(n => walk(n, 0));
+                    if (!lines.length) lines.push('<small style="opacity:.7">No accounts registered with a tracked invite yet</small>');
+                }
+                out.innerHTML = lines.join('');
+            };
 
             // Wire up all event handlers
             const saveBtnTop = document.getElementById('btn-save-site-top');
@@ -3721,6 +5299,13 @@ class TroughApp {
             if (saveBtn) saveBtn.onclick = doSave;
             const saveCore = document.getElementById('btn-save-site-core');
             if (saveCore) saveCore.onclick = doSave;
+            // Offer every locale with translations as the site default
+            fetch('/api/admin/i18n', { credentials:'include' }).then(r => r.ok ? r.json() : null).then(d => {
+                const sel = document.getElementById('default-locale');
+                if (!sel || !d || !Array.isArray(d.locales)) return;
+                const current = sel.value;
+                sel.innerHTML = d.locales.map(l => `<option value="${this.escapeHTML(l)}"${l===current?' selected':''}>${this.escapeHTML(l)}</option>`).join('');
+            }).catch(() => {});
 
             // Wire SMTP test
             const btnTest = document.getElementById('btn-smtp-test');
@@ -3736,6 +5321,37 @@ class TroughApp {
                 }
             };
 
+            // Wire mail outbox (dead-lettered sends)
+            const btnOutbox = document.getElementById('btn-mail-outbox');
+            const outboxEl = document.getElementById('mail-outbox-list');
+            const loadOutbox = async () => {
+                const r = await fetch('/api/admin/mail/outbox?status=dead', { credentials:'include' });
+                outboxEl.innerHTML = '';
+                if (!r.ok) { outboxEl.innerHTML = '<div class="meta" style="opacity:.8">Mail outbox unavailable</div>'; return; }
+                const d = await r.json().catch(()=>({mail:[]}));
+                if (!(d.mail||[]).length) { outboxEl.innerHTML = '<div class="meta" style="opacity:.8">No failed sends</div>'; return; }
+                d.mail.forEach(m => {
+                    const row = document.createElement('div');
+                    row.style.cssText = 'display:grid;grid-template-columns:1fr auto auto;gap:8px;align-items:center;border:1px solid var(--border);border-radius:8px;padding:8px;';
+                    row.innerHTML = `<div><div style="font-weight:600">${this.escapeHTML(String(m.subject||''))} → ${this.escapeHTML(String(m.to_email||''))}</div><div class="meta" style="opacity:.8">${m.attempts} attempts • ${new Date(m.created_at).toLocaleString()} • ${this.escapeHTML(String(m.last_error||''))}</div></div><button class="nav-btn" data-act="retry">Retry</button><button class="nav-btn nav-btn-danger" data-act="remove">Delete</button>`;
+                    row.querySelector('[data-act="retry"]').onclick = async () => {
+                        const rr = await this.fetchWithCSRF(`/api/admin/mail/outbox/${m.id}/retry`, { method:'POST', credentials:'include' });
+                        if (rr.status===204) { this.showNotification('Requeued'); loadOutbox(); } else { this.showNotification('Retry failed','error'); }
+                    };
+                    row.querySelector('[data-act="remove"]').onclick = async () => {
+                        const rr = await this.fetchWithCSRF(`/api/admin/mail/outbox/${m.id}`, { method:'DELETE', credentials:'include' });
+                        if (rr.status===204) { loadOutbox(); } else { this.showNotification('Delete failed','error'); }
+                    };
+                    outboxEl.appendChild(row);
+                });
+            };
+            if (btnOutbox && outboxEl) btnOutbox.onclick = () => {
+                const show = outboxEl.style.display === 'none';
+                outboxEl.style.display = show ? 'grid' : 'none';
+                btnOutbox.textContent = show ? 'Hide' : 'Show';
+                if (show) loadOutbox();
+            };
+
             // Wire storage test
             const btnTestStorage = document.getElementById('btn-test-storage');
             if (btnTestStorage) btnTestStorage.onclick = async () => {
@@ -3778,11 +5394,51 @@ class TroughApp {
                 const row = document.createElement('div');
                 row.className = 'user-row';
                 const left = document.createElement('div'); left.className = 'left';
-                left.innerHTML = `<div class="handle">@${this.escapeHTML(String(u.username))}</div><div class="id">${this.escapeHTML(String(u.id))}</div>`;
+                left.innerHTML = `<div class="handle">@${this.escapeHTML(String(u.username))}</div><div class="id">${this.escapeHTML(String(u.id))}${u.suspension ? (u.suspension.until ? ' · suspended until ' + this.escapeHTML(new Date(u.suspension.until).toLocaleString()) : ' · disabled') : ''}${u.is_shadowbanned ? ' · shadowbanned' : ''}</div>`;
                 const right = document.createElement('div'); right.className='actions';
                 const modBtn = document.createElement('button'); modBtn.className='nav-btn'; modBtn.textContent = u.is_moderator ? 'Unmod' : 'Make mod';
                 modBtn.onclick = async () => { const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}`, { method:'PATCH', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify({ is_moderator: !u.is_moderator }) }); if (r.ok) { u.is_moderator = !u.is_moderator; modBtn.textContent = u.is_moderator ? 'Unmod' : 'Make mod'; } };
                 right.appendChild(modBtn);
+                if (isAdminLocal) {
+                    const verifyBtn = document.createElement('button'); verifyBtn.className='nav-btn'; verifyBtn.textContent = u.is_verified ? 'Unverify' : 'Verify';
+                    verifyBtn.title = u.verified_reason || 'Grant the verified badge';
+                    verifyBtn.onclick = async () => {
+                        const body = { verified: !u.is_verified };
+                        if (body.verified) { body.reason = (window.prompt(`What was verified about @${u.username}? (shown with the badge)`) || '').trim(); if (!body.reason) return; }
+                        const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/verified`, { method:'PUT', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify(body) });
+                        if (r.ok) { u.is_verified = body.verified; u.verified_reason = body.reason; verifyBtn.textContent = u.is_verified ? 'Unverify' : 'Verify'; verifyBtn.title = u.verified_reason || 'Grant the verified badge'; } else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); }
+                    };
+                    right.appendChild(verifyBtn);
+                }
+                if (isAdminLocal || !(u.is_admin || u.is_moderator)) {
+                    const shadowBtn = document.createElement('button'); shadowBtn.className='nav-btn'; shadowBtn.textContent = u.is_shadowbanned ? 'Unshadowban' : 'Shadowban';
+                    shadowBtn.title = 'Hide this user\'s uploads from public feeds without telling them';
+                    shadowBtn.onclick = async () => { const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}`, { method:'PATCH', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify({ is_shadowbanned: !u.is_shadowbanned }) }); if (r.ok) { u.is_shadowbanned = !u.is_shadowbanned; shadowBtn.textContent = u.is_shadowbanned ? 'Unshadowban' : 'Shadowban'; } else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); } };
+                    right.appendChild(shadowBtn);
+                    const suspendBtn = document.createElement('button'); suspendBtn.className='nav-btn';
+                    const suspended = () => !!u.suspension;
+                    suspendBtn.textContent = suspended() ? 'Lift suspension' : 'Suspend';
+                    suspendBtn.onclick = async () => {
+                        if (suspended()) {
+                            const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/suspend`, { method:'DELETE', credentials:'include' });
+                            if (r.status === 204) { u.suspension = null; u.is_disabled = false; suspendBtn.textContent = 'Suspend'; this.showNotification('Suspension lifted'); }
+                            else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); }
+                            return;
+                        }
+                        const daysRaw = window.prompt(`Suspend @${u.username} for how many days?${isAdminLocal ? ' (leave empty for indefinitely)' : ''}`, '1');
+                        if (daysRaw === null) return;
+                        const days = parseFloat(daysRaw);
+                        if (daysRaw.trim() !== '' && !(days > 0)) { this.showNotification('Enter a number of days', 'error'); return; }
+                        const reason = (window.prompt('Reason shown to the user (optional)') || '').trim();
+                        const body = { reason };
+                        if (days > 0) body.until = new Date(Date.now() + days * 86400000).toISOString();
+                        const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/suspend`, { method:'POST', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify(body) });
+                        const x = await r.json().catch(()=>({}));
+                        if (r.ok) { u.suspension = x.user?.suspension || { reason, until: body.until || null }; u.is_disabled = true; suspendBtn.textContent = 'Lift suspension'; this.showNotification('User suspended'); }
+                        else this.showNotification(x.error||'Failed','error');
+                    };
+                    right.appendChild(suspendBtn);
+                }
                 if (isAdminLocal) {
                     const adminBtn = document.createElement('button'); adminBtn.className='nav-btn'; adminBtn.textContent = u.is_admin ? 'Revoke admin' : 'Make admin';
                     adminBtn.onclick = async () => { const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}`, { method:'PATCH', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify({ is_admin: !u.is_admin }) }); if (r.ok) { u.is_admin = !u.is_admin; adminBtn.textContent = u.is_admin ? 'Revoke admin' : 'Make admin'; } };
@@ -3793,6 +5449,23 @@ class TroughApp {
                     const verifyBtn = document.createElement('button'); verifyBtn.className='nav-btn'; verifyBtn.textContent='Send verify';
                     verifyBtn.onclick = async () => { const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/send-verification`, { method:'POST', credentials:'include' }); if (r.status===204) this.showNotification('Verification sent'); else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); } };
                     right.appendChild(verifyBtn);
+                    if (!u.is_admin) {
+                        const impBtn = document.createElement('button'); impBtn.className='nav-btn'; impBtn.textContent='Login as';
+                        impBtn.title = 'Act as this user for up to an hour to debug what they see; every request is audited';
+                        impBtn.onclick = async () => {
+                            const reason = (window.prompt(`Why are you logging in as @${u.username}? (recorded in the audit log)`) || '').trim();
+                            if (!reason) return;
+                            const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/impersonate`, { method:'POST', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify({ reason, minutes: 15 }) });
+                            const d = await r.json().catch(()=>({}));
+                            if (!r.ok) { this.showNotification(d.error||'Failed','error'); return; }
+                            try { localStorage.setItem('token', d.token); localStorage.setItem('user', JSON.stringify(d.user)); } catch {}
+                            location.href = '/';
+                        };
+                        right.appendChild(impBtn);
+                    }
+                    const msgBtn = document.createElement('button'); msgBtn.className='nav-btn'; msgBtn.textContent='Message';
+                    msgBtn.onclick = async () => { const text = (window.prompt(`Message to @${u.username}`) || '').trim(); if (!text) return; const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}/message`, { method:'POST', headers: { 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify({ message: text }) }); if (r.ok) this.showNotification('Message sent'); else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Failed','error'); } };
+                    right.appendChild(msgBtn);
                     const delBtn = document.createElement('button'); delBtn.className='nav-btn'; delBtn.style.background='var(--color-danger)'; delBtn.style.color='#fff'; delBtn.textContent='Delete';
                     delBtn.onclick = async () => { const ok = await this.showConfirm('Delete user?'); if (!ok) return; const r = await this.fetchWithCSRF(`/api/admin/users/${u.id}`, { method:'DELETE', credentials:'include' }); if (r.status===204) { row.remove(); } else { this.showNotification('Delete failed','error'); } };
                     right.appendChild(delBtn);
@@ -3824,6 +5497,40 @@ class TroughApp {
             }
         };
         searchInput.addEventListener('input', (e) => { clearTimeout(timer); timer = setTimeout(() => doSearch(e.target.value.trim(), 1), 250); });
+
+        // Registration approval queue (admins only; the list carries emails)
+        const loadPendingRegs = async () => {
+            const box = document.getElementById('pending-regs-box'); const list = document.getElementById('pending-regs');
+            if (!isAdminLocal || !box || !list) return;
+            const r = await this.fetchWithCSRF('/api/admin/registrations?limit=100', { credentials: 'include' });
+            const d = r.ok ? await r.json() : {};
+            const users = d.users || [];
+            box.style.display = users.length ? '' : 'none';
+            list.innerHTML = '';
+            users.forEach(u => {
+                const row = document.createElement('div'); row.className = 'user-row';
+                const left = document.createElement('div'); left.className = 'left';
+                left.innerHTML = `<div class="handle">@${this.escapeHTML(String(u.username))}</div><div class="id">${this.escapeHTML(String(u.email||''))} · ${this.escapeHTML(new Date(u.created_at).toLocaleString())}</div>`;
+                const right = document.createElement('div'); right.className = 'actions';
+                const approve = document.createElement('button'); approve.className = 'nav-btn'; approve.textContent = 'Approve';
+                approve.onclick = async () => {
+                    const r = await this.fetchWithCSRF(`/api/admin/registrations/${u.id}/approve`, { method: 'POST', credentials: 'include' });
+                    if (r.ok) { this.showNotification(`Approved @${u.username}`); loadPendingRegs(); }
+                    else { const e = await r.json().catch(() => ({})); this.showNotification(e.error || 'Failed', 'error'); }
+                };
+                const reject = document.createElement('button'); reject.className = 'link-btn'; reject.textContent = 'Reject';
+                reject.onclick = async () => {
+                    const reason = prompt(`Reject @${u.username}? Their account is deleted. Optional note for their email:`, '');
+                    if (reason === null) return;
+                    const r = await this.fetchWithCSRF(`/api/admin/registrations/${u.id}/reject`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify({ reason }) });
+                    if (r.status === 204) { this.showNotification(`Rejected @${u.username}`); loadPendingRegs(); }
+                    else { const e = await r.json().catch(() => ({})); this.showNotification(e.error || 'Failed', 'error'); }
+                };
+                right.appendChild(approve); right.appendChild(reject);
+                row.appendChild(left); row.appendChild(right); list.appendChild(row);
+            });
+        };
+        loadPendingRegs();
         prevBtn?.addEventListener('click', () => { const q = searchInput.value.trim(); if (!q) return; doSearch(q, Math.max(1, currentPage - 1)); });
         nextBtn?.addEventListener('click', () => { const q = searchInput.value.trim(); if (!q) return; doSearch(q, currentPage + 1); });
     }
@@ -3918,12 +5625,105 @@ class TroughApp {
         };
     }
 
+    // Public copyright takedown form. The image can be given as its page or file URL.
+    async renderTakedownPage() {
+        this.gallery.innerHTML = '';
+        if (this.gallery) this.gallery.className = 'gallery settings-mode';
+        if (this.profileTop) this.profileTop.innerHTML = '';
+        const wrap = document.createElement('div'); wrap.className = 'settings-wrap';
+        wrap.innerHTML = `
+          <section class="settings-group">
+            <div class="settings-label">Copyright takedown request</div>
+            <div class="meta" style="opacity:.8">Report an image on this site that infringes a work you own or represent. Staff review every request and may disable the image while they do.</div>
+            <input type="text" id="td-image" class="settings-input" placeholder="Link to the image"/>
+            <input type="text" id="td-name" class="settings-input" placeholder="Your full name" autocomplete="name"/>
+            <input type="email" id="td-email" class="settings-input" placeholder="Email address" autocomplete="email"/>
+            <input type="text" id="td-org" class="settings-input" placeholder="Organization (optional)" autocomplete="organization"/>
+            <textarea id="td-work" class="settings-input" rows="3" placeholder="The original work (title, where it is published)"></textarea>
+            <textarea id="td-desc" class="settings-input" rows="4" placeholder="Anything else staff should know (optional)"></textarea>
+            <label style="display:flex;gap:6px;align-items:flex-start"><input type="checkbox" id="td-good-faith"/> I believe in good faith that this use is not authorized by the owner, its agent or the law.</label>
+            <label style="display:flex;gap:6px;align-items:flex-start"><input type="checkbox" id="td-accurate"/> The information in this request is accurate, and under penalty of perjury I am the owner or authorized to act for the owner.</label>
+            <input type="text" id="td-signature" class="settings-input" placeholder="Signature (type your full name)"/>
+            <div class="settings-actions"><button id="td-submit" class="nav-btn">Submit request</button></div>
+          </section>`;
+        this.gallery.appendChild(wrap);
+        const pre = new URLSearchParams(location.search).get('image');
+        if (pre) wrap.querySelector('#td-image').value = pre;
+        const btn = wrap.querySelector('#td-submit');
+        btn.onclick = async () => {
+            const val = (id) => wrap.querySelector(id).value.trim();
+            const body = {
+                image: val('#td-image'), name: val('#td-name'), email: val('#td-email'), organization: val('#td-org'),
+                original_work: val('#td-work'), description: val('#td-desc'), signature: val('#td-signature'),
+                good_faith: wrap.querySelector('#td-good-faith').checked, accurate: wrap.querySelector('#td-accurate').checked,
+            };
+            btn.disabled = true;
+            try {
+                const r = await this.fetchWithCSRF('/api/takedown', { method: 'POST', headers: { 'Content-Type': 'application/json' }, credentials: 'include', body: JSON.stringify(body) });
+                const d = await r.json().catch(() => ({}));
+                if (r.status === 201) {
+                    wrap.innerHTML = `<section class="settings-group"><div class="settings-label">Request received</div><div class="meta">Staff will review it. Your reference is <code>${this.escapeHTML(String(d.id))}</code>.</div></section>`;
+                } else {
+                    this.showNotification(d.error || 'Request failed', 'error');
+                }
+            } finally { btn.disabled = false; }
+        };
+    }
+
     async renderVerifyPage() {
         const token = new URLSearchParams(location.search).get('token') || '';
         try { const r = await fetch('/api/verify-email', { method:'POST', headers:{'Content-Type':'application/json'}, body: JSON.stringify({ token }) }); if (r.status===204) this.showNotification('Email verified'); else { const e = await r.json().catch(()=>({})); this.showNotification(e.error||'Verification failed','error'); } } catch {}
         history.replaceState({}, '', '/'); this.init();
     }
 
+    async renderEmailChangePage() {
+        const token = new URLSearchParams(location.search).get('token') || '';
+        const confirming = location.pathname === '/confirm-email';
+        try {
+            const r = await fetch(confirming ? '/api/confirm-email-change' : '/api/cancel-email-change', { method:'POST', headers:{'Content-Type':'application/json'}, body: JSON.stringify({ token }) });
+            const d = await r.json().catch(()=>({}));
+            if (r.ok) this.showNotification(confirming ? 'Email changed to ' + (d.email || 'the new address') : 'Email change cancelled. Consider changing your password.');
+            else this.showNotification(d.error || 'That link is no longer valid', 'error');
+        } catch {}
+        history.replaceState({}, '', '/'); this.init();
+    }
+
+    // Addresses the rate limiter has flagged must solve the site's challenge (built-in proof
+    // of work, hCaptcha or Turnstile) before register/forgot-password go through.
+    async solveAuthChallenge(desc, mount) {
+        if (!desc || !desc.provider) return {};
+        if (desc.provider === 'pow') {
+            const p = desc.pow || {};
+            if (mount) mount.textContent = 'Checking your browser…';
+            return { challenge_token: p.challenge, challenge_solution: await this.solvePoW(p.challenge, Number(p.difficulty) || 0) };
+        }
+        const hc = desc.provider === 'hcaptcha';
+        const api = hc ? 'hcaptcha' : 'turnstile';
+        if (!window[api]) {
+            await new Promise((resolve, reject) => {
+                const sc = document.createElement('script');
+                sc.src = hc ? 'https://js.hcaptcha.com/1/api.js?render=explicit' : 'https://challenges.cloudflare.com/turnstile/v0/api.js?render=explicit';
+                sc.async = true; sc.onload = resolve; sc.onerror = () => reject(new Error('Challenge failed to load'));
+                document.head.appendChild(sc);
+            });
+        }
+        mount.innerHTML = '';
+        const box = document.createElement('div'); mount.appendChild(box);
+        return new Promise((resolve) => {
+            window[api].render(box, { sitekey: desc.site_key, callback: (token) => resolve({ challenge_token: token }) });
+        });
+    }
+
+    async solvePoW(challenge, difficulty) {
+        const enc = new TextEncoder();
+        for (let n = 0; ; n++) {
+            const sum = new Uint8Array(await crypto.subtle.digest('SHA-256', enc.encode(challenge + ':' + n)));
+            let zeros = 0;
+            for (const b of sum) { if (b === 0) { zeros += 8; continue; } zeros += Math.clz32(b) - 24; break; }
+            if (zeros >= difficulty) return String(n);
+        }
+    }
+
     async openForgotPassword() {
         const overlay = document.createElement('div'); overlay.style.cssText='position:fixed;inset:0;z-index:3050;background:rgba(0,0,0,0.6);backdrop-filter:blur(8px);display:flex;align-items:center;justify-content:center;padding:24px;';
         const panel = document.createElement('div'); panel.style.cssText='max-width:420px;width:100%;background:var(--surface-elevated);border:1px solid var(--border);border-radius:12px;padding:16px;color:var(--text-primary)';
@@ -3938,7 +5738,16 @@ class TroughApp {
             const prevText = btn.textContent;
             btn.textContent = 'Sending…';
             try {
-                const r = await fetch('/api/forgot-password', { method:'POST', headers:{'Content-Type':'application/json'}, body: JSON.stringify({ email }) });
+                const send = (extra) => fetch('/api/forgot-password', { method:'POST', headers:{'Content-Type':'application/json'}, body: JSON.stringify({ email, ...extra }) });
+                let r = await send({});
+                if (r.status===403) {
+                    const d = await r.clone().json().catch(()=>({}));
+                    if (d.challenge_required) {
+                        const mount = document.createElement('div'); mount.style.margin = '8px 0'; panel.insertBefore(mount, panel.querySelector('.settings-actions'));
+                        r = await send(await this.solveAuthChallenge(d.challenge, mount));
+                        mount.remove();
+                    }
+                }
                 if (r.status===204) {
                     this.showNotification('Check your email');
                     overlay.remove();
@@ -3996,16 +5805,130 @@ class TroughApp {
             <div class="single-header">
               <h1 class="single-title" title="${this.escapeHTML(String(title))}">${this.escapeHTML(String(title))}</h1>
               <div style="display:flex; align-items:center; gap:8px;">
-                <a href="/@${encodeURIComponent(username)}" class="single-username link-btn" style="text-decoration:none">@${this.escapeHTML(String(username))}</a>
+                <a href="/@${encodeURIComponent(username)}" class="single-username link-btn" style="text-decoration:none">@${this.escapeHTML(String(username))}</a>${this.verifiedBadge(data.user_verified)}
                 <button id="single-collect" class="like-btn collect-btn" title="Collect">✧</button>
+                <button id="single-collected" class="link-btn" type="button" style="font-family:var(--font-mono);font-size:12px" disabled>✦ ${Number(data.collected_count)||0}</button>
+                <a id="single-download" class="link-btn" href="/api/images/${encodeURIComponent(String(data.id||id))}/download" download style="text-decoration:none" title="Download original">Download</a>
+                ${data.has_workflow ? `<a id="single-workflow" class="link-btn" href="/api/images/${encodeURIComponent(String(data.id||id))}/workflow" download style="text-decoration:none" title="Download the ComfyUI workflow">Workflow</a>` : ''}
+                ${this.currentUser?.username === data.username && data.media_type !== 'video' ? `<button id="single-replace" class="link-btn" type="button" title="Replace the file, keeping this page and its stats">Replace file</button><input id="single-replace-input" type="file" accept="image/jpeg,image/png,image/webp,image/gif" style="display:none">` : ''}
+                ${this.currentUser?.is_admin || this.currentUser?.is_moderator ? `<button id="single-history" class="link-btn" type="button" title="Who changed this image and when">History</button>` : ''}
+                <a class="link-btn" href="/takedown?image=${encodeURIComponent(String(data.id||id))}" style="text-decoration:none;opacity:.7" title="Report a copyright infringement">Report copyright</a>
               </div>
             </div>
             <div style="position:relative;display:flex;justify-content:center">
-              <img src="${this.getImageURL(data.filename)}" alt="${title}" style="max-width:100%;max-height:76vh;border-radius:10px;"/>
+              ${data.media_type === 'video'
+                ? `<video src="${this.getImageURL(data.filename)}" poster="${this.stillURL(data)}" controls playsinline loop preload="metadata" style="max-width:100%;max-height:76vh;border-radius:10px;"></video>`
+                : `<img src="${this.getImageURL(data.filename)}" alt="${title}" style="max-width:100%;max-height:76vh;border-radius:10px;"/>`}
             </div>
             ${captionHtml}
+            <div id="single-license" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px;opacity:.75"></div>
+            ${data.ai_model ? `<div id="single-model" class="meta" style="font-family:var(--font-mono);font-size:12px">Model: <a href="/?model=${encodeURIComponent(String(data.ai_model))}" class="link-btn" title="More images made with this model">${this.escapeHTML(String(data.ai_model))}</a></div>` : ''}
+            <div id="single-collectors" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px"></div>
+            <div id="single-links" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px"></div>
+            <div id="single-remix" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px"></div>
+            <div id="single-remixes" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px"></div>
+            <div id="single-edits" class="meta" style="display:none;font-family:var(--font-mono);font-size:12px"></div>
+            <div id="single-featured" class="meta" style="display:flex;gap:8px;align-items:center;font-family:var(--font-mono);font-size:12px"></div>
           </div>`;
         this.gallery.appendChild(wrap);
+        const renderFeatured = () => {
+            const el = wrap.querySelector('#single-featured');
+            const note = data.featured_at ? `★ Featured${data.featured_note ? ` — ${this.escapeHTML(String(data.featured_note))}` : ''}` : '';
+            const canFeature = !!this.currentUser?.is_admin && data.visibility !== 'private' && data.visibility !== 'unlisted' && data.moderation_status !== 'pending';
+            el.innerHTML = `<span>${note}</span>${canFeature ? `<button id="single-feature" class="link-btn">${data.featured_at ? 'Unfeature' : 'Feature'}</button>` : ''}`;
+            const btn = el.querySelector('#single-feature');
+            if (!btn) return;
+            btn.onclick = async () => {
+                let init = { method: 'DELETE', credentials: 'include' };
+                if (!data.featured_at) {
+                    const note = window.prompt('Curator note (optional)', '');
+                    if (note === null) return;
+                    init = { method: 'PUT', credentials: 'include', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ note }) };
+                }
+                const r = await this.fetchWithCSRF(`/api/admin/images/${encodeURIComponent(String(data.id || id))}/featured`, init);
+                const out = await r.json().catch(() => ({}));
+                if (!r.ok) { this.showNotification(out.error || 'Failed', 'error'); return; }
+                data.featured_at = out.featured ? new Date().toISOString() : null;
+                data.featured_note = out.featured ? out.note : null;
+                renderFeatured();
+            };
+        };
+        renderFeatured();
+        if (Array.isArray(data.links) && data.links.length) {
+            const el = wrap.querySelector('#single-links');
+            el.innerHTML = `Links: ${data.links.map(l => {
+                let host = '';
+                try { host = new URL(l.url).hostname; } catch { return ''; }
+                return `<a href="${this.escapeHTML(String(l.url))}" rel="${this.escapeHTML(String(l.rel || 'ugc nofollow noopener'))}" target="_blank" class="link-btn">${this.escapeHTML(String(l.label || host))}</a>`;
+            }).filter(Boolean).join(' · ')}`;
+            el.style.display = 'block';
+        }
+        if (data.remix_of || data.remix_of_url) {
+            const el = wrap.querySelector('#single-remix');
+            el.innerHTML = data.remix_of
+                ? `Remixed from <a href="/i/${encodeURIComponent(String(data.remix_of))}" class="link-btn">an image on this site</a>`
+                : `Remixed from <a href="${this.escapeHTML(String(data.remix_of_url))}" rel="nofollow noopener ugc" target="_blank" class="link-btn">${this.escapeHTML(String(data.remix_of_url))}</a>`;
+            el.style.display = 'block';
+            if (data.remix_of) {
+                fetch(`/api/images/${encodeURIComponent(String(data.remix_of))}`).then(r => r.ok ? r.json() : null).then(p => {
+                    if (!p) return;
+                    const a = el.querySelector('a');
+                    if (a) a.textContent = `${p.title || p.original_name || 'Untitled'} by @${p.username}`;
+                }).catch(() => {});
+            }
+        }
+        fetch(`/api/images/${encodeURIComponent(String(data.id || id))}/remixes?limit=12`).then(r => r.ok ? r.json() : null).then(out => {
+            const list = out && Array.isArray(out.remixes) ? out.remixes : [];
+            if (!list.length) return;
+            const el = wrap.querySelector('#single-remixes');
+            el.innerHTML = `Remixes: ${list.map(r => `<a href="/i/${encodeURIComponent(String(r.id))}" class="link-btn">${this.escapeHTML(String(r.original_name || 'Untitled'))}</a> by @${this.escapeHTML(String(r.username || ''))}`).join(' · ')}`;
+            el.style.display = 'block';
+        }).catch(() => {});
+        const historyBtn = wrap.querySelector('#single-history');
+        if (historyBtn) {
+            historyBtn.onclick = async () => {
+                const el = wrap.querySelector('#single-edits');
+                if (el.style.display !== 'none') { el.style.display = 'none'; return; }
+                const r = await fetch(`/api/images/${encodeURIComponent(String(data.id || id))}/edits`, { credentials: 'include' });
+                const out = await r.json().catch(() => ({}));
+                if (!r.ok) { this.showNotification(out.error || 'Failed', 'error'); return; }
+                const edits = Array.isArray(out.edits) ? out.edits : [];
+                const show = (v) => v == null || v === '' ? '—' : this.escapeHTML(String(v).length > 80 ? String(v).slice(0, 80) + '…' : String(v));
+                el.innerHTML = edits.length ? edits.map(e => `<div>${this.escapeHTML(new Date(e.created_at).toLocaleString())} · ${e.editor_username ? '@' + this.escapeHTML(String(e.editor_username)) : 'deleted user'}${e.by_moderator ? ' (moderator)' : ''} · ${this.escapeHTML(String(e.field))}: ${show(e.old_value)} → ${show(e.new_value)}</div>`).join('') : '<div>No edits</div>';
+                el.style.display = 'block';
+            };
+        }
+        const replaceBtn = wrap.querySelector('#single-replace');
+        const replaceInput = wrap.querySelector('#single-replace-input');
+        if (replaceBtn && replaceInput) {
+            replaceBtn.onclick = () => replaceInput.click();
+            replaceInput.onchange = async () => {
+                const file = replaceInput.files && replaceInput.files[0];
+                if (!file) return;
+                if (!window.confirm('Replace this image\'s file? The previous file is kept as a version.')) { replaceInput.value = ''; return; }
+                const fd = new FormData();
+                fd.append('image', file);
+                replaceBtn.disabled = true;
+                const r = await this.fetchWithCSRF(`/api/images/${encodeURIComponent(String(data.id || id))}/file`, { method: 'POST', credentials: 'include', body: fd });
+                const out = await r.json().catch(() => ({}));
+                replaceBtn.disabled = false;
+                replaceInput.value = '';
+                if (!r.ok) { this.showNotification(out.error || 'Replace failed', 'error'); return; }
+                this.showNotification('File replaced');
+                this.renderImagePage(id);
+            };
+        }
+        if (data.license) {
+            this.getLicenses().then(list => {
+                const lic = list.find(l => l.id === data.license);
+                const el = wrap.querySelector('#single-license');
+                if (!lic || !el) return;
+                el.innerHTML = lic.url
+                    ? `License: <a href="${this.escapeHTML(String(lic.url))}" rel="license noopener" target="_blank" class="link-btn">${this.escapeHTML(String(lic.name))}</a>`
+                    : `License: ${this.escapeHTML(String(lic.name))}`;
+                el.style.display = 'block';
+            });
+        }
 
         // Update document title and meta description on client-side navigation
         try {
@@ -4030,7 +5953,7 @@ class TroughApp {
             };
             const ensureName = (n) => ensureMeta(n);
 
-            const imgURL = this.getImageURL(data.filename);
+            const imgURL = this.stillURL(data);
             const imgAbs = imgURL && imgURL.startsWith('/') ? (location.origin + imgURL) : imgURL;
             const ogType = 'article';
             ensureProp('og:site_name').setAttribute('content', siteTitle);
@@ -4139,6 +6062,25 @@ class TroughApp {
             toggleBtn.addEventListener('click', (e) => { e.stopPropagation(); doToggle(); });
             toggleInsideBtn.addEventListener('click', (e) => { e.stopPropagation(); doToggle(); });
         }
+        // The uploader can open the list of who collected the image
+        const collectedBtn = document.getElementById('single-collected');
+        const collectorsEl = document.getElementById('single-collectors');
+        if (collectedBtn && collectorsEl && this.currentUser && this.currentUser.username === username && Number(data.collected_count) > 0) {
+            collectedBtn.disabled = false;
+            collectedBtn.title = 'Who collected this';
+            collectedBtn.onclick = async () => {
+                if (collectorsEl.style.display !== 'none') { collectorsEl.style.display = 'none'; return; }
+                try {
+                    const resp = await fetch(`/api/images/${encodeURIComponent(String(data.id))}/collectors?limit=100`, { credentials: 'include' });
+                    if (!resp.ok) { this.showNotification('Failed to load collectors', 'error'); return; }
+                    const out = await resp.json();
+                    const list = out.collectors || [];
+                    collectorsEl.innerHTML = list.map(u => `<a href="/@${encodeURIComponent(u.username)}" class="link-btn" style="text-decoration:none">@${this.escapeHTML(u.username)}</a>`).join(' ')
+                        + (out.total > list.length ? ` <span style="opacity:.7">+${out.total - list.length} more</span>` : '');
+                    collectorsEl.style.display = 'block';
+                } catch { this.showNotification('Failed to load collectors', 'error'); }
+            };
+        }
         // Wire collect on single image page (disallow owner)
         const collectBtn = document.getElementById('single-collect');
         if (collectBtn) {
@@ -4157,10 +6099,100 @@ class TroughApp {
         }
     }
 
+    // Returns the CMS page path for a location path (/help/faq -> help/faq), or null when the
+    // path belongs to another view
+    cmsSlugOf(pathname) {
+        const m = /^\/([a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?(?:\/[a-z0-9](?:[a-z0-9-]{0,58}[a-z0-9])?){0,3})$/.exec(String(pathname||''));
+        if (!m) return null;
+        const root = m[1].split('/')[0];
+        return ['i', 'api', 'admin', 'settings', 'uploads'].includes(root) && m[1].includes('/') ? null : m[1];
+    }
+
+    // Themed prompts with their leaderboards; signed-in users enter their images from here
+    async renderChallengesPage(status = 'current') {
+        if (this.profileTop) this.profileTop.innerHTML = '';
+        this.gallery.innerHTML = '';
+        this.gallery.classList.add('settings-mode');
+        const epoch = this.renderEpoch;
+        const wrap = document.createElement('section');
+        wrap.className = 'mono-col';
+        wrap.style.cssText = 'margin:0 auto 16px;max-width:980px;padding:16px;color:var(--text-primary);display:grid;gap:12px';
+        const isAdmin = !!this.currentUser?.is_admin;
+        wrap.innerHTML = `
+          <div style="display:flex;gap:8px;align-items:center;flex-wrap:wrap">
+            <h1 style="margin:0;font-weight:800;letter-spacing:-0.02em">Challenges</h1>
+            ${['current', 'upcoming', 'past'].map(st => `<button class="nav-btn" data-status="${st}" ${st === status ? 'disabled' : ''}>${st[0].toUpperCase() + st.slice(1)}</button>`).join('')}
+            ${isAdmin ? '<button id="challenge-new" class="link-btn" style="margin-left:auto">New challenge</button>' : ''}
+          </div>
+          <div id="challenge-list" style="display:grid;gap:12px"></div>`;
+        this.gallery.appendChild(wrap);
+        document.title = `Challenges - ${document.querySelector('.logo')?.getAttribute('data-text') || 'TROUGH'}`;
+        wrap.querySelectorAll('button[data-status]').forEach(b => { b.onclick = () => this.renderChallengesPage(b.dataset.status); });
+        const newBtn = wrap.querySelector('#challenge-new');
+        if (newBtn) newBtn.onclick = async () => {
+            const title = window.prompt('Title'); if (!title) return;
+            const prompt = window.prompt('Prompt'); if (!prompt) return;
+            const days = Number(window.prompt('Runs for how many days?', '7'));
+            if (!(days > 0)) return;
+            const body = { title, prompt, ends_at: new Date(Date.now() + days * 86400000).toISOString() };
+            const r = await this.fetchWithCSRF('/api/admin/challenges', { method: 'POST', credentials: 'include', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(body) });
+            const out = await r.json().catch(() => ({}));
+            if (!r.ok) { this.showNotification(out.error || 'Failed', 'error'); return; }
+            this.renderChallengesPage('current');
+        };
+        const list = wrap.querySelector('#challenge-list');
+        let challenges = [];
+        try {
+            const r = await fetch(`/api/challenges?status=${encodeURIComponent(status)}`);
+            if (r.ok) challenges = (await r.json()).challenges || [];
+        } catch {}
+        if (epoch !== this.renderEpoch) return;
+        if (!challenges.length) {
+            list.innerHTML = `<div style="color:var(--text-secondary);font-family:var(--font-mono)">No ${this.escapeHTML(status)} challenges.</div>`;
+            return;
+        }
+        challenges.forEach(ch => {
+            const card = document.createElement('article');
+            card.style.cssText = 'border:1px solid var(--border);border-radius:12px;padding:12px;background:var(--surface-elevated);display:grid;gap:8px';
+            const canEnter = !!this.currentUser && ch.status === 'current';
+            card.innerHTML = `
+              <div style="font-weight:800">${this.escapeHTML(String(ch.title))}</div>
+              <div style="color:var(--text-secondary)">${this.escapeHTML(String(ch.prompt))}</div>
+              <div style="font-family:var(--font-mono);font-size:12px;opacity:.75">${this.escapeHTML(new Date(ch.starts_at).toLocaleDateString())} – ${this.escapeHTML(new Date(ch.ends_at).toLocaleDateString())} · ${Number(ch.entry_count) || 0} entries</div>
+              <div style="display:flex;gap:8px">
+                <button class="link-btn" data-act="board">Leaderboard</button>
+                ${canEnter ? '<button class="link-btn" data-act="enter">Enter an image</button>' : ''}
+              </div>
+              <div data-board style="display:none;gap:10px;overflow-x:auto"></div>`;
+            const board = card.querySelector('[data-board]');
+            card.querySelector('[data-act="board"]').onclick = async () => {
+                if (board.style.display === 'flex') { board.style.display = 'none'; return; }
+                const r = await fetch(`/api/challenges/${encodeURIComponent(ch.id)}/leaderboard?limit=24`, { credentials: 'include' });
+                const entries = r.ok ? ((await r.json()).entries || []).filter(e => e.display !== 'hide') : [];
+                board.innerHTML = entries.length
+                    ? entries.map(e => `<a href="/i/${encodeURIComponent(e.id)}" style="flex:0 0 auto;display:grid;gap:4px;text-decoration:none;color:inherit"><img src="${this.stillURL(e)}" alt="" loading="lazy" style="height:120px;width:auto;border-radius:8px;border:1px solid var(--border);${e.display === 'blur' ? 'filter:blur(16px)' : ''}"/><span style="font-family:var(--font-mono);font-size:12px">#${Number(e.rank)} @${this.escapeHTML(String(e.username || ''))} · ✦ ${Number(e.collected_count) || 0}</span></a>`).join('')
+                    : '<span style="color:var(--text-secondary);font-family:var(--font-mono)">No entries yet.</span>';
+                board.style.display = 'flex';
+            };
+            const enterBtn = card.querySelector('[data-act="enter"]');
+            if (enterBtn) enterBtn.onclick = async () => {
+                const ref = window.prompt('Link to one of your public images');
+                if (!ref) return;
+                const m = String(ref).match(/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/i);
+                if (!m) { this.showNotification('That is not an image link', 'error'); return; }
+                const r = await this.fetchWithCSRF(`/api/challenges/${encodeURIComponent(ch.id)}/entries`, { method: 'POST', credentials: 'include', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ image_id: m[0] }) });
+                const out = await r.json().catch(() => ({}));
+                if (!r.ok) { this.showNotification(out.error || 'Failed', 'error'); return; }
+                this.showNotification('Entered');
+            };
+            list.appendChild(card);
+        });
+    }
+
     // Render a CMS page by slug; returns true if handled
     async renderCMSPage(slug) {
         try {
-            const r = await fetch(`/api/pages/${encodeURIComponent(slug)}`);
+            const r = await fetch(`/api/pages/${String(slug).split('/').map(encodeURIComponent).join('/')}`);
             if (!r.ok) return false;
             const d = await r.json().catch(()=>null);
             if (!d) return false;
@@ -4323,6 +6355,7 @@ class TroughApp {
                             redirect_url: (panel.querySelector('#pgx-redirect').value||'').trim() || null,
                             meta_title: panel.querySelector('#pgx-meta-title').value,
                             meta_description: panel.querySelector('#pgx-meta-desc').value,
+                            position: pageRow?.position||0,
                         };
                         try {
                             const rr = await this.fetchWithCSRF(`/api/admin/pages/${encodeURIComponent(pid)}`, { method:'PUT', headers:{ 'Content-Type':'application/json' }, credentials:'include', body: JSON.stringify(body) });
@@ -4349,10 +6382,10 @@ class TroughApp {
         header.style.cssText = 'padding:24px 24px 16px;border-bottom:1px solid var(--border);';
         header.innerHTML = `
             <div style="display:flex;align-items:center;justify-content:space-between;margin-bottom:8px">
-                <h2 style="margin:0;font-size:1.25rem;font-weight:var(--weight-semibold);color:var(--text-primary)">Migrate to Remote Storage</h2>
+                <h2 style="margin:0;font-size:1.25rem;font-weight:var(--weight-semibold);color:var(--text-primary)">Storage Migration</h2>
                 <button id="close-migration" style="background:none;border:none;color:var(--text-secondary);font-size:1.5rem;cursor:pointer;padding:4px;border-radius:4px" title="Close">&times;</button>
             </div>
-            <p style="margin:0;color:var(--text-secondary);font-size:0.9rem;line-height:1.4">Move all local uploads to your configured remote storage and update image URLs in the database.</p>
+            <p style="margin:0;color:var(--text-secondary);font-size:0.9rem;line-height:1.4">Copy all uploads, including avatars and site assets, from one storage to another and update their URLs in the database. By default local uploads move to your configured storage. Safe to re-run: files already stored are skipped.</p>
         `;
 
         const content = document.createElement('div');
@@ -4373,10 +6406,40 @@ class TroughApp {
                 <label style="display:flex;align-items:center;gap:8px;padding:12px;border:1px solid var(--border);border-radius:8px;cursor:pointer;transition:all 0.2s ease" onmouseover="this.style.borderColor='var(--accent)'" onmouseout="this.style.borderColor='var(--border)'">
                     <input type="checkbox" id="cleanup-local" style="accent-color:var(--accent)"/>
                     <div>
-                        <div style="font-weight:var(--weight-medium);color:var(--text-primary);margin-bottom:2px">Delete local files after successful upload</div>
-                        <div style="font-size:0.8rem;color:var(--text-secondary)">Recommended: Saves disk space by removing local copies once they're safely stored remotely</div>
+                        <div style="font-weight:var(--weight-medium);color:var(--text-primary);margin-bottom:2px">Delete source files after they are copied</div>
+                        <div style="font-size:0.8rem;color:var(--text-secondary)">Saves space by removing each source file once it is safely stored in the target and nothing refers to it</div>
                     </div>
                 </label>
+                <label style="display:flex;align-items:center;gap:8px;padding:0 12px;cursor:pointer">
+                    <input type="checkbox" id="migration-dry-run" style="accent-color:var(--accent)"/>
+                    <span style="color:var(--text-primary)">Dry run: only report what would be copied and updated</span>
+                </label>
+                <details style="padding:0 12px">
+                    <summary style="cursor:pointer;color:var(--text-secondary)">Advanced</summary>
+                    <div style="display:grid;gap:8px;margin-top:8px">
+                        ${['source', 'target'].map(side => `
+                        <div style="display:grid;gap:6px">
+                            <label style="display:flex;align-items:center;gap:8px">${side === 'source' ? 'From' : 'To'}
+                                <select id="migration-${side}" class="settings-input" style="flex:1">
+                                    <option value="local" ${side === 'source' ? 'selected' : ''}>Local uploads</option>
+                                    <option value="current" ${side === 'target' ? 'selected' : ''}>Configured storage</option>
+                                    <option value="s3">S3-compatible bucket</option>
+                                    <option value="r2">Cloudflare R2 bucket</option>
+                                </select>
+                            </label>
+                            <div id="migration-${side}-bucket" style="display:none;gap:6px">
+                                <input id="migration-${side}-endpoint" class="settings-input" placeholder="Endpoint (host, e.g. s3.us-east-1.amazonaws.com)"/>
+                                <input id="migration-${side}-name" class="settings-input" placeholder="Bucket"/>
+                                <input id="migration-${side}-public" class="settings-input" placeholder="Public base URL (optional)"/>
+                                <label style="display:flex;gap:8px;align-items:center"><input id="migration-${side}-path" type="checkbox"/> Force path-style URLs</label>
+                            </div>
+                        </div>`).join('')}
+                        <div class="meta" style="opacity:.8">Bucket credentials come from MIGRATE_S3_ACCESS_KEY_ID and MIGRATE_S3_SECRET_ACCESS_KEY on the server, or else the configured storage keys.</div>
+                        <label style="display:flex;align-items:center;gap:8px"><input type="checkbox" id="migration-rewrite" checked/> Update database references to the new location</label>
+                        <label style="display:flex;align-items:center;gap:8px">Parallel copies <input id="migration-concurrency" class="settings-input no-spinner" type="number" min="1" max="16" placeholder="4" style="width:80px"/></label>
+                        <label style="display:flex;align-items:center;gap:8px">Bandwidth limit (KB/s) <input id="migration-bandwidth" class="settings-input no-spinner" type="number" min="0" placeholder="unlimited" style="width:120px"/></label>
+                    </div>
+                </details>
             </div>
 
             <div id="migration-results" style="display:none;margin-top:16px;padding:12px;border-radius:8px;"></div>
@@ -4410,9 +6473,34 @@ class TroughApp {
         document.getElementById('close-migration').onclick = closeMigration;
         document.getElementById('cancel-migration').onclick = closeMigration;
 
+        ['source', 'target'].forEach(side => {
+            const sel = document.getElementById(`migration-${side}`);
+            sel.onchange = () => {
+                document.getElementById(`migration-${side}-bucket`).style.display = (sel.value === 's3' || sel.value === 'r2') ? 'grid' : 'none';
+            };
+        });
+
         document.getElementById('start-migration').onclick = async () => {
-            const cleanupLocal = document.getElementById('cleanup-local').checked;
-            await this.performMigration(cleanupLocal, closeMigration);
+            const backend = (side) => {
+                const provider = document.getElementById(`migration-${side}`).value;
+                if (provider !== 's3' && provider !== 'r2') return provider;
+                return {
+                    provider,
+                    endpoint: document.getElementById(`migration-${side}-endpoint`).value.trim(),
+                    bucket: document.getElementById(`migration-${side}-name`).value.trim(),
+                    public_base_url: document.getElementById(`migration-${side}-public`).value.trim(),
+                    force_path_style: document.getElementById(`migration-${side}-path`).checked
+                };
+            };
+            await this.performMigration({
+                source: backend('source'),
+                target: backend('target'),
+                dry_run: document.getElementById('migration-dry-run').checked,
+                rewrite_urls: document.getElementById('migration-rewrite').checked,
+                delete_source: document.getElementById('cleanup-local').checked,
+                concurrency: parseInt(document.getElementById('migration-concurrency').value, 10) || 0,
+                bandwidth_kbps: parseInt(document.getElementById('migration-bandwidth').value, 10) || 0
+            }, closeMigration);
         };
 
         // Close on overlay click
@@ -4430,7 +6518,7 @@ class TroughApp {
         document.addEventListener('keydown', keyHandler);
     }
 
-    async performMigration(cleanupLocal, closeModalFunction) {
+    async performMigration(migration, closeModalFunction) {
         const statusEl = document.getElementById('migration-status');
         const optionsEl = document.getElementById('migration-options');
         const actionsEl = document.getElementById('migration-actions');
@@ -4446,30 +6534,32 @@ class TroughApp {
 
         try {
             phaseEl.textContent = 'Starting migration...';
-            progressBar.style.width = '20%';
-            detailsEl.textContent = 'Preparing to transfer files...';
-
-            // Simulate progress updates
-            setTimeout(() => {
-                if (progressBar.style.width === '20%') {
-                    progressBar.style.width = '40%';
-                    detailsEl.textContent = 'Uploading files to remote storage...';
-                }
-            }, 500);
+            progressBar.style.width = '5%';
+            detailsEl.textContent = 'Queueing the migration...';
 
-            const response = await this.fetchWithCSRF('/api/admin/site/export-uploads', {
+            const response = await this.fetchWithCSRF('/api/admin/storage/migrate', {
                 method: 'POST',
                 headers: {
                     'Authorization': `Bearer ${localStorage.getItem('token')}`,
                     'Content-Type': 'application/json'
                 },
-                body: JSON.stringify({ cleanup_local: cleanupLocal })
+                body: JSON.stringify(migration)
             });
 
-            progressBar.style.width = '80%';
-            detailsEl.textContent = 'Processing response...';
+            let result = await response.json();
 
-            const result = await response.json();
+            // The migration runs as a background job; follow its progress until it settles
+            if ((response.status === 202 || response.status === 409) && result.job) {
+                phaseEl.textContent = response.status === 409 ? 'Following the running migration...' : (migration.dry_run ? 'Checking files...' : 'Copying files...');
+                const job = await this.followMigration((p) => {
+                    if (!p || !p.total_files) { detailsEl.textContent = 'Listing source files...'; return; }
+                    const pct = Math.round(100 * (p.processed_files || 0) / p.total_files);
+                    progressBar.style.width = Math.max(5, pct) + '%';
+                    detailsEl.textContent = `${p.processed_files || 0} / ${p.total_files} files · ${p.uploaded_files || 0} uploaded · ${p.skipped_files || 0} already stored` + (p.error_count ? ` · ${p.error_count} errors` : '');
+                });
+                if (!job) throw new Error('Migration is still running; reopen this dialog or check the Jobs tab for its progress');
+                result = Object.assign({ error: job.last_error || undefined }, job.result || {});
+            }
 
             progressBar.style.width = '100%';
 
@@ -4478,8 +6568,8 @@ class TroughApp {
                 const spinner = document.getElementById('migration-spinner');
                 if (spinner) spinner.style.display = 'none';
                 
-                phaseEl.textContent = 'Migration completed successfully!';
-                detailsEl.textContent = 'All files have been transferred and database updated.';
+                phaseEl.textContent = result.dry_run ? 'Dry run complete: nothing was changed' : 'Migration completed successfully!';
+                detailsEl.textContent = result.dry_run ? 'A real run would do the following.' : 'All files have been transferred and database updated.';
                 
                 resultsEl.style.display = 'block';
                 resultsEl.style.background = 'var(--color-ok-bg, #0f2e1f)';
@@ -4488,14 +6578,15 @@ class TroughApp {
                 resultsEl.innerHTML = `
                     <div style="font-weight:var(--weight-medium, 500);margin-bottom:8px">✅ Migration Summary</div>
                     <div style="font-size:0.9rem;line-height:1.4">
-                        • <strong>${result.uploaded_files || 0}</strong> files uploaded to remote storage<br>
-                        • <strong>${result.updated_records || 0}</strong> database records updated<br>
-                        ${result.cleaned_files > 0 ? `• <strong>${result.cleaned_files}</strong> local files cleaned up<br>` : ''}
+                        • <strong>${result.uploaded_files || 0}</strong> files ${result.dry_run ? 'to copy' : 'copied'} (${((result.copied_bytes || 0) / 1048576).toFixed(1)} MB)<br>
+                        ${result.skipped_files > 0 ? `• <strong>${result.skipped_files}</strong> files were already stored<br>` : ''}
+                        • <strong>${result.updated_records || 0}</strong> database records ${result.dry_run ? 'to update' : 'updated'}<br>
+                        ${result.cleaned_files > 0 ? `• <strong>${result.cleaned_files}</strong> source files ${result.dry_run ? 'to clean up' : 'cleaned up'}<br>` : ''}
                         ${result.total_files > 0 ? `• Total files processed: <strong>${result.total_files}</strong>` : ''}
                     </div>
                 `;
 
-                this.showNotification('Migration completed successfully!', 'success');
+                this.showNotification(result.dry_run ? 'Dry run complete' : 'Migration completed successfully!', 'success');
             } else {
                 throw new Error(result.error || 'Migration failed');
             }
@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -42,7 +47,7 @@ func (m *MockUserRepository) BeginTx() (*sqlx.Tx, error) {
 	return args.Get(0).(*sqlx.Tx), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) GetByEmail(_ context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -50,7 +55,7 @@ func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByUsername(username string) (*models.User, error) {
+func (m *MockUserRepository) GetByUsername(_ context.Context, username string) (*models.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -58,7 +63,7 @@ func (m *MockUserRepository) GetByUsername(username string) (*models.User, error
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByID(id uuid.UUID) (*models.User, error) {
+func (m *MockUserRepository) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -66,6 +71,15 @@ func (m *MockUserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByIDs(_ context.Context, ids []uuid.UUID) ([]models.User, error) {
+	args := m.Called(ids)
+	var users []models.User
+	if u := args.Get(0); u != nil {
+		users = u.([]models.User)
+	}
+	return users, args.Error(1)
+}
+
 func (m *MockUserRepository) UpdateProfile(id uuid.UUID, updates models.UpdateUserRequest) (*models.User, error) {
 	args := m.Called(id, updates)
 	if args.Get(0) == nil {
@@ -99,6 +113,53 @@ func (m *MockUserRepository) SetDisabled(id uuid.UUID, disabled bool) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Suspend(id uuid.UUID, reason string, until *time.Time) error {
+	args := m.Called(id, reason, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ReleaseExpiredSuspensions() ([]uuid.UUID, error) {
+	args := m.Called()
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockUserRepository) BulkApply(action string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(action, ids)
+	var changed []uuid.UUID
+	if c := args.Get(0); c != nil {
+		changed = c.([]uuid.UUID)
+	}
+	return changed, args.Error(1)
+}
+
+func (m *MockUserRepository) SetVerified(id uuid.UUID, verified bool, reason *string) error {
+	args := m.Called(id, verified, reason)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListPendingApproval(page, limit int) ([]models.User, int, error) {
+	args := m.Called(page, limit)
+	var users []models.User
+	if u := args.Get(0); u != nil {
+		users = u.([]models.User)
+	}
+	total := 0
+	if t := args.Get(1); t != nil {
+		total = t.(int)
+	}
+	return users, total, args.Error(2)
+}
+
+func (m *MockUserRepository) Approve(id uuid.UUID) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SetShadowbanned(id uuid.UUID, shadowbanned bool) error {
+	args := m.Called(id, shadowbanned)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) SetModerator(id uuid.UUID, isModerator bool) error {
 	args := m.Called(id, isModerator)
 	return args.Error(0)
@@ -130,7 +191,38 @@ func (m *MockUserRepository) SearchUsers(q string, page, limit int) ([]models.Us
 	return users, total, args.Error(2)
 }
 
+// stubDriver hands out connections whose transactions do nothing, so handlers that
+// begin one can run against the mock repository.
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("stub: no statements") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return stubConn{}, nil }
+func (stubConn) Commit() error                       { return nil }
+func (stubConn) Rollback() error                     { return nil }
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+func stubTx(t *testing.T) *sqlx.Tx {
+	db, err := sqlx.Open("stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
 func TestRegisterSuccess(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
 	mockRepo := new(MockUserRepository)
 	handler := handlers.NewAuthHandler(mockRepo)
 
@@ -139,7 +231,7 @@ func TestRegisterSuccess(t *testing.T) {
 
 	mockRepo.On("GetByEmail", "test@example.com").Return(nil, sql.ErrNoRows)
 	mockRepo.On("GetByUsername", "testuser").Return(nil, sql.ErrNoRows)
-	mockRepo.On("BeginTx").Return(nil, nil)
+	mockRepo.On("BeginTx").Return(stubTx(t), nil)
 	mockRepo.On("CreateWithTx", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
 
 	reqBody := map[string]string{
@@ -187,6 +279,7 @@ func TestRegisterEmailExists(t *testing.T) {
 }
 
 func TestLoginSuccess(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
 	mockRepo := new(MockUserRepository)
 	handler := handlers.NewAuthHandler(mockRepo)
 
@@ -203,8 +296,8 @@ func TestLoginSuccess(t *testing.T) {
 	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
 
 	reqBody := map[string]string{
-		"identifier": "test@example.com",
-		"password":   "Password123!",
+		"login_identifier": "test@example.com",
+		"login_password":   "Password123!",
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -219,6 +312,7 @@ func TestLoginSuccess(t *testing.T) {
 }
 
 func TestLoginSuccessWithUsername(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
 	mockRepo := new(MockUserRepository)
 	handler := handlers.NewAuthHandler(mockRepo)
 
@@ -235,8 +329,8 @@ func TestLoginSuccessWithUsername(t *testing.T) {
 	mockRepo.On("GetByUsername", "testuser").Return(user, nil)
 
 	reqBody := map[string]string{
-		"identifier": "testuser",
-		"password":   "Password123!",
+		"login_identifier": "testuser",
+		"login_password":   "Password123!",
 	}
 
 	body, _ := json.Marshal(reqBody)
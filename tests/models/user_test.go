@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yourusername/trough/models"
@@ -38,3 +39,23 @@ func TestUserToResponse(t *testing.T) {
 	assert.Equal(t, "testuser", response.Username)
 	assert.Nil(t, response.Bio)
 }
+
+func TestUserActiveSuspension(t *testing.T) {
+	assert.Nil(t, (&models.User{}).ActiveSuspension())
+
+	disabled := &models.User{IsDisabled: true}
+	if s := disabled.ActiveSuspension(); assert.NotNil(t, s) {
+		assert.Nil(t, s.Until)
+	}
+
+	future := time.Now().Add(time.Hour)
+	timed := &models.User{IsDisabled: true, SuspensionReason: "spam", SuspendedUntil: &future}
+	if s := timed.ActiveSuspension(); assert.NotNil(t, s) {
+		assert.Equal(t, "spam", s.Reason)
+		assert.Equal(t, &future, s.Until)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	expired := &models.User{IsDisabled: true, SuspendedUntil: &past}
+	assert.Nil(t, expired.ActiveSuspension(), "an expired suspension no longer applies")
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yourusername/trough/services"
@@ -41,3 +42,59 @@ aesthetic:
 	assert.Equal(t, "TestKey", config.AISignatures[0].Key)
 	assert.Equal(t, 1024, config.Aesthetic.MaxWidth)
 }
+
+func TestLoadConfigEnvOverridesAndValidation(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "test-config-*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.WriteString("server:\n  port: 9000\n  read_timeout: 5s\npaths:\n  backup_dir: /var/backups/trough\n")
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	t.Setenv("PORT", "9100")
+	t.Setenv("JWT_LIFETIME", "12h")
+	config, err := services.LoadConfig(tempFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, 9100, config.Server.Port, "env overrides the file")
+	assert.Equal(t, 5*time.Second, config.Server.ReadTimeout, "file overrides defaults")
+	assert.Equal(t, 30*time.Second, config.Server.WriteTimeout, "unset keys keep defaults")
+	assert.Equal(t, "/var/backups/trough", config.Paths.BackupDir)
+	assert.Equal(t, 12*time.Hour, config.Auth.JWTLifetime)
+	assert.Equal(t, 60, config.ProgressiveRateLimiting.BaseCapacity)
+
+	t.Setenv("PORT", "70000")
+	_, err = services.LoadConfig(tempFile.Name())
+	assert.Error(t, err)
+
+	t.Setenv("PORT", "abc")
+	_, err = services.LoadConfig(tempFile.Name())
+	assert.Error(t, err)
+}
+
+func TestLoadConfigTLSValidation(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "/etc/trough/cert.pem")
+	_, err := services.LoadConfig("nonexistent.yaml")
+	assert.Error(t, err, "cert without key")
+
+	t.Setenv("TLS_KEY_FILE", "/etc/trough/key.pem")
+	t.Setenv("BIND_ADDRESS", "127.0.0.1")
+	t.Setenv("PORT", "8443")
+	config, err := services.LoadConfig("nonexistent.yaml")
+	assert.NoError(t, err)
+	assert.True(t, config.Server.TLS.Enabled())
+	assert.Equal(t, "127.0.0.1:8443", config.Server.ListenAddress())
+
+	t.Setenv("ACME_DOMAINS", "example.com, www.example.com")
+	_, err = services.LoadConfig("nonexistent.yaml")
+	assert.Error(t, err, "cert files and ACME together")
+
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+	config, err = services.LoadConfig("nonexistent.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, config.Server.TLS.ACMEDomains)
+
+	t.Setenv("PREFORK", "true")
+	_, err = services.LoadConfig("nonexistent.yaml")
+	assert.Error(t, err, "prefork with ACME")
+}